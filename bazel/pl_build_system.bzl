@@ -18,7 +18,7 @@
 # Compute the final copts based on various options.
 
 load("@io_bazel_rules_docker//go:image.bzl", "go_image")
-load("@io_bazel_rules_go//go:def.bzl", "go_binary", "go_context", "go_library", "go_test")
+load("@io_bazel_rules_go//go:def.bzl", "go_binary", "go_context", "go_cross_binary", "go_library", "go_test")
 load("@rules_cc//cc:defs.bzl", "cc_binary", "cc_library", "cc_test")
 load("@rules_python//python:defs.bzl", "py_test")
 load("//bazel:toolchain_transitions.bzl", "qemu_interactive_runner")
@@ -452,6 +452,21 @@ def pl_go_image(**kwargs):
         **kwargs
     )
 
+# pl_go_fips_image builds an image for the given binary using the boringcrypto go sdk, for clusters
+# which require FIPS validated crypto.
+def pl_go_fips_image(name, binary, **kwargs):
+    go_cross_binary(
+        name = name + "_boringcrypto_binary",
+        sdk_version = pl_boringcrypto_go_sdk[0],
+        tags = ["manual"],
+        target = binary,
+    )
+    pl_go_image(
+        name = name,
+        binary = ":" + name + "_boringcrypto_binary",
+        **kwargs
+    )
+
 def _add_no_pie(kwargs):
     if "gc_linkopts" not in kwargs:
         kwargs["gc_linkopts"] = []
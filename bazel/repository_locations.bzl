@@ -81,6 +81,11 @@ REPOSITORY_LOCATIONS = dict(
         strip_prefix = "tdigest-85e0f70092460e60236821db4c25143768d3da12",
         urls = ["https://github.com/pixie-io/tdigest/archive/85e0f70092460e60236821db4c25143768d3da12.tar.gz"],
     ),
+    com_github_facebook_zstd = dict(
+        sha256 = "9c4396cc829cfae319a6e2615202e82aad41372073482fce286fac78646d3ee4",
+        strip_prefix = "zstd-1.5.5",
+        urls = ["https://github.com/facebook/zstd/releases/download/v1.5.5/zstd-1.5.5.tar.gz"],
+    ),
     com_github_fmeum_rules_meta = dict(
         sha256 = "ed3ed909e6e3f34a11d7c2adcc461535975a875fe434719540a4e6f63434a866",
         strip_prefix = "rules_meta-0.0.4",
@@ -162,6 +167,11 @@ REPOSITORY_LOCATIONS = dict(
         strip_prefix = "nats.c-3.3.0",
         urls = ["https://github.com/nats-io/nats.c/archive/refs/tags/v3.3.0.tar.gz"],
     ),
+    com_github_lz4_lz4 = dict(
+        sha256 = "0b0e3aa07c8c063ddf40b082bdf7e37a1562bda40a0ff5272957f3e987e0e54b",
+        strip_prefix = "lz4-1.9.4",
+        urls = ["https://github.com/lz4/lz4/archive/refs/tags/v1.9.4.tar.gz"],
+    ),
     com_github_neargye_magic_enum = dict(
         sha256 = "4fe6627407a656d0d73879c0346b251ccdcfb718c37bef5410ba172c7c7d5f9a",
         strip_prefix = "magic_enum-0.7.0",
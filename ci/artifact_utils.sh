@@ -68,7 +68,7 @@ upload_artifact_to_mirrors() {
 
   if [[ -n "${artifact_type}" ]] && [[ "${version}" != "latest" ]]; then
     artifact_upload_log="$(realpath "${ARTIFACT_UPLOAD_LOG:?}")"
-    jq --null-input --args '{artifactType: "'"${artifact_type}"'", sha256: "'"$(cat "${artifact_path}.sha256")"'", urls: $ARGS.positional}' -- \
+    jq --null-input --rawfile signature "${artifact_path}.asc" --args '{artifactType: "'"${artifact_type}"'", sha256: "'"$(cat "${artifact_path}.sha256")"'", signature: $signature, urls: $ARGS.positional}' -- \
       "${urls[@]}" >> "${artifact_upload_log}"
   fi
 }
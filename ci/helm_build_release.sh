@@ -19,8 +19,8 @@
 set -ex
 
 usage() {
-  echo "Usage: $0 <version> <yaml_tar>"
-  echo "Example: $0 0.1.2 /tmp/yamls.tar"
+  echo "Usage: $0 <version> <chart_dir>"
+  echo "Example: $0 0.1.2 /tmp/vizier-chart"
 }
 
 parse_args() {
@@ -29,36 +29,24 @@ parse_args() {
   fi
 
   VERSION=$1
-  YAML_TAR=$2
+  CHART_DIR=$2
 }
 
 parse_args "$@"
 
-tmp_path="/tmp/helm-${VERSION}"
 tmp_dir="$(mktemp -d)"
 artifacts_dir="${ARTIFACTS_DIR:?}"
 index_file="${INDEX_FILE:?}"
 gh_repo="${GH_REPO:?}"
 
-mkdir -p "${tmp_path}"
-# A Helm chart contains two main items:
-# 1. Chart.yaml which specifies the version and name of the chart.
-# 2. `templates` directory, which contains the template YAMLs that should be filled out.
-
-# Create Chart.yaml for this release.
-echo "apiVersion: v2
-name: vizier-chart
-type: application
-version: ${VERSION}" > "${tmp_path}/Chart.yaml"
-
-# Extract templated YAMLs.
-tar xvf "${YAML_TAR}" -C "${tmp_dir}"
-mv "${tmp_dir}/pixie_yamls" "${tmp_path}/templates"
+# The chart (Chart.yaml, values.yaml, values.schema.json and the templates) is generated by the
+# template_generator from the same templated YAMLs that are used by the CLI and the operator.
+helm lint "${CHART_DIR}"
 
 mkdir -p "${tmp_dir}/charts"
 
 # Generates tgz for the new release helm chart.
-helm package "${tmp_path}" -d "${tmp_dir}/charts"
+helm package "${CHART_DIR}" -d "${tmp_dir}/charts"
 
 cp "${tmp_dir}/charts/vizier-chart-${VERSION}.tgz" "${artifacts_dir}/vizier-chart-${VERSION}.tgz"
 sha256sum "${tmp_dir}/charts/vizier-chart-${VERSION}.tgz" | awk '{print $1}' > sha
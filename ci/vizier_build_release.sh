@@ -51,8 +51,9 @@ upload_artifact_to_mirrors "vizier" "${release_tag}" "${yamls_tar}" "vizier_yaml
 
 # Upload templated YAMLs.
 tmp_dir="$(mktemp -d)"
+chart_dir="${tmp_dir}/vizier-chart"
 bazel run -c opt //src/utils/template_generator:template_generator -- \
-      --base "${yamls_tar}" --version "${release_tag}" --out "${tmp_dir}"
+      --base "${yamls_tar}" --version "${release_tag}" --out "${tmp_dir}" --chart_out "${chart_dir}"
 tmpl_path="${tmp_dir}/yamls.tar"
 upload_artifact_to_mirrors "vizier" "${release_tag}" "${tmpl_path}" "vizier_template_yamls.tar" AT_CONTAINER_SET_TEMPLATE_YAMLS
 
@@ -61,7 +62,7 @@ if [[ $VERSION != *"-"* ]]; then
   # Update Vizier YAMLS in latest.
   upload_artifact_to_mirrors "vizier" "latest" "${yamls_tar}" "vizier_yamls.tar"
 
-  ./ci/helm_build_release.sh "${release_tag}" "${tmpl_path}"
+  ./ci/helm_build_release.sh "${release_tag}" "${chart_dir}"
 fi
 
 create_manifest_update "vizier" "${release_tag}" > "${manifest_updates}"
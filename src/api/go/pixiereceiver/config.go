@@ -0,0 +1,162 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pixiereceiver
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configopaque"
+)
+
+const (
+	defaultCollectionInterval = time.Minute
+	defaultMetricPrefix       = "pixie."
+	defaultTimeColumn         = "time_"
+	defaultSpanNameColumn     = "req_path"
+	defaultDurationColumn     = "latency"
+)
+
+// Config configures the Pixie receiver.
+type Config struct {
+	// APIKey is the Pixie API key used to run the scripts.
+	APIKey configopaque.String `mapstructure:"api_key"`
+	// CloudAddr is the address of Pixie Cloud. Defaults to work.withpixie.ai:443.
+	CloudAddr string `mapstructure:"cloud_addr"`
+	// ClusterIDs are the Viziers that the scripts run on. Defaults to all healthy Viziers of the org.
+	ClusterIDs []string `mapstructure:"cluster_ids"`
+	// Scripts are the PxL scripts that are run on a schedule.
+	Scripts []ScriptConfig `mapstructure:"scripts"`
+}
+
+// ScriptConfig configures a single PxL script, and how its results are converted. Each script
+// produces either metrics or spans, so it configures exactly one of Metrics and Spans.
+type ScriptConfig struct {
+	// Name identifies the script in logs and in the pixie.script resource attribute.
+	Name string `mapstructure:"name"`
+	// PxL is the script to run. Its start_time should cover the collection interval.
+	PxL string `mapstructure:"pxl"`
+	// CollectionInterval is how often the script runs. Defaults to a minute.
+	CollectionInterval time.Duration `mapstructure:"collection_interval"`
+	// ResourceAttributes maps resource attributes to the columns that they're read from, for
+	// example k8s.pod.name: pod. Rows with the same resource attributes are grouped together.
+	ResourceAttributes map[string]string `mapstructure:"resource_attributes"`
+	// Metrics converts the script's tables into metrics.
+	Metrics *MetricsConfig `mapstructure:"metrics"`
+	// Spans converts the script's tables into spans.
+	Spans *SpansConfig `mapstructure:"spans"`
+}
+
+// MetricsConfig configures how tables are converted into metrics. Every value column becomes a
+// gauge, and the other columns become attributes of its data points.
+type MetricsConfig struct {
+	// ValueColumns are the columns that are converted into gauges. Defaults to all of the numeric columns.
+	ValueColumns []string `mapstructure:"value_columns"`
+	// Prefix is prepended to the column name to get the metric name. Defaults to "pixie.".
+	Prefix string `mapstructure:"prefix"`
+	// TimeColumn is the column with the time of the data point. Defaults to time_.
+	TimeColumn string `mapstructure:"time_column"`
+}
+
+// SpansConfig configures how tables are converted into spans. Every row becomes a span, and the
+// columns that aren't used for the span itself become its attributes.
+type SpansConfig struct {
+	// NameColumn is the column with the name of the span. Defaults to req_path.
+	NameColumn string `mapstructure:"name_column"`
+	// StartTimeColumn is the column with the start time of the span. Defaults to time_.
+	StartTimeColumn string `mapstructure:"start_time_column"`
+	// DurationColumn is the column with the duration of the span in nanoseconds. Defaults to latency.
+	DurationColumn string `mapstructure:"duration_column"`
+	// TraceIDColumn is the column with the hex encoded trace ID. A random trace ID is used if unset.
+	TraceIDColumn string `mapstructure:"trace_id_column"`
+	// SpanIDColumn is the column with the hex encoded span ID. A random span ID is used if unset.
+	SpanIDColumn string `mapstructure:"span_id_column"`
+	// ParentSpanIDColumn is the column with the hex encoded ID of the parent span, if any.
+	ParentSpanIDColumn string `mapstructure:"parent_span_id_column"`
+}
+
+var _ component.Config = (*Config)(nil)
+
+func createDefaultConfig() component.Config {
+	return &Config{}
+}
+
+// Validate checks that the config is complete.
+func (cfg *Config) Validate() error {
+	if cfg.APIKey == "" {
+		return errors.New("api_key is required")
+	}
+	if len(cfg.Scripts) == 0 {
+		return errors.New("at least one script is required")
+	}
+	names := make(map[string]bool)
+	for i := range cfg.Scripts {
+		s := &cfg.Scripts[i]
+		if s.Name == "" {
+			return fmt.Errorf("script #%d: name is required", i)
+		}
+		if names[s.Name] {
+			return fmt.Errorf("script %s: duplicate name", s.Name)
+		}
+		names[s.Name] = true
+		if s.PxL == "" {
+			return fmt.Errorf("script %s: pxl is required", s.Name)
+		}
+		if s.CollectionInterval < 0 {
+			return fmt.Errorf("script %s: collection_interval must not be negative", s.Name)
+		}
+		if (s.Metrics == nil) == (s.Spans == nil) {
+			return fmt.Errorf("script %s: exactly one of metrics and spans is required", s.Name)
+		}
+	}
+	return nil
+}
+
+// withDefaults returns a copy of the script config with the defaults filled in.
+func (s ScriptConfig) withDefaults() ScriptConfig {
+	if s.CollectionInterval == 0 {
+		s.CollectionInterval = defaultCollectionInterval
+	}
+	if s.Metrics != nil {
+		m := *s.Metrics
+		s.Metrics = &m
+		if m.Prefix == "" {
+			m.Prefix = defaultMetricPrefix
+		}
+		if m.TimeColumn == "" {
+			m.TimeColumn = defaultTimeColumn
+		}
+	}
+	if s.Spans != nil {
+		sp := *s.Spans
+		s.Spans = &sp
+		if sp.NameColumn == "" {
+			sp.NameColumn = defaultSpanNameColumn
+		}
+		if sp.StartTimeColumn == "" {
+			sp.StartTimeColumn = defaultTimeColumn
+		}
+		if sp.DurationColumn == "" {
+			sp.DurationColumn = defaultDurationColumn
+		}
+	}
+	return s
+}
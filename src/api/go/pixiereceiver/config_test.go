@@ -0,0 +1,107 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pixiereceiver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	valid := func() *Config {
+		return &Config{
+			APIKey: "px-api-key",
+			Scripts: []ScriptConfig{
+				{Name: "http_stats", PxL: "px.display(df)", Metrics: &MetricsConfig{}},
+				{Name: "http_spans", PxL: "px.display(df)", Spans: &SpansConfig{}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(cfg *Config)
+		errMsg string
+	}{
+		{
+			name:   "valid",
+			modify: func(cfg *Config) {},
+		},
+		{
+			name:   "missing api key",
+			modify: func(cfg *Config) { cfg.APIKey = "" },
+			errMsg: "api_key is required",
+		},
+		{
+			name:   "no scripts",
+			modify: func(cfg *Config) { cfg.Scripts = nil },
+			errMsg: "at least one script is required",
+		},
+		{
+			name:   "duplicate name",
+			modify: func(cfg *Config) { cfg.Scripts[1].Name = "http_stats" },
+			errMsg: "script http_stats: duplicate name",
+		},
+		{
+			name:   "missing pxl",
+			modify: func(cfg *Config) { cfg.Scripts[0].PxL = "" },
+			errMsg: "script http_stats: pxl is required",
+		},
+		{
+			name:   "negative interval",
+			modify: func(cfg *Config) { cfg.Scripts[0].CollectionInterval = -time.Second },
+			errMsg: "script http_stats: collection_interval must not be negative",
+		},
+		{
+			name:   "metrics and spans",
+			modify: func(cfg *Config) { cfg.Scripts[0].Spans = &SpansConfig{} },
+			errMsg: "script http_stats: exactly one of metrics and spans is required",
+		},
+		{
+			name:   "neither metrics nor spans",
+			modify: func(cfg *Config) { cfg.Scripts[1].Spans = nil },
+			errMsg: "script http_spans: exactly one of metrics and spans is required",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := valid()
+			test.modify(cfg)
+			err := cfg.Validate()
+			if test.errMsg == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.errMsg)
+			}
+		})
+	}
+}
+
+func TestScriptConfig_WithDefaults(t *testing.T) {
+	s := ScriptConfig{Name: "http_stats", PxL: "px.display(df)", Metrics: &MetricsConfig{}}
+	d := s.withDefaults()
+	assert.Equal(t, defaultCollectionInterval, d.CollectionInterval)
+	assert.Equal(t, "pixie.", d.Metrics.Prefix)
+	assert.Equal(t, "time_", d.Metrics.TimeColumn)
+	// The original config isn't modified.
+	assert.Equal(t, "", s.Metrics.Prefix)
+}
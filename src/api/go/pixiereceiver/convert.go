@@ -0,0 +1,309 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pixiereceiver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"px.dev/pixie/src/api/go/pxapi"
+	"px.dev/pixie/src/api/go/pxapi/types"
+	"px.dev/pixie/src/api/proto/vizierpb"
+)
+
+const (
+	scopeName = "px.dev/pixiereceiver"
+
+	clusterIDAttr = "pixie.cluster.id"
+	scriptAttr    = "pixie.script"
+)
+
+// resourceOf returns the resource attributes of the record, and a key that identifies them.
+func resourceOf(r *types.Record, script *ScriptConfig) (map[string]string, string) {
+	attrs := make(map[string]string, len(script.ResourceAttributes))
+	names := make([]string, 0, len(script.ResourceAttributes))
+	for name, col := range script.ResourceAttributes {
+		if d := r.GetDatum(col); d != nil {
+			attrs[name] = d.String()
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var key strings.Builder
+	for _, name := range names {
+		key.WriteString(name)
+		key.WriteByte('=')
+		key.WriteString(attrs[name])
+		key.WriteByte(0)
+	}
+	return attrs, key.String()
+}
+
+func fillResource(res pcommon.Resource, attrs map[string]string, clusterID string, script *ScriptConfig) {
+	res.Attributes().PutStr(clusterIDAttr, clusterID)
+	res.Attributes().PutStr(scriptAttr, script.Name)
+	for name, value := range attrs {
+		res.Attributes().PutStr(name, value)
+	}
+}
+
+// isResourceColumn returns whether the column is one of the script's resource attributes.
+func isResourceColumn(script *ScriptConfig, col string) bool {
+	for _, c := range script.ResourceAttributes {
+		if c == col {
+			return true
+		}
+	}
+	return false
+}
+
+// putAttribute sets the attribute to the value of the datum, keeping its type where OTel has one.
+func putAttribute(attrs pcommon.Map, name string, d types.Datum) {
+	switch v := d.(type) {
+	case *types.BooleanValue:
+		attrs.PutBool(name, v.Value())
+	case *types.Int64Value:
+		attrs.PutInt(name, v.Value())
+	case *types.Float64Value:
+		attrs.PutDouble(name, v.Value())
+	default:
+		attrs.PutStr(name, d.String())
+	}
+}
+
+func timestampOf(r *types.Record, col string) pcommon.Timestamp {
+	if t, ok := r.GetDatum(col).(*types.Time64NSValue); ok {
+		return pcommon.NewTimestampFromTime(t.Value())
+	}
+	return pcommon.NewTimestampFromTime(time.Now())
+}
+
+type tableHandler struct {
+	handleRecord func(r *types.Record)
+}
+
+func (h *tableHandler) HandleInit(ctx context.Context, md types.TableMetadata) error {
+	return nil
+}
+
+func (h *tableHandler) HandleRecord(ctx context.Context, r *types.Record) error {
+	h.handleRecord(r)
+	return nil
+}
+
+func (h *tableHandler) HandleDone(ctx context.Context) error {
+	return nil
+}
+
+// metricsBuilder converts the tables of a script into metrics, as their records are streamed.
+type metricsBuilder struct {
+	script    *ScriptConfig
+	clusterID string
+
+	metrics   pmetric.Metrics
+	resources map[string]*resourceMetrics
+}
+
+type resourceMetrics struct {
+	scope  pmetric.ScopeMetrics
+	byName map[string]pmetric.Metric
+}
+
+var _ pxapi.TableMuxer = (*metricsBuilder)(nil)
+
+func newMetricsBuilder(script *ScriptConfig, clusterID string) *metricsBuilder {
+	return &metricsBuilder{
+		script:    script,
+		clusterID: clusterID,
+		metrics:   pmetric.NewMetrics(),
+		resources: make(map[string]*resourceMetrics),
+	}
+}
+
+func (b *metricsBuilder) AcceptTable(ctx context.Context, md types.TableMetadata) (pxapi.TableRecordHandler, error) {
+	cfg := b.script.Metrics
+	valueCols := make(map[string]bool)
+	for _, col := range cfg.ValueColumns {
+		valueCols[col] = true
+	}
+	if len(valueCols) == 0 {
+		for _, col := range md.ColInfo {
+			isNumeric := col.Type == vizierpb.INT64 || col.Type == vizierpb.FLOAT64
+			if isNumeric && col.Name != cfg.TimeColumn && !isResourceColumn(b.script, col.Name) {
+				valueCols[col.Name] = true
+			}
+		}
+	}
+	return &tableHandler{handleRecord: func(r *types.Record) {
+		b.handleRecord(r, valueCols)
+	}}, nil
+}
+
+func (b *metricsBuilder) resource(r *types.Record) *resourceMetrics {
+	attrs, key := resourceOf(r, b.script)
+	if rm, ok := b.resources[key]; ok {
+		return rm
+	}
+	res := b.metrics.ResourceMetrics().AppendEmpty()
+	fillResource(res.Resource(), attrs, b.clusterID, b.script)
+	scope := res.ScopeMetrics().AppendEmpty()
+	scope.Scope().SetName(scopeName)
+	rm := &resourceMetrics{scope: scope, byName: make(map[string]pmetric.Metric)}
+	b.resources[key] = rm
+	return rm
+}
+
+func (b *metricsBuilder) handleRecord(r *types.Record, valueCols map[string]bool) {
+	cfg := b.script.Metrics
+	rm := b.resource(r)
+	ts := timestampOf(r, cfg.TimeColumn)
+
+	for i, col := range r.TableMetadata.ColInfo {
+		if !valueCols[col.Name] {
+			continue
+		}
+		name := cfg.Prefix + col.Name
+		m, ok := rm.byName[name]
+		if !ok {
+			m = rm.scope.Metrics().AppendEmpty()
+			m.SetName(name)
+			m.SetEmptyGauge()
+			rm.byName[name] = m
+		}
+		dp := m.Gauge().DataPoints().AppendEmpty()
+		dp.SetTimestamp(ts)
+		switch v := r.Data[i].(type) {
+		case *types.Int64Value:
+			dp.SetIntValue(v.Value())
+		case *types.Float64Value:
+			dp.SetDoubleValue(v.Value())
+		}
+		for j, attrCol := range r.TableMetadata.ColInfo {
+			if valueCols[attrCol.Name] || attrCol.Name == cfg.TimeColumn || isResourceColumn(b.script, attrCol.Name) {
+				continue
+			}
+			putAttribute(dp.Attributes(), attrCol.Name, r.Data[j])
+		}
+	}
+}
+
+// spansBuilder converts the tables of a script into spans, as their records are streamed.
+type spansBuilder struct {
+	script    *ScriptConfig
+	clusterID string
+
+	traces    ptrace.Traces
+	resources map[string]ptrace.ScopeSpans
+}
+
+var _ pxapi.TableMuxer = (*spansBuilder)(nil)
+
+func newSpansBuilder(script *ScriptConfig, clusterID string) *spansBuilder {
+	return &spansBuilder{
+		script:    script,
+		clusterID: clusterID,
+		traces:    ptrace.NewTraces(),
+		resources: make(map[string]ptrace.ScopeSpans),
+	}
+}
+
+func (b *spansBuilder) AcceptTable(ctx context.Context, md types.TableMetadata) (pxapi.TableRecordHandler, error) {
+	return &tableHandler{handleRecord: b.handleRecord}, nil
+}
+
+func (b *spansBuilder) resource(r *types.Record) ptrace.ScopeSpans {
+	attrs, key := resourceOf(r, b.script)
+	if ss, ok := b.resources[key]; ok {
+		return ss
+	}
+	res := b.traces.ResourceSpans().AppendEmpty()
+	fillResource(res.Resource(), attrs, b.clusterID, b.script)
+	ss := res.ScopeSpans().AppendEmpty()
+	ss.Scope().SetName(scopeName)
+	b.resources[key] = ss
+	return ss
+}
+
+// decodeID fills the ID from the hex encoded column. It returns false if the column is unset or
+// doesn't hold an ID of the right length.
+func decodeID(r *types.Record, col string, id []byte) bool {
+	if col != "" {
+		if d := r.GetDatum(col); d != nil {
+			if b, err := hex.DecodeString(d.String()); err == nil && len(b) == len(id) {
+				copy(id, b)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (b *spansBuilder) handleRecord(r *types.Record) {
+	cfg := b.script.Spans
+	span := b.resource(r).Spans().AppendEmpty()
+
+	if d := r.GetDatum(cfg.NameColumn); d != nil {
+		span.SetName(d.String())
+	}
+	start := timestampOf(r, cfg.StartTimeColumn)
+	span.SetStartTimestamp(start)
+	end := start
+	if d, ok := r.GetDatum(cfg.DurationColumn).(*types.Int64Value); ok {
+		end = start + pcommon.Timestamp(d.Value())
+	}
+	span.SetEndTimestamp(end)
+
+	var traceID pcommon.TraceID
+	if !decodeID(r, cfg.TraceIDColumn, traceID[:]) {
+		_, _ = rand.Read(traceID[:])
+	}
+	span.SetTraceID(traceID)
+	var spanID pcommon.SpanID
+	if !decodeID(r, cfg.SpanIDColumn, spanID[:]) {
+		_, _ = rand.Read(spanID[:])
+	}
+	span.SetSpanID(spanID)
+	var parentID pcommon.SpanID
+	if decodeID(r, cfg.ParentSpanIDColumn, parentID[:]) {
+		span.SetParentSpanID(parentID)
+	}
+
+	used := map[string]bool{
+		cfg.NameColumn:         true,
+		cfg.StartTimeColumn:    true,
+		cfg.DurationColumn:     true,
+		cfg.TraceIDColumn:      true,
+		cfg.SpanIDColumn:       true,
+		cfg.ParentSpanIDColumn: true,
+	}
+	for i, col := range r.TableMetadata.ColInfo {
+		if used[col.Name] || isResourceColumn(b.script, col.Name) {
+			continue
+		}
+		putAttribute(span.Attributes(), col.Name, r.Data[i])
+	}
+}
@@ -0,0 +1,187 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pixiereceiver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+
+	"px.dev/pixie/src/api/go/pxapi"
+	"px.dev/pixie/src/api/go/pxapi/types"
+	"px.dev/pixie/src/api/proto/vizierpb"
+)
+
+type testCol struct {
+	name  string
+	value interface{}
+}
+
+// streamTable streams rows of the given columns into the muxer, reusing the record like pxapi does.
+func streamTable(t *testing.T, mux pxapi.TableMuxer, cols []string, rows [][]interface{}) {
+	md := &types.TableMetadata{Name: "output", ColIdxByName: make(map[string]int64)}
+	for i, name := range cols {
+		var dt vizierpb.DataType
+		switch rows[0][i].(type) {
+		case int64:
+			dt = vizierpb.INT64
+		case float64:
+			dt = vizierpb.FLOAT64
+		case time.Time:
+			dt = vizierpb.TIME64NS
+		default:
+			dt = vizierpb.STRING
+		}
+		md.ColInfo = append(md.ColInfo, types.ColSchema{Name: name, Type: dt})
+		md.ColIdxByName[name] = int64(i)
+	}
+
+	ctx := context.Background()
+	h, err := mux.AcceptTable(ctx, *md)
+	require.NoError(t, err)
+	require.NoError(t, h.HandleInit(ctx, *md))
+	r := &types.Record{TableMetadata: md, Data: make([]types.Datum, len(cols))}
+	for _, row := range rows {
+		for i, v := range row {
+			schema := &md.ColInfo[i]
+			switch v := v.(type) {
+			case int64:
+				d := types.NewInt64Value(schema)
+				d.ScanInt64(v)
+				r.Data[i] = d
+			case float64:
+				d := types.NewFloat64Value(schema)
+				d.ScanFloat64(v)
+				r.Data[i] = d
+			case time.Time:
+				d := types.NewTime64NSValue(schema)
+				d.ScanInt64(v.UnixNano())
+				r.Data[i] = d
+			case string:
+				d := types.NewStringValue(schema)
+				d.ScanString(v)
+				r.Data[i] = d
+			}
+		}
+		require.NoError(t, h.HandleRecord(ctx, r))
+	}
+	require.NoError(t, h.HandleDone(ctx))
+}
+
+func TestMetricsBuilder(t *testing.T) {
+	script := (&ScriptConfig{
+		Name:               "http_stats",
+		PxL:                "px.display(df)",
+		ResourceAttributes: map[string]string{"k8s.pod.name": "pod"},
+		Metrics:            &MetricsConfig{},
+	}).withDefaults()
+	b := newMetricsBuilder(&script, "cluster-1")
+
+	now := time.Unix(1700000000, 0)
+	streamTable(t, b, []string{"time_", "pod", "req_path", "count", "latency"}, [][]interface{}{
+		{now, "pl/pod-a", "/a", int64(3), 1.5},
+		{now, "pl/pod-a", "/b", int64(5), 2.5},
+		{now, "pl/pod-b", "/a", int64(7), 3.5},
+	})
+
+	rms := b.metrics.ResourceMetrics()
+	require.Equal(t, 2, rms.Len())
+	assert.Equal(t, 6, b.metrics.DataPointCount())
+
+	res := rms.At(0).Resource().Attributes()
+	clusterID, _ := res.Get("pixie.cluster.id")
+	assert.Equal(t, "cluster-1", clusterID.Str())
+	scriptName, _ := res.Get("pixie.script")
+	assert.Equal(t, "http_stats", scriptName.Str())
+	pod, _ := res.Get("k8s.pod.name")
+	assert.Equal(t, "pl/pod-a", pod.Str())
+
+	metrics := rms.At(0).ScopeMetrics().At(0).Metrics()
+	require.Equal(t, 2, metrics.Len())
+	assert.Equal(t, "pixie.count", metrics.At(0).Name())
+	dps := metrics.At(0).Gauge().DataPoints()
+	require.Equal(t, 2, dps.Len())
+	assert.Equal(t, int64(3), dps.At(0).IntValue())
+	assert.Equal(t, pcommon.NewTimestampFromTime(now), dps.At(0).Timestamp())
+	path, _ := dps.At(0).Attributes().Get("req_path")
+	assert.Equal(t, "/a", path.Str())
+	_, hasPod := dps.At(0).Attributes().Get("pod")
+	assert.False(t, hasPod)
+
+	assert.Equal(t, "pixie.latency", metrics.At(1).Name())
+	assert.Equal(t, 1.5, metrics.At(1).Gauge().DataPoints().At(0).DoubleValue())
+}
+
+func TestMetricsBuilder_ValueColumns(t *testing.T) {
+	script := (&ScriptConfig{
+		Name:    "http_stats",
+		PxL:     "px.display(df)",
+		Metrics: &MetricsConfig{ValueColumns: []string{"latency"}, Prefix: "http."},
+	}).withDefaults()
+	b := newMetricsBuilder(&script, "cluster-1")
+
+	streamTable(t, b, []string{"time_", "count", "latency"}, [][]interface{}{
+		{time.Unix(1700000000, 0), int64(3), 1.5},
+	})
+
+	metrics := b.metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	require.Equal(t, 1, metrics.Len())
+	assert.Equal(t, "http.latency", metrics.At(0).Name())
+	count, ok := metrics.At(0).Gauge().DataPoints().At(0).Attributes().Get("count")
+	require.True(t, ok)
+	assert.Equal(t, int64(3), count.Int())
+}
+
+func TestSpansBuilder(t *testing.T) {
+	script := (&ScriptConfig{
+		Name:  "http_spans",
+		PxL:   "px.display(df)",
+		Spans: &SpansConfig{TraceIDColumn: "trace_id"},
+	}).withDefaults()
+	b := newSpansBuilder(&script, "cluster-1")
+
+	start := time.Unix(1700000000, 0)
+	streamTable(t, b, []string{"time_", "req_path", "latency", "trace_id", "resp_status"}, [][]interface{}{
+		{start, "/a", int64(2 * time.Millisecond), "0102030405060708090a0b0c0d0e0f10", int64(200)},
+		{start, "/b", int64(time.Millisecond), "not-an-id", int64(500)},
+	})
+
+	require.Equal(t, 2, b.traces.SpanCount())
+	spans := b.traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+
+	span := spans.At(0)
+	assert.Equal(t, "/a", span.Name())
+	assert.Equal(t, pcommon.NewTimestampFromTime(start), span.StartTimestamp())
+	assert.Equal(t, pcommon.NewTimestampFromTime(start.Add(2*time.Millisecond)), span.EndTimestamp())
+	assert.Equal(t, "0102030405060708090a0b0c0d0e0f10", span.TraceID().String())
+	assert.False(t, span.SpanID().IsEmpty())
+	assert.True(t, span.ParentSpanID().IsEmpty())
+	status, _ := span.Attributes().Get("resp_status")
+	assert.Equal(t, int64(200), status.Int())
+	_, hasPath := span.Attributes().Get("req_path")
+	assert.False(t, hasPath)
+
+	// An invalid ID gets a random one instead.
+	assert.False(t, spans.At(1).TraceID().IsEmpty())
+	assert.NotEqual(t, span.TraceID(), spans.At(1).TraceID())
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package pixiereceiver implements an OpenTelemetry Collector receiver that runs PxL scripts on a
+// schedule with the Pixie Go API client, and passes their results on as metrics or spans.
+package pixiereceiver
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/receiver"
+)
+
+// Type is the type of the receiver in the collector config.
+var Type = component.MustNewType("pixie")
+
+const stability = component.StabilityLevelAlpha
+
+// NewFactory returns the factory for the Pixie receiver.
+func NewFactory() receiver.Factory {
+	return receiver.NewFactory(
+		Type,
+		createDefaultConfig,
+		receiver.WithMetrics(createMetricsReceiver, stability),
+		receiver.WithTraces(createTracesReceiver, stability),
+	)
+}
+
+func createMetricsReceiver(ctx context.Context, set receiver.CreateSettings, cfg component.Config, next consumer.Metrics) (receiver.Metrics, error) {
+	r := newPixieReceiver(cfg.(*Config), set, true)
+	if len(r.scripts) == 0 {
+		return nil, errors.New("the receiver is used in a metrics pipeline, but none of its scripts produce metrics")
+	}
+	r.nextMetrics = next
+	return r, nil
+}
+
+func createTracesReceiver(ctx context.Context, set receiver.CreateSettings, cfg component.Config, next consumer.Traces) (receiver.Traces, error) {
+	r := newPixieReceiver(cfg.(*Config), set, false)
+	if len(r.scripts) == 0 {
+		return nil, errors.New("the receiver is used in a traces pipeline, but none of its scripts produce spans")
+	}
+	r.nextTraces = next
+	return r, nil
+}
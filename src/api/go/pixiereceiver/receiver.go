@@ -0,0 +1,229 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pixiereceiver
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/receiver"
+	"go.uber.org/zap"
+
+	"px.dev/pixie/src/api/go/pxapi"
+)
+
+// scriptRunner runs a single script on a Vizier, streaming the results into the muxer.
+type scriptRunner func(ctx context.Context, clusterID, pxl string, mux pxapi.TableMuxer) error
+
+// pixieReceiver runs the configured scripts on a schedule, and passes their results on to the
+// next consumer of the pipeline. A receiver only runs the scripts that produce the data type of
+// its pipeline.
+type pixieReceiver struct {
+	cfg     *Config
+	scripts []ScriptConfig
+	logger  *zap.Logger
+
+	nextMetrics consumer.Metrics
+	nextTraces  consumer.Traces
+
+	client *pxapi.Client
+	// viziers caches the clients for each Vizier.
+	viziers   map[string]*pxapi.VizierClient
+	viziersMu sync.Mutex
+	run       scriptRunner
+	// listClusters returns the healthy Viziers to run the scripts on, if none are configured.
+	listClusters func(ctx context.Context) ([]string, error)
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+var (
+	_ receiver.Metrics = (*pixieReceiver)(nil)
+	_ receiver.Traces  = (*pixieReceiver)(nil)
+)
+
+func newPixieReceiver(cfg *Config, set receiver.CreateSettings, wantMetrics bool) *pixieReceiver {
+	r := &pixieReceiver{
+		cfg:     cfg,
+		logger:  set.Logger,
+		viziers: make(map[string]*pxapi.VizierClient),
+	}
+	for _, s := range cfg.Scripts {
+		if (s.Metrics != nil) == wantMetrics {
+			r.scripts = append(r.scripts, s.withDefaults())
+		}
+	}
+	r.run = r.executeScript
+	r.listClusters = r.healthyClusters
+	return r
+}
+
+// Start connects to Pixie Cloud and starts running the scripts.
+func (r *pixieReceiver) Start(ctx context.Context, host component.Host) error {
+	opts := []pxapi.ClientOption{pxapi.WithAPIKey(string(r.cfg.APIKey))}
+	if r.cfg.CloudAddr != "" {
+		opts = append(opts, pxapi.WithCloudAddr(r.cfg.CloudAddr))
+	}
+	client, err := pxapi.NewClient(ctx, opts...)
+	if err != nil {
+		return err
+	}
+	r.client = client
+
+	// The scripts run until shutdown, rather than for the lifetime of the start context.
+	runCtx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	for i := range r.scripts {
+		script := &r.scripts[i]
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			r.schedule(runCtx, script)
+		}()
+	}
+	return nil
+}
+
+// Shutdown stops running the scripts, and waits for the running ones to finish.
+func (r *pixieReceiver) Shutdown(ctx context.Context) error {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if r.client != nil {
+		return r.client.Close()
+	}
+	return nil
+}
+
+func (r *pixieReceiver) schedule(ctx context.Context, script *ScriptConfig) {
+	ticker := time.NewTicker(script.CollectionInterval)
+	defer ticker.Stop()
+	for {
+		r.collect(ctx, script)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *pixieReceiver) clusterIDs(ctx context.Context) ([]string, error) {
+	if len(r.cfg.ClusterIDs) > 0 {
+		return r.cfg.ClusterIDs, nil
+	}
+	return r.listClusters(ctx)
+}
+
+func (r *pixieReceiver) healthyClusters(ctx context.Context) ([]string, error) {
+	viziers, err := r.client.ListViziers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, vz := range viziers {
+		if vz.Status == pxapi.VizierStatusHealthy {
+			ids = append(ids, vz.ID)
+		}
+	}
+	return ids, nil
+}
+
+// collect runs the script once on every Vizier. A failure on one Vizier doesn't stop the others.
+func (r *pixieReceiver) collect(ctx context.Context, script *ScriptConfig) {
+	logger := r.logger.With(zap.String("script", script.Name))
+	// Each run has to finish before the next one is due.
+	ctx, cancel := context.WithTimeout(ctx, script.CollectionInterval)
+	defer cancel()
+
+	clusterIDs, err := r.clusterIDs(ctx)
+	if err != nil {
+		logger.Error("Failed to list Viziers", zap.Error(err))
+		return
+	}
+	for _, clusterID := range clusterIDs {
+		if err := r.collectFromCluster(ctx, script, clusterID); err != nil && !errors.Is(err, context.Canceled) {
+			logger.Error("Failed to run script", zap.String("cluster_id", clusterID), zap.Error(err))
+		}
+	}
+}
+
+func (r *pixieReceiver) collectFromCluster(ctx context.Context, script *ScriptConfig, clusterID string) error {
+	if script.Metrics != nil {
+		b := newMetricsBuilder(script, clusterID)
+		if err := r.run(ctx, clusterID, script.PxL, b); err != nil {
+			return err
+		}
+		if b.metrics.DataPointCount() == 0 {
+			return nil
+		}
+		return r.nextMetrics.ConsumeMetrics(ctx, b.metrics)
+	}
+
+	b := newSpansBuilder(script, clusterID)
+	if err := r.run(ctx, clusterID, script.PxL, b); err != nil {
+		return err
+	}
+	if b.traces.SpanCount() == 0 {
+		return nil
+	}
+	return r.nextTraces.ConsumeTraces(ctx, b.traces)
+}
+
+func (r *pixieReceiver) vizierClient(ctx context.Context, clusterID string) (*pxapi.VizierClient, error) {
+	r.viziersMu.Lock()
+	defer r.viziersMu.Unlock()
+	if vz, ok := r.viziers[clusterID]; ok {
+		return vz, nil
+	}
+	vz, err := r.client.NewVizierClient(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	r.viziers[clusterID] = vz
+	return vz, nil
+}
+
+func (r *pixieReceiver) executeScript(ctx context.Context, clusterID, pxl string, mux pxapi.TableMuxer) error {
+	vz, err := r.vizierClient(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+	res, err := vz.ExecuteScript(ctx, pxl, mux)
+	if err != nil {
+		return err
+	}
+	defer res.Close()
+	return res.Stream()
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxapi
+
+import (
+	"context"
+	"time"
+)
+
+// AgentState is the health of a Vizier agent, as reported by the metadata service.
+type AgentState string
+
+// Agent states.
+const (
+	AgentStateUnknown      AgentState = "AGENT_STATE_UNKNOWN"
+	AgentStateHealthy      AgentState = "AGENT_STATE_HEALTHY"
+	AgentStateUnresponsive AgentState = "AGENT_STATE_UNRESPONSIVE"
+	AgentStateDisconnected AgentState = "AGENT_STATE_DISCONNECTED"
+	AgentStateLameduck     AgentState = "AGENT_STATE_LAMEDUCK"
+)
+
+const agentStatusTableName = "agents"
+
+const agentStatusScript = `
+import px
+px.display(px.GetAgentStatus(), '` + agentStatusTableName + `')
+`
+
+// AgentStatus is the status of a single agent (PEM or Kelvin) in a Vizier.
+type AgentStatus struct {
+	// ID of the agent (uuid as a string).
+	ID string `pxl:"agent_id"`
+	// ASID is the agent short ID.
+	ASID int64 `pxl:"asid"`
+	// Hostname of the node that the agent runs on.
+	Hostname string `pxl:"hostname"`
+	// IPAddress of the agent.
+	IPAddress string `pxl:"ip_address"`
+	// State is the health of the agent.
+	State AgentState `pxl:"agent_state"`
+	// CreateTime is when the agent registered.
+	CreateTime time.Time `pxl:"create_time"`
+	// SinceLastHeartbeat is how long ago the agent last sent a heartbeat.
+	SinceLastHeartbeat time.Duration `pxl:"last_heartbeat_ns"`
+}
+
+// ListAgents gets the status of every agent in the Vizier.
+func (v *VizierClient) ListAgents(ctx context.Context) ([]*AgentStatus, error) {
+	tc := NewTableCollector[AgentStatus](agentStatusTableName)
+	res, err := v.ExecuteScript(ctx, agentStatusScript, tc)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	if err := res.Stream(); err != nil {
+		return nil, err
+	}
+	return tc.Rows, nil
+}
+
+// WatchAgents watches the agents in the Vizier, and sends an event whenever an agent registers,
+// goes away, or changes state, hostname, or IP address. Failing to list the agents ends the watch.
+func (v *VizierClient) WatchAgents(ctx context.Context, opts ...WatchOption) (*Watch[AgentStatus], error) {
+	list := func(ctx context.Context) (map[string]*AgentStatus, error) {
+		agents, err := v.ListAgents(ctx)
+		if err != nil {
+			return nil, err
+		}
+		byID := make(map[string]*AgentStatus, len(agents))
+		for _, a := range agents {
+			byID[a.ID] = a
+		}
+		return byID, nil
+	}
+	changed := func(prev, cur *AgentStatus) bool {
+		// The heartbeat changes on every poll, so it isn't compared.
+		return prev.State != cur.State || prev.Hostname != cur.Hostname || prev.IPAddress != cur.IPAddress
+	}
+	return newWatch(ctx, list, changed, opts)
+}
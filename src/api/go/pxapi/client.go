@@ -23,13 +23,15 @@ import (
 	"crypto/tls"
 	"fmt"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/metadata"
 
 	"px.dev/pixie/src/api/go/pxapi/types"
-	"px.dev/pixie/src/api/go/pxapi/utils"
 	"px.dev/pixie/src/api/proto/cloudpb"
 	"px.dev/pixie/src/api/proto/vizierpb"
 )
@@ -62,8 +64,23 @@ type Client struct {
 	cloudAddr string
 
 	useEncryption bool
-
-	grpcConn *grpc.ClientConn
+	// encKeyLifetime is how long an encryption key pair is reused across script executions.
+	encKeyLifetime time.Duration
+	// perSessionKeys uses a new encryption key pair for every script execution.
+	perSessionKeys bool
+
+	retryPolicy        *RetryPolicy
+	keepalive          keepalive.ClientParameters
+	poolSize           int
+	unaryInterceptors  []grpc.UnaryClientInterceptor
+	streamInterceptors []grpc.StreamClientInterceptor
+	dialOpts           []grpc.DialOption
+
+	// grpcConn is the first connection of the pool, which is used for calls to the cloud.
+	grpcConn  *grpc.ClientConn
+	grpcConns []*grpc.ClientConn
+	// nextConn is the index of the connection in the pool that the next Vizier client uses.
+	nextConn uint32
 	cmClient cloudpb.VizierClusterInfoClient
 	vizier   vizierpb.VizierServiceClient
 }
@@ -73,6 +90,9 @@ func NewClient(ctx context.Context, opts ...ClientOption) (*Client, error) {
 	c := &Client{
 		cloudAddr:     defaultCloudAddr,
 		useEncryption: true,
+		retryPolicy:   DefaultRetryPolicy(),
+		keepalive:     DefaultKeepalive(),
+		poolSize:      1,
 	}
 
 	for _, opt := range opts {
@@ -91,11 +111,37 @@ func (c *Client) init(ctx context.Context) error {
 	tlsConfig := &tls.Config{InsecureSkipVerify: isInternal}
 	creds := credentials.NewTLS(tlsConfig)
 
-	conn, err := grpc.Dial(c.cloudAddr, grpc.WithTransportCredentials(creds))
-	if err != nil {
-		return err
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithKeepaliveParams(c.keepalive),
+		grpc.WithChainUnaryInterceptor(c.unaryInterceptors...),
+		grpc.WithChainStreamInterceptor(c.streamInterceptors...),
+	}
+	if c.retryPolicy != nil {
+		sc, err := c.retryPolicy.serviceConfig()
+		if err != nil {
+			return err
+		}
+		dialOpts = append(dialOpts, grpc.WithDefaultServiceConfig(sc))
+	} else {
+		dialOpts = append(dialOpts, grpc.WithDisableRetry())
+	}
+	dialOpts = append(dialOpts, c.dialOpts...)
+
+	poolSize := c.poolSize
+	if poolSize < 1 {
+		poolSize = 1
+	}
+	for i := 0; i < poolSize; i++ {
+		conn, err := grpc.DialContext(ctx, c.cloudAddr, dialOpts...)
+		if err != nil {
+			_ = c.Close()
+			return err
+		}
+		c.grpcConns = append(c.grpcConns, conn)
 	}
 
+	conn := c.grpcConns[0]
 	c.grpcConn = conn
 	c.cmClient = cloudpb.NewVizierClusterInfoClient(conn)
 
@@ -103,6 +149,25 @@ func (c *Client) init(ctx context.Context) error {
 	return nil
 }
 
+// Close closes all of the client's connections. Clients that are created for the lifetime of the
+// program don't need to be closed.
+func (c *Client) Close() error {
+	var firstErr error
+	for _, conn := range c.grpcConns {
+		if err := conn.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	c.grpcConns = nil
+	return firstErr
+}
+
+// vizierConn returns the connection from the pool that the next Vizier client uses.
+func (c *Client) vizierConn() *grpc.ClientConn {
+	n := atomic.AddUint32(&c.nextConn, 1) - 1
+	return c.grpcConns[int(n)%len(c.grpcConns)]
+}
+
 func (c *Client) cloudCtxWithMD(ctx context.Context) context.Context {
 	ctx = metadata.AppendToOutgoingContext(ctx,
 		"pixie-api-client", "go")
@@ -121,22 +186,24 @@ func (c *Client) cloudCtxWithMD(ctx context.Context) context.Context {
 
 // NewVizierClient creates a new vizier client, for the passed in vizierID.
 func (c *Client) NewVizierClient(ctx context.Context, vizierID string) (*VizierClient, error) {
-	var err error
-	vzConn := c.grpcConn
+	vzConn := c.vizierConn()
 
-	var encOpts, decOpts *vizierpb.ExecuteScriptRequest_EncryptionOptions
+	var keys *keyManager
 	if c.useEncryption {
-		encOpts, decOpts, err = utils.CreateEncryptionOptions()
-		if err != nil {
-			return nil, err
+		keys = newKeyManager(c.encKeyLifetime, c.perSessionKeys)
+		// Generate the first key pair up front, so that key generation errors surface here.
+		// Per-session keys are generated for each execution instead.
+		if !c.perSessionKeys {
+			if err := keys.rotate(); err != nil {
+				return nil, err
+			}
 		}
 	}
 
 	// Now create the actual client.
 	vzClient := &VizierClient{
 		cloud:    c,
-		encOpts:  encOpts,
-		decOpts:  decOpts,
+		keys:     keys,
 		vizierID: vizierID,
 		vzClient: vizierpb.NewVizierServiceClient(vzConn),
 	}
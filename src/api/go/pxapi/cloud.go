@@ -123,6 +123,37 @@ func (c *Client) CreateDeployKey(ctx context.Context, desc string) (*cloudpb.Dep
 	return dk, nil
 }
 
+// ListDeployKeys lists the metadata of all the deploy keys in the org. The key values are not included.
+func (c *Client) ListDeployKeys(ctx context.Context) ([]*cloudpb.DeploymentKeyMetadata, error) {
+	keyMgr := cloudpb.NewVizierDeploymentKeyManagerClient(c.grpcConn)
+	resp, err := keyMgr.List(c.cloudCtxWithMD(ctx), &cloudpb.ListDeploymentKeyRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Keys, nil
+}
+
+// GetDeployKey gets a deploy key, including its value, by ID.
+func (c *Client) GetDeployKey(ctx context.Context, id string) (*cloudpb.DeploymentKey, error) {
+	req := &cloudpb.GetDeploymentKeyRequest{
+		ID: utils.ProtoFromUUIDStrOrNil(id),
+	}
+	keyMgr := cloudpb.NewVizierDeploymentKeyManagerClient(c.grpcConn)
+	resp, err := keyMgr.Get(c.cloudCtxWithMD(ctx), req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Key, nil
+}
+
+// DeleteDeployKey deletes a deploy key by ID.
+func (c *Client) DeleteDeployKey(ctx context.Context, id string) error {
+	req := utils.ProtoFromUUIDStrOrNil(id)
+	keyMgr := cloudpb.NewVizierDeploymentKeyManagerClient(c.grpcConn)
+	_, err := keyMgr.Delete(c.cloudCtxWithMD(ctx), req)
+	return err
+}
+
 // CreateAPIKey creates and API key with the passed in description.
 func (c *Client) CreateAPIKey(ctx context.Context, desc string) (*cloudpb.APIKey, error) {
 	req := &cloudpb.CreateAPIKeyRequest{
@@ -146,6 +177,29 @@ func (c *Client) DeleteAPIKey(ctx context.Context, id string) error {
 	return err
 }
 
+// ListAPIKeys lists the metadata of all the API keys in the org. The key values are not included.
+func (c *Client) ListAPIKeys(ctx context.Context) ([]*cloudpb.APIKeyMetadata, error) {
+	apiKeyMgr := cloudpb.NewAPIKeyManagerClient(c.grpcConn)
+	resp, err := apiKeyMgr.List(c.cloudCtxWithMD(ctx), &cloudpb.ListAPIKeyRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Keys, nil
+}
+
+// GetAPIKey gets an API key, including its value, by ID.
+func (c *Client) GetAPIKey(ctx context.Context, id string) (*cloudpb.APIKey, error) {
+	req := &cloudpb.GetAPIKeyRequest{
+		ID: utils.ProtoFromUUIDStrOrNil(id),
+	}
+	apiKeyMgr := cloudpb.NewAPIKeyManagerClient(c.grpcConn)
+	resp, err := apiKeyMgr.Get(c.cloudCtxWithMD(ctx), req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Key, nil
+}
+
 // GetLatestVizierVersion returns the latest version of vizier available.
 func (c *Client) GetLatestVizierVersion(ctx context.Context) (string, error) {
 	return c.getLatestArtifact(ctx, "vizier", cloudpb.AT_CONTAINER_SET_YAMLS)
@@ -173,3 +227,23 @@ func (c *Client) getLatestArtifact(ctx context.Context, an string, at cloudpb.Ar
 	}
 	return resp.Artifact[0].VersionStr, nil
 }
+
+// WatchViziers watches the Viziers registered with Pixie, and sends an event whenever a Vizier is
+// registered, removed, or changes name, version, or status. Failing to list the Viziers ends the watch.
+func (c *Client) WatchViziers(ctx context.Context, opts ...WatchOption) (*Watch[VizierInfo], error) {
+	list := func(ctx context.Context) (map[string]*VizierInfo, error) {
+		viziers, err := c.ListViziers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		byID := make(map[string]*VizierInfo, len(viziers))
+		for _, v := range viziers {
+			byID[v.ID] = v
+		}
+		return byID, nil
+	}
+	changed := func(prev, cur *VizierInfo) bool {
+		return *prev != *cur
+	}
+	return newWatch(ctx, list, changed, opts)
+}
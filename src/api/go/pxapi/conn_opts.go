@@ -0,0 +1,135 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/keepalive"
+)
+
+// RetryPolicy configures how calls to Pixie are retried when they fail with a retryable code.
+// Streaming calls, such as script executions, are only retried until the first response arrives.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts per call, including the first one. It must be at least 2.
+	MaxAttempts int
+	// InitialBackoff is the backoff before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the backoff between retries.
+	MaxBackoff time.Duration
+	// BackoffMultiplier is the factor the backoff grows by after each retry.
+	BackoffMultiplier float64
+	// RetryableCodes are the status codes that the call is retried on.
+	RetryableCodes []codes.Code
+}
+
+// DefaultRetryPolicy returns the retry policy that clients use unless it's overridden with WithRetryPolicy.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:       4,
+		InitialBackoff:    250 * time.Millisecond,
+		MaxBackoff:        5 * time.Second,
+		BackoffMultiplier: 2,
+		RetryableCodes:    []codes.Code{codes.Unavailable, codes.ResourceExhausted},
+	}
+}
+
+// DefaultKeepalive returns the keepalive parameters that clients use unless they're overridden with WithKeepalive.
+// Pings are only sent while calls are active, and no more often than Pixie Cloud allows.
+func DefaultKeepalive() keepalive.ClientParameters {
+	return keepalive.ClientParameters{
+		Time:    5 * time.Minute,
+		Timeout: 20 * time.Second,
+	}
+}
+
+func formatDuration(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+}
+
+// allowsReconnect returns whether a broken script stream is resumed after the given number of
+// reconnects without a response in between. Without a retry policy, a stream is resumed once.
+func (p *RetryPolicy) allowsReconnect(reconnects int) bool {
+	if p == nil {
+		return reconnects == 0
+	}
+	return reconnects < p.MaxAttempts-1
+}
+
+// wait sleeps for the backoff before the given reconnect attempt, counting from zero.
+func (p *RetryPolicy) wait(ctx context.Context, attempt int) error {
+	if p == nil {
+		return nil
+	}
+	backoff := float64(p.InitialBackoff)
+	for i := 0; i < attempt; i++ {
+		backoff *= p.BackoffMultiplier
+	}
+	d := time.Duration(backoff)
+	if d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// serviceConfig returns the gRPC service config that applies the retry policy to every method.
+func (p *RetryPolicy) serviceConfig() (string, error) {
+	if p.MaxAttempts < 2 {
+		return "", errors.New("retry policy needs at least 2 attempts")
+	}
+	if p.InitialBackoff <= 0 || p.MaxBackoff <= 0 || p.BackoffMultiplier <= 0 {
+		return "", errors.New("retry policy needs a positive backoff")
+	}
+	if len(p.RetryableCodes) == 0 {
+		return "", errors.New("retry policy needs at least one retryable code")
+	}
+
+	sc := map[string]interface{}{
+		"methodConfig": []interface{}{
+			map[string]interface{}{
+				// An empty name applies the config to all methods.
+				"name": []interface{}{map[string]interface{}{}},
+				"retryPolicy": map[string]interface{}{
+					"maxAttempts":          p.MaxAttempts,
+					"initialBackoff":       formatDuration(p.InitialBackoff),
+					"maxBackoff":           formatDuration(p.MaxBackoff),
+					"backoffMultiplier":    p.BackoffMultiplier,
+					"retryableStatusCodes": p.RetryableCodes,
+				},
+			},
+		},
+	}
+	b, err := json.Marshal(sc)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,160 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxapi
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+)
+
+// flakyClusterInfoServer fails the first calls with the given code.
+type flakyClusterInfoServer struct {
+	cloudpb.UnimplementedVizierClusterInfoServer
+
+	mu       sync.Mutex
+	calls    int
+	failures int
+	code     codes.Code
+}
+
+func (f *flakyClusterInfoServer) GetClusterInfo(ctx context.Context, req *cloudpb.GetClusterInfoRequest) (*cloudpb.GetClusterInfoResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.calls <= f.failures {
+		return nil, status.Error(f.code, "flaky")
+	}
+	return &cloudpb.GetClusterInfoResponse{}, nil
+}
+
+func newFlakyClient(t *testing.T, srv *flakyClusterInfoServer, opts ...ClientOption) *Client {
+	lis := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer()
+	cloudpb.RegisterVizierClusterInfoServer(s, srv)
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+
+	opts = append(opts, WithDialOptions(
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	))
+	c, err := NewClient(context.Background(), append([]ClientOption{WithCloudAddr("bufnet")}, opts...)...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func fastRetryPolicy() *RetryPolicy {
+	p := DefaultRetryPolicy()
+	p.InitialBackoff = time.Millisecond
+	p.MaxBackoff = time.Millisecond
+	return p
+}
+
+func TestClient_RetriesUnavailable(t *testing.T) {
+	srv := &flakyClusterInfoServer{failures: 2, code: codes.Unavailable}
+	c := newFlakyClient(t, srv, WithRetryPolicy(fastRetryPolicy()))
+
+	_, err := c.ListViziers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, srv.calls)
+}
+
+func TestClient_RetriesGiveUp(t *testing.T) {
+	srv := &flakyClusterInfoServer{failures: 10, code: codes.Unavailable}
+	c := newFlakyClient(t, srv, WithRetryPolicy(fastRetryPolicy()))
+
+	_, err := c.ListViziers(context.Background())
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 4, srv.calls)
+}
+
+func TestClient_NoRetryForOtherCodes(t *testing.T) {
+	srv := &flakyClusterInfoServer{failures: 1, code: codes.PermissionDenied}
+	c := newFlakyClient(t, srv, WithRetryPolicy(fastRetryPolicy()))
+
+	_, err := c.ListViziers(context.Background())
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Equal(t, 1, srv.calls)
+}
+
+func TestClient_RetriesDisabled(t *testing.T) {
+	srv := &flakyClusterInfoServer{failures: 1, code: codes.Unavailable}
+	c := newFlakyClient(t, srv, WithRetryPolicy(nil))
+
+	_, err := c.ListViziers(context.Background())
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 1, srv.calls)
+}
+
+func TestClient_InvalidRetryPolicy(t *testing.T) {
+	_, err := NewClient(context.Background(), WithRetryPolicy(&RetryPolicy{MaxAttempts: 1}))
+	assert.Error(t, err)
+}
+
+func TestClient_UnaryInterceptors(t *testing.T) {
+	var methods []string
+	interceptor := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		methods = append(methods, method)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	srv := &flakyClusterInfoServer{}
+	c := newFlakyClient(t, srv, WithUnaryInterceptors(interceptor))
+
+	_, err := c.ListViziers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/px.cloudapi.VizierClusterInfo/GetClusterInfo"}, methods)
+}
+
+func TestClient_ConnectionPool(t *testing.T) {
+	srv := &flakyClusterInfoServer{}
+	c := newFlakyClient(t, srv, WithConnectionPoolSize(2), WithE2EEncryption(false))
+	require.Len(t, c.grpcConns, 2)
+
+	conns := make(map[*grpc.ClientConn]bool)
+	for i := 0; i < 4; i++ {
+		conns[c.vizierConn()] = true
+	}
+	assert.Len(t, conns, 2)
+}
+
+func TestRetryPolicy_AllowsReconnect(t *testing.T) {
+	var noRetries *RetryPolicy
+	assert.True(t, noRetries.allowsReconnect(0))
+	assert.False(t, noRetries.allowsReconnect(1))
+
+	p := DefaultRetryPolicy()
+	assert.True(t, p.allowsReconnect(2))
+	assert.False(t, p.allowsReconnect(3))
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxapi
+
+import (
+	"sync"
+	"time"
+
+	"px.dev/pixie/src/api/go/pxapi/utils"
+	"px.dev/pixie/src/api/proto/vizierpb"
+)
+
+// sessionKeys is the key pair used to encrypt and decrypt the results of a single script execution.
+type sessionKeys struct {
+	encOpts *vizierpb.ExecuteScriptRequest_EncryptionOptions
+	decOpts *vizierpb.ExecuteScriptRequest_EncryptionOptions
+}
+
+// keyManager hands out the encryption keys for script executions and rotates them according to the
+// configured lifetime. Each execution captures the keys it started with, so rotating never breaks a
+// result stream that is already in flight, including when that stream is reconnected.
+type keyManager struct {
+	mu sync.Mutex
+	// lifetime is how long a key pair may be reused. Zero means the key pair never expires.
+	lifetime time.Duration
+	// perSession generates a new key pair for every script execution.
+	perSession bool
+
+	current   *sessionKeys
+	createdAt time.Time
+
+	// Overridable for tests.
+	now    func() time.Time
+	create func() (*vizierpb.ExecuteScriptRequest_EncryptionOptions, *vizierpb.ExecuteScriptRequest_EncryptionOptions, error)
+}
+
+func newKeyManager(lifetime time.Duration, perSession bool) *keyManager {
+	return &keyManager{
+		lifetime:   lifetime,
+		perSession: perSession,
+		now:        time.Now,
+		create:     utils.CreateEncryptionOptions,
+	}
+}
+
+// keysForSession returns the key pair to use for a new script execution, generating a new one
+// if there is no valid key pair.
+func (k *keyManager) keysForSession() (*sessionKeys, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.perSession || k.current == nil || k.expired() {
+		if err := k.rotateLocked(); err != nil {
+			return nil, err
+		}
+	}
+	return k.current, nil
+}
+
+// rotate replaces the current key pair. Executions that already started keep their own keys.
+func (k *keyManager) rotate() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.rotateLocked()
+}
+
+// rotateExpired replaces the key pair after vizier rejected the expired keys, and returns the key pair to retry
+// with. If another execution has already rotated the expired keys, its key pair is reused.
+func (k *keyManager) rotateExpired(expired *vizierpb.ExecuteScriptRequest_EncryptionOptions) (*sessionKeys, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.perSession || k.current == nil || k.current.encOpts == expired {
+		if err := k.rotateLocked(); err != nil {
+			return nil, err
+		}
+	}
+	return k.current, nil
+}
+
+func (k *keyManager) expired() bool {
+	return k.lifetime > 0 && k.now().Sub(k.createdAt) >= k.lifetime
+}
+
+func (k *keyManager) rotateLocked() error {
+	encOpts, decOpts, err := k.create()
+	if err != nil {
+		return err
+	}
+	k.current = &sessionKeys{
+		encOpts: encOpts,
+		decOpts: decOpts,
+	}
+	k.createdAt = k.now()
+	return nil
+}
@@ -0,0 +1,214 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/go/pxapi/errdefs"
+	"px.dev/pixie/src/api/proto/vizierpb"
+)
+
+// fakeKeyCreator returns distinguishable key pairs without doing real RSA generation.
+func fakeKeyCreator(count *int) func() (*vizierpb.ExecuteScriptRequest_EncryptionOptions, *vizierpb.ExecuteScriptRequest_EncryptionOptions, error) {
+	return func() (*vizierpb.ExecuteScriptRequest_EncryptionOptions, *vizierpb.ExecuteScriptRequest_EncryptionOptions, error) {
+		*count++
+		key := string(rune('a' + *count))
+		return &vizierpb.ExecuteScriptRequest_EncryptionOptions{JwkKey: "pub-" + key},
+			&vizierpb.ExecuteScriptRequest_EncryptionOptions{JwkKey: "priv-" + key}, nil
+	}
+}
+
+func TestKeyManager_Lifetime(t *testing.T) {
+	now := time.Unix(1000, 0)
+	count := 0
+	k := newKeyManager(time.Minute, false)
+	k.now = func() time.Time { return now }
+	k.create = fakeKeyCreator(&count)
+
+	first, err := k.keysForSession()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	now = now.Add(30 * time.Second)
+	second, err := k.keysForSession()
+	require.NoError(t, err)
+	assert.Same(t, first, second)
+	assert.Equal(t, 1, count)
+
+	now = now.Add(30 * time.Second)
+	third, err := k.keysForSession()
+	require.NoError(t, err)
+	assert.NotSame(t, first, third)
+	assert.Equal(t, 2, count)
+	// Rotation must not mutate keys that are held by in-flight sessions.
+	assert.Equal(t, "priv-b", first.decOpts.JwkKey)
+	assert.Equal(t, "priv-c", third.decOpts.JwkKey)
+}
+
+func TestKeyManager_NoLifetime(t *testing.T) {
+	now := time.Unix(1000, 0)
+	count := 0
+	k := newKeyManager(0, false)
+	k.now = func() time.Time { return now }
+	k.create = fakeKeyCreator(&count)
+
+	first, err := k.keysForSession()
+	require.NoError(t, err)
+	now = now.Add(24 * 365 * time.Hour)
+	second, err := k.keysForSession()
+	require.NoError(t, err)
+	assert.Same(t, first, second)
+	assert.Equal(t, 1, count)
+}
+
+func TestKeyManager_PerSession(t *testing.T) {
+	count := 0
+	k := newKeyManager(0, true)
+	k.create = fakeKeyCreator(&count)
+
+	first, err := k.keysForSession()
+	require.NoError(t, err)
+	second, err := k.keysForSession()
+	require.NoError(t, err)
+	assert.NotEqual(t, first.encOpts.JwkKey, second.encOpts.JwkKey)
+	assert.Equal(t, 2, count)
+}
+
+func TestKeyManager_Rotate(t *testing.T) {
+	count := 0
+	k := newKeyManager(0, false)
+	k.create = fakeKeyCreator(&count)
+
+	first, err := k.keysForSession()
+	require.NoError(t, err)
+	require.NoError(t, k.rotate())
+	second, err := k.keysForSession()
+	require.NoError(t, err)
+	assert.NotSame(t, first, second)
+	assert.Equal(t, 2, count)
+}
+
+func TestKeyManager_CreateError(t *testing.T) {
+	k := newKeyManager(0, false)
+	k.create = func() (*vizierpb.ExecuteScriptRequest_EncryptionOptions, *vizierpb.ExecuteScriptRequest_EncryptionOptions, error) {
+		return nil, nil, errors.New("no entropy")
+	}
+	_, err := k.keysForSession()
+	assert.Error(t, err)
+}
+
+func TestKeyManager_RotateExpired(t *testing.T) {
+	count := 0
+	k := newKeyManager(0, false)
+	k.create = fakeKeyCreator(&count)
+
+	first, err := k.keysForSession()
+	require.NoError(t, err)
+	second, err := k.rotateExpired(first.encOpts)
+	require.NoError(t, err)
+	assert.NotSame(t, first, second)
+
+	// Another execution that started with the expired keys reuses the rotated keys.
+	third, err := k.rotateExpired(first.encOpts)
+	require.NoError(t, err)
+	assert.Same(t, second, third)
+	assert.Equal(t, 2, count)
+}
+
+// fakeExecuteStream returns the error, or EOF if there is none.
+type fakeExecuteStream struct {
+	grpc.ClientStream
+	err error
+}
+
+func (f *fakeExecuteStream) Recv() (*vizierpb.ExecuteScriptResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return nil, io.EOF
+}
+
+func (f *fakeExecuteStream) Context() context.Context {
+	return context.Background()
+}
+
+// keyExpiringVizier rejects every key that is in expired.
+type keyExpiringVizier struct {
+	vizierpb.VizierServiceClient
+	expired map[string]bool
+	reqs    []*vizierpb.ExecuteScriptRequest
+}
+
+func (f *keyExpiringVizier) ExecuteScript(ctx context.Context, req *vizierpb.ExecuteScriptRequest, opts ...grpc.CallOption) (vizierpb.VizierService_ExecuteScriptClient, error) {
+	f.reqs = append(f.reqs, req)
+	if f.expired[req.EncryptionOptions.JwkKey] {
+		return &fakeExecuteStream{err: status.Error(codes.FailedPrecondition, "encryption key expired: the key was first used 1h0m0s ago")}, nil
+	}
+	return &fakeExecuteStream{}, nil
+}
+
+func TestExecuteScript_RotatesExpiredKeys(t *testing.T) {
+	count := 0
+	keys := newKeyManager(0, false)
+	keys.create = fakeKeyCreator(&count)
+	vz := &keyExpiringVizier{expired: map[string]bool{"pub-b": true}}
+	v := &VizierClient{cloud: &Client{}, vizierID: "vz", vzClient: vz, keys: keys}
+
+	sr, err := v.ExecuteScript(context.Background(), "import px", nil)
+	require.NoError(t, err)
+	require.NoError(t, sr.Stream())
+
+	require.Len(t, vz.reqs, 2)
+	assert.Equal(t, "pub-b", vz.reqs[0].EncryptionOptions.JwkKey)
+	assert.Equal(t, "pub-c", vz.reqs[1].EncryptionOptions.JwkKey)
+	assert.Equal(t, "import px", vz.reqs[1].QueryStr)
+	assert.Equal(t, "priv-c", sr.decOpts.JwkKey)
+
+	// Later executions use the rotated keys right away.
+	sr, err = v.ExecuteScript(context.Background(), "import px", nil)
+	require.NoError(t, err)
+	require.NoError(t, sr.Stream())
+	require.Len(t, vz.reqs, 3)
+	assert.Equal(t, "pub-c", vz.reqs[2].EncryptionOptions.JwkKey)
+}
+
+func TestExecuteScript_RotatedKeysAlsoExpired(t *testing.T) {
+	count := 0
+	keys := newKeyManager(0, false)
+	keys.create = fakeKeyCreator(&count)
+	vz := &keyExpiringVizier{expired: map[string]bool{"pub-b": true, "pub-c": true}}
+	v := &VizierClient{cloud: &Client{}, vizierID: "vz", vzClient: vz, keys: keys}
+
+	sr, err := v.ExecuteScript(context.Background(), "import px", nil)
+	require.NoError(t, err)
+	err = sr.Stream()
+	assert.ErrorIs(t, err, errdefs.ErrEncryptionKeyExpired)
+	assert.Len(t, vz.reqs, 2)
+}
@@ -36,6 +36,8 @@ var (
 
 	// ErrMissingDecryptionKey occurs if vizier sends encrypted table data without being asked to do so.
 	ErrMissingDecryptionKey = errors.New("missing decryption key but got encrypted data")
+	// ErrEncryptionKeyExpired occurs if vizier keeps rejecting the encryption key as expired, even after it was rotated.
+	ErrEncryptionKeyExpired = errors.New("encryption key expired")
 
 	// ErrInternal specifies an unknown internal error has occurred.
 	ErrInternal = errors.New("internal error")
@@ -56,6 +58,10 @@ var (
 
 	// ErrMissingArtifact occurs when an artifact could not be found.
 	ErrMissingArtifact = errors.New("missing artifact")
+	// ErrPluginNotFound is invoked when trying to fetch information for a nonexistent plugin ID.
+	ErrPluginNotFound = errors.New("plugin not found")
+	// ErrAlertRouteNotFound is invoked when trying to fetch a nonexistent alert route ID.
+	ErrAlertRouteNotFound = errors.New("alert route not found")
 )
 
 // MultiError is an interface to allow access to groups of errors.
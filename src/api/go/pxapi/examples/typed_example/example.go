@@ -0,0 +1,95 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"px.dev/pixie/src/api/go/pxapi"
+	"px.dev/pixie/src/api/go/pxapi/errdefs"
+)
+
+var (
+	pxl = `
+import px
+df = px.DataFrame('http_events')
+df = df[['time_', 'upid', 'req_path', 'resp_status', 'latency']]
+df = df.head(10)
+px.display(df, 'http')
+`
+)
+
+// httpEvent is a row of the http table. Untagged fields are matched to the snake_case column names.
+type httpEvent struct {
+	Time       time.Time `pxl:"time_"`
+	UPID       uuid.UUID `pxl:"upid"`
+	ReqPath    string
+	RespStatus int
+	Latency    time.Duration
+}
+
+func main() {
+	apiKey, ok := os.LookupEnv("PX_API_KEY")
+	if !ok {
+		panic("please set PX_API_KEY")
+	}
+	clusterID, ok := os.LookupEnv("PX_CLUSTER_ID")
+	if !ok {
+		panic("please set PX_CLUSTER_ID")
+	}
+
+	ctx := context.Background()
+	client, err := pxapi.NewClient(ctx, pxapi.WithAPIKey(apiKey))
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("Running on Cluster: %s\n", clusterID)
+
+	tc := pxapi.NewTableCollector[httpEvent]("http")
+
+	fmt.Println("Running script")
+	vz, err := client.NewVizierClient(ctx, clusterID)
+	if err != nil {
+		panic(err)
+	}
+
+	resultSet, err := vz.ExecuteScript(ctx, pxl, tc)
+	if err != nil {
+		panic(err)
+	}
+
+	defer resultSet.Close()
+	if err := resultSet.Stream(); err != nil {
+		if errdefs.IsCompilationError(err) {
+			fmt.Printf("Got compiler error: \n %s\n", err.Error())
+		} else {
+			fmt.Printf("Got error : %+v, while streaming\n", err)
+		}
+	}
+
+	for _, ev := range tc.Rows {
+		fmt.Printf("%s %s %s %d %s\n", ev.Time.Format(time.RFC3339), ev.UPID, ev.ReqPath, ev.RespStatus, ev.Latency)
+	}
+}
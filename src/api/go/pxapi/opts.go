@@ -18,6 +18,14 @@
 
 package pxapi
 
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
 // ClientOption configures options on the client.
 type ClientOption func(client *Client)
 
@@ -48,3 +56,126 @@ func WithE2EEncryption(enabled bool) ClientOption {
 		c.useEncryption = enabled
 	}
 }
+
+// WithEncryptionKeyLifetime is the option to rotate the E2E encryption keys once they are older than
+// the given lifetime. Rotation only applies to new script executions. A zero lifetime never rotates.
+func WithEncryptionKeyLifetime(lifetime time.Duration) ClientOption {
+	return func(c *Client) {
+		c.encKeyLifetime = lifetime
+	}
+}
+
+// WithPerSessionEncryptionKeys is the option to use a new ephemeral E2E encryption key pair for
+// every script execution.
+func WithPerSessionEncryptionKeys() ClientOption {
+	return func(c *Client) {
+		c.perSessionKeys = true
+	}
+}
+
+// WithRetryPolicy is the option to set how failed calls are retried. A nil policy disables retries.
+func WithRetryPolicy(policy *RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.retryPolicy = policy
+	}
+}
+
+// WithKeepalive is the option to set how the client pings Pixie to detect broken connections.
+func WithKeepalive(params keepalive.ClientParameters) ClientOption {
+	return func(c *Client) {
+		c.keepalive = params
+	}
+}
+
+// WithConnectionPoolSize is the option to spread Vizier clients over the given number of connections,
+// so that many concurrent script executions aren't limited by the streams of a single connection.
+func WithConnectionPoolSize(size int) ClientOption {
+	return func(c *Client) {
+		c.poolSize = size
+	}
+}
+
+// WithUnaryInterceptors is the option to add interceptors to all unary calls, for example to
+// collect metrics or traces. Interceptors run in the given order.
+func WithUnaryInterceptors(interceptors ...grpc.UnaryClientInterceptor) ClientOption {
+	return func(c *Client) {
+		c.unaryInterceptors = append(c.unaryInterceptors, interceptors...)
+	}
+}
+
+// WithStreamInterceptors is the option to add interceptors to all streaming calls, including script
+// executions. Interceptors run in the given order.
+func WithStreamInterceptors(interceptors ...grpc.StreamClientInterceptor) ClientOption {
+	return func(c *Client) {
+		c.streamInterceptors = append(c.streamInterceptors, interceptors...)
+	}
+}
+
+// WithDialOptions is the option to pass additional options when dialing Pixie. They are applied after
+// the client's own options, so they take precedence.
+func WithDialOptions(opts ...grpc.DialOption) ClientOption {
+	return func(c *Client) {
+		c.dialOpts = append(c.dialOpts, opts...)
+	}
+}
+
+// ScriptOption configures how a single script is executed.
+type ScriptOption func(opts *scriptOptions)
+
+type scriptOptions struct {
+	explain bool
+	analyze bool
+	maxRows int64
+}
+
+// WithExplain is the option to have Vizier return the compiled query plan in the
+// QueryPlanTableName table alongside the script results.
+func WithExplain() ScriptOption {
+	return func(o *scriptOptions) {
+		o.explain = true
+	}
+}
+
+// WithAnalyze is the option to collect per-operator execution stats. The stats are annotated
+// on the query plan and returned in the QueryExecStatsTableName table. Implies WithExplain.
+func WithAnalyze() ScriptOption {
+	return func(o *scriptOptions) {
+		o.explain = true
+		o.analyze = true
+	}
+}
+
+// WithMaxRows is the option to limit each table of the results to its first n rows, so that an
+// unbounded script can't exhaust the memory of the client. Vizier is asked for one extra row per
+// table, so that the tables that had more rows are reported in ResultsStats.TruncatedTables. A
+// max_output_rows_per_table pragma in the script itself takes precedence on the Vizier side.
+func WithMaxRows(n int64) ScriptOption {
+	return func(o *scriptOptions) {
+		o.maxRows = n
+	}
+}
+
+// ApplyScriptOptions returns the PxL script with the pragmas for the given options prepended, for callers
+// that execute scripts without a Client, such as the CLI.
+func ApplyScriptOptions(pxl string, opts ...ScriptOption) string {
+	so := &scriptOptions{}
+	for _, opt := range opts {
+		opt(so)
+	}
+	return so.applyToScript(pxl)
+}
+
+// applyToScript prepends the pragmas for the selected options to the PxL script.
+func (o *scriptOptions) applyToScript(pxl string) string {
+	pragmas := ""
+	if o.explain {
+		pragmas += "#px:set explain=true\n"
+	}
+	if o.analyze {
+		pragmas += "#px:set analyze=true\n"
+	}
+	if o.maxRows > 0 {
+		pragmas += fmt.Sprintf("#px:set max_output_rows_per_table=%d\n", o.maxRows+1)
+	}
+	return pragmas + pxl
+}
@@ -0,0 +1,228 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxapi
+
+import (
+	"context"
+
+	"github.com/gogo/protobuf/types"
+
+	"px.dev/pixie/src/api/go/pxapi/errdefs"
+	"px.dev/pixie/src/api/go/pxapi/utils"
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/api/proto/uuidpb"
+)
+
+// RetentionPluginConfig is the org's configuration for a plugin that supports long-term data retention.
+type RetentionPluginConfig struct {
+	// ID of the plugin, for example "otel".
+	PluginID string
+	// Enabled is whether the org has the plugin enabled.
+	Enabled bool
+	// Version of the plugin that is enabled. When updating, empty uses the latest version.
+	Version string
+	// Configs are the values of the plugin's configurable fields, keyed by field name.
+	Configs map[string]string
+	// CustomExportURL overrides the plugin's default export URL, if the plugin allows it.
+	CustomExportURL string
+	// InsecureTLS disables TLS verification when exporting, if the plugin allows it.
+	InsecureTLS bool
+}
+
+// GetRetentionPluginConfig gets the org's configuration for the given retention plugin.
+func (c *Client) GetRetentionPluginConfig(ctx context.Context, pluginID string) (*RetentionPluginConfig, error) {
+	pluginClient := cloudpb.NewPluginServiceClient(c.grpcConn)
+	resp, err := pluginClient.GetPlugins(c.cloudCtxWithMD(ctx), &cloudpb.GetPluginsRequest{
+		Kind: cloudpb.PK_RETENTION,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var plugin *cloudpb.Plugin
+	for _, p := range resp.Plugins {
+		if p.Id == pluginID {
+			plugin = p
+			break
+		}
+	}
+	if plugin == nil {
+		return nil, errdefs.ErrPluginNotFound
+	}
+
+	cfg := &RetentionPluginConfig{
+		PluginID: pluginID,
+		Enabled:  plugin.RetentionEnabled,
+		Version:  plugin.EnabledVersion,
+	}
+	if !cfg.Enabled {
+		return cfg, nil
+	}
+
+	orgCfg, err := pluginClient.GetOrgRetentionPluginConfig(c.cloudCtxWithMD(ctx), &cloudpb.GetOrgRetentionPluginConfigRequest{
+		PluginId: pluginID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	cfg.Configs = orgCfg.Configs
+	cfg.CustomExportURL = orgCfg.CustomExportUrl
+	cfg.InsecureTLS = orgCfg.InsecureTLS
+	return cfg, nil
+}
+
+// UpdateRetentionPluginConfig updates the org's configuration for a retention plugin. Enabling a
+// plugin that was disabled also enables its preset scripts.
+func (c *Client) UpdateRetentionPluginConfig(ctx context.Context, cfg *RetentionPluginConfig) error {
+	req := &cloudpb.UpdateRetentionPluginConfigRequest{
+		PluginId:        cfg.PluginID,
+		Configs:         cfg.Configs,
+		Enabled:         &types.BoolValue{Value: cfg.Enabled},
+		CustomExportUrl: &types.StringValue{Value: cfg.CustomExportURL},
+		InsecureTLS:     &types.BoolValue{Value: cfg.InsecureTLS},
+	}
+	if cfg.Version != "" {
+		req.Version = &types.StringValue{Value: cfg.Version}
+	}
+
+	pluginClient := cloudpb.NewPluginServiceClient(c.grpcConn)
+	_, err := pluginClient.UpdateRetentionPluginConfig(c.cloudCtxWithMD(ctx), req)
+	return err
+}
+
+// AlertRoute sends the events from the alert rules on the org's scripts to an alert plugin.
+type AlertRoute struct {
+	// ID of the route (uuid as a string).
+	ID string
+	// Name of the route.
+	Name string
+	// PluginID is the alert plugin that events are sent to. Either "slack" or "pagerduty".
+	PluginID string
+	// Destination is the Slack incoming webhook URL, or the PagerDuty routing key.
+	Destination string
+	// Template is an optional Go text/template used to render the message.
+	Template string
+	// ClusterID limits the route to events from a single cluster. Empty matches all clusters.
+	ClusterID string
+	// ScriptID limits the route to events from a single script. Empty matches all scripts.
+	ScriptID string
+}
+
+func optionalUUIDToString(pb *uuidpb.UUID) string {
+	if pb == nil || (pb.HighBits == 0 && pb.LowBits == 0) {
+		return ""
+	}
+	return utils.ProtoToUUIDStr(pb)
+}
+
+func optionalUUIDFromString(id string) *uuidpb.UUID {
+	if id == "" {
+		return nil
+	}
+	return utils.ProtoFromUUIDStrOrNil(id)
+}
+
+func alertRouteFromProto(r *cloudpb.AlertRoute) *AlertRoute {
+	return &AlertRoute{
+		ID:          utils.ProtoToUUIDStr(r.ID),
+		Name:        r.Name,
+		PluginID:    r.PluginId,
+		Destination: r.Destination,
+		Template:    r.Template,
+		ClusterID:   optionalUUIDToString(r.ClusterID),
+		ScriptID:    optionalUUIDToString(r.ScriptID),
+	}
+}
+
+// ListAlertRoutes lists the alert routes in the org.
+func (c *Client) ListAlertRoutes(ctx context.Context) ([]*AlertRoute, error) {
+	pluginClient := cloudpb.NewPluginServiceClient(c.grpcConn)
+	resp, err := pluginClient.GetAlertRoutes(c.cloudCtxWithMD(ctx), &cloudpb.GetAlertRoutesRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	routes := make([]*AlertRoute, len(resp.Routes))
+	for i, r := range resp.Routes {
+		routes[i] = alertRouteFromProto(r)
+	}
+	return routes, nil
+}
+
+// GetAlertRoute gets an alert route by ID.
+func (c *Client) GetAlertRoute(ctx context.Context, id string) (*AlertRoute, error) {
+	routes, err := c.ListAlertRoutes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range routes {
+		if r.ID == id {
+			return r, nil
+		}
+	}
+	return nil, errdefs.ErrAlertRouteNotFound
+}
+
+// CreateAlertRoute creates an alert route, and returns its ID. The ID of the passed in route is ignored.
+func (c *Client) CreateAlertRoute(ctx context.Context, route *AlertRoute) (string, error) {
+	req := &cloudpb.CreateAlertRouteRequest{
+		Route: &cloudpb.AlertRoute{
+			Name:        route.Name,
+			PluginId:    route.PluginID,
+			Destination: route.Destination,
+			Template:    route.Template,
+			ClusterID:   optionalUUIDFromString(route.ClusterID),
+			ScriptID:    optionalUUIDFromString(route.ScriptID),
+		},
+	}
+
+	pluginClient := cloudpb.NewPluginServiceClient(c.grpcConn)
+	resp, err := pluginClient.CreateAlertRoute(c.cloudCtxWithMD(ctx), req)
+	if err != nil {
+		return "", err
+	}
+	return utils.ProtoToUUIDStr(resp.ID), nil
+}
+
+// UpdateAlertRoute replaces the name, destination, template and filters of the alert route with
+// the route's ID. The plugin of a route cannot be changed.
+func (c *Client) UpdateAlertRoute(ctx context.Context, route *AlertRoute) error {
+	req := &cloudpb.UpdateAlertRouteRequest{
+		ID:          utils.ProtoFromUUIDStrOrNil(route.ID),
+		Name:        &types.StringValue{Value: route.Name},
+		Destination: &types.StringValue{Value: route.Destination},
+		Template:    &types.StringValue{Value: route.Template},
+		// A nil UUID clears the filter.
+		ClusterID: utils.ProtoFromUUIDStrOrNil(route.ClusterID),
+		ScriptID:  utils.ProtoFromUUIDStrOrNil(route.ScriptID),
+	}
+
+	pluginClient := cloudpb.NewPluginServiceClient(c.grpcConn)
+	_, err := pluginClient.UpdateAlertRoute(c.cloudCtxWithMD(ctx), req)
+	return err
+}
+
+// DeleteAlertRoute deletes an alert route by ID.
+func (c *Client) DeleteAlertRoute(ctx context.Context, id string) error {
+	pluginClient := cloudpb.NewPluginServiceClient(c.grpcConn)
+	_, err := pluginClient.DeleteAlertRoute(c.cloudCtxWithMD(ctx), &cloudpb.DeleteAlertRouteRequest{
+		ID: utils.ProtoFromUUIDStrOrNil(id),
+	})
+	return err
+}
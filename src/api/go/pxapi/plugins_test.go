@@ -0,0 +1,180 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxapi
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"px.dev/pixie/src/api/go/pxapi/errdefs"
+	"px.dev/pixie/src/api/go/pxapi/utils"
+	"px.dev/pixie/src/api/proto/cloudpb"
+)
+
+type fakePluginServer struct {
+	cloudpb.UnimplementedPluginServiceServer
+
+	plugins  []*cloudpb.Plugin
+	config   *cloudpb.GetOrgRetentionPluginConfigResponse
+	routes   []*cloudpb.AlertRoute
+	updateRP *cloudpb.UpdateRetentionPluginConfigRequest
+	createAR *cloudpb.CreateAlertRouteRequest
+	updateAR *cloudpb.UpdateAlertRouteRequest
+}
+
+func (f *fakePluginServer) GetPlugins(ctx context.Context, req *cloudpb.GetPluginsRequest) (*cloudpb.GetPluginsResponse, error) {
+	return &cloudpb.GetPluginsResponse{Plugins: f.plugins}, nil
+}
+
+func (f *fakePluginServer) GetOrgRetentionPluginConfig(ctx context.Context, req *cloudpb.GetOrgRetentionPluginConfigRequest) (*cloudpb.GetOrgRetentionPluginConfigResponse, error) {
+	return f.config, nil
+}
+
+func (f *fakePluginServer) UpdateRetentionPluginConfig(ctx context.Context, req *cloudpb.UpdateRetentionPluginConfigRequest) (*cloudpb.UpdateRetentionPluginConfigResponse, error) {
+	f.updateRP = req
+	return &cloudpb.UpdateRetentionPluginConfigResponse{}, nil
+}
+
+func (f *fakePluginServer) GetAlertRoutes(ctx context.Context, req *cloudpb.GetAlertRoutesRequest) (*cloudpb.GetAlertRoutesResponse, error) {
+	return &cloudpb.GetAlertRoutesResponse{Routes: f.routes}, nil
+}
+
+func (f *fakePluginServer) CreateAlertRoute(ctx context.Context, req *cloudpb.CreateAlertRouteRequest) (*cloudpb.CreateAlertRouteResponse, error) {
+	f.createAR = req
+	return &cloudpb.CreateAlertRouteResponse{ID: utils.ProtoFromUUIDStrOrNil(clusterID2)}, nil
+}
+
+func (f *fakePluginServer) UpdateAlertRoute(ctx context.Context, req *cloudpb.UpdateAlertRouteRequest) (*cloudpb.UpdateAlertRouteResponse, error) {
+	f.updateAR = req
+	return &cloudpb.UpdateAlertRouteResponse{}, nil
+}
+
+func newPluginTestClient(t *testing.T, srv *fakePluginServer) *Client {
+	lis := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer()
+	cloudpb.RegisterPluginServiceServer(s, srv)
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+
+	dialer := func(ctx context.Context, url string) (net.Conn, error) {
+		return lis.Dial()
+	}
+	conn, err := grpc.Dial("bufnet", grpc.WithContextDialer(dialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return &Client{apiKey: "abc", grpcConn: conn}
+}
+
+func TestClient_GetRetentionPluginConfig(t *testing.T) {
+	srv := &fakePluginServer{
+		plugins: []*cloudpb.Plugin{
+			{Id: "otel", RetentionEnabled: true, EnabledVersion: "0.0.2"},
+			{Id: "bigquery"},
+		},
+		config: &cloudpb.GetOrgRetentionPluginConfigResponse{
+			Configs:         map[string]string{"API_KEY": "key"},
+			CustomExportUrl: "otel.example.com:4317",
+			InsecureTLS:     true,
+		},
+	}
+	c := newPluginTestClient(t, srv)
+
+	cfg, err := c.GetRetentionPluginConfig(context.Background(), "otel")
+	require.NoError(t, err)
+	assert.Equal(t, &RetentionPluginConfig{
+		PluginID:        "otel",
+		Enabled:         true,
+		Version:         "0.0.2",
+		Configs:         map[string]string{"API_KEY": "key"},
+		CustomExportURL: "otel.example.com:4317",
+		InsecureTLS:     true,
+	}, cfg)
+
+	cfg, err = c.GetRetentionPluginConfig(context.Background(), "bigquery")
+	require.NoError(t, err)
+	assert.Equal(t, &RetentionPluginConfig{PluginID: "bigquery"}, cfg)
+
+	_, err = c.GetRetentionPluginConfig(context.Background(), "missing")
+	assert.ErrorIs(t, err, errdefs.ErrPluginNotFound)
+}
+
+func TestClient_UpdateRetentionPluginConfig(t *testing.T) {
+	srv := &fakePluginServer{}
+	c := newPluginTestClient(t, srv)
+
+	err := c.UpdateRetentionPluginConfig(context.Background(), &RetentionPluginConfig{
+		PluginID: "otel",
+		Enabled:  true,
+		Configs:  map[string]string{"API_KEY": "key"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "otel", srv.updateRP.PluginId)
+	assert.True(t, srv.updateRP.Enabled.Value)
+	assert.Nil(t, srv.updateRP.Version)
+	assert.Equal(t, "", srv.updateRP.CustomExportUrl.Value)
+	assert.Equal(t, map[string]string{"API_KEY": "key"}, srv.updateRP.Configs)
+}
+
+func TestClient_AlertRoutes(t *testing.T) {
+	srv := &fakePluginServer{
+		routes: []*cloudpb.AlertRoute{
+			{
+				ID:          utils.ProtoFromUUIDStrOrNil(clusterID1),
+				Name:        "oncall",
+				PluginId:    "pagerduty",
+				Destination: "routing-key",
+				ClusterID:   utils.ProtoFromUUIDStrOrNil(clusterID2),
+			},
+		},
+	}
+	c := newPluginTestClient(t, srv)
+
+	route, err := c.GetAlertRoute(context.Background(), clusterID1)
+	require.NoError(t, err)
+	assert.Equal(t, &AlertRoute{
+		ID:          clusterID1,
+		Name:        "oncall",
+		PluginID:    "pagerduty",
+		Destination: "routing-key",
+		ClusterID:   clusterID2,
+	}, route)
+
+	_, err = c.GetAlertRoute(context.Background(), clusterID2)
+	assert.ErrorIs(t, err, errdefs.ErrAlertRouteNotFound)
+
+	id, err := c.CreateAlertRoute(context.Background(), &AlertRoute{Name: "all", PluginID: "slack", Destination: "https://hooks.slack.com/a"})
+	require.NoError(t, err)
+	assert.Equal(t, clusterID2, id)
+	assert.Equal(t, "slack", srv.createAR.Route.PluginId)
+	assert.Nil(t, srv.createAR.Route.ClusterID)
+
+	route.ClusterID = ""
+	require.NoError(t, c.UpdateAlertRoute(context.Background(), route))
+	assert.Equal(t, "oncall", srv.updateAR.Name.Value)
+	// Clearing the cluster sends a nil UUID.
+	require.NotNil(t, srv.updateAR.ClusterID)
+	assert.Equal(t, "", optionalUUIDToString(srv.updateAR.ClusterID))
+}
@@ -40,6 +40,8 @@ type tableTracker struct {
 	md      types.TableMetadata
 	handler TableRecordHandler
 	done    bool
+	// rows is the number of rows that were passed to the handler.
+	rows int64
 }
 
 // ResultsStats stores statistics about the data.
@@ -51,6 +53,39 @@ type ResultsStats struct {
 	CompilationTime  time.Duration
 	BytesProcessed   int64
 	RecordsProcessed int64
+
+	// PerfBufferLostEvents is the number of events dropped by the agents during the time range
+	// of the script, before they could be recorded.
+	PerfBufferLostEvents int64
+	// TablesWithEvictedData are the tables whose data in the time range of the script had
+	// already been evicted when the script ran.
+	TablesWithEvictedData []string
+	// MissingAgentIDs are the agents that were left out of the script because they were
+	// unresponsive, so the results only include data from the remaining agents.
+	MissingAgentIDs []string
+	// MaxClockSkew is the largest difference between the clocks of the agents that ran the script.
+	// It is only reported when it exceeds the threshold of the Vizier, since durations computed
+	// from the timestamps of different nodes may be off by up to this much.
+	MaxClockSkew time.Duration
+	// ClockOffsets are the estimated offsets of the clocks of the agents from the clock of the
+	// Vizier, keyed by agent ID, when the clock skew is reported. Subtracting the offset of an
+	// agent from the timestamps that it recorded corrects them.
+	ClockOffsets map[string]time.Duration
+	// TruncatedTables are the tables that had more rows than the limit set with WithMaxRows, so
+	// only their first rows were passed to their handlers.
+	TruncatedTables []string
+}
+
+// HasDataLoss returns whether data was lost in the time range of the script, which means that
+// the results may be undercounted.
+func (r *ResultsStats) HasDataLoss() bool {
+	return r.PerfBufferLostEvents > 0 || len(r.TablesWithEvictedData) > 0
+}
+
+// HasClockSkew returns whether the clocks of the agents that ran the script were skewed beyond the
+// threshold of the Vizier.
+func (r *ResultsStats) HasClockSkew() bool {
+	return r.MaxClockSkew > 0
 }
 
 // ScriptResults tracks the results of a script, and provides mechanisms to cancel, etc.
@@ -61,14 +96,19 @@ type ScriptResults struct {
 
 	tableIDToTracker map[string]*tableTracker
 	tm               TableMuxer
+	encOpts          *vizierpb.ExecuteScriptRequest_EncryptionOptions
 	decOpts          *vizierpb.ExecuteScriptRequest_EncryptionOptions
 	wg               sync.WaitGroup
 
 	stats *ResultsStats
 
 	v       *VizierClient
+	req     *vizierpb.ExecuteScriptRequest
 	queryID string
 	origCtx context.Context
+
+	// maxRows is the limit on the rows passed to the handler of each table, if positive.
+	maxRows int64
 }
 
 func newScriptResults() *ScriptResults {
@@ -148,6 +188,10 @@ func isTransientGRPCError(err error) bool {
 	if !ok {
 		return false
 	}
+	// Connections that break mid-stream, for example when keepalive pings go unanswered, surface as unavailable.
+	if s.Code() == codes.Unavailable {
+		return true
+	}
 	if s.Code() == codes.Internal && strings.Contains(s.Message(), "RST_STREAM") {
 		return true
 	}
@@ -157,14 +201,47 @@ func isTransientGRPCError(err error) bool {
 	return false
 }
 
+// isEncryptionKeyExpiredError returns true if vizier rejected the script because its encryption key has expired.
+func isEncryptionKeyExpiredError(err error) bool {
+	s, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+	return s.Code() == codes.FailedPrecondition && strings.HasPrefix(s.Message(), errdefs.ErrEncryptionKeyExpired.Error())
+}
+
+// restartWithRotatedKeys runs the script again with rotated keys, after vizier rejected the keys it started with.
+func (s *ScriptResults) restartWithRotatedKeys() error {
+	keys, err := s.v.keys.rotateExpired(s.encOpts)
+	if err != nil {
+		return err
+	}
+	req := *s.req
+	req.EncryptionOptions = keys.encOpts
+	ctx, cancel := context.WithCancel(s.origCtx)
+	res, err := s.v.vzClient.ExecuteScript(s.v.cloud.cloudCtxWithMD(ctx), &req)
+	if err != nil {
+		cancel()
+		return err
+	}
+	s.cancel()
+	s.cancel = cancel
+	s.c = res
+	s.req = &req
+	s.encOpts = keys.encOpts
+	s.decOpts = keys.decOpts
+	return nil
+}
+
 func (s *ScriptResults) reconnect() error {
 	if s.queryID == "" {
 		return errors.New("cannot reconnect to query that hasn't returned a QueryID yet")
 	}
+	// Reuse the keys this session started with, even if the client has rotated its keys since.
 	req := &vizierpb.ExecuteScriptRequest{
 		ClusterID:         s.v.vizierID,
 		QueryID:           s.queryID,
-		EncryptionOptions: s.v.encOpts,
+		EncryptionOptions: s.encOpts,
 	}
 	ctx, cancel := context.WithCancel(s.origCtx)
 	res, err := s.v.vzClient.ExecuteScript(s.v.cloud.cloudCtxWithMD(ctx), req)
@@ -179,6 +256,10 @@ func (s *ScriptResults) reconnect() error {
 
 func (s *ScriptResults) run() error {
 	ctx := s.c.Context()
+	// reconnects counts the reconnects since the last response, so that a stream that keeps failing gives up.
+	reconnects := 0
+	// rotatedKeys is set once the script was restarted with rotated keys, so that it is only restarted once.
+	rotatedKeys := false
 	for {
 		resp, err := s.c.Recv()
 
@@ -187,8 +268,23 @@ func (s *ScriptResults) run() error {
 				// Stream has terminated.
 				return nil
 			}
-			if isTransientGRPCError(err) {
+			if isEncryptionKeyExpiredError(err) && s.v.keys != nil && s.queryID == "" {
+				if rotatedKeys {
+					return fmt.Errorf("%w: %v", errdefs.ErrEncryptionKeyExpired, err)
+				}
+				rotatedKeys = true
+				if err := s.restartWithRotatedKeys(); err != nil {
+					return fmt.Errorf("encryption key expired, error occurred while rotating: %w", err)
+				}
+				ctx = s.c.Context()
+				continue
+			}
+			if isTransientGRPCError(err) && s.v.cloud.retryPolicy.allowsReconnect(reconnects) {
 				origErr := err
+				if err := s.v.cloud.retryPolicy.wait(s.origCtx, reconnects); err != nil {
+					return origErr
+				}
+				reconnects++
 				err = s.reconnect()
 				if err != nil {
 					return fmt.Errorf("streaming failed: %w, error occurred while reconnecting: %v", origErr, err)
@@ -201,6 +297,7 @@ func (s *ScriptResults) run() error {
 		if resp == nil {
 			return nil
 		}
+		reconnects = 0
 		if s.queryID == "" {
 			s.queryID = resp.QueryID
 		}
@@ -320,6 +417,13 @@ func (s *ScriptResults) handleTableRowbatch(ctx context.Context, b *vizierpb.Row
 
 	// Now loop through the rows, covert the values at each column and call the handler.
 	for rowIdx := int64(0); rowIdx < b.NumRows; rowIdx++ {
+		if s.maxRows > 0 && tracker.rows >= s.maxRows {
+			if !containsString(s.stats.TruncatedTables, tracker.md.Name) {
+				s.stats.TruncatedTables = append(s.stats.TruncatedTables, tracker.md.Name)
+			}
+			break
+		}
+		tracker.rows++
 		for colIdx := int64(0); colIdx < numCols; colIdx++ {
 			if err := extractDataFromCol(b.Cols, rowIdx, colIdx, row); err != nil {
 				return err
@@ -387,9 +491,38 @@ func (s *ScriptResults) handleStats(ctx context.Context, qes *vizierpb.QueryExec
 	s.stats.RecordsProcessed += qes.RecordsProcessed
 	s.stats.CompilationTime = time.Duration(qes.Timing.CompilationTimeNs) * time.Nanosecond
 	s.stats.ExecutionTime = time.Duration(qes.Timing.ExecutionTimeNs) * time.Nanosecond
+	if dl := qes.DataLoss; dl != nil {
+		s.stats.PerfBufferLostEvents += dl.PerfBufferLostEvents
+		for _, table := range dl.TablesWithEvictedData {
+			if !containsString(s.stats.TablesWithEvictedData, table) {
+				s.stats.TablesWithEvictedData = append(s.stats.TablesWithEvictedData, table)
+			}
+		}
+	}
+	for _, agentID := range qes.MissingAgentIDs {
+		if !containsString(s.stats.MissingAgentIDs, agentID) {
+			s.stats.MissingAgentIDs = append(s.stats.MissingAgentIDs, agentID)
+		}
+	}
+	if cs := qes.ClockSkew; cs != nil {
+		s.stats.MaxClockSkew = time.Duration(cs.MaxSkewNs)
+		s.stats.ClockOffsets = make(map[string]time.Duration, len(cs.AgentClockOffsetsNs))
+		for agentID, offset := range cs.AgentClockOffsetsNs {
+			s.stats.ClockOffsets[agentID] = time.Duration(offset)
+		}
+	}
 	return nil
 }
 
+func containsString(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
+
 // Stats returns the execution and script stats.
 func (s *ScriptResults) Stats() *ResultsStats {
 	return s.stats
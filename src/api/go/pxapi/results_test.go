@@ -22,6 +22,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc/codes"
@@ -232,6 +233,74 @@ func TestProcessOneTable(t *testing.T) {
 	assert.Equal(t, []int64{1, 2, 3, 4, 5}, httpTable.Data)
 }
 
+func TestHandleStatsDataLoss(t *testing.T) {
+	results := newScriptResults()
+	ctx := context.Background()
+
+	statsResponse := func(dl *vizierpb.DataLossStats) *vizierpb.ExecuteScriptResponse {
+		return &vizierpb.ExecuteScriptResponse{
+			Result: &vizierpb.ExecuteScriptResponse_Data{
+				Data: &vizierpb.QueryData{
+					ExecutionStats: &vizierpb.QueryExecutionStats{
+						Timing:   &vizierpb.QueryTimingInfo{},
+						DataLoss: dl,
+					},
+				},
+			},
+		}
+	}
+
+	assert.Nil(t, results.handleGRPCMsg(ctx, statsResponse(nil)))
+	assert.False(t, results.Stats().HasDataLoss())
+
+	assert.Nil(t, results.handleGRPCMsg(ctx, statsResponse(&vizierpb.DataLossStats{
+		PerfBufferLostEvents:  3,
+		TablesWithEvictedData: []string{"http_events"},
+	})))
+	assert.Nil(t, results.handleGRPCMsg(ctx, statsResponse(&vizierpb.DataLossStats{
+		PerfBufferLostEvents:  2,
+		TablesWithEvictedData: []string{"http_events", "dns_events"},
+	})))
+	assert.True(t, results.Stats().HasDataLoss())
+	assert.Equal(t, int64(5), results.Stats().PerfBufferLostEvents)
+	assert.Equal(t, []string{"http_events", "dns_events"}, results.Stats().TablesWithEvictedData)
+}
+
+func TestHandleStatsClockSkew(t *testing.T) {
+	results := newScriptResults()
+	ctx := context.Background()
+
+	statsResponse := func(cs *vizierpb.ClockSkewStats) *vizierpb.ExecuteScriptResponse {
+		return &vizierpb.ExecuteScriptResponse{
+			Result: &vizierpb.ExecuteScriptResponse_Data{
+				Data: &vizierpb.QueryData{
+					ExecutionStats: &vizierpb.QueryExecutionStats{
+						Timing:    &vizierpb.QueryTimingInfo{},
+						ClockSkew: cs,
+					},
+				},
+			},
+		}
+	}
+
+	assert.Nil(t, results.handleGRPCMsg(ctx, statsResponse(nil)))
+	assert.False(t, results.Stats().HasClockSkew())
+
+	assert.Nil(t, results.handleGRPCMsg(ctx, statsResponse(&vizierpb.ClockSkewStats{
+		MaxSkewNs: (250 * time.Millisecond).Nanoseconds(),
+		AgentClockOffsetsNs: map[string]int64{
+			"agent1": (-50 * time.Millisecond).Nanoseconds(),
+			"agent2": (200 * time.Millisecond).Nanoseconds(),
+		},
+	})))
+	assert.True(t, results.Stats().HasClockSkew())
+	assert.Equal(t, 250*time.Millisecond, results.Stats().MaxClockSkew)
+	assert.Equal(t, map[string]time.Duration{
+		"agent1": -50 * time.Millisecond,
+		"agent2": 200 * time.Millisecond,
+	}, results.Stats().ClockOffsets)
+}
+
 func TestProcessNoEnd(t *testing.T) {
 	results := newScriptResults()
 	tm := newTableMux()
@@ -447,3 +516,43 @@ func TestExecuteScriptGetsOtherErrorOnStream(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.EqualError(t, err, "invalid/missing arguments: Script should not be empty.")
 }
+
+func TestProcessMaxRows(t *testing.T) {
+	results := newScriptResults()
+	results.maxRows = 3
+	tm := newTableMux()
+	results.tm = tm
+
+	relation := &vizierpb.Relation{
+		Columns: []*vizierpb.Relation_ColumnInfo{
+			noSemTypeColInfo("http_status", vizierpb.INT64),
+		},
+	}
+	table := NewFakeTable("http_table", "abc", relation)
+	small := NewFakeTable("small_table", "def", relation)
+
+	messages := []*vizierpb.ExecuteScriptResponse{
+		table.MetadataResponse(),
+		small.MetadataResponse(),
+		table.RowBatchResponse([]*vizierpb.Column{
+			makeInt64Column([]int64{1, 2}),
+		}, 2),
+		small.RowBatchResponse([]*vizierpb.Column{
+			makeInt64Column([]int64{1, 2, 3}),
+		}, 3),
+		table.RowBatchResponse([]*vizierpb.Column{
+			makeInt64Column([]int64{3, 4}),
+		}, 2),
+		table.EndResponse(),
+		small.EndResponse(),
+	}
+
+	ctx := context.Background()
+	for _, msg := range messages {
+		assert.NoError(t, results.handleGRPCMsg(ctx, msg))
+	}
+
+	assert.Equal(t, []int64{1, 2, 3}, tm.Tables["http_table"].Data)
+	assert.Equal(t, []int64{1, 2, 3}, tm.Tables["small_table"].Data)
+	assert.Equal(t, []string{"http_table"}, results.Stats().TruncatedTables)
+}
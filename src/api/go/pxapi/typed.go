@@ -0,0 +1,85 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxapi
+
+import (
+	"context"
+
+	"px.dev/pixie/src/api/go/pxapi/types"
+)
+
+// TypedTableHandler is a TableRecordHandler that decodes each record of a table into a struct of type T
+// before passing it to a callback. See types.Decoder for how columns are mapped to the fields of T.
+type TypedTableHandler[T any] struct {
+	dec      *types.Decoder
+	onRecord func(ctx context.Context, row *T) error
+}
+
+// NewTypedTableHandler creates a handler that calls onRecord with each record of the table decoded into a new T.
+func NewTypedTableHandler[T any](onRecord func(ctx context.Context, row *T) error) *TypedTableHandler[T] {
+	return &TypedTableHandler[T]{onRecord: onRecord}
+}
+
+// HandleInit is called when the table metadata is available. It fails if T can't be decoded from the table.
+func (h *TypedTableHandler[T]) HandleInit(ctx context.Context, metadata types.TableMetadata) error {
+	dec, err := types.NewDecoder(&metadata, new(T))
+	if err != nil {
+		return err
+	}
+	h.dec = dec
+	return nil
+}
+
+// HandleRecord is called for each record of the table.
+func (h *TypedTableHandler[T]) HandleRecord(ctx context.Context, r *types.Record) error {
+	row := new(T)
+	if err := h.dec.Decode(r, row); err != nil {
+		return err
+	}
+	return h.onRecord(ctx, row)
+}
+
+// HandleDone is called when the table streaming is complete.
+func (h *TypedTableHandler[T]) HandleDone(ctx context.Context) error {
+	return nil
+}
+
+// TableCollector is a TableMuxer that collects the records of a single table as structs of type T.
+// Other tables in the results are ignored.
+type TableCollector[T any] struct {
+	tableName string
+	// Rows holds the decoded records of the table, in the order that they were received.
+	Rows []*T
+}
+
+// NewTableCollector creates a collector for the table with the given name.
+func NewTableCollector[T any](tableName string) *TableCollector[T] {
+	return &TableCollector[T]{tableName: tableName}
+}
+
+// AcceptTable is called when a new table is streamed.
+func (c *TableCollector[T]) AcceptTable(ctx context.Context, metadata types.TableMetadata) (TableRecordHandler, error) {
+	if metadata.Name != c.tableName {
+		return nil, nil
+	}
+	return NewTypedTableHandler(func(ctx context.Context, row *T) error {
+		c.Rows = append(c.Rows, row)
+		return nil
+	}), nil
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxapi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+)
+
+type httpRow struct {
+	Path   string `pxl:"req_path"`
+	Status int64  `pxl:"http_status"`
+}
+
+func TestTableCollector(t *testing.T) {
+	results := newScriptResults()
+	tc := NewTableCollector[httpRow]("http_table")
+	results.tm = tc
+
+	relation := &vizierpb.Relation{
+		Columns: []*vizierpb.Relation_ColumnInfo{
+			noSemTypeColInfo("req_path", vizierpb.STRING),
+			noSemTypeColInfo("http_status", vizierpb.INT64),
+		},
+	}
+	table := NewFakeTable("http_table", "abc", relation)
+	other := NewFakeTable("other_table", "def", relation)
+
+	messages := []*vizierpb.ExecuteScriptResponse{
+		table.MetadataResponse(),
+		other.MetadataResponse(),
+		table.RowBatchResponse([]*vizierpb.Column{
+			makeStringColumn([]string{"/a", "/b"}),
+			makeInt64Column([]int64{200, 404}),
+		}, 2),
+		other.RowBatchResponse([]*vizierpb.Column{
+			makeStringColumn([]string{"/c"}),
+			makeInt64Column([]int64{500}),
+		}, 1),
+		table.EndResponse(),
+		other.EndResponse(),
+	}
+
+	ctx := context.Background()
+	for _, msg := range messages {
+		require.NoError(t, results.handleGRPCMsg(ctx, msg))
+	}
+
+	assert.Equal(t, []*httpRow{
+		{Path: "/a", Status: 200},
+		{Path: "/b", Status: 404},
+	}, tc.Rows)
+}
+
+func TestTypedTableHandler_SchemaMismatch(t *testing.T) {
+	results := newScriptResults()
+	results.tm = NewTableCollector[httpRow]("http_table")
+
+	relation := &vizierpb.Relation{
+		Columns: []*vizierpb.Relation_ColumnInfo{
+			noSemTypeColInfo("req_path", vizierpb.STRING),
+		},
+	}
+	table := NewFakeTable("http_table", "abc", relation)
+	assert.Error(t, results.handleGRPCMsg(context.Background(), table.MetadataResponse()))
+}
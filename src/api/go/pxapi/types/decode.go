@@ -0,0 +1,233 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package types
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gofrs/uuid"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+)
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	uuidType = reflect.TypeOf(uuid.UUID{})
+)
+
+// setterFunc stores a datum into a struct field.
+type setterFunc func(field reflect.Value, d Datum) error
+
+type fieldDecoder struct {
+	index []int
+	col   int64
+	set   setterFunc
+}
+
+// Decoder decodes the records of a table into structs of a single type.
+//
+// Each exported field of the struct is filled from the column named by its `pxl` struct tag. Fields
+// without a tag are filled from the column with the same name as the field, or with the snake_case of its
+// name, if the table has one. For example, the field ReqPath is filled from the column "req_path". Fields
+// tagged with `pxl:"-"` are skipped. Tagged fields must have a matching column.
+//
+// Columns are converted to fields as follows:
+//   - BOOLEAN columns decode into bool fields.
+//   - INT64 columns decode into any integer field, including time.Duration.
+//   - FLOAT64 columns decode into float32 and float64 fields.
+//   - TIME64NS columns decode into time.Time fields, or int64 fields as nanoseconds since the epoch.
+//   - UINT128 columns decode into uuid.UUID or [16]byte fields.
+//   - Columns of any type decode into string fields, using the string representation of the value.
+type Decoder struct {
+	typ    reflect.Type
+	fields []*fieldDecoder
+}
+
+// NewDecoder creates a decoder for records of the table into values of the same type as dst, which
+// must be a pointer to a struct. It fails if any of the struct's fields can't be decoded from the table.
+func NewDecoder(md *TableMetadata, dst interface{}) (*Decoder, error) {
+	t := reflect.TypeOf(dst)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("can only decode records into a pointer to a struct, got %T", dst)
+	}
+	return newDecoder(md, t.Elem())
+}
+
+func newDecoder(md *TableMetadata, t reflect.Type) (*Decoder, error) {
+	d := &Decoder{typ: t}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			// Unexported field.
+			continue
+		}
+		tag, tagged := f.Tag.Lookup("pxl")
+		if tag == "-" {
+			continue
+		}
+
+		col := int64(-1)
+		if tagged {
+			col = md.IndexOf(tag)
+			if col < 0 {
+				return nil, fmt.Errorf("table %s has no column %q for field %s", md.Name, tag, f.Name)
+			}
+		} else {
+			col = md.IndexOf(f.Name)
+			if col < 0 {
+				col = md.IndexOf(toSnakeCase(f.Name))
+			}
+			if col < 0 {
+				continue
+			}
+		}
+
+		schema := md.ColInfo[col]
+		set, err := setterFor(schema.Type, f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("column %q can't be decoded into field %s: %w", schema.Name, f.Name, err)
+		}
+		d.fields = append(d.fields, &fieldDecoder{index: f.Index, col: col, set: set})
+	}
+	return d, nil
+}
+
+// Decode stores the record into dst, which must be a pointer to the decoder's struct type.
+func (d *Decoder) Decode(r *Record, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Type() != d.typ {
+		return fmt.Errorf("decoder for %s can't decode into %T", d.typ, dst)
+	}
+	v = v.Elem()
+	for _, f := range d.fields {
+		if err := f.set(v.FieldByIndex(f.index), r.Data[f.col]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Decode stores the record into dst, which must be a pointer to a struct. See Decoder for how columns
+// are mapped to fields. Use a Decoder instead when decoding many records of the same table.
+func (r *Record) Decode(dst interface{}) error {
+	d, err := NewDecoder(r.TableMetadata, dst)
+	if err != nil {
+		return err
+	}
+	return d.Decode(r, dst)
+}
+
+func setterFor(dataType DataType, t reflect.Type) (setterFunc, error) {
+	if t.Kind() == reflect.String {
+		// Every type can be decoded into a string.
+		return func(f reflect.Value, d Datum) error {
+			f.SetString(d.String())
+			return nil
+		}, nil
+	}
+
+	switch dataType {
+	case vizierpb.BOOLEAN:
+		if t.Kind() == reflect.Bool {
+			return func(f reflect.Value, d Datum) error {
+				f.SetBool(d.(*BooleanValue).Value())
+				return nil
+			}, nil
+		}
+	case vizierpb.INT64:
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return func(f reflect.Value, d Datum) error {
+				v := d.(*Int64Value).Value()
+				if f.OverflowInt(v) {
+					return fmt.Errorf("value %d overflows %s", v, f.Type())
+				}
+				f.SetInt(v)
+				return nil
+			}, nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return func(f reflect.Value, d Datum) error {
+				v := d.(*Int64Value).Value()
+				if v < 0 || f.OverflowUint(uint64(v)) {
+					return fmt.Errorf("value %d overflows %s", v, f.Type())
+				}
+				f.SetUint(uint64(v))
+				return nil
+			}, nil
+		}
+	case vizierpb.FLOAT64:
+		if t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64 {
+			return func(f reflect.Value, d Datum) error {
+				f.SetFloat(d.(*Float64Value).Value())
+				return nil
+			}, nil
+		}
+	case vizierpb.TIME64NS:
+		if t == timeType {
+			return func(f reflect.Value, d Datum) error {
+				f.Set(reflect.ValueOf(d.(*Time64NSValue).Value()))
+				return nil
+			}, nil
+		}
+		if t.Kind() == reflect.Int64 {
+			return func(f reflect.Value, d Datum) error {
+				f.SetInt(d.(*Time64NSValue).Value().UnixNano())
+				return nil
+			}, nil
+		}
+	case vizierpb.UINT128:
+		if t == uuidType || (t.Kind() == reflect.Array && t.Len() == 16 && t.Elem().Kind() == reflect.Uint8) {
+			return func(f reflect.Value, d Datum) error {
+				reflect.Copy(f, reflect.ValueOf(d.(*UInt128Value).Value()))
+				return nil
+			}, nil
+		}
+	case vizierpb.STRING:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return func(f reflect.Value, d Datum) error {
+				f.SetBytes([]byte(d.(*StringValue).Value()))
+				return nil
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported conversion from %s to %s", dataType, t)
+}
+
+// toSnakeCase converts a Go field name to the snake_case naming used by PxL columns. Runs of capitals
+// are treated as a single word, so HTTPStatus becomes http_status.
+func toSnakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && !unicode.IsUpper(runes[i-1])
+			nextLower := i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1])
+			if prevLower || nextLower {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
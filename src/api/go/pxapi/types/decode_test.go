@@ -0,0 +1,151 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package types_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/api/go/pxapi/types"
+	"px.dev/pixie/src/api/proto/vizierpb"
+)
+
+func makeRecord() *types.Record {
+	md := &types.TableMetadata{
+		Name: "http_events",
+		ColInfo: []types.ColSchema{
+			{Name: "time_", Type: vizierpb.TIME64NS},
+			{Name: "upid", Type: vizierpb.UINT128},
+			{Name: "req_path", Type: vizierpb.STRING},
+			{Name: "resp_status", Type: vizierpb.INT64},
+			{Name: "latency", Type: vizierpb.INT64, SemanticType: vizierpb.ST_DURATION_NS},
+			{Name: "cpu_usage", Type: vizierpb.FLOAT64},
+			{Name: "is_error", Type: vizierpb.BOOLEAN},
+		},
+		ColIdxByName: map[string]int64{
+			"time_":       0,
+			"upid":        1,
+			"req_path":    2,
+			"resp_status": 3,
+			"latency":     4,
+			"cpu_usage":   5,
+			"is_error":    6,
+		},
+	}
+
+	t := types.NewTime64NSValue(&md.ColInfo[0])
+	t.ScanInt64(1600000000000000000)
+	upid := types.NewUint128Value(&md.ColInfo[1])
+	upid.ScanUInt128(&vizierpb.UInt128{High: 0x0102030405060708, Low: 0x090a0b0c0d0e0f10})
+	path := types.NewStringValue(&md.ColInfo[2])
+	path.ScanString("/healthz")
+	status := types.NewInt64Value(&md.ColInfo[3])
+	status.ScanInt64(200)
+	latency := types.NewInt64Value(&md.ColInfo[4])
+	latency.ScanInt64(int64(15 * time.Millisecond))
+	cpu := types.NewFloat64Value(&md.ColInfo[5])
+	cpu.ScanFloat64(0.25)
+	isErr := types.NewBooleanValue(&md.ColInfo[6])
+	isErr.ScanBool(true)
+
+	return &types.Record{
+		Data:          []types.Datum{t, upid, path, status, latency, cpu, isErr},
+		TableMetadata: md,
+	}
+}
+
+func TestRecord_Decode(t *testing.T) {
+	type httpEvent struct {
+		Time       time.Time `pxl:"time_"`
+		UPID       uuid.UUID `pxl:"upid"`
+		ReqPath    string
+		RespStatus int32
+		Latency    time.Duration `pxl:"latency"`
+		CPUUsage   float64       `pxl:"cpu_usage"`
+		IsError    bool
+		Ignored    string `pxl:"-"`
+		Missing    int
+		unexported int
+	}
+
+	var ev httpEvent
+	require.NoError(t, makeRecord().Decode(&ev))
+	assert.Equal(t, time.Unix(0, 1600000000000000000), ev.Time)
+	assert.Equal(t, "01020304-0506-0708-090a-0b0c0d0e0f10", ev.UPID.String())
+	assert.Equal(t, "/healthz", ev.ReqPath)
+	assert.Equal(t, int32(200), ev.RespStatus)
+	assert.Equal(t, 15*time.Millisecond, ev.Latency)
+	assert.Equal(t, 0.25, ev.CPUUsage)
+	assert.True(t, ev.IsError)
+	assert.Empty(t, ev.Ignored)
+	assert.Zero(t, ev.Missing)
+}
+
+func TestRecord_DecodeAlternateTypes(t *testing.T) {
+	type row struct {
+		TimeNS  int64    `pxl:"time_"`
+		UPID    string   `pxl:"upid"`
+		UPIDRaw [16]byte `pxl:"upid"`
+		Path    []byte   `pxl:"req_path"`
+		Status  string   `pxl:"resp_status"`
+		Latency uint64   `pxl:"latency"`
+	}
+
+	var r row
+	require.NoError(t, makeRecord().Decode(&r))
+	assert.Equal(t, int64(1600000000000000000), r.TimeNS)
+	assert.Equal(t, "01020304-0506-0708-090a-0b0c0d0e0f10", r.UPID)
+	assert.Equal(t, [16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}, r.UPIDRaw)
+	assert.Equal(t, []byte("/healthz"), r.Path)
+	assert.Equal(t, "200", r.Status)
+	assert.Equal(t, uint64(15*time.Millisecond), r.Latency)
+}
+
+func TestNewDecoder_Errors(t *testing.T) {
+	md := makeRecord().TableMetadata
+
+	type missingColumn struct {
+		Foo string `pxl:"foo"`
+	}
+	_, err := types.NewDecoder(md, &missingColumn{})
+	assert.ErrorContains(t, err, `no column "foo"`)
+
+	type badType struct {
+		IsError int `pxl:"is_error"`
+	}
+	_, err = types.NewDecoder(md, &badType{})
+	assert.ErrorContains(t, err, "unsupported conversion")
+
+	_, err = types.NewDecoder(md, missingColumn{})
+	assert.Error(t, err)
+}
+
+func TestDecoder_Overflow(t *testing.T) {
+	type row struct {
+		Status int8 `pxl:"resp_status"`
+	}
+	r := makeRecord()
+	d, err := types.NewDecoder(r.TableMetadata, &row{})
+	require.NoError(t, err)
+	assert.ErrorContains(t, d.Decode(r, &row{}), "overflows")
+}
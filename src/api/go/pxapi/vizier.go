@@ -26,21 +26,40 @@ import (
 	"px.dev/pixie/src/api/proto/vizierpb"
 )
 
+const (
+	// QueryPlanTableName is the table that holds the query plan when a script is run WithExplain.
+	QueryPlanTableName = "__query_plan__"
+	// QueryExecStatsTableName is the table that holds per-operator execution stats when a script is run WithAnalyze.
+	QueryExecStatsTableName = "__query_exec_stats__"
+)
+
 // VizierClient is the client for a single vizier.
 type VizierClient struct {
 	cloud    *Client
 	vizierID string
 	vzClient vizierpb.VizierServiceClient
-	encOpts  *vizierpb.ExecuteScriptRequest_EncryptionOptions
-	decOpts  *vizierpb.ExecuteScriptRequest_EncryptionOptions
+	// keys is nil when E2E encryption is disabled.
+	keys *keyManager
 }
 
 // ExecuteScript runs the script on vizier.
-func (v *VizierClient) ExecuteScript(ctx context.Context, pxl string, mux TableMuxer) (*ScriptResults, error) {
+func (v *VizierClient) ExecuteScript(ctx context.Context, pxl string, mux TableMuxer, opts ...ScriptOption) (*ScriptResults, error) {
+	so := &scriptOptions{}
+	for _, opt := range opts {
+		opt(so)
+	}
+	var encOpts, decOpts *vizierpb.ExecuteScriptRequest_EncryptionOptions
+	if v.keys != nil {
+		keys, err := v.keys.keysForSession()
+		if err != nil {
+			return nil, err
+		}
+		encOpts, decOpts = keys.encOpts, keys.decOpts
+	}
 	req := &vizierpb.ExecuteScriptRequest{
 		ClusterID:         v.vizierID,
-		QueryStr:          pxl,
-		EncryptionOptions: v.encOpts,
+		QueryStr:          so.applyToScript(pxl),
+		EncryptionOptions: encOpts,
 	}
 	origCtx := ctx
 	ctx, cancel := context.WithCancel(ctx)
@@ -54,13 +73,25 @@ func (v *VizierClient) ExecuteScript(ctx context.Context, pxl string, mux TableM
 	sr.c = res
 	sr.cancel = cancel
 	sr.tm = mux
-	sr.decOpts = v.decOpts
+	sr.encOpts = encOpts
+	sr.decOpts = decOpts
 	sr.v = v
+	sr.req = req
 	sr.origCtx = origCtx
+	sr.maxRows = so.maxRows
 
 	return sr, nil
 }
 
+// RotateEncryptionKeys replaces the key pair used to encrypt results. Scripts that are already
+// running keep decrypting with the keys they started with. This is a no-op if E2E encryption is disabled.
+func (v *VizierClient) RotateEncryptionKeys() error {
+	if v.keys == nil {
+		return nil
+	}
+	return v.keys.rotate()
+}
+
 // GenerateOTelScript generates an otel export script for a given pxl script that has px.display calls.
 func (v *VizierClient) GenerateOTelScript(ctx context.Context, pxl string) (string, error) {
 	req := &vizierpb.GenerateOTelScriptRequest{
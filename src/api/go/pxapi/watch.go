@@ -0,0 +1,184 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxapi
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const defaultWatchPollInterval = 10 * time.Second
+
+// WatchEventType is the kind of change that a WatchEvent reports.
+type WatchEventType string
+
+// Watch event types.
+const (
+	// WatchEventAdded is sent for each object when the watch starts, and when a new object appears.
+	WatchEventAdded WatchEventType = "Added"
+	// WatchEventModified is sent when the state of an object changes.
+	WatchEventModified WatchEventType = "Modified"
+	// WatchEventDeleted is sent when an object disappears.
+	WatchEventDeleted WatchEventType = "Deleted"
+)
+
+// WatchEvent is a single change to a watched object.
+type WatchEvent[T any] struct {
+	Type WatchEventType
+	// Object is the current state of the object, or its last known state for WatchEventDeleted.
+	Object *T
+	// Previous is the prior state of the object, and is only set for WatchEventModified.
+	Previous *T
+}
+
+// WatchOption configures a watch.
+type WatchOption func(o *watchOptions)
+
+type watchOptions struct {
+	pollInterval time.Duration
+}
+
+// WithPollInterval sets how often the watch checks for changes.
+func WithPollInterval(interval time.Duration) WatchOption {
+	return func(o *watchOptions) {
+		o.pollInterval = interval
+	}
+}
+
+// Watch streams the changes to a set of objects. The events channel is closed when the watch is
+// stopped, when its context is cancelled, or when listing the objects fails, after which Err reports
+// the reason that the watch ended.
+type Watch[T any] struct {
+	events chan *WatchEvent[T]
+	cancel context.CancelFunc
+
+	mu  sync.Mutex
+	err error
+}
+
+// listFunc lists the current state of the watched objects, keyed by their ID.
+type listFunc[T any] func(ctx context.Context) (map[string]*T, error)
+
+// newWatch lists the objects once, so that errors such as bad credentials are returned to the caller
+// right away, and then polls for changes in the background. changed reports whether an object's state
+// differs enough to send a WatchEventModified.
+func newWatch[T any](ctx context.Context, list listFunc[T], changed func(prev, cur *T) bool, opts []WatchOption) (*Watch[T], error) {
+	o := &watchOptions{pollInterval: defaultWatchPollInterval}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	cur, err := list(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	w := &Watch[T]{
+		events: make(chan *WatchEvent[T]),
+		cancel: cancel,
+	}
+	go w.run(ctx, list, changed, o.pollInterval, cur)
+	return w, nil
+}
+
+func (w *Watch[T]) run(ctx context.Context, list listFunc[T], changed func(prev, cur *T) bool, interval time.Duration, cur map[string]*T) {
+	defer close(w.events)
+	defer w.cancel()
+
+	prev := map[string]*T{}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if !w.sendDiff(ctx, prev, cur, changed) {
+			w.setErr(ctx.Err())
+			return
+		}
+		prev = cur
+
+		select {
+		case <-ctx.Done():
+			w.setErr(ctx.Err())
+			return
+		case <-ticker.C:
+		}
+
+		var err error
+		cur, err = list(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				err = ctx.Err()
+			}
+			w.setErr(err)
+			return
+		}
+	}
+}
+
+// sendDiff sends the events that turn prev into cur. It returns false if the context was cancelled.
+func (w *Watch[T]) sendDiff(ctx context.Context, prev, cur map[string]*T, changed func(prev, cur *T) bool) bool {
+	var events []*WatchEvent[T]
+	for id, obj := range cur {
+		p, ok := prev[id]
+		if !ok {
+			events = append(events, &WatchEvent[T]{Type: WatchEventAdded, Object: obj})
+		} else if changed(p, obj) {
+			events = append(events, &WatchEvent[T]{Type: WatchEventModified, Object: obj, Previous: p})
+		}
+	}
+	for id, obj := range prev {
+		if _, ok := cur[id]; !ok {
+			events = append(events, &WatchEvent[T]{Type: WatchEventDeleted, Object: obj})
+		}
+	}
+
+	for _, e := range events {
+		select {
+		case w.events <- e:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+func (w *Watch[T]) setErr(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.err = err
+}
+
+// Events returns the channel that the watch sends changes on.
+func (w *Watch[T]) Events() <-chan *WatchEvent[T] {
+	return w.events
+}
+
+// Stop ends the watch. Events that have not been received are dropped.
+func (w *Watch[T]) Stop() {
+	w.cancel()
+}
+
+// Err returns the reason that the watch ended, once the events channel is closed. It is
+// context.Canceled if the watch was stopped.
+func (w *Watch[T]) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
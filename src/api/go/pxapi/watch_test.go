@@ -0,0 +1,144 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxapi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"px.dev/pixie/src/api/go/pxapi/utils"
+	"px.dev/pixie/src/api/proto/cloudpb"
+)
+
+type fakeClusterInfoClient struct {
+	cloudpb.VizierClusterInfoClient
+
+	mu       sync.Mutex
+	clusters []*cloudpb.ClusterInfo
+	err      error
+}
+
+func (f *fakeClusterInfoClient) GetClusterInfo(ctx context.Context, in *cloudpb.GetClusterInfoRequest, opts ...grpc.CallOption) (*cloudpb.GetClusterInfoResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &cloudpb.GetClusterInfoResponse{Clusters: f.clusters}, nil
+}
+
+func (f *fakeClusterInfoClient) set(clusters []*cloudpb.ClusterInfo, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.clusters = clusters
+	f.err = err
+}
+
+const (
+	clusterID1 = "7ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	clusterID2 = "8ba7b810-9dad-11d1-80b4-00c04fd430c8"
+)
+
+func clusterInfo(id string, status cloudpb.ClusterStatus) *cloudpb.ClusterInfo {
+	return &cloudpb.ClusterInfo{
+		ID:            utils.ProtoFromUUIDStrOrNil(id),
+		ClusterName:   "cluster-" + id[:1],
+		VizierVersion: "0.1.0",
+		Status:        status,
+	}
+}
+
+func nextEvent(t *testing.T, w *Watch[VizierInfo]) *WatchEvent[VizierInfo] {
+	select {
+	case e, ok := <-w.Events():
+		require.True(t, ok, "watch ended: %v", w.Err())
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for watch event")
+	}
+	return nil
+}
+
+func TestWatchViziers(t *testing.T) {
+	cm := &fakeClusterInfoClient{}
+	cm.set([]*cloudpb.ClusterInfo{clusterInfo(clusterID1, cloudpb.CS_HEALTHY)}, nil)
+	c := &Client{cmClient: cm}
+
+	w, err := c.WatchViziers(context.Background(), WithPollInterval(10*time.Millisecond))
+	require.NoError(t, err)
+	defer w.Stop()
+
+	e := nextEvent(t, w)
+	assert.Equal(t, WatchEventAdded, e.Type)
+	assert.Equal(t, clusterID1, e.Object.ID)
+	assert.Equal(t, VizierStatusHealthy, e.Object.Status)
+
+	cm.set([]*cloudpb.ClusterInfo{
+		clusterInfo(clusterID1, cloudpb.CS_UNHEALTHY),
+		clusterInfo(clusterID2, cloudpb.CS_HEALTHY),
+	}, nil)
+	events := map[WatchEventType]*WatchEvent[VizierInfo]{}
+	for i := 0; i < 2; i++ {
+		e := nextEvent(t, w)
+		events[e.Type] = e
+	}
+	require.Contains(t, events, WatchEventModified)
+	assert.Equal(t, clusterID1, events[WatchEventModified].Object.ID)
+	assert.Equal(t, VizierStatusUnhealthy, events[WatchEventModified].Object.Status)
+	assert.Equal(t, VizierStatusHealthy, events[WatchEventModified].Previous.Status)
+	require.Contains(t, events, WatchEventAdded)
+	assert.Equal(t, clusterID2, events[WatchEventAdded].Object.ID)
+
+	cm.set([]*cloudpb.ClusterInfo{clusterInfo(clusterID2, cloudpb.CS_HEALTHY)}, nil)
+	e = nextEvent(t, w)
+	assert.Equal(t, WatchEventDeleted, e.Type)
+	assert.Equal(t, clusterID1, e.Object.ID)
+
+	w.Stop()
+	for range w.Events() {
+	}
+	assert.ErrorIs(t, w.Err(), context.Canceled)
+}
+
+func TestWatchViziers_ListError(t *testing.T) {
+	listErr := errors.New("unauthenticated")
+	cm := &fakeClusterInfoClient{}
+	cm.set(nil, listErr)
+	c := &Client{cmClient: cm}
+
+	_, err := c.WatchViziers(context.Background())
+	assert.ErrorIs(t, err, listErr)
+
+	cm.set([]*cloudpb.ClusterInfo{clusterInfo(clusterID1, cloudpb.CS_HEALTHY)}, nil)
+	w, err := c.WatchViziers(context.Background(), WithPollInterval(10*time.Millisecond))
+	require.NoError(t, err)
+	nextEvent(t, w)
+
+	cm.set(nil, listErr)
+	for range w.Events() {
+	}
+	assert.ErrorIs(t, w.Err(), listErr)
+}
@@ -0,0 +1,42 @@
+terraform {
+  required_providers {
+    pixie = {
+      source = "pixie-io/pixie"
+    }
+  }
+}
+
+# The API key is read from the PX_API_KEY environment variable.
+provider "pixie" {}
+
+resource "pixie_deploy_key" "prod" {
+  description = "prod clusters"
+}
+
+resource "pixie_api_key" "ci" {
+  description = "CI scripts"
+}
+
+resource "pixie_retention_plugin" "otel" {
+  plugin_id         = "otel"
+  custom_export_url = "otel-collector.example.com:4317"
+  configs = {
+    "Headers" = ""
+  }
+}
+
+resource "pixie_alert_route" "oncall" {
+  name        = "oncall"
+  plugin_id   = "pagerduty"
+  destination = var.pagerduty_routing_key
+}
+
+variable "pagerduty_routing_key" {
+  type      = string
+  sensitive = true
+}
+
+output "deploy_key" {
+  value     = pixie_deploy_key.prod.key
+  sensitive = true
+}
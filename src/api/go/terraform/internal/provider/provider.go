@@ -0,0 +1,139 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package provider implements a Terraform provider that manages the configuration of an org in
+// Pixie Cloud, using the Pixie Go API client.
+package provider
+
+import (
+	"context"
+	"os"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"px.dev/pixie/src/api/go/pxapi"
+)
+
+const (
+	apiKeyEnv    = "PX_API_KEY"
+	cloudAddrEnv = "PX_CLOUD_ADDR"
+)
+
+// New returns a function that creates the Pixie provider, for use with providerserver.Serve.
+func New(version string) func() provider.Provider {
+	return func() provider.Provider {
+		return &pixieProvider{version: version}
+	}
+}
+
+type pixieProvider struct {
+	version string
+}
+
+type pixieProviderModel struct {
+	APIKey    types.String `tfsdk:"api_key"`
+	CloudAddr types.String `tfsdk:"cloud_addr"`
+}
+
+func (p *pixieProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
+	resp.TypeName = "pixie"
+	resp.Version = p.version
+}
+
+func (p *pixieProvider) Schema(ctx context.Context, req provider.SchemaRequest, resp *provider.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Manages the configuration of a Pixie Cloud org.",
+		Attributes: map[string]schema.Attribute{
+			"api_key": schema.StringAttribute{
+				Description: "The API key used to authenticate with Pixie Cloud. Defaults to the " + apiKeyEnv + " environment variable.",
+				Optional:    true,
+				Sensitive:   true,
+			},
+			"cloud_addr": schema.StringAttribute{
+				Description: "The address of Pixie Cloud. Defaults to the " + cloudAddrEnv + " environment variable, or work.withpixie.ai:443.",
+				Optional:    true,
+			},
+		},
+	}
+}
+
+func (p *pixieProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
+	var config pixieProviderModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	apiKey := os.Getenv(apiKeyEnv)
+	if !config.APIKey.IsNull() {
+		apiKey = config.APIKey.ValueString()
+	}
+	if apiKey == "" {
+		resp.Diagnostics.AddAttributeError(path.Root("api_key"), "Missing Pixie API key",
+			"Set the api_key attribute or the "+apiKeyEnv+" environment variable. Keys can be created with `px api-key create`.")
+		return
+	}
+
+	opts := []pxapi.ClientOption{pxapi.WithAPIKey(apiKey)}
+	cloudAddr := os.Getenv(cloudAddrEnv)
+	if !config.CloudAddr.IsNull() {
+		cloudAddr = config.CloudAddr.ValueString()
+	}
+	if cloudAddr != "" {
+		opts = append(opts, pxapi.WithCloudAddr(cloudAddr))
+	}
+
+	client, err := pxapi.NewClient(ctx, opts...)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to create Pixie client", err.Error())
+		return
+	}
+	resp.ResourceData = client
+}
+
+func (p *pixieProvider) Resources(ctx context.Context) []func() resource.Resource {
+	return []func() resource.Resource{
+		newAPIKeyResource,
+		newDeployKeyResource,
+		newRetentionPluginResource,
+		newAlertRouteResource,
+	}
+}
+
+func (p *pixieProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
+	return nil
+}
+
+// clientFromProviderData gets the client created by Configure. The provider data is nil when the
+// provider has not been configured yet, such as during validation.
+func clientFromProviderData(data any, resp *resource.ConfigureResponse) *pxapi.Client {
+	if data == nil {
+		return nil
+	}
+	client, ok := data.(*pxapi.Client)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected provider data", "Expected a *pxapi.Client.")
+		return nil
+	}
+	return client
+}
@@ -0,0 +1,264 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package provider
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	gogotypes "github.com/gogo/protobuf/types"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"px.dev/pixie/src/api/go/pxapi"
+	"px.dev/pixie/src/api/go/pxapi/utils"
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/api/proto/uuidpb"
+)
+
+const testKeyID = "7ba7b810-9dad-11d1-80b4-00c04fd430c8"
+
+// fakeKeyStore stores the descriptions of keys in memory, by ID. It backs both the fake API key
+// manager and the fake deploy key manager.
+type fakeKeyStore struct {
+	keys map[string]string
+}
+
+func (f *fakeKeyStore) create(desc string) *uuidpb.UUID {
+	f.keys[testKeyID] = desc
+	return utils.ProtoFromUUIDStrOrNil(testKeyID)
+}
+
+func (f *fakeKeyStore) get(id *uuidpb.UUID) (string, error) {
+	desc, ok := f.keys[utils.ProtoToUUIDStr(id)]
+	if !ok {
+		return "", status.Error(codes.NotFound, "key not found")
+	}
+	return desc, nil
+}
+
+func (f *fakeKeyStore) delete(id *uuidpb.UUID) error {
+	if _, err := f.get(id); err != nil {
+		return err
+	}
+	delete(f.keys, utils.ProtoToUUIDStr(id))
+	return nil
+}
+
+var testCreatedAt = &gogotypes.Timestamp{Seconds: 1600000000}
+
+type fakeAPIKeyServer struct {
+	cloudpb.UnimplementedAPIKeyManagerServer
+
+	*fakeKeyStore
+}
+
+func (f *fakeAPIKeyServer) Create(ctx context.Context, req *cloudpb.CreateAPIKeyRequest) (*cloudpb.APIKey, error) {
+	return &cloudpb.APIKey{ID: f.create(req.Desc), Key: "px-api-key", Desc: req.Desc, CreatedAt: testCreatedAt}, nil
+}
+
+func (f *fakeAPIKeyServer) Get(ctx context.Context, req *cloudpb.GetAPIKeyRequest) (*cloudpb.GetAPIKeyResponse, error) {
+	desc, err := f.get(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &cloudpb.GetAPIKeyResponse{Key: &cloudpb.APIKey{ID: req.ID, Key: "px-api-key", Desc: desc, CreatedAt: testCreatedAt}}, nil
+}
+
+func (f *fakeAPIKeyServer) Delete(ctx context.Context, req *uuidpb.UUID) (*gogotypes.Empty, error) {
+	return &gogotypes.Empty{}, f.delete(req)
+}
+
+type fakeDeployKeyServer struct {
+	cloudpb.UnimplementedVizierDeploymentKeyManagerServer
+
+	*fakeKeyStore
+}
+
+func (f *fakeDeployKeyServer) Create(ctx context.Context, req *cloudpb.CreateDeploymentKeyRequest) (*cloudpb.DeploymentKey, error) {
+	return &cloudpb.DeploymentKey{ID: f.create(req.Desc), Key: "px-dep-key", Desc: req.Desc, CreatedAt: testCreatedAt}, nil
+}
+
+func (f *fakeDeployKeyServer) Get(ctx context.Context, req *cloudpb.GetDeploymentKeyRequest) (*cloudpb.GetDeploymentKeyResponse, error) {
+	desc, err := f.get(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &cloudpb.GetDeploymentKeyResponse{Key: &cloudpb.DeploymentKey{ID: req.ID, Key: "px-dep-key", Desc: desc, CreatedAt: testCreatedAt}}, nil
+}
+
+func (f *fakeDeployKeyServer) Delete(ctx context.Context, req *uuidpb.UUID) (*gogotypes.Empty, error) {
+	return &gogotypes.Empty{}, f.delete(req)
+}
+
+func newTestClient(t *testing.T, store *fakeKeyStore) *pxapi.Client {
+	lis := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer()
+	cloudpb.RegisterAPIKeyManagerServer(s, &fakeAPIKeyServer{fakeKeyStore: store})
+	cloudpb.RegisterVizierDeploymentKeyManagerServer(s, &fakeDeployKeyServer{fakeKeyStore: store})
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+
+	dialer := func(ctx context.Context, url string) (net.Conn, error) {
+		return lis.Dial()
+	}
+	c, err := pxapi.NewClient(context.Background(),
+		pxapi.WithCloudAddr("bufnet"),
+		pxapi.WithAPIKey("abc"),
+		pxapi.WithDialOptions(grpc.WithContextDialer(dialer), grpc.WithTransportCredentials(insecure.NewCredentials())))
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestProvider_Schemas(t *testing.T) {
+	ctx := context.Background()
+	p := New("test")()
+
+	var provResp provider.SchemaResponse
+	p.Schema(ctx, provider.SchemaRequest{}, &provResp)
+	require.False(t, provResp.Diagnostics.HasError(), provResp.Diagnostics)
+	assert.False(t, provResp.Schema.ValidateImplementation(ctx).HasError())
+
+	names := map[string]bool{}
+	for _, newResource := range p.Resources(ctx) {
+		r := newResource()
+		var mdResp resource.MetadataResponse
+		r.Metadata(ctx, resource.MetadataRequest{ProviderTypeName: "pixie"}, &mdResp)
+		assert.False(t, names[mdResp.TypeName], "duplicate resource %s", mdResp.TypeName)
+		names[mdResp.TypeName] = true
+
+		var resp resource.SchemaResponse
+		r.Schema(ctx, resource.SchemaRequest{}, &resp)
+		require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+		diags := resp.Schema.ValidateImplementation(ctx)
+		assert.False(t, diags.HasError(), "%s: %v", mdResp.TypeName, diags)
+	}
+	assert.Equal(t, map[string]bool{
+		"pixie_api_key":          true,
+		"pixie_deploy_key":       true,
+		"pixie_retention_plugin": true,
+		"pixie_alert_route":      true,
+	}, names)
+}
+
+func keySchema(t *testing.T, r resource.Resource) resource.SchemaResponse {
+	var resp resource.SchemaResponse
+	r.Schema(context.Background(), resource.SchemaRequest{}, &resp)
+	require.False(t, resp.Diagnostics.HasError())
+	return resp
+}
+
+func TestKeyResource_CRUD(t *testing.T) {
+	tests := []struct {
+		name        string
+		newResource func() resource.Resource
+		key         string
+	}{
+		{
+			name:        "api key",
+			newResource: newAPIKeyResource,
+			key:         "px-api-key",
+		},
+		{
+			name:        "deploy key",
+			newResource: newDeployKeyResource,
+			key:         "px-dep-key",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := &fakeKeyStore{keys: map[string]string{}}
+			r := test.newResource()
+			var cfgResp resource.ConfigureResponse
+			r.(resource.ResourceWithConfigure).Configure(ctx, resource.ConfigureRequest{ProviderData: newTestClient(t, store)}, &cfgResp)
+			require.False(t, cfgResp.Diagnostics.HasError())
+
+			s := keySchema(t, r).Schema
+			nullState := func() tfsdk.State {
+				return tfsdk.State{Schema: s, Raw: tftypes.NewValue(s.Type().TerraformType(ctx), nil)}
+			}
+
+			// Create the key.
+			plan := tfsdk.Plan{Schema: s, Raw: tftypes.NewValue(s.Type().TerraformType(ctx), nil)}
+			require.False(t, plan.Set(ctx, &keyResourceModel{
+				ID:          types.StringUnknown(),
+				Description: types.StringValue("ci"),
+				Key:         types.StringUnknown(),
+				CreatedAt:   types.StringUnknown(),
+			}).HasError())
+			createResp := resource.CreateResponse{State: nullState()}
+			r.Create(ctx, resource.CreateRequest{Plan: plan}, &createResp)
+			require.False(t, createResp.Diagnostics.HasError(), createResp.Diagnostics)
+			assert.Equal(t, map[string]string{testKeyID: "ci"}, store.keys)
+
+			var created keyResourceModel
+			require.False(t, createResp.State.Get(ctx, &created).HasError())
+			assert.Equal(t, keyResourceModel{
+				ID:          types.StringValue(testKeyID),
+				Description: types.StringValue("ci"),
+				Key:         types.StringValue(test.key),
+				CreatedAt:   types.StringValue("2020-09-13T12:26:40Z"),
+			}, created)
+
+			// Read the key back.
+			readResp := resource.ReadResponse{State: createResp.State}
+			r.Read(ctx, resource.ReadRequest{State: createResp.State}, &readResp)
+			require.False(t, readResp.Diagnostics.HasError(), readResp.Diagnostics)
+			var read keyResourceModel
+			require.False(t, readResp.State.Get(ctx, &read).HasError())
+			assert.Equal(t, created, read)
+
+			// Keys can't be updated in place.
+			updateResp := resource.UpdateResponse{State: createResp.State}
+			r.Update(ctx, resource.UpdateRequest{Plan: plan, State: createResp.State}, &updateResp)
+			assert.True(t, updateResp.Diagnostics.HasError())
+
+			// Delete the key.
+			deleteResp := resource.DeleteResponse{State: createResp.State}
+			r.Delete(ctx, resource.DeleteRequest{State: createResp.State}, &deleteResp)
+			require.False(t, deleteResp.Diagnostics.HasError(), deleteResp.Diagnostics)
+			assert.Empty(t, store.keys)
+
+			// A key that was deleted outside of Terraform is removed from the state.
+			readResp = resource.ReadResponse{State: createResp.State}
+			r.Read(ctx, resource.ReadRequest{State: createResp.State}, &readResp)
+			require.False(t, readResp.Diagnostics.HasError(), readResp.Diagnostics)
+			assert.True(t, readResp.State.Raw.IsNull())
+
+			// Deleting a key that is already gone succeeds.
+			deleteResp = resource.DeleteResponse{State: createResp.State}
+			r.Delete(ctx, resource.DeleteRequest{State: createResp.State}, &deleteResp)
+			assert.False(t, deleteResp.Diagnostics.HasError(), deleteResp.Diagnostics)
+		})
+	}
+}
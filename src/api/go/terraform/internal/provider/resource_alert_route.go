@@ -0,0 +1,201 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package provider
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"px.dev/pixie/src/api/go/pxapi"
+	"px.dev/pixie/src/api/go/pxapi/errdefs"
+)
+
+var _ resource.ResourceWithImportState = &alertRouteResource{}
+
+// alertRouteResource manages a route that sends the events from the alert rules on the org's
+// scripts to Slack or PagerDuty.
+type alertRouteResource struct {
+	client *pxapi.Client
+}
+
+type alertRouteResourceModel struct {
+	ID          types.String `tfsdk:"id"`
+	Name        types.String `tfsdk:"name"`
+	PluginID    types.String `tfsdk:"plugin_id"`
+	Destination types.String `tfsdk:"destination"`
+	Template    types.String `tfsdk:"template"`
+	ClusterID   types.String `tfsdk:"cluster_id"`
+	ScriptID    types.String `tfsdk:"script_id"`
+}
+
+func newAlertRouteResource() resource.Resource {
+	return &alertRouteResource{}
+}
+
+func (r *alertRouteResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_alert_route"
+}
+
+func (r *alertRouteResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "A route that sends the events from the alert rules on the org's scripts to an alert plugin.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Description:   "The ID of the route.",
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"name": schema.StringAttribute{
+				Description: "The name of the route.",
+				Required:    true,
+			},
+			"plugin_id": schema.StringAttribute{
+				Description:   "The alert plugin that events are sent to. Either \"slack\" or \"pagerduty\".",
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+				Validators:    []validator.String{stringvalidator.OneOf("slack", "pagerduty")},
+			},
+			"destination": schema.StringAttribute{
+				Description: "Where events are sent. For Slack, the incoming webhook URL. For PagerDuty, the Events API v2 routing key.",
+				Required:    true,
+				Sensitive:   true,
+			},
+			"template": schema.StringAttribute{
+				Description: "A Go text/template used to render the message. Defaults to the plugin's message.",
+				Optional:    true,
+				Computed:    true,
+				Default:     stringdefault.StaticString(""),
+			},
+			"cluster_id": schema.StringAttribute{
+				Description: "If set, only events from this cluster are sent to the route.",
+				Optional:    true,
+				Computed:    true,
+				Default:     stringdefault.StaticString(""),
+			},
+			"script_id": schema.StringAttribute{
+				Description: "If set, only events from this script are sent to the route.",
+				Optional:    true,
+				Computed:    true,
+				Default:     stringdefault.StaticString(""),
+			},
+		},
+	}
+}
+
+func (r *alertRouteResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.client = clientFromProviderData(req.ProviderData, resp)
+}
+
+func (m *alertRouteResourceModel) toRoute() *pxapi.AlertRoute {
+	return &pxapi.AlertRoute{
+		ID:          m.ID.ValueString(),
+		Name:        m.Name.ValueString(),
+		PluginID:    m.PluginID.ValueString(),
+		Destination: m.Destination.ValueString(),
+		Template:    m.Template.ValueString(),
+		ClusterID:   m.ClusterID.ValueString(),
+		ScriptID:    m.ScriptID.ValueString(),
+	}
+}
+
+func (m *alertRouteResourceModel) set(route *pxapi.AlertRoute) {
+	m.ID = types.StringValue(route.ID)
+	m.Name = types.StringValue(route.Name)
+	m.PluginID = types.StringValue(route.PluginID)
+	m.Destination = types.StringValue(route.Destination)
+	m.Template = types.StringValue(route.Template)
+	m.ClusterID = types.StringValue(route.ClusterID)
+	m.ScriptID = types.StringValue(route.ScriptID)
+}
+
+func (r *alertRouteResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan alertRouteResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	id, err := r.client.CreateAlertRoute(ctx, plan.toRoute())
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to create alert route", err.Error())
+		return
+	}
+	plan.ID = types.StringValue(id)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *alertRouteResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state alertRouteResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	route, err := r.client.GetAlertRoute(ctx, state.ID.ValueString())
+	if errors.Is(err, errdefs.ErrAlertRouteNotFound) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read alert route", err.Error())
+		return
+	}
+	state.set(route)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *alertRouteResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan alertRouteResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.client.UpdateAlertRoute(ctx, plan.toRoute()); err != nil {
+		resp.Diagnostics.AddError("Failed to update alert route", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *alertRouteResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state alertRouteResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.client.DeleteAlertRoute(ctx, state.ID.ValueString()); err != nil {
+		resp.Diagnostics.AddError("Failed to delete alert route", err.Error())
+	}
+}
+
+func (r *alertRouteResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+}
@@ -0,0 +1,224 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	gogotypes "github.com/gogo/protobuf/types"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/go/pxapi"
+	"px.dev/pixie/src/api/go/pxapi/utils"
+)
+
+var _ resource.ResourceWithImportState = &keyResource{}
+
+// keyInfo is the part of an API key or deploy key that the key resources manage.
+type keyInfo struct {
+	id        string
+	key       string
+	desc      string
+	createdAt *gogotypes.Timestamp
+}
+
+// keyResource manages a key in the org. API keys and deploy keys behave the same way, so both
+// resources are a keyResource with different client calls. Keys cannot be changed once created, so
+// any change replaces the key.
+type keyResource struct {
+	client *pxapi.Client
+
+	typeName    string
+	description string
+	create      func(ctx context.Context, c *pxapi.Client, desc string) (*keyInfo, error)
+	get         func(ctx context.Context, c *pxapi.Client, id string) (*keyInfo, error)
+	delete      func(ctx context.Context, c *pxapi.Client, id string) error
+}
+
+type keyResourceModel struct {
+	ID          types.String `tfsdk:"id"`
+	Description types.String `tfsdk:"description"`
+	Key         types.String `tfsdk:"key"`
+	CreatedAt   types.String `tfsdk:"created_at"`
+}
+
+func newAPIKeyResource() resource.Resource {
+	return &keyResource{
+		typeName:    "api_key",
+		description: "An API key, used to authenticate with Pixie Cloud as the user that created it.",
+		create: func(ctx context.Context, c *pxapi.Client, desc string) (*keyInfo, error) {
+			k, err := c.CreateAPIKey(ctx, desc)
+			if err != nil {
+				return nil, err
+			}
+			return &keyInfo{id: utils.ProtoToUUIDStr(k.ID), key: k.Key, desc: k.Desc, createdAt: k.CreatedAt}, nil
+		},
+		get: func(ctx context.Context, c *pxapi.Client, id string) (*keyInfo, error) {
+			k, err := c.GetAPIKey(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			return &keyInfo{id: utils.ProtoToUUIDStr(k.ID), key: k.Key, desc: k.Desc, createdAt: k.CreatedAt}, nil
+		},
+		delete: func(ctx context.Context, c *pxapi.Client, id string) error {
+			return c.DeleteAPIKey(ctx, id)
+		},
+	}
+}
+
+func newDeployKeyResource() resource.Resource {
+	return &keyResource{
+		typeName:    "deploy_key",
+		description: "A deploy key, used to register new Vizier clusters with the org.",
+		create: func(ctx context.Context, c *pxapi.Client, desc string) (*keyInfo, error) {
+			k, err := c.CreateDeployKey(ctx, desc)
+			if err != nil {
+				return nil, err
+			}
+			return &keyInfo{id: utils.ProtoToUUIDStr(k.ID), key: k.Key, desc: k.Desc, createdAt: k.CreatedAt}, nil
+		},
+		get: func(ctx context.Context, c *pxapi.Client, id string) (*keyInfo, error) {
+			k, err := c.GetDeployKey(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			return &keyInfo{id: utils.ProtoToUUIDStr(k.ID), key: k.Key, desc: k.Desc, createdAt: k.CreatedAt}, nil
+		},
+		delete: func(ctx context.Context, c *pxapi.Client, id string) error {
+			return c.DeleteDeployKey(ctx, id)
+		},
+	}
+}
+
+func (r *keyResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_" + r.typeName
+}
+
+func (r *keyResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: r.description,
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Description:   "The ID of the key.",
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"description": schema.StringAttribute{
+				Description:   "A description of what the key is used for.",
+				Optional:      true,
+				Computed:      true,
+				Default:       stringdefault.StaticString(""),
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"key": schema.StringAttribute{
+				Description:   "The value of the key.",
+				Computed:      true,
+				Sensitive:     true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"created_at": schema.StringAttribute{
+				Description:   "When the key was created, in RFC 3339 format.",
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+		},
+	}
+}
+
+func (r *keyResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.client = clientFromProviderData(req.ProviderData, resp)
+}
+
+func (m *keyResourceModel) set(k *keyInfo) {
+	m.ID = types.StringValue(k.id)
+	m.Key = types.StringValue(k.key)
+	m.Description = types.StringValue(k.desc)
+	m.CreatedAt = types.StringValue("")
+	if t, err := gogotypes.TimestampFromProto(k.createdAt); err == nil {
+		m.CreatedAt = types.StringValue(t.Format(time.RFC3339))
+	}
+}
+
+func (r *keyResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan keyResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	k, err := r.create(ctx, r.client, plan.Description.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError(fmt.Sprintf("Failed to create %s", r.typeName), err.Error())
+		return
+	}
+	plan.set(k)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *keyResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state keyResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	k, err := r.get(ctx, r.client, state.ID.ValueString())
+	if status.Code(err) == codes.NotFound {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError(fmt.Sprintf("Failed to read %s", r.typeName), err.Error())
+		return
+	}
+	state.set(k)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *keyResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	// Every configurable attribute requires replacement, so there is never anything to update in place.
+	resp.Diagnostics.AddError(fmt.Sprintf("Cannot update %s", r.typeName), "Keys cannot be changed once created.")
+}
+
+func (r *keyResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state keyResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := r.delete(ctx, r.client, state.ID.ValueString())
+	if err != nil && status.Code(err) != codes.NotFound {
+		resp.Diagnostics.AddError(fmt.Sprintf("Failed to delete %s", r.typeName), err.Error())
+	}
+}
+
+func (r *keyResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+}
@@ -0,0 +1,227 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package provider
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"px.dev/pixie/src/api/go/pxapi"
+	"px.dev/pixie/src/api/go/pxapi/errdefs"
+)
+
+var _ resource.ResourceWithImportState = &retentionPluginResource{}
+
+// retentionPluginResource manages the org's configuration of a long-term data retention plugin.
+// The plugin itself always exists, so creating the resource enables the plugin and destroying it
+// disables the plugin.
+type retentionPluginResource struct {
+	client *pxapi.Client
+}
+
+type retentionPluginResourceModel struct {
+	ID              types.String `tfsdk:"id"`
+	PluginID        types.String `tfsdk:"plugin_id"`
+	Enabled         types.Bool   `tfsdk:"enabled"`
+	Version         types.String `tfsdk:"version"`
+	Configs         types.Map    `tfsdk:"configs"`
+	CustomExportURL types.String `tfsdk:"custom_export_url"`
+	InsecureTLS     types.Bool   `tfsdk:"insecure_tls"`
+}
+
+func newRetentionPluginResource() resource.Resource {
+	return &retentionPluginResource{}
+}
+
+func (r *retentionPluginResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_retention_plugin"
+}
+
+func (r *retentionPluginResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "The org's configuration of a plugin that exports data for long-term retention.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Description:   "The ID of the plugin.",
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"plugin_id": schema.StringAttribute{
+				Description:   "The ID of the plugin, for example \"otel\".",
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"enabled": schema.BoolAttribute{
+				Description: "Whether the plugin is enabled. Enabling the plugin also enables its preset scripts.",
+				Optional:    true,
+				Computed:    true,
+				Default:     booldefault.StaticBool(true),
+			},
+			"version": schema.StringAttribute{
+				Description: "The version of the plugin to enable. Defaults to the latest version.",
+				Optional:    true,
+				Computed:    true,
+			},
+			"configs": schema.MapAttribute{
+				Description: "Values for the plugin's configurable fields, such as API keys, keyed by field name.",
+				ElementType: types.StringType,
+				Optional:    true,
+				Sensitive:   true,
+			},
+			"custom_export_url": schema.StringAttribute{
+				Description: "Overrides the plugin's default export URL, if the plugin allows it.",
+				Optional:    true,
+				Computed:    true,
+				Default:     stringdefault.StaticString(""),
+			},
+			"insecure_tls": schema.BoolAttribute{
+				Description: "Disables TLS verification when exporting, if the plugin allows it.",
+				Optional:    true,
+				Computed:    true,
+				Default:     booldefault.StaticBool(false),
+			},
+		},
+	}
+}
+
+func (r *retentionPluginResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.client = clientFromProviderData(req.ProviderData, resp)
+}
+
+// apply writes the planned configuration to the org, and reads back the enabled version.
+func (r *retentionPluginResource) apply(ctx context.Context, plan *retentionPluginResourceModel) error {
+	cfg := &pxapi.RetentionPluginConfig{
+		PluginID:        plan.PluginID.ValueString(),
+		Enabled:         plan.Enabled.ValueBool(),
+		CustomExportURL: plan.CustomExportURL.ValueString(),
+		InsecureTLS:     plan.InsecureTLS.ValueBool(),
+	}
+	if !plan.Version.IsUnknown() && !plan.Version.IsNull() {
+		cfg.Version = plan.Version.ValueString()
+	}
+	if !plan.Configs.IsNull() {
+		cfg.Configs = make(map[string]string)
+		if diags := plan.Configs.ElementsAs(ctx, &cfg.Configs, false); diags.HasError() {
+			return errors.New("configs must be a map of strings")
+		}
+	}
+	if err := r.client.UpdateRetentionPluginConfig(ctx, cfg); err != nil {
+		return err
+	}
+
+	cur, err := r.client.GetRetentionPluginConfig(ctx, cfg.PluginID)
+	if err != nil {
+		return err
+	}
+	plan.ID = types.StringValue(cfg.PluginID)
+	plan.Version = types.StringValue(cur.Version)
+	return nil
+}
+
+func (r *retentionPluginResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan retentionPluginResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.apply(ctx, &plan); err != nil {
+		resp.Diagnostics.AddError("Failed to configure retention plugin", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *retentionPluginResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state retentionPluginResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	cfg, err := r.client.GetRetentionPluginConfig(ctx, state.ID.ValueString())
+	if errors.Is(err, errdefs.ErrPluginNotFound) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read retention plugin", err.Error())
+		return
+	}
+
+	state.PluginID = types.StringValue(cfg.PluginID)
+	state.Enabled = types.BoolValue(cfg.Enabled)
+	// A disabled plugin has no configuration, so keep the configured values to show the drift on enabled
+	// alone.
+	if cfg.Enabled {
+		state.Version = types.StringValue(cfg.Version)
+		state.CustomExportURL = types.StringValue(cfg.CustomExportURL)
+		state.InsecureTLS = types.BoolValue(cfg.InsecureTLS)
+		if len(cfg.Configs) > 0 || !state.Configs.IsNull() {
+			configs, diags := types.MapValueFrom(ctx, types.StringType, cfg.Configs)
+			resp.Diagnostics.Append(diags...)
+			state.Configs = configs
+		}
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *retentionPluginResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan retentionPluginResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.apply(ctx, &plan); err != nil {
+		resp.Diagnostics.AddError("Failed to configure retention plugin", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *retentionPluginResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state retentionPluginResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := r.client.UpdateRetentionPluginConfig(ctx, &pxapi.RetentionPluginConfig{
+		PluginID: state.ID.ValueString(),
+		Enabled:  false,
+	})
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to disable retention plugin", err.Error())
+	}
+}
+
+func (r *retentionPluginResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("plugin_id"), req.ID)...)
+}
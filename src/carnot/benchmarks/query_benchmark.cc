@@ -0,0 +1,223 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Benchmarks end-to-end query latency and the time spent in each operator for queries shaped like
+// the ones the standard scripts run, over synthetic tables of increasing size.
+//
+// To compare two versions, run the benchmark on each and diff the JSON output, e.g. with
+// google benchmark's compare.py:
+//
+//   query_benchmark --benchmark_out=before.json --benchmark_out_format=json
+//   query_benchmark --benchmark_out=after.json --benchmark_out_format=json
+//   compare.py benchmarks before.json after.json
+//
+// The per-operator counters are named "<operator>_self_ns" and are averaged over iterations.
+
+#include <benchmark/benchmark.h>
+
+#include <limits>
+#include <map>
+#include <memory>
+#include <string>
+#include <vector>
+
+#include <absl/strings/strip.h>
+#include <absl/strings/substitute.h>
+#include <sole.hpp>
+
+#include "src/carnot/benchmarks/schemas.h"
+#include "src/carnot/benchmarks/synthetic_data.h"
+#include "src/carnot/carnot.h"
+#include "src/carnot/exec/local_grpc_result_server.h"
+#include "src/carnot/funcs/funcs.h"
+#include "src/carnot/udf/udf.h"
+#include "src/common/base/base.h"
+#include "src/common/benchmark/benchmark.h"
+
+DEFINE_string(schema_file, "",
+              "A text format table_store.schemapb.Schema whose relations replace the builtin "
+              "benchmark relations.");
+DEFINE_uint64(synthetic_data_seed, 42, "The seed for the synthetic data generator.");
+DEFINE_int64(synthetic_data_cardinality, 64,
+             "The number of distinct values for identifier-like columns.");
+
+namespace px {
+namespace carnot {
+namespace benchmarks {
+
+using exec::LocalGRPCResultSinkServer;
+
+constexpr char kHTTPErrorRateQuery[] = R"pxl(
+import px
+df = px.DataFrame(table='http_events', select=['time_', 'req_path', 'resp_status', 'latency'])
+df.failure = df.resp_status >= 400
+df = df.groupby(['req_path']).agg(
+    error_rate=('failure', px.mean),
+    latency=('latency', px.quantiles),
+    throughput=('latency', px.count),
+)
+px.display(df, '$0')
+)pxl";
+
+constexpr char kHTTPTimeseriesQuery[] = R"pxl(
+import px
+df = px.DataFrame(table='http_events', select=['time_', 'upid', 'latency', 'resp_body_size'])
+df.timestamp = px.bin(df.time_, 10 * 1000 * 1000 * 1000)
+df = df.groupby(['timestamp', 'upid']).agg(
+    latency_max=('latency', px.max),
+    bytes=('resp_body_size', px.sum),
+)
+px.display(df, '$0')
+)pxl";
+
+constexpr char kHTTPFilterQuery[] = R"pxl(
+import px
+df = px.DataFrame(table='http_events')
+df = df[df.resp_status >= 500]
+df = df[px.contains(df.req_path, '1')]
+df = df.head(100)
+px.display(df, '$0')
+)pxl";
+
+constexpr char kProcessStatsJoinQuery[] = R"pxl(
+import px
+procs = px.DataFrame(table='process_stats', select=['upid', 'cpu_utime_ns', 'rss_bytes'])
+procs = procs.groupby('upid').agg(
+    cpu=('cpu_utime_ns', px.sum),
+    rss=('rss_bytes', px.mean),
+)
+conns = px.DataFrame(table='conn_stats', select=['upid', 'bytes_sent', 'bytes_recv'])
+conns = conns.groupby('upid').agg(
+    sent=('bytes_sent', px.sum),
+    recv=('bytes_recv', px.sum),
+)
+df = procs.merge(conns, how='inner', left_on='upid', right_on='upid', suffixes=['', '_x'])
+px.display(df, '$0')
+)pxl";
+
+std::unique_ptr<Carnot> SetUpCarnot(std::shared_ptr<table_store::TableStore> table_store,
+                                    LocalGRPCResultSinkServer* server) {
+  auto func_registry = std::make_unique<px::carnot::udf::Registry>("default_registry");
+  funcs::RegisterFuncsOrDie(func_registry.get());
+  auto clients_config = std::make_unique<Carnot::ClientsConfig>(Carnot::ClientsConfig{
+      [server](const std::string& address, const std::string&) {
+        return server->StubGenerator(address);
+      },
+      [](grpc::ClientContext*) {},
+  });
+  auto server_config = std::make_unique<Carnot::ServerConfig>();
+  server_config->grpc_server_port = 0;
+
+  return px::carnot::Carnot::Create(sole::uuid4(), std::move(func_registry), table_store,
+                                    std::move(clients_config), std::move(server_config))
+      .ConsumeValueOrDie();
+}
+
+// Operator debug strings look like "Op:Map(...)", the counters are keyed by the "Map" part.
+std::string OperatorName(std::string_view debug_string) {
+  absl::ConsumePrefix(&debug_string, "Op:");
+  return std::string(debug_string.substr(0, debug_string.find_first_of("(<")));
+}
+
+// NOLINTNEXTLINE : runtime/references.
+void BM_Query(benchmark::State& state, std::vector<std::string> tables, const std::string& query) {
+  // The default table size limit would expire most of the larger tables before they are read.
+  if (gflags::GetCommandLineFlagInfoOrDie("table_store_table_size_limit").is_default) {
+    FLAGS_table_store_table_size_limit = std::numeric_limits<int32_t>::max();
+  }
+  auto relations = LoadRelations(FLAGS_schema_file).ConsumeValueOrDie();
+
+  SyntheticDataOptions opts;
+  opts.num_rows = state.range(0);
+  opts.seed = FLAGS_synthetic_data_seed;
+  opts.cardinality = FLAGS_synthetic_data_cardinality;
+
+  auto table_store = std::make_shared<table_store::TableStore>();
+  for (const auto& name : tables) {
+    auto it = relations.find(name);
+    CHECK(it != relations.end()) << absl::Substitute("Missing relation for table $0", name);
+    auto table = GenerateSyntheticTable(name, it->second, opts).ConsumeValueOrDie();
+    table_store->AddTable(name, table);
+  }
+
+  auto server = LocalGRPCResultSinkServer();
+  auto carnot = SetUpCarnot(table_store, &server);
+
+  int64_t bytes_processed = 0;
+  int64_t records_processed = 0;
+  std::map<std::string, int64_t> operator_self_time_ns;
+  int i = 0;
+  for (auto _ : state) {
+    auto query_with_table_name = absl::Substitute(query, "results_" + std::to_string(i));
+    auto s = carnot->ExecuteQuery(query_with_table_name, sole::uuid4(), CurrentTimeNS(),
+                                  /* analyze */ true);
+    if (!s.ok()) {
+      LOG(FATAL) << absl::Substitute("Benchmark query did not execute successfully: $0",
+                                     s.msg());
+    }
+
+    state.PauseTiming();
+    auto stats = server.exec_stats().ConsumeValueOrDie();
+    bytes_processed += stats.execution_stats().bytes_processed();
+    records_processed += stats.execution_stats().records_processed();
+    for (const auto& agent_stats : stats.agent_execution_stats()) {
+      for (const auto& op_stats : agent_stats.operator_execution_stats()) {
+        auto it = op_stats.extra_info().find("DebugString");
+        if (it == op_stats.extra_info().end()) {
+          continue;
+        }
+        operator_self_time_ns[OperatorName(it->second)] += op_stats.self_execution_time_ns();
+      }
+    }
+    server.ResetQueryResults();
+    state.ResumeTiming();
+    ++i;
+  }
+
+  state.SetBytesProcessed(bytes_processed);
+  state.SetItemsProcessed(records_processed);
+  for (const auto& [name, self_time_ns] : operator_self_time_ns) {
+    state.counters[name + "_self_ns"] =
+        benchmark::Counter(static_cast<double>(self_time_ns), benchmark::Counter::kAvgIterations);
+  }
+}
+
+BENCHMARK_CAPTURE(BM_Query, http_error_rate, {"http_events"}, kHTTPErrorRateQuery)
+    ->RangeMultiplier(8)
+    ->Range(1 << 10, 1 << 20)
+    ->Unit(benchmark::kMillisecond);
+
+BENCHMARK_CAPTURE(BM_Query, http_timeseries, {"http_events"}, kHTTPTimeseriesQuery)
+    ->RangeMultiplier(8)
+    ->Range(1 << 10, 1 << 20)
+    ->Unit(benchmark::kMillisecond);
+
+BENCHMARK_CAPTURE(BM_Query, http_filter, {"http_events"}, kHTTPFilterQuery)
+    ->RangeMultiplier(8)
+    ->Range(1 << 10, 1 << 20)
+    ->Unit(benchmark::kMillisecond);
+
+BENCHMARK_CAPTURE(BM_Query, process_stats_join, {"process_stats", "conn_stats"},
+                  kProcessStatsJoinQuery)
+    ->RangeMultiplier(8)
+    ->Range(1 << 10, 1 << 20)
+    ->Unit(benchmark::kMillisecond);
+
+}  // namespace benchmarks
+}  // namespace carnot
+}  // namespace px
@@ -0,0 +1,127 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#include "src/carnot/benchmarks/schemas.h"
+
+#include <google/protobuf/text_format.h>
+
+#include "src/common/base/file.h"
+#include "src/table_store/schemapb/schema.pb.h"
+
+namespace px {
+namespace carnot {
+namespace benchmarks {
+
+using table_store::schema::Relation;
+using types::DataType;
+using types::SemanticType;
+
+namespace {
+
+Relation HTTPEventsRelation() {
+  Relation r;
+  r.AddColumn(DataType::TIME64NS, "time_", SemanticType::ST_TIME_NS);
+  r.AddColumn(DataType::UINT128, "upid", SemanticType::ST_UPID);
+  r.AddColumn(DataType::STRING, "remote_addr", SemanticType::ST_IP_ADDRESS);
+  r.AddColumn(DataType::INT64, "remote_port", SemanticType::ST_PORT);
+  r.AddColumn(DataType::INT64, "trace_role", SemanticType::ST_NONE);
+  r.AddColumn(DataType::INT64, "major_version", SemanticType::ST_NONE);
+  r.AddColumn(DataType::INT64, "minor_version", SemanticType::ST_NONE);
+  r.AddColumn(DataType::INT64, "content_type", SemanticType::ST_NONE);
+  r.AddColumn(DataType::STRING, "req_headers", SemanticType::ST_NONE);
+  r.AddColumn(DataType::STRING, "req_method", SemanticType::ST_HTTP_REQ_METHOD);
+  r.AddColumn(DataType::STRING, "req_path", SemanticType::ST_NONE);
+  r.AddColumn(DataType::STRING, "req_body", SemanticType::ST_NONE);
+  r.AddColumn(DataType::INT64, "req_body_size", SemanticType::ST_BYTES);
+  r.AddColumn(DataType::STRING, "resp_headers", SemanticType::ST_NONE);
+  r.AddColumn(DataType::INT64, "resp_status", SemanticType::ST_HTTP_RESP_STATUS);
+  r.AddColumn(DataType::STRING, "resp_message", SemanticType::ST_HTTP_RESP_MESSAGE);
+  r.AddColumn(DataType::STRING, "resp_body", SemanticType::ST_NONE);
+  r.AddColumn(DataType::INT64, "resp_body_size", SemanticType::ST_BYTES);
+  r.AddColumn(DataType::INT64, "latency", SemanticType::ST_DURATION_NS);
+  return r;
+}
+
+Relation ProcessStatsRelation() {
+  Relation r;
+  r.AddColumn(DataType::TIME64NS, "time_", SemanticType::ST_TIME_NS);
+  r.AddColumn(DataType::UINT128, "upid", SemanticType::ST_UPID);
+  r.AddColumn(DataType::INT64, "major_faults", SemanticType::ST_NONE);
+  r.AddColumn(DataType::INT64, "minor_faults", SemanticType::ST_NONE);
+  r.AddColumn(DataType::INT64, "cpu_utime_ns", SemanticType::ST_DURATION_NS);
+  r.AddColumn(DataType::INT64, "cpu_ktime_ns", SemanticType::ST_DURATION_NS);
+  r.AddColumn(DataType::INT64, "num_threads", SemanticType::ST_NONE);
+  r.AddColumn(DataType::INT64, "vsize_bytes", SemanticType::ST_BYTES);
+  r.AddColumn(DataType::INT64, "rss_bytes", SemanticType::ST_BYTES);
+  r.AddColumn(DataType::INT64, "rchar_bytes", SemanticType::ST_BYTES);
+  r.AddColumn(DataType::INT64, "wchar_bytes", SemanticType::ST_BYTES);
+  r.AddColumn(DataType::INT64, "read_bytes", SemanticType::ST_BYTES);
+  r.AddColumn(DataType::INT64, "write_bytes", SemanticType::ST_BYTES);
+  return r;
+}
+
+Relation ConnStatsRelation() {
+  Relation r;
+  r.AddColumn(DataType::TIME64NS, "time_", SemanticType::ST_TIME_NS);
+  r.AddColumn(DataType::UINT128, "upid", SemanticType::ST_UPID);
+  r.AddColumn(DataType::STRING, "remote_addr", SemanticType::ST_IP_ADDRESS);
+  r.AddColumn(DataType::INT64, "remote_port", SemanticType::ST_PORT);
+  r.AddColumn(DataType::INT64, "trace_role", SemanticType::ST_NONE);
+  r.AddColumn(DataType::INT64, "addr_family", SemanticType::ST_NONE);
+  r.AddColumn(DataType::INT64, "protocol", SemanticType::ST_NONE);
+  r.AddColumn(DataType::BOOLEAN, "ssl", SemanticType::ST_NONE);
+  r.AddColumn(DataType::INT64, "conn_open", SemanticType::ST_NONE);
+  r.AddColumn(DataType::INT64, "conn_close", SemanticType::ST_NONE);
+  r.AddColumn(DataType::INT64, "conn_active", SemanticType::ST_NONE);
+  r.AddColumn(DataType::INT64, "bytes_sent", SemanticType::ST_BYTES);
+  r.AddColumn(DataType::INT64, "bytes_recv", SemanticType::ST_BYTES);
+  return r;
+}
+
+}  // namespace
+
+RelationMap BuiltinRelations() {
+  return {
+      {"http_events", HTTPEventsRelation()},
+      {"process_stats", ProcessStatsRelation()},
+      {"conn_stats", ConnStatsRelation()},
+  };
+}
+
+StatusOr<RelationMap> LoadRelations(const std::string& schema_file) {
+  RelationMap relations = BuiltinRelations();
+  if (schema_file.empty()) {
+    return relations;
+  }
+
+  PX_ASSIGN_OR_RETURN(std::string schema_str, ReadFileToString(schema_file));
+  table_store::schemapb::Schema schema_pb;
+  if (!google::protobuf::TextFormat::ParseFromString(schema_str, &schema_pb)) {
+    return error::InvalidArgument("Could not parse schema file $0", schema_file);
+  }
+  for (const auto& [name, relation_pb] : schema_pb.relation_map()) {
+    Relation relation;
+    PX_RETURN_IF_ERROR(relation.FromProto(&relation_pb));
+    relations[name] = relation;
+  }
+  return relations;
+}
+
+}  // namespace benchmarks
+}  // namespace carnot
+}  // namespace px
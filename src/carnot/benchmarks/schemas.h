@@ -0,0 +1,49 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#pragma once
+
+#include <map>
+#include <string>
+
+#include "src/common/base/base.h"
+#include "src/table_store/schema/relation.h"
+
+namespace px {
+namespace carnot {
+namespace benchmarks {
+
+using RelationMap = std::map<std::string, table_store::schema::Relation>;
+
+/**
+ * BuiltinRelations returns the relations of the tables that the query benchmarks read. They
+ * mirror the columns and semantic types of the Stirling tables with the same names, so benchmark
+ * data has the same shape as production data without depending on Stirling.
+ */
+RelationMap BuiltinRelations();
+
+/**
+ * LoadRelations reads the relations from a text format table_store.schemapb.Schema, such as
+ * one produced from the schemas of a running Vizier. Relations in the file replace the builtin
+ * relations of the same name, so the benchmarks can run against the exact schema of a release.
+ */
+StatusOr<RelationMap> LoadRelations(const std::string& schema_file);
+
+}  // namespace benchmarks
+}  // namespace carnot
+}  // namespace px
@@ -0,0 +1,308 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#include "src/carnot/benchmarks/synthetic_data.h"
+
+#include <algorithm>
+#include <functional>
+#include <random>
+#include <utility>
+#include <vector>
+
+#include <absl/random/zipf_distribution.h>
+#include <absl/strings/match.h>
+#include <absl/strings/substitute.h>
+
+#include "src/shared/types/arrow_adapter.h"
+#include "src/shared/types/type_utils.h"
+#include "src/shared/types/types.h"
+#include "src/shared/upid/upid.h"
+#include "src/table_store/schema/row_batch.h"
+
+namespace px {
+namespace carnot {
+namespace benchmarks {
+
+using table_store::Table;
+using table_store::schema::Relation;
+using table_store::schema::RowBatch;
+using table_store::schema::RowDescriptor;
+using types::DataType;
+using types::SemanticType;
+
+namespace {
+
+// A BatchGenerator produces the next num_rows values of a single column.
+using BatchGenerator = std::function<std::shared_ptr<arrow::Array>(int64_t num_rows)>;
+
+template <typename TValue, typename TFunc>
+BatchGenerator MakeGenerator(TFunc value_fn) {
+  return [value_fn = std::move(value_fn)](int64_t num_rows) mutable {
+    std::vector<TValue> data;
+    data.reserve(num_rows);
+    for (int64_t i = 0; i < num_rows; ++i) {
+      data.push_back(value_fn());
+    }
+    return types::ToArrow(data, arrow::default_memory_pool());
+  };
+}
+
+// Picks an element of the given values according to the given weights.
+template <typename T>
+class WeightedChoice {
+ public:
+  WeightedChoice(std::vector<T> values, const std::vector<double>& weights)
+      : values_(std::move(values)), dist_(weights.begin(), weights.end()) {}
+
+  const T& operator()(std::mt19937_64* rng) { return values_[dist_(*rng)]; }
+
+ private:
+  std::vector<T> values_;
+  std::discrete_distribution<size_t> dist_;
+};
+
+// Picks an element of a pool of values, favoring the first ones the way real traffic favors a
+// handful of hot services, pods and endpoints.
+template <typename T>
+class ZipfChoice {
+ public:
+  explicit ZipfChoice(std::vector<T> values)
+      : values_(std::move(values)), dist_(values_.size() - 1, 1.2) {}
+
+  const T& operator()(std::mt19937_64* rng) { return values_[dist_(*rng)]; }
+
+ private:
+  std::vector<T> values_;
+  absl::zipf_distribution<size_t> dist_;
+};
+
+std::vector<std::string> StringPool(std::string_view pattern, int64_t size) {
+  std::vector<std::string> pool;
+  pool.reserve(size);
+  for (int64_t i = 0; i < size; ++i) {
+    pool.push_back(absl::Substitute(pattern, i, i % 256, i / 256));
+  }
+  return pool;
+}
+
+std::string RandomText(std::mt19937_64* rng, int64_t mean_size) {
+  constexpr char kCharSet[] =
+      "0123456789"
+      "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+      "abcdefghijklmnopqrstuvwxyz"
+      " {}\":,";
+  std::uniform_int_distribution<size_t> char_dist(0, sizeof(kCharSet) - 2);
+  std::uniform_int_distribution<int64_t> size_dist(0, 2 * std::max<int64_t>(mean_size, 1));
+  std::string out(size_dist(*rng), ' ');
+  for (auto& c : out) {
+    c = kCharSet[char_dist(*rng)];
+  }
+  return out;
+}
+
+BatchGenerator TimeGenerator(std::shared_ptr<std::mt19937_64> rng,
+                             const SyntheticDataOptions& opts) {
+  std::uniform_int_distribution<int64_t> jitter(0, std::max<int64_t>(opts.time_step_ns, 1));
+  int64_t time_ns = opts.start_time_ns;
+  return MakeGenerator<types::Time64NSValue>([=]() mutable {
+    // Rows arrive in time order but not at a perfectly regular interval.
+    time_ns += jitter(*rng) + opts.time_step_ns / 2;
+    return types::Time64NSValue(time_ns);
+  });
+}
+
+BatchGenerator UPIDGenerator(std::shared_ptr<std::mt19937_64> rng,
+                             const SyntheticDataOptions& opts) {
+  std::vector<absl::uint128> upids;
+  for (int64_t i = 0; i < opts.cardinality; ++i) {
+    md::UPID upid(/*asid*/ 1 + i % 8, /*pid*/ 1000 + i, /*ts_ns*/ opts.start_time_ns - i);
+    upids.push_back(upid.value());
+  }
+  ZipfChoice<absl::uint128> choice(std::move(upids));
+  return MakeGenerator<types::UInt128Value>(
+      [=]() mutable { return types::UInt128Value(choice(rng.get())); });
+}
+
+BatchGenerator StringGenerator(std::shared_ptr<std::mt19937_64> rng, const std::string& name,
+                               SemanticType semantic_type, const SyntheticDataOptions& opts) {
+  auto pool_generator = [&](std::string_view pattern) {
+    ZipfChoice<std::string> choice(StringPool(pattern, opts.cardinality));
+    return MakeGenerator<types::StringValue>(
+        [=]() mutable { return types::StringValue(choice(rng.get())); });
+  };
+
+  switch (semantic_type) {
+    case SemanticType::ST_IP_ADDRESS:
+      return pool_generator("10.$2.$1.$0");
+    case SemanticType::ST_SERVICE_NAME:
+      return pool_generator("pl/service-$0");
+    case SemanticType::ST_POD_NAME:
+      return pool_generator("pl/pod-$0-5b9f7c6d8-x2k4q");
+    case SemanticType::ST_NODE_NAME:
+      return pool_generator("gke-node-$0");
+    case SemanticType::ST_NAMESPACE_NAME:
+      return pool_generator("namespace-$0");
+    case SemanticType::ST_CONTAINER_NAME:
+      return pool_generator("container-$0");
+    case SemanticType::ST_HTTP_REQ_METHOD: {
+      WeightedChoice<std::string> choice({"GET", "POST", "PUT", "DELETE", "PATCH"},
+                                         {70, 20, 5, 3, 2});
+      return MakeGenerator<types::StringValue>(
+          [=]() mutable { return types::StringValue(choice(rng.get())); });
+    }
+    case SemanticType::ST_HTTP_RESP_MESSAGE: {
+      WeightedChoice<std::string> choice(
+          {"OK", "Created", "Not Found", "Internal Server Error", "Service Unavailable"},
+          {85, 5, 6, 3, 1});
+      return MakeGenerator<types::StringValue>(
+          [=]() mutable { return types::StringValue(choice(rng.get())); });
+    }
+    default:
+      break;
+  }
+
+  if (absl::StrContains(name, "path")) {
+    return pool_generator("/api/v1/resource-$0");
+  }
+  if (absl::StrContains(name, "body") || absl::StrContains(name, "headers") ||
+      absl::StrContains(name, "query") || absl::StrContains(name, "msg")) {
+    int64_t body_size = opts.body_size;
+    return MakeGenerator<types::StringValue>(
+        [=]() mutable { return types::StringValue(RandomText(rng.get(), body_size)); });
+  }
+  return pool_generator("value-$0");
+}
+
+BatchGenerator Int64Generator(std::shared_ptr<std::mt19937_64> rng, const std::string& name,
+                              SemanticType semantic_type, const SyntheticDataOptions& opts) {
+  auto long_tailed = [&](double median) {
+    std::lognormal_distribution<double> dist(std::log(median), 1.0);
+    return MakeGenerator<types::Int64Value>(
+        [=]() mutable { return types::Int64Value(static_cast<int64_t>(dist(*rng))); });
+  };
+
+  switch (semantic_type) {
+    case SemanticType::ST_PORT: {
+      ZipfChoice<int64_t> choice({80, 443, 8080, 3306, 9092, 50051, 6379, 5432});
+      return MakeGenerator<types::Int64Value>(
+          [=]() mutable { return types::Int64Value(choice(rng.get())); });
+    }
+    case SemanticType::ST_HTTP_RESP_STATUS: {
+      WeightedChoice<int64_t> choice({200, 201, 404, 500, 503}, {85, 5, 6, 3, 1});
+      return MakeGenerator<types::Int64Value>(
+          [=]() mutable { return types::Int64Value(choice(rng.get())); });
+    }
+    case SemanticType::ST_DURATION_NS:
+      // A median latency of one millisecond.
+      return long_tailed(1000 * 1000);
+    case SemanticType::ST_BYTES:
+      return long_tailed(4096);
+    default:
+      break;
+  }
+
+  if (name == "trace_role") {
+    // kRoleClient and kRoleServer.
+    WeightedChoice<int64_t> choice({1, 2}, {50, 50});
+    return MakeGenerator<types::Int64Value>(
+        [=]() mutable { return types::Int64Value(choice(rng.get())); });
+  }
+  if (absl::EndsWith(name, "_ns")) {
+    return long_tailed(1000 * 1000);
+  }
+  if (absl::EndsWith(name, "_bytes")) {
+    return long_tailed(4096);
+  }
+  std::uniform_int_distribution<int64_t> dist(0, std::max<int64_t>(opts.cardinality - 1, 0));
+  return MakeGenerator<types::Int64Value>(
+      [=]() mutable { return types::Int64Value(dist(*rng)); });
+}
+
+StatusOr<BatchGenerator> ColumnGenerator(std::shared_ptr<std::mt19937_64> rng,
+                                         const std::string& name, DataType data_type,
+                                         SemanticType semantic_type,
+                                         const SyntheticDataOptions& opts) {
+  switch (data_type) {
+    case DataType::TIME64NS:
+      return TimeGenerator(std::move(rng), opts);
+    case DataType::UINT128:
+      return UPIDGenerator(std::move(rng), opts);
+    case DataType::STRING:
+      return StringGenerator(std::move(rng), name, semantic_type, opts);
+    case DataType::INT64:
+      return Int64Generator(std::move(rng), name, semantic_type, opts);
+    case DataType::FLOAT64: {
+      std::uniform_real_distribution<double> dist(0, 100);
+      return MakeGenerator<types::Float64Value>(
+          [=]() mutable { return types::Float64Value(dist(*rng)); });
+    }
+    case DataType::BOOLEAN: {
+      std::bernoulli_distribution dist(0.1);
+      return MakeGenerator<types::BoolValue>(
+          [=]() mutable { return types::BoolValue(dist(*rng)); });
+    }
+    default:
+      return error::InvalidArgument("Cannot generate data for column '$0' of type $1", name,
+                                    types::ToString(data_type));
+  }
+}
+
+}  // namespace
+
+StatusOr<std::shared_ptr<Table>> GenerateSyntheticTable(std::string_view table_name,
+                                                        const Relation& relation,
+                                                        const SyntheticDataOptions& opts) {
+  if (opts.num_rows < 0 || opts.rows_per_batch <= 0 || opts.cardinality <= 0) {
+    return error::InvalidArgument(
+        "num_rows must be non-negative, rows_per_batch and cardinality must be positive");
+  }
+
+  std::vector<BatchGenerator> generators;
+  for (size_t i = 0; i < relation.NumColumns(); ++i) {
+    // Each column has its own stream, so adding a column doesn't change the values of the others.
+    auto rng = std::make_shared<std::mt19937_64>(opts.seed + i);
+    PX_ASSIGN_OR_RETURN(auto generator,
+                        ColumnGenerator(std::move(rng), relation.GetColumnName(i),
+                                        relation.GetColumnType(i),
+                                        relation.GetColumnSemanticType(i), opts));
+    generators.push_back(std::move(generator));
+  }
+
+  auto table = Table::Create(table_name, relation);
+  RowDescriptor rd(relation.col_types());
+  for (int64_t offset = 0; offset < opts.num_rows; offset += opts.rows_per_batch) {
+    int64_t num_rows = std::min(opts.rows_per_batch, opts.num_rows - offset);
+    RowBatch rb(rd, num_rows);
+    for (auto& generator : generators) {
+      PX_RETURN_IF_ERROR(rb.AddColumn(generator(num_rows)));
+    }
+    PX_RETURN_IF_ERROR(table->WriteRowBatch(rb));
+  }
+
+  if (table->GetTableStats().batches_expired > 0) {
+    return error::ResourceUnavailable(
+        "Table $0 expired data while it was being generated, raise "
+        "--table_store_table_size_limit",
+        table_name);
+  }
+  return table;
+}
+
+}  // namespace benchmarks
+}  // namespace carnot
+}  // namespace px
@@ -0,0 +1,67 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#pragma once
+
+#include <memory>
+#include <string>
+
+#include "src/common/base/base.h"
+#include "src/table_store/schema/relation.h"
+#include "src/table_store/table/table.h"
+
+namespace px {
+namespace carnot {
+namespace benchmarks {
+
+/**
+ * SyntheticDataOptions controls the size and shape of a generated table.
+ */
+struct SyntheticDataOptions {
+  // The total number of rows to generate.
+  int64_t num_rows = 1024;
+  // The number of rows written per row batch.
+  int64_t rows_per_batch = 1024;
+  // The seed for the random number generator. The same seed always produces the same table.
+  uint64_t seed = 42;
+  // The timestamp of the first row and the average spacing between rows.
+  int64_t start_time_ns = 0;
+  int64_t time_step_ns = 1000 * 1000;
+  // The number of distinct values for identifier-like columns (UPIDs, addresses, names, paths).
+  int64_t cardinality = 64;
+  // The average length of free-form string columns such as request and response bodies.
+  int64_t body_size = 128;
+};
+
+/**
+ * GenerateSyntheticTable creates a table with the given relation and fills it with deterministic,
+ * pseudo-random data. Values are chosen from each column's semantic type (and, when that is not
+ * specific enough, its name) so that they look like what Stirling would collect: time columns
+ * increase monotonically, UPIDs and addresses come from a bounded pool, status codes and methods
+ * follow a realistic mix and latencies and byte counts are long tailed.
+ *
+ * Returns an error if the table store would have expired any of the generated rows, since the
+ * benchmark numbers would otherwise silently be for a smaller table.
+ */
+StatusOr<std::shared_ptr<table_store::Table>> GenerateSyntheticTable(
+    std::string_view table_name, const table_store::schema::Relation& relation,
+    const SyntheticDataOptions& opts);
+
+}  // namespace benchmarks
+}  // namespace carnot
+}  // namespace px
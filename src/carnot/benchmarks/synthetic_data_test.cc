@@ -0,0 +1,126 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#include "src/carnot/benchmarks/synthetic_data.h"
+
+#include <memory>
+#include <vector>
+
+#include <absl/container/flat_hash_set.h>
+
+#include "src/carnot/benchmarks/schemas.h"
+#include "src/common/testing/testing.h"
+#include "src/shared/types/arrow_adapter.h"
+
+namespace px {
+namespace carnot {
+namespace benchmarks {
+
+using table_store::Table;
+using table_store::schema::Relation;
+using table_store::schema::RowBatch;
+
+std::vector<std::unique_ptr<RowBatch>> ReadAll(Table* table, int64_t num_cols) {
+  std::vector<int64_t> cols;
+  for (int64_t i = 0; i < num_cols; ++i) {
+    cols.push_back(i);
+  }
+  std::vector<std::unique_ptr<RowBatch>> batches;
+  Table::Cursor cursor(table);
+  while (!cursor.Done()) {
+    batches.push_back(cursor.GetNextRowBatch(cols).ConsumeValueOrDie());
+  }
+  return batches;
+}
+
+TEST(GenerateSyntheticTableTest, rows_and_batches) {
+  auto relation = BuiltinRelations()["http_events"];
+  SyntheticDataOptions opts;
+  opts.num_rows = 2500;
+  opts.rows_per_batch = 1000;
+  ASSERT_OK_AND_ASSIGN(auto table, GenerateSyntheticTable("http_events", relation, opts));
+
+  auto batches = ReadAll(table.get(), relation.NumColumns());
+  ASSERT_EQ(3, batches.size());
+  EXPECT_EQ(1000, batches[0]->num_rows());
+  EXPECT_EQ(1000, batches[1]->num_rows());
+  EXPECT_EQ(500, batches[2]->num_rows());
+}
+
+TEST(GenerateSyntheticTableTest, deterministic) {
+  auto relation = BuiltinRelations()["conn_stats"];
+  SyntheticDataOptions opts;
+  opts.num_rows = 100;
+  ASSERT_OK_AND_ASSIGN(auto table1, GenerateSyntheticTable("conn_stats", relation, opts));
+  ASSERT_OK_AND_ASSIGN(auto table2, GenerateSyntheticTable("conn_stats", relation, opts));
+  opts.seed++;
+  ASSERT_OK_AND_ASSIGN(auto table3, GenerateSyntheticTable("conn_stats", relation, opts));
+
+  auto batch1 = ReadAll(table1.get(), relation.NumColumns());
+  auto batch2 = ReadAll(table2.get(), relation.NumColumns());
+  auto batch3 = ReadAll(table3.get(), relation.NumColumns());
+  for (size_t i = 0; i < relation.NumColumns(); ++i) {
+    EXPECT_TRUE(batch1[0]->ColumnAt(i)->Equals(batch2[0]->ColumnAt(i)));
+  }
+  EXPECT_FALSE(batch1[0]->ColumnAt(1)->Equals(batch3[0]->ColumnAt(1)));
+}
+
+TEST(GenerateSyntheticTableTest, semantic_values) {
+  auto relation = BuiltinRelations()["http_events"];
+  SyntheticDataOptions opts;
+  opts.num_rows = 1000;
+  opts.start_time_ns = 1000;
+  opts.cardinality = 4;
+  ASSERT_OK_AND_ASSIGN(auto table, GenerateSyntheticTable("http_events", relation, opts));
+  auto batches = ReadAll(table.get(), relation.NumColumns());
+  ASSERT_EQ(1, batches.size());
+  const auto& rb = batches[0];
+
+  auto time_col = rb->ColumnAt(relation.GetColumnIndex("time_")).get();
+  auto upid_col = rb->ColumnAt(relation.GetColumnIndex("upid")).get();
+  auto status_col = rb->ColumnAt(relation.GetColumnIndex("resp_status")).get();
+  auto method_col = rb->ColumnAt(relation.GetColumnIndex("req_method")).get();
+  auto latency_col = rb->ColumnAt(relation.GetColumnIndex("latency")).get();
+
+  int64_t prev_time = opts.start_time_ns;
+  absl::flat_hash_set<absl::uint128> upids;
+  for (int64_t i = 0; i < rb->num_rows(); ++i) {
+    int64_t time = types::GetValueFromArrowArray<types::TIME64NS>(time_col, i);
+    EXPECT_GT(time, prev_time);
+    prev_time = time;
+
+    upids.insert(types::GetValueFromArrowArray<types::UINT128>(upid_col, i));
+    EXPECT_THAT(types::GetValueFromArrowArray<types::INT64>(status_col, i),
+                ::testing::AnyOf(200, 201, 404, 500, 503));
+    EXPECT_THAT(types::GetValueFromArrowArray<types::STRING>(method_col, i),
+                ::testing::AnyOf("GET", "POST", "PUT", "DELETE", "PATCH"));
+    EXPECT_GE(types::GetValueFromArrowArray<types::INT64>(latency_col, i), 0);
+  }
+  EXPECT_LE(upids.size(), 4);
+}
+
+TEST(GenerateSyntheticTableTest, invalid_options) {
+  auto relation = BuiltinRelations()["process_stats"];
+  SyntheticDataOptions opts;
+  opts.rows_per_batch = 0;
+  EXPECT_NOT_OK(GenerateSyntheticTable("process_stats", relation, opts));
+}
+
+}  // namespace benchmarks
+}  // namespace carnot
+}  // namespace px
@@ -16,6 +16,7 @@
  * SPDX-License-Identifier: Apache-2.0
  */
 
+#include <algorithm>
 #include <memory>
 #include <string>
 
@@ -261,6 +262,38 @@ Status SendTransferResultChunkToOutgoingConns(
   return Status::OK();
 }
 
+namespace {
+
+void AddEvictedTable(const std::string& table, queryresultspb::DataLossStats* out) {
+  for (const auto& t : out->tables_with_evicted_data()) {
+    if (t == table) {
+      return;
+    }
+  }
+  out->add_tables_with_evicted_data(table);
+}
+
+// Merges the data loss seen by one execution graph on this agent into the agent's stats.
+void MergeDataLoss(const exec::DataLoss& loss, queryresultspb::DataLossStats* out) {
+  out->set_perf_buffer_lost_events(
+      std::max(out->perf_buffer_lost_events(), loss.perf_buffer_lost_events));
+  for (const auto& table : loss.tables_with_evicted_data) {
+    AddEvictedTable(table, out);
+  }
+}
+
+// Merges the data loss reported by another agent into this agent's stats. Perf buffer losses
+// on different agents are separate events, so they are summed.
+void MergeDataLoss(const queryresultspb::DataLossStats& loss, queryresultspb::DataLossStats* out) {
+  out->set_perf_buffer_lost_events(out->perf_buffer_lost_events() +
+                                   loss.perf_buffer_lost_events());
+  for (const auto& table : loss.tables_with_evicted_data()) {
+    AddEvictedTable(table, out);
+  }
+}
+
+}  // namespace
+
 Status SendFinalExecutionStatsToOutgoingConns(
     const sole::uuid& query_id,
     const absl::flat_hash_map<std::string, carnotpb::ResultSinkService::StubInterface*>&
@@ -286,6 +319,7 @@ Status SendFinalExecutionStatsToOutgoingConns(
   stats->mutable_timing()->set_execution_time_ns(agent_stats.execution_time_ns());
   stats->set_bytes_processed(total_bytes_processed);
   stats->set_records_processed(total_records_processed);
+  *(stats->mutable_data_loss()) = agent_stats.data_loss();
   return SendTransferResultChunkToOutgoingConns(outgoing_servers, add_auth_to_grpc_context_func,
                                                 std::move(req));
 }
@@ -367,6 +401,7 @@ Status CarnotImpl::ExecutePlan(const planpb::Plan& logical_plan, const sole::uui
             auto exec_stats = exec_graph.GetStats();
             bytes_processed += exec_stats.bytes_processed;
             rows_processed += exec_stats.rows_processed;
+            MergeDataLoss(exec_stats.data_loss, agent_operator_exec_stats.mutable_data_loss());
 
             if (analyze) {
               for (int64_t node_id : pf->dag().TopologicalSort()) {
@@ -445,6 +480,7 @@ Status CarnotImpl::ExecutePlan(const planpb::Plan& logical_plan, const sole::uui
   for (const auto& agent_stats : input_agent_stats) {
     bytes_processed += agent_stats.bytes_processed();
     rows_processed += agent_stats.records_processed();
+    MergeDataLoss(agent_stats.data_loss(), agent_operator_exec_stats.mutable_data_loss());
   }
 
   agent_operator_exec_stats.set_execution_time_ns(timer.ElapsedTime_us() * 1000);
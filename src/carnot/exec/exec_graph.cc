@@ -333,6 +333,7 @@ Status ExecutionGraph::Execute() {
 ExecutionStats ExecutionGraph::GetStats() const {
   int64_t bytes_processed = 0;
   int64_t rows_processed = 0;
+  DataLoss data_loss;
   for (int64_t src_id : sources_) {
     // Grab the nodes.
     auto res = nodes_.find(src_id);
@@ -342,8 +343,20 @@ ExecutionStats ExecutionGraph::GetStats() const {
     auto source_node = static_cast<SourceNode*>(node);
     bytes_processed += source_node->BytesProcessed();
     rows_processed += source_node->RowsProcessed();
+    // All of the sources read the same perf buffer loss counts, so take the largest count rather
+    // than summing them.
+    const DataLoss& source_loss = source_node->data_loss();
+    data_loss.perf_buffer_lost_events =
+        std::max(data_loss.perf_buffer_lost_events, source_loss.perf_buffer_lost_events);
+    for (const auto& table : source_loss.tables_with_evicted_data) {
+      if (std::find(data_loss.tables_with_evicted_data.begin(),
+                    data_loss.tables_with_evicted_data.end(),
+                    table) == data_loss.tables_with_evicted_data.end()) {
+        data_loss.tables_with_evicted_data.push_back(table);
+      }
+    }
   }
-  return ExecutionStats({bytes_processed, rows_processed});
+  return ExecutionStats({bytes_processed, rows_processed, std::move(data_loss)});
 }
 
 }  // namespace exec
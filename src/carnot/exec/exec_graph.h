@@ -46,6 +46,7 @@ namespace exec {
 struct ExecutionStats {
   int64_t bytes_processed;
   int64_t rows_processed;
+  DataLoss data_loss;
 };
 
 constexpr std::chrono::milliseconds kDefaultYieldTimeoutMS{1000};
@@ -38,6 +38,15 @@ enum class ExecNodeType : int8_t {
   kProcessingNode = 2,
 };
 
+// DataLoss describes the data in the time range read by a source that was lost before the
+// source could read it.
+struct DataLoss {
+  // The number of events dropped from the perf buffers during the time range.
+  int64_t perf_buffer_lost_events = 0;
+  // The tables whose data in the time range had already been evicted.
+  std::vector<std::string> tables_with_evicted_data;
+};
+
 struct ExecNodeStats {
   explicit ExecNodeStats(bool collect_stats) : collect_exec_stats(collect_stats) {}
   void AddOutputStats(const table_store::schema::RowBatch& rb) {
@@ -359,6 +368,7 @@ class SourceNode : public ExecNode {
   virtual bool NextBatchReady() = 0;
   int64_t BytesProcessed() const { return bytes_processed_; }
   int64_t RowsProcessed() const { return rows_processed_; }
+  const DataLoss& data_loss() const { return data_loss_; }
   Status SendEndOfStream(ExecState* exec_state) {
     // TODO(philkuz) this part is not tracked w/ the timer. Need to include this in NVI or cut
     // losses.
@@ -370,6 +380,7 @@ class SourceNode : public ExecNode {
  protected:
   int64_t rows_processed_ = 0;
   int64_t bytes_processed_ = 0;
+  DataLoss data_loss_;
 };
 
 /**
@@ -28,6 +28,7 @@
 #include <grpcpp/grpcpp.h>
 
 #include "src/carnot/exec/grpc_source_node.h"
+#include "src/carnot/exec/row_batch_compression.h"
 #include "src/common/base/base.h"
 #include "src/common/uuid/uuid.h"
 
@@ -43,7 +44,7 @@ GRPCRouter::SourceNodeTracker* GRPCRouter::GetSourceNodeTracker(QueryTracker* qu
 
 Status GRPCRouter::EnqueueRowBatch(QueryTracker* query_tracker,
                                    std::unique_ptr<carnotpb::TransferResultChunkRequest> req) {
-  if (!req->has_query_result() || !req->query_result().has_row_batch() ||
+  if (!req->has_query_result() || !HasRowBatch(req->query_result()) ||
       req->query_result().destination_case() !=
           carnotpb::TransferResultChunkRequest_SinkResult::DestinationCase::kGrpcSourceId) {
     return error::Internal(
@@ -138,7 +139,7 @@ void GRPCRouter::MarkResultStreamContextAsComplete(QueryTracker* query_tracker,
     }
     return ::grpc::Status::OK;
   }
-  if (req->has_query_result() && req->query_result().has_row_batch()) {
+  if (req->has_query_result() && HasRowBatch(req->query_result())) {
     state->stream_has_query_results = true;
     state->source_node_id = req->query_result().grpc_source_id();
     auto s = EnqueueRowBatch(state->query_tracker.get(), std::move(req));
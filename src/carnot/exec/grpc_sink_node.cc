@@ -18,19 +18,30 @@
 
 #include "src/carnot/exec/grpc_sink_node.h"
 
+#include <algorithm>
 #include <chrono>
 #include <memory>
 #include <string>
+#include <utility>
 #include <vector>
 
 #include <absl/strings/substitute.h>
 
 #include "src/carnot/carnotpb/carnot.pb.h"
+#include "src/carnot/exec/row_batch_compression.h"
 #include "src/carnot/planpb/plan.pb.h"
 #include "src/common/base/macros.h"
 #include "src/common/uuid/uuid_utils.h"
 #include "src/table_store/table_store.h"
 
+DEFINE_string(grpc_sink_compression, gflags::StringFromEnv("PL_GRPC_SINK_COMPRESSION", "lz4"),
+              "The codec used to compress row batches sent to other Carnot instances, such as "
+              "from PEMs to Kelvin. One of none, lz4 or zstd.");
+DEFINE_int64(grpc_sink_compression_min_bytes,
+             gflags::Int64FromEnv("PL_GRPC_SINK_COMPRESSION_MIN_BYTES", 4096),
+             "Row batches smaller than this are sent uncompressed, since compressing them "
+             "saves little.");
+
 namespace px {
 namespace carnot {
 namespace exec {
@@ -99,6 +110,12 @@ Status GRPCSinkNode::InitImpl(const plan::Operator& plan_node) {
   input_descriptor_ = std::make_unique<RowDescriptor>(input_descriptors_[0]);
   const auto* sink_plan_node = static_cast<const plan::GRPCSinkOperator*>(&plan_node);
   plan_node_ = std::make_unique<plan::GRPCSinkOperator>(*sink_plan_node);
+
+  // Only other Carnot instances can decompress the row batches.
+  if (plan_node_->has_grpc_source_id()) {
+    PX_ASSIGN_OR_RETURN(compression_, ParseRowBatchCompression(FLAGS_grpc_sink_compression));
+    compression_min_bytes_ = FLAGS_grpc_sink_compression_min_bytes;
+  }
   return Status::OK();
 }
 
@@ -233,10 +250,19 @@ static inline bool GetRowSizes(const RowBatch& rb, std::vector<int64_t>* string_
   return has_string_col;
 }
 
+size_t GRPCSinkNode::DesiredBatchSizeBytes() const {
+  float multiplier = 1.0f;
+  if (compression_ != carnotpb::ROW_BATCH_COMPRESSION_NONE && compression_ratio_ > 0) {
+    multiplier = std::min(1.0f / compression_ratio_, kMaxCompressedBatchSizeMultiplier);
+    multiplier = std::max(multiplier, 1.0f);
+  }
+  return static_cast<size_t>(max_batch_size_ * batch_size_factor_ * multiplier);
+}
+
 std::vector<int64_t> GRPCSinkNode::SplitBatchSizes(bool has_string_col,
                                                    const std::vector<int64_t>& string_col_row_sizes,
                                                    int64_t other_col_row_size) const {
-  int64_t desired_batch_size_bytes = static_cast<int64_t>(max_batch_size_ * batch_size_factor_);
+  int64_t desired_batch_size_bytes = static_cast<int64_t>(DesiredBatchSizeBytes());
   std::vector<int64_t> new_batches_num_rows;
   if (has_string_col) {
     int64_t batch_bytes = 0;
@@ -302,17 +328,46 @@ Status GRPCSinkNode::SplitAndSendBatch(ExecState* exec_state, const RowBatch& rb
   return ConsumeNextImplNoSplit(exec_state, *output_rb, parent_idx);
 }
 
+Status GRPCSinkNode::OptionallyCompressRowBatch(carnotpb::TransferResultChunkRequest* req) {
+  if (compression_ == carnotpb::ROW_BATCH_COMPRESSION_NONE) {
+    return Status::OK();
+  }
+  const auto& rb = req->query_result().row_batch();
+  size_t uncompressed_size = rb.ByteSizeLong();
+  if (uncompressed_size < compression_min_bytes_) {
+    return Status::OK();
+  }
+
+  carnotpb::CompressedRowBatch compressed;
+  PX_RETURN_IF_ERROR(CompressRowBatch(rb, compression_, &compressed));
+  float ratio = static_cast<float>(compressed.data().size()) / uncompressed_size;
+  compression_ratio_ =
+      kCompressionRatioWeight * ratio + (1 - kCompressionRatioWeight) * compression_ratio_;
+  // Setting the compressed row batch clears the uncompressed one, since they share a oneof.
+  *req->mutable_query_result()->mutable_compressed_row_batch() = std::move(compressed);
+  return Status::OK();
+}
+
 Status GRPCSinkNode::ConsumeNextImpl(ExecState* exec_state, const RowBatch& rb, size_t parent_idx) {
-  if (rb.NumBytes() > (max_batch_size_ * batch_size_factor_)) {
+  if (static_cast<size_t>(rb.NumBytes()) > DesiredBatchSizeBytes()) {
     return SplitAndSendBatch(exec_state, rb, parent_idx);
   }
   return ConsumeNextImplNoSplit(exec_state, rb, parent_idx);
 }
 
-Status GRPCSinkNode::ConsumeNextImplNoSplit(ExecState* exec_state, const RowBatch& rb, size_t) {
+Status GRPCSinkNode::ConsumeNextImplNoSplit(ExecState* exec_state, const RowBatch& rb,
+                                            size_t parent_idx) {
   PX_ASSIGN_OR_RETURN(auto req, RequestWithMetadata(plan_node_.get(), exec_state));
   // Serialize the RowBatch.
   PX_RETURN_IF_ERROR(rb.ToProto(req.mutable_query_result()->mutable_row_batch()));
+  PX_RETURN_IF_ERROR(OptionallyCompressRowBatch(&req));
+  if (req.ByteSizeLong() > max_batch_size_ && rb.num_rows() > 1 &&
+      static_cast<size_t>(rb.NumBytes()) > max_batch_size_ * batch_size_factor_) {
+    // The batch was only this large because the previous batches compressed well, but this one
+    // didn't. Fall back to the uncompressed batch size and split it again.
+    compression_ratio_ = 1.0f;
+    return SplitAndSendBatch(exec_state, rb, parent_idx);
+  }
 
   PX_RETURN_IF_ERROR(TryWriteRequest(exec_state, req));
 
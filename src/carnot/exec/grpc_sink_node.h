@@ -50,6 +50,11 @@ constexpr float kBatchSizeFactor = 0.9f;
 
 // Number of times to retry connecting to grpc before giving up.
 constexpr size_t kGRPCRetries = 3;
+// When row batches are compressed, batches are made larger so that the compressed batches are
+// closer to the max batch size. This bounds how much larger than the max batch size they can be.
+constexpr float kMaxCompressedBatchSizeMultiplier = 4.0f;
+// The weight of the latest batch in the moving average of the compression ratio.
+constexpr float kCompressionRatioWeight = 0.2f;
 
 class GRPCSinkNode : public SinkNode {
  public:
@@ -67,6 +72,10 @@ class GRPCSinkNode : public SinkNode {
   const std::chrono::time_point<std::chrono::system_clock>& testing_last_send_time() const {
     return last_send_time_;
   }
+  void testing_set_compression(carnotpb::RowBatchCompression compression, size_t min_bytes) {
+    compression_ = compression;
+    compression_min_bytes_ = min_bytes;
+  }
 
  protected:
   std::string DebugStringImpl() override;
@@ -90,6 +99,9 @@ class GRPCSinkNode : public SinkNode {
   Status StartConnectionWithRetries(ExecState* exec_state, size_t n_retries);
   Status CancelledByServer(ExecState* exec_state);
   Status TryWriteRequest(ExecState* exec_state, const carnotpb::TransferResultChunkRequest& req);
+  Status OptionallyCompressRowBatch(carnotpb::TransferResultChunkRequest* req);
+  // The size that row batches are split to before they are sent.
+  size_t DesiredBatchSizeBytes() const;
 
   bool cancelled_ = false;
 
@@ -107,6 +119,13 @@ class GRPCSinkNode : public SinkNode {
 
   size_t max_batch_size_;
   float batch_size_factor_;
+
+  // Row batches sent to other Carnot instances are compressed with this codec, if they are at
+  // least compression_min_bytes_ large.
+  carnotpb::RowBatchCompression compression_ = carnotpb::ROW_BATCH_COMPRESSION_NONE;
+  size_t compression_min_bytes_ = 0;
+  // The moving average of the compressed size over the uncompressed size of the row batches.
+  float compression_ratio_ = 1.0f;
 };
 
 }  // namespace exec
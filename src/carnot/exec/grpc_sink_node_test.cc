@@ -27,6 +27,7 @@
 
 #include "src/carnot/carnotpb/carnot.pb.h"
 #include "src/carnot/carnotpb/carnot_mock.grpc.pb.h"
+#include "src/carnot/exec/row_batch_compression.h"
 #include "src/carnot/exec/test_utils.h"
 #include "src/carnot/planpb/plan.pb.h"
 #include "src/carnot/planpb/test_proto.h"
@@ -427,6 +428,49 @@ TEST_F(GRPCSinkNodeTest, update_connection_time) {
   EXPECT_GT(after_flush_time, before_flush_time);
 }
 
+TEST_F(GRPCSinkNodeTest, compressed_internal_result) {
+  auto op_proto = planpb::testutils::CreateTestGRPCSink1PB();
+  auto plan_node = std::make_unique<plan::GRPCSinkOperator>(1);
+  auto s = plan_node->Init(op_proto.grpc_sink_op());
+  RowDescriptor input_rd({types::DataType::INT64});
+  RowDescriptor output_rd({types::DataType::INT64});
+
+  TransferResultChunkResponse resp;
+  resp.set_success(true);
+
+  TransferResultChunkRequest actual_proto;
+  auto writer = new grpc::testing::MockClientWriter<TransferResultChunkRequest>();
+  EXPECT_CALL(*writer, Write(_, _))
+      .Times(2)
+      .WillOnce(Return(true))  // Initiate stream
+      .WillOnce(DoAll(SaveArg<0>(&actual_proto), Return(true)));
+  EXPECT_CALL(*writer, WritesDone());
+  EXPECT_CALL(*writer, Finish()).WillOnce(Return(grpc::Status::OK));
+  EXPECT_CALL(*mock_, TransferResultChunkRaw(_, _))
+      .WillOnce(DoAll(SetArgPointee<1>(resp), Return(writer)));
+
+  auto tester = exec::ExecNodeTester<GRPCSinkNode, plan::GRPCSinkOperator>(
+      *plan_node, output_rd, {input_rd}, exec_state_.get());
+  tester.node()->testing_set_compression(carnotpb::ROW_BATCH_COMPRESSION_LZ4, /*min_bytes*/ 0);
+
+  std::vector<types::Int64Value> data(100, 1);
+  auto rb = RowBatchBuilder(output_rd, 100, /*eow*/ true, /*eos*/ true)
+                .AddColumn<types::Int64Value>(data)
+                .get();
+  tester.ConsumeNext(rb, 5, 0);
+  tester.Close();
+
+  ASSERT_TRUE(actual_proto.query_result().has_compressed_row_batch());
+  EXPECT_EQ(carnotpb::ROW_BATCH_COMPRESSION_LZ4,
+            actual_proto.query_result().compressed_row_batch().codec());
+
+  table_store::schemapb::RowBatchData expected;
+  ASSERT_OK(rb.ToProto(&expected));
+  table_store::schemapb::RowBatchData actual;
+  ASSERT_OK(DecompressRowBatch(actual_proto.query_result().compressed_row_batch(), &actual));
+  EXPECT_THAT(actual, EqualsProto(expected.DebugString()));
+}
+
 struct SplitTestCase {
   size_t max_batch_size = 4096;
   float batch_size_factor = 0.5;
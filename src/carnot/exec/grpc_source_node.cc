@@ -24,6 +24,7 @@
 
 #include <absl/strings/substitute.h>
 
+#include "src/carnot/exec/row_batch_compression.h"
 #include "src/carnot/planpb/plan.pb.h"
 
 namespace px {
@@ -72,12 +73,21 @@ Status GRPCSourceNode::PopRowBatch() {
         "Called GRPCSourceNode::OptionallyPopRowBatch but there was no available row batch in the "
         "queue.");
   }
-  if (!rb_request->has_query_result() || !rb_request->query_result().has_row_batch()) {
+  if (!rb_request->has_query_result() || !HasRowBatch(rb_request->query_result())) {
     return error::Internal(
         "GRPCSourceNode::PopRowBatch expected TransferResultChunkRequest to have RowBatch "
         "message.");
   }
 
+  // Compressed row batches are decompressed here, rather than when they are received, so that the
+  // work happens on the query's thread rather than on the GRPC server's.
+  if (rb_request->query_result().has_compressed_row_batch()) {
+    table_store::schemapb::RowBatchData rb_data;
+    PX_RETURN_IF_ERROR(
+        DecompressRowBatch(rb_request->query_result().compressed_row_batch(), &rb_data));
+    PX_ASSIGN_OR_RETURN(rb_, RowBatch::FromProto(rb_data));
+    return Status::OK();
+  }
   PX_ASSIGN_OR_RETURN(rb_, RowBatch::FromProto(rb_request->query_result().row_batch()));
   return Status::OK();
 }
@@ -19,14 +19,17 @@
 #include "src/carnot/exec/memory_source_node.h"
 #include "src/table_store/table/table.h"
 
+#include <algorithm>
 #include <limits>
 #include <string>
+#include <utility>
 #include <vector>
 
 #include <absl/strings/substitute.h>
 
 #include "src/carnot/planpb/plan.pb.h"
 #include "src/common/base/base.h"
+#include "src/shared/types/arrow_adapter.h"
 
 namespace px {
 namespace carnot {
@@ -35,6 +38,60 @@ namespace exec {
 using StartSpec = Table::Cursor::StartSpec;
 using StopSpec = Table::Cursor::StopSpec;
 
+namespace {
+
+// Stirling writes cumulative counts of the events lost from its perf buffers to this table.
+constexpr char kAgentStatsTable[] = "px_agent_stats";
+constexpr char kPerfBufferLostEventsCol[] = "perf_buffer_lost_events";
+
+// Returns the number of events that were lost from the perf buffers between the start and stop
+// times, or 0 if the agent does not record its own stats.
+StatusOr<int64_t> PerfBufferLostEvents(table_store::TableStore* table_store, int64_t start_time,
+                                       const StopSpec& stop_spec) {
+  Table* agent_stats = table_store->GetTable(kAgentStatsTable);
+  if (agent_stats == nullptr) {
+    return 0;
+  }
+  auto relation = agent_stats->GetRelation();
+  if (!relation.HasColumn(kPerfBufferLostEventsCol)) {
+    return 0;
+  }
+  std::vector<int64_t> cols = {relation.GetColumnIndex(kPerfBufferLostEventsCol)};
+
+  StartSpec start_spec;
+  start_spec.type = StartSpec::StartType::StartAtTime;
+  start_spec.start_time = start_time;
+  StopSpec agent_stats_stop_spec;
+  if (stop_spec.type == StopSpec::StopType::StopAtTime ||
+      stop_spec.type == StopSpec::StopType::StopAtTimeOrEndOfTable) {
+    agent_stats_stop_spec.type = StopSpec::StopType::StopAtTimeOrEndOfTable;
+    agent_stats_stop_spec.stop_time = stop_spec.stop_time;
+  } else {
+    agent_stats_stop_spec.type = StopSpec::StopType::CurrentEndOfTable;
+  }
+
+  // The counts are cumulative, so the loss in the time range is the difference between the
+  // largest and smallest counts within it.
+  int64_t min_lost = std::numeric_limits<int64_t>::max();
+  int64_t max_lost = 0;
+  Table::Cursor cursor(agent_stats, start_spec, agent_stats_stop_spec);
+  while (!cursor.Done() && cursor.NextBatchReady()) {
+    PX_ASSIGN_OR_RETURN(auto row_batch, cursor.GetNextRowBatch(cols));
+    auto col = row_batch->ColumnAt(0);
+    for (int64_t i = 0; i < row_batch->num_rows(); ++i) {
+      int64_t lost = types::GetValueFromArrowArray<types::INT64>(col.get(), i);
+      min_lost = std::min(min_lost, lost);
+      max_lost = std::max(max_lost, lost);
+    }
+  }
+  if (max_lost < min_lost) {
+    return 0;
+  }
+  return max_lost - min_lost;
+}
+
+}  // namespace
+
 std::string MemorySourceNode::DebugStringImpl() {
   return absl::Substitute("Exec::MemorySourceNode: <name: $0, output: $1>", plan_node_->TableName(),
                           output_descriptor_->DebugString());
@@ -85,11 +142,33 @@ Status MemorySourceNode::OpenImpl(ExecState* exec_state) {
       stop_spec.type = StopSpec::StopType::CurrentEndOfTable;
     }
   }
-  cursor_ = std::make_unique<Table::Cursor>(table_, start_spec, stop_spec);
+  if (plan_node_->HasStartTime()) {
+    ComputeDataLoss(exec_state, stop_spec);
+  }
+  cursor_ = std::make_unique<Table::Cursor>(table_, start_spec, std::move(stop_spec));
 
   return Status::OK();
 }
 
+void MemorySourceNode::ComputeDataLoss(ExecState* exec_state, const StopSpec& stop_spec) {
+  // If the table has already evicted data newer than the start time, then some of the data that
+  // the query asked for is missing.
+  auto table_stats = table_->GetTableStats();
+  if (table_stats.batches_expired > 0 && table_stats.min_time > plan_node_->start_time()) {
+    data_loss_.tables_with_evicted_data.push_back(plan_node_->TableName());
+  }
+
+  // Data loss accounting is best effort, and should never fail the query.
+  auto lost_or_s =
+      PerfBufferLostEvents(exec_state->table_store(), plan_node_->start_time(), stop_spec);
+  if (!lost_or_s.ok()) {
+    VLOG(1) << absl::Substitute("Failed to compute perf buffer losses for table $0: $1",
+                                plan_node_->TableName(), lost_or_s.msg());
+    return;
+  }
+  data_loss_.perf_buffer_lost_events = lost_or_s.ConsumeValueOrDie();
+}
+
 Status MemorySourceNode::CloseImpl(ExecState*) {
   stats()->AddExtraInfo("streaming", streaming_ ? "true" : "false");
   return Status::OK();
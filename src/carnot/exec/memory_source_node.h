@@ -56,6 +56,8 @@ class MemorySourceNode : public SourceNode {
 
  private:
   StatusOr<std::unique_ptr<RowBatch>> GetNextRowBatch(ExecState* exec_state);
+  // Records the data that was lost from the time range read by this source.
+  void ComputeDataLoss(ExecState* exec_state, const Table::Cursor::StopSpec& stop_spec);
   bool InfiniteStreamNextBatchReady();
   // Whether this memory source will stream future results.
   bool streaming_ = false;
@@ -0,0 +1,147 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#include "src/carnot/exec/row_batch_compression.h"
+
+#include <lz4.h>
+#include <zstd.h>
+
+#include <limits>
+#include <string>
+#include <utility>
+
+#include <absl/strings/ascii.h>
+
+namespace px {
+namespace carnot {
+namespace exec {
+
+// Favor speed over compression ratio, since the batches are compressed on the query path.
+constexpr int kZSTDCompressionLevel = 1;
+
+StatusOr<carnotpb::RowBatchCompression> ParseRowBatchCompression(std::string_view name) {
+  auto lower = absl::AsciiStrToLower(name);
+  if (lower == "none" || lower.empty()) {
+    return carnotpb::ROW_BATCH_COMPRESSION_NONE;
+  }
+  if (lower == "lz4") {
+    return carnotpb::ROW_BATCH_COMPRESSION_LZ4;
+  }
+  if (lower == "zstd") {
+    return carnotpb::ROW_BATCH_COMPRESSION_ZSTD;
+  }
+  return error::InvalidArgument(
+      "Unknown row batch compression '$0', expected one of none, lz4, zstd", name);
+}
+
+Status CompressRowBatch(const table_store::schemapb::RowBatchData& rb,
+                        carnotpb::RowBatchCompression codec,
+                        carnotpb::CompressedRowBatch* compressed) {
+  std::string serialized;
+  if (!rb.SerializeToString(&serialized)) {
+    return error::Internal("Failed to serialize row batch");
+  }
+  compressed->set_codec(codec);
+  compressed->set_uncompressed_size(serialized.size());
+  std::string* out = compressed->mutable_data();
+
+  switch (codec) {
+    case carnotpb::ROW_BATCH_COMPRESSION_NONE:
+      *out = std::move(serialized);
+      return Status::OK();
+    case carnotpb::ROW_BATCH_COMPRESSION_LZ4: {
+      if (serialized.size() > static_cast<size_t>(LZ4_MAX_INPUT_SIZE)) {
+        return error::InvalidArgument("Row batch of $0 bytes is too large to compress with LZ4",
+                                      serialized.size());
+      }
+      int src_size = static_cast<int>(serialized.size());
+      out->resize(LZ4_compressBound(src_size));
+      int n = LZ4_compress_default(serialized.data(), out->data(), src_size,
+                                   static_cast<int>(out->size()));
+      if (n <= 0) {
+        return error::Internal("Failed to compress row batch with LZ4");
+      }
+      out->resize(n);
+      return Status::OK();
+    }
+    case carnotpb::ROW_BATCH_COMPRESSION_ZSTD: {
+      out->resize(ZSTD_compressBound(serialized.size()));
+      size_t n = ZSTD_compress(out->data(), out->size(), serialized.data(), serialized.size(),
+                               kZSTDCompressionLevel);
+      if (ZSTD_isError(n)) {
+        return error::Internal("Failed to compress row batch with zstd: $0", ZSTD_getErrorName(n));
+      }
+      out->resize(n);
+      return Status::OK();
+    }
+    default:
+      return error::InvalidArgument("Unsupported row batch compression $0",
+                                    carnotpb::RowBatchCompression_Name(codec));
+  }
+}
+
+Status DecompressRowBatch(const carnotpb::CompressedRowBatch& compressed,
+                          table_store::schemapb::RowBatchData* rb) {
+  const std::string& in = compressed.data();
+  if (compressed.uncompressed_size() < 0 ||
+      compressed.uncompressed_size() > std::numeric_limits<int>::max()) {
+    return error::InvalidArgument("Invalid uncompressed row batch size $0",
+                                  compressed.uncompressed_size());
+  }
+  std::string serialized;
+
+  switch (compressed.codec()) {
+    case carnotpb::ROW_BATCH_COMPRESSION_NONE:
+      serialized = in;
+      break;
+    case carnotpb::ROW_BATCH_COMPRESSION_LZ4: {
+      serialized.resize(compressed.uncompressed_size());
+      int n = LZ4_decompress_safe(in.data(), serialized.data(), static_cast<int>(in.size()),
+                                  static_cast<int>(serialized.size()));
+      if (n < 0 || static_cast<size_t>(n) != serialized.size()) {
+        return error::InvalidArgument("Failed to decompress LZ4 row batch");
+      }
+      break;
+    }
+    case carnotpb::ROW_BATCH_COMPRESSION_ZSTD: {
+      serialized.resize(compressed.uncompressed_size());
+      size_t n = ZSTD_decompress(serialized.data(), serialized.size(), in.data(), in.size());
+      if (ZSTD_isError(n)) {
+        return error::InvalidArgument("Failed to decompress zstd row batch: $0",
+                                      ZSTD_getErrorName(n));
+      }
+      if (n != serialized.size()) {
+        return error::InvalidArgument(
+            "Failed to decompress zstd row batch: expected $0 bytes, got $1", serialized.size(), n);
+      }
+      break;
+    }
+    default:
+      return error::InvalidArgument("Unsupported row batch compression $0",
+                                    carnotpb::RowBatchCompression_Name(compressed.codec()));
+  }
+
+  if (!rb->ParseFromString(serialized)) {
+    return error::InvalidArgument("Failed to parse decompressed row batch");
+  }
+  return Status::OK();
+}
+
+}  // namespace exec
+}  // namespace carnot
+}  // namespace px
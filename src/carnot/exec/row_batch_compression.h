@@ -0,0 +1,59 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#pragma once
+
+#include <string>
+#include <string_view>
+
+#include "src/carnot/carnotpb/carnot.pb.h"
+#include "src/common/base/base.h"
+#include "src/table_store/schemapb/schema.pb.h"
+
+namespace px {
+namespace carnot {
+namespace exec {
+
+/**
+ * @brief Parses the name of a row batch compression codec: none, lz4 or zstd.
+ */
+StatusOr<carnotpb::RowBatchCompression> ParseRowBatchCompression(std::string_view name);
+
+/**
+ * @brief Serializes and compresses the row batch with the given codec.
+ */
+Status CompressRowBatch(const table_store::schemapb::RowBatchData& rb,
+                        carnotpb::RowBatchCompression codec,
+                        carnotpb::CompressedRowBatch* compressed);
+
+/**
+ * @brief Decompresses and parses a row batch compressed by CompressRowBatch.
+ */
+Status DecompressRowBatch(const carnotpb::CompressedRowBatch& compressed,
+                          table_store::schemapb::RowBatchData* rb);
+
+/**
+ * @brief Returns whether the sink result contains row batch data, either compressed or not.
+ */
+inline bool HasRowBatch(const carnotpb::TransferResultChunkRequest::SinkResult& result) {
+  return result.has_row_batch() || result.has_compressed_row_batch();
+}
+
+}  // namespace exec
+}  // namespace carnot
+}  // namespace px
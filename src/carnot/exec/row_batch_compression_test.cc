@@ -0,0 +1,94 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#include "src/carnot/exec/row_batch_compression.h"
+
+#include <string>
+#include <vector>
+
+#include <gtest/gtest.h>
+
+#include "src/carnot/exec/test_utils.h"
+#include "src/common/testing/testing.h"
+#include "src/shared/types/types.h"
+
+namespace px {
+namespace carnot {
+namespace exec {
+
+using table_store::schema::RowDescriptor;
+
+class RowBatchCompressionTest : public ::testing::TestWithParam<carnotpb::RowBatchCompression> {
+ protected:
+  table_store::schemapb::RowBatchData TestRowBatch() {
+    RowDescriptor rd({types::DataType::INT64, types::DataType::STRING});
+    std::vector<types::Int64Value> ints(1000, 42);
+    std::vector<types::StringValue> strs(1000, "a string that compresses well");
+    auto rb = RowBatchBuilder(rd, 1000, /*eow*/ true, /*eos*/ true)
+                  .AddColumn<types::Int64Value>(ints)
+                  .AddColumn<types::StringValue>(strs)
+                  .get();
+    table_store::schemapb::RowBatchData rb_data;
+    EXPECT_OK(rb.ToProto(&rb_data));
+    return rb_data;
+  }
+};
+
+TEST_P(RowBatchCompressionTest, round_trip) {
+  auto rb_data = TestRowBatch();
+
+  carnotpb::CompressedRowBatch compressed;
+  ASSERT_OK(CompressRowBatch(rb_data, GetParam(), &compressed));
+  EXPECT_EQ(GetParam(), compressed.codec());
+  EXPECT_EQ(rb_data.ByteSizeLong(), compressed.uncompressed_size());
+  if (GetParam() != carnotpb::ROW_BATCH_COMPRESSION_NONE) {
+    EXPECT_LT(compressed.data().size(), rb_data.ByteSizeLong());
+  }
+
+  table_store::schemapb::RowBatchData decompressed;
+  ASSERT_OK(DecompressRowBatch(compressed, &decompressed));
+  EXPECT_EQ(rb_data.SerializeAsString(), decompressed.SerializeAsString());
+}
+
+TEST_P(RowBatchCompressionTest, corrupt_data) {
+  if (GetParam() == carnotpb::ROW_BATCH_COMPRESSION_NONE) {
+    GTEST_SKIP() << "Uncompressed batches are only checked by the proto parser.";
+  }
+  carnotpb::CompressedRowBatch compressed;
+  ASSERT_OK(CompressRowBatch(TestRowBatch(), GetParam(), &compressed));
+  compressed.set_data(compressed.data().substr(0, compressed.data().size() / 2));
+
+  table_store::schemapb::RowBatchData decompressed;
+  EXPECT_NOT_OK(DecompressRowBatch(compressed, &decompressed));
+}
+
+INSTANTIATE_TEST_SUITE_P(Codecs, RowBatchCompressionTest,
+                         ::testing::Values(carnotpb::ROW_BATCH_COMPRESSION_NONE,
+                                           carnotpb::ROW_BATCH_COMPRESSION_LZ4,
+                                           carnotpb::ROW_BATCH_COMPRESSION_ZSTD));
+
+TEST(ParseRowBatchCompressionTest, names) {
+  EXPECT_OK_AND_EQ(ParseRowBatchCompression("none"), carnotpb::ROW_BATCH_COMPRESSION_NONE);
+  EXPECT_OK_AND_EQ(ParseRowBatchCompression("lz4"), carnotpb::ROW_BATCH_COMPRESSION_LZ4);
+  EXPECT_OK_AND_EQ(ParseRowBatchCompression("zstd"), carnotpb::ROW_BATCH_COMPRESSION_ZSTD);
+  EXPECT_NOT_OK(ParseRowBatchCompression("gzip"));
+}
+
+}  // namespace exec
+}  // namespace carnot
+}  // namespace px
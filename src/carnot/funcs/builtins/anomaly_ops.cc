@@ -0,0 +1,43 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#include "src/carnot/funcs/builtins/anomaly_ops.h"
+#include "src/carnot/udf/registry.h"
+#include "src/common/base/base.h"
+
+namespace px {
+namespace carnot {
+namespace builtins {
+
+void RegisterAnomalyOpsOrDie(udf::Registry* registry) {
+  CHECK(registry != nullptr);
+  registry->RegisterOrDie<StddevUDA<types::Int64Value>>("stddev");
+  registry->RegisterOrDie<StddevUDA<types::Float64Value>>("stddev");
+  registry->RegisterOrDie<EWMAZScoreUDA<types::Int64Value>>("ewma_zscore");
+  registry->RegisterOrDie<EWMAZScoreUDA<types::Float64Value>>("ewma_zscore");
+  registry->RegisterOrDie<ZScoreUDF<types::Int64Value>>("zscore");
+  registry->RegisterOrDie<ZScoreUDF<types::Float64Value>>("zscore");
+  registry->RegisterOrDie<RobustZScoreUDF<types::Int64Value>>("robust_zscore");
+  registry->RegisterOrDie<RobustZScoreUDF<types::Float64Value>>("robust_zscore");
+  registry->RegisterOrDie<HourOfDayUDF>("hour_of_day");
+  registry->RegisterOrDie<DayOfWeekUDF>("day_of_week");
+}
+
+}  // namespace builtins
+}  // namespace carnot
+}  // namespace px
@@ -0,0 +1,334 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#pragma once
+
+#include <algorithm>
+#include <cmath>
+#include <cstring>
+#include <utility>
+#include <vector>
+
+#include "src/carnot/udf/registry.h"
+#include "src/carnot/udf/type_inference.h"
+#include "src/common/base/error.h"
+#include "src/shared/types/types.h"
+
+namespace px {
+namespace carnot {
+namespace builtins {
+
+/**
+ * The UDFs and UDAs in this file score values against a baseline of the same metric, so that
+ * alerting scripts can find values that are unusual for a service. Baselines are computed with
+ * aggregates (px.mean and px.stddev, px.quantiles and px.mad), grouped by px.hour_of_day or
+ * px.day_of_week for seasonal metrics, and joined back to the values that are scored.
+ */
+
+template <typename TArg>
+class StddevUDA : public udf::UDA {
+ public:
+  // Update and Merge use Welford's and Chan's algorithms, which are numerically stable.
+  void Update(FunctionContext*, TArg arg) {
+    double val = static_cast<double>(arg.val);
+    info_.count++;
+    double delta = val - info_.mean;
+    info_.mean += delta / info_.count;
+    info_.m2 += delta * (val - info_.mean);
+  }
+  void Merge(FunctionContext*, const StddevUDA& other) {
+    if (other.info_.count == 0) {
+      return;
+    }
+    double count = info_.count + other.info_.count;
+    double delta = other.info_.mean - info_.mean;
+    info_.mean += delta * other.info_.count / count;
+    info_.m2 += other.info_.m2 + delta * delta * info_.count * other.info_.count / count;
+    info_.count += other.info_.count;
+  }
+  Float64Value Finalize(FunctionContext*) {
+    if (info_.count < 2) {
+      return 0.0;
+    }
+    return std::sqrt(info_.m2 / (info_.count - 1));
+  }
+
+  StringValue Serialize(FunctionContext*) {
+    return StringValue(reinterpret_cast<char*>(&info_), sizeof(info_));
+  }
+
+  Status Deserialize(FunctionContext*, const StringValue& data) {
+    std::memcpy(&info_, data.data(), sizeof(info_));
+    return Status::OK();
+  }
+
+  static udf::InfRuleVec SemanticInferenceRules() {
+    return {udf::InheritTypeFromArgs<StddevUDA>::Create({types::ST_BYTES, types::ST_DURATION_NS})};
+  }
+
+  static udf::UDADocBuilder Doc() {
+    return udf::UDADocBuilder("Calculate the sample standard deviation.")
+        .Details(
+            "Calculates the sample standard deviation of the aggregated data. Use it with "
+            "`px.mean` and `px.zscore` to find values that are far from their baseline.")
+        .Example(R"doc(
+        | df = df.groupby('service').agg(
+        |     latency_mean=('latency', px.mean),
+        |     latency_stddev=('latency', px.stddev),
+        | )
+        )doc")
+        .Arg("arg", "The data to calculate the standard deviation of.")
+        .Returns("The standard deviation of the data, or 0 if there are less than 2 values.");
+  }
+
+ protected:
+  struct StddevInfo {
+    uint64_t count = 0;
+    double mean = 0;
+    double m2 = 0;
+  };
+
+  StddevInfo info_;
+};
+
+template <typename TArg>
+class EWMAZScoreUDA : public udf::UDA {
+ public:
+  // The smoothing factor of the moving average, which weighs roughly the last 20 values.
+  static constexpr double kAlpha = 0.1;
+  // The number of the most recent values that are kept in the state, which bounds its size.
+  static constexpr size_t kMaxPoints = 10000;
+
+  void Update(FunctionContext*, Time64NSValue time, TArg val) {
+    points_.push_back({time.val, static_cast<double>(val.val)});
+    if (points_.size() >= 2 * kMaxPoints) {
+      Trim();
+    }
+  }
+  void Merge(FunctionContext*, const EWMAZScoreUDA& other) {
+    points_.insert(points_.end(), other.points_.begin(), other.points_.end());
+    if (points_.size() > kMaxPoints) {
+      Trim();
+    }
+  }
+
+  Float64Value Finalize(FunctionContext*) {
+    if (points_.size() < 2) {
+      return 0.0;
+    }
+    if (points_.size() > kMaxPoints) {
+      Trim();
+    }
+    std::sort(points_.begin(), points_.end(),
+              [](const Point& a, const Point& b) { return a.time < b.time; });
+    // The exponentially weighted mean and variance of every value before the latest one.
+    double mean = points_[0].val;
+    double var = 0;
+    for (size_t i = 1; i < points_.size() - 1; ++i) {
+      double delta = points_[i].val - mean;
+      double incr = kAlpha * delta;
+      mean += incr;
+      var = (1 - kAlpha) * (var + delta * incr);
+    }
+    if (var <= 0) {
+      return 0.0;
+    }
+    return (points_.back().val - mean) / std::sqrt(var);
+  }
+
+  StringValue Serialize(FunctionContext*) {
+    return StringValue(reinterpret_cast<const char*>(points_.data()),
+                       points_.size() * sizeof(Point));
+  }
+
+  Status Deserialize(FunctionContext*, const StringValue& data) {
+    if (data.size() % sizeof(Point) != 0) {
+      return error::InvalidArgument("invalid serialized ewma_zscore state");
+    }
+    points_.resize(data.size() / sizeof(Point));
+    std::memcpy(points_.data(), data.data(), data.size());
+    return Status::OK();
+  }
+
+  static udf::UDADocBuilder Doc() {
+    return udf::UDADocBuilder(
+               "Scores the latest value against the exponentially weighted moving average of the "
+               "values before it.")
+        .Details(
+            "Orders the aggregated values by time, and computes the exponentially weighted moving "
+            "average (EWMA) and standard deviation of every value except the latest one, with a "
+            "smoothing factor of 0.1. Returns how many of those standard deviations the latest "
+            "value is away from the average. Because the moving average follows trends, this "
+            "catches sudden changes rather than slow drift. Only the latest 10000 values of each "
+            "group are kept, so aggregate windowed values such as the output of `px.bin` rather "
+            "than raw events.")
+        .Example(R"doc(
+        | df.timestamp = px.bin(df.time_, px.seconds(10))
+        | df = df.groupby(['service', 'timestamp']).agg(latency=('latency', px.mean))
+        | df = df.groupby('service').agg(score=('timestamp', 'latency', px.ewma_zscore))
+        | df = df[px.abs(df.score) > 3]
+        )doc")
+        .Arg("time", "The time of each value, which orders the values.")
+        .Arg("val", "The values to score.")
+        .Returns("The z-score of the latest value, or 0 if there is no variation before it.");
+  }
+
+ protected:
+  struct Point {
+    int64_t time;
+    double val;
+  };
+
+  // Trim keeps the latest kMaxPoints values.
+  void Trim() {
+    std::nth_element(points_.begin(), points_.begin() + (points_.size() - kMaxPoints),
+                     points_.end(), [](const Point& a, const Point& b) { return a.time < b.time; });
+    points_.erase(points_.begin(), points_.begin() + (points_.size() - kMaxPoints));
+  }
+
+  std::vector<Point> points_;
+};
+
+template <typename TArg>
+class ZScoreUDF : public udf::ScalarUDF {
+ public:
+  Float64Value Exec(FunctionContext*, TArg val, Float64Value mean, Float64Value stddev) {
+    if (stddev.val == 0) {
+      return 0.0;
+    }
+    return (static_cast<double>(val.val) - mean.val) / stddev.val;
+  }
+
+  static udf::ScalarUDFDocBuilder Doc() {
+    return udf::ScalarUDFDocBuilder("Scores a value against the mean and standard deviation.")
+        .Details(
+            "Returns how many standard deviations the value is away from the mean. Values with a "
+            "score above 3 or below -3 are commonly considered outliers. The mean and standard "
+            "deviation are usually a baseline computed with `px.mean` and `px.stddev`.")
+        .Example(R"doc(
+        | baseline = df.groupby('service').agg(
+        |     latency_mean=('latency', px.mean),
+        |     latency_stddev=('latency', px.stddev),
+        | )
+        | df = df.merge(baseline, how='inner', left_on='service', right_on='service',
+        |               suffixes=['', '_baseline'])
+        | df.score = px.zscore(df.latency, df.latency_mean, df.latency_stddev)
+        )doc")
+        .Arg("val", "The value to score.")
+        .Arg("mean", "The mean of the baseline.")
+        .Arg("stddev", "The standard deviation of the baseline.")
+        .Returns("The z-score of the value, or 0 if the standard deviation is 0.");
+  }
+};
+
+template <typename TArg>
+class RobustZScoreUDF : public udf::ScalarUDF {
+ public:
+  // The ratio of the MAD to the standard deviation of a normal distribution, which puts robust
+  // z-scores on the same scale as z-scores.
+  static constexpr double kMADScale = 0.6745;
+
+  Float64Value Exec(FunctionContext*, TArg val, Float64Value median, Float64Value mad) {
+    if (mad.val == 0) {
+      return 0.0;
+    }
+    return kMADScale * (static_cast<double>(val.val) - median.val) / mad.val;
+  }
+
+  static udf::ScalarUDFDocBuilder Doc() {
+    return udf::ScalarUDFDocBuilder(
+               "Scores a value against the median and median absolute deviation.")
+        .Details(
+            "Returns the modified z-score of the value (Iglewicz and Hoaglin), which is scaled "
+            "to be comparable with `px.zscore`. Values with a score above 3.5 or below -3.5 are "
+            "commonly considered outliers. Unlike `px.zscore`, the baseline isn't skewed by the "
+            "outliers in it. The median and median absolute deviation are usually a baseline "
+            "computed with `px.quantiles` and `px.mad`.")
+        .Example(R"doc(
+        | baseline = df.groupby('service').agg(
+        |     latency_dist=('latency', px.quantiles),
+        |     latency_mad=('latency', px.mad),
+        | )
+        | baseline.latency_median = px.pluck_float64(baseline.latency_dist, 'p50')
+        | df = df.merge(baseline, how='inner', left_on='service', right_on='service',
+        |               suffixes=['', '_baseline'])
+        | df.score = px.robust_zscore(df.latency, df.latency_median, df.latency_mad)
+        )doc")
+        .Arg("val", "The value to score.")
+        .Arg("median", "The median of the baseline.")
+        .Arg("mad", "The median absolute deviation of the baseline.")
+        .Returns("The robust z-score of the value, or 0 if the median absolute deviation is 0.");
+  }
+};
+
+class HourOfDayUDF : public udf::ScalarUDF {
+ public:
+  Int64Value Exec(FunctionContext*, Time64NSValue time) {
+    int64_t hours = time.val / kNanosPerHour - (time.val % kNanosPerHour < 0 ? 1 : 0);
+    return FloorMod(hours, 24);
+  }
+
+  static udf::ScalarUDFDocBuilder Doc() {
+    return udf::ScalarUDFDocBuilder("Get the hour of the day of a time, in UTC.")
+        .Details(
+            "Returns the hour of the day (0-23) of the time, in UTC. Group baselines by the hour "
+            "of the day to compare metrics with daily patterns to the same hour on previous "
+            "days.")
+        .Example(R"doc(
+        | df.hour = px.hour_of_day(df.time_)
+        | baseline = df.groupby(['service', 'hour']).agg(latency_mad=('latency', px.mad))
+        )doc")
+        .Arg("time", "The time to get the hour of.")
+        .Returns("The hour of the day, from 0 to 23.");
+  }
+
+  static constexpr int64_t kNanosPerHour = 3600LL * 1000 * 1000 * 1000;
+
+  static int64_t FloorMod(int64_t a, int64_t b) { return ((a % b) + b) % b; }
+};
+
+class DayOfWeekUDF : public udf::ScalarUDF {
+ public:
+  Int64Value Exec(FunctionContext*, Time64NSValue time) {
+    int64_t days = time.val / kNanosPerDay - (time.val % kNanosPerDay < 0 ? 1 : 0);
+    // The Unix epoch was on a Thursday.
+    return HourOfDayUDF::FloorMod(days + 3, 7);
+  }
+
+  static udf::ScalarUDFDocBuilder Doc() {
+    return udf::ScalarUDFDocBuilder("Get the day of the week of a time, in UTC.")
+        .Details(
+            "Returns the day of the week of the time, in UTC, where 0 is Monday and 6 is Sunday. "
+            "Group baselines by the day of the week to compare metrics with weekly patterns to "
+            "the same day on previous weeks.")
+        .Example(R"doc(
+        | df.day = px.day_of_week(df.time_)
+        | df.weekend = df.day >= 5
+        )doc")
+        .Arg("time", "The time to get the day of.")
+        .Returns("The day of the week, from 0 (Monday) to 6 (Sunday).");
+  }
+
+  static constexpr int64_t kNanosPerDay = 24 * HourOfDayUDF::kNanosPerHour;
+};
+
+void RegisterAnomalyOpsOrDie(udf::Registry* registry);
+
+}  // namespace builtins
+}  // namespace carnot
+}  // namespace px
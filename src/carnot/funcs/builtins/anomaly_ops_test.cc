@@ -0,0 +1,137 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#include <gtest/gtest.h>
+#include <cmath>
+
+#include "src/carnot/funcs/builtins/anomaly_ops.h"
+#include "src/carnot/udf/test_utils.h"
+#include "src/common/base/base.h"
+#include "src/common/testing/testing.h"
+
+namespace px {
+namespace carnot {
+namespace builtins {
+
+TEST(AnomalyOps, stddev) {
+  auto uda_tester = udf::UDATester<StddevUDA<types::Float64Value>>();
+  uda_tester.ForInput(2)
+      .ForInput(4)
+      .ForInput(4)
+      .ForInput(4)
+      .ForInput(5)
+      .ForInput(5)
+      .ForInput(7)
+      .ForInput(9);
+  EXPECT_NEAR(2.1380899352993952, uda_tester.Result().val, 1e-9);
+}
+
+TEST(AnomalyOps, stddev_merge) {
+  auto tester_a = udf::UDATester<StddevUDA<types::Int64Value>>();
+  tester_a.ForInput(1).ForInput(2).ForInput(3);
+  auto tester_b = udf::UDATester<StddevUDA<types::Int64Value>>();
+  tester_b.ForInput(4).ForInput(5).ForInput(6).ForInput(7).ForInput(8);
+  EXPECT_OK(tester_a.Deserialize(tester_b.Serialize()));
+  EXPECT_NEAR(2.4494897427831779, tester_a.Result().val, 1e-9);
+}
+
+TEST(AnomalyOps, stddev_single_value) {
+  auto uda_tester = udf::UDATester<StddevUDA<types::Float64Value>>();
+  uda_tester.ForInput(3.5).Expect(0);
+}
+
+TEST(AnomalyOps, ewma_zscore) {
+  auto uda_tester = udf::UDATester<EWMAZScoreUDA<types::Float64Value>>();
+  // A steady signal that alternates between 10 and 12, followed by a spike. The values are added
+  // out of order, since the UDA orders them by time.
+  uda_tester.ForInput(100, 50);
+  for (int64_t i = 0; i < 100; ++i) {
+    uda_tester.ForInput(i, i % 2 == 0 ? 10 : 12);
+  }
+  EXPECT_GT(uda_tester.Result().val, 10);
+}
+
+TEST(AnomalyOps, ewma_zscore_steady) {
+  auto uda_tester = udf::UDATester<EWMAZScoreUDA<types::Int64Value>>();
+  for (int64_t i = 0; i < 100; ++i) {
+    uda_tester.ForInput(i, i % 2 == 0 ? 10 : 12);
+  }
+  EXPECT_LT(std::abs(uda_tester.Result().val), 2);
+}
+
+TEST(AnomalyOps, ewma_zscore_no_variation) {
+  auto uda_tester = udf::UDATester<EWMAZScoreUDA<types::Int64Value>>();
+  uda_tester.ForInput(1, 10).ForInput(2, 10).ForInput(3, 10).Expect(0);
+}
+
+TEST(AnomalyOps, ewma_zscore_serde) {
+  auto tester_a = udf::UDATester<EWMAZScoreUDA<types::Float64Value>>();
+  auto tester_b = udf::UDATester<EWMAZScoreUDA<types::Float64Value>>();
+  auto tester_all = udf::UDATester<EWMAZScoreUDA<types::Float64Value>>();
+  for (int64_t i = 0; i < 50; ++i) {
+    double val = i % 3 + (i == 49 ? 20 : 0);
+    (i % 2 == 0 ? tester_a : tester_b).ForInput(i, val);
+    tester_all.ForInput(i, val);
+  }
+  EXPECT_OK(tester_a.Deserialize(tester_b.Serialize()));
+  EXPECT_DOUBLE_EQ(tester_all.Result().val, tester_a.Result().val);
+}
+
+TEST(AnomalyOps, ewma_zscore_keeps_latest_values) {
+  auto uda_tester = udf::UDATester<EWMAZScoreUDA<types::Int64Value>>();
+  // The early values are dropped once there are too many, so they don't affect the score.
+  for (int64_t i = 0; i < 3 * static_cast<int64_t>(EWMAZScoreUDA<types::Int64Value>::kMaxPoints);
+       ++i) {
+    uda_tester.ForInput(i, i < 100 ? 1000 : i % 2);
+  }
+  EXPECT_LT(std::abs(uda_tester.Result().val), 2);
+}
+
+TEST(AnomalyOps, zscore) {
+  auto udf_tester = udf::UDFTester<ZScoreUDF<types::Float64Value>>();
+  udf_tester.ForInput(16.0, 10.0, 2.0).Expect(3.0);
+  udf_tester.ForInput(4.0, 10.0, 2.0).Expect(-3.0);
+  udf_tester.ForInput(4.0, 10.0, 0.0).Expect(0.0);
+}
+
+TEST(AnomalyOps, robust_zscore) {
+  auto udf_tester = udf::UDFTester<RobustZScoreUDF<types::Int64Value>>();
+  udf_tester.ForInput(20, 10.0, 2.0).Expect(3.3725);
+  udf_tester.ForInput(20, 10.0, 0.0).Expect(0.0);
+}
+
+TEST(AnomalyOps, hour_of_day) {
+  auto udf_tester = udf::UDFTester<HourOfDayUDF>();
+  // 2023-11-14T22:13:20Z.
+  udf_tester.ForInput(1700000000000000000).Expect(22);
+  udf_tester.ForInput(0).Expect(0);
+  udf_tester.ForInput(-1).Expect(23);
+}
+
+TEST(AnomalyOps, day_of_week) {
+  auto udf_tester = udf::UDFTester<DayOfWeekUDF>();
+  // 2023-11-14T22:13:20Z was a Tuesday.
+  udf_tester.ForInput(1700000000000000000).Expect(1);
+  // 1970-01-01 was a Thursday, and 1969-12-31 a Wednesday.
+  udf_tester.ForInput(0).Expect(3);
+  udf_tester.ForInput(-1).Expect(2);
+}
+
+}  // namespace builtins
+}  // namespace carnot
+}  // namespace px
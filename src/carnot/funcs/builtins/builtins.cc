@@ -17,6 +17,7 @@
  */
 
 #include "src/carnot/funcs/builtins/builtins.h"
+#include "src/carnot/funcs/builtins/anomaly_ops.h"
 #include "src/carnot/funcs/builtins/collections.h"
 #include "src/carnot/funcs/builtins/conditionals.h"
 #include "src/carnot/funcs/builtins/json_ops.h"
@@ -53,6 +54,7 @@ void RegisterBuiltinsOrDie(udf::Registry* registry) {
   RegisterURIOpsOrDie(registry);
   RegisterUtilOpsOrDie(registry);
   RegisterPProfOpsOrDie(registry);
+  RegisterAnomalyOpsOrDie(registry);
 }
 
 }  // namespace builtins
@@ -16,6 +16,7 @@
  * SPDX-License-Identifier: Apache-2.0
  */
 
+#include <utility>
 #include <vector>
 
 #include "src/carnot/funcs/builtins/math_sketches.h"
@@ -27,6 +28,10 @@ namespace builtins {
 void RegisterMathSketchesOrDie(udf::Registry* registry) {
   registry->RegisterOrDie<QuantilesUDA<types::Int64Value>>("quantiles");
   registry->RegisterOrDie<QuantilesUDA<types::Float64Value>>("quantiles");
+  registry->RegisterOrDie<MADUDA<types::Int64Value>>("mad");
+  registry->RegisterOrDie<MADUDA<types::Float64Value>>("mad");
+  registry->RegisterOrDie<TopKUDA<types::StringValue>>("top_k");
+  registry->RegisterOrDie<TopKUDA<types::Int64Value>>("top_k");
 }
 
 void WriteCentroidArray(rapidjson::Writer<rapidjson::StringBuffer>* writer,
@@ -52,6 +57,40 @@ std::vector<tdigest::Centroid> CentroidArrayFromJSON(const rapidjson::Value& val
   return centroids;
 }
 
+StringValue SerializeTDigest(tdigest::TDigest* digest) {
+  rapidjson::StringBuffer sb;
+  rapidjson::Writer<rapidjson::StringBuffer> writer(sb);
+  writer.StartObject();
+  writer.Key(kTDigestProcessedKey);
+  WriteCentroidArray(&writer, digest->processed());
+  writer.Key(kTDigestUnprocessedKey);
+  WriteCentroidArray(&writer, digest->unprocessed());
+  writer.Key(kTDigestCompressionKey);
+  writer.Double(digest->compression());
+  writer.Key(kTDigestMaxUnprocessedKey);
+  writer.Uint64(digest->maxUnprocessed());
+  writer.Key(kTDigestMaxProcessedKey);
+  writer.Uint64(digest->maxProcessed());
+  writer.EndObject();
+  return sb.GetString();
+}
+
+Status DeserializeTDigest(const StringValue& json, tdigest::TDigest* digest) {
+  rapidjson::Document d;
+  rapidjson::ParseResult ok = d.Parse(json.data());
+  if (ok == nullptr) {
+    return error::InvalidArgument("invalid serialized tdigest");
+  }
+  auto processed = CentroidArrayFromJSON(d[kTDigestProcessedKey]);
+  auto unprocessed = CentroidArrayFromJSON(d[kTDigestUnprocessedKey]);
+  auto compression = d[kTDigestCompressionKey].GetDouble();
+  auto maxUnprocessed = d[kTDigestMaxUnprocessedKey].GetUint64();
+  auto maxProcessed = d[kTDigestMaxProcessedKey].GetUint64();
+  *digest = tdigest::TDigest(std::move(processed), std::move(unprocessed), compression,
+                             maxUnprocessed, maxProcessed);
+  return Status::OK();
+}
+
 }  // namespace builtins
 }  // namespace carnot
 }  // namespace px
@@ -17,9 +17,13 @@
  */
 
 #pragma once
+#include <absl/container/flat_hash_map.h>
 #include <rapidjson/document.h>
 #include <rapidjson/stringbuffer.h>
 #include <rapidjson/writer.h>
+#include <algorithm>
+#include <cmath>
+#include <string>
 #include <utility>
 #include <vector>
 
@@ -37,6 +41,16 @@ void WriteCentroidArray(rapidjson::Writer<rapidjson::StringBuffer>* writer,
 
 std::vector<tdigest::Centroid> CentroidArrayFromJSON(const rapidjson::Value& val);
 
+inline constexpr char kTDigestProcessedKey[] = "0";
+inline constexpr char kTDigestUnprocessedKey[] = "1";
+inline constexpr char kTDigestCompressionKey[] = "2";
+inline constexpr char kTDigestMaxUnprocessedKey[] = "3";
+inline constexpr char kTDigestMaxProcessedKey[] = "4";
+
+// SerializeTDigest and DeserializeTDigest convert the state of UDAs that are backed by a tdigest.
+StringValue SerializeTDigest(tdigest::TDigest* digest);
+Status DeserializeTDigest(const StringValue& json, tdigest::TDigest* digest);
+
 // TODO(zasgar): PL-419 Replace this when we add support for structs.
 template <typename TArg>
 class QuantilesUDA : public udf::UDA {
@@ -61,44 +75,13 @@ class QuantilesUDA : public udf::UDA {
     return sb.GetString();
   }
 
-  static constexpr char kProcessedKey[] = "0";
-  static constexpr char kUnprocessedKey[] = "1";
-  static constexpr char kCompressionKey[] = "2";
-  static constexpr char kMaxUnprocessedKey[] = "3";
-  static constexpr char kMaxProcessedKey[] = "4";
+  static constexpr const char* kProcessedKey = kTDigestProcessedKey;
+  static constexpr const char* kUnprocessedKey = kTDigestUnprocessedKey;
 
-  StringValue Serialize(FunctionContext*) {
-    rapidjson::StringBuffer sb;
-    rapidjson::Writer<rapidjson::StringBuffer> writer(sb);
-    writer.StartObject();
-    writer.Key(kProcessedKey);
-    WriteCentroidArray(&writer, digest_.processed());
-    writer.Key(kUnprocessedKey);
-    WriteCentroidArray(&writer, digest_.unprocessed());
-    writer.Key(kCompressionKey);
-    writer.Double(digest_.compression());
-    writer.Key(kMaxUnprocessedKey);
-    writer.Uint64(digest_.maxUnprocessed());
-    writer.Key(kMaxProcessedKey);
-    writer.Uint64(digest_.maxProcessed());
-    writer.EndObject();
-    return sb.GetString();
-  }
+  StringValue Serialize(FunctionContext*) { return SerializeTDigest(&digest_); }
 
   Status Deserialize(FunctionContext*, const StringValue& json) {
-    rapidjson::Document d;
-    rapidjson::ParseResult ok = d.Parse(json.data());
-    if (ok == nullptr) {
-      return error::InvalidArgument("invalid serialized tdigest");
-    }
-    auto processed = CentroidArrayFromJSON(d[kProcessedKey]);
-    auto unprocessed = CentroidArrayFromJSON(d[kUnprocessedKey]);
-    auto compression = d[kCompressionKey].GetDouble();
-    auto maxUnprocessed = d[kMaxUnprocessedKey].GetUint64();
-    auto maxProcessed = d[kMaxProcessedKey].GetUint64();
-    digest_ = tdigest::TDigest(std::move(processed), std::move(unprocessed), compression,
-                               maxUnprocessed, maxProcessed);
-    return Status::OK();
+    return DeserializeTDigest(json, &digest_);
   }
 
   static udf::InfRuleVec SemanticInferenceRules() {
@@ -129,6 +112,298 @@ class QuantilesUDA : public udf::UDA {
   tdigest::TDigest digest_;
 };
 
+template <typename TArg>
+class MADUDA : public udf::UDA {
+ public:
+  MADUDA() : digest_(1000) {}
+  void Update(FunctionContext*, TArg val) { digest_.add(val.val); }
+  void Merge(FunctionContext*, const MADUDA& other) { digest_.merge(&other.digest_); }
+
+  Float64Value Finalize(FunctionContext*) {
+    if (digest_.processed().empty() && digest_.unprocessed().empty()) {
+      return 0.0;
+    }
+    double median = digest_.quantile(0.5);
+    // The absolute deviations are approximated from the centroids of the digest, so that the
+    // data doesn't have to be kept around for a second pass.
+    tdigest::TDigest deviations(digest_.compression());
+    for (const auto& c : digest_.processed()) {
+      deviations.add(std::abs(c.mean() - median), c.weight());
+    }
+    for (const auto& c : digest_.unprocessed()) {
+      deviations.add(std::abs(c.mean() - median), c.weight());
+    }
+    return deviations.quantile(0.5);
+  }
+
+  StringValue Serialize(FunctionContext*) { return SerializeTDigest(&digest_); }
+
+  Status Deserialize(FunctionContext*, const StringValue& json) {
+    return DeserializeTDigest(json, &digest_);
+  }
+
+  static udf::InfRuleVec SemanticInferenceRules() {
+    return {udf::InheritTypeFromArgs<MADUDA>::Create({types::ST_BYTES, types::ST_DURATION_NS})};
+  }
+
+  static udf::UDADocBuilder Doc() {
+    return udf::UDADocBuilder("Approximates the median absolute deviation of the aggregated data.")
+        .Details(
+            "The median absolute deviation (MAD) is the median of the distances of the data from "
+            "its median. Unlike the standard deviation, it isn't skewed by the outliers that "
+            "you're trying to detect, which makes it a good measure of the normal spread of "
+            "latencies and error counts. It is approximated with the same "
+            "[tdigest](https://github.com/tdunning/t-digest) as `px.quantiles`. Use it with "
+            "`px.robust_zscore` to score values against their baseline.")
+        .Example(R"doc(
+        | # Calculate the baseline latency of each service.
+        | df = df.groupby('service').agg(
+        |     latency_dist=('latency', px.quantiles),
+        |     latency_mad=('latency', px.mad),
+        | )
+        | df.latency_median = px.pluck_float64(df.latency_dist, 'p50')
+        )doc")
+        .Arg("val", "The data to calculate the median absolute deviation of.")
+        .Returns("The approximate median absolute deviation of the data.");
+  }
+
+ protected:
+  tdigest::TDigest digest_;
+};
+
+/**
+ * SpaceSavingSketch tracks the approximate most frequent items of a stream using a bounded number
+ * of counters (Metwally et al., "Efficient Computation of Frequent and Top-k Elements in Data
+ * Streams"). Each counter stores an over-estimate of the item's count along with the maximum
+ * over-estimation error, so that count - error is a guaranteed lower bound.
+ */
+class SpaceSavingSketch {
+ public:
+  struct Counter {
+    int64_t count = 0;
+    int64_t error = 0;
+  };
+
+  explicit SpaceSavingSketch(size_t capacity) : capacity_(capacity) {}
+
+  void Add(const std::string& item, int64_t weight = 1) {
+    total_ += weight;
+    auto it = counters_.find(item);
+    if (it != counters_.end()) {
+      it->second.count += weight;
+      return;
+    }
+    if (counters_.size() < capacity_) {
+      counters_[item] = Counter{weight, 0};
+      return;
+    }
+    // Replace the smallest counter, inheriting its count as the error bound.
+    auto min_it = MinCounter();
+    int64_t min_count = min_it->second.count;
+    counters_.erase(min_it);
+    counters_[item] = Counter{min_count + weight, min_count};
+  }
+
+  // Merge combines two sketches following the mergeable summaries construction (Agarwal et al.):
+  // items missing from a full sketch may have had up to that sketch's minimum count.
+  void Merge(const SpaceSavingSketch& other) {
+    int64_t this_min = Full() ? MinCounter()->second.count : 0;
+    int64_t other_min = other.Full() ? other.MinCounter()->second.count : 0;
+
+    absl::flat_hash_map<std::string, Counter> merged;
+    for (const auto& [item, c] : counters_) {
+      auto other_it = other.counters_.find(item);
+      if (other_it == other.counters_.end()) {
+        merged[item] = Counter{c.count + other_min, c.error + other_min};
+      } else {
+        merged[item] =
+            Counter{c.count + other_it->second.count, c.error + other_it->second.error};
+      }
+    }
+    for (const auto& [item, c] : other.counters_) {
+      if (counters_.contains(item)) {
+        continue;
+      }
+      merged[item] = Counter{c.count + this_min, c.error + this_min};
+    }
+
+    counters_ = std::move(merged);
+    total_ += other.total_;
+    if (counters_.size() > capacity_) {
+      auto sorted = Sorted();
+      counters_.clear();
+      for (size_t i = 0; i < capacity_; ++i) {
+        counters_.insert(sorted[i]);
+      }
+    }
+  }
+
+  // Sorted returns the tracked items by descending count.
+  std::vector<std::pair<std::string, Counter>> Sorted() const {
+    std::vector<std::pair<std::string, Counter>> items(counters_.begin(), counters_.end());
+    std::sort(items.begin(), items.end(), [](const auto& a, const auto& b) {
+      if (a.second.count != b.second.count) {
+        return a.second.count > b.second.count;
+      }
+      return a.first < b.first;
+    });
+    return items;
+  }
+
+  void Insert(std::string item, Counter c) { counters_[std::move(item)] = c; }
+  void set_total(int64_t total) { total_ = total; }
+  int64_t total() const { return total_; }
+  size_t capacity() const { return capacity_; }
+
+ private:
+  bool Full() const { return counters_.size() >= capacity_; }
+
+  absl::flat_hash_map<std::string, Counter>::const_iterator MinCounter() const {
+    return std::min_element(counters_.begin(), counters_.end(), [](const auto& a, const auto& b) {
+      return a.second.count < b.second.count;
+    });
+  }
+
+  size_t capacity_;
+  int64_t total_ = 0;
+  absl::flat_hash_map<std::string, Counter> counters_;
+};
+
+inline std::string TopKKey(const types::StringValue& val) { return val; }
+inline std::string TopKKey(const types::Int64Value& val) { return std::to_string(val.val); }
+
+template <typename TArg>
+class TopKUDA : public udf::UDA {
+ public:
+  // The number of counters tracked per returned value. Tracking more counters than we output keeps
+  // the error on the reported items low, even after merging partial aggregates from many agents.
+  static constexpr int64_t kCountersPerResult = 10;
+  static constexpr int64_t kDefaultK = 20;
+  static constexpr int64_t kMaxK = 1000;
+
+  TopKUDA() : TopKUDA(kDefaultK) {}
+  explicit TopKUDA(int64_t k) : k_(k), sketch_(Capacity(k)) {}
+
+  Status Init(FunctionContext*, Int64Value k) {
+    if (k.val < 1 || k.val > kMaxK) {
+      return error::InvalidArgument("top_k expects k to be between 1 and $0, received $1", kMaxK,
+                                    k.val);
+    }
+    k_ = k.val;
+    sketch_ = SpaceSavingSketch(Capacity(k_));
+    return Status::OK();
+  }
+
+  void Update(FunctionContext*, TArg val) { sketch_.Add(TopKKey(val)); }
+  void Merge(FunctionContext*, const TopKUDA& other) { sketch_.Merge(other.sketch_); }
+
+  StringValue Finalize(FunctionContext*) {
+    rapidjson::StringBuffer sb;
+    rapidjson::Writer<rapidjson::StringBuffer> writer(sb);
+    writer.StartArray();
+    auto sorted = sketch_.Sorted();
+    for (size_t i = 0; i < sorted.size() && i < static_cast<size_t>(k_); ++i) {
+      writer.StartObject();
+      writer.Key("value");
+      writer.String(sorted[i].first.data(), sorted[i].first.size());
+      writer.Key("count");
+      writer.Int64(sorted[i].second.count);
+      writer.Key("error");
+      writer.Int64(sorted[i].second.error);
+      writer.EndObject();
+    }
+    writer.EndArray();
+    return sb.GetString();
+  }
+
+  static constexpr char kCountersKey[] = "0";
+  static constexpr char kTotalKey[] = "1";
+  static constexpr char kKKey[] = "2";
+
+  StringValue Serialize(FunctionContext*) {
+    rapidjson::StringBuffer sb;
+    rapidjson::Writer<rapidjson::StringBuffer> writer(sb);
+    writer.StartObject();
+    writer.Key(kCountersKey);
+    writer.StartArray();
+    for (const auto& [item, c] : sketch_.Sorted()) {
+      writer.StartArray();
+      writer.String(item.data(), item.size());
+      writer.Int64(c.count);
+      writer.Int64(c.error);
+      writer.EndArray();
+    }
+    writer.EndArray();
+    writer.Key(kTotalKey);
+    writer.Int64(sketch_.total());
+    writer.Key(kKKey);
+    writer.Int64(k_);
+    writer.EndObject();
+    return sb.GetString();
+  }
+
+  Status Deserialize(FunctionContext*, const StringValue& json) {
+    rapidjson::Document d;
+    rapidjson::ParseResult ok = d.Parse(json.data());
+    if (ok == nullptr || !d.IsObject()) {
+      return error::InvalidArgument("invalid serialized top_k sketch: expected an object");
+    }
+    if (!d.HasMember(kCountersKey) || !d[kCountersKey].IsArray()) {
+      return error::InvalidArgument(
+          "invalid serialized top_k sketch: expected an array of counters");
+    }
+    if (!d.HasMember(kTotalKey) || !d[kTotalKey].IsInt64()) {
+      return error::InvalidArgument("invalid serialized top_k sketch: expected an integer total");
+    }
+    if (!d.HasMember(kKKey) || !d[kKKey].IsInt64() || d[kKKey].GetInt64() < 1 ||
+        d[kKKey].GetInt64() > kMaxK) {
+      return error::InvalidArgument("invalid serialized top_k sketch: expected k between 1 and $0",
+                                    kMaxK);
+    }
+
+    int64_t k = d[kKKey].GetInt64();
+    SpaceSavingSketch sketch(Capacity(k));
+    for (const auto& counter : d[kCountersKey].GetArray()) {
+      if (!counter.IsArray() || counter.Size() != 3 || !counter[0].IsString() ||
+          !counter[1].IsInt64() || !counter[2].IsInt64()) {
+        return error::InvalidArgument(
+            "invalid serialized top_k sketch: expected counters of [value, count, error]");
+      }
+      sketch.Insert(std::string(counter[0].GetString(), counter[0].GetStringLength()),
+                    {counter[1].GetInt64(), counter[2].GetInt64()});
+    }
+    sketch.set_total(d[kTotalKey].GetInt64());
+    k_ = k;
+    sketch_ = std::move(sketch);
+    return Status::OK();
+  }
+
+  static udf::UDADocBuilder Doc() {
+    return udf::UDADocBuilder("Approximates the most frequent values of the aggregated data.")
+        .Details(
+            "Uses the space-saving algorithm to track the most frequent values with bounded "
+            "memory, which makes it much cheaper than a full group-by and sort over high "
+            "cardinality columns. Partial results computed on each agent are merged on Kelvin. "
+            "Returns a serialized JSON array of the `k` most frequent values, sorted by "
+            "descending count. Each entry contains the `value`, its estimated `count`, and the "
+            "maximum over-estimation `error` of that count.")
+        .Example(R"doc(
+        | # Find the 20 endpoints with the most errors.
+        | df = df[df.resp_status >= 400]
+        | df = df.agg(top_endpoints=('req_path', px.top_k(20)))
+        )doc")
+        .Arg("k", "The number of most frequent values to return, at most 1000.")
+        .Arg("val", "The data to find the most frequent values of.")
+        .Returns("The most frequent values and their counts, serialized as a JSON array.");
+  }
+
+ protected:
+  static size_t Capacity(int64_t k) { return static_cast<size_t>(k * kCountersPerResult); }
+
+  int64_t k_;
+  SpaceSavingSketch sketch_;
+};
+
 void RegisterMathSketchesOrDie(udf::Registry* registry);
 
 }  // namespace builtins
@@ -19,6 +19,8 @@
 #include <rapidjson/document.h>
 #include <rapidjson/stringbuffer.h>
 #include <rapidjson/writer.h>
+#include <string>
+#include <vector>
 
 #include <gtest/gtest.h>
 
@@ -108,6 +110,148 @@ TEST(MathSketches, quantiles_serde) {
   EXPECT_EQ(res_before_serde, res_after_serde);
 }
 
+TEST(MathSketches, mad) {
+  auto uda_tester = udf::UDATester<MADUDA<types::Float64Value>>();
+  // The median of 0..1000 is 500, and half of the values are within 250 of it.
+  for (int64_t i = 0; i <= 1000; ++i) {
+    uda_tester.ForInput(i);
+  }
+  EXPECT_NEAR(250, uda_tester.Result().val, 5);
+}
+
+TEST(MathSketches, mad_ignores_outliers) {
+  auto uda_tester = udf::UDATester<MADUDA<types::Int64Value>>();
+  for (int64_t i = 0; i < 1000; ++i) {
+    uda_tester.ForInput(100 + i % 11 - 5);
+  }
+  uda_tester.ForInput(1000000);
+  auto res = uda_tester.Result();
+  EXPECT_GT(res.val, 2);
+  EXPECT_LT(res.val, 4);
+}
+
+TEST(MathSketches, mad_serde) {
+  auto uda_tester = udf::UDATester<MADUDA<types::Float64Value>>();
+  auto res_before_serde =
+      uda_tester.ForInput(1).ForInput(2).ForInput(4).ForInput(6).ForInput(9).Result();
+  auto new_uda_tester = udf::UDATester<MADUDA<types::Float64Value>>();
+  EXPECT_OK(new_uda_tester.Deserialize(uda_tester.Serialize()));
+  EXPECT_DOUBLE_EQ(res_before_serde.val, new_uda_tester.Result().val);
+}
+
+TEST(MathSketches, mad_empty) {
+  auto uda_tester = udf::UDATester<MADUDA<types::Float64Value>>();
+  EXPECT_DOUBLE_EQ(0, uda_tester.Result().val);
+}
+
+TEST(MathSketches, top_k_exact_when_under_capacity) {
+  auto uda_tester = udf::UDATester<TopKUDA<types::StringValue>>();
+  auto res = uda_tester.ForInput("/a")
+                 .ForInput("/b")
+                 .ForInput("/a")
+                 .ForInput("/c")
+                 .ForInput("/a")
+                 .ForInput("/b")
+                 .Result();
+
+  rapidjson::Document d;
+  d.Parse(res.data());
+  ASSERT_TRUE(d.IsArray());
+  ASSERT_EQ(3, d.GetArray().Size());
+  EXPECT_EQ("/a", std::string(d[0]["value"].GetString()));
+  EXPECT_EQ(3, d[0]["count"].GetInt64());
+  EXPECT_EQ(0, d[0]["error"].GetInt64());
+  EXPECT_EQ("/b", std::string(d[1]["value"].GetString()));
+  EXPECT_EQ(2, d[1]["count"].GetInt64());
+  EXPECT_EQ("/c", std::string(d[2]["value"].GetString()));
+  EXPECT_EQ(1, d[2]["count"].GetInt64());
+}
+
+TEST(MathSketches, top_k_heavy_hitters) {
+  auto uda_tester = udf::UDATester<TopKUDA<types::Int64Value>>();
+  // One heavy hitter interleaved with many more distinct values than there are counters.
+  for (int64_t i = 0; i < 10000; ++i) {
+    uda_tester.ForInput(i % 2 == 0 ? 42 : 1000 + i);
+  }
+  rapidjson::Document d;
+  d.Parse(uda_tester.Result().data());
+  ASSERT_TRUE(d.IsArray());
+  EXPECT_EQ(static_cast<size_t>(TopKUDA<types::Int64Value>::kDefaultK), d.GetArray().Size());
+  EXPECT_EQ("42", std::string(d[0]["value"].GetString()));
+  // Space-saving guarantees count - error <= true count <= count.
+  EXPECT_GE(d[0]["count"].GetInt64(), 5000);
+  EXPECT_LE(d[0]["count"].GetInt64() - d[0]["error"].GetInt64(), 5000);
+}
+
+TEST(MathSketches, top_k_merge_and_serde) {
+  auto tester_a = udf::UDATester<TopKUDA<types::StringValue>>();
+  tester_a.ForInput("x").ForInput("x").ForInput("y");
+  auto tester_b = udf::UDATester<TopKUDA<types::StringValue>>();
+  tester_b.ForInput("y").ForInput("y").ForInput("y").ForInput("z");
+
+  auto serialized = tester_b.Serialize();
+  EXPECT_OK(tester_a.Deserialize(serialized));
+
+  rapidjson::Document d;
+  d.Parse(tester_a.Result().data());
+  ASSERT_EQ(3, d.GetArray().Size());
+  EXPECT_EQ("y", std::string(d[0]["value"].GetString()));
+  EXPECT_EQ(4, d[0]["count"].GetInt64());
+  EXPECT_EQ("x", std::string(d[1]["value"].GetString()));
+  EXPECT_EQ(2, d[1]["count"].GetInt64());
+}
+
+TEST(MathSketches, top_k_init) {
+  TopKUDA<types::StringValue> uda;
+  EXPECT_OK(uda.Init(nullptr, 2));
+  uda.Update(nullptr, "a");
+  uda.Update(nullptr, "b");
+  uda.Update(nullptr, "b");
+  uda.Update(nullptr, "c");
+
+  rapidjson::Document d;
+  d.Parse(uda.Finalize(nullptr).data());
+  ASSERT_EQ(2, d.GetArray().Size());
+  EXPECT_EQ("b", std::string(d[0]["value"].GetString()));
+
+  EXPECT_NOT_OK(uda.Init(nullptr, 0));
+  EXPECT_NOT_OK(uda.Init(nullptr, TopKUDA<types::StringValue>::kMaxK + 1));
+}
+
+TEST(MathSketches, top_k_serde_keeps_k) {
+  auto uda_tester = udf::UDATester<TopKUDA<types::Int64Value>>(3);
+  for (int64_t i = 0; i < 10; ++i) {
+    uda_tester.ForInput(i);
+  }
+  TopKUDA<types::Int64Value> other;
+  EXPECT_OK(other.Deserialize(nullptr, uda_tester.Serialize()));
+
+  rapidjson::Document d;
+  d.Parse(other.Finalize(nullptr).data());
+  EXPECT_EQ(3, d.GetArray().Size());
+}
+
+TEST(MathSketches, top_k_deserialize_invalid) {
+  std::vector<std::string> invalid = {
+      "not json",
+      "[]",
+      R"({"1": 1, "2": 20})",
+      R"({"0": {}, "1": 1, "2": 20})",
+      R"({"0": [], "2": 20})",
+      R"({"0": [], "1": "1", "2": 20})",
+      R"({"0": [], "1": 1})",
+      R"({"0": [], "1": 1, "2": 0})",
+      R"({"0": ["a"], "1": 1, "2": 20})",
+      R"({"0": [["a", 1]], "1": 1, "2": 20})",
+      R"({"0": [[1, 1, 0]], "1": 1, "2": 20})",
+      R"({"0": [["a", 1.5, 0]], "1": 1, "2": 20})",
+  };
+  for (const auto& json : invalid) {
+    TopKUDA<types::StringValue> uda;
+    EXPECT_NOT_OK(uda.Deserialize(nullptr, json)) << json;
+  }
+}
+
 }  // namespace builtins
 }  // namespace carnot
 }  // namespace px
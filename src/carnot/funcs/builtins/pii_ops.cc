@@ -19,7 +19,9 @@
 #include <map>
 #include <vector>
 
+#include <absl/strings/escaping.h>
 #include <absl/strings/numbers.h>
+#include <openssl/hmac.h>
 #include "src/carnot/funcs/builtins/pii_ops.h"
 
 namespace px {
@@ -32,6 +34,7 @@ void RegisterPIIOpsOrDie(udf::Registry* registry) {
    * Scalar UDFs.
    *****************************************/
   registry->RegisterOrDie<RedactPIIUDF>("redact_pii_best_effort");
+  registry->RegisterOrDie<RedactHashUDF>("redact_hash");
   /*****************************************
    * Aggregate UDFs.
    *****************************************/
@@ -403,6 +406,17 @@ StringValue RedactPIIUDF::Exec(FunctionContext*, StringValue input) {
   return ReplaceTagsWithSubs(input, &tags);
 }
 
+StringValue RedactHashUDF::Exec(FunctionContext*, StringValue input, StringValue salt) {
+  // Only a prefix of the digest is kept, which is plenty to tell values apart.
+  constexpr size_t kHashBytes = 16;
+  uint8_t digest[EVP_MAX_MD_SIZE];
+  unsigned int digest_len = 0;
+  HMAC(EVP_sha256(), salt.data(), salt.size(), reinterpret_cast<const uint8_t*>(input.data()),
+       input.size(), digest, &digest_len);
+  return absl::BytesToHexString(std::string_view(reinterpret_cast<const char*>(digest),
+                                                 std::min<size_t>(kHashBytes, digest_len)));
+}
+
 }  // namespace builtins
 }  // namespace carnot
 }  // namespace px
@@ -96,6 +96,24 @@ class RedactPIIUDF : public udf::ScalarUDF {
   std::vector<std::unique_ptr<Tagger>> taggers_;
 };
 
+class RedactHashUDF : public udf::ScalarUDF {
+ public:
+  StringValue Exec(FunctionContext*, StringValue input, StringValue salt);
+
+  static udf::ScalarUDFDocBuilder Doc() {
+    return udf::ScalarUDFDocBuilder("Replace a string with a salted hash of it.")
+        .Details(
+            "Replace the string with the hex encoded first 16 bytes of its HMAC-SHA256, keyed "
+            "with the salt. Equal strings hash to the same value, so the redacted column can still "
+            "be grouped or joined on, but the original value can't be read back without knowing "
+            "the salt.")
+        .Example(R"doc(df.remote_addr = px.redact_hash(df.remote_addr, 'my-salt'))doc")
+        .Arg("input_str", "The string to hash.")
+        .Arg("salt", "The key to hash the string with.")
+        .Returns("The hash of the string.");
+  }
+};
+
 void RegisterPIIOpsOrDie(udf::Registry* registry);
 
 template <Tag::Type TTag>
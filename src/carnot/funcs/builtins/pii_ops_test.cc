@@ -267,6 +267,15 @@ INSTANTIATE_TEST_SUITE_P(TemplatedRedactionTest, RedactionTest,
                                                           EmailGen(), CCGen(), IMEIGen(), SSNGen(),
                                                           NegativeExampleGen()})));
 
+TEST(RedactHashTest, basic) {
+  udf::UDFTester<RedactHashUDF>()
+      .ForInput("10.0.0.1", "salt")
+      .Expect("7e51953c5cff8781a9d299d70c93c3e2");
+  udf::UDFTester<RedactHashUDF>()
+      .ForInput("10.0.0.1", "other")
+      .Expect("a297375a94bc95ebd59ac721a932aea9");
+}
+
 }  // namespace builtins
 }  // namespace carnot
 }  // namespace px
@@ -65,6 +65,10 @@ void RegisterMetadataOpsOrDie(px::carnot::udf::Registry* registry) {
   registry->RegisterOrDie<PodNameToReplicaSetIDUDF>("pod_name_to_replicaset_id");
   registry->RegisterOrDie<PodNameToDeploymentNameUDF>("pod_name_to_deployment_name");
   registry->RegisterOrDie<PodNameToDeploymentIDUDF>("pod_name_to_deployment_id");
+  registry->RegisterOrDie<PodIDToWorkloadNameUDF>("pod_id_to_workload_name");
+  registry->RegisterOrDie<PodIDToWorkloadKindUDF>("pod_id_to_workload_kind");
+  registry->RegisterOrDie<PodNameToWorkloadNameUDF>("pod_name_to_workload_name");
+  registry->RegisterOrDie<PodNameToWorkloadKindUDF>("pod_name_to_workload_kind");
   registry->RegisterOrDie<PodNameToPodIDUDF>("pod_name_to_pod_id");
   registry->RegisterOrDie<PodNameToPodIPUDF>("pod_name_to_pod_ip");
   registry->RegisterOrDie<PodNameToServiceNameUDF>("pod_name_to_service_name");
@@ -109,6 +113,10 @@ void RegisterMetadataOpsOrDie(px::carnot::udf::Registry* registry) {
   registry->RegisterOrDie<UPIDToContainerIDUDF>("upid_to_container_id");
   registry->RegisterOrDie<UPIDToCmdLineUDF>("upid_to_cmdline");
   registry->RegisterOrDie<UPIDToContainerNameUDF>("upid_to_container_name");
+  registry->RegisterOrDie<UPIDToImageUDF>("upid_to_image");
+  registry->RegisterOrDie<UPIDToImageTagUDF>("upid_to_image_tag");
+  registry->RegisterOrDie<UPIDToImageDigestUDF>("upid_to_image_digest");
+  registry->RegisterOrDie<UPIDToEnvLabelUDF>("upid_to_env_label");
   registry->RegisterOrDie<UPIDToHostnameUDF>("upid_to_hostname");
   registry->RegisterOrDie<UPIDToNamespaceUDF>("upid_to_namespace");
   registry->RegisterOrDie<UPIDToNodeNameUDF>("upid_to_node_name");
@@ -124,6 +132,8 @@ void RegisterMetadataOpsOrDie(px::carnot::udf::Registry* registry) {
   registry->RegisterOrDie<UPIDToReplicaSetIDUDF>("upid_to_replicaset_id");
   registry->RegisterOrDie<UPIDToDeploymentNameUDF>("upid_to_deployment_name");
   registry->RegisterOrDie<UPIDToDeploymentIDUDF>("upid_to_deployment_id");
+  registry->RegisterOrDie<UPIDToWorkloadNameUDF>("upid_to_workload_name");
+  registry->RegisterOrDie<UPIDToWorkloadKindUDF>("upid_to_workload_kind");
   registry->RegisterOrDie<UPIDToStringUDF>("upid_to_string");
   registry->RegisterOrDie<HostnameUDF>("_exec_hostname");
   registry->RegisterOrDie<HostNumCPUsUDF>("_exec_host_num_cpus");
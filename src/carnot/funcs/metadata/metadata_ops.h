@@ -307,6 +307,117 @@ class UPIDToContainerNameUDF : public ScalarUDF {
   static udfspb::UDFSourceExecutor Executor() { return udfspb::UDFSourceExecutor::UDF_PEM; }
 };
 
+class UPIDToImageUDF : public ScalarUDF {
+ public:
+  StringValue Exec(FunctionContext* ctx, UInt128Value upid_value) {
+    auto md = GetMetadataState(ctx);
+    auto container_info = UPIDToContainer(md, upid_value);
+    if (container_info == nullptr) {
+      return "";
+    }
+    return container_info->image();
+  }
+
+  static udf::ScalarUDFDocBuilder Doc() {
+    return udf::ScalarUDFDocBuilder("Get the container image from a UPID.")
+        .Details(
+            "Gets the image of the container the process with the given Unique Process ID (UPID) "
+            "is running in. If the UPID has no associated container, or the container image is "
+            "not known, this function will return an empty string.")
+        .Example("df.image = px.upid_to_image(df.upid)")
+        .Arg("upid", "The UPID of the process to get the container image for.")
+        .Returns("The container image for the UPID passed in.");
+  }
+
+  // This UDF can currently only run on PEMs, because only PEMs have the UPID information.
+  static udfspb::UDFSourceExecutor Executor() { return udfspb::UDFSourceExecutor::UDF_PEM; }
+};
+
+/**
+ * Returns the tag of a container image reference, such as "1.2.3" for "gcr.io/px/app:1.2.3".
+ * Returns an empty string if the image has no tag, or is referenced by digest.
+ */
+inline std::string ImageTag(std::string_view image) {
+  if (image.find('@') != std::string_view::npos) {
+    return "";
+  }
+  size_t name_start = image.rfind('/');
+  name_start = (name_start == std::string_view::npos) ? 0 : name_start + 1;
+  size_t tag_start = image.find(':', name_start);
+  if (tag_start == std::string_view::npos) {
+    return "";
+  }
+  return std::string(image.substr(tag_start + 1));
+}
+
+/**
+ * Returns the digest of a container image ID, such as "sha256:abc" for
+ * "docker-pullable://gcr.io/px/app@sha256:abc".
+ */
+inline std::string ImageDigest(std::string_view image_id) {
+  size_t scheme_end = image_id.find("://");
+  if (scheme_end != std::string_view::npos) {
+    image_id.remove_prefix(scheme_end + 3);
+  }
+  size_t digest_start = image_id.rfind('@');
+  if (digest_start != std::string_view::npos) {
+    image_id.remove_prefix(digest_start + 1);
+  }
+  return std::string(image_id);
+}
+
+class UPIDToImageTagUDF : public ScalarUDF {
+ public:
+  StringValue Exec(FunctionContext* ctx, UInt128Value upid_value) {
+    auto md = GetMetadataState(ctx);
+    auto container_info = UPIDToContainer(md, upid_value);
+    if (container_info == nullptr) {
+      return "";
+    }
+    return ImageTag(container_info->image());
+  }
+
+  static udf::ScalarUDFDocBuilder Doc() {
+    return udf::ScalarUDFDocBuilder("Get the container image tag from a UPID.")
+        .Details(
+            "Gets the tag of the image of the container the process with the given Unique Process "
+            "ID (UPID) is running in, such as `1.2.3` for `gcr.io/org/app:1.2.3`. "
+            "Returns an empty string if the image has no tag, or is referenced by digest.")
+        .Example("df.image_tag = px.upid_to_image_tag(df.upid)")
+        .Arg("upid", "The UPID of the process to get the container image tag for.")
+        .Returns("The container image tag for the UPID passed in.");
+  }
+
+  // This UDF can currently only run on PEMs, because only PEMs have the UPID information.
+  static udfspb::UDFSourceExecutor Executor() { return udfspb::UDFSourceExecutor::UDF_PEM; }
+};
+
+class UPIDToImageDigestUDF : public ScalarUDF {
+ public:
+  StringValue Exec(FunctionContext* ctx, UInt128Value upid_value) {
+    auto md = GetMetadataState(ctx);
+    auto container_info = UPIDToContainer(md, upid_value);
+    if (container_info == nullptr) {
+      return "";
+    }
+    return ImageDigest(container_info->image_id());
+  }
+
+  static udf::ScalarUDFDocBuilder Doc() {
+    return udf::ScalarUDFDocBuilder("Get the container image digest from a UPID.")
+        .Details(
+            "Gets the digest of the image of the container the process with the given Unique "
+            "Process ID (UPID) is running in, such as `sha256:...`. "
+            "If the UPID has no associated container, this function will return an empty string.")
+        .Example("df.image_digest = px.upid_to_image_digest(df.upid)")
+        .Arg("upid", "The UPID of the process to get the container image digest for.")
+        .Returns("The container image digest for the UPID passed in.");
+  }
+
+  // This UDF can currently only run on PEMs, because only PEMs have the UPID information.
+  static udfspb::UDFSourceExecutor Executor() { return udfspb::UDFSourceExecutor::UDF_PEM; }
+};
+
 inline const px::md::PodInfo* UPIDtoPod(const px::md::AgentMetadataState* md,
                                         types::UInt128Value upid_value) {
   auto container_info = UPIDToContainer(md, upid_value);
@@ -2249,6 +2360,195 @@ class PodNameToDeploymentIDUDF : public ScalarUDF {
   }
 };
 
+/**
+ * @brief Returns the name of the top-level workload that controls the pod with the pod ID.
+ */
+class PodIDToWorkloadNameUDF : public ScalarUDF {
+ public:
+  StringValue Exec(FunctionContext* ctx, StringValue pod_id) {
+    auto md = GetMetadataState(ctx);
+
+    const auto* pod_info = md->k8s_metadata_state().PodInfoByID(pod_id);
+    if (pod_info == nullptr) {
+      return "";
+    }
+
+    auto workload = md->k8s_metadata_state().PodWorkload(pod_info);
+    return absl::Substitute("$0/$1", pod_info->ns(), workload.name);
+  }
+
+  static udf::ScalarUDFDocBuilder Doc() {
+    return udf::ScalarUDFDocBuilder(
+               "Get the name of the workload which controls the pod with pod ID.")
+        .Details(
+            "Follows the owners of the Pod (specified by Pod ID) up to the top-level workload, "
+            "for example the Deployment that owns the Pod's ReplicaSet, or the CronJob that owns "
+            "the Pod's Job. If this pod is not controlled by anything, returns the pod name.")
+        .Example("df.workload = px.pod_id_to_workload_name(df.pod_id)")
+        .Arg("pod_id", "The Pod ID of the Pod to get the workload name for.")
+        .Returns("The k8s workload name which controls the Pod with the Pod ID.");
+  }
+};
+
+/**
+ * @brief Returns the kind of the top-level workload that controls the pod with the pod ID.
+ */
+class PodIDToWorkloadKindUDF : public ScalarUDF {
+ public:
+  StringValue Exec(FunctionContext* ctx, StringValue pod_id) {
+    auto md = GetMetadataState(ctx);
+
+    const auto* pod_info = md->k8s_metadata_state().PodInfoByID(pod_id);
+    if (pod_info == nullptr) {
+      return "";
+    }
+
+    return md->k8s_metadata_state().PodWorkload(pod_info).kind;
+  }
+
+  static udf::ScalarUDFDocBuilder Doc() {
+    return udf::ScalarUDFDocBuilder(
+               "Get the kind of the workload which controls the pod with pod ID.")
+        .Details(
+            "Gets the Kubernetes kind (eg. Deployment, StatefulSet, CronJob) of the top-level "
+            "workload that owns the Pod (specified by Pod ID). If this pod is not controlled by "
+            "anything, returns \"Pod\".")
+        .Example("df.workload_kind = px.pod_id_to_workload_kind(df.pod_id)")
+        .Arg("pod_id", "The Pod ID of the Pod to get the workload kind for.")
+        .Returns("The k8s workload kind which controls the Pod with the Pod ID.");
+  }
+};
+
+/**
+ * @brief Returns the name of the top-level workload that controls the pod with the pod name.
+ */
+class PodNameToWorkloadNameUDF : public ScalarUDF {
+ public:
+  StringValue Exec(FunctionContext* ctx, StringValue pod_name) {
+    auto md = GetMetadataState(ctx);
+
+    // This UDF expects the pod name to be in the format of "<ns>/<pod-name>".
+    PX_ASSIGN_OR(auto pod_name_view, internal::K8sName(pod_name), return "");
+    auto pod_id = md->k8s_metadata_state().PodIDByName(pod_name_view);
+
+    const auto* pod_info = md->k8s_metadata_state().PodInfoByID(pod_id);
+    if (pod_info == nullptr) {
+      return "";
+    }
+
+    auto workload = md->k8s_metadata_state().PodWorkload(pod_info);
+    return absl::Substitute("$0/$1", pod_info->ns(), workload.name);
+  }
+
+  static udf::ScalarUDFDocBuilder Doc() {
+    return udf::ScalarUDFDocBuilder(
+               "Get the name of the workload which controls the pod with the specified pod name.")
+        .Details(
+            "Follows the owners of the Pod (specified by pod name) up to the top-level workload, "
+            "for example the Deployment that owns the Pod's ReplicaSet, or the CronJob that owns "
+            "the Pod's Job. If this pod is not controlled by anything, returns the pod name.")
+        .Example("df.workload = px.pod_name_to_workload_name(df.pod_name)")
+        .Arg("pod_name", "The Pod name of the Pod to get the workload name for.")
+        .Returns("The k8s workload name which controls the Pod with the pod name.");
+  }
+};
+
+/**
+ * @brief Returns the kind of the top-level workload that controls the pod with the pod name.
+ */
+class PodNameToWorkloadKindUDF : public ScalarUDF {
+ public:
+  StringValue Exec(FunctionContext* ctx, StringValue pod_name) {
+    auto md = GetMetadataState(ctx);
+
+    // This UDF expects the pod name to be in the format of "<ns>/<pod-name>".
+    PX_ASSIGN_OR(auto pod_name_view, internal::K8sName(pod_name), return "");
+    auto pod_id = md->k8s_metadata_state().PodIDByName(pod_name_view);
+
+    const auto* pod_info = md->k8s_metadata_state().PodInfoByID(pod_id);
+    if (pod_info == nullptr) {
+      return "";
+    }
+
+    return md->k8s_metadata_state().PodWorkload(pod_info).kind;
+  }
+
+  static udf::ScalarUDFDocBuilder Doc() {
+    return udf::ScalarUDFDocBuilder(
+               "Get the kind of the workload which controls the pod with the specified pod name.")
+        .Details(
+            "Gets the Kubernetes kind (eg. Deployment, StatefulSet, CronJob) of the top-level "
+            "workload that owns the Pod (specified by pod name). If this pod is not controlled "
+            "by anything, returns \"Pod\".")
+        .Example("df.workload_kind = px.pod_name_to_workload_kind(df.pod_name)")
+        .Arg("pod_name", "The Pod name of the Pod to get the workload kind for.")
+        .Returns("The k8s workload kind which controls the Pod with the pod name.");
+  }
+};
+
+/**
+ * @brief Returns the name of the top-level workload that controls the pod of the UPID.
+ */
+class UPIDToWorkloadNameUDF : public ScalarUDF {
+ public:
+  StringValue Exec(FunctionContext* ctx, UInt128Value upid_value) {
+    auto md = GetMetadataState(ctx);
+    auto pod_info = UPIDtoPod(md, upid_value);
+    if (pod_info == nullptr) {
+      return "";
+    }
+
+    auto workload = md->k8s_metadata_state().PodWorkload(pod_info);
+    return absl::Substitute("$0/$1", pod_info->ns(), workload.name);
+  }
+
+  static udf::ScalarUDFDocBuilder Doc() {
+    return udf::ScalarUDFDocBuilder("Get the workload name from a UPID.")
+        .Details(
+            "Gets the name of the top-level Kubernetes workload (eg. Deployment, StatefulSet, "
+            "DaemonSet, CronJob) that controls the process with the given Unique Process ID "
+            "(UPID). If the given process doesn't have an associated Kubernetes Pod, this "
+            "function returns an empty string.")
+        .Example("df.workload = px.upid_to_workload_name(df.upid)")
+        .Arg("upid", "The UPID of the process to get the workload name for.")
+        .Returns("The Kubernetes workload name for the UPID passed in.");
+  }
+
+  // This UDF can currently only run on PEMs, because only PEMs have the UPID information.
+  static udfspb::UDFSourceExecutor Executor() { return udfspb::UDFSourceExecutor::UDF_PEM; }
+};
+
+/**
+ * @brief Returns the kind of the top-level workload that controls the pod of the UPID.
+ */
+class UPIDToWorkloadKindUDF : public ScalarUDF {
+ public:
+  StringValue Exec(FunctionContext* ctx, UInt128Value upid_value) {
+    auto md = GetMetadataState(ctx);
+    auto pod_info = UPIDtoPod(md, upid_value);
+    if (pod_info == nullptr) {
+      return "";
+    }
+
+    return md->k8s_metadata_state().PodWorkload(pod_info).kind;
+  }
+
+  static udf::ScalarUDFDocBuilder Doc() {
+    return udf::ScalarUDFDocBuilder("Get the workload kind from a UPID.")
+        .Details(
+            "Gets the kind of the top-level Kubernetes workload (eg. Deployment, StatefulSet, "
+            "DaemonSet, CronJob) that controls the process with the given Unique Process ID "
+            "(UPID). If the given process doesn't have an associated Kubernetes Pod, this "
+            "function returns an empty string.")
+        .Example("df.workload_kind = px.upid_to_workload_kind(df.upid)")
+        .Arg("upid", "The UPID of the process to get the workload kind for.")
+        .Returns("The Kubernetes workload kind for the UPID passed in.");
+  }
+
+  // This UDF can currently only run on PEMs, because only PEMs have the UPID information.
+  static udfspb::UDFSourceExecutor Executor() { return udfspb::UDFSourceExecutor::UDF_PEM; }
+};
+
 /**
  * @brief Returns the service names for the given pod name.
  */
@@ -2870,6 +3170,47 @@ class UPIDToCmdLineUDF : public ScalarUDF {
   static udfspb::UDFSourceExecutor Executor() { return udfspb::UDFSourceExecutor::UDF_PEM; }
 };
 
+class UPIDToEnvLabelUDF : public ScalarUDF {
+ public:
+  /**
+   * @brief Gets the value of an environment variable label for the upid.
+   *
+   * @param ctx: The function context.
+   * @param upid_value: The UPID value
+   * @param name: The name of the environment variable.
+   * @return StringValue: the value of the environment variable for the UPID.
+   */
+  StringValue Exec(FunctionContext* ctx, UInt128Value upid_value, StringValue name) {
+    auto md = GetMetadataState(ctx);
+    auto upid_uint128 = absl::MakeUint128(upid_value.High64(), upid_value.Low64());
+    auto upid = md::UPID(upid_uint128);
+    auto pid_info = md->GetPIDByUPID(upid);
+    if (pid_info == nullptr) {
+      return "";
+    }
+    auto it = pid_info->env_labels().find(name);
+    if (it == pid_info->env_labels().end()) {
+      return "";
+    }
+    return it->second;
+  }
+
+  static udf::ScalarUDFDocBuilder Doc() {
+    return udf::ScalarUDFDocBuilder("Get an environment variable of a UPID.")
+        .Details(
+            "Gets the value of the named environment variable of the process with the given "
+            "Unique Process ID (UPID). Only environment variables listed in the PEM's "
+            "--metadata_env_labels flag are collected; any other name returns an empty string.")
+        .Example("df.app_version = px.upid_to_env_label(df.upid, 'APP_VERSION')")
+        .Arg("upid", "The UPID to get the environment variable for.")
+        .Arg("name", "The name of the environment variable.")
+        .Returns("The value of the environment variable for the UPID passed in.");
+  }
+
+  // This UDF can currently only run on PEMs, because only PEMs have the UPID information.
+  static udfspb::UDFSourceExecutor Executor() { return udfspb::UDFSourceExecutor::UDF_PEM; }
+};
+
 inline std::string PodInfoToPodQoS(const px::md::PodInfo* pod_info) {
   if (pod_info == nullptr) {
     return "";
@@ -3021,6 +3362,12 @@ class IPToServiceIDUDF : public ScalarUDF {
     if (service_id != "") {
       return service_id;
     }
+    // Then, check the IPs that serve a service without belonging to a pod, such as the addresses
+    // of services without a selector or ExternalName services.
+    service_id = md->k8s_metadata_state().ServiceIDByEndpointIP(ip);
+    if (service_id != "") {
+      return service_id;
+    }
     // Next, check to see if the IP is in the list of of Pod IPs.
     auto pod_id = md->k8s_metadata_state().PodIDByIP(ip);
     if (pod_id == "") {
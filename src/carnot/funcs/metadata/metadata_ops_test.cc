@@ -78,6 +78,7 @@ class MetadataOpsTest : public ::testing::Test {
     // Apply PID updates to metadata state.
     auto upid1 = md::UPID(123, 567, 89101);
     auto pid1 = std::make_unique<md::PIDInfo>(upid1, "exe", "test", "pod1_container_1");
+    pid1->set_env_labels({{"APP_VERSION", "1.4.2"}});
     metadata_state_->AddUPID(upid1, std::move(pid1));
     auto upid2 = md::UPID(123, 567, 468);
     auto pid2 = std::make_unique<md::PIDInfo>(upid2, "exe", "cmdline", "pod2_container_1");
@@ -185,6 +186,56 @@ TEST_F(MetadataOpsTest, upid_to_container_name_test) {
   udf_tester.ForInput(upid3).Expect("");
 }
 
+TEST_F(MetadataOpsTest, upid_to_image_test) {
+  auto function_ctx = std::make_unique<FunctionContext>(metadata_state_, nullptr);
+  auto udf_tester = px::carnot::udf::UDFTester<UPIDToImageUDF>(std::move(function_ctx));
+  auto upid1 = types::UInt128Value(528280977975, 89101);
+  udf_tester.ForInput(upid1).Expect("gcr.io/pixie-oss/demo/app:1.4.2");
+  auto upid2 = types::UInt128Value(528280977975, 468);
+  udf_tester.ForInput(upid2).Expect("");
+  auto upid3 = types::UInt128Value(528280977975, 123);
+  udf_tester.ForInput(upid3).Expect("");
+}
+
+TEST_F(MetadataOpsTest, upid_to_image_tag_test) {
+  auto function_ctx = std::make_unique<FunctionContext>(metadata_state_, nullptr);
+  auto udf_tester = px::carnot::udf::UDFTester<UPIDToImageTagUDF>(std::move(function_ctx));
+  auto upid1 = types::UInt128Value(528280977975, 89101);
+  udf_tester.ForInput(upid1).Expect("1.4.2");
+  auto upid3 = types::UInt128Value(528280977975, 123);
+  udf_tester.ForInput(upid3).Expect("");
+}
+
+TEST_F(MetadataOpsTest, upid_to_image_digest_test) {
+  auto function_ctx = std::make_unique<FunctionContext>(metadata_state_, nullptr);
+  auto udf_tester = px::carnot::udf::UDFTester<UPIDToImageDigestUDF>(std::move(function_ctx));
+  auto upid1 = types::UInt128Value(528280977975, 89101);
+  udf_tester.ForInput(upid1).Expect("sha256:abcdef");
+  auto upid3 = types::UInt128Value(528280977975, 123);
+  udf_tester.ForInput(upid3).Expect("");
+}
+
+TEST_F(MetadataOpsTest, image_tag_and_digest_parsing) {
+  EXPECT_EQ(ImageTag("gcr.io/px/app:1.2.3"), "1.2.3");
+  EXPECT_EQ(ImageTag("localhost:5000/app"), "");
+  EXPECT_EQ(ImageTag("localhost:5000/app:latest"), "latest");
+  EXPECT_EQ(ImageTag("gcr.io/px/app@sha256:abc"), "");
+  EXPECT_EQ(ImageTag(""), "");
+  EXPECT_EQ(ImageDigest("docker-pullable://gcr.io/px/app@sha256:abc"), "sha256:abc");
+  EXPECT_EQ(ImageDigest("sha256:abc"), "sha256:abc");
+  EXPECT_EQ(ImageDigest(""), "");
+}
+
+TEST_F(MetadataOpsTest, upid_to_env_label_test) {
+  auto function_ctx = std::make_unique<FunctionContext>(metadata_state_, nullptr);
+  auto udf_tester = px::carnot::udf::UDFTester<UPIDToEnvLabelUDF>(std::move(function_ctx));
+  auto upid1 = types::UInt128Value(528280977975, 89101);
+  udf_tester.ForInput(upid1, "APP_VERSION").Expect("1.4.2");
+  udf_tester.ForInput(upid1, "GIT_SHA").Expect("");
+  auto upid2 = types::UInt128Value(528280977975, 468);
+  udf_tester.ForInput(upid2, "APP_VERSION").Expect("");
+}
+
 TEST_F(MetadataOpsTest, upid_to_service_id_test) {
   auto function_ctx = std::make_unique<FunctionContext>(metadata_state_, nullptr);
   auto udf_tester = px::carnot::udf::UDFTester<UPIDToServiceIDUDF>(std::move(function_ctx));
@@ -743,6 +794,13 @@ TEST_F(MetadataOpsTest, ip_to_service_id_test) {
   EXPECT_EQ(udf.Exec(function_ctx.get(), "1.1.1.10"), "");
   EXPECT_EQ(udf.Exec(function_ctx.get(), "1.2.1.2"), "");
   EXPECT_EQ(udf.Exec(function_ctx.get(), "127.0.0.2"), "3_uid");
+
+  // IPs of a service which don't belong to a pod.
+  EXPECT_EQ(udf.Exec(function_ctx.get(), "10.0.0.1"), "");
+  updates_->enqueue(px::metadatapb::testutils::CreateSelectorlessServiceUpdatePB());
+  EXPECT_OK(px::md::ApplyK8sUpdates(11, metadata_state_.get(), &md_filter_, updates_.get()));
+  EXPECT_EQ(udf.Exec(function_ctx.get(), "10.0.0.1"), "selectorless_service_uid");
+  EXPECT_EQ(udf.Exec(function_ctx.get(), "10.0.0.2"), "selectorless_service_uid");
 }
 
 TEST_F(MetadataOpsTest, upid_to_qos) {
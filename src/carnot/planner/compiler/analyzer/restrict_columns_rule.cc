@@ -16,6 +16,7 @@
  * SPDX-License-Identifier: Apache-2.0
  */
 
+#include <memory>
 #include <string>
 #include <vector>
 
@@ -33,10 +34,19 @@ bool IsRestrictedColumn(CompilerState* compiler_state, std::string_view table_na
   return set.contains(input_col_name);
 }
 
-StatusOr<bool> RestrictColumnsRule::Apply(IRNode* ir_node) {
-  if (redaction_impl_ == nullptr) {
+bool RestrictColumnsRule::NeedsRedaction(const std::string& table_name) {
+  const auto& options = compiler_state_->redaction_options();
+  if (options.column_redactions.contains(table_name)) {
+    return true;
+  }
+  if (!options.use_full_redaction && !options.use_px_redact_pii_best_effort) {
     return false;
   }
+  // TODO(philkuz) optimize out maps that don't do anything.
+  return compiler_state_->table_names_to_sensitive_columns()->contains(table_name);
+}
+
+StatusOr<bool> RestrictColumnsRule::Apply(IRNode* ir_node) {
   if (!Match(ir_node, MemorySource())) {
     return false;
   }
@@ -44,13 +54,20 @@ StatusOr<bool> RestrictColumnsRule::Apply(IRNode* ir_node) {
   if (!memsrc->is_type_resolved()) {
     return false;
   }
-  // Early exit if the table is not in the sensitive tables list.
-  // TODO(philkuz) optimize out maps that don't do anything.
-  if (!compiler_state_->table_names_to_sensitive_columns()->contains(memsrc->table_name())) {
+  // Early exit if none of the table's columns are redacted.
+  if (!NeedsRedaction(memsrc->table_name())) {
     return false;
   }
 
-  PX_ASSIGN_OR_RETURN(auto new_op, redaction_impl_(memsrc));
+  ColExpressionVector col_exprs;
+  for (const auto& input_col_name : memsrc->resolved_table_type()->ColumnNames()) {
+    PX_ASSIGN_OR_RETURN(ExpressionIR * col_expr, RedactColumn(memsrc, input_col_name));
+    col_exprs.emplace_back(input_col_name, col_expr);
+  }
+  PX_ASSIGN_OR_RETURN(MapIR * new_op,
+                      memsrc->graph()->CreateNode<MapIR>(memsrc->ast(), memsrc, col_exprs,
+                                                         /* keep_input_columns */ false));
+
   // Update all of memsrc's dependencies to point to the new node.
   for (const auto& dep : memsrc->Children()) {
     if (!dep->IsOperator()) {
@@ -69,49 +86,51 @@ StatusOr<bool> RestrictColumnsRule::Apply(IRNode* ir_node) {
   return true;
 }
 
-StatusOr<OperatorIR*> RestrictColumnsRule::RedactAllData(MemorySourceIR* memsrc) {
-  ColExpressionVector col_exprs;
-  for (const auto& input_col_name : memsrc->resolved_table_type()->ColumnNames()) {
-    if (IsRestrictedColumn(compiler_state_, memsrc->table_name(), input_col_name)) {
-      // Replace column with restricted value.
-      PX_ASSIGN_OR_RETURN(StringIR * restricted_ir,
-                          memsrc->graph()->CreateNode<StringIR>(memsrc->ast(), "REDACTED"));
-      col_exprs.emplace_back(input_col_name, restricted_ir);
-      continue;
-    }
+StatusOr<ExpressionIR*> RestrictColumnsRule::RedactColumn(MemorySourceIR* memsrc,
+                                                          const std::string& col_name) {
+  const auto& options = compiler_state_->redaction_options();
+  auto graph = memsrc->graph();
+  bool sensitive = IsRestrictedColumn(compiler_state_, memsrc->table_name(), col_name);
 
-    // Copy the column otherwise.
-    PX_ASSIGN_OR_RETURN(ColumnIR * column_ir,
-                        memsrc->graph()->CreateNode<ColumnIR>(memsrc->ast(), input_col_name,
-                                                              /*parent_op_idx*/ 0));
-    col_exprs.emplace_back(input_col_name, column_ir);
+  if (sensitive && options.use_full_redaction) {
+    // Replace column with restricted value.
+    return graph->CreateNode<StringIR>(memsrc->ast(), "REDACTED");
   }
-  // Create the new map.
-  return memsrc->graph()->CreateNode<MapIR>(memsrc->ast(), memsrc, col_exprs,
-                                            /* keep_input_columns */ false);
-}
 
-StatusOr<OperatorIR*> RestrictColumnsRule::RedactPIIData(MemorySourceIR* memsrc) {
-  ColExpressionVector col_exprs;
-  for (const auto& input_col_name : memsrc->resolved_table_type()->ColumnNames()) {
-    PX_ASSIGN_OR_RETURN(ColumnIR * column_ir,
-                        memsrc->graph()->CreateNode<ColumnIR>(memsrc->ast(), input_col_name,
-                                                              /*parent_op_idx*/ 0));
-    ExpressionIR* new_col_expr_ir = column_ir;
-    if (IsRestrictedColumn(compiler_state_, memsrc->table_name(), input_col_name)) {
-      // Replace column with call to px.redact_pii_best_effort.
-      PX_ASSIGN_OR_RETURN(
-          FuncIR * restricted_ir,
-          memsrc->graph()->CreateNode<FuncIR>(
-              memsrc->ast(), FuncIR::Op{FuncIR::Opcode::non_op, "", "redact_pii_best_effort"},
-              std::vector<ExpressionIR*>{column_ir}));
-      new_col_expr_ir = restricted_ir;
+  auto table_it = options.column_redactions.find(memsrc->table_name());
+  if (table_it != options.column_redactions.end()) {
+    auto col_it = table_it->second.find(col_name);
+    if (col_it != table_it->second.end()) {
+      PX_ASSIGN_OR_RETURN(auto col_type, memsrc->resolved_table_type()->GetColumnType(col_name));
+      bool is_string = col_type->IsValueType() &&
+                       std::static_pointer_cast<ValueType>(col_type)->data_type() ==
+                           types::DataType::STRING;
+      if (col_it->second == ColumnRedactionAction::kHash && is_string) {
+        // Replace column with call to px.redact_hash, so that equal values stay equal.
+        PX_ASSIGN_OR_RETURN(ColumnIR * column_ir, graph->CreateNode<ColumnIR>(
+                                                      memsrc->ast(), col_name, /*parent_op_idx*/ 0));
+        PX_ASSIGN_OR_RETURN(StringIR * salt_ir,
+                            graph->CreateNode<StringIR>(memsrc->ast(), options.hash_salt));
+        return graph->CreateNode<FuncIR>(
+            memsrc->ast(), FuncIR::Op{FuncIR::Opcode::non_op, "", "redact_hash"},
+            std::vector<ExpressionIR*>{column_ir, salt_ir});
+      }
+      // Columns that can't be hashed are hidden instead.
+      return graph->CreateNode<StringIR>(memsrc->ast(), "REDACTED");
     }
-    col_exprs.emplace_back(input_col_name, new_col_expr_ir);
   }
-  // Create the new map.
-  return memsrc->graph()->CreateNode<MapIR>(memsrc->ast(), memsrc, col_exprs,
-                                            /* keep_input_columns */ false);
+
+  PX_ASSIGN_OR_RETURN(ColumnIR * column_ir,
+                      graph->CreateNode<ColumnIR>(memsrc->ast(), col_name, /*parent_op_idx*/ 0));
+  if (sensitive && options.use_px_redact_pii_best_effort) {
+    // Replace column with call to px.redact_pii_best_effort.
+    return graph->CreateNode<FuncIR>(
+        memsrc->ast(), FuncIR::Op{FuncIR::Opcode::non_op, "", "redact_pii_best_effort"},
+        std::vector<ExpressionIR*>{column_ir});
+  }
+
+  // Copy the column otherwise.
+  return column_ir;
 }
 
 }  // namespace compiler
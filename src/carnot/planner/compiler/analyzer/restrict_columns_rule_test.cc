@@ -135,6 +135,90 @@ TEST_F(RulesTest, redact_column_using_px_redact_pii_best_effort) {
   EXPECT_TRUE(map->is_type_resolved());
 }
 
+TEST_F(RulesTest, redact_column_using_column_redactions) {
+  MemorySourceIR* mem_src =
+      graph->CreateNode<MemorySourceIR>(ast, "http_table", std::vector<std::string>{})
+          .ConsumeValueOrDie();
+
+  MemorySinkIR* sink = MakeMemSink(mem_src, "sink");
+  auto http_table = table_store::schema::Relation(
+      std::vector<types::DataType>(
+          {types::DataType::INT64, types::DataType::STRING, types::DataType::STRING}),
+      std::vector<std::string>({"id", "remote_addr", "req_body"}));
+  compiler_state_->relation_map()->emplace("http_table", http_table);
+  // The table has no sensitive columns, so only the column redactions apply.
+  RedactionOptions options;
+  options.column_redactions["http_table"] = {
+      {"id", ColumnRedactionAction::kHash},
+      {"remote_addr", ColumnRedactionAction::kHash},
+      {"req_body", ColumnRedactionAction::kHide},
+  };
+  options.hash_salt = "salt";
+  compiler_state_->set_redaction_options(options);
+
+  // ResolveTypes first.
+  ResolveTypesRule type_rule(compiler_state_.get());
+  ASSERT_OK(type_rule.Execute(graph.get()));
+
+  // Now apply the rule.
+  RestrictColumnsRule rule(compiler_state_.get());
+  auto status = rule.Execute(graph.get());
+  ASSERT_OK(status);
+  EXPECT_TRUE(status.ValueOrDie());
+
+  ASSERT_MATCH(sink->parents()[0], Map());
+
+  auto map = static_cast<MapIR*>(sink->parents()[0]);
+  // Only strings can be hashed, so the id is hidden instead.
+  EXPECT_MATCH(map->col_exprs()[0].node, String("REDACTED"));
+  ASSERT_MATCH(map->col_exprs()[1].node, Func("redact_hash"));
+  auto hash_func = static_cast<FuncIR*>(map->col_exprs()[1].node);
+  ASSERT_EQ(hash_func->all_args().size(), 2);
+  EXPECT_MATCH(hash_func->all_args()[0], ColumnNode("remote_addr"));
+  EXPECT_MATCH(hash_func->all_args()[1], String("salt"));
+  EXPECT_MATCH(map->col_exprs()[2].node, String("REDACTED"));
+
+  EXPECT_TRUE(map->is_type_resolved());
+}
+
+TEST_F(RulesTest, column_redactions_take_precedence_over_pii_redaction) {
+  MemorySourceIR* mem_src =
+      graph->CreateNode<MemorySourceIR>(ast, "sensitive_table", std::vector<std::string>{})
+          .ConsumeValueOrDie();
+
+  MemorySinkIR* sink = MakeMemSink(mem_src, "sink");
+  auto sensitive_table = table_store::schema::Relation(
+      std::vector<types::DataType>(
+          {types::DataType::INT64, types::DataType::STRING, types::DataType::STRING}),
+      std::vector<std::string>({"id", "sensitive_data", "other_data"}));
+  compiler_state_->relation_map()->emplace("sensitive_table", sensitive_table);
+  compiler_state_->table_names_to_sensitive_columns()->emplace(
+      "sensitive_table", absl::flat_hash_set<std::string>{"sensitive_data", "other_data"});
+  RedactionOptions options;
+  options.use_px_redact_pii_best_effort = true;
+  options.column_redactions["sensitive_table"] = {
+      {"sensitive_data", ColumnRedactionAction::kHide},
+  };
+  compiler_state_->set_redaction_options(options);
+
+  // ResolveTypes first.
+  ResolveTypesRule type_rule(compiler_state_.get());
+  ASSERT_OK(type_rule.Execute(graph.get()));
+
+  // Now apply the rule.
+  RestrictColumnsRule rule(compiler_state_.get());
+  auto status = rule.Execute(graph.get());
+  ASSERT_OK(status);
+  EXPECT_TRUE(status.ValueOrDie());
+
+  ASSERT_MATCH(sink->parents()[0], Map());
+
+  auto map = static_cast<MapIR*>(sink->parents()[0]);
+  EXPECT_MATCH(map->col_exprs()[0].node, ColumnNode("id"));
+  EXPECT_MATCH(map->col_exprs()[1].node, String("REDACTED"));
+  EXPECT_MATCH(map->col_exprs()[2].node, Func("redact_pii_best_effort", ColumnNode("other_data")));
+}
+
 }  // namespace compiler
 }  // namespace planner
 }  // namespace carnot
@@ -83,10 +83,24 @@ class IDRegistryKey {
   std::vector<uint64_t> init_arg_hashes_;
 };
 
+// ColumnRedactionAction specifies how a single redacted column is replaced.
+enum class ColumnRedactionAction {
+  kHide,
+  kHash,
+};
+
+using ColumnRedactionMap =
+    absl::flat_hash_map<std::string, absl::flat_hash_map<std::string, ColumnRedactionAction>>;
+
 // RedactionOptions specifies options for how to redact sensitive columns.
 struct RedactionOptions {
   bool use_full_redaction = false;
   bool use_px_redact_pii_best_effort = false;
+  // Map from table name to the columns of the table that are redacted regardless of whether they
+  // are sensitive.
+  ColumnRedactionMap column_redactions;
+  // The salt used for columns redacted with ColumnRedactionAction::kHash.
+  std::string hash_salt;
 };
 
 struct PluginConfig {
@@ -59,6 +59,19 @@ static inline RedactionOptions RedactionOptionsFromPb(
   RedactionOptions options;
   options.use_full_redaction = redaction_options.use_full_redaction();
   options.use_px_redact_pii_best_effort = redaction_options.use_px_redact_pii_best_effort();
+  for (const auto& col : redaction_options.column_redactions()) {
+    auto action = col.action() == distributedpb::RedactionOptions::ColumnRedaction::REDACTION_HASH
+                      ? ColumnRedactionAction::kHash
+                      : ColumnRedactionAction::kHide;
+    auto& table_cols = options.column_redactions[col.table_name()];
+    // If the same column is listed more than once, the strictest action wins.
+    auto it = table_cols.find(col.column_name());
+    if (it != table_cols.end() && it->second == ColumnRedactionAction::kHide) {
+      continue;
+    }
+    table_cols[col.column_name()] = action;
+  }
+  options.hash_salt = redaction_options.hash_salt();
   return options;
 }
 
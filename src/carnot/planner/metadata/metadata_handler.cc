@@ -67,6 +67,12 @@ std::unique_ptr<MetadataHandler> MetadataHandler::Create() {
                                            {"replica_set", "replicaset"},
                                            {MetadataType::UPID, MetadataType::POD_ID,
                                             MetadataType::POD_NAME, MetadataType::REPLICASET_ID});
+  handler->AddObject<NameMetadataProperty>(MetadataType::WORKLOAD_NAME, {"workload"},
+                                           {MetadataType::UPID, MetadataType::POD_ID,
+                                            MetadataType::POD_NAME});
+  handler->AddObject<IdMetadataProperty>(MetadataType::WORKLOAD_KIND, {},
+                                         {MetadataType::UPID, MetadataType::POD_ID,
+                                          MetadataType::POD_NAME});
   handler->AddObject<NameMetadataProperty>(MetadataType::NODE_NAME, {"node"},
                                            {MetadataType::UPID, MetadataType::POD_ID});
   handler->AddObject<NameMetadataProperty>(MetadataType::HOSTNAME, {"host"}, {MetadataType::UPID});
@@ -194,19 +194,28 @@ StatusOr<FuncIR*> ParseNameTuple(IR* ir, const pypa::AstPtr& ast,
   }
 
   auto func = tuple->items()[num_args];
-  if (func->type() != QLObjectType::kFunction) {
+  FuncIR* func_ir = nullptr;
+  if (func->type() == QLObjectType::kFunction) {
+    PX_ASSIGN_OR_RETURN(auto called, std::static_pointer_cast<FuncObject>(func)->Call({}, ast));
+    PX_ASSIGN_OR_RETURN(func_ir, GetArgAs<FuncIR>(called, "last tuple argument"));
+    if (func_ir->all_args().size() != 0) {
+      return func_ir->CreateIRNodeError("Unexpected aggregate function");
+    }
+  } else if (ExprObject::IsExprObject(func) &&
+             Match(static_cast<ExprObject*>(func.get())->expr(), Func())) {
+    // Aggregate functions that take init args are called with only those, such as px.top_k(20).
+    // The columns are added after them.
+    func_ir = static_cast<FuncIR*>(static_cast<ExprObject*>(func.get())->expr());
+    for (const auto& arg : func_ir->all_args()) {
+      if (!Match(arg, DataNode())) {
+        return arg->CreateIRNodeError(
+            "Aggregate function arguments must be constants, the columns are given in the tuple");
+      }
+    }
+  } else {
     return func->CreateError("Expected second tuple argument to be type Func, received $0",
                              func->name());
   }
-  PX_ASSIGN_OR_RETURN(auto called, std::static_pointer_cast<FuncObject>(func)->Call({}, ast));
-
-  PX_ASSIGN_OR_RETURN(FuncIR * func_ir, GetArgAs<FuncIR>(called, "last tuple argument"));
-
-  // The function should be specified as a single function by itself.
-  // This could change in the future.
-  if (func_ir->all_args().size() != 0) {
-    return func_ir->CreateIRNodeError("Unexpected aggregate function");
-  }
 
   // parent_op_idx is 0 because we only have one parent for an aggregate.
   for (auto name : arg_names) {
@@ -44,6 +44,20 @@ StatusOr<QLObjectPtr> UDFHandler(IR* graph, const std::string& name, const pypa:
   return ExprObject::Create(node, visitor);
 }
 
+StatusOr<QLObjectPtr> UDFWithArgsHandler(IR* graph, const std::string& name,
+                                         const pypa::AstPtr& ast, const ParsedArgs& args,
+                                         ASTVisitor* visitor) {
+  std::vector<ExpressionIR*> expr_args;
+  for (const auto& arg : args.variable_args()) {
+    PX_ASSIGN_OR_RETURN(ExpressionIR * expr, GetArgAs<ExpressionIR>(arg, name));
+    expr_args.push_back(expr);
+  }
+  PX_ASSIGN_OR_RETURN(FuncIR * node, graph->CreateNode<FuncIR>(
+                                         ast, FuncIR::Op{FuncIR::Opcode::non_op, "", name},
+                                         expr_args));
+  return ExprObject::Create(node, visitor);
+}
+
 class DataframeTest : public QLObjectTest {
  protected:
   void SetUp() override {
@@ -60,6 +74,15 @@ class DataframeTest : public QLObjectTest {
                                          std::placeholders::_2, std::placeholders::_3),
                                ast_visitor.get())
                                .ConsumeValueOrDie());
+    var_table->Add("top_k", FuncObject::Create(
+                                "top_k", {}, {},
+                                /* has_variable_len_args */ true,
+                                /* has_variable_len_kwargs */ false,
+                                std::bind(&UDFWithArgsHandler, graph.get(), "top_k",
+                                          std::placeholders::_1, std::placeholders::_2,
+                                          std::placeholders::_3),
+                                ast_visitor.get())
+                                .ConsumeValueOrDie());
   }
 
   std::shared_ptr<VarTable> var_table;
@@ -197,6 +220,27 @@ TEST_F(DataframeTest, Agg) {
   ASSERT_THAT(col_names, UnorderedElementsAre("col1", "col2"));
 }
 
+TEST_F(DataframeTest, Agg_WithInitArgs) {
+  ASSERT_OK(ParseScript(var_table, "agg = df.agg(top=('col1', top_k(5)))"));
+  auto var = var_table->Lookup("agg");
+  OperatorIR* op = static_cast<Dataframe*>(var.get())->op();
+  ASSERT_MATCH(op, BlockingAgg());
+  BlockingAggIR* agg = static_cast<BlockingAggIR*>(op);
+  ASSERT_EQ(agg->aggregate_expressions().size(), 1);
+
+  ASSERT_MATCH(agg->aggregate_expressions()[0].node, Func());
+  FuncIR* fn = static_cast<FuncIR*>(agg->aggregate_expressions()[0].node);
+  EXPECT_EQ(fn->func_name(), "top_k");
+  ASSERT_EQ(fn->all_args().size(), 2);
+  ASSERT_MATCH(fn->all_args()[0], Int(5));
+  ASSERT_MATCH(fn->all_args()[1], ColumnNode("col1"));
+}
+
+TEST_F(DataframeTest, Agg_InitArgsMustBeConstants) {
+  EXPECT_THAT(ParseScript(var_table, "df.agg(top=('col1', top_k(df.col2)))"),
+              HasCompilerError("Aggregate function arguments must be constants"));
+}
+
 TEST_F(DataframeTest, Agg_FailsWithPositionalArgs) {
   EXPECT_THAT(ParseScript(var_table, "df.agg(mean)"),
               HasCompilerError("agg.* takes 0 arguments but 1 .* given"));
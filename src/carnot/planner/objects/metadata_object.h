@@ -54,6 +54,8 @@ class MetadataObject : public QLObject {
   * deployment_name ("deployment"): Sources: "upid","deployment_id","pod_id","pod_name","replicaset_name", "replicaset_id"
   * replicaset_id ("replica_set_id"): Sources: "upid","pod_id","pod_name", "replicaset_name"
   * replicaset_name ("replica_set", "replicaset"): Sources: "upid","pod_id","pod_name", "replicaset_id"
+  * workload_name ("workload"): Sources: "upid","pod_id","pod_name"
+  * workload_kind: Sources: "upid","pod_id","pod_name"
   * namespace: Sources: "upid"
   * node_name ("node"): Sources: "upid"
   * hostname ("host"): Sources: "upid"
@@ -31,6 +31,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"google.golang.org/grpc"
 
 	"px.dev/pixie/src/api/proto/cloudpb"
 	"px.dev/pixie/src/api/proto/vizierpb"
@@ -38,14 +39,18 @@ import (
 	"px.dev/pixie/src/cloud/api/controllers"
 	"px.dev/pixie/src/cloud/api/ptproxy"
 	"px.dev/pixie/src/cloud/autocomplete"
+	"px.dev/pixie/src/cloud/shared/auditlog"
 	"px.dev/pixie/src/cloud/shared/esutils"
 	"px.dev/pixie/src/cloud/shared/idprovider"
+	"px.dev/pixie/src/cloud/shared/metering"
+	"px.dev/pixie/src/cloud/shared/objstore"
 	"px.dev/pixie/src/cloud/shared/vzshard"
 	"px.dev/pixie/src/shared/services"
 	svcEnv "px.dev/pixie/src/shared/services/env"
 	"px.dev/pixie/src/shared/services/handler"
 	"px.dev/pixie/src/shared/services/healthz"
 	"px.dev/pixie/src/shared/services/msgbus"
+	"px.dev/pixie/src/shared/services/rbac"
 	"px.dev/pixie/src/shared/services/server"
 	"px.dev/pixie/src/utils/script"
 )
@@ -66,12 +71,25 @@ func init() {
 
 	pflag.String("auth_connector_name", "", "If any, the name of the auth connector to be used with Pixie")
 	pflag.String("auth_connector_callback_url", "", "If any, the callback URL for the auth connector")
+
+	pflag.String("scim_identity_provider", "saml", "The identity provider that users provisioned over SCIM log in with")
+
+	pflag.String("audit_index_name", "audit_log", "The elastic index name for the audit log")
+	pflag.String("audit_retention", "365d", "How long audit events are kept in elastic")
+	pflag.String("audit_webhook_url", "", "If any, the URL that audit events are posted to")
+	pflag.String("audit_webhook_secret", "", "The secret used to sign audit webhook requests")
+	pflag.String("audit_export_bucket", "", "If any, the bucket in the configured object storage that audit events are exported to")
+	pflag.String("audit_export_prefix", "audit", "The prefix for audit objects in the export bucket")
+	pflag.String("usage_index_name", "usage", "The elastic index name for metered usage")
+	pflag.String("usage_retention", "400d", "How long metered usage is kept in elastic")
+	pflag.String("usage_quota_index_name", "usage_quotas", "The elastic index name for usage quotas")
 }
 
 func main() {
 	services.SetupService("api-service", 51200)
 	services.SetupSSLClientFlags()
 	vzshard.SetupFlags()
+	objstore.SetupFlags()
 	services.PostFlagSetupAndParse()
 	services.CheckServiceFlags()
 	services.CheckSSLClientFlags()
@@ -120,7 +138,7 @@ func main() {
 		log.WithError(err).Fatal("Failed to init Hydra + Kratos idprovider client")
 	}
 
-	ps, drps, err := apienv.NewPluginServiceClients()
+	ps, drps, arps, err := apienv.NewPluginServiceClients()
 	if err != nil {
 		log.WithError(err).Fatal("Failed to connect to plugin service")
 	}
@@ -144,6 +162,54 @@ func main() {
 		log.WithError(err).Fatal("Could not connect to elastic")
 	}
 
+	auditStore, err := auditlog.NewElasticStore(es, viper.GetString("audit_index_name"), viper.GetString("audit_retention"))
+	if err != nil {
+		log.WithError(err).Fatal("Could not create audit log index")
+	}
+	var auditExporters []auditlog.Exporter
+	if viper.GetString("audit_webhook_url") != "" {
+		auditExporters = append(auditExporters, auditlog.NewWebhookExporter(viper.GetString("audit_webhook_url"), viper.GetString("audit_webhook_secret")))
+	}
+	if viper.GetString("audit_export_bucket") != "" {
+		exportStore, err := objstore.New(context.Background(), objstore.ConfigFromFlags(viper.GetString("audit_export_bucket"), viper.GetString("audit_export_prefix")))
+		if err != nil {
+			log.WithError(err).Fatal("Could not create audit log object storage exporter")
+		}
+		auditExporters = append(auditExporters, auditlog.NewStoreExporter(exportStore))
+	}
+	auditLog := auditlog.NewLogger(auditStore, auditExporters...)
+	auditLog.Start()
+	defer auditLog.Stop()
+	// Other services publish their audit events over NATS.
+	auditSub, err := auditlog.SubscribeNATS(nc, auditLog)
+	if err != nil {
+		log.WithError(err).Fatal("Could not subscribe to audit events")
+	}
+	defer func() {
+		if err := auditSub.Unsubscribe(); err != nil {
+			log.WithError(err).Error("Failed to unsubscribe from audit events")
+		}
+	}()
+
+	usageStore, err := metering.NewElasticStore(es, viper.GetString("usage_index_name"), viper.GetString("usage_retention"))
+	if err != nil {
+		log.WithError(err).Fatal("Could not create usage index")
+	}
+	quotaStore, err := metering.NewElasticQuotaStore(es, viper.GetString("usage_quota_index_name"))
+	if err != nil {
+		log.WithError(err).Fatal("Could not create usage quota index")
+	}
+	usageCollector := metering.NewCollector(usageStore)
+	usageCollector.Start()
+	defer usageCollector.Stop()
+	quotaEnforcer := metering.NewEnforcer(quotaStore, usageStore)
+	// Viziers report the usage of the scripts that they run on their own, such as retention scripts.
+	vizierUsageSub, err := metering.SubscribeVizierUsage(nc, vc, usageCollector)
+	if err != nil {
+		log.WithError(err).Fatal("Could not subscribe to Vizier usage reports")
+	}
+	defer vizierUsageSub.Unsubscribe()
+
 	mux := http.NewServeMux()
 	mux.Handle("/api/auth/signup", handler.New(env, controllers.AuthSignupHandler))
 	mux.Handle("/api/auth/login", handler.New(env, controllers.AuthLoginHandler))
@@ -151,12 +217,20 @@ func main() {
 	mux.Handle("/api/auth/logout", handler.New(env, controllers.AuthLogoutHandler))
 	mux.Handle("/api/auth/refetch", handler.New(env, controllers.AuthRefetchHandler))
 	mux.Handle("/api/auth/oauth/login", handler.New(env, controllers.AuthOAuthLoginHandler))
+	scim := &controllers.SCIMServer{
+		ProfileServiceClient: pc,
+		OrgServiceClient:     oc,
+		IdentityProvider:     viper.GetString("scim_identity_provider"),
+	}
+	mux.Handle(controllers.SCIMPathPrefix+"/", controllers.WithSCIMAuthMiddleware(env, scim))
+	mux.Handle(controllers.AuditLogPath, controllers.WithAugmentedAuthMiddleware(env, &controllers.AuditLogServer{Store: auditStore}))
 	// This is an unauthenticated path that will check and validate if a particular domain
 	// is available for registration. This need to be unauthenticated because we need to check this before
 	// the user registers.
 	mux.Handle("/api/authorized", controllers.WithAugmentedAuthMiddleware(env, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintf(w, "OK")
 	})))
+	mux.Handle(controllers.OpenAPIPath, controllers.OpenAPIHandler())
 
 	if viper.GetString("auth_connector_name") != "" {
 		mux.Handle(fmt.Sprintf("/api/auth/%s", viper.GetString("auth_connector_name")), handler.New(env, controllers.AuthConnectorHandler))
@@ -184,6 +258,11 @@ func main() {
 			"/px.cloudapi.ConfigService/GetConfigForOperator": true,
 			"/px.cloudapi.AuthService/Login":                  true,
 		},
+		// Roles are checked after the default middleware has authenticated the caller.
+		GRPCServerOpts: []grpc.ServerOption{
+			grpc.ChainUnaryInterceptor(rbac.UnaryServerInterceptor(controllers.CloudAPIPolicy)),
+			grpc.ChainStreamInterceptor(rbac.StreamServerInterceptor(controllers.CloudAPIPolicy)),
+		},
 	}
 
 	domainName := viper.GetString("domain_name")
@@ -198,24 +277,28 @@ func main() {
 
 	artifactTrackerServer := controllers.ArtifactTrackerServer{
 		ArtifactTrackerClient: at,
+		AuditLog:              auditLog,
 	}
 	cloudpb.RegisterArtifactTrackerServer(s.GRPCServer(), artifactTrackerServer)
 
-	cis := &controllers.VizierClusterInfo{VzMgr: vc, ArtifactTrackerClient: at}
+	cis := &controllers.VizierClusterInfo{VzMgr: vc, ArtifactTrackerClient: at, AuditLog: auditLog}
 	cloudpb.RegisterVizierClusterInfoServer(s.GRPCServer(), cis)
 
-	vdks := &controllers.VizierDeploymentKeyServer{VzDeploymentKey: vk}
+	vdks := &controllers.VizierDeploymentKeyServer{VzDeploymentKey: vk, AuditLog: auditLog}
 	cloudpb.RegisterVizierDeploymentKeyManagerServer(s.GRPCServer(), vdks)
 
-	aks := &controllers.APIKeyServer{APIKeyClient: ak}
+	aks := &controllers.APIKeyServer{APIKeyClient: ak, AuditLog: auditLog}
 	cloudpb.RegisterAPIKeyManagerServer(s.GRPCServer(), aks)
 
 	authServer := &controllers.AuthServer{AuthClient: ac}
 	cloudpb.RegisterAuthServiceServer(s.GRPCServer(), authServer)
 
 	vpt := ptproxy.NewVizierPassThroughProxy(nc, vc)
+	vpt.EnableMetering(usageCollector, quotaEnforcer)
 	vizierpb.RegisterVizierServiceServer(s.GRPCServer(), vpt)
 	vizierpb.RegisterVizierDebugServiceServer(s.GRPCServer(), vpt)
+	mux.Handle(controllers.ScriptExecutionPath, controllers.WithAugmentedAuthMiddleware(env, &controllers.ScriptExecutionGateway{Vizier: vpt}))
+	mux.Handle(controllers.ScriptStreamPath, controllers.WithAugmentedAuthMiddleware(env, &controllers.ScriptStreamGateway{Vizier: vpt, AllowedOrigins: allowedOrigins}))
 
 	sm, err := apienv.NewScriptMgrServiceClient()
 	if err != nil {
@@ -224,6 +307,25 @@ func main() {
 	sms := &controllers.ScriptMgrServer{ScriptMgr: sm}
 	cloudpb.RegisterScriptMgrServer(s.GRPCServer(), sms)
 
+	sr, err := apienv.NewScriptRegistryServiceClient()
+	if err != nil {
+		log.WithError(err).Fatal("Failed to init script registry client.")
+	}
+	srs := &controllers.ScriptRegistryServer{Registry: sr, AuditLog: auditLog}
+	cloudpb.RegisterScriptRegistryServer(s.GRPCServer(), srs)
+	mux.Handle(controllers.ScriptRegistryBundlePath, controllers.WithAugmentedAuthMiddleware(env, srs))
+
+	qh, err := apienv.NewQueryHistoryServiceClient()
+	if err != nil {
+		log.WithError(err).Fatal("Failed to init query history client.")
+	}
+	qhs := &controllers.QueryHistoryServer{QueryHistory: qh, AuditLog: auditLog}
+	cloudpb.RegisterQueryHistoryServer(s.GRPCServer(), qhs)
+	mux.Handle(controllers.SharedQueryPathPrefix, controllers.WithAugmentedAuthMiddleware(env, qhs))
+
+	usageServer := &controllers.UsageServer{Usage: usageStore, Quotas: quotaStore, Enforcer: quotaEnforcer, AuditLog: auditLog}
+	cloudpb.RegisterUsageServiceServer(s.GRPCServer(), usageServer)
+
 	mdIndexName := viper.GetString("md_index_name")
 	if mdIndexName == "" {
 		log.Fatal("Must specify a name for the elastic index.")
@@ -265,18 +367,25 @@ func main() {
 	as := &controllers.AutocompleteServer{Suggester: esSuggester}
 	cloudpb.RegisterAutocompleteServiceServer(s.GRPCServer(), as)
 
-	os := &controllers.OrganizationServiceServer{ProfileServiceClient: pc, AuthServiceClient: ac, OrgServiceClient: oc}
+	os := &controllers.OrganizationServiceServer{ProfileServiceClient: pc, AuthServiceClient: ac, OrgServiceClient: oc, AuditLog: auditLog}
 	cloudpb.RegisterOrganizationServiceServer(s.GRPCServer(), os)
 
-	us := &controllers.UserServiceServer{ProfileServiceClient: pc, OrgServiceClient: oc}
+	us := &controllers.UserServiceServer{ProfileServiceClient: pc, OrgServiceClient: oc, AuditLog: auditLog}
 	cloudpb.RegisterUserServiceServer(s.GRPCServer(), us)
 
 	cs := &controllers.ConfigServiceServer{ConfigServiceClient: cm}
 	cloudpb.RegisterConfigServiceServer(s.GRPCServer(), cs)
 
-	pss := &controllers.PluginServiceServer{PluginServiceClient: ps, DataRetentionPluginServiceClient: drps}
+	pss := &controllers.PluginServiceServer{PluginServiceClient: ps, DataRetentionPluginServiceClient: drps, AlertRoutePluginServiceClient: arps, AuditLog: auditLog}
 	cloudpb.RegisterPluginServiceServer(s.GRPCServer(), pss)
 
+	vf, err := apienv.NewVZFleetServiceClient()
+	if err != nil {
+		log.WithError(err).Fatal("Failed to init vzmgr fleet client.")
+	}
+	fs := &controllers.FleetServer{VzFleet: vf, VizierClusterInfo: cis, PluginServer: pss, Vizier: vpt, AuditLog: auditLog}
+	cloudpb.RegisterFleetManagerServer(s.GRPCServer(), fs)
+
 	gqlEnv := controllers.GraphQLEnv{
 		ArtifactTrackerServer: artifactTrackerServer,
 		VizierClusterInfo:     cis,
@@ -32,16 +32,17 @@ func init() {
 }
 
 // NewPluginServiceClients creates the vzmgr RPC client stubs.
-func NewPluginServiceClients() (pluginpb.PluginServiceClient, pluginpb.DataRetentionPluginServiceClient, error) {
+func NewPluginServiceClients() (pluginpb.PluginServiceClient, pluginpb.DataRetentionPluginServiceClient, pluginpb.AlertRoutePluginServiceClient, error) {
 	dialOpts, err := services.GetGRPCClientDialOpts()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	pluginChan, err := grpc.Dial(viper.GetString("plugin_service"), dialOpts...)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
-	return pluginpb.NewPluginServiceClient(pluginChan), pluginpb.NewDataRetentionPluginServiceClient(pluginChan), nil
+	return pluginpb.NewPluginServiceClient(pluginChan), pluginpb.NewDataRetentionPluginServiceClient(pluginChan),
+		pluginpb.NewAlertRoutePluginServiceClient(pluginChan), nil
 }
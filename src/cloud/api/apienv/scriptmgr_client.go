@@ -45,3 +45,33 @@ func NewScriptMgrServiceClient() (scriptmgrpb.ScriptMgrServiceClient, error) {
 
 	return scriptmgrpb.NewScriptMgrServiceClient(authChannel), nil
 }
+
+// NewScriptRegistryServiceClient creates a new script registry RPC client stub.
+func NewScriptRegistryServiceClient() (scriptmgrpb.ScriptRegistryServiceClient, error) {
+	dialOpts, err := services.GetGRPCClientDialOpts()
+	if err != nil {
+		return nil, err
+	}
+
+	registryChannel, err := grpc.Dial(viper.GetString("scriptmgr_service"), dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scriptmgrpb.NewScriptRegistryServiceClient(registryChannel), nil
+}
+
+// NewQueryHistoryServiceClient creates a new query history RPC client stub.
+func NewQueryHistoryServiceClient() (scriptmgrpb.QueryHistoryServiceClient, error) {
+	dialOpts, err := services.GetGRPCClientDialOpts()
+	if err != nil {
+		return nil, err
+	}
+
+	historyChannel, err := grpc.Dial(viper.GetString("scriptmgr_service"), dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scriptmgrpb.NewQueryHistoryServiceClient(historyChannel), nil
+}
@@ -45,3 +45,18 @@ func NewVZMgrServiceClients() (vzmgrpb.VZMgrServiceClient, vzmgrpb.VZDeploymentK
 
 	return vzmgrpb.NewVZMgrServiceClient(vzMgrChan), vzmgrpb.NewVZDeploymentKeyServiceClient(vzMgrChan), nil
 }
+
+// NewVZFleetServiceClient creates the vzmgr fleet RPC client stub.
+func NewVZFleetServiceClient() (vzmgrpb.VZFleetServiceClient, error) {
+	dialOpts, err := services.GetGRPCClientDialOpts()
+	if err != nil {
+		return nil, err
+	}
+
+	vzMgrChan, err := grpc.Dial(viper.GetString("vzmgr_service"), dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return vzmgrpb.NewVZFleetServiceClient(vzMgrChan), nil
+}
@@ -26,31 +26,39 @@ import (
 	"px.dev/pixie/src/api/proto/cloudpb"
 	"px.dev/pixie/src/api/proto/uuidpb"
 	"px.dev/pixie/src/cloud/auth/authpb"
+	"px.dev/pixie/src/cloud/shared/auditlog"
 )
 
 // APIKeyServer is the server that implements the APIKeyManager gRPC service.
 type APIKeyServer struct {
 	APIKeyClient authpb.APIKeyServiceClient
+	AuditLog     auditlog.Recorder
 }
 
 func apiKeyToCloudAPI(key *authpb.APIKey) *cloudpb.APIKey {
 	return &cloudpb.APIKey{
-		ID:        key.ID,
-		OrgID:     key.OrgID,
-		UserID:    key.UserID,
-		Key:       key.Key,
-		CreatedAt: key.CreatedAt,
-		Desc:      key.Desc,
+		ID:         key.ID,
+		OrgID:      key.OrgID,
+		UserID:     key.UserID,
+		Key:        key.Key,
+		CreatedAt:  key.CreatedAt,
+		Desc:       key.Desc,
+		Scopes:     key.Scopes,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
 	}
 }
 
 func apiKeyMetadataToCloudAPI(key *authpb.APIKeyMetadata) *cloudpb.APIKeyMetadata {
 	return &cloudpb.APIKeyMetadata{
-		ID:        key.ID,
-		OrgID:     key.OrgID,
-		UserID:    key.UserID,
-		CreatedAt: key.CreatedAt,
-		Desc:      key.Desc,
+		ID:         key.ID,
+		OrgID:      key.OrgID,
+		UserID:     key.UserID,
+		CreatedAt:  key.CreatedAt,
+		Desc:       key.Desc,
+		Scopes:     key.Scopes,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
 	}
 }
 
@@ -61,7 +69,12 @@ func (v *APIKeyServer) Create(ctx context.Context, req *cloudpb.CreateAPIKeyRequ
 		return nil, err
 	}
 
-	resp, err := v.APIKeyClient.Create(ctx, &authpb.CreateAPIKeyRequest{Desc: req.Desc})
+	resp, err := v.APIKeyClient.Create(ctx, &authpb.CreateAPIKeyRequest{
+		Desc:      req.Desc,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	})
+	recordAudit(ctx, v.AuditLog, auditlog.ActionAPIKeyCreated, auditResourceID(resp.GetID()), err)
 	if err != nil {
 		return nil, err
 	}
@@ -112,7 +125,9 @@ func (v *APIKeyServer) Delete(ctx context.Context, uuid *uuidpb.UUID) (*types.Em
 	if err != nil {
 		return nil, err
 	}
-	return v.APIKeyClient.Delete(ctx, uuid)
+	resp, err := v.APIKeyClient.Delete(ctx, uuid)
+	recordAudit(ctx, v.AuditLog, auditlog.ActionAPIKeyDeleted, auditResourceID(uuid), err)
+	return resp, err
 }
 
 // LookupAPIKey gets the complete API key information using just the Key.
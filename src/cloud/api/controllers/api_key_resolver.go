@@ -23,6 +23,7 @@ import (
 	"sort"
 
 	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
 	"github.com/graph-gophers/graphql-go"
 
 	"px.dev/pixie/src/api/proto/cloudpb"
@@ -34,6 +35,9 @@ type APIKeyMetadataResolver struct {
 	id          uuid.UUID
 	createdAtNs int64
 	desc        string
+	scopes      []string
+	expiresAt   *types.Timestamp
+	lastUsedAt  *types.Timestamp
 }
 
 // ID returns API key ID.
@@ -51,6 +55,24 @@ func (d *APIKeyMetadataResolver) Desc() string {
 	return d.desc
 }
 
+// Scopes returns the role scopes that restrict the key.
+func (d *APIKeyMetadataResolver) Scopes() []string {
+	if d.scopes == nil {
+		return []string{}
+	}
+	return d.scopes
+}
+
+// ExpiresAtMs returns the time at which the API key expires, if it does.
+func (d *APIKeyMetadataResolver) ExpiresAtMs() *float64 {
+	return timestampToMs(d.expiresAt)
+}
+
+// LastUsedAtMs returns the time at which the API key was last used, if it was.
+func (d *APIKeyMetadataResolver) LastUsedAtMs() *float64 {
+	return timestampToMs(d.lastUsedAt)
+}
+
 // APIKeyResolver is the resolver responsible for API keys.
 type APIKeyResolver struct {
 	APIKeyMetadataResolver
@@ -62,10 +84,24 @@ func (d *APIKeyResolver) Key() string {
 	return d.key
 }
 
+type createAPIKeyArgs struct {
+	Scopes      *[]string
+	ExpiresAtMs *float64
+}
+
 // CreateAPIKey creates a new API key.
-func (q *QueryResolver) CreateAPIKey(ctx context.Context) (*APIKeyResolver, error) {
+func (q *QueryResolver) CreateAPIKey(ctx context.Context, args *createAPIKeyArgs) (*APIKeyResolver, error) {
+	if err := authorizeGQL(ctx, "/px.cloudapi.APIKeyManager/Create", nil); err != nil {
+		return nil, err
+	}
+	req := &cloudpb.CreateAPIKeyRequest{
+		ExpiresAt: msToTimestamp(args.ExpiresAtMs),
+	}
+	if args.Scopes != nil {
+		req.Scopes = *args.Scopes
+	}
 	grpcAPI := q.Env.APIKeyMgr
-	res, err := grpcAPI.Create(ctx, &cloudpb.CreateAPIKeyRequest{})
+	res, err := grpcAPI.Create(ctx, req)
 	if err != nil {
 		return nil, rpcErrorHelper(err)
 	}
@@ -83,6 +119,9 @@ func apiKeyToResolver(key *cloudpb.APIKey) (*APIKeyResolver, error) {
 			id:          keyID,
 			createdAtNs: key.CreatedAt.Seconds*NanosPerSecond + int64(key.CreatedAt.Nanos),
 			desc:        key.Desc,
+			scopes:      key.Scopes,
+			expiresAt:   key.ExpiresAt,
+			lastUsedAt:  key.LastUsedAt,
 		},
 		key: key.Key,
 	}, nil
@@ -99,6 +138,9 @@ func apiKeyMetadatasToResolver(mds []*cloudpb.APIKeyMetadata) ([]*APIKeyMetadata
 			id:          mdu,
 			createdAtNs: md.CreatedAt.Seconds*NanosPerSecond + int64(md.CreatedAt.Nanos),
 			desc:        md.Desc,
+			scopes:      md.Scopes,
+			expiresAt:   md.ExpiresAt,
+			lastUsedAt:  md.LastUsedAt,
 		}
 		mdrs = append(mdrs, resolved)
 	}
@@ -135,6 +177,9 @@ func (q *QueryResolver) APIKey(ctx context.Context, args *getOrDeleteAPIKeyArgs)
 
 // DeleteAPIKey deletes a specific API key.
 func (q *QueryResolver) DeleteAPIKey(ctx context.Context, args *getOrDeleteAPIKeyArgs) (bool, error) {
+	if err := authorizeGQL(ctx, "/px.cloudapi.APIKeyManager/Delete", nil); err != nil {
+		return false, err
+	}
 	grpcAPI := q.Env.APIKeyMgr
 	_, err := grpcAPI.Delete(ctx, utils.ProtoFromUUIDStrOrNil(string(args.ID)))
 	if err != nil {
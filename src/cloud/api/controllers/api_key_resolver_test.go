@@ -26,6 +26,7 @@ import (
 	"github.com/gogo/protobuf/types"
 	"github.com/golang/mock/gomock"
 	"github.com/graph-gophers/graphql-go/gqltesting"
+	"github.com/stretchr/testify/require"
 
 	"px.dev/pixie/src/api/proto/cloudpb"
 	"px.dev/pixie/src/cloud/api/controllers/testutils"
@@ -275,6 +276,61 @@ func TestCreateAPIKey(t *testing.T) {
 	}
 }
 
+func TestCreateAPIKey_ScopedExpiring(t *testing.T) {
+	keyID := "7ba7b810-9dad-11d1-80b4-00c04fd430c8"
+
+	gqlEnv, mockClients, cleanup := testutils.CreateTestGraphQLEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	createTimePb, err := types.TimestampProto(time.Date(2020, 03, 9, 17, 46, 100, 1232409, time.UTC))
+	require.NoError(t, err)
+	expiresAtPb, err := types.TimestampProto(time.Date(2030, 01, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	mockClients.MockAPIKey.EXPECT().
+		Create(gomock.Any(), &cloudpb.CreateAPIKeyRequest{
+			Scopes:    []string{"role:viewer"},
+			ExpiresAt: expiresAtPb,
+		}).
+		Return(&cloudpb.APIKey{
+			ID:        utils.ProtoFromUUIDStrOrNil(keyID),
+			Key:       "foobar",
+			CreatedAt: createTimePb,
+			Desc:      "key description",
+			Scopes:    []string{"role:viewer"},
+			ExpiresAt: expiresAtPb,
+		}, nil)
+
+	gqlSchema := LoadSchema(gqlEnv)
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema:  gqlSchema,
+			Context: ctx,
+			Query: `
+				mutation {
+					CreateAPIKey(scopes: ["role:viewer"], expiresAtMs: 1893456000000.0) {
+						id
+						scopes
+						expiresAtMs
+						lastUsedAtMs
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"CreateAPIKey": {
+						"id": "7ba7b810-9dad-11d1-80b4-00c04fd430c8",
+						"scopes": ["role:viewer"],
+						"expiresAtMs": 1893456000000,
+						"lastUsedAtMs": null
+					}
+				}
+			`,
+		},
+	})
+}
+
 func TestDeleteAPIKey(t *testing.T) {
 	tests := []struct {
 		name string
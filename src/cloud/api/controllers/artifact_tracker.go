@@ -27,13 +27,17 @@ import (
 
 	"px.dev/pixie/src/api/proto/cloudpb"
 	"px.dev/pixie/src/cloud/artifact_tracker/artifacttrackerpb"
+	"px.dev/pixie/src/cloud/shared/auditlog"
 	"px.dev/pixie/src/shared/artifacts/versionspb"
+	"px.dev/pixie/src/shared/services/authcontext"
 	srvutils "px.dev/pixie/src/shared/services/utils"
+	"px.dev/pixie/src/utils"
 )
 
 // ArtifactTrackerServer is the GRPC server responsible for providing access to artifacts.
 type ArtifactTrackerServer struct {
 	ArtifactTrackerClient artifacttrackerpb.ArtifactTrackerClient
+	AuditLog              auditlog.Recorder
 }
 
 func getArtifactTypeFromCloudProto(a cloudpb.ArtifactType) versionspb.ArtifactType {
@@ -42,10 +46,16 @@ func getArtifactTypeFromCloudProto(a cloudpb.ArtifactType) versionspb.ArtifactTy
 		return versionspb.AT_LINUX_AMD64
 	case cloudpb.AT_DARWIN_AMD64:
 		return versionspb.AT_DARWIN_AMD64
+	case cloudpb.AT_LINUX_ARM64:
+		return versionspb.AT_LINUX_ARM64
+	case cloudpb.AT_DARWIN_ARM64:
+		return versionspb.AT_DARWIN_ARM64
 	case cloudpb.AT_CONTAINER_SET_YAMLS:
 		return versionspb.AT_CONTAINER_SET_YAMLS
 	case cloudpb.AT_CONTAINER_SET_LINUX_AMD64:
 		return versionspb.AT_CONTAINER_SET_LINUX_AMD64
+	case cloudpb.AT_CONTAINER_SET_LINUX_ARM64:
+		return versionspb.AT_CONTAINER_SET_LINUX_ARM64
 	case cloudpb.AT_CONTAINER_SET_TEMPLATE_YAMLS:
 		return versionspb.AT_CONTAINER_SET_TEMPLATE_YAMLS
 	default:
@@ -59,10 +69,16 @@ func getArtifactTypeFromVersionsProto(a versionspb.ArtifactType) cloudpb.Artifac
 		return cloudpb.AT_LINUX_AMD64
 	case versionspb.AT_DARWIN_AMD64:
 		return cloudpb.AT_DARWIN_AMD64
+	case versionspb.AT_LINUX_ARM64:
+		return cloudpb.AT_LINUX_ARM64
+	case versionspb.AT_DARWIN_ARM64:
+		return cloudpb.AT_DARWIN_ARM64
 	case versionspb.AT_CONTAINER_SET_YAMLS:
 		return cloudpb.AT_CONTAINER_SET_YAMLS
 	case versionspb.AT_CONTAINER_SET_LINUX_AMD64:
 		return cloudpb.AT_CONTAINER_SET_LINUX_AMD64
+	case versionspb.AT_CONTAINER_SET_LINUX_ARM64:
+		return cloudpb.AT_CONTAINER_SET_LINUX_ARM64
 	case versionspb.AT_CONTAINER_SET_TEMPLATE_YAMLS:
 		return cloudpb.AT_CONTAINER_SET_TEMPLATE_YAMLS
 	default:
@@ -149,5 +165,72 @@ func (a ArtifactTrackerServer) GetDownloadLink(ctx context.Context, req *cloudpb
 		Url:        resp.Url,
 		SHA256:     resp.SHA256,
 		ValidUntil: resp.ValidUntil,
+		Signature:  resp.Signature,
 	}, nil
 }
+
+func orgBrandingToCloudProto(b *artifacttrackerpb.OrgBranding) *cloudpb.OrgBranding {
+	return &cloudpb.OrgBranding{
+		Announcement:    b.Announcement,
+		AnnouncementURL: b.AnnouncementURL,
+		DocsURL:         b.DocsURL,
+		SupportContact:  b.SupportContact,
+	}
+}
+
+// GetOrgBranding gets the branding of the user's org.
+func (a ArtifactTrackerServer) GetOrgBranding(ctx context.Context, req *cloudpb.GetOrgBrandingRequest) (*cloudpb.OrgBranding, error) {
+	sCtx, err := authcontext.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orgID := utils.ProtoFromUUIDStrOrNil(sCtx.Claims.GetUserClaims().OrgID)
+
+	serviceAuthToken, err := getServiceCredentials(viper.GetString("jwt_signing_key"))
+	if err != nil {
+		return nil, err
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization",
+		fmt.Sprintf("bearer %s", serviceAuthToken))
+
+	resp, err := a.ArtifactTrackerClient.GetOrgBranding(ctx, &artifacttrackerpb.GetOrgBrandingRequest{OrgID: orgID})
+	if err != nil {
+		return nil, err
+	}
+	return orgBrandingToCloudProto(resp), nil
+}
+
+// UpdateOrgBranding replaces the branding of the user's org.
+func (a ArtifactTrackerServer) UpdateOrgBranding(ctx context.Context, req *cloudpb.UpdateOrgBrandingRequest) (*cloudpb.OrgBranding, error) {
+	sCtx, err := authcontext.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orgID := utils.ProtoFromUUIDStrOrNil(sCtx.Claims.GetUserClaims().OrgID)
+
+	serviceAuthToken, err := getServiceCredentials(viper.GetString("jwt_signing_key"))
+	if err != nil {
+		return nil, err
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization",
+		fmt.Sprintf("bearer %s", serviceAuthToken))
+
+	atReq := &artifacttrackerpb.UpdateOrgBrandingRequest{
+		OrgID:    orgID,
+		Branding: &artifacttrackerpb.OrgBranding{},
+	}
+	if b := req.Branding; b != nil {
+		atReq.Branding = &artifacttrackerpb.OrgBranding{
+			Announcement:    b.Announcement,
+			AnnouncementURL: b.AnnouncementURL,
+			DocsURL:         b.DocsURL,
+			SupportContact:  b.SupportContact,
+		}
+	}
+	resp, err := a.ArtifactTrackerClient.UpdateOrgBranding(ctx, atReq)
+	recordAudit(ctx, a.AuditLog, auditlog.ActionOrgUpdated, auditResourceID(orgID), err)
+	if err != nil {
+		return nil, err
+	}
+	return orgBrandingToCloudProto(resp), nil
+}
@@ -31,6 +31,7 @@ import (
 	"px.dev/pixie/src/cloud/api/controllers/testutils"
 	"px.dev/pixie/src/cloud/artifact_tracker/artifacttrackerpb"
 	"px.dev/pixie/src/shared/artifacts/versionspb"
+	"px.dev/pixie/src/utils"
 )
 
 func TestArtifactTracker_GetArtifactList(t *testing.T) {
@@ -139,3 +140,66 @@ func TestArtifactTracker_GetDownloadLink(t *testing.T) {
 	assert.Equal(t, "http://localhost", resp.Url)
 	assert.Equal(t, "sha", resp.SHA256)
 }
+
+func TestArtifactTracker_GetOrgBranding(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	mockClients.MockArtifact.EXPECT().GetOrgBranding(gomock.Any(),
+		&artifacttrackerpb.GetOrgBrandingRequest{
+			OrgID: utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+		}).
+		Return(&artifacttrackerpb.OrgBranding{
+			Announcement:   "Maintenance on Friday",
+			SupportContact: "#pixie-help",
+		}, nil)
+
+	artifactTrackerServer := &controllers.ArtifactTrackerServer{
+		ArtifactTrackerClient: mockClients.MockArtifact,
+	}
+
+	resp, err := artifactTrackerServer.GetOrgBranding(ctx, &cloudpb.GetOrgBrandingRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, &cloudpb.OrgBranding{
+		Announcement:   "Maintenance on Friday",
+		SupportContact: "#pixie-help",
+	}, resp)
+}
+
+func TestArtifactTracker_UpdateOrgBranding(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	branding := &artifacttrackerpb.OrgBranding{
+		DocsURL:        "https://docs.example.com",
+		SupportContact: "pixie-help@example.com",
+	}
+	mockClients.MockArtifact.EXPECT().UpdateOrgBranding(gomock.Any(),
+		&artifacttrackerpb.UpdateOrgBrandingRequest{
+			OrgID:    utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+			Branding: branding,
+		}).
+		Return(branding, nil)
+
+	artifactTrackerServer := &controllers.ArtifactTrackerServer{
+		ArtifactTrackerClient: mockClients.MockArtifact,
+	}
+
+	resp, err := artifactTrackerServer.UpdateOrgBranding(ctx, &cloudpb.UpdateOrgBrandingRequest{
+		Branding: &cloudpb.OrgBranding{
+			DocsURL:        "https://docs.example.com",
+			SupportContact: "pixie-help@example.com",
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "https://docs.example.com", resp.DocsURL)
+	assert.Equal(t, "pixie-help@example.com", resp.SupportContact)
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofrs/uuid"
+	log "github.com/sirupsen/logrus"
+
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/cloud/shared/auditlog"
+	"px.dev/pixie/src/shared/services/authcontext"
+	"px.dev/pixie/src/shared/services/rbac"
+	"px.dev/pixie/src/utils"
+)
+
+// AuditLogPath is the path that the audit log of the caller's org is served on.
+const AuditLogPath = "/api/audit/events"
+
+// recordAudit records the outcome of an administrative action, if the server has an audit log.
+func recordAudit(ctx context.Context, r auditlog.Recorder, action auditlog.Action, resourceID string, err error) {
+	if r == nil {
+		return
+	}
+	r.Record(ctx, auditlog.NewEvent(ctx, action, resourceID, err))
+}
+
+func auditResourceID(id *uuidpb.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return utils.UUIDFromProtoOrNil(id).String()
+}
+
+// AuditLogServer serves the audit log of the caller's org as JSON. Only org admins can read it.
+// The events can be filtered with the since and until (RFC 3339), action, actorID and limit query
+// parameters.
+type AuditLogServer struct {
+	Store auditlog.Store
+}
+
+type auditLogResponse struct {
+	Events []*auditlog.Event `json:"events"`
+}
+
+// ServeHTTP handles requests for the audit log.
+func (a *AuditLogServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sCtx, err := authcontext.FromContext(r.Context())
+	if err != nil || sCtx.Claims == nil {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return
+	}
+	if err := rbac.Authorize(r.Context(), rbac.RoleAdmin, nil); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	orgID := uuid.FromStringOrNil(sCtx.Claims.GetUserClaims().OrgID)
+	if orgID == uuid.Nil {
+		http.Error(w, "user does not belong to an org", http.StatusForbidden)
+		return
+	}
+
+	q, err := parseAuditLogQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q.OrgID = orgID
+
+	events, err := a.Store.Query(r.Context(), q)
+	if err != nil {
+		log.WithError(err).Error("Failed to query audit log")
+		http.Error(w, "failed to query audit log", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&auditLogResponse{Events: events}); err != nil {
+		log.WithError(err).Error("Failed to write audit log response")
+	}
+}
+
+func parseAuditLogQuery(r *http.Request) (*auditlog.Query, error) {
+	params := r.URL.Query()
+	q := &auditlog.Query{
+		Action:  auditlog.Action(params.Get("action")),
+		ActorID: params.Get("actorID"),
+	}
+	var err error
+	if s := params.Get("since"); s != "" {
+		if q.Since, err = time.Parse(time.RFC3339, s); err != nil {
+			return nil, err
+		}
+	}
+	if s := params.Get("until"); s != "" {
+		if q.Until, err = time.Parse(time.RFC3339, s); err != nil {
+			return nil, err
+		}
+	}
+	if s := params.Get("limit"); s != "" {
+		if q.Limit, err = strconv.Atoi(s); err != nil {
+			return nil, err
+		}
+	}
+	return q, nil
+}
@@ -0,0 +1,156 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/cloud/api/controllers"
+	"px.dev/pixie/src/cloud/api/controllers/testutils"
+	"px.dev/pixie/src/cloud/auth/authpb"
+	"px.dev/pixie/src/cloud/shared/auditlog"
+	"px.dev/pixie/src/shared/services/rbac"
+	"px.dev/pixie/src/utils"
+)
+
+type fakeAuditLog struct {
+	events []*auditlog.Event
+	query  *auditlog.Query
+}
+
+func (f *fakeAuditLog) Record(ctx context.Context, e *auditlog.Event) {
+	f.events = append(f.events, e)
+}
+
+func (f *fakeAuditLog) Write(ctx context.Context, events []*auditlog.Event) error {
+	f.events = append(f.events, events...)
+	return nil
+}
+
+func (f *fakeAuditLog) Query(ctx context.Context, q *auditlog.Query) ([]*auditlog.Event, error) {
+	f.query = q
+	return f.events, nil
+}
+
+func TestAPIKeyServer_Create_AuditLog(t *testing.T) {
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+
+	keyID := utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	mockClients.MockAPIKey.EXPECT().
+		Create(gomock.Any(), &authpb.CreateAPIKeyRequest{Desc: "test key"}).
+		Return(&authpb.APIKey{ID: keyID, Key: "foobar", CreatedAt: types.TimestampNow()}, nil)
+	mockClients.MockAPIKey.EXPECT().
+		Delete(gomock.Any(), keyID).
+		Return(nil, errors.New("not found"))
+
+	al := &fakeAuditLog{}
+	s := &controllers.APIKeyServer{APIKeyClient: mockClients.MockAPIKey, AuditLog: al}
+
+	ctx := CreateTestContext()
+	_, err := s.Create(ctx, &cloudpb.CreateAPIKeyRequest{Desc: "test key"})
+	require.NoError(t, err)
+	_, err = s.Delete(ctx, keyID)
+	require.Error(t, err)
+
+	require.Len(t, al.events, 2)
+	assert.Equal(t, auditlog.ActionAPIKeyCreated, al.events[0].Action)
+	assert.Equal(t, "6ba7b810-9dad-11d1-80b4-00c04fd430c8", al.events[0].ResourceID)
+	assert.Equal(t, "6ba7b810-9dad-11d1-80b4-00c04fd430c9", al.events[0].ActorID)
+	assert.Equal(t, uuid.FromStringOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"), al.events[0].OrgID)
+	assert.True(t, al.events[0].Succeeded)
+
+	assert.Equal(t, auditlog.ActionAPIKeyDeleted, al.events[1].Action)
+	assert.False(t, al.events[1].Succeeded)
+	assert.Equal(t, "not found", al.events[1].Error)
+}
+
+func TestAuditLogServer(t *testing.T) {
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		query        string
+		expectedCode int
+	}{
+		{
+			name:         "admin",
+			ctx:          createTestContextWithRole(rbac.RoleAdmin),
+			query:        "?action=api_key.create&since=2022-01-01T00:00:00Z&limit=10",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "viewer",
+			ctx:          createTestContextWithRole(rbac.RoleViewer),
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "bad time",
+			ctx:          CreateTestContext(),
+			query:        "?since=yesterday",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "unauthenticated",
+			ctx:          context.Background(),
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			al := &fakeAuditLog{
+				events: []*auditlog.Event{{Action: auditlog.ActionAPIKeyCreated, ResourceID: "key"}},
+			}
+			s := &controllers.AuditLogServer{Store: al}
+
+			req := httptest.NewRequest(http.MethodGet, controllers.AuditLogPath+test.query, nil).WithContext(test.ctx)
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, req)
+			require.Equal(t, test.expectedCode, w.Code)
+			if test.expectedCode != http.StatusOK {
+				return
+			}
+
+			assert.Equal(t, uuid.FromStringOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"), al.query.OrgID)
+			assert.Equal(t, auditlog.ActionAPIKeyCreated, al.query.Action)
+			assert.Equal(t, time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), al.query.Since)
+			assert.Equal(t, 10, al.query.Limit)
+
+			resp := struct {
+				Events []*auditlog.Event `json:"events"`
+			}{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Len(t, resp.Events, 1)
+			assert.Equal(t, "key", resp.Events[0].ResourceID)
+		})
+	}
+}
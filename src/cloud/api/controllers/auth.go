@@ -52,6 +52,57 @@ func GetServiceCredentials(signingKey string) (string, error) {
 	return srvutils.SignJWTClaims(claims, signingKey)
 }
 
+// authSignupRequest is the body of a signup request. The tokens are from the identity provider.
+type authSignupRequest struct {
+	AccessToken string `json:"accessToken"`
+	IDToken     string `json:"idToken"`
+	InviteToken string `json:"inviteToken,omitempty"`
+}
+
+// authLoginRequest is the body of a login request. The tokens are from the identity provider.
+type authLoginRequest struct {
+	AccessToken string `json:"accessToken"`
+	IDToken     string `json:"idToken"`
+	State       string `json:"state"`
+	InviteToken string `json:"inviteToken,omitempty"`
+}
+
+// authLoginEmbedRequest is the body of a login request from an embedded UI.
+type authLoginEmbedRequest struct {
+	AccessToken string `json:"accessToken"`
+	IDToken     string `json:"idToken"`
+	State       string `json:"state"`
+}
+
+type authUserInfo struct {
+	UserID    string `json:"userID"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Email     string `json:"email"`
+}
+
+type authOrgInfo struct {
+	OrgID   string `json:"orgID"`
+	OrgName string `json:"orgName"`
+}
+
+// authLoginResponse is the response to a successful login.
+type authLoginResponse struct {
+	Token       string       `json:"token"`
+	ExpiresAt   int64        `json:"expiresAt"`
+	UserInfo    authUserInfo `json:"userInfo"`
+	UserCreated bool         `json:"userCreated"`
+	OrgInfo     authOrgInfo  `json:"orgInfo"`
+}
+
+// authSignupResponse is the response to a successful signup.
+type authSignupResponse struct {
+	Token      string       `json:"token"`
+	ExpiresAt  int64        `json:"expiresAt"`
+	UserInfo   authUserInfo `json:"userInfo"`
+	OrgCreated bool         `json:"orgCreated"`
+}
+
 // AuthOAuthLoginHandler handles logins for OSS oauth support.
 func AuthOAuthLoginHandler(env commonenv.Env, w http.ResponseWriter, r *http.Request) error {
 	apiEnv, ok := env.(apienv.APIEnv)
@@ -90,11 +141,7 @@ func AuthSignupHandler(env commonenv.Env, w http.ResponseWriter, r *http.Request
 	}
 
 	// Extract params from the body which consists of the Auth0 ID token.
-	var params struct {
-		AccessToken string `json:"accessToken"`
-		IDToken     string `json:"idToken"`
-		InviteToken string `json:"inviteToken,omitempty"`
-	}
+	var params authSignupRequest
 
 	defer r.Body.Close()
 	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
@@ -196,12 +243,7 @@ func AuthLoginHandler(env commonenv.Env, w http.ResponseWriter, r *http.Request)
 	}
 
 	// Extract params from the body which consists of the Auth0 ID token.
-	var params struct {
-		AccessToken string `json:"accessToken"`
-		IDToken     string `json:"idToken"`
-		State       string `json:"state"`
-		InviteToken string `json:"inviteToken,omitempty"`
-	}
+	var params authLoginRequest
 
 	defer r.Body.Close()
 	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
@@ -308,11 +350,7 @@ func AuthLoginHandlerEmbed(env commonenv.Env, w http.ResponseWriter, r *http.Req
 	}
 
 	// Extract params from the body which consists of the Auth0 ID token.
-	var params struct {
-		AccessToken string `json:"accessToken"`
-		IDToken     string `json:"idToken"`
-		State       string `json:"state"`
-	}
+	var params authLoginEmbedRequest
 
 	defer r.Body.Close()
 	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
@@ -549,21 +587,7 @@ func setSessionCookie(session *sessions.Session, token string, expiresAt int64,
 }
 
 func sendUserInfo(w http.ResponseWriter, userInfo *authpb.AuthenticatedUserInfo, orgInfo *authpb.LoginReply_OrgInfo, token string, expiresAt int64, userCreated bool) error {
-	var data struct {
-		Token     string `json:"token"`
-		ExpiresAt int64  `json:"expiresAt"`
-		UserInfo  struct {
-			UserID    string `json:"userID"`
-			FirstName string `json:"firstName"`
-			LastName  string `json:"lastName"`
-			Email     string `json:"email"`
-		} `json:"userInfo"`
-		UserCreated bool `json:"userCreated"`
-		OrgInfo     struct {
-			OrgID   string `json:"orgID"`
-			OrgName string `json:"orgName"`
-		} `json:"orgInfo"`
-	}
+	var data authLoginResponse
 
 	data.Token = token
 	data.ExpiresAt = expiresAt
@@ -586,17 +610,7 @@ func sendUserInfo(w http.ResponseWriter, userInfo *authpb.AuthenticatedUserInfo,
 }
 
 func sendSignupUserInfo(w http.ResponseWriter, userInfo *authpb.AuthenticatedUserInfo, token string, expiresAt int64, orgCreated bool) error {
-	var data struct {
-		Token     string `json:"token"`
-		ExpiresAt int64  `json:"expiresAt"`
-		UserInfo  struct {
-			UserID    string `json:"userID"`
-			FirstName string `json:"firstName"`
-			LastName  string `json:"lastName"`
-			Email     string `json:"email"`
-		} `json:"userInfo"`
-		OrgCreated bool `json:"orgCreated"`
-	}
+	var data authSignupResponse
 
 	data.Token = token
 	data.ExpiresAt = expiresAt
@@ -33,6 +33,9 @@ import (
 
 // CreateCluster creates a new cluster.
 func (q *QueryResolver) CreateCluster(ctx context.Context) (*ClusterInfoResolver, error) {
+	if err := authorizeGQL(ctx, "/px.cloudapi.VizierClusterInfo/CreateCluster", nil); err != nil {
+		return nil, err
+	}
 	return nil, errors.New("Deprecated. Please use `px deploy`")
 }
 
@@ -33,6 +33,7 @@ import (
 	"px.dev/pixie/src/cloud/api/controllers/schema/complete"
 	"px.dev/pixie/src/cloud/api/controllers/testutils"
 	"px.dev/pixie/src/shared/services/authcontext"
+	"px.dev/pixie/src/shared/services/rbac"
 	svcutils "px.dev/pixie/src/shared/services/utils"
 	"px.dev/pixie/src/utils"
 )
@@ -40,6 +41,7 @@ import (
 func CreateTestContext() context.Context {
 	sCtx := authcontext.New()
 	sCtx.Claims = svcutils.GenerateJWTForUser("6ba7b810-9dad-11d1-80b4-00c04fd430c9", "6ba7b810-9dad-11d1-80b4-00c04fd430c8", "test@test.com", time.Now(), "pixie")
+	sCtx.Claims.Scopes = append(sCtx.Claims.Scopes, rbac.RoleAdmin.Scope())
 	return authcontext.NewContext(context.Background(), sCtx)
 }
 
@@ -52,6 +54,7 @@ func CreateTestContextNoOrg() context.Context {
 func CreateAPIUserTestContext() context.Context {
 	sCtx := authcontext.New()
 	sCtx.Claims = svcutils.GenerateJWTForAPIUser("6ba7b810-9dad-11d1-80b4-00c04fd430c9", "6ba7b810-9dad-11d1-80b4-00c04fd430c8", time.Now(), "pixie")
+	sCtx.Claims.Scopes = append(sCtx.Claims.Scopes, rbac.RoleAdmin.Scope())
 	return authcontext.NewContext(context.Background(), sCtx)
 }
 
@@ -43,23 +43,24 @@ func (c *ConfigServiceServer) GetConfigForVizier(ctx context.Context,
 	resp, err := c.ConfigServiceClient.GetConfigForVizier(ctx, &configmanagerpb.ConfigForVizierRequest{
 		Namespace: req.Namespace,
 		VzSpec: &vizierconfigpb.VizierSpec{
-			Version:                  vizSpecReq.Version,
-			DeployKey:                vizSpecReq.DeployKey,
-			CustomDeployKeySecret:    vizSpecReq.CustomDeployKeySecret,
-			DisableAutoUpdate:        vizSpecReq.DisableAutoUpdate,
-			UseEtcdOperator:          vizSpecReq.UseEtcdOperator,
-			ClusterName:              vizSpecReq.ClusterName,
-			CloudAddr:                vizSpecReq.CloudAddr,
-			DevCloudNamespace:        vizSpecReq.DevCloudNamespace,
-			PemMemoryLimit:           vizSpecReq.PemMemoryLimit,
-			PemMemoryRequest:         vizSpecReq.PemMemoryRequest,
-			Pod_Policy:               vizSpecReq.Pod_Policy,
-			Patches:                  vizSpecReq.Patches,
-			ClockConverter:           vizSpecReq.ClockConverter,
-			DataCollectorParams:      vizSpecReq.DataCollectorParams,
-			DataAccess:               vizSpecReq.DataAccess,
-			LeadershipElectionParams: vizSpecReq.LeadershipElectionParams,
-			Registry:                 vizSpecReq.Registry,
+			Version:                   vizSpecReq.Version,
+			DeployKey:                 vizSpecReq.DeployKey,
+			CustomDeployKeySecret:     vizSpecReq.CustomDeployKeySecret,
+			DisableAutoUpdate:         vizSpecReq.DisableAutoUpdate,
+			UseEtcdOperator:           vizSpecReq.UseEtcdOperator,
+			ClusterName:               vizSpecReq.ClusterName,
+			CloudAddr:                 vizSpecReq.CloudAddr,
+			DevCloudNamespace:         vizSpecReq.DevCloudNamespace,
+			PemMemoryLimit:            vizSpecReq.PemMemoryLimit,
+			PemMemoryRequest:          vizSpecReq.PemMemoryRequest,
+			Pod_Policy:                vizSpecReq.Pod_Policy,
+			Patches:                   vizSpecReq.Patches,
+			ClockConverter:            vizSpecReq.ClockConverter,
+			DataCollectorParams:       vizSpecReq.DataCollectorParams,
+			DataAccess:                vizSpecReq.DataAccess,
+			LeadershipElectionParams:  vizSpecReq.LeadershipElectionParams,
+			Registry:                  vizSpecReq.Registry,
+			SingleProcessControlPlane: vizSpecReq.SingleProcessControlPlane,
 		},
 		K8sVersion: req.K8sVersion,
 	})
@@ -28,6 +28,7 @@ import (
 	apiUtils "px.dev/pixie/src/api/go/pxapi/utils"
 	"px.dev/pixie/src/api/proto/cloudpb"
 	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/cloud/shared/auditlog"
 	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
 	"px.dev/pixie/src/shared/services/authcontext"
 	"px.dev/pixie/src/utils"
@@ -36,26 +37,33 @@ import (
 // VizierDeploymentKeyServer is the server that implements the VizierDeploymentKeyManager gRPC service.
 type VizierDeploymentKeyServer struct {
 	VzDeploymentKey vzmgrpb.VZDeploymentKeyServiceClient
+	AuditLog        auditlog.Recorder
 }
 
 func deployKeyToCloudAPI(key *vzmgrpb.DeploymentKey) *cloudpb.DeploymentKey {
 	return &cloudpb.DeploymentKey{
-		ID:        key.ID,
-		OrgID:     key.OrgID,
-		UserID:    key.UserID,
-		Key:       key.Key,
-		CreatedAt: key.CreatedAt,
-		Desc:      key.Desc,
+		ID:         key.ID,
+		OrgID:      key.OrgID,
+		UserID:     key.UserID,
+		Key:        key.Key,
+		CreatedAt:  key.CreatedAt,
+		Desc:       key.Desc,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		Viewer:     key.Viewer,
 	}
 }
 
 func deployKeyMetadataToCloudAPI(key *vzmgrpb.DeploymentKeyMetadata) *cloudpb.DeploymentKeyMetadata {
 	return &cloudpb.DeploymentKeyMetadata{
-		ID:        key.ID,
-		OrgID:     key.OrgID,
-		UserID:    key.UserID,
-		CreatedAt: key.CreatedAt,
-		Desc:      key.Desc,
+		ID:         key.ID,
+		OrgID:      key.OrgID,
+		UserID:     key.UserID,
+		CreatedAt:  key.CreatedAt,
+		Desc:       key.Desc,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		Viewer:     key.Viewer,
 	}
 }
 
@@ -78,10 +86,13 @@ func (v *VizierDeploymentKeyServer) Create(ctx context.Context, req *cloudpb.Cre
 		return nil, status.Error(codes.Internal, "error parsing user ID as UUID")
 	}
 	resp, err := v.VzDeploymentKey.Create(ctx, &vzmgrpb.CreateDeploymentKeyRequest{
-		Desc:   req.Desc,
-		OrgID:  orgID,
-		UserID: userID,
+		Desc:      req.Desc,
+		OrgID:     orgID,
+		UserID:    userID,
+		ExpiresAt: req.ExpiresAt,
+		Viewer:    req.Viewer,
 	})
+	recordAudit(ctx, v.AuditLog, auditlog.ActionDeployKeyCreated, auditResourceID(resp.GetID()), err)
 	if err != nil {
 		return nil, err
 	}
@@ -163,10 +174,12 @@ func (v *VizierDeploymentKeyServer) Delete(ctx context.Context, uuid *uuidpb.UUI
 	if err != nil {
 		return nil, err
 	}
-	return v.VzDeploymentKey.Delete(ctx, &vzmgrpb.DeleteDeploymentKeyRequest{
+	resp, err := v.VzDeploymentKey.Delete(ctx, &vzmgrpb.DeleteDeploymentKeyRequest{
 		OrgID: orgID,
 		ID:    uuid,
 	})
+	recordAudit(ctx, v.AuditLog, auditlog.ActionDeployKeyDeleted, auditResourceID(uuid), err)
+	return resp, err
 }
 
 // LookupDeploymentKey gets the complete API key information using just the Key.
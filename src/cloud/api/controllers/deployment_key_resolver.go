@@ -23,6 +23,7 @@ import (
 	"sort"
 
 	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
 	"github.com/graph-gophers/graphql-go"
 
 	"px.dev/pixie/src/api/proto/cloudpb"
@@ -32,11 +33,30 @@ import (
 // NanosPerSecond is the number of nanoseconds per second.
 const NanosPerSecond int64 = 1000 * 1000 * 1000
 
+func timestampToMs(ts *types.Timestamp) *float64 {
+	if ts == nil {
+		return nil
+	}
+	ms := float64(ts.Seconds*NanosPerSecond+int64(ts.Nanos)) / 1e6
+	return &ms
+}
+
+func msToTimestamp(ms *float64) *types.Timestamp {
+	if ms == nil {
+		return nil
+	}
+	ns := int64(*ms * 1e6)
+	return &types.Timestamp{Seconds: ns / NanosPerSecond, Nanos: int32(ns % NanosPerSecond)}
+}
+
 // DeploymentKeyMetadataResolver is the resolver responsible for deploy key metadata.
 type DeploymentKeyMetadataResolver struct {
 	id          uuid.UUID
 	createdAtNs int64
 	desc        string
+	expiresAt   *types.Timestamp
+	lastUsedAt  *types.Timestamp
+	viewer      bool
 }
 
 // ID returns deployment key ID.
@@ -54,6 +74,21 @@ func (d *DeploymentKeyMetadataResolver) Desc() string {
 	return d.desc
 }
 
+// ExpiresAtMs returns the time at which the deployment key expires, if it does.
+func (d *DeploymentKeyMetadataResolver) ExpiresAtMs() *float64 {
+	return timestampToMs(d.expiresAt)
+}
+
+// LastUsedAtMs returns the time at which the deployment key was last used, if it was.
+func (d *DeploymentKeyMetadataResolver) LastUsedAtMs() *float64 {
+	return timestampToMs(d.lastUsedAt)
+}
+
+// Viewer returns whether the deployment key registers read-only clusters.
+func (d *DeploymentKeyMetadataResolver) Viewer() bool {
+	return d.viewer
+}
+
 // DeploymentKeyResolver resolves metadata and the current key value for a single key.
 type DeploymentKeyResolver struct {
 	DeploymentKeyMetadataResolver
@@ -65,10 +100,21 @@ func (d *DeploymentKeyResolver) Key() string {
 	return d.key
 }
 
+type createDeploymentKeyArgs struct {
+	ExpiresAtMs *float64
+	Viewer      *bool
+}
+
 // CreateDeploymentKey creates a new deployment key.
-func (q *QueryResolver) CreateDeploymentKey(ctx context.Context) (*DeploymentKeyResolver, error) {
+func (q *QueryResolver) CreateDeploymentKey(ctx context.Context, args *createDeploymentKeyArgs) (*DeploymentKeyResolver, error) {
+	if err := authorizeGQL(ctx, "/px.cloudapi.VizierDeploymentKeyManager/Create", nil); err != nil {
+		return nil, err
+	}
 	grpcAPI := q.Env.VizierDeployKeyMgr
-	res, err := grpcAPI.Create(ctx, &cloudpb.CreateDeploymentKeyRequest{})
+	res, err := grpcAPI.Create(ctx, &cloudpb.CreateDeploymentKeyRequest{
+		ExpiresAt: msToTimestamp(args.ExpiresAtMs),
+		Viewer:    args.Viewer != nil && *args.Viewer,
+	})
 	if err != nil {
 		return nil, rpcErrorHelper(err)
 	}
@@ -86,6 +132,9 @@ func deploymentKeyToResolver(key *cloudpb.DeploymentKey) (*DeploymentKeyResolver
 			id:          keyID,
 			createdAtNs: key.CreatedAt.Seconds*NanosPerSecond + int64(key.CreatedAt.Nanos),
 			desc:        key.Desc,
+			expiresAt:   key.ExpiresAt,
+			lastUsedAt:  key.LastUsedAt,
+			viewer:      key.Viewer,
 		},
 		key: key.Key,
 	}, nil
@@ -102,6 +151,9 @@ func deploymentKeyMetadatasToResolver(mds []*cloudpb.DeploymentKeyMetadata) ([]*
 			id:          mdu,
 			createdAtNs: md.CreatedAt.Seconds*NanosPerSecond + int64(md.CreatedAt.Nanos),
 			desc:        md.Desc,
+			expiresAt:   md.ExpiresAt,
+			lastUsedAt:  md.LastUsedAt,
+			viewer:      md.Viewer,
 		}
 		mdrs = append(mdrs, resolved)
 	}
@@ -137,6 +189,9 @@ func (q *QueryResolver) DeploymentKey(ctx context.Context, args *getOrDeleteDepl
 
 // DeleteDeploymentKey deletes a specific deployment key.
 func (q *QueryResolver) DeleteDeploymentKey(ctx context.Context, args *getOrDeleteDeployKeyArgs) (bool, error) {
+	if err := authorizeGQL(ctx, "/px.cloudapi.VizierDeploymentKeyManager/Delete", nil); err != nil {
+		return false, err
+	}
 	grpcAPI := q.Env.VizierDeployKeyMgr
 	_, err := grpcAPI.Delete(ctx, utils.ProtoFromUUIDStrOrNil(string(args.ID)))
 	if err != nil {
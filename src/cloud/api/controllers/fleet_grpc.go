@@ -0,0 +1,294 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/gogo/protobuf/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/cloud/shared/auditlog"
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
+	"px.dev/pixie/src/shared/services/authcontext"
+	"px.dev/pixie/src/utils"
+)
+
+// FleetServer is the server that implements the FleetManager gRPC service. Rollouts are applied
+// through the cluster and plugin servers so that they are validated and audited the same way as
+// changes to a single cluster. Scripts that read from every cluster run through the Vizier proxy.
+type FleetServer struct {
+	VzFleet           vzmgrpb.VZFleetServiceClient
+	VizierClusterInfo *VizierClusterInfo
+	PluginServer      cloudpb.PluginServiceServer
+	Vizier            vizierpb.VizierServiceServer
+	AuditLog          auditlog.Recorder
+}
+
+func fleetToCloudAPI(f *vzmgrpb.Fleet) *cloudpb.Fleet {
+	return &cloudpb.Fleet{
+		ID:         f.ID,
+		Name:       f.Name,
+		Desc:       f.Desc,
+		Selector:   f.Selector,
+		CreatedAt:  f.CreatedAt,
+		ClusterIDs: f.ClusterIDs,
+	}
+}
+
+func orgIDFromContext(ctx context.Context) (*uuidpb.UUID, error) {
+	sCtx, err := authcontext.FromContext(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	orgID := utils.ProtoFromUUIDStrOrNil(sCtx.Claims.GetUserClaims().OrgID)
+	if orgID == nil {
+		return nil, status.Error(codes.Internal, "error parsing org ID as UUID")
+	}
+	return orgID, nil
+}
+
+// CreateFleet creates a fleet in the caller's org.
+func (f *FleetServer) CreateFleet(ctx context.Context, req *cloudpb.CreateFleetRequest) (*cloudpb.Fleet, error) {
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := f.VzFleet.CreateFleet(ctx, &vzmgrpb.CreateFleetRequest{
+		OrgID:    orgID,
+		Name:     req.Name,
+		Desc:     req.Desc,
+		Selector: req.Selector,
+	})
+	recordAudit(ctx, f.AuditLog, auditlog.ActionFleetCreated, auditResourceID(resp.GetID()), err)
+	if err != nil {
+		return nil, err
+	}
+	return fleetToCloudAPI(resp), nil
+}
+
+// GetFleet gets a fleet in the caller's org by ID or name.
+func (f *FleetServer) GetFleet(ctx context.Context, req *cloudpb.GetFleetRequest) (*cloudpb.Fleet, error) {
+	if (req.ID == nil) == (req.Name == "") {
+		return nil, status.Error(codes.InvalidArgument, "exactly one of fleet ID or name must be specified")
+	}
+	resp, err := f.getFleet(ctx, req.ID, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	return fleetToCloudAPI(resp), nil
+}
+
+func (f *FleetServer) getFleet(ctx context.Context, id *uuidpb.UUID, name string) (*vzmgrpb.Fleet, error) {
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return f.VzFleet.GetFleet(ctx, &vzmgrpb.GetFleetRequest{
+		OrgID: orgID,
+		ID:    id,
+		Name:  name,
+	})
+}
+
+// ListFleets lists the fleets in the caller's org.
+func (f *FleetServer) ListFleets(ctx context.Context, req *cloudpb.ListFleetsRequest) (*cloudpb.ListFleetsResponse, error) {
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := f.VzFleet.ListFleets(ctx, &vzmgrpb.ListFleetsRequest{OrgID: orgID})
+	if err != nil {
+		return nil, err
+	}
+	fleets := make([]*cloudpb.Fleet, len(resp.Fleets))
+	for i, fl := range resp.Fleets {
+		fleets[i] = fleetToCloudAPI(fl)
+	}
+	return &cloudpb.ListFleetsResponse{Fleets: fleets}, nil
+}
+
+// DeleteFleet deletes a fleet in the caller's org.
+func (f *FleetServer) DeleteFleet(ctx context.Context, req *uuidpb.UUID) (*types.Empty, error) {
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := f.VzFleet.DeleteFleet(ctx, &vzmgrpb.DeleteFleetRequest{
+		OrgID: orgID,
+		ID:    req,
+	})
+	recordAudit(ctx, f.AuditLog, auditlog.ActionFleetDeleted, auditResourceID(req), err)
+	return resp, err
+}
+
+// UpdateClusterLabels replaces the labels on a cluster in the caller's org.
+func (f *FleetServer) UpdateClusterLabels(ctx context.Context, req *cloudpb.UpdateClusterLabelsRequest) (*types.Empty, error) {
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := f.VzFleet.UpdateClusterLabels(ctx, &vzmgrpb.UpdateClusterLabelsRequest{
+		OrgID:     orgID,
+		ClusterID: req.ClusterID,
+		Labels:    req.Labels,
+	})
+	recordAudit(ctx, f.AuditLog, auditlog.ActionClusterLabelsUpdated, auditResourceID(req.ClusterID), err)
+	return resp, err
+}
+
+// GetClusterLabels gets the labels on a cluster in the caller's org.
+func (f *FleetServer) GetClusterLabels(ctx context.Context, req *cloudpb.GetClusterLabelsRequest) (*cloudpb.GetClusterLabelsResponse, error) {
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := f.VzFleet.GetClusterLabels(ctx, &vzmgrpb.GetClusterLabelsRequest{
+		OrgID:     orgID,
+		ClusterID: req.ClusterID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &cloudpb.GetClusterLabelsResponse{Labels: resp.Labels}, nil
+}
+
+// GetFleetHealth returns the cluster info for every cluster in a fleet.
+func (f *FleetServer) GetFleetHealth(ctx context.Context, req *cloudpb.GetFleetHealthRequest) (*cloudpb.GetFleetHealthResponse, error) {
+	fl, err := f.getFleet(ctx, req.FleetID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &cloudpb.GetFleetHealthResponse{
+		NumClusters: int64(len(fl.ClusterIDs)),
+	}
+	if len(fl.ClusterIDs) == 0 {
+		return resp, nil
+	}
+
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.VizierClusterInfo.getClusterInfoForViziers(ctx, fl.ClusterIDs)
+	if err != nil {
+		return nil, err
+	}
+	resp.Clusters = info.Clusters
+	for _, c := range info.Clusters {
+		if c.Status == cloudpb.CS_HEALTHY {
+			resp.NumHealthyClusters++
+		}
+	}
+	return resp, nil
+}
+
+// RolloutFleetRetentionScript sets a retention script to run on exactly the clusters currently in
+// a fleet. Clusters that join the fleet later aren't added to the script until it is rolled out
+// again.
+func (f *FleetServer) RolloutFleetRetentionScript(ctx context.Context, req *cloudpb.RolloutFleetRetentionScriptRequest) (*cloudpb.RolloutFleetRetentionScriptResponse, error) {
+	if req.ScriptID == nil {
+		return nil, status.Error(codes.InvalidArgument, "script ID must be specified")
+	}
+	fl, err := f.getFleet(ctx, req.FleetID, "")
+	if err != nil {
+		return nil, err
+	}
+	// A retention script without clusters runs on every cluster in the org, so an empty fleet
+	// can't be rolled out.
+	if len(fl.ClusterIDs) == 0 {
+		return nil, status.Error(codes.FailedPrecondition, "fleet does not contain any clusters")
+	}
+
+	_, err = f.PluginServer.UpdateRetentionScript(ctx, &cloudpb.UpdateRetentionScriptRequest{
+		ID:         req.ScriptID,
+		ClusterIDs: fl.ClusterIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &cloudpb.RolloutFleetRetentionScriptResponse{ClusterIDs: fl.ClusterIDs}, nil
+}
+
+// UpdateOrInstallFleet updates or installs Vizier on every cluster in a fleet. A failure on one
+// cluster doesn't stop the others from being updated; the result for each cluster is returned.
+func (f *FleetServer) UpdateOrInstallFleet(ctx context.Context, req *cloudpb.UpdateOrInstallFleetRequest) (*cloudpb.UpdateOrInstallFleetResponse, error) {
+	if req.Version == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "version cannot be empty")
+	}
+	fl, err := f.getFleet(ctx, req.FleetID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &cloudpb.UpdateOrInstallFleetResponse{}
+	for _, clusterID := range fl.ClusterIDs {
+		res := &cloudpb.UpdateOrInstallFleetResponse_ClusterResult{ClusterID: clusterID}
+		updateResp, err := f.VizierClusterInfo.UpdateOrInstallCluster(ctx, &cloudpb.UpdateOrInstallClusterRequest{
+			ClusterID:    clusterID,
+			Version:      req.Version,
+			RedeployEtcd: req.RedeployEtcd,
+		})
+		if err != nil {
+			// An invalid version fails on every cluster, so there is no point in continuing.
+			if status.Code(err) == codes.InvalidArgument {
+				return nil, err
+			}
+			res.Error = status.Convert(err).Message()
+		} else {
+			res.UpdateStarted = updateResp.UpdateStarted
+		}
+		resp.Results = append(resp.Results, res)
+	}
+	return resp, nil
+}
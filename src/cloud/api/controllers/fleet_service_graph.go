@@ -0,0 +1,267 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/cloud/shared/servicegraph"
+	"px.dev/pixie/src/utils"
+)
+
+const defaultServiceGraphStartTime = "-5m"
+
+// The trace roles of http_events, from the perspective of the traced service.
+const (
+	traceRoleClient = 1
+	traceRoleServer = 2
+)
+
+// fleetServiceGraphPxL collects the service graph of a single cluster, along with the IPs that the
+// cluster's services are reachable on and that its requests are sent from.
+const fleetServiceGraphPxL = `
+import px
+
+
+def edges(start_time: str):
+    df = px.DataFrame('http_events', start_time=start_time)
+    df.service = df.ctx['service']
+    df = df[df.service != '']
+    df.remote_service = px.service_id_to_service_name(px.ip_to_service_id(df.remote_addr))
+    df.error = px.select(df.resp_status >= 400, 1, 0)
+    df = df.groupby(['service', 'remote_addr', 'remote_service', 'trace_role']).agg(
+        num_requests=('latency', px.count),
+        num_errors=('error', px.sum),
+        total_latency=('latency', px.sum),
+    )
+    return df
+
+
+def service_ips(start_time: str):
+    df = px.DataFrame('http_events', start_time=start_time)
+    df = df[df.trace_role == 2]
+    df.service = df.ctx['service']
+    df.service_id = df.ctx['service_id']
+    df = df[df.service != '']
+    df = df.groupby(['service', 'service_id']).agg(num_requests=('latency', px.count))
+    df.cluster_ip = px.service_id_to_cluster_ip(df.service_id)
+    df.external_ips = px.service_id_to_external_ips(df.service_id)
+    return df[['service', 'cluster_ip', 'external_ips']]
+
+
+def egress_ips():
+    df = px.GetAgentStatus()
+    df = df[df.ip_address != '']
+    return df[['ip_address']]
+`
+
+// scriptRows collects the rows of every table of a script, by table name.
+type scriptRows struct {
+	names  map[string]string
+	tables map[string][]map[string]interface{}
+}
+
+func (s *scriptRows) table(id string, t *scriptTable) error {
+	s.names[id] = t.Name
+	return nil
+}
+
+func (s *scriptRows) row(tableID string, row map[string]interface{}) error {
+	name := s.names[tableID]
+	s.tables[name] = append(s.tables[name], row)
+	return nil
+}
+
+func (s *scriptRows) batchDone() error {
+	return nil
+}
+
+func (s *scriptRows) done(queryID string, stats *scriptStats) error {
+	return nil
+}
+
+// GetFleetServiceGraph returns the service graph of every cluster in a fleet, stitched into a
+// single graph. A cluster whose graph can't be fetched is left out, and its error is returned.
+func (f *FleetServer) GetFleetServiceGraph(ctx context.Context, req *cloudpb.GetFleetServiceGraphRequest) (*cloudpb.GetFleetServiceGraphResponse, error) {
+	fl, err := f.getFleet(ctx, req.FleetID, "")
+	if err != nil {
+		return nil, err
+	}
+	startTime := req.StartTime
+	if startTime == "" {
+		startTime = defaultServiceGraphStartTime
+	}
+
+	graphs := make([]*servicegraph.ClusterGraph, len(fl.ClusterIDs))
+	errs := make([]error, len(fl.ClusterIDs))
+	var wg sync.WaitGroup
+	for i, clusterID := range fl.ClusterIDs {
+		wg.Add(1)
+		go func(i int, clusterID *uuidpb.UUID) {
+			defer wg.Done()
+			graphs[i], errs[i] = f.clusterServiceGraph(ctx, clusterID, startTime)
+		}(i, clusterID)
+	}
+	wg.Wait()
+
+	resp := &cloudpb.GetFleetServiceGraphResponse{}
+	var fetched []*servicegraph.ClusterGraph
+	for i, err := range errs {
+		if err != nil {
+			resp.ClusterErrors = append(resp.ClusterErrors, &cloudpb.GetFleetServiceGraphResponse_ClusterError{
+				ClusterID: fl.ClusterIDs[i],
+				Error:     status.Convert(err).Message(),
+			})
+			continue
+		}
+		fetched = append(fetched, graphs[i])
+	}
+
+	g := servicegraph.Merge(fetched)
+	for _, e := range g.Edges {
+		edge := &cloudpb.ServiceGraphEdge{
+			Requestor:    serviceGraphNodeToCloudAPI(e.Requestor),
+			Responder:    serviceGraphNodeToCloudAPI(e.Responder),
+			NumRequests:  e.NumRequests,
+			NumErrors:    e.NumErrors,
+			CrossCluster: e.CrossCluster,
+		}
+		if e.NumRequests > 0 {
+			edge.LatencyNs = e.TotalLatencyNS / e.NumRequests
+		}
+		resp.Edges = append(resp.Edges, edge)
+	}
+	resp.AmbiguousIPs = g.AmbiguousIPs
+	return resp, nil
+}
+
+func serviceGraphNodeToCloudAPI(e servicegraph.Endpoint) *cloudpb.ServiceGraphNode {
+	n := &cloudpb.ServiceGraphNode{
+		Service: e.Service,
+		IP:      e.IP,
+	}
+	if e.ClusterID != "" {
+		n.ClusterID = utils.ProtoFromUUIDStrOrNil(e.ClusterID)
+	}
+	return n
+}
+
+// clusterServiceGraph runs the service graph script on a cluster and converts its results.
+func (f *FleetServer) clusterServiceGraph(ctx context.Context, clusterID *uuidpb.UUID, startTime string) (*servicegraph.ClusterGraph, error) {
+	if f.Vizier == nil {
+		return nil, status.Error(codes.Unimplemented, "script execution is not available")
+	}
+	startTimeArg := []*vizierpb.ExecuteScriptRequest_FuncToExecute_ArgValue{{Name: "start_time", Value: startTime}}
+	req := &vizierpb.ExecuteScriptRequest{
+		QueryStr:  fleetServiceGraphPxL,
+		ClusterID: utils.ProtoToUUIDStr(clusterID),
+		QueryName: "fleet_service_graph",
+		ExecFuncs: []*vizierpb.ExecuteScriptRequest_FuncToExecute{
+			{FuncName: "edges", OutputTablePrefix: "edges", ArgValues: startTimeArg},
+			{FuncName: "service_ips", OutputTablePrefix: "service_ips", ArgValues: startTimeArg},
+			{FuncName: "egress_ips", OutputTablePrefix: "egress_ips"},
+		},
+	}
+
+	out := &scriptRows{names: make(map[string]string), tables: make(map[string][]map[string]interface{})}
+	stream := &gatewayStream{ctx: ctx, out: out, columns: make(map[string][]*scriptColumn)}
+	err := f.Vizier.ExecuteScript(req, stream)
+	if err == nil {
+		err = stream.scriptErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return clusterGraphFromRows(utils.ProtoToUUIDStr(clusterID), out.tables)
+}
+
+func clusterGraphFromRows(clusterID string, tables map[string][]map[string]interface{}) (*servicegraph.ClusterGraph, error) {
+	g := &servicegraph.ClusterGraph{
+		ClusterID:  clusterID,
+		ServiceIPs: make(map[string]string),
+	}
+	for _, row := range tables["edges"] {
+		local := servicegraph.Endpoint{Service: rowString(row, "service")}
+		remote := servicegraph.Endpoint{Service: rowString(row, "remote_service")}
+		if remote.Service == "" {
+			remote.IP = rowString(row, "remote_addr")
+		}
+		e := &servicegraph.ClusterEdge{
+			Edge: servicegraph.Edge{
+				NumRequests:    rowInt(row, "num_requests"),
+				NumErrors:      rowInt(row, "num_errors"),
+				TotalLatencyNS: rowInt(row, "total_latency"),
+			},
+		}
+		switch role := rowInt(row, "trace_role"); role {
+		case traceRoleClient:
+			e.Role = servicegraph.RoleClient
+			e.Requestor, e.Responder = local, remote
+		case traceRoleServer:
+			e.Role = servicegraph.RoleServer
+			e.Requestor, e.Responder = remote, local
+		default:
+			return nil, fmt.Errorf("unknown trace role %d", role)
+		}
+		g.Edges = append(g.Edges, e)
+	}
+
+	for _, row := range tables["service_ips"] {
+		svc := rowString(row, "service")
+		if ip := rowString(row, "cluster_ip"); ip != "" {
+			g.ServiceIPs[ip] = svc
+		}
+		// The external IPs are a JSON array.
+		if ips := rowString(row, "external_ips"); ips != "" {
+			var external []string
+			if err := json.Unmarshal([]byte(ips), &external); err != nil {
+				return nil, fmt.Errorf("invalid external IPs for service %s: %w", svc, err)
+			}
+			for _, ip := range external {
+				g.ServiceIPs[ip] = svc
+			}
+		}
+	}
+
+	for _, row := range tables["egress_ips"] {
+		if ip := rowString(row, "ip_address"); ip != "" {
+			g.EgressIPs = append(g.EgressIPs, ip)
+		}
+	}
+	return g, nil
+}
+
+func rowString(row map[string]interface{}, col string) string {
+	s, _ := row[col].(string)
+	return s
+}
+
+func rowInt(row map[string]interface{}, col string) int64 {
+	i, _ := row[col].(int64)
+	return i
+}
@@ -0,0 +1,458 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gogo/protobuf/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/cloud/api/controllers"
+	"px.dev/pixie/src/cloud/api/controllers/testutils"
+	"px.dev/pixie/src/cloud/artifact_tracker/artifacttrackerpb"
+	"px.dev/pixie/src/cloud/plugin/pluginpb"
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
+	"px.dev/pixie/src/shared/artifacts/versionspb"
+	"px.dev/pixie/src/shared/cvmsgspb"
+	"px.dev/pixie/src/utils"
+)
+
+var (
+	testFleetOrgID   = utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	testFleetID      = utils.ProtoFromUUIDStrOrNil("8ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	testFleetCluster = []*uuidpb.UUID{
+		utils.ProtoFromUUIDStrOrNil("7ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+		utils.ProtoFromUUIDStrOrNil("7ba7b810-9dad-11d1-80b4-00c04fd430c9"),
+	}
+)
+
+func testFleet(clusterIDs []*uuidpb.UUID) *vzmgrpb.Fleet {
+	return &vzmgrpb.Fleet{
+		ID:         testFleetID,
+		OrgID:      testFleetOrgID,
+		Name:       "prod",
+		Selector:   map[string]string{"env": "prod"},
+		ClusterIDs: clusterIDs,
+	}
+}
+
+func TestFleetServer_CreateFleet(t *testing.T) {
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	mockClients.MockVzFleet.EXPECT().
+		CreateFleet(gomock.Any(), &vzmgrpb.CreateFleetRequest{
+			OrgID:    testFleetOrgID,
+			Name:     "prod",
+			Desc:     "production clusters",
+			Selector: map[string]string{"env": "prod"},
+		}).
+		Return(testFleet(testFleetCluster), nil)
+
+	fleetServer := &controllers.FleetServer{VzFleet: mockClients.MockVzFleet}
+	resp, err := fleetServer.CreateFleet(ctx, &cloudpb.CreateFleetRequest{
+		Name:     "prod",
+		Desc:     "production clusters",
+		Selector: map[string]string{"env": "prod"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, testFleetID, resp.ID)
+	assert.Equal(t, "prod", resp.Name)
+	assert.Equal(t, map[string]string{"env": "prod"}, resp.Selector)
+	assert.Equal(t, testFleetCluster, resp.ClusterIDs)
+}
+
+func TestFleetServer_GetFleet(t *testing.T) {
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	mockClients.MockVzFleet.EXPECT().
+		GetFleet(gomock.Any(), &vzmgrpb.GetFleetRequest{
+			OrgID: testFleetOrgID,
+			Name:  "prod",
+		}).
+		Return(testFleet(testFleetCluster), nil)
+
+	fleetServer := &controllers.FleetServer{VzFleet: mockClients.MockVzFleet}
+	resp, err := fleetServer.GetFleet(ctx, &cloudpb.GetFleetRequest{Name: "prod"})
+	require.NoError(t, err)
+	assert.Equal(t, testFleetID, resp.ID)
+
+	_, err = fleetServer.GetFleet(ctx, &cloudpb.GetFleetRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = fleetServer.GetFleet(ctx, &cloudpb.GetFleetRequest{ID: testFleetID, Name: "prod"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestFleetServer_GetFleetHealth(t *testing.T) {
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	mockClients.MockVzFleet.EXPECT().
+		GetFleet(gomock.Any(), &vzmgrpb.GetFleetRequest{
+			OrgID: testFleetOrgID,
+			ID:    testFleetID,
+		}).
+		Return(testFleet(testFleetCluster), nil)
+	mockClients.MockVzMgr.EXPECT().
+		GetVizierInfos(gomock.Any(), &vzmgrpb.GetVizierInfosRequest{
+			VizierIDs: testFleetCluster,
+		}).
+		Return(&vzmgrpb.GetVizierInfosResponse{
+			VizierInfos: []*cvmsgspb.VizierInfo{
+				{
+					VizierID:    testFleetCluster[0],
+					Status:      cvmsgspb.VZ_ST_HEALTHY,
+					ClusterName: "prod-us",
+				},
+				{
+					VizierID:    testFleetCluster[1],
+					Status:      cvmsgspb.VZ_ST_DISCONNECTED,
+					ClusterName: "prod-eu",
+				},
+			},
+		}, nil)
+
+	fleetServer := &controllers.FleetServer{
+		VzFleet:           mockClients.MockVzFleet,
+		VizierClusterInfo: &controllers.VizierClusterInfo{VzMgr: mockClients.MockVzMgr},
+	}
+	resp, err := fleetServer.GetFleetHealth(ctx, &cloudpb.GetFleetHealthRequest{FleetID: testFleetID})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), resp.NumClusters)
+	assert.Equal(t, int64(1), resp.NumHealthyClusters)
+	require.Len(t, resp.Clusters, 2)
+	assert.Equal(t, cloudpb.CS_HEALTHY, resp.Clusters[0].Status)
+	assert.Equal(t, cloudpb.CS_DISCONNECTED, resp.Clusters[1].Status)
+}
+
+func TestFleetServer_RolloutFleetRetentionScript(t *testing.T) {
+	scriptID := utils.ProtoFromUUIDStrOrNil("9ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+	tests := []struct {
+		name         string
+		clusterIDs   []*uuidpb.UUID
+		expectedCode codes.Code
+	}{
+		{
+			name:         "fleet with clusters",
+			clusterIDs:   testFleetCluster,
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "empty fleet",
+			clusterIDs:   nil,
+			expectedCode: codes.FailedPrecondition,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+			defer cleanup()
+			ctx := CreateTestContext()
+
+			mockClients.MockVzFleet.EXPECT().
+				GetFleet(gomock.Any(), &vzmgrpb.GetFleetRequest{
+					OrgID: testFleetOrgID,
+					ID:    testFleetID,
+				}).
+				Return(testFleet(test.clusterIDs), nil)
+			if test.expectedCode == codes.OK {
+				mockClients.MockDataRetentionPlugin.EXPECT().
+					UpdateRetentionScript(gomock.Any(), &pluginpb.UpdateRetentionScriptRequest{
+						ScriptID:   scriptID,
+						ClusterIDs: test.clusterIDs,
+					}).
+					Return(&pluginpb.UpdateRetentionScriptResponse{}, nil)
+			}
+
+			fleetServer := &controllers.FleetServer{
+				VzFleet: mockClients.MockVzFleet,
+				PluginServer: &controllers.PluginServiceServer{
+					DataRetentionPluginServiceClient: mockClients.MockDataRetentionPlugin,
+				},
+			}
+			resp, err := fleetServer.RolloutFleetRetentionScript(ctx, &cloudpb.RolloutFleetRetentionScriptRequest{
+				FleetID:  testFleetID,
+				ScriptID: scriptID,
+			})
+			assert.Equal(t, test.expectedCode, status.Code(err))
+			if test.expectedCode == codes.OK {
+				assert.Equal(t, test.clusterIDs, resp.ClusterIDs)
+			}
+		})
+	}
+}
+
+func TestFleetServer_UpdateOrInstallFleet(t *testing.T) {
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	mockClients.MockVzFleet.EXPECT().
+		GetFleet(gomock.Any(), &vzmgrpb.GetFleetRequest{
+			OrgID: testFleetOrgID,
+			ID:    testFleetID,
+		}).
+		Return(testFleet(testFleetCluster), nil)
+	mockClients.MockArtifact.EXPECT().
+		GetDownloadLink(gomock.Any(), &artifacttrackerpb.GetDownloadLinkRequest{
+			ArtifactName: "vizier",
+			VersionStr:   "0.1.30",
+			ArtifactType: versionspb.AT_CONTAINER_SET_YAMLS,
+		}).
+		Return(nil, nil).
+		Times(2)
+	mockClients.MockVzMgr.EXPECT().
+		UpdateOrInstallVizier(gomock.Any(), &cvmsgspb.UpdateOrInstallVizierRequest{
+			VizierID: testFleetCluster[0],
+			Version:  "0.1.30",
+		}).
+		Return(&cvmsgspb.UpdateOrInstallVizierResponse{UpdateStarted: true}, nil)
+	mockClients.MockVzMgr.EXPECT().
+		UpdateOrInstallVizier(gomock.Any(), &cvmsgspb.UpdateOrInstallVizierRequest{
+			VizierID: testFleetCluster[1],
+			Version:  "0.1.30",
+		}).
+		Return(nil, status.Error(codes.Unavailable, "cluster is disconnected"))
+
+	fleetServer := &controllers.FleetServer{
+		VzFleet: mockClients.MockVzFleet,
+		VizierClusterInfo: &controllers.VizierClusterInfo{
+			VzMgr:                 mockClients.MockVzMgr,
+			ArtifactTrackerClient: mockClients.MockArtifact,
+		},
+	}
+	resp, err := fleetServer.UpdateOrInstallFleet(ctx, &cloudpb.UpdateOrInstallFleetRequest{
+		FleetID: testFleetID,
+		Version: "0.1.30",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []*cloudpb.UpdateOrInstallFleetResponse_ClusterResult{
+		{
+			ClusterID:     testFleetCluster[0],
+			UpdateStarted: true,
+		},
+		{
+			ClusterID: testFleetCluster[1],
+			Error:     "cluster is disconnected",
+		},
+	}, resp.Results)
+}
+
+func TestFleetServer_UpdateOrInstallFleet_InvalidVersion(t *testing.T) {
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	mockClients.MockVzFleet.EXPECT().
+		GetFleet(gomock.Any(), gomock.Any()).
+		Return(testFleet(testFleetCluster), nil)
+	mockClients.MockArtifact.EXPECT().
+		GetDownloadLink(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("no such version"))
+
+	fleetServer := &controllers.FleetServer{
+		VzFleet: mockClients.MockVzFleet,
+		VizierClusterInfo: &controllers.VizierClusterInfo{
+			VzMgr:                 mockClients.MockVzMgr,
+			ArtifactTrackerClient: mockClients.MockArtifact,
+		},
+	}
+	_, err := fleetServer.UpdateOrInstallFleet(ctx, &cloudpb.UpdateOrInstallFleetRequest{
+		FleetID: testFleetID,
+		Version: "0.0.0",
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestFleetServer_DeleteFleet(t *testing.T) {
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	mockClients.MockVzFleet.EXPECT().
+		DeleteFleet(gomock.Any(), &vzmgrpb.DeleteFleetRequest{
+			OrgID: testFleetOrgID,
+			ID:    testFleetID,
+		}).
+		Return(&types.Empty{}, nil)
+
+	fleetServer := &controllers.FleetServer{VzFleet: mockClients.MockVzFleet}
+	_, err := fleetServer.DeleteFleet(ctx, testFleetID)
+	require.NoError(t, err)
+}
+
+// fleetVizierService returns the responses for the cluster that the script is executed on.
+type fleetVizierService struct {
+	vizierpb.UnimplementedVizierServiceServer
+	resps map[string][]*vizierpb.ExecuteScriptResponse
+	errs  map[string]error
+}
+
+func (f *fleetVizierService) ExecuteScript(req *vizierpb.ExecuteScriptRequest, srv vizierpb.VizierService_ExecuteScriptServer) error {
+	for _, resp := range f.resps[req.ClusterID] {
+		if err := srv.Send(resp); err != nil {
+			return err
+		}
+	}
+	return f.errs[req.ClusterID]
+}
+
+func stringColumn(vals ...string) *vizierpb.Column {
+	data := make([][]byte, len(vals))
+	for i, v := range vals {
+		data[i] = []byte(v)
+	}
+	return &vizierpb.Column{ColData: &vizierpb.Column_StringData{StringData: &vizierpb.StringColumn{Data: data}}}
+}
+
+func int64Column(vals ...int64) *vizierpb.Column {
+	return &vizierpb.Column{ColData: &vizierpb.Column_Int64Data{Int64Data: &vizierpb.Int64Column{Data: vals}}}
+}
+
+// tableResponses returns the responses that stream a table with the given columns.
+func tableResponses(name string, colNames []string, cols ...*vizierpb.Column) []*vizierpb.ExecuteScriptResponse {
+	rel := &vizierpb.Relation{}
+	for i, c := range cols {
+		colType := vizierpb.STRING
+		if _, ok := c.ColData.(*vizierpb.Column_Int64Data); ok {
+			colType = vizierpb.INT64
+		}
+		rel.Columns = append(rel.Columns, &vizierpb.Relation_ColumnInfo{ColumnName: colNames[i], ColumnType: colType})
+	}
+	numRows := int64(0)
+	switch c := cols[0].ColData.(type) {
+	case *vizierpb.Column_StringData:
+		numRows = int64(len(c.StringData.Data))
+	case *vizierpb.Column_Int64Data:
+		numRows = int64(len(c.Int64Data.Data))
+	}
+	return []*vizierpb.ExecuteScriptResponse{
+		{
+			Result: &vizierpb.ExecuteScriptResponse_MetaData{MetaData: &vizierpb.QueryMetadata{
+				ID:       name + "-id",
+				Name:     name,
+				Relation: rel,
+			}},
+		},
+		{
+			Result: &vizierpb.ExecuteScriptResponse_Data{Data: &vizierpb.QueryData{
+				Batch: &vizierpb.RowBatchData{TableID: name + "-id", Cols: cols, NumRows: numRows},
+			}},
+		},
+	}
+}
+
+func edgesResponses(service, remoteAddr, remoteService string, traceRole, numRequests int64) []*vizierpb.ExecuteScriptResponse {
+	return tableResponses("edges",
+		[]string{"service", "remote_addr", "remote_service", "trace_role", "num_requests", "num_errors", "total_latency"},
+		stringColumn(service), stringColumn(remoteAddr), stringColumn(remoteService),
+		int64Column(traceRole), int64Column(numRequests), int64Column(1), int64Column(numRequests*1000))
+}
+
+func TestFleetServer_GetFleetServiceGraph(t *testing.T) {
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	mockClients.MockVzFleet.EXPECT().
+		GetFleet(gomock.Any(), &vzmgrpb.GetFleetRequest{
+			OrgID: testFleetOrgID,
+			ID:    testFleetID,
+		}).
+		Return(testFleet(testFleetCluster), nil)
+
+	clusterA := utils.ProtoToUUIDStr(testFleetCluster[0])
+	clusterB := utils.ProtoToUUIDStr(testFleetCluster[1])
+	var respsA, respsB []*vizierpb.ExecuteScriptResponse
+	// Cluster A calls the load balancer of a service in cluster B.
+	respsA = append(respsA, edgesResponses("pl/frontend", "34.1.1.1", "", 1, 10)...)
+	respsA = append(respsA, tableResponses("egress_ips", []string{"ip_address"}, stringColumn("10.0.0.1"))...)
+	// Cluster B sees the same requests from the node of cluster A.
+	respsB = append(respsB, edgesResponses("pl/backend", "10.0.0.1", "", 2, 10)...)
+	respsB = append(respsB, tableResponses("service_ips", []string{"service", "cluster_ip", "external_ips"},
+		stringColumn("pl/backend"), stringColumn("10.96.0.20"), stringColumn(`["34.1.1.1"]`))...)
+
+	fleetServer := &controllers.FleetServer{
+		VzFleet: mockClients.MockVzFleet,
+		Vizier: &fleetVizierService{
+			resps: map[string][]*vizierpb.ExecuteScriptResponse{clusterA: respsA, clusterB: respsB},
+		},
+	}
+	resp, err := fleetServer.GetFleetServiceGraph(ctx, &cloudpb.GetFleetServiceGraphRequest{FleetID: testFleetID})
+	require.NoError(t, err)
+	assert.Empty(t, resp.ClusterErrors)
+	assert.Equal(t, []*cloudpb.ServiceGraphEdge{
+		{
+			Requestor:    &cloudpb.ServiceGraphNode{ClusterID: testFleetCluster[0], Service: "pl/frontend"},
+			Responder:    &cloudpb.ServiceGraphNode{ClusterID: testFleetCluster[1], Service: "pl/backend"},
+			NumRequests:  10,
+			NumErrors:    1,
+			LatencyNs:    1000,
+			CrossCluster: true,
+		},
+	}, resp.Edges)
+}
+
+func TestFleetServer_GetFleetServiceGraph_ClusterError(t *testing.T) {
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	mockClients.MockVzFleet.EXPECT().
+		GetFleet(gomock.Any(), &vzmgrpb.GetFleetRequest{
+			OrgID: testFleetOrgID,
+			ID:    testFleetID,
+		}).
+		Return(testFleet(testFleetCluster), nil)
+
+	clusterA := utils.ProtoToUUIDStr(testFleetCluster[0])
+	clusterB := utils.ProtoToUUIDStr(testFleetCluster[1])
+	fleetServer := &controllers.FleetServer{
+		VzFleet: mockClients.MockVzFleet,
+		Vizier: &fleetVizierService{
+			resps: map[string][]*vizierpb.ExecuteScriptResponse{
+				clusterA: edgesResponses("pl/frontend", "34.1.1.1", "", 1, 10),
+			},
+			errs: map[string]error{clusterB: status.Error(codes.Unavailable, "cluster is disconnected")},
+		},
+	}
+	resp, err := fleetServer.GetFleetServiceGraph(ctx, &cloudpb.GetFleetServiceGraphRequest{FleetID: testFleetID})
+	require.NoError(t, err)
+	assert.Equal(t, []*cloudpb.GetFleetServiceGraphResponse_ClusterError{
+		{ClusterID: testFleetCluster[1], Error: "cluster is disconnected"},
+	}, resp.ClusterErrors)
+	require.Len(t, resp.Edges, 1)
+	assert.Equal(t, &cloudpb.ServiceGraphNode{IP: "34.1.1.1"}, resp.Edges[0].Responder)
+	assert.False(t, resp.Edges[0].CrossCluster)
+}
@@ -0,0 +1,373 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"px.dev/pixie/src/cloud/shared/openapi"
+)
+
+// OpenAPIPath is the path that the OpenAPI document of the cloud HTTP APIs is served on.
+const OpenAPIPath = "/api/v1/openapi.json"
+
+const (
+	bearerAuthScheme  = "bearerAuth"
+	apiKeyAuthScheme  = "apiKeyAuth"
+	sessionAuthScheme = "sessionAuth"
+)
+
+func queryParam(name, description string, schema *openapi.Schema) *openapi.Parameter {
+	return &openapi.Parameter{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+// responses adds the plain text errors that the handlers reply with to the successful responses.
+func responses(ok map[string]*openapi.Response, errCodes ...int) map[string]*openapi.Response {
+	for _, code := range errCodes {
+		ok[strconv.Itoa(code)] = &openapi.Response{
+			Description: http.StatusText(code),
+			Content:     map[string]*openapi.MediaType{"text/plain": {Schema: &openapi.Schema{Type: "string"}}},
+		}
+	}
+	return ok
+}
+
+func jsonResponse(description string, s *openapi.Schema) *openapi.Response {
+	return &openapi.Response{Description: description, Content: openapi.JSONContent(s)}
+}
+
+func jsonBody(d *openapi.Document, v interface{}) *openapi.RequestBody {
+	return &openapi.RequestBody{Required: true, Content: openapi.JSONContent(d.SchemaOf(v))}
+}
+
+// OpenAPISpec returns the OpenAPI document of the public HTTP APIs of Pixie Cloud. The gRPC APIs are
+// described by their protobuf definitions instead. The schemas are generated from the types that the
+// handlers use, so the document always matches the running API server.
+func OpenAPISpec() *openapi.Document {
+	d := openapi.NewDocument(&openapi.Info{
+		Title:       "Pixie Cloud API",
+		Description: "The HTTP APIs of Pixie Cloud, for clients that can't use gRPC.",
+		Version:     "v1",
+	})
+	d.Components.SecuritySchemes[bearerAuthScheme] = &openapi.SecurityScheme{
+		Type:         "http",
+		Scheme:       "bearer",
+		BearerFormat: "JWT",
+		Description:  "A token returned by the login endpoints.",
+	}
+	d.Components.SecuritySchemes[apiKeyAuthScheme] = &openapi.SecurityScheme{
+		Type:        "apiKey",
+		In:          "header",
+		Name:        "pixie-api-key",
+		Description: "An API key of the org, which can be exchanged for a token by logging in.",
+	}
+	d.Components.SecuritySchemes[sessionAuthScheme] = &openapi.SecurityScheme{
+		Type:        "apiKey",
+		In:          "cookie",
+		Name:        "default-session5",
+		Description: "The session cookie set by the login endpoints.",
+	}
+	d.Security = []openapi.SecurityRequirement{{bearerAuthScheme: {}}, {sessionAuthScheme: {}}}
+	d.Tags = []*openapi.Tag{
+		{Name: "auth", Description: "Log in and manage sessions."},
+		{Name: "orgs", Description: "Manage the members and audit log of an org."},
+		{Name: "scripts", Description: "Execute PxL scripts on clusters."},
+	}
+
+	addAuthOperations(d)
+	addOrgOperations(d)
+	addScriptOperations(d)
+	return d
+}
+
+func addAuthOperations(d *openapi.Document) {
+	noAuth := []openapi.SecurityRequirement{}
+	d.AddOperation(http.MethodPost, "/api/auth/signup", &openapi.Operation{
+		Tags:        []string{"auth"},
+		Summary:     "Sign up a new user with tokens from the identity provider.",
+		OperationID: "signup",
+		RequestBody: jsonBody(d, &authSignupRequest{}),
+		Responses: responses(map[string]*openapi.Response{
+			"200": jsonResponse("The user was signed up, and the session cookie is set.", d.SchemaOf(&authSignupResponse{})),
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError),
+		Security: noAuth,
+	})
+	d.AddOperation(http.MethodPost, "/api/auth/login", &openapi.Operation{
+		Tags:        []string{"auth"},
+		Summary:     "Log in with tokens from the identity provider, or with an API key.",
+		OperationID: "login",
+		RequestBody: &openapi.RequestBody{Content: openapi.JSONContent(d.SchemaOf(&authLoginRequest{}))},
+		Responses: responses(map[string]*openapi.Response{
+			"200": jsonResponse("The user was logged in, and the session cookie is set.", d.SchemaOf(&authLoginResponse{})),
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError),
+		Security: []openapi.SecurityRequirement{{}, {apiKeyAuthScheme: {}}},
+	})
+	d.AddOperation(http.MethodPost, "/api/auth/loginEmbed", &openapi.Operation{
+		Tags:        []string{"auth"},
+		Summary:     "Log in from an embedded UI, without setting the session cookie.",
+		OperationID: "loginEmbed",
+		RequestBody: &openapi.RequestBody{Content: openapi.JSONContent(d.SchemaOf(&authLoginEmbedRequest{}))},
+		Responses: responses(map[string]*openapi.Response{
+			"200": jsonResponse("The user was logged in.", d.SchemaOf(&authLoginResponse{})),
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError),
+		Security: []openapi.SecurityRequirement{{}, {apiKeyAuthScheme: {}}},
+	})
+	d.AddOperation(http.MethodPost, "/api/auth/logout", &openapi.Operation{
+		Tags:        []string{"auth"},
+		Summary:     "Log out, and clear the session cookie.",
+		OperationID: "logout",
+		Responses: responses(map[string]*openapi.Response{
+			"200": {Description: "The user was logged out."},
+		}, http.StatusBadRequest, http.StatusInternalServerError),
+		Security: []openapi.SecurityRequirement{{sessionAuthScheme: {}}},
+	})
+	d.AddOperation(http.MethodPost, "/api/auth/refetch", &openapi.Operation{
+		Tags:        []string{"auth"},
+		Summary:     "Refresh the session with a token that has up to date claims.",
+		OperationID: "refetchToken",
+		Responses: responses(map[string]*openapi.Response{
+			"200": {Description: "The session cookie was refreshed."},
+		}, http.StatusUnauthorized, http.StatusInternalServerError),
+	})
+	d.AddOperation(http.MethodGet, "/api/authorized", &openapi.Operation{
+		Tags:        []string{"auth"},
+		Summary:     "Check that the caller is authenticated.",
+		OperationID: "checkAuthorized",
+		Responses: responses(map[string]*openapi.Response{
+			"200": {Description: "The caller is authenticated."},
+		}, http.StatusUnauthorized),
+	})
+}
+
+func addOrgOperations(d *openapi.Document) {
+	d.AddOperation(http.MethodGet, AuditLogPath, &openapi.Operation{
+		Tags:        []string{"orgs"},
+		Summary:     "List the audit log of the caller's org. Only org admins can read it.",
+		OperationID: "listAuditEvents",
+		Parameters: []*openapi.Parameter{
+			queryParam("since", "Only return events at or after this time.", &openapi.Schema{Type: "string", Format: "date-time"}),
+			queryParam("until", "Only return events before this time.", &openapi.Schema{Type: "string", Format: "date-time"}),
+			queryParam("action", "Only return events for this action, such as api_key.create.", &openapi.Schema{Type: "string"}),
+			queryParam("actorID", "Only return events for actions taken by this actor.", &openapi.Schema{Type: "string"}),
+			queryParam("limit", "The maximum number of events to return.", &openapi.Schema{Type: "integer", Format: "int32"}),
+		},
+		Responses: responses(map[string]*openapi.Response{
+			"200": jsonResponse("The matching events, newest first.", d.SchemaOf(&auditLogResponse{})),
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusInternalServerError),
+	})
+
+	// The SCIM API authenticates with the org's API key as a bearer token.
+	scimAuth := []openapi.SecurityRequirement{{bearerAuthScheme: {}}}
+	scimContent := func(v interface{}) map[string]*openapi.MediaType {
+		return map[string]*openapi.MediaType{scimContentType: {Schema: d.SchemaOf(v)}}
+	}
+	scimResponses := func(description string, v interface{}, codes ...string) map[string]*openapi.Response {
+		r := map[string]*openapi.Response{
+			"default": {Description: "A SCIM error.", Content: scimContent(&scimError{})},
+		}
+		for _, code := range codes {
+			r[code] = &openapi.Response{Description: description}
+			if v != nil {
+				r[code].Content = scimContent(v)
+			}
+		}
+		return r
+	}
+	userIDParam := &openapi.Parameter{Name: "id", In: "path", Required: true, Schema: &openapi.Schema{Type: "string", Format: "uuid"}}
+
+	d.AddOperation(http.MethodGet, SCIMPathPrefix+"/Users", &openapi.Operation{
+		Tags:        []string{"orgs"},
+		Summary:     "List the members of the org, for SCIM provisioning.",
+		OperationID: "scimListUsers",
+		Parameters: []*openapi.Parameter{
+			queryParam("filter", `An equality filter on userName, externalId or emails.value, such as userName eq "user@example.com".`, &openapi.Schema{Type: "string"}),
+			queryParam("startIndex", "The 1-based index of the first user to return.", &openapi.Schema{Type: "integer", Format: "int32"}),
+			queryParam("count", "The maximum number of users to return.", &openapi.Schema{Type: "integer", Format: "int32"}),
+		},
+		Responses: scimResponses("The members of the org.", &scimListResponse{}, "200"),
+		Security:  scimAuth,
+	})
+	d.AddOperation(http.MethodPost, SCIMPathPrefix+"/Users", &openapi.Operation{
+		Tags:        []string{"orgs"},
+		Summary:     "Add a user to the org.",
+		OperationID: "scimCreateUser",
+		RequestBody: &openapi.RequestBody{Required: true, Content: scimContent(&SCIMUser{})},
+		Responses:   scimResponses("The user was created.", &SCIMUser{}, "201"),
+		Security:    scimAuth,
+	})
+	d.AddOperation(http.MethodGet, SCIMPathPrefix+"/Users/{id}", &openapi.Operation{
+		Tags:        []string{"orgs"},
+		Summary:     "Get a member of the org.",
+		OperationID: "scimGetUser",
+		Parameters:  []*openapi.Parameter{userIDParam},
+		Responses:   scimResponses("The user.", &SCIMUser{}, "200"),
+		Security:    scimAuth,
+	})
+	d.AddOperation(http.MethodPut, SCIMPathPrefix+"/Users/{id}", &openapi.Operation{
+		Tags:        []string{"orgs"},
+		Summary:     "Activate or deactivate a member of the org.",
+		OperationID: "scimReplaceUser",
+		Parameters:  []*openapi.Parameter{userIDParam},
+		RequestBody: &openapi.RequestBody{Required: true, Content: scimContent(&SCIMUser{})},
+		Responses:   scimResponses("The updated user.", &SCIMUser{}, "200"),
+		Security:    scimAuth,
+	})
+	d.AddOperation(http.MethodPatch, SCIMPathPrefix+"/Users/{id}", &openapi.Operation{
+		Tags:        []string{"orgs"},
+		Summary:     "Activate or deactivate a member of the org with a SCIM patch.",
+		OperationID: "scimPatchUser",
+		Parameters:  []*openapi.Parameter{userIDParam},
+		RequestBody: &openapi.RequestBody{Required: true, Content: scimContent(&scimPatchRequest{})},
+		Responses:   scimResponses("The updated user.", &SCIMUser{}, "200"),
+		Security:    scimAuth,
+	})
+	d.AddOperation(http.MethodDelete, SCIMPathPrefix+"/Users/{id}", &openapi.Operation{
+		Tags:        []string{"orgs"},
+		Summary:     "Remove a user from the org.",
+		OperationID: "scimDeleteUser",
+		Parameters:  []*openapi.Parameter{userIDParam},
+		Responses:   scimResponses("The user was removed.", nil, "204"),
+		Security:    scimAuth,
+	})
+
+	groupIDParam := &openapi.Parameter{Name: "id", In: "path", Required: true, Schema: &openapi.Schema{Type: "string", Enum: []string{"admin", "editor", "viewer"}}}
+	d.AddOperation(http.MethodGet, SCIMPathPrefix+"/Groups", &openapi.Operation{
+		Tags:        []string{"orgs"},
+		Summary:     "List the roles of the org as SCIM groups, whose members are the users with the role.",
+		OperationID: "scimListGroups",
+		Parameters: []*openapi.Parameter{
+			queryParam("filter", `An equality filter on displayName, such as displayName eq "viewer".`, &openapi.Schema{Type: "string"}),
+			queryParam("startIndex", "The 1-based index of the first group to return.", &openapi.Schema{Type: "integer", Format: "int32"}),
+			queryParam("count", "The maximum number of groups to return.", &openapi.Schema{Type: "integer", Format: "int32"}),
+		},
+		Responses: scimResponses("The roles of the org.", &scimListResponse{}, "200"),
+		Security:  scimAuth,
+	})
+	d.AddOperation(http.MethodPost, SCIMPathPrefix+"/Groups", &openapi.Operation{
+		Tags:        []string{"orgs"},
+		Summary:     "Link a group named after a role to the role, and give the role to the group's members.",
+		OperationID: "scimCreateGroup",
+		RequestBody: &openapi.RequestBody{Required: true, Content: scimContent(&SCIMGroup{})},
+		Responses:   scimResponses("The role's group.", &SCIMGroup{}, "201"),
+		Security:    scimAuth,
+	})
+	d.AddOperation(http.MethodGet, SCIMPathPrefix+"/Groups/{id}", &openapi.Operation{
+		Tags:        []string{"orgs"},
+		Summary:     "Get the users with a role.",
+		OperationID: "scimGetGroup",
+		Parameters:  []*openapi.Parameter{groupIDParam},
+		Responses:   scimResponses("The role's group.", &SCIMGroup{}, "200"),
+		Security:    scimAuth,
+	})
+	d.AddOperation(http.MethodPut, SCIMPathPrefix+"/Groups/{id}", &openapi.Operation{
+		Tags:        []string{"orgs"},
+		Summary:     "Replace the users with a role.",
+		OperationID: "scimReplaceGroup",
+		Parameters:  []*openapi.Parameter{groupIDParam},
+		RequestBody: &openapi.RequestBody{Required: true, Content: scimContent(&SCIMGroup{})},
+		Responses:   scimResponses("The updated group.", &SCIMGroup{}, "200"),
+		Security:    scimAuth,
+	})
+	d.AddOperation(http.MethodPatch, SCIMPathPrefix+"/Groups/{id}", &openapi.Operation{
+		Tags:        []string{"orgs"},
+		Summary:     "Add or remove users with a role with a SCIM patch.",
+		OperationID: "scimPatchGroup",
+		Parameters:  []*openapi.Parameter{groupIDParam},
+		RequestBody: &openapi.RequestBody{Required: true, Content: scimContent(&scimPatchRequest{})},
+		Responses:   scimResponses("The updated group.", &SCIMGroup{}, "200"),
+		Security:    scimAuth,
+	})
+	d.AddOperation(http.MethodDelete, SCIMPathPrefix+"/Groups/{id}", &openapi.Operation{
+		Tags:        []string{"orgs"},
+		Summary:     "Take a role away from all of the users with it.",
+		OperationID: "scimDeleteGroup",
+		Parameters:  []*openapi.Parameter{groupIDParam},
+		Responses:   scimResponses("The role was taken away from its users.", nil, "204"),
+		Security:    scimAuth,
+	})
+}
+
+func addScriptOperations(d *openapi.Document) {
+	d.AddOperation(http.MethodPost, ScriptExecutionPath, &openapi.Operation{
+		Tags:    []string{"scripts"},
+		Summary: "Execute a PxL script on a cluster.",
+		Description: "With format=json, the rows of each table are paginated with offset and limit. Each page " +
+			"executes the script again, so pages of live data may overlap. With format=ndjson, every table and " +
+			"row is streamed as a line of JSON as the cluster produces it.",
+		OperationID: "executeScript",
+		Parameters: []*openapi.Parameter{
+			queryParam("format", "The format of the results.", &openapi.Schema{Type: "string", Enum: []string{"json", "ndjson"}}),
+			queryParam("offset", "The index of the first row of each table to return, for format=json.", &openapi.Schema{Type: "integer", Format: "int64"}),
+			queryParam("limit", "The maximum number of rows of each table to return, for format=json. At most 10000.", &openapi.Schema{Type: "integer", Format: "int64"}),
+		},
+		RequestBody: jsonBody(d, &executeScriptHTTPRequest{}),
+		Responses: responses(map[string]*openapi.Response{
+			"200": {
+				Description: "The results of the script.",
+				Content: map[string]*openapi.MediaType{
+					"application/json":     {Schema: d.SchemaOf(&executeScriptHTTPResponse{})},
+					"application/x-ndjson": {Schema: d.SchemaOf(&streamMessage{})},
+				},
+			},
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusServiceUnavailable),
+	})
+	d.AddOperation(http.MethodGet, ScriptStreamPath, &openapi.Operation{
+		Tags:    []string{"scripts"},
+		Summary: "Stream the results of a PxL script over a WebSocket.",
+		Description: "After the upgrade, the client sends a ScriptStreamRequest as the first message. The server " +
+			"then sends StreamMessages of type table, row, cursor and heartbeat, and ends with a done or error " +
+			"message. A client that reconnects can send the last cursor it received to skip the rows it already has.",
+		OperationID: "streamScript",
+		Parameters: []*openapi.Parameter{
+			{Name: "Upgrade", In: "header", Required: true, Schema: &openapi.Schema{Type: "string", Enum: []string{"websocket"}}},
+		},
+		RequestBody: &openapi.RequestBody{
+			Description: "The first WebSocket message.",
+			Content:     openapi.JSONContent(d.SchemaOf(&scriptStreamRequest{})),
+		},
+		Responses: responses(map[string]*openapi.Response{
+			"101": jsonResponse("The WebSocket was opened. Each message has this schema.", d.SchemaOf(&streamMessage{})),
+		}, http.StatusUnauthorized, http.StatusForbidden),
+	})
+	d.AddOperation(http.MethodGet, ScriptRegistryBundlePath, &openapi.Operation{
+		Tags:        []string{"scripts"},
+		Summary:     "Get the published registry scripts of the caller's org, as a script bundle.",
+		OperationID: "getScriptRegistryBundle",
+		Responses: responses(map[string]*openapi.Response{
+			"200": jsonResponse("The script bundle.", d.SchemaOf(&bundleResponse{})),
+		}, http.StatusUnauthorized, http.StatusForbidden, http.StatusInternalServerError),
+	})
+	d.AddOperation(http.MethodGet, SharedQueryPathPrefix+"{token}", &openapi.Operation{
+		Tags:        []string{"scripts"},
+		Summary:     "Get a saved query that was shared with a link, with its results.",
+		OperationID: "getSharedQuery",
+		Parameters: []*openapi.Parameter{
+			{Name: "token", In: "path", Required: true, Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: responses(map[string]*openapi.Response{
+			"200": jsonResponse("The shared query.", d.SchemaOf(&sharedQueryResponse{})),
+		}, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError),
+	})
+}
+
+// OpenAPIHandler serves the OpenAPI document of the cloud HTTP APIs.
+func OpenAPIHandler() http.Handler {
+	return openapi.Handler(OpenAPISpec())
+}
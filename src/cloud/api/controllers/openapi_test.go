@@ -0,0 +1,100 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/api/controllers"
+)
+
+// collectRefs returns every $ref in the decoded JSON document.
+func collectRefs(v interface{}, refs map[string]bool) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if ref, ok := child.(string); ok && k == "$ref" {
+				refs[ref] = true
+			}
+			collectRefs(child, refs)
+		}
+	case []interface{}:
+		for _, child := range v {
+			collectRefs(child, refs)
+		}
+	}
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	controllers.OpenAPIHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, controllers.OpenAPIPath, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	doc := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+
+	paths := doc["paths"].(map[string]interface{})
+	for _, path := range []string{
+		"/api/auth/login",
+		"/api/auth/signup",
+		controllers.AuditLogPath,
+		controllers.SCIMPathPrefix + "/Users",
+		controllers.SCIMPathPrefix + "/Users/{id}",
+		controllers.ScriptExecutionPath,
+		controllers.ScriptStreamPath,
+		controllers.ScriptRegistryBundlePath,
+		controllers.SharedQueryPathPrefix + "{token}",
+	} {
+		assert.Contains(t, paths, path)
+	}
+
+	// Every operation has a unique ID, for client generators.
+	ids := map[string]bool{}
+	for _, item := range paths {
+		for _, op := range item.(map[string]interface{}) {
+			id := op.(map[string]interface{})["operationId"].(string)
+			assert.False(t, ids[id], "duplicate operation ID %s", id)
+			ids[id] = true
+		}
+	}
+
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	refs := map[string]bool{}
+	collectRefs(doc, refs)
+	for ref := range refs {
+		assert.Contains(t, schemas, strings.TrimPrefix(ref, "#/components/schemas/"))
+	}
+
+	req := schemas["ExecuteScriptHTTPRequest"].(map[string]interface{})
+	assert.ElementsMatch(t, []interface{}{"clusterID", "pxl"}, req["required"])
+	stream := schemas["ScriptStreamRequest"].(map[string]interface{})["properties"].(map[string]interface{})
+	// The fields of the embedded execute request are promoted.
+	assert.Contains(t, stream, "pxl")
+	assert.Contains(t, stream, "cursor")
+	login := schemas["AuthLoginResponse"].(map[string]interface{})["properties"].(map[string]interface{})
+	assert.Contains(t, login, "token")
+	assert.Contains(t, login, "userInfo")
+}
@@ -31,8 +31,10 @@ import (
 	"px.dev/pixie/src/api/proto/uuidpb"
 	"px.dev/pixie/src/cloud/auth/authpb"
 	"px.dev/pixie/src/cloud/profile/profilepb"
+	"px.dev/pixie/src/cloud/shared/auditlog"
 	"px.dev/pixie/src/shared/services/authcontext"
 	"px.dev/pixie/src/shared/services/events"
+	"px.dev/pixie/src/shared/services/rbac"
 	"px.dev/pixie/src/utils"
 )
 
@@ -41,6 +43,7 @@ type OrganizationServiceServer struct {
 	ProfileServiceClient profilepb.ProfileServiceClient
 	AuthServiceClient    authpb.AuthServiceClient
 	OrgServiceClient     profilepb.OrgServiceClient
+	AuditLog             auditlog.Recorder
 }
 
 // InviteUser creates and returns an invite link for the org for the specified user info.
@@ -67,6 +70,7 @@ func (o *OrganizationServiceServer) InviteUser(ctx context.Context, externalReq
 	}
 
 	resp, err := o.AuthServiceClient.InviteUser(ctx, internalReq)
+	recordAudit(ctx, o.AuditLog, auditlog.ActionUserInvited, externalReq.Email, err)
 	if err != nil {
 		return nil, err
 	}
@@ -143,9 +147,11 @@ func (o *OrganizationServiceServer) CreateOrg(ctx context.Context, req *cloudpb.
 			Set("org_name", req.OrgName).
 			Set("org_id", utils.ProtoToUUIDStr(orgID)),
 	})
+	// The user that creates the org administers it.
 	_, err = o.ProfileServiceClient.UpdateUser(ctx, &profilepb.UpdateUserRequest{
 		ID:    utils.ProtoFromUUIDStrOrNil(sCtx.Claims.GetUserClaims().UserID),
 		OrgID: orgID,
+		Roles: &profilepb.UserRoles{Roles: []string{string(rbac.RoleAdmin)}},
 	})
 	if err != nil {
 		return nil, err
@@ -171,6 +177,7 @@ func (o *OrganizationServiceServer) UpdateOrg(ctx context.Context, req *cloudpb.
 		ID:              req.ID,
 		EnableApprovals: req.EnableApprovals,
 	})
+	recordAudit(ctx, o.AuditLog, auditlog.ActionOrgUpdated, auditResourceID(req.ID), err)
 	if err != nil {
 		return nil, err
 	}
@@ -250,6 +257,7 @@ func (o *OrganizationServiceServer) RemoveUserFromOrg(ctx context.Context, req *
 		ID:    req.UserID,
 		OrgID: &uuidpb.UUID{},
 	})
+	recordAudit(ctx, o.AuditLog, auditlog.ActionUserRemoved, auditResourceID(req.UserID), err)
 	if err != nil {
 		return nil, err
 	}
@@ -371,6 +379,7 @@ func (o *OrganizationServiceServer) CreateInviteToken(ctx context.Context, req *
 	resp, err := o.OrgServiceClient.CreateInviteToken(ctx, &profilepb.CreateInviteTokenRequest{
 		OrgID: req.OrgID,
 	})
+	recordAudit(ctx, o.AuditLog, auditlog.ActionInviteTokenCreated, auditResourceID(req.OrgID), err)
 	if err != nil {
 		return nil, err
 	}
@@ -393,7 +402,9 @@ func (o *OrganizationServiceServer) RevokeAllInviteTokens(ctx context.Context, r
 		return nil, status.Errorf(codes.PermissionDenied, "cannot revoke invites for org")
 	}
 
-	return o.OrgServiceClient.RevokeAllInviteTokens(ctx, req)
+	resp, err := o.OrgServiceClient.RevokeAllInviteTokens(ctx, req)
+	recordAudit(ctx, o.AuditLog, auditlog.ActionInviteTokensRevoked, auditResourceID(req), err)
+	return resp, err
 }
 
 // VerifyInviteToken verifies that the given invite JWT is still valid by performing expiration and
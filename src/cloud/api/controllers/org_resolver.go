@@ -45,6 +45,9 @@ type UserInviteResolver struct {
 // InviteUser invites the user with the given name and email address to the org by providing
 // an invite link.
 func (q *QueryResolver) InviteUser(ctx context.Context, args *inviteUserArgs) (*UserInviteResolver, error) {
+	if err := authorizeGQL(ctx, "/px.cloudapi.OrganizationService/InviteUser", nil); err != nil {
+		return nil, err
+	}
 	grpcAPI := q.Env.OrgServer
 
 	resp, err := grpcAPI.InviteUser(ctx, &cloudpb.InviteUserRequest{
@@ -200,6 +203,9 @@ type editableOrgSettings struct {
 
 // UpdateOrgSettings updates settings for the given org.
 func (q *QueryResolver) UpdateOrgSettings(ctx context.Context, args updateOrgSettingsArgs) (*OrgInfoResolver, error) {
+	if err := authorizeGQL(ctx, "/px.cloudapi.OrganizationService/UpdateOrg", nil); err != nil {
+		return nil, err
+	}
 	idPb := utils.ProtoFromUUIDStrOrNil(string(args.OrgID))
 	req := &cloudpb.UpdateOrgRequest{
 		ID: idPb,
@@ -232,6 +238,9 @@ type createInviteTokenArgs struct {
 
 // CreateInviteToken creates a signed invite JWT for the given org with an expiration of 1 week.
 func (q *QueryResolver) CreateInviteToken(ctx context.Context, args *createInviteTokenArgs) (string, error) {
+	if err := authorizeGQL(ctx, "/px.cloudapi.OrganizationService/CreateInviteToken", nil); err != nil {
+		return "", err
+	}
 	grpcAPI := q.Env.OrgServer
 
 	resp, err := grpcAPI.CreateInviteToken(ctx, &cloudpb.CreateInviteTokenRequest{
@@ -251,6 +260,9 @@ type revokeAllInviteTokensArgs struct {
 
 // RevokeAllInviteTokens revokes all pending invited for the given org by rotating the JWT signing key.
 func (q *QueryResolver) RevokeAllInviteTokens(ctx context.Context, args *revokeAllInviteTokensArgs) (bool, error) {
+	if err := authorizeGQL(ctx, "/px.cloudapi.OrganizationService/RevokeAllInviteTokens", nil); err != nil {
+		return false, err
+	}
 	grpcAPI := q.Env.OrgServer
 
 	_, err := grpcAPI.RevokeAllInviteTokens(ctx, utils.ProtoFromUUIDStrOrNil(string(args.OrgID)))
@@ -286,6 +298,9 @@ type removeUserFromOrg struct {
 
 // RemoveUserFromOrg removes the given user from the current org.
 func (q *QueryResolver) RemoveUserFromOrg(ctx context.Context, args *removeUserFromOrg) (bool, error) {
+	if err := authorizeGQL(ctx, "/px.cloudapi.OrganizationService/RemoveUserFromOrg", nil); err != nil {
+		return false, err
+	}
 	grpcAPI := q.Env.OrgServer
 
 	resp, err := grpcAPI.RemoveUserFromOrg(ctx, &cloudpb.RemoveUserFromOrgRequest{UserID: utils.ProtoFromUUIDStrOrNil(string(args.UserID))})
@@ -296,3 +311,74 @@ func (q *QueryResolver) RemoveUserFromOrg(ctx context.Context, args *removeUserF
 
 	return resp.Success, nil
 }
+
+// OrgBrandingResolver resolves the branding of an org.
+type OrgBrandingResolver struct {
+	branding *cloudpb.OrgBranding
+}
+
+// Announcement returns the message shown at the top of the UI.
+func (b *OrgBrandingResolver) Announcement() string {
+	return b.branding.Announcement
+}
+
+// AnnouncementURL returns the link to more details about the announcement.
+func (b *OrgBrandingResolver) AnnouncementURL() string {
+	return b.branding.AnnouncementURL
+}
+
+// DocsURL returns the base URL of the docs that the UI links to.
+func (b *OrgBrandingResolver) DocsURL() string {
+	return b.branding.DocsURL
+}
+
+// SupportContact returns who to contact for help.
+func (b *OrgBrandingResolver) SupportContact() string {
+	return b.branding.SupportContact
+}
+
+// OrgBranding gets the branding of the current user's org.
+func (q *QueryResolver) OrgBranding(ctx context.Context) (*OrgBrandingResolver, error) {
+	resp, err := q.Env.ArtifactTrackerServer.GetOrgBranding(ctx, &cloudpb.GetOrgBrandingRequest{})
+	if err != nil {
+		return nil, rpcErrorHelper(err)
+	}
+	return &OrgBrandingResolver{resp}, nil
+}
+
+type updateOrgBrandingArgs struct {
+	Branding editableOrgBranding
+}
+
+type editableOrgBranding struct {
+	Announcement    *string
+	AnnouncementURL *string
+	DocsURL         *string
+	SupportContact  *string
+}
+
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// UpdateOrgBranding replaces the branding of the current user's org.
+func (q *QueryResolver) UpdateOrgBranding(ctx context.Context, args updateOrgBrandingArgs) (*OrgBrandingResolver, error) {
+	if err := authorizeGQL(ctx, "/px.cloudapi.ArtifactTracker/UpdateOrgBranding", nil); err != nil {
+		return nil, err
+	}
+	resp, err := q.Env.ArtifactTrackerServer.UpdateOrgBranding(ctx, &cloudpb.UpdateOrgBrandingRequest{
+		Branding: &cloudpb.OrgBranding{
+			Announcement:    stringOrEmpty(args.Branding.Announcement),
+			AnnouncementURL: stringOrEmpty(args.Branding.AnnouncementURL),
+			DocsURL:         stringOrEmpty(args.Branding.DocsURL),
+			SupportContact:  stringOrEmpty(args.Branding.SupportContact),
+		},
+	})
+	if err != nil {
+		return nil, rpcErrorHelper(err)
+	}
+	return &OrgBrandingResolver{resp}, nil
+}
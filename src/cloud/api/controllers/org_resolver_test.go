@@ -573,3 +573,86 @@ func TestOrgSettingsResolver_RemoveUserFromOrg(t *testing.T) {
 		})
 	}
 }
+
+func TestOrgBrandingResolver(t *testing.T) {
+	gqlEnv, mockClients, cleanup := gqltestutils.CreateTestGraphQLEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	mockClients.MockArtifact.EXPECT().
+		GetOrgBranding(gomock.Any(), &cloudpb.GetOrgBrandingRequest{}).
+		Return(&cloudpb.OrgBranding{
+			Announcement:    "Maintenance on Friday",
+			AnnouncementURL: "https://status.example.com",
+			DocsURL:         "https://docs.example.com",
+		}, nil)
+
+	gqlSchema := LoadSchema(gqlEnv)
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema:  gqlSchema,
+			Context: ctx,
+			Query: `
+				query {
+					orgBranding {
+						announcement
+						announcementURL
+						docsURL
+						supportContact
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"orgBranding": {
+						"announcement": "Maintenance on Friday",
+						"announcementURL": "https://status.example.com",
+						"docsURL": "https://docs.example.com",
+						"supportContact": ""
+					}
+				}
+			`,
+		},
+	})
+}
+
+func TestOrgBrandingResolver_UpdateOrgBranding(t *testing.T) {
+	gqlEnv, mockClients, cleanup := gqltestutils.CreateTestGraphQLEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	mockClients.MockArtifact.EXPECT().
+		UpdateOrgBranding(gomock.Any(), &cloudpb.UpdateOrgBrandingRequest{
+			Branding: &cloudpb.OrgBranding{
+				SupportContact: "#pixie-help",
+			},
+		}).
+		Return(&cloudpb.OrgBranding{
+			Announcement:   "Maintenance on Friday",
+			SupportContact: "#pixie-help",
+		}, nil)
+
+	gqlSchema := LoadSchema(gqlEnv)
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema:  gqlSchema,
+			Context: ctx,
+			Query: `
+				mutation {
+					UpdateOrgBranding(branding: { supportContact: "#pixie-help" }) {
+						announcement
+						supportContact
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"UpdateOrgBranding": {
+						"announcement": "Maintenance on Friday",
+						"supportContact": "#pixie-help"
+					}
+				}
+			`,
+		},
+	})
+}
@@ -74,7 +74,7 @@ func TestOrganizationServiceServer_InviteUser(t *testing.T) {
 					InviteLink: "withpixie.ai/invite&id=abcd",
 				}, nil)
 
-			os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg}
+			os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg, nil}
 
 			resp, err := os.InviteUser(ctx, &cloudpb.InviteUserRequest{
 				Email:     "bobloblaw@lawblog.law",
@@ -97,7 +97,7 @@ func TestOrganizationServiceServer_CreateOrg_UserAlreadyInOrg(t *testing.T) {
 	defer cleanup()
 	ctx := CreateTestContext()
 
-	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg}
+	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg, nil}
 
 	_, err := os.CreateOrg(ctx, &cloudpb.CreateOrgRequest{
 		OrgName: "new_org_name",
@@ -124,12 +124,13 @@ func TestOrganizationServiceServer_CreateOrg_UserWithNoOrg(t *testing.T) {
 	mockClients.MockProfile.EXPECT().UpdateUser(gomock.Any(), &profilepb.UpdateUserRequest{
 		ID:    utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c9"),
 		OrgID: orgID,
+		Roles: &profilepb.UserRoles{Roles: []string{"admin"}},
 	}).Return(&profilepb.UserInfo{
 		ID:    utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c9"),
 		OrgID: orgID,
 	}, nil)
 
-	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg}
+	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg, nil}
 
 	resp, err := os.CreateOrg(ctx, &cloudpb.CreateOrgRequest{
 		OrgName: "new_org_name",
@@ -147,7 +148,7 @@ func TestOrganizationServiceServer_CreateOrg_BadOrgName(t *testing.T) {
 	defer cleanup()
 	ctx := CreateTestContextNoOrg()
 
-	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg}
+	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg, nil}
 
 	_, err := os.CreateOrg(ctx, &cloudpb.CreateOrgRequest{
 		OrgName: "a.b",
@@ -165,7 +166,7 @@ func TestOrganizationServiceServer_RemoveUserFromOrg(t *testing.T) {
 	defer cleanup()
 	ctx := CreateTestContext()
 
-	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg}
+	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg, nil}
 
 	userID := utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd43000")
 	orgID := utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
@@ -199,7 +200,7 @@ func TestOrganizationServiceServer_RemoveUserFromOrg_UserNotInOrg(t *testing.T)
 	defer cleanup()
 	ctx := CreateTestContext()
 
-	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg}
+	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg, nil}
 
 	userID := utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd43010")
 	orgID := utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430d0")
@@ -239,7 +240,7 @@ func TestOrganizationServiceServer_AddOrgIDEConfig(t *testing.T) {
 		},
 	}, nil)
 
-	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg}
+	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg, nil}
 
 	resp, err := os.AddOrgIDEConfig(ctx, &cloudpb.AddOrgIDEConfigRequest{
 		OrgID: utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
@@ -267,7 +268,7 @@ func TestOrganizationServiceServer_DeleteOrgIDEConfig(t *testing.T) {
 		IDEName: "test",
 	}).Return(&profilepb.DeleteOrgIDEConfigResponse{}, nil)
 
-	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg}
+	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg, nil}
 
 	resp, err := os.DeleteOrgIDEConfig(ctx, &cloudpb.DeleteOrgIDEConfigRequest{
 		OrgID:   utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
@@ -418,7 +419,7 @@ func TestOrganizationServiceServer_CorrectOrgPermissions(t *testing.T) {
 			defer cleanup()
 			ctx := CreateTestContext()
 
-			os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, &fakeOrg{}}
+			os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, &fakeOrg{}, nil}
 			// Incorrect org call.
 			err := test.funcCall(ctx, os, utils.ProtoFromUUIDStrOrNil("11111111-9dad-11d1-80b4-00c04fd430c8"))
 			require.Error(t, err)
@@ -453,7 +454,7 @@ func TestOrganizationServiceServer_GetOrgIDEConfigs(t *testing.T) {
 		},
 	}, nil)
 
-	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg}
+	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg, nil}
 
 	resp, err := os.GetOrgIDEConfigs(ctx, &cloudpb.GetOrgIDEConfigsRequest{
 		OrgID: utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
@@ -23,6 +23,7 @@ import (
 
 	"px.dev/pixie/src/api/proto/cloudpb"
 	"px.dev/pixie/src/cloud/plugin/pluginpb"
+	"px.dev/pixie/src/cloud/shared/auditlog"
 	"px.dev/pixie/src/shared/services/authcontext"
 	"px.dev/pixie/src/utils"
 )
@@ -31,6 +32,8 @@ import (
 type PluginServiceServer struct {
 	PluginServiceClient              pluginpb.PluginServiceClient
 	DataRetentionPluginServiceClient pluginpb.DataRetentionPluginServiceClient
+	AlertRoutePluginServiceClient    pluginpb.AlertRoutePluginServiceClient
+	AuditLog                         auditlog.Recorder
 }
 
 func kindCloudProtoToPluginProto(kind cloudpb.PluginKind) pluginpb.PluginKind {
@@ -174,6 +177,7 @@ func (p *PluginServiceServer) UpdateRetentionPluginConfig(ctx context.Context, r
 		InsecureTLS:     req.InsecureTLS,
 		DisablePresets:  req.DisablePresets,
 	})
+	recordAudit(ctx, p.AuditLog, auditlog.ActionRetentionPluginUpdated, req.PluginId, err)
 	if err != nil {
 		return nil, err
 	}
@@ -277,6 +281,7 @@ func (p *PluginServiceServer) UpdateRetentionScript(ctx context.Context, req *cl
 		ExportUrl:   req.ExportUrl,
 		ClusterIDs:  req.ClusterIDs,
 	})
+	recordAudit(ctx, p.AuditLog, auditlog.ActionScriptDeployed, auditResourceID(req.ID), err)
 	if err != nil {
 		return nil, err
 	}
@@ -314,6 +319,7 @@ func (p *PluginServiceServer) CreateRetentionScript(ctx context.Context, req *cl
 		},
 		OrgID: orgID,
 	})
+	recordAudit(ctx, p.AuditLog, auditlog.ActionScriptDeployed, auditResourceID(resp.GetID()), err)
 	if err != nil {
 		return nil, err
 	}
@@ -339,9 +345,135 @@ func (p *PluginServiceServer) DeleteRetentionScript(ctx context.Context, req *cl
 		ID:    req.ID,
 		OrgID: orgID,
 	})
+	recordAudit(ctx, p.AuditLog, auditlog.ActionScriptDeleted, auditResourceID(req.ID), err)
 	if err != nil {
 		return nil, err
 	}
 
 	return &cloudpb.DeleteRetentionScriptResponse{}, nil
 }
+
+func alertRouteToCloudProto(r *pluginpb.AlertRoute) *cloudpb.AlertRoute {
+	return &cloudpb.AlertRoute{
+		ID:          r.ID,
+		Name:        r.Name,
+		PluginId:    r.PluginId,
+		Destination: r.Destination,
+		Template:    r.Template,
+		ClusterID:   r.ClusterID,
+		ScriptID:    r.ScriptID,
+	}
+}
+
+// GetAlertRoutes fetches the alert routes configured by the org.
+func (p *PluginServiceServer) GetAlertRoutes(ctx context.Context, req *cloudpb.GetAlertRoutesRequest) (*cloudpb.GetAlertRoutesResponse, error) {
+	sCtx, err := authcontext.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orgID := utils.ProtoFromUUIDStrOrNil(sCtx.Claims.GetUserClaims().OrgID)
+
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.AlertRoutePluginServiceClient.GetAlertRoutes(ctx, &pluginpb.GetAlertRoutesRequest{OrgID: orgID})
+	if err != nil {
+		return nil, err
+	}
+
+	routes := make([]*cloudpb.AlertRoute, len(resp.Routes))
+	for i, r := range resp.Routes {
+		routes[i] = alertRouteToCloudProto(r)
+	}
+	return &cloudpb.GetAlertRoutesResponse{Routes: routes}, nil
+}
+
+// CreateAlertRoute creates a route which sends alert events to an alert plugin.
+func (p *PluginServiceServer) CreateAlertRoute(ctx context.Context, req *cloudpb.CreateAlertRouteRequest) (*cloudpb.CreateAlertRouteResponse, error) {
+	sCtx, err := authcontext.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orgID := utils.ProtoFromUUIDStrOrNil(sCtx.Claims.GetUserClaims().OrgID)
+
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r := req.GetRoute()
+	resp, err := p.AlertRoutePluginServiceClient.CreateAlertRoute(ctx, &pluginpb.CreateAlertRouteRequest{
+		Route: &pluginpb.AlertRoute{
+			OrgID:       orgID,
+			Name:        r.GetName(),
+			PluginId:    r.GetPluginId(),
+			Destination: r.GetDestination(),
+			Template:    r.GetTemplate(),
+			ClusterID:   r.GetClusterID(),
+			ScriptID:    r.GetScriptID(),
+		},
+	})
+	recordAudit(ctx, p.AuditLog, auditlog.ActionAlertRouteCreated, auditResourceID(resp.GetID()), err)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cloudpb.CreateAlertRouteResponse{ID: resp.ID}, nil
+}
+
+// UpdateAlertRoute updates an alert route.
+func (p *PluginServiceServer) UpdateAlertRoute(ctx context.Context, req *cloudpb.UpdateAlertRouteRequest) (*cloudpb.UpdateAlertRouteResponse, error) {
+	sCtx, err := authcontext.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orgID := utils.ProtoFromUUIDStrOrNil(sCtx.Claims.GetUserClaims().OrgID)
+
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = p.AlertRoutePluginServiceClient.UpdateAlertRoute(ctx, &pluginpb.UpdateAlertRouteRequest{
+		ID:          req.ID,
+		OrgID:       orgID,
+		Name:        req.Name,
+		Destination: req.Destination,
+		Template:    req.Template,
+		ClusterID:   req.ClusterID,
+		ScriptID:    req.ScriptID,
+	})
+	recordAudit(ctx, p.AuditLog, auditlog.ActionAlertRouteUpdated, auditResourceID(req.ID), err)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cloudpb.UpdateAlertRouteResponse{}, nil
+}
+
+// DeleteAlertRoute deletes an alert route.
+func (p *PluginServiceServer) DeleteAlertRoute(ctx context.Context, req *cloudpb.DeleteAlertRouteRequest) (*cloudpb.DeleteAlertRouteResponse, error) {
+	sCtx, err := authcontext.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orgID := utils.ProtoFromUUIDStrOrNil(sCtx.Claims.GetUserClaims().OrgID)
+
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = p.AlertRoutePluginServiceClient.DeleteAlertRoute(ctx, &pluginpb.DeleteAlertRouteRequest{
+		ID:    req.ID,
+		OrgID: orgID,
+	})
+	recordAudit(ctx, p.AuditLog, auditlog.ActionAlertRouteDeleted, auditResourceID(req.ID), err)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cloudpb.DeleteAlertRouteResponse{}, nil
+}
@@ -224,6 +224,9 @@ type updateRetentionPluginConfigArgs struct {
 
 // UpdateRetentionPluginConfig updates the configs for a retention plugin, including enabling/disabling the plugin.
 func (q *QueryResolver) UpdateRetentionPluginConfig(ctx context.Context, args updateRetentionPluginConfigArgs) (bool, error) {
+	if err := authorizeGQL(ctx, "/px.cloudapi.PluginService/UpdateRetentionPluginConfig", nil); err != nil {
+		return false, err
+	}
 	configs := make(map[string]string)
 	for _, c := range args.Configs.Configs {
 		configs[c.Name] = c.Value
@@ -385,6 +388,9 @@ func (q *QueryResolver) RetentionScript(ctx context.Context, args retentionScrip
 
 // UpdateRetentionScript updates the details for a single retention script.
 func (q *QueryResolver) UpdateRetentionScript(ctx context.Context, args updateRetentionScriptArgs) (bool, error) {
+	if err := authorizeGQL(ctx, "/px.cloudapi.PluginService/UpdateRetentionScript", nil); err != nil {
+		return false, err
+	}
 	req := &cloudpb.UpdateRetentionScriptRequest{
 		ID: utils.ProtoFromUUIDStrOrNil(string(args.ID)),
 	}
@@ -441,6 +447,9 @@ func (q *QueryResolver) UpdateRetentionScript(ctx context.Context, args updateRe
 
 // DeleteRetentionScript deletes a retention script.
 func (q *QueryResolver) DeleteRetentionScript(ctx context.Context, args deleteRetentionScriptArgs) (bool, error) {
+	if err := authorizeGQL(ctx, "/px.cloudapi.PluginService/DeleteRetentionScript", nil); err != nil {
+		return false, err
+	}
 	req := &cloudpb.DeleteRetentionScriptRequest{
 		ID: utils.ProtoFromUUIDStrOrNil(string(args.ID)),
 	}
@@ -455,6 +464,9 @@ func (q *QueryResolver) DeleteRetentionScript(ctx context.Context, args deleteRe
 
 // CreateRetentionScript creates a new retention script.
 func (q *QueryResolver) CreateRetentionScript(ctx context.Context, args createRetentionScriptArgs) (graphql.ID, error) {
+	if err := authorizeGQL(ctx, "/px.cloudapi.PluginService/CreateRetentionScript", nil); err != nil {
+		return "", err
+	}
 	req := &cloudpb.CreateRetentionScriptRequest{}
 
 	if args.Script == nil {
@@ -188,7 +188,7 @@ func TestGetPlugins(t *testing.T) {
 					Plugins: test.orgRetentionPlugins,
 				}, nil)
 
-			pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin}
+			pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin, mockClients.MockAlertRoutePlugin, nil}
 
 			resp, err := pServer.GetPlugins(ctx, &cloudpb.GetPluginsRequest{
 				Kind: cloudpb.PK_RETENTION,
@@ -224,7 +224,7 @@ func TestGetOrgRetentionPluginConfig(t *testing.T) {
 			InsecureTLS:     true,
 		}, nil)
 
-	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin}
+	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin, mockClients.MockAlertRoutePlugin, nil}
 
 	resp, err := pServer.GetOrgRetentionPluginConfig(ctx, &cloudpb.GetOrgRetentionPluginConfigRequest{
 		PluginId: "test-plugin",
@@ -263,7 +263,7 @@ func TestGetRetentionPluginInfo(t *testing.T) {
 			DefaultExportURL:     "https://test.com",
 		}, nil)
 
-	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin}
+	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin, mockClients.MockAlertRoutePlugin, nil}
 
 	resp, err := pServer.GetRetentionPluginInfo(ctx, &cloudpb.GetRetentionPluginInfoRequest{
 		PluginId: "test-plugin",
@@ -307,7 +307,7 @@ func TestUpdateRetentionPluginConfig(t *testing.T) {
 	mockClients.MockDataRetentionPlugin.EXPECT().UpdateOrgRetentionPluginConfig(gomock.Any(), mockReq).
 		Return(&pluginpb.UpdateOrgRetentionPluginConfigResponse{}, nil)
 
-	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin}
+	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin, mockClients.MockAlertRoutePlugin, nil}
 
 	resp, err := pServer.UpdateRetentionPluginConfig(ctx, &cloudpb.UpdateRetentionPluginConfigRequest{
 		PluginId: "test-plugin",
@@ -370,7 +370,7 @@ func TestGetRetentionScripts(t *testing.T) {
 			},
 		}, nil)
 
-	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin}
+	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin, mockClients.MockAlertRoutePlugin, nil}
 
 	resp, err := pServer.GetRetentionScripts(ctx, &cloudpb.GetRetentionScriptsRequest{})
 
@@ -445,7 +445,7 @@ func TestGetRetentionScript(t *testing.T) {
 			},
 		}, nil)
 
-	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin}
+	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin, mockClients.MockAlertRoutePlugin, nil}
 
 	resp, err := pServer.GetRetentionScript(ctx, &cloudpb.GetRetentionScriptRequest{
 		ID: scriptID,
@@ -500,7 +500,7 @@ func TestUpdateRetentionScript(t *testing.T) {
 	mockClients.MockDataRetentionPlugin.EXPECT().UpdateRetentionScript(gomock.Any(), mockReq).
 		Return(&pluginpb.UpdateRetentionScriptResponse{}, nil)
 
-	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin}
+	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin, mockClients.MockAlertRoutePlugin, nil}
 
 	resp, err := pServer.UpdateRetentionScript(ctx, &cloudpb.UpdateRetentionScriptRequest{
 		ID:          scriptID,
@@ -556,7 +556,7 @@ func TestCreateRetentionScript(t *testing.T) {
 	mockClients.MockDataRetentionPlugin.EXPECT().CreateRetentionScript(gomock.Any(), mockReq).
 		Return(&pluginpb.CreateRetentionScriptResponse{ID: scriptID}, nil)
 
-	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin}
+	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin, mockClients.MockAlertRoutePlugin, nil}
 
 	resp, err := pServer.CreateRetentionScript(ctx, &cloudpb.CreateRetentionScriptRequest{
 		ScriptName:  "Test Script",
@@ -597,7 +597,7 @@ func TestDeleteRetentionScript(t *testing.T) {
 	mockClients.MockDataRetentionPlugin.EXPECT().DeleteRetentionScript(gomock.Any(), mockReq).
 		Return(&pluginpb.DeleteRetentionScriptResponse{}, nil)
 
-	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin}
+	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin, mockClients.MockAlertRoutePlugin, nil}
 
 	resp, err := pServer.DeleteRetentionScript(ctx, &cloudpb.DeleteRetentionScriptRequest{
 		ID: scriptID,
@@ -608,3 +608,119 @@ func TestDeleteRetentionScript(t *testing.T) {
 
 	assert.Equal(t, &cloudpb.DeleteRetentionScriptResponse{}, resp)
 }
+
+func TestGetAlertRoutes(t *testing.T) {
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	routeID := utils.ProtoFromUUIDStrOrNil("1ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	clusterID := utils.ProtoFromUUIDStrOrNil("2ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+	mockClients.MockAlertRoutePlugin.EXPECT().GetAlertRoutes(gomock.Any(), &pluginpb.GetAlertRoutesRequest{
+		OrgID: utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+	}).Return(&pluginpb.GetAlertRoutesResponse{
+		Routes: []*pluginpb.AlertRoute{
+			{
+				ID:          routeID,
+				OrgID:       utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+				Name:        "oncall",
+				PluginId:    "pagerduty",
+				Destination: "routing-key",
+				ClusterID:   clusterID,
+			},
+		},
+	}, nil)
+
+	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin, mockClients.MockAlertRoutePlugin, nil}
+
+	resp, err := pServer.GetAlertRoutes(ctx, &cloudpb.GetAlertRoutesRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, &cloudpb.GetAlertRoutesResponse{
+		Routes: []*cloudpb.AlertRoute{
+			{
+				ID:          routeID,
+				Name:        "oncall",
+				PluginId:    "pagerduty",
+				Destination: "routing-key",
+				ClusterID:   clusterID,
+			},
+		},
+	}, resp)
+}
+
+func TestCreateAlertRoute(t *testing.T) {
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	routeID := utils.ProtoFromUUIDStrOrNil("1ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	scriptID := utils.ProtoFromUUIDStrOrNil("3ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+	mockClients.MockAlertRoutePlugin.EXPECT().CreateAlertRoute(gomock.Any(), &pluginpb.CreateAlertRouteRequest{
+		Route: &pluginpb.AlertRoute{
+			OrgID:       utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+			Name:        "alerts-channel",
+			PluginId:    "slack",
+			Destination: "https://hooks.slack.com/services/abc",
+			Template:    "{{.RuleName}} is {{.Status}}",
+			ScriptID:    scriptID,
+		},
+	}).Return(&pluginpb.CreateAlertRouteResponse{ID: routeID}, nil)
+
+	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin, mockClients.MockAlertRoutePlugin, nil}
+
+	resp, err := pServer.CreateAlertRoute(ctx, &cloudpb.CreateAlertRouteRequest{
+		Route: &cloudpb.AlertRoute{
+			Name:        "alerts-channel",
+			PluginId:    "slack",
+			Destination: "https://hooks.slack.com/services/abc",
+			Template:    "{{.RuleName}} is {{.Status}}",
+			ScriptID:    scriptID,
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &cloudpb.CreateAlertRouteResponse{ID: routeID}, resp)
+}
+
+func TestUpdateAlertRoute(t *testing.T) {
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	routeID := utils.ProtoFromUUIDStrOrNil("1ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+	mockClients.MockAlertRoutePlugin.EXPECT().UpdateAlertRoute(gomock.Any(), &pluginpb.UpdateAlertRouteRequest{
+		ID:       routeID,
+		OrgID:    utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+		Template: &types.StringValue{Value: "{{.RuleName}}"},
+	}).Return(&pluginpb.UpdateAlertRouteResponse{}, nil)
+
+	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin, mockClients.MockAlertRoutePlugin, nil}
+
+	resp, err := pServer.UpdateAlertRoute(ctx, &cloudpb.UpdateAlertRouteRequest{
+		ID:       routeID,
+		Template: &types.StringValue{Value: "{{.RuleName}}"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &cloudpb.UpdateAlertRouteResponse{}, resp)
+}
+
+func TestDeleteAlertRoute(t *testing.T) {
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	routeID := utils.ProtoFromUUIDStrOrNil("1ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+	mockClients.MockAlertRoutePlugin.EXPECT().DeleteAlertRoute(gomock.Any(), &pluginpb.DeleteAlertRouteRequest{
+		ID:    routeID,
+		OrgID: utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+	}).Return(&pluginpb.DeleteAlertRouteResponse{}, nil)
+
+	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin, mockClients.MockAlertRoutePlugin, nil}
+
+	resp, err := pServer.DeleteAlertRoute(ctx, &cloudpb.DeleteAlertRouteRequest{ID: routeID})
+	require.NoError(t, err)
+	assert.Equal(t, &cloudpb.DeleteAlertRouteResponse{}, resp)
+}
@@ -0,0 +1,323 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/cloud/scriptmgr/scriptmgrpb"
+	"px.dev/pixie/src/cloud/shared/auditlog"
+	"px.dev/pixie/src/shared/services/authcontext"
+	"px.dev/pixie/src/utils"
+)
+
+// SharedQueryPathPrefix is the path prefix that shared queries are served on as JSON, followed by
+// their share token. It makes up the sharing links of saved queries.
+const SharedQueryPathPrefix = "/api/v1/shared-queries/"
+
+// QueryHistoryServer is the server that implements the QueryHistory gRPC service.
+type QueryHistoryServer struct {
+	QueryHistory scriptmgrpb.QueryHistoryServiceClient
+	AuditLog     auditlog.Recorder
+}
+
+func savedQueryMetadataToCloudAPI(md *scriptmgrpb.SavedQueryMetadata) *cloudpb.SavedQueryMetadata {
+	return &cloudpb.SavedQueryMetadata{
+		ID:              md.ID,
+		Name:            md.Name,
+		UserID:          md.UserID,
+		ClusterID:       md.ClusterID,
+		ScriptName:      md.ScriptName,
+		ExecutedAt:      md.ExecutedAt,
+		CreatedAt:       md.CreatedAt,
+		ResultSizeBytes: md.ResultSizeBytes,
+		Shared:          md.Shared,
+	}
+}
+
+func savedQueryToCloudAPI(resp *scriptmgrpb.GetSavedQueryResp) *cloudpb.SavedQuery {
+	args := make([]*cloudpb.SavedQueryArg, len(resp.Args))
+	for i, arg := range resp.Args {
+		args[i] = &cloudpb.SavedQueryArg{Name: arg.Name, Value: arg.Value}
+	}
+	return &cloudpb.SavedQuery{
+		Metadata: savedQueryMetadataToCloudAPI(resp.Metadata),
+		Pxl:      resp.Pxl,
+		Args:     args,
+		Result:   resp.Result,
+	}
+}
+
+func sharedQueryURL(token string) string {
+	return fmt.Sprintf("https://work.%s%s%s", viper.GetString("domain_name"), SharedQueryPathPrefix, token)
+}
+
+// SaveQuery saves a script execution and its results, on behalf of the caller.
+func (q *QueryHistoryServer) SaveQuery(ctx context.Context, req *cloudpb.SaveQueryRequest) (*cloudpb.SaveQueryResponse, error) {
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]*scriptmgrpb.SavedQueryArg, len(req.Args))
+	for i, arg := range req.Args {
+		args[i] = &scriptmgrpb.SavedQueryArg{Name: arg.Name, Value: arg.Value}
+	}
+	resp, err := q.QueryHistory.SaveQuery(ctx, &scriptmgrpb.SaveQueryReq{
+		OrgID:      orgID,
+		UserID:     userID,
+		Name:       req.Name,
+		ClusterID:  req.ClusterID,
+		ScriptName: req.ScriptName,
+		Pxl:        req.Pxl,
+		Args:       args,
+		ExecutedAt: req.ExecutedAt,
+		Result:     req.Result,
+	})
+	recordAudit(ctx, q.AuditLog, auditlog.ActionSavedQueryCreated, auditResourceID(resp.GetID()), err)
+	if err != nil {
+		return nil, err
+	}
+	return &cloudpb.SaveQueryResponse{ID: resp.ID}, nil
+}
+
+// ListSavedQueries lists the saved queries of the caller's org.
+func (q *QueryHistoryServer) ListSavedQueries(ctx context.Context, req *cloudpb.ListSavedQueriesRequest) (*cloudpb.ListSavedQueriesResponse, error) {
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var userID *uuidpb.UUID
+	if req.Mine {
+		userID, err = userIDFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := q.QueryHistory.ListSavedQueries(ctx, &scriptmgrpb.ListSavedQueriesReq{
+		OrgID:  orgID,
+		UserID: userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	queries := make([]*cloudpb.SavedQueryMetadata, len(resp.Queries))
+	for i, md := range resp.Queries {
+		queries[i] = savedQueryMetadataToCloudAPI(md)
+	}
+	return &cloudpb.ListSavedQueriesResponse{Queries: queries}, nil
+}
+
+// GetSavedQuery gets a saved query of the caller's org and its results.
+func (q *QueryHistoryServer) GetSavedQuery(ctx context.Context, req *uuidpb.UUID) (*cloudpb.SavedQuery, error) {
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := q.QueryHistory.GetSavedQuery(ctx, &scriptmgrpb.GetSavedQueryReq{
+		OrgID: orgID,
+		ID:    req,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return savedQueryToCloudAPI(resp), nil
+}
+
+// DeleteSavedQuery deletes a query that the caller saved.
+func (q *QueryHistoryServer) DeleteSavedQuery(ctx context.Context, req *uuidpb.UUID) (*types.Empty, error) {
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = q.QueryHistory.DeleteSavedQuery(ctx, &scriptmgrpb.DeleteSavedQueryReq{
+		OrgID:  orgID,
+		ID:     req,
+		UserID: userID,
+	})
+	recordAudit(ctx, q.AuditLog, auditlog.ActionSavedQueryDeleted, auditResourceID(req), err)
+	if err != nil {
+		return nil, err
+	}
+	return &types.Empty{}, nil
+}
+
+// ShareSavedQuery creates or revokes the sharing link of a query that the caller saved.
+func (q *QueryHistoryServer) ShareSavedQuery(ctx context.Context, req *cloudpb.ShareSavedQueryRequest) (*cloudpb.ShareSavedQueryResponse, error) {
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := q.QueryHistory.ShareSavedQuery(ctx, &scriptmgrpb.ShareSavedQueryReq{
+		OrgID:  orgID,
+		ID:     req.ID,
+		UserID: userID,
+		Revoke: req.Revoke,
+	})
+	action := auditlog.ActionSavedQueryShared
+	if req.Revoke {
+		action = auditlog.ActionSavedQueryUnshared
+	}
+	recordAudit(ctx, q.AuditLog, action, auditResourceID(req.ID), err)
+	if err != nil {
+		return nil, err
+	}
+	if resp.ShareToken == "" {
+		return &cloudpb.ShareSavedQueryResponse{}, nil
+	}
+	return &cloudpb.ShareSavedQueryResponse{
+		ShareToken: resp.ShareToken,
+		ShareURL:   sharedQueryURL(resp.ShareToken),
+	}, nil
+}
+
+// GetSharedQuery gets a query that was shared with a link. Shared queries can be viewed from any org.
+func (q *QueryHistoryServer) GetSharedQuery(ctx context.Context, req *cloudpb.GetSharedQueryRequest) (*cloudpb.SavedQuery, error) {
+	if req.ShareToken == "" {
+		return nil, status.Error(codes.InvalidArgument, "share token is required")
+	}
+	ctx, err := contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := q.QueryHistory.GetSharedQuery(ctx, &scriptmgrpb.GetSharedQueryReq{ShareToken: req.ShareToken})
+	if err != nil {
+		return nil, err
+	}
+	return savedQueryToCloudAPI(resp), nil
+}
+
+type sharedQueryArg struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type sharedQueryResponse struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	ClusterID  string            `json:"clusterID,omitempty"`
+	ScriptName string            `json:"scriptName,omitempty"`
+	ExecutedAt string            `json:"executedAt,omitempty"`
+	Pxl        string            `json:"pxl"`
+	Args       []*sharedQueryArg `json:"args"`
+	Result     json.RawMessage   `json:"result"`
+}
+
+// ServeHTTP serves a shared query as JSON, so that its sharing link can be opened by any logged in
+// user. The result is embedded as is, in the format of the script execution endpoint.
+func (q *QueryHistoryServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sCtx, err := authcontext.FromContext(r.Context())
+	if err != nil || sCtx.Claims == nil {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return
+	}
+	token := strings.TrimPrefix(r.URL.Path, SharedQueryPathPrefix)
+	if token == "" || strings.Contains(token, "/") {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	sq, err := q.GetSharedQuery(r.Context(), &cloudpb.GetSharedQueryRequest{ShareToken: token})
+	if status.Code(err) == codes.NotFound {
+		http.Error(w, "shared query not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to get shared query")
+		http.Error(w, "failed to get shared query", http.StatusInternalServerError)
+		return
+	}
+
+	resp := &sharedQueryResponse{
+		ID:         utils.UUIDFromProtoOrNil(sq.Metadata.ID).String(),
+		Name:       sq.Metadata.Name,
+		ScriptName: sq.Metadata.ScriptName,
+		Pxl:        sq.Pxl,
+		Args:       make([]*sharedQueryArg, len(sq.Args)),
+		Result:     json.RawMessage(sq.Result),
+	}
+	if sq.Metadata.ClusterID != nil {
+		resp.ClusterID = utils.UUIDFromProtoOrNil(sq.Metadata.ClusterID).String()
+	}
+	if t, err := types.TimestampFromProto(sq.Metadata.ExecutedAt); err == nil {
+		resp.ExecutedAt = t.UTC().Format(time.RFC3339)
+	}
+	for i, arg := range sq.Args {
+		resp.Args[i] = &sharedQueryArg{Name: arg.Name, Value: arg.Value}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to write shared query")
+	}
+}
@@ -0,0 +1,229 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/cloud/api/controllers"
+	"px.dev/pixie/src/cloud/api/controllers/testutils"
+	"px.dev/pixie/src/cloud/scriptmgr/scriptmgrpb"
+	"px.dev/pixie/src/cloud/shared/auditlog"
+	"px.dev/pixie/src/utils"
+)
+
+var testSavedQueryID = utils.ProtoFromUUIDStrOrNil("7ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+func TestQueryHistoryServer_SaveQuery(t *testing.T) {
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	mockClients.MockQueryHistory.EXPECT().
+		SaveQuery(gomock.Any(), &scriptmgrpb.SaveQueryReq{
+			OrgID:  testRegistryOrgID,
+			UserID: testRegistryUserID,
+			Name:   "http errors",
+			Pxl:    "px.display(1)",
+			Args:   []*scriptmgrpb.SavedQueryArg{{Name: "start_time", Value: "-5m"}},
+			Result: `{"tables":[]}`,
+		}).
+		Return(&scriptmgrpb.SaveQueryResp{ID: testSavedQueryID}, nil)
+
+	al := &fakeAuditLog{}
+	s := &controllers.QueryHistoryServer{QueryHistory: mockClients.MockQueryHistory, AuditLog: al}
+	resp, err := s.SaveQuery(ctx, &cloudpb.SaveQueryRequest{
+		Name:   "http errors",
+		Pxl:    "px.display(1)",
+		Args:   []*cloudpb.SavedQueryArg{{Name: "start_time", Value: "-5m"}},
+		Result: `{"tables":[]}`,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, testSavedQueryID, resp.ID)
+
+	require.Len(t, al.events, 1)
+	assert.Equal(t, auditlog.ActionSavedQueryCreated, al.events[0].Action)
+	assert.Equal(t, "7ba7b810-9dad-11d1-80b4-00c04fd430c8", al.events[0].ResourceID)
+}
+
+func TestQueryHistoryServer_ListSavedQueries(t *testing.T) {
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	mockClients.MockQueryHistory.EXPECT().
+		ListSavedQueries(gomock.Any(), &scriptmgrpb.ListSavedQueriesReq{
+			OrgID:  testRegistryOrgID,
+			UserID: testRegistryUserID,
+		}).
+		Return(&scriptmgrpb.ListSavedQueriesResp{
+			Queries: []*scriptmgrpb.SavedQueryMetadata{
+				{ID: testSavedQueryID, Name: "http errors", UserID: testRegistryUserID, ResultSizeBytes: 13, Shared: true},
+			},
+		}, nil)
+
+	s := &controllers.QueryHistoryServer{QueryHistory: mockClients.MockQueryHistory}
+	resp, err := s.ListSavedQueries(ctx, &cloudpb.ListSavedQueriesRequest{Mine: true})
+	require.NoError(t, err)
+	require.Len(t, resp.Queries, 1)
+	assert.Equal(t, "http errors", resp.Queries[0].Name)
+	assert.Equal(t, int64(13), resp.Queries[0].ResultSizeBytes)
+	assert.True(t, resp.Queries[0].Shared)
+}
+
+func TestQueryHistoryServer_ShareSavedQuery(t *testing.T) {
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	mockClients.MockQueryHistory.EXPECT().
+		ShareSavedQuery(gomock.Any(), &scriptmgrpb.ShareSavedQueryReq{
+			OrgID:  testRegistryOrgID,
+			ID:     testSavedQueryID,
+			UserID: testRegistryUserID,
+		}).
+		Return(&scriptmgrpb.ShareSavedQueryResp{ShareToken: "abc"}, nil)
+	mockClients.MockQueryHistory.EXPECT().
+		ShareSavedQuery(gomock.Any(), &scriptmgrpb.ShareSavedQueryReq{
+			OrgID:  testRegistryOrgID,
+			ID:     testSavedQueryID,
+			UserID: testRegistryUserID,
+			Revoke: true,
+		}).
+		Return(nil, status.Error(codes.PermissionDenied, "only the user who saved the query can change it"))
+
+	al := &fakeAuditLog{}
+	s := &controllers.QueryHistoryServer{QueryHistory: mockClients.MockQueryHistory, AuditLog: al}
+	resp, err := s.ShareSavedQuery(ctx, &cloudpb.ShareSavedQueryRequest{ID: testSavedQueryID})
+	require.NoError(t, err)
+	assert.Equal(t, "abc", resp.ShareToken)
+	assert.Equal(t, "https://work.withpixie.ai/api/v1/shared-queries/abc", resp.ShareURL)
+
+	_, err = s.ShareSavedQuery(ctx, &cloudpb.ShareSavedQueryRequest{ID: testSavedQueryID, Revoke: true})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	require.Len(t, al.events, 2)
+	assert.Equal(t, auditlog.ActionSavedQueryShared, al.events[0].Action)
+	assert.True(t, al.events[0].Succeeded)
+	assert.Equal(t, auditlog.ActionSavedQueryUnshared, al.events[1].Action)
+	assert.False(t, al.events[1].Succeeded)
+}
+
+func TestQueryHistoryServer_ServeHTTP(t *testing.T) {
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		path         string
+		found        bool
+		revoked      bool
+		expectedCode int
+	}{
+		{
+			name:         "shared",
+			ctx:          CreateTestContext(),
+			path:         controllers.SharedQueryPathPrefix + "abc",
+			found:        true,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "other org",
+			ctx:          CreateTestContextNoOrg(),
+			path:         controllers.SharedQueryPathPrefix + "abc",
+			found:        true,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "revoked",
+			ctx:          CreateTestContext(),
+			path:         controllers.SharedQueryPathPrefix + "abc",
+			revoked:      true,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "no token",
+			ctx:          CreateTestContext(),
+			path:         controllers.SharedQueryPathPrefix,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "unauthenticated",
+			ctx:          context.Background(),
+			path:         controllers.SharedQueryPathPrefix + "abc",
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+			defer cleanup()
+
+			if test.found {
+				mockClients.MockQueryHistory.EXPECT().
+					GetSharedQuery(gomock.Any(), &scriptmgrpb.GetSharedQueryReq{ShareToken: "abc"}).
+					Return(&scriptmgrpb.GetSavedQueryResp{
+						Metadata: &scriptmgrpb.SavedQueryMetadata{ID: testSavedQueryID, Name: "http errors"},
+						Pxl:      "px.display(1)",
+						Args:     []*scriptmgrpb.SavedQueryArg{{Name: "start_time", Value: "-5m"}},
+						Result:   `{"tables":[{"name":"output"}]}`,
+					}, nil)
+			}
+			if test.revoked {
+				mockClients.MockQueryHistory.EXPECT().
+					GetSharedQuery(gomock.Any(), &scriptmgrpb.GetSharedQueryReq{ShareToken: "abc"}).
+					Return(nil, status.Error(codes.NotFound, "shared query not found"))
+			}
+
+			s := &controllers.QueryHistoryServer{QueryHistory: mockClients.MockQueryHistory}
+			req := httptest.NewRequest(http.MethodGet, test.path, nil).WithContext(test.ctx)
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, req)
+			require.Equal(t, test.expectedCode, w.Code)
+			if test.expectedCode != http.StatusOK {
+				return
+			}
+
+			resp := struct {
+				ID     string `json:"id"`
+				Pxl    string `json:"pxl"`
+				Result struct {
+					Tables []struct {
+						Name string `json:"name"`
+					} `json:"tables"`
+				} `json:"result"`
+			}{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, "7ba7b810-9dad-11d1-80b4-00c04fd430c8", resp.ID)
+			assert.Equal(t, "px.display(1)", resp.Pxl)
+			require.Len(t, resp.Result.Tables, 1)
+			assert.Equal(t, "output", resp.Result.Tables[0].Name)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+
+	"px.dev/pixie/src/shared/services/rbac"
+)
+
+// CloudAPIPolicy is the role that each cloud API method requires. Methods that aren't listed only
+// require the caller to be a viewer. It is enforced on gRPC calls by the API server and on the
+// matching GraphQL resolvers.
+var CloudAPIPolicy = &rbac.Policy{
+	DefaultRole: rbac.RoleViewer,
+	Methods: map[string]rbac.Role{
+		// Joining or creating an org happens before the user has a role in it.
+		"/px.cloudapi.OrganizationService/CreateOrg":         rbac.RoleNone,
+		"/px.cloudapi.OrganizationService/VerifyInviteToken": rbac.RoleNone,
+		// These methods don't require authentication at all.
+		"/px.cloudapi.ArtifactTracker/GetArtifactList":    rbac.RoleNone,
+		"/px.cloudapi.ArtifactTracker/GetDownloadLink":    rbac.RoleNone,
+		"/pl.cloudapi.ArtifactTracker/GetArtifactList":    rbac.RoleNone,
+		"/pl.cloudapi.ArtifactTracker/GetDownloadLink":    rbac.RoleNone,
+		"/px.cloudapi.ConfigService/GetConfigForVizier":   rbac.RoleNone,
+		"/px.cloudapi.ConfigService/GetConfigForOperator": rbac.RoleNone,
+		"/px.cloudapi.AuthService/Login":                  rbac.RoleNone,
+
+		"/px.cloudapi.VizierClusterInfo/CreateCluster":             rbac.RoleEditor,
+		"/px.cloudapi.VizierClusterInfo/UpdateClusterVizierConfig": rbac.RoleEditor,
+		"/px.cloudapi.VizierClusterInfo/UpdateOrInstallCluster":    rbac.RoleEditor,
+		"/px.cloudapi.PluginService/CreateRetentionScript":         rbac.RoleEditor,
+		"/px.cloudapi.PluginService/UpdateRetentionScript":         rbac.RoleEditor,
+		"/px.cloudapi.PluginService/DeleteRetentionScript":         rbac.RoleEditor,
+		"/px.cloudapi.PluginService/CreateAlertRoute":              rbac.RoleEditor,
+		"/px.cloudapi.PluginService/UpdateAlertRoute":              rbac.RoleEditor,
+		"/px.cloudapi.PluginService/DeleteAlertRoute":              rbac.RoleEditor,
+		"/px.cloudapi.FleetManager/CreateFleet":                    rbac.RoleEditor,
+		"/px.cloudapi.FleetManager/DeleteFleet":                    rbac.RoleEditor,
+		"/px.cloudapi.FleetManager/UpdateClusterLabels":            rbac.RoleEditor,
+		"/px.cloudapi.FleetManager/RolloutFleetRetentionScript":    rbac.RoleEditor,
+		"/px.cloudapi.FleetManager/UpdateOrInstallFleet":           rbac.RoleEditor,
+		"/px.cloudapi.ScriptRegistry/CreateRegistryScriptVersion":  rbac.RoleEditor,
+		"/px.cloudapi.ScriptRegistry/SubmitRegistryScriptVersion":  rbac.RoleEditor,
+		"/px.cloudapi.ScriptRegistry/DeleteRegistryScript":         rbac.RoleEditor,
+		"/px.cloudapi.QueryHistory/SaveQuery":                      rbac.RoleEditor,
+		"/px.cloudapi.QueryHistory/DeleteSavedQuery":               rbac.RoleEditor,
+		"/px.cloudapi.QueryHistory/ShareSavedQuery":                rbac.RoleEditor,
+		"/px.api.vizierpb.VizierDebugService/DebugLog":             rbac.RoleEditor,
+		"/px.api.vizierpb.VizierDebugService/DebugPods":            rbac.RoleEditor,
+		"/px.api.vizierpb.VizierDebugService/SelfTest":             rbac.RoleEditor,
+
+		"/px.cloudapi.APIKeyManager/Create":                       rbac.RoleAdmin,
+		"/px.cloudapi.APIKeyManager/Delete":                       rbac.RoleAdmin,
+		"/px.cloudapi.VizierDeploymentKeyManager/Create":          rbac.RoleAdmin,
+		"/px.cloudapi.VizierDeploymentKeyManager/Delete":          rbac.RoleAdmin,
+		"/px.cloudapi.OrganizationService/UpdateOrg":              rbac.RoleAdmin,
+		"/px.cloudapi.ArtifactTracker/UpdateOrgBranding":          rbac.RoleAdmin,
+		"/px.cloudapi.OrganizationService/InviteUser":             rbac.RoleAdmin,
+		"/px.cloudapi.OrganizationService/RemoveUserFromOrg":      rbac.RoleAdmin,
+		"/px.cloudapi.OrganizationService/CreateInviteToken":      rbac.RoleAdmin,
+		"/px.cloudapi.OrganizationService/RevokeAllInviteTokens":  rbac.RoleAdmin,
+		"/px.cloudapi.OrganizationService/AddOrgIDEConfig":        rbac.RoleAdmin,
+		"/px.cloudapi.OrganizationService/DeleteOrgIDEConfig":     rbac.RoleAdmin,
+		"/px.cloudapi.PluginService/UpdateRetentionPluginConfig":  rbac.RoleAdmin,
+		"/px.cloudapi.ScriptRegistry/ReviewRegistryScriptVersion": rbac.RoleAdmin,
+		"/px.cloudapi.UsageService/SetQuota":                      rbac.RoleAdmin,
+		"/px.cloudapi.UsageService/DeleteQuota":                   rbac.RoleAdmin,
+	},
+}
+
+// authorizeGQL checks the policy for a GraphQL resolver, which calls the gRPC servers directly
+// and so isn't covered by the gRPC interceptors.
+func authorizeGQL(ctx context.Context, method string, req interface{}) error {
+	if err := rbac.Authorize(ctx, CloudAPIPolicy.RequiredRole(method), req); err != nil {
+		return rpcErrorHelper(err)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/cloud/api/controllers"
+	"px.dev/pixie/src/cloud/api/controllers/testutils"
+	"px.dev/pixie/src/shared/services/authcontext"
+	"px.dev/pixie/src/shared/services/rbac"
+	svcutils "px.dev/pixie/src/shared/services/utils"
+	"px.dev/pixie/src/utils"
+)
+
+func createTestContextWithRole(role rbac.Role) context.Context {
+	sCtx := authcontext.New()
+	sCtx.Claims = svcutils.GenerateJWTForUser("6ba7b810-9dad-11d1-80b4-00c04fd430c9", "6ba7b810-9dad-11d1-80b4-00c04fd430c8", "test@test.com", time.Now(), "pixie")
+	sCtx.Claims.Scopes = append(sCtx.Claims.Scopes, role.Scope())
+	return authcontext.NewContext(context.Background(), sCtx)
+}
+
+func TestCloudAPIPolicy_GQL(t *testing.T) {
+	tests := []struct {
+		name    string
+		role    rbac.Role
+		allowed bool
+	}{
+		{
+			name:    "admin",
+			role:    rbac.RoleAdmin,
+			allowed: true,
+		},
+		{
+			name:    "editor",
+			role:    rbac.RoleEditor,
+			allowed: false,
+		},
+		{
+			name:    "viewer",
+			role:    rbac.RoleViewer,
+			allowed: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gqlEnv, mockClients, cleanup := testutils.CreateTestGraphQLEnv(t)
+			defer cleanup()
+
+			if test.allowed {
+				mockClients.MockAPIKey.EXPECT().
+					Create(gomock.Any(), &cloudpb.CreateAPIKeyRequest{}).
+					Return(&cloudpb.APIKey{
+						ID:        utils.ProtoFromUUIDStrOrNil("7ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+						CreatedAt: types.TimestampNow(),
+					}, nil)
+			}
+
+			gqlSchema := LoadSchema(gqlEnv)
+			resp := gqlSchema.Exec(createTestContextWithRole(test.role), `mutation { CreateAPIKey { id } }`, "", nil)
+			if test.allowed {
+				require.Empty(t, resp.Errors)
+				return
+			}
+			require.Len(t, resp.Errors, 1)
+			assert.Equal(t, codes.PermissionDenied, resp.Errors[0].Extensions["code"])
+		})
+	}
+}
+
+func TestCloudAPIPolicy_RequiredRole(t *testing.T) {
+	assert.Equal(t, rbac.RoleAdmin, controllers.CloudAPIPolicy.RequiredRole("/px.cloudapi.APIKeyManager/Create"))
+	assert.Equal(t, rbac.RoleEditor, controllers.CloudAPIPolicy.RequiredRole("/px.cloudapi.PluginService/UpdateRetentionScript"))
+	assert.Equal(t, rbac.RoleViewer, controllers.CloudAPIPolicy.RequiredRole("/px.api.vizierpb.VizierService/ExecuteScript"))
+	assert.Equal(t, rbac.RoleNone, controllers.CloudAPIPolicy.RequiredRole("/px.cloudapi.OrganizationService/CreateOrg"))
+	assert.Equal(t, rbac.RoleNone, controllers.CloudAPIPolicy.RequiredRole("/px.cloudapi.AuthService/Login"))
+}
@@ -0,0 +1,359 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/cloud/scriptmgr/scriptmgrpb"
+	"px.dev/pixie/src/cloud/shared/auditlog"
+	"px.dev/pixie/src/shared/services/authcontext"
+	"px.dev/pixie/src/utils"
+)
+
+// ScriptRegistryBundlePath is the path that the published scripts of the caller's org are served on,
+// in the same format as the public script bundles.
+const ScriptRegistryBundlePath = "/api/script-registry/bundle.json"
+
+// ScriptRegistryServer is the server that implements the ScriptRegistry gRPC service.
+type ScriptRegistryServer struct {
+	Registry scriptmgrpb.ScriptRegistryServiceClient
+	AuditLog auditlog.Recorder
+}
+
+func registryScriptToCloudAPI(s *scriptmgrpb.RegistryScript) *cloudpb.RegistryScript {
+	return &cloudpb.RegistryScript{
+		ID:               s.ID,
+		Name:             s.Name,
+		LatestVersion:    s.LatestVersion,
+		PublishedVersion: s.PublishedVersion,
+		CreatedAt:        s.CreatedAt,
+	}
+}
+
+func registryVersionMetadataToCloudAPI(md *scriptmgrpb.RegistryScriptVersionMetadata) *cloudpb.RegistryScriptVersionMetadata {
+	return &cloudpb.RegistryScriptVersionMetadata{
+		ScriptID:      md.ScriptID,
+		Version:       md.Version,
+		Status:        cloudpb.RegistryScriptVersionStatus(md.Status),
+		ShortDoc:      md.ShortDoc,
+		AuthorID:      md.AuthorID,
+		ReviewerID:    md.ReviewerID,
+		ReviewComment: md.ReviewComment,
+		CreatedAt:     md.CreatedAt,
+		PublishedAt:   md.PublishedAt,
+	}
+}
+
+func userIDFromContext(ctx context.Context) (*uuidpb.UUID, error) {
+	sCtx, err := authcontext.FromContext(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	userID := utils.ProtoFromUUIDStrOrNil(sCtx.Claims.GetUserClaims().UserID)
+	if userID == nil {
+		return nil, status.Error(codes.Internal, "error parsing user ID as UUID")
+	}
+	return userID, nil
+}
+
+// ListRegistryScripts lists the scripts in the caller's org.
+func (s *ScriptRegistryServer) ListRegistryScripts(ctx context.Context, req *cloudpb.ListRegistryScriptsRequest) (*cloudpb.ListRegistryScriptsResponse, error) {
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.Registry.ListRegistryScripts(ctx, &scriptmgrpb.ListRegistryScriptsReq{OrgID: orgID})
+	if err != nil {
+		return nil, err
+	}
+	scripts := make([]*cloudpb.RegistryScript, len(resp.Scripts))
+	for i, script := range resp.Scripts {
+		scripts[i] = registryScriptToCloudAPI(script)
+	}
+	return &cloudpb.ListRegistryScriptsResponse{Scripts: scripts}, nil
+}
+
+// GetRegistryScript gets a script in the caller's org by ID or name.
+func (s *ScriptRegistryServer) GetRegistryScript(ctx context.Context, req *cloudpb.GetRegistryScriptRequest) (*cloudpb.GetRegistryScriptResponse, error) {
+	if (req.ID == nil) == (req.Name == "") {
+		return nil, status.Error(codes.InvalidArgument, "exactly one of script ID or name must be specified")
+	}
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.Registry.GetRegistryScript(ctx, &scriptmgrpb.GetRegistryScriptReq{
+		OrgID:    orgID,
+		ScriptID: req.ID,
+		Name:     req.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]*cloudpb.RegistryScriptVersionMetadata, len(resp.Versions))
+	for i, v := range resp.Versions {
+		versions[i] = registryVersionMetadataToCloudAPI(v)
+	}
+	return &cloudpb.GetRegistryScriptResponse{
+		Script:   registryScriptToCloudAPI(resp.Script),
+		Versions: versions,
+	}, nil
+}
+
+// GetRegistryScriptVersion gets the contents of a version of a script in the caller's org.
+func (s *ScriptRegistryServer) GetRegistryScriptVersion(ctx context.Context, req *cloudpb.GetRegistryScriptVersionRequest) (*cloudpb.RegistryScriptVersion, error) {
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.Registry.GetRegistryScriptVersion(ctx, &scriptmgrpb.GetRegistryScriptVersionReq{
+		OrgID:    orgID,
+		ScriptID: req.ScriptID,
+		Version:  req.Version,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &cloudpb.RegistryScriptVersion{
+		Metadata: registryVersionMetadataToCloudAPI(resp.Metadata),
+		LongDoc:  resp.LongDoc,
+		Pxl:      resp.Pxl,
+		Vis:      resp.Vis,
+	}, nil
+}
+
+// CreateRegistryScriptVersion creates a new draft version of a script, authored by the caller.
+func (s *ScriptRegistryServer) CreateRegistryScriptVersion(ctx context.Context, req *cloudpb.CreateRegistryScriptVersionRequest) (*cloudpb.CreateRegistryScriptVersionResponse, error) {
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.Registry.CreateRegistryScriptVersion(ctx, &scriptmgrpb.CreateRegistryScriptVersionReq{
+		OrgID:    orgID,
+		AuthorID: userID,
+		Name:     req.Name,
+		ShortDoc: req.ShortDoc,
+		LongDoc:  req.LongDoc,
+		Pxl:      req.Pxl,
+		Vis:      req.Vis,
+	})
+	recordAudit(ctx, s.AuditLog, auditlog.ActionRegistryScriptVersionCreated, auditResourceID(resp.GetScriptID()), err)
+	if err != nil {
+		return nil, err
+	}
+	return &cloudpb.CreateRegistryScriptVersionResponse{
+		ScriptID: resp.ScriptID,
+		Version:  resp.Version,
+	}, nil
+}
+
+// SubmitRegistryScriptVersion submits a version of a script for review.
+func (s *ScriptRegistryServer) SubmitRegistryScriptVersion(ctx context.Context, req *cloudpb.SubmitRegistryScriptVersionRequest) (*types.Empty, error) {
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.Registry.SubmitRegistryScriptVersion(ctx, &scriptmgrpb.SubmitRegistryScriptVersionReq{
+		OrgID:    orgID,
+		ScriptID: req.ScriptID,
+		Version:  req.Version,
+	})
+	recordAudit(ctx, s.AuditLog, auditlog.ActionRegistryScriptVersionSubmitted, auditResourceID(req.ScriptID), err)
+	if err != nil {
+		return nil, err
+	}
+	return &types.Empty{}, nil
+}
+
+// ReviewRegistryScriptVersion approves or rejects a version of a script, with the caller as the reviewer.
+func (s *ScriptRegistryServer) ReviewRegistryScriptVersion(ctx context.Context, req *cloudpb.ReviewRegistryScriptVersionRequest) (*types.Empty, error) {
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.Registry.ReviewRegistryScriptVersion(ctx, &scriptmgrpb.ReviewRegistryScriptVersionReq{
+		OrgID:      orgID,
+		ScriptID:   req.ScriptID,
+		Version:    req.Version,
+		ReviewerID: userID,
+		Approve:    req.Approve,
+		Comment:    req.Comment,
+	})
+	recordAudit(ctx, s.AuditLog, auditlog.ActionRegistryScriptVersionReviewed, auditResourceID(req.ScriptID), err)
+	if err != nil {
+		return nil, err
+	}
+	return &types.Empty{}, nil
+}
+
+// DeleteRegistryScript deletes a script in the caller's org.
+func (s *ScriptRegistryServer) DeleteRegistryScript(ctx context.Context, req *uuidpb.UUID) (*types.Empty, error) {
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.Registry.DeleteRegistryScript(ctx, &scriptmgrpb.DeleteRegistryScriptReq{
+		OrgID:    orgID,
+		ScriptID: req,
+	})
+	recordAudit(ctx, s.AuditLog, auditlog.ActionRegistryScriptDeleted, auditResourceID(req), err)
+	if err != nil {
+		return nil, err
+	}
+	return &types.Empty{}, nil
+}
+
+// GetRegistryBundle gets the latest published version of every script in the caller's org.
+func (s *ScriptRegistryServer) GetRegistryBundle(ctx context.Context, req *cloudpb.GetRegistryBundleRequest) (*cloudpb.GetRegistryBundleResponse, error) {
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.Registry.GetRegistryBundle(ctx, &scriptmgrpb.GetRegistryBundleReq{OrgID: orgID})
+	if err != nil {
+		return nil, err
+	}
+	scripts := make([]*cloudpb.RegistryBundleScript, len(resp.Scripts))
+	for i, script := range resp.Scripts {
+		scripts[i] = &cloudpb.RegistryBundleScript{
+			Name:     script.Name,
+			Version:  script.Version,
+			ShortDoc: script.ShortDoc,
+			LongDoc:  script.LongDoc,
+			Pxl:      script.Pxl,
+			Vis:      script.Vis,
+		}
+	}
+	return &cloudpb.GetRegistryBundleResponse{Scripts: scripts}, nil
+}
+
+type bundleScript struct {
+	Pxl      string `json:"pxl"`
+	Vis      string `json:"vis"`
+	ShortDoc string `json:"ShortDoc"`
+	LongDoc  string `json:"LongDoc"`
+	OrgID    string `json:"orgID"`
+}
+
+type bundleResponse struct {
+	Scripts map[string]*bundleScript `json:"scripts"`
+}
+
+// ServeHTTP serves the published scripts of the caller's org as a script bundle, so that the Live
+// UI can load them alongside the public bundles. Scripts are keyed by "org_id/<org ID>/<name>",
+// which the bundle readers display as "<org name>/<name>".
+func (s *ScriptRegistryServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sCtx, err := authcontext.FromContext(r.Context())
+	if err != nil || sCtx.Claims == nil {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return
+	}
+	orgID := sCtx.Claims.GetUserClaims().OrgID
+	if uuid.FromStringOrNil(orgID) == uuid.Nil {
+		http.Error(w, "user does not belong to an org", http.StatusForbidden)
+		return
+	}
+
+	resp, err := s.GetRegistryBundle(r.Context(), &cloudpb.GetRegistryBundleRequest{})
+	if err != nil {
+		log.WithError(err).Error("Failed to get script registry bundle")
+		http.Error(w, "failed to get script registry bundle", http.StatusInternalServerError)
+		return
+	}
+
+	b := &bundleResponse{Scripts: make(map[string]*bundleScript, len(resp.Scripts))}
+	for _, script := range resp.Scripts {
+		b.Scripts[fmt.Sprintf("org_id/%s/%s", orgID, script.Name)] = &bundleScript{
+			Pxl:      script.Pxl,
+			Vis:      script.Vis,
+			ShortDoc: script.ShortDoc,
+			LongDoc:  script.LongDoc,
+			OrgID:    orgID,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(b); err != nil {
+		log.WithError(err).Error("Failed to write script registry bundle")
+	}
+}
@@ -0,0 +1,203 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/cloud/api/controllers"
+	"px.dev/pixie/src/cloud/api/controllers/testutils"
+	"px.dev/pixie/src/cloud/scriptmgr/scriptmgrpb"
+	"px.dev/pixie/src/cloud/shared/auditlog"
+	"px.dev/pixie/src/utils"
+)
+
+var (
+	testRegistryOrgID    = utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	testRegistryUserID   = utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c9")
+	testRegistryScriptID = utils.ProtoFromUUIDStrOrNil("9ba7b810-9dad-11d1-80b4-00c04fd430c8")
+)
+
+func TestScriptRegistryServer_CreateRegistryScriptVersion(t *testing.T) {
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	mockClients.MockScriptRegistry.EXPECT().
+		CreateRegistryScriptVersion(gomock.Any(), &scriptmgrpb.CreateRegistryScriptVersionReq{
+			OrgID:    testRegistryOrgID,
+			AuthorID: testRegistryUserID,
+			Name:     "http/errors",
+			ShortDoc: "HTTP errors",
+			Pxl:      "px.display(1)",
+		}).
+		Return(&scriptmgrpb.CreateRegistryScriptVersionResp{ScriptID: testRegistryScriptID, Version: 2}, nil)
+
+	al := &fakeAuditLog{}
+	s := &controllers.ScriptRegistryServer{Registry: mockClients.MockScriptRegistry, AuditLog: al}
+	resp, err := s.CreateRegistryScriptVersion(ctx, &cloudpb.CreateRegistryScriptVersionRequest{
+		Name:     "http/errors",
+		ShortDoc: "HTTP errors",
+		Pxl:      "px.display(1)",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, testRegistryScriptID, resp.ScriptID)
+	assert.Equal(t, int64(2), resp.Version)
+
+	require.Len(t, al.events, 1)
+	assert.Equal(t, auditlog.ActionRegistryScriptVersionCreated, al.events[0].Action)
+	assert.Equal(t, "9ba7b810-9dad-11d1-80b4-00c04fd430c8", al.events[0].ResourceID)
+}
+
+func TestScriptRegistryServer_ReviewRegistryScriptVersion(t *testing.T) {
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	mockClients.MockScriptRegistry.EXPECT().
+		ReviewRegistryScriptVersion(gomock.Any(), &scriptmgrpb.ReviewRegistryScriptVersionReq{
+			OrgID:      testRegistryOrgID,
+			ScriptID:   testRegistryScriptID,
+			Version:    2,
+			ReviewerID: testRegistryUserID,
+			Approve:    true,
+			Comment:    "lgtm",
+		}).
+		Return(nil, status.Error(codes.PermissionDenied, "versions can't be reviewed by their author"))
+
+	al := &fakeAuditLog{}
+	s := &controllers.ScriptRegistryServer{Registry: mockClients.MockScriptRegistry, AuditLog: al}
+	_, err := s.ReviewRegistryScriptVersion(ctx, &cloudpb.ReviewRegistryScriptVersionRequest{
+		ScriptID: testRegistryScriptID,
+		Version:  2,
+		Approve:  true,
+		Comment:  "lgtm",
+	})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	require.Len(t, al.events, 1)
+	assert.Equal(t, auditlog.ActionRegistryScriptVersionReviewed, al.events[0].Action)
+	assert.False(t, al.events[0].Succeeded)
+}
+
+func TestScriptRegistryServer_GetRegistryScript(t *testing.T) {
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	mockClients.MockScriptRegistry.EXPECT().
+		GetRegistryScript(gomock.Any(), &scriptmgrpb.GetRegistryScriptReq{
+			OrgID: testRegistryOrgID,
+			Name:  "http/errors",
+		}).
+		Return(&scriptmgrpb.GetRegistryScriptResp{
+			Script: &scriptmgrpb.RegistryScript{ID: testRegistryScriptID, Name: "http/errors", LatestVersion: 2, PublishedVersion: 1},
+			Versions: []*scriptmgrpb.RegistryScriptVersionMetadata{
+				{ScriptID: testRegistryScriptID, Version: 2, Status: scriptmgrpb.RSV_IN_REVIEW},
+				{ScriptID: testRegistryScriptID, Version: 1, Status: scriptmgrpb.RSV_PUBLISHED},
+			},
+		}, nil)
+
+	s := &controllers.ScriptRegistryServer{Registry: mockClients.MockScriptRegistry}
+	resp, err := s.GetRegistryScript(ctx, &cloudpb.GetRegistryScriptRequest{Name: "http/errors"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), resp.Script.PublishedVersion)
+	require.Len(t, resp.Versions, 2)
+	assert.Equal(t, cloudpb.RSV_IN_REVIEW, resp.Versions[0].Status)
+	assert.Equal(t, cloudpb.RSV_PUBLISHED, resp.Versions[1].Status)
+
+	_, err = s.GetRegistryScript(ctx, &cloudpb.GetRegistryScriptRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestScriptRegistryServer_ServeHTTP(t *testing.T) {
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		expectedCode int
+	}{
+		{
+			name:         "user",
+			ctx:          CreateTestContext(),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "no org",
+			ctx:          CreateTestContextNoOrg(),
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "unauthenticated",
+			ctx:          context.Background(),
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+			defer cleanup()
+
+			if test.expectedCode == http.StatusOK {
+				mockClients.MockScriptRegistry.EXPECT().
+					GetRegistryBundle(gomock.Any(), &scriptmgrpb.GetRegistryBundleReq{OrgID: testRegistryOrgID}).
+					Return(&scriptmgrpb.GetRegistryBundleResp{
+						Scripts: []*scriptmgrpb.RegistryBundleScript{
+							{Name: "http/errors", Version: 1, ShortDoc: "HTTP errors", Pxl: "px.display(1)"},
+						},
+					}, nil)
+			}
+
+			s := &controllers.ScriptRegistryServer{Registry: mockClients.MockScriptRegistry}
+			req := httptest.NewRequest(http.MethodGet, controllers.ScriptRegistryBundlePath, nil).WithContext(test.ctx)
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, req)
+			require.Equal(t, test.expectedCode, w.Code)
+			if test.expectedCode != http.StatusOK {
+				return
+			}
+
+			resp := struct {
+				Scripts map[string]struct {
+					Pxl      string `json:"pxl"`
+					ShortDoc string `json:"ShortDoc"`
+					OrgID    string `json:"orgID"`
+				} `json:"scripts"`
+			}{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Len(t, resp.Scripts, 1)
+			script, ok := resp.Scripts["org_id/6ba7b810-9dad-11d1-80b4-00c04fd430c8/http/errors"]
+			require.True(t, ok)
+			assert.Equal(t, "px.display(1)", script.Pxl)
+			assert.Equal(t, "HTTP errors", script.ShortDoc)
+			assert.Equal(t, "6ba7b810-9dad-11d1-80b4-00c04fd430c8", script.OrgID)
+		})
+	}
+}
@@ -0,0 +1,809 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/cloud/api/apienv"
+	"px.dev/pixie/src/cloud/profile/profilepb"
+	"px.dev/pixie/src/shared/services/authcontext"
+	"px.dev/pixie/src/shared/services/httpmiddleware"
+	"px.dev/pixie/src/shared/services/rbac"
+	"px.dev/pixie/src/utils"
+)
+
+const (
+	scimUserSchema         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimGroupSchema        = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	scimListResponseSchema = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimPatchOpSchema      = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	scimErrorSchema        = "urn:ietf:params:scim:api:messages:2.0:Error"
+	scimSPConfigSchema     = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+
+	scimContentType = "application/scim+json"
+	// SCIMPathPrefix is the path that the SCIM API is served on.
+	SCIMPathPrefix = "/scim/v2"
+)
+
+// scimFilterRegex matches the equality filters that IdPs use to look up users before provisioning them.
+var scimFilterRegex = regexp.MustCompile(`^(userName|externalId|emails\.value)\s+eq\s+"([^"]*)"$`)
+
+// scimGroupFilterRegex matches the filter that IdPs use to look up a group before pushing it.
+var scimGroupFilterRegex = regexp.MustCompile(`^displayName\s+eq\s+"([^"]*)"$`)
+
+// scimMemberPathRegex matches the path that IdPs use to remove a single member from a group.
+var scimMemberPathRegex = regexp.MustCompile(`^members\[value\s+eq\s+"([^"]*)"\]$`)
+
+// scimGroupRoles are the roles that are provisioned as SCIM groups, in the order they are listed.
+var scimGroupRoles = []rbac.Role{rbac.RoleAdmin, rbac.RoleEditor, rbac.RoleViewer}
+
+// SCIMName is the name of a SCIM user.
+type SCIMName struct {
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// SCIMEmail is an email of a SCIM user.
+type SCIMEmail struct {
+	Value   string `json:"value"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// SCIMUser is the SCIM 2.0 representation of a Pixie user.
+type SCIMUser struct {
+	Schemas    []string    `json:"schemas"`
+	ID         string      `json:"id,omitempty"`
+	ExternalID string      `json:"externalId,omitempty"`
+	UserName   string      `json:"userName"`
+	Name       *SCIMName   `json:"name,omitempty"`
+	Emails     []SCIMEmail `json:"emails,omitempty"`
+	Active     *bool       `json:"active,omitempty"`
+}
+
+// SCIMGroupMember is a member of a SCIM group.
+type SCIMGroupMember struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+
+// SCIMGroup is the SCIM 2.0 representation of a Pixie role. Its members are the users that have the role.
+type SCIMGroup struct {
+	Schemas     []string          `json:"schemas"`
+	ID          string            `json:"id,omitempty"`
+	DisplayName string            `json:"displayName"`
+	Members     []SCIMGroupMember `json:"members"`
+}
+
+type scimListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+type scimPatchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value"`
+}
+
+type scimPatchRequest struct {
+	Schemas    []string      `json:"schemas"`
+	Operations []scimPatchOp `json:"Operations"`
+}
+
+type scimError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+func (e *scimError) Error() string {
+	return e.Detail
+}
+
+func newSCIMError(code int, scimType, format string, args ...interface{}) *scimError {
+	return &scimError{
+		Schemas:  []string{scimErrorSchema},
+		Status:   strconv.Itoa(code),
+		ScimType: scimType,
+		Detail:   fmt.Sprintf(format, args...),
+	}
+}
+
+// SCIMServer serves the SCIM 2.0 Users and Groups APIs, so that IdPs such as Okta and Azure AD can provision and
+// deprovision the members of an org. Requests are scoped to the org of the API key they authenticate with,
+// and require the admin role.
+//
+// The user's externalId must be the ID that the auth provider assigns to the user, so that the user is
+// recognized on their first login.
+//
+// Groups are the Pixie roles: there is one group per role, whose ID and displayName are the role's name, and
+// whose members are the users that have the role. Pushing an IdP group named after a role assigns the role to
+// the group's members, which restricts them to it from their next login.
+type SCIMServer struct {
+	ProfileServiceClient profilepb.ProfileServiceClient
+	OrgServiceClient     profilepb.OrgServiceClient
+	// IdentityProvider is recorded as the identity provider of provisioned users.
+	IdentityProvider string
+}
+
+// WithSCIMAuthMiddleware authenticates SCIM requests. IdPs send the org's API key as a bearer token,
+// rather than in the pixie-api-key header.
+func WithSCIMAuthMiddleware(env apienv.APIEnv, next http.Handler) http.Handler {
+	authed := WithAugmentedAuthMiddleware(env, next)
+	f := func(w http.ResponseWriter, r *http.Request) {
+		apiKey, ok := httpmiddleware.GetTokenFromBearer(r)
+		if !ok {
+			writeSCIMError(w, newSCIMError(http.StatusUnauthorized, "", "missing bearer API key"))
+			return
+		}
+		r = r.Clone(r.Context())
+		r.Header.Del("Authorization")
+		r.Header.Set("pixie-api-key", apiKey)
+		authed.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(f)
+}
+
+// ServeHTTP implements http.Handler.
+func (s *SCIMServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	orgID, err := scimOrgID(r.Context())
+	if err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, SCIMPathPrefix), "/")
+	parts := strings.Split(path, "/")
+
+	var resp interface{}
+	code := http.StatusOK
+	switch {
+	case path == "ServiceProviderConfig" && r.Method == http.MethodGet:
+		resp = scimServiceProviderConfig()
+	case path == "Groups" && r.Method == http.MethodGet:
+		resp, err = s.listGroups(r, orgID)
+	case path == "Groups" && r.Method == http.MethodPost:
+		resp, err = s.createGroup(r, orgID)
+		code = http.StatusCreated
+	case len(parts) == 2 && parts[0] == "Groups":
+		resp, err = s.handleGroup(r, orgID, parts[1])
+		if r.Method == http.MethodDelete && err == nil {
+			code = http.StatusNoContent
+		}
+	case path == "Users" && r.Method == http.MethodGet:
+		resp, err = s.listUsers(r, orgID)
+	case path == "Users" && r.Method == http.MethodPost:
+		resp, err = s.createUser(r, orgID)
+		code = http.StatusCreated
+	case len(parts) == 2 && parts[0] == "Users":
+		resp, err = s.handleUser(r, orgID, parts[1])
+		if r.Method == http.MethodDelete && err == nil {
+			code = http.StatusNoContent
+		}
+	default:
+		err = newSCIMError(http.StatusNotFound, "", "unknown SCIM endpoint %s %s", r.Method, r.URL.Path)
+	}
+
+	if err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", scimContentType)
+	w.WriteHeader(code)
+	if resp != nil {
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+func (s *SCIMServer) handleUser(r *http.Request, orgID uuid.UUID, userIDStr string) (*SCIMUser, error) {
+	userID, err := uuid.FromString(userIDStr)
+	if err != nil {
+		return nil, newSCIMError(http.StatusNotFound, "", "user %s not found", userIDStr)
+	}
+	user, err := s.getOrgUser(r.Context(), orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		return userToSCIM(user), nil
+	case http.MethodPut:
+		req := &SCIMUser{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			return nil, newSCIMError(http.StatusBadRequest, "invalidSyntax", "invalid user: %v", err)
+		}
+		// Only the active state can be changed, the rest of the profile comes from the auth provider.
+		if req.Active == nil {
+			return userToSCIM(user), nil
+		}
+		return s.setActive(r.Context(), user, *req.Active)
+	case http.MethodPatch:
+		req := &scimPatchRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			return nil, newSCIMError(http.StatusBadRequest, "invalidSyntax", "invalid patch: %v", err)
+		}
+		active, err := activeFromPatch(req)
+		if err != nil {
+			return nil, err
+		}
+		if active == nil {
+			return userToSCIM(user), nil
+		}
+		return s.setActive(r.Context(), user, *active)
+	case http.MethodDelete:
+		// Deleting the user from the IdP removes them from the org, as in RemoveUserFromOrg.
+		_, err := s.ProfileServiceClient.UpdateUser(r.Context(), &profilepb.UpdateUserRequest{
+			ID:    user.ID,
+			OrgID: &uuidpb.UUID{},
+		})
+		if err != nil {
+			return nil, grpcToSCIMError(err)
+		}
+		return nil, nil
+	default:
+		return nil, newSCIMError(http.StatusMethodNotAllowed, "", "method %s not allowed", r.Method)
+	}
+}
+
+func (s *SCIMServer) listUsers(r *http.Request, orgID uuid.UUID) (*scimListResponse, error) {
+	resp, err := s.OrgServiceClient.GetUsersInOrg(r.Context(), &profilepb.GetUsersInOrgRequest{
+		OrgID: utils.ProtoFromUUID(orgID),
+	})
+	if err != nil {
+		return nil, grpcToSCIMError(err)
+	}
+
+	var match func(*profilepb.UserInfo) bool
+	if filter := strings.TrimSpace(r.URL.Query().Get("filter")); filter != "" {
+		m := scimFilterRegex.FindStringSubmatch(filter)
+		if m == nil {
+			return nil, newSCIMError(http.StatusBadRequest, "invalidFilter", "unsupported filter %s", filter)
+		}
+		attr, val := m[1], m[2]
+		match = func(u *profilepb.UserInfo) bool {
+			if attr == "externalId" {
+				return u.AuthProviderID == val
+			}
+			return strings.EqualFold(u.Email, val)
+		}
+	}
+
+	users := make([]*SCIMUser, 0)
+	for _, u := range resp.Users {
+		if match == nil || match(u) {
+			users = append(users, userToSCIM(u))
+		}
+	}
+
+	startIndex, start, end := scimPage(r, len(users))
+	return &scimListResponse{
+		Schemas:      []string{scimListResponseSchema},
+		TotalResults: len(users),
+		StartIndex:   startIndex,
+		ItemsPerPage: end - start,
+		Resources:    users[start:end],
+	}, nil
+}
+
+// scimPage returns the requested startIndex, and the bounds of the requested page in a list of total resources.
+func scimPage(r *http.Request, total int) (int, int, int) {
+	// SCIM pagination is 1-indexed.
+	startIndex := 1
+	if v, err := strconv.Atoi(r.URL.Query().Get("startIndex")); err == nil && v > 1 {
+		startIndex = v
+	}
+	count := total
+	if v, err := strconv.Atoi(r.URL.Query().Get("count")); err == nil && v >= 0 {
+		count = v
+	}
+	start := startIndex - 1
+	if start > total {
+		start = total
+	}
+	end := start + count
+	if end > total {
+		end = total
+	}
+	return startIndex, start, end
+}
+
+func (s *SCIMServer) createUser(r *http.Request, orgID uuid.UUID) (*SCIMUser, error) {
+	ctx := r.Context()
+	req := &SCIMUser{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return nil, newSCIMError(http.StatusBadRequest, "invalidSyntax", "invalid user: %v", err)
+	}
+	email := req.primaryEmail()
+	if email == "" {
+		return nil, newSCIMError(http.StatusBadRequest, "invalidValue", "user must have an email")
+	}
+	if req.ExternalID == "" {
+		return nil, newSCIMError(http.StatusBadRequest, "invalidValue", "user must have an externalId")
+	}
+
+	if _, err := s.ProfileServiceClient.GetUserByEmail(ctx, &profilepb.GetUserByEmailRequest{Email: email}); err == nil {
+		return nil, newSCIMError(http.StatusConflict, "uniqueness", "user %s already exists", email)
+	}
+
+	createReq := &profilepb.CreateUserRequest{
+		OrgID:            utils.ProtoFromUUID(orgID),
+		Email:            email,
+		IdentityProvider: s.IdentityProvider,
+		AuthProviderID:   req.ExternalID,
+	}
+	if req.Name != nil {
+		createReq.FirstName = req.Name.GivenName
+		createReq.LastName = req.Name.FamilyName
+	}
+	userID, err := s.ProfileServiceClient.CreateUser(ctx, createReq)
+	if err != nil {
+		return nil, grpcToSCIMError(err)
+	}
+
+	// Users provisioned by the IdP don't need approval, unless they're provisioned as inactive.
+	active := req.Active == nil || *req.Active
+	user, err := s.ProfileServiceClient.UpdateUser(ctx, &profilepb.UpdateUserRequest{
+		ID:         userID,
+		IsApproved: &types.BoolValue{Value: active},
+	})
+	if err != nil {
+		return nil, grpcToSCIMError(err)
+	}
+	return userToSCIM(user), nil
+}
+
+func (s *SCIMServer) setActive(ctx context.Context, user *profilepb.UserInfo, active bool) (*SCIMUser, error) {
+	// Deactivated users can't log in, since login requires the user to be approved.
+	updated, err := s.ProfileServiceClient.UpdateUser(ctx, &profilepb.UpdateUserRequest{
+		ID:         user.ID,
+		IsApproved: &types.BoolValue{Value: active},
+	})
+	if err != nil {
+		return nil, grpcToSCIMError(err)
+	}
+	return userToSCIM(updated), nil
+}
+
+func (s *SCIMServer) getOrgUser(ctx context.Context, orgID, userID uuid.UUID) (*profilepb.UserInfo, error) {
+	user, err := s.ProfileServiceClient.GetUser(ctx, utils.ProtoFromUUID(userID))
+	if err != nil {
+		return nil, grpcToSCIMError(err)
+	}
+	// Users in other orgs are reported as missing, to not leak their existence.
+	if utils.UUIDFromProtoOrNil(user.OrgID) != orgID {
+		return nil, newSCIMError(http.StatusNotFound, "", "user %s not found", userID.String())
+	}
+	return user, nil
+}
+
+func (s *SCIMServer) listGroups(r *http.Request, orgID uuid.UUID) (*scimListResponse, error) {
+	roles := scimGroupRoles
+	if filter := strings.TrimSpace(r.URL.Query().Get("filter")); filter != "" {
+		m := scimGroupFilterRegex.FindStringSubmatch(filter)
+		if m == nil {
+			return nil, newSCIMError(http.StatusBadRequest, "invalidFilter", "unsupported filter %s", filter)
+		}
+		roles = nil
+		if role, err := rbac.ParseRole(m[1]); err == nil {
+			roles = []rbac.Role{role}
+		}
+	}
+
+	users, err := s.orgUsers(r.Context(), orgID)
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]*SCIMGroup, len(roles))
+	for i, role := range roles {
+		groups[i] = groupToSCIM(role, users)
+	}
+
+	startIndex, start, end := scimPage(r, len(groups))
+	return &scimListResponse{
+		Schemas:      []string{scimListResponseSchema},
+		TotalResults: len(groups),
+		StartIndex:   startIndex,
+		ItemsPerPage: end - start,
+		Resources:    groups[start:end],
+	}, nil
+}
+
+func (s *SCIMServer) createGroup(r *http.Request, orgID uuid.UUID) (*SCIMGroup, error) {
+	req := &SCIMGroup{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return nil, newSCIMError(http.StatusBadRequest, "invalidSyntax", "invalid group: %v", err)
+	}
+	role, err := rbac.ParseRole(req.DisplayName)
+	if err != nil {
+		return nil, newSCIMError(http.StatusBadRequest, "invalidValue", "group names must be one of the Pixie roles: admin, editor or viewer")
+	}
+	// The role's group always exists, so pushing a group links to it, and adds the pushed members.
+	return s.updateGroupMembers(r.Context(), orgID, role, func(members map[string]bool) error {
+		for _, id := range memberIDs(req.Members) {
+			members[id] = true
+		}
+		return nil
+	})
+}
+
+func (s *SCIMServer) handleGroup(r *http.Request, orgID uuid.UUID, groupID string) (*SCIMGroup, error) {
+	role, err := rbac.ParseRole(groupID)
+	if err != nil {
+		return nil, newSCIMError(http.StatusNotFound, "", "group %s not found", groupID)
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		users, err := s.orgUsers(r.Context(), orgID)
+		if err != nil {
+			return nil, err
+		}
+		return groupToSCIM(role, users), nil
+	case http.MethodPut:
+		req := &SCIMGroup{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			return nil, newSCIMError(http.StatusBadRequest, "invalidSyntax", "invalid group: %v", err)
+		}
+		return s.updateGroupMembers(r.Context(), orgID, role, func(members map[string]bool) error {
+			replaceMembers(members, memberIDs(req.Members))
+			return nil
+		})
+	case http.MethodPatch:
+		req := &scimPatchRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			return nil, newSCIMError(http.StatusBadRequest, "invalidSyntax", "invalid patch: %v", err)
+		}
+		return s.updateGroupMembers(r.Context(), orgID, role, func(members map[string]bool) error {
+			return applyMembersPatch(req, members)
+		})
+	case http.MethodDelete:
+		// Deleting the IdP group takes the role away from all of its members.
+		_, err := s.updateGroupMembers(r.Context(), orgID, role, func(members map[string]bool) error {
+			replaceMembers(members, nil)
+			return nil
+		})
+		return nil, err
+	default:
+		return nil, newSCIMError(http.StatusMethodNotAllowed, "", "method %s not allowed", r.Method)
+	}
+}
+
+// updateGroupMembers calls update with the IDs of the users that have the role, and then gives the role to the
+// users that were added, and takes it away from the users that were removed.
+func (s *SCIMServer) updateGroupMembers(ctx context.Context, orgID uuid.UUID, role rbac.Role, update func(map[string]bool) error) (*SCIMGroup, error) {
+	users, err := s.orgUsers(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	members := make(map[string]bool)
+	for _, u := range users {
+		if hasRole(u, role) {
+			members[utils.UUIDFromProtoOrNil(u.ID).String()] = true
+		}
+	}
+	if err := update(members); err != nil {
+		return nil, err
+	}
+
+	inOrg := make(map[string]bool, len(users))
+	for _, u := range users {
+		inOrg[utils.UUIDFromProtoOrNil(u.ID).String()] = true
+	}
+	for id := range members {
+		if !inOrg[id] {
+			return nil, newSCIMError(http.StatusBadRequest, "invalidValue", "user %s is not a member of the org", id)
+		}
+	}
+
+	for i, u := range users {
+		want := members[utils.UUIDFromProtoOrNil(u.ID).String()]
+		if hasRole(u, role) == want {
+			continue
+		}
+		roles := make([]string, 0, len(u.Roles)+1)
+		for _, r := range u.Roles {
+			if r != string(role) {
+				roles = append(roles, r)
+			}
+		}
+		if want {
+			roles = append(roles, string(role))
+		}
+		// Users that are removed from their last group are left without a role, and so lose access
+		// to the org until they're added to a group again.
+		updated, err := s.ProfileServiceClient.UpdateUser(ctx, &profilepb.UpdateUserRequest{
+			ID:    u.ID,
+			Roles: &profilepb.UserRoles{Roles: roles},
+		})
+		if err != nil {
+			return nil, grpcToSCIMError(err)
+		}
+		users[i] = updated
+	}
+	return groupToSCIM(role, users), nil
+}
+
+func (s *SCIMServer) orgUsers(ctx context.Context, orgID uuid.UUID) ([]*profilepb.UserInfo, error) {
+	resp, err := s.OrgServiceClient.GetUsersInOrg(ctx, &profilepb.GetUsersInOrgRequest{
+		OrgID: utils.ProtoFromUUID(orgID),
+	})
+	if err != nil {
+		return nil, grpcToSCIMError(err)
+	}
+	return resp.Users, nil
+}
+
+// applyMembersPatch applies the member changes of the patch to the IDs of the group's members.
+func applyMembersPatch(req *scimPatchRequest, members map[string]bool) error {
+	for _, op := range req.Operations {
+		if op.Path == "" {
+			// Azure AD sends the replaced attributes as an object without a path.
+			attrs := make(map[string]json.RawMessage)
+			if err := json.Unmarshal(op.Value, &attrs); err != nil {
+				return newSCIMError(http.StatusBadRequest, "invalidValue", "invalid patch value")
+			}
+			raw, ok := attrs["members"]
+			if !ok {
+				// The displayName is the role's name, so the other attributes can't be changed.
+				continue
+			}
+			op.Path, op.Value = "members", raw
+		}
+		if op.Path == "displayName" {
+			continue
+		}
+
+		// Okta and Azure AD remove a single member with a filter on the path.
+		if m := scimMemberPathRegex.FindStringSubmatch(op.Path); m != nil && strings.EqualFold(op.Op, "remove") {
+			delete(members, m[1])
+			continue
+		}
+		if op.Path != "members" {
+			return newSCIMError(http.StatusBadRequest, "invalidPath", "unsupported patch path %s", op.Path)
+		}
+		var values []SCIMGroupMember
+		if len(op.Value) > 0 {
+			if err := json.Unmarshal(op.Value, &values); err != nil {
+				return newSCIMError(http.StatusBadRequest, "invalidValue", "invalid members")
+			}
+		}
+
+		switch strings.ToLower(op.Op) {
+		case "add":
+			for _, id := range memberIDs(values) {
+				members[id] = true
+			}
+		case "remove":
+			// A remove without values removes all of the members.
+			if len(values) == 0 {
+				replaceMembers(members, nil)
+			}
+			for _, id := range memberIDs(values) {
+				delete(members, id)
+			}
+		case "replace":
+			replaceMembers(members, memberIDs(values))
+		default:
+			return newSCIMError(http.StatusBadRequest, "invalidValue", "unsupported patch op %s", op.Op)
+		}
+	}
+	return nil
+}
+
+func replaceMembers(members map[string]bool, ids []string) {
+	for id := range members {
+		delete(members, id)
+	}
+	for _, id := range ids {
+		members[id] = true
+	}
+}
+
+func memberIDs(members []SCIMGroupMember) []string {
+	ids := make([]string, len(members))
+	for i, m := range members {
+		ids[i] = m.Value
+	}
+	return ids
+}
+
+func hasRole(u *profilepb.UserInfo, role rbac.Role) bool {
+	for _, r := range u.Roles {
+		if r == string(role) {
+			return true
+		}
+	}
+	return false
+}
+
+func groupToSCIM(role rbac.Role, users []*profilepb.UserInfo) *SCIMGroup {
+	members := make([]SCIMGroupMember, 0)
+	for _, u := range users {
+		if hasRole(u, role) {
+			members = append(members, SCIMGroupMember{Value: utils.UUIDFromProtoOrNil(u.ID).String(), Display: u.Email})
+		}
+	}
+	return &SCIMGroup{
+		Schemas:     []string{scimGroupSchema},
+		ID:          string(role),
+		DisplayName: string(role),
+		Members:     members,
+	}
+}
+
+// activeFromPatch returns the active state set by the patch, or nil if the patch doesn't change it.
+func activeFromPatch(req *scimPatchRequest) (*bool, error) {
+	var active *bool
+	for _, op := range req.Operations {
+		if !strings.EqualFold(op.Op, "replace") && !strings.EqualFold(op.Op, "add") {
+			return nil, newSCIMError(http.StatusBadRequest, "invalidValue", "unsupported patch op %s", op.Op)
+		}
+		switch op.Path {
+		case "active":
+			v, err := parseSCIMBool(op.Value)
+			if err != nil {
+				return nil, err
+			}
+			active = &v
+		case "":
+			// Azure AD and Okta send the replaced attributes as an object without a path.
+			attrs := make(map[string]json.RawMessage)
+			if err := json.Unmarshal(op.Value, &attrs); err != nil {
+				return nil, newSCIMError(http.StatusBadRequest, "invalidValue", "invalid patch value")
+			}
+			if raw, ok := attrs["active"]; ok {
+				v, err := parseSCIMBool(raw)
+				if err != nil {
+					return nil, err
+				}
+				active = &v
+			}
+		default:
+			// Other attributes are owned by the auth provider, so ignore them.
+		}
+	}
+	return active, nil
+}
+
+// parseSCIMBool parses a boolean, some IdPs send booleans as strings.
+func parseSCIMBool(raw json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(raw, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		if v, err := strconv.ParseBool(s); err == nil {
+			return v, nil
+		}
+	}
+	return false, newSCIMError(http.StatusBadRequest, "invalidValue", "invalid boolean %s", string(raw))
+}
+
+func (u *SCIMUser) primaryEmail() string {
+	for _, e := range u.Emails {
+		if e.Primary {
+			return e.Value
+		}
+	}
+	if len(u.Emails) > 0 {
+		return u.Emails[0].Value
+	}
+	if strings.Contains(u.UserName, "@") {
+		return u.UserName
+	}
+	return ""
+}
+
+func userToSCIM(u *profilepb.UserInfo) *SCIMUser {
+	active := u.IsApproved && !utils.IsNilUUIDProto(u.OrgID)
+	return &SCIMUser{
+		Schemas:    []string{scimUserSchema},
+		ID:         utils.UUIDFromProtoOrNil(u.ID).String(),
+		ExternalID: u.AuthProviderID,
+		UserName:   u.Email,
+		Name: &SCIMName{
+			GivenName:  u.FirstName,
+			FamilyName: u.LastName,
+		},
+		Emails: []SCIMEmail{{Value: u.Email, Primary: true}},
+		Active: &active,
+	}
+}
+
+func scimServiceProviderConfig() map[string]interface{} {
+	unsupported := map[string]bool{"supported": false}
+	return map[string]interface{}{
+		"schemas":        []string{scimSPConfigSchema},
+		"patch":          map[string]bool{"supported": true},
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": 1000},
+		"changePassword": unsupported,
+		"sort":           unsupported,
+		"etag":           unsupported,
+		"authenticationSchemes": []map[string]string{
+			{
+				"type":        "oauthbearertoken",
+				"name":        "API Key",
+				"description": "A Pixie API key for the org, sent as a bearer token",
+			},
+		},
+	}
+}
+
+func scimOrgID(ctx context.Context) (uuid.UUID, error) {
+	sCtx, err := authcontext.FromContext(ctx)
+	if err != nil {
+		return uuid.Nil, newSCIMError(http.StatusUnauthorized, "", "unauthenticated")
+	}
+	// Provisioning users is an org admin operation.
+	if err := rbac.Authorize(ctx, rbac.RoleAdmin, nil); err != nil {
+		return uuid.Nil, grpcToSCIMError(err)
+	}
+	orgID := uuid.FromStringOrNil(sCtx.Claims.GetUserClaims().OrgID)
+	if orgID == uuid.Nil {
+		return uuid.Nil, newSCIMError(http.StatusForbidden, "", "API key does not belong to an org")
+	}
+	return orgID, nil
+}
+
+func grpcToSCIMError(err error) error {
+	switch status.Code(err) {
+	case codes.NotFound:
+		return newSCIMError(http.StatusNotFound, "", "%s", status.Convert(err).Message())
+	case codes.InvalidArgument:
+		return newSCIMError(http.StatusBadRequest, "invalidValue", "%s", status.Convert(err).Message())
+	case codes.AlreadyExists:
+		return newSCIMError(http.StatusConflict, "uniqueness", "%s", status.Convert(err).Message())
+	case codes.PermissionDenied:
+		return newSCIMError(http.StatusForbidden, "", "%s", status.Convert(err).Message())
+	default:
+		return newSCIMError(http.StatusInternalServerError, "", "%s", status.Convert(err).Message())
+	}
+}
+
+func writeSCIMError(w http.ResponseWriter, err error) {
+	var se *scimError
+	if !errors.As(err, &se) {
+		se = newSCIMError(http.StatusInternalServerError, "", "%s", err.Error())
+	}
+	code, _ := strconv.Atoi(se.Status)
+	w.Header().Set("Content-Type", scimContentType)
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(se)
+}
@@ -0,0 +1,419 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/cloud/api/controllers"
+	"px.dev/pixie/src/cloud/api/controllers/testutils"
+	"px.dev/pixie/src/cloud/profile/profilepb"
+	"px.dev/pixie/src/shared/services/authcontext"
+	svcutils "px.dev/pixie/src/shared/services/utils"
+	"px.dev/pixie/src/utils"
+)
+
+const (
+	scimTestOrgID   = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	scimTestUserID  = "7ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	scimOtherOrgID  = "8ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	scimTestUserExt = "https://idp.example.com|u123"
+)
+
+func scimTestUser(orgID string, approved bool) *profilepb.UserInfo {
+	return &profilepb.UserInfo{
+		ID:             utils.ProtoFromUUIDStrOrNil(scimTestUserID),
+		OrgID:          utils.ProtoFromUUIDStrOrNil(orgID),
+		FirstName:      "first",
+		LastName:       "last",
+		Email:          "abc@example.com",
+		IsApproved:     approved,
+		AuthProviderID: scimTestUserExt,
+	}
+}
+
+func doSCIMRequest(t *testing.T, s *controllers.SCIMServer, method, path, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	return doSCIMRequestWithContext(t, s, CreateTestContext(), method, path, body)
+}
+
+func doSCIMRequestWithContext(t *testing.T, s *controllers.SCIMServer, ctx context.Context, method, path, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body)).WithContext(ctx)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+
+	resp := make(map[string]interface{})
+	if w.Body.Len() > 0 {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	}
+	return w, resp
+}
+
+func newTestSCIMServer(t *testing.T) (*controllers.SCIMServer, *testutils.MockAPIClients, func()) {
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	return &controllers.SCIMServer{
+		ProfileServiceClient: mockClients.MockProfile,
+		OrgServiceClient:     mockClients.MockOrg,
+		IdentityProvider:     "saml",
+	}, mockClients, cleanup
+}
+
+func TestSCIMServer_ListUsers(t *testing.T) {
+	s, mockClients, cleanup := newTestSCIMServer(t)
+	defer cleanup()
+
+	other := scimTestUser(scimTestOrgID, true)
+	other.ID = utils.ProtoFromUUID(uuid.Must(uuid.NewV4()))
+	other.Email = "other@example.com"
+
+	mockClients.MockOrg.EXPECT().GetUsersInOrg(gomock.Any(), &profilepb.GetUsersInOrgRequest{
+		OrgID: utils.ProtoFromUUIDStrOrNil(scimTestOrgID),
+	}).Return(&profilepb.GetUsersInOrgResponse{
+		Users: []*profilepb.UserInfo{scimTestUser(scimTestOrgID, true), other},
+	}, nil).Times(2)
+
+	w, resp := doSCIMRequest(t, s, http.MethodGet, "/scim/v2/Users", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, float64(2), resp["totalResults"])
+
+	w, resp = doSCIMRequest(t, s, http.MethodGet, `/scim/v2/Users?filter=userName+eq+"ABC@example.com"`, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, float64(1), resp["totalResults"])
+	resources := resp["Resources"].([]interface{})
+	require.Len(t, resources, 1)
+	user := resources[0].(map[string]interface{})
+	assert.Equal(t, scimTestUserID, user["id"])
+	assert.Equal(t, scimTestUserExt, user["externalId"])
+	assert.Equal(t, true, user["active"])
+}
+
+func TestSCIMServer_ListUsersBadFilter(t *testing.T) {
+	s, mockClients, cleanup := newTestSCIMServer(t)
+	defer cleanup()
+
+	mockClients.MockOrg.EXPECT().GetUsersInOrg(gomock.Any(), gomock.Any()).
+		Return(&profilepb.GetUsersInOrgResponse{}, nil)
+
+	w, resp := doSCIMRequest(t, s, http.MethodGet, `/scim/v2/Users?filter=title+co+"eng"`, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalidFilter", resp["scimType"])
+}
+
+func TestSCIMServer_CreateUser(t *testing.T) {
+	s, mockClients, cleanup := newTestSCIMServer(t)
+	defer cleanup()
+
+	mockClients.MockProfile.EXPECT().GetUserByEmail(gomock.Any(), &profilepb.GetUserByEmailRequest{Email: "abc@example.com"}).
+		Return(nil, status.Error(codes.NotFound, "no such user"))
+	mockClients.MockProfile.EXPECT().CreateUser(gomock.Any(), &profilepb.CreateUserRequest{
+		OrgID:            utils.ProtoFromUUIDStrOrNil(scimTestOrgID),
+		FirstName:        "first",
+		LastName:         "last",
+		Email:            "abc@example.com",
+		IdentityProvider: "saml",
+		AuthProviderID:   scimTestUserExt,
+	}).Return(utils.ProtoFromUUIDStrOrNil(scimTestUserID), nil)
+	mockClients.MockProfile.EXPECT().UpdateUser(gomock.Any(), &profilepb.UpdateUserRequest{
+		ID:         utils.ProtoFromUUIDStrOrNil(scimTestUserID),
+		IsApproved: &types.BoolValue{Value: true},
+	}).Return(scimTestUser(scimTestOrgID, true), nil)
+
+	body := `{"schemas":["urn:ietf:params:scim:schemas:core:2.0:User"],"userName":"abc@example.com",` +
+		`"externalId":"` + scimTestUserExt + `","name":{"givenName":"first","familyName":"last"},` +
+		`"emails":[{"value":"abc@example.com","primary":true}],"active":true}`
+	w, resp := doSCIMRequest(t, s, http.MethodPost, "/scim/v2/Users", body)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, scimTestUserID, resp["id"])
+}
+
+func TestSCIMServer_CreateUserConflict(t *testing.T) {
+	s, mockClients, cleanup := newTestSCIMServer(t)
+	defer cleanup()
+
+	mockClients.MockProfile.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).
+		Return(scimTestUser(scimTestOrgID, true), nil)
+
+	body := `{"userName":"abc@example.com","externalId":"` + scimTestUserExt + `"}`
+	w, resp := doSCIMRequest(t, s, http.MethodPost, "/scim/v2/Users", body)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "uniqueness", resp["scimType"])
+}
+
+func TestSCIMServer_PatchDeactivate(t *testing.T) {
+	patches := []struct {
+		name string
+		body string
+	}{
+		{
+			name: "okta style",
+			body: `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],` +
+				`"Operations":[{"op":"replace","value":{"active":false}}]}`,
+		},
+		{
+			name: "azure style",
+			body: `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],` +
+				`"Operations":[{"op":"Replace","path":"active","value":"False"}]}`,
+		},
+	}
+
+	for _, test := range patches {
+		t.Run(test.name, func(t *testing.T) {
+			s, mockClients, cleanup := newTestSCIMServer(t)
+			defer cleanup()
+
+			mockClients.MockProfile.EXPECT().GetUser(gomock.Any(), utils.ProtoFromUUIDStrOrNil(scimTestUserID)).
+				Return(scimTestUser(scimTestOrgID, true), nil)
+			mockClients.MockProfile.EXPECT().UpdateUser(gomock.Any(), &profilepb.UpdateUserRequest{
+				ID:         utils.ProtoFromUUIDStrOrNil(scimTestUserID),
+				IsApproved: &types.BoolValue{Value: false},
+			}).Return(scimTestUser(scimTestOrgID, false), nil)
+
+			w, resp := doSCIMRequest(t, s, http.MethodPatch, "/scim/v2/Users/"+scimTestUserID, test.body)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, false, resp["active"])
+		})
+	}
+}
+
+func TestSCIMServer_DeleteUser(t *testing.T) {
+	s, mockClients, cleanup := newTestSCIMServer(t)
+	defer cleanup()
+
+	mockClients.MockProfile.EXPECT().GetUser(gomock.Any(), utils.ProtoFromUUIDStrOrNil(scimTestUserID)).
+		Return(scimTestUser(scimTestOrgID, true), nil)
+	mockClients.MockProfile.EXPECT().UpdateUser(gomock.Any(), &profilepb.UpdateUserRequest{
+		ID:    utils.ProtoFromUUIDStrOrNil(scimTestUserID),
+		OrgID: &uuidpb.UUID{},
+	}).Return(scimTestUser("", true), nil)
+
+	w, _ := doSCIMRequest(t, s, http.MethodDelete, "/scim/v2/Users/"+scimTestUserID, "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestSCIMServer_UserInOtherOrg(t *testing.T) {
+	s, mockClients, cleanup := newTestSCIMServer(t)
+	defer cleanup()
+
+	mockClients.MockProfile.EXPECT().GetUser(gomock.Any(), utils.ProtoFromUUIDStrOrNil(scimTestUserID)).
+		Return(scimTestUser(scimOtherOrgID, true), nil)
+
+	w, _ := doSCIMRequest(t, s, http.MethodDelete, "/scim/v2/Users/"+scimTestUserID, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestSCIMServer_RequiresAdmin(t *testing.T) {
+	s, _, cleanup := newTestSCIMServer(t)
+	defer cleanup()
+
+	sCtx := authcontext.New()
+	sCtx.Claims = svcutils.GenerateJWTForAPIUser(scimTestUserID, scimTestOrgID, time.Now(), "pixie")
+	sCtx.Claims.Scopes = append(sCtx.Claims.Scopes, "role:editor")
+	ctx := authcontext.NewContext(context.Background(), sCtx)
+
+	w, _ := doSCIMRequestWithContext(t, s, ctx, http.MethodGet, "/scim/v2/Users", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w, _ = doSCIMRequestWithContext(t, s, ctx, http.MethodDelete, "/scim/v2/Users/"+scimTestUserID, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+const scimOtherUserID = "9ba7b810-9dad-11d1-80b4-00c04fd430c8"
+
+// scimGroupTestUsers returns a user with the roles, and another user with the viewer role.
+func scimGroupTestUsers(roles ...string) []*profilepb.UserInfo {
+	user := scimTestUser(scimTestOrgID, true)
+	user.Roles = roles
+	other := scimTestUser(scimTestOrgID, true)
+	other.ID = utils.ProtoFromUUIDStrOrNil(scimOtherUserID)
+	other.Email = "other@example.com"
+	other.Roles = []string{"viewer"}
+	return []*profilepb.UserInfo{user, other}
+}
+
+func scimGroupMemberIDs(group map[string]interface{}) []string {
+	var ids []string
+	for _, m := range group["members"].([]interface{}) {
+		ids = append(ids, m.(map[string]interface{})["value"].(string))
+	}
+	return ids
+}
+
+func TestSCIMServer_ListGroups(t *testing.T) {
+	s, mockClients, cleanup := newTestSCIMServer(t)
+	defer cleanup()
+
+	mockClients.MockOrg.EXPECT().GetUsersInOrg(gomock.Any(), &profilepb.GetUsersInOrgRequest{
+		OrgID: utils.ProtoFromUUIDStrOrNil(scimTestOrgID),
+	}).Return(&profilepb.GetUsersInOrgResponse{Users: scimGroupTestUsers("editor")}, nil).Times(3)
+
+	w, resp := doSCIMRequest(t, s, http.MethodGet, "/scim/v2/Groups", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, float64(3), resp["totalResults"])
+	var names []string
+	for _, g := range resp["Resources"].([]interface{}) {
+		names = append(names, g.(map[string]interface{})["displayName"].(string))
+	}
+	assert.Equal(t, []string{"admin", "editor", "viewer"}, names)
+
+	w, resp = doSCIMRequest(t, s, http.MethodGet, `/scim/v2/Groups?filter=displayName+eq+"Editor"`, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	resources := resp["Resources"].([]interface{})
+	require.Len(t, resources, 1)
+	group := resources[0].(map[string]interface{})
+	assert.Equal(t, "editor", group["id"])
+	assert.Equal(t, []string{scimTestUserID}, scimGroupMemberIDs(group))
+
+	// Groups that aren't roles don't exist.
+	w, resp = doSCIMRequest(t, s, http.MethodGet, `/scim/v2/Groups?filter=displayName+eq+"engineering"`, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, float64(0), resp["totalResults"])
+
+	w, _ = doSCIMRequest(t, s, http.MethodGet, "/scim/v2/Groups/engineering", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestSCIMServer_CreateGroup(t *testing.T) {
+	s, mockClients, cleanup := newTestSCIMServer(t)
+	defer cleanup()
+
+	mockClients.MockOrg.EXPECT().GetUsersInOrg(gomock.Any(), gomock.Any()).
+		Return(&profilepb.GetUsersInOrgResponse{Users: scimGroupTestUsers("viewer")}, nil)
+	// Only the pushed member that doesn't have the role yet is updated.
+	updated := scimGroupTestUsers("viewer", "editor")[0]
+	mockClients.MockProfile.EXPECT().UpdateUser(gomock.Any(), &profilepb.UpdateUserRequest{
+		ID:    utils.ProtoFromUUIDStrOrNil(scimTestUserID),
+		Roles: &profilepb.UserRoles{Roles: []string{"viewer", "editor"}},
+	}).Return(updated, nil)
+
+	body := `{"schemas":["urn:ietf:params:scim:schemas:core:2.0:Group"],"displayName":"editor",` +
+		`"members":[{"value":"` + scimTestUserID + `"}]}`
+	w, resp := doSCIMRequest(t, s, http.MethodPost, "/scim/v2/Groups", body)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "editor", resp["id"])
+	assert.Equal(t, []string{scimTestUserID}, scimGroupMemberIDs(resp))
+
+	w, resp = doSCIMRequest(t, s, http.MethodPost, "/scim/v2/Groups", `{"displayName":"engineering"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalidValue", resp["scimType"])
+}
+
+func TestSCIMServer_PatchGroupMembers(t *testing.T) {
+	patches := []struct {
+		name string
+		body string
+	}{
+		{
+			name: "okta style",
+			body: `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[` +
+				`{"op":"remove","path":"members[value eq \"` + scimOtherUserID + `\"]"},` +
+				`{"op":"add","path":"members","value":[{"value":"` + scimTestUserID + `"}]}]}`,
+		},
+		{
+			name: "azure style",
+			body: `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[` +
+				`{"op":"Remove","path":"members","value":[{"value":"` + scimOtherUserID + `"}]},` +
+				`{"op":"Add","path":"members","value":[{"value":"` + scimTestUserID + `"}]}]}`,
+		},
+		{
+			name: "replace",
+			body: `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[` +
+				`{"op":"replace","value":{"displayName":"viewer","members":[{"value":"` + scimTestUserID + `"}]}}]}`,
+		},
+	}
+
+	for _, test := range patches {
+		t.Run(test.name, func(t *testing.T) {
+			s, mockClients, cleanup := newTestSCIMServer(t)
+			defer cleanup()
+
+			mockClients.MockOrg.EXPECT().GetUsersInOrg(gomock.Any(), gomock.Any()).
+				Return(&profilepb.GetUsersInOrgResponse{Users: scimGroupTestUsers()}, nil)
+			updated := scimGroupTestUsers("viewer")
+			mockClients.MockProfile.EXPECT().UpdateUser(gomock.Any(), &profilepb.UpdateUserRequest{
+				ID:    utils.ProtoFromUUIDStrOrNil(scimTestUserID),
+				Roles: &profilepb.UserRoles{Roles: []string{"viewer"}},
+			}).Return(updated[0], nil)
+			updated[1].Roles = nil
+			mockClients.MockProfile.EXPECT().UpdateUser(gomock.Any(), &profilepb.UpdateUserRequest{
+				ID:    utils.ProtoFromUUIDStrOrNil(scimOtherUserID),
+				Roles: &profilepb.UserRoles{Roles: []string{}},
+			}).Return(updated[1], nil)
+
+			w, resp := doSCIMRequest(t, s, http.MethodPatch, "/scim/v2/Groups/viewer", test.body)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, []string{scimTestUserID}, scimGroupMemberIDs(resp))
+		})
+	}
+}
+
+func TestSCIMServer_PatchGroupUnknownMember(t *testing.T) {
+	s, mockClients, cleanup := newTestSCIMServer(t)
+	defer cleanup()
+
+	mockClients.MockOrg.EXPECT().GetUsersInOrg(gomock.Any(), gomock.Any()).
+		Return(&profilepb.GetUsersInOrgResponse{Users: scimGroupTestUsers()}, nil)
+
+	body := `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[` +
+		`{"op":"add","path":"members","value":[{"value":"` + scimOtherOrgID + `"}]}]}`
+	w, resp := doSCIMRequest(t, s, http.MethodPatch, "/scim/v2/Groups/admin", body)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalidValue", resp["scimType"])
+}
+
+func TestSCIMServer_DeleteGroup(t *testing.T) {
+	s, mockClients, cleanup := newTestSCIMServer(t)
+	defer cleanup()
+
+	mockClients.MockOrg.EXPECT().GetUsersInOrg(gomock.Any(), gomock.Any()).
+		Return(&profilepb.GetUsersInOrgResponse{Users: scimGroupTestUsers("editor", "viewer")}, nil)
+	updated := scimGroupTestUsers("editor")
+	mockClients.MockProfile.EXPECT().UpdateUser(gomock.Any(), &profilepb.UpdateUserRequest{
+		ID:    utils.ProtoFromUUIDStrOrNil(scimTestUserID),
+		Roles: &profilepb.UserRoles{Roles: []string{"editor"}},
+	}).Return(updated[0], nil)
+	mockClients.MockProfile.EXPECT().UpdateUser(gomock.Any(), &profilepb.UpdateUserRequest{
+		ID:    utils.ProtoFromUUIDStrOrNil(scimOtherUserID),
+		Roles: &profilepb.UserRoles{Roles: []string{}},
+	}).Return(updated[1], nil)
+
+	w, _ := doSCIMRequest(t, s, http.MethodDelete, "/scim/v2/Groups/viewer", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestSCIMServer_Config(t *testing.T) {
+	s, _, cleanup := newTestSCIMServer(t)
+	defer cleanup()
+
+	w, resp := doSCIMRequest(t, s, http.MethodGet, "/scim/v2/ServiceProviderConfig", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, map[string]interface{}{"supported": true}, resp["patch"])
+}
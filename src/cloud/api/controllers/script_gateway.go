@@ -0,0 +1,444 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofrs/uuid"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/shared/services"
+	"px.dev/pixie/src/shared/services/authcontext"
+	"px.dev/pixie/src/shared/services/rbac"
+)
+
+// ScriptExecutionPath is the path of the REST endpoint that executes PxL scripts, for clients that
+// can't use gRPC.
+const ScriptExecutionPath = "/api/v1/scripts/execute"
+
+const (
+	defaultScriptResultsLimit = 1000
+	maxScriptResultsLimit     = 10000
+	executeScriptMethod       = "/px.api.vizierpb.VizierService/ExecuteScript"
+)
+
+// ScriptExecutionGateway executes a PxL script, POSTed as JSON, on a cluster and returns the results
+// as JSON. With ?format=json, the default, the rows of each table are paginated with the offset and
+// limit query parameters. Each page executes the script again, so pages of live data may overlap.
+// With ?format=ndjson, every row is streamed as a line of JSON as the cluster produces it.
+type ScriptExecutionGateway struct {
+	Vizier vizierpb.VizierServiceServer
+}
+
+type executeScriptFunc struct {
+	FuncName          string            `json:"funcName"`
+	OutputTablePrefix string            `json:"outputTablePrefix,omitempty"`
+	Args              map[string]string `json:"args,omitempty"`
+}
+
+type executeScriptHTTPRequest struct {
+	ClusterID string               `json:"clusterID"`
+	PxL       string               `json:"pxl"`
+	QueryName string               `json:"queryName,omitempty"`
+	ExecFuncs []*executeScriptFunc `json:"execFuncs,omitempty"`
+}
+
+func (r *executeScriptHTTPRequest) toProto() *vizierpb.ExecuteScriptRequest {
+	req := &vizierpb.ExecuteScriptRequest{
+		QueryStr:  r.PxL,
+		ClusterID: r.ClusterID,
+		QueryName: r.QueryName,
+	}
+	for _, f := range r.ExecFuncs {
+		fn := &vizierpb.ExecuteScriptRequest_FuncToExecute{
+			FuncName:          f.FuncName,
+			OutputTablePrefix: f.OutputTablePrefix,
+		}
+		for name, value := range f.Args {
+			fn.ArgValues = append(fn.ArgValues, &vizierpb.ExecuteScriptRequest_FuncToExecute_ArgValue{Name: name, Value: value})
+		}
+		req.ExecFuncs = append(req.ExecFuncs, fn)
+	}
+	return req
+}
+
+type scriptColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type scriptTable struct {
+	Name    string                   `json:"name"`
+	Columns []*scriptColumn          `json:"columns"`
+	Rows    []map[string]interface{} `json:"rows"`
+	// TotalRows is the number of rows in the table, across all pages.
+	TotalRows int64 `json:"totalRows"`
+	// NextOffset is the offset of the next page, if there is one.
+	NextOffset *int64 `json:"nextOffset,omitempty"`
+}
+
+type scriptStats struct {
+	BytesProcessed   int64 `json:"bytesProcessed"`
+	RecordsProcessed int64 `json:"recordsProcessed"`
+}
+
+type executeScriptHTTPResponse struct {
+	QueryID string         `json:"queryID"`
+	Tables  []*scriptTable `json:"tables"`
+	Stats   *scriptStats   `json:"stats"`
+}
+
+// scriptResultsWriter receives the results of a script as they are streamed from the cluster.
+type scriptResultsWriter interface {
+	table(id string, t *scriptTable) error
+	row(tableID string, row map[string]interface{}) error
+	// batchDone is called after each batch of rows.
+	batchDone() error
+	done(queryID string, stats *scriptStats) error
+}
+
+// collectedResults buffers one page of rows per table, to be written as a single JSON document.
+type collectedResults struct {
+	w      http.ResponseWriter
+	offset int64
+	limit  int64
+
+	tables   []*scriptTable
+	tableIDs map[string]*scriptTable
+}
+
+func (c *collectedResults) table(id string, t *scriptTable) error {
+	t.Rows = []map[string]interface{}{}
+	c.tables = append(c.tables, t)
+	c.tableIDs[id] = t
+	return nil
+}
+
+func (c *collectedResults) row(tableID string, row map[string]interface{}) error {
+	t := c.tableIDs[tableID]
+	if t.TotalRows >= c.offset && t.TotalRows < c.offset+c.limit {
+		t.Rows = append(t.Rows, row)
+	}
+	t.TotalRows++
+	return nil
+}
+
+func (c *collectedResults) batchDone() error {
+	return nil
+}
+
+func (c *collectedResults) done(queryID string, stats *scriptStats) error {
+	for _, t := range c.tables {
+		if next := c.offset + c.limit; t.TotalRows > next {
+			t.NextOffset = &next
+		}
+	}
+	c.w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(c.w).Encode(&executeScriptHTTPResponse{
+		QueryID: queryID,
+		Tables:  c.tables,
+		Stats:   stats,
+	})
+}
+
+// streamMessage is a line of a streamed NDJSON response, or a message on the script stream WebSocket.
+type streamMessage struct {
+	Type      string                 `json:"type"`
+	Table     *scriptTable           `json:"table,omitempty"`
+	TableName string                 `json:"tableName,omitempty"`
+	Row       map[string]interface{} `json:"row,omitempty"`
+	QueryID   string                 `json:"queryID,omitempty"`
+	Stats     *scriptStats           `json:"stats,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+	// Cursor is only sent on the WebSocket, and resumes the stream after the rows received so far.
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// streamedResults writes each table and row as a line of JSON as soon as it arrives.
+type streamedResults struct {
+	w       http.ResponseWriter
+	enc     *json.Encoder
+	started bool
+	names   map[string]string
+}
+
+func (s *streamedResults) write(l *streamMessage) error {
+	if !s.started {
+		s.w.Header().Set("Content-Type", "application/x-ndjson")
+		s.started = true
+	}
+	return s.enc.Encode(l)
+}
+
+func (s *streamedResults) batchDone() error {
+	s.flush()
+	return nil
+}
+
+func (s *streamedResults) flush() {
+	if f, ok := s.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (s *streamedResults) table(id string, t *scriptTable) error {
+	s.names[id] = t.Name
+	return s.write(&streamMessage{Type: "table", Table: t})
+}
+
+func (s *streamedResults) row(tableID string, row map[string]interface{}) error {
+	return s.write(&streamMessage{Type: "row", TableName: s.names[tableID], Row: row})
+}
+
+func (s *streamedResults) done(queryID string, stats *scriptStats) error {
+	err := s.write(&streamMessage{Type: "done", QueryID: queryID, Stats: stats})
+	s.flush()
+	return err
+}
+
+// gatewayStream adapts the results writer into the server stream that the Vizier proxy sends to.
+type gatewayStream struct {
+	ctx context.Context
+	out scriptResultsWriter
+
+	queryID string
+	stats   scriptStats
+	// columns are the columns of each table, by table ID.
+	columns map[string][]*scriptColumn
+	// scriptErr is set if the script failed to compile or run.
+	scriptErr error
+}
+
+func (g *gatewayStream) Context() context.Context {
+	return g.ctx
+}
+
+func (g *gatewayStream) SetHeader(metadata.MD) error {
+	return nil
+}
+
+func (g *gatewayStream) SendHeader(metadata.MD) error {
+	return nil
+}
+
+func (g *gatewayStream) SetTrailer(metadata.MD) {}
+
+func (g *gatewayStream) RecvMsg(m interface{}) error {
+	return errors.New("not supported")
+}
+
+func (g *gatewayStream) SendMsg(m interface{}) error {
+	resp, ok := m.(*vizierpb.ExecuteScriptResponse)
+	if !ok {
+		return fmt.Errorf("unexpected message type %T", m)
+	}
+	return g.Send(resp)
+}
+
+func (g *gatewayStream) Send(resp *vizierpb.ExecuteScriptResponse) error {
+	if resp.QueryID != "" {
+		g.queryID = resp.QueryID
+	}
+	if resp.Status != nil && resp.Status.Code != 0 {
+		g.scriptErr = errors.New(resp.Status.Message)
+		return nil
+	}
+	if md := resp.GetMetaData(); md != nil {
+		return g.handleMetadata(md)
+	}
+	data := resp.GetData()
+	if data == nil {
+		return nil
+	}
+	if stats := data.ExecutionStats; stats != nil {
+		g.stats.BytesProcessed += stats.BytesProcessed
+		g.stats.RecordsProcessed += stats.RecordsProcessed
+	}
+	if data.Batch != nil {
+		return g.handleBatch(data.Batch)
+	}
+	return nil
+}
+
+func (g *gatewayStream) handleMetadata(md *vizierpb.QueryMetadata) error {
+	t := &scriptTable{Name: md.Name}
+	for _, col := range md.Relation.GetColumns() {
+		t.Columns = append(t.Columns, &scriptColumn{Name: col.ColumnName, Type: col.ColumnType.String()})
+	}
+	g.columns[md.ID] = t.Columns
+	return g.out.table(md.ID, t)
+}
+
+func (g *gatewayStream) handleBatch(b *vizierpb.RowBatchData) error {
+	cols, ok := g.columns[b.TableID]
+	if !ok {
+		return fmt.Errorf("received data for unknown table %s", b.TableID)
+	}
+	if len(b.Cols) != len(cols) {
+		return fmt.Errorf("received %d columns for table with %d columns", len(b.Cols), len(cols))
+	}
+	for rowIdx := int64(0); rowIdx < b.NumRows; rowIdx++ {
+		row := make(map[string]interface{}, len(cols))
+		for colIdx, col := range b.Cols {
+			v, err := columnValue(col, rowIdx)
+			if err != nil {
+				return err
+			}
+			row[cols[colIdx].Name] = v
+		}
+		if err := g.out.row(b.TableID, row); err != nil {
+			return err
+		}
+	}
+	return g.out.batchDone()
+}
+
+// columnValue converts a value in a column into the type it is written to JSON as. Times are written in
+// RFC 3339 format, and 128-bit integers, which are usually UPIDs, as UUIDs.
+func columnValue(col *vizierpb.Column, rowIdx int64) (interface{}, error) {
+	switch c := col.ColData.(type) {
+	case *vizierpb.Column_BooleanData:
+		return c.BooleanData.Data[rowIdx], nil
+	case *vizierpb.Column_Int64Data:
+		return c.Int64Data.Data[rowIdx], nil
+	case *vizierpb.Column_Time64NsData:
+		return time.Unix(0, c.Time64NsData.Data[rowIdx]).UTC().Format(time.RFC3339Nano), nil
+	case *vizierpb.Column_Float64Data:
+		return c.Float64Data.Data[rowIdx], nil
+	case *vizierpb.Column_StringData:
+		return string(c.StringData.Data[rowIdx]), nil
+	case *vizierpb.Column_Uint128Data:
+		v := c.Uint128Data.Data[rowIdx]
+		b := make([]byte, 16)
+		binary.BigEndian.PutUint64(b, v.High)
+		binary.BigEndian.PutUint64(b[8:], v.Low)
+		return uuid.FromBytesOrNil(b).String(), nil
+	}
+	return nil, fmt.Errorf("unsupported column type %T", col.ColData)
+}
+
+func parseScriptResultsPage(r *http.Request) (int64, int64, error) {
+	params := r.URL.Query()
+	var offset int64
+	limit := int64(defaultScriptResultsLimit)
+	var err error
+	if s := params.Get("offset"); s != "" {
+		if offset, err = strconv.ParseInt(s, 10, 64); err != nil || offset < 0 {
+			return 0, 0, errors.New("offset must be a non-negative integer")
+		}
+	}
+	if s := params.Get("limit"); s != "" {
+		if limit, err = strconv.ParseInt(s, 10, 64); err != nil || limit <= 0 {
+			return 0, 0, errors.New("limit must be a positive integer")
+		}
+		if limit > maxScriptResultsLimit {
+			limit = maxScriptResultsLimit
+		}
+	}
+	return offset, limit, nil
+}
+
+// ServeHTTP handles requests to execute a script.
+func (g *ScriptExecutionGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sCtx, err := authcontext.FromContext(r.Context())
+	if err != nil || sCtx.Claims == nil {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return
+	}
+
+	body := &executeScriptHTTPRequest{}
+	if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if body.PxL == "" {
+		http.Error(w, "pxl must be specified", http.StatusBadRequest)
+		return
+	}
+	if uuid.FromStringOrNil(body.ClusterID) == uuid.Nil {
+		http.Error(w, "clusterID must be a valid UUID", http.StatusBadRequest)
+		return
+	}
+	req := body.toProto()
+	// The gRPC interceptors don't run for this endpoint, so the policy for the gRPC method is checked here.
+	if err := rbac.Authorize(r.Context(), CloudAPIPolicy.RequiredRole(executeScriptMethod), req); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	var out scriptResultsWriter
+	var streamed *streamedResults
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		offset, limit, err := parseScriptResultsPage(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		out = &collectedResults{w: w, offset: offset, limit: limit, tableIDs: make(map[string]*scriptTable)}
+	case "ndjson":
+		streamed = &streamedResults{w: w, enc: json.NewEncoder(w), names: make(map[string]string)}
+		out = streamed
+	default:
+		http.Error(w, fmt.Sprintf("unknown format %q", format), http.StatusBadRequest)
+		return
+	}
+
+	stream := &gatewayStream{ctx: r.Context(), out: out, columns: make(map[string][]*scriptColumn)}
+	err = g.Vizier.ExecuteScript(req, stream)
+	if err == nil {
+		err = stream.scriptErr
+	}
+	if err == nil {
+		if err := out.done(stream.queryID, &stream.stats); err != nil {
+			log.WithError(err).Error("Failed to write script results")
+		}
+		return
+	}
+
+	// Once rows have been streamed the status can't change, so the error is sent as the last line.
+	s := status.Convert(err)
+	if streamed != nil && streamed.started {
+		if err := streamed.write(&streamMessage{Type: "error", Error: s.Message()}); err != nil {
+			log.WithError(err).Error("Failed to write script error")
+		}
+		streamed.flush()
+		return
+	}
+	if err == stream.scriptErr {
+		http.Error(w, s.Message(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, s.Message(), services.HTTPStatusFromCode(s.Code()))
+}
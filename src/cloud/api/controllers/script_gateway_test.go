@@ -0,0 +1,272 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/cloud/api/controllers"
+)
+
+const testGatewayClusterID = "00000000-1111-2222-2222-333333333333"
+
+type fakeVizierService struct {
+	vizierpb.UnimplementedVizierServiceServer
+	req   *vizierpb.ExecuteScriptRequest
+	resps []*vizierpb.ExecuteScriptResponse
+	err   error
+}
+
+func (f *fakeVizierService) ExecuteScript(req *vizierpb.ExecuteScriptRequest, srv vizierpb.VizierService_ExecuteScriptServer) error {
+	f.req = req
+	for _, resp := range f.resps {
+		if err := srv.Send(resp); err != nil {
+			return err
+		}
+	}
+	return f.err
+}
+
+func testGatewayResponses() []*vizierpb.ExecuteScriptResponse {
+	return []*vizierpb.ExecuteScriptResponse{
+		{
+			QueryID: "query",
+			Result: &vizierpb.ExecuteScriptResponse_MetaData{MetaData: &vizierpb.QueryMetadata{
+				ID:   "table-1",
+				Name: "output",
+				Relation: &vizierpb.Relation{Columns: []*vizierpb.Relation_ColumnInfo{
+					{ColumnName: "time_", ColumnType: vizierpb.TIME64NS},
+					{ColumnName: "service", ColumnType: vizierpb.STRING},
+					{ColumnName: "count", ColumnType: vizierpb.INT64},
+				}},
+			}},
+		},
+		{
+			QueryID: "query",
+			Result: &vizierpb.ExecuteScriptResponse_Data{Data: &vizierpb.QueryData{
+				Batch: &vizierpb.RowBatchData{
+					TableID: "table-1",
+					NumRows: 3,
+					Cols: []*vizierpb.Column{
+						{ColData: &vizierpb.Column_Time64NsData{Time64NsData: &vizierpb.Time64NSColumn{Data: []int64{0, 1000, 2000}}}},
+						{ColData: &vizierpb.Column_StringData{StringData: &vizierpb.StringColumn{Data: [][]byte{[]byte("a"), []byte("b"), []byte("c")}}}},
+						{ColData: &vizierpb.Column_Int64Data{Int64Data: &vizierpb.Int64Column{Data: []int64{1, 2, 3}}}},
+					},
+				},
+			}},
+		},
+		{
+			QueryID: "query",
+			Result: &vizierpb.ExecuteScriptResponse_Data{Data: &vizierpb.QueryData{
+				ExecutionStats: &vizierpb.QueryExecutionStats{BytesProcessed: 100, RecordsProcessed: 3},
+			}},
+		},
+	}
+}
+
+func newScriptExecutionRequest(t *testing.T, ctx context.Context, query string, body map[string]interface{}) *http.Request {
+	b, err := json.Marshal(body)
+	require.NoError(t, err)
+	return httptest.NewRequest(http.MethodPost, controllers.ScriptExecutionPath+query, bytes.NewReader(b)).WithContext(ctx)
+}
+
+func TestScriptExecutionGateway_JSON(t *testing.T) {
+	vz := &fakeVizierService{resps: testGatewayResponses()}
+	g := &controllers.ScriptExecutionGateway{Vizier: vz}
+
+	req := newScriptExecutionRequest(t, CreateTestContext(), "?offset=1&limit=1", map[string]interface{}{
+		"clusterID": testGatewayClusterID,
+		"pxl":       "px.display(df)",
+		"execFuncs": []map[string]interface{}{
+			{"funcName": "main", "args": map[string]string{"start": "-5m"}},
+		},
+	})
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	assert.Equal(t, testGatewayClusterID, vz.req.ClusterID)
+	assert.Equal(t, "px.display(df)", vz.req.QueryStr)
+	require.Len(t, vz.req.ExecFuncs, 1)
+	assert.Equal(t, "main", vz.req.ExecFuncs[0].FuncName)
+	assert.Equal(t, "start", vz.req.ExecFuncs[0].ArgValues[0].Name)
+	assert.Equal(t, "-5m", vz.req.ExecFuncs[0].ArgValues[0].Value)
+
+	resp := struct {
+		QueryID string `json:"queryID"`
+		Tables  []struct {
+			Name    string                   `json:"name"`
+			Columns []map[string]string      `json:"columns"`
+			Rows    []map[string]interface{} `json:"rows"`
+			Total   int64                    `json:"totalRows"`
+			Next    *int64                   `json:"nextOffset"`
+		} `json:"tables"`
+		Stats map[string]int64 `json:"stats"`
+	}{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "query", resp.QueryID)
+	require.Len(t, resp.Tables, 1)
+	table := resp.Tables[0]
+	assert.Equal(t, "output", table.Name)
+	assert.Equal(t, []map[string]string{
+		{"name": "time_", "type": "TIME64NS"},
+		{"name": "service", "type": "STRING"},
+		{"name": "count", "type": "INT64"},
+	}, table.Columns)
+	assert.Equal(t, []map[string]interface{}{
+		{"time_": "1970-01-01T00:00:00.000001Z", "service": "b", "count": float64(2)},
+	}, table.Rows)
+	assert.Equal(t, int64(3), table.Total)
+	require.NotNil(t, table.Next)
+	assert.Equal(t, int64(2), *table.Next)
+	assert.Equal(t, int64(100), resp.Stats["bytesProcessed"])
+}
+
+func TestScriptExecutionGateway_NDJSON(t *testing.T) {
+	vz := &fakeVizierService{resps: testGatewayResponses()}
+	g := &controllers.ScriptExecutionGateway{Vizier: vz}
+
+	req := newScriptExecutionRequest(t, CreateTestContext(), "?format=ndjson", map[string]interface{}{
+		"clusterID": testGatewayClusterID,
+		"pxl":       "px.display(df)",
+	})
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+
+	var types []string
+	var services []interface{}
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		line := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		types = append(types, line["type"].(string))
+		if line["type"] == "row" {
+			assert.Equal(t, "output", line["tableName"])
+			services = append(services, line["row"].(map[string]interface{})["service"])
+		}
+	}
+	assert.Equal(t, []string{"table", "row", "row", "row", "done"}, types)
+	assert.Equal(t, []interface{}{"a", "b", "c"}, services)
+}
+
+func TestScriptExecutionGateway_Errors(t *testing.T) {
+	validBody := map[string]interface{}{"clusterID": testGatewayClusterID, "pxl": "px.display(df)"}
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		query        string
+		body         map[string]interface{}
+		vz           *fakeVizierService
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "unauthenticated",
+			ctx:          context.Background(),
+			body:         validBody,
+			vz:           &fakeVizierService{},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "missing pxl",
+			ctx:          CreateTestContext(),
+			body:         map[string]interface{}{"clusterID": testGatewayClusterID},
+			vz:           &fakeVizierService{},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "bad cluster",
+			ctx:          CreateTestContext(),
+			body:         map[string]interface{}{"clusterID": "cluster", "pxl": "px.display(df)"},
+			vz:           &fakeVizierService{},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "bad format",
+			ctx:          CreateTestContext(),
+			query:        "?format=csv",
+			body:         validBody,
+			vz:           &fakeVizierService{},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "compilation error",
+			ctx:          CreateTestContext(),
+			body:         validBody,
+			vz:           &fakeVizierService{resps: []*vizierpb.ExecuteScriptResponse{{Status: &vizierpb.Status{Code: 3, Message: "name 'df' is not defined"}}}},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "name 'df' is not defined",
+		},
+		{
+			name:         "cluster unavailable",
+			ctx:          CreateTestContext(),
+			body:         validBody,
+			vz:           &fakeVizierService{err: status.Error(codes.Unavailable, "cluster is not in a healthy state")},
+			expectedCode: http.StatusServiceUnavailable,
+			expectedBody: "cluster is not in a healthy state",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g := &controllers.ScriptExecutionGateway{Vizier: test.vz}
+			w := httptest.NewRecorder()
+			g.ServeHTTP(w, newScriptExecutionRequest(t, test.ctx, test.query, test.body))
+			assert.Equal(t, test.expectedCode, w.Code)
+			assert.True(t, strings.Contains(w.Body.String(), test.expectedBody))
+		})
+	}
+}
+
+func TestScriptExecutionGateway_NDJSONErrorAfterRows(t *testing.T) {
+	vz := &fakeVizierService{
+		resps: testGatewayResponses()[:2],
+		err:   status.Error(codes.Internal, "stream reset"),
+	}
+	g := &controllers.ScriptExecutionGateway{Vizier: vz}
+
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, newScriptExecutionRequest(t, CreateTestContext(), "?format=ndjson", map[string]interface{}{
+		"clusterID": testGatewayClusterID,
+		"pxl":       "px.display(df)",
+	}))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	last := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &last))
+	assert.Equal(t, "error", last["type"])
+	assert.Equal(t, "stream reset", last["error"])
+}
@@ -0,0 +1,296 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/shared/services/authcontext"
+	"px.dev/pixie/src/shared/services/rbac"
+)
+
+// ScriptStreamPath is the path of the WebSocket endpoint that streams the results of PxL scripts, for
+// browsers that can't use gRPC.
+const ScriptStreamPath = "/api/v1/scripts/stream"
+
+const (
+	defaultStreamHeartbeatInterval = 15 * time.Second
+	streamRequestTimeout           = 30 * time.Second
+	streamWriteTimeout             = 10 * time.Second
+)
+
+// ScriptStreamGateway streams the results of a PxL script over a WebSocket. The client sends the
+// script as the first message, in the same JSON format as the ScriptExecutionGateway. The gateway then
+// sends a message for each table and row, a cursor message after each batch of rows, and a done or
+// error message before closing the socket. A heartbeat message and a ping are sent periodically so that
+// clients and proxies can tell an idle stream from a dead one.
+//
+// A client that reconnects can set the cursor field of the request to the last cursor it received.
+// The script is executed again, and the rows that were already sent for each table are skipped, so
+// streams of live data may overlap.
+type ScriptStreamGateway struct {
+	Vizier vizierpb.VizierServiceServer
+	// AllowedOrigins are the origins, besides the API's own, that browsers may connect from.
+	AllowedOrigins []string
+	// HeartbeatInterval is how often heartbeats are sent. It defaults to 15 seconds.
+	HeartbeatInterval time.Duration
+}
+
+type scriptStreamRequest struct {
+	executeScriptHTTPRequest
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// streamCursor is the number of rows of each table, by name, that have been sent.
+type streamCursor struct {
+	Rows map[string]int64 `json:"rows"`
+}
+
+func (c *streamCursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeStreamCursor(s string) (*streamCursor, error) {
+	c := &streamCursor{Rows: make(map[string]int64)}
+	if s == "" {
+		return c, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	if err := json.Unmarshal(b, c); err != nil || c.Rows == nil {
+		return nil, errors.New("invalid cursor")
+	}
+	return c, nil
+}
+
+// socketResults writes the results of a script to a WebSocket. Writes are serialized, since the
+// heartbeats are sent concurrently with the results.
+type socketResults struct {
+	conn *websocket.Conn
+	mu   sync.Mutex
+
+	names map[string]string
+	// skip is the number of rows of each table to skip, from the cursor that the client resumed from.
+	skip map[string]int64
+	sent *streamCursor
+}
+
+func (s *socketResults) write(m *streamMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout)); err != nil {
+		return err
+	}
+	return s.conn.WriteJSON(m)
+}
+
+func (s *socketResults) heartbeat() error {
+	if err := s.write(&streamMessage{Type: "heartbeat"}); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout))
+}
+
+func (s *socketResults) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	if err := s.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(streamWriteTimeout)); err != nil {
+		log.WithError(err).Debug("Failed to close script stream")
+	}
+}
+
+func (s *socketResults) table(id string, t *scriptTable) error {
+	s.names[id] = t.Name
+	return s.write(&streamMessage{Type: "table", Table: t})
+}
+
+func (s *socketResults) row(tableID string, row map[string]interface{}) error {
+	name := s.names[tableID]
+	n := s.sent.Rows[name]
+	if n < s.skip[name] {
+		// The row was sent before the client reconnected, so it only advances the cursor.
+		s.sent.Rows[name] = n + 1
+		return nil
+	}
+	if err := s.write(&streamMessage{Type: "row", TableName: name, Row: row}); err != nil {
+		return err
+	}
+	s.sent.Rows[name] = n + 1
+	return nil
+}
+
+func (s *socketResults) batchDone() error {
+	return s.write(&streamMessage{Type: "cursor", Cursor: s.sent.encode()})
+}
+
+func (s *socketResults) done(queryID string, stats *scriptStats) error {
+	return s.write(&streamMessage{Type: "done", QueryID: queryID, Stats: stats, Cursor: s.sent.encode()})
+}
+
+func (g *ScriptStreamGateway) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		// Only browsers set the origin.
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if u.Host == r.Host {
+		return true
+	}
+	for _, allowed := range g.AllowedOrigins {
+		if origin == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// readRequest reads and validates the script request, which must be the first message on the socket.
+func (g *ScriptStreamGateway) readRequest(ctx context.Context, conn *websocket.Conn) (*vizierpb.ExecuteScriptRequest, *streamCursor, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(streamRequestTimeout)); err != nil {
+		return nil, nil, err
+	}
+	body := &scriptStreamRequest{}
+	if err := conn.ReadJSON(body); err != nil {
+		return nil, nil, errors.New("invalid request")
+	}
+	if body.PxL == "" {
+		return nil, nil, errors.New("pxl must be specified")
+	}
+	if uuid.FromStringOrNil(body.ClusterID) == uuid.Nil {
+		return nil, nil, errors.New("clusterID must be a valid UUID")
+	}
+	cursor, err := decodeStreamCursor(body.Cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+	req := body.toProto()
+	// The gRPC interceptors don't run for this endpoint, so the policy for the gRPC method is checked here.
+	if err := rbac.Authorize(ctx, CloudAPIPolicy.RequiredRole(executeScriptMethod), req); err != nil {
+		return nil, nil, err
+	}
+	return req, cursor, nil
+}
+
+// ServeHTTP handles requests to stream a script.
+func (g *ScriptStreamGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sCtx, err := authcontext.FromContext(r.Context())
+	if err != nil || sCtx.Claims == nil {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return
+	}
+	upgrader := websocket.Upgrader{CheckOrigin: g.checkOrigin}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already replied with an error.
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	out := &socketResults{conn: conn, names: make(map[string]string)}
+	defer out.close()
+	req, cursor, err := g.readRequest(ctx, conn)
+	if err != nil {
+		if err := out.write(&streamMessage{Type: "error", Error: status.Convert(err).Message()}); err != nil {
+			log.WithError(err).Error("Failed to write script error")
+		}
+		return
+	}
+	out.skip = cursor.Rows
+	out.sent = &streamCursor{Rows: make(map[string]int64)}
+
+	interval := g.HeartbeatInterval
+	if interval == 0 {
+		interval = defaultStreamHeartbeatInterval
+	}
+	go g.readUntilClosed(conn, cancel, interval)
+	go g.sendHeartbeats(ctx, out, interval)
+
+	stream := &gatewayStream{ctx: ctx, out: out, columns: make(map[string][]*scriptColumn)}
+	err = g.Vizier.ExecuteScript(req, stream)
+	if err == nil {
+		err = stream.scriptErr
+	}
+	if err == nil {
+		err = out.done(stream.queryID, &stream.stats)
+	} else {
+		err = out.write(&streamMessage{Type: "error", Error: status.Convert(err).Message()})
+	}
+	if err != nil && ctx.Err() == nil {
+		log.WithError(err).Error("Failed to write script results")
+	}
+}
+
+// readUntilClosed reads from the socket so that pongs and close messages are handled, and cancels the
+// script once the client goes away. Messages from the client after the request are ignored.
+func (g *ScriptStreamGateway) readUntilClosed(conn *websocket.Conn, cancel context.CancelFunc, interval time.Duration) {
+	defer cancel()
+	extend := func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2*interval + streamWriteTimeout))
+	}
+	conn.SetPongHandler(extend)
+	if err := extend(""); err != nil {
+		return
+	}
+	for {
+		if _, _, err := conn.NextReader(); err != nil {
+			return
+		}
+	}
+}
+
+func (g *ScriptStreamGateway) sendHeartbeats(ctx context.Context, out *socketResults, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := out.heartbeat(); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,217 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/cloud/api/controllers"
+)
+
+// blockingVizierService only returns once the script is cancelled.
+type blockingVizierService struct {
+	vizierpb.UnimplementedVizierServiceServer
+}
+
+func (b *blockingVizierService) ExecuteScript(req *vizierpb.ExecuteScriptRequest, srv vizierpb.VizierService_ExecuteScriptServer) error {
+	<-srv.Context().Done()
+	return srv.Context().Err()
+}
+
+func dialScriptStream(t *testing.T, ctx context.Context, g *controllers.ScriptStreamGateway) *websocket.Conn {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.ServeHTTP(w, r.WithContext(ctx))
+	}))
+	t.Cleanup(s.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http")+controllers.ScriptStreamPath, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func readStreamMessages(t *testing.T, conn *websocket.Conn) []map[string]interface{} {
+	var msgs []map[string]interface{}
+	for {
+		msg := map[string]interface{}{}
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		if err := conn.ReadJSON(&msg); err != nil {
+			require.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure), "unexpected error: %v", err)
+			return msgs
+		}
+		msgs = append(msgs, msg)
+	}
+}
+
+func messageTypes(msgs []map[string]interface{}) []string {
+	var types []string
+	for _, m := range msgs {
+		types = append(types, m["type"].(string))
+	}
+	return types
+}
+
+func TestScriptStreamGateway(t *testing.T) {
+	vz := &fakeVizierService{resps: testGatewayResponses()}
+	conn := dialScriptStream(t, CreateTestContext(), &controllers.ScriptStreamGateway{Vizier: vz})
+
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{
+		"clusterID": testGatewayClusterID,
+		"pxl":       "px.display(df)",
+		"execFuncs": []map[string]interface{}{{"funcName": "main"}},
+	}))
+	msgs := readStreamMessages(t, conn)
+	assert.Equal(t, []string{"table", "row", "row", "row", "cursor", "done"}, messageTypes(msgs))
+	assert.Equal(t, "px.display(df)", vz.req.QueryStr)
+	assert.Equal(t, "main", vz.req.ExecFuncs[0].FuncName)
+	assert.Equal(t, "output", msgs[0]["table"].(map[string]interface{})["name"])
+	assert.Equal(t, "a", msgs[1]["row"].(map[string]interface{})["service"])
+	assert.Equal(t, "query", msgs[5]["queryID"])
+	assert.Equal(t, msgs[4]["cursor"], msgs[5]["cursor"])
+}
+
+func TestScriptStreamGateway_Resume(t *testing.T) {
+	vz := &fakeVizierService{resps: testGatewayResponses()}
+	g := &controllers.ScriptStreamGateway{Vizier: vz}
+
+	// Run the script once to get a cursor for its three rows.
+	conn := dialScriptStream(t, CreateTestContext(), g)
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{"clusterID": testGatewayClusterID, "pxl": "px.display(df)"}))
+	msgs := readStreamMessages(t, conn)
+	cursor := msgs[len(msgs)-1]["cursor"].(string)
+
+	// The second run returns a new batch of rows after the ones that were already sent.
+	resps := testGatewayResponses()
+	vz.resps = append(resps[:2], resps[1:]...)
+
+	conn = dialScriptStream(t, CreateTestContext(), g)
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{
+		"clusterID": testGatewayClusterID,
+		"pxl":       "px.display(df)",
+		"cursor":    cursor,
+	}))
+	msgs = readStreamMessages(t, conn)
+	// The first batch was already sent, so only the rows of the repeated batch are sent.
+	assert.Equal(t, []string{"table", "cursor", "row", "row", "row", "cursor", "done"}, messageTypes(msgs))
+}
+
+func TestScriptStreamGateway_Heartbeat(t *testing.T) {
+	conn := dialScriptStream(t, CreateTestContext(), &controllers.ScriptStreamGateway{
+		Vizier:            &blockingVizierService{},
+		HeartbeatInterval: 10 * time.Millisecond,
+	})
+
+	pinged := make(chan struct{}, 1)
+	conn.SetPingHandler(func(string) error {
+		select {
+		case pinged <- struct{}{}:
+		default:
+		}
+		return nil
+	})
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{"clusterID": testGatewayClusterID, "pxl": "px.display(df)"}))
+
+	msg := map[string]interface{}{}
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	require.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, "heartbeat", msg["type"])
+	// Pings are handled while reading the next message.
+	require.NoError(t, conn.ReadJSON(&msg))
+	select {
+	case <-pinged:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no ping received")
+	}
+}
+
+func TestScriptStreamGateway_Errors(t *testing.T) {
+	tests := []struct {
+		name          string
+		req           map[string]interface{}
+		vz            vizierpb.VizierServiceServer
+		expectedError string
+	}{
+		{
+			name:          "missing pxl",
+			req:           map[string]interface{}{"clusterID": testGatewayClusterID},
+			vz:            &fakeVizierService{},
+			expectedError: "pxl must be specified",
+		},
+		{
+			name:          "bad cursor",
+			req:           map[string]interface{}{"clusterID": testGatewayClusterID, "pxl": "px.display(df)", "cursor": "???"},
+			vz:            &fakeVizierService{},
+			expectedError: "invalid cursor",
+		},
+		{
+			name: "compilation error",
+			req:  map[string]interface{}{"clusterID": testGatewayClusterID, "pxl": "px.display(df)"},
+			vz: &fakeVizierService{resps: []*vizierpb.ExecuteScriptResponse{
+				{Status: &vizierpb.Status{Code: 3, Message: "name 'df' is not defined"}},
+			}},
+			expectedError: "name 'df' is not defined",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conn := dialScriptStream(t, CreateTestContext(), &controllers.ScriptStreamGateway{Vizier: test.vz})
+			require.NoError(t, conn.WriteJSON(test.req))
+			msgs := readStreamMessages(t, conn)
+			require.NotEmpty(t, msgs)
+			last := msgs[len(msgs)-1]
+			assert.Equal(t, "error", last["type"])
+			assert.Equal(t, test.expectedError, last["error"])
+		})
+	}
+}
+
+func TestScriptStreamGateway_Unauthenticated(t *testing.T) {
+	g := &controllers.ScriptStreamGateway{Vizier: &fakeVizierService{}}
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, httptest.NewRequest(http.MethodGet, controllers.ScriptStreamPath, nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestScriptStreamGateway_CrossOrigin(t *testing.T) {
+	g := &controllers.ScriptStreamGateway{Vizier: &fakeVizierService{}, AllowedOrigins: []string{"https://dashboards.example.com"}}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.ServeHTTP(w, r.WithContext(CreateTestContext()))
+	}))
+	defer s.Close()
+	addr := "ws" + strings.TrimPrefix(s.URL, "http") + controllers.ScriptStreamPath
+
+	_, resp, err := websocket.DefaultDialer.Dial(addr, http.Header{"Origin": []string{"https://evil.example.com"}})
+	require.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	conn, _, err := websocket.DefaultDialer.Dial(addr, http.Header{"Origin": []string{"https://dashboards.example.com"}})
+	require.NoError(t, err)
+	conn.Close()
+}
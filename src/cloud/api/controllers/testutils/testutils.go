@@ -32,6 +32,7 @@ import (
 	mock_configmanagerpb "px.dev/pixie/src/cloud/config_manager/configmanagerpb/mock"
 	mock_pluginpb "px.dev/pixie/src/cloud/plugin/pluginpb/mock"
 	mock_profilepb "px.dev/pixie/src/cloud/profile/profilepb/mock"
+	mock_scriptmgrpb "px.dev/pixie/src/cloud/scriptmgr/scriptmgrpb/mock"
 	mock_vzmgrpb "px.dev/pixie/src/cloud/vzmgr/vzmgrpb/mock"
 )
 
@@ -90,12 +91,16 @@ type MockAPIClients struct {
 	MockProfile             *mock_profilepb.MockProfileServiceClient
 	MockOrg                 *mock_profilepb.MockOrgServiceClient
 	MockVzDeployKey         *mock_vzmgrpb.MockVZDeploymentKeyServiceClient
+	MockVzFleet             *mock_vzmgrpb.MockVZFleetServiceClient
 	MockAPIKey              *mock_auth.MockAPIKeyServiceClient
 	MockVzMgr               *mock_vzmgrpb.MockVZMgrServiceClient
 	MockArtifact            *mock_artifacttrackerpb.MockArtifactTrackerClient
 	MockConfigMgr           *mock_configmanagerpb.MockConfigManagerServiceClient
 	MockPlugin              *mock_pluginpb.MockPluginServiceClient
 	MockDataRetentionPlugin *mock_pluginpb.MockDataRetentionPluginServiceClient
+	MockAlertRoutePlugin    *mock_pluginpb.MockAlertRoutePluginServiceClient
+	MockScriptRegistry      *mock_scriptmgrpb.MockScriptRegistryServiceClient
+	MockQueryHistory        *mock_scriptmgrpb.MockQueryHistoryServiceClient
 }
 
 // CreateTestAPIEnv creates a test environment and mock clients.
@@ -110,11 +115,15 @@ func CreateTestAPIEnv(t *testing.T) (apienv.APIEnv, *MockAPIClients, func()) {
 	mockOrgClient := mock_profilepb.NewMockOrgServiceClient(ctrl)
 	mockVzMgrClient := mock_vzmgrpb.NewMockVZMgrServiceClient(ctrl)
 	mockVzDeployKey := mock_vzmgrpb.NewMockVZDeploymentKeyServiceClient(ctrl)
+	mockVzFleet := mock_vzmgrpb.NewMockVZFleetServiceClient(ctrl)
 	mockAPIKey := mock_auth.NewMockAPIKeyServiceClient(ctrl)
 	mockArtifactTrackerClient := mock_artifacttrackerpb.NewMockArtifactTrackerClient(ctrl)
 	mockConfigMgrClient := mock_configmanagerpb.NewMockConfigManagerServiceClient(ctrl)
 	mockPluginClient := mock_pluginpb.NewMockPluginServiceClient(ctrl)
 	mockRetentionClient := mock_pluginpb.NewMockDataRetentionPluginServiceClient(ctrl)
+	mockAlertRouteClient := mock_pluginpb.NewMockAlertRoutePluginServiceClient(ctrl)
+	mockScriptRegistryClient := mock_scriptmgrpb.NewMockScriptRegistryServiceClient(ctrl)
+	mockQueryHistoryClient := mock_scriptmgrpb.NewMockQueryHistoryServiceClient(ctrl)
 	apiEnv, err := apienv.New(mockAuthClient, mockProfileClient, mockOrgClient, mockVzDeployKey, mockAPIKey, mockVzMgrClient, mockArtifactTrackerClient, nil, mockConfigMgrClient, mockPluginClient, mockRetentionClient)
 	if err != nil {
 		t.Fatal("failed to init api env")
@@ -127,9 +136,13 @@ func CreateTestAPIEnv(t *testing.T) (apienv.APIEnv, *MockAPIClients, func()) {
 		MockVzMgr:               mockVzMgrClient,
 		MockAPIKey:              mockAPIKey,
 		MockVzDeployKey:         mockVzDeployKey,
+		MockVzFleet:             mockVzFleet,
 		MockArtifact:            mockArtifactTrackerClient,
 		MockConfigMgr:           mockConfigMgrClient,
 		MockPlugin:              mockPluginClient,
 		MockDataRetentionPlugin: mockRetentionClient,
+		MockAlertRoutePlugin:    mockAlertRouteClient,
+		MockScriptRegistry:      mockScriptRegistryClient,
+		MockQueryHistory:        mockQueryHistoryClient,
 	}, ctrl.Finish
 }
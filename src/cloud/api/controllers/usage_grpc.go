@@ -0,0 +1,231 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/cloud/shared/auditlog"
+	"px.dev/pixie/src/cloud/shared/metering"
+	"px.dev/pixie/src/utils"
+)
+
+// UsageServer is the server that implements the UsageService gRPC service.
+type UsageServer struct {
+	Usage  metering.Store
+	Quotas metering.QuotaStore
+	// Enforcer, if set, is told when an org's quotas change.
+	Enforcer *metering.Enforcer
+	AuditLog auditlog.Recorder
+}
+
+var usageMetricToProto = map[metering.Metric]cloudpb.UsageMetric{
+	metering.MetricQueryExecutions: cloudpb.UM_QUERY_EXECUTIONS,
+	metering.MetricBytesScanned:    cloudpb.UM_BYTES_SCANNED,
+	metering.MetricBytesExported:   cloudpb.UM_BYTES_EXPORTED,
+}
+
+var usageMetricFromProto = map[cloudpb.UsageMetric]metering.Metric{
+	cloudpb.UM_QUERY_EXECUTIONS: metering.MetricQueryExecutions,
+	cloudpb.UM_BYTES_SCANNED:    metering.MetricBytesScanned,
+	cloudpb.UM_BYTES_EXPORTED:   metering.MetricBytesExported,
+}
+
+var usageIntervalFromProto = map[cloudpb.UsageInterval]metering.Interval{
+	cloudpb.UI_DAY:   metering.IntervalDay,
+	cloudpb.UI_HOUR:  metering.IntervalHour,
+	cloudpb.UI_MONTH: metering.IntervalMonth,
+}
+
+var quotaPeriodToProto = map[metering.Period]cloudpb.QuotaPeriod{
+	metering.PeriodDay:   cloudpb.QP_DAY,
+	metering.PeriodMonth: cloudpb.QP_MONTH,
+}
+
+var quotaPeriodFromProto = map[cloudpb.QuotaPeriod]metering.Period{
+	cloudpb.QP_DAY:   metering.PeriodDay,
+	cloudpb.QP_MONTH: metering.PeriodMonth,
+}
+
+func orgUUIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return utils.UUIDFromProtoOrNil(orgID), nil
+}
+
+func metricFromProto(m cloudpb.UsageMetric) (metering.Metric, error) {
+	metric, ok := usageMetricFromProto[m]
+	if !ok {
+		return "", status.Error(codes.InvalidArgument, "unknown usage metric")
+	}
+	return metric, nil
+}
+
+// GetUsageReport reports the usage of the caller's org.
+func (u *UsageServer) GetUsageReport(ctx context.Context, req *cloudpb.GetUsageReportRequest) (*cloudpb.GetUsageReportResponse, error) {
+	orgID, err := orgUUIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	interval, ok := usageIntervalFromProto[req.Interval]
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "unknown usage interval")
+	}
+
+	q := &metering.ReportQuery{
+		OrgID:     orgID,
+		ClusterID: utils.UUIDFromProtoOrNil(req.ClusterID),
+		Interval:  interval,
+	}
+	if req.Since != nil {
+		if q.Since, err = types.TimestampFromProto(req.Since); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid since time")
+		}
+	}
+	if req.Until != nil {
+		if q.Until, err = types.TimestampFromProto(req.Until); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid until time")
+		}
+	}
+	if !q.Since.IsZero() && !q.Until.IsZero() && !q.Since.Before(q.Until) {
+		return nil, status.Error(codes.InvalidArgument, "since must be before until")
+	}
+
+	buckets, err := u.Usage.Report(ctx, q)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to get usage report")
+	}
+	resp := &cloudpb.GetUsageReportResponse{Buckets: make([]*cloudpb.UsageBucket, 0, len(buckets))}
+	for _, b := range buckets {
+		start, err := types.TimestampProto(b.Start)
+		if err != nil {
+			return nil, err
+		}
+		resp.Buckets = append(resp.Buckets, &cloudpb.UsageBucket{
+			Start:     start,
+			ClusterID: utils.ProtoFromUUID(b.ClusterID),
+			Metric:    usageMetricToProto[b.Metric],
+			Value:     b.Value,
+		})
+	}
+	return resp, nil
+}
+
+// GetQuotas gets the quotas of the caller's org, and the usage in their current periods.
+func (u *UsageServer) GetQuotas(ctx context.Context, req *cloudpb.GetQuotasRequest) (*cloudpb.GetQuotasResponse, error) {
+	orgID, err := orgUUIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	quotas, err := u.Quotas.List(ctx, orgID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to get quotas")
+	}
+	resp := &cloudpb.GetQuotasResponse{Quotas: make([]*cloudpb.Quota, 0, len(quotas))}
+	now := time.Now()
+	for _, q := range quotas {
+		used, err := u.Usage.Total(ctx, orgID, q.Metric, q.Period.Start(now))
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to get usage")
+		}
+		updatedAt, err := types.TimestampProto(q.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		resp.Quotas = append(resp.Quotas, &cloudpb.Quota{
+			Metric:    usageMetricToProto[q.Metric],
+			Limit:     q.Limit,
+			Period:    quotaPeriodToProto[q.Period],
+			Used:      used,
+			UpdatedAt: updatedAt,
+		})
+	}
+	return resp, nil
+}
+
+// SetQuota creates or replaces a quota in the caller's org.
+func (u *UsageServer) SetQuota(ctx context.Context, req *cloudpb.SetQuotaRequest) (*types.Empty, error) {
+	orgID, err := orgUUIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metric, err := metricFromProto(req.Metric)
+	if err != nil {
+		return nil, err
+	}
+	period, ok := quotaPeriodFromProto[req.Period]
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "unknown quota period")
+	}
+	if req.Limit <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "quota limit must be positive")
+	}
+
+	err = u.Quotas.Set(ctx, &metering.Quota{
+		OrgID:     orgID,
+		Metric:    metric,
+		Limit:     req.Limit,
+		Period:    period,
+		UpdatedAt: time.Now(),
+	})
+	recordAudit(ctx, u.AuditLog, auditlog.ActionQuotaSet, string(metric), err)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to set quota")
+	}
+	if u.Enforcer != nil {
+		u.Enforcer.Invalidate(orgID)
+	}
+	return &types.Empty{}, nil
+}
+
+// DeleteQuota deletes a quota in the caller's org.
+func (u *UsageServer) DeleteQuota(ctx context.Context, req *cloudpb.DeleteQuotaRequest) (*types.Empty, error) {
+	orgID, err := orgUUIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metric, err := metricFromProto(req.Metric)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.Quotas.Delete(ctx, orgID, metric)
+	recordAudit(ctx, u.AuditLog, auditlog.ActionQuotaDeleted, string(metric), err)
+	if errors.Is(err, metering.ErrQuotaNotFound) {
+		return nil, status.Error(codes.NotFound, "quota not found")
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to delete quota")
+	}
+	if u.Enforcer != nil {
+		u.Enforcer.Invalidate(orgID)
+	}
+	return &types.Empty{}, nil
+}
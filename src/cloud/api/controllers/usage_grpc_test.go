@@ -0,0 +1,202 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/cloud/api/controllers"
+	"px.dev/pixie/src/cloud/shared/auditlog"
+	"px.dev/pixie/src/cloud/shared/metering"
+	"px.dev/pixie/src/utils"
+)
+
+var testUsageOrgID = uuid.FromStringOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+type fakeUsageStore struct {
+	query   *metering.ReportQuery
+	buckets []*metering.ReportBucket
+	total   int64
+}
+
+func (s *fakeUsageStore) Write(ctx context.Context, usage []*metering.Usage) error {
+	return nil
+}
+
+func (s *fakeUsageStore) Report(ctx context.Context, q *metering.ReportQuery) ([]*metering.ReportBucket, error) {
+	s.query = q
+	return s.buckets, nil
+}
+
+func (s *fakeUsageStore) Total(ctx context.Context, orgID uuid.UUID, metric metering.Metric, since time.Time) (int64, error) {
+	return s.total, nil
+}
+
+type fakeQuotaStore struct {
+	quotas map[metering.Metric]*metering.Quota
+}
+
+func (s *fakeQuotaStore) List(ctx context.Context, orgID uuid.UUID) ([]*metering.Quota, error) {
+	var quotas []*metering.Quota
+	for _, q := range s.quotas {
+		if q.OrgID == orgID {
+			quotas = append(quotas, q)
+		}
+	}
+	return quotas, nil
+}
+
+func (s *fakeQuotaStore) Set(ctx context.Context, q *metering.Quota) error {
+	s.quotas[q.Metric] = q
+	return nil
+}
+
+func (s *fakeQuotaStore) Delete(ctx context.Context, orgID uuid.UUID, metric metering.Metric) error {
+	if _, ok := s.quotas[metric]; !ok {
+		return metering.ErrQuotaNotFound
+	}
+	delete(s.quotas, metric)
+	return nil
+}
+
+func TestUsageServer_GetUsageReport(t *testing.T) {
+	ctx := CreateTestContext()
+	clusterID := uuid.Must(uuid.NewV4())
+	start := time.Date(2021, time.March, 14, 0, 0, 0, 0, time.UTC)
+	store := &fakeUsageStore{buckets: []*metering.ReportBucket{
+		{Start: start, ClusterID: clusterID, Metric: metering.MetricBytesScanned, Value: 1024},
+	}}
+	s := &controllers.UsageServer{Usage: store, Quotas: &fakeQuotaStore{}}
+
+	since, err := types.TimestampProto(start)
+	require.NoError(t, err)
+	resp, err := s.GetUsageReport(ctx, &cloudpb.GetUsageReportRequest{
+		Since:     since,
+		Interval:  cloudpb.UI_HOUR,
+		ClusterID: utils.ProtoFromUUID(clusterID),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, testUsageOrgID, store.query.OrgID)
+	assert.Equal(t, clusterID, store.query.ClusterID)
+	assert.Equal(t, metering.IntervalHour, store.query.Interval)
+	assert.True(t, start.Equal(store.query.Since))
+	assert.True(t, store.query.Until.IsZero())
+
+	require.Len(t, resp.Buckets, 1)
+	assert.Equal(t, since, resp.Buckets[0].Start)
+	assert.Equal(t, utils.ProtoFromUUID(clusterID), resp.Buckets[0].ClusterID)
+	assert.Equal(t, cloudpb.UM_BYTES_SCANNED, resp.Buckets[0].Metric)
+	assert.Equal(t, int64(1024), resp.Buckets[0].Value)
+}
+
+func TestUsageServer_GetUsageReportInvalidRange(t *testing.T) {
+	ctx := CreateTestContext()
+	s := &controllers.UsageServer{Usage: &fakeUsageStore{}, Quotas: &fakeQuotaStore{}}
+
+	ts := types.TimestampNow()
+	_, err := s.GetUsageReport(ctx, &cloudpb.GetUsageReportRequest{Since: ts, Until: ts})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestUsageServer_SetQuota(t *testing.T) {
+	ctx := CreateTestContext()
+	quotas := &fakeQuotaStore{quotas: make(map[metering.Metric]*metering.Quota)}
+	store := &fakeUsageStore{total: 40}
+	al := &fakeAuditLog{}
+	s := &controllers.UsageServer{
+		Usage:    store,
+		Quotas:   quotas,
+		Enforcer: metering.NewEnforcer(quotas, store),
+		AuditLog: al,
+	}
+
+	_, err := s.SetQuota(ctx, &cloudpb.SetQuotaRequest{
+		Metric: cloudpb.UM_QUERY_EXECUTIONS,
+		Limit:  100,
+		Period: cloudpb.QP_DAY,
+	})
+	require.NoError(t, err)
+	require.Contains(t, quotas.quotas, metering.MetricQueryExecutions)
+	assert.Equal(t, testUsageOrgID, quotas.quotas[metering.MetricQueryExecutions].OrgID)
+
+	require.Len(t, al.events, 1)
+	assert.Equal(t, auditlog.ActionQuotaSet, al.events[0].Action)
+	assert.Equal(t, "query_executions", al.events[0].ResourceID)
+
+	resp, err := s.GetQuotas(ctx, &cloudpb.GetQuotasRequest{})
+	require.NoError(t, err)
+	require.Len(t, resp.Quotas, 1)
+	assert.Equal(t, cloudpb.UM_QUERY_EXECUTIONS, resp.Quotas[0].Metric)
+	assert.Equal(t, cloudpb.QP_DAY, resp.Quotas[0].Period)
+	assert.Equal(t, int64(100), resp.Quotas[0].Limit)
+	assert.Equal(t, int64(40), resp.Quotas[0].Used)
+}
+
+func TestUsageServer_SetQuotaInvalid(t *testing.T) {
+	ctx := CreateTestContext()
+	al := &fakeAuditLog{}
+	s := &controllers.UsageServer{
+		Usage:    &fakeUsageStore{},
+		Quotas:   &fakeQuotaStore{quotas: make(map[metering.Metric]*metering.Quota)},
+		AuditLog: al,
+	}
+
+	tests := []*cloudpb.SetQuotaRequest{
+		{Metric: cloudpb.UM_UNKNOWN, Limit: 100, Period: cloudpb.QP_DAY},
+		{Metric: cloudpb.UM_BYTES_SCANNED, Limit: 100, Period: cloudpb.QP_UNKNOWN},
+		{Metric: cloudpb.UM_BYTES_SCANNED, Limit: 0, Period: cloudpb.QP_MONTH},
+	}
+	for _, req := range tests {
+		_, err := s.SetQuota(ctx, req)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+	assert.Len(t, al.events, 0)
+}
+
+func TestUsageServer_DeleteQuota(t *testing.T) {
+	ctx := CreateTestContext()
+	quotas := &fakeQuotaStore{quotas: map[metering.Metric]*metering.Quota{
+		metering.MetricBytesExported: {OrgID: testUsageOrgID, Metric: metering.MetricBytesExported, Limit: 1, Period: metering.PeriodMonth},
+	}}
+	al := &fakeAuditLog{}
+	s := &controllers.UsageServer{Usage: &fakeUsageStore{}, Quotas: quotas, AuditLog: al}
+
+	_, err := s.DeleteQuota(ctx, &cloudpb.DeleteQuotaRequest{Metric: cloudpb.UM_BYTES_EXPORTED})
+	require.NoError(t, err)
+	assert.Len(t, quotas.quotas, 0)
+
+	_, err = s.DeleteQuota(ctx, &cloudpb.DeleteQuotaRequest{Metric: cloudpb.UM_BYTES_EXPORTED})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	require.Len(t, al.events, 2)
+	assert.Equal(t, auditlog.ActionQuotaDeleted, al.events[0].Action)
+	assert.True(t, al.events[0].Succeeded)
+	assert.False(t, al.events[1].Succeeded)
+}
@@ -27,6 +27,7 @@ import (
 	"px.dev/pixie/src/api/proto/cloudpb"
 	"px.dev/pixie/src/api/proto/uuidpb"
 	"px.dev/pixie/src/cloud/profile/profilepb"
+	"px.dev/pixie/src/cloud/shared/auditlog"
 	"px.dev/pixie/src/shared/services/authcontext"
 	claimsutils "px.dev/pixie/src/shared/services/utils"
 	"px.dev/pixie/src/utils"
@@ -36,6 +37,7 @@ import (
 type UserServiceServer struct {
 	ProfileServiceClient profilepb.ProfileServiceClient
 	OrgServiceClient     profilepb.OrgServiceClient
+	AuditLog             auditlog.Recorder
 }
 
 // GetUser will retrieve user based on UUID.
@@ -145,6 +147,9 @@ func (u *UserServiceServer) UpdateUser(ctx context.Context, req *cloudpb.UpdateU
 	}
 
 	resp, err := u.ProfileServiceClient.UpdateUser(ctx, in)
+	if req.IsApproved != nil {
+		recordAudit(ctx, u.AuditLog, auditlog.ActionUserApprovalUpdated, auditResourceID(req.ID), err)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -27,6 +27,7 @@ import (
 
 	"px.dev/pixie/src/api/proto/cloudpb"
 	"px.dev/pixie/src/shared/services/authcontext"
+	"px.dev/pixie/src/shared/services/rbac"
 	"px.dev/pixie/src/utils"
 )
 
@@ -162,6 +163,10 @@ type editableUserPermissions struct {
 
 // UpdateUserPermissions updates user permissions.
 func (q *QueryResolver) UpdateUserPermissions(ctx context.Context, args *updateUserPermissionsArgs) (*UserInfoResolver, error) {
+	// Approving users is part of managing the org.
+	if err := rbac.Authorize(ctx, rbac.RoleAdmin, nil); err != nil {
+		return nil, rpcErrorHelper(err)
+	}
 	userID := utils.ProtoFromUUIDStrOrNil(string(args.UserID))
 	req := &cloudpb.UpdateUserRequest{
 		ID: userID,
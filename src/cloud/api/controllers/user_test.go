@@ -207,7 +207,7 @@ func TestServer_UpdateUser(t *testing.T) {
 					Return(updatedUserInfo, nil)
 			}
 
-			userServer := &controllers.UserServiceServer{mockClients.MockProfile, mockClients.MockOrg}
+			userServer := &controllers.UserServiceServer{mockClients.MockProfile, mockClients.MockOrg, nil}
 			resp, err := userServer.UpdateUser(tc.ctx, req)
 
 			if !tc.shouldReject {
@@ -266,7 +266,7 @@ func TestServer_DeleteUser(t *testing.T) {
 					Return(&profilepb.DeleteUserResponse{}, nil)
 			}
 
-			userServer := &controllers.UserServiceServer{mockClients.MockProfile, mockClients.MockOrg}
+			userServer := &controllers.UserServiceServer{mockClients.MockProfile, mockClients.MockOrg, nil}
 			resp, err := userServer.DeleteUser(tc.ctx, req)
 
 			if !tc.shouldReject {
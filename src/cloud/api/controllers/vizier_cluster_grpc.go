@@ -30,6 +30,7 @@ import (
 	"px.dev/pixie/src/api/proto/cloudpb"
 	"px.dev/pixie/src/api/proto/uuidpb"
 	"px.dev/pixie/src/cloud/artifact_tracker/artifacttrackerpb"
+	"px.dev/pixie/src/cloud/shared/auditlog"
 	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
 	"px.dev/pixie/src/shared/artifacts/versionspb"
 	"px.dev/pixie/src/shared/cvmsgspb"
@@ -42,6 +43,7 @@ import (
 type VizierClusterInfo struct {
 	VzMgr                 vzmgrpb.VZMgrServiceClient
 	ArtifactTrackerClient artifacttrackerpb.ArtifactTrackerClient
+	AuditLog              auditlog.Recorder
 }
 
 func contextWithAuthToken(ctx context.Context) (context.Context, error) {
@@ -208,6 +210,7 @@ func (v *VizierClusterInfo) getClusterInfoForViziers(ctx context.Context, ids []
 			NumInstrumentedNodes:          vzInfo.NumInstrumentedNodes,
 			PreviousStatus:                prevS,
 			PreviousStatusTime:            vzInfo.PreviousStatusTime,
+			ReadOnly:                      vzInfo.ReadOnly,
 		})
 	}
 
@@ -272,6 +275,7 @@ func (v *VizierClusterInfo) UpdateOrInstallCluster(ctx context.Context, req *clo
 		Version:      req.Version,
 		RedeployEtcd: req.RedeployEtcd,
 	})
+	recordAudit(ctx, v.AuditLog, auditlog.ActionClusterUpdated, auditResourceID(req.ClusterID), err)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,67 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// openapi_gen writes the OpenAPI document of the cloud HTTP APIs, so that clients in other languages
+// can be generated without a running API server.
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"px.dev/pixie/src/cloud/api/controllers"
+	"px.dev/pixie/src/cloud/shared/openapi"
+)
+
+func init() {
+	pflag.String("output", "", "The file to write the OpenAPI document to. Defaults to stdout.")
+	pflag.String("server_url", "https://work.withpixie.ai", "The URL of the Pixie Cloud to list as the server of the API.")
+}
+
+func main() {
+	pflag.Parse()
+	viper.AutomaticEnv()
+	viper.SetEnvPrefix("PL")
+	if err := viper.BindPFlags(pflag.CommandLine); err != nil {
+		log.WithError(err).Fatal("Failed to bind flags")
+	}
+
+	d := controllers.OpenAPISpec()
+	if url := viper.GetString("server_url"); url != "" {
+		d.Servers = []*openapi.Server{{URL: url}}
+	}
+	b, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		log.WithError(err).Fatal("Failed to encode OpenAPI document")
+	}
+	b = append(b, '\n')
+
+	out := viper.GetString("output")
+	if out == "" {
+		_, err = os.Stdout.Write(b)
+	} else {
+		err = os.WriteFile(out, b, 0o644)
+	}
+	if err != nil {
+		log.WithError(err).Fatal("Failed to write OpenAPI document")
+	}
+}
@@ -48,6 +48,8 @@ var (
 	ErrCredentialGenerate = status.Error(codes.Internal, "failed to generate creds for cluster")
 	// ErrPermissionDenied occurs when permission is denied to the cluster.
 	ErrPermissionDenied = status.Error(codes.PermissionDenied, "permission denied for access to cluster")
+	// ErrReadOnlyCluster occurs when a mutation is sent to a cluster that was registered with a viewer deploy key.
+	ErrReadOnlyCluster = status.Error(codes.PermissionDenied, "cluster is read-only and does not allow mutations")
 )
 
 // requestProxyer manages a single proxy request.
@@ -72,6 +74,11 @@ type ClusterIDer interface {
 	GetClusterID() string
 }
 
+// mutationRequest is implemented by requests that may mutate the cluster, such as by deploying tracepoints.
+type mutationRequest interface {
+	GetMutation() bool
+}
+
 func newRequestProxyer(vzmgr vzmgrClient, nc *nats.Conn, debugMode bool, r ClusterIDer, s grpcStream) (*requestProxyer, error) {
 	// Make a request to Vizier Manager validate that we have permissions to access the cluster
 	// and get the key to generate the token.
@@ -95,9 +102,14 @@ func newRequestProxyer(vzmgr vzmgrClient, nc *nats.Conn, debugMode bool, r Clust
 	}
 	p.clusterID = clusterID
 
-	signedToken, err := p.validateRequestAndFetchCreds(ctx, debugMode, vzmgr)
+	mutation := false
+	if m, ok := r.(mutationRequest); ok {
+		mutation = m.GetMutation()
+	}
+
+	signedToken, err := p.validateRequestAndFetchCreds(ctx, debugMode, mutation, vzmgr)
 	if err != nil {
-		if err == ErrNotAvailable {
+		if err == ErrNotAvailable || err == ErrReadOnlyCluster {
 			return nil, err
 		}
 		return nil, ErrCredentialFetch
@@ -124,7 +136,7 @@ func newRequestProxyer(vzmgr vzmgrClient, nc *nats.Conn, debugMode bool, r Clust
 	return p, nil
 }
 
-func (p requestProxyer) validateRequestAndFetchCreds(ctx context.Context, debugMode bool, vzmgr vzmgrClient) (string, error) {
+func (p requestProxyer) validateRequestAndFetchCreds(ctx context.Context, debugMode bool, mutation bool, vzmgr vzmgrClient) (string, error) {
 	var signingKey string
 	clusterIDProto := utils.ProtoFromUUID(p.clusterID)
 
@@ -137,6 +149,9 @@ func (p requestProxyer) validateRequestAndFetchCreds(ctx context.Context, debugM
 			}
 			return err
 		}
+		if mutation && resp.ReadOnly {
+			return ErrReadOnlyCluster
+		}
 		if debugMode {
 			if resp.Status == cvmsgspb.VZ_ST_DISCONNECTED {
 				return ErrNotAvailable
@@ -299,6 +314,11 @@ func (p *requestProxyer) processNatsMsg(msg *nats.Msg) error {
 		if err != nil {
 			return fmt.Errorf("Failed to send DebugPodsResp message: %w", err)
 		}
+	case *cvmsgspb.V2CAPIStreamResponse_SelfTestResp:
+		err = p.srv.SendMsg(parsed.SelfTestResp)
+		if err != nil {
+			return fmt.Errorf("Failed to send SelfTestResp message: %w", err)
+		}
 	case *cvmsgspb.V2CAPIStreamResponse_Status:
 		// Status message come when the stream is closed.
 		if codes.Code(parsed.Status.Code) == codes.OK {
@@ -21,11 +21,15 @@ package ptproxy
 import (
 	"context"
 
+	"github.com/gofrs/uuid"
 	"github.com/nats-io/nats.go"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"px.dev/pixie/src/api/proto/uuidpb"
 	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/cloud/shared/metering"
 	"px.dev/pixie/src/shared/cvmsgspb"
 	"px.dev/pixie/src/shared/services/authcontext"
 	"px.dev/pixie/src/shared/services/jwtpb"
@@ -41,6 +45,9 @@ type vzmgrClient interface {
 type VizierPassThroughProxy struct {
 	nc *nats.Conn
 	vc vzmgrClient
+
+	meter  metering.Meter
+	quotas metering.QuotaChecker
 }
 
 // NewVizierPassThroughProxy creates a new passthrough proxy.
@@ -48,20 +55,78 @@ func NewVizierPassThroughProxy(nc *nats.Conn, vc vzmgrClient) *VizierPassThrough
 	return &VizierPassThroughProxy{nc: nc, vc: vc}
 }
 
+// EnableMetering meters the scripts executed through the proxy, and rejects scripts from orgs that
+// have used up their quotas. The quota checker is optional.
+func (v *VizierPassThroughProxy) EnableMetering(meter metering.Meter, quotas metering.QuotaChecker) {
+	v.meter = meter
+	v.quotas = quotas
+}
+
+// meteredExecuteScriptServer counts the bytes processed by a script as its results are streamed.
+type meteredExecuteScriptServer struct {
+	vizierpb.VizierService_ExecuteScriptServer
+	bytesProcessed int64
+}
+
+func (m *meteredExecuteScriptServer) SendMsg(data interface{}) error {
+	if resp, ok := data.(*vizierpb.ExecuteScriptResponse); ok {
+		m.bytesProcessed += resp.GetData().GetExecutionStats().GetBytesProcessed()
+	}
+	return m.VizierService_ExecuteScriptServer.SendMsg(data)
+}
+
+func (v *VizierPassThroughProxy) checkQuotas(ctx context.Context, orgID uuid.UUID) error {
+	if v.quotas == nil {
+		return nil
+	}
+	for _, metric := range []metering.Metric{metering.MetricQueryExecutions, metering.MetricBytesScanned} {
+		if err := v.quotas.Check(ctx, orgID, metric); err != nil {
+			return status.Error(codes.ResourceExhausted, err.Error())
+		}
+	}
+	return nil
+}
+
 // ExecuteScript is the GRPC stream method.
 func (v *VizierPassThroughProxy) ExecuteScript(req *vizierpb.ExecuteScriptRequest, srv vizierpb.VizierService_ExecuteScriptServer) error {
-	rp, err := newRequestProxyer(v.vc, v.nc, false, req, srv)
+	if v.meter == nil {
+		_, err := v.executeScript(req, srv)
+		return err
+	}
+
+	_, claims, err := getCredsFromCtx(srv.Context())
 	if err != nil {
 		return err
 	}
+	orgID := uuid.FromStringOrNil(claims.GetUserClaims().GetOrgID())
+	if err := v.checkQuotas(srv.Context(), orgID); err != nil {
+		return err
+	}
+	metered := &meteredExecuteScriptServer{VizierService_ExecuteScriptServer: srv}
+	sent, err := v.executeScript(req, metered)
+	// Scripts that were never sent to the cluster aren't metered.
+	if !sent {
+		return err
+	}
+	clusterID := uuid.FromStringOrNil(req.ClusterID)
+	v.meter.Record(srv.Context(), metering.NewUsage(orgID, clusterID, metering.MetricQueryExecutions, 1))
+	v.meter.Record(srv.Context(), metering.NewUsage(orgID, clusterID, metering.MetricBytesScanned, metered.bytesProcessed))
+	return err
+}
+
+func (v *VizierPassThroughProxy) executeScript(req *vizierpb.ExecuteScriptRequest, srv vizierpb.VizierService_ExecuteScriptServer) (bool, error) {
+	rp, err := newRequestProxyer(v.vc, v.nc, false, req, srv)
+	if err != nil {
+		return false, err
+	}
 	defer rp.Finish()
 	vizReq := rp.prepareVizierRequest()
 	vizReq.Msg = &cvmsgspb.C2VAPIStreamRequest_ExecReq{ExecReq: req}
 	if err := rp.sendMessageToVizier(vizReq); err != nil {
-		return err
+		return false, err
 	}
 
-	return rp.Run()
+	return true, rp.Run()
 }
 
 // HealthCheck is the GRPC stream method.
@@ -147,6 +212,21 @@ func (v *VizierPassThroughProxy) DebugPods(req *vizierpb.DebugPodsRequest, srv v
 	return rp.Run()
 }
 
+// SelfTest is the GRPC stream method to check the connectivity of the Vizier components to their dependencies.
+func (v *VizierPassThroughProxy) SelfTest(req *vizierpb.SelfTestRequest, srv vizierpb.VizierDebugService_SelfTestServer) error {
+	rp, err := newRequestProxyer(v.vc, v.nc, true, req, srv)
+	if err != nil {
+		return err
+	}
+	defer rp.Finish()
+	vizReq := rp.prepareVizierRequest()
+	vizReq.Msg = &cvmsgspb.C2VAPIStreamRequest_SelfTestReq{SelfTestReq: req}
+	if err := rp.sendMessageToVizier(vizReq); err != nil {
+		return err
+	}
+	return rp.Run()
+}
+
 func getCredsFromCtx(ctx context.Context) (string, *jwtpb.JWTClaims, error) {
 	aCtx, err := authcontext.FromContext(ctx)
 	if err != nil {
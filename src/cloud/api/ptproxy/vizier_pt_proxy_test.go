@@ -20,6 +20,7 @@ package ptproxy_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -44,6 +45,7 @@ import (
 	"px.dev/pixie/src/api/proto/uuidpb"
 	"px.dev/pixie/src/api/proto/vizierpb"
 	"px.dev/pixie/src/cloud/api/ptproxy"
+	"px.dev/pixie/src/cloud/shared/metering"
 	"px.dev/pixie/src/cloud/shared/vzshard"
 	"px.dev/pixie/src/shared/cvmsgspb"
 	"px.dev/pixie/src/shared/services/env"
@@ -66,13 +68,20 @@ type testState struct {
 }
 
 func createTestState(t *testing.T) (*testState, func(t *testing.T)) {
+	return createTestStateWithProxy(t, func(p *ptproxy.VizierPassThroughProxy) {})
+}
+
+// createTestStateWithProxy is createTestState, but lets the test configure the proxies.
+func createTestStateWithProxy(t *testing.T, configure func(p *ptproxy.VizierPassThroughProxy)) (*testState, func(t *testing.T)) {
 	lis := bufconn.Listen(bufSize)
 	env := env.New("withpixie.ai")
 	s := server.CreateGRPCServer(env, &server.GRPCServerOptions{})
 
 	nc, natsCleanup := testingutils.MustStartTestNATS(t)
 
-	vizierpb.RegisterVizierServiceServer(s, ptproxy.NewVizierPassThroughProxy(nc, &fakeVzMgr{}))
+	vpt := ptproxy.NewVizierPassThroughProxy(nc, &fakeVzMgr{})
+	configure(vpt)
+	vizierpb.RegisterVizierServiceServer(s, vpt)
 	vizierpb.RegisterVizierDebugServiceServer(s, ptproxy.NewVizierPassThroughProxy(nc, &fakeVzMgr{}))
 
 	eg := errgroup.Group{}
@@ -123,6 +132,7 @@ func TestVizierPassThroughProxy_ExecuteScript(t *testing.T) {
 
 		clusterID      string
 		authToken      string
+		mutation       bool
 		respFromVizier []*cvmsgspb.V2CAPIStreamResponse
 
 		expGRPCError     error
@@ -200,6 +210,32 @@ func TestVizierPassThroughProxy_ExecuteScript(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Read-only cluster",
+
+			clusterID: "40000000-1111-2222-2222-333333333333",
+			authToken: validTestToken,
+			respFromVizier: []*cvmsgspb.V2CAPIStreamResponse{
+				{
+					Msg: &cvmsgspb.V2CAPIStreamResponse_ExecResp{ExecResp: &vizierpb.ExecuteScriptResponse{QueryID: "abc"}},
+				},
+			},
+
+			expGRPCResponses: []*vizierpb.ExecuteScriptResponse{
+				{
+					QueryID: "abc",
+				},
+			},
+		},
+		{
+			name: "Mutation on read-only cluster",
+
+			clusterID: "40000000-1111-2222-2222-333333333333",
+			authToken: validTestToken,
+			mutation:  true,
+
+			expGRPCError: ptproxy.ErrReadOnlyCluster,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -213,7 +249,7 @@ func TestVizierPassThroughProxy_ExecuteScript(t *testing.T) {
 			ctx, cancel := context.WithCancel(ctx)
 			defer cancel()
 			resp, err := client.ExecuteScript(ctx,
-				&vizierpb.ExecuteScriptRequest{ClusterID: tc.clusterID})
+				&vizierpb.ExecuteScriptRequest{ClusterID: tc.clusterID, Mutation: tc.mutation})
 			require.NoError(t, err)
 
 			fv := newFakeVizier(t, uuid.FromStringOrNil(tc.clusterID), ts.nc)
@@ -283,6 +319,111 @@ func TestVizierPassThroughProxy_ExecuteScript(t *testing.T) {
 	}
 }
 
+type fakeMeter struct {
+	mu    sync.Mutex
+	usage []*metering.Usage
+}
+
+func (m *fakeMeter) Record(ctx context.Context, u *metering.Usage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.usage = append(m.usage, u)
+}
+
+type fakeQuotaChecker struct {
+	err error
+}
+
+func (q *fakeQuotaChecker) Check(ctx context.Context, orgID uuid.UUID, metric metering.Metric) error {
+	return q.err
+}
+
+func TestVizierPassThroughProxy_ExecuteScriptMetering(t *testing.T) {
+	viper.Set("jwt_signing_key", "the-key")
+	clusterID := uuid.FromStringOrNil("00000000-1111-2222-2222-333333333333")
+
+	testCases := []struct {
+		name     string
+		quotaErr error
+
+		expCode  codes.Code
+		expUsage map[metering.Metric]int64
+	}{
+		{
+			name: "Metered",
+
+			expCode: codes.OK,
+			expUsage: map[metering.Metric]int64{
+				metering.MetricQueryExecutions: 1,
+				metering.MetricBytesScanned:    300,
+			},
+		},
+		{
+			name:     "Quota exceeded",
+			quotaErr: errors.New("bytes_scanned quota exceeded"),
+
+			expCode: codes.ResourceExhausted,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			meter := &fakeMeter{}
+			ts, cleanup := createTestStateWithProxy(t, func(p *ptproxy.VizierPassThroughProxy) {
+				p.EnableMetering(meter, &fakeQuotaChecker{err: tc.quotaErr})
+			})
+			defer cleanup(t)
+
+			client := vizierpb.NewVizierServiceClient(ts.conn)
+			ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization",
+				fmt.Sprintf("bearer %s", testingutils.GenerateTestJWTToken(t, viper.GetString("jwt_signing_key"))))
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
+			resp, err := client.ExecuteScript(ctx, &vizierpb.ExecuteScriptRequest{ClusterID: clusterID.String()})
+			require.NoError(t, err)
+
+			withStats := func(bytes int64) *cvmsgspb.V2CAPIStreamResponse {
+				return &cvmsgspb.V2CAPIStreamResponse{
+					Msg: &cvmsgspb.V2CAPIStreamResponse_ExecResp{ExecResp: &vizierpb.ExecuteScriptResponse{
+						Result: &vizierpb.ExecuteScriptResponse_Data{Data: &vizierpb.QueryData{
+							ExecutionStats: &vizierpb.QueryExecutionStats{BytesProcessed: bytes},
+						}},
+					}},
+				}
+			}
+			fv := newFakeVizier(t, clusterID, ts.nc)
+			fv.Run(t, []*cvmsgspb.V2CAPIStreamResponse{withStats(100), withStats(200)})
+			defer fv.Stop()
+
+			for {
+				_, err = resp.Recv()
+				if err != nil {
+					break
+				}
+			}
+			if err == io.EOF {
+				err = nil
+			}
+			assert.Equal(t, tc.expCode, status.Code(err))
+
+			meter.mu.Lock()
+			defer meter.mu.Unlock()
+			usage := make(map[metering.Metric]int64)
+			for _, u := range meter.usage {
+				assert.Equal(t, testingutils.TestOrgID, u.OrgID.String())
+				assert.Equal(t, clusterID, u.ClusterID)
+				usage[u.Metric] += u.Value
+			}
+			if tc.expUsage == nil {
+				assert.Len(t, usage, 0)
+			} else {
+				assert.Equal(t, tc.expUsage, usage)
+			}
+		})
+	}
+}
+
 func TestVizierPassThroughProxy_HealthCheck(t *testing.T) {
 	viper.Set("jwt_signing_key", "the-key")
 
@@ -719,6 +860,152 @@ func TestVizierPassThroughProxy_DebugPods(t *testing.T) {
 	}
 }
 
+func TestVizierPassThroughProxy_SelfTest(t *testing.T) {
+	viper.Set("jwt_signing_key", "the-key")
+
+	ts, cleanup := createTestState(t)
+	defer cleanup(t)
+
+	client := vizierpb.NewVizierDebugServiceClient(ts.conn)
+	validTestToken := testingutils.GenerateTestJWTToken(t, viper.GetString("jwt_signing_key"))
+
+	testCases := []struct {
+		name string
+
+		clusterID      string
+		authToken      string
+		respFromVizier []*cvmsgspb.V2CAPIStreamResponse
+
+		expGRPCError     error
+		expGRPCResponses []*vizierpb.SelfTestResponse
+	}{
+		{
+			name: "Normal Stream",
+
+			clusterID: "00000000-1111-2222-2222-333333333333",
+			authToken: validTestToken,
+			respFromVizier: []*cvmsgspb.V2CAPIStreamResponse{
+				{
+					Msg: &cvmsgspb.V2CAPIStreamResponse_SelfTestResp{
+						SelfTestResp: &vizierpb.SelfTestResponse{
+							Component: "cloud-connector",
+							Checks: []*vizierpb.DependencyCheck{
+								{
+									Name: "cloud",
+									OK:   true,
+								},
+							},
+						},
+					},
+				},
+				{
+					Msg: &cvmsgspb.V2CAPIStreamResponse_SelfTestResp{
+						SelfTestResp: &vizierpb.SelfTestResponse{
+							Component: "metadata",
+							Error:     "connection refused",
+						},
+					},
+				},
+			},
+
+			expGRPCError: nil,
+			expGRPCResponses: []*vizierpb.SelfTestResponse{
+				{
+					Component: "cloud-connector",
+					Checks: []*vizierpb.DependencyCheck{
+						{
+							Name: "cloud",
+							OK:   true,
+						},
+					},
+				},
+				{
+					Component: "metadata",
+					Error:     "connection refused",
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if len(tc.authToken) > 0 {
+				ctx = metadata.AppendToOutgoingContext(ctx, "authorization",
+					fmt.Sprintf("bearer %s", tc.authToken))
+			}
+
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			resp, err := client.SelfTest(ctx,
+				&vizierpb.SelfTestRequest{ClusterID: tc.clusterID})
+			assert.Nil(t, err)
+
+			fv := newFakeVizier(t, uuid.FromStringOrNil(tc.clusterID), ts.nc)
+			fv.Run(t, tc.respFromVizier)
+			defer fv.Stop()
+
+			grpcDataCh := make(chan *vizierpb.SelfTestResponse)
+			var gotReadErr error
+			var eg errgroup.Group
+			eg.Go(func() error {
+				defer close(grpcDataCh)
+				for {
+					d, err := resp.Recv()
+					if err != nil && err != io.EOF {
+						gotReadErr = err
+					}
+					if err == io.EOF {
+						return nil
+					}
+					if d == nil {
+						return nil
+					}
+					grpcDataCh <- d
+				}
+			})
+
+			var responses []*vizierpb.SelfTestResponse
+			eg.Go(func() error {
+				timeout := time.NewTimer(defaultTimeout)
+				defer timeout.Stop()
+				for {
+					select {
+					case <-resp.Context().Done():
+						return nil
+					case <-timeout.C:
+						return fmt.Errorf("timeout")
+					case msg := <-grpcDataCh:
+						if msg == nil {
+							return nil
+						}
+						responses = append(responses, msg)
+					}
+				}
+			})
+
+			err = eg.Wait()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.expGRPCError != nil {
+				if gotReadErr == nil {
+					t.Fatal("Expected to get GRPC error")
+				}
+				assert.Equal(t, status.Code(tc.expGRPCError), status.Code(gotReadErr))
+			}
+			if tc.expGRPCResponses == nil {
+				if len(responses) != 0 {
+					t.Fatal("Expected to get no responses")
+				}
+			} else {
+				assert.Equal(t, tc.expGRPCResponses, responses)
+			}
+		})
+	}
+}
+
 type fakeVzMgr struct{}
 
 func (v *fakeVzMgr) GetVizierInfo(ctx context.Context, in *uuidpb.UUID, opts ...grpc.CallOption) (*cvmsgspb.VizierInfo, error) {
@@ -762,6 +1049,16 @@ func (v *fakeVzMgr) GetVizierInfo(ctx context.Context, in *uuidpb.UUID, opts ...
 			},
 			nil,
 		},
+		"40000000-1111-2222-2222-333333333333": {
+			&cvmsgspb.VizierInfo{
+				VizierID:        utils.ProtoFromUUIDStrOrNil("40000000-1111-2222-2222-333333333333"),
+				Status:          cvmsgspb.VZ_ST_HEALTHY,
+				LastHeartbeatNs: 0,
+				Config:          &cvmsgspb.VizierConfig{},
+				ReadOnly:        true,
+			},
+			nil,
+		},
 	}
 
 	u := utils.UUIDFromProtoOrNil(in)
@@ -801,6 +1098,12 @@ func (v *fakeVzMgr) GetVizierConnectionInfo(ctx context.Context, in *uuidpb.UUID
 			},
 			nil,
 		},
+		"40000000-1111-2222-2222-333333333333": {
+			&cvmsgspb.VizierConnectionInfo{
+				Token: "abc4",
+			},
+			nil,
+		},
 	}
 	u := utils.UUIDFromProtoOrNil(in)
 	results, ok := bakedResponses[u.String()]
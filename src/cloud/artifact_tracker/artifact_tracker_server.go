@@ -25,18 +25,14 @@ import (
 	"os"
 	"time"
 
-	"cloud.google.com/go/storage"
-	"github.com/googleapis/google-cloud-go-testing/storage/stiface"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
-	"golang.org/x/oauth2/google"
-	"golang.org/x/oauth2/jwt"
-	"google.golang.org/api/option"
 
 	"px.dev/pixie/src/cloud/artifact_tracker/artifacttrackerenv"
 	atpb "px.dev/pixie/src/cloud/artifact_tracker/artifacttrackerpb"
 	"px.dev/pixie/src/cloud/artifact_tracker/controllers"
+	"px.dev/pixie/src/cloud/shared/objstore"
 	"px.dev/pixie/src/shared/artifacts/manifest"
 	"px.dev/pixie/src/shared/services"
 	"px.dev/pixie/src/shared/services/healthz"
@@ -45,7 +41,7 @@ import (
 
 func init() {
 	pflag.String("artifact_bucket", "pl-artifacts", "The name of the artifact bucket.")
-	pflag.String("sa_key_path", "/creds/service_account.json", "The path to the service account JSON file.")
+	pflag.String("sa_key_path", "/creds/service_account.json", "The path to the GCS service account JSON file.")
 	pflag.String("vizier_version", "", "If specified, the db will not be queried. The only vizier version is assumed to be the one specified.")
 	pflag.String("cli_version", "", "If specified, the db will not be queried. The only CLI version is assumed to be the one specified.")
 	pflag.String("operator_version", "", "If specified, the db will not be queried. The only operator version is assumed to be the one specified.")
@@ -55,23 +51,9 @@ func init() {
 	pflag.Duration("manifest_poll_period", 1*time.Minute, "Specify how often to poll for manifest changes")
 }
 
-func loadServiceAccountConfig() *jwt.Config {
-	saKeyFile := viper.GetString("sa_key_path")
-	saKey, err := os.ReadFile(saKeyFile)
-
-	if err != nil {
-		return nil
-	}
-
-	saCfg, err := google.JWTConfigFromJSON(saKey)
-	if err != nil {
-		return nil
-	}
-	return saCfg
-}
-
 func main() {
 	services.SetupService("artifact-tracker-service", 50750)
+	objstore.SetupFlags()
 	services.PostFlagSetupAndParse()
 	services.CheckServiceFlags()
 	services.SetupServiceLogging()
@@ -81,25 +63,19 @@ func main() {
 	mux.Handle("/debug/", http.DefaultServeMux)
 	healthz.RegisterDefaultChecks(mux)
 
-	saCfg := loadServiceAccountConfig()
-
-	ctx := context.Background()
-	var client *storage.Client
-	var err error
-
-	if saCfg != nil {
-		client, err = storage.NewClient(ctx, option.WithCredentialsFile(viper.GetString("sa_key_path")))
-	} else {
-		client, err = storage.NewClient(ctx, option.WithoutAuthentication())
+	storeCfg := objstore.ConfigFromFlags(viper.GetString("artifact_bucket"), "")
+	// Public GCS buckets are read anonymously when there is no service account.
+	if _, err := os.Stat(viper.GetString("sa_key_path")); err == nil {
+		storeCfg.CredentialsFile = viper.GetString("sa_key_path")
 	}
+	store, err := objstore.New(context.Background(), storeCfg)
 	if err != nil {
-		log.WithError(err).Fatal("Failed to initialize GCS client.")
+		log.WithError(err).Fatal("Failed to initialize artifact storage.")
 	}
 
 	env := artifacttrackerenv.New()
 
-	bucket := viper.GetString("artifact_bucket")
-	svr := controllers.NewServer(stiface.AdaptClient(client), bucket, saCfg)
+	svr := controllers.NewServer(store)
 
 	// If any versions are not hardcoded, then we need to poll for the artifact manifest.
 	if (viper.GetString("vizier_version") == "") || (viper.GetString("cli_version") == "") || (viper.GetString("operator_version") == "") {
@@ -0,0 +1,155 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/jsonpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	apb "px.dev/pixie/src/cloud/artifact_tracker/artifacttrackerpb"
+	"px.dev/pixie/src/cloud/shared/objstore"
+	"px.dev/pixie/src/utils"
+)
+
+const (
+	// brandingPrefix is the path in the artifact bucket that the branding of each org is stored under, as
+	// <org_id>.json. The default branding of the deployment is in default.json.
+	brandingPrefix = "branding"
+	// maxAnnouncementLength is the longest announcement that an org may set, so that it fits in a banner.
+	maxAnnouncementLength = 512
+)
+
+func orgBrandingKey(orgID uuid.UUID) string {
+	return path.Join(brandingPrefix, orgID.String()+".json")
+}
+
+func defaultBrandingKey() string {
+	return path.Join(brandingPrefix, "default.json")
+}
+
+// readBranding reads the branding at the key. It returns empty branding if none has been set.
+func (s *Server) readBranding(ctx context.Context, key string) (*apb.OrgBranding, error) {
+	r, err := s.store.Get(ctx, key)
+	if errors.Is(err, objstore.ErrNotFound) {
+		return &apb.OrgBranding{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	branding := &apb.OrgBranding{}
+	if err := jsonpb.Unmarshal(r, branding); err != nil {
+		return nil, fmt.Errorf("failed to parse branding %s: %w", key, err)
+	}
+	return branding, nil
+}
+
+// withDefaults fills in the fields of the branding that an org hasn't set from the defaults.
+func withDefaults(branding, defaults *apb.OrgBranding) *apb.OrgBranding {
+	res := *branding
+	// An announcement and its link only make sense together.
+	if res.Announcement == "" {
+		res.Announcement = defaults.Announcement
+		res.AnnouncementURL = defaults.AnnouncementURL
+	}
+	if res.DocsURL == "" {
+		res.DocsURL = defaults.DocsURL
+	}
+	if res.SupportContact == "" {
+		res.SupportContact = defaults.SupportContact
+	}
+	return &res
+}
+
+func validateBrandingURL(field, u string) error {
+	if u == "" {
+		return nil
+	}
+	parsed, err := url.Parse(u)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return status.Errorf(codes.InvalidArgument, "%s must be an http or https URL", field)
+	}
+	return nil
+}
+
+func validateBranding(branding *apb.OrgBranding) error {
+	if len(branding.Announcement) > maxAnnouncementLength {
+		return status.Errorf(codes.InvalidArgument, "announcement cannot be longer than %d characters", maxAnnouncementLength)
+	}
+	if branding.AnnouncementURL != "" && branding.Announcement == "" {
+		return status.Error(codes.InvalidArgument, "announcement_url requires an announcement")
+	}
+	if err := validateBrandingURL("announcement_url", branding.AnnouncementURL); err != nil {
+		return err
+	}
+	return validateBrandingURL("docs_url", branding.DocsURL)
+}
+
+// GetOrgBranding returns the branding of the org, with the default branding of the deployment for any fields
+// that the org hasn't set.
+func (s *Server) GetOrgBranding(ctx context.Context, in *apb.GetOrgBrandingRequest) (*apb.OrgBranding, error) {
+	orgID := utils.UUIDFromProtoOrNil(in.OrgID)
+	if orgID == uuid.Nil {
+		return nil, status.Error(codes.InvalidArgument, "org ID cannot be empty")
+	}
+
+	branding, err := s.readBranding(ctx, orgBrandingKey(orgID))
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to read org branding")
+	}
+	defaults, err := s.readBranding(ctx, defaultBrandingKey())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to read default branding")
+	}
+	return withDefaults(branding, defaults), nil
+}
+
+// UpdateOrgBranding replaces the branding of the org, and returns the branding that the org now has.
+func (s *Server) UpdateOrgBranding(ctx context.Context, in *apb.UpdateOrgBrandingRequest) (*apb.OrgBranding, error) {
+	orgID := utils.UUIDFromProtoOrNil(in.OrgID)
+	if orgID == uuid.Nil {
+		return nil, status.Error(codes.InvalidArgument, "org ID cannot be empty")
+	}
+	branding := in.Branding
+	if branding == nil {
+		branding = &apb.OrgBranding{}
+	}
+	if err := validateBranding(branding); err != nil {
+		return nil, err
+	}
+
+	m := jsonpb.Marshaler{}
+	data, err := m.MarshalToString(branding)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to encode org branding")
+	}
+	if err := s.store.Put(ctx, orgBrandingKey(orgID), []byte(data), "application/json"); err != nil {
+		return nil, status.Error(codes.Internal, "failed to write org branding")
+	}
+	return s.GetOrgBranding(ctx, &apb.GetOrgBrandingRequest{OrgID: in.OrgID})
+}
@@ -0,0 +1,182 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	apb "px.dev/pixie/src/cloud/artifact_tracker/artifacttrackerpb"
+	"px.dev/pixie/src/cloud/artifact_tracker/controllers"
+	"px.dev/pixie/src/cloud/shared/objstore"
+	"px.dev/pixie/src/utils"
+)
+
+// memStore is an objstore.Store that keeps its objects in memory.
+type memStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newMemStore(objects map[string]string) *memStore {
+	s := &memStore{objects: make(map[string][]byte)}
+	for k, v := range objects {
+		s.objects[k] = []byte(v)
+	}
+	return s
+}
+
+func (s *memStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, objstore.ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *memStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = data
+	return nil
+}
+
+func (s *memStore) Attrs(ctx context.Context, key string) (*objstore.Attrs, error) {
+	return nil, objstore.ErrNotFound
+}
+
+func (s *memStore) DownloadURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	return "", objstore.ErrNotFound
+}
+
+func TestServer_GetOrgBranding(t *testing.T) {
+	orgID := uuid.Must(uuid.NewV4())
+	otherOrgID := uuid.Must(uuid.NewV4())
+
+	store := newMemStore(map[string]string{
+		"branding/default.json":                `{"announcement": "Maintenance on Friday", "announcementUrl": "https://status.example.com", "supportContact": "#pixie-help"}`,
+		"branding/" + orgID.String() + ".json": `{"docsUrl": "https://docs.example.com"}`,
+	})
+	s := controllers.NewServer(store)
+
+	resp, err := s.GetOrgBranding(context.Background(), &apb.GetOrgBrandingRequest{OrgID: utils.ProtoFromUUID(orgID)})
+	require.NoError(t, err)
+	assert.Equal(t, &apb.OrgBranding{
+		Announcement:    "Maintenance on Friday",
+		AnnouncementURL: "https://status.example.com",
+		DocsURL:         "https://docs.example.com",
+		SupportContact:  "#pixie-help",
+	}, resp)
+
+	resp, err = s.GetOrgBranding(context.Background(), &apb.GetOrgBrandingRequest{OrgID: utils.ProtoFromUUID(otherOrgID)})
+	require.NoError(t, err)
+	assert.Equal(t, &apb.OrgBranding{
+		Announcement:    "Maintenance on Friday",
+		AnnouncementURL: "https://status.example.com",
+		SupportContact:  "#pixie-help",
+	}, resp)
+}
+
+func TestServer_GetOrgBranding_NoBranding(t *testing.T) {
+	s := controllers.NewServer(newMemStore(nil))
+
+	resp, err := s.GetOrgBranding(context.Background(), &apb.GetOrgBrandingRequest{
+		OrgID: utils.ProtoFromUUID(uuid.Must(uuid.NewV4())),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &apb.OrgBranding{}, resp)
+
+	_, err = s.GetOrgBranding(context.Background(), &apb.GetOrgBrandingRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServer_UpdateOrgBranding(t *testing.T) {
+	orgID := uuid.Must(uuid.NewV4())
+	store := newMemStore(map[string]string{
+		"branding/default.json": `{"announcement": "Maintenance on Friday", "supportContact": "#pixie-help"}`,
+	})
+	s := controllers.NewServer(store)
+
+	resp, err := s.UpdateOrgBranding(context.Background(), &apb.UpdateOrgBrandingRequest{
+		OrgID: utils.ProtoFromUUID(orgID),
+		Branding: &apb.OrgBranding{
+			Announcement:    "Pixie is moving to a new cluster",
+			AnnouncementURL: "https://wiki.example.com/pixie",
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &apb.OrgBranding{
+		Announcement:    "Pixie is moving to a new cluster",
+		AnnouncementURL: "https://wiki.example.com/pixie",
+		SupportContact:  "#pixie-help",
+	}, resp)
+
+	resp, err = s.GetOrgBranding(context.Background(), &apb.GetOrgBrandingRequest{OrgID: utils.ProtoFromUUID(orgID)})
+	require.NoError(t, err)
+	assert.Equal(t, "Pixie is moving to a new cluster", resp.Announcement)
+}
+
+func TestServer_UpdateOrgBranding_Invalid(t *testing.T) {
+	orgID := utils.ProtoFromUUID(uuid.Must(uuid.NewV4()))
+	s := controllers.NewServer(newMemStore(nil))
+
+	tests := []struct {
+		name     string
+		branding *apb.OrgBranding
+	}{
+		{
+			name:     "non-http docs URL",
+			branding: &apb.OrgBranding{DocsURL: "javascript:alert(1)"},
+		},
+		{
+			name:     "relative announcement URL",
+			branding: &apb.OrgBranding{Announcement: "hi", AnnouncementURL: "/status"},
+		},
+		{
+			name:     "announcement URL without an announcement",
+			branding: &apb.OrgBranding{AnnouncementURL: "https://status.example.com"},
+		},
+		{
+			name:     "long announcement",
+			branding: &apb.OrgBranding{Announcement: string(bytes.Repeat([]byte("a"), 513))},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := s.UpdateOrgBranding(context.Background(), &apb.UpdateOrgBrandingRequest{
+				OrgID:    orgID,
+				Branding: test.branding,
+			})
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+}
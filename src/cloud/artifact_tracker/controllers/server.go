@@ -26,23 +26,18 @@ import (
 	"strings"
 	"time"
 
-	"cloud.google.com/go/storage"
 	"github.com/gogo/protobuf/types"
-	"github.com/googleapis/google-cloud-go-testing/storage/stiface"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
-	"golang.org/x/oauth2/jwt"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	apb "px.dev/pixie/src/cloud/artifact_tracker/artifacttrackerpb"
+	"px.dev/pixie/src/cloud/shared/objstore"
 	"px.dev/pixie/src/shared/artifacts/manifest"
 	vpb "px.dev/pixie/src/shared/artifacts/versionspb"
 )
 
-// URLSigner is the function used to sign urls.
-var URLSigner = storage.SignedURL
-
 const (
 	vizierArtifactName   = "vizier"
 	cliArtifactName      = "cli"
@@ -51,15 +46,13 @@ const (
 
 // Server is the controller for the artifact tracker service.
 type Server struct {
-	sc             stiface.Client
-	artifactBucket string
-	gcsSA          *jwt.Config
-	m              *manifest.ArtifactManifest
+	store objstore.Store
+	m     *manifest.ArtifactManifest
 }
 
 // NewServer creates a new artifact tracker server.
-func NewServer(client stiface.Client, bucket string, gcsSA *jwt.Config) *Server {
-	return &Server{sc: client, artifactBucket: bucket, gcsSA: gcsSA}
+func NewServer(store objstore.Store) *Server {
+	return &Server{store: store}
 }
 
 func (s *Server) getArtifactListSpecifiedVizier() (*vpb.ArtifactSet, error) {
@@ -72,6 +65,7 @@ func (s *Server) getArtifactListSpecifiedVizier() (*vpb.ArtifactSet, error) {
 					vpb.AT_CONTAINER_SET_YAMLS,
 					vpb.AT_CONTAINER_SET_TEMPLATE_YAMLS,
 					vpb.AT_CONTAINER_SET_LINUX_AMD64,
+					vpb.AT_CONTAINER_SET_LINUX_ARM64,
 				},
 			},
 		},
@@ -87,6 +81,7 @@ func (s *Server) getArtifactListSpecifiedCLI() (*vpb.ArtifactSet, error) {
 				AvailableArtifacts: []vpb.ArtifactType{
 					vpb.AT_LINUX_AMD64,
 					vpb.AT_DARWIN_AMD64,
+					vpb.AT_DARWIN_ARM64,
 				},
 			},
 		},
@@ -102,6 +97,7 @@ func (s *Server) getArtifactListSpecifiedOperator() (*vpb.ArtifactSet, error) {
 				AvailableArtifacts: []vpb.ArtifactType{
 					vpb.AT_CONTAINER_SET_TEMPLATE_YAMLS,
 					vpb.AT_CONTAINER_SET_LINUX_AMD64,
+					vpb.AT_CONTAINER_SET_LINUX_ARM64,
 					vpb.AT_CONTAINER_SET_YAMLS,
 				},
 			},
@@ -147,6 +143,10 @@ func downloadSuffix(at vpb.ArtifactType) string {
 		return "linux_amd64"
 	case vpb.AT_DARWIN_AMD64:
 		return "darwin_amd64"
+	case vpb.AT_LINUX_ARM64:
+		return "linux_arm64"
+	case vpb.AT_DARWIN_ARM64:
+		return "darwin_arm64"
 	case vpb.AT_CONTAINER_SET_YAMLS:
 		return "yamls.tar"
 	case vpb.AT_CONTAINER_SET_TEMPLATE_YAMLS:
@@ -155,6 +155,15 @@ func downloadSuffix(at vpb.ArtifactType) string {
 	return "unknown"
 }
 
+// isCLIArtifactType returns whether the artifact type is a CLI binary.
+func isCLIArtifactType(at vpb.ArtifactType) bool {
+	switch at {
+	case vpb.AT_LINUX_AMD64, vpb.AT_DARWIN_AMD64, vpb.AT_LINUX_ARM64, vpb.AT_DARWIN_ARM64:
+		return true
+	}
+	return false
+}
+
 // GetDownloadLink returns a signed download link that can be used to download the artifact.
 func (s *Server) GetDownloadLink(ctx context.Context, in *apb.GetDownloadLinkRequest) (*apb.GetDownloadLinkResponse, error) {
 	versionStr := in.VersionStr
@@ -173,7 +182,7 @@ func (s *Server) GetDownloadLink(ctx context.Context, in *apb.GetDownloadLinkReq
 		return nil, status.Error(codes.InvalidArgument, "artifact type cannot be unknown")
 	}
 
-	if !(at == vpb.AT_DARWIN_AMD64 || at == vpb.AT_LINUX_AMD64 || at == vpb.AT_CONTAINER_SET_YAMLS || at == vpb.AT_CONTAINER_SET_TEMPLATE_YAMLS) {
+	if !(isCLIArtifactType(at) || at == vpb.AT_CONTAINER_SET_YAMLS || at == vpb.AT_CONTAINER_SET_TEMPLATE_YAMLS) {
 		return nil, status.Error(codes.InvalidArgument, "artifact type cannot be downloaded")
 	}
 
@@ -187,7 +196,7 @@ func (s *Server) GetDownloadLink(ctx context.Context, in *apb.GetDownloadLinkReq
 		if versionStr != viper.GetString("operator_version") {
 			return nil, status.Error(codes.NotFound, "artifact not found")
 		}
-	} else if name == cliArtifactName && (at == vpb.AT_DARWIN_AMD64 || at == vpb.AT_LINUX_AMD64) && viper.GetString("cli_version") != "" {
+	} else if name == cliArtifactName && isCLIArtifactType(at) && viper.GetString("cli_version") != "" {
 		if versionStr != viper.GetString("cli_version") {
 			return nil, status.Error(codes.NotFound, "artifact not found")
 		}
@@ -216,23 +225,21 @@ func (s *Server) GetDownloadLink(ctx context.Context, in *apb.GetDownloadLinkReq
 		}
 	}
 
-	expires := time.Now().Add(time.Minute * 60)
+	validFor := time.Minute * 60
+	expires := time.Now().Add(validFor)
 
 	// Artifact found, generate the download link.
-	// location: gs://<artifact_bucket>/cli/2019.10.03-1/cli_linux_amd64
-	bucket := s.artifactBucket
-
+	// location: <artifact_bucket>/cli/2019.10.03-1/cli_linux_amd64
 	objectPath := path.Join(name, versionStr, fmt.Sprintf("%s_%s", name, downloadSuffix(at)))
-	attr, err := s.sc.Bucket(bucket).Object(objectPath).Attrs(ctx)
+	url, err := s.store.DownloadURL(ctx, objectPath, validFor)
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to get URL")
 	}
-	url := attr.MediaLink
 
 	tpb, _ := types.TimestampProto(expires)
 
 	sha256ObjectPath := objectPath + ".sha256"
-	r, err := s.sc.Bucket(bucket).Object(sha256ObjectPath).NewReader(ctx)
+	r, err := s.store.Get(ctx, sha256ObjectPath)
 
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to fetch sha256 file")
@@ -248,9 +255,27 @@ func (s *Server) GetDownloadLink(ctx context.Context, in *apb.GetDownloadLinkReq
 		Url:        url,
 		SHA256:     strings.TrimSpace(string(sha256bytes)),
 		ValidUntil: tpb,
+		Signature:  s.readSignature(ctx, objectPath),
 	}, nil
 }
 
+// readSignature reads the detached signature that is uploaded next to the artifact. Older artifacts were
+// not signed, so a missing signature is not an error; clients decide whether to accept unsigned artifacts.
+func (s *Server) readSignature(ctx context.Context, objectPath string) string {
+	r, err := s.store.Get(ctx, objectPath+".asc")
+	if err != nil {
+		return ""
+	}
+	defer r.Close()
+
+	sig, err := io.ReadAll(r)
+	if err != nil {
+		log.WithError(err).WithField("object", objectPath).Error("Failed to read artifact signature")
+		return ""
+	}
+	return string(sig)
+}
+
 func (s *Server) getDownloadLinkForMirrors(ctx context.Context, am *vpb.ArtifactMirrors) (*apb.GetDownloadLinkResponse, error) {
 	// For now we return a download link to the first mirror.
 	// In the future, the API will change to support returning multiple mirrors.
@@ -264,6 +289,7 @@ func (s *Server) getDownloadLinkForMirrors(ctx context.Context, am *vpb.Artifact
 		Url:        url,
 		SHA256:     strings.TrimSpace(string(sha256Bytes)),
 		ValidUntil: valid,
+		Signature:  am.Signature,
 	}, nil
 }
 
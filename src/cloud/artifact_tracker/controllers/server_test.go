@@ -29,32 +29,32 @@ import (
 
 	"cloud.google.com/go/storage"
 	"github.com/gogo/protobuf/types"
-	"github.com/googleapis/google-cloud-go-testing/storage/stiface"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"golang.org/x/oauth2/jwt"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	apb "px.dev/pixie/src/cloud/artifact_tracker/artifacttrackerpb"
 	"px.dev/pixie/src/cloud/artifact_tracker/controllers"
+	"px.dev/pixie/src/cloud/shared/objstore"
 	"px.dev/pixie/src/shared/artifacts/manifest"
 	vpb "px.dev/pixie/src/shared/artifacts/versionspb"
 	"px.dev/pixie/src/utils/testingutils"
 )
 
-func mustSetupFakeBucket(t *testing.T) stiface.Client {
-	return testingutils.NewMockGCSClient(map[string]*testingutils.MockGCSBucket{
+func mustSetupFakeBucket(t *testing.T) objstore.Store {
+	return objstore.NewGCSStore(testingutils.NewMockGCSClient(map[string]*testingutils.MockGCSBucket{
 		"test-bucket": testingutils.NewMockGCSBucket(
 			map[string]*testingutils.MockGCSObject{
 				"cli/1.2.1-pre.3/cli_linux_amd64.sha256": testingutils.NewMockGCSObject([]byte("the-sha256"), nil),
+				"cli/1.2.1-pre.3/cli_linux_amd64.asc":    testingutils.NewMockGCSObject([]byte("the-signature"), nil),
 				"cli/1.2.1-pre.3/cli_linux_amd64": testingutils.NewMockGCSObject([]byte("mybin"), &storage.ObjectAttrs{
 					MediaLink: "the-url",
 				}),
 			},
 			nil,
 		),
-	})
+	}), "test-bucket", "")
 }
 
 func startTestHTTPServer(t *testing.T) *httptest.Server {
@@ -138,6 +138,7 @@ func loadTestManifest(s *controllers.Server, ts *httptest.Server) error {
             {
               "artifact_type": "AT_CONTAINER_SET_YAMLS",
               "sha256": "efgh",
+              "signature": "yamls-signature",
               "urls": [
                 "{{ .ServerURL }}/vizier/0.1.1/container_yamls"
               ]
@@ -173,7 +174,7 @@ func loadTestManifest(s *controllers.Server, ts *httptest.Server) error {
 }
 
 func TestServer_GetArtifactList(t *testing.T) {
-	server := controllers.NewServer(nil, "bucket", nil)
+	server := controllers.NewServer(nil)
 
 	ts := startTestHTTPServer(t)
 	defer ts.Close()
@@ -304,10 +305,7 @@ func TestServer_GetArtifactList(t *testing.T) {
 func TestServer_GetDownloadLink(t *testing.T) {
 	storageClient := mustSetupFakeBucket(t)
 
-	server := controllers.NewServer(storageClient, "test-bucket", &jwt.Config{
-		Email:      "test@test.com",
-		PrivateKey: []byte("the-key"),
-	})
+	server := controllers.NewServer(storageClient)
 
 	ts := startTestHTTPServer(t)
 	defer ts.Close()
@@ -362,8 +360,9 @@ func TestServer_GetDownloadLink(t *testing.T) {
 				ArtifactType: vpb.AT_LINUX_AMD64,
 			},
 			expectedResp: &apb.GetDownloadLinkResponse{
-				Url:    "the-url",
-				SHA256: "the-sha256",
+				Url:       "the-url",
+				SHA256:    "the-sha256",
+				Signature: "the-signature",
 			},
 			errCode: codes.OK,
 		},
@@ -375,8 +374,9 @@ func TestServer_GetDownloadLink(t *testing.T) {
 				ArtifactType: vpb.AT_CONTAINER_SET_YAMLS,
 			},
 			expectedResp: &apb.GetDownloadLinkResponse{
-				Url:    ts.URL + "/vizier/0.1.1/container_yamls",
-				SHA256: "efgh",
+				Url:       ts.URL + "/vizier/0.1.1/container_yamls",
+				SHA256:    "efgh",
+				Signature: "yamls-signature",
 			},
 			errCode: codes.OK,
 		},
@@ -395,6 +395,7 @@ func TestServer_GetDownloadLink(t *testing.T) {
 				assert.True(t, time.Until(ts) > 0)
 				assert.Equal(t, tc.expectedResp.Url, resp.Url)
 				assert.Equal(t, tc.expectedResp.SHA256, resp.SHA256)
+				assert.Equal(t, tc.expectedResp.Signature, resp.Signature)
 			}
 		})
 	}
@@ -36,12 +36,15 @@ import (
 	"px.dev/pixie/src/api/proto/uuidpb"
 	"px.dev/pixie/src/cloud/auth/authpb"
 	"px.dev/pixie/src/shared/services/authcontext"
+	"px.dev/pixie/src/shared/services/rbac"
 	"px.dev/pixie/src/utils"
 )
 
 var (
 	// ErrAPIKeyNotFound is used when the specified API key cannot be located.
 	ErrAPIKeyNotFound = errors.New("invalid API key")
+	// ErrAPIKeyExpired is used when the specified API key has expired.
+	ErrAPIKeyExpired = errors.New("API key has expired")
 )
 
 const (
@@ -70,12 +73,31 @@ func (s *Service) Create(ctx context.Context, req *authpb.CreateAPIKeyRequest) (
 		return nil, status.Error(codes.Unauthenticated, err.Error())
 	}
 
+	if err := rbac.ValidateScopes(req.Scopes); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	// A key can't be used to gain more access than the user creating it has.
+	if keyBindings, found := rbac.ParseScopes(req.Scopes); found && !rbac.BindingsForClaims(sCtx.Claims).Includes(keyBindings) {
+		return nil, status.Error(codes.PermissionDenied, "cannot create an API key with more access than your own")
+	}
+	var expiresAt *time.Time
+	if req.ExpiresAt != nil {
+		t, err := types.TimestampFromProto(req.ExpiresAt)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid expiration time")
+		}
+		if !t.After(time.Now()) {
+			return nil, status.Error(codes.InvalidArgument, "expiration time must be in the future")
+		}
+		expiresAt = &t
+	}
+
 	var id uuid.UUID
 	var ts time.Time
 	// We store a version of the key in hashed_key that is salted using a constant salt (dbKey),
 	// to allow us to an associative lookup. This is secure since the API key is a UUID and won't collide.
-	query := `INSERT INTO api_keys(org_id, user_id, hashed_key, encrypted_key, description)
-                VALUES($1, $2, sha256($3), PGP_SYM_ENCRYPT($3::text, $4::text), $5)
+	query := `INSERT INTO api_keys(org_id, user_id, hashed_key, encrypted_key, description, scopes, expires_at)
+                VALUES($1, $2, sha256($3), PGP_SYM_ENCRYPT($3::text, $4::text), $5, $6, $7)
                 RETURNING id, created_at`
 	keyID, err := uuid.NewV4()
 	if err != nil {
@@ -87,7 +109,9 @@ func (s *Service) Create(ctx context.Context, req *authpb.CreateAPIKeyRequest) (
 		sCtx.Claims.GetUserClaims().UserID,
 		key,
 		s.dbKey,
-		req.Desc).
+		req.Desc,
+		strings.Join(req.Scopes, " "),
+		expiresAt).
 		Scan(&id, &ts)
 	if err != nil {
 		log.WithError(err).Error("Failed to insert API keys")
@@ -99,6 +123,8 @@ func (s *Service) Create(ctx context.Context, req *authpb.CreateAPIKeyRequest) (
 		ID:        utils.ProtoFromUUID(id),
 		Key:       key,
 		CreatedAt: tp,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
 	}, nil
 }
 
@@ -110,7 +136,7 @@ func (s *Service) List(ctx context.Context, req *authpb.ListAPIKeyRequest) (*aut
 	}
 
 	// Return all keys when the OrgID matches.
-	query := `SELECT id, org_id, user_id, created_at, description
+	query := `SELECT id, org_id, user_id, created_at, description, scopes, expires_at, last_used_at
                 FROM api_keys
                 WHERE org_id=$1
                 ORDER BY created_at`
@@ -131,18 +157,23 @@ func (s *Service) List(ctx context.Context, req *authpb.ListAPIKeyRequest) (*aut
 		var userID uuid.UUID
 		var createdAt time.Time
 		var desc string
-		err = rows.Scan(&id, &orgID, &userID, &createdAt, &desc)
+		var scopes string
+		var expiresAt, lastUsedAt *time.Time
+		err = rows.Scan(&id, &orgID, &userID, &createdAt, &desc, &scopes, &expiresAt, &lastUsedAt)
 		if err != nil {
 			log.WithError(err).Error("Failed to read data from postgres")
 			return nil, status.Error(codes.Internal, "failed to read data")
 		}
 		tProto, _ := types.TimestampProto(createdAt)
 		keys = append(keys, &authpb.APIKeyMetadata{
-			ID:        utils.ProtoFromUUIDStrOrNil(id),
-			OrgID:     utils.ProtoFromUUID(orgID),
-			UserID:    utils.ProtoFromUUID(userID),
-			CreatedAt: tProto,
-			Desc:      desc,
+			ID:         utils.ProtoFromUUIDStrOrNil(id),
+			OrgID:      utils.ProtoFromUUID(orgID),
+			UserID:     utils.ProtoFromUUID(userID),
+			CreatedAt:  tProto,
+			Desc:       desc,
+			Scopes:     strings.Fields(scopes),
+			ExpiresAt:  timestampProtoOrNil(expiresAt),
+			LastUsedAt: timestampProtoOrNil(lastUsedAt),
 		})
 	}
 	return &authpb.ListAPIKeyResponse{
@@ -166,10 +197,14 @@ func (s *Service) Get(ctx context.Context, req *authpb.GetAPIKeyRequest) (*authp
 	var key string
 	var createdAt time.Time
 	var desc string
-	query := `SELECT CONVERT_FROM(PGP_SYM_DECRYPT(encrypted_key, $3::text)::bytea, 'UTF8'), org_id, user_id, created_at, description
+	var scopes string
+	var expiresAt, lastUsedAt *time.Time
+	query := `SELECT CONVERT_FROM(PGP_SYM_DECRYPT(encrypted_key, $3::text)::bytea, 'UTF8'), org_id, user_id, created_at, description,
+                scopes, expires_at, last_used_at
                 FROM api_keys
                 WHERE org_id=$1 AND id=$2`
-	err = s.db.QueryRowxContext(ctx, query, sCtx.Claims.GetUserClaims().OrgID, tokenID, s.dbKey).Scan(&key, &orgID, &userID, &createdAt, &desc)
+	err = s.db.QueryRowxContext(ctx, query, sCtx.Claims.GetUserClaims().OrgID, tokenID, s.dbKey).
+		Scan(&key, &orgID, &userID, &createdAt, &desc, &scopes, &expiresAt, &lastUsedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, status.Error(codes.NotFound, "No such API key")
@@ -179,12 +214,15 @@ func (s *Service) Get(ctx context.Context, req *authpb.GetAPIKeyRequest) (*authp
 
 	createdAtProto, _ := types.TimestampProto(createdAt)
 	return &authpb.GetAPIKeyResponse{Key: &authpb.APIKey{
-		ID:        req.ID,
-		OrgID:     utils.ProtoFromUUID(orgID),
-		UserID:    utils.ProtoFromUUID(userID),
-		Key:       key,
-		CreatedAt: createdAtProto,
-		Desc:      desc,
+		ID:         req.ID,
+		OrgID:      utils.ProtoFromUUID(orgID),
+		UserID:     utils.ProtoFromUUID(userID),
+		Key:        key,
+		CreatedAt:  createdAtProto,
+		Desc:       desc,
+		Scopes:     strings.Fields(scopes),
+		ExpiresAt:  timestampProtoOrNil(expiresAt),
+		LastUsedAt: timestampProtoOrNil(lastUsedAt),
 	}}, nil
 }
 
@@ -221,9 +259,31 @@ func (s *Service) Delete(ctx context.Context, req *uuidpb.UUID) (*types.Empty, e
 	return &types.Empty{}, nil
 }
 
+// AuthenticateAPIKey gets the API key information for a key that is being used to authenticate.
+// Expired keys are rejected, and the key's last use is recorded.
+func (s *Service) AuthenticateAPIKey(ctx context.Context, key string) (*authpb.APIKey, error) {
+	resp, err := s.fetchAPIKeyUsingKeyFromDB(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if resp.ExpiresAt != nil {
+		expiresAt, err := types.TimestampFromProto(resp.ExpiresAt)
+		if err != nil || !expiresAt.After(time.Now()) {
+			return nil, ErrAPIKeyExpired
+		}
+	}
+
+	query := `UPDATE api_keys SET last_used_at=NOW() WHERE id=$1`
+	if _, err := s.db.ExecContext(ctx, query, utils.UUIDFromProtoOrNil(resp.ID)); err != nil {
+		// Failing to track usage shouldn't stop the key from working.
+		log.WithError(err).Error("Failed to update API key last used time")
+	}
+	return resp, nil
+}
+
 // FetchOrgUserIDUsingAPIKey gets the org and user ID based on the API key.
 func (s *Service) FetchOrgUserIDUsingAPIKey(ctx context.Context, key string) (uuid.UUID, uuid.UUID, error) {
-	resp, err := s.fetchAPIKeyUsingKeyFromDB(ctx, key)
+	resp, err := s.AuthenticateAPIKey(ctx, key)
 	if err != nil {
 		return uuid.Nil, uuid.Nil, err
 	}
@@ -268,10 +328,13 @@ func (s *Service) fetchAPIKeyUsingKeyFromDB(ctx context.Context, key string) (*a
 	var userID uuid.UUID
 	var createdAt time.Time
 	var desc string
-	query := `SELECT id, org_id, user_id, created_at, description
+	var scopes string
+	var expiresAt, lastUsedAt *time.Time
+	query := `SELECT id, org_id, user_id, created_at, description, scopes, expires_at, last_used_at
                 FROM api_keys
                 WHERE hashed_key=sha256($1) and PGP_SYM_DECRYPT(encrypted_key::bytea, $2::text)::bytea=$1`
-	err := s.db.QueryRowxContext(ctx, query, key, s.dbKey).Scan(&id, &orgID, &userID, &createdAt, &desc)
+	err := s.db.QueryRowxContext(ctx, query, key, s.dbKey).
+		Scan(&id, &orgID, &userID, &createdAt, &desc, &scopes, &expiresAt, &lastUsedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrAPIKeyNotFound
@@ -281,11 +344,22 @@ func (s *Service) fetchAPIKeyUsingKeyFromDB(ctx context.Context, key string) (*a
 
 	createdAtProto, _ := types.TimestampProto(createdAt)
 	return &authpb.APIKey{
-		ID:        utils.ProtoFromUUID(id),
-		OrgID:     utils.ProtoFromUUID(orgID),
-		UserID:    utils.ProtoFromUUID(userID),
-		Key:       key,
-		CreatedAt: createdAtProto,
-		Desc:      desc,
+		ID:         utils.ProtoFromUUID(id),
+		OrgID:      utils.ProtoFromUUID(orgID),
+		UserID:     utils.ProtoFromUUID(userID),
+		Key:        key,
+		CreatedAt:  createdAtProto,
+		Desc:       desc,
+		Scopes:     strings.Fields(scopes),
+		ExpiresAt:  timestampProtoOrNil(expiresAt),
+		LastUsedAt: timestampProtoOrNil(lastUsedAt),
 	}, nil
 }
+
+func timestampProtoOrNil(t *time.Time) *types.Timestamp {
+	if t == nil {
+		return nil
+	}
+	tp, _ := types.TimestampProto(*t)
+	return tp
+}
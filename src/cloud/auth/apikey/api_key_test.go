@@ -146,6 +146,93 @@ func TestAPIKeyService_CreateAPIKey(t *testing.T) {
 	}
 }
 
+func TestAPIKeyService_CreateAPIKey_ScopedExpiring(t *testing.T) {
+	mustLoadTestData(db)
+
+	ctx := createTestContext()
+	svc := New(db, testDBKey)
+
+	clusterScope := "role:editor:cluster:7ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	expiresAt, err := types.TimestampProto(time.Now().Add(time.Hour).Round(time.Second))
+	require.NoError(t, err)
+	resp, err := svc.Create(ctx, &authpb.CreateAPIKeyRequest{
+		Desc:      "scoped key",
+		Scopes:    []string{"role:viewer", clusterScope},
+		ExpiresAt: expiresAt,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"role:viewer", clusterScope}, resp.Scopes)
+
+	getResp, err := svc.Get(ctx, &authpb.GetAPIKeyRequest{ID: resp.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"role:viewer", clusterScope}, getResp.Key.Scopes)
+	assert.Equal(t, expiresAt.Seconds, getResp.Key.ExpiresAt.Seconds)
+	assert.Nil(t, getResp.Key.LastUsedAt)
+}
+
+func TestAPIKeyService_CreateAPIKey_BadScopes(t *testing.T) {
+	mustLoadTestData(db)
+
+	viewerCtx := authcontext.New()
+	viewerCtx.Claims = jwtutils.GenerateJWTForUser(testAuthUserID.String(), testAuthOrgID.String(), "test@test.com", time.Now(), "pixie")
+	viewerCtx.Claims.Scopes = append(viewerCtx.Claims.Scopes, "role:viewer")
+
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		req          *authpb.CreateAPIKeyRequest
+		expectedCode codes.Code
+	}{
+		{
+			name:         "malformed scope",
+			ctx:          createTestContext(),
+			req:          &authpb.CreateAPIKeyRequest{Scopes: []string{"role:owner"}},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "more access than the creator",
+			ctx:          authcontext.NewContext(context.Background(), viewerCtx),
+			req:          &authpb.CreateAPIKeyRequest{Scopes: []string{"role:admin"}},
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:         "expiration in the past",
+			ctx:          createTestContext(),
+			req:          &authpb.CreateAPIKeyRequest{ExpiresAt: types.TimestampNow()},
+			expectedCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			svc := New(db, testDBKey)
+			_, err := svc.Create(test.ctx, test.req)
+			require.Error(t, err)
+			assert.Equal(t, test.expectedCode, status.Code(err))
+		})
+	}
+}
+
+func TestService_AuthenticateAPIKey(t *testing.T) {
+	mustLoadTestData(db)
+	db.MustExec(`UPDATE api_keys SET expires_at=NOW() - INTERVAL '1 hour' WHERE id=$1`, testKey2ID)
+
+	ctx := createTestContext()
+	svc := New(db, testDBKey)
+
+	resp, err := svc.AuthenticateAPIKey(ctx, "px-api-key1")
+	require.NoError(t, err)
+	assert.Equal(t, testKey1ID, utils.UUIDFromProtoOrNil(resp.ID))
+
+	// The key's use should be recorded.
+	getResp, err := svc.Get(ctx, &authpb.GetAPIKeyRequest{ID: utils.ProtoFromUUID(testKey1ID)})
+	require.NoError(t, err)
+	assert.NotNil(t, getResp.Key.LastUsedAt)
+
+	_, err = svc.AuthenticateAPIKey(ctx, "px-api-key2")
+	assert.Equal(t, ErrAPIKeyExpired, err)
+}
+
 func TestAPIKeyService_ListAPIKeys(t *testing.T) {
 	mustLoadTestData(db)
 
@@ -87,6 +87,11 @@ func main() {
 		if err != nil {
 			log.WithError(err).Fatal("Failed to initialize OIDC connector")
 		}
+	case "saml":
+		a, err = controllers.NewSAMLConnector()
+		if err != nil {
+			log.WithError(err).Fatal("Failed to initialize SAML connector")
+		}
 	case "auth0":
 		a, err = controllers.NewAuth0Connector(controllers.NewAuth0Config())
 		if err != nil {
@@ -98,7 +103,7 @@ func main() {
 			log.WithError(err).Fatal("Failed to initialize hydraKratosConnector")
 		}
 	default:
-		log.Fatalf("Cannot initialize authProvider '%s'. Only 'auth0', 'oidc', and 'hydra' are supported.", authProvider)
+		log.Fatalf("Cannot initialize authProvider '%s'. Only 'auth0', 'oidc', 'saml', and 'hydra' are supported.", authProvider)
 	}
 
 	env, err := authenv.NewWithDefaults()
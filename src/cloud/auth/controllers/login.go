@@ -36,6 +36,7 @@ import (
 	"px.dev/pixie/src/cloud/auth/authpb"
 	"px.dev/pixie/src/cloud/profile/profilepb"
 	"px.dev/pixie/src/shared/services/authcontext"
+	"px.dev/pixie/src/shared/services/rbac"
 	srvutils "px.dev/pixie/src/shared/services/utils"
 	"px.dev/pixie/src/utils"
 )
@@ -278,6 +279,10 @@ func (s *Server) completeUserLogin(ctx context.Context, userInfo *UserInfo, orgI
 
 	expiresAt := time.Now().Add(RefreshTokenValidDuration)
 	claims := srvutils.GenerateJWTForUser(utils.ProtoToUUIDStr(user.ID), orgID, userInfo.Email, expiresAt, viper.GetString("domain_name"))
+	// The user's roles are the ones on their profile, along with any that their identity provider
+	// assigns at login.
+	claims.Scopes = append(claims.Scopes, rbac.ScopesForRoles(userInfo.Roles)...)
+	claims.Scopes = append(claims.Scopes, rbac.ScopesForRoles(user.Roles)...)
 	tkn, err := srvutils.SignJWTClaims(claims, s.env.JWTSigningKey())
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to generate token")
@@ -487,10 +492,12 @@ func (s *Server) createUser(ctx context.Context, userInfo *UserInfo, orgID *uuid
 // GetAugmentedTokenForAPIKey produces an augmented token for the user given a API key.
 func (s *Server) GetAugmentedTokenForAPIKey(ctx context.Context, in *authpb.GetAugmentedTokenForAPIKeyRequest) (*authpb.GetAugmentedTokenForAPIKeyResponse, error) {
 	// Find the org/user associated with the token.
-	orgID, userID, err := s.apiKeyMgr.FetchOrgUserIDUsingAPIKey(ctx, in.APIKey)
+	apiKey, err := s.apiKeyMgr.AuthenticateAPIKey(ctx, in.APIKey)
 	if err != nil {
 		return nil, status.Errorf(codes.Unauthenticated, "Invalid API key")
 	}
+	orgID := utils.UUIDFromProtoOrNil(apiKey.OrgID)
+	userID := utils.UUIDFromProtoOrNil(apiKey.UserID)
 
 	// Generate service token, so that we can make a call to the Profile service.
 	svcJWT := srvutils.GenerateJWTForService("AuthService", viper.GetString("domain_name"))
@@ -507,8 +514,26 @@ func (s *Server) GetAugmentedTokenForAPIKey(ctx context.Context, in *authpb.GetA
 		return nil, status.Errorf(codes.Internal, "Failed to generate auth token")
 	}
 
-	// Create JWT for user/org.
-	claims := srvutils.GenerateJWTForAPIUser(userID.String(), orgID.String(), time.Now().Add(AugmentedTokenValidDuration), viper.GetString("domain_name"))
+	// The key can't grant more than its owner's current roles. Keys without role scopes act with all
+	// of the owner's roles.
+	owner, err := s.env.ProfileClient().GetUser(ctxWithSvcCreds, utils.ProtoFromUUID(userID))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to generate auth token")
+	}
+	roles, _ := rbac.ParseScopes(rbac.ScopesForRoles(owner.Roles))
+	if keyRoles, found := rbac.ParseScopes(apiKey.Scopes); found {
+		roles = roles.Intersect(keyRoles)
+	}
+
+	// Create JWT for user/org. The token can't outlive the key.
+	expiresAt := time.Now().Add(AugmentedTokenValidDuration)
+	if apiKey.ExpiresAt != nil {
+		if keyExpiresAt, err := types.TimestampFromProto(apiKey.ExpiresAt); err == nil && keyExpiresAt.Before(expiresAt) {
+			expiresAt = keyExpiresAt
+		}
+	}
+	claims := srvutils.GenerateJWTForAPIUser(userID.String(), orgID.String(), expiresAt, viper.GetString("domain_name"))
+	claims.Scopes = append(claims.Scopes, roles.Scopes()...)
 	token, err := srvutils.SignJWTClaims(claims, s.env.JWTSigningKey())
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Failed to generate auth token")
@@ -714,6 +739,7 @@ func (s *Server) GetAuthConnectorToken(ctx context.Context, req *authpb.GetAuthC
 	now := time.Now()
 	expiresAt := now.Add(AuthConnectorTokenValidDuration)
 	claims := srvutils.GenerateJWTForUser(utils.UUIDFromProtoOrNil(userInfo.ID).String(), utils.UUIDFromProtoOrNil(userInfo.OrgID).String(), userInfo.Email, expiresAt, viper.GetString("domain_name"))
+	claims.Scopes = append(claims.Scopes, rbac.RoleScopes(sCtx.Claims.Scopes)...)
 	token, err := srvutils.ProtoToToken(claims)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create authConnector token")
@@ -771,6 +797,10 @@ func (s *Server) RefetchToken(ctx context.Context, in *authpb.RefetchTokenReques
 		expiresAt,
 		viper.GetString("domain_name"),
 	)
+	// Roles that the IdP assigned at login are carried over until the user logs in again. Roles on
+	// the user's profile, such as the admin role of a user that just created their org, are added.
+	claims.Scopes = append(claims.Scopes, rbac.RoleScopes(aCtx.Claims.Scopes)...)
+	claims.Scopes = append(claims.Scopes, rbac.ScopesForRoles(user.Roles)...)
 	tkn, err := srvutils.SignJWTClaims(claims, s.env.JWTSigningKey())
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to generate token")
@@ -24,7 +24,6 @@ import (
 	"testing"
 	"time"
 
-	"github.com/gofrs/uuid"
 	"github.com/gogo/protobuf/types"
 	"github.com/golang/mock/gomock"
 	"github.com/spf13/viper"
@@ -40,6 +39,7 @@ import (
 	"px.dev/pixie/src/cloud/profile/profilepb"
 	mock_profile "px.dev/pixie/src/cloud/profile/profilepb/mock"
 	"px.dev/pixie/src/shared/services/authcontext"
+	"px.dev/pixie/src/shared/services/rbac"
 	srvutils "px.dev/pixie/src/shared/services/utils"
 	"px.dev/pixie/src/utils"
 	"px.dev/pixie/src/utils/testingutils"
@@ -760,6 +760,155 @@ func TestServer_Login_UserInExistingOrg(t *testing.T) {
 	verifyToken(t, resp.Token, userID, orgID, resp.ExpiresAt, "jwtkey")
 }
 
+func TestServer_Login_UserWithSCIMRoles(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	orgID := "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	orgPb := utils.ProtoFromUUIDStrOrNil(orgID)
+	userID := "7ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	userPb := utils.ProtoFromUUIDStrOrNil(userID)
+
+	a := mock_controllers.NewMockAuthProvider(ctrl)
+	authProviderID := "github|abc123"
+	a.EXPECT().GetUserInfoFromAccessToken("tokenabc").Return(&controllers.UserInfo{
+		Email:            "abc@gmail.com",
+		EmailVerified:    true,
+		AuthProviderID:   authProviderID,
+		IdentityProvider: auth0IdentityProvider,
+	}, nil)
+
+	mockProfile := mock_profile.NewMockProfileServiceClient(ctrl)
+	mockOrg := mock_profile.NewMockOrgServiceClient(ctrl)
+	// The user was assigned the viewer role through a SCIM group.
+	mockProfile.EXPECT().
+		GetUserByAuthProviderID(gomock.Any(), &profilepb.GetUserByAuthProviderIDRequest{
+			AuthProviderID: authProviderID,
+		}).Times(2).
+		Return(&profilepb.UserInfo{
+			ID:    userPb,
+			OrgID: orgPb,
+			Roles: []string{"viewer"},
+		}, nil)
+	mockOrg.EXPECT().
+		GetOrg(gomock.Any(), orgPb).
+		Return(&profilepb.OrgInfo{ID: orgPb, DomainName: &types.StringValue{}}, nil)
+	mockProfile.EXPECT().
+		UpdateUser(gomock.Any(), gomock.Any()).
+		Return(nil, nil)
+	mockOrg.EXPECT().
+		UpdateOrg(gomock.Any(), gomock.Any()).
+		Return(nil, nil)
+
+	viper.Set("jwt_signing_key", "jwtkey")
+	viper.Set("domain_name", "withpixie.ai")
+
+	env, err := authenv.New(mockProfile, mockOrg)
+	require.NoError(t, err)
+	s, err := controllers.NewServer(env, a, nil)
+	require.NoError(t, err)
+
+	resp, err := doLoginRequest(getTestContext(), t, s)
+	require.NoError(t, err)
+	parsed, err := srvutils.ParseToken(resp.Token, "jwtkey", "withpixie.ai")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"user", "role:viewer"}, srvutils.GetScopes(parsed))
+}
+
+func TestServer_Login_UserWithoutRoles(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	orgID := "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	orgPb := utils.ProtoFromUUIDStrOrNil(orgID)
+	userID := "7ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	userPb := utils.ProtoFromUUIDStrOrNil(userID)
+
+	a := mock_controllers.NewMockAuthProvider(ctrl)
+	authProviderID := "github|abc123"
+	a.EXPECT().GetUserInfoFromAccessToken("tokenabc").Return(&controllers.UserInfo{
+		Email:            "abc@gmail.com",
+		EmailVerified:    true,
+		AuthProviderID:   authProviderID,
+		IdentityProvider: auth0IdentityProvider,
+	}, nil)
+
+	mockProfile := mock_profile.NewMockProfileServiceClient(ctrl)
+	mockOrg := mock_profile.NewMockOrgServiceClient(ctrl)
+	// The user was removed from their last SCIM group.
+	mockProfile.EXPECT().
+		GetUserByAuthProviderID(gomock.Any(), &profilepb.GetUserByAuthProviderIDRequest{
+			AuthProviderID: authProviderID,
+		}).Times(2).
+		Return(&profilepb.UserInfo{
+			ID:    userPb,
+			OrgID: orgPb,
+			Roles: []string{},
+		}, nil)
+	mockOrg.EXPECT().
+		GetOrg(gomock.Any(), orgPb).
+		Return(&profilepb.OrgInfo{ID: orgPb, DomainName: &types.StringValue{}}, nil)
+	mockProfile.EXPECT().
+		UpdateUser(gomock.Any(), gomock.Any()).
+		Return(nil, nil)
+	mockOrg.EXPECT().
+		UpdateOrg(gomock.Any(), gomock.Any()).
+		Return(nil, nil)
+
+	viper.Set("jwt_signing_key", "jwtkey")
+	viper.Set("domain_name", "withpixie.ai")
+
+	env, err := authenv.New(mockProfile, mockOrg)
+	require.NoError(t, err)
+	s, err := controllers.NewServer(env, a, nil)
+	require.NoError(t, err)
+
+	resp, err := doLoginRequest(getTestContext(), t, s)
+	require.NoError(t, err)
+	parsed, err := srvutils.ParseToken(resp.Token, "jwtkey", "withpixie.ai")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"user"}, srvutils.GetScopes(parsed))
+	claims, err := srvutils.TokenToProto(parsed)
+	require.NoError(t, err)
+	assert.Equal(t, rbac.RoleNone, rbac.BindingsForClaims(claims).OrgRole)
+}
+
+func TestServer_RefetchToken_ProfileRoles(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	a := mock_controllers.NewMockAuthProvider(ctrl)
+	mockProfile := mock_profile.NewMockProfileServiceClient(ctrl)
+	mockOrg := mock_profile.NewMockOrgServiceClient(ctrl)
+	// The user just created their org, which made them its admin.
+	mockProfile.EXPECT().
+		GetUser(gomock.Any(), utils.ProtoFromUUIDStrOrNil(testingutils.TestUserID)).
+		Return(&profilepb.UserInfo{
+			ID:    utils.ProtoFromUUIDStrOrNil(testingutils.TestUserID),
+			OrgID: utils.ProtoFromUUIDStrOrNil(testingutils.TestOrgID),
+			Email: "test@test.com",
+			Roles: []string{"admin"},
+		}, nil)
+
+	viper.Set("jwt_signing_key", "jwtkey")
+	viper.Set("domain_name", "withpixie.ai")
+
+	env, err := authenv.New(mockProfile, mockOrg)
+	require.NoError(t, err)
+	s, err := controllers.NewServer(env, a, nil)
+	require.NoError(t, err)
+
+	claims := testingutils.GenerateTestClaims(t)
+	claims.Scopes = append(claims.Scopes, "role:viewer")
+	resp, err := s.RefetchToken(getTestContext(), &authpb.RefetchTokenRequest{
+		Token: testingutils.SignPBClaims(t, claims, "jwtkey"),
+	})
+	require.NoError(t, err)
+	parsed, err := srvutils.ParseToken(resp.Token, "jwtkey", "withpixie.ai")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"user", "role:viewer", "role:admin"}, srvutils.GetScopes(parsed))
+}
+
 func TestServer_GetAugmentedToken(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	a := mock_controllers.NewMockAuthProvider(ctrl)
@@ -1116,7 +1265,10 @@ func TestServer_GetAugmentedTokenFromAPIKey(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	a := mock_controllers.NewMockAuthProvider(ctrl)
 	apiKeyServer := mock_controllers.NewMockAPIKeyMgr(ctrl)
-	apiKeyServer.EXPECT().FetchOrgUserIDUsingAPIKey(gomock.Any(), "test_api").Return(uuid.FromStringOrNil(testingutils.TestOrgID), uuid.FromStringOrNil(testingutils.TestUserID), nil)
+	apiKeyServer.EXPECT().AuthenticateAPIKey(gomock.Any(), "test_api").Return(&authpb.APIKey{
+		OrgID:  utils.ProtoFromUUIDStrOrNil(testingutils.TestOrgID),
+		UserID: utils.ProtoFromUUIDStrOrNil(testingutils.TestUserID),
+	}, nil)
 
 	mockProfile := mock_profile.NewMockProfileServiceClient(ctrl)
 	mockOrg := mock_profile.NewMockOrgServiceClient(ctrl)
@@ -1126,6 +1278,13 @@ func TestServer_GetAugmentedTokenFromAPIKey(t *testing.T) {
 	mockOrg.EXPECT().
 		GetOrg(gomock.Any(), utils.ProtoFromUUIDStrOrNil(testingutils.TestOrgID)).
 		Return(mockOrgInfo, nil)
+	mockProfile.EXPECT().
+		GetUser(gomock.Any(), utils.ProtoFromUUIDStrOrNil(testingutils.TestUserID)).
+		Return(&profilepb.UserInfo{
+			ID:    utils.ProtoFromUUIDStrOrNil(testingutils.TestUserID),
+			OrgID: utils.ProtoFromUUIDStrOrNil(testingutils.TestOrgID),
+			Roles: []string{"editor"},
+		}, nil)
 
 	viper.Set("jwt_signing_key", "jwtkey")
 	viper.Set("domain_name", "withpixie.ai")
@@ -1155,6 +1314,127 @@ func TestServer_GetAugmentedTokenFromAPIKey(t *testing.T) {
 	assert.Equal(t, testingutils.TestOrgID, srvutils.GetOrgID(parsed))
 	assert.Equal(t, resp.ExpiresAt, parsed.Expiration().Unix())
 	assert.True(t, srvutils.GetIsAPIUser(parsed))
+	// Unscoped keys act with the owner's roles.
+	assert.ElementsMatch(t, []string{"user", "role:editor"}, srvutils.GetScopes(parsed))
+}
+
+func TestServer_GetAugmentedTokenFromAPIKey_ScopedExpiring(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	a := mock_controllers.NewMockAuthProvider(ctrl)
+	apiKeyServer := mock_controllers.NewMockAPIKeyMgr(ctrl)
+	keyExpiresAt := time.Now().Add(10 * time.Minute)
+	keyExpiresAtProto, err := types.TimestampProto(keyExpiresAt)
+	require.NoError(t, err)
+	apiKeyServer.EXPECT().AuthenticateAPIKey(gomock.Any(), "test_api").Return(&authpb.APIKey{
+		OrgID:     utils.ProtoFromUUIDStrOrNil(testingutils.TestOrgID),
+		UserID:    utils.ProtoFromUUIDStrOrNil(testingutils.TestUserID),
+		Scopes:    []string{"role:viewer"},
+		ExpiresAt: keyExpiresAtProto,
+	}, nil)
+
+	mockProfile := mock_profile.NewMockProfileServiceClient(ctrl)
+	mockOrg := mock_profile.NewMockOrgServiceClient(ctrl)
+	mockOrg.EXPECT().
+		GetOrg(gomock.Any(), utils.ProtoFromUUIDStrOrNil(testingutils.TestOrgID)).
+		Return(&profilepb.OrgInfo{ID: utils.ProtoFromUUIDStrOrNil(testingutils.TestOrgID)}, nil)
+	mockProfile.EXPECT().
+		GetUser(gomock.Any(), utils.ProtoFromUUIDStrOrNil(testingutils.TestUserID)).
+		Return(&profilepb.UserInfo{
+			ID:    utils.ProtoFromUUIDStrOrNil(testingutils.TestUserID),
+			OrgID: utils.ProtoFromUUIDStrOrNil(testingutils.TestOrgID),
+			Roles: []string{"admin"},
+		}, nil)
+
+	viper.Set("jwt_signing_key", "jwtkey")
+	viper.Set("domain_name", "withpixie.ai")
+
+	env, err := authenv.New(mockProfile, mockOrg)
+	require.NoError(t, err)
+	s, err := controllers.NewServer(env, a, apiKeyServer)
+	require.NoError(t, err)
+
+	resp, err := s.GetAugmentedTokenForAPIKey(context.Background(), &authpb.GetAugmentedTokenForAPIKeyRequest{
+		APIKey: "test_api",
+	})
+	require.NoError(t, err)
+
+	// The token shouldn't outlive the key.
+	assert.Equal(t, keyExpiresAt.Unix(), resp.ExpiresAt)
+
+	parsed, err := srvutils.ParseToken(resp.Token, "jwtkey", "withpixie.ai")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"user", "role:viewer"}, srvutils.GetScopes(parsed))
+}
+
+func TestServer_GetAugmentedTokenFromAPIKey_OwnerDemoted(t *testing.T) {
+	tests := []struct {
+		name           string
+		keyScopes      []string
+		ownerRoles     []string
+		expectedScopes []string
+	}{
+		{
+			name:           "scoped key",
+			keyScopes:      []string{"role:editor"},
+			ownerRoles:     []string{"viewer"},
+			expectedScopes: []string{"user", "role:viewer"},
+		},
+		{
+			name:           "unscoped key",
+			ownerRoles:     []string{"viewer"},
+			expectedScopes: []string{"user", "role:viewer"},
+		},
+		{
+			name:           "owner without roles",
+			keyScopes:      []string{"role:editor"},
+			expectedScopes: []string{"user"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			a := mock_controllers.NewMockAuthProvider(ctrl)
+			apiKeyServer := mock_controllers.NewMockAPIKeyMgr(ctrl)
+			apiKeyServer.EXPECT().AuthenticateAPIKey(gomock.Any(), "test_api").Return(&authpb.APIKey{
+				OrgID:  utils.ProtoFromUUIDStrOrNil(testingutils.TestOrgID),
+				UserID: utils.ProtoFromUUIDStrOrNil(testingutils.TestUserID),
+				Scopes: test.keyScopes,
+			}, nil)
+
+			mockProfile := mock_profile.NewMockProfileServiceClient(ctrl)
+			mockOrg := mock_profile.NewMockOrgServiceClient(ctrl)
+			mockOrg.EXPECT().
+				GetOrg(gomock.Any(), utils.ProtoFromUUIDStrOrNil(testingutils.TestOrgID)).
+				Return(&profilepb.OrgInfo{ID: utils.ProtoFromUUIDStrOrNil(testingutils.TestOrgID)}, nil)
+			mockProfile.EXPECT().
+				GetUser(gomock.Any(), utils.ProtoFromUUIDStrOrNil(testingutils.TestUserID)).
+				Return(&profilepb.UserInfo{
+					ID:    utils.ProtoFromUUIDStrOrNil(testingutils.TestUserID),
+					OrgID: utils.ProtoFromUUIDStrOrNil(testingutils.TestOrgID),
+					Roles: test.ownerRoles,
+				}, nil)
+
+			viper.Set("jwt_signing_key", "jwtkey")
+			viper.Set("domain_name", "withpixie.ai")
+
+			env, err := authenv.New(mockProfile, mockOrg)
+			require.NoError(t, err)
+			s, err := controllers.NewServer(env, a, apiKeyServer)
+			require.NoError(t, err)
+
+			resp, err := s.GetAugmentedTokenForAPIKey(context.Background(), &authpb.GetAugmentedTokenForAPIKeyRequest{
+				APIKey: "test_api",
+			})
+			require.NoError(t, err)
+
+			parsed, err := srvutils.ParseToken(resp.Token, "jwtkey", "withpixie.ai")
+			require.NoError(t, err)
+			assert.ElementsMatch(t, test.expectedScopes, srvutils.GetScopes(parsed))
+		})
+	}
 }
 
 func TestServer_Signup_LookupHostedDomain(t *testing.T) {
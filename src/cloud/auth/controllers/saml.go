@@ -0,0 +1,497 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/russellhaering/goxmldsig/etreeutils"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	samlIdentityProvider = "saml"
+
+	samlAssertionNS = "urn:oasis:names:tc:SAML:2.0:assertion"
+	samlProtocolNS  = "urn:oasis:names:tc:SAML:2.0:protocol"
+	samlStatusOK    = "urn:oasis:names:tc:SAML:2.0:status:Success"
+
+	// The allowed clock drift between Pixie Cloud and the IdP when checking assertion validity windows.
+	samlClockSkew = 1 * time.Minute
+)
+
+func init() {
+	pflag.String("saml_sp_entity_id", "", "The SAML entity ID of Pixie Cloud, this must match the audience of the assertions")
+	pflag.String("saml_acs_url", "", "The SAML assertion consumer service URL that the IdPs post their responses to")
+	pflag.String("saml_idp_config", "", "Path to the YAML file containing the per-org SAML IdP configuration")
+}
+
+// SAMLAttributeMapping names the assertion attributes that hold the user's information.
+type SAMLAttributeMapping struct {
+	Email     string `yaml:"email"`
+	FirstName string `yaml:"firstName"`
+	LastName  string `yaml:"lastName"`
+	Name      string `yaml:"name"`
+	Groups    string `yaml:"groups"`
+}
+
+// SAMLIdPConfig is the configuration for a single org's SAML IdP.
+type SAMLIdPConfig struct {
+	// EntityID is the issuer of the IdP's assertions.
+	EntityID string `yaml:"entityID"`
+	// OrgDomain is the domain of the org that users of this IdP belong to. Users are provisioned
+	// into the org on their first login.
+	OrgDomain string `yaml:"orgDomain"`
+	// Certificates are the PEM encoded certificates the IdP signs its assertions with. More than
+	// one certificate may be specified while the IdP rotates its signing key.
+	Certificates []string `yaml:"certificates"`
+	// Attributes overrides the default attribute names.
+	Attributes SAMLAttributeMapping `yaml:"attributes"`
+	// GroupRoles maps the IdP's groups to Pixie roles.
+	GroupRoles map[string][]string `yaml:"groupRoles"`
+
+	certs []*x509.Certificate
+}
+
+// SAMLConfig is the format of the saml_idp_config file.
+type SAMLConfig struct {
+	IdPs []*SAMLIdPConfig `yaml:"idps"`
+}
+
+var defaultSAMLAttributes = SAMLAttributeMapping{
+	Email:     "email",
+	FirstName: "firstName",
+	LastName:  "lastName",
+	Name:      "name",
+	Groups:    "groups",
+}
+
+// SAMLConnector implements the AuthProvider interface for SAML 2.0 IdPs. The access token is the
+// base64 encoded SAMLResponse that the IdP posted to the assertion consumer service.
+type SAMLConnector struct {
+	EntityID string
+	ACSURL   string
+
+	idps map[string]*SAMLIdPConfig
+
+	// seenAssertions tracks the IDs of recently consumed assertions, to prevent them from being replayed
+	// until they expire.
+	seenMu         sync.Mutex
+	seenAssertions map[string]time.Time
+
+	now func() time.Time
+}
+
+// NewSAMLConnector provides an implementation of a SAMLConnector.
+func NewSAMLConnector() (*SAMLConnector, error) {
+	configPath := viper.GetString("saml_idp_config")
+	if configPath == "" {
+		return nil, errors.New("SAML IdP config missing")
+	}
+	b, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &SAMLConfig{}
+	if err := yaml.Unmarshal(b, cfg); err != nil {
+		return nil, err
+	}
+	return NewSAMLConnectorWithConfig(viper.GetString("saml_sp_entity_id"), viper.GetString("saml_acs_url"), cfg)
+}
+
+// NewSAMLConnectorWithConfig creates a SAMLConnector for the given service provider and IdPs.
+func NewSAMLConnectorWithConfig(entityID, acsURL string, cfg *SAMLConfig) (*SAMLConnector, error) {
+	if entityID == "" {
+		return nil, errors.New("SAML service provider entity ID missing")
+	}
+	if acsURL == "" {
+		return nil, errors.New("SAML assertion consumer service URL missing")
+	}
+	if cfg == nil || len(cfg.IdPs) == 0 {
+		return nil, errors.New("no SAML IdPs configured")
+	}
+
+	idps := make(map[string]*SAMLIdPConfig)
+	for _, idp := range cfg.IdPs {
+		if idp.EntityID == "" {
+			return nil, errors.New("SAML IdP entity ID missing")
+		}
+		if _, ok := idps[idp.EntityID]; ok {
+			return nil, fmt.Errorf("duplicate SAML IdP %s", idp.EntityID)
+		}
+		if len(idp.Certificates) == 0 {
+			return nil, fmt.Errorf("no certificates configured for SAML IdP %s", idp.EntityID)
+		}
+		for _, certPEM := range idp.Certificates {
+			cert, err := parseSAMLCertificate(certPEM)
+			if err != nil {
+				return nil, fmt.Errorf("invalid certificate for SAML IdP %s: %w", idp.EntityID, err)
+			}
+			idp.certs = append(idp.certs, cert)
+		}
+		idp.Attributes = idp.Attributes.withDefaults()
+		idps[idp.EntityID] = idp
+	}
+
+	return &SAMLConnector{
+		EntityID:       entityID,
+		ACSURL:         acsURL,
+		idps:           idps,
+		seenAssertions: make(map[string]time.Time),
+		now:            time.Now,
+	}, nil
+}
+
+func parseSAMLCertificate(certStr string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(certStr))
+	if block != nil {
+		return x509.ParseCertificate(block.Bytes)
+	}
+	// IdP metadata usually holds the bare base64 DER certificate.
+	der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(certStr), ""))
+	if err != nil {
+		return nil, errors.New("certificate is neither PEM nor base64 DER")
+	}
+	return x509.ParseCertificate(der)
+}
+
+func (m SAMLAttributeMapping) withDefaults() SAMLAttributeMapping {
+	if m.Email == "" {
+		m.Email = defaultSAMLAttributes.Email
+	}
+	if m.FirstName == "" {
+		m.FirstName = defaultSAMLAttributes.FirstName
+	}
+	if m.LastName == "" {
+		m.LastName = defaultSAMLAttributes.LastName
+	}
+	if m.Name == "" {
+		m.Name = defaultSAMLAttributes.Name
+	}
+	if m.Groups == "" {
+		m.Groups = defaultSAMLAttributes.Groups
+	}
+	return m
+}
+
+// GetUserInfoFromAccessToken validates the SAMLResponse and returns the UserInfo from its assertion.
+func (c *SAMLConnector) GetUserInfoFromAccessToken(accessToken string) (*UserInfo, error) {
+	raw, err := base64.StdEncoding.DecodeString(accessToken)
+	if err != nil {
+		return nil, errors.New("SAML response is not base64 encoded")
+	}
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(raw); err != nil {
+		return nil, errors.New("SAML response is not valid XML")
+	}
+	resp := doc.Root()
+	if resp == nil || resp.Tag != "Response" || resp.NamespaceURI() != samlProtocolNS {
+		return nil, errors.New("expected a SAML Response")
+	}
+
+	if dest := resp.SelectAttrValue("Destination", ""); dest != "" && dest != c.ACSURL {
+		return nil, fmt.Errorf("SAML response destination %s does not match", dest)
+	}
+	status := samlChild(resp, samlProtocolNS, "Status")
+	statusCode := samlChild(status, samlProtocolNS, "StatusCode")
+	if statusCode == nil || statusCode.SelectAttrValue("Value", "") != samlStatusOK {
+		return nil, errors.New("SAML response does not have a success status")
+	}
+	if samlChild(resp, samlAssertionNS, "EncryptedAssertion") != nil {
+		return nil, errors.New("encrypted SAML assertions are not supported")
+	}
+
+	assertion := samlChild(resp, samlAssertionNS, "Assertion")
+	if assertion == nil {
+		return nil, errors.New("SAML response has no assertion")
+	}
+	issuer := strings.TrimSpace(samlText(samlChild(assertion, samlAssertionNS, "Issuer")))
+	idp, ok := c.idps[issuer]
+	if !ok {
+		return nil, fmt.Errorf("unknown SAML IdP %s", issuer)
+	}
+
+	// Only ever read the assertion from the element returned by signature validation, so that
+	// unsigned content can't be smuggled in beside the signed content.
+	assertion, err = c.verifiedAssertion(idp, resp, assertion)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkAssertion(assertion); err != nil {
+		return nil, err
+	}
+	return c.userInfoFromAssertion(idp, assertion)
+}
+
+func (c *SAMLConnector) verifiedAssertion(idp *SAMLIdPConfig, resp, assertion *etree.Element) (*etree.Element, error) {
+	vctx := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{Roots: idp.certs})
+	vctx.Clock = dsig.NewFakeClockAt(c.now())
+
+	if samlChild(resp, dsig.Namespace, "Signature") != nil {
+		validated, err := vctx.Validate(resp)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SAML response signature: %w", err)
+		}
+		assertion = samlChild(validated, samlAssertionNS, "Assertion")
+		if assertion == nil {
+			return nil, errors.New("signed SAML response has no assertion")
+		}
+		// The assertion may be signed as well, in which case that signature must hold too.
+		if samlChild(assertion, dsig.Namespace, "Signature") == nil {
+			return assertion, nil
+		}
+	}
+
+	// The assertion is usually relying on namespaces declared on the Response, so those must be copied
+	// onto the assertion before it is validated on its own.
+	detached, err := detachSAMLElement(assertion)
+	if err != nil {
+		return nil, err
+	}
+	validated, err := vctx.Validate(detached)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SAML assertion signature: %w", err)
+	}
+	return validated, nil
+}
+
+func detachSAMLElement(el *etree.Element) (*etree.Element, error) {
+	nsCtx, err := etreeutils.NSBuildParentContext(el)
+	if err != nil {
+		return nil, err
+	}
+	return etreeutils.NSDetatch(nsCtx, el)
+}
+
+func (c *SAMLConnector) checkAssertion(assertion *etree.Element) error {
+	now := c.now()
+
+	conditions := samlChild(assertion, samlAssertionNS, "Conditions")
+	if conditions == nil {
+		return errors.New("SAML assertion has no conditions")
+	}
+	notOnOrAfter, err := checkSAMLWindow(conditions, now)
+	if err != nil {
+		return err
+	}
+	audienceOK := false
+	for _, restriction := range samlChildren(conditions, samlAssertionNS, "AudienceRestriction") {
+		for _, audience := range samlChildren(restriction, samlAssertionNS, "Audience") {
+			if strings.TrimSpace(samlText(audience)) == c.EntityID {
+				audienceOK = true
+			}
+		}
+	}
+	if !audienceOK {
+		return errors.New("SAML assertion is not intended for this service provider")
+	}
+
+	subject := samlChild(assertion, samlAssertionNS, "Subject")
+	if subject == nil {
+		return errors.New("SAML assertion has no subject")
+	}
+	bearerOK := false
+	for _, confirmation := range samlChildren(subject, samlAssertionNS, "SubjectConfirmation") {
+		if confirmation.SelectAttrValue("Method", "") != "urn:oasis:names:tc:SAML:2.0:cm:bearer" {
+			continue
+		}
+		data := samlChild(confirmation, samlAssertionNS, "SubjectConfirmationData")
+		if data == nil || data.SelectAttrValue("Recipient", "") != c.ACSURL {
+			continue
+		}
+		if _, err := checkSAMLWindow(data, now); err != nil {
+			continue
+		}
+		bearerOK = true
+	}
+	if !bearerOK {
+		return errors.New("SAML assertion has no valid bearer subject confirmation")
+	}
+
+	id := assertion.SelectAttrValue("ID", "")
+	if id == "" {
+		return errors.New("SAML assertion has no ID")
+	}
+	if notOnOrAfter.IsZero() {
+		// Without an expiry, remember the assertion for as long as the clock skew allows.
+		notOnOrAfter = now.Add(samlClockSkew)
+	}
+	return c.markAssertionSeen(id, notOnOrAfter.Add(samlClockSkew))
+}
+
+// checkSAMLWindow checks the NotBefore and NotOnOrAfter attributes of the element, and returns the expiry.
+func checkSAMLWindow(el *etree.Element, now time.Time) (time.Time, error) {
+	var notOnOrAfter time.Time
+	if v := el.SelectAttrValue("NotBefore", ""); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return notOnOrAfter, fmt.Errorf("invalid NotBefore %s", v)
+		}
+		if now.Add(samlClockSkew).Before(t) {
+			return notOnOrAfter, errors.New("SAML assertion is not yet valid")
+		}
+	}
+	if v := el.SelectAttrValue("NotOnOrAfter", ""); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return notOnOrAfter, fmt.Errorf("invalid NotOnOrAfter %s", v)
+		}
+		if !now.Add(-samlClockSkew).Before(t) {
+			return notOnOrAfter, errors.New("SAML assertion has expired")
+		}
+		notOnOrAfter = t
+	}
+	return notOnOrAfter, nil
+}
+
+func (c *SAMLConnector) markAssertionSeen(id string, expiry time.Time) error {
+	c.seenMu.Lock()
+	defer c.seenMu.Unlock()
+
+	now := c.now()
+	for seenID, seenExpiry := range c.seenAssertions {
+		if now.After(seenExpiry) {
+			delete(c.seenAssertions, seenID)
+		}
+	}
+	if _, ok := c.seenAssertions[id]; ok {
+		return errors.New("SAML assertion has already been used")
+	}
+	c.seenAssertions[id] = expiry
+	return nil
+}
+
+func (c *SAMLConnector) userInfoFromAssertion(idp *SAMLIdPConfig, assertion *etree.Element) (*UserInfo, error) {
+	nameID := strings.TrimSpace(samlText(samlChild(samlChild(assertion, samlAssertionNS, "Subject"), samlAssertionNS, "NameID")))
+	if nameID == "" {
+		return nil, errors.New("SAML assertion has no NameID")
+	}
+
+	attrs := make(map[string][]string)
+	for _, stmt := range samlChildren(assertion, samlAssertionNS, "AttributeStatement") {
+		for _, attr := range samlChildren(stmt, samlAssertionNS, "Attribute") {
+			name := attr.SelectAttrValue("Name", "")
+			for _, val := range samlChildren(attr, samlAssertionNS, "AttributeValue") {
+				attrs[name] = append(attrs[name], strings.TrimSpace(samlText(val)))
+			}
+		}
+	}
+	first := func(name string) string {
+		if vals := attrs[name]; len(vals) > 0 {
+			return vals[0]
+		}
+		return ""
+	}
+
+	email := first(idp.Attributes.Email)
+	if email == "" && strings.Contains(nameID, "@") {
+		email = nameID
+	}
+	if email == "" {
+		return nil, errors.New("SAML assertion has no email")
+	}
+
+	info := &UserInfo{
+		Email: email,
+		// The IdP is trusted to have verified the emails of the users in its directory.
+		EmailVerified:    true,
+		FirstName:        first(idp.Attributes.FirstName),
+		LastName:         first(idp.Attributes.LastName),
+		Name:             first(idp.Attributes.Name),
+		IdentityProvider: samlIdentityProvider,
+		// NameIDs are only unique per IdP.
+		AuthProviderID: fmt.Sprintf("%s|%s", idp.EntityID, nameID),
+		HostedDomain:   idp.OrgDomain,
+		Roles:          idp.rolesForGroups(attrs[idp.Attributes.Groups]),
+	}
+	if info.Name == "" {
+		info.Name = strings.TrimSpace(fmt.Sprintf("%s %s", info.FirstName, info.LastName))
+	}
+	return info, nil
+}
+
+// rolesForGroups maps the user's IdP groups to the deduplicated, sorted list of Pixie roles.
+func (idp *SAMLIdPConfig) rolesForGroups(groups []string) []string {
+	roleSet := make(map[string]bool)
+	for _, g := range groups {
+		for _, r := range idp.GroupRoles[g] {
+			roleSet[r] = true
+		}
+	}
+	if len(roleSet) == 0 {
+		return nil
+	}
+	roles := make([]string, 0, len(roleSet))
+	for r := range roleSet {
+		roles = append(roles, r)
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+func samlChildren(el *etree.Element, ns, tag string) []*etree.Element {
+	if el == nil {
+		return nil
+	}
+	var children []*etree.Element
+	for _, child := range el.ChildElements() {
+		if child.Tag == tag && child.NamespaceURI() == ns {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+func samlChild(el *etree.Element, ns, tag string) *etree.Element {
+	children := samlChildren(el, ns, tag)
+	if len(children) == 0 {
+		return nil
+	}
+	return children[0]
+}
+
+func samlText(el *etree.Element) string {
+	if el == nil {
+		return ""
+	}
+	return el.Text()
+}
+
+// CreateInviteLink implements the AuthProvider interface, but invites are managed by the IdP for SAML.
+func (c *SAMLConnector) CreateInviteLink(authProviderID string) (*CreateInviteLinkResponse, error) {
+	return nil, errors.New("pixie's SAML implementation does not support inviting users with InviteLinks")
+}
+
+// CreateIdentity implements the AuthProvider interface, but identities are managed by the IdP for SAML.
+func (c *SAMLConnector) CreateIdentity(string) (*CreateIdentityResponse, error) {
+	return nil, errors.New("pixie's SAML implementation does not support creating identities")
+}
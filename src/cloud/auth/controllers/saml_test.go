@@ -0,0 +1,248 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/auth/controllers"
+)
+
+const (
+	testSPEntityID  = "https://work.withpixie.ai/saml"
+	testACSURL      = "https://work.withpixie.ai/api/auth/saml/acs"
+	testIdPEntityID = "https://idp.example.com/metadata"
+)
+
+type samlAssertionOpts struct {
+	id           string
+	issuer       string
+	audience     string
+	recipient    string
+	notBefore    time.Time
+	notOnOrAfter time.Time
+	groups       []string
+}
+
+func defaultSAMLAssertionOpts() *samlAssertionOpts {
+	now := time.Now()
+	return &samlAssertionOpts{
+		id:           "_assertion1",
+		issuer:       testIdPEntityID,
+		audience:     testSPEntityID,
+		recipient:    testACSURL,
+		notBefore:    now.Add(-2 * time.Minute),
+		notOnOrAfter: now.Add(5 * time.Minute),
+		groups:       []string{"eng", "oncall"},
+	}
+}
+
+func makeSAMLAssertion(opts *samlAssertionOpts) string {
+	groups := ""
+	for _, g := range opts.groups {
+		groups += fmt.Sprintf("<saml:AttributeValue>%s</saml:AttributeValue>", g)
+	}
+	return fmt.Sprintf(`<saml:Assertion xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="%s" Version="2.0">`+
+		`<saml:Issuer>%s</saml:Issuer>`+
+		`<saml:Subject><saml:NameID>u123</saml:NameID>`+
+		`<saml:SubjectConfirmation Method="urn:oasis:names:tc:SAML:2.0:cm:bearer">`+
+		`<saml:SubjectConfirmationData Recipient="%s" NotOnOrAfter="%s"/></saml:SubjectConfirmation></saml:Subject>`+
+		`<saml:Conditions NotBefore="%s" NotOnOrAfter="%s">`+
+		`<saml:AudienceRestriction><saml:Audience>%s</saml:Audience></saml:AudienceRestriction></saml:Conditions>`+
+		`<saml:AttributeStatement>`+
+		`<saml:Attribute Name="email"><saml:AttributeValue>abc@example.com</saml:AttributeValue></saml:Attribute>`+
+		`<saml:Attribute Name="firstName"><saml:AttributeValue>first</saml:AttributeValue></saml:Attribute>`+
+		`<saml:Attribute Name="lastName"><saml:AttributeValue>last</saml:AttributeValue></saml:Attribute>`+
+		`<saml:Attribute Name="groups">%s</saml:Attribute>`+
+		`</saml:AttributeStatement></saml:Assertion>`,
+		opts.id, opts.issuer, opts.recipient, opts.notOnOrAfter.UTC().Format(time.RFC3339),
+		opts.notBefore.UTC().Format(time.RFC3339), opts.notOnOrAfter.UTC().Format(time.RFC3339),
+		opts.audience, groups)
+}
+
+func signSAMLAssertion(t *testing.T, ks dsig.X509KeyStore, assertion string) string {
+	doc := etree.NewDocument()
+	require.NoError(t, doc.ReadFromString(assertion))
+	ctx := dsig.NewDefaultSigningContext(ks)
+	ctx.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+	signed, err := ctx.SignEnveloped(doc.Root())
+	require.NoError(t, err)
+	out := etree.NewDocument()
+	out.SetRoot(signed)
+	s, err := out.WriteToString()
+	require.NoError(t, err)
+	return s
+}
+
+func makeSAMLResponse(assertion string) string {
+	resp := `<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" ID="_resp1" Version="2.0" Destination="` + testACSURL + `">` +
+		`<samlp:Status><samlp:StatusCode Value="urn:oasis:names:tc:SAML:2.0:status:Success"/></samlp:Status>` +
+		assertion + `</samlp:Response>`
+	return base64.StdEncoding.EncodeToString([]byte(resp))
+}
+
+func newTestSAMLConnector(t *testing.T, ks dsig.X509KeyStore) *controllers.SAMLConnector {
+	_, certDER, err := ks.GetKeyPair()
+	require.NoError(t, err)
+	certPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}))
+
+	c, err := controllers.NewSAMLConnectorWithConfig(testSPEntityID, testACSURL, &controllers.SAMLConfig{
+		IdPs: []*controllers.SAMLIdPConfig{
+			{
+				EntityID:     testIdPEntityID,
+				OrgDomain:    "example.com",
+				Certificates: []string{certPEM},
+				GroupRoles: map[string][]string{
+					"eng":    {"viewer"},
+					"oncall": {"viewer", "editor"},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	return c
+}
+
+func TestSAMLConnector_GetUserInfo(t *testing.T) {
+	ks := dsig.RandomKeyStoreForTest()
+	c := newTestSAMLConnector(t, ks)
+
+	token := makeSAMLResponse(signSAMLAssertion(t, ks, makeSAMLAssertion(defaultSAMLAssertionOpts())))
+	info, err := c.GetUserInfoFromAccessToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, &controllers.UserInfo{
+		Email:            "abc@example.com",
+		EmailVerified:    true,
+		FirstName:        "first",
+		LastName:         "last",
+		Name:             "first last",
+		IdentityProvider: "saml",
+		AuthProviderID:   testIdPEntityID + "|u123",
+		HostedDomain:     "example.com",
+		Roles:            []string{"editor", "viewer"},
+	}, info)
+
+	// The same assertion can't be used twice.
+	_, err = c.GetUserInfoFromAccessToken(token)
+	assert.ErrorContains(t, err, "already been used")
+}
+
+func TestSAMLConnector_InvalidAssertions(t *testing.T) {
+	ks := dsig.RandomKeyStoreForTest()
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		modify func(*samlAssertionOpts)
+		token  func(string) string
+		errMsg string
+	}{
+		{
+			name:   "unknown issuer",
+			modify: func(o *samlAssertionOpts) { o.issuer = "https://other-idp" },
+			errMsg: "unknown SAML IdP",
+		},
+		{
+			name:   "wrong audience",
+			modify: func(o *samlAssertionOpts) { o.audience = "https://other-sp" },
+			errMsg: "not intended for this service provider",
+		},
+		{
+			name:   "wrong recipient",
+			modify: func(o *samlAssertionOpts) { o.recipient = "https://other-sp/acs" },
+			errMsg: "no valid bearer subject confirmation",
+		},
+		{
+			name: "expired",
+			modify: func(o *samlAssertionOpts) {
+				o.notBefore = now.Add(-time.Hour)
+				o.notOnOrAfter = now.Add(-30 * time.Minute)
+			},
+			errMsg: "expired",
+		},
+		{
+			name:   "not yet valid",
+			modify: func(o *samlAssertionOpts) { o.notBefore = now.Add(time.Hour) },
+			errMsg: "not yet valid",
+		},
+		{
+			name: "tampered",
+			token: func(signed string) string {
+				return makeSAMLResponse(strings.Replace(signed, "abc@example.com", "admin@example.com", 1))
+			},
+			errMsg: "invalid SAML assertion signature",
+		},
+		{
+			name: "unsigned",
+			token: func(string) string {
+				return makeSAMLResponse(makeSAMLAssertion(defaultSAMLAssertionOpts()))
+			},
+			errMsg: "invalid SAML assertion signature",
+		},
+		{
+			name:   "not base64",
+			token:  func(string) string { return "<xml>" },
+			errMsg: "not base64 encoded",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := newTestSAMLConnector(t, ks)
+			opts := defaultSAMLAssertionOpts()
+			if test.modify != nil {
+				test.modify(opts)
+			}
+			signed := signSAMLAssertion(t, ks, makeSAMLAssertion(opts))
+			token := makeSAMLResponse(signed)
+			if test.token != nil {
+				token = test.token(signed)
+			}
+			_, err := c.GetUserInfoFromAccessToken(token)
+			assert.ErrorContains(t, err, test.errMsg)
+		})
+	}
+}
+
+func TestSAMLConnector_UntrustedCertificate(t *testing.T) {
+	c := newTestSAMLConnector(t, dsig.RandomKeyStoreForTest())
+	token := makeSAMLResponse(signSAMLAssertion(t, dsig.RandomKeyStoreForTest(), makeSAMLAssertion(defaultSAMLAssertionOpts())))
+	_, err := c.GetUserInfoFromAccessToken(token)
+	assert.ErrorContains(t, err, "invalid SAML assertion signature")
+}
+
+func TestNewSAMLConnectorWithConfig_Invalid(t *testing.T) {
+	_, err := controllers.NewSAMLConnectorWithConfig(testSPEntityID, testACSURL, &controllers.SAMLConfig{})
+	assert.Error(t, err)
+
+	_, err = controllers.NewSAMLConnectorWithConfig(testSPEntityID, testACSURL, &controllers.SAMLConfig{
+		IdPs: []*controllers.SAMLIdPConfig{{EntityID: testIdPEntityID, Certificates: []string{"not a cert"}}},
+	})
+	assert.ErrorContains(t, err, "invalid certificate")
+}
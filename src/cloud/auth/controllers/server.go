@@ -21,14 +21,13 @@ package controllers
 import (
 	"context"
 
-	"github.com/gofrs/uuid"
-
 	"px.dev/pixie/src/cloud/auth/authenv"
+	"px.dev/pixie/src/cloud/auth/authpb"
 )
 
 // APIKeyMgr is the internal interface for managing API keys.
 type APIKeyMgr interface {
-	FetchOrgUserIDUsingAPIKey(ctx context.Context, key string) (uuid.UUID, uuid.UUID, error)
+	AuthenticateAPIKey(ctx context.Context, key string) (*authpb.APIKey, error)
 }
 
 // UserInfo contains all the info about a user. It's not tied to any specific AuthProvider.
@@ -50,6 +49,9 @@ type UserInfo struct {
 	// HostedDomain is the name of an org that a user belongs to according to the IdentityProvider.
 	// If empty, the IdentityProvider does not consider the user as part of an org.
 	HostedDomain string
+	// Roles are the Pixie roles that the IdentityProvider's groups map to. Only set by AuthProviders
+	// that support group mapping.
+	Roles []string
 }
 
 // CreateInviteLinkResponse contaions the InviteLink and any accompanying information.
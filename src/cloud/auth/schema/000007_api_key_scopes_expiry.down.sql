@@ -0,0 +1,8 @@
+ALTER TABLE api_keys
+  DROP COLUMN scopes;
+
+ALTER TABLE api_keys
+  DROP COLUMN expires_at;
+
+ALTER TABLE api_keys
+  DROP COLUMN last_used_at;
@@ -0,0 +1,9 @@
+-- Space separated role scopes that restrict the key. Empty for keys with the full access of their owner.
+ALTER TABLE api_keys
+  ADD COLUMN scopes varchar(4000) NOT NULL DEFAULT '';
+
+ALTER TABLE api_keys
+  ADD COLUMN expires_at TIMESTAMP;
+
+ALTER TABLE api_keys
+  ADD COLUMN last_used_at TIMESTAMP;
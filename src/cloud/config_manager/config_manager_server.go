@@ -34,6 +34,7 @@ import (
 	"px.dev/pixie/src/shared/services/env"
 	"px.dev/pixie/src/shared/services/healthz"
 	"px.dev/pixie/src/shared/services/server"
+	"px.dev/pixie/src/utils/shared/artifacts"
 )
 
 func init() {
@@ -43,6 +44,8 @@ func init() {
 	pflag.String("dev_sentry", "", "Key for dev Viziers that is used to send errors and stacktraces to Sentry.")
 	pflag.String("operator_sentry", "", "Key for prod Operators that is used to send errors and stacktraces to Sentry.")
 	pflag.String("ld_sdk_key", "", "LaunchDarkly SDK key for feature flags.")
+	pflag.String("artifact_keyring", "", "Path to an armored GPG public keyring that downloaded Vizier templates must be signed by.")
+	pflag.Bool("skip_artifact_verification", false, "Use downloaded Vizier templates without verifying their checksum and signature.")
 }
 
 func newArtifactTrackerClient() (atpb.ArtifactTrackerClient, error) {
@@ -93,7 +96,11 @@ func main() {
 	if err != nil {
 		log.WithError(err).Fatal("Could not connect with Artifact Service.")
 	}
-	svr := controllers.NewServer(atClient, deployKeyClient, viper.GetString("ld_sdk_key"))
+	verifyOpts, err := artifacts.LoadVerificationOptions(viper.GetString("artifact_keyring"), viper.GetBool("skip_artifact_verification"))
+	if err != nil {
+		log.WithError(err).Fatal("Could not load artifact signing keyring.")
+	}
+	svr := controllers.NewServer(atClient, deployKeyClient, viper.GetString("ld_sdk_key"), verifyOpts)
 	serverOpts := &server.GRPCServerOptions{
 		DisableAuth: map[string]bool{
 			"/px.services.ConfigManagerService/GetConfigForVizier":   true,
@@ -19,6 +19,7 @@
 package controllers
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -42,6 +43,7 @@ import (
 	versionspb "px.dev/pixie/src/shared/artifacts/versionspb"
 	srvutils "px.dev/pixie/src/shared/services/utils"
 	"px.dev/pixie/src/utils"
+	"px.dev/pixie/src/utils/shared/artifacts"
 	"px.dev/pixie/src/utils/shared/tar"
 	yamls "px.dev/pixie/src/utils/shared/yamls"
 	vizieryamls "px.dev/pixie/src/utils/template_generator/vizier_yamls"
@@ -52,14 +54,16 @@ type Server struct {
 	atClient            atpb.ArtifactTrackerClient
 	deployKeyClient     vzmgrpb.VZDeploymentKeyServiceClient
 	vzFeatureFlagClient VizierFeatureFlagClient
+	verifyOpts          *artifacts.VerificationOptions
 }
 
-// NewServer creates GRPC handlers.
-func NewServer(atClient atpb.ArtifactTrackerClient, deployKeyClient vzmgrpb.VZDeploymentKeyServiceClient, ldSDKKey string) *Server {
+// NewServer creates GRPC handlers. Downloaded Vizier templates are checked with the given verification options.
+func NewServer(atClient atpb.ArtifactTrackerClient, deployKeyClient vzmgrpb.VZDeploymentKeyServiceClient, ldSDKKey string, verifyOpts *artifacts.VerificationOptions) *Server {
 	return &Server{
 		atClient:            atClient,
 		deployKeyClient:     deployKeyClient,
 		vzFeatureFlagClient: NewVizierFeatureFlagClient(ldSDKKey),
+		verifyOpts:          verifyOpts,
 	}
 }
 
@@ -138,7 +142,7 @@ func (s *Server) GetConfigForVizier(ctx context.Context,
 	in *cpb.ConfigForVizierRequest) (*cpb.ConfigForVizierResponse, error) {
 	log.Info("Fetching config for Vizier")
 
-	templatedYAMLs, err := fetchVizierTemplates(ctx, "", in.VzSpec.Version, s.atClient)
+	templatedYAMLs, err := fetchVizierTemplates(ctx, "", in.VzSpec.Version, s.atClient, s.verifyOpts)
 	if err != nil {
 		log.WithError(err).Error("Failed to fetch Vizier templates")
 		return nil, err
@@ -180,21 +184,22 @@ func (s *Server) GetConfigForVizier(ctx context.Context,
 	// We should eventually clean up the templating code, since our Helm charts and extracted YAMLs will now just
 	// be simple CRDs.
 	tmplValues := &vizieryamls.VizierTmplValues{
-		DeployKey:             in.VzSpec.DeployKey,
-		CustomDeployKeySecret: in.VzSpec.CustomDeployKeySecret,
-		UseEtcdOperator:       in.VzSpec.UseEtcdOperator,
-		PEMMemoryLimit:        pemMemoryLimit,
-		PEMMemoryRequest:      pemMemoryRequest,
-		Namespace:             in.Namespace,
-		CloudAddr:             cloudAddr,
-		CloudUpdateAddr:       updateCloudAddr,
-		ClusterName:           in.VzSpec.ClusterName,
-		DisableAutoUpdate:     in.VzSpec.DisableAutoUpdate,
-		SentryDSN:             getSentryDSN(in.VzSpec.Version),
-		ClockConverter:        in.VzSpec.ClockConverter,
-		DataAccess:            in.VzSpec.DataAccess,
-		Registry:              in.VzSpec.Registry,
-		UseBetaPdbVersion:     useBetaPDB,
+		DeployKey:                 in.VzSpec.DeployKey,
+		CustomDeployKeySecret:     in.VzSpec.CustomDeployKeySecret,
+		UseEtcdOperator:           in.VzSpec.UseEtcdOperator,
+		PEMMemoryLimit:            pemMemoryLimit,
+		PEMMemoryRequest:          pemMemoryRequest,
+		Namespace:                 in.Namespace,
+		CloudAddr:                 cloudAddr,
+		CloudUpdateAddr:           updateCloudAddr,
+		ClusterName:               in.VzSpec.ClusterName,
+		DisableAutoUpdate:         in.VzSpec.DisableAutoUpdate,
+		SentryDSN:                 getSentryDSN(in.VzSpec.Version),
+		ClockConverter:            in.VzSpec.ClockConverter,
+		DataAccess:                in.VzSpec.DataAccess,
+		Registry:                  in.VzSpec.Registry,
+		UseBetaPdbVersion:         useBetaPDB,
+		SingleProcessControlPlane: in.VzSpec.SingleProcessControlPlane,
 	}
 
 	if in.VzSpec.DataCollectorParams != nil && in.VzSpec.DataCollectorParams.DatastreamBufferSize != 0 {
@@ -263,10 +268,10 @@ func getSentryDSN(vizierVersion string) string {
 	return viper.GetString("prod_sentry")
 }
 
-// fetchVizierTemplates gets a download link, untars file, and
+// fetchVizierTemplates gets a download link, verifies and untars the file, and
 // converts to yaml maps.
 func fetchVizierTemplates(ctx context.Context, authToken,
-	versionStr string, atClient atpb.ArtifactTrackerClient) ([]*yamls.YAMLFile, error) {
+	versionStr string, atClient atpb.ArtifactTrackerClient, verifyOpts *artifacts.VerificationOptions) ([]*yamls.YAMLFile, error) {
 	req := &atpb.GetDownloadLinkRequest{
 		ArtifactName: "vizier",
 		VersionStr:   versionStr,
@@ -283,7 +288,15 @@ func fetchVizierTemplates(ctx context.Context, authToken,
 	}
 	defer reader.Close()
 
-	yamlMap, err := tar.ReadTarFileFromReader(reader)
+	templates, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyOpts.Verify(templates, resp.SHA256, resp.Signature); err != nil {
+		return nil, err
+	}
+
+	yamlMap, err := tar.ReadTarFileFromReader(bytes.NewReader(templates))
 	if err != nil {
 		return nil, err
 	}
@@ -202,6 +202,10 @@ func (s *Server) fetchScriptsForVizier(vizierID *uuidpb.UUID) (map[string]*cvmsg
 		log.WithError(err).Error("Could not find Vizier for org")
 		return nil, err
 	}
+	// Scripts aren't deployed to read-only Viziers.
+	if resp.ReadOnly {
+		return map[string]*cvmsgspb.CronScript{}, nil
+	}
 
 	// Fetch all scripts registered to this Vizier.
 	query := `SELECT id, script, cluster_ids, PGP_SYM_DECRYPT(configs, $1::text) as configs, frequency_s FROM cron_scripts WHERE org_id=$2 AND enabled=true`
@@ -631,7 +635,7 @@ func (s *Server) sendCronScriptUpdateToViziers(msg *cvmsgspb.CronScriptUpdate, o
 
 	for _, v := range vzInfoResp.VizierInfos {
 		vzUUID := utils.UUIDFromProtoOrNil(v.VizierID)
-		if v.Status != cvmsgspb.VZ_ST_DISCONNECTED && v.Status != cvmsgspb.VZ_ST_UNKNOWN {
+		if v.Status != cvmsgspb.VZ_ST_DISCONNECTED && v.Status != cvmsgspb.VZ_ST_UNKNOWN && !v.ReadOnly {
 			go s.retryMessageUntilResponse(c2vMsg, vzshard.C2VTopic(cvmsgs.CronScriptUpdatesChannel, vzUUID), vzshard.V2CTopic(fmt.Sprintf("%s:%s", cvmsgs.CronScriptUpdatesResponseChannel, msg.RequestID), vzUUID))
 		}
 	}
@@ -0,0 +1,195 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package alertroutes renders and sends alert events to first-party alert plugins (Slack and PagerDuty).
+package alertroutes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
+
+	"px.dev/pixie/src/shared/alerting"
+	"px.dev/pixie/src/shared/cvmsgspb"
+	"px.dev/pixie/src/utils"
+)
+
+// IDs of the supported alert plugins.
+const (
+	PluginSlack     = "slack"
+	PluginPagerDuty = "pagerduty"
+)
+
+const (
+	sendTimeout    = 10 * time.Second
+	statusResolved = "resolved"
+)
+
+var defaultTemplates = map[string]string{
+	PluginSlack: `{{if eq .Status "resolved"}}:white_check_mark:{{else}}:rotating_light:{{end}} ` +
+		`[{{upper .Status}}] {{.RuleName}}: value {{.Value}} {{.Operator}} {{.Threshold}}` +
+		`{{range $k, $v := .Labels}} {{$k}}={{$v}}{{end}} (cluster {{.ClusterID}})`,
+	PluginPagerDuty: `[{{upper .Status}}] {{.RuleName}}: value {{.Value}} {{.Operator}} {{.Threshold}}` +
+		`{{range $k, $v := .Labels}} {{$k}}={{$v}}{{end}}`,
+}
+
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// Event is an alert event received from a cluster. Its fields are available to route templates.
+type Event struct {
+	ClusterID   uuid.UUID
+	ScriptID    uuid.UUID
+	Fingerprint string
+	RuleName    string
+	Status      string
+	Severity    string
+	Labels      map[string]string
+	Value       float64
+	Threshold   float64
+	Operator    string
+	StartsAt    time.Time
+	Timestamp   time.Time
+}
+
+// EventFromProto converts an alert event sent by the given cluster.
+func EventFromProto(clusterID uuid.UUID, e *cvmsgspb.AlertEvent) (*Event, error) {
+	ev := &Event{
+		ClusterID:   clusterID,
+		ScriptID:    utils.UUIDFromProtoOrNil(e.ScriptID),
+		Fingerprint: e.Fingerprint,
+		RuleName:    e.RuleName,
+		Status:      e.Status,
+		Severity:    e.Severity,
+		Labels:      e.Labels,
+		Value:       e.Value,
+		Threshold:   e.Threshold,
+		Operator:    e.Operator,
+	}
+	var err error
+	if e.StartsAt != nil {
+		if ev.StartsAt, err = types.TimestampFromProto(e.StartsAt); err != nil {
+			return nil, err
+		}
+	}
+	if e.Timestamp != nil {
+		if ev.Timestamp, err = types.TimestampFromProto(e.Timestamp); err != nil {
+			return nil, err
+		}
+	}
+	return ev, nil
+}
+
+// Validate checks that the plugin is supported, and that the destination and template are valid for it.
+func Validate(pluginID, destination, tmpl string) error {
+	switch pluginID {
+	case PluginSlack:
+		u, err := url.Parse(destination)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("slack destination must be an https webhook URL")
+		}
+	case PluginPagerDuty:
+		if destination == "" {
+			return fmt.Errorf("pagerduty destination must be a routing key")
+		}
+	default:
+		return fmt.Errorf("unknown alert plugin '%s'", pluginID)
+	}
+	_, err := parseTemplate(pluginID, tmpl)
+	return err
+}
+
+func parseTemplate(pluginID, tmpl string) (*template.Template, error) {
+	if tmpl == "" {
+		tmpl = defaultTemplates[pluginID]
+	}
+	t, err := template.New(pluginID).Funcs(templateFuncs).Option("missingkey=zero").Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return t, nil
+}
+
+// Render renders the event with the given template. If the template is empty, the plugin's default template is used.
+func Render(pluginID, tmpl string, e *Event) (string, error) {
+	t, err := parseTemplate(pluginID, tmpl)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, e); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Sender sends rendered alert events to the alert plugins.
+type Sender struct {
+	Client       *http.Client
+	PagerDutyURL string
+}
+
+// NewSender creates a new sender.
+func NewSender() *Sender {
+	return &Sender{
+		Client:       &http.Client{Timeout: sendTimeout},
+		PagerDutyURL: alerting.DefaultPagerDutyURL,
+	}
+}
+
+// Send renders the event using the template and sends it to the plugin destination.
+func (s *Sender) Send(ctx context.Context, pluginID, destination, tmpl string, e *Event) error {
+	text, err := Render(pluginID, tmpl, e)
+	if err != nil {
+		return err
+	}
+	switch pluginID {
+	case PluginSlack:
+		return alerting.PostJSON(ctx, s.Client, destination, nil, map[string]string{"text": text})
+	case PluginPagerDuty:
+		return alerting.PostJSON(ctx, s.Client, s.PagerDutyURL, nil, pagerDutyEventFor(destination, text, e))
+	default:
+		return fmt.Errorf("unknown alert plugin '%s'", pluginID)
+	}
+}
+
+// pagerDutyEventFor builds the Events API v2 request for the event. The dedup key includes the cluster, since the
+// same alert rule can fire independently on each cluster running the script.
+func pagerDutyEventFor(routingKey, summary string, e *Event) *alerting.PagerDutyEvent {
+	dedupKey := fmt.Sprintf("%s-%s", e.ClusterID, e.Fingerprint)
+	if e.Status == statusResolved {
+		return alerting.NewPagerDutyEvent(routingKey, dedupKey, nil)
+	}
+	return alerting.NewPagerDutyEvent(routingKey, dedupKey, &alerting.PagerDutyPayload{
+		Summary:       summary,
+		Source:        fmt.Sprintf("pixie/%s", e.ClusterID),
+		Severity:      alerting.PagerDutySeverity(e.Severity),
+		Timestamp:     e.Timestamp.Format(time.RFC3339),
+		CustomDetails: e.Labels,
+	})
+}
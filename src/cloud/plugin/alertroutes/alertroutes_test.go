@@ -0,0 +1,122 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package alertroutes_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/plugin/alertroutes"
+)
+
+var testClusterID = uuid.FromStringOrNil("7ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+func testEvent(status string) *alertroutes.Event {
+	return &alertroutes.Event{
+		ClusterID:   testClusterID,
+		Fingerprint: "abcd",
+		RuleName:    "high_latency",
+		Status:      status,
+		Severity:    "critical",
+		Labels:      map[string]string{"service": "orders", "namespace": "px-sock-shop"},
+		Value:       350,
+		Threshold:   200,
+		Operator:    ">",
+		Timestamp:   time.Unix(1700000000, 0).UTC(),
+	}
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, alertroutes.Validate(alertroutes.PluginSlack, "https://hooks.slack.com/services/abc", ""))
+	assert.NoError(t, alertroutes.Validate(alertroutes.PluginPagerDuty, "routing-key", "{{.RuleName}}"))
+	assert.Error(t, alertroutes.Validate(alertroutes.PluginSlack, "http://hooks.slack.com/services/abc", ""))
+	assert.Error(t, alertroutes.Validate(alertroutes.PluginPagerDuty, "", ""))
+	assert.Error(t, alertroutes.Validate(alertroutes.PluginPagerDuty, "routing-key", "{{.RuleName"))
+	assert.Error(t, alertroutes.Validate("opsgenie", "key", ""))
+}
+
+func TestRender(t *testing.T) {
+	text, err := alertroutes.Render(alertroutes.PluginSlack, "", testEvent("firing"))
+	require.NoError(t, err)
+	assert.Equal(t, ":rotating_light: [FIRING] high_latency: value 350 > 200 namespace=px-sock-shop service=orders (cluster 7ba7b810-9dad-11d1-80b4-00c04fd430c8)", text)
+
+	text, err = alertroutes.Render(alertroutes.PluginSlack, `{{.Severity}}: {{.RuleName}} on {{index .Labels "service"}} is {{.Status}}`, testEvent("resolved"))
+	require.NoError(t, err)
+	assert.Equal(t, "critical: high_latency on orders is resolved", text)
+}
+
+func TestSender_Slack(t *testing.T) {
+	var body map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+	}))
+	defer srv.Close()
+
+	s := alertroutes.NewSender()
+	err := s.Send(context.Background(), alertroutes.PluginSlack, srv.URL, "{{.RuleName}} {{.Status}}", testEvent("firing"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"text": "high_latency firing"}, body)
+}
+
+func TestSender_PagerDuty(t *testing.T) {
+	var bodies []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		bodies = append(bodies, body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	s := alertroutes.NewSender()
+	s.PagerDutyURL = srv.URL
+	require.NoError(t, s.Send(context.Background(), alertroutes.PluginPagerDuty, "routing-key", "", testEvent("firing")))
+	require.NoError(t, s.Send(context.Background(), alertroutes.PluginPagerDuty, "routing-key", "", testEvent("resolved")))
+
+	require.Len(t, bodies, 2)
+	dedupKey := testClusterID.String() + "-abcd"
+	assert.Equal(t, "routing-key", bodies[0]["routing_key"])
+	assert.Equal(t, "trigger", bodies[0]["event_action"])
+	assert.Equal(t, dedupKey, bodies[0]["dedup_key"])
+	payload := bodies[0]["payload"].(map[string]interface{})
+	assert.Equal(t, "[FIRING] high_latency: value 350 > 200 namespace=px-sock-shop service=orders", payload["summary"])
+	assert.Equal(t, "critical", payload["severity"])
+
+	assert.Equal(t, "resolve", bodies[1]["event_action"])
+	assert.Equal(t, dedupKey, bodies[1]["dedup_key"])
+	assert.Nil(t, bodies[1]["payload"])
+}
+
+func TestSender_Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	s := alertroutes.NewSender()
+	assert.Error(t, s.Send(context.Background(), alertroutes.PluginSlack, srv.URL, "", testEvent("firing")))
+}
@@ -0,0 +1,156 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/proto"
+	"github.com/gogo/protobuf/types"
+	"github.com/nats-io/nats.go"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc/metadata"
+
+	"px.dev/pixie/src/cloud/plugin/alertroutes"
+	"px.dev/pixie/src/cloud/shared/vzshard"
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
+	"px.dev/pixie/src/shared/cvmsgs"
+	"px.dev/pixie/src/shared/cvmsgspb"
+	jwtutils "px.dev/pixie/src/shared/services/utils"
+	"px.dev/pixie/src/utils"
+)
+
+// AlertRouter listens for alert events sent by Viziers and sends them to the alert routes configured by the
+// Vizier's org.
+type AlertRouter struct {
+	server      *Server
+	nc          *nats.Conn
+	vzmgrClient vzmgrpb.VZMgrServiceClient
+	sender      *alertroutes.Sender
+
+	done chan struct{}
+	once sync.Once
+}
+
+// NewAlertRouter creates a new alert router, and starts handling alert events.
+func NewAlertRouter(server *Server, nc *nats.Conn, vzmgrClient vzmgrpb.VZMgrServiceClient, sender *alertroutes.Sender) *AlertRouter {
+	r := &AlertRouter{
+		server:      server,
+		nc:          nc,
+		vzmgrClient: vzmgrClient,
+		sender:      sender,
+		done:        make(chan struct{}),
+	}
+	for _, shard := range vzshard.GenerateShardRange() {
+		r.startShardedHandler(shard)
+	}
+	return r
+}
+
+// Stop stops handling alert events.
+func (r *AlertRouter) Stop() {
+	r.once.Do(func() {
+		close(r.done)
+	})
+}
+
+func (r *AlertRouter) startShardedHandler(shard string) {
+	natsCh := make(chan *nats.Msg, 8192)
+	sub, err := r.nc.ChanSubscribe(fmt.Sprintf("v2c.%s.*.%s", shard, cvmsgs.AlertEventChannel), natsCh)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to subscribe to NATS channel")
+	}
+
+	go func() {
+		for {
+			select {
+			case <-r.done:
+				sub.Unsubscribe()
+				return
+			case msg := <-natsCh:
+				pb := &cvmsgspb.V2CMessage{}
+				err := proto.Unmarshal(msg.Data, pb)
+				if err != nil {
+					log.WithError(err).Error("Could not unmarshal message")
+					continue
+				}
+				r.HandleAlertEvent(pb)
+			}
+		}
+	}()
+}
+
+func (r *AlertRouter) orgForVizier(vizierID uuid.UUID) (uuid.UUID, error) {
+	claims := jwtutils.GenerateJWTForService("vzmgr Service", viper.GetString("domain_name"))
+	token, err := jwtutils.SignJWTClaims(claims, viper.GetString("jwt_signing_key"))
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization",
+		fmt.Sprintf("bearer %s", token))
+	resp, err := r.vzmgrClient.GetOrgFromVizier(ctx, utils.ProtoFromUUID(vizierID))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return utils.UUIDFromProtoOrNil(resp.OrgID), nil
+}
+
+// HandleAlertEvent sends an alert event to all of the matching routes in the Vizier's org.
+func (r *AlertRouter) HandleAlertEvent(msg *cvmsgspb.V2CMessage) {
+	pb := &cvmsgspb.AlertEvent{}
+	err := types.UnmarshalAny(msg.Msg, pb)
+	if err != nil {
+		log.WithError(err).Error("Could not unmarshal alert event")
+		return
+	}
+
+	vizierID := uuid.FromStringOrNil(msg.VizierID)
+	ev, err := alertroutes.EventFromProto(vizierID, pb)
+	if err != nil {
+		log.WithError(err).Error("Invalid alert event")
+		return
+	}
+
+	orgID, err := r.orgForVizier(vizierID)
+	if err != nil {
+		log.WithError(err).Error("Could not find org for Vizier")
+		return
+	}
+
+	routes, err := r.server.getAlertRoutesForOrg(context.Background(), orgID)
+	if err != nil {
+		log.WithError(err).Error("Failed to fetch alert routes")
+		return
+	}
+
+	for _, route := range routes {
+		if !route.Matches(ev.ClusterID, ev.ScriptID) {
+			continue
+		}
+		err := r.sender.Send(context.Background(), route.PluginID, route.Destination, route.template(), ev)
+		if err != nil {
+			log.WithError(err).WithField("route", route.ID).Error("Failed to send alert event")
+		}
+	}
+}
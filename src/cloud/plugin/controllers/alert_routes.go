@@ -0,0 +1,253 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/segmentio/analytics-go/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/cloud/plugin/alertroutes"
+	"px.dev/pixie/src/cloud/plugin/pluginpb"
+	"px.dev/pixie/src/shared/services/events"
+	"px.dev/pixie/src/utils"
+)
+
+// AlertRoute is a route which sends alert events from the org's clusters to an alert plugin.
+type AlertRoute struct {
+	ID          uuid.UUID     `db:"id"`
+	OrgID       uuid.UUID     `db:"org_id"`
+	Name        string        `db:"name"`
+	PluginID    string        `db:"plugin_id"`
+	Destination string        `db:"destination"`
+	Template    *string       `db:"template"`
+	ClusterID   uuid.NullUUID `db:"cluster_id"`
+	ScriptID    uuid.NullUUID `db:"script_id"`
+}
+
+// Matches returns whether events from the given cluster and script should be sent to the route.
+func (r *AlertRoute) Matches(clusterID uuid.UUID, scriptID uuid.UUID) bool {
+	if r.ClusterID.Valid && r.ClusterID.UUID != clusterID {
+		return false
+	}
+	if r.ScriptID.Valid && r.ScriptID.UUID != scriptID {
+		return false
+	}
+	return true
+}
+
+func (r *AlertRoute) template() string {
+	if r.Template == nil {
+		return ""
+	}
+	return *r.Template
+}
+
+func (r *AlertRoute) toProto() *pluginpb.AlertRoute {
+	route := &pluginpb.AlertRoute{
+		ID:          utils.ProtoFromUUID(r.ID),
+		OrgID:       utils.ProtoFromUUID(r.OrgID),
+		Name:        r.Name,
+		PluginId:    r.PluginID,
+		Destination: r.Destination,
+		Template:    r.template(),
+	}
+	if r.ClusterID.Valid {
+		route.ClusterID = utils.ProtoFromUUID(r.ClusterID.UUID)
+	}
+	if r.ScriptID.Valid {
+		route.ScriptID = utils.ProtoFromUUID(r.ScriptID.UUID)
+	}
+	return route
+}
+
+func nullUUIDFromProto(id *uuidpb.UUID) uuid.NullUUID {
+	u := utils.UUIDFromProtoOrNil(id)
+	return uuid.NullUUID{UUID: u, Valid: u != uuid.Nil}
+}
+
+const selectAlertRoutesQuery = `SELECT id, org_id, name, plugin_id, PGP_SYM_DECRYPT(destination, $1::text) as destination, template, cluster_id, script_id FROM plugin_alert_routes`
+
+func (s *Server) getAlertRoutesForOrg(ctx context.Context, orgID uuid.UUID) ([]*AlertRoute, error) {
+	key, err := s.keys.Key(ctx, orgID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to fetch org data key")
+	}
+	rows, err := s.db.Queryx(selectAlertRoutesQuery+` WHERE org_id=$2 ORDER BY name`, key, orgID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to fetch alert routes")
+	}
+	defer rows.Close()
+
+	routes := []*AlertRoute{}
+	for rows.Next() {
+		var r AlertRoute
+		if err := rows.StructScan(&r); err != nil {
+			return nil, status.Error(codes.Internal, "failed to read alert routes")
+		}
+		routes = append(routes, &r)
+	}
+	return routes, nil
+}
+
+func (s *Server) getAlertRoute(ctx context.Context, orgID uuid.UUID, id uuid.UUID) (*AlertRoute, error) {
+	key, err := s.keys.Key(ctx, orgID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to fetch org data key")
+	}
+	rows, err := s.db.Queryx(selectAlertRoutesQuery+` WHERE org_id=$2 AND id=$3`, key, orgID, id)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to fetch alert route")
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, status.Error(codes.NotFound, "alert route not found")
+	}
+	var r AlertRoute
+	if err := rows.StructScan(&r); err != nil {
+		return nil, status.Error(codes.Internal, "failed to read alert route")
+	}
+	return &r, nil
+}
+
+// GetAlertRoutes gets all alert routes configured by the org.
+func (s *Server) GetAlertRoutes(ctx context.Context, req *pluginpb.GetAlertRoutesRequest) (*pluginpb.GetAlertRoutesResponse, error) {
+	routes, err := s.getAlertRoutesForOrg(ctx, utils.UUIDFromProtoOrNil(req.OrgID))
+	if err != nil {
+		return nil, err
+	}
+	resp := &pluginpb.GetAlertRoutesResponse{Routes: make([]*pluginpb.AlertRoute, len(routes))}
+	for i, r := range routes {
+		resp.Routes[i] = r.toProto()
+	}
+	return resp, nil
+}
+
+// CreateAlertRoute creates a new alert route for the org.
+func (s *Server) CreateAlertRoute(ctx context.Context, req *pluginpb.CreateAlertRouteRequest) (*pluginpb.CreateAlertRouteResponse, error) {
+	r := req.Route
+	if r == nil {
+		return nil, status.Error(codes.InvalidArgument, "route is required")
+	}
+	if r.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "route name is required")
+	}
+	if err := alertroutes.Validate(r.PluginId, r.Destination, r.Template); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	orgID := utils.UUIDFromProtoOrNil(r.OrgID)
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to create route ID")
+	}
+
+	err = s.withOrgKeyTx(ctx, orgID, func(txn *sqlx.Tx, key string) error {
+		query := `INSERT INTO plugin_alert_routes (id, org_id, name, plugin_id, destination, template, cluster_id, script_id) VALUES ($1, $2, $3, $4, PGP_SYM_ENCRYPT($5, $6), $7, $8, $9)`
+		_, err := txn.Exec(query, id, orgID, r.Name, r.PluginId, r.Destination, key, r.Template, nullUUIDFromProto(r.ClusterID), nullUUIDFromProto(r.ScriptID))
+		return err
+	})
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to create alert route")
+	}
+
+	events.Client().Enqueue(&analytics.Track{
+		UserId: orgID.String(),
+		Event:  events.PluginAlertRouteCreated,
+		Properties: analytics.NewProperties().
+			Set("plugin_id", r.PluginId).
+			Set("route_id", id.String()),
+	})
+
+	return &pluginpb.CreateAlertRouteResponse{ID: utils.ProtoFromUUID(id)}, nil
+}
+
+// UpdateAlertRoute updates an existing alert route.
+func (s *Server) UpdateAlertRoute(ctx context.Context, req *pluginpb.UpdateAlertRouteRequest) (*pluginpb.UpdateAlertRouteResponse, error) {
+	orgID := utils.UUIDFromProtoOrNil(req.OrgID)
+	r, err := s.getAlertRoute(ctx, orgID, utils.UUIDFromProtoOrNil(req.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		if req.Name.Value == "" {
+			return nil, status.Error(codes.InvalidArgument, "route name is required")
+		}
+		r.Name = req.Name.Value
+	}
+	if req.Destination != nil {
+		r.Destination = req.Destination.Value
+	}
+	if req.Template != nil {
+		r.Template = &req.Template.Value
+	}
+	if req.ClusterID != nil {
+		r.ClusterID = nullUUIDFromProto(req.ClusterID)
+	}
+	if req.ScriptID != nil {
+		r.ScriptID = nullUUIDFromProto(req.ScriptID)
+	}
+	if err := alertroutes.Validate(r.PluginID, r.Destination, r.template()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	err = s.withOrgKeyTx(ctx, orgID, func(txn *sqlx.Tx, key string) error {
+		query := `UPDATE plugin_alert_routes SET name=$1, destination=PGP_SYM_ENCRYPT($2, $3), template=$4, cluster_id=$5, script_id=$6 WHERE org_id=$7 AND id=$8`
+		_, err := txn.Exec(query, r.Name, r.Destination, key, r.Template, r.ClusterID, r.ScriptID, orgID, r.ID)
+		return err
+	})
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to update alert route")
+	}
+	return &pluginpb.UpdateAlertRouteResponse{}, nil
+}
+
+// DeleteAlertRoute deletes an alert route.
+func (s *Server) DeleteAlertRoute(ctx context.Context, req *pluginpb.DeleteAlertRouteRequest) (*pluginpb.DeleteAlertRouteResponse, error) {
+	orgID := utils.UUIDFromProtoOrNil(req.OrgID)
+	id := utils.UUIDFromProtoOrNil(req.ID)
+
+	resp, err := s.db.Exec(`DELETE FROM plugin_alert_routes WHERE org_id=$1 AND id=$2`, orgID, id)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to delete alert route")
+	}
+	rowsDel, err := resp.RowsAffected()
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to delete alert route")
+	}
+	if rowsDel == 0 {
+		return nil, status.Error(codes.NotFound, "alert route not found")
+	}
+
+	events.Client().Enqueue(&analytics.Track{
+		UserId: orgID.String(),
+		Event:  events.PluginAlertRouteDeleted,
+		Properties: analytics.NewProperties().
+			Set("route_id", id.String()),
+	})
+
+	return &pluginpb.DeleteAlertRouteResponse{}, nil
+}
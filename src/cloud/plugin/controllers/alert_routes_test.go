@@ -0,0 +1,258 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/cloud/plugin/alertroutes"
+	"px.dev/pixie/src/cloud/plugin/controllers"
+	"px.dev/pixie/src/cloud/plugin/pluginpb"
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
+	mock_vzmgrpb "px.dev/pixie/src/cloud/vzmgr/vzmgrpb/mock"
+	"px.dev/pixie/src/shared/cvmsgspb"
+	"px.dev/pixie/src/utils"
+	"px.dev/pixie/src/utils/testingutils"
+)
+
+const (
+	alertRouteOrgID     = "223e4567-e89b-12d3-a456-426655440000"
+	alertRouteClusterID = "323e4567-e89b-12d3-a456-426655440000"
+	alertRouteScriptID  = "423e4567-e89b-12d3-a456-426655440000"
+)
+
+func mustLoadAlertRoutes(t *testing.T, s *controllers.Server, routes ...*pluginpb.AlertRoute) []uuid.UUID {
+	db.MustExec(`DELETE FROM plugin_alert_routes`)
+	ids := make([]uuid.UUID, len(routes))
+	for i, r := range routes {
+		resp, err := s.CreateAlertRoute(createTestContext(), &pluginpb.CreateAlertRouteRequest{Route: r})
+		require.NoError(t, err)
+		ids[i] = utils.UUIDFromProtoOrNil(resp.ID)
+	}
+	return ids
+}
+
+func TestServer_AlertRoutes(t *testing.T) {
+	s := controllers.New(db, controllers.NewOrgKeyManager(db, nil, "test"), nil)
+	ids := mustLoadAlertRoutes(t, s,
+		&pluginpb.AlertRoute{
+			OrgID:       utils.ProtoFromUUIDStrOrNil(alertRouteOrgID),
+			Name:        "oncall",
+			PluginId:    alertroutes.PluginPagerDuty,
+			Destination: "routing-key",
+			ClusterID:   utils.ProtoFromUUIDStrOrNil(alertRouteClusterID),
+		},
+		&pluginpb.AlertRoute{
+			OrgID:       utils.ProtoFromUUIDStrOrNil(alertRouteOrgID),
+			Name:        "alerts-channel",
+			PluginId:    alertroutes.PluginSlack,
+			Destination: "https://hooks.slack.com/services/abc",
+			Template:    "{{.RuleName}} is {{.Status}}",
+		},
+	)
+
+	resp, err := s.GetAlertRoutes(createTestContext(), &pluginpb.GetAlertRoutesRequest{
+		OrgID: utils.ProtoFromUUIDStrOrNil(alertRouteOrgID),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []*pluginpb.AlertRoute{
+		{
+			ID:          utils.ProtoFromUUID(ids[1]),
+			OrgID:       utils.ProtoFromUUIDStrOrNil(alertRouteOrgID),
+			Name:        "alerts-channel",
+			PluginId:    alertroutes.PluginSlack,
+			Destination: "https://hooks.slack.com/services/abc",
+			Template:    "{{.RuleName}} is {{.Status}}",
+		},
+		{
+			ID:          utils.ProtoFromUUID(ids[0]),
+			OrgID:       utils.ProtoFromUUIDStrOrNil(alertRouteOrgID),
+			Name:        "oncall",
+			PluginId:    alertroutes.PluginPagerDuty,
+			Destination: "routing-key",
+			ClusterID:   utils.ProtoFromUUIDStrOrNil(alertRouteClusterID),
+		},
+	}, resp.Routes)
+
+	_, err = s.UpdateAlertRoute(createTestContext(), &pluginpb.UpdateAlertRouteRequest{
+		ID:        utils.ProtoFromUUID(ids[0]),
+		OrgID:     utils.ProtoFromUUIDStrOrNil(alertRouteOrgID),
+		Name:      &types.StringValue{Value: "oncall-primary"},
+		ClusterID: utils.ProtoFromUUID(uuid.Nil),
+		ScriptID:  utils.ProtoFromUUIDStrOrNil(alertRouteScriptID),
+	})
+	require.NoError(t, err)
+
+	resp, err = s.GetAlertRoutes(createTestContext(), &pluginpb.GetAlertRoutesRequest{
+		OrgID: utils.ProtoFromUUIDStrOrNil(alertRouteOrgID),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Routes, 2)
+	assert.Equal(t, "oncall-primary", resp.Routes[1].Name)
+	assert.Nil(t, resp.Routes[1].ClusterID)
+	assert.Equal(t, utils.ProtoFromUUIDStrOrNil(alertRouteScriptID), resp.Routes[1].ScriptID)
+
+	_, err = s.DeleteAlertRoute(createTestContext(), &pluginpb.DeleteAlertRouteRequest{
+		ID:    utils.ProtoFromUUID(ids[1]),
+		OrgID: utils.ProtoFromUUIDStrOrNil(alertRouteOrgID),
+	})
+	require.NoError(t, err)
+
+	_, err = s.DeleteAlertRoute(createTestContext(), &pluginpb.DeleteAlertRouteRequest{
+		ID:    utils.ProtoFromUUID(ids[1]),
+		OrgID: utils.ProtoFromUUIDStrOrNil(alertRouteOrgID),
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	resp, err = s.GetAlertRoutes(createTestContext(), &pluginpb.GetAlertRoutesRequest{
+		OrgID: utils.ProtoFromUUIDStrOrNil(alertRouteOrgID),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Routes, 1)
+}
+
+func TestServer_CreateAlertRouteInvalid(t *testing.T) {
+	s := controllers.New(db, controllers.NewOrgKeyManager(db, nil, "test"), nil)
+	mustLoadAlertRoutes(t, s)
+
+	_, err := s.CreateAlertRoute(createTestContext(), &pluginpb.CreateAlertRouteRequest{
+		Route: &pluginpb.AlertRoute{
+			OrgID:       utils.ProtoFromUUIDStrOrNil(alertRouteOrgID),
+			Name:        "bad",
+			PluginId:    alertroutes.PluginSlack,
+			Destination: "not-a-url",
+		},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = s.CreateAlertRoute(createTestContext(), &pluginpb.CreateAlertRouteRequest{
+		Route: &pluginpb.AlertRoute{
+			OrgID:       utils.ProtoFromUUIDStrOrNil(alertRouteOrgID),
+			Name:        "bad",
+			PluginId:    alertroutes.PluginPagerDuty,
+			Destination: "routing-key",
+			Template:    "{{.RuleName",
+		},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestAlertRouter_HandleAlertEvent(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, body["text"])
+	}))
+	defer srv.Close()
+
+	s := controllers.New(db, controllers.NewOrgKeyManager(db, nil, "test"), nil)
+	mustLoadAlertRoutes(t, s,
+		&pluginpb.AlertRoute{
+			OrgID:       utils.ProtoFromUUIDStrOrNil(alertRouteOrgID),
+			Name:        "all",
+			PluginId:    alertroutes.PluginSlack,
+			Destination: "https://hooks.slack.com/services/abc",
+			Template:    "all: {{.RuleName}} {{.Status}}",
+		},
+		&pluginpb.AlertRoute{
+			OrgID:       utils.ProtoFromUUIDStrOrNil(alertRouteOrgID),
+			Name:        "matching-script",
+			PluginId:    alertroutes.PluginSlack,
+			Destination: "https://hooks.slack.com/services/def",
+			Template:    "script: {{.RuleName}} {{.Status}}",
+			ScriptID:    utils.ProtoFromUUIDStrOrNil(alertRouteScriptID),
+		},
+		&pluginpb.AlertRoute{
+			OrgID:       utils.ProtoFromUUIDStrOrNil(alertRouteOrgID),
+			Name:        "other-cluster",
+			PluginId:    alertroutes.PluginSlack,
+			Destination: "https://hooks.slack.com/services/ghi",
+			Template:    "other: {{.RuleName}} {{.Status}}",
+			ClusterID:   utils.ProtoFromUUIDStrOrNil("523e4567-e89b-12d3-a456-426655440000"),
+		},
+	)
+	// Send all routes to the test server instead of Slack.
+	db.MustExec(`UPDATE plugin_alert_routes SET destination=PGP_SYM_ENCRYPT($1, $2)`, srv.URL, "test")
+
+	nc, natsCleanup := testingutils.MustStartTestNATS(t)
+	defer natsCleanup()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockVZMgr := mock_vzmgrpb.NewMockVZMgrServiceClient(ctrl)
+	mockVZMgr.EXPECT().GetOrgFromVizier(gomock.Any(), utils.ProtoFromUUIDStrOrNil(alertRouteClusterID)).
+		Return(&vzmgrpb.GetOrgFromVizierResponse{OrgID: utils.ProtoFromUUIDStrOrNil(alertRouteOrgID)}, nil)
+
+	router := controllers.NewAlertRouter(s, nc, mockVZMgr, alertroutes.NewSender())
+	defer router.Stop()
+
+	ts, err := types.TimestampProto(time.Unix(1700000000, 0))
+	require.NoError(t, err)
+	anyMsg, err := types.MarshalAny(&cvmsgspb.AlertEvent{
+		ScriptID:    utils.ProtoFromUUIDStrOrNil(alertRouteScriptID),
+		Fingerprint: "abcd",
+		RuleName:    "high_latency",
+		Status:      "firing",
+		StartsAt:    ts,
+		Timestamp:   ts,
+	})
+	require.NoError(t, err)
+
+	router.HandleAlertEvent(&cvmsgspb.V2CMessage{
+		VizierID: alertRouteClusterID,
+		Msg:      anyMsg,
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.ElementsMatch(t, []string{"all: high_latency firing", "script: high_latency firing"}, received)
+}
+
+func TestAlertRoute_Matches(t *testing.T) {
+	clusterID := uuid.FromStringOrNil(alertRouteClusterID)
+	scriptID := uuid.FromStringOrNil(alertRouteScriptID)
+
+	r := &controllers.AlertRoute{}
+	assert.True(t, r.Matches(clusterID, scriptID))
+
+	r.ClusterID = uuid.NullUUID{UUID: clusterID, Valid: true}
+	assert.True(t, r.Matches(clusterID, scriptID))
+	assert.False(t, r.Matches(uuid.Must(uuid.NewV4()), scriptID))
+
+	r.ScriptID = uuid.NullUUID{UUID: scriptID, Valid: true}
+	assert.True(t, r.Matches(clusterID, scriptID))
+	assert.False(t, r.Matches(clusterID, uuid.Must(uuid.NewV4())))
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofrs/uuid"
+	"github.com/jmoiron/sqlx"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/cloud/plugin/pluginpb"
+	"px.dev/pixie/src/cloud/shared/orgkeys"
+	"px.dev/pixie/src/utils"
+)
+
+// orgDataKeysTable is the table that stores the wrapped data keys of the orgs.
+const orgDataKeysTable = "plugin_org_data_keys"
+
+// encryptedColumns are the columns holding org data that are encrypted with the org's data key.
+var encryptedColumns = []orgkeys.EncryptedColumn{
+	{Table: "org_data_retention_plugins", Column: "configurations", OrgIDColumn: "org_id"},
+	{Table: "org_data_retention_plugins", Column: "custom_export_url", OrgIDColumn: "org_id"},
+	{Table: "plugin_retention_scripts", Column: "export_url", OrgIDColumn: "org_id"},
+	{Table: "plugin_alert_routes", Column: "destination", OrgIDColumn: "org_id"},
+}
+
+// NewOrgKeyManager creates the manager for the keys that the plugin service encrypts org data with. If wrapper is
+// nil, all org data is encrypted with the database key.
+func NewOrgKeyManager(db *sqlx.DB, wrapper orgkeys.KeyWrapper, dbKey string) *orgkeys.Manager {
+	return orgkeys.NewManager(db, orgkeys.Config{
+		Wrapper:   wrapper,
+		LegacyKey: dbKey,
+		KeysTable: orgDataKeysTable,
+		Columns:   encryptedColumns,
+	})
+}
+
+// withOrgKeyTx runs fn in a transaction, with the org's data key locked so that it isn't rotated until the
+// transaction is committed.
+func (s *Server) withOrgKeyTx(ctx context.Context, orgID uuid.UUID, fn func(txn *sqlx.Tx, key string) error) error {
+	txn, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer txn.Rollback()
+
+	key, err := s.keys.KeyTx(ctx, txn, orgID)
+	if err != nil {
+		return err
+	}
+	if err := fn(txn, key); err != nil {
+		return err
+	}
+	return txn.Commit()
+}
+
+// RotateOrgDataKey rotates the org's data key, and re-encrypts the org's stored plugin data with the new key.
+func (s *Server) RotateOrgDataKey(ctx context.Context, req *pluginpb.RotateOrgDataKeyRequest) (*pluginpb.RotateOrgDataKeyResponse, error) {
+	orgID := utils.UUIDFromProtoOrNil(req.OrgID)
+	if orgID == uuid.Nil {
+		return nil, status.Error(codes.InvalidArgument, "org ID is required")
+	}
+	version, err := s.keys.Rotate(ctx, orgID)
+	if errors.Is(err, orgkeys.ErrRotationUnsupported) {
+		return nil, status.Error(codes.FailedPrecondition, "org data keys are not enabled")
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to rotate org data key")
+	}
+	return &pluginpb.RotateOrgDataKeyResponse{Version: int64(version)}, nil
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	mock_cronscriptpb "px.dev/pixie/src/cloud/cron_script/cronscriptpb/mock"
+	"px.dev/pixie/src/cloud/plugin/controllers"
+	"px.dev/pixie/src/cloud/plugin/pluginpb"
+	"px.dev/pixie/src/cloud/shared/orgkeys"
+	"px.dev/pixie/src/utils"
+)
+
+func TestServer_OrgDataKeys(t *testing.T) {
+	mustLoadTestData(db)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockCSClient := mock_cronscriptpb.NewMockCronScriptServiceClient(ctrl)
+
+	keys := controllers.NewOrgKeyManager(db, orgkeys.NewLocalKeyWrapper("kek"), "test")
+	s := controllers.New(db, keys, mockCSClient)
+
+	orgID := utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440000")
+	req := &pluginpb.GetOrgRetentionPluginConfigRequest{
+		OrgID:    orgID,
+		PluginID: "test-plugin",
+	}
+	expected := &pluginpb.GetOrgRetentionPluginConfigResponse{
+		Configurations: map[string]string{
+			"license_key2": "12345",
+		},
+		CustomExportUrl: "https://localhost1:8080",
+		InsecureTLS:     true,
+	}
+
+	// The org gets its own key the first time its data is read, and the data is re-encrypted with it.
+	resp, err := s.GetOrgRetentionPluginConfig(createTestContext(), req)
+	require.NoError(t, err)
+	assert.Equal(t, expected, resp)
+
+	var count int
+	err = db.Get(&count, `SELECT COUNT(*) FROM plugin_org_data_keys WHERE org_id=$1`, utils.UUIDFromProtoOrNil(orgID))
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	query := `SELECT PGP_SYM_DECRYPT(export_url, $1::text) FROM plugin_retention_scripts WHERE org_id=$2`
+	_, err = db.Exec(query, "test", utils.UUIDFromProtoOrNil(orgID))
+	assert.Error(t, err)
+	// Other orgs are not affected.
+	_, err = db.Exec(query, "test", "223e4567-e89b-12d3-a456-426655440002")
+	assert.NoError(t, err)
+
+	oldKey, err := keys.Key(context.Background(), utils.UUIDFromProtoOrNil(orgID))
+	require.NoError(t, err)
+	_, err = db.Exec(query, oldKey, utils.UUIDFromProtoOrNil(orgID))
+	assert.NoError(t, err)
+
+	rotateResp, err := s.RotateOrgDataKey(createTestContext(), &pluginpb.RotateOrgDataKeyRequest{OrgID: orgID})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), rotateResp.Version)
+
+	// The data is re-encrypted with the new key.
+	resp, err = s.GetOrgRetentionPluginConfig(createTestContext(), req)
+	require.NoError(t, err)
+	assert.Equal(t, expected, resp)
+	_, err = db.Exec(query, oldKey, utils.UUIDFromProtoOrNil(orgID))
+	assert.Error(t, err)
+}
+
+func TestServer_RotateOrgDataKey_Disabled(t *testing.T) {
+	mustLoadTestData(db)
+
+	s := controllers.New(db, controllers.NewOrgKeyManager(db, nil, "test"), nil)
+	_, err := s.RotateOrgDataKey(createTestContext(), &pluginpb.RotateOrgDataKeyRequest{
+		OrgID: utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440000"),
+	})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = s.RotateOrgDataKey(createTestContext(), &pluginpb.RotateOrgDataKeyRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
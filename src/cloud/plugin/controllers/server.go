@@ -23,6 +23,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/gofrs/uuid"
@@ -38,6 +39,7 @@ import (
 	"px.dev/pixie/src/api/proto/uuidpb"
 	"px.dev/pixie/src/cloud/cron_script/cronscriptpb"
 	"px.dev/pixie/src/cloud/plugin/pluginpb"
+	"px.dev/pixie/src/cloud/shared/orgkeys"
 	"px.dev/pixie/src/shared/scripts"
 	"px.dev/pixie/src/shared/services/authcontext"
 	"px.dev/pixie/src/shared/services/events"
@@ -46,8 +48,8 @@ import (
 
 // Server is a bridge implementation of the pluginService.
 type Server struct {
-	db    *sqlx.DB
-	dbKey string
+	db   *sqlx.DB
+	keys *orgkeys.Manager
 
 	cronScriptClient cronscriptpb.CronScriptServiceClient
 
@@ -55,11 +57,11 @@ type Server struct {
 	once sync.Once
 }
 
-// New creates a new server.
-func New(db *sqlx.DB, dbKey string, cronScriptClient cronscriptpb.CronScriptServiceClient) *Server {
+// New creates a new server. The org data that it stores is encrypted with the keys from the given manager.
+func New(db *sqlx.DB, keys *orgkeys.Manager, cronScriptClient cronscriptpb.CronScriptServiceClient) *Server {
 	return &Server{
 		db:               db,
-		dbKey:            dbKey,
+		keys:             keys,
 		cronScriptClient: cronScriptClient,
 		done:             make(chan struct{}),
 	}
@@ -226,7 +228,11 @@ func (s *Server) GetOrgRetentionPluginConfig(ctx context.Context, req *pluginpb.
 	query := `SELECT PGP_SYM_DECRYPT(configurations, $1::text), PGP_SYM_DECRYPT(custom_export_url, $1::text), insecure_tls FROM org_data_retention_plugins WHERE org_id=$2 AND plugin_id=$3`
 
 	orgID := utils.UUIDFromProtoOrNil(req.OrgID)
-	rows, err := s.db.Queryx(query, s.dbKey, orgID, req.PluginID)
+	key, err := s.keys.Key(ctx, orgID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch org data key")
+	}
+	rows, err := s.db.Queryx(query, key, orgID, req.PluginID)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Failed to fetch plugin")
 	}
@@ -266,7 +272,11 @@ func (s *Server) GetOrgRetentionPluginConfig(ctx context.Context, req *pluginpb.
 
 func (s *Server) enableOrgRetention(ctx context.Context, txn *sqlx.Tx, orgID uuid.UUID, pluginID string, version string, configurations []byte, customExportURL *string, insecureTLS bool, disablePresets bool) error {
 	query := `INSERT INTO org_data_retention_plugins (org_id, plugin_id, version, configurations, custom_export_url, insecure_tls) VALUES ($1, $2, $3, PGP_SYM_ENCRYPT($4, $5), PGP_SYM_ENCRYPT($6, $5), $7)`
-	_, err := txn.Exec(query, orgID, pluginID, version, configurations, s.dbKey, customExportURL, insecureTLS)
+	key, err := s.keys.KeyTx(ctx, txn, orgID)
+	if err != nil {
+		return status.Errorf(codes.Internal, "Failed to fetch org data key")
+	}
+	_, err = txn.Exec(query, orgID, pluginID, version, configurations, key, customExportURL, insecureTLS)
 	if err != nil {
 		return status.Errorf(codes.Internal, "Failed to create plugin for org")
 	}
@@ -464,13 +474,17 @@ func (s *Server) updateOrgRetentionConfigs(ctx context.Context, txn *sqlx.Tx, or
 		return err
 	}
 
-	_, err = txn.Exec(query, version, configurations, s.dbKey, orgID, pluginID, customExportURL, insecureTLS)
+	key, err := s.keys.KeyTx(ctx, txn, orgID)
+	if err != nil {
+		return err
+	}
+	_, err = txn.Exec(query, version, configurations, key, orgID, pluginID, customExportURL, insecureTLS)
 	return err
 }
 
 func (s *Server) propagateConfigChangesToScripts(ctx context.Context, txn *sqlx.Tx, orgID uuid.UUID, pluginID string, version string, configurations []byte, customExportURL *string, insecureTLS bool) error {
 	// Fetch default export URL for plugin.
-	pluginExportURL, _, _, err := s.getPluginConfigs(txn, orgID, pluginID)
+	pluginExportURL, _, _, err := s.getPluginConfigs(ctx, txn, orgID, pluginID)
 	if err != nil {
 		return err
 	}
@@ -481,7 +495,11 @@ func (s *Server) propagateConfigChangesToScripts(ctx context.Context, txn *sqlx.
 
 	// Fetch all scripts belonging to this plugin.
 	query := `SELECT script_id, PGP_SYM_DECRYPT(export_url, $1::text) as export_url from plugin_retention_scripts WHERE org_id=$2 AND plugin_id=$3`
-	rows, err := txn.Queryx(query, s.dbKey, orgID, pluginID)
+	key, err := s.keys.KeyTx(ctx, txn, orgID)
+	if err != nil {
+		return status.Errorf(codes.Internal, "Failed to fetch org data key")
+	}
+	rows, err := txn.Queryx(query, key, orgID, pluginID)
 	if err != nil {
 		return status.Errorf(codes.Internal, "Failed to fetch scripts")
 	}
@@ -512,22 +530,14 @@ func (s *Server) propagateConfigChangesToScripts(ctx context.Context, txn *sqlx.
 		if exportURL == "" {
 			exportURL = pluginExportURL
 		}
-		config := &scripts.Config{
-			OtelEndpointConfig: &scripts.OtelEndpointConfig{
-				URL:      exportURL,
-				Headers:  configMap,
-				Insecure: insecureTLS,
-			},
-		}
-
-		mConfig, err := yaml.Marshal(&config)
+		mConfig, err := scriptConfigToYAML(configMap, exportURL, insecureTLS)
 		if err != nil {
-			return status.Error(codes.Internal, "failed to marshal configs")
+			return err
 		}
 
 		_, err = s.cronScriptClient.UpdateScript(ctx, &cronscriptpb.UpdateScriptRequest{
 			ScriptId: utils.ProtoFromUUID(sc.ScriptID),
-			Configs:  &types.StringValue{Value: string(mConfig)},
+			Configs:  &types.StringValue{Value: mConfig},
 			OrgID:    utils.ProtoFromUUID(orgID),
 		})
 		if err != nil {
@@ -572,7 +582,11 @@ func (s *Server) UpdateOrgRetentionPluginConfig(ctx context.Context, req *plugin
 
 	// Fetch current configs.
 	query := `SELECT version, PGP_SYM_DECRYPT(configurations, $1::text), PGP_SYM_DECRYPT(custom_export_url, $1::text), insecure_tls FROM org_data_retention_plugins WHERE org_id=$2 AND plugin_id=$3`
-	rows, err := txn.Queryx(query, s.dbKey, orgID, req.PluginID)
+	key, err := s.keys.KeyTx(ctx, txn, orgID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch org data key")
+	}
+	rows, err := txn.Queryx(query, key, orgID, req.PluginID)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Failed to fetch plugin")
 	}
@@ -772,7 +786,11 @@ func (s *Server) GetRetentionScript(ctx context.Context, req *pluginpb.GetRetent
 	query := `SELECT script_name, description, is_preset, plugin_id, PGP_SYM_DECRYPT(export_url, $1::text) as export_url from plugin_retention_scripts WHERE org_id=$2 AND script_id=$3`
 	orgID := utils.UUIDFromProtoOrNil(req.OrgID)
 	scriptID := utils.UUIDFromProtoOrNil(req.ScriptID)
-	rows, err := s.db.Queryx(query, s.dbKey, orgID, scriptID)
+	key, err := s.keys.Key(ctx, orgID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch org data key")
+	}
+	rows, err := s.db.Queryx(query, key, orgID, scriptID)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Failed to fetch script")
 	}
@@ -819,7 +837,7 @@ func (s *Server) GetRetentionScript(ctx context.Context, req *pluginpb.GetRetent
 }
 
 func (s *Server) createRetentionScript(ctx context.Context, txn *sqlx.Tx, orgID uuid.UUID, pluginID string, rs *RetentionScript, contents string, clusterIDs []*uuidpb.UUID, frequencyS int64, disabled bool) (*uuidpb.UUID, error) {
-	pluginExportURL, configMap, insecureTLS, err := s.getPluginConfigs(txn, orgID, pluginID)
+	pluginExportURL, configMap, insecureTLS, err := s.getPluginConfigs(ctx, txn, orgID, pluginID)
 	if err != nil {
 		return nil, err
 	}
@@ -848,7 +866,11 @@ func (s *Server) createRetentionScript(ctx context.Context, txn *sqlx.Tx, orgID
 	scriptID := cronScriptResp.ID
 
 	query := `INSERT INTO plugin_retention_scripts (org_id, plugin_id, script_id, script_name, description, export_url, is_preset) VALUES ($1, $2, $3, $4, $5, PGP_SYM_ENCRYPT($6, $7), $8)`
-	_, err = txn.Exec(query, orgID, pluginID, utils.UUIDFromProtoOrNil(scriptID), rs.ScriptName, rs.Description, rs.ExportURL, s.dbKey, rs.IsPreset)
+	key, err := s.keys.KeyTx(ctx, txn, orgID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch org data key")
+	}
+	_, err = txn.Exec(query, orgID, pluginID, utils.UUIDFromProtoOrNil(scriptID), rs.ScriptName, rs.Description, rs.ExportURL, key, rs.IsPreset)
 	if err == nil {
 		return scriptID, nil
 	}
@@ -910,9 +932,13 @@ func (s *Server) CreateRetentionScript(ctx context.Context, req *pluginpb.Create
 	}, nil
 }
 
-func (s *Server) getPluginConfigs(txn *sqlx.Tx, orgID uuid.UUID, pluginID string) (string, map[string]string, bool, error) {
+func (s *Server) getPluginConfigs(ctx context.Context, txn *sqlx.Tx, orgID uuid.UUID, pluginID string) (string, map[string]string, bool, error) {
 	query := `SELECT PGP_SYM_DECRYPT(o.configurations, $1::text), r.default_export_url, PGP_SYM_DECRYPT(o.custom_export_url, $1::text), insecure_tls FROM org_data_retention_plugins o, data_retention_plugin_releases r WHERE org_id=$2 AND r.plugin_id=$3 AND o.plugin_id=r.plugin_id AND r.version = o.version`
-	rows, err := txn.Queryx(query, s.dbKey, orgID, pluginID)
+	key, err := s.keys.KeyTx(ctx, txn, orgID)
+	if err != nil {
+		return "", nil, false, status.Errorf(codes.Internal, "failed to fetch org data key")
+	}
+	rows, err := txn.Queryx(query, key, orgID, pluginID)
 	if err != nil {
 		return "", nil, false, status.Errorf(codes.Internal, "failed to fetch plugin")
 	}
@@ -945,6 +971,13 @@ func (s *Server) getPluginConfigs(txn *sqlx.Tx, orgID uuid.UUID, pluginID string
 	return pluginExportURL, configMap, insecureTLS, nil
 }
 
+// isObjectStoreURL returns whether the export URL points at an S3, GCS or Azure Blob Storage bucket, rather than an
+// OTel endpoint.
+func isObjectStoreURL(exportURL string) bool {
+	return strings.HasPrefix(exportURL, "s3://") || strings.HasPrefix(exportURL, "gs://") ||
+		strings.HasPrefix(exportURL, "az://")
+}
+
 func scriptConfigToYAML(configMap map[string]string, exportURL string, insecureTLS bool) (string, error) {
 	config := &scripts.Config{
 		OtelEndpointConfig: &scripts.OtelEndpointConfig{
@@ -953,6 +986,20 @@ func scriptConfigToYAML(configMap map[string]string, exportURL string, insecureT
 			Insecure: insecureTLS,
 		},
 	}
+	if isObjectStoreURL(exportURL) {
+		// For object storage, the plugin's configurations hold the bucket settings and credentials instead of headers.
+		config = &scripts.Config{
+			ObjectStoreConfig: &scripts.ObjectStoreConfig{
+				URL:               exportURL,
+				Region:            configMap["region"],
+				Endpoint:          configMap["endpoint"],
+				StorageAccount:    configMap["storageAccount"],
+				AccessKeyID:       configMap["accessKeyID"],
+				SecretAccessKey:   configMap["secretAccessKey"],
+				ServiceAccountKey: configMap["serviceAccountKey"],
+			},
+		}
+	}
 
 	mConfig, err := yaml.Marshal(&config)
 	if err != nil {
@@ -974,10 +1021,33 @@ func (s *Server) UpdateRetentionScript(ctx context.Context, req *pluginpb.Update
 		return nil, err
 	}
 
-	// Fetch existing script.
-	query := `SELECT org_id, script_name, description, PGP_SYM_DECRYPT(export_url, $1::text) as export_url, plugin_id from plugin_retention_scripts WHERE script_id=$2`
+	// Fetch the org of the existing script, which is needed to decrypt it.
+	var scriptOrgID uuid.UUID
 	scriptID := utils.UUIDFromProtoOrNil(req.ScriptID)
-	rows, err := txn.Queryx(query, s.dbKey, scriptID)
+	err = txn.Get(&scriptOrgID, `SELECT org_id from plugin_retention_scripts WHERE script_id=$1`, scriptID)
+	if err == sql.ErrNoRows {
+		return nil, status.Error(codes.NotFound, "script not found")
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch script")
+	}
+
+	sCtx, err := authcontext.FromContext(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "Unauthenticated")
+	}
+	claimsOrgIDstr := sCtx.Claims.GetUserClaims().OrgID
+	if scriptOrgID.String() != claimsOrgIDstr {
+		return nil, status.Errorf(codes.Unauthenticated, "Unauthorized")
+	}
+
+	// Fetch existing script.
+	key, err := s.keys.KeyTx(ctx, txn, scriptOrgID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch org data key")
+	}
+	query := `SELECT org_id, script_name, description, PGP_SYM_DECRYPT(export_url, $1::text) as export_url, plugin_id from plugin_retention_scripts WHERE script_id=$2 AND org_id=$3`
+	rows, err := txn.Queryx(query, key, scriptID, scriptOrgID)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Failed to fetch script")
 	}
@@ -993,17 +1063,8 @@ func (s *Server) UpdateRetentionScript(ctx context.Context, req *pluginpb.Update
 	}
 	rows.Close()
 
-	sCtx, err := authcontext.FromContext(ctx)
-	if err != nil {
-		return nil, status.Errorf(codes.Unauthenticated, "Unauthenticated")
-	}
-	claimsOrgIDstr := sCtx.Claims.GetUserClaims().OrgID
-	if script.OrgID.String() != claimsOrgIDstr {
-		return nil, status.Errorf(codes.Unauthenticated, "Unauthorized")
-	}
-
 	// Fetch config + headers from plugin info.
-	pluginExportURL, configMap, insecureTLS, err := s.getPluginConfigs(txn, script.OrgID, script.PluginID)
+	pluginExportURL, configMap, insecureTLS, err := s.getPluginConfigs(ctx, txn, script.OrgID, script.PluginID)
 	if err != nil {
 		return nil, err
 	}
@@ -1023,7 +1084,7 @@ func (s *Server) UpdateRetentionScript(ctx context.Context, req *pluginpb.Update
 
 	// Update retention scripts with new info.
 	query = `UPDATE plugin_retention_scripts SET script_name = $1, export_url = PGP_SYM_ENCRYPT($2, $3), description = $4 WHERE script_id = $5`
-	_, err = txn.Exec(query, scriptName, exportURL, s.dbKey, description, scriptID)
+	_, err = txn.Exec(query, scriptName, exportURL, key, description, scriptID)
 
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Failed to update retention script")
@@ -84,6 +84,7 @@ func createTestContext() context.Context {
 }
 
 func mustLoadTestData(db *sqlx.DB) {
+	db.MustExec(`DELETE FROM plugin_org_data_keys`)
 	db.MustExec(`DELETE FROM plugin_retention_scripts`)
 	db.MustExec(`DELETE FROM org_data_retention_plugins`)
 	db.MustExec(`DELETE FROM data_retention_plugin_releases`)
@@ -174,7 +175,7 @@ func TestServer_GetPlugins(t *testing.T) {
 	defer ctrl.Finish()
 	mockCSClient := mock_cronscriptpb.NewMockCronScriptServiceClient(ctrl)
 
-	s := controllers.New(db, "test", mockCSClient)
+	s := controllers.New(db, controllers.NewOrgKeyManager(db, nil, "test"), mockCSClient)
 	resp, err := s.GetPlugins(createTestContext(), &pluginpb.GetPluginsRequest{})
 	require.NoError(t, err)
 	require.NotNil(t, resp)
@@ -207,7 +208,7 @@ func TestServer_GetPluginsWithKind(t *testing.T) {
 	defer ctrl.Finish()
 	mockCSClient := mock_cronscriptpb.NewMockCronScriptServiceClient(ctrl)
 
-	s := controllers.New(db, "test", mockCSClient)
+	s := controllers.New(db, controllers.NewOrgKeyManager(db, nil, "test"), mockCSClient)
 	resp, err := s.GetPlugins(createTestContext(), &pluginpb.GetPluginsRequest{Kind: pluginpb.PLUGIN_KIND_RETENTION})
 	require.NoError(t, err)
 	require.NotNil(t, resp)
@@ -232,7 +233,7 @@ func TestServer_GetRetentionPluginConfig(t *testing.T) {
 	defer ctrl.Finish()
 	mockCSClient := mock_cronscriptpb.NewMockCronScriptServiceClient(ctrl)
 
-	s := controllers.New(db, "test", mockCSClient)
+	s := controllers.New(db, controllers.NewOrgKeyManager(db, nil, "test"), mockCSClient)
 	resp, err := s.GetRetentionPluginConfig(createTestContext(), &pluginpb.GetRetentionPluginConfigRequest{
 		ID:      "test-plugin",
 		Version: "0.0.2",
@@ -272,7 +273,7 @@ func TestServer_GetRetentionPluginsForOrg(t *testing.T) {
 	defer ctrl.Finish()
 	mockCSClient := mock_cronscriptpb.NewMockCronScriptServiceClient(ctrl)
 
-	s := controllers.New(db, "test", mockCSClient)
+	s := controllers.New(db, controllers.NewOrgKeyManager(db, nil, "test"), mockCSClient)
 	resp, err := s.GetRetentionPluginsForOrg(createTestContext(), &pluginpb.GetRetentionPluginsForOrgRequest{
 		OrgID: utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440001"),
 	})
@@ -300,7 +301,7 @@ func TestServer_GetOrgRetentionPluginConfig(t *testing.T) {
 	defer ctrl.Finish()
 	mockCSClient := mock_cronscriptpb.NewMockCronScriptServiceClient(ctrl)
 
-	s := controllers.New(db, "test", mockCSClient)
+	s := controllers.New(db, controllers.NewOrgKeyManager(db, nil, "test"), mockCSClient)
 	resp, err := s.GetOrgRetentionPluginConfig(createTestContext(), &pluginpb.GetOrgRetentionPluginConfigRequest{
 		PluginID: "test-plugin",
 		OrgID:    utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440001"),
@@ -1101,7 +1102,7 @@ func TestServer_UpdateRetentionConfigs(t *testing.T) {
 				return &cronscriptpb.DeleteScriptResponse{}, nil
 			}).AnyTimes()
 
-			s := controllers.New(db, "test", mockCSClient)
+			s := controllers.New(db, controllers.NewOrgKeyManager(db, nil, "test"), mockCSClient)
 
 			resp, err := s.UpdateOrgRetentionPluginConfig(createTestContext(), test.request)
 
@@ -1201,7 +1202,7 @@ func TestServer_GetRetentionScripts(t *testing.T) {
 		},
 	}, nil)
 
-	s := controllers.New(db, "test", mockCSClient)
+	s := controllers.New(db, controllers.NewOrgKeyManager(db, nil, "test"), mockCSClient)
 	resp, err := s.GetRetentionScripts(createTestContext(), &pluginpb.GetRetentionScriptsRequest{
 		OrgID: utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440000"),
 	})
@@ -1260,7 +1261,7 @@ func TestServer_GetRetentionScript(t *testing.T) {
 		},
 	}, nil)
 
-	s := controllers.New(db, "test", mockCSClient)
+	s := controllers.New(db, controllers.NewOrgKeyManager(db, nil, "test"), mockCSClient)
 	resp, err := s.GetRetentionScript(createTestContext(), &pluginpb.GetRetentionScriptRequest{
 		OrgID:    utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440000"),
 		ScriptID: utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440000"),
@@ -1323,7 +1324,7 @@ func TestServer_CreateRetentionScript(t *testing.T) {
 		ID: utils.ProtoFromUUIDStrOrNil("323e4567-e89b-12d3-a456-426655440000"),
 	}, nil)
 
-	s := controllers.New(db, "test", mockCSClient)
+	s := controllers.New(db, controllers.NewOrgKeyManager(db, nil, "test"), mockCSClient)
 	resp, err := s.CreateRetentionScript(createTestContext(), &pluginpb.CreateRetentionScriptRequest{
 		Script: &pluginpb.DetailedRetentionScript{
 			Script: &pluginpb.RetentionScript{
@@ -1398,7 +1399,7 @@ func TestServer_CreateRetentionScriptNameDuplicatesAllowed(t *testing.T) {
 		ID: utils.ProtoFromUUIDStrOrNil("323e4567-e89b-12d3-a456-426655440000"),
 	}, nil)
 
-	s := controllers.New(db, "test", mockCSClient)
+	s := controllers.New(db, controllers.NewOrgKeyManager(db, nil, "test"), mockCSClient)
 	_, err = s.CreateRetentionScript(createTestContext(), &pluginpb.CreateRetentionScriptRequest{
 		Script: &pluginpb.DetailedRetentionScript{
 			Script: &pluginpb.RetentionScript{
@@ -1489,7 +1490,7 @@ func TestServer_UpdateRetentionScript(t *testing.T) {
 		OrgID:      utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440000"),
 	})
 
-	s := controllers.New(db, "test", mockCSClient)
+	s := controllers.New(db, controllers.NewOrgKeyManager(db, nil, "test"), mockCSClient)
 	resp, err := s.UpdateRetentionScript(createTestContext(), &pluginpb.UpdateRetentionScriptRequest{
 		ScriptID:   utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440000"),
 		ScriptName: &types.StringValue{Value: "Updated Script"},
@@ -1533,7 +1534,7 @@ func TestServer_DeleteRetentionScript(t *testing.T) {
 		OrgID: utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440000"),
 	}).Return(&cronscriptpb.DeleteScriptResponse{}, nil)
 
-	s := controllers.New(db, "test", mockCSClient)
+	s := controllers.New(db, controllers.NewOrgKeyManager(db, nil, "test"), mockCSClient)
 	resp, err := s.DeleteRetentionScript(createTestContext(), &pluginpb.DeleteRetentionScriptRequest{
 		ID:    utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440000"),
 		OrgID: utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440000"),
@@ -1555,7 +1556,7 @@ func TestServer_DeletePresetRetentionScript(t *testing.T) {
 	defer ctrl.Finish()
 	mockCSClient := mock_cronscriptpb.NewMockCronScriptServiceClient(ctrl)
 
-	s := controllers.New(db, "test", mockCSClient)
+	s := controllers.New(db, controllers.NewOrgKeyManager(db, nil, "test"), mockCSClient)
 	_, err := s.DeleteRetentionScript(createTestContext(), &pluginpb.DeleteRetentionScriptRequest{
 		ID:    utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440001"),
 		OrgID: utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440000"),
@@ -19,29 +19,37 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	_ "net/http/pprof"
 
 	bindata "github.com/golang-migrate/migrate/source/go_bindata"
+	"github.com/nats-io/nats.go"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"google.golang.org/grpc"
 
 	"px.dev/pixie/src/cloud/cron_script/cronscriptpb"
+	"px.dev/pixie/src/cloud/plugin/alertroutes"
 	"px.dev/pixie/src/cloud/plugin/controllers"
 	"px.dev/pixie/src/cloud/plugin/pluginpb"
 	"px.dev/pixie/src/cloud/plugin/schema"
+	"px.dev/pixie/src/cloud/shared/orgkeys"
 	"px.dev/pixie/src/cloud/shared/pgmigrate"
+	"px.dev/pixie/src/cloud/shared/vzshard"
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
 	"px.dev/pixie/src/shared/services"
 	"px.dev/pixie/src/shared/services/env"
 	"px.dev/pixie/src/shared/services/healthz"
+	"px.dev/pixie/src/shared/services/msgbus"
 	"px.dev/pixie/src/shared/services/pg"
 	"px.dev/pixie/src/shared/services/server"
 )
 
 func init() {
 	pflag.String("cron_script_service", "cron-script-service.plc.svc.cluster.local:50700", "The cronscript service url (load balancer/list is ok)")
+	pflag.String("vzmgr_service", "kubernetes:///vzmgr-service.plc:51800", "The vzmgr service url (load balancer/list is ok)")
 }
 
 // NewCronScriptServiceClient creates a new cron script service RPC client stub.
@@ -59,8 +67,37 @@ func NewCronScriptServiceClient() (cronscriptpb.CronScriptServiceClient, error)
 	return cronscriptpb.NewCronScriptServiceClient(csChannel), nil
 }
 
+func newVZMgrClient() (vzmgrpb.VZMgrServiceClient, error) {
+	dialOpts, err := services.GetGRPCClientDialOpts()
+	if err != nil {
+		return nil, err
+	}
+
+	vzmgrChannel, err := grpc.Dial(viper.GetString("vzmgr_service"), dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return vzmgrpb.NewVZMgrServiceClient(vzmgrChannel), nil
+}
+
+func mustSetupNATS() *nats.Conn {
+	nc := msgbus.MustConnectNATS()
+
+	nc.SetErrorHandler(func(conn *nats.Conn, subscription *nats.Subscription, err error) {
+		if err != nil {
+			log.WithError(err).
+				WithField("Subject", subscription.Subject).
+				Error("Got NATS error")
+		}
+	})
+	return nc
+}
+
 func main() {
 	services.SetupService("plugin-service", 50600)
+	vzshard.SetupFlags()
+	orgkeys.SetupFlags()
 	services.PostFlagSetupAndParse()
 	services.CheckServiceFlags()
 	services.SetupServiceLogging()
@@ -88,10 +125,27 @@ func main() {
 	if err != nil {
 		log.Fatal("Failed to start cronscript client")
 	}
-	c := controllers.New(db, dbKey, csClient)
+	keyWrapper, err := orgkeys.NewKeyWrapperFromFlags(context.Background())
+	if err != nil {
+		log.WithError(err).Fatal("Failed to set up org data key wrapper")
+	}
+	if keyWrapper == nil {
+		log.Warn("No org data key wrapper is configured, org data will be encrypted with the database key")
+	}
+	c := controllers.New(db, controllers.NewOrgKeyManager(db, keyWrapper, dbKey), csClient)
+
+	nc := mustSetupNATS()
+	vzmgrClient, err := newVZMgrClient()
+	if err != nil {
+		log.WithError(err).Fatal("Failed to initialize vizier manager RPC client")
+	}
+	router := controllers.NewAlertRouter(c, nc, vzmgrClient, alertroutes.NewSender())
+	defer router.Stop()
 
 	pluginpb.RegisterPluginServiceServer(s.GRPCServer(), c)
 	pluginpb.RegisterDataRetentionPluginServiceServer(s.GRPCServer(), c)
+	pluginpb.RegisterAlertRoutePluginServiceServer(s.GRPCServer(), c)
+	pluginpb.RegisterOrgDataKeyServiceServer(s.GRPCServer(), c)
 
 	s.Start()
 	s.StopOnInterrupt()
@@ -18,4 +18,4 @@
 
 package pluginpb
 
-//go:generate mockgen -source=service.pb.go -destination=mock/service_mock.gen.go PluginServiceClient DataRetentionPluginServiceClient
+//go:generate mockgen -source=service.pb.go -destination=mock/service_mock.gen.go PluginServiceClient DataRetentionPluginServiceClient AlertRoutePluginServiceClient OrgDataKeyServiceClient
@@ -0,0 +1,22 @@
+CREATE TABLE plugin_alert_routes (
+  -- id is the ID of the route.
+  id UUID NOT NULL,
+  -- org_id is the org who owns this route.
+  org_id UUID NOT NULL,
+  -- name is the name of the route.
+  name varchar(1024) NOT NULL,
+  -- plugin_id is the alert plugin that events are sent to, such as slack or pagerduty.
+  plugin_id varchar(1024) NOT NULL,
+  -- destination is where events are sent, such as a webhook URL or routing key. It is encrypted.
+  destination bytea NOT NULL,
+  -- template is the text/template used to render the message. If empty, the plugin's default is used.
+  template varchar(65536),
+  -- cluster_id is the cluster whose events are matched. If null, events from all clusters match.
+  cluster_id UUID,
+  -- script_id is the cron script whose events are matched. If null, events from all scripts match.
+  script_id UUID,
+
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX plugin_alert_routes_org_id_idx ON plugin_alert_routes (org_id);
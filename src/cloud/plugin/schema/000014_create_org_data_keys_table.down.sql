@@ -0,0 +1 @@
+DROP TABLE IF EXISTS plugin_org_data_keys;
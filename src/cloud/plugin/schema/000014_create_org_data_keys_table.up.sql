@@ -0,0 +1,14 @@
+CREATE TABLE plugin_org_data_keys (
+  -- org_id is the org that the key belongs to.
+  org_id UUID NOT NULL,
+  -- version is incremented each time the key is rotated.
+  version INT NOT NULL,
+  -- wrapped_key is the org's data key, encrypted by the key encryption key.
+  wrapped_key bytea NOT NULL,
+  -- kek_id identifies the key encryption key that wrapped the data key, such as a Cloud KMS key name.
+  kek_id varchar(1024) NOT NULL,
+  -- rotated_at is when the key was created.
+  rotated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY (org_id)
+);
@@ -22,6 +22,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/gofrs/uuid"
@@ -39,6 +40,7 @@ import (
 	"px.dev/pixie/src/cloud/profile/profilepb"
 	"px.dev/pixie/src/cloud/project_manager/projectmanagerpb"
 	"px.dev/pixie/src/shared/services/authcontext"
+	"px.dev/pixie/src/shared/services/rbac"
 	claimsutils "px.dev/pixie/src/shared/services/utils"
 	"px.dev/pixie/src/utils"
 )
@@ -140,6 +142,10 @@ func userInfoToProto(u *datastore.UserInfo) *profilepb.UserInfo {
 	if u.OrgID != nil {
 		orgID = utils.ProtoFromUUID(*u.OrgID)
 	}
+	var roles []string
+	if u.Roles != "" {
+		roles = strings.Fields(u.Roles)
+	}
 	return &profilepb.UserInfo{
 		ID:               utils.ProtoFromUUID(u.ID),
 		OrgID:            orgID,
@@ -150,6 +156,7 @@ func userInfoToProto(u *datastore.UserInfo) *profilepb.UserInfo {
 		IsApproved:       u.IsApproved,
 		IdentityProvider: u.IdentityProvider,
 		AuthProviderID:   u.AuthProviderID,
+		Roles:            roles,
 	}
 }
 
@@ -177,7 +184,8 @@ func toExternalError(err error) error {
 
 // CreateUser is the GRPC method to create  new user.
 func (s *Server) CreateUser(ctx context.Context, req *profilepb.CreateUserRequest) (*uuidpb.UUID, error) {
-	// Users with no org are considered approved by default.
+	// Users with no org are considered approved by default. New users can only view their org until
+	// they're given another role.
 	userInfo := &datastore.UserInfo{
 		FirstName:        req.FirstName,
 		LastName:         req.LastName,
@@ -185,6 +193,7 @@ func (s *Server) CreateUser(ctx context.Context, req *profilepb.CreateUserReques
 		IsApproved:       true,
 		IdentityProvider: req.IdentityProvider,
 		AuthProviderID:   req.AuthProviderID,
+		Roles:            string(rbac.RoleViewer),
 	}
 	orgID := utils.UUIDFromProtoOrNil(req.OrgID)
 	if orgID != uuid.Nil {
@@ -252,6 +261,7 @@ func (s *Server) CreateOrgAndUser(ctx context.Context, req *profilepb.CreateOrgA
 		// By default, the creating user is the owner and should be approved.
 		IsApproved:     true,
 		AuthProviderID: req.User.AuthProviderID,
+		Roles:          string(rbac.RoleAdmin),
 	}
 	if len(orgInfo.OrgName) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "invalid org name")
@@ -399,6 +409,18 @@ func (s *Server) UpdateUser(ctx context.Context, req *profilepb.UpdateUserReques
 		userInfo.IsApproved = req.IsApproved.Value
 	}
 
+	if req.Roles != nil {
+		roles := make([]string, len(req.Roles.Roles))
+		for i, r := range req.Roles.Roles {
+			role, err := rbac.ParseRole(r)
+			if err != nil {
+				return nil, status.Error(codes.InvalidArgument, err.Error())
+			}
+			roles[i] = string(role)
+		}
+		userInfo.Roles = strings.Join(roles, " ")
+	}
+
 	err = s.uds.UpdateUser(userInfo)
 	if err != nil {
 		return nil, toExternalError(err)
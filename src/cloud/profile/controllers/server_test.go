@@ -212,6 +212,7 @@ func TestServer_CreateUser(t *testing.T) {
 					IsApproved:       !tc.enableApprovals,
 					IdentityProvider: tc.userInfo.IdentityProvider,
 					AuthProviderID:   tc.userInfo.AuthProviderID,
+					Roles:            "viewer",
 				}
 				if utils.UUIDFromProtoOrNil(tc.userInfo.OrgID) != uuid.Nil {
 					req.OrgID = &testOrgUUID
@@ -527,6 +528,7 @@ func TestServer_CreateOrgAndUser_SuccessCases(t *testing.T) {
 				IsApproved:       true,
 				IdentityProvider: tc.req.User.IdentityProvider,
 				AuthProviderID:   tc.req.User.AuthProviderID,
+				Roles:            "admin",
 			}
 			exOrg := &datastore.OrgInfo{
 				DomainName: &tc.req.Org.DomainName,
@@ -643,6 +645,7 @@ func TestServer_CreateOrgAndUser_CreateProjectFailed(t *testing.T) {
 		Email:            req.User.Email,
 		IsApproved:       true,
 		IdentityProvider: "github",
+		Roles:            "admin",
 	}
 	exOrg := &datastore.OrgInfo{
 		DomainName: &req.Org.DomainName,
@@ -1145,6 +1148,43 @@ func TestServer_UpdateUser(t *testing.T) {
 	}
 }
 
+func TestServer_UpdateUser_Roles(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	uds := mock_controllers.NewMockUserDatastore(ctrl)
+	ods := mock_controllers.NewMockOrgDatastore(ctrl)
+	usds := mock_controllers.NewMockUserSettingsDatastore(ctrl)
+	osds := mock_controllers.NewMockOrgSettingsDatastore(ctrl)
+
+	s := controllers.NewServer(nil, uds, usds, ods, osds)
+	userID := uuid.FromStringOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c9")
+	orgID := uuid.FromStringOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+	uds.EXPECT().
+		GetUser(userID).
+		Return(&datastore.UserInfo{ID: userID, OrgID: &orgID, Roles: "viewer"}, nil)
+	uds.EXPECT().
+		UpdateUser(&datastore.UserInfo{ID: userID, OrgID: &orgID, Roles: "editor admin"}).
+		Return(nil)
+
+	resp, err := s.UpdateUser(CreateTestContext(), &profilepb.UpdateUserRequest{
+		ID:    utils.ProtoFromUUID(userID),
+		Roles: &profilepb.UserRoles{Roles: []string{"Editor", "admin"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"editor", "admin"}, resp.Roles)
+
+	uds.EXPECT().
+		GetUser(userID).
+		Return(&datastore.UserInfo{ID: userID, OrgID: &orgID}, nil)
+	_, err = s.UpdateUser(CreateTestContext(), &profilepb.UpdateUserRequest{
+		ID:    utils.ProtoFromUUID(userID),
+		Roles: &profilepb.UserRoles{Roles: []string{"owner"}},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
 func TestServer_UpdateOrg_EnableApprovals(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -51,6 +51,8 @@ type UserInfo struct {
 	IsApproved       bool       `db:"is_approved"`
 	IdentityProvider string     `db:"identity_provider"`
 	AuthProviderID   string     `db:"auth_provider_id"`
+	// Roles are the space separated Pixie roles assigned to the user through SCIM.
+	Roles string `db:"roles"`
 }
 
 // OrgInfo tracks information about an organization.
@@ -126,7 +128,7 @@ func (d *Datastore) CreateUser(userInfo *UserInfo) (uuid.UUID, error) {
 
 // GetUser gets user information by user ID.
 func (d *Datastore) GetUser(id uuid.UUID) (*UserInfo, error) {
-	query := `SELECT id, org_id, first_name, last_name, email, profile_picture, is_approved, identity_provider, auth_provider_id FROM users WHERE id=$1`
+	query := `SELECT id, org_id, first_name, last_name, email, profile_picture, is_approved, identity_provider, auth_provider_id, roles FROM users WHERE id=$1`
 	rows, err := d.db.Queryx(query, id)
 	if err != nil {
 		return nil, err
@@ -315,7 +317,7 @@ func (d *Datastore) CreateInviteSigningKey(id uuid.UUID) (string, error) {
 
 // GetUserByEmail gets user info by email.
 func (d *Datastore) GetUserByEmail(email string) (*UserInfo, error) {
-	query := `SELECT id, org_id, first_name, last_name, email, profile_picture, is_approved, identity_provider, auth_provider_id FROM users WHERE email=$1`
+	query := `SELECT id, org_id, first_name, last_name, email, profile_picture, is_approved, identity_provider, auth_provider_id, roles FROM users WHERE email=$1`
 	rows, err := d.db.Queryx(query, email)
 	if err != nil {
 		return nil, err
@@ -332,7 +334,7 @@ func (d *Datastore) GetUserByEmail(email string) (*UserInfo, error) {
 
 // GetUserByAuthProviderID gets userinfo by auth provider id.
 func (d *Datastore) GetUserByAuthProviderID(id string) (*UserInfo, error) {
-	query := `SELECT id, org_id, first_name, last_name, email, profile_picture, is_approved, identity_provider, auth_provider_id FROM users WHERE auth_provider_id=$1`
+	query := `SELECT id, org_id, first_name, last_name, email, profile_picture, is_approved, identity_provider, auth_provider_id, roles FROM users WHERE auth_provider_id=$1`
 	rows, err := d.db.Queryx(query, id)
 	if err != nil {
 		return nil, err
@@ -406,7 +408,7 @@ func (d *Datastore) DeleteOrgAndUsers(orgID uuid.UUID) error {
 }
 
 func (d *Datastore) createUserUsingTxn(txn *sqlx.Tx, userInfo *UserInfo) (uuid.UUID, error) {
-	query := `INSERT INTO users (org_id, first_name, last_name, email, is_approved, identity_provider, auth_provider_id) VALUES (:org_id, :first_name, :last_name, :email, :is_approved, :identity_provider, :auth_provider_id) RETURNING id`
+	query := `INSERT INTO users (org_id, first_name, last_name, email, is_approved, identity_provider, auth_provider_id, roles) VALUES (:org_id, :first_name, :last_name, :email, :is_approved, :identity_provider, :auth_provider_id, :roles) RETURNING id`
 	rows, err := txn.NamedQuery(query, userInfo)
 	if err != nil {
 		return uuid.Nil, err
@@ -457,7 +459,7 @@ func (d *Datastore) createOrgUsingTxn(txn *sqlx.Tx, orgInfo *OrgInfo) (uuid.UUID
 
 // GetUsersInOrg gets all users in the given org.
 func (d *Datastore) GetUsersInOrg(orgID uuid.UUID) ([]*UserInfo, error) {
-	query := `SELECT id, org_id, first_name, last_name, email, profile_picture, is_approved, identity_provider, auth_provider_id FROM users WHERE org_id=$1 order by created_at desc`
+	query := `SELECT id, org_id, first_name, last_name, email, profile_picture, is_approved, identity_provider, auth_provider_id, roles FROM users WHERE org_id=$1 order by created_at desc`
 	rows, err := d.db.Queryx(query, orgID)
 	if err != nil {
 		return nil, err
@@ -498,7 +500,7 @@ func (d *Datastore) NumUsersInOrg(orgID uuid.UUID) (int, error) {
 
 // UpdateUser updates the user in the database.
 func (d *Datastore) UpdateUser(userInfo *UserInfo) error {
-	query := `UPDATE users SET profile_picture = :profile_picture, is_approved = :is_approved, org_id = :org_id WHERE id = :id`
+	query := `UPDATE users SET profile_picture = :profile_picture, is_approved = :is_approved, org_id = :org_id, roles = :roles WHERE id = :id`
 	_, err := d.db.NamedExec(query, userInfo)
 	return err
 }
@@ -587,6 +587,24 @@ func TestDatastore(t *testing.T) {
 		assert.Equal(t, *userInfoFetched.OrgID, orgID)
 	})
 
+	t.Run("update user roles", func(t *testing.T) {
+		mustLoadTestData(db)
+		d := datastore.NewDatastore(db, "test_key")
+
+		userID := uuid.FromStringOrNil("123e4567-e89b-12d3-a456-426655440001")
+		userInfo, err := d.GetUser(userID)
+		require.NoError(t, err)
+		assert.Equal(t, "", userInfo.Roles)
+
+		userInfo.Roles = "editor viewer"
+		err = d.UpdateUser(userInfo)
+		require.NoError(t, err)
+
+		userInfoFetched, err := d.GetUser(userID)
+		require.NoError(t, err)
+		assert.Equal(t, "editor viewer", userInfoFetched.Roles)
+	})
+
 	t.Run("Get user attributes", func(t *testing.T) {
 		mustLoadTestData(db)
 		d := datastore.NewDatastore(db, "test_key")
@@ -0,0 +1,3 @@
+-- Space separated Pixie roles assigned to the user through SCIM group provisioning.
+ALTER TABLE users
+  ADD COLUMN roles varchar(1000) NOT NULL DEFAULT '';
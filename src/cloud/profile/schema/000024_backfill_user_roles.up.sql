@@ -0,0 +1,3 @@
+-- Users that predate roles had full access to their org, so they keep it as admins. Users created
+-- from now on are given a role when they're created.
+UPDATE users SET roles = 'admin' WHERE roles = '';
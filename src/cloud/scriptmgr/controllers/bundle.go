@@ -22,9 +22,10 @@ import (
 	"context"
 	"encoding/json"
 
-	"github.com/googleapis/google-cloud-go-testing/storage/stiface"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/cloud/shared/objstore"
 )
 
 /*
@@ -44,9 +45,9 @@ type bundle struct {
 	Scripts map[string]*pixieScript `json:"scripts"`
 }
 
-func getBundle(sc stiface.Client, bundleBucket string, bundlePath string) (*bundle, error) {
+func getBundle(bundleStore objstore.Store, bundlePath string) (*bundle, error) {
 	ctx := context.Background()
-	r, err := sc.Bucket(bundleBucket).Object(bundlePath).NewReader(ctx)
+	r, err := bundleStore.Get(ctx, bundlePath)
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to download bundle.json")
 	}
@@ -26,13 +26,13 @@ import (
 
 	"github.com/gofrs/uuid"
 	"github.com/gogo/protobuf/jsonpb"
-	"github.com/googleapis/google-cloud-go-testing/storage/stiface"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	"px.dev/pixie/src/api/proto/vispb"
 	"px.dev/pixie/src/cloud/scriptmgr/scriptmgrpb"
+	"px.dev/pixie/src/cloud/shared/objstore"
 	"px.dev/pixie/src/utils"
 )
 
@@ -57,20 +57,18 @@ type scriptStore struct {
 
 // Server implements the GRPC Server for the scriptmgr service.
 type Server struct {
-	bundleBucket    string
 	bundlePath      string
-	sc              stiface.Client
+	bundleStore     objstore.Store
 	store           *scriptStore
 	storeLastUpdate time.Time
 	SeedUUID        uuid.UUID
 }
 
-// NewServer creates a new GRPC scriptmgr server.
-func NewServer(bundleBucket string, bundlePath string, sc stiface.Client) *Server {
+// NewServer creates a new GRPC scriptmgr server, which reads the bundle of scripts at bundlePath in the store.
+func NewServer(bundleStore objstore.Store, bundlePath string) *Server {
 	s := &Server{
-		bundleBucket: bundleBucket,
-		bundlePath:   bundlePath,
-		sc:           sc,
+		bundlePath:  bundlePath,
+		bundleStore: bundleStore,
 		store: &scriptStore{
 			Scripts:   make(map[uuid.UUID]*scriptModel),
 			LiveViews: make(map[uuid.UUID]*liveViewModel),
@@ -81,9 +79,8 @@ func NewServer(bundleBucket string, bundlePath string, sc stiface.Client) *Serve
 	err := s.updateStore()
 	if err != nil {
 		log.WithError(err).
-			WithField("bucket", s.bundleBucket).
 			WithField("path", s.bundlePath).
-			Error("Failed to update store using bundle.json from gcs.")
+			Error("Failed to update store using bundle.json from object storage.")
 	}
 	return s
 }
@@ -118,7 +115,7 @@ func (s *Server) addScript(name string, bundleScript *pixieScript, hasLiveView b
 }
 
 func (s *Server) updateStore() error {
-	b, err := getBundle(s.sc, s.bundleBucket, s.bundlePath)
+	b, err := getBundle(s.bundleStore, s.bundlePath)
 	if err != nil {
 		return err
 	}
@@ -146,10 +143,9 @@ func (s *Server) storeUpdater() {
 	ctx := context.Background()
 	for range t.C {
 		log.Trace("Checking if bundle needs updating...")
-		attrs, err := s.sc.Bucket(s.bundleBucket).Object(s.bundlePath).Attrs(ctx)
+		attrs, err := s.bundleStore.Attrs(ctx, s.bundlePath)
 		if err != nil {
 			log.WithError(err).
-				WithField("bucket", s.bundleBucket).
 				WithField("path", s.bundlePath).
 				Error("Failed to get attrs of bundle.json")
 			continue
@@ -159,9 +155,8 @@ func (s *Server) storeUpdater() {
 			err := s.updateStore()
 			if err != nil {
 				log.WithError(err).
-					WithField("bucket", s.bundleBucket).
 					WithField("path", s.bundlePath).
-					Error("Failed to update bundle.json from gcs.")
+					Error("Failed to update bundle.json from object storage.")
 			}
 			log.
 				WithField("scripts", s.store.Scripts).
@@ -28,7 +28,6 @@ import (
 	"cloud.google.com/go/storage"
 	"github.com/gofrs/uuid"
 	"github.com/gogo/protobuf/jsonpb"
-	"github.com/googleapis/google-cloud-go-testing/storage/stiface"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/codes"
@@ -37,6 +36,7 @@ import (
 	"px.dev/pixie/src/api/proto/vispb"
 	"px.dev/pixie/src/cloud/scriptmgr/controllers"
 	"px.dev/pixie/src/cloud/scriptmgr/scriptmgrpb"
+	"px.dev/pixie/src/cloud/shared/objstore"
 	"px.dev/pixie/src/utils"
 	"px.dev/pixie/src/utils/testingutils"
 )
@@ -88,11 +88,11 @@ var testBundle = map[string]scriptsDef{
 	},
 }
 
-func mustSetupFakeBucket(t *testing.T, testBundle map[string]scriptsDef) stiface.Client {
+func mustSetupFakeBucket(t *testing.T, testBundle map[string]scriptsDef) objstore.Store {
 	bundleJSON, err := json.Marshal(testBundle)
 	require.NoError(t, err)
 
-	return testingutils.NewMockGCSClient(map[string]*testingutils.MockGCSBucket{
+	return objstore.NewGCSStore(testingutils.NewMockGCSClient(map[string]*testingutils.MockGCSBucket{
 		bundleBucket: testingutils.NewMockGCSBucket(
 			map[string]*testingutils.MockGCSObject{
 				bundlePath: testingutils.NewMockGCSObject(
@@ -104,7 +104,7 @@ func mustSetupFakeBucket(t *testing.T, testBundle map[string]scriptsDef) stiface
 			},
 			nil,
 		),
-	})
+	}), bundleBucket, "")
 }
 
 func TestScriptMgr_GetLiveViews(t *testing.T) {
@@ -131,7 +131,7 @@ func TestScriptMgr_GetLiveViews(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			c := mustSetupFakeBucket(t, testBundle)
-			s := controllers.NewServer(bundleBucket, bundlePath, c)
+			s := controllers.NewServer(c, bundlePath)
 			ctx := context.Background()
 
 			req := &scriptmgrpb.GetLiveViewsReq{}
@@ -174,7 +174,7 @@ func TestScriptMgr_GetLiveViewContents(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			c := mustSetupFakeBucket(t, testBundle)
-			s := controllers.NewServer(bundleBucket, bundlePath, c)
+			s := controllers.NewServer(c, bundlePath)
 			ctx := context.Background()
 
 			id := uuid.NewV5(s.SeedUUID, tc.liveViewName)
@@ -252,7 +252,7 @@ func TestScriptMgr_GetScripts(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			c := mustSetupFakeBucket(t, testBundle)
-			s := controllers.NewServer(bundleBucket, bundlePath, c)
+			s := controllers.NewServer(c, bundlePath)
 			ctx := context.Background()
 
 			req := &scriptmgrpb.GetScriptsReq{}
@@ -295,7 +295,7 @@ func TestScriptMgr_GetScriptContents(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			c := mustSetupFakeBucket(t, testBundle)
-			s := controllers.NewServer(bundleBucket, bundlePath, c)
+			s := controllers.NewServer(c, bundlePath)
 			ctx := context.Background()
 			id := uuid.NewV5(s.SeedUUID, tc.scriptName)
 			req := &scriptmgrpb.GetScriptContentsReq{
@@ -0,0 +1,324 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package queryhistory stores the script executions that users save, along with a snapshot of their results,
+// so that findings outlive the data retention of the cluster.
+package queryhistory
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
+	"github.com/jmoiron/sqlx"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/cloud/scriptmgr/scriptmgrpb"
+	"px.dev/pixie/src/utils"
+)
+
+// MaxResultSizeBytes is the largest result snapshot that can be saved. It leaves room for the rest of the
+// request within the default gRPC message size limit.
+const MaxResultSizeBytes = 3 << 20
+
+const shareTokenBytes = 32
+
+// Server implements the QueryHistoryService.
+type Server struct {
+	db *sqlx.DB
+}
+
+// NewServer creates a new query history server.
+func NewServer(db *sqlx.DB) *Server {
+	return &Server{db: db}
+}
+
+type savedQueryRow struct {
+	ID         uuid.UUID      `db:"id"`
+	OrgID      uuid.UUID      `db:"org_id"`
+	UserID     uuid.UUID      `db:"user_id"`
+	Name       string         `db:"name"`
+	ClusterID  uuid.NullUUID  `db:"cluster_id"`
+	ScriptName sql.NullString `db:"script_name"`
+	ExecutedAt time.Time      `db:"executed_at"`
+	CreatedAt  time.Time      `db:"created_at"`
+	ResultSize int64          `db:"result_size"`
+	ShareToken sql.NullString `db:"share_token"`
+}
+
+func (r *savedQueryRow) toProto() *scriptmgrpb.SavedQueryMetadata {
+	md := &scriptmgrpb.SavedQueryMetadata{
+		ID:              utils.ProtoFromUUID(r.ID),
+		Name:            r.Name,
+		UserID:          utils.ProtoFromUUID(r.UserID),
+		ScriptName:      r.ScriptName.String,
+		ResultSizeBytes: r.ResultSize,
+		Shared:          r.ShareToken.Valid,
+	}
+	md.ExecutedAt, _ = types.TimestampProto(r.ExecutedAt)
+	md.CreatedAt, _ = types.TimestampProto(r.CreatedAt)
+	if r.ClusterID.Valid {
+		md.ClusterID = utils.ProtoFromUUID(r.ClusterID.UUID)
+	}
+	return md
+}
+
+type savedQueryContents struct {
+	savedQueryRow
+	Pxl    string `db:"pxl"`
+	Args   []byte `db:"args"`
+	Result string `db:"result"`
+}
+
+func (c *savedQueryContents) toResp() (*scriptmgrpb.GetSavedQueryResp, error) {
+	var args []*scriptmgrpb.SavedQueryArg
+	if err := json.Unmarshal(c.Args, &args); err != nil {
+		return nil, status.Error(codes.Internal, "failed to read saved query args")
+	}
+	return &scriptmgrpb.GetSavedQueryResp{
+		Metadata: c.savedQueryRow.toProto(),
+		Pxl:      c.Pxl,
+		Args:     args,
+		Result:   c.Result,
+	}, nil
+}
+
+const selectMetadataColumns = `id, org_id, user_id, name, cluster_id, script_name, executed_at, created_at,
+	octet_length(result::text) AS result_size, share_token`
+
+func orgIDFromProto(id *uuidpb.UUID) (uuid.UUID, error) {
+	orgID := utils.UUIDFromProtoOrNil(id)
+	if orgID == uuid.Nil {
+		return uuid.Nil, status.Error(codes.InvalidArgument, "invalid org ID")
+	}
+	return orgID, nil
+}
+
+func userIDFromProto(id *uuidpb.UUID) (uuid.UUID, error) {
+	userID := utils.UUIDFromProtoOrNil(id)
+	if userID == uuid.Nil {
+		return uuid.Nil, status.Error(codes.InvalidArgument, "invalid user ID")
+	}
+	return userID, nil
+}
+
+func newShareToken() (string, error) {
+	b := make([]byte, shareTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (s *Server) getSavedQuery(orgID uuid.UUID, id uuid.UUID) (*savedQueryRow, error) {
+	var row savedQueryRow
+	err := s.db.Get(&row, `SELECT `+selectMetadataColumns+` FROM saved_queries WHERE org_id=$1 AND id=$2`, orgID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Error(codes.NotFound, "saved query not found")
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to fetch saved query")
+	}
+	return &row, nil
+}
+
+// getOwnedSavedQuery fetches a saved query for a change that only the user who saved it may make.
+func (s *Server) getOwnedSavedQuery(orgIDPb *uuidpb.UUID, idPb *uuidpb.UUID, userIDPb *uuidpb.UUID) (*savedQueryRow, error) {
+	orgID, err := orgIDFromProto(orgIDPb)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := userIDFromProto(userIDPb)
+	if err != nil {
+		return nil, err
+	}
+	row, err := s.getSavedQuery(orgID, utils.UUIDFromProtoOrNil(idPb))
+	if err != nil {
+		return nil, err
+	}
+	if row.UserID != userID {
+		return nil, status.Error(codes.PermissionDenied, "only the user who saved the query can change it")
+	}
+	return row, nil
+}
+
+// SaveQuery saves a script execution and the snapshot of its results.
+func (s *Server) SaveQuery(ctx context.Context, req *scriptmgrpb.SaveQueryReq) (*scriptmgrpb.SaveQueryResp, error) {
+	orgID, err := orgIDFromProto(req.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := userIDFromProto(req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+	if req.Pxl == "" {
+		return nil, status.Error(codes.InvalidArgument, "pxl is required")
+	}
+	if len(req.Result) > MaxResultSizeBytes {
+		return nil, status.Errorf(codes.InvalidArgument, "result is %d bytes, which is over the limit of %d bytes",
+			len(req.Result), MaxResultSizeBytes)
+	}
+	if !json.Valid([]byte(req.Result)) {
+		return nil, status.Error(codes.InvalidArgument, "result must be a JSON document")
+	}
+
+	executedAt := time.Now()
+	if req.ExecutedAt != nil {
+		executedAt, err = types.TimestampFromProto(req.ExecutedAt)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid execution time")
+		}
+	}
+	args := req.Args
+	if args == nil {
+		args = []*scriptmgrpb.SavedQueryArg{}
+	}
+	argsJSON, err := json.Marshal(args)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to save query")
+	}
+	clusterID := uuid.NullUUID{UUID: utils.UUIDFromProtoOrNil(req.ClusterID)}
+	clusterID.Valid = clusterID.UUID != uuid.Nil
+	scriptName := sql.NullString{String: req.ScriptName, Valid: req.ScriptName != ""}
+
+	var id uuid.UUID
+	err = s.db.Get(&id, `INSERT INTO saved_queries (org_id, user_id, name, cluster_id, script_name, pxl, args, result, executed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`, orgID, userID, req.Name, clusterID, scriptName,
+		req.Pxl, string(argsJSON), req.Result, executedAt.UTC())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to save query")
+	}
+	return &scriptmgrpb.SaveQueryResp{ID: utils.ProtoFromUUID(id)}, nil
+}
+
+// ListSavedQueries lists the saved queries of an org, newest first.
+func (s *Server) ListSavedQueries(ctx context.Context, req *scriptmgrpb.ListSavedQueriesReq) (*scriptmgrpb.ListSavedQueriesResp, error) {
+	orgID, err := orgIDFromProto(req.OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []savedQueryRow
+	query := `SELECT ` + selectMetadataColumns + ` FROM saved_queries WHERE org_id=$1`
+	queryArgs := []interface{}{orgID}
+	if userID := utils.UUIDFromProtoOrNil(req.UserID); userID != uuid.Nil {
+		query += ` AND user_id=$2`
+		queryArgs = append(queryArgs, userID)
+	}
+	err = s.db.Select(&rows, query+` ORDER BY created_at DESC`, queryArgs...)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list saved queries")
+	}
+
+	resp := &scriptmgrpb.ListSavedQueriesResp{Queries: make([]*scriptmgrpb.SavedQueryMetadata, len(rows))}
+	for i := range rows {
+		resp.Queries[i] = rows[i].toProto()
+	}
+	return resp, nil
+}
+
+// GetSavedQuery returns a saved query of the org and its results.
+func (s *Server) GetSavedQuery(ctx context.Context, req *scriptmgrpb.GetSavedQueryReq) (*scriptmgrpb.GetSavedQueryResp, error) {
+	orgID, err := orgIDFromProto(req.OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	var row savedQueryContents
+	err = s.db.Get(&row, `SELECT `+selectMetadataColumns+`, pxl, args, result FROM saved_queries WHERE org_id=$1 AND id=$2`,
+		orgID, utils.UUIDFromProtoOrNil(req.ID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Error(codes.NotFound, "saved query not found")
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to fetch saved query")
+	}
+	return row.toResp()
+}
+
+// DeleteSavedQuery deletes a saved query, along with any links that it was shared with.
+func (s *Server) DeleteSavedQuery(ctx context.Context, req *scriptmgrpb.DeleteSavedQueryReq) (*scriptmgrpb.DeleteSavedQueryResp, error) {
+	row, err := s.getOwnedSavedQuery(req.OrgID, req.ID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.db.Exec(`DELETE FROM saved_queries WHERE id=$1`, row.ID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to delete saved query")
+	}
+	return &scriptmgrpb.DeleteSavedQueryResp{}, nil
+}
+
+// ShareSavedQuery creates the share token of a saved query, or revokes it.
+func (s *Server) ShareSavedQuery(ctx context.Context, req *scriptmgrpb.ShareSavedQueryReq) (*scriptmgrpb.ShareSavedQueryResp, error) {
+	row, err := s.getOwnedSavedQuery(req.OrgID, req.ID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Revoke {
+		_, err = s.db.Exec(`UPDATE saved_queries SET share_token=NULL WHERE id=$1`, row.ID)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to revoke share token")
+		}
+		return &scriptmgrpb.ShareSavedQueryResp{}, nil
+	}
+	if row.ShareToken.Valid {
+		return &scriptmgrpb.ShareSavedQueryResp{ShareToken: row.ShareToken.String}, nil
+	}
+
+	token, err := newShareToken()
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to create share token")
+	}
+	_, err = s.db.Exec(`UPDATE saved_queries SET share_token=$1 WHERE id=$2`, token, row.ID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to create share token")
+	}
+	return &scriptmgrpb.ShareSavedQueryResp{ShareToken: token}, nil
+}
+
+// GetSharedQuery returns a saved query by its share token, regardless of the org of the caller.
+func (s *Server) GetSharedQuery(ctx context.Context, req *scriptmgrpb.GetSharedQueryReq) (*scriptmgrpb.GetSavedQueryResp, error) {
+	if req.ShareToken == "" {
+		return nil, status.Error(codes.InvalidArgument, "share token is required")
+	}
+
+	var row savedQueryContents
+	err := s.db.Get(&row, `SELECT `+selectMetadataColumns+`, pxl, args, result FROM saved_queries WHERE share_token=$1`,
+		req.ShareToken)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Error(codes.NotFound, "shared query not found")
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to fetch shared query")
+	}
+	return row.toResp()
+}
@@ -0,0 +1,255 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package queryhistory_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	bindata "github.com/golang-migrate/migrate/source/go_bindata"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/cloud/scriptmgr/queryhistory"
+	"px.dev/pixie/src/cloud/scriptmgr/schema"
+	"px.dev/pixie/src/cloud/scriptmgr/scriptmgrpb"
+	"px.dev/pixie/src/shared/services/pgtest"
+	"px.dev/pixie/src/utils"
+)
+
+var db *sqlx.DB
+
+var (
+	orgID       = uuid.FromStringOrNil("223e4567-e89b-12d3-a456-426655440000")
+	otherOrgID  = uuid.FromStringOrNil("323e4567-e89b-12d3-a456-426655440000")
+	userID      = uuid.FromStringOrNil("423e4567-e89b-12d3-a456-426655440000")
+	otherUserID = uuid.FromStringOrNil("523e4567-e89b-12d3-a456-426655440000")
+	clusterID   = uuid.FromStringOrNil("623e4567-e89b-12d3-a456-426655440000")
+)
+
+const testResult = `{"queryID":"abcd","tables":[{"name":"output","columns":[{"name":"count","type":"INT64"}],"rows":[{"count":10}]}]}`
+
+func TestMain(m *testing.M) {
+	err := testMain(m)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Got error: %v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func testMain(m *testing.M) error {
+	s := bindata.Resource(schema.AssetNames(), schema.Asset)
+	testDB, teardown, err := pgtest.SetupTestDB(s)
+	if err != nil {
+		return fmt.Errorf("failed to start test database: %w", err)
+	}
+
+	defer teardown()
+	db = testDB
+
+	if c := m.Run(); c != 0 {
+		return fmt.Errorf("some tests failed with code: %d", c)
+	}
+	return nil
+}
+
+func mustClearTables(db *sqlx.DB) {
+	db.MustExec(`DELETE FROM saved_queries`)
+}
+
+func saveQuery(t *testing.T, s *queryhistory.Server, org uuid.UUID, user uuid.UUID, name string) uuid.UUID {
+	resp, err := s.SaveQuery(context.Background(), &scriptmgrpb.SaveQueryReq{
+		OrgID:      utils.ProtoFromUUID(org),
+		UserID:     utils.ProtoFromUUID(user),
+		Name:       name,
+		ClusterID:  utils.ProtoFromUUID(clusterID),
+		ScriptName: "px/http_data",
+		Pxl:        "px.display(1)",
+		Args: []*scriptmgrpb.SavedQueryArg{
+			{Name: "start_time", Value: "-5m"},
+		},
+		Result: testResult,
+	})
+	require.NoError(t, err)
+	return utils.UUIDFromProtoOrNil(resp.ID)
+}
+
+func TestServer_SaveQuery(t *testing.T) {
+	mustClearTables(db)
+	s := queryhistory.NewServer(db)
+
+	id := saveQuery(t, s, orgID, userID, "incident 123")
+
+	resp, err := s.GetSavedQuery(context.Background(), &scriptmgrpb.GetSavedQueryReq{
+		OrgID: utils.ProtoFromUUID(orgID),
+		ID:    utils.ProtoFromUUID(id),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "incident 123", resp.Metadata.Name)
+	assert.Equal(t, utils.ProtoFromUUID(userID), resp.Metadata.UserID)
+	assert.Equal(t, utils.ProtoFromUUID(clusterID), resp.Metadata.ClusterID)
+	assert.Equal(t, "px/http_data", resp.Metadata.ScriptName)
+	assert.NotNil(t, resp.Metadata.ExecutedAt)
+	assert.False(t, resp.Metadata.Shared)
+	assert.Equal(t, "px.display(1)", resp.Pxl)
+	assert.Equal(t, []*scriptmgrpb.SavedQueryArg{{Name: "start_time", Value: "-5m"}}, resp.Args)
+	assert.JSONEq(t, testResult, resp.Result)
+
+	// Saved queries aren't visible to other orgs.
+	_, err = s.GetSavedQuery(context.Background(), &scriptmgrpb.GetSavedQueryReq{
+		OrgID: utils.ProtoFromUUID(otherOrgID),
+		ID:    utils.ProtoFromUUID(id),
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_SaveQuery_Invalid(t *testing.T) {
+	mustClearTables(db)
+	s := queryhistory.NewServer(db)
+
+	tests := []struct {
+		name   string
+		result string
+	}{
+		{name: "not JSON", result: "table output"},
+		{name: "too large", result: `"` + strings.Repeat("a", queryhistory.MaxResultSizeBytes) + `"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := s.SaveQuery(context.Background(), &scriptmgrpb.SaveQueryReq{
+				OrgID:  utils.ProtoFromUUID(orgID),
+				UserID: utils.ProtoFromUUID(userID),
+				Name:   "query",
+				Pxl:    "px.display(1)",
+				Result: test.result,
+			})
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+}
+
+func TestServer_ListSavedQueries(t *testing.T) {
+	mustClearTables(db)
+	s := queryhistory.NewServer(db)
+
+	saveQuery(t, s, orgID, userID, "first")
+	saveQuery(t, s, orgID, otherUserID, "second")
+	saveQuery(t, s, otherOrgID, userID, "other org")
+
+	resp, err := s.ListSavedQueries(context.Background(), &scriptmgrpb.ListSavedQueriesReq{
+		OrgID: utils.ProtoFromUUID(orgID),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Queries, 2)
+	assert.Equal(t, "second", resp.Queries[0].Name)
+	assert.Equal(t, "first", resp.Queries[1].Name)
+	assert.Greater(t, resp.Queries[0].ResultSizeBytes, int64(0))
+
+	resp, err = s.ListSavedQueries(context.Background(), &scriptmgrpb.ListSavedQueriesReq{
+		OrgID:  utils.ProtoFromUUID(orgID),
+		UserID: utils.ProtoFromUUID(userID),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Queries, 1)
+	assert.Equal(t, "first", resp.Queries[0].Name)
+}
+
+func TestServer_DeleteSavedQuery(t *testing.T) {
+	mustClearTables(db)
+	s := queryhistory.NewServer(db)
+
+	id := saveQuery(t, s, orgID, userID, "query")
+
+	// Only the user who saved the query can delete it.
+	_, err := s.DeleteSavedQuery(context.Background(), &scriptmgrpb.DeleteSavedQueryReq{
+		OrgID:  utils.ProtoFromUUID(orgID),
+		ID:     utils.ProtoFromUUID(id),
+		UserID: utils.ProtoFromUUID(otherUserID),
+	})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = s.DeleteSavedQuery(context.Background(), &scriptmgrpb.DeleteSavedQueryReq{
+		OrgID:  utils.ProtoFromUUID(orgID),
+		ID:     utils.ProtoFromUUID(id),
+		UserID: utils.ProtoFromUUID(userID),
+	})
+	require.NoError(t, err)
+
+	_, err = s.GetSavedQuery(context.Background(), &scriptmgrpb.GetSavedQueryReq{
+		OrgID: utils.ProtoFromUUID(orgID),
+		ID:    utils.ProtoFromUUID(id),
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_ShareSavedQuery(t *testing.T) {
+	mustClearTables(db)
+	s := queryhistory.NewServer(db)
+
+	id := saveQuery(t, s, orgID, userID, "query")
+
+	_, err := s.ShareSavedQuery(context.Background(), &scriptmgrpb.ShareSavedQueryReq{
+		OrgID:  utils.ProtoFromUUID(orgID),
+		ID:     utils.ProtoFromUUID(id),
+		UserID: utils.ProtoFromUUID(otherUserID),
+	})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	shareResp, err := s.ShareSavedQuery(context.Background(), &scriptmgrpb.ShareSavedQueryReq{
+		OrgID:  utils.ProtoFromUUID(orgID),
+		ID:     utils.ProtoFromUUID(id),
+		UserID: utils.ProtoFromUUID(userID),
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, shareResp.ShareToken)
+
+	// Sharing again returns the same token.
+	again, err := s.ShareSavedQuery(context.Background(), &scriptmgrpb.ShareSavedQueryReq{
+		OrgID:  utils.ProtoFromUUID(orgID),
+		ID:     utils.ProtoFromUUID(id),
+		UserID: utils.ProtoFromUUID(userID),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, shareResp.ShareToken, again.ShareToken)
+
+	shared, err := s.GetSharedQuery(context.Background(), &scriptmgrpb.GetSharedQueryReq{ShareToken: shareResp.ShareToken})
+	require.NoError(t, err)
+	assert.Equal(t, utils.ProtoFromUUID(id), shared.Metadata.ID)
+	assert.True(t, shared.Metadata.Shared)
+	assert.JSONEq(t, testResult, shared.Result)
+
+	_, err = s.ShareSavedQuery(context.Background(), &scriptmgrpb.ShareSavedQueryReq{
+		OrgID:  utils.ProtoFromUUID(orgID),
+		ID:     utils.ProtoFromUUID(id),
+		UserID: utils.ProtoFromUUID(userID),
+		Revoke: true,
+	})
+	require.NoError(t, err)
+
+	_, err = s.GetSharedQuery(context.Background(), &scriptmgrpb.GetSharedQueryReq{ShareToken: shareResp.ShareToken})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -0,0 +1,441 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package registry implements the versioned script registry, which stores an org's scripts and vis specs
+// along with their review state.
+package registry
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/gogo/protobuf/types"
+	"github.com/jmoiron/sqlx"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/api/proto/vispb"
+	"px.dev/pixie/src/cloud/scriptmgr/scriptmgrpb"
+	"px.dev/pixie/src/utils"
+)
+
+// Statuses of a script version, as stored in the database.
+const (
+	statusDraft     = "draft"
+	statusInReview  = "in_review"
+	statusPublished = "published"
+	statusRejected  = "rejected"
+)
+
+var statusToProto = map[string]scriptmgrpb.RegistryScriptVersionStatus{
+	statusDraft:     scriptmgrpb.RSV_DRAFT,
+	statusInReview:  scriptmgrpb.RSV_IN_REVIEW,
+	statusPublished: scriptmgrpb.RSV_PUBLISHED,
+	statusRejected:  scriptmgrpb.RSV_REJECTED,
+}
+
+// Server implements the ScriptRegistryService.
+type Server struct {
+	db *sqlx.DB
+}
+
+// NewServer creates a new script registry server.
+func NewServer(db *sqlx.DB) *Server {
+	return &Server{db: db}
+}
+
+type scriptRow struct {
+	ID               uuid.UUID     `db:"id"`
+	Name             string        `db:"name"`
+	CreatedAt        time.Time     `db:"created_at"`
+	LatestVersion    int64         `db:"latest_version"`
+	PublishedVersion sql.NullInt64 `db:"published_version"`
+}
+
+func (r *scriptRow) toProto() *scriptmgrpb.RegistryScript {
+	createdAt, _ := types.TimestampProto(r.CreatedAt)
+	return &scriptmgrpb.RegistryScript{
+		ID:               utils.ProtoFromUUID(r.ID),
+		Name:             r.Name,
+		LatestVersion:    r.LatestVersion,
+		PublishedVersion: r.PublishedVersion.Int64,
+		CreatedAt:        createdAt,
+	}
+}
+
+type versionRow struct {
+	ScriptID      uuid.UUID      `db:"script_id"`
+	Version       int64          `db:"version"`
+	Status        string         `db:"status"`
+	ShortDoc      sql.NullString `db:"short_doc"`
+	LongDoc       sql.NullString `db:"long_doc"`
+	Pxl           string         `db:"pxl"`
+	Vis           sql.NullString `db:"vis"`
+	AuthorID      uuid.UUID      `db:"author_id"`
+	ReviewerID    uuid.NullUUID  `db:"reviewer_id"`
+	ReviewComment sql.NullString `db:"review_comment"`
+	CreatedAt     time.Time      `db:"created_at"`
+	PublishedAt   sql.NullTime   `db:"published_at"`
+}
+
+func (r *versionRow) metadata() *scriptmgrpb.RegistryScriptVersionMetadata {
+	md := &scriptmgrpb.RegistryScriptVersionMetadata{
+		ScriptID:      utils.ProtoFromUUID(r.ScriptID),
+		Version:       r.Version,
+		Status:        statusToProto[r.Status],
+		ShortDoc:      r.ShortDoc.String,
+		AuthorID:      utils.ProtoFromUUID(r.AuthorID),
+		ReviewComment: r.ReviewComment.String,
+	}
+	md.CreatedAt, _ = types.TimestampProto(r.CreatedAt)
+	if r.ReviewerID.Valid {
+		md.ReviewerID = utils.ProtoFromUUID(r.ReviewerID.UUID)
+	}
+	if r.PublishedAt.Valid {
+		md.PublishedAt, _ = types.TimestampProto(r.PublishedAt.Time)
+	}
+	return md
+}
+
+const selectScriptsQuery = `SELECT s.id, s.name, s.created_at,
+	(SELECT MAX(v.version) FROM registry_script_versions v WHERE v.script_id = s.id) AS latest_version,
+	(SELECT MAX(v.version) FROM registry_script_versions v WHERE v.script_id = s.id AND v.status = 'published') AS published_version
+	FROM registry_scripts s`
+
+const selectVersionsQuery = `SELECT script_id, version, status, short_doc, long_doc, pxl, vis, author_id, reviewer_id,
+	review_comment, created_at, published_at FROM registry_script_versions`
+
+func orgIDFromProto(id *uuidpb.UUID) (uuid.UUID, error) {
+	orgID := utils.UUIDFromProtoOrNil(id)
+	if orgID == uuid.Nil {
+		return uuid.Nil, status.Error(codes.InvalidArgument, "invalid org ID")
+	}
+	return orgID, nil
+}
+
+// validateVis checks that the vis spec, if any, is a valid JSON vis spec.
+func validateVis(vis string) error {
+	if vis == "" {
+		return nil
+	}
+	var v vispb.Vis
+	if err := jsonpb.UnmarshalString(vis, &v); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid vis spec: %s", err.Error())
+	}
+	return nil
+}
+
+// validateName checks that the script name is a valid path such as "http/errors".
+func validateName(name string) error {
+	if name == "" {
+		return status.Error(codes.InvalidArgument, "script name is required")
+	}
+	if strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/") || strings.Contains(name, "//") {
+		return status.Error(codes.InvalidArgument, "script name must be a path such as 'http/errors'")
+	}
+	if strings.HasPrefix(name, "px/") {
+		return status.Error(codes.InvalidArgument, "the 'px/' prefix is reserved for Pixie scripts")
+	}
+	return nil
+}
+
+func (s *Server) getScript(orgID uuid.UUID, scriptID uuid.UUID, name string) (*scriptRow, error) {
+	var row scriptRow
+	var err error
+	if scriptID != uuid.Nil {
+		err = s.db.Get(&row, selectScriptsQuery+` WHERE s.org_id=$1 AND s.id=$2`, orgID, scriptID)
+	} else {
+		err = s.db.Get(&row, selectScriptsQuery+` WHERE s.org_id=$1 AND s.name=$2`, orgID, name)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Error(codes.NotFound, "script not found")
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to fetch script")
+	}
+	return &row, nil
+}
+
+func (s *Server) getVersion(orgID uuid.UUID, scriptID uuid.UUID, version int64) (*versionRow, error) {
+	// Check that the script belongs to the org.
+	if _, err := s.getScript(orgID, scriptID, ""); err != nil {
+		return nil, err
+	}
+
+	var row versionRow
+	var err error
+	if version == 0 {
+		err = s.db.Get(&row, selectVersionsQuery+` WHERE script_id=$1 AND status='published' ORDER BY version DESC LIMIT 1`, scriptID)
+	} else {
+		err = s.db.Get(&row, selectVersionsQuery+` WHERE script_id=$1 AND version=$2`, scriptID, version)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Error(codes.NotFound, "script version not found")
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to fetch script version")
+	}
+	return &row, nil
+}
+
+// ListRegistryScripts lists the scripts in the org's registry.
+func (s *Server) ListRegistryScripts(ctx context.Context, req *scriptmgrpb.ListRegistryScriptsReq) (*scriptmgrpb.ListRegistryScriptsResp, error) {
+	orgID, err := orgIDFromProto(req.OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []scriptRow
+	err = s.db.Select(&rows, selectScriptsQuery+` WHERE s.org_id=$1 ORDER BY s.name`, orgID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list scripts")
+	}
+
+	resp := &scriptmgrpb.ListRegistryScriptsResp{Scripts: make([]*scriptmgrpb.RegistryScript, len(rows))}
+	for i := range rows {
+		resp.Scripts[i] = rows[i].toProto()
+	}
+	return resp, nil
+}
+
+// GetRegistryScript returns a script and the metadata of all of its versions.
+func (s *Server) GetRegistryScript(ctx context.Context, req *scriptmgrpb.GetRegistryScriptReq) (*scriptmgrpb.GetRegistryScriptResp, error) {
+	orgID, err := orgIDFromProto(req.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	script, err := s.getScript(orgID, utils.UUIDFromProtoOrNil(req.ScriptID), req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []versionRow
+	err = s.db.Select(&rows, selectVersionsQuery+` WHERE script_id=$1 ORDER BY version DESC`, script.ID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to fetch script versions")
+	}
+
+	resp := &scriptmgrpb.GetRegistryScriptResp{
+		Script:   script.toProto(),
+		Versions: make([]*scriptmgrpb.RegistryScriptVersionMetadata, len(rows)),
+	}
+	for i := range rows {
+		resp.Versions[i] = rows[i].metadata()
+	}
+	return resp, nil
+}
+
+// GetRegistryScriptVersion returns the contents of a version of a script.
+func (s *Server) GetRegistryScriptVersion(ctx context.Context, req *scriptmgrpb.GetRegistryScriptVersionReq) (*scriptmgrpb.GetRegistryScriptVersionResp, error) {
+	orgID, err := orgIDFromProto(req.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.getVersion(orgID, utils.UUIDFromProtoOrNil(req.ScriptID), req.Version)
+	if err != nil {
+		return nil, err
+	}
+	return &scriptmgrpb.GetRegistryScriptVersionResp{
+		Metadata: v.metadata(),
+		LongDoc:  v.LongDoc.String,
+		Pxl:      v.Pxl,
+		Vis:      v.Vis.String,
+	}, nil
+}
+
+// CreateRegistryScriptVersion creates a new draft version of a script, creating the script if it does not exist yet.
+func (s *Server) CreateRegistryScriptVersion(ctx context.Context, req *scriptmgrpb.CreateRegistryScriptVersionReq) (*scriptmgrpb.CreateRegistryScriptVersionResp, error) {
+	orgID, err := orgIDFromProto(req.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateName(req.Name); err != nil {
+		return nil, err
+	}
+	if req.Pxl == "" {
+		return nil, status.Error(codes.InvalidArgument, "pxl is required")
+	}
+	if err := validateVis(req.Vis); err != nil {
+		return nil, err
+	}
+
+	txn, err := s.db.Beginx()
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to create script version")
+	}
+	defer txn.Rollback()
+
+	var scriptID uuid.UUID
+	err = txn.Get(&scriptID, `INSERT INTO registry_scripts (org_id, name) VALUES ($1, $2)
+		ON CONFLICT (org_id, name) DO UPDATE SET name = EXCLUDED.name RETURNING id`, orgID, req.Name)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to create script")
+	}
+
+	// Lock the script's row so that concurrent requests don't pick the same version number.
+	if _, err := txn.Exec(`SELECT id FROM registry_scripts WHERE id=$1 FOR UPDATE`, scriptID); err != nil {
+		return nil, status.Error(codes.Internal, "failed to create script version")
+	}
+	var version int64
+	err = txn.Get(&version, `SELECT COALESCE(MAX(version), 0) + 1 FROM registry_script_versions WHERE script_id=$1`, scriptID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to create script version")
+	}
+
+	_, err = txn.Exec(`INSERT INTO registry_script_versions (script_id, version, short_doc, long_doc, pxl, vis, author_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`, scriptID, version, req.ShortDoc, req.LongDoc, req.Pxl, req.Vis,
+		utils.UUIDFromProtoOrNil(req.AuthorID))
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to create script version")
+	}
+	if err := txn.Commit(); err != nil {
+		return nil, status.Error(codes.Internal, "failed to create script version")
+	}
+
+	return &scriptmgrpb.CreateRegistryScriptVersionResp{
+		ScriptID: utils.ProtoFromUUID(scriptID),
+		Version:  version,
+	}, nil
+}
+
+// SubmitRegistryScriptVersion submits a draft or rejected version for review.
+func (s *Server) SubmitRegistryScriptVersion(ctx context.Context, req *scriptmgrpb.SubmitRegistryScriptVersionReq) (*scriptmgrpb.SubmitRegistryScriptVersionResp, error) {
+	orgID, err := orgIDFromProto(req.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.getVersion(orgID, utils.UUIDFromProtoOrNil(req.ScriptID), req.Version)
+	if err != nil {
+		return nil, err
+	}
+	if v.Status != statusDraft && v.Status != statusRejected {
+		return nil, status.Errorf(codes.FailedPrecondition, "only draft or rejected versions can be submitted, version is %s", v.Status)
+	}
+
+	_, err = s.db.Exec(`UPDATE registry_script_versions SET status='in_review', reviewer_id=NULL, review_comment=NULL
+		WHERE script_id=$1 AND version=$2`, v.ScriptID, v.Version)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to submit script version")
+	}
+	return &scriptmgrpb.SubmitRegistryScriptVersionResp{}, nil
+}
+
+// ReviewRegistryScriptVersion approves or rejects a version that is in review. Versions can't be reviewed by their author.
+func (s *Server) ReviewRegistryScriptVersion(ctx context.Context, req *scriptmgrpb.ReviewRegistryScriptVersionReq) (*scriptmgrpb.ReviewRegistryScriptVersionResp, error) {
+	orgID, err := orgIDFromProto(req.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Version == 0 {
+		return nil, status.Error(codes.InvalidArgument, "version is required")
+	}
+	v, err := s.getVersion(orgID, utils.UUIDFromProtoOrNil(req.ScriptID), req.Version)
+	if err != nil {
+		return nil, err
+	}
+	if v.Status != statusInReview {
+		return nil, status.Errorf(codes.FailedPrecondition, "only versions in review can be reviewed, version is %s", v.Status)
+	}
+	reviewerID := utils.UUIDFromProtoOrNil(req.ReviewerID)
+	if reviewerID == v.AuthorID {
+		return nil, status.Error(codes.PermissionDenied, "versions can't be reviewed by their author")
+	}
+
+	// The status is checked again by the update, so that concurrent reviews can't both succeed.
+	var res sql.Result
+	if req.Approve {
+		res, err = s.db.Exec(`UPDATE registry_script_versions SET status='published', reviewer_id=$1, review_comment=$2,
+			published_at=NOW() WHERE script_id=$3 AND version=$4 AND status='in_review'`, reviewerID, req.Comment, v.ScriptID, v.Version)
+	} else {
+		res, err = s.db.Exec(`UPDATE registry_script_versions SET status='rejected', reviewer_id=$1, review_comment=$2
+			WHERE script_id=$3 AND version=$4 AND status='in_review'`, reviewerID, req.Comment, v.ScriptID, v.Version)
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to review script version")
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to review script version")
+	}
+	if n == 0 {
+		return nil, status.Error(codes.FailedPrecondition, "only versions in review can be reviewed, version was already reviewed")
+	}
+	return &scriptmgrpb.ReviewRegistryScriptVersionResp{}, nil
+}
+
+// DeleteRegistryScript deletes a script and all of its versions.
+func (s *Server) DeleteRegistryScript(ctx context.Context, req *scriptmgrpb.DeleteRegistryScriptReq) (*scriptmgrpb.DeleteRegistryScriptResp, error) {
+	orgID, err := orgIDFromProto(req.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.db.Exec(`DELETE FROM registry_scripts WHERE org_id=$1 AND id=$2`, orgID, utils.UUIDFromProtoOrNil(req.ScriptID))
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to delete script")
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to delete script")
+	}
+	if n == 0 {
+		return nil, status.Error(codes.NotFound, "script not found")
+	}
+	return &scriptmgrpb.DeleteRegistryScriptResp{}, nil
+}
+
+// GetRegistryBundle returns the latest published version of every script in the org.
+func (s *Server) GetRegistryBundle(ctx context.Context, req *scriptmgrpb.GetRegistryBundleReq) (*scriptmgrpb.GetRegistryBundleResp, error) {
+	orgID, err := orgIDFromProto(req.OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT DISTINCT ON (s.id) s.name, v.version, v.short_doc, v.long_doc, v.pxl, v.vis
+		FROM registry_scripts s JOIN registry_script_versions v ON v.script_id = s.id
+		WHERE s.org_id=$1 AND v.status='published' ORDER BY s.id, v.version DESC`
+	rows, err := s.db.Queryx(query, orgID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to fetch bundle")
+	}
+	defer rows.Close()
+
+	resp := &scriptmgrpb.GetRegistryBundleResp{}
+	for rows.Next() {
+		var name, pxl string
+		var version int64
+		var shortDoc, longDoc, vis sql.NullString
+		if err := rows.Scan(&name, &version, &shortDoc, &longDoc, &pxl, &vis); err != nil {
+			return nil, status.Error(codes.Internal, "failed to read bundle")
+		}
+		resp.Scripts = append(resp.Scripts, &scriptmgrpb.RegistryBundleScript{
+			Name:     name,
+			Version:  version,
+			ShortDoc: shortDoc.String,
+			LongDoc:  longDoc.String,
+			Pxl:      pxl,
+			Vis:      vis.String,
+		})
+	}
+	return resp, nil
+}
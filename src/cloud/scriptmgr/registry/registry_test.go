@@ -0,0 +1,299 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package registry_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	bindata "github.com/golang-migrate/migrate/source/go_bindata"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/cloud/scriptmgr/registry"
+	"px.dev/pixie/src/cloud/scriptmgr/schema"
+	"px.dev/pixie/src/cloud/scriptmgr/scriptmgrpb"
+	"px.dev/pixie/src/shared/services/pgtest"
+	"px.dev/pixie/src/utils"
+)
+
+var db *sqlx.DB
+
+var (
+	orgID      = uuid.FromStringOrNil("223e4567-e89b-12d3-a456-426655440000")
+	otherOrgID = uuid.FromStringOrNil("323e4567-e89b-12d3-a456-426655440000")
+	authorID   = uuid.FromStringOrNil("423e4567-e89b-12d3-a456-426655440000")
+	reviewerID = uuid.FromStringOrNil("523e4567-e89b-12d3-a456-426655440000")
+)
+
+func TestMain(m *testing.M) {
+	err := testMain(m)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Got error: %v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func testMain(m *testing.M) error {
+	s := bindata.Resource(schema.AssetNames(), schema.Asset)
+	testDB, teardown, err := pgtest.SetupTestDB(s)
+	if err != nil {
+		return fmt.Errorf("failed to start test database: %w", err)
+	}
+
+	defer teardown()
+	db = testDB
+
+	if c := m.Run(); c != 0 {
+		return fmt.Errorf("some tests failed with code: %d", c)
+	}
+	return nil
+}
+
+func mustClearTables(db *sqlx.DB) {
+	db.MustExec(`DELETE FROM registry_script_versions`)
+	db.MustExec(`DELETE FROM registry_scripts`)
+}
+
+func createVersion(t *testing.T, s *registry.Server, org uuid.UUID, name string, pxl string) *scriptmgrpb.CreateRegistryScriptVersionResp {
+	resp, err := s.CreateRegistryScriptVersion(context.Background(), &scriptmgrpb.CreateRegistryScriptVersionReq{
+		OrgID:    utils.ProtoFromUUID(org),
+		AuthorID: utils.ProtoFromUUID(authorID),
+		Name:     name,
+		ShortDoc: "short doc",
+		LongDoc:  "long doc",
+		Pxl:      pxl,
+	})
+	require.NoError(t, err)
+	return resp
+}
+
+func TestServer_CreateRegistryScriptVersion(t *testing.T) {
+	mustClearTables(db)
+	s := registry.NewServer(db)
+
+	v1 := createVersion(t, s, orgID, "http/errors", "px.display(1)")
+	assert.Equal(t, int64(1), v1.Version)
+	v2 := createVersion(t, s, orgID, "http/errors", "px.display(2)")
+	assert.Equal(t, int64(2), v2.Version)
+	assert.Equal(t, v1.ScriptID, v2.ScriptID)
+
+	// The same name in another org is a different script.
+	other := createVersion(t, s, otherOrgID, "http/errors", "px.display(3)")
+	assert.Equal(t, int64(1), other.Version)
+	assert.NotEqual(t, v1.ScriptID, other.ScriptID)
+
+	resp, err := s.GetRegistryScript(context.Background(), &scriptmgrpb.GetRegistryScriptReq{
+		OrgID: utils.ProtoFromUUID(orgID),
+		Name:  "http/errors",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), resp.Script.LatestVersion)
+	assert.Equal(t, int64(0), resp.Script.PublishedVersion)
+	require.Len(t, resp.Versions, 2)
+	assert.Equal(t, int64(2), resp.Versions[0].Version)
+	assert.Equal(t, scriptmgrpb.RSV_DRAFT, resp.Versions[0].Status)
+	assert.Equal(t, utils.ProtoFromUUID(authorID), resp.Versions[0].AuthorID)
+}
+
+func TestServer_CreateRegistryScriptVersion_Invalid(t *testing.T) {
+	mustClearTables(db)
+	s := registry.NewServer(db)
+
+	tests := []struct {
+		name string
+		req  *scriptmgrpb.CreateRegistryScriptVersionReq
+	}{
+		{
+			name: "missing name",
+			req:  &scriptmgrpb.CreateRegistryScriptVersionReq{Pxl: "px.display(1)"},
+		},
+		{
+			name: "reserved prefix",
+			req:  &scriptmgrpb.CreateRegistryScriptVersionReq{Name: "px/http_data", Pxl: "px.display(1)"},
+		},
+		{
+			name: "missing pxl",
+			req:  &scriptmgrpb.CreateRegistryScriptVersionReq{Name: "http/errors"},
+		},
+		{
+			name: "invalid vis",
+			req:  &scriptmgrpb.CreateRegistryScriptVersionReq{Name: "http/errors", Pxl: "px.display(1)", Vis: "{not json"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.req.OrgID = utils.ProtoFromUUID(orgID)
+			_, err := s.CreateRegistryScriptVersion(context.Background(), test.req)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+}
+
+func TestServer_ReviewWorkflow(t *testing.T) {
+	mustClearTables(db)
+	s := registry.NewServer(db)
+	ctx := context.Background()
+
+	v := createVersion(t, s, orgID, "http/errors", "px.display(1)")
+	versionReq := &scriptmgrpb.GetRegistryScriptVersionReq{
+		OrgID:    utils.ProtoFromUUID(orgID),
+		ScriptID: v.ScriptID,
+		Version:  v.Version,
+	}
+
+	// Drafts can't be reviewed.
+	_, err := s.ReviewRegistryScriptVersion(ctx, &scriptmgrpb.ReviewRegistryScriptVersionReq{
+		OrgID:      utils.ProtoFromUUID(orgID),
+		ScriptID:   v.ScriptID,
+		Version:    v.Version,
+		ReviewerID: utils.ProtoFromUUID(reviewerID),
+		Approve:    true,
+	})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = s.SubmitRegistryScriptVersion(ctx, &scriptmgrpb.SubmitRegistryScriptVersionReq{
+		OrgID:    utils.ProtoFromUUID(orgID),
+		ScriptID: v.ScriptID,
+		Version:  v.Version,
+	})
+	require.NoError(t, err)
+
+	// Authors can't review their own versions.
+	_, err = s.ReviewRegistryScriptVersion(ctx, &scriptmgrpb.ReviewRegistryScriptVersionReq{
+		OrgID:      utils.ProtoFromUUID(orgID),
+		ScriptID:   v.ScriptID,
+		Version:    v.Version,
+		ReviewerID: utils.ProtoFromUUID(authorID),
+		Approve:    true,
+	})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = s.ReviewRegistryScriptVersion(ctx, &scriptmgrpb.ReviewRegistryScriptVersionReq{
+		OrgID:      utils.ProtoFromUUID(orgID),
+		ScriptID:   v.ScriptID,
+		Version:    v.Version,
+		ReviewerID: utils.ProtoFromUUID(reviewerID),
+		Comment:    "needs a vis spec",
+	})
+	require.NoError(t, err)
+
+	resp, err := s.GetRegistryScriptVersion(ctx, versionReq)
+	require.NoError(t, err)
+	assert.Equal(t, scriptmgrpb.RSV_REJECTED, resp.Metadata.Status)
+	assert.Equal(t, "needs a vis spec", resp.Metadata.ReviewComment)
+
+	// Rejected versions can be resubmitted.
+	_, err = s.SubmitRegistryScriptVersion(ctx, &scriptmgrpb.SubmitRegistryScriptVersionReq{
+		OrgID:    utils.ProtoFromUUID(orgID),
+		ScriptID: v.ScriptID,
+		Version:  v.Version,
+	})
+	require.NoError(t, err)
+	_, err = s.ReviewRegistryScriptVersion(ctx, &scriptmgrpb.ReviewRegistryScriptVersionReq{
+		OrgID:      utils.ProtoFromUUID(orgID),
+		ScriptID:   v.ScriptID,
+		Version:    v.Version,
+		ReviewerID: utils.ProtoFromUUID(reviewerID),
+		Approve:    true,
+	})
+	require.NoError(t, err)
+
+	resp, err = s.GetRegistryScriptVersion(ctx, versionReq)
+	require.NoError(t, err)
+	assert.Equal(t, scriptmgrpb.RSV_PUBLISHED, resp.Metadata.Status)
+	assert.NotNil(t, resp.Metadata.PublishedAt)
+	assert.Equal(t, "px.display(1)", resp.Pxl)
+
+	// Published versions can't be resubmitted.
+	_, err = s.SubmitRegistryScriptVersion(ctx, &scriptmgrpb.SubmitRegistryScriptVersionReq{
+		OrgID:    utils.ProtoFromUUID(orgID),
+		ScriptID: v.ScriptID,
+		Version:  v.Version,
+	})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestServer_GetRegistryBundle(t *testing.T) {
+	mustClearTables(db)
+	s := registry.NewServer(db)
+	ctx := context.Background()
+
+	publish := func(v *scriptmgrpb.CreateRegistryScriptVersionResp) {
+		db.MustExec(`UPDATE registry_script_versions SET status='published', published_at=NOW() WHERE script_id=$1 AND version=$2`,
+			utils.UUIDFromProtoOrNil(v.ScriptID), v.Version)
+	}
+	publish(createVersion(t, s, orgID, "http/errors", "px.display(1)"))
+	createVersion(t, s, orgID, "http/errors", "px.display(2)")
+	createVersion(t, s, orgID, "dns/latency", "px.display(3)")
+	publish(createVersion(t, s, otherOrgID, "net/flows", "px.display(4)"))
+
+	resp, err := s.GetRegistryBundle(ctx, &scriptmgrpb.GetRegistryBundleReq{OrgID: utils.ProtoFromUUID(orgID)})
+	require.NoError(t, err)
+	require.Len(t, resp.Scripts, 1)
+	assert.Equal(t, "http/errors", resp.Scripts[0].Name)
+	assert.Equal(t, int64(1), resp.Scripts[0].Version)
+	assert.Equal(t, "px.display(1)", resp.Scripts[0].Pxl)
+
+	// Version 0 resolves to the latest published version.
+	getResp, err := s.GetRegistryScript(ctx, &scriptmgrpb.GetRegistryScriptReq{OrgID: utils.ProtoFromUUID(orgID), Name: "http/errors"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), getResp.Script.PublishedVersion)
+	vResp, err := s.GetRegistryScriptVersion(ctx, &scriptmgrpb.GetRegistryScriptVersionReq{
+		OrgID:    utils.ProtoFromUUID(orgID),
+		ScriptID: getResp.Script.ID,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), vResp.Metadata.Version)
+}
+
+func TestServer_DeleteRegistryScript(t *testing.T) {
+	mustClearTables(db)
+	s := registry.NewServer(db)
+	ctx := context.Background()
+
+	v := createVersion(t, s, orgID, "http/errors", "px.display(1)")
+
+	// Scripts can't be deleted from another org.
+	_, err := s.DeleteRegistryScript(ctx, &scriptmgrpb.DeleteRegistryScriptReq{
+		OrgID:    utils.ProtoFromUUID(otherOrgID),
+		ScriptID: v.ScriptID,
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = s.DeleteRegistryScript(ctx, &scriptmgrpb.DeleteRegistryScriptReq{
+		OrgID:    utils.ProtoFromUUID(orgID),
+		ScriptID: v.ScriptID,
+	})
+	require.NoError(t, err)
+
+	_, err = s.GetRegistryScript(ctx, &scriptmgrpb.GetRegistryScriptReq{
+		OrgID:    utils.ProtoFromUUID(orgID),
+		ScriptID: v.ScriptID,
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS registry_script_versions;
+DROP TYPE IF EXISTS registry_script_version_status;
+DROP TABLE IF EXISTS registry_scripts;
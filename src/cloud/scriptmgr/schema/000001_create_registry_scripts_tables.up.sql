@@ -0,0 +1,46 @@
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+CREATE TABLE registry_scripts (
+  -- id is the ID of the script.
+  id UUID UNIQUE DEFAULT uuid_generate_v4(),
+  -- org_id is the org who owns the script.
+  org_id UUID NOT NULL,
+  -- name is the name of the script within the org, for example "http/errors".
+  name varchar(1024) NOT NULL,
+  -- created_at is when the first version of the script was created.
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY (id),
+  UNIQUE (org_id, name)
+);
+
+CREATE TYPE registry_script_version_status AS ENUM ('draft', 'in_review', 'published', 'rejected');
+
+CREATE TABLE registry_script_versions (
+  -- script_id is the script that this is a version of.
+  script_id UUID NOT NULL REFERENCES registry_scripts(id) ON DELETE CASCADE,
+  -- version is the version number. Versions of a script are numbered from 1.
+  version integer NOT NULL,
+  -- short_doc is a short description of the script.
+  short_doc varchar(1024),
+  -- long_doc is a longer description of the script.
+  long_doc varchar(65536),
+  -- pxl is the PxL script.
+  pxl varchar NOT NULL,
+  -- vis is the JSON vis spec of the script. May be empty for scripts without a live view.
+  vis varchar,
+  -- status is the review state of the version.
+  status registry_script_version_status NOT NULL DEFAULT 'draft',
+  -- author_id is the user who created the version.
+  author_id UUID NOT NULL,
+  -- reviewer_id is the user who approved or rejected the version.
+  reviewer_id UUID,
+  -- review_comment is the comment left by the reviewer.
+  review_comment varchar(65536),
+  -- created_at is when the version was created.
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  -- published_at is when the version was approved.
+  published_at TIMESTAMP,
+
+  PRIMARY KEY (script_id, version)
+);
@@ -0,0 +1,30 @@
+CREATE TABLE saved_queries (
+  -- id is the ID of the saved query.
+  id UUID UNIQUE DEFAULT uuid_generate_v4(),
+  -- org_id is the org that the query was saved in.
+  org_id UUID NOT NULL,
+  -- user_id is the user who saved the query.
+  user_id UUID NOT NULL,
+  -- name is the name that the user gave the query.
+  name varchar(1024) NOT NULL,
+  -- cluster_id is the cluster that the script was executed on.
+  cluster_id UUID,
+  -- script_name is the name of the script, if it was a named script.
+  script_name varchar(1024),
+  -- pxl is the PxL script that was executed.
+  pxl varchar NOT NULL,
+  -- args is the JSON list of the arguments that the script was executed with.
+  args jsonb NOT NULL DEFAULT '[]',
+  -- result is the JSON snapshot of the results of the execution.
+  result jsonb NOT NULL,
+  -- share_token is the token that the query can be viewed with outside of its org, if it was shared.
+  share_token varchar(64) UNIQUE,
+  -- executed_at is when the script was executed.
+  executed_at TIMESTAMP NOT NULL,
+  -- created_at is when the query was saved.
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX saved_queries_org_id_created_at_idx ON saved_queries (org_id, created_at DESC);
@@ -23,28 +23,33 @@ import (
 	"net/http"
 	_ "net/http/pprof"
 
-	"cloud.google.com/go/storage"
-	"github.com/googleapis/google-cloud-go-testing/storage/stiface"
+	bindata "github.com/golang-migrate/migrate/source/go_bindata"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
-	"google.golang.org/api/option"
 
 	"px.dev/pixie/src/cloud/scriptmgr/controllers"
+	"px.dev/pixie/src/cloud/scriptmgr/queryhistory"
+	"px.dev/pixie/src/cloud/scriptmgr/registry"
+	"px.dev/pixie/src/cloud/scriptmgr/schema"
 	"px.dev/pixie/src/cloud/scriptmgr/scriptmgrpb"
+	"px.dev/pixie/src/cloud/shared/objstore"
+	"px.dev/pixie/src/cloud/shared/pgmigrate"
 	"px.dev/pixie/src/shared/services"
 	"px.dev/pixie/src/shared/services/env"
 	"px.dev/pixie/src/shared/services/healthz"
+	"px.dev/pixie/src/shared/services/pg"
 	"px.dev/pixie/src/shared/services/server"
 )
 
 func init() {
-	pflag.String("bundle_bucket", "pixie-prod-artifacts", "GCS Bucket containing the bundle of scripts.")
+	pflag.String("bundle_bucket", "pixie-prod-artifacts", "Bucket containing the bundle of scripts.")
 	pflag.String("bundle_path", "script-bundles/bundle.json", "Path to bundle within bucket.")
 }
 
 func main() {
 	services.SetupService("scriptmgr-service", 52000)
+	objstore.SetupFlags()
 	services.PostFlagSetupAndParse()
 	services.CheckServiceFlags()
 	services.SetupServiceLogging()
@@ -56,19 +61,25 @@ func main() {
 
 	s := server.NewPLServer(env.New(viper.GetString("domain_name")), mux)
 
-	client, err := storage.NewClient(context.Background(), option.WithoutAuthentication())
+	bundleStore, err := objstore.New(context.Background(), objstore.ConfigFromFlags(viper.GetString("bundle_bucket"), ""))
 	if err != nil {
-		log.WithError(err).Fatal("Failed to initialize GCS client.")
+		log.WithError(err).Fatal("Failed to initialize script bundle storage.")
 	}
 
-	svr := controllers.NewServer(
-		viper.GetString("bundle_bucket"),
-		viper.GetString("bundle_path"),
-		stiface.AdaptClient(client))
+	svr := controllers.NewServer(bundleStore, viper.GetString("bundle_path"))
 	svr.Start()
 
 	scriptmgrpb.RegisterScriptMgrServiceServer(s.GRPCServer(), svr)
 
+	db := pg.MustConnectDefaultPostgresDB()
+	err = pgmigrate.PerformMigrationsUsingBindata(db, "scriptmgr_service_migrations",
+		bindata.Resource(schema.AssetNames(), schema.Asset))
+	if err != nil {
+		log.WithError(err).Fatal("Failed to apply migrations")
+	}
+	scriptmgrpb.RegisterScriptRegistryServiceServer(s.GRPCServer(), registry.NewServer(db))
+	scriptmgrpb.RegisterQueryHistoryServiceServer(s.GRPCServer(), queryhistory.NewServer(db))
+
 	s.Start()
 	s.StopOnInterrupt()
 }
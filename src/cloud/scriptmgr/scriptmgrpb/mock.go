@@ -18,4 +18,4 @@
 
 package scriptmgrpb
 
-//go:generate mockgen -source=service.pb.go -destination=mock/scriptmgrpb_mock.gen.go ScriptMgrServiceClient
+//go:generate mockgen -source=service.pb.go -destination=mock/scriptmgrpb_mock.gen.go ScriptMgrServiceClient ScriptRegistryServiceClient QueryHistoryServiceClient
@@ -0,0 +1,177 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package auditlog records the administrative actions taken in an org, so that they can be
+// queried later and exported to external systems for compliance.
+package auditlog
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"px.dev/pixie/src/shared/services/authcontext"
+	"px.dev/pixie/src/shared/services/utils"
+)
+
+// Action is the type of administrative action that an Event records.
+type Action string
+
+const (
+	// ActionUserInvited is recorded when a user is invited to the org.
+	ActionUserInvited Action = "user.invite"
+	// ActionUserRemoved is recorded when a user is removed from the org.
+	ActionUserRemoved Action = "user.remove"
+	// ActionUserApprovalUpdated is recorded when a user is approved or unapproved.
+	ActionUserApprovalUpdated Action = "user.update_approval"
+	// ActionInviteTokenCreated is recorded when an invite link is created.
+	ActionInviteTokenCreated Action = "invite_token.create"
+	// ActionInviteTokensRevoked is recorded when all invite links are revoked.
+	ActionInviteTokensRevoked Action = "invite_token.revoke_all"
+	// ActionOrgUpdated is recorded when the org settings change.
+	ActionOrgUpdated Action = "org.update"
+	// ActionAPIKeyCreated is recorded when an API key is created.
+	ActionAPIKeyCreated Action = "api_key.create"
+	// ActionAPIKeyDeleted is recorded when an API key is deleted.
+	ActionAPIKeyDeleted Action = "api_key.delete"
+	// ActionDeployKeyCreated is recorded when a deploy key is created.
+	ActionDeployKeyCreated Action = "deploy_key.create"
+	// ActionDeployKeyDeleted is recorded when a deploy key is deleted.
+	ActionDeployKeyDeleted Action = "deploy_key.delete"
+	// ActionDeployKeyUsed is recorded when a deploy key is looked up to deploy a cluster.
+	ActionDeployKeyUsed Action = "deploy_key.use"
+	// ActionClusterRegistered is recorded when a cluster is registered with the org.
+	ActionClusterRegistered Action = "cluster.register"
+	// ActionClusterUpdated is recorded when a cluster is updated to a new version of Vizier.
+	ActionClusterUpdated Action = "cluster.update"
+	// ActionRetentionPluginUpdated is recorded when a retention plugin is enabled, disabled or configured.
+	ActionRetentionPluginUpdated Action = "retention_plugin.update"
+	// ActionScriptDeployed is recorded when a retention script is created or updated.
+	ActionScriptDeployed Action = "script.deploy"
+	// ActionScriptDeleted is recorded when a retention script is deleted.
+	ActionScriptDeleted Action = "script.delete"
+	// ActionAlertRouteCreated is recorded when an alert route is created.
+	ActionAlertRouteCreated Action = "alert_route.create"
+	// ActionAlertRouteUpdated is recorded when an alert route is updated.
+	ActionAlertRouteUpdated Action = "alert_route.update"
+	// ActionAlertRouteDeleted is recorded when an alert route is deleted.
+	ActionAlertRouteDeleted Action = "alert_route.delete"
+	// ActionFleetCreated is recorded when a fleet is created.
+	ActionFleetCreated Action = "fleet.create"
+	// ActionFleetDeleted is recorded when a fleet is deleted.
+	ActionFleetDeleted Action = "fleet.delete"
+	// ActionClusterLabelsUpdated is recorded when the labels on a cluster change.
+	ActionClusterLabelsUpdated Action = "cluster.update_labels"
+	// ActionRegistryScriptVersionCreated is recorded when a new version of a registry script is created.
+	ActionRegistryScriptVersionCreated Action = "registry_script.create_version"
+	// ActionRegistryScriptVersionSubmitted is recorded when a version of a registry script is submitted for review.
+	ActionRegistryScriptVersionSubmitted Action = "registry_script.submit_version"
+	// ActionRegistryScriptVersionReviewed is recorded when a version of a registry script is approved or rejected.
+	ActionRegistryScriptVersionReviewed Action = "registry_script.review_version"
+	// ActionRegistryScriptDeleted is recorded when a registry script is deleted.
+	ActionRegistryScriptDeleted Action = "registry_script.delete"
+	// ActionQuotaSet is recorded when a usage quota is created or changed.
+	ActionQuotaSet Action = "quota.set"
+	// ActionQuotaDeleted is recorded when a usage quota is deleted.
+	ActionQuotaDeleted Action = "quota.delete"
+	// ActionSavedQueryCreated is recorded when a query execution is saved to the query history.
+	ActionSavedQueryCreated Action = "saved_query.create"
+	// ActionSavedQueryDeleted is recorded when a saved query is deleted.
+	ActionSavedQueryDeleted Action = "saved_query.delete"
+	// ActionSavedQueryShared is recorded when a sharing link is created for a saved query.
+	ActionSavedQueryShared Action = "saved_query.share"
+	// ActionSavedQueryUnshared is recorded when the sharing link of a saved query is revoked.
+	ActionSavedQueryUnshared Action = "saved_query.unshare"
+)
+
+// ActorType is the kind of principal that took an action.
+type ActorType string
+
+const (
+	// ActorTypeUser is a user that logged in.
+	ActorTypeUser ActorType = "user"
+	// ActorTypeAPIKey is a user that authenticated with an API key.
+	ActorTypeAPIKey ActorType = "api_key"
+	// ActorTypeService is a Pixie Cloud service.
+	ActorTypeService ActorType = "service"
+	// ActorTypeCluster is a Vizier cluster.
+	ActorTypeCluster ActorType = "cluster"
+)
+
+// Event is a single entry in the audit log.
+type Event struct {
+	ID        uuid.UUID `json:"id"`
+	Time      time.Time `json:"time"`
+	OrgID     uuid.UUID `json:"orgID"`
+	ActorID   string    `json:"actorID"`
+	ActorType ActorType `json:"actorType"`
+	// ActorEmail is only set for users that logged in.
+	ActorEmail string `json:"actorEmail,omitempty"`
+	Action     Action `json:"action"`
+	// ResourceID is the ID of the object that the action applied to, if any.
+	ResourceID string `json:"resourceID,omitempty"`
+	Succeeded  bool   `json:"succeeded"`
+	// Error is the reason that the action failed.
+	Error   string            `json:"error,omitempty"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// Recorder records audit events. Recording is best effort, and never fails the action.
+type Recorder interface {
+	Record(ctx context.Context, e *Event)
+}
+
+// NewEvent creates an event for an action taken by the caller in the context. The outcome of the
+// action is taken from err.
+func NewEvent(ctx context.Context, action Action, resourceID string, err error) *Event {
+	e := &Event{
+		ID:         uuid.Must(uuid.NewV4()),
+		Time:       time.Now(),
+		Action:     action,
+		ResourceID: resourceID,
+		Succeeded:  err == nil,
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+
+	sCtx, ctxErr := authcontext.FromContext(ctx)
+	if ctxErr != nil || sCtx.Claims == nil {
+		return e
+	}
+	claims := sCtx.Claims
+	switch utils.GetClaimsType(claims) {
+	case utils.UserClaimType:
+		uc := claims.GetUserClaims()
+		e.OrgID = uuid.FromStringOrNil(uc.OrgID)
+		e.ActorID = uc.UserID
+		e.ActorType = ActorTypeUser
+		e.ActorEmail = uc.Email
+		if uc.IsAPIUser {
+			e.ActorType = ActorTypeAPIKey
+		}
+	case utils.ServiceClaimType:
+		e.ActorID = claims.GetServiceClaims().ServiceID
+		e.ActorType = ActorTypeService
+	case utils.ClusterClaimType:
+		e.ActorID = claims.GetClusterClaims().ClusterID
+		e.ActorType = ActorTypeCluster
+	}
+	return e
+}
@@ -0,0 +1,129 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package auditlog_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/shared/auditlog"
+	"px.dev/pixie/src/shared/services/authcontext"
+	"px.dev/pixie/src/utils/testingutils"
+)
+
+type fakeStore struct {
+	mu     sync.Mutex
+	events []*auditlog.Event
+}
+
+func (s *fakeStore) Write(ctx context.Context, events []*auditlog.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, events...)
+	return nil
+}
+
+func (s *fakeStore) Query(ctx context.Context, q *auditlog.Query) ([]*auditlog.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.events, nil
+}
+
+func (s *fakeStore) numEvents() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.events)
+}
+
+type fakeExporter struct {
+	mu      sync.Mutex
+	batches [][]*auditlog.Event
+}
+
+func (e *fakeExporter) Export(ctx context.Context, events []*auditlog.Event) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.batches = append(e.batches, events)
+	return nil
+}
+
+func TestNewEvent(t *testing.T) {
+	sCtx := authcontext.New()
+	sCtx.Claims = testingutils.GenerateTestClaims(t)
+	ctx := authcontext.NewContext(context.Background(), sCtx)
+
+	e := auditlog.NewEvent(ctx, auditlog.ActionAPIKeyCreated, "key-id", nil)
+	assert.Equal(t, auditlog.ActionAPIKeyCreated, e.Action)
+	assert.Equal(t, "key-id", e.ResourceID)
+	assert.True(t, e.Succeeded)
+	assert.Equal(t, auditlog.ActorTypeUser, e.ActorType)
+	assert.Equal(t, sCtx.Claims.GetUserClaims().UserID, e.ActorID)
+	assert.Equal(t, sCtx.Claims.GetUserClaims().Email, e.ActorEmail)
+	assert.Equal(t, uuid.FromStringOrNil(sCtx.Claims.GetUserClaims().OrgID), e.OrgID)
+	assert.NotEqual(t, uuid.Nil, e.ID)
+
+	e = auditlog.NewEvent(ctx, auditlog.ActionAPIKeyDeleted, "", errors.New("not found"))
+	assert.False(t, e.Succeeded)
+	assert.Equal(t, "not found", e.Error)
+}
+
+func TestNewEvent_Service(t *testing.T) {
+	sCtx := authcontext.New()
+	sCtx.Claims = testingutils.GenerateTestServiceClaims(t, "vzmgr")
+	ctx := authcontext.NewContext(context.Background(), sCtx)
+
+	e := auditlog.NewEvent(ctx, auditlog.ActionClusterRegistered, "", nil)
+	assert.Equal(t, auditlog.ActorTypeService, e.ActorType)
+	assert.Equal(t, uuid.Nil, e.OrgID)
+}
+
+func TestLogger(t *testing.T) {
+	store := &fakeStore{}
+	exp := &fakeExporter{}
+	l := auditlog.NewLogger(store, exp).WithFlushInterval(10 * time.Millisecond)
+	l.Start()
+
+	ctx := context.Background()
+	l.Record(ctx, auditlog.NewEvent(ctx, auditlog.ActionUserInvited, "a", nil))
+	l.Record(ctx, auditlog.NewEvent(ctx, auditlog.ActionUserRemoved, "b", nil))
+
+	require.Eventually(t, func() bool {
+		return store.numEvents() == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	l.Record(ctx, auditlog.NewEvent(ctx, auditlog.ActionOrgUpdated, "c", nil))
+	// Stop flushes the events that are still queued.
+	l.Stop()
+	assert.Equal(t, 3, store.numEvents())
+
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+	numExported := 0
+	for _, b := range exp.batches {
+		numExported += len(b)
+	}
+	assert.Equal(t, 3, numExported)
+}
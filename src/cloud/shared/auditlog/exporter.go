@@ -0,0 +1,132 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package auditlog
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"px.dev/pixie/src/cloud/shared/objstore"
+)
+
+// Exporter sends audit events to an external system, such as a SIEM.
+type Exporter interface {
+	Export(ctx context.Context, events []*Event) error
+}
+
+// SignatureHeader is the header that holds the HMAC-SHA256 of the body of webhook requests, hex
+// encoded, so that receivers can check that the events came from Pixie Cloud.
+const SignatureHeader = "X-Pixie-Signature"
+
+// webhookPayload is the body of the requests sent by the WebhookExporter.
+type webhookPayload struct {
+	Events []*Event `json:"events"`
+}
+
+// WebhookExporter posts batches of events as JSON to a URL.
+type WebhookExporter struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+// NewWebhookExporter creates an exporter that posts to the URL. Requests are signed with the
+// secret if it is set.
+func NewWebhookExporter(url string, secret string) *WebhookExporter {
+	return &WebhookExporter{
+		url:    url,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Sign returns the signature of a webhook body.
+func Sign(secret []byte, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Export posts the events to the webhook.
+func (w *WebhookExporter) Export(ctx context.Context, events []*Event) error {
+	body, err := json.Marshal(&webhookPayload{Events: events})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(w.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(w.secret, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("audit webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// StoreExporter writes batches of events to object storage, as newline delimited JSON. Each batch
+// is written to its own object under <year>/<month>/<day>/ in the store.
+type StoreExporter struct {
+	store objstore.Store
+}
+
+// NewStoreExporter creates an exporter that writes to the store.
+func NewStoreExporter(store objstore.Store) *StoreExporter {
+	return &StoreExporter{store: store}
+}
+
+// Export writes the events to a new object in the store.
+func (e *StoreExporter) Export(ctx context.Context, events []*Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, ev := range events {
+		if err := enc.Encode(ev); err != nil {
+			return err
+		}
+	}
+	return e.store.Put(ctx, objectKey(time.Now()), buf.Bytes(), "application/x-ndjson")
+}
+
+func objectKey(now time.Time) string {
+	now = now.UTC()
+	name := fmt.Sprintf("%s-%s.jsonl", now.Format("20060102T150405Z"), uuid.Must(uuid.NewV4()))
+	return path.Join(now.Format("2006/01/02"), name)
+}
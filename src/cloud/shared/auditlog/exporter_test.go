@@ -0,0 +1,111 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package auditlog_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/shared/auditlog"
+	"px.dev/pixie/src/cloud/shared/objstore"
+)
+
+func TestWebhookExporter(t *testing.T) {
+	var body []byte
+	var signature string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		require.NoError(t, err)
+		signature = r.Header.Get(auditlog.SignatureHeader)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	e := auditlog.NewEvent(ctx, auditlog.ActionAPIKeyCreated, "key-id", nil)
+	exp := auditlog.NewWebhookExporter(srv.URL, "secret")
+	require.NoError(t, exp.Export(ctx, []*auditlog.Event{e}))
+
+	assert.Equal(t, auditlog.Sign([]byte("secret"), body), signature)
+	payload := struct {
+		Events []*auditlog.Event `json:"events"`
+	}{}
+	require.NoError(t, json.Unmarshal(body, &payload))
+	require.Len(t, payload.Events, 1)
+	assert.Equal(t, e.ID, payload.Events[0].ID)
+	assert.Equal(t, auditlog.ActionAPIKeyCreated, payload.Events[0].Action)
+}
+
+func TestWebhookExporter_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	exp := auditlog.NewWebhookExporter(srv.URL, "")
+	err := exp.Export(context.Background(), []*auditlog.Event{{Action: auditlog.ActionOrgUpdated}})
+	assert.Error(t, err)
+}
+
+// fakeObjectStore records the objects written to object storage.
+type fakeObjectStore struct {
+	objstore.Store
+	keys         []string
+	bodies       []string
+	contentTypes []string
+}
+
+func (f *fakeObjectStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	f.keys = append(f.keys, key)
+	f.bodies = append(f.bodies, string(data))
+	f.contentTypes = append(f.contentTypes, contentType)
+	return nil
+}
+
+func TestStoreExporter(t *testing.T) {
+	store := &fakeObjectStore{}
+	exp := auditlog.NewStoreExporter(store)
+
+	ctx := context.Background()
+	events := []*auditlog.Event{
+		auditlog.NewEvent(ctx, auditlog.ActionDeployKeyCreated, "a", nil),
+		auditlog.NewEvent(ctx, auditlog.ActionDeployKeyDeleted, "a", nil),
+	}
+	require.NoError(t, exp.Export(ctx, events))
+	// Empty batches don't create objects.
+	require.NoError(t, exp.Export(ctx, nil))
+
+	require.Len(t, store.keys, 1)
+	assert.Regexp(t, `^\d{4}/\d{2}/\d{2}/.+\.jsonl$`, store.keys[0])
+	assert.Equal(t, "application/x-ndjson", store.contentTypes[0])
+
+	lines := strings.Split(strings.TrimSpace(store.bodies[0]), "\n")
+	require.Len(t, lines, 2)
+	e := &auditlog.Event{}
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), e))
+	assert.Equal(t, auditlog.ActionDeployKeyDeleted, e.Action)
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package auditlog
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultFlushInterval = 5 * time.Second
+	defaultBatchSize     = 100
+	// Events are dropped, and logged, if the store falls this far behind.
+	maxPendingEvents = 10000
+)
+
+// Logger is a Recorder that writes events to a Store, and forwards them to Exporters, in batches
+// in the background.
+type Logger struct {
+	store     Store
+	exporters []Exporter
+
+	flushInterval time.Duration
+	batchSize     int
+
+	eventCh chan *Event
+	quitCh  chan struct{}
+	wg      sync.WaitGroup
+	once    sync.Once
+}
+
+// NewLogger creates a Logger. Start must be called before any events are written.
+func NewLogger(store Store, exporters ...Exporter) *Logger {
+	return &Logger{
+		store:         store,
+		exporters:     exporters,
+		flushInterval: defaultFlushInterval,
+		batchSize:     defaultBatchSize,
+		eventCh:       make(chan *Event, maxPendingEvents),
+		quitCh:        make(chan struct{}),
+	}
+}
+
+// WithFlushInterval sets how long events can wait before being written.
+func (l *Logger) WithFlushInterval(d time.Duration) *Logger {
+	l.flushInterval = d
+	return l
+}
+
+// Record queues the event to be written.
+func (l *Logger) Record(ctx context.Context, e *Event) {
+	select {
+	case l.eventCh <- e:
+	default:
+		log.WithField("action", e.Action).WithField("orgID", e.OrgID).Error("Audit log is full, dropping event")
+	}
+}
+
+// Start writes queued events in the background until Stop is called.
+func (l *Logger) Start() {
+	l.wg.Add(1)
+	go l.run()
+}
+
+// Stop writes any queued events and stops the logger.
+func (l *Logger) Stop() {
+	l.once.Do(func() {
+		close(l.quitCh)
+	})
+	l.wg.Wait()
+}
+
+func (l *Logger) run() {
+	defer l.wg.Done()
+	ticker := time.NewTicker(l.flushInterval)
+	defer ticker.Stop()
+
+	var batch []*Event
+	for {
+		select {
+		case <-l.quitCh:
+			// Drain whatever is left before exiting.
+			for len(l.eventCh) > 0 {
+				batch = append(batch, <-l.eventCh)
+			}
+			l.flush(batch)
+			return
+		case e := <-l.eventCh:
+			batch = append(batch, e)
+			if len(batch) >= l.batchSize {
+				l.flush(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			l.flush(batch)
+			batch = nil
+		}
+	}
+}
+
+func (l *Logger) flush(batch []*Event) {
+	if len(batch) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err := l.store.Write(ctx, batch); err != nil {
+		log.WithError(err).WithField("numEvents", len(batch)).Error("Failed to write audit events")
+	}
+	for _, exp := range l.exporters {
+		if err := exp.Export(ctx, batch); err != nil {
+			log.WithError(err).WithField("numEvents", len(batch)).Error("Failed to export audit events")
+		}
+	}
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package auditlog
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/nats-io/nats.go"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// NATSSubject is the subject that services without access to the audit log store publish events on.
+	NATSSubject = "auditlog.events"
+	natsQueue   = "auditlog"
+)
+
+// NATSRecorder publishes events over NATS, to be written by the service that owns the audit log.
+type NATSRecorder struct {
+	nc *nats.Conn
+}
+
+// NewNATSRecorder creates a NATSRecorder.
+func NewNATSRecorder(nc *nats.Conn) *NATSRecorder {
+	return &NATSRecorder{nc: nc}
+}
+
+// Record publishes the event.
+func (r *NATSRecorder) Record(ctx context.Context, e *Event) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		log.WithError(err).Error("Failed to marshal audit event")
+		return
+	}
+	if err := r.nc.Publish(NATSSubject, b); err != nil {
+		log.WithError(err).WithField("action", e.Action).Error("Failed to publish audit event")
+	}
+}
+
+// SubscribeNATS records the events published by NATSRecorders. Subscribers share a queue group, so
+// each event is only recorded once.
+func SubscribeNATS(nc *nats.Conn, r Recorder) (*nats.Subscription, error) {
+	return nc.QueueSubscribe(NATSSubject, natsQueue, func(msg *nats.Msg) {
+		e := &Event{}
+		if err := json.Unmarshal(msg.Data, e); err != nil {
+			log.WithError(err).Error("Failed to unmarshal audit event")
+			return
+		}
+		r.Record(context.Background(), e)
+	})
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package auditlog_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/shared/auditlog"
+	"px.dev/pixie/src/utils/testingutils"
+)
+
+type fakeRecorder struct {
+	mu     sync.Mutex
+	events []*auditlog.Event
+}
+
+func (r *fakeRecorder) Record(ctx context.Context, e *auditlog.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func TestNATSRecorder(t *testing.T) {
+	nc, cleanup := testingutils.MustStartTestNATS(t)
+	defer cleanup()
+
+	rec := &fakeRecorder{}
+	sub, err := auditlog.SubscribeNATS(nc, rec)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, sub.Unsubscribe())
+	}()
+
+	ctx := context.Background()
+	e := auditlog.NewEvent(ctx, auditlog.ActionClusterRegistered, "cluster-id", nil)
+	auditlog.NewNATSRecorder(nc).Record(ctx, e)
+
+	require.Eventually(t, func() bool {
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		return len(rec.events) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, e.ID, rec.events[0].ID)
+	assert.Equal(t, auditlog.ActionClusterRegistered, rec.events[0].Action)
+	assert.Equal(t, "cluster-id", rec.events[0].ResourceID)
+}
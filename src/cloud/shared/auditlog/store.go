@@ -0,0 +1,160 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package auditlog
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/olivere/elastic/v7"
+
+	"px.dev/pixie/src/cloud/shared/esutils"
+)
+
+// DefaultQueryLimit is the number of events returned by a query that doesn't set a limit.
+const DefaultQueryLimit = 100
+
+// MaxQueryLimit is the largest number of events that a single query can return.
+const MaxQueryLimit = 1000
+
+// Query filters the events in the audit log. Zero-valued fields don't filter.
+type Query struct {
+	OrgID   uuid.UUID
+	Since   time.Time
+	Until   time.Time
+	Action  Action
+	ActorID string
+	Limit   int
+}
+
+// Store persists audit events.
+type Store interface {
+	Write(ctx context.Context, events []*Event) error
+	// Query returns the events of an org that match the query, most recent first.
+	Query(ctx context.Context, q *Query) ([]*Event, error)
+}
+
+// indexMapping is the mapping of the audit log index in elastic.
+const indexMapping = `
+{
+  "settings": {
+    "number_of_shards": 1
+  },
+  "mappings": {
+    "properties": {
+      "id": {"type": "keyword"},
+      "time": {"type": "date"},
+      "orgID": {"type": "keyword"},
+      "actorID": {"type": "keyword"},
+      "actorType": {"type": "keyword"},
+      "actorEmail": {"type": "keyword"},
+      "action": {"type": "keyword"},
+      "resourceID": {"type": "keyword"},
+      "succeeded": {"type": "boolean"},
+      "error": {"type": "text"},
+      "details": {"type": "object", "dynamic": true}
+    }
+  }
+}
+`
+
+// ElasticStore stores audit events in elastic.
+type ElasticStore struct {
+	es        *elastic.Client
+	indexName string
+}
+
+// NewElasticStore creates the audit log index, or updates its mapping, and returns a store using it.
+// Old indices are deleted after the retention period, which is a duration in elastic's format, eg. "365d".
+func NewElasticStore(es *elastic.Client, indexName string, retention string) (*ElasticStore, error) {
+	err := esutils.NewManagedIndex(es, indexName).
+		IndexFromJSONString(indexMapping).
+		MaxIndexAge("30d").
+		TimeBeforeDelete(retention).
+		Migrate(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return &ElasticStore{es: es, indexName: indexName}, nil
+}
+
+// Write indexes the events.
+func (s *ElasticStore) Write(ctx context.Context, events []*Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	bulk := s.es.Bulk().Index(s.indexName)
+	for _, e := range events {
+		bulk.Add(elastic.NewBulkIndexRequest().Id(e.ID.String()).Doc(e))
+	}
+	_, err := bulk.Do(ctx)
+	return err
+}
+
+// Query searches for the events matching the query.
+func (s *ElasticStore) Query(ctx context.Context, q *Query) ([]*Event, error) {
+	bq := elastic.NewBoolQuery().Filter(elastic.NewTermQuery("orgID", q.OrgID.String()))
+	if !q.Since.IsZero() || !q.Until.IsZero() {
+		rq := elastic.NewRangeQuery("time")
+		if !q.Since.IsZero() {
+			rq = rq.Gte(q.Since)
+		}
+		if !q.Until.IsZero() {
+			rq = rq.Lt(q.Until)
+		}
+		bq = bq.Filter(rq)
+	}
+	if q.Action != "" {
+		bq = bq.Filter(elastic.NewTermQuery("action", string(q.Action)))
+	}
+	if q.ActorID != "" {
+		bq = bq.Filter(elastic.NewTermQuery("actorID", q.ActorID))
+	}
+
+	resp, err := s.es.Search(s.indexName).
+		Query(bq).
+		Sort("time", false).
+		Size(queryLimit(q.Limit)).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*Event, 0, len(resp.Hits.Hits))
+	for _, hit := range resp.Hits.Hits {
+		e := &Event{}
+		if err := json.Unmarshal(hit.Source, e); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+func queryLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultQueryLimit
+	}
+	if limit > MaxQueryLimit {
+		return MaxQueryLimit
+	}
+	return limit
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package metering
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultFlushInterval = 10 * time.Second
+	defaultBatchSize     = 500
+	// Usage is dropped, and logged, if the store falls this far behind.
+	maxPendingUsage = 10000
+)
+
+// Collector is a Meter that writes usage to a Store in batches in the background.
+type Collector struct {
+	store Store
+
+	flushInterval time.Duration
+	batchSize     int
+
+	usageCh chan *Usage
+	quitCh  chan struct{}
+	wg      sync.WaitGroup
+	once    sync.Once
+}
+
+// NewCollector creates a Collector. Start must be called before any usage is written.
+func NewCollector(store Store) *Collector {
+	return &Collector{
+		store:         store,
+		flushInterval: defaultFlushInterval,
+		batchSize:     defaultBatchSize,
+		usageCh:       make(chan *Usage, maxPendingUsage),
+		quitCh:        make(chan struct{}),
+	}
+}
+
+// WithFlushInterval sets how long usage can wait before being written.
+func (c *Collector) WithFlushInterval(d time.Duration) *Collector {
+	c.flushInterval = d
+	return c
+}
+
+// Record queues the usage to be written. Usage with no value isn't recorded.
+func (c *Collector) Record(ctx context.Context, u *Usage) {
+	if u.Value == 0 {
+		return
+	}
+	select {
+	case c.usageCh <- u:
+	default:
+		log.WithField("metric", u.Metric).WithField("orgID", u.OrgID).Error("Usage queue is full, dropping usage")
+	}
+}
+
+// Start writes queued usage in the background until Stop is called.
+func (c *Collector) Start() {
+	c.wg.Add(1)
+	go c.run()
+}
+
+// Stop writes any queued usage and stops the collector.
+func (c *Collector) Stop() {
+	c.once.Do(func() {
+		close(c.quitCh)
+	})
+	c.wg.Wait()
+}
+
+func (c *Collector) run() {
+	defer c.wg.Done()
+	ticker := time.NewTicker(c.flushInterval)
+	defer ticker.Stop()
+
+	var batch []*Usage
+	for {
+		select {
+		case <-c.quitCh:
+			// Drain whatever is left before exiting.
+			for len(c.usageCh) > 0 {
+				batch = append(batch, <-c.usageCh)
+			}
+			c.flush(batch)
+			return
+		case u := <-c.usageCh:
+			batch = append(batch, u)
+			if len(batch) >= c.batchSize {
+				c.flush(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			c.flush(batch)
+			batch = nil
+		}
+	}
+}
+
+func (c *Collector) flush(batch []*Usage) {
+	if len(batch) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err := c.store.Write(ctx, batch); err != nil {
+		log.WithError(err).WithField("numUsage", len(batch)).Error("Failed to write usage")
+	}
+}
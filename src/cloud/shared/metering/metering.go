@@ -0,0 +1,78 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package metering records how much each org uses Pixie Cloud, so that usage can be reported on,
+// charged back, and limited by quotas.
+package metering
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Metric is a kind of usage that is metered.
+type Metric string
+
+const (
+	// MetricQueryExecutions counts the scripts executed through Pixie Cloud.
+	MetricQueryExecutions Metric = "query_executions"
+	// MetricBytesScanned is the number of bytes that Viziers processed to execute scripts.
+	MetricBytesScanned Metric = "bytes_scanned"
+	// MetricBytesExported is the number of bytes that Viziers exported to object stores.
+	MetricBytesExported Metric = "bytes_exported"
+)
+
+// Metrics are all of the metered kinds of usage.
+var Metrics = []Metric{MetricQueryExecutions, MetricBytesScanned, MetricBytesExported}
+
+// Valid returns whether the metric is metered.
+func (m Metric) Valid() bool {
+	for _, metric := range Metrics {
+		if m == metric {
+			return true
+		}
+	}
+	return false
+}
+
+// Usage is an amount of a metric that was used by a cluster at a point in time.
+type Usage struct {
+	Time      time.Time `json:"time"`
+	OrgID     uuid.UUID `json:"orgID"`
+	ClusterID uuid.UUID `json:"clusterID"`
+	Metric    Metric    `json:"metric"`
+	Value     int64     `json:"value"`
+}
+
+// Meter records usage. Recording is best effort, and never fails the metered action.
+type Meter interface {
+	Record(ctx context.Context, u *Usage)
+}
+
+// NewUsage creates a usage record for the current time.
+func NewUsage(orgID, clusterID uuid.UUID, metric Metric, value int64) *Usage {
+	return &Usage{
+		Time:      time.Now(),
+		OrgID:     orgID,
+		ClusterID: clusterID,
+		Metric:    metric,
+		Value:     value,
+	}
+}
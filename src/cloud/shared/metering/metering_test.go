@@ -0,0 +1,167 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package metering_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/shared/metering"
+)
+
+type fakeQuotaStore struct {
+	quotas []*metering.Quota
+	err    error
+	calls  int
+}
+
+func (s *fakeQuotaStore) List(ctx context.Context, orgID uuid.UUID) ([]*metering.Quota, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	var quotas []*metering.Quota
+	for _, q := range s.quotas {
+		if q.OrgID == orgID {
+			quotas = append(quotas, q)
+		}
+	}
+	return quotas, nil
+}
+
+func (s *fakeQuotaStore) Set(ctx context.Context, q *metering.Quota) error {
+	s.quotas = append(s.quotas, q)
+	return nil
+}
+
+func (s *fakeQuotaStore) Delete(ctx context.Context, orgID uuid.UUID, metric metering.Metric) error {
+	return nil
+}
+
+type fakeStore struct {
+	mu     sync.Mutex
+	usage  []*metering.Usage
+	totals map[metering.Metric]int64
+	err    error
+	since  time.Time
+}
+
+func (s *fakeStore) Write(ctx context.Context, usage []*metering.Usage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.usage = append(s.usage, usage...)
+	return nil
+}
+
+func (s *fakeStore) Report(ctx context.Context, q *metering.ReportQuery) ([]*metering.ReportBucket, error) {
+	return nil, nil
+}
+
+func (s *fakeStore) Total(ctx context.Context, orgID uuid.UUID, metric metering.Metric, since time.Time) (int64, error) {
+	s.since = since
+	return s.totals[metric], s.err
+}
+
+func TestPeriod_Start(t *testing.T) {
+	ts := time.Date(2021, time.March, 14, 15, 9, 26, 0, time.UTC)
+	assert.Equal(t, time.Date(2021, time.March, 14, 0, 0, 0, 0, time.UTC), metering.PeriodDay.Start(ts))
+	assert.Equal(t, time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC), metering.PeriodMonth.Start(ts))
+}
+
+func TestEnforcer_Check(t *testing.T) {
+	orgID := uuid.Must(uuid.NewV4())
+	quotas := &fakeQuotaStore{quotas: []*metering.Quota{
+		{OrgID: orgID, Metric: metering.MetricBytesScanned, Limit: 100, Period: metering.PeriodMonth},
+		{OrgID: uuid.Must(uuid.NewV4()), Metric: metering.MetricQueryExecutions, Limit: 1, Period: metering.PeriodDay},
+	}}
+	usage := &fakeStore{totals: map[metering.Metric]int64{
+		metering.MetricBytesScanned:    100,
+		metering.MetricQueryExecutions: 10,
+	}}
+	e := metering.NewEnforcer(quotas, usage).WithCacheTTL(0)
+	ctx := context.Background()
+
+	err := e.Check(ctx, orgID, metering.MetricBytesScanned)
+	require.Error(t, err)
+	var qErr *metering.QuotaExceededError
+	require.True(t, errors.As(err, &qErr))
+	assert.Equal(t, int64(100), qErr.Used)
+	assert.Equal(t, metering.PeriodMonth.Start(time.Now()), usage.since)
+
+	// The quota on query executions belongs to another org.
+	assert.NoError(t, e.Check(ctx, orgID, metering.MetricQueryExecutions))
+
+	usage.totals[metering.MetricBytesScanned] = 99
+	assert.NoError(t, e.Check(ctx, orgID, metering.MetricBytesScanned))
+}
+
+func TestEnforcer_CheckCachesQuotas(t *testing.T) {
+	orgID := uuid.Must(uuid.NewV4())
+	quotas := &fakeQuotaStore{}
+	e := metering.NewEnforcer(quotas, &fakeStore{})
+	ctx := context.Background()
+
+	require.NoError(t, e.Check(ctx, orgID, metering.MetricBytesScanned))
+	require.NoError(t, e.Check(ctx, orgID, metering.MetricBytesScanned))
+	assert.Equal(t, 1, quotas.calls)
+
+	e.Invalidate(orgID)
+	require.NoError(t, e.Check(ctx, orgID, metering.MetricBytesScanned))
+	assert.Equal(t, 2, quotas.calls)
+}
+
+func TestEnforcer_CheckFailsOpen(t *testing.T) {
+	orgID := uuid.Must(uuid.NewV4())
+	ctx := context.Background()
+
+	e := metering.NewEnforcer(&fakeQuotaStore{err: errors.New("elastic is down")}, &fakeStore{})
+	assert.NoError(t, e.Check(ctx, orgID, metering.MetricBytesScanned))
+
+	quotas := &fakeQuotaStore{quotas: []*metering.Quota{
+		{OrgID: orgID, Metric: metering.MetricBytesScanned, Limit: 1, Period: metering.PeriodDay},
+	}}
+	e = metering.NewEnforcer(quotas, &fakeStore{err: errors.New("elastic is down")})
+	assert.NoError(t, e.Check(ctx, orgID, metering.MetricBytesScanned))
+}
+
+func TestCollector(t *testing.T) {
+	store := &fakeStore{}
+	c := metering.NewCollector(store).WithFlushInterval(time.Hour)
+	c.Start()
+
+	ctx := context.Background()
+	orgID := uuid.Must(uuid.NewV4())
+	clusterID := uuid.Must(uuid.NewV4())
+	c.Record(ctx, metering.NewUsage(orgID, clusterID, metering.MetricQueryExecutions, 1))
+	c.Record(ctx, metering.NewUsage(orgID, clusterID, metering.MetricBytesScanned, 0))
+	c.Record(ctx, metering.NewUsage(orgID, clusterID, metering.MetricBytesScanned, 2048))
+	c.Stop()
+
+	// Usage with no value is dropped.
+	require.Len(t, store.usage, 2)
+	assert.Equal(t, metering.MetricQueryExecutions, store.usage[0].Metric)
+	assert.Equal(t, int64(2048), store.usage[1].Value)
+}
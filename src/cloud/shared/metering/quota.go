@@ -0,0 +1,295 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package metering
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/olivere/elastic/v7"
+	log "github.com/sirupsen/logrus"
+
+	"px.dev/pixie/src/cloud/shared/esutils"
+)
+
+// Period is the window that a quota limits usage over.
+type Period string
+
+const (
+	// PeriodDay limits usage per UTC day.
+	PeriodDay Period = "day"
+	// PeriodMonth limits usage per UTC calendar month.
+	PeriodMonth Period = "month"
+)
+
+// Valid returns whether the period is supported.
+func (p Period) Valid() bool {
+	return p == PeriodDay || p == PeriodMonth
+}
+
+// Start returns the start of the period that t is in.
+func (p Period) Start(t time.Time) time.Time {
+	t = t.UTC()
+	if p == PeriodMonth {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Quota limits the usage of a metric by an org in each period. An org has at most one quota per metric.
+type Quota struct {
+	OrgID     uuid.UUID `json:"orgID"`
+	Metric    Metric    `json:"metric"`
+	Limit     int64     `json:"limit"`
+	Period    Period    `json:"period"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ErrQuotaNotFound is returned when deleting a quota that doesn't exist.
+var ErrQuotaNotFound = errors.New("quota not found")
+
+// QuotaExceededError is returned when an org has used up a quota.
+type QuotaExceededError struct {
+	Quota *Quota
+	Used  int64
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%s quota exceeded: used %d of %d this %s", e.Quota.Metric, e.Used, e.Quota.Limit, e.Quota.Period)
+}
+
+// QuotaStore persists quotas.
+type QuotaStore interface {
+	List(ctx context.Context, orgID uuid.UUID) ([]*Quota, error)
+	Set(ctx context.Context, q *Quota) error
+	Delete(ctx context.Context, orgID uuid.UUID, metric Metric) error
+}
+
+// QuotaChecker is the hook that metered actions call before they run.
+type QuotaChecker interface {
+	// Check returns a QuotaExceededError if the org can't use any more of the metric.
+	Check(ctx context.Context, orgID uuid.UUID, metric Metric) error
+}
+
+// quotaIndexMapping is the mapping of the quota index in elastic.
+const quotaIndexMapping = `
+{
+  "settings": {
+    "number_of_shards": 1
+  },
+  "mappings": {
+    "properties": {
+      "orgID": {"type": "keyword"},
+      "metric": {"type": "keyword"},
+      "limit": {"type": "long"},
+      "period": {"type": "keyword"},
+      "updatedAt": {"type": "date"}
+    }
+  }
+}
+`
+
+// ElasticQuotaStore stores quotas in elastic.
+type ElasticQuotaStore struct {
+	es        *elastic.Client
+	indexName string
+}
+
+// NewElasticQuotaStore creates the quota index, or updates its mapping, and returns a store using it.
+func NewElasticQuotaStore(es *elastic.Client, indexName string) (*ElasticQuotaStore, error) {
+	err := esutils.NewIndex(es).
+		Name(indexName).
+		FromJSONString(quotaIndexMapping).
+		Migrate(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return &ElasticQuotaStore{es: es, indexName: indexName}, nil
+}
+
+func quotaDocID(orgID uuid.UUID, metric Metric) string {
+	return fmt.Sprintf("%s:%s", orgID, metric)
+}
+
+// List returns the quotas of an org.
+func (s *ElasticQuotaStore) List(ctx context.Context, orgID uuid.UUID) ([]*Quota, error) {
+	resp, err := s.es.Search(s.indexName).
+		Query(elastic.NewTermQuery("orgID", orgID.String())).
+		Size(len(Metrics)).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	quotas := make([]*Quota, 0, len(resp.Hits.Hits))
+	for _, hit := range resp.Hits.Hits {
+		q := &Quota{}
+		if err := json.Unmarshal(hit.Source, q); err != nil {
+			return nil, err
+		}
+		quotas = append(quotas, q)
+	}
+	return quotas, nil
+}
+
+// Set creates or replaces the quota for the metric in the org.
+func (s *ElasticQuotaStore) Set(ctx context.Context, q *Quota) error {
+	_, err := s.es.Index().
+		Index(s.indexName).
+		Id(quotaDocID(q.OrgID, q.Metric)).
+		BodyJson(q).
+		Refresh("true").
+		Do(ctx)
+	return err
+}
+
+// Delete removes the quota for the metric in the org.
+func (s *ElasticQuotaStore) Delete(ctx context.Context, orgID uuid.UUID, metric Metric) error {
+	_, err := s.es.Delete().
+		Index(s.indexName).
+		Id(quotaDocID(orgID, metric)).
+		Refresh("true").
+		Do(ctx)
+	if elastic.IsNotFound(err) {
+		return ErrQuotaNotFound
+	}
+	return err
+}
+
+// defaultCacheTTL is how long the enforcer uses quotas and usage totals before fetching them again.
+// Quotas are enforced approximately: an org can go over a quota by the usage in one TTL.
+const defaultCacheTTL = time.Minute
+
+type cachedQuotas struct {
+	quotas    []*Quota
+	fetchedAt time.Time
+}
+
+type totalKey struct {
+	orgID  uuid.UUID
+	metric Metric
+}
+
+type cachedTotal struct {
+	since     time.Time
+	total     int64
+	fetchedAt time.Time
+}
+
+// Enforcer is a QuotaChecker that compares the usage in a Store to the quotas in a QuotaStore.
+type Enforcer struct {
+	quotas QuotaStore
+	usage  Store
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu     sync.Mutex
+	byOrg  map[uuid.UUID]*cachedQuotas
+	totals map[totalKey]*cachedTotal
+}
+
+// NewEnforcer creates an Enforcer.
+func NewEnforcer(quotas QuotaStore, usage Store) *Enforcer {
+	return &Enforcer{
+		quotas: quotas,
+		usage:  usage,
+		ttl:    defaultCacheTTL,
+		now:    time.Now,
+		byOrg:  make(map[uuid.UUID]*cachedQuotas),
+		totals: make(map[totalKey]*cachedTotal),
+	}
+}
+
+// WithCacheTTL sets how long quotas and usage totals are cached for.
+func (e *Enforcer) WithCacheTTL(d time.Duration) *Enforcer {
+	e.ttl = d
+	return e
+}
+
+// Invalidate drops the cached quotas of an org, so that changes to them take effect immediately.
+func (e *Enforcer) Invalidate(orgID uuid.UUID) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.byOrg, orgID)
+}
+
+// Check returns a QuotaExceededError if the org has used up its quota for the metric. Failures to
+// read quotas or usage are logged and allow the action, so that metering outages don't take down queries.
+func (e *Enforcer) Check(ctx context.Context, orgID uuid.UUID, metric Metric) error {
+	quotas, err := e.orgQuotas(ctx, orgID)
+	if err != nil {
+		log.WithError(err).WithField("orgID", orgID).Error("Failed to fetch quotas")
+		return nil
+	}
+	for _, q := range quotas {
+		if q.Metric != metric {
+			continue
+		}
+		used, err := e.total(ctx, orgID, metric, q.Period.Start(e.now()))
+		if err != nil {
+			log.WithError(err).WithField("orgID", orgID).WithField("metric", metric).Error("Failed to fetch usage")
+			return nil
+		}
+		if used >= q.Limit {
+			return &QuotaExceededError{Quota: q, Used: used}
+		}
+	}
+	return nil
+}
+
+func (e *Enforcer) orgQuotas(ctx context.Context, orgID uuid.UUID) ([]*Quota, error) {
+	e.mu.Lock()
+	c, ok := e.byOrg[orgID]
+	e.mu.Unlock()
+	if ok && e.now().Sub(c.fetchedAt) < e.ttl {
+		return c.quotas, nil
+	}
+
+	quotas, err := e.quotas.List(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	e.byOrg[orgID] = &cachedQuotas{quotas: quotas, fetchedAt: e.now()}
+	e.mu.Unlock()
+	return quotas, nil
+}
+
+func (e *Enforcer) total(ctx context.Context, orgID uuid.UUID, metric Metric, since time.Time) (int64, error) {
+	key := totalKey{orgID: orgID, metric: metric}
+	e.mu.Lock()
+	c, ok := e.totals[key]
+	e.mu.Unlock()
+	if ok && c.since.Equal(since) && e.now().Sub(c.fetchedAt) < e.ttl {
+		return c.total, nil
+	}
+
+	total, err := e.usage.Total(ctx, orgID, metric, since)
+	if err != nil {
+		return 0, err
+	}
+	e.mu.Lock()
+	e.totals[key] = &cachedTotal{since: since, total: total, fetchedAt: e.now()}
+	e.mu.Unlock()
+	return total, nil
+}
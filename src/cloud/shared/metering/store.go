@@ -0,0 +1,238 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package metering
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/olivere/elastic/v7"
+
+	"px.dev/pixie/src/cloud/shared/esutils"
+)
+
+// Interval is the width of the buckets in a usage report.
+type Interval string
+
+const (
+	// IntervalHour reports usage per hour.
+	IntervalHour Interval = "hour"
+	// IntervalDay reports usage per day.
+	IntervalDay Interval = "day"
+	// IntervalMonth reports usage per calendar month.
+	IntervalMonth Interval = "month"
+)
+
+// calendarInterval is the elastic calendar interval of the buckets.
+func (i Interval) calendarInterval() (string, error) {
+	switch i {
+	case IntervalHour:
+		return "1h", nil
+	case IntervalDay, "":
+		return "1d", nil
+	case IntervalMonth:
+		return "1M", nil
+	}
+	return "", fmt.Errorf("unknown interval %q", i)
+}
+
+// ReportQuery selects the usage of an org to report on. Zero-valued fields don't filter.
+type ReportQuery struct {
+	OrgID     uuid.UUID
+	ClusterID uuid.UUID
+	Since     time.Time
+	Until     time.Time
+	// Interval defaults to IntervalDay.
+	Interval Interval
+}
+
+// ReportBucket is the total usage of a metric by a cluster in one interval of a report.
+type ReportBucket struct {
+	Start     time.Time
+	ClusterID uuid.UUID
+	Metric    Metric
+	Value     int64
+}
+
+// Store persists usage.
+type Store interface {
+	Write(ctx context.Context, usage []*Usage) error
+	// Report returns the usage matching the query, in order of time.
+	Report(ctx context.Context, q *ReportQuery) ([]*ReportBucket, error)
+	// Total returns the total usage of a metric by an org since the given time.
+	Total(ctx context.Context, orgID uuid.UUID, metric Metric, since time.Time) (int64, error)
+}
+
+// indexMapping is the mapping of the usage index in elastic.
+const indexMapping = `
+{
+  "settings": {
+    "number_of_shards": 1
+  },
+  "mappings": {
+    "properties": {
+      "time": {"type": "date"},
+      "orgID": {"type": "keyword"},
+      "clusterID": {"type": "keyword"},
+      "metric": {"type": "keyword"},
+      "value": {"type": "long"}
+    }
+  }
+}
+`
+
+// maxReportClusters is the largest number of clusters that are broken out in one interval of a report.
+const maxReportClusters = 1000
+
+// ElasticStore stores usage in elastic.
+type ElasticStore struct {
+	es        *elastic.Client
+	indexName string
+}
+
+// NewElasticStore creates the usage index, or updates its mapping, and returns a store using it.
+// Old indices are deleted after the retention period, which is a duration in elastic's format, eg. "400d".
+func NewElasticStore(es *elastic.Client, indexName string, retention string) (*ElasticStore, error) {
+	err := esutils.NewManagedIndex(es, indexName).
+		IndexFromJSONString(indexMapping).
+		MaxIndexAge("30d").
+		TimeBeforeDelete(retention).
+		Migrate(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return &ElasticStore{es: es, indexName: indexName}, nil
+}
+
+// Write indexes the usage.
+func (s *ElasticStore) Write(ctx context.Context, usage []*Usage) error {
+	if len(usage) == 0 {
+		return nil
+	}
+	bulk := s.es.Bulk().Index(s.indexName)
+	for _, u := range usage {
+		bulk.Add(elastic.NewBulkIndexRequest().Doc(u))
+	}
+	_, err := bulk.Do(ctx)
+	return err
+}
+
+func timeRangeQuery(since time.Time, until time.Time) *elastic.RangeQuery {
+	rq := elastic.NewRangeQuery("time")
+	if !since.IsZero() {
+		rq = rq.Gte(since)
+	}
+	if !until.IsZero() {
+		rq = rq.Lt(until)
+	}
+	return rq
+}
+
+// Report aggregates the usage matching the query into buckets.
+func (s *ElasticStore) Report(ctx context.Context, q *ReportQuery) ([]*ReportBucket, error) {
+	interval, err := q.Interval.calendarInterval()
+	if err != nil {
+		return nil, err
+	}
+
+	bq := elastic.NewBoolQuery().
+		Filter(elastic.NewTermQuery("orgID", q.OrgID.String())).
+		Filter(timeRangeQuery(q.Since, q.Until))
+	if q.ClusterID != uuid.Nil {
+		bq = bq.Filter(elastic.NewTermQuery("clusterID", q.ClusterID.String()))
+	}
+
+	agg := elastic.NewDateHistogramAggregation().
+		Field("time").
+		CalendarInterval(interval).
+		SubAggregation("by_cluster", elastic.NewTermsAggregation().
+			Field("clusterID").
+			Size(maxReportClusters).
+			SubAggregation("by_metric", elastic.NewTermsAggregation().
+				Field("metric").
+				Size(len(Metrics)).
+				SubAggregation("value", elastic.NewSumAggregation().Field("value"))))
+
+	resp, err := s.es.Search(s.indexName).
+		Query(bq).
+		Size(0).
+		Aggregation("by_time", agg).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byTime, ok := resp.Aggregations.DateHistogram("by_time")
+	if !ok {
+		return nil, errors.New("usage report is missing aggregations")
+	}
+	var buckets []*ReportBucket
+	for _, tb := range byTime.Buckets {
+		start := time.Unix(0, int64(tb.Key)*int64(time.Millisecond)).UTC()
+		byCluster, ok := tb.Terms("by_cluster")
+		if !ok {
+			continue
+		}
+		for _, cb := range byCluster.Buckets {
+			clusterID := uuid.FromStringOrNil(fmt.Sprint(cb.Key))
+			byMetric, ok := cb.Terms("by_metric")
+			if !ok {
+				continue
+			}
+			for _, mb := range byMetric.Buckets {
+				buckets = append(buckets, &ReportBucket{
+					Start:     start,
+					ClusterID: clusterID,
+					Metric:    Metric(fmt.Sprint(mb.Key)),
+					Value:     sumValue(mb.Aggregations),
+				})
+			}
+		}
+	}
+	return buckets, nil
+}
+
+// Total sums the usage of a metric by an org since the given time.
+func (s *ElasticStore) Total(ctx context.Context, orgID uuid.UUID, metric Metric, since time.Time) (int64, error) {
+	bq := elastic.NewBoolQuery().
+		Filter(elastic.NewTermQuery("orgID", orgID.String())).
+		Filter(elastic.NewTermQuery("metric", string(metric))).
+		Filter(timeRangeQuery(since, time.Time{}))
+
+	resp, err := s.es.Search(s.indexName).
+		Query(bq).
+		Size(0).
+		Aggregation("value", elastic.NewSumAggregation().Field("value")).
+		Do(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return sumValue(resp.Aggregations), nil
+}
+
+func sumValue(aggs elastic.Aggregations) int64 {
+	sum, ok := aggs.Sum("value")
+	if !ok || sum.Value == nil {
+		return 0
+	}
+	return int64(*sum.Value)
+}
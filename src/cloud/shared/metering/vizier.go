@@ -0,0 +1,158 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package metering
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/proto"
+	"github.com/gogo/protobuf/types"
+	"github.com/nats-io/nats.go"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/cloud/shared/vzshard"
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
+	"px.dev/pixie/src/shared/cvmsgs"
+	"px.dev/pixie/src/shared/cvmsgspb"
+	jwtutils "px.dev/pixie/src/shared/services/utils"
+	"px.dev/pixie/src/utils"
+)
+
+const vizierUsageQueue = "metering"
+
+type vzmgrClient interface {
+	GetOrgFromVizier(ctx context.Context, in *uuidpb.UUID, opts ...grpc.CallOption) (*vzmgrpb.GetOrgFromVizierResponse, error)
+}
+
+// VizierUsageSubscriber meters the usage reports that Viziers send after running cron scripts.
+type VizierUsageSubscriber struct {
+	meter Meter
+	vzmgr vzmgrClient
+	subs  []*nats.Subscription
+
+	mu sync.Mutex
+	// orgIDs caches the org of each Vizier, which doesn't change.
+	orgIDs map[uuid.UUID]uuid.UUID
+}
+
+// SubscribeVizierUsage starts metering the usage reported by Viziers. Subscribers share a queue
+// group, so each report is only metered once.
+func SubscribeVizierUsage(nc *nats.Conn, vzmgr vzmgrClient, meter Meter) (*VizierUsageSubscriber, error) {
+	s := &VizierUsageSubscriber{
+		meter:  meter,
+		vzmgr:  vzmgr,
+		orgIDs: make(map[uuid.UUID]uuid.UUID),
+	}
+	for _, shard := range vzshard.GenerateShardRange() {
+		sub, err := nc.QueueSubscribe(fmt.Sprintf("v2c.%s.*.%s", shard, cvmsgs.UsageReportChannel), vizierUsageQueue, s.handleMsg)
+		if err != nil {
+			s.Unsubscribe()
+			return nil, err
+		}
+		s.subs = append(s.subs, sub)
+	}
+	return s, nil
+}
+
+// Unsubscribe stops metering Vizier usage reports.
+func (s *VizierUsageSubscriber) Unsubscribe() {
+	for _, sub := range s.subs {
+		if err := sub.Unsubscribe(); err != nil {
+			log.WithError(err).Error("Failed to unsubscribe from usage reports")
+		}
+	}
+	s.subs = nil
+}
+
+func (s *VizierUsageSubscriber) handleMsg(msg *nats.Msg) {
+	v2c := &cvmsgspb.V2CMessage{}
+	if err := proto.Unmarshal(msg.Data, v2c); err != nil {
+		log.WithError(err).Error("Could not unmarshal message")
+		return
+	}
+	s.HandleUsageReport(v2c)
+}
+
+func (s *VizierUsageSubscriber) orgForVizier(vizierID uuid.UUID) (uuid.UUID, error) {
+	s.mu.Lock()
+	orgID, ok := s.orgIDs[vizierID]
+	s.mu.Unlock()
+	if ok {
+		return orgID, nil
+	}
+
+	claims := jwtutils.GenerateJWTForService("vzmgr Service", viper.GetString("domain_name"))
+	token, err := jwtutils.SignJWTClaims(claims, viper.GetString("jwt_signing_key"))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization",
+		fmt.Sprintf("bearer %s", token))
+	resp, err := s.vzmgr.GetOrgFromVizier(ctx, utils.ProtoFromUUID(vizierID))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	orgID = utils.UUIDFromProtoOrNil(resp.OrgID)
+
+	s.mu.Lock()
+	s.orgIDs[vizierID] = orgID
+	s.mu.Unlock()
+	return orgID, nil
+}
+
+// HandleUsageReport meters a usage report sent by a Vizier.
+func (s *VizierUsageSubscriber) HandleUsageReport(msg *cvmsgspb.V2CMessage) {
+	report := &cvmsgspb.UsageReport{}
+	if err := types.UnmarshalAny(msg.Msg, report); err != nil {
+		log.WithError(err).Error("Could not unmarshal usage report")
+		return
+	}
+
+	vizierID := uuid.FromStringOrNil(msg.VizierID)
+	orgID, err := s.orgForVizier(vizierID)
+	if err != nil {
+		log.WithError(err).WithField("vizierID", vizierID).Error("Could not find org for Vizier")
+		return
+	}
+
+	ts := time.Now()
+	if report.Timestamp != nil {
+		if t, err := types.TimestampFromProto(report.Timestamp); err == nil {
+			ts = t
+		}
+	}
+	ctx := context.Background()
+	for metric, value := range map[Metric]int64{
+		MetricQueryExecutions: 1,
+		MetricBytesScanned:    report.BytesProcessed,
+		MetricBytesExported:   report.BytesExported,
+	} {
+		u := NewUsage(orgID, vizierID, metric, value)
+		u.Time = ts
+		s.meter.Record(ctx, u)
+	}
+}
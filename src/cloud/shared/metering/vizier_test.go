@@ -0,0 +1,119 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package metering_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/proto"
+	"github.com/gogo/protobuf/types"
+	"github.com/golang/mock/gomock"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/shared/metering"
+	"px.dev/pixie/src/cloud/shared/vzshard"
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
+	mock_vzmgrpb "px.dev/pixie/src/cloud/vzmgr/vzmgrpb/mock"
+	"px.dev/pixie/src/shared/cvmsgs"
+	"px.dev/pixie/src/shared/cvmsgspb"
+	"px.dev/pixie/src/utils"
+	"px.dev/pixie/src/utils/testingutils"
+)
+
+type fakeMeter struct {
+	mu    sync.Mutex
+	usage map[metering.Metric]*metering.Usage
+}
+
+func (m *fakeMeter) Record(ctx context.Context, u *metering.Usage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.usage[u.Metric] = u
+}
+
+func TestSubscribeVizierUsage(t *testing.T) {
+	viper.Set("jwt_signing_key", "jwtkey")
+	viper.Set("vizier_shard_max", 255)
+	nc, cleanup := testingutils.MustStartTestNATS(t)
+	defer cleanup()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	orgID := uuid.Must(uuid.NewV4())
+	vizierID := uuid.Must(uuid.NewV4())
+	vzmgr := mock_vzmgrpb.NewMockVZMgrServiceClient(ctrl)
+	// The org is only looked up once per Vizier.
+	vzmgr.EXPECT().
+		GetOrgFromVizier(gomock.Any(), utils.ProtoFromUUID(vizierID)).
+		Return(&vzmgrpb.GetOrgFromVizierResponse{OrgID: utils.ProtoFromUUID(orgID)}, nil)
+
+	meter := &fakeMeter{usage: make(map[metering.Metric]*metering.Usage)}
+	sub, err := metering.SubscribeVizierUsage(nc, vzmgr, meter)
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+	require.NoError(t, nc.Flush())
+
+	ts := time.Date(2021, time.March, 14, 15, 9, 26, 0, time.UTC)
+	tsPb, err := types.TimestampProto(ts)
+	require.NoError(t, err)
+	publish := func(bytesProcessed int64) {
+		anyMsg, err := types.MarshalAny(&cvmsgspb.UsageReport{
+			ScriptID:       utils.ProtoFromUUID(uuid.Must(uuid.NewV4())),
+			BytesProcessed: bytesProcessed,
+			BytesExported:  512,
+			Timestamp:      tsPb,
+		})
+		require.NoError(t, err)
+		b, err := proto.Marshal(&cvmsgspb.V2CMessage{VizierID: vizierID.String(), Msg: anyMsg})
+		require.NoError(t, err)
+		require.NoError(t, nc.Publish(vzshard.V2CTopic(cvmsgs.UsageReportChannel, vizierID), b))
+	}
+
+	publish(1024)
+	require.Eventually(t, func() bool {
+		meter.mu.Lock()
+		defer meter.mu.Unlock()
+		return len(meter.usage) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	meter.mu.Lock()
+	assert.Equal(t, int64(1), meter.usage[metering.MetricQueryExecutions].Value)
+	assert.Equal(t, int64(1024), meter.usage[metering.MetricBytesScanned].Value)
+	assert.Equal(t, int64(512), meter.usage[metering.MetricBytesExported].Value)
+	for _, u := range meter.usage {
+		assert.Equal(t, orgID, u.OrgID)
+		assert.Equal(t, vizierID, u.ClusterID)
+		assert.True(t, ts.Equal(u.Time))
+	}
+	meter.mu.Unlock()
+
+	publish(2048)
+	require.Eventually(t, func() bool {
+		meter.mu.Lock()
+		defer meter.mu.Unlock()
+		return meter.usage[metering.MetricBytesScanned].Value == 2048
+	}, 5*time.Second, 10*time.Millisecond)
+}
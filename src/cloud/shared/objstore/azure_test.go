@@ -0,0 +1,135 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package objstore_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/shared/objstore"
+)
+
+type fakeBlob struct {
+	data        []byte
+	contentType string
+}
+
+// fakeBlobService serves the blobs of a container, and records the SAS permissions of each request.
+type fakeBlobService struct {
+	mu          sync.Mutex
+	blobs       map[string]*fakeBlob
+	permissions []string
+}
+
+func (f *fakeBlobService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	q := r.URL.Query()
+	if q.Get("sig") == "" || q.Get("sv") == "" || q.Get("se") == "" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	f.permissions = append(f.permissions, q.Get("sp"))
+
+	switch r.Method {
+	case http.MethodPut:
+		if r.Header.Get("x-ms-blob-type") != "BlockBlob" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		f.blobs[r.URL.Path] = &fakeBlob{data: data, contentType: r.Header.Get("Content-Type")}
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodGet, http.MethodHead:
+		b, ok := f.blobs[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", b.contentType)
+		w.Header().Set("Last-Modified", time.Unix(1700000000, 0).UTC().Format(http.TimeFormat))
+		_, _ = w.Write(b.data)
+	}
+}
+
+func TestAzureStore(t *testing.T) {
+	fake := &fakeBlobService{blobs: map[string]*fakeBlob{}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	key := base64.StdEncoding.EncodeToString([]byte("the-account-key"))
+	s, err := objstore.NewAzureStore(srv.Client(), srv.URL, "account", key, "container", "scripts")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, s.Put(ctx, "bundle.json", []byte("{}"), "application/json"))
+	require.Contains(t, fake.blobs, "/container/scripts/bundle.json")
+
+	r, err := s.Get(ctx, "bundle.json")
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	r.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "{}", string(data))
+
+	attrs, err := s.Attrs(ctx, "bundle.json")
+	require.NoError(t, err)
+	assert.Equal(t, &objstore.Attrs{
+		Size:        2,
+		ContentType: "application/json",
+		Updated:     time.Unix(1700000000, 0).UTC(),
+	}, attrs)
+
+	_, err = s.Get(ctx, "missing.json")
+	assert.True(t, errors.Is(err, objstore.ErrNotFound))
+	_, err = s.Attrs(ctx, "missing.json")
+	assert.True(t, errors.Is(err, objstore.ErrNotFound))
+
+	// Writes need the create and write permissions, everything else is read only.
+	assert.Equal(t, []string{"cw", "r", "r", "r", "r"}, fake.permissions)
+
+	u, err := s.DownloadURL(ctx, "bundle.json", time.Hour)
+	require.NoError(t, err)
+	parsed, err := url.Parse(u)
+	require.NoError(t, err)
+	assert.Equal(t, "/container/scripts/bundle.json", parsed.Path)
+	assert.Equal(t, "r", parsed.Query().Get("sp"))
+	expiry, err := time.Parse(time.RFC3339, parsed.Query().Get("se"))
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expiry, time.Minute)
+	assert.True(t, strings.HasPrefix(u, srv.URL))
+}
+
+func TestNewAzureStore_InvalidKey(t *testing.T) {
+	_, err := objstore.NewAzureStore(http.DefaultClient, "https://account.blob.core.windows.net", "account", "not base64!", "container", "")
+	require.Error(t, err)
+}
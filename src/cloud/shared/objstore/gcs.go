@@ -0,0 +1,98 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package objstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/googleapis/google-cloud-go-testing/storage/stiface"
+)
+
+// GCSStore stores objects in a Google Cloud Storage bucket.
+type GCSStore struct {
+	client stiface.Client
+	bucket string
+	prefix string
+}
+
+// NewGCSStore creates a store for the bucket that uses the given GCS client.
+func NewGCSStore(client stiface.Client, bucket string, prefix string) *GCSStore {
+	return &GCSStore{client: client, bucket: bucket, prefix: prefix}
+}
+
+func (s *GCSStore) object(key string) stiface.ObjectHandle {
+	return s.client.Bucket(s.bucket).Object(path.Join(s.prefix, key))
+}
+
+// Get opens an object in the bucket.
+func (s *GCSStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	r, err := s.object(key).NewReader(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Put writes an object to the bucket, replacing any existing object.
+func (s *GCSStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	w := s.object(key).NewWriter(ctx)
+	w.ObjectAttrs().ContentType = contentType
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// Attrs returns the attributes of an object in the bucket.
+func (s *GCSStore) Attrs(ctx context.Context, key string) (*Attrs, error) {
+	attrs, err := s.object(key).Attrs(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Attrs{
+		Size:        attrs.Size,
+		ContentType: attrs.ContentType,
+		Updated:     attrs.Updated,
+	}, nil
+}
+
+// DownloadURL returns the media link of the object. The artifact buckets are public, so the link does not
+// expire.
+func (s *GCSStore) DownloadURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	attrs, err := s.object(key).Attrs(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return attrs.MediaLink, nil
+}
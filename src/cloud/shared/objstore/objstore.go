@@ -0,0 +1,172 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package objstore is the object storage of the cloud services, such as the artifacts, the script bundles and the
+// exported audit events. The backend is selected by the storage flags, so that self-hosted clouds are not tied to
+// GCS.
+package objstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/googleapis/google-cloud-go-testing/storage/stiface"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"google.golang.org/api/option"
+)
+
+// ErrNotFound is returned when an object doesn't exist.
+var ErrNotFound = errors.New("object not found")
+
+// The supported storage providers.
+const (
+	ProviderGCS   = "gcs"
+	ProviderS3    = "s3"
+	ProviderMinIO = "minio"
+	ProviderAzure = "azure"
+)
+
+// Attrs are the attributes of an object.
+type Attrs struct {
+	Size        int64
+	ContentType string
+	Updated     time.Time
+}
+
+// Store is a bucket in object storage. Keys are relative to the prefix of the store.
+type Store interface {
+	// Get opens the object at the key, or returns ErrNotFound.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Put writes the object at the key, replacing any existing object.
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Attrs returns the attributes of the object at the key, or ErrNotFound.
+	Attrs(ctx context.Context, key string) (*Attrs, error)
+	// DownloadURL returns a URL that the object can be downloaded from, without credentials, for at least the
+	// given duration.
+	DownloadURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+}
+
+// Config selects and configures the backend of a store.
+type Config struct {
+	// Provider is one of gcs, s3, minio or azure.
+	Provider string
+	// Bucket is the bucket, or the container for Azure Blob Storage.
+	Bucket string
+	// Prefix is prepended to all the keys.
+	Prefix string
+	// Endpoint overrides the endpoint of the provider. It is required for MinIO.
+	Endpoint string
+	// Region is the region of S3 buckets.
+	Region string
+	// AccessKeyID and SecretAccessKey are the static credentials for S3 and MinIO. The default AWS credential
+	// chain is used when they are not set.
+	AccessKeyID     string
+	SecretAccessKey string
+	// CredentialsFile is the service account key file for GCS. Public buckets are accessed anonymously when it
+	// is not set.
+	CredentialsFile string
+	// AzureAccount and AzureAccountKey are the storage account for Azure Blob Storage and its shared key.
+	AzureAccount    string
+	AzureAccountKey string
+}
+
+// SetupFlags adds the flags that select the object storage of a service.
+func SetupFlags() {
+	pflag.String("storage_provider", ProviderGCS, "The object storage provider: gcs, s3, minio or azure")
+	pflag.String("storage_endpoint", "", "The endpoint of the object storage, required for MinIO")
+	pflag.String("storage_region", "us-west-2", "The region of the S3 buckets")
+	pflag.String("storage_access_key_id", "", "The access key ID for S3 or MinIO, if not using the default AWS credentials")
+	pflag.String("storage_secret_access_key", "", "The secret access key for S3 or MinIO")
+	pflag.String("storage_azure_account", "", "The Azure storage account")
+	pflag.String("storage_azure_account_key", "", "The shared key of the Azure storage account")
+}
+
+// ConfigFromFlags returns the config of a store for the bucket, with the backend selected by the storage flags.
+func ConfigFromFlags(bucket string, prefix string) *Config {
+	return &Config{
+		Provider:        viper.GetString("storage_provider"),
+		Bucket:          bucket,
+		Prefix:          prefix,
+		Endpoint:        viper.GetString("storage_endpoint"),
+		Region:          viper.GetString("storage_region"),
+		AccessKeyID:     viper.GetString("storage_access_key_id"),
+		SecretAccessKey: viper.GetString("storage_secret_access_key"),
+		AzureAccount:    viper.GetString("storage_azure_account"),
+		AzureAccountKey: viper.GetString("storage_azure_account_key"),
+	}
+}
+
+// New creates the store for the config.
+func New(ctx context.Context, cfg *Config) (Store, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("a bucket is required for object storage")
+	}
+
+	switch cfg.Provider {
+	case ProviderGCS:
+		opts := []option.ClientOption{option.WithoutAuthentication()}
+		if cfg.CredentialsFile != "" {
+			opts = []option.ClientOption{option.WithCredentialsFile(cfg.CredentialsFile)}
+		}
+		if cfg.Endpoint != "" {
+			opts = append(opts, option.WithEndpoint(cfg.Endpoint))
+		}
+		client, err := storage.NewClient(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return NewGCSStore(stiface.AdaptClient(client), cfg.Bucket, cfg.Prefix), nil
+	case ProviderS3, ProviderMinIO:
+		awsCfg := &aws.Config{Region: aws.String(cfg.Region)}
+		if cfg.Endpoint != "" {
+			awsCfg.Endpoint = aws.String(cfg.Endpoint)
+			awsCfg.S3ForcePathStyle = aws.Bool(true)
+		} else if cfg.Provider == ProviderMinIO {
+			return nil, errors.New("an endpoint is required for MinIO")
+		}
+		if cfg.AccessKeyID != "" {
+			awsCfg.Credentials = credentials.NewStaticCredentials(cfg.AccessKeyID, cfg.SecretAccessKey, "")
+		}
+		sess, err := session.NewSession(awsCfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewS3Store(s3.New(sess), cfg.Bucket, cfg.Prefix), nil
+	case ProviderAzure:
+		if cfg.AzureAccount == "" || cfg.AzureAccountKey == "" {
+			return nil, errors.New("a storage account and its key are required for Azure Blob Storage")
+		}
+		endpoint := cfg.Endpoint
+		if endpoint == "" {
+			endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", cfg.AzureAccount)
+		}
+		return NewAzureStore(&http.Client{Timeout: time.Minute}, endpoint, cfg.AzureAccount, cfg.AzureAccountKey, cfg.Bucket, cfg.Prefix)
+	default:
+		return nil, fmt.Errorf("unsupported storage provider '%s', expected gcs, s3, minio or azure", cfg.Provider)
+	}
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package objstore_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/shared/objstore"
+	"px.dev/pixie/src/utils/testingutils"
+)
+
+func TestNew_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *objstore.Config
+		err  string
+	}{
+		{
+			name: "missing bucket",
+			cfg:  &objstore.Config{Provider: objstore.ProviderS3},
+			err:  "a bucket is required",
+		},
+		{
+			name: "minio without endpoint",
+			cfg:  &objstore.Config{Provider: objstore.ProviderMinIO, Bucket: "b"},
+			err:  "an endpoint is required",
+		},
+		{
+			name: "azure without key",
+			cfg:  &objstore.Config{Provider: objstore.ProviderAzure, Bucket: "b", AzureAccount: "a"},
+			err:  "a storage account and its key are required",
+		},
+		{
+			name: "unknown provider",
+			cfg:  &objstore.Config{Provider: "ftp", Bucket: "b"},
+			err:  "unsupported storage provider 'ftp'",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := objstore.New(context.Background(), test.cfg)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.err)
+		})
+	}
+}
+
+func TestNew_MinIO(t *testing.T) {
+	s, err := objstore.New(context.Background(), &objstore.Config{
+		Provider:        objstore.ProviderMinIO,
+		Bucket:          "artifacts",
+		Endpoint:        "http://minio.plc.svc:9000",
+		Region:          "us-east-1",
+		AccessKeyID:     "id",
+		SecretAccessKey: "secret",
+	})
+	require.NoError(t, err)
+	require.IsType(t, &objstore.S3Store{}, s)
+
+	// MinIO buckets are addressed by path rather than by subdomain.
+	u, err := s.DownloadURL(context.Background(), "cli/cli_linux_amd64", time.Hour)
+	require.NoError(t, err)
+	assert.Contains(t, u, "http://minio.plc.svc:9000/artifacts/cli/cli_linux_amd64?")
+}
+
+func TestGCSStore(t *testing.T) {
+	updated := time.Unix(1700000000, 0)
+	client := testingutils.NewMockGCSClient(map[string]*testingutils.MockGCSBucket{
+		"bucket": testingutils.NewMockGCSBucket(map[string]*testingutils.MockGCSObject{
+			"prefix/bundle.json": testingutils.NewMockGCSObject([]byte("{}"), &storage.ObjectAttrs{
+				Size:      2,
+				Updated:   updated,
+				MediaLink: "the-url",
+			}),
+		}, nil),
+	})
+	s := objstore.NewGCSStore(client, "bucket", "prefix")
+	ctx := context.Background()
+
+	r, err := s.Get(ctx, "bundle.json")
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "{}", string(data))
+
+	attrs, err := s.Attrs(ctx, "bundle.json")
+	require.NoError(t, err)
+	assert.Equal(t, &objstore.Attrs{Size: 2, Updated: updated}, attrs)
+
+	u, err := s.DownloadURL(ctx, "bundle.json", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "the-url", u)
+
+	_, err = s.Get(ctx, "missing.json")
+	assert.Error(t, err)
+}
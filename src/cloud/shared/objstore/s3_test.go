@@ -0,0 +1,106 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package objstore_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/shared/objstore"
+)
+
+type fakeS3Object struct {
+	data        []byte
+	contentType string
+	updated     time.Time
+}
+
+type fakeS3 struct {
+	s3iface.S3API
+	objects map[string]*fakeS3Object
+}
+
+func (f *fakeS3) GetObjectWithContext(ctx aws.Context, in *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error) {
+	obj, ok := f.objects[*in.Bucket+"/"+*in.Key]
+	if !ok {
+		return nil, awserr.New(s3.ErrCodeNoSuchKey, "no such key", nil)
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader(string(obj.data)))}, nil
+}
+
+func (f *fakeS3) HeadObjectWithContext(ctx aws.Context, in *s3.HeadObjectInput, opts ...request.Option) (*s3.HeadObjectOutput, error) {
+	obj, ok := f.objects[*in.Bucket+"/"+*in.Key]
+	if !ok {
+		return nil, awserr.New("NotFound", "not found", nil)
+	}
+	return &s3.HeadObjectOutput{
+		ContentLength: aws.Int64(int64(len(obj.data))),
+		ContentType:   aws.String(obj.contentType),
+		LastModified:  aws.Time(obj.updated),
+	}, nil
+}
+
+func (f *fakeS3) PutObjectWithContext(ctx aws.Context, in *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error) {
+	b, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.objects[*in.Bucket+"/"+*in.Key] = &fakeS3Object{data: b, contentType: *in.ContentType, updated: time.Unix(1700000000, 0)}
+	return &s3.PutObjectOutput{}, nil
+}
+
+func TestS3Store(t *testing.T) {
+	client := &fakeS3{objects: map[string]*fakeS3Object{}}
+	s := objstore.NewS3Store(client, "bucket", "audit")
+	ctx := context.Background()
+
+	require.NoError(t, s.Put(ctx, "2023/events.jsonl", []byte("{}\n"), "application/x-ndjson"))
+	require.Contains(t, client.objects, "bucket/audit/2023/events.jsonl")
+
+	r, err := s.Get(ctx, "2023/events.jsonl")
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "{}\n", string(data))
+
+	attrs, err := s.Attrs(ctx, "2023/events.jsonl")
+	require.NoError(t, err)
+	assert.Equal(t, &objstore.Attrs{
+		Size:        3,
+		ContentType: "application/x-ndjson",
+		Updated:     time.Unix(1700000000, 0),
+	}, attrs)
+
+	_, err = s.Get(ctx, "missing")
+	assert.True(t, errors.Is(err, objstore.ErrNotFound))
+	_, err = s.Attrs(ctx, "missing")
+	assert.True(t, errors.Is(err, objstore.ErrNotFound))
+}
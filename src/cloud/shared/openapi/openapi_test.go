@@ -0,0 +1,116 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package openapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/shared/openapi"
+)
+
+type embedded struct {
+	Embedded string `json:"embedded"`
+}
+
+type node struct {
+	embedded
+	ID       uuid.UUID         `json:"id"`
+	Time     time.Time         `json:"time"`
+	Count    int64             `json:"count,omitempty"`
+	Ratio    float64           `json:"ratio"`
+	Labels   map[string]string `json:"labels"`
+	Children []*node           `json:"children"`
+	Data     []byte            `json:"data"`
+	Any      interface{}       `json:"any"`
+	Ignored  string            `json:"-"`
+	Untagged bool
+	hidden   string
+}
+
+func TestDocument_SchemaOf(t *testing.T) {
+	d := openapi.NewDocument(&openapi.Info{Title: "test", Version: "v1"})
+
+	ref := d.SchemaOf(&node{})
+	assert.Equal(t, "#/components/schemas/Node", ref.Ref)
+	// The same type is only generated once.
+	assert.Equal(t, ref, d.SchemaOf(node{}))
+
+	s := d.Components.Schemas["Node"]
+	require.NotNil(t, s)
+	assert.Equal(t, "object", s.Type)
+	assert.ElementsMatch(t, []string{"embedded", "id", "time", "count", "ratio", "labels", "children", "data", "any", "Untagged"}, keys(s.Properties))
+	assert.NotContains(t, s.Required, "count")
+	assert.Contains(t, s.Required, "id")
+
+	assert.Equal(t, &openapi.Schema{Type: "string"}, s.Properties["embedded"])
+	assert.Equal(t, &openapi.Schema{Type: "string", Format: "uuid"}, s.Properties["id"])
+	assert.Equal(t, &openapi.Schema{Type: "string", Format: "date-time"}, s.Properties["time"])
+	assert.Equal(t, &openapi.Schema{Type: "integer", Format: "int64"}, s.Properties["count"])
+	assert.Equal(t, &openapi.Schema{Type: "number", Format: "double"}, s.Properties["ratio"])
+	assert.Equal(t, &openapi.Schema{Type: "object", AdditionalProperties: &openapi.Schema{Type: "string"}}, s.Properties["labels"])
+	assert.Equal(t, &openapi.Schema{Type: "array", Items: ref}, s.Properties["children"])
+	assert.Equal(t, &openapi.Schema{Type: "string", Format: "byte"}, s.Properties["data"])
+	assert.Equal(t, &openapi.Schema{}, s.Properties["any"])
+	assert.Equal(t, &openapi.Schema{Type: "boolean"}, s.Properties["Untagged"])
+}
+
+func keys(m map[string]*openapi.Schema) []string {
+	var k []string
+	for name := range m {
+		k = append(k, name)
+	}
+	return k
+}
+
+func TestHandler(t *testing.T) {
+	d := openapi.NewDocument(&openapi.Info{Title: "test", Version: "v1"})
+	d.AddOperation(http.MethodGet, "/nodes", &openapi.Operation{
+		OperationID: "listNodes",
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "The nodes.", Content: openapi.JSONContent(d.SchemaOf(&node{}))},
+		},
+		Security: []openapi.SecurityRequirement{},
+	})
+
+	w := httptest.NewRecorder()
+	openapi.Handler(d).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	doc := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Equal(t, openapi.Version, doc["openapi"])
+	op := doc["paths"].(map[string]interface{})["/nodes"].(map[string]interface{})["get"].(map[string]interface{})
+	assert.Equal(t, "listNodes", op["operationId"])
+	// An empty list of security requirements is kept, since it makes the operation unauthenticated.
+	assert.Equal(t, []interface{}{}, op["security"])
+	assert.Contains(t, doc["components"].(map[string]interface{})["schemas"], "Node")
+
+	w = httptest.NewRecorder()
+	openapi.Handler(d).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/openapi.json", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -0,0 +1,236 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package orgkeys manages per-org data keys for encrypting org data at rest. Each org's data is encrypted with its
+// own randomly generated data key, which is stored wrapped by a key encryption key held in a KMS. A query that
+// decrypts one org's data with its key can't read the data of another org.
+package orgkeys
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/gofrs/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrRotationUnsupported is returned when rotating keys without a KeyWrapper.
+var ErrRotationUnsupported = errors.New("org data keys can't be rotated without a key wrapper")
+
+// dataKeySize is the size in bytes of the generated data keys.
+const dataKeySize = 32
+
+// EncryptedColumn is a column holding org data encrypted with PGP_SYM_ENCRYPT, using the org's data key.
+type EncryptedColumn struct {
+	Table  string
+	Column string
+	// OrgIDColumn is the column of the table that holds the ID of the org that owns the row.
+	OrgIDColumn string
+}
+
+// Config configures a Manager.
+type Config struct {
+	// Wrapper wraps the data keys. If nil, orgs don't get their own keys, and all org data is encrypted with
+	// LegacyKey.
+	Wrapper KeyWrapper
+	// LegacyKey is the key that org data was encrypted with before the org got its own key.
+	LegacyKey string
+	// KeysTable is the table that stores the wrapped data keys. It must have the org_id, version, wrapped_key,
+	// kek_id and rotated_at columns.
+	KeysTable string
+	// Columns are all of the columns that are encrypted with the org data keys. They are re-encrypted when a key
+	// is rotated.
+	Columns []EncryptedColumn
+}
+
+// Manager creates, rotates and fetches the data keys of orgs. Keys are created for orgs the first time they are
+// needed, and the org's existing data is re-encrypted from the legacy key.
+type Manager struct {
+	db  *sqlx.DB
+	cfg Config
+
+	// unwrapped caches the unwrapped data keys, by their wrapped form.
+	unwrappedMu sync.Mutex
+	unwrapped   map[string]string
+}
+
+// NewManager creates a Manager.
+func NewManager(db *sqlx.DB, cfg Config) *Manager {
+	return &Manager{
+		db:        db,
+		cfg:       cfg,
+		unwrapped: make(map[string]string),
+	}
+}
+
+// Key returns the key to encrypt and decrypt the org's data with, as the passphrase for PGP_SYM_ENCRYPT and
+// PGP_SYM_DECRYPT. Writes should use KeyTx instead, so that the key can't be rotated before they complete.
+func (m *Manager) Key(ctx context.Context, orgID uuid.UUID) (string, error) {
+	if m.cfg.Wrapper == nil {
+		return m.cfg.LegacyKey, nil
+	}
+	if err := m.ensureKey(ctx, orgID); err != nil {
+		return "", err
+	}
+	key, _, err := m.lookup(ctx, m.db, orgID, "")
+	return key, err
+}
+
+// KeyTx is like Key, but locks the org's key within the transaction, so that it can't be rotated until the
+// transaction is done.
+func (m *Manager) KeyTx(ctx context.Context, tx *sqlx.Tx, orgID uuid.UUID) (string, error) {
+	if m.cfg.Wrapper == nil {
+		return m.cfg.LegacyKey, nil
+	}
+	if err := m.ensureKey(ctx, orgID); err != nil {
+		return "", err
+	}
+	key, _, err := m.lookup(ctx, tx, orgID, " FOR SHARE")
+	return key, err
+}
+
+// Rotate generates a new data key for the org, and re-encrypts all of the org's data with it. Returns the version of
+// the new key.
+func (m *Manager) Rotate(ctx context.Context, orgID uuid.UUID) (int, error) {
+	if m.cfg.Wrapper == nil {
+		return 0, ErrRotationUnsupported
+	}
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	version, err := m.rotate(ctx, tx, orgID)
+	if err != nil {
+		return 0, err
+	}
+	return version, tx.Commit()
+}
+
+// ensureKey creates a key for the org if it doesn't have one yet.
+func (m *Manager) ensureKey(ctx context.Context, orgID uuid.UUID) error {
+	var exists bool
+	err := m.db.GetContext(ctx, &exists, fmt.Sprintf(`SELECT EXISTS(SELECT 1 FROM %s WHERE org_id=$1)`, m.cfg.KeysTable), orgID)
+	if err != nil || exists {
+		return err
+	}
+
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := m.lockOrg(ctx, tx, orgID); err != nil {
+		return err
+	}
+	// Another caller may have created the key while we waited for the lock.
+	err = tx.GetContext(ctx, &exists, fmt.Sprintf(`SELECT EXISTS(SELECT 1 FROM %s WHERE org_id=$1)`, m.cfg.KeysTable), orgID)
+	if err != nil || exists {
+		return err
+	}
+	if _, err := m.rotate(ctx, tx, orgID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// lockOrg serializes the creation and rotation of the org's key, including when the org has no key to lock yet.
+func (m *Manager) lockOrg(ctx context.Context, tx *sqlx.Tx, orgID uuid.UUID) error {
+	_, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, m.cfg.KeysTable+"/"+orgID.String())
+	return err
+}
+
+func (m *Manager) rotate(ctx context.Context, tx *sqlx.Tx, orgID uuid.UUID) (int, error) {
+	if err := m.lockOrg(ctx, tx, orgID); err != nil {
+		return 0, err
+	}
+	oldKey, oldVersion, err := m.lookup(ctx, tx, orgID, " FOR UPDATE")
+	if errors.Is(err, sql.ErrNoRows) {
+		oldKey, oldVersion, err = m.cfg.LegacyKey, 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return 0, err
+	}
+	newKey := hex.EncodeToString(dataKey)
+	wrapped, err := m.cfg.Wrapper.WrapKey(ctx, orgID, dataKey)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, c := range m.cfg.Columns {
+		query := fmt.Sprintf(`UPDATE %[1]s SET %[2]s=PGP_SYM_ENCRYPT(PGP_SYM_DECRYPT(%[2]s, $1::text), $2::text) WHERE %[3]s=$3 AND %[2]s IS NOT NULL`,
+			c.Table, c.Column, c.OrgIDColumn)
+		if _, err := tx.ExecContext(ctx, query, oldKey, newKey, orgID); err != nil {
+			return 0, fmt.Errorf("re-encrypting %s.%s: %w", c.Table, c.Column, err)
+		}
+	}
+
+	version := oldVersion + 1
+	query := fmt.Sprintf(`INSERT INTO %s (org_id, version, wrapped_key, kek_id, rotated_at) VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (org_id) DO UPDATE SET version=EXCLUDED.version, wrapped_key=EXCLUDED.wrapped_key, kek_id=EXCLUDED.kek_id, rotated_at=EXCLUDED.rotated_at`,
+		m.cfg.KeysTable)
+	if _, err := tx.ExecContext(ctx, query, orgID, version, wrapped, m.cfg.Wrapper.ID()); err != nil {
+		return 0, err
+	}
+	m.cacheKey(wrapped, newKey)
+	return version, nil
+}
+
+// lookup fetches and unwraps the org's key. Returns sql.ErrNoRows if the org doesn't have a key.
+func (m *Manager) lookup(ctx context.Context, q sqlx.QueryerContext, orgID uuid.UUID, lock string) (string, int, error) {
+	var row struct {
+		Version    int    `db:"version"`
+		WrappedKey []byte `db:"wrapped_key"`
+	}
+	query := fmt.Sprintf(`SELECT version, wrapped_key FROM %s WHERE org_id=$1%s`, m.cfg.KeysTable, lock)
+	if err := sqlx.GetContext(ctx, q, &row, query, orgID); err != nil {
+		return "", 0, err
+	}
+
+	m.unwrappedMu.Lock()
+	key, ok := m.unwrapped[string(row.WrappedKey)]
+	m.unwrappedMu.Unlock()
+	if ok {
+		return key, row.Version, nil
+	}
+
+	dataKey, err := m.cfg.Wrapper.UnwrapKey(ctx, orgID, row.WrappedKey)
+	if err != nil {
+		return "", 0, fmt.Errorf("unwrapping data key: %w", err)
+	}
+	key = hex.EncodeToString(dataKey)
+	m.cacheKey(row.WrappedKey, key)
+	return key, row.Version, nil
+}
+
+func (m *Manager) cacheKey(wrapped []byte, key string) {
+	m.unwrappedMu.Lock()
+	defer m.unwrappedMu.Unlock()
+	m.unwrapped[string(wrapped)] = key
+}
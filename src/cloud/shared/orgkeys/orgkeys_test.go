@@ -0,0 +1,189 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package orgkeys_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/shared/orgkeys"
+	"px.dev/pixie/src/shared/services/pgtest"
+)
+
+var db *sqlx.DB
+
+func TestMain(m *testing.M) {
+	err := testMain(m)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Got error: %v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func testMain(m *testing.M) error {
+	testDB, teardown, err := pgtest.SetupTestDB(nil)
+	if err != nil {
+		return fmt.Errorf("failed to start test database: %w", err)
+	}
+	defer teardown()
+	db = testDB
+
+	db.MustExec(`CREATE EXTENSION IF NOT EXISTS pgcrypto`)
+	db.MustExec(`CREATE TABLE org_data_keys (
+		org_id UUID PRIMARY KEY,
+		version INT NOT NULL,
+		wrapped_key BYTEA NOT NULL,
+		kek_id VARCHAR(1024) NOT NULL,
+		rotated_at TIMESTAMP NOT NULL
+	)`)
+	db.MustExec(`CREATE TABLE secrets (org_id UUID NOT NULL, value BYTEA)`)
+
+	if c := m.Run(); c != 0 {
+		return fmt.Errorf("some tests failed with code: %d", c)
+	}
+	return nil
+}
+
+func newTestManager(wrapper orgkeys.KeyWrapper) *orgkeys.Manager {
+	return orgkeys.NewManager(db, orgkeys.Config{
+		Wrapper:   wrapper,
+		LegacyKey: "legacy",
+		KeysTable: "org_data_keys",
+		Columns: []orgkeys.EncryptedColumn{
+			{Table: "secrets", Column: "value", OrgIDColumn: "org_id"},
+		},
+	})
+}
+
+func mustLoadTestData(db *sqlx.DB) {
+	db.MustExec(`DELETE FROM org_data_keys`)
+	db.MustExec(`DELETE FROM secrets`)
+	db.MustExec(`INSERT INTO secrets (org_id, value) VALUES ($1, PGP_SYM_ENCRYPT($2, 'legacy'))`, testOrgID, "org secret")
+	db.MustExec(`INSERT INTO secrets (org_id, value) VALUES ($1, PGP_SYM_ENCRYPT($2, 'legacy'))`, otherOrgID, "other org secret")
+	db.MustExec(`INSERT INTO secrets (org_id, value) VALUES ($1, NULL)`, testOrgID)
+}
+
+func readSecret(t *testing.T, key string, orgID interface{}) (string, error) {
+	var value string
+	err := db.Get(&value, `SELECT PGP_SYM_DECRYPT(value, $1::text) FROM secrets WHERE org_id=$2 AND value IS NOT NULL`, key, orgID)
+	return value, err
+}
+
+func TestManager_LegacyKey(t *testing.T) {
+	mustLoadTestData(db)
+	m := newTestManager(nil)
+
+	key, err := m.Key(context.Background(), testOrgID)
+	require.NoError(t, err)
+	assert.Equal(t, "legacy", key)
+
+	_, err = m.Rotate(context.Background(), testOrgID)
+	assert.ErrorIs(t, err, orgkeys.ErrRotationUnsupported)
+}
+
+func TestManager_CreatesKey(t *testing.T) {
+	mustLoadTestData(db)
+	m := newTestManager(orgkeys.NewLocalKeyWrapper("secret"))
+
+	key, err := m.Key(context.Background(), testOrgID)
+	require.NoError(t, err)
+	assert.NotEqual(t, "legacy", key)
+
+	// The org's data was re-encrypted with its own key.
+	value, err := readSecret(t, key, testOrgID)
+	require.NoError(t, err)
+	assert.Equal(t, "org secret", value)
+	_, err = readSecret(t, "legacy", testOrgID)
+	assert.Error(t, err)
+
+	// The other org's data is untouched, and can't be read with the org's key.
+	_, err = readSecret(t, key, otherOrgID)
+	assert.Error(t, err)
+	value, err = readSecret(t, "legacy", otherOrgID)
+	require.NoError(t, err)
+	assert.Equal(t, "other org secret", value)
+
+	// The key is stable.
+	again, err := m.Key(context.Background(), testOrgID)
+	require.NoError(t, err)
+	assert.Equal(t, key, again)
+}
+
+func TestManager_Rotate(t *testing.T) {
+	mustLoadTestData(db)
+	m := newTestManager(orgkeys.NewLocalKeyWrapper("secret"))
+
+	oldKey, err := m.Key(context.Background(), testOrgID)
+	require.NoError(t, err)
+
+	version, err := m.Rotate(context.Background(), testOrgID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, version)
+
+	newKey, err := m.Key(context.Background(), testOrgID)
+	require.NoError(t, err)
+	assert.NotEqual(t, oldKey, newKey)
+
+	value, err := readSecret(t, newKey, testOrgID)
+	require.NoError(t, err)
+	assert.Equal(t, "org secret", value)
+	_, err = readSecret(t, oldKey, testOrgID)
+	assert.Error(t, err)
+
+	// Another manager, such as on another replica of the service, gets the rotated key.
+	other := newTestManager(orgkeys.NewLocalKeyWrapper("secret"))
+	otherKey, err := other.Key(context.Background(), testOrgID)
+	require.NoError(t, err)
+	assert.Equal(t, newKey, otherKey)
+}
+
+func TestManager_KeyTx(t *testing.T) {
+	mustLoadTestData(db)
+	m := newTestManager(orgkeys.NewLocalKeyWrapper("secret"))
+
+	tx, err := db.Beginx()
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	key, err := m.KeyTx(context.Background(), tx, otherOrgID)
+	require.NoError(t, err)
+	tx.MustExec(`INSERT INTO secrets (org_id, value) VALUES ($1, PGP_SYM_ENCRYPT($2, $3))`, otherOrgID, "new secret", key)
+	require.NoError(t, tx.Commit())
+
+	var values []string
+	err = db.Select(&values, `SELECT PGP_SYM_DECRYPT(value, $1::text) FROM secrets WHERE org_id=$2 ORDER BY 1`, key, otherOrgID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"new secret", "other org secret"}, values)
+}
+
+func TestManager_WrongWrapper(t *testing.T) {
+	mustLoadTestData(db)
+	_, err := newTestManager(orgkeys.NewLocalKeyWrapper("secret")).Key(context.Background(), testOrgID)
+	require.NoError(t, err)
+
+	_, err = newTestManager(orgkeys.NewLocalKeyWrapper("other")).Key(context.Background(), testOrgID)
+	assert.Error(t, err)
+}
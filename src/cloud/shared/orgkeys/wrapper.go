@@ -0,0 +1,152 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package orgkeys
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+
+	kms "cloud.google.com/go/kms/apiv1"
+	"github.com/gofrs/uuid"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	kmspb "google.golang.org/genproto/googleapis/cloud/kms/v1"
+)
+
+// SetupFlags adds the flags that choose how org data keys are wrapped.
+func SetupFlags() {
+	pflag.String("org_data_key_kms_key", "", "The resource name of the Cloud KMS key used to wrap org data keys")
+	pflag.String("org_data_key_secret", "", "The secret used to wrap org data keys, if no Cloud KMS key is set")
+}
+
+// KeyWrapper encrypts and decrypts data keys with a key encryption key that it holds, such as a key in a KMS. The
+// org that a data key belongs to is bound to the wrapped key, so that it can't be unwrapped for another org.
+type KeyWrapper interface {
+	// ID identifies the key encryption key. It is stored alongside the keys it wraps.
+	ID() string
+	WrapKey(ctx context.Context, orgID uuid.UUID, key []byte) ([]byte, error)
+	UnwrapKey(ctx context.Context, orgID uuid.UUID, wrapped []byte) ([]byte, error)
+}
+
+// NewKeyWrapperFromFlags returns the KeyWrapper chosen by the flags. It returns nil if neither a KMS key nor a secret
+// is set, in which case org data is still encrypted with the legacy database key.
+func NewKeyWrapperFromFlags(ctx context.Context) (KeyWrapper, error) {
+	if name := viper.GetString("org_data_key_kms_key"); name != "" {
+		return NewGCPKeyWrapper(ctx, name)
+	}
+	if secret := viper.GetString("org_data_key_secret"); secret != "" {
+		return NewLocalKeyWrapper(secret), nil
+	}
+	return nil, nil
+}
+
+// LocalKeyWrapper wraps keys with AES-GCM, using a key derived from a secret held by the service.
+type LocalKeyWrapper struct {
+	aead cipher.AEAD
+}
+
+// NewLocalKeyWrapper creates a LocalKeyWrapper from the secret.
+func NewLocalKeyWrapper(secret string) *LocalKeyWrapper {
+	kek := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(kek[:])
+	if err != nil {
+		// This can only happen for invalid key sizes, and the key is always 32 bytes.
+		panic(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return &LocalKeyWrapper{aead: aead}
+}
+
+// ID implements KeyWrapper.
+func (w *LocalKeyWrapper) ID() string {
+	return "local"
+}
+
+// WrapKey implements KeyWrapper.
+func (w *LocalKeyWrapper) WrapKey(ctx context.Context, orgID uuid.UUID, key []byte) ([]byte, error) {
+	nonce := make([]byte, w.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return w.aead.Seal(nonce, nonce, key, orgID.Bytes()), nil
+}
+
+// UnwrapKey implements KeyWrapper.
+func (w *LocalKeyWrapper) UnwrapKey(ctx context.Context, orgID uuid.UUID, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < w.aead.NonceSize() {
+		return nil, errors.New("wrapped key is too short")
+	}
+	nonce, ciphertext := wrapped[:w.aead.NonceSize()], wrapped[w.aead.NonceSize():]
+	return w.aead.Open(nil, nonce, ciphertext, orgID.Bytes())
+}
+
+// GCPKeyWrapper wraps keys with a Cloud KMS symmetric key.
+type GCPKeyWrapper struct {
+	client  *kms.KeyManagementClient
+	keyName string
+}
+
+// NewGCPKeyWrapper creates a GCPKeyWrapper for the Cloud KMS key with the given resource name, of the form
+// projects/*/locations/*/keyRings/*/cryptoKeys/*.
+func NewGCPKeyWrapper(ctx context.Context, keyName string) (*GCPKeyWrapper, error) {
+	client, err := kms.NewKeyManagementClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &GCPKeyWrapper{client: client, keyName: keyName}, nil
+}
+
+// ID implements KeyWrapper.
+func (w *GCPKeyWrapper) ID() string {
+	return w.keyName
+}
+
+// WrapKey implements KeyWrapper.
+func (w *GCPKeyWrapper) WrapKey(ctx context.Context, orgID uuid.UUID, key []byte) ([]byte, error) {
+	resp, err := w.client.Encrypt(ctx, &kmspb.EncryptRequest{
+		Name:                        w.keyName,
+		Plaintext:                   key,
+		AdditionalAuthenticatedData: orgID.Bytes(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Ciphertext, nil
+}
+
+// UnwrapKey implements KeyWrapper.
+func (w *GCPKeyWrapper) UnwrapKey(ctx context.Context, orgID uuid.UUID, wrapped []byte) ([]byte, error) {
+	resp, err := w.client.Decrypt(ctx, &kmspb.DecryptRequest{
+		Name:                        w.keyName,
+		Ciphertext:                  wrapped,
+		AdditionalAuthenticatedData: orgID.Bytes(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Plaintext, nil
+}
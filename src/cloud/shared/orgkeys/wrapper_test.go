@@ -0,0 +1,73 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package orgkeys_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/shared/orgkeys"
+)
+
+var (
+	testOrgID  = uuid.FromStringOrNil("223e4567-e89b-12d3-a456-426655440000")
+	otherOrgID = uuid.FromStringOrNil("323e4567-e89b-12d3-a456-426655440000")
+)
+
+func TestLocalKeyWrapper(t *testing.T) {
+	w := orgkeys.NewLocalKeyWrapper("secret")
+	key := []byte("0123456789abcdef0123456789abcdef")
+
+	wrapped, err := w.WrapKey(context.Background(), testOrgID, key)
+	require.NoError(t, err)
+	assert.NotContains(t, string(wrapped), string(key))
+
+	unwrapped, err := w.UnwrapKey(context.Background(), testOrgID, wrapped)
+	require.NoError(t, err)
+	assert.Equal(t, key, unwrapped)
+
+	// Wrapping the same key again gives a different result.
+	wrappedAgain, err := w.WrapKey(context.Background(), testOrgID, key)
+	require.NoError(t, err)
+	assert.NotEqual(t, wrapped, wrappedAgain)
+}
+
+func TestLocalKeyWrapper_OtherOrg(t *testing.T) {
+	w := orgkeys.NewLocalKeyWrapper("secret")
+	wrapped, err := w.WrapKey(context.Background(), testOrgID, []byte("key"))
+	require.NoError(t, err)
+
+	_, err = w.UnwrapKey(context.Background(), otherOrgID, wrapped)
+	assert.Error(t, err)
+}
+
+func TestLocalKeyWrapper_WrongSecret(t *testing.T) {
+	wrapped, err := orgkeys.NewLocalKeyWrapper("secret").WrapKey(context.Background(), testOrgID, []byte("key"))
+	require.NoError(t, err)
+
+	_, err = orgkeys.NewLocalKeyWrapper("other").UnwrapKey(context.Background(), testOrgID, wrapped)
+	assert.Error(t, err)
+
+	_, err = orgkeys.NewLocalKeyWrapper("secret").UnwrapKey(context.Background(), testOrgID, wrapped[:4])
+	assert.Error(t, err)
+}
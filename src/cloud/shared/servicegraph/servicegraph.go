@@ -0,0 +1,258 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package servicegraph stitches the service graphs of the clusters in a fleet into one topology.
+// Each cluster only sees its side of a cross-cluster call: the client side sees a request to an
+// IP outside the cluster, and the server side sees a request from one. Those IPs are matched
+// against the IPs that other clusters expose their services on and send their traffic from, so
+// that both sides resolve to the same edge.
+package servicegraph
+
+import (
+	"sort"
+)
+
+// Endpoint is one side of an edge. Service is set if the endpoint was resolved to a service,
+// and ClusterID if it was resolved to a cluster. Otherwise the raw IP is all that is known.
+type Endpoint struct {
+	ClusterID string
+	// Service is the namespaced name of the service, "ns/name".
+	Service string
+	IP      string
+}
+
+func (e Endpoint) resolved() bool {
+	return e.Service != ""
+}
+
+// Edge aggregates the requests from one endpoint to another.
+type Edge struct {
+	Requestor Endpoint
+	Responder Endpoint
+
+	NumRequests    int64
+	NumErrors      int64
+	TotalLatencyNS int64
+
+	// CrossCluster is set on merged edges if the requestor and responder are in different clusters.
+	CrossCluster bool
+}
+
+// Role is the side of a request that a cluster observed.
+type Role int
+
+const (
+	// RoleClient edges were observed on the requestor, which is a service in the cluster.
+	RoleClient Role = iota
+	// RoleServer edges were observed on the responder, which is a service in the cluster.
+	RoleServer
+)
+
+// ClusterEdge is an edge as observed by one cluster. The local side is always a service in that
+// cluster, and the remote side has a Service only if the cluster could resolve it itself.
+type ClusterEdge struct {
+	Edge
+	Role Role
+}
+
+// ClusterGraph is the service graph observed by a single cluster.
+type ClusterGraph struct {
+	ClusterID string
+	Edges     []*ClusterEdge
+	// ServiceIPs maps the IPs that the services of the cluster are reachable on, such as
+	// external and load balancer IPs, to the namespaced service name.
+	ServiceIPs map[string]string
+	// EgressIPs are the IPs that requests leaving the cluster are sent from, such as node IPs.
+	EgressIPs []string
+}
+
+// Graph is the stitched service graph of a fleet.
+type Graph struct {
+	Edges []*Edge
+	// AmbiguousIPs are IPs claimed by more than one cluster. They are left unresolved, because
+	// private address ranges are commonly reused across clusters.
+	AmbiguousIPs []string
+}
+
+type edgeKey struct {
+	requestor Endpoint
+	responder Endpoint
+}
+
+// ipIndex maps IPs to the endpoint that owns them, dropping IPs owned by more than one cluster.
+type ipIndex struct {
+	owners    map[string]Endpoint
+	ambiguous map[string]bool
+}
+
+func newIPIndex() *ipIndex {
+	return &ipIndex{owners: make(map[string]Endpoint), ambiguous: make(map[string]bool)}
+}
+
+func (idx *ipIndex) add(ip string, e Endpoint) {
+	if ip == "" || idx.ambiguous[ip] {
+		return
+	}
+	if cur, ok := idx.owners[ip]; ok && cur.ClusterID != e.ClusterID {
+		delete(idx.owners, ip)
+		idx.ambiguous[ip] = true
+		return
+	}
+	idx.owners[ip] = e
+}
+
+func (idx *ipIndex) lookup(ip string) (Endpoint, bool) {
+	e, ok := idx.owners[ip]
+	return e, ok
+}
+
+// Merge stitches the graphs observed by each cluster into a single graph. Remote IPs are resolved
+// to the services and clusters that own them. A cross-cluster request that is observed by both
+// clusters is only counted once: the client side is kept, because it knows the requesting service,
+// and server side observations that it already covers are dropped.
+func Merge(graphs []*ClusterGraph) *Graph {
+	services := newIPIndex()
+	egress := newIPIndex()
+	for _, g := range graphs {
+		for ip, svc := range g.ServiceIPs {
+			services.add(ip, Endpoint{ClusterID: g.ClusterID, Service: svc})
+		}
+		for _, ip := range g.EgressIPs {
+			egress.add(ip, Endpoint{ClusterID: g.ClusterID})
+		}
+	}
+
+	clientEdges := make(map[edgeKey]*Edge)
+	var serverEdges []*Edge
+	for _, g := range graphs {
+		for _, ce := range g.Edges {
+			e := ce.Edge
+			switch ce.Role {
+			case RoleClient:
+				e.Requestor.ClusterID = g.ClusterID
+				e.Responder = resolveResponder(e.Responder, g.ClusterID, services)
+				addEdge(clientEdges, &e)
+			case RoleServer:
+				e.Responder.ClusterID = g.ClusterID
+				e.Requestor = resolveRequestor(e.Requestor, g.ClusterID, egress)
+				serverEdges = append(serverEdges, &e)
+			}
+		}
+	}
+
+	// The clusters that each responder was seen being called from by the client side.
+	observedCallers := make(map[Endpoint]map[string]bool)
+	for k := range clientEdges {
+		if observedCallers[k.responder] == nil {
+			observedCallers[k.responder] = make(map[string]bool)
+		}
+		observedCallers[k.responder][k.requestor.ClusterID] = true
+	}
+
+	merged := make(map[edgeKey]*Edge, len(clientEdges))
+	for k, e := range clientEdges {
+		merged[k] = e
+	}
+	for _, e := range serverEdges {
+		// Requests between services that are both traced are observed on both sides.
+		if _, ok := clientEdges[edgeKey{requestor: e.Requestor, responder: e.Responder}]; ok {
+			continue
+		}
+		// Requests from other clusters are only attributed to a cluster on the server side, so
+		// they are covered by any client side edge from that cluster.
+		if !e.Requestor.resolved() && e.Requestor.ClusterID != "" &&
+			observedCallers[e.Responder][e.Requestor.ClusterID] {
+			continue
+		}
+		addEdge(merged, e)
+	}
+
+	out := &Graph{}
+	for _, e := range merged {
+		e.CrossCluster = e.Requestor.ClusterID != "" && e.Responder.ClusterID != "" &&
+			e.Requestor.ClusterID != e.Responder.ClusterID
+		out.Edges = append(out.Edges, e)
+	}
+	sort.Slice(out.Edges, func(i, j int) bool {
+		return lessEdge(out.Edges[i], out.Edges[j])
+	})
+	for ip := range services.ambiguous {
+		out.AmbiguousIPs = append(out.AmbiguousIPs, ip)
+	}
+	for ip := range egress.ambiguous {
+		if !services.ambiguous[ip] {
+			out.AmbiguousIPs = append(out.AmbiguousIPs, ip)
+		}
+	}
+	sort.Strings(out.AmbiguousIPs)
+	return out
+}
+
+func resolveResponder(e Endpoint, clusterID string, services *ipIndex) Endpoint {
+	if e.resolved() {
+		// Services that the cluster resolved itself are in that cluster.
+		e.ClusterID = clusterID
+		return e
+	}
+	if owner, ok := services.lookup(e.IP); ok {
+		return owner
+	}
+	return e
+}
+
+func resolveRequestor(e Endpoint, clusterID string, egress *ipIndex) Endpoint {
+	if e.resolved() {
+		e.ClusterID = clusterID
+		return e
+	}
+	if owner, ok := egress.lookup(e.IP); ok && owner.ClusterID != clusterID {
+		// Only the cluster is known. The IP is dropped so that all requests from that cluster are
+		// aggregated together.
+		return owner
+	}
+	return e
+}
+
+func addEdge(edges map[edgeKey]*Edge, e *Edge) {
+	k := edgeKey{requestor: e.Requestor, responder: e.Responder}
+	cur, ok := edges[k]
+	if !ok {
+		edges[k] = e
+		return
+	}
+	cur.NumRequests += e.NumRequests
+	cur.NumErrors += e.NumErrors
+	cur.TotalLatencyNS += e.TotalLatencyNS
+}
+
+func lessEndpoint(a, b Endpoint) bool {
+	if a.ClusterID != b.ClusterID {
+		return a.ClusterID < b.ClusterID
+	}
+	if a.Service != b.Service {
+		return a.Service < b.Service
+	}
+	return a.IP < b.IP
+}
+
+func lessEdge(a, b *Edge) bool {
+	if a.Requestor != b.Requestor {
+		return lessEndpoint(a.Requestor, b.Requestor)
+	}
+	return lessEndpoint(a.Responder, b.Responder)
+}
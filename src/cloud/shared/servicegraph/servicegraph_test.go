@@ -0,0 +1,200 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package servicegraph_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"px.dev/pixie/src/cloud/shared/servicegraph"
+)
+
+func clientEdge(from, toIP string, n int64) *servicegraph.ClusterEdge {
+	return &servicegraph.ClusterEdge{
+		Role: servicegraph.RoleClient,
+		Edge: servicegraph.Edge{
+			Requestor:   servicegraph.Endpoint{Service: from},
+			Responder:   servicegraph.Endpoint{IP: toIP},
+			NumRequests: n,
+		},
+	}
+}
+
+func serverEdge(fromIP, to string, n int64) *servicegraph.ClusterEdge {
+	return &servicegraph.ClusterEdge{
+		Role: servicegraph.RoleServer,
+		Edge: servicegraph.Edge{
+			Requestor:   servicegraph.Endpoint{IP: fromIP},
+			Responder:   servicegraph.Endpoint{Service: to},
+			NumRequests: n,
+		},
+	}
+}
+
+func TestMerge_CrossCluster(t *testing.T) {
+	graphs := []*servicegraph.ClusterGraph{
+		{
+			ClusterID: "a",
+			Edges: []*servicegraph.ClusterEdge{
+				clientEdge("pl/frontend", "34.1.1.1", 10),
+				{
+					Role: servicegraph.RoleClient,
+					Edge: servicegraph.Edge{
+						Requestor:   servicegraph.Endpoint{Service: "pl/frontend"},
+						Responder:   servicegraph.Endpoint{Service: "pl/cache"},
+						NumRequests: 4,
+					},
+				},
+			},
+			EgressIPs: []string{"10.0.0.1"},
+		},
+		{
+			ClusterID: "b",
+			Edges: []*servicegraph.ClusterEdge{
+				// The same requests, as seen by the server.
+				serverEdge("10.0.0.1", "pl/backend", 10),
+			},
+			ServiceIPs: map[string]string{"34.1.1.1": "pl/backend"},
+			EgressIPs:  []string{"10.1.0.1"},
+		},
+	}
+
+	g := servicegraph.Merge(graphs)
+	assert.Equal(t, []*servicegraph.Edge{
+		{
+			Requestor:   servicegraph.Endpoint{ClusterID: "a", Service: "pl/frontend"},
+			Responder:   servicegraph.Endpoint{ClusterID: "a", Service: "pl/cache"},
+			NumRequests: 4,
+		},
+		{
+			Requestor:    servicegraph.Endpoint{ClusterID: "a", Service: "pl/frontend"},
+			Responder:    servicegraph.Endpoint{ClusterID: "b", Service: "pl/backend"},
+			NumRequests:  10,
+			CrossCluster: true,
+		},
+	}, g.Edges)
+	assert.Empty(t, g.AmbiguousIPs)
+}
+
+func TestMerge_ServerSideOnly(t *testing.T) {
+	graphs := []*servicegraph.ClusterGraph{
+		{
+			ClusterID: "a",
+			EgressIPs: []string{"10.0.0.1", "10.0.0.2"},
+		},
+		{
+			ClusterID: "b",
+			Edges: []*servicegraph.ClusterEdge{
+				serverEdge("10.0.0.1", "pl/backend", 3),
+				serverEdge("10.0.0.2", "pl/backend", 2),
+				serverEdge("8.8.8.8", "pl/backend", 1),
+			},
+		},
+	}
+
+	g := servicegraph.Merge(graphs)
+	assert.Equal(t, []*servicegraph.Edge{
+		{
+			Requestor:   servicegraph.Endpoint{IP: "8.8.8.8"},
+			Responder:   servicegraph.Endpoint{ClusterID: "b", Service: "pl/backend"},
+			NumRequests: 1,
+		},
+		{
+			Requestor:    servicegraph.Endpoint{ClusterID: "a"},
+			Responder:    servicegraph.Endpoint{ClusterID: "b", Service: "pl/backend"},
+			NumRequests:  5,
+			CrossCluster: true,
+		},
+	}, g.Edges)
+}
+
+func TestMerge_AmbiguousIPs(t *testing.T) {
+	graphs := []*servicegraph.ClusterGraph{
+		{
+			ClusterID: "a",
+			Edges: []*servicegraph.ClusterEdge{
+				clientEdge("pl/frontend", "10.96.0.10", 1),
+			},
+			ServiceIPs: map[string]string{"10.96.0.10": "kube-system/kube-dns"},
+		},
+		{
+			ClusterID:  "b",
+			ServiceIPs: map[string]string{"10.96.0.10": "kube-system/kube-dns"},
+		},
+	}
+
+	g := servicegraph.Merge(graphs)
+	assert.Equal(t, []*servicegraph.Edge{
+		{
+			Requestor:   servicegraph.Endpoint{ClusterID: "a", Service: "pl/frontend"},
+			Responder:   servicegraph.Endpoint{IP: "10.96.0.10"},
+			NumRequests: 1,
+		},
+	}, g.Edges)
+	assert.Equal(t, []string{"10.96.0.10"}, g.AmbiguousIPs)
+}
+
+func TestMerge_IntraCluster(t *testing.T) {
+	graphs := []*servicegraph.ClusterGraph{
+		{
+			ClusterID: "a",
+			Edges: []*servicegraph.ClusterEdge{
+				{
+					Role: servicegraph.RoleClient,
+					Edge: servicegraph.Edge{
+						Requestor:   servicegraph.Endpoint{Service: "pl/frontend"},
+						Responder:   servicegraph.Endpoint{Service: "pl/backend"},
+						NumRequests: 7,
+					},
+				},
+				{
+					Role: servicegraph.RoleServer,
+					Edge: servicegraph.Edge{
+						Requestor:   servicegraph.Endpoint{Service: "pl/frontend"},
+						Responder:   servicegraph.Endpoint{Service: "pl/backend"},
+						NumRequests: 7,
+					},
+				},
+				{
+					Role: servicegraph.RoleServer,
+					Edge: servicegraph.Edge{
+						Requestor:   servicegraph.Endpoint{Service: "pl/cron"},
+						Responder:   servicegraph.Endpoint{Service: "pl/backend"},
+						NumRequests: 1,
+					},
+				},
+			},
+		},
+	}
+
+	g := servicegraph.Merge(graphs)
+	assert.Equal(t, []*servicegraph.Edge{
+		{
+			Requestor:   servicegraph.Endpoint{ClusterID: "a", Service: "pl/cron"},
+			Responder:   servicegraph.Endpoint{ClusterID: "a", Service: "pl/backend"},
+			NumRequests: 1,
+		},
+		{
+			Requestor:   servicegraph.Endpoint{ClusterID: "a", Service: "pl/frontend"},
+			Responder:   servicegraph.Endpoint{ClusterID: "a", Service: "pl/backend"},
+			NumRequests: 7,
+		},
+	}, g.Edges)
+}
@@ -209,6 +209,25 @@ func (s *Server) validateOrgOwnsCluster(ctx context.Context, clusterID *uuidpb.U
 	return nil
 }
 
+// validateOrgCanMutateCluster checks that the cluster belongs to the org in the context, and that
+// it isn't read-only.
+func (s *Server) validateOrgCanMutateCluster(ctx context.Context, clusterID *uuidpb.UUID) error {
+	if err := s.validateOrgOwnsCluster(ctx, clusterID); err != nil {
+		return err
+	}
+
+	query := `SELECT read_only FROM vizier_cluster WHERE id=$1`
+	var readOnly bool
+	if err := s.db.QueryRowContext(ctx, query, utils.UUIDFromProtoOrNil(clusterID)).Scan(&readOnly); err != nil {
+		log.WithError(err).Error("Failed to check whether the cluster is read-only")
+		return status.Error(codes.Internal, "failed to fetch cluster")
+	}
+	if readOnly {
+		return status.Error(codes.PermissionDenied, "cluster was registered with a viewer deploy key and is read-only")
+	}
+	return nil
+}
+
 // CreateVizierCluster creates a new tracked vizier cluster.
 func (s *Server) CreateVizierCluster(ctx context.Context, req *vzmgrpb.CreateVizierClusterRequest) (*uuidpb.UUID, error) {
 	return nil, status.Errorf(codes.Unimplemented, "Deprecated. Please use `px deploy`")
@@ -263,6 +282,7 @@ type VizierInfo struct {
 	OrgID                         uuid.UUID     `db:"org_id"`
 	PrevStatus                    *vizierStatus `db:"prev_status"`
 	PrevStatusTime                *time.Time    `db:"prev_status_time"`
+	ReadOnly                      bool          `db:"read_only"`
 }
 
 func vizierInfoToProto(vzInfo VizierInfo) *cvmsgspb.VizierInfo {
@@ -322,6 +342,7 @@ func vizierInfoToProto(vzInfo VizierInfo) *cvmsgspb.VizierInfo {
 		NumInstrumentedNodes:          vzInfo.NumInstrumentedNodes,
 		PreviousStatus:                prevStatus,
 		PreviousStatusTime:            prevStatusTime,
+		ReadOnly:                      vzInfo.ReadOnly,
 	}
 }
 
@@ -345,7 +366,7 @@ func (s *Server) GetVizierInfos(ctx context.Context, req *vzmgrpb.GetVizierInfos
 	strQuery := `SELECT i.vizier_cluster_id, c.cluster_uid, c.cluster_name, i.cluster_version, i.operator_version, i.vizier_version,
 			  c.org_id, i.status, (EXTRACT(EPOCH FROM age(now(), i.last_heartbeat))*1E9)::bigint as last_heartbeat,
               i.control_plane_pod_statuses, i.unhealthy_data_plane_pod_statuses,
-							i.num_nodes, i.num_instrumented_nodes, i.status_message, i.prev_status, i.prev_status_time
+							i.num_nodes, i.num_instrumented_nodes, i.status_message, i.prev_status, i.prev_status_time, c.read_only
               FROM vizier_cluster_info as i, vizier_cluster as c
               WHERE i.vizier_cluster_id=c.id AND i.vizier_cluster_id IN (?) AND c.org_id='%s'`
 	strQuery = fmt.Sprintf(strQuery, orgIDstr)
@@ -398,7 +419,7 @@ func (s *Server) GetVizierInfo(ctx context.Context, req *uuidpb.UUID) (*cvmsgspb
 	query := `SELECT i.vizier_cluster_id, c.cluster_uid, c.cluster_name, i.cluster_version, i.operator_version, i.vizier_version,
 			  i.status, (EXTRACT(EPOCH FROM age(now(), i.last_heartbeat))*1E9)::bigint as last_heartbeat,
               i.control_plane_pod_statuses, i.unhealthy_data_plane_pod_statuses,
-							i.num_nodes, i.num_instrumented_nodes, i.status_message, i.prev_status, i.prev_status_time
+							i.num_nodes, i.num_instrumented_nodes, i.status_message, i.prev_status, i.prev_status_time, c.read_only
               from vizier_cluster_info as i, vizier_cluster as c
               WHERE i.vizier_cluster_id=$1 AND i.vizier_cluster_id=c.id`
 	vzInfo := VizierInfo{}
@@ -429,7 +450,7 @@ func (s *Server) GetVizierInfo(ctx context.Context, req *uuidpb.UUID) (*cvmsgspb
 
 // UpdateVizierConfig supports updating of the Vizier config.
 func (s *Server) UpdateVizierConfig(ctx context.Context, req *cvmsgspb.UpdateVizierConfigRequest) (*cvmsgspb.UpdateVizierConfigResponse, error) {
-	if err := s.validateOrgOwnsCluster(ctx, req.VizierID); err != nil {
+	if err := s.validateOrgCanMutateCluster(ctx, req.VizierID); err != nil {
 		return nil, err
 	}
 
@@ -460,9 +481,13 @@ func (s *Server) GetVizierConnectionInfo(ctx context.Context, req *uuidpb.UUID)
 		return nil, err
 	}
 
-	// Generate a signed token for this cluster.
+	// Generate a signed token for this cluster. Requests made on behalf of a user carry the user's identity and
+	// roles, so that the Vizier can apply the policies for the user, such as the column policies.
 	jwtKey := info.JWTSigningKey[SaltLength:]
 	claims := jwtutils.GenerateJWTForCluster("vizier_cluster", "vizier")
+	if sCtx, err := authcontext.FromContext(ctx); err == nil && jwtutils.GetClaimsType(sCtx.Claims) == jwtutils.UserClaimType {
+		claims = jwtutils.GenerateJWTForClusterUser(sCtx.Claims, "vizier")
+	}
 	tokenString, err := jwtutils.SignJWTClaims(claims, jwtKey)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to sign token: %s", err.Error())
@@ -701,7 +726,7 @@ func getServiceCredentials(signingKey string) (string, error) {
 
 // UpdateOrInstallVizier updates or installs the given vizier cluster to the specified version.
 func (s *Server) UpdateOrInstallVizier(ctx context.Context, req *cvmsgspb.UpdateOrInstallVizierRequest) (*cvmsgspb.UpdateOrInstallVizierResponse, error) {
-	if err := s.validateOrgOwnsCluster(ctx, req.VizierID); err != nil {
+	if err := s.validateOrgCanMutateCluster(ctx, req.VizierID); err != nil {
 		return nil, err
 	}
 
@@ -797,8 +822,9 @@ func setClusterName(ctx context.Context, tx *sqlx.Tx, clusterID uuid.UUID, gener
 	return finalName, err
 }
 
-// ProvisionOrClaimVizier provisions a given cluster or returns the ID if it already exists,
-func (s *Server) ProvisionOrClaimVizier(ctx context.Context, orgID uuid.UUID, userID uuid.UUID, clusterUID string, clusterName string) (uuid.UUID, string, error) {
+// ProvisionOrClaimVizier provisions a given cluster or returns the ID if it already exists.
+// The cluster is marked read-only if readOnly is set, and writable otherwise.
+func (s *Server) ProvisionOrClaimVizier(ctx context.Context, orgID uuid.UUID, userID uuid.UUID, clusterUID string, clusterName string, readOnly bool) (uuid.UUID, string, error) {
 	// TODO(zasgar): This duplicates some functionality in the Create function. Will deprecate that Create function soon.
 	tx, err := s.db.BeginTxx(ctx, nil)
 	if err != nil {
@@ -823,10 +849,16 @@ func (s *Server) ProvisionOrClaimVizier(ctx context.Context, orgID uuid.UUID, us
 	}
 
 	assignNameAndCommit := func() (uuid.UUID, string, error) {
+		// The cluster takes on the access of the key that it was last registered with.
+		query := `UPDATE vizier_cluster SET read_only=$1 WHERE id=$2`
+		if _, err := tx.ExecContext(ctx, query, readOnly, clusterID); err != nil {
+			return uuid.Nil, "", vzerrors.ErrInternalDB
+		}
+
 		// Check if cluster already has a name.
 		var existingName *string
 
-		query := `SELECT cluster_name from vizier_cluster WHERE id=$1`
+		query = `SELECT cluster_name from vizier_cluster WHERE id=$1`
 		err := tx.QueryRowxContext(ctx, query, clusterID).Scan(&existingName)
 		if err != nil {
 			return uuid.Nil, "", vzerrors.ErrInternalDB
@@ -923,13 +955,14 @@ func (s *Server) ProvisionOrClaimVizier(ctx context.Context, orgID uuid.UUID, us
 
 // GetOrgFromVizier fetches the org to which a Vizier belongs. This is intended to be for internal use only.
 func (s *Server) GetOrgFromVizier(ctx context.Context, id *uuidpb.UUID) (*vzmgrpb.GetOrgFromVizierResponse, error) {
-	query := `SELECT org_id FROM vizier_cluster where id=$1`
+	query := `SELECT org_id, read_only FROM vizier_cluster where id=$1`
 
 	vzID := utils.UUIDFromProtoOrNil(id)
 	var orgID uuid.UUID
-	err := s.db.QueryRowxContext(ctx, query, vzID).Scan(&orgID)
+	var readOnly bool
+	err := s.db.QueryRowxContext(ctx, query, vzID).Scan(&orgID, &readOnly)
 	if err != nil {
 		return nil, err
 	}
-	return &vzmgrpb.GetOrgFromVizierResponse{OrgID: utils.ProtoFromUUID(orgID)}, nil
+	return &vzmgrpb.GetOrgFromVizierResponse{OrgID: utils.ProtoFromUUID(orgID), ReadOnly: readOnly}, nil
 }
@@ -310,7 +310,10 @@ func TestServer_GetVizierConnectionInfo(t *testing.T) {
 	token, err := srvutils.ParseToken(resp.Token, "key0", "vizier")
 	require.NoError(t, err)
 
-	assert.Equal(t, []string{"cluster"}, srvutils.GetScopes(token))
+	// The token carries the identity and scopes of the user that the request is made for.
+	assert.Equal(t, []string{"user"}, srvutils.GetScopes(token))
+	assert.Equal(t, "abcdef", srvutils.GetUserID(token))
+	assert.Equal(t, testAuthOrgID, srvutils.GetOrgID(token))
 }
 
 func TestServer_VizierConnectedHealthy(t *testing.T) {
@@ -618,6 +621,34 @@ func TestServer_UpdateOrInstallVizier(t *testing.T) {
 	require.NotNil(t, resp)
 }
 
+func TestServer_UpdateOrInstallVizier_ReadOnly(t *testing.T) {
+	mustLoadTestData(db)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Registering with a viewer key makes the cluster read-only.
+	userID := uuid.Must(uuid.NewV4())
+	s := controllers.New(db, "test", nil, mock_controllers.NewMockVzUpdater(ctrl))
+	clusterID, _, err := s.ProvisionOrClaimVizier(context.Background(), uuid.FromStringOrNil(testAuthOrgID), userID, "existing_cluster", "", true)
+	require.NoError(t, err)
+
+	info, err := s.GetVizierInfo(CreateTestContext(), utils.ProtoFromUUID(clusterID))
+	require.NoError(t, err)
+	assert.True(t, info.ReadOnly)
+
+	_, err = s.UpdateOrInstallVizier(CreateTestContext(), &cvmsgspb.UpdateOrInstallVizierRequest{
+		VizierID: utils.ProtoFromUUID(clusterID),
+		Version:  "1.3.0",
+	})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = s.UpdateVizierConfig(CreateTestContext(), &cvmsgspb.UpdateVizierConfigRequest{
+		VizierID: utils.ProtoFromUUID(clusterID),
+	})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
 func TestServer_GetViziersByShard(t *testing.T) {
 	mustLoadTestData(db)
 
@@ -752,7 +783,7 @@ func TestServer_ProvisionOrClaimVizier(t *testing.T) {
 	userID := uuid.Must(uuid.NewV4())
 
 	// This should select the first cluster with an empty UID that is disconnected.
-	clusterID, clusterName, err := s.ProvisionOrClaimVizier(context.Background(), uuid.FromStringOrNil(testAuthOrgID), userID, "my cluster", "")
+	clusterID, clusterName, err := s.ProvisionOrClaimVizier(context.Background(), uuid.FromStringOrNil(testAuthOrgID), userID, "my cluster", "", false)
 	require.NoError(t, err)
 	// Should select the disconnected cluster.
 	assert.Equal(t, testDisconnectedClusterEmptyUID, clusterID.String())
@@ -801,7 +832,7 @@ func TestServer_ProvisionOrClaimVizierWIthExistingUID(t *testing.T) {
 			userID := uuid.Must(uuid.NewV4())
 
 			// This should select the existing cluster with the same UID.
-			clusterID, clusterName, err := s.ProvisionOrClaimVizier(context.Background(), uuid.FromStringOrNil(testAuthOrgID), userID, "existing_cluster", test.inputName)
+			clusterID, clusterName, err := s.ProvisionOrClaimVizier(context.Background(), uuid.FromStringOrNil(testAuthOrgID), userID, "existing_cluster", test.inputName, false)
 			require.NoError(t, err)
 			// Should select the disconnected cluster.
 			assert.Equal(t, testExistingCluster, clusterID.String())
@@ -816,7 +847,7 @@ func TestServer_ProvisionOrClaimVizier_WithExistingActiveUID(t *testing.T) {
 	s := controllers.New(db, "test", nil, nil)
 	userID := uuid.Must(uuid.NewV4())
 	// This should select cause an error b/c we are trying to provision a cluster that is not disconnected.
-	clusterID, clusterName, err := s.ProvisionOrClaimVizier(context.Background(), uuid.FromStringOrNil(testAuthOrgID), userID, "my_other_cluster", "")
+	clusterID, clusterName, err := s.ProvisionOrClaimVizier(context.Background(), uuid.FromStringOrNil(testAuthOrgID), userID, "my_other_cluster", "", false)
 	assert.NotNil(t, err)
 	assert.Equal(t, vzerrors.ErrProvisionFailedVizierIsActive, err)
 	assert.Equal(t, uuid.Nil, clusterID)
@@ -829,7 +860,7 @@ func TestServer_ProvisionOrClaimVizier_WithNewCluster(t *testing.T) {
 	s := controllers.New(db, "test", nil, nil)
 	userID := uuid.Must(uuid.NewV4())
 	// This should select cause an error b/c we are trying to provision a cluster that is not disconnected.
-	clusterID, clusterName, err := s.ProvisionOrClaimVizier(context.Background(), uuid.FromStringOrNil(testNonAuthOrgID), userID, "my_other_cluster", "")
+	clusterID, clusterName, err := s.ProvisionOrClaimVizier(context.Background(), uuid.FromStringOrNil(testNonAuthOrgID), userID, "my_other_cluster", "", false)
 	require.NoError(t, err)
 	assert.NotEqual(t, uuid.Nil, clusterID)
 	// Some random name should get assigned by the nameGenerator.
@@ -843,7 +874,7 @@ func TestServer_ProvisionOrClaimVizier_WithExistingName(t *testing.T) {
 	userID := uuid.Must(uuid.NewV4())
 
 	// This should select the existing cluster with the same UID.
-	clusterID, clusterName, err := s.ProvisionOrClaimVizier(context.Background(), uuid.FromStringOrNil(testAuthOrgID), userID, "some_cluster", "test_cluster_1234\n")
+	clusterID, clusterName, err := s.ProvisionOrClaimVizier(context.Background(), uuid.FromStringOrNil(testAuthOrgID), userID, "some_cluster", "test_cluster_1234\n", false)
 	require.NoError(t, err)
 	// Should select the disconnected cluster.
 	assert.Equal(t, testDisconnectedClusterEmptyUID, clusterID.String())
@@ -26,6 +26,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"px.dev/pixie/src/cloud/shared/auditlog"
 	"px.dev/pixie/src/cloud/vzmgr/vzerrors"
 	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
 	"px.dev/pixie/src/utils"
@@ -33,21 +34,22 @@ import (
 
 // InfoFetcher fetches information about deployments using the key.
 type InfoFetcher interface {
-	FetchOrgUserIDUsingDeploymentKey(context.Context, string) (uuid.UUID, uuid.UUID, uuid.UUID, error)
+	UseDeploymentKey(context.Context, string) (*vzmgrpb.DeploymentKey, error)
 }
 
 // VizierProvisioner provisions a new Vizier.
 type VizierProvisioner interface {
 	// ProvisionVizier creates the vizier, with specified org_id, user_id, cluster_uid. Returns
 	// Cluster ID or error. If it already exists it will return the current cluster ID. Will return an error if the cluster is
-	// currently active (ie. Not disconnected).
-	ProvisionOrClaimVizier(context.Context, uuid.UUID, uuid.UUID, string, string) (uuid.UUID, string, error)
+	// currently active (ie. Not disconnected). The last argument marks the vizier as read-only.
+	ProvisionOrClaimVizier(context.Context, uuid.UUID, uuid.UUID, string, string, bool) (uuid.UUID, string, error)
 }
 
 // Service is the deployment service.
 type Service struct {
 	deploymentInfoFetcher InfoFetcher
 	vp                    VizierProvisioner
+	auditLog              auditlog.Recorder
 }
 
 // New creates a deployment service.
@@ -55,30 +57,67 @@ func New(dif InfoFetcher, vp VizierProvisioner) *Service {
 	return &Service{deploymentInfoFetcher: dif, vp: vp}
 }
 
+// WithAuditLog records the use of deploy keys and the clusters they register in the audit log.
+func (s *Service) WithAuditLog(r auditlog.Recorder) *Service {
+	s.auditLog = r
+	return s
+}
+
 // RegisterVizierDeployment will use the deployment key to generate or fetch the vizier key.
 func (s *Service) RegisterVizierDeployment(ctx context.Context, req *vzmgrpb.RegisterVizierDeploymentRequest) (*vzmgrpb.RegisterVizierDeploymentResponse, error) {
 	if len(req.K8sClusterUID) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "empty cluster UID is not allowed")
 	}
 	// Fetch the orgID and userID based on the deployment key.
-	orgID, userID, keyID, err := s.deploymentInfoFetcher.FetchOrgUserIDUsingDeploymentKey(ctx, req.DeploymentKey)
+	key, err := s.deploymentInfoFetcher.UseDeploymentKey(ctx, req.DeploymentKey)
 	if err != nil {
 		return nil, status.Error(codes.Unauthenticated, "invalid/unknown deployment key")
 	}
+	orgID := utils.UUIDFromProtoOrNil(key.OrgID)
+	userID := utils.UUIDFromProtoOrNil(key.UserID)
+	keyID := utils.UUIDFromProtoOrNil(key.ID)
 	// Now we know the org and user ID to use for deployment. The process is as follows:
 	// 1. Try to fetch a cluster with either an empty UID or one where the UID matches the one in the protobuf.
 	// 2. If the UID matches then return that cluster.
 	// 3. Otherwise, pick a cluster with no UID specified and claim it.
 	// 4. If no empty clusters exist then we create a new cluster.
-	clusterID, clusterName, err := s.vp.ProvisionOrClaimVizier(ctx, orgID, userID, req.K8sClusterUID, req.K8sClusterName)
+	// Clusters registered with a viewer key are read-only.
+	clusterID, clusterName, err := s.vp.ProvisionOrClaimVizier(ctx, orgID, userID, req.K8sClusterUID, req.K8sClusterName, key.Viewer)
 	if err != nil {
 		return nil, vzerrors.ToGRPCError(err)
 	}
 
-	log.WithField("orgID", orgID).WithField("keyID", keyID).WithField("clusterID", clusterID).WithField("clusterName", clusterName).Info("Successfully registered Vizier deployment")
+	log.WithField("orgID", orgID).WithField("keyID", keyID).WithField("clusterID", clusterID).WithField("clusterName", clusterName).WithField("readOnly", key.Viewer).Info("Successfully registered Vizier deployment")
+	s.recordRegistration(ctx, orgID, userID, keyID, clusterID, clusterName, key.Viewer)
 
 	return &vzmgrpb.RegisterVizierDeploymentResponse{
 		VizierID:   utils.ProtoFromUUID(clusterID),
 		VizierName: clusterName,
 	}, nil
 }
+
+func (s *Service) recordRegistration(ctx context.Context, orgID, userID, keyID, clusterID uuid.UUID, clusterName string, readOnly bool) {
+	if s.auditLog == nil {
+		return
+	}
+	// The caller is the service relaying the registration, so attribute the events to the owner
+	// of the deploy key instead.
+	details := map[string]string{
+		"deployKeyID": keyID.String(),
+		"clusterID":   clusterID.String(),
+		"clusterName": clusterName,
+	}
+	if readOnly {
+		details["readOnly"] = "true"
+	}
+	for _, e := range []*auditlog.Event{
+		auditlog.NewEvent(ctx, auditlog.ActionDeployKeyUsed, keyID.String(), nil),
+		auditlog.NewEvent(ctx, auditlog.ActionClusterRegistered, clusterID.String(), nil),
+	} {
+		e.OrgID = orgID
+		e.ActorID = userID.String()
+		e.ActorType = auditlog.ActorTypeUser
+		e.Details = details
+		s.auditLog.Record(ctx, e)
+	}
+}
@@ -29,6 +29,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"px.dev/pixie/src/cloud/shared/auditlog"
 	"px.dev/pixie/src/cloud/vzmgr/deployment"
 	"px.dev/pixie/src/cloud/vzmgr/vzerrors"
 	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
@@ -42,22 +43,30 @@ var (
 
 	testValidClusterID = uuid.FromStringOrNil("553e4567-e89b-12d3-a456-426655440000")
 
-	testValidDeploymentKey = "883e4567-e89b-12d3-a456-426655440000"
+	testValidDeploymentKey  = "883e4567-e89b-12d3-a456-426655440000"
+	testViewerDeploymentKey = "983e4567-e89b-12d3-a456-426655440000"
 )
 
 type fakeDF struct{}
 
-func (f *fakeDF) FetchOrgUserIDUsingDeploymentKey(ctx context.Context, key string) (uuid.UUID, uuid.UUID, uuid.UUID, error) {
-	if key == testValidDeploymentKey {
-		return testOrgID, testUserID, testKeyID, nil
+func (f *fakeDF) UseDeploymentKey(ctx context.Context, key string) (*vzmgrpb.DeploymentKey, error) {
+	if key == testValidDeploymentKey || key == testViewerDeploymentKey {
+		return &vzmgrpb.DeploymentKey{
+			ID:     utils.ProtoFromUUID(testKeyID),
+			OrgID:  utils.ProtoFromUUID(testOrgID),
+			UserID: utils.ProtoFromUUID(testUserID),
+			Viewer: key == testViewerDeploymentKey,
+		}, nil
 	}
-	return uuid.Nil, uuid.Nil, uuid.Nil, vzerrors.ErrDeploymentKeyNotFound
+	return nil, vzerrors.ErrDeploymentKeyNotFound
 }
 
 type fakeProvisioner struct {
+	readOnly bool
 }
 
-func (f *fakeProvisioner) ProvisionOrClaimVizier(ctx context.Context, orgID uuid.UUID, userID uuid.UUID, clusterUID string, clusterName string) (uuid.UUID, string, error) {
+func (f *fakeProvisioner) ProvisionOrClaimVizier(ctx context.Context, orgID uuid.UUID, userID uuid.UUID, clusterUID string, clusterName string, readOnly bool) (uuid.UUID, string, error) {
+	f.readOnly = readOnly
 	if testOrgID == orgID && testUserID == userID && clusterUID == "cluster1" && clusterName == "test" {
 		return testValidClusterID, clusterName, nil
 	}
@@ -81,6 +90,70 @@ func TestService_RegisterVizierDeployment(t *testing.T) {
 	assert.Equal(t, testValidClusterID, utils.UUIDFromProtoOrNil(resp.VizierID))
 }
 
+func TestService_RegisterVizierDeployment_ViewerKey(t *testing.T) {
+	vp := &fakeProvisioner{}
+	svc := deployment.New(&fakeDF{}, vp)
+
+	ctx := context.Background()
+	_, err := svc.RegisterVizierDeployment(ctx, &vzmgrpb.RegisterVizierDeploymentRequest{
+		K8sClusterUID:  "cluster1",
+		DeploymentKey:  testValidDeploymentKey,
+		K8sClusterName: "test",
+	})
+	require.NoError(t, err)
+	assert.False(t, vp.readOnly)
+
+	// Clusters registered with a viewer key are read-only.
+	resp, err := svc.RegisterVizierDeployment(ctx, &vzmgrpb.RegisterVizierDeploymentRequest{
+		K8sClusterUID:  "cluster1",
+		DeploymentKey:  testViewerDeploymentKey,
+		K8sClusterName: "test",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, testValidClusterID, utils.UUIDFromProtoOrNil(resp.VizierID))
+	assert.True(t, vp.readOnly)
+}
+
+type fakeAuditLog struct {
+	events []*auditlog.Event
+}
+
+func (f *fakeAuditLog) Record(ctx context.Context, e *auditlog.Event) {
+	f.events = append(f.events, e)
+}
+
+func TestService_RegisterVizierDeployment_AuditLog(t *testing.T) {
+	al := &fakeAuditLog{}
+	svc := deployment.New(&fakeDF{}, &fakeProvisioner{}).WithAuditLog(al)
+
+	ctx := context.Background()
+	_, err := svc.RegisterVizierDeployment(ctx, &vzmgrpb.RegisterVizierDeploymentRequest{
+		K8sClusterUID:  "cluster1",
+		DeploymentKey:  testValidDeploymentKey,
+		K8sClusterName: "test",
+	})
+	require.NoError(t, err)
+
+	require.Len(t, al.events, 2)
+	assert.Equal(t, auditlog.ActionDeployKeyUsed, al.events[0].Action)
+	assert.Equal(t, testKeyID.String(), al.events[0].ResourceID)
+	assert.Equal(t, auditlog.ActionClusterRegistered, al.events[1].Action)
+	assert.Equal(t, testValidClusterID.String(), al.events[1].ResourceID)
+	for _, e := range al.events {
+		assert.Equal(t, testOrgID, e.OrgID)
+		assert.Equal(t, testUserID.String(), e.ActorID)
+		assert.Equal(t, "test", e.Details["clusterName"])
+	}
+
+	// Failed registrations aren't attributed to an org, so they aren't recorded.
+	_, err = svc.RegisterVizierDeployment(ctx, &vzmgrpb.RegisterVizierDeploymentRequest{
+		K8sClusterUID: "cluster2",
+		DeploymentKey: "a bad key",
+	})
+	require.Error(t, err)
+	assert.Len(t, al.events, 2)
+}
+
 func TestService_RegisterVizierDeployment_ClusterAlreadyRunning(t *testing.T) {
 	svc := deployment.New(&fakeDF{}, &fakeProvisioner{})
 
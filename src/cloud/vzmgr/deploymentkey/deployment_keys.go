@@ -68,17 +68,29 @@ func (s *Service) Create(ctx context.Context, req *vzmgrpb.CreateDeploymentKeyRe
 		return nil, status.Error(codes.InvalidArgument, "invalid user id format")
 	}
 
+	var expiresAt *time.Time
+	if req.ExpiresAt != nil {
+		t, err := types.TimestampFromProto(req.ExpiresAt)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid expiration time")
+		}
+		if !t.After(time.Now()) {
+			return nil, status.Error(codes.InvalidArgument, "expiration time must be in the future")
+		}
+		expiresAt = &t
+	}
+
 	var id uuid.UUID
 	var ts time.Time
-	query := `INSERT INTO vizier_deployment_keys(org_id, user_id, hashed_key, encrypted_key, description)
-                VALUES($1, $2, sha256($3), PGP_SYM_ENCRYPT($3::text, $4::text), $5)
+	query := `INSERT INTO vizier_deployment_keys(org_id, user_id, hashed_key, encrypted_key, description, expires_at, viewer)
+                VALUES($1, $2, sha256($3), PGP_SYM_ENCRYPT($3::text, $4::text), $5, $6, $7)
               RETURNING id, created_at`
 	keyID, err := uuid.NewV4()
 	if err != nil {
 		return nil, err
 	}
 	key := deployKeyPrefix + keyID.String()
-	err = s.db.QueryRowxContext(ctx, query, orgID, userID, key, s.dbKey, req.Desc).
+	err = s.db.QueryRowxContext(ctx, query, orgID, userID, key, s.dbKey, req.Desc, expiresAt, req.Viewer).
 		Scan(&id, &ts)
 	if err != nil {
 		log.WithError(err).Error("Failed to insert deployment keys")
@@ -90,6 +102,8 @@ func (s *Service) Create(ctx context.Context, req *vzmgrpb.CreateDeploymentKeyRe
 		ID:        utils.ProtoFromUUID(id),
 		Key:       key,
 		CreatedAt: tp,
+		ExpiresAt: req.ExpiresAt,
+		Viewer:    req.Viewer,
 	}, nil
 }
 
@@ -101,7 +115,7 @@ func (s *Service) List(ctx context.Context, req *vzmgrpb.ListDeploymentKeyReques
 	}
 
 	// Return all clusters when the OrgID matches.
-	query := `SELECT id, org_id, user_id, created_at, description
+	query := `SELECT id, org_id, user_id, created_at, description, expires_at, last_used_at, viewer
                 FROM vizier_deployment_keys
                 WHERE org_id=$1
                 ORDER BY created_at`
@@ -122,18 +136,23 @@ func (s *Service) List(ctx context.Context, req *vzmgrpb.ListDeploymentKeyReques
 		var userID uuid.UUID
 		var createdAt time.Time
 		var desc string
-		err = rows.Scan(&id, &orgID, &userID, &createdAt, &desc)
+		var expiresAt, lastUsedAt *time.Time
+		var viewer bool
+		err = rows.Scan(&id, &orgID, &userID, &createdAt, &desc, &expiresAt, &lastUsedAt, &viewer)
 		if err != nil {
 			log.WithError(err).Error("Failed to read data from postgres")
 			return nil, status.Error(codes.Internal, "failed to read data")
 		}
 		tProto, _ := types.TimestampProto(createdAt)
 		keys = append(keys, &vzmgrpb.DeploymentKeyMetadata{
-			ID:        utils.ProtoFromUUIDStrOrNil(id),
-			OrgID:     utils.ProtoFromUUID(orgID),
-			UserID:    utils.ProtoFromUUID(userID),
-			CreatedAt: tProto,
-			Desc:      desc,
+			ID:         utils.ProtoFromUUIDStrOrNil(id),
+			OrgID:      utils.ProtoFromUUID(orgID),
+			UserID:     utils.ProtoFromUUID(userID),
+			CreatedAt:  tProto,
+			Desc:       desc,
+			ExpiresAt:  timestampProtoOrNil(expiresAt),
+			LastUsedAt: timestampProtoOrNil(lastUsedAt),
+			Viewer:     viewer,
 		})
 	}
 	return &vzmgrpb.ListDeploymentKeyResponse{
@@ -156,23 +175,29 @@ func (s *Service) Get(ctx context.Context, req *vzmgrpb.GetDeploymentKeyRequest)
 	var key string
 	var createdAt time.Time
 	var desc string
-	query := `SELECT CONVERT_FROM(PGP_SYM_DECRYPT(encrypted_key, $3::text)::bytea, 'UTF8'), user_id, created_at, description
+	var expiresAt, lastUsedAt *time.Time
+	var viewer bool
+	query := `SELECT CONVERT_FROM(PGP_SYM_DECRYPT(encrypted_key, $3::text)::bytea, 'UTF8'), user_id, created_at, description,
+                expires_at, last_used_at, viewer
                 FROM vizier_deployment_keys
                 WHERE org_id=$1 AND id=$2`
 	err = s.db.QueryRowxContext(ctx, query, orgID, tokenID, s.dbKey).
-		Scan(&key, &userID, &createdAt, &desc)
+		Scan(&key, &userID, &createdAt, &desc, &expiresAt, &lastUsedAt, &viewer)
 	if err != nil {
 		return nil, status.Error(codes.NotFound, "No such deployment key")
 	}
 
 	createdAtProto, _ := types.TimestampProto(createdAt)
 	return &vzmgrpb.GetDeploymentKeyResponse{Key: &vzmgrpb.DeploymentKey{
-		ID:        req.ID,
-		OrgID:     utils.ProtoFromUUID(orgID),
-		UserID:    utils.ProtoFromUUID(userID),
-		Key:       key,
-		CreatedAt: createdAtProto,
-		Desc:      desc,
+		ID:         req.ID,
+		OrgID:      utils.ProtoFromUUID(orgID),
+		UserID:     utils.ProtoFromUUID(userID),
+		Key:        key,
+		CreatedAt:  createdAtProto,
+		Desc:       desc,
+		ExpiresAt:  timestampProtoOrNil(expiresAt),
+		LastUsedAt: timestampProtoOrNil(lastUsedAt),
+		Viewer:     viewer,
 	}}, nil
 }
 
@@ -208,25 +233,26 @@ func (s *Service) Delete(ctx context.Context, req *vzmgrpb.DeleteDeploymentKeyRe
 	return &types.Empty{}, nil
 }
 
-// FetchOrgUserIDUsingDeploymentKey gets the org and user ID based on the deployment key.
-func (s *Service) FetchOrgUserIDUsingDeploymentKey(ctx context.Context, key string) (uuid.UUID, uuid.UUID, uuid.UUID, error) {
+// UseDeploymentKey gets the deployment key that a cluster is registering with. Expired keys are
+// rejected, and the key's last use is recorded.
+func (s *Service) UseDeploymentKey(ctx context.Context, key string) (*vzmgrpb.DeploymentKey, error) {
 	resp, err := s.fetchDeploymentKeyUsingKeyFromDB(ctx, key)
 	if err != nil {
-		return uuid.Nil, uuid.Nil, uuid.Nil, err
-	}
-	oid, err := utils.UUIDFromProto(resp.OrgID)
-	if err != nil {
-		return uuid.Nil, uuid.Nil, uuid.Nil, err
+		return nil, err
 	}
-	uid, err := utils.UUIDFromProto(resp.UserID)
-	if err != nil {
-		return uuid.Nil, uuid.Nil, uuid.Nil, err
+	if resp.ExpiresAt != nil {
+		expiresAt, err := types.TimestampFromProto(resp.ExpiresAt)
+		if err != nil || !expiresAt.After(time.Now()) {
+			return nil, vzerrors.ErrDeploymentKeyExpired
+		}
 	}
-	keyID, err := utils.UUIDFromProto(resp.ID)
-	if err != nil {
-		return uuid.Nil, uuid.Nil, uuid.Nil, err
+
+	query := `UPDATE vizier_deployment_keys SET last_used_at=NOW() WHERE id=$1`
+	if _, err := s.db.ExecContext(ctx, query, utils.UUIDFromProtoOrNil(resp.ID)); err != nil {
+		// Failing to track usage shouldn't stop the key from working.
+		log.WithError(err).Error("Failed to update deployment key last used time")
 	}
-	return oid, uid, keyID, nil
+	return resp, nil
 }
 
 // LookupDeploymentKey gets the complete Deployment key information using just the Key.
@@ -251,11 +277,13 @@ func (s *Service) fetchDeploymentKeyUsingKeyFromDB(ctx context.Context, key stri
 	var userID uuid.UUID
 	var createdAt time.Time
 	var desc string
-	query := `SELECT id, org_id, user_id, created_at, description
+	var expiresAt, lastUsedAt *time.Time
+	var viewer bool
+	query := `SELECT id, org_id, user_id, created_at, description, expires_at, last_used_at, viewer
                 FROM vizier_deployment_keys
                 WHERE hashed_key=sha256($1) AND PGP_SYM_DECRYPT(encrypted_key::bytea, $2::text)::bytea=$1`
 	err := s.db.QueryRowxContext(ctx, query, key, s.dbKey).
-		Scan(&id, &orgID, &userID, &createdAt, &desc)
+		Scan(&id, &orgID, &userID, &createdAt, &desc, &expiresAt, &lastUsedAt, &viewer)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, vzerrors.ErrDeploymentKeyNotFound
@@ -265,11 +293,22 @@ func (s *Service) fetchDeploymentKeyUsingKeyFromDB(ctx context.Context, key stri
 
 	createdAtProto, _ := types.TimestampProto(createdAt)
 	return &vzmgrpb.DeploymentKey{
-		ID:        utils.ProtoFromUUID(id),
-		OrgID:     utils.ProtoFromUUID(orgID),
-		UserID:    utils.ProtoFromUUID(userID),
-		Key:       key,
-		CreatedAt: createdAtProto,
-		Desc:      desc,
+		ID:         utils.ProtoFromUUID(id),
+		OrgID:      utils.ProtoFromUUID(orgID),
+		UserID:     utils.ProtoFromUUID(userID),
+		Key:        key,
+		CreatedAt:  createdAtProto,
+		Desc:       desc,
+		ExpiresAt:  timestampProtoOrNil(expiresAt),
+		LastUsedAt: timestampProtoOrNil(lastUsedAt),
+		Viewer:     viewer,
 	}, nil
 }
+
+func timestampProtoOrNil(t *time.Time) *types.Timestamp {
+	if t == nil {
+		return nil
+	}
+	tp, _ := types.TimestampProto(*t)
+	return tp
+}
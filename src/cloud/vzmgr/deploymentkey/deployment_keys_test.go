@@ -433,7 +433,7 @@ func TestDeploymentKeyService_Delete_NonExistentKey(t *testing.T) {
 	}
 }
 
-func TestService_FetchOrgUserIDUsingDeploymentKey(t *testing.T) {
+func TestService_UseDeploymentKey(t *testing.T) {
 	mustLoadTestData(db)
 	tests := []struct {
 		name string
@@ -454,16 +454,85 @@ func TestService_FetchOrgUserIDUsingDeploymentKey(t *testing.T) {
 			ctx := test.ctx
 			svc := New(db, testDBKey)
 
-			orgID, userID, keyID, err := svc.FetchOrgUserIDUsingDeploymentKey(ctx, "px-dep-key1")
+			key, err := svc.UseDeploymentKey(ctx, "px-dep-key1")
 			require.NoError(t, err)
-			assert.Equal(t, testAuthOrgID, orgID)
-			assert.Equal(t, testAuthUserID, userID)
-			assert.Equal(t, testKey1ID, keyID)
+			assert.Equal(t, testAuthOrgID, utils.UUIDFromProtoOrNil(key.OrgID))
+			assert.Equal(t, testAuthUserID, utils.UUIDFromProtoOrNil(key.UserID))
+			assert.Equal(t, testKey1ID, utils.UUIDFromProtoOrNil(key.ID))
+			assert.False(t, key.Viewer)
 		})
 	}
 }
 
-func TestService_FetchOrgUserIDUsingDeploymentKey_OldKeys(t *testing.T) {
+func TestService_UseDeploymentKey_Expiring(t *testing.T) {
+	mustLoadTestData(db)
+
+	ctx := createTestContext()
+	svc := New(db, testDBKey)
+
+	expiresAt, err := types.TimestampProto(time.Now().Add(time.Hour).Round(time.Second))
+	require.NoError(t, err)
+	resp, err := svc.Create(ctx, &vzmgrpb.CreateDeploymentKeyRequest{
+		OrgID:     utils.ProtoFromUUID(testAuthOrgID),
+		UserID:    utils.ProtoFromUUID(testAuthUserID),
+		Desc:      "expiring key",
+		ExpiresAt: expiresAt,
+	})
+	require.NoError(t, err)
+
+	key, err := svc.UseDeploymentKey(ctx, resp.Key)
+	require.NoError(t, err)
+	keyID := utils.UUIDFromProtoOrNil(key.ID)
+	assert.Equal(t, utils.UUIDFromProtoOrNil(resp.ID), keyID)
+
+	// The key's use should be recorded.
+	getResp, err := svc.Get(ctx, &vzmgrpb.GetDeploymentKeyRequest{
+		ID:    resp.ID,
+		OrgID: utils.ProtoFromUUID(testAuthOrgID),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, expiresAt.Seconds, getResp.Key.ExpiresAt.Seconds)
+	assert.NotNil(t, getResp.Key.LastUsedAt)
+
+	db.MustExec(`UPDATE vizier_deployment_keys SET expires_at=NOW() - INTERVAL '1 hour' WHERE id=$1`, keyID)
+	_, err = svc.UseDeploymentKey(ctx, resp.Key)
+	assert.Equal(t, vzerrors.ErrDeploymentKeyExpired, err)
+
+	_, err = svc.Create(ctx, &vzmgrpb.CreateDeploymentKeyRequest{
+		OrgID:     utils.ProtoFromUUID(testAuthOrgID),
+		UserID:    utils.ProtoFromUUID(testAuthUserID),
+		ExpiresAt: types.TimestampNow(),
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestService_UseDeploymentKey_Viewer(t *testing.T) {
+	mustLoadTestData(db)
+
+	ctx := createTestContext()
+	svc := New(db, testDBKey)
+
+	resp, err := svc.Create(ctx, &vzmgrpb.CreateDeploymentKeyRequest{
+		OrgID:  utils.ProtoFromUUID(testAuthOrgID),
+		UserID: utils.ProtoFromUUID(testAuthUserID),
+		Desc:   "demo key",
+		Viewer: true,
+	})
+	require.NoError(t, err)
+	assert.True(t, resp.Viewer)
+
+	key, err := svc.UseDeploymentKey(ctx, resp.Key)
+	require.NoError(t, err)
+	assert.True(t, key.Viewer)
+
+	listResp, err := svc.List(ctx, &vzmgrpb.ListDeploymentKeyRequest{OrgID: utils.ProtoFromUUID(testAuthOrgID)})
+	require.NoError(t, err)
+	for _, k := range listResp.Keys {
+		assert.Equal(t, utils.UUIDFromProtoOrNil(k.ID) == utils.UUIDFromProtoOrNil(resp.ID), k.Viewer)
+	}
+}
+
+func TestService_UseDeploymentKey_OldKeys(t *testing.T) {
 	// Tests to make sure key without the prefix 'px-dep-' work.
 	mustLoadTestData(db)
 	tests := []struct {
@@ -485,16 +554,16 @@ func TestService_FetchOrgUserIDUsingDeploymentKey_OldKeys(t *testing.T) {
 			ctx := test.ctx
 			svc := New(db, testDBKey)
 
-			orgID, userID, keyID, err := svc.FetchOrgUserIDUsingDeploymentKey(ctx, "key1")
+			key, err := svc.UseDeploymentKey(ctx, "key1")
 			require.NoError(t, err)
-			assert.Equal(t, testAuthOrgID, orgID)
-			assert.Equal(t, testAuthUserID, userID)
-			assert.Equal(t, testKey1ID, keyID)
+			assert.Equal(t, testAuthOrgID, utils.UUIDFromProtoOrNil(key.OrgID))
+			assert.Equal(t, testAuthUserID, utils.UUIDFromProtoOrNil(key.UserID))
+			assert.Equal(t, testKey1ID, utils.UUIDFromProtoOrNil(key.ID))
 		})
 	}
 }
 
-func TestService_FetchOrgUserIDUsingDeploymentKey_BadKey(t *testing.T) {
+func TestService_UseDeploymentKey_BadKey(t *testing.T) {
 	mustLoadTestData(db)
 	tests := []struct {
 		name string
@@ -515,12 +584,10 @@ func TestService_FetchOrgUserIDUsingDeploymentKey_BadKey(t *testing.T) {
 			ctx := test.ctx
 			svc := New(db, testDBKey)
 
-			orgID, userID, keyID, err := svc.FetchOrgUserIDUsingDeploymentKey(ctx, "some rando key that does not exist")
+			key, err := svc.UseDeploymentKey(ctx, "some rando key that does not exist")
 			assert.NotNil(t, err)
 			assert.Equal(t, vzerrors.ErrDeploymentKeyNotFound, err)
-			assert.Equal(t, uuid.Nil, orgID)
-			assert.Equal(t, uuid.Nil, userID)
-			assert.Equal(t, uuid.Nil, keyID)
+			assert.Nil(t, key)
 		})
 	}
 }
@@ -0,0 +1,363 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package fleet
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
+	"github.com/jackc/pgx"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
+	"px.dev/pixie/src/utils"
+)
+
+const (
+	// See https://www.postgresql.org/docs/current/errcodes-appendix.html
+	// Code for `unique_violation`
+	uniqueViolation = "23505"
+)
+
+// Label keys and values follow the same format as Kubernetes label values. Values may also be
+// empty.
+var labelRegex = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_./]{0,253}[A-Za-z0-9])?$`)
+
+// Service is used to manage fleets and the cluster labels that they select on.
+type Service struct {
+	db *sqlx.DB
+}
+
+// New creates a new Service.
+func New(db *sqlx.DB) *Service {
+	return &Service{
+		db: db,
+	}
+}
+
+func validateLabels(labels map[string]string) error {
+	for k, v := range labels {
+		if !labelRegex.MatchString(k) {
+			return status.Errorf(codes.InvalidArgument, "invalid label key '%s'", k)
+		}
+		if v != "" && !labelRegex.MatchString(v) {
+			return status.Errorf(codes.InvalidArgument, "invalid label value '%s'", v)
+		}
+	}
+	return nil
+}
+
+// CreateFleet creates a fleet in the org.
+func (s *Service) CreateFleet(ctx context.Context, req *vzmgrpb.CreateFleetRequest) (*vzmgrpb.Fleet, error) {
+	orgID, err := utils.UUIDFromProto(req.OrgID)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid org id format")
+	}
+	if req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "fleet name cannot be empty")
+	}
+	if err := validateLabels(req.Selector); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to create fleet")
+	}
+	defer tx.Rollback()
+
+	var id uuid.UUID
+	query := `INSERT INTO vizier_fleets(org_id, name, description) VALUES($1, $2, $3) RETURNING id`
+	err = tx.QueryRowxContext(ctx, query, orgID, req.Name, req.Desc).Scan(&id)
+	if err != nil {
+		if e, ok := err.(pgx.PgError); ok && e.Code == uniqueViolation {
+			return nil, status.Errorf(codes.AlreadyExists, "fleet '%s' already exists", req.Name)
+		}
+		log.WithError(err).Error("Failed to insert fleet")
+		return nil, status.Error(codes.Internal, "failed to create fleet")
+	}
+
+	query = `INSERT INTO vizier_fleet_selectors(fleet_id, label_key, label_value) VALUES($1, $2, $3)`
+	for k, v := range req.Selector {
+		if _, err := tx.ExecContext(ctx, query, id, k, v); err != nil {
+			log.WithError(err).Error("Failed to insert fleet selector")
+			return nil, status.Error(codes.Internal, "failed to create fleet")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.WithError(err).Error("Failed to commit fleet")
+		return nil, status.Error(codes.Internal, "failed to create fleet")
+	}
+
+	return s.getFleet(ctx, `WHERE org_id=$1 AND id=$2`, orgID, id)
+}
+
+// GetFleet returns the fleet specified by ID or name, if it's owned by the org.
+func (s *Service) GetFleet(ctx context.Context, req *vzmgrpb.GetFleetRequest) (*vzmgrpb.Fleet, error) {
+	orgID, err := utils.UUIDFromProto(req.OrgID)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid org id format")
+	}
+
+	if req.Name != "" {
+		return s.getFleet(ctx, `WHERE org_id=$1 AND name=$2`, orgID, req.Name)
+	}
+	id, err := utils.UUIDFromProto(req.ID)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid fleet id format")
+	}
+	return s.getFleet(ctx, `WHERE org_id=$1 AND id=$2`, orgID, id)
+}
+
+// ListFleets returns all the fleets belonging to an org.
+func (s *Service) ListFleets(ctx context.Context, req *vzmgrpb.ListFleetsRequest) (*vzmgrpb.ListFleetsResponse, error) {
+	orgID, err := utils.UUIDFromProto(req.OrgID)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid org id format")
+	}
+
+	fleets, err := s.queryFleets(ctx, `WHERE org_id=$1 ORDER BY name`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return &vzmgrpb.ListFleetsResponse{
+		Fleets: fleets,
+	}, nil
+}
+
+// DeleteFleet removes the fleet. The clusters in the fleet and their labels are unaffected.
+func (s *Service) DeleteFleet(ctx context.Context, req *vzmgrpb.DeleteFleetRequest) (*types.Empty, error) {
+	orgID, err := utils.UUIDFromProto(req.OrgID)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid org id format")
+	}
+	id, err := utils.UUIDFromProto(req.ID)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid fleet id format")
+	}
+
+	query := `DELETE FROM vizier_fleets WHERE org_id=$1 AND id=$2`
+	res, err := s.db.ExecContext(ctx, query, orgID, id)
+	if err != nil {
+		log.WithError(err).Error("Failed to delete fleet")
+		return nil, status.Error(codes.Internal, "failed to delete fleet")
+	}
+	c, err := res.RowsAffected()
+	if err != nil {
+		log.WithError(err).Error("Failed to delete fleet")
+		return nil, status.Error(codes.Internal, "failed to delete fleet")
+	}
+	if c == 0 {
+		return nil, status.Error(codes.NotFound, "no such fleet to delete")
+	}
+
+	return &types.Empty{}, nil
+}
+
+// UpdateClusterLabels replaces the labels on a cluster owned by the org.
+func (s *Service) UpdateClusterLabels(ctx context.Context, req *vzmgrpb.UpdateClusterLabelsRequest) (*types.Empty, error) {
+	orgID, clusterID, err := s.validateOrgOwnsCluster(ctx, req.OrgID, req.ClusterID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateLabels(req.Labels); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to update cluster labels")
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM vizier_cluster_labels WHERE vizier_cluster_id=$1`
+	if _, err := tx.ExecContext(ctx, query, clusterID); err != nil {
+		log.WithError(err).Error("Failed to delete cluster labels")
+		return nil, status.Error(codes.Internal, "failed to update cluster labels")
+	}
+	query = `INSERT INTO vizier_cluster_labels(vizier_cluster_id, label_key, label_value) VALUES($1, $2, $3)`
+	for k, v := range req.Labels {
+		if _, err := tx.ExecContext(ctx, query, clusterID, k, v); err != nil {
+			log.WithError(err).Error("Failed to insert cluster label")
+			return nil, status.Error(codes.Internal, "failed to update cluster labels")
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.WithError(err).Error("Failed to commit cluster labels")
+		return nil, status.Error(codes.Internal, "failed to update cluster labels")
+	}
+
+	log.WithField("org_id", orgID).WithField("cluster_id", clusterID).Info("Updated cluster labels")
+	return &types.Empty{}, nil
+}
+
+// GetClusterLabels returns the labels on a cluster owned by the org.
+func (s *Service) GetClusterLabels(ctx context.Context, req *vzmgrpb.GetClusterLabelsRequest) (*vzmgrpb.GetClusterLabelsResponse, error) {
+	_, clusterID, err := s.validateOrgOwnsCluster(ctx, req.OrgID, req.ClusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT label_key, label_value FROM vizier_cluster_labels WHERE vizier_cluster_id=$1`
+	labels, err := s.queryLabels(ctx, query, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	return &vzmgrpb.GetClusterLabelsResponse{
+		Labels: labels,
+	}, nil
+}
+
+func (s *Service) validateOrgOwnsCluster(ctx context.Context, orgIDPb *uuidpb.UUID, clusterIDPb *uuidpb.UUID) (uuid.UUID, uuid.UUID, error) {
+	orgID, err := utils.UUIDFromProto(orgIDPb)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, status.Error(codes.InvalidArgument, "invalid org id format")
+	}
+	clusterID, err := utils.UUIDFromProto(clusterIDPb)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, status.Error(codes.InvalidArgument, "invalid cluster id format")
+	}
+
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM vizier_cluster WHERE id=$1 AND org_id=$2)`
+	if err := s.db.QueryRowxContext(ctx, query, clusterID, orgID).Scan(&exists); err != nil {
+		log.WithError(err).Error("Failed to look up cluster")
+		return uuid.Nil, uuid.Nil, status.Error(codes.Internal, "failed to look up cluster")
+	}
+	if !exists {
+		return uuid.Nil, uuid.Nil, status.Error(codes.NotFound, "no such cluster")
+	}
+	return orgID, clusterID, nil
+}
+
+func (s *Service) getFleet(ctx context.Context, where string, args ...interface{}) (*vzmgrpb.Fleet, error) {
+	fleets, err := s.queryFleets(ctx, where, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(fleets) == 0 {
+		return nil, status.Error(codes.NotFound, "no such fleet")
+	}
+	return fleets[0], nil
+}
+
+// queryFleets fetches the fleets matching the where clause, along with their selectors and the
+// clusters they contain.
+func (s *Service) queryFleets(ctx context.Context, where string, args ...interface{}) ([]*vzmgrpb.Fleet, error) {
+	query := `SELECT id, org_id, name, description, created_at FROM vizier_fleets ` + where
+	rows, err := s.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		log.WithError(err).Error("Failed to fetch fleets")
+		return nil, status.Error(codes.Internal, "failed to fetch fleets")
+	}
+	defer rows.Close()
+
+	type fleetRow struct {
+		id, orgID  uuid.UUID
+		name, desc string
+		createdAt  time.Time
+	}
+	var fleetRows []fleetRow
+	for rows.Next() {
+		var r fleetRow
+		if err := rows.Scan(&r.id, &r.orgID, &r.name, &r.desc, &r.createdAt); err != nil {
+			log.WithError(err).Error("Failed to read data from postgres")
+			return nil, status.Error(codes.Internal, "failed to read data")
+		}
+		fleetRows = append(fleetRows, r)
+	}
+	rows.Close()
+
+	fleets := make([]*vzmgrpb.Fleet, len(fleetRows))
+	for i, r := range fleetRows {
+		selector, err := s.queryLabels(ctx, `SELECT label_key, label_value FROM vizier_fleet_selectors WHERE fleet_id=$1`, r.id)
+		if err != nil {
+			return nil, err
+		}
+		clusterIDs, err := s.fleetClusters(ctx, r.orgID, r.id)
+		if err != nil {
+			return nil, err
+		}
+		createdAt, _ := types.TimestampProto(r.createdAt)
+		fleets[i] = &vzmgrpb.Fleet{
+			ID:         utils.ProtoFromUUID(r.id),
+			OrgID:      utils.ProtoFromUUID(r.orgID),
+			Name:       r.name,
+			Desc:       r.desc,
+			Selector:   selector,
+			CreatedAt:  createdAt,
+			ClusterIDs: clusterIDs,
+		}
+	}
+	return fleets, nil
+}
+
+// fleetClusters returns the clusters in the org which have every label in the fleet's selector.
+func (s *Service) fleetClusters(ctx context.Context, orgID uuid.UUID, fleetID uuid.UUID) ([]*uuidpb.UUID, error) {
+	query := `SELECT c.id FROM vizier_cluster AS c
+                WHERE c.org_id=$1 AND NOT EXISTS (
+                  SELECT 1 FROM vizier_fleet_selectors AS s
+                    WHERE s.fleet_id=$2 AND NOT EXISTS (
+                      SELECT 1 FROM vizier_cluster_labels AS l
+                        WHERE l.vizier_cluster_id=c.id AND l.label_key=s.label_key AND l.label_value=s.label_value))
+                ORDER BY c.created_at`
+	var ids []uuid.UUID
+	if err := s.db.SelectContext(ctx, &ids, query, orgID, fleetID); err != nil {
+		log.WithError(err).Error("Failed to fetch fleet clusters")
+		return nil, status.Error(codes.Internal, "failed to fetch fleet clusters")
+	}
+
+	clusterIDs := make([]*uuidpb.UUID, len(ids))
+	for i, id := range ids {
+		clusterIDs[i] = utils.ProtoFromUUID(id)
+	}
+	return clusterIDs, nil
+}
+
+func (s *Service) queryLabels(ctx context.Context, query string, id uuid.UUID) (map[string]string, error) {
+	rows, err := s.db.QueryxContext(ctx, query, id)
+	if err != nil {
+		log.WithError(err).Error("Failed to fetch labels")
+		return nil, status.Error(codes.Internal, "failed to fetch labels")
+	}
+	defer rows.Close()
+
+	labels := make(map[string]string)
+	for rows.Next() {
+		var k, v string
+		if err := rows.Scan(&k, &v); err != nil {
+			log.WithError(err).Error("Failed to read data from postgres")
+			return nil, status.Error(codes.Internal, "failed to read data")
+		}
+		labels[k] = v
+	}
+	return labels, nil
+}
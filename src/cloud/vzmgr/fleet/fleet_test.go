@@ -0,0 +1,287 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package fleet
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	bindata "github.com/golang-migrate/migrate/source/go_bindata"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/cloud/vzmgr/schema"
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
+	"px.dev/pixie/src/shared/services/pgtest"
+	"px.dev/pixie/src/utils"
+)
+
+var (
+	testOrgID      = uuid.FromStringOrNil("223e4567-e89b-12d3-a456-426655440000")
+	testOtherOrgID = uuid.FromStringOrNil("223e4567-e89b-12d3-a456-426655440001")
+
+	testProdCluster1ID = uuid.FromStringOrNil("123e4567-e89b-12d3-a456-426655440000")
+	testProdCluster2ID = uuid.FromStringOrNil("123e4567-e89b-12d3-a456-426655440001")
+	testDevClusterID   = uuid.FromStringOrNil("123e4567-e89b-12d3-a456-426655440002")
+	testOtherClusterID = uuid.FromStringOrNil("123e4567-e89b-12d3-a456-426655440003")
+)
+
+func TestMain(m *testing.M) {
+	err := testMain(m)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Got error: %v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+var db *sqlx.DB
+
+func testMain(m *testing.M) error {
+	s := bindata.Resource(schema.AssetNames(), schema.Asset)
+	testDB, teardown, err := pgtest.SetupTestDB(s)
+	if err != nil {
+		return fmt.Errorf("failed to start test database: %w", err)
+	}
+
+	defer teardown()
+	db = testDB
+
+	if c := m.Run(); c != 0 {
+		return fmt.Errorf("some tests failed with code: %d", c)
+	}
+	return nil
+}
+
+func mustLoadTestData(db *sqlx.DB) {
+	db.MustExec(`DELETE FROM vizier_fleets`)
+	db.MustExec(`DELETE FROM vizier_cluster_labels`)
+	db.MustExec(`DELETE FROM vizier_cluster`)
+
+	insertCluster := `INSERT INTO vizier_cluster(org_id, id, cluster_uid, cluster_name) VALUES ($1, $2, $3, $4)`
+	db.MustExec(insertCluster, testOrgID, testProdCluster1ID, "k8s-1", "prod-us")
+	db.MustExec(insertCluster, testOrgID, testProdCluster2ID, "k8s-2", "prod-eu")
+	db.MustExec(insertCluster, testOrgID, testDevClusterID, "k8s-3", "dev")
+	db.MustExec(insertCluster, testOtherOrgID, testOtherClusterID, "k8s-4", "prod-other")
+
+	insertLabel := `INSERT INTO vizier_cluster_labels(vizier_cluster_id, label_key, label_value) VALUES ($1, $2, $3)`
+	db.MustExec(insertLabel, testProdCluster1ID, "env", "prod")
+	db.MustExec(insertLabel, testProdCluster1ID, "region", "us")
+	db.MustExec(insertLabel, testProdCluster2ID, "env", "prod")
+	db.MustExec(insertLabel, testProdCluster2ID, "region", "eu")
+	db.MustExec(insertLabel, testDevClusterID, "env", "dev")
+	db.MustExec(insertLabel, testOtherClusterID, "env", "prod")
+}
+
+func clusterIDs(ids []*uuidpb.UUID) []uuid.UUID {
+	res := make([]uuid.UUID, len(ids))
+	for i, id := range ids {
+		res[i] = utils.UUIDFromProtoOrNil(id)
+	}
+	return res
+}
+
+func TestService_CreateFleet(t *testing.T) {
+	tests := []struct {
+		name             string
+		selector         map[string]string
+		expectedClusters []uuid.UUID
+	}{
+		{
+			name:             "single label",
+			selector:         map[string]string{"env": "prod"},
+			expectedClusters: []uuid.UUID{testProdCluster1ID, testProdCluster2ID},
+		},
+		{
+			name:             "multiple labels",
+			selector:         map[string]string{"env": "prod", "region": "eu"},
+			expectedClusters: []uuid.UUID{testProdCluster2ID},
+		},
+		{
+			name:             "no labels",
+			selector:         map[string]string{},
+			expectedClusters: []uuid.UUID{testProdCluster1ID, testProdCluster2ID, testDevClusterID},
+		},
+		{
+			name:             "no matches",
+			selector:         map[string]string{"env": "staging"},
+			expectedClusters: []uuid.UUID{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mustLoadTestData(db)
+
+			svc := New(db)
+			resp, err := svc.CreateFleet(context.Background(), &vzmgrpb.CreateFleetRequest{
+				OrgID:    utils.ProtoFromUUID(testOrgID),
+				Name:     "my-fleet",
+				Desc:     "a fleet",
+				Selector: test.selector,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, "my-fleet", resp.Name)
+			assert.Equal(t, "a fleet", resp.Desc)
+			assert.Equal(t, test.selector, resp.Selector)
+			assert.Equal(t, test.expectedClusters, clusterIDs(resp.ClusterIDs))
+		})
+	}
+}
+
+func TestService_CreateFleet_Errors(t *testing.T) {
+	mustLoadTestData(db)
+	svc := New(db)
+
+	_, err := svc.CreateFleet(context.Background(), &vzmgrpb.CreateFleetRequest{
+		OrgID: utils.ProtoFromUUID(testOrgID),
+		Name:  "prod",
+	})
+	require.NoError(t, err)
+
+	_, err = svc.CreateFleet(context.Background(), &vzmgrpb.CreateFleetRequest{
+		OrgID: utils.ProtoFromUUID(testOrgID),
+		Name:  "prod",
+	})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	_, err = svc.CreateFleet(context.Background(), &vzmgrpb.CreateFleetRequest{
+		OrgID:    utils.ProtoFromUUID(testOrgID),
+		Name:     "bad",
+		Selector: map[string]string{"env prod": "yes"},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = svc.CreateFleet(context.Background(), &vzmgrpb.CreateFleetRequest{
+		OrgID: utils.ProtoFromUUID(testOrgID),
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestService_GetListDeleteFleet(t *testing.T) {
+	mustLoadTestData(db)
+	svc := New(db)
+	ctx := context.Background()
+
+	prod, err := svc.CreateFleet(ctx, &vzmgrpb.CreateFleetRequest{
+		OrgID:    utils.ProtoFromUUID(testOrgID),
+		Name:     "prod",
+		Selector: map[string]string{"env": "prod"},
+	})
+	require.NoError(t, err)
+	_, err = svc.CreateFleet(ctx, &vzmgrpb.CreateFleetRequest{
+		OrgID:    utils.ProtoFromUUID(testOrgID),
+		Name:     "dev",
+		Selector: map[string]string{"env": "dev"},
+	})
+	require.NoError(t, err)
+
+	byName, err := svc.GetFleet(ctx, &vzmgrpb.GetFleetRequest{
+		OrgID: utils.ProtoFromUUID(testOrgID),
+		Name:  "prod",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, prod, byName)
+
+	byID, err := svc.GetFleet(ctx, &vzmgrpb.GetFleetRequest{
+		OrgID: utils.ProtoFromUUID(testOrgID),
+		ID:    prod.ID,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, prod, byID)
+
+	// Fleets in other orgs aren't visible.
+	_, err = svc.GetFleet(ctx, &vzmgrpb.GetFleetRequest{
+		OrgID: utils.ProtoFromUUID(testOtherOrgID),
+		ID:    prod.ID,
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	list, err := svc.ListFleets(ctx, &vzmgrpb.ListFleetsRequest{OrgID: utils.ProtoFromUUID(testOrgID)})
+	require.NoError(t, err)
+	require.Len(t, list.Fleets, 2)
+	assert.Equal(t, "dev", list.Fleets[0].Name)
+	assert.Equal(t, []uuid.UUID{testDevClusterID}, clusterIDs(list.Fleets[0].ClusterIDs))
+	assert.Equal(t, "prod", list.Fleets[1].Name)
+
+	_, err = svc.DeleteFleet(ctx, &vzmgrpb.DeleteFleetRequest{
+		OrgID: utils.ProtoFromUUID(testOtherOrgID),
+		ID:    prod.ID,
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = svc.DeleteFleet(ctx, &vzmgrpb.DeleteFleetRequest{
+		OrgID: utils.ProtoFromUUID(testOrgID),
+		ID:    prod.ID,
+	})
+	require.NoError(t, err)
+
+	list, err = svc.ListFleets(ctx, &vzmgrpb.ListFleetsRequest{OrgID: utils.ProtoFromUUID(testOrgID)})
+	require.NoError(t, err)
+	require.Len(t, list.Fleets, 1)
+	assert.Equal(t, "dev", list.Fleets[0].Name)
+}
+
+func TestService_UpdateClusterLabels(t *testing.T) {
+	mustLoadTestData(db)
+	svc := New(db)
+	ctx := context.Background()
+
+	fleet, err := svc.CreateFleet(ctx, &vzmgrpb.CreateFleetRequest{
+		OrgID:    utils.ProtoFromUUID(testOrgID),
+		Name:     "prod",
+		Selector: map[string]string{"env": "prod"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{testProdCluster1ID, testProdCluster2ID}, clusterIDs(fleet.ClusterIDs))
+
+	// Promote the dev cluster to prod.
+	_, err = svc.UpdateClusterLabels(ctx, &vzmgrpb.UpdateClusterLabelsRequest{
+		OrgID:     utils.ProtoFromUUID(testOrgID),
+		ClusterID: utils.ProtoFromUUID(testDevClusterID),
+		Labels:    map[string]string{"env": "prod", "team": "payments"},
+	})
+	require.NoError(t, err)
+
+	labels, err := svc.GetClusterLabels(ctx, &vzmgrpb.GetClusterLabelsRequest{
+		OrgID:     utils.ProtoFromUUID(testOrgID),
+		ClusterID: utils.ProtoFromUUID(testDevClusterID),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod", "team": "payments"}, labels.Labels)
+
+	fleet, err = svc.GetFleet(ctx, &vzmgrpb.GetFleetRequest{OrgID: utils.ProtoFromUUID(testOrgID), ID: fleet.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{testProdCluster1ID, testProdCluster2ID, testDevClusterID}, clusterIDs(fleet.ClusterIDs))
+
+	// Clusters in other orgs can't be labeled.
+	_, err = svc.UpdateClusterLabels(ctx, &vzmgrpb.UpdateClusterLabelsRequest{
+		OrgID:     utils.ProtoFromUUID(testOrgID),
+		ClusterID: utils.ProtoFromUUID(testOtherClusterID),
+		Labels:    map[string]string{"env": "dev"},
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -0,0 +1,5 @@
+ALTER TABLE vizier_deployment_keys
+  DROP COLUMN expires_at;
+
+ALTER TABLE vizier_deployment_keys
+  DROP COLUMN last_used_at;
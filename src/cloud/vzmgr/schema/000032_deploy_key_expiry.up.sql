@@ -0,0 +1,5 @@
+ALTER TABLE vizier_deployment_keys
+  ADD COLUMN expires_at TIMESTAMP;
+
+ALTER TABLE vizier_deployment_keys
+  ADD COLUMN last_used_at TIMESTAMP;
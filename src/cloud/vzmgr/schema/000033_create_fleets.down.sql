@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS vizier_fleet_selectors;
+DROP TABLE IF EXISTS vizier_fleets;
+DROP TABLE IF EXISTS vizier_cluster_labels;
@@ -0,0 +1,32 @@
+-- Labels attached to a cluster, for example "env=prod". Fleets select clusters by their labels.
+CREATE TABLE vizier_cluster_labels (
+  vizier_cluster_id UUID NOT NULL,
+  label_key varchar(255) NOT NULL,
+  label_value varchar(255) NOT NULL,
+
+  PRIMARY KEY(vizier_cluster_id, label_key),
+  FOREIGN KEY(vizier_cluster_id) REFERENCES vizier_cluster(id) ON DELETE CASCADE
+);
+
+-- A fleet is a named group of clusters in an org.
+CREATE TABLE vizier_fleets (
+  id UUID UNIQUE DEFAULT uuid_generate_v4(),
+  org_id UUID NOT NULL,
+  name varchar(255) NOT NULL,
+  description varchar(1000) NOT NULL DEFAULT '',
+  created_at TIMESTAMP DEFAULT NOW(),
+
+  PRIMARY KEY(id),
+  UNIQUE(org_id, name)
+);
+
+-- The labels a cluster must have to be part of a fleet. A fleet without any selectors contains
+-- every cluster in the org.
+CREATE TABLE vizier_fleet_selectors (
+  fleet_id UUID NOT NULL,
+  label_key varchar(255) NOT NULL,
+  label_value varchar(255) NOT NULL,
+
+  PRIMARY KEY(fleet_id, label_key),
+  FOREIGN KEY(fleet_id) REFERENCES vizier_fleets(id) ON DELETE CASCADE
+);
@@ -0,0 +1,5 @@
+ALTER TABLE vizier_deployment_keys
+  DROP COLUMN viewer;
+
+ALTER TABLE vizier_cluster
+  DROP COLUMN read_only;
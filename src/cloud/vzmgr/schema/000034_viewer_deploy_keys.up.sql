@@ -0,0 +1,6 @@
+ALTER TABLE vizier_deployment_keys
+  ADD COLUMN viewer BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Clusters registered with a viewer deploy key reject mutating operations.
+ALTER TABLE vizier_cluster
+  ADD COLUMN read_only BOOLEAN NOT NULL DEFAULT FALSE;
@@ -28,6 +28,8 @@ import (
 var (
 	// ErrDeploymentKeyNotFound is used when specified key cannot be located.
 	ErrDeploymentKeyNotFound = errors.New("invalid deployment key")
+	// ErrDeploymentKeyExpired is used when the specified key has expired.
+	ErrDeploymentKeyExpired = errors.New("deployment key has expired")
 	// ErrProvisionFailedVizierIsActive errors when the specified vizier is active and not disconnected.
 	ErrProvisionFailedVizierIsActive = errors.New("provisioning failed because vizier with specified UID is already active")
 	// ErrInternalDB is used for internal errors related to DB.
@@ -41,6 +43,8 @@ func ToGRPCError(err error) error {
 		return status.Error(codes.ResourceExhausted, err.Error())
 	case ErrDeploymentKeyNotFound:
 		return status.Error(codes.NotFound, err.Error())
+	case ErrDeploymentKeyExpired:
+		return status.Error(codes.Unauthenticated, err.Error())
 	case ErrInternalDB:
 		return status.Error(codes.Internal, err.Error())
 	}
@@ -23,6 +23,7 @@ import (
 	"net/http"
 	_ "net/http/pprof"
 
+	"px.dev/pixie/src/cloud/shared/auditlog"
 	"px.dev/pixie/src/cloud/shared/messages"
 
 	bindata "github.com/golang-migrate/migrate/source/go_bindata"
@@ -38,6 +39,7 @@ import (
 	"px.dev/pixie/src/cloud/vzmgr/controllers"
 	"px.dev/pixie/src/cloud/vzmgr/deployment"
 	"px.dev/pixie/src/cloud/vzmgr/deploymentkey"
+	"px.dev/pixie/src/cloud/vzmgr/fleet"
 	"px.dev/pixie/src/cloud/vzmgr/schema"
 	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
 	"px.dev/pixie/src/shared/services"
@@ -153,13 +155,15 @@ func main() {
 
 	c := controllers.New(db, dbKey, nc, updater)
 	dks := deploymentkey.New(db, dbKey)
-	ds := deployment.New(dks, c)
+	ds := deployment.New(dks, c).WithAuditLog(auditlog.NewNATSRecorder(nc))
+	fs := fleet.New(db)
 
 	sm := controllers.NewStatusMonitor(db)
 	defer sm.Stop()
 	vzmgrpb.RegisterVZMgrServiceServer(s.GRPCServer(), c)
 	vzmgrpb.RegisterVZDeploymentKeyServiceServer(s.GRPCServer(), dks)
 	vzmgrpb.RegisterVZDeploymentServiceServer(s.GRPCServer(), ds)
+	vzmgrpb.RegisterVZFleetServiceServer(s.GRPCServer(), fs)
 
 	var mdr *controllers.MetadataReader
 	go func() {
@@ -21,6 +21,13 @@
 #include <nats/adapters/libuv.h>
 #include <nats/nats.h>
 
+#include "src/common/base/base.h"
+
+DEFINE_string(nats_user, gflags::StringFromEnv("PL_NATS_USER", ""),
+              "The user to authenticate to NATS as, if NATS requires authorization.");
+DEFINE_string(nats_password, gflags::StringFromEnv("PL_NATS_PASSWORD", ""),
+              "The password of the NATS user.");
+
 namespace px {
 namespace event {
 
@@ -45,6 +52,10 @@ Status NATSConnectorBase::ConnectBase(Dispatcher* base_dispatcher) {
                                       tls_config_->tls_key.c_str());
   }
 
+  if (!FLAGS_nats_user.empty()) {
+    natsOptions_SetUserInfo(nats_opts, FLAGS_nats_user.c_str(), FLAGS_nats_password.c_str());
+  }
+
   natsOptions_SetMaxReconnect(nats_opts, -1);
   natsOptions_SetDisconnectedCB(nats_opts, DisconnectedCB, this);
   natsOptions_SetReconnectedCB(nats_opts, ReconnectedCB, this);
@@ -176,6 +176,13 @@ class JSONObjectBuilder {
     writer_.Uint64(value);
   }
 
+  // Writes a key-value pair where value is already JSON, which is written as is.
+  void WriteKVRawJSON(std::string_view key, std::string_view json) {
+    DCHECK(!object_ended_);
+    writer_.String(key.data(), key.size());
+    writer_.RawValue(json.data(), json.size(), rapidjson::kObjectType);
+  }
+
   // Writes a key-value pair where value is an array of strings.
   void WriteKV(std::string_view key, VectorView<std::string> value) {
     DCHECK(!object_ended_);
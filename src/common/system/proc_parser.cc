@@ -596,6 +596,31 @@ Status ProcParser::ParseFromKeyValueFile(
   return Status::OK();
 }
 
+absl::flat_hash_map<std::string, std::string> ProcParser::GetPIDEnvVars(
+    int32_t pid, const absl::flat_hash_set<std::string>& names) const {
+  absl::flat_hash_map<std::string, std::string> env_vars;
+  if (names.empty()) {
+    return env_vars;
+  }
+
+  const auto fpath = ProcPidPath(pid, "environ");
+  std::ifstream ifs(fpath);
+  if (!ifs) {
+    return env_vars;
+  }
+
+  // The variables are separated by null characters.
+  std::string entry;
+  while (std::getline(ifs, entry, '\0')) {
+    std::vector<std::string_view> name_value = absl::StrSplit(entry, absl::MaxSplits('=', 1));
+    if (name_value.size() != 2 || !names.contains(name_value[0])) {
+      continue;
+    }
+    env_vars[name_value[0]] = std::string(name_value[1]);
+  }
+  return env_vars;
+}
+
 std::string ProcParser::GetPIDCmdline(int32_t pid) const {
   const auto fpath = ProcPidPath(pid, "cmdline");
   std::ifstream ifs(fpath);
@@ -201,6 +201,16 @@ class ProcParser {
    */
   std::string GetPIDCmdline(int32_t pid) const;
 
+  /**
+   * Gets the selected environment variables of a given pid.
+   * @param pid is the pid for which we want the environment variables.
+   * @param names are the names of the environment variables to return.
+   * @return A map from name to value, for the selected variables that are set. Empty map implies
+   * none were set, or we failed to read the file.
+   */
+  absl::flat_hash_map<std::string, std::string> GetPIDEnvVars(
+      int32_t pid, const absl::flat_hash_set<std::string>& names) const;
+
   /**
    * Returns the /proc/<pid>/exe
    */
@@ -40,6 +40,7 @@ using ::testing::Contains;
 using ::testing::ElementsAre;
 using ::testing::IsEmpty;
 using ::testing::MatchesRegex;
+using ::testing::Pair;
 using ::testing::Return;
 using ::testing::ReturnArg;
 using ::testing::ReturnRef;
@@ -283,6 +284,16 @@ TEST_F(ProcParserTest, read_pid_cmdline) {
               parser_->GetPIDCmdline(123));
 }
 
+TEST_F(ProcParserTest, read_pid_env_vars) {
+  PX_SET_FOR_SCOPE(FLAGS_proc_path, GetPathToTestDataFile("testdata/proc"));
+  EXPECT_THAT(parser_->GetPIDEnvVars(123, {"APP_VERSION", "GIT_SHA", "EMPTY", "MISSING"}),
+              UnorderedElementsAre(Pair("APP_VERSION", "1.4.2"), Pair("GIT_SHA", "deadbeef"),
+                                   Pair("EMPTY", "")));
+  EXPECT_THAT(parser_->GetPIDEnvVars(123, {}), IsEmpty());
+  // PID 456 has no environ file.
+  EXPECT_THAT(parser_->GetPIDEnvVars(456, {"APP_VERSION"}), IsEmpty());
+}
+
 TEST_F(ProcParserTest, read_pid_metadata_null) {
   PX_SET_FOR_SCOPE(FLAGS_proc_path, GetPathToTestDataFile("testdata/proc"));
   EXPECT_THAT("/usr/lib/at-spi2-core/at-spi2-registryd --use-gnome-session",
@@ -153,7 +153,7 @@ func (w *workloadImpl) Close() error {
 			RestConfig: w.clusterCtx.RestConfig(),
 			Timeout:    5 * time.Minute,
 		}
-		return od.DeleteNamespace()
+		return od.DeleteNamespace(context.Background())
 	}
 	w.namespacesToDelete = make(map[string]bool)
 	return nil
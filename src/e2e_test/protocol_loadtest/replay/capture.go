@@ -0,0 +1,138 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package replay replays captured protocol flows against test services. A flow is the traffic of
+// one client connection: the bytes the client sent, and the responses it waited for in between.
+// Each flow is annotated with the records that Stirling should trace for it, so that the tables
+// traced during a replay can be checked against the manifest written by the replayer.
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Protocol is the protocol of a flow. It selects the target the flow is replayed against.
+type Protocol string
+
+const (
+	// ProtocolHTTP is HTTP/1.x.
+	ProtocolHTTP Protocol = "http"
+	// ProtocolGRPC is gRPC over cleartext HTTP/2.
+	ProtocolGRPC Protocol = "grpc"
+	// ProtocolMySQL is the MySQL client/server protocol.
+	ProtocolMySQL Protocol = "mysql"
+	// ProtocolKafka is the Kafka wire protocol.
+	ProtocolKafka Protocol = "kafka"
+)
+
+// Direction is the direction of a message, from the perspective of the client.
+type Direction string
+
+const (
+	// DirectionSend messages are written to the connection.
+	DirectionSend Direction = "send"
+	// DirectionRecv messages are read from the connection before the flow continues. The captured
+	// data is only used for its length, since the responses of the test service may differ.
+	DirectionRecv Direction = "recv"
+)
+
+// Message is a chunk of data in a flow.
+type Message struct {
+	Direction Direction `json:"direction"`
+	// Data is the raw message. For text protocols, Text can be set instead.
+	Data []byte `json:"data,omitempty"`
+	Text string `json:"text,omitempty"`
+	// DelayMS is how long after the previous message this one was captured, in milliseconds.
+	DelayMS int64 `json:"delay_ms,omitempty"`
+}
+
+func (m *Message) bytes() []byte {
+	if len(m.Data) > 0 {
+		return m.Data
+	}
+	return []byte(m.Text)
+}
+
+func (m *Message) delay() time.Duration {
+	return time.Duration(m.DelayMS) * time.Millisecond
+}
+
+// Record is a record that Stirling should trace for a flow, such as a row of http_events. The
+// attributes are the columns that identify it, for example {"req_path": "/healthz"}.
+type Record struct {
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// Flow is the captured traffic of a single client connection.
+type Flow struct {
+	Name     string     `json:"name"`
+	Protocol Protocol   `json:"protocol"`
+	Messages []*Message `json:"messages"`
+	// Records are the records that Stirling should trace each time the flow is replayed.
+	Records []*Record `json:"records,omitempty"`
+}
+
+// Capture is a set of captured flows.
+type Capture struct {
+	Flows []*Flow `json:"flows"`
+}
+
+// Validate checks that the capture can be replayed.
+func (c *Capture) Validate() error {
+	if len(c.Flows) == 0 {
+		return fmt.Errorf("capture has no flows")
+	}
+	for i, f := range c.Flows {
+		if f.Name == "" {
+			return fmt.Errorf("flow %d has no name", i)
+		}
+		switch f.Protocol {
+		case ProtocolHTTP, ProtocolGRPC, ProtocolMySQL, ProtocolKafka:
+		default:
+			return fmt.Errorf("flow %s has unknown protocol %q", f.Name, f.Protocol)
+		}
+		for j, m := range f.Messages {
+			if m.Direction != DirectionSend && m.Direction != DirectionRecv {
+				return fmt.Errorf("message %d of flow %s has unknown direction %q", j, f.Name, m.Direction)
+			}
+			if len(m.bytes()) == 0 {
+				return fmt.Errorf("message %d of flow %s is empty", j, f.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// LoadCapture reads and validates a capture from a JSON file.
+func LoadCapture(path string) (*Capture, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Capture{}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("failed to parse capture %s: %w", path, err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,172 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package replay_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/e2e_test/protocol_loadtest/replay"
+)
+
+// startHTTPServer starts a server that responds to every request on a connection with "ok", and
+// returns its address and the paths it was requested at.
+func startHTTPServer(t *testing.T) (string, chan string) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { lis.Close() })
+
+	paths := make(chan string, 100)
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					req, err := http.ReadRequest(r)
+					if err != nil {
+						return
+					}
+					paths <- req.URL.Path
+					if _, err := conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok")); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return lis.Addr().String(), paths
+}
+
+func TestLoadCapture(t *testing.T) {
+	c, err := replay.LoadCapture("testdata/http_capture.json")
+	require.NoError(t, err)
+	require.Len(t, c.Flows, 1)
+	assert.Equal(t, replay.ProtocolHTTP, c.Flows[0].Protocol)
+	assert.Len(t, c.Flows[0].Messages, 4)
+	assert.Len(t, c.Flows[0].Records, 2)
+}
+
+func TestCapture_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		capture *replay.Capture
+	}{
+		{
+			name:    "no flows",
+			capture: &replay.Capture{},
+		},
+		{
+			name: "unknown protocol",
+			capture: &replay.Capture{Flows: []*replay.Flow{
+				{Name: "f", Protocol: "smtp"},
+			}},
+		},
+		{
+			name: "empty message",
+			capture: &replay.Capture{Flows: []*replay.Flow{
+				{Name: "f", Protocol: replay.ProtocolHTTP, Messages: []*replay.Message{{Direction: replay.DirectionSend}}},
+			}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Error(t, test.capture.Validate())
+		})
+	}
+}
+
+func TestReplayer_Run(t *testing.T) {
+	addr, paths := startHTTPServer(t)
+	c, err := replay.LoadCapture("testdata/http_capture.json")
+	require.NoError(t, err)
+
+	r, err := replay.New(c, &replay.Config{
+		Targets:     map[replay.Protocol]string{replay.ProtocolHTTP: addr},
+		Speed:       1,
+		Concurrency: 2,
+		Loops:       3,
+	})
+	require.NoError(t, err)
+	m, err := r.Run(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, m.Flows, 3)
+	for _, f := range m.Flows {
+		assert.Empty(t, f.Error)
+		assert.NotEmpty(t, f.LocalAddr)
+		assert.Len(t, f.Records, 2)
+		// The captured delay between the requests is kept.
+		assert.GreaterOrEqual(t, f.End.Sub(f.Start), 10*time.Millisecond)
+	}
+	assert.Equal(t, &replay.ProtocolSummary{
+		Flows:           3,
+		ExpectedRecords: 6,
+		BytesSent:       3 * int64(len("GET /healthz HTTP/1.1\r\nHost: localhost\r\n\r\n")+len("GET /readyz HTTP/1.1\r\nHost: localhost\r\n\r\n")),
+		BytesRecv:       3 * 2 * int64(len("HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok")),
+	}, m.Summary[replay.ProtocolHTTP])
+
+	close(paths)
+	count := map[string]int{}
+	for p := range paths {
+		count[p]++
+	}
+	assert.Equal(t, map[string]int{"/healthz": 3, "/readyz": 3}, count)
+}
+
+func TestReplayer_FailedFlow(t *testing.T) {
+	// Nothing listens on the target, so the flow can't connect.
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	lis.Close()
+
+	c, err := replay.LoadCapture("testdata/http_capture.json")
+	require.NoError(t, err)
+	r, err := replay.New(c, &replay.Config{
+		Targets: map[replay.Protocol]string{replay.ProtocolHTTP: addr},
+	})
+	require.NoError(t, err)
+	m, err := r.Run(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, m.Flows, 1)
+	assert.NotEmpty(t, m.Flows[0].Error)
+	assert.Empty(t, m.Flows[0].Records)
+	assert.Equal(t, 1, m.Summary[replay.ProtocolHTTP].FailedFlows)
+	assert.Equal(t, 0, m.Summary[replay.ProtocolHTTP].ExpectedRecords)
+}
+
+func TestNew_MissingTarget(t *testing.T) {
+	c, err := replay.LoadCapture("testdata/http_capture.json")
+	require.NoError(t, err)
+	_, err = replay.New(c, &replay.Config{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,305 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultDialTimeout = 5 * time.Second
+	defaultReadTimeout = 5 * time.Second
+	// recvIdleTimeout is how long to wait for more of a response once some of it has been read.
+	// Responses of the test services don't have to match the length of the captured ones.
+	recvIdleTimeout = 100 * time.Millisecond
+)
+
+// Config configures how a capture is replayed.
+type Config struct {
+	// Targets are the addresses, host:port, that the flows of each protocol are replayed against.
+	Targets map[Protocol]string
+	// Speed scales the delays between the messages of a flow: 2 replays twice as fast as the
+	// capture. If it is 0, messages are sent without any delay.
+	Speed float64
+	// FlowsPerSecond limits how many flows are started per second. If it is 0, flows are started
+	// as soon as a connection is free.
+	FlowsPerSecond float64
+	// Concurrency is the number of flows replayed at the same time, each on its own connection.
+	Concurrency int
+	// Loops is the number of times each flow is replayed.
+	Loops int
+
+	DialTimeout time.Duration
+	ReadTimeout time.Duration
+}
+
+// Replayer replays a capture against the test services.
+type Replayer struct {
+	capture *Capture
+	cfg     *Config
+	limiter *rate.Limiter
+}
+
+// New creates a replayer for the capture. Every protocol in the capture needs a target.
+func New(capture *Capture, cfg *Config) (*Replayer, error) {
+	if err := capture.Validate(); err != nil {
+		return nil, err
+	}
+	for _, f := range capture.Flows {
+		if cfg.Targets[f.Protocol] == "" {
+			return nil, fmt.Errorf("no target for %s flow %s", f.Protocol, f.Name)
+		}
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.Loops <= 0 {
+		cfg.Loops = 1
+	}
+	if cfg.Speed < 0 {
+		return nil, errors.New("speed cannot be negative")
+	}
+	if cfg.DialTimeout == 0 {
+		cfg.DialTimeout = defaultDialTimeout
+	}
+	if cfg.ReadTimeout == 0 {
+		cfg.ReadTimeout = defaultReadTimeout
+	}
+	r := &Replayer{capture: capture, cfg: cfg}
+	if cfg.FlowsPerSecond > 0 {
+		r.limiter = rate.NewLimiter(rate.Limit(cfg.FlowsPerSecond), 1)
+	}
+	return r, nil
+}
+
+type job struct {
+	loop int
+	flow *Flow
+}
+
+// Run replays every flow of the capture, and returns the manifest of what was sent. A flow that
+// fails is recorded in the manifest, and doesn't stop the others. Run only returns an error if
+// the context is cancelled.
+func (r *Replayer) Run(ctx context.Context) (*Manifest, error) {
+	m := &Manifest{Start: time.Now()}
+	jobs := make(chan job)
+	results := make(chan *FlowResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < r.cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				results <- r.replayFlow(ctx, j.loop, j.flow)
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for loop := 0; loop < r.cfg.Loops; loop++ {
+			for _, f := range r.capture.Flows {
+				if r.limiter != nil {
+					if err := r.limiter.Wait(ctx); err != nil {
+						return
+					}
+				}
+				select {
+				case jobs <- job{loop: loop, flow: f}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	for res := range results {
+		m.Flows = append(m.Flows, res)
+	}
+	m.End = time.Now()
+	m.summarize()
+	return m, ctx.Err()
+}
+
+func (r *Replayer) sleep(ctx context.Context, d time.Duration) error {
+	if r.cfg.Speed == 0 || d == 0 {
+		return nil
+	}
+	t := time.NewTimer(time.Duration(float64(d) / r.cfg.Speed))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Replayer) replayFlow(ctx context.Context, loop int, f *Flow) *FlowResult {
+	res := &FlowResult{
+		Flow:     f.Name,
+		Protocol: f.Protocol,
+		Loop:     loop,
+		Target:   r.cfg.Targets[f.Protocol],
+		Start:    time.Now(),
+	}
+	err := r.runFlow(ctx, f, res)
+	res.End = time.Now()
+	if err != nil {
+		res.Error = err.Error()
+	} else {
+		res.Records = f.Records
+	}
+	return res
+}
+
+func (r *Replayer) runFlow(ctx context.Context, f *Flow, res *FlowResult) error {
+	d := net.Dialer{Timeout: r.cfg.DialTimeout}
+	conn, err := d.DialContext(ctx, "tcp", res.Target)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	res.LocalAddr = conn.LocalAddr().String()
+
+	buf := make([]byte, 64*1024)
+	for i, msg := range f.Messages {
+		if err := r.sleep(ctx, msg.delay()); err != nil {
+			return err
+		}
+		data := msg.bytes()
+		switch msg.Direction {
+		case DirectionSend:
+			n, err := conn.Write(data)
+			res.BytesSent += int64(n)
+			if err != nil {
+				return fmt.Errorf("failed to send message %d: %w", i, err)
+			}
+		case DirectionRecv:
+			n, err := r.recv(conn, buf, len(data))
+			res.BytesRecv += int64(n)
+			if err != nil {
+				return fmt.Errorf("failed to receive message %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// recv reads up to expected bytes. It waits up to the read timeout for the response to start,
+// then returns once the connection has been idle for a short while.
+func (r *Replayer) recv(conn net.Conn, buf []byte, expected int) (int, error) {
+	total := 0
+	timeout := r.cfg.ReadTimeout
+	for total < expected {
+		if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+			return total, err
+		}
+		n, err := conn.Read(buf)
+		total += n
+		if err != nil {
+			var netErr net.Error
+			if total > 0 && errors.As(err, &netErr) && netErr.Timeout() {
+				return total, nil
+			}
+			return total, err
+		}
+		timeout = recvIdleTimeout
+	}
+	return total, nil
+}
+
+// FlowResult is a single replay of a flow.
+type FlowResult struct {
+	Flow     string   `json:"flow"`
+	Protocol Protocol `json:"protocol"`
+	Loop     int      `json:"loop"`
+	Target   string   `json:"target"`
+	// LocalAddr is the client address of the connection, which identifies it in the traced tables.
+	LocalAddr string    `json:"local_addr,omitempty"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	BytesSent int64     `json:"bytes_sent"`
+	BytesRecv int64     `json:"bytes_recv"`
+	// Records are the records that Stirling should have traced. They are only set if the flow
+	// was replayed without errors.
+	Records []*Record `json:"records,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// ProtocolSummary is the totals of the flows of a protocol.
+type ProtocolSummary struct {
+	Flows           int   `json:"flows"`
+	FailedFlows     int   `json:"failed_flows"`
+	ExpectedRecords int   `json:"expected_records"`
+	BytesSent       int64 `json:"bytes_sent"`
+	BytesRecv       int64 `json:"bytes_recv"`
+}
+
+// Manifest is the ground truth of a replay.
+type Manifest struct {
+	Start   time.Time                     `json:"start"`
+	End     time.Time                     `json:"end"`
+	Flows   []*FlowResult                 `json:"flows"`
+	Summary map[Protocol]*ProtocolSummary `json:"summary"`
+}
+
+func (m *Manifest) summarize() {
+	sort.Slice(m.Flows, func(i, j int) bool {
+		return m.Flows[i].Start.Before(m.Flows[j].Start)
+	})
+	m.Summary = make(map[Protocol]*ProtocolSummary)
+	for _, f := range m.Flows {
+		s, ok := m.Summary[f.Protocol]
+		if !ok {
+			s = &ProtocolSummary{}
+			m.Summary[f.Protocol] = s
+		}
+		s.Flows++
+		if f.Error != "" {
+			s.FailedFlows++
+		}
+		s.ExpectedRecords += len(f.Records)
+		s.BytesSent += f.BytesSent
+		s.BytesRecv += f.BytesRecv
+	}
+}
+
+// WriteFile writes the manifest as JSON.
+func (m *Manifest) WriteFile(path string) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"px.dev/pixie/src/e2e_test/protocol_loadtest/replay"
+)
+
+func init() {
+	pflag.String("capture", "", "Path to the JSON capture of the flows to replay")
+	pflag.String("manifest", "", "Path to write the manifest of the replayed flows to")
+
+	pflag.String("http_addr", "", "The host:port to replay HTTP flows against")
+	pflag.String("grpc_addr", "", "The host:port to replay gRPC flows against")
+	pflag.String("mysql_addr", "", "The host:port to replay MySQL flows against")
+	pflag.String("kafka_addr", "", "The host:port to replay Kafka flows against")
+
+	pflag.Float64("speed", 1, "Multiplier for the captured delays between messages. 0 sends messages without delays")
+	pflag.Float64("flows_per_second", 0, "Maximum number of flows started per second. 0 is unlimited")
+	pflag.Int("concurrency", 1, "Number of flows replayed at the same time")
+	pflag.Int("loops", 1, "Number of times each flow is replayed")
+}
+
+func main() {
+	pflag.Parse()
+	viper.AutomaticEnv()
+	viper.BindPFlags(pflag.CommandLine)
+
+	capture, err := replay.LoadCapture(viper.GetString("capture"))
+	if err != nil {
+		log.WithError(err).Fatal("Failed to load capture")
+	}
+
+	targets := make(map[replay.Protocol]string)
+	for p, flag := range map[replay.Protocol]string{
+		replay.ProtocolHTTP:  "http_addr",
+		replay.ProtocolGRPC:  "grpc_addr",
+		replay.ProtocolMySQL: "mysql_addr",
+		replay.ProtocolKafka: "kafka_addr",
+	} {
+		if addr := viper.GetString(flag); addr != "" {
+			targets[p] = addr
+		}
+	}
+
+	r, err := replay.New(capture, &replay.Config{
+		Targets:        targets,
+		Speed:          viper.GetFloat64("speed"),
+		FlowsPerSecond: viper.GetFloat64("flows_per_second"),
+		Concurrency:    viper.GetInt("concurrency"),
+		Loops:          viper.GetInt("loops"),
+	})
+	if err != nil {
+		log.WithError(err).Fatal("Failed to create replayer")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	m, err := r.Run(ctx)
+	if err != nil {
+		log.WithError(err).Error("Replay was interrupted")
+	}
+	for p, s := range m.Summary {
+		log.WithField("protocol", p).
+			WithField("flows", s.Flows).
+			WithField("failed_flows", s.FailedFlows).
+			WithField("expected_records", s.ExpectedRecords).
+			WithField("bytes_sent", s.BytesSent).
+			Info("Replayed flows")
+	}
+	log.Infof("Replayed %d flows in %v", len(m.Flows), m.End.Sub(m.Start))
+
+	if path := viper.GetString("manifest"); path != "" {
+		if err := m.WriteFile(path); err != nil {
+			log.WithError(err).Fatal("Failed to write manifest")
+		}
+	}
+}
@@ -0,0 +1,240 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package testcluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// recoveryTimeout is how long WaitForRecovery waits for Vizier to recover from a fault.
+const recoveryTimeout = 10 * time.Minute
+
+// Fault is a disruption of a running Vizier: it force-kills the pods that match its selectors.
+type Fault struct {
+	Name string
+	// Selectors are the label selectors of the pods to kill. Any of them may match no pods, for
+	// instance only one of the etcd and the persistent datastore is deployed.
+	Selectors []string
+	// Count is the number of matching pods to kill, picked at random. All of them are killed if
+	// it is zero.
+	Count int
+}
+
+var (
+	// FaultNATS kills the NATS servers, through which all of the Vizier services communicate.
+	FaultNATS = Fault{Name: "nats", Selectors: []string{"name=pl-nats"}}
+	// FaultDatastore kills the metadata datastore, whether it is etcd or the metadata service's
+	// persistent store.
+	FaultDatastore = Fault{Name: "datastore", Selectors: []string{"etcd_cluster=pl-etcd", "name=vizier-metadata"}}
+	// FaultCloudConnector kills the cloud connector, disconnecting Vizier from the cloud.
+	FaultCloudConnector = Fault{Name: "cloud-connector", Selectors: []string{"name=vizier-cloud-connector"}}
+	// FaultRandomPEM kills one PEM.
+	FaultRandomPEM = Fault{Name: "random-pem", Selectors: []string{"name=vizier-pem"}, Count: 1}
+)
+
+// ControlPlaneFaults are the faults that chaos tests inject by default.
+var ControlPlaneFaults = []Fault{FaultNATS, FaultDatastore, FaultCloudConnector, FaultRandomPEM}
+
+// InjectFault kills the pods selected by the fault, and returns their names.
+func (c *Cluster) InjectFault(ctx context.Context, f Fault) ([]string, error) {
+	killed, err := killPods(ctx, c.clusterCtx.Clientset(), c.opts.Namespace, f, rand.New(rand.NewSource(time.Now().UnixNano())))
+	if err != nil {
+		return nil, err
+	}
+	log.WithField("fault", f.Name).WithField("pods", killed).Info("Injected fault")
+	return killed, nil
+}
+
+func killPods(ctx context.Context, cs kubernetes.Interface, namespace string, f Fault, r *rand.Rand) ([]string, error) {
+	var pods []v1.Pod
+	for _, selector := range f.Selectors {
+		l, err := cs.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return nil, err
+		}
+		pods = append(pods, l.Items...)
+	}
+	if len(pods) == 0 {
+		return nil, fmt.Errorf("fault %s matched no pods", f.Name)
+	}
+	r.Shuffle(len(pods), func(i, j int) { pods[i], pods[j] = pods[j], pods[i] })
+	if f.Count > 0 && f.Count < len(pods) {
+		pods = pods[:f.Count]
+	}
+
+	// Skip the grace period, the pod should die the way it would if its node went away.
+	gracePeriod := int64(0)
+	var killed []string
+	for _, p := range pods {
+		err := cs.CoreV1().Pods(namespace).Delete(ctx, p.Name, metav1.DeleteOptions{GracePeriodSeconds: &gracePeriod})
+		if err != nil {
+			return killed, err
+		}
+		killed = append(killed, p.Name)
+	}
+	return killed, nil
+}
+
+// healthyAgentsScript counts the agents that are healthy.
+const healthyAgentsScript = `
+import px
+df = px.GetAgentStatus()
+df = df[df.agent_state == 'AGENT_STATE_HEALTHY']
+df = df.agg(agents=('agent_id', px.count))
+px.display(df, 'output')
+`
+
+// CheckInvariants returns an error if Vizier has not fully recovered: each of its workloads must
+// have all of its replicas ready, scripts must run through the cloud, and every PEM and Kelvin pod
+// must have a healthy agent registered with the metadata service.
+func (c *Cluster) CheckInvariants(ctx context.Context) error {
+	if err := checkWorkloadsReady(ctx, c.clusterCtx.Clientset(), c.opts.Namespace); err != nil {
+		return err
+	}
+	tables, err := c.RunScript(ctx, healthyAgentsScript)
+	if err != nil {
+		return fmt.Errorf("failed to run script: %w", err)
+	}
+	rows := tables["output"]
+	if len(rows) != 1 {
+		return fmt.Errorf("agent status returned %d rows", len(rows))
+	}
+
+	// Every PEM and Kelvin registers as an agent.
+	agents, err := c.clusterCtx.Clientset().CoreV1().Pods(c.opts.Namespace).List(ctx, metav1.ListOptions{LabelSelector: "name in (vizier-pem, kelvin)"})
+	if err != nil {
+		return err
+	}
+	expected := fmt.Sprint(len(agents.Items))
+	if rows[0]["agents"] != expected {
+		return fmt.Errorf("%s healthy agents, expected %s", rows[0]["agents"], expected)
+	}
+	return nil
+}
+
+func checkWorkloadsReady(ctx context.Context, cs kubernetes.Interface, namespace string) error {
+	deployments, err := cs.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, d := range deployments.Items {
+		if desired := replicas(d.Spec.Replicas); d.Status.ReadyReplicas < desired {
+			return fmt.Errorf("deployment %s has %d/%d ready replicas", d.Name, d.Status.ReadyReplicas, desired)
+		}
+	}
+	statefulSets, err := cs.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, s := range statefulSets.Items {
+		if desired := replicas(s.Spec.Replicas); s.Status.ReadyReplicas < desired {
+			return fmt.Errorf("statefulset %s has %d/%d ready replicas", s.Name, s.Status.ReadyReplicas, desired)
+		}
+	}
+	daemonSets, err := cs.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, d := range daemonSets.Items {
+		if !daemonSetReady(&d) {
+			return fmt.Errorf("daemonset %s has %d/%d ready pods", d.Name, d.Status.NumberReady, d.Status.DesiredNumberScheduled)
+		}
+	}
+	return nil
+}
+
+func replicas(r *int32) int32 {
+	// The API server defaults unset replicas to one.
+	if r == nil {
+		return 1
+	}
+	return *r
+}
+
+func daemonSetReady(d *appsv1.DaemonSet) bool {
+	return d.Status.DesiredNumberScheduled > 0 && d.Status.NumberReady == d.Status.DesiredNumberScheduled
+}
+
+// WaitForRecovery waits until CheckInvariants passes.
+func (c *Cluster) WaitForRecovery(ctx context.Context) error {
+	expBackoff := backoff.NewExponentialBackOff()
+	expBackoff.InitialInterval = 5 * time.Second
+	expBackoff.MaxInterval = 30 * time.Second
+	expBackoff.MaxElapsedTime = recoveryTimeout
+	bo := backoff.WithContext(expBackoff, ctx)
+
+	notify := func(err error, dur time.Duration) {
+		log.WithError(err).Infof("Vizier has not recovered, checking again in %v", dur.Round(time.Second))
+	}
+	return backoff.RetryNotify(func() error { return c.CheckInvariants(ctx) }, bo, notify)
+}
+
+// ChaosOptions configures RunWithChaos.
+type ChaosOptions struct {
+	Faults []Fault
+	// Interval is the time between faults. The first fault is injected after one interval.
+	Interval time.Duration
+}
+
+// RunWithChaos runs op while injecting the given faults, one after another, and then waits for
+// Vizier to recover. Faults stop as soon as op returns. The error of op is returned as is, since
+// whether op should survive the faults is up to the test.
+func (c *Cluster) RunWithChaos(ctx context.Context, opts ChaosOptions, op func(ctx context.Context) error) (opErr error, err error) {
+	if len(opts.Faults) == 0 {
+		return nil, errors.New("no faults to inject")
+	}
+	chaosCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	var faultErr error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for _, f := range opts.Faults {
+			select {
+			case <-chaosCtx.Done():
+				return
+			case <-time.After(opts.Interval):
+			}
+			if _, err := c.InjectFault(chaosCtx, f); err != nil && chaosCtx.Err() == nil {
+				faultErr = err
+				return
+			}
+		}
+	}()
+
+	opErr = op(ctx)
+	cancel()
+	wg.Wait()
+	if faultErr != nil {
+		return opErr, faultErr
+	}
+	return opErr, c.WaitForRecovery(ctx)
+}
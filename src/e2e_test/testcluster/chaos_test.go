@@ -0,0 +1,123 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package testcluster
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func pod(name string, labels map[string]string) *v1.Pod {
+	return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "pl", Labels: labels}}
+}
+
+func TestKillPods(t *testing.T) {
+	objs := []runtime.Object{
+		pod("pl-nats-0", map[string]string{"name": "pl-nats"}),
+		pod("vizier-metadata-0", map[string]string{"name": "vizier-metadata"}),
+		pod("vizier-pem-a", map[string]string{"name": "vizier-pem"}),
+		pod("vizier-pem-b", map[string]string{"name": "vizier-pem"}),
+		pod("vizier-pem-c", map[string]string{"name": "vizier-pem"}),
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		fault       Fault
+		expectedNum int
+		expectedErr bool
+	}{
+		{name: "nats", fault: FaultNATS, expectedNum: 1},
+		{name: "datastore with only one of the selectors matching", fault: FaultDatastore, expectedNum: 1},
+		{name: "one pem", fault: FaultRandomPEM, expectedNum: 1},
+		{name: "all pems", fault: Fault{Name: "pems", Selectors: []string{"name=vizier-pem"}}, expectedNum: 3},
+		{name: "no match", fault: FaultCloudConnector, expectedErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cs := fake.NewSimpleClientset(objs...)
+			killed, err := killPods(ctx, cs, "pl", test.fault, rand.New(rand.NewSource(0)))
+			if test.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, killed, test.expectedNum)
+
+			pods, err := cs.CoreV1().Pods("pl").List(ctx, metav1.ListOptions{})
+			require.NoError(t, err)
+			assert.Len(t, pods.Items, len(objs)-test.expectedNum)
+			for _, p := range pods.Items {
+				assert.NotContains(t, killed, p.Name)
+			}
+		})
+	}
+}
+
+func TestCheckWorkloadsReady(t *testing.T) {
+	two := int32(2)
+	deployment := func(ready int32) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "kelvin", Namespace: "pl"},
+			Spec:       appsv1.DeploymentSpec{Replicas: &two},
+			Status:     appsv1.DeploymentStatus{ReadyReplicas: ready},
+		}
+	}
+	daemonSet := func(ready int32) *appsv1.DaemonSet {
+		return &appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "vizier-pem", Namespace: "pl"},
+			Status:     appsv1.DaemonSetStatus{DesiredNumberScheduled: 3, NumberReady: ready},
+		}
+	}
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "pl-nats", Namespace: "pl"},
+		Status:     appsv1.StatefulSetStatus{ReadyReplicas: 1},
+	}
+
+	tests := []struct {
+		name        string
+		objs        []runtime.Object
+		expectedErr bool
+	}{
+		{name: "ready", objs: []runtime.Object{deployment(2), daemonSet(3), statefulSet}},
+		{name: "deployment not ready", objs: []runtime.Object{deployment(1), daemonSet(3), statefulSet}, expectedErr: true},
+		{name: "daemonset not ready", objs: []runtime.Object{deployment(2), daemonSet(2), statefulSet}, expectedErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkWorkloadsReady(context.Background(), fake.NewSimpleClientset(test.objs...), "pl")
+			if test.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package testcluster
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Provider creates and deletes local clusters.
+type Provider string
+
+const (
+	// ProviderKind creates clusters with kind (Kubernetes in Docker).
+	ProviderKind Provider = "kind"
+	// ProviderK3d creates clusters with k3d (k3s in Docker).
+	ProviderK3d Provider = "k3d"
+)
+
+// binary is the CLI that manages clusters for the provider.
+func (p Provider) binary() (string, error) {
+	switch p {
+	case ProviderKind:
+		return "kind", nil
+	case ProviderK3d:
+		return "k3d", nil
+	}
+	return "", fmt.Errorf("unknown cluster provider %q", p)
+}
+
+func (p Provider) createArgs(name, k8sImage string) []string {
+	switch p {
+	case ProviderKind:
+		args := []string{"create", "cluster", "--name", name, "--wait", "5m"}
+		if k8sImage != "" {
+			args = append(args, "--image", k8sImage)
+		}
+		return args
+	case ProviderK3d:
+		args := []string{"cluster", "create", name, "--wait", "--kubeconfig-update-default=false"}
+		if k8sImage != "" {
+			args = append(args, "--image", k8sImage)
+		}
+		return args
+	}
+	return nil
+}
+
+func (p Provider) kubeconfigArgs(name string) []string {
+	switch p {
+	case ProviderKind:
+		return []string{"get", "kubeconfig", "--name", name}
+	case ProviderK3d:
+		return []string{"kubeconfig", "get", name}
+	}
+	return nil
+}
+
+func (p Provider) deleteArgs(name string) []string {
+	switch p {
+	case ProviderKind:
+		return []string{"delete", "cluster", "--name", name}
+	case ProviderK3d:
+		return []string{"cluster", "delete", name}
+	}
+	return nil
+}
+
+func (p Provider) run(args ...string) ([]byte, error) {
+	bin, err := p.binary()
+	if err != nil {
+		return nil, err
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(bin, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to run `%s %s`: %w, stderr: %s", bin, strings.Join(args, " "), err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package testcluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProvider_Args(t *testing.T) {
+	tests := []struct {
+		name           string
+		provider       Provider
+		image          string
+		expectedCreate []string
+		expectedConfig []string
+		expectedDelete []string
+	}{
+		{
+			name:           "kind",
+			provider:       ProviderKind,
+			expectedCreate: []string{"create", "cluster", "--name", "test", "--wait", "5m"},
+			expectedConfig: []string{"get", "kubeconfig", "--name", "test"},
+			expectedDelete: []string{"delete", "cluster", "--name", "test"},
+		},
+		{
+			name:           "kind with image",
+			provider:       ProviderKind,
+			image:          "kindest/node:v1.25.3",
+			expectedCreate: []string{"create", "cluster", "--name", "test", "--wait", "5m", "--image", "kindest/node:v1.25.3"},
+			expectedConfig: []string{"get", "kubeconfig", "--name", "test"},
+			expectedDelete: []string{"delete", "cluster", "--name", "test"},
+		},
+		{
+			name:           "k3d",
+			provider:       ProviderK3d,
+			expectedCreate: []string{"cluster", "create", "test", "--wait", "--kubeconfig-update-default=false"},
+			expectedConfig: []string{"kubeconfig", "get", "test"},
+			expectedDelete: []string{"cluster", "delete", "test"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.provider.binary()
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedCreate, test.provider.createArgs("test", test.image))
+			assert.Equal(t, test.expectedConfig, test.provider.kubeconfigArgs("test"))
+			assert.Equal(t, test.expectedDelete, test.provider.deleteArgs("test"))
+		})
+	}
+}
+
+func TestProvider_Unknown(t *testing.T) {
+	_, err := Provider("minikube").binary()
+	assert.Error(t, err)
+}
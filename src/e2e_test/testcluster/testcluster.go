@@ -0,0 +1,187 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package testcluster manages the lifecycle of ephemeral clusters for end-to-end tests. A test
+// creates a kind or k3d cluster, deploys a pinned Vizier build to it, runs PxL against it, and
+// the cluster is torn down when the test finishes:
+//
+//	c := testcluster.New(t, testcluster.OptionsFromEnv())
+//	c.DeployVizier()
+//	c.WaitForRows(ctx, script, "output", 1)
+package testcluster
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	log "github.com/sirupsen/logrus"
+
+	"px.dev/pixie/src/e2e_test/perf_tool/pkg/cluster"
+	"px.dev/pixie/src/e2e_test/perf_tool/pkg/pixie"
+)
+
+const (
+	defaultCloudAddr       = "withpixie.ai:443"
+	defaultVizierNamespace = "pl"
+	deleteTimeout          = 5 * time.Minute
+)
+
+// Options configures the cluster that is created, and the Vizier that is deployed to it.
+type Options struct {
+	Provider Provider
+	// K8sImage is the node image of the cluster, which pins the Kubernetes version. The
+	// provider's default is used if it is empty.
+	K8sImage string
+	// VizierVersion is the Vizier version that DeployVizier deploys. The latest version is
+	// deployed if it is empty, so CI should always set it.
+	VizierVersion string
+	// Namespace is the namespace Vizier is deployed to.
+	Namespace string
+
+	APIKey    string
+	CloudAddr string
+
+	// KeepCluster leaves the cluster running after the test, to debug it. Vizier is still deleted.
+	KeepCluster bool
+}
+
+// OptionsFromEnv reads the options from the environment, so that the same tests can run in CI
+// and locally:
+//
+//	PX_API_KEY, PX_CLOUD_ADDR: the cloud that Vizier connects to.
+//	PX_E2E_PROVIDER: "kind" (the default) or "k3d".
+//	PX_E2E_K8S_IMAGE: the node image of the cluster.
+//	PX_E2E_VIZIER_VERSION: the Vizier version to deploy.
+//	PX_E2E_KEEP_CLUSTER: set to keep the cluster after the test.
+func OptionsFromEnv() *Options {
+	opts := &Options{
+		Provider:      Provider(os.Getenv("PX_E2E_PROVIDER")),
+		K8sImage:      os.Getenv("PX_E2E_K8S_IMAGE"),
+		VizierVersion: os.Getenv("PX_E2E_VIZIER_VERSION"),
+		APIKey:        os.Getenv("PX_API_KEY"),
+		CloudAddr:     os.Getenv("PX_CLOUD_ADDR"),
+		KeepCluster:   os.Getenv("PX_E2E_KEEP_CLUSTER") != "",
+	}
+	if opts.Provider == "" {
+		opts.Provider = ProviderKind
+	}
+	return opts
+}
+
+// Cluster is an ephemeral cluster created for a test.
+type Cluster struct {
+	Name string
+
+	t          testing.TB
+	opts       *Options
+	clusterCtx *cluster.Context
+	pxCtx      *pixie.Context
+	clusterID  uuid.UUID
+
+	// vizierDeployed is set once DeployVizier has started, so that Vizier is deleted on teardown
+	// even if the deploy failed part way through.
+	vizierDeployed bool
+}
+
+// New creates a cluster, which is torn down when the test and all its subtests complete. The test
+// is skipped if the provider's CLI, or the px CLI, isn't installed, or no API key is set.
+func New(t testing.TB, opts *Options) *Cluster {
+	t.Helper()
+	if opts.Namespace == "" {
+		opts.Namespace = defaultVizierNamespace
+	}
+	if opts.CloudAddr == "" {
+		opts.CloudAddr = defaultCloudAddr
+	}
+	bin, err := opts.Provider.binary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range []string{bin, "px"} {
+		if _, err := exec.LookPath(b); err != nil {
+			t.Skipf("%s is not installed", b)
+		}
+	}
+	if opts.APIKey == "" {
+		t.Skip("PX_API_KEY is not set")
+	}
+
+	c := &Cluster{
+		Name:  fmt.Sprintf("px-e2e-%d", rand.Intn(1000000)),
+		t:     t,
+		opts:  opts,
+		pxCtx: pixie.NewContext(opts.APIKey, opts.CloudAddr),
+	}
+	log.WithField("cluster", c.Name).WithField("provider", opts.Provider).Info("Creating cluster")
+	if _, err := opts.Provider.run(opts.Provider.createArgs(c.Name, opts.K8sImage)...); err != nil {
+		t.Fatal(err)
+	}
+	// Register the teardown before anything else can fail, so the cluster is never leaked.
+	t.Cleanup(c.teardown)
+
+	kubeconfig, err := opts.Provider.run(opts.Provider.kubeconfigArgs(c.Name)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.clusterCtx, err = cluster.NewContextFromConfig(kubeconfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// ClusterContext returns the kubeconfig and clients of the cluster.
+func (c *Cluster) ClusterContext() *cluster.Context {
+	return c.clusterCtx
+}
+
+// ClusterID returns the ID of the deployed Vizier, or uuid.Nil if it hasn't been deployed.
+func (c *Cluster) ClusterID() uuid.UUID {
+	return c.clusterID
+}
+
+// PixieContext returns the context used to run px commands and scripts against the cluster.
+func (c *Cluster) PixieContext() *pixie.Context {
+	return c.pxCtx
+}
+
+func (c *Cluster) teardown() {
+	if c.vizierDeployed {
+		if err := c.DeleteVizier(); err != nil {
+			c.t.Errorf("failed to delete Vizier: %v", err)
+		}
+	}
+	if c.clusterCtx != nil {
+		if err := c.clusterCtx.Close(); err != nil {
+			log.WithError(err).Error("Failed to remove kubeconfig")
+		}
+	}
+	if c.opts.KeepCluster {
+		log.WithField("cluster", c.Name).Info("Keeping cluster")
+		return
+	}
+	log.WithField("cluster", c.Name).Info("Deleting cluster")
+	if _, err := c.opts.Provider.run(c.opts.Provider.deleteArgs(c.Name)...); err != nil {
+		c.t.Errorf("failed to delete cluster: %v", err)
+	}
+}
@@ -0,0 +1,213 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package testcluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/gofrs/uuid"
+	log "github.com/sirupsen/logrus"
+
+	"px.dev/pixie/src/api/go/pxapi"
+	"px.dev/pixie/src/api/go/pxapi/types"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+const (
+	operatorNamespace = "px-operator"
+	// scriptTimeout is the timeout of a single execution of a script.
+	scriptTimeout = time.Minute
+	// waitTimeout is how long WaitForRows waits for a script to return the expected rows. Data
+	// takes a while to show up after Vizier is deployed.
+	waitTimeout = 10 * time.Minute
+)
+
+// DeployVizier deploys the pinned Vizier version to the cluster and waits for it to connect to
+// the cloud. Vizier is deleted when the test completes.
+func (c *Cluster) DeployVizier(extraArgs ...string) error {
+	if _, err := c.pxCtx.RunPXCmd(c.clusterCtx, "auth", "login", "--use_api_key=true"); err != nil {
+		return err
+	}
+	args := []string{"deploy", "-y", "--cluster_name", c.Name, "--namespace", c.opts.Namespace}
+	if c.opts.VizierVersion != "" {
+		args = append(args, "--vizier_version", c.opts.VizierVersion)
+	}
+	args = append(args, extraArgs...)
+
+	c.vizierDeployed = true
+	log.WithField("cluster", c.Name).WithField("version", c.opts.VizierVersion).Info("Deploying Vizier")
+	if _, err := c.pxCtx.RunPXCmd(c.clusterCtx, args...); err != nil {
+		return err
+	}
+	clusterIDBytes, err := c.pxCtx.RunPXCmd(c.clusterCtx, "get", "cluster", "--id")
+	if err != nil {
+		return err
+	}
+	id, err := uuid.FromString(strings.Trim(string(clusterIDBytes), " \n"))
+	if err != nil {
+		return err
+	}
+	c.clusterID = id
+	c.pxCtx.SetClusterID(id)
+	return nil
+}
+
+// UpgradeVizier updates the deployed Vizier to the given version.
+func (c *Cluster) UpgradeVizier(version string) error {
+	if c.clusterID == uuid.Nil {
+		return errors.New("must call DeployVizier before UpgradeVizier")
+	}
+	log.WithField("cluster", c.Name).WithField("version", version).Info("Upgrading Vizier")
+	_, err := c.pxCtx.RunPXCmd(c.clusterCtx, "update", "vizier", "-y", "--cluster", c.clusterID.String(), "--vizier_version", version)
+	return err
+}
+
+// DeleteVizier deletes Vizier, the operator and all of their cluster-scoped resources, the same
+// way that `px delete` does.
+func (c *Cluster) DeleteVizier() error {
+	log.WithField("cluster", c.Name).Info("Deleting Vizier")
+	ctx := context.Background()
+	od := k8s.ObjectDeleter{
+		Namespace:  c.opts.Namespace,
+		Clientset:  c.clusterCtx.Clientset(),
+		RestConfig: c.clusterCtx.RestConfig(),
+		Timeout:    deleteTimeout,
+	}
+	opOd := k8s.ObjectDeleter{
+		Namespace:  operatorNamespace,
+		Clientset:  c.clusterCtx.Clientset(),
+		RestConfig: c.clusterCtx.RestConfig(),
+		Timeout:    deleteTimeout,
+	}
+	if err := od.DeleteNamespace(ctx); err != nil {
+		return err
+	}
+	if err := opOd.DeleteNamespace(ctx); err != nil {
+		return err
+	}
+	if _, err := od.DeleteByLabel(ctx, "app=pl-monitoring"); err != nil {
+		return err
+	}
+	c.vizierDeployed = false
+	return nil
+}
+
+// Row is a row of a script's output, with each value formatted as a string.
+type Row map[string]string
+
+// tableCollector collects the rows of every table that a script outputs.
+type tableCollector struct {
+	mu     sync.Mutex
+	tables map[string][]Row
+}
+
+type tableHandler struct {
+	c    *tableCollector
+	name string
+}
+
+// AcceptTable implements pxapi.TableMuxer.
+func (tc *tableCollector) AcceptTable(ctx context.Context, md types.TableMetadata) (pxapi.TableRecordHandler, error) {
+	return &tableHandler{c: tc, name: md.Name}, nil
+}
+
+// HandleInit implements pxapi.TableRecordHandler.
+func (h *tableHandler) HandleInit(context.Context, types.TableMetadata) error {
+	h.c.mu.Lock()
+	defer h.c.mu.Unlock()
+	if _, ok := h.c.tables[h.name]; !ok {
+		h.c.tables[h.name] = []Row{}
+	}
+	return nil
+}
+
+// HandleRecord implements pxapi.TableRecordHandler.
+func (h *tableHandler) HandleRecord(ctx context.Context, r *types.Record) error {
+	row := make(Row, len(r.Data))
+	for i, col := range r.TableMetadata.ColInfo {
+		row[col.Name] = r.Data[i].String()
+	}
+	h.c.mu.Lock()
+	defer h.c.mu.Unlock()
+	h.c.tables[h.name] = append(h.c.tables[h.name], row)
+	return nil
+}
+
+// HandleDone implements pxapi.TableRecordHandler.
+func (h *tableHandler) HandleDone(context.Context) error {
+	return nil
+}
+
+// RunScript runs a PxL script on the cluster's Vizier, and returns the rows of each output table
+// by table name.
+func (c *Cluster) RunScript(ctx context.Context, pxl string) (map[string][]Row, error) {
+	ctx, cancel := context.WithTimeout(ctx, scriptTimeout)
+	defer cancel()
+	vz, err := c.pxCtx.NewVizierClient()
+	if err != nil {
+		return nil, err
+	}
+	tc := &tableCollector{tables: make(map[string][]Row)}
+	rs, err := vz.ExecuteScript(ctx, pxl, tc)
+	if err != nil {
+		return nil, err
+	}
+	defer rs.Close()
+	if err := rs.Stream(); err != nil {
+		return nil, err
+	}
+	return tc.tables, nil
+}
+
+// WaitForRows runs a PxL script until the given table has at least minRows rows, and returns them.
+// Compilation errors are retried as well, since the tables a script reads from only exist once
+// the PEMs have started tracing.
+func (c *Cluster) WaitForRows(ctx context.Context, pxl string, table string, minRows int) ([]Row, error) {
+	expBackoff := backoff.NewExponentialBackOff()
+	expBackoff.InitialInterval = time.Second
+	expBackoff.MaxInterval = 30 * time.Second
+	expBackoff.MaxElapsedTime = waitTimeout
+	bo := backoff.WithContext(expBackoff, ctx)
+
+	var rows []Row
+	op := func() error {
+		tables, err := c.RunScript(ctx, pxl)
+		if err != nil {
+			return err
+		}
+		rows = tables[table]
+		if len(rows) < minRows {
+			return fmt.Errorf("table %s has %d rows, expected at least %d", table, len(rows), minRows)
+		}
+		return nil
+	}
+	notify := func(err error, dur time.Duration) {
+		log.WithError(err).Tracef("Script did not return the expected rows, retrying in %v", dur.Round(time.Second))
+	}
+	if err := backoff.RetryNotify(op, bo, notify); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package vizier_chaos_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/e2e_test/testcluster"
+)
+
+const processStatsScript = `
+import px
+df = px.DataFrame('process_stats', start_time='-1m')
+df = df.groupby('upid').agg(count=('cpu_ktime_ns', px.count))
+px.display(df, 'output')
+`
+
+// faultInterval is long enough for each fault to take effect before the next one.
+const faultInterval = 20 * time.Second
+
+// TestVizierChaos kills the control plane components and PEMs of a running Vizier while queries
+// and an upgrade are in flight, and checks that Vizier recovers from each fault.
+func TestVizierChaos(t *testing.T) {
+	if os.Getenv("PX_E2E_CHAOS") == "" {
+		t.Skip("PX_E2E_CHAOS is not set")
+	}
+	c := testcluster.New(t, testcluster.OptionsFromEnv())
+	ctx := context.Background()
+	require.NoError(t, c.DeployVizier())
+	_, err := c.WaitForRows(ctx, processStatsScript, "output", 1)
+	require.NoError(t, err)
+	require.NoError(t, c.WaitForRecovery(ctx))
+
+	for _, f := range testcluster.ControlPlaneFaults {
+		f := f
+		t.Run("mid-query/"+f.Name, func(t *testing.T) {
+			// A query may fail while the fault is in effect, but must not hang, and queries must
+			// succeed again once Vizier has recovered.
+			opErr, err := c.RunWithChaos(ctx, testcluster.ChaosOptions{
+				Faults:   []testcluster.Fault{f},
+				Interval: faultInterval,
+			}, func(ctx context.Context) error {
+				deadline := time.Now().Add(3 * faultInterval)
+				for time.Now().Before(deadline) {
+					if _, err := c.RunScript(ctx, processStatsScript); err != nil {
+						t.Logf("query failed during fault %s: %v", f.Name, err)
+						time.Sleep(time.Second)
+					}
+				}
+				return nil
+			})
+			require.NoError(t, err)
+			assert.NoError(t, opErr)
+			_, err = c.WaitForRows(ctx, processStatsScript, "output", 1)
+			assert.NoError(t, err)
+		})
+	}
+
+	t.Run("mid-upgrade", func(t *testing.T) {
+		version := os.Getenv("PX_E2E_UPGRADE_VERSION")
+		if version == "" {
+			t.Skip("PX_E2E_UPGRADE_VERSION is not set")
+		}
+		opErr, err := c.RunWithChaos(ctx, testcluster.ChaosOptions{
+			Faults:   testcluster.ControlPlaneFaults,
+			Interval: faultInterval,
+		}, func(ctx context.Context) error {
+			return c.UpgradeVizier(version)
+		})
+		require.NoError(t, err)
+		// The upgrade may be interrupted, but running it again must complete it.
+		if opErr != nil {
+			t.Logf("upgrade failed during faults: %v", opErr)
+			require.NoError(t, c.UpgradeVizier(version))
+			require.NoError(t, c.WaitForRecovery(ctx))
+		}
+		_, err = c.WaitForRows(ctx, processStatsScript, "output", 1)
+		assert.NoError(t, err)
+	})
+}
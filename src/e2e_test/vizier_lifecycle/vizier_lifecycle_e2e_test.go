@@ -0,0 +1,67 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package vizier_lifecycle_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"px.dev/pixie/src/e2e_test/testcluster"
+)
+
+// The script reads from a table that requires BPF, to check that the PEMs are tracing.
+const processStatsScript = `
+import px
+df = px.DataFrame('process_stats', start_time='-1m')
+df = df.groupby('upid').agg(count=('cpu_ktime_ns', px.count))
+px.display(df, 'output')
+`
+
+func TestVizierLifecycle(t *testing.T) {
+	opts := testcluster.OptionsFromEnv()
+	c := testcluster.New(t, opts)
+	ctx := context.Background()
+
+	t.Run("deploy", func(t *testing.T) {
+		require.NoError(t, c.DeployVizier())
+		_, err := c.WaitForRows(ctx, processStatsScript, "output", 1)
+		require.NoError(t, err)
+	})
+
+	t.Run("upgrade", func(t *testing.T) {
+		version := os.Getenv("PX_E2E_UPGRADE_VERSION")
+		if version == "" {
+			t.Skip("PX_E2E_UPGRADE_VERSION is not set")
+		}
+		require.NoError(t, c.UpgradeVizier(version))
+		_, err := c.WaitForRows(ctx, processStatsScript, "output", 1)
+		require.NoError(t, err)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, c.DeleteVizier())
+		_, err := c.ClusterContext().Clientset().CoreV1().Namespaces().Get(ctx, opts.Namespace, metav1.GetOptions{})
+		assert.Error(t, err)
+	})
+}
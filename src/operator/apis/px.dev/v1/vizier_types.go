@@ -0,0 +1,488 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Generate the code for deep-copying the CRD in go.
+//go:generate controller-gen object
+// Generate the CRD YAMLs.
+// v1 is the storage version, so the CRD is generated from here for every version in the group.
+//go:generate controller-gen crd:trivialVersions=true rbac:roleName=operator-role webhook paths=../... output:crd:artifacts:config=crd output:crd:dir:=../../../../../k8s/operator/crd/base
+// Generate the clientset.
+//go:generate client-gen --input=px.dev/v1alpha1,px.dev/v1 --clientset-name=versioned --go-header-file=/dev/null --input-base=px.dev/pixie/src/operator/apis --output-package=px.dev/pixie/src/operator/client
+
+package v1
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"px.dev/pixie/src/shared/status"
+)
+
+// VizierSpec defines the desired state of Vizier
+type VizierSpec struct {
+	// Version is the desired version of the Vizier instance.
+	Version string `json:"version,omitempty"`
+	// DeployKey is the deploy key associated with the Vizier instance. This is used to link the Vizier to a
+	// specific user/org. This is required unless specifying a CustomDeployKeySecret.
+	DeployKey string `json:"deployKey,omitempty"`
+	// CustomDeployKeySecret is the name of the secret where the deploy key is stored.
+	CustomDeployKeySecret string `json:"customDeployKeySecret,omitempty"`
+	// DisableAutoUpdate specifies whether auto update should be enabled for the Vizier instance.
+	DisableAutoUpdate bool `json:"disableAutoUpdate,omitempty"`
+	// UseEtcdOperator specifies whether the metadata service should use etcd for storage.
+	UseEtcdOperator bool `json:"useEtcdOperator,omitempty"`
+	// SingleProcessControlPlane runs the metadata service, the query broker, the cloud connector and NATS in a single
+	// pod, for small and edge clusters. Metadata is kept on the pod's local disk, and is rebuilt from the agents and
+	// K8s when the pod is rescheduled. This can't be combined with UseEtcdOperator, and can't be changed once the
+	// Vizier is deployed.
+	SingleProcessControlPlane bool `json:"singleProcessControlPlane,omitempty"`
+	// ClusterName is a name for the Vizier instance, usually specifying which cluster the Vizier is
+	// deployed to. If not specified, a random name will be generated.
+	ClusterName string `json:"clusterName,omitempty"`
+	// CloudAddr is the address of the cloud instance that the Vizier should be pointing to.
+	CloudAddr string `json:"cloudAddr,omitempty"`
+	// CloudTLS overrides how the Vizier verifies the cloud's certificate. This allows CloudAddr to be an IP, for clusters
+	// which can reach the cloud at a fixed address but can't resolve its name.
+	CloudTLS *CloudTLS `json:"cloudTLS,omitempty"`
+	// DevCloudNamespace should be specified only for dev versions of Pixie cloud which have no ingress to help
+	// redirect traffic to the correct service. The DevCloudNamespace is the namespace that the dev Pixie cloud is
+	// running on, for example: "plc-dev".
+	DevCloudNamespace string `json:"devCloudNamespace,omitempty"`
+	// PemMemoryLimit is a memory limit applied specifically to PEM pods.
+	PemMemoryLimit string `json:"pemMemoryLimit,omitempty"`
+	// PemMemoryRequest is a memory request applied specifically
+	// to PEM pods. It will automatically use the value of pemMemoryLimit
+	// if not specified.
+	PemMemoryRequest string `json:"pemMemoryRequest,omitempty"`
+	// ClockConverter specifies which routine to use for converting timestamps to a synced reference time.
+	ClockConverter ClockConverterType `json:"clockConverter,omitempty"`
+	// Pod defines the policy for creating Vizier pods.
+	Pod *PodPolicy `json:"pod,omitempty"`
+	// Patches defines patches that should be applied to Vizier resources.
+	// The key of the patch should be the name of the resource that is patched. The value of the patch is the patch,
+	// encoded as a string which follow the "strategic merge patch" rules for K8s.
+	Patches map[string]string `json:"patches,omitempty"`
+	// DataAccess defines the level of data that may be accesssed when executing a script on the cluster. If none specified,
+	// assumes full data access.
+	DataAccess DataAccessLevel `json:"dataAccess,omitempty"`
+	// DataCollectorParams specifies the set of params for configuring the dataCollector. If no params are specified, defaults are used.
+	DataCollectorParams *DataCollectorParams `json:"dataCollectorParams,omitempty"`
+	// LeadershipElectionParams specifies configurable values for the K8s leaderships elections which Vizier uses manage pod leadership.
+	LeadershipElectionParams *LeadershipElectionParams `json:"leadershipElectionParams,omitempty"`
+	// Registry specifies the image registry to use rather than Pixie's default registry (gcr.io). We expect any forward slashes in
+	// Pixie's image paths are replaced with a "-". For example: "gcr.io/pixie-oss/pixie-dev/vizier/metadata_server_image:latest"
+	// should be pushed to "$registry/gcr.io-pixie-oss-pixie-dev-vizier-metadata_server_image:latest".
+	Registry string `json:"registry,omitempty"`
+	// Autopilot should be set if running Pixie on GKE Autopilot.
+	Autopilot bool `json:"autopilot,omitempty"`
+	// Profile is a preset of resource settings for the Vizier. Settings which are specified explicitly take precedence
+	// over the ones from the profile.
+	Profile DeploymentProfile `json:"profile,omitempty"`
+	// WorkloadIdentity binds the Vizier components which access the cloud provider, such as the query broker when it
+	// exports to object storage, to a cloud identity. This is used instead of static credentials.
+	WorkloadIdentity *WorkloadIdentity `json:"workloadIdentity,omitempty"`
+	// FIPSMode restricts the Vizier services to FIPS approved cryptography, including the TLS cipher suites used by
+	// NATS and gRPC. The Go services are run from their images built with BoringCrypto.
+	FIPSMode bool `json:"fipsMode,omitempty"`
+	// NetworkPolicy deploys NetworkPolicies which only allow the traffic that Vizier needs, for clusters which deny
+	// traffic by default.
+	NetworkPolicy *NetworkPolicy `json:"networkPolicy,omitempty"`
+	// NATS hardens the NATS message bus that the Vizier services communicate over.
+	NATS *NATSParams `json:"nats,omitempty"`
+	// Availability protects the Vizier's query path against voluntary disruptions, such as node maintenance.
+	Availability *AvailabilityParams `json:"availability,omitempty"`
+}
+
+// CloudTLS specifies how the Vizier verifies the cloud's TLS certificate.
+type CloudTLS struct {
+	// ServerName is the name that is sent as the TLS SNI and that the cloud's certificate is verified against. If not
+	// specified, the host of CloudAddr is used.
+	ServerName string `json:"serverName,omitempty"`
+	// CABundle is a PEM encoded bundle of the CAs that the cloud's certificate is verified against, instead of the
+	// system's roots.
+	CABundle string `json:"caBundle,omitempty"`
+}
+
+// DeploymentProfile defines a preset of resource settings for the Vizier.
+// +kubebuilder:validation:Enum=default;small
+type DeploymentProfile string
+
+const (
+	// DeploymentProfileDefault uses the default resource settings.
+	DeploymentProfileDefault DeploymentProfile = "default"
+	// DeploymentProfileSmall reduces the footprint of Vizier for edge and single-node clusters, such as k3s and microk8s.
+	// It lowers the PEM memory and table store sizes, and disables the continuous profiler.
+	DeploymentProfileSmall DeploymentProfile = "small"
+)
+
+// DataAccessLevel defines the levels of data access that can be used when executing a script on a cluster.
+// +kubebuilder:validation:Enum=Full;Restricted
+type DataAccessLevel string
+
+const (
+	// DataAccessUnknown indicates that the data access level is unspecified.
+	DataAccessUnknown DataAccessLevel = ""
+	// DataAccessFull provides complete, unrestricted access to all collected data.
+	DataAccessFull DataAccessLevel = "Full"
+	// DataAccessRestricted restricts users from accessing columns that may contain sensitive data, for example: HTTP response
+	// bodies. These columns will be entirely replaced by a redacted string.
+	DataAccessRestricted DataAccessLevel = "Restricted"
+	// DataAccessPIIRestricted does a best effort redaction of PII. Current PII types include: IP addresses, email addresses, MAC addresses, credit card numbers, and IMEI numbers.
+	// Note that the best effort redaction is not perfect and as such if security and privacy are of the utmost concern, one should use DataAccessRestricted.
+	DataAccessPIIRestricted DataAccessLevel = "PIIRestricted"
+)
+
+// ClockConverterType defines which clock conversion routine to use for converting timestamps to a synced reference time.
+// +kubebuilder:validation:Enum=default;grpc
+type ClockConverterType string
+
+const (
+	// ClockConverterDefault specifies using the default clock conversion routine.
+	ClockConverterDefault ClockConverterType = "default"
+	// ClockConverterGrpc specifies using the grpc clocksync integration to convert to a synced reference time.
+	ClockConverterGrpc ClockConverterType = "grpc"
+)
+
+// VizierStatus defines the observed state of Vizier
+type VizierStatus struct {
+	// Version is the actual version of the Vizier instance.
+	Version string `json:"version,omitempty"`
+	// VizierPhase is a high-level summary of where the Vizier is in its lifecycle.
+	VizierPhase VizierPhase `json:"vizierPhase,omitempty"`
+	// VizierReason is a short, machine understandable string that gives the reason
+	// for the transition into the Vizier's current status.
+	VizierReason string `json:"vizierReason,omitempty"`
+	// ReconciliationPhase describes the state the Reconciler is in for this Vizier. See the
+	// documentation above the ReconciliationPhase type for more information.
+	ReconciliationPhase ReconciliationPhase `json:"reconciliationPhase,omitempty"`
+	// LastReconciliationPhaseTime is the last time that the ReconciliationPhase changed.
+	LastReconciliationPhaseTime *metav1.Time `json:"lastReconciliationPhaseTime,omitempty"`
+	// Message is a human-readable message with details about why the Vizier is in this condition.
+	Message string `json:"message,omitempty"`
+	// SentryDSN is key for Viziers that is used to send errors and stacktraces to Sentry.
+	SentryDSN string `json:"sentryDSN,omitempty"`
+	// A checksum of the last reconciled Vizier spec. If this checksum does not match the checksum
+	// of the current vizier spec, reconciliation should be performed.
+	Checksum []byte `json:"checksum,omitempty"`
+	// OperatorVersion is the actual version of the Operator instance.
+	OperatorVersion string `json:"operatorVersion,omitempty"`
+	// ExportHealth summarizes the deliveries of the cron scripts which export data, as reported by the query broker.
+	ExportHealth *ExportHealthStatus `json:"exportHealth,omitempty"`
+	// DatastoreHealth summarizes the size and maintenance of the metadata datastore, as reported by the metadata
+	// service.
+	DatastoreHealth *DatastoreHealthStatus `json:"datastoreHealth,omitempty"`
+}
+
+// ExportHealthStatus is the health of the exports of the cron scripts, such as to OpenTelemetry or object storage.
+type ExportHealthStatus struct {
+	// FailingExports is the number of exports that failed every retry of a delivery since they last succeeded.
+	FailingExports int `json:"failingExports,omitempty"`
+	// PendingRetries is the number of failed deliveries which are waiting to be retried.
+	PendingRetries int `json:"pendingRetries,omitempty"`
+	// DeadLetters is the number of deliveries which were given up on after exhausting their retries.
+	DeadLetters int `json:"deadLetters,omitempty"`
+	// DroppedDeadLetters is the number of dead letters which were evicted because the buffer was full.
+	DroppedDeadLetters int64 `json:"droppedDeadLetters,omitempty"`
+	// LastError is the error of the most recent failed delivery.
+	LastError string `json:"lastError,omitempty"`
+	// LastFailureTime is the time of the most recent failed delivery.
+	LastFailureTime *metav1.Time `json:"lastFailureTime,omitempty"`
+}
+
+// DatastoreHealthStatus is the health of the metadata datastore, which rejects metadata writes once it is full.
+type DatastoreHealthStatus struct {
+	// Backend is the kind of datastore, either etcd or pebble.
+	Backend string `json:"backend,omitempty"`
+	// SizeBytes is the space that the datastore occupies on disk.
+	SizeBytes int64 `json:"sizeBytes,omitempty"`
+	// InUseBytes is the part of SizeBytes that is used by live data. The rest is reclaimed by defragmenting.
+	InUseBytes int64 `json:"inUseBytes,omitempty"`
+	// QuotaBytes is the size past which the datastore rejects writes: the etcd quota, or the size of the metadata
+	// volume.
+	QuotaBytes int64 `json:"quotaBytes,omitempty"`
+	// SizeAlarm is set when the datastore is close to its quota, or when the datastore itself raised an alarm.
+	SizeAlarm bool `json:"sizeAlarm,omitempty"`
+	// Alarms are the alarms raised by the datastore itself, such as etcd's NOSPACE.
+	Alarms []string `json:"alarms,omitempty"`
+	// LastCompactionTime is the time of the most recent compaction.
+	LastCompactionTime *metav1.Time `json:"lastCompactionTime,omitempty"`
+	// LastDefragTime is the time of the most recent defragmentation.
+	LastDefragTime *metav1.Time `json:"lastDefragTime,omitempty"`
+	// LastError is the error of the most recent round of maintenance, if it failed.
+	LastError string `json:"lastError,omitempty"`
+}
+
+// VizierPhase is a high-level summary of where the Vizier is in its lifecycle.
+type VizierPhase string
+
+const (
+	// VizierPhaseNone indicates that the vizier phase is unknown.
+	VizierPhaseNone VizierPhase = ""
+
+	// VizierPhaseDisconnected indicates that the vizier has been unable to contact and register with Pixie Cloud.
+	VizierPhaseDisconnected VizierPhase = "Disconnected"
+	// VizierPhaseHealthy indicates that the vizier is fully functioning and queryable.
+	VizierPhaseHealthy VizierPhase = "Healthy"
+	// VizierPhaseUpdating indicates that the vizier is in the process of creating or updating.
+	VizierPhaseUpdating VizierPhase = "Updating"
+	// VizierPhaseUnhealthy indicates that the vizier is not in a healthy state and is unqueryable.
+	VizierPhaseUnhealthy VizierPhase = "Unhealthy"
+	// VizierPhaseDegraded indicates that the vizier is in a queryable state, but data may be missing.
+	VizierPhaseDegraded VizierPhase = "Degraded"
+)
+
+// ReconciliationPhase is the state the Reconciler has reached while managing this
+// vizier. When the Reconciler creates a Vizier, the Reconciler sets this value to `Updating`.
+// When successful, the Reconciler moves to a `Ready` phase. If unsuccessful,
+// will move from `Updating` to `Failed`. When the Reconciler updates the Vizier
+// again, the phase will be set to `Updating`.
+type ReconciliationPhase string
+
+const (
+	// ReconciliationPhaseNone indicates that the Reconciler does not know the Vizier's Reconcilliation state.
+	ReconciliationPhaseNone ReconciliationPhase = ""
+	// ReconciliationPhaseReady indicates that the Reconciler has finished updating to the desired Vizier version.
+	ReconciliationPhaseReady ReconciliationPhase = "Ready"
+	// ReconciliationPhaseUpdating indicates that the Reconciler is currently updating this Vizier.
+	ReconciliationPhaseUpdating ReconciliationPhase = "Updating"
+	// ReconciliationPhaseFailed indicates that the Reconciler failed to apply the desired Vizier version.
+	ReconciliationPhaseFailed ReconciliationPhase = "Failed"
+)
+
+// PodPolicy defines the policy for creating Vizier pods.
+type PodPolicy struct {
+	// Labels specifies the labels to attach to pods the operator creates.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations specifies the annotations to attach to pods the operator creates.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Resources is the resource requirements for a container.
+	// This field cannot be updated once the cluster is created.
+	Resources v1.ResourceRequirements `json:"resources,omitempty"`
+	// NodeSelector is a selector which must be true for the pod to fit on a node.
+	// Selector which must match a node's labels for the pod to be scheduled on that node.
+	// More info: https://kubernetes.io/docs/concepts/configuration/assign-pod-node/
+	// This field cannot be updated once the cluster is created.
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// The securityContext which should be set on non-privileged pods. All pods which require privileged permissions
+	// will still require a privileged securityContext.
+	SecurityContext *PodSecurityContext `json:"securityContext,omitempty"`
+}
+
+// PodSecurityContext describes the desired security context for non-privileged pods. This may be required for some
+// cases with more restrictive PodSecurityAdmissions.
+type PodSecurityContext struct {
+	// Whether a securityContext should be set on the pod. In cases where no PSPs are applied to the cluster, this is
+	// not necessary.
+	Enabled bool `json:"enabled,omitempty"`
+	// A special supplemental group that applies to all containers in a pod.
+	FSGroup int64 `json:"fsGroup,omitempty"`
+	// The UID to run the entrypoint of the container process.
+	RunAsUser int64 `json:"runAsUser,omitempty"`
+	// The GID to run the entrypoint of the container process.
+	RunAsGroup int64 `json:"runAsGroup,omitempty"`
+}
+
+// DataCollectorParams specifies internal data collector configurations.
+type DataCollectorParams struct {
+	// DatastreamBufferSize is the data buffer size per connection.
+	// Default size is 1 Mbyte. For high-throughput applications, try increasing this number if experiencing data loss.
+	DatastreamBufferSize uint32 `json:"datastreamBufferSize,omitempty"`
+	// DatastreamBufferSpikeSize is the maximum temporary size of a data stream buffer before processing.
+	DatastreamBufferSpikeSize uint32 `json:"datastreamBufferSpikeSize,omitempty"`
+	// This contains custom flags that should be passed to the PEM via environment variables.
+	CustomPEMFlags map[string]string `json:"customPEMFlags,omitempty"`
+	// PayloadCapture controls how much of the request and response bodies the PEMs record, per namespace, service
+	// and protocol. If not specified, bodies are truncated to the PEM's default limit.
+	PayloadCapture *PayloadCapturePolicy `json:"payloadCapture,omitempty"`
+}
+
+// PayloadCaptureMode is how much of the bodies of traced requests and responses are recorded.
+// +kubebuilder:validation:Enum=Full;Truncate;HeadersOnly
+type PayloadCaptureMode string
+
+const (
+	// PayloadCaptureFull records bodies up to maxBodyBytes, or 64KiB if unset.
+	PayloadCaptureFull PayloadCaptureMode = "Full"
+	// PayloadCaptureTruncate records bodies truncated to maxBodyBytes, or the PEM's default limit if unset.
+	PayloadCaptureTruncate PayloadCaptureMode = "Truncate"
+	// PayloadCaptureHeadersOnly records the headers and metadata of requests and responses, but none of the bodies.
+	PayloadCaptureHeadersOnly PayloadCaptureMode = "HeadersOnly"
+)
+
+// PayloadCapturePolicy decides how much of the payloads of traced traffic is recorded, so that deep payload capture
+// can be enabled only for the workloads where it is safe.
+type PayloadCapturePolicy struct {
+	// DefaultMode applies to traffic that matches none of the rules. Defaults to Truncate.
+	DefaultMode PayloadCaptureMode `json:"defaultMode,omitempty"`
+	// Rules are evaluated in order, and the first rule that matches the traffic decides its mode.
+	Rules []PayloadCaptureRule `json:"rules,omitempty"`
+}
+
+// PayloadCaptureRule sets the payload capture mode of the traffic of a namespace, service, or protocol.
+// Unset fields match all traffic.
+type PayloadCaptureRule struct {
+	// Namespace is the namespace of the traced pods.
+	Namespace string `json:"namespace,omitempty"`
+	// Service is the name of a service that the traced pods belong to.
+	Service string `json:"service,omitempty"`
+	// Protocol is the traced protocol.
+	// +kubebuilder:validation:Enum=http;http2;mysql;cql;pgsql;redis;nats;kafka;amqp
+	Protocol string `json:"protocol,omitempty"`
+	// Mode is how much of the bodies of the matching traffic is recorded.
+	Mode PayloadCaptureMode `json:"mode"`
+	// MaxBodyBytes is the maximum number of bytes recorded per body in the Full and Truncate modes.
+	MaxBodyBytes uint32 `json:"maxBodyBytes,omitempty"`
+}
+
+// LeadershipElectionParams specifies configurable values for the K8s leaderships elections which Vizier uses manage pod leadership.
+type LeadershipElectionParams struct {
+	// ElectionPeriodMs defines how frequently Vizier attempts to run a K8s leader election, in milliseconds. The period
+	// also determines how long Vizier waits for a leader election response back from the K8s API. If the K8s API is
+	// slow to respond, consider increasing this number.
+	ElectionPeriodMs int64 `json:"electionPeriodMs,omitempty"`
+}
+
+// WorkloadIdentity specifies the cloud provider identity that Vizier's service accounts act as. Only the settings
+// for the cloud provider that the cluster runs on need to be specified.
+type WorkloadIdentity struct {
+	// AWSRoleARN is the ARN of the IAM role to assume through EKS IAM roles for service accounts (IRSA).
+	AWSRoleARN string `json:"awsRoleARN,omitempty"`
+	// GCPServiceAccount is the email of the Google service account to act as through GKE Workload Identity.
+	GCPServiceAccount string `json:"gcpServiceAccount,omitempty"`
+	// AzureClientID is the client ID of the Azure AD application or managed identity to use through
+	// Azure Workload Identity.
+	AzureClientID string `json:"azureClientID,omitempty"`
+	// AzureTenantID is the Azure AD tenant of the application. If not specified, the cluster's tenant is used.
+	AzureTenantID string `json:"azureTenantID,omitempty"`
+}
+
+// NetworkPolicyProvider is the kind of policy resources used to restrict Vizier's traffic.
+// +kubebuilder:validation:Enum=kubernetes;cilium
+type NetworkPolicyProvider string
+
+const (
+	// NetworkPolicyProviderKubernetes uses standard K8s NetworkPolicies. Since these can't select traffic by domain,
+	// the control plane is allowed to reach any address on the API server and HTTPS ports.
+	NetworkPolicyProviderKubernetes NetworkPolicyProvider = "kubernetes"
+	// NetworkPolicyProviderCilium additionally uses a CiliumNetworkPolicy to limit the control plane's external
+	// traffic to the K8s API server and the Pixie cloud's domain.
+	NetworkPolicyProviderCilium NetworkPolicyProvider = "cilium"
+)
+
+// NetworkPolicy specifies the network policies which are deployed with the Vizier.
+type NetworkPolicy struct {
+	// Enabled specifies whether network policies should be deployed.
+	Enabled bool `json:"enabled,omitempty"`
+	// Provider is the kind of policy resources to deploy. Defaults to "kubernetes". With "cilium", any external
+	// endpoint other than the Pixie cloud, such as an object store for exports, must be allowed by another policy.
+	Provider NetworkPolicyProvider `json:"provider,omitempty"`
+}
+
+// NATSParams specifies how the Vizier services authenticate to NATS, and what they are permitted to do on it.
+// Regardless of these settings, NATS only accepts clients with a certificate signed by the Vizier's CA.
+type NATSParams struct {
+	// Authorization gives each Vizier service its own NATS user with a generated password, stored in the
+	// pl-nats-auth secret. Each user is only permitted to publish and subscribe to the subjects in its permissions.
+	// By default, the agents (PEMs and Kelvin) can't access the subjects bridged to Pixie Cloud, and the other
+	// services are unrestricted.
+	Authorization bool `json:"authorization,omitempty"`
+	// Permissions overrides the default permissions of the NATS users, keyed by user: agent, metadata, query-broker,
+	// cloud-connector or control-plane, which is the user of the single process control plane.
+	Permissions map[string]NATSPermissions `json:"permissions,omitempty"`
+}
+
+// NATSPermissions are the subjects that a NATS user may publish and subscribe to.
+type NATSPermissions struct {
+	// Publish restricts the subjects that the user may publish to.
+	Publish *NATSSubjectPermissions `json:"publish,omitempty"`
+	// Subscribe restricts the subjects that the user may subscribe to.
+	Subscribe *NATSSubjectPermissions `json:"subscribe,omitempty"`
+}
+
+// NATSSubjectPermissions lists the allowed and denied subjects, which may include NATS wildcards. If allow is
+// empty, all subjects that aren't denied are allowed.
+type NATSSubjectPermissions struct {
+	// Allow is the subjects that may be used.
+	Allow []string `json:"allow,omitempty"`
+	// Deny is the subjects that may not be used, even if they match an allowed subject.
+	Deny []string `json:"deny,omitempty"`
+}
+
+// AvailabilityParams specifies how Kelvin, the query broker, the metadata service and the cloud connector are
+// protected against voluntary disruptions.
+type AvailabilityParams struct {
+	// PodDisruptionBudget deploys a PodDisruptionBudget for each of the components, so that at most one of its pods
+	// is evicted at a time.
+	PodDisruptionBudget bool `json:"podDisruptionBudget,omitempty"`
+	// TopologyKey is the node label which the pods of each component are spread across, for example
+	// "kubernetes.io/hostname" or "topology.kubernetes.io/zone". The pods are not spread if this is unset.
+	TopologyKey string `json:"topologyKey,omitempty"`
+	// WhenUnsatisfiable specifies how pods are scheduled if they can't be spread evenly. Defaults to ScheduleAnyway.
+	WhenUnsatisfiable v1.UnsatisfiableConstraintAction `json:"whenUnsatisfiable,omitempty"`
+}
+
+// Vizier is the Schema for the viziers API
+// +genclient
+// +genclient:noStatus
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:storageversion
+type Vizier struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VizierSpec   `json:"spec,omitempty"`
+	Status VizierStatus `json:"status,omitempty"`
+}
+
+// SetReconciliationPhase updates the Vizier status with the given ReconciliationPhase.
+func (vz *Vizier) SetReconciliationPhase(rp ReconciliationPhase) {
+	vz.Status.ReconciliationPhase = rp
+	timeNow := metav1.Now()
+	vz.Status.LastReconciliationPhaseTime = &timeNow
+}
+
+// SetStatus updates the Vizier status with the given Reason.
+func (vz *Vizier) SetStatus(reason status.VizierReason) {
+	vz.Status.VizierPhase = ReasonToPhase(reason)
+	vz.Status.VizierReason = string(reason)
+	vz.Status.Message = reason.GetMessage()
+}
+
+// ReasonToPhase converts the Reason into the relevant Phase.
+func ReasonToPhase(reason status.VizierReason) VizierPhase {
+	switch reason {
+	case "":
+		return VizierPhaseHealthy
+	case status.CloudConnectorMissing:
+		return VizierPhaseDisconnected
+	case status.PEMsSomeInsufficientMemory, status.KernelVersionsIncompatible, status.PEMsHighFailureRate, status.NodesPartiallyMonitored,
+		status.ExportsFailing, status.MetadataDatastoreSizeAlarm:
+		return VizierPhaseDegraded
+	default:
+		return VizierPhaseUnhealthy
+	}
+}
+
+// VizierList contains a list of Vizier
+// +kubebuilder:object:root=true
+type VizierList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Vizier `json:"items"`
+}
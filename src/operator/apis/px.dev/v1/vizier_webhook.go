@@ -0,0 +1,173 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package v1
+
+import (
+	"crypto/x509"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/yaml"
+)
+
+// +kubebuilder:webhook:path=/mutate-px-dev-v1-vizier,mutating=true,failurePolicy=fail,sideEffects=None,groups=px.dev,resources=viziers,verbs=create;update,versions=v1,name=mvizier.px.dev,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-px-dev-v1-vizier,mutating=false,failurePolicy=fail,sideEffects=None,groups=px.dev,resources=viziers,verbs=create;update,versions=v1,name=vvizier.px.dev,admissionReviewVersions=v1
+
+// Default fills in the values that the operator otherwise assumes for unset fields, so that they are visible on the CR.
+func (vz *Vizier) Default() {
+	if vz.Spec.Profile == "" {
+		vz.Spec.Profile = DeploymentProfileDefault
+	}
+	if vz.Spec.ClockConverter == "" {
+		vz.Spec.ClockConverter = ClockConverterDefault
+	}
+	if vz.Spec.DataAccess == DataAccessUnknown {
+		vz.Spec.DataAccess = DataAccessFull
+	}
+	if vz.Spec.NetworkPolicy != nil && vz.Spec.NetworkPolicy.Enabled && vz.Spec.NetworkPolicy.Provider == "" {
+		vz.Spec.NetworkPolicy.Provider = NetworkPolicyProviderKubernetes
+	}
+	if a := vz.Spec.Availability; a != nil && a.TopologyKey != "" && a.WhenUnsatisfiable == "" {
+		a.WhenUnsatisfiable = corev1.ScheduleAnyway
+	}
+}
+
+// ValidateCreate validates a new Vizier.
+func (vz *Vizier) ValidateCreate() error {
+	errs := vz.validateSpec()
+	if vz.Spec.DeployKey == "" && vz.Spec.CustomDeployKeySecret == "" {
+		errs = append(errs, field.Required(field.NewPath("spec", "deployKey"),
+			"one of deployKey or customDeployKeySecret must be specified"))
+	}
+	return vz.toAPIError(errs)
+}
+
+// ValidateUpdate validates an update to a Vizier.
+func (vz *Vizier) ValidateUpdate(old runtime.Object) error {
+	oldVz, ok := old.(*Vizier)
+	if !ok {
+		return fmt.Errorf("expected a Vizier but got a %T", old)
+	}
+	errs := vz.validateSpec()
+	if oldVz.Spec.UseEtcdOperator != vz.Spec.UseEtcdOperator {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "useEtcdOperator"),
+			"the metadata storage can't be changed on a running Vizier, it must be redeployed"))
+	}
+	if oldVz.Spec.SingleProcessControlPlane != vz.Spec.SingleProcessControlPlane {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "singleProcessControlPlane"),
+			"the control plane mode can't be changed on a running Vizier, it must be redeployed"))
+	}
+	return vz.toAPIError(errs)
+}
+
+// ValidateDelete validates the deletion of a Vizier. Deletes are always allowed.
+func (vz *Vizier) ValidateDelete() error {
+	return nil
+}
+
+func (vz *Vizier) validateSpec() field.ErrorList {
+	var errs field.ErrorList
+	spec := field.NewPath("spec")
+
+	var limit, request *resource.Quantity
+	if vz.Spec.PemMemoryLimit != "" {
+		q, err := resource.ParseQuantity(vz.Spec.PemMemoryLimit)
+		if err != nil {
+			errs = append(errs, field.Invalid(spec.Child("pemMemoryLimit"), vz.Spec.PemMemoryLimit, err.Error()))
+		} else {
+			limit = &q
+		}
+	}
+	if vz.Spec.PemMemoryRequest != "" {
+		q, err := resource.ParseQuantity(vz.Spec.PemMemoryRequest)
+		if err != nil {
+			errs = append(errs, field.Invalid(spec.Child("pemMemoryRequest"), vz.Spec.PemMemoryRequest, err.Error()))
+		} else {
+			request = &q
+		}
+	}
+	if limit != nil && request != nil && request.Cmp(*limit) > 0 {
+		errs = append(errs, field.Invalid(spec.Child("pemMemoryRequest"), vz.Spec.PemMemoryRequest,
+			"must be less than or equal to pemMemoryLimit"))
+	}
+
+	for name, patch := range vz.Spec.Patches {
+		var obj map[string]interface{}
+		if err := yaml.Unmarshal([]byte(patch), &obj); err != nil {
+			errs = append(errs, field.Invalid(spec.Child("patches").Key(name), patch, "must be a valid strategic merge patch: "+err.Error()))
+		}
+	}
+
+	if p := vz.Spec.LeadershipElectionParams; p != nil && p.ElectionPeriodMs < 0 {
+		errs = append(errs, field.Invalid(spec.Child("leadershipElectionParams", "electionPeriodMs"), p.ElectionPeriodMs,
+			"must not be negative"))
+	}
+
+	if a := vz.Spec.Availability; a != nil && a.TopologyKey == "" && a.WhenUnsatisfiable != "" {
+		errs = append(errs, field.Invalid(spec.Child("availability", "whenUnsatisfiable"), a.WhenUnsatisfiable,
+			"requires topologyKey to be set"))
+	}
+
+	if p := vz.Spec.DataCollectorParams; p != nil && p.PayloadCapture != nil {
+		for i, r := range p.PayloadCapture.Rules {
+			if r.Mode == PayloadCaptureHeadersOnly && r.MaxBodyBytes != 0 {
+				errs = append(errs, field.Invalid(spec.Child("dataCollectorParams", "payloadCapture", "rules").Index(i).Child("maxBodyBytes"),
+					r.MaxBodyBytes, "must not be set when the mode is HeadersOnly"))
+			}
+		}
+	}
+
+	if n := vz.Spec.NATS; n != nil {
+		users := sets.NewString("agent", "metadata", "query-broker", "cloud-connector", "control-plane")
+		for user := range n.Permissions {
+			if !users.Has(user) {
+				errs = append(errs, field.NotSupported(spec.Child("nats", "permissions").Key(user), user, users.List()))
+			}
+		}
+	}
+
+	if vz.Spec.SingleProcessControlPlane && vz.Spec.UseEtcdOperator {
+		errs = append(errs, field.Invalid(spec.Child("useEtcdOperator"), vz.Spec.UseEtcdOperator,
+			"must not be set with singleProcessControlPlane, which keeps metadata on the control plane's local disk"))
+	}
+	if vz.Spec.SingleProcessControlPlane && vz.Spec.Autopilot {
+		errs = append(errs, field.Invalid(spec.Child("autopilot"), vz.Spec.Autopilot,
+			"must not be set with singleProcessControlPlane, which is not supported on GKE Autopilot"))
+	}
+
+	if t := vz.Spec.CloudTLS; t != nil && t.CABundle != "" {
+		if ok := x509.NewCertPool().AppendCertsFromPEM([]byte(t.CABundle)); !ok {
+			errs = append(errs, field.Invalid(spec.Child("cloudTLS", "caBundle"), "<PEM>",
+				"must contain at least one PEM-encoded certificate"))
+		}
+	}
+	return errs
+}
+
+func (vz *Vizier) toAPIError(errs field.ErrorList) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(SchemeGroupVersion.WithKind("Vizier").GroupKind(), vz.Name, errs)
+}
@@ -0,0 +1,183 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package v1_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+
+	v1 "px.dev/pixie/src/operator/apis/px.dev/v1"
+)
+
+func TestVizier_Default(t *testing.T) {
+	vz := &v1.Vizier{
+		Spec: v1.VizierSpec{
+			NetworkPolicy: &v1.NetworkPolicy{Enabled: true},
+			Availability:  &v1.AvailabilityParams{TopologyKey: "kubernetes.io/hostname"},
+		},
+	}
+	vz.Default()
+
+	assert.Equal(t, v1.DeploymentProfileDefault, vz.Spec.Profile)
+	assert.Equal(t, v1.ClockConverterDefault, vz.Spec.ClockConverter)
+	assert.Equal(t, v1.DataAccessFull, vz.Spec.DataAccess)
+	assert.Equal(t, v1.NetworkPolicyProviderKubernetes, vz.Spec.NetworkPolicy.Provider)
+	assert.Equal(t, corev1.ScheduleAnyway, vz.Spec.Availability.WhenUnsatisfiable)
+
+	// Explicit settings are kept.
+	vz = &v1.Vizier{Spec: v1.VizierSpec{DataAccess: v1.DataAccessRestricted, Profile: v1.DeploymentProfileSmall}}
+	vz.Default()
+	assert.Equal(t, v1.DataAccessRestricted, vz.Spec.DataAccess)
+	assert.Equal(t, v1.DeploymentProfileSmall, vz.Spec.Profile)
+}
+
+func TestVizier_ValidateCreate(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    v1.VizierSpec
+		wantErr string
+	}{
+		{
+			name: "valid",
+			spec: v1.VizierSpec{DeployKey: "key", PemMemoryLimit: "2Gi", PemMemoryRequest: "1Gi"},
+		},
+		{
+			name: "custom deploy key secret",
+			spec: v1.VizierSpec{CustomDeployKeySecret: "my-secret"},
+		},
+		{
+			name:    "missing deploy key",
+			spec:    v1.VizierSpec{},
+			wantErr: "spec.deployKey",
+		},
+		{
+			name:    "bad memory limit",
+			spec:    v1.VizierSpec{DeployKey: "key", PemMemoryLimit: "lots"},
+			wantErr: "spec.pemMemoryLimit",
+		},
+		{
+			name:    "request over limit",
+			spec:    v1.VizierSpec{DeployKey: "key", PemMemoryLimit: "1Gi", PemMemoryRequest: "2Gi"},
+			wantErr: "spec.pemMemoryRequest",
+		},
+		{
+			name:    "bad patch",
+			spec:    v1.VizierSpec{DeployKey: "key", Patches: map[string]string{"vizier-pem": "{not yaml"}},
+			wantErr: "spec.patches[vizier-pem]",
+		},
+		{
+			name:    "whenUnsatisfiable without topologyKey",
+			spec:    v1.VizierSpec{DeployKey: "key", Availability: &v1.AvailabilityParams{WhenUnsatisfiable: corev1.DoNotSchedule}},
+			wantErr: "spec.availability.whenUnsatisfiable",
+		},
+		{
+			name: "cloud TLS server name only",
+			spec: v1.VizierSpec{DeployKey: "key", CloudAddr: "10.0.0.1:443", CloudTLS: &v1.CloudTLS{ServerName: "withpixie.ai"}},
+		},
+		{
+			name:    "bad cloud CA bundle",
+			spec:    v1.VizierSpec{DeployKey: "key", CloudTLS: &v1.CloudTLS{CABundle: "not a cert"}},
+			wantErr: "spec.cloudTLS.caBundle",
+		},
+		{
+			name: "payload capture rules",
+			spec: v1.VizierSpec{DeployKey: "key", DataCollectorParams: &v1.DataCollectorParams{
+				PayloadCapture: &v1.PayloadCapturePolicy{Rules: []v1.PayloadCaptureRule{
+					{Namespace: "dev", Mode: v1.PayloadCaptureFull, MaxBodyBytes: 16384},
+					{Namespace: "payments", Mode: v1.PayloadCaptureHeadersOnly},
+				}},
+			}},
+		},
+		{
+			name: "headers only payload capture with body limit",
+			spec: v1.VizierSpec{DeployKey: "key", DataCollectorParams: &v1.DataCollectorParams{
+				PayloadCapture: &v1.PayloadCapturePolicy{Rules: []v1.PayloadCaptureRule{
+					{Namespace: "payments", Mode: v1.PayloadCaptureHeadersOnly, MaxBodyBytes: 1024},
+				}},
+			}},
+			wantErr: "spec.dataCollectorParams.payloadCapture.rules[0].maxBodyBytes",
+		},
+		{
+			name: "nats permissions",
+			spec: v1.VizierSpec{DeployKey: "key", NATS: &v1.NATSParams{
+				Authorization: true,
+				Permissions: map[string]v1.NATSPermissions{
+					"agent": {Publish: &v1.NATSSubjectPermissions{Allow: []string{"UpdateAgent"}}},
+				},
+			}},
+		},
+		{
+			name: "nats permissions for unknown user",
+			spec: v1.VizierSpec{DeployKey: "key", NATS: &v1.NATSParams{
+				Authorization: true,
+				Permissions:   map[string]v1.NATSPermissions{"pem": {}},
+			}},
+			wantErr: "spec.nats.permissions[pem]",
+		},
+		{
+			name: "single process control plane with nats authorization",
+			spec: v1.VizierSpec{DeployKey: "key", SingleProcessControlPlane: true, NATS: &v1.NATSParams{
+				Authorization: true,
+				Permissions:   map[string]v1.NATSPermissions{"control-plane": {}},
+			}},
+		},
+		{
+			name:    "single process control plane with etcd",
+			spec:    v1.VizierSpec{DeployKey: "key", SingleProcessControlPlane: true, UseEtcdOperator: true},
+			wantErr: "spec.useEtcdOperator",
+		},
+		{
+			name:    "single process control plane on autopilot",
+			spec:    v1.VizierSpec{DeployKey: "key", SingleProcessControlPlane: true, Autopilot: true},
+			wantErr: "spec.autopilot",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vz := &v1.Vizier{Spec: test.spec}
+			err := vz.ValidateCreate()
+			if test.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), test.wantErr)
+			}
+		})
+	}
+}
+
+func TestVizier_ValidateUpdate(t *testing.T) {
+	old := &v1.Vizier{Spec: v1.VizierSpec{DeployKey: "key", UseEtcdOperator: false}}
+
+	// The deploy key is only required on create.
+	assert.NoError(t, (&v1.Vizier{Spec: v1.VizierSpec{Version: "0.11.0"}}).ValidateUpdate(old))
+
+	err := (&v1.Vizier{Spec: v1.VizierSpec{DeployKey: "key", UseEtcdOperator: true}}).ValidateUpdate(old)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "spec.useEtcdOperator")
+	}
+
+	err = (&v1.Vizier{Spec: v1.VizierSpec{DeployKey: "key", SingleProcessControlPlane: true}}).ValidateUpdate(old)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "spec.singleProcessControlPlane")
+	}
+}
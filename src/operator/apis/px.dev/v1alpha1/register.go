@@ -16,7 +16,7 @@
  * SPDX-License-Identifier: Apache-2.0
  */
 
-// Package v1alpha1 contains API Schema definitions for the pixie v1 API group
+// Package v1alpha1 contains API Schema definitions for the pixie v1alpha1 API group
 // +kubebuilder:object:generate=true
 // +groupName=px.dev
 package v1alpha1
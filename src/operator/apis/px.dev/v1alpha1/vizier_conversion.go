@@ -0,0 +1,65 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	v1 "px.dev/pixie/src/operator/apis/px.dev/v1"
+)
+
+// ConvertTo converts this Vizier to the v1 hub version.
+func (vz *Vizier) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v1.Vizier)
+	if !ok {
+		return fmt.Errorf("cannot convert a v1alpha1 Vizier to %T", dstRaw)
+	}
+	dst.ObjectMeta = vz.ObjectMeta
+	if err := convertFields(&vz.Spec, &dst.Spec); err != nil {
+		return err
+	}
+	return convertFields(&vz.Status, &dst.Status)
+}
+
+// ConvertFrom converts from the v1 hub version to this Vizier.
+func (vz *Vizier) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v1.Vizier)
+	if !ok {
+		return fmt.Errorf("cannot convert %T to a v1alpha1 Vizier", srcRaw)
+	}
+	vz.ObjectMeta = src.ObjectMeta
+	if err := convertFields(&src.Spec, &vz.Spec); err != nil {
+		return err
+	}
+	return convertFields(&src.Status, &vz.Status)
+}
+
+// convertFields copies between the v1alpha1 and v1 versions of a struct by their JSON field names. The two versions
+// have the same schema, so every field carries over. Fields which are renamed or restructured in a later version
+// need to be converted explicitly after this.
+func convertFields(src, dst interface{}) error {
+	b, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, dst)
+}
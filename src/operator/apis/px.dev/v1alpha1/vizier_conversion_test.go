@@ -0,0 +1,75 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package v1alpha1_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "px.dev/pixie/src/operator/apis/px.dev/v1"
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+func TestVizier_ConvertRoundTrip(t *testing.T) {
+	// metav1.Time is serialized with second precision, the same as when it is stored.
+	now := metav1.NewTime(time.Unix(1650000000, 0))
+	vz := &v1alpha1.Vizier{
+		ObjectMeta: metav1.ObjectMeta{Name: "pixie", Namespace: "pl", Labels: map[string]string{"a": "b"}},
+		Spec: v1alpha1.VizierSpec{
+			Version:        "0.10.0",
+			DeployKey:      "key",
+			ClusterName:    "test-cluster",
+			PemMemoryLimit: "2Gi",
+			DataAccess:     v1alpha1.DataAccessRestricted,
+			Pod: &v1alpha1.PodPolicy{
+				Labels:          map[string]string{"c": "d"},
+				SecurityContext: &v1alpha1.PodSecurityContext{Enabled: true, RunAsUser: 1000},
+			},
+			Patches:             map[string]string{"vizier-pem": `{"spec": {}}`},
+			DataCollectorParams: &v1alpha1.DataCollectorParams{CustomPEMFlags: map[string]string{"PL_FLAG": "1"}},
+			NetworkPolicy:       &v1alpha1.NetworkPolicy{Enabled: true, Provider: v1alpha1.NetworkPolicyProviderCilium},
+		},
+		Status: v1alpha1.VizierStatus{
+			Version:                     "0.10.0",
+			VizierPhase:                 v1alpha1.VizierPhaseHealthy,
+			ReconciliationPhase:         v1alpha1.ReconciliationPhaseReady,
+			LastReconciliationPhaseTime: &now,
+		},
+	}
+
+	hub := &v1.Vizier{}
+	require.NoError(t, vz.ConvertTo(hub))
+	assert.Equal(t, "pixie", hub.Name)
+	assert.Equal(t, "0.10.0", hub.Spec.Version)
+	assert.Equal(t, v1.DataAccessRestricted, hub.Spec.DataAccess)
+	assert.Equal(t, int64(1000), hub.Spec.Pod.SecurityContext.RunAsUser)
+	assert.Equal(t, v1.NetworkPolicyProviderCilium, hub.Spec.NetworkPolicy.Provider)
+	assert.Equal(t, v1.VizierPhaseHealthy, hub.Status.VizierPhase)
+
+	back := &v1alpha1.Vizier{}
+	require.NoError(t, back.ConvertFrom(hub))
+	assert.Equal(t, vz.ObjectMeta, back.ObjectMeta)
+	assert.Equal(t, vz.Spec, back.Spec)
+	assert.Equal(t, vz.Status.Version, back.Status.Version)
+	assert.True(t, vz.Status.LastReconciliationPhaseTime.Equal(back.Status.LastReconciliationPhaseTime))
+}
@@ -16,12 +16,8 @@
  * SPDX-License-Identifier: Apache-2.0
  */
 
-// Generate the code for deep-copying the CRD in go.
+// Generate the code for deep-copying the CRD in go. The CRD and clientset are generated from the v1 package.
 //go:generate controller-gen object
-// Generate the CRD YAMLs.
-//go:generate controller-gen crd:trivialVersions=true rbac:roleName=operator-role webhook output:crd:artifacts:config=crd output:crd:dir:=../../../../../k8s/operator/crd/base
-// Generate the clientset.
-//go:generate client-gen --input=px.dev/v1alpha1 --clientset-name=versioned --go-header-file=/dev/null --input-base=px.dev/pixie/src/operator/apis --output-package=px.dev/pixie/src/operator/client
 
 package v1alpha1
 
@@ -45,11 +41,19 @@ type VizierSpec struct {
 	DisableAutoUpdate bool `json:"disableAutoUpdate,omitempty"`
 	// UseEtcdOperator specifies whether the metadata service should use etcd for storage.
 	UseEtcdOperator bool `json:"useEtcdOperator,omitempty"`
+	// SingleProcessControlPlane runs the metadata service, the query broker, the cloud connector and NATS in a single
+	// pod, for small and edge clusters. Metadata is kept on the pod's local disk, and is rebuilt from the agents and
+	// K8s when the pod is rescheduled. This can't be combined with UseEtcdOperator, and can't be changed once the
+	// Vizier is deployed.
+	SingleProcessControlPlane bool `json:"singleProcessControlPlane,omitempty"`
 	// ClusterName is a name for the Vizier instance, usually specifying which cluster the Vizier is
 	// deployed to. If not specified, a random name will be generated.
 	ClusterName string `json:"clusterName,omitempty"`
 	// CloudAddr is the address of the cloud instance that the Vizier should be pointing to.
 	CloudAddr string `json:"cloudAddr,omitempty"`
+	// CloudTLS overrides how the Vizier verifies the cloud's certificate. This allows CloudAddr to be an IP, for clusters
+	// which can reach the cloud at a fixed address but can't resolve its name.
+	CloudTLS *CloudTLS `json:"cloudTLS,omitempty"`
 	// DevCloudNamespace should be specified only for dev versions of Pixie cloud which have no ingress to help
 	// redirect traffic to the correct service. The DevCloudNamespace is the namespace that the dev Pixie cloud is
 	// running on, for example: "plc-dev".
@@ -81,8 +85,46 @@ type VizierSpec struct {
 	Registry string `json:"registry,omitempty"`
 	// Autopilot should be set if running Pixie on GKE Autopilot.
 	Autopilot bool `json:"autopilot,omitempty"`
+	// Profile is a preset of resource settings for the Vizier. Settings which are specified explicitly take precedence
+	// over the ones from the profile.
+	Profile DeploymentProfile `json:"profile,omitempty"`
+	// WorkloadIdentity binds the Vizier components which access the cloud provider, such as the query broker when it
+	// exports to object storage, to a cloud identity. This is used instead of static credentials.
+	WorkloadIdentity *WorkloadIdentity `json:"workloadIdentity,omitempty"`
+	// FIPSMode restricts the Vizier services to FIPS approved cryptography, including the TLS cipher suites used by
+	// NATS and gRPC. The Go services are run from their images built with BoringCrypto.
+	FIPSMode bool `json:"fipsMode,omitempty"`
+	// NetworkPolicy deploys NetworkPolicies which only allow the traffic that Vizier needs, for clusters which deny
+	// traffic by default.
+	NetworkPolicy *NetworkPolicy `json:"networkPolicy,omitempty"`
+	// NATS hardens the NATS message bus that the Vizier services communicate over.
+	NATS *NATSParams `json:"nats,omitempty"`
+	// Availability protects the Vizier's query path against voluntary disruptions, such as node maintenance.
+	Availability *AvailabilityParams `json:"availability,omitempty"`
 }
 
+// CloudTLS specifies how the Vizier verifies the cloud's TLS certificate.
+type CloudTLS struct {
+	// ServerName is the name that is sent as the TLS SNI and that the cloud's certificate is verified against. If not
+	// specified, the host of CloudAddr is used.
+	ServerName string `json:"serverName,omitempty"`
+	// CABundle is a PEM encoded bundle of the CAs that the cloud's certificate is verified against, instead of the
+	// system's roots.
+	CABundle string `json:"caBundle,omitempty"`
+}
+
+// DeploymentProfile defines a preset of resource settings for the Vizier.
+// +kubebuilder:validation:Enum=default;small
+type DeploymentProfile string
+
+const (
+	// DeploymentProfileDefault uses the default resource settings.
+	DeploymentProfileDefault DeploymentProfile = "default"
+	// DeploymentProfileSmall reduces the footprint of Vizier for edge and single-node clusters, such as k3s and microk8s.
+	// It lowers the PEM memory and table store sizes, and disables the continuous profiler.
+	DeploymentProfileSmall DeploymentProfile = "small"
+)
+
 // DataAccessLevel defines the levels of data access that can be used when executing a script on a cluster.
 // +kubebuilder:validation:Enum=Full;Restricted
 type DataAccessLevel string
@@ -134,6 +176,50 @@ type VizierStatus struct {
 	Checksum []byte `json:"checksum,omitempty"`
 	// OperatorVersion is the actual version of the Operator instance.
 	OperatorVersion string `json:"operatorVersion,omitempty"`
+	// ExportHealth summarizes the deliveries of the cron scripts which export data, as reported by the query broker.
+	ExportHealth *ExportHealthStatus `json:"exportHealth,omitempty"`
+	// DatastoreHealth summarizes the size and maintenance of the metadata datastore, as reported by the metadata
+	// service.
+	DatastoreHealth *DatastoreHealthStatus `json:"datastoreHealth,omitempty"`
+}
+
+// ExportHealthStatus is the health of the exports of the cron scripts, such as to OpenTelemetry or object storage.
+type ExportHealthStatus struct {
+	// FailingExports is the number of exports that failed every retry of a delivery since they last succeeded.
+	FailingExports int `json:"failingExports,omitempty"`
+	// PendingRetries is the number of failed deliveries which are waiting to be retried.
+	PendingRetries int `json:"pendingRetries,omitempty"`
+	// DeadLetters is the number of deliveries which were given up on after exhausting their retries.
+	DeadLetters int `json:"deadLetters,omitempty"`
+	// DroppedDeadLetters is the number of dead letters which were evicted because the buffer was full.
+	DroppedDeadLetters int64 `json:"droppedDeadLetters,omitempty"`
+	// LastError is the error of the most recent failed delivery.
+	LastError string `json:"lastError,omitempty"`
+	// LastFailureTime is the time of the most recent failed delivery.
+	LastFailureTime *metav1.Time `json:"lastFailureTime,omitempty"`
+}
+
+// DatastoreHealthStatus is the health of the metadata datastore, which rejects metadata writes once it is full.
+type DatastoreHealthStatus struct {
+	// Backend is the kind of datastore, either etcd or pebble.
+	Backend string `json:"backend,omitempty"`
+	// SizeBytes is the space that the datastore occupies on disk.
+	SizeBytes int64 `json:"sizeBytes,omitempty"`
+	// InUseBytes is the part of SizeBytes that is used by live data. The rest is reclaimed by defragmenting.
+	InUseBytes int64 `json:"inUseBytes,omitempty"`
+	// QuotaBytes is the size past which the datastore rejects writes: the etcd quota, or the size of the metadata
+	// volume.
+	QuotaBytes int64 `json:"quotaBytes,omitempty"`
+	// SizeAlarm is set when the datastore is close to its quota, or when the datastore itself raised an alarm.
+	SizeAlarm bool `json:"sizeAlarm,omitempty"`
+	// Alarms are the alarms raised by the datastore itself, such as etcd's NOSPACE.
+	Alarms []string `json:"alarms,omitempty"`
+	// LastCompactionTime is the time of the most recent compaction.
+	LastCompactionTime *metav1.Time `json:"lastCompactionTime,omitempty"`
+	// LastDefragTime is the time of the most recent defragmentation.
+	LastDefragTime *metav1.Time `json:"lastDefragTime,omitempty"`
+	// LastError is the error of the most recent round of maintenance, if it failed.
+	LastError string `json:"lastError,omitempty"`
 }
 
 // VizierPhase is a high-level summary of where the Vizier is in its lifecycle.
@@ -215,6 +301,47 @@ type DataCollectorParams struct {
 	DatastreamBufferSpikeSize uint32 `json:"datastreamBufferSpikeSize,omitempty"`
 	// This contains custom flags that should be passed to the PEM via environment variables.
 	CustomPEMFlags map[string]string `json:"customPEMFlags,omitempty"`
+	// PayloadCapture controls how much of the request and response bodies the PEMs record, per namespace, service
+	// and protocol. If not specified, bodies are truncated to the PEM's default limit.
+	PayloadCapture *PayloadCapturePolicy `json:"payloadCapture,omitempty"`
+}
+
+// PayloadCaptureMode is how much of the bodies of traced requests and responses are recorded.
+// +kubebuilder:validation:Enum=Full;Truncate;HeadersOnly
+type PayloadCaptureMode string
+
+const (
+	// PayloadCaptureFull records bodies up to maxBodyBytes, or 64KiB if unset.
+	PayloadCaptureFull PayloadCaptureMode = "Full"
+	// PayloadCaptureTruncate records bodies truncated to maxBodyBytes, or the PEM's default limit if unset.
+	PayloadCaptureTruncate PayloadCaptureMode = "Truncate"
+	// PayloadCaptureHeadersOnly records the headers and metadata of requests and responses, but none of the bodies.
+	PayloadCaptureHeadersOnly PayloadCaptureMode = "HeadersOnly"
+)
+
+// PayloadCapturePolicy decides how much of the payloads of traced traffic is recorded, so that deep payload capture
+// can be enabled only for the workloads where it is safe.
+type PayloadCapturePolicy struct {
+	// DefaultMode applies to traffic that matches none of the rules. Defaults to Truncate.
+	DefaultMode PayloadCaptureMode `json:"defaultMode,omitempty"`
+	// Rules are evaluated in order, and the first rule that matches the traffic decides its mode.
+	Rules []PayloadCaptureRule `json:"rules,omitempty"`
+}
+
+// PayloadCaptureRule sets the payload capture mode of the traffic of a namespace, service, or protocol.
+// Unset fields match all traffic.
+type PayloadCaptureRule struct {
+	// Namespace is the namespace of the traced pods.
+	Namespace string `json:"namespace,omitempty"`
+	// Service is the name of a service that the traced pods belong to.
+	Service string `json:"service,omitempty"`
+	// Protocol is the traced protocol.
+	// +kubebuilder:validation:Enum=http;http2;mysql;cql;pgsql;redis;nats;kafka;amqp
+	Protocol string `json:"protocol,omitempty"`
+	// Mode is how much of the bodies of the matching traffic is recorded.
+	Mode PayloadCaptureMode `json:"mode"`
+	// MaxBodyBytes is the maximum number of bytes recorded per body in the Full and Truncate modes.
+	MaxBodyBytes uint32 `json:"maxBodyBytes,omitempty"`
 }
 
 // LeadershipElectionParams specifies configurable values for the K8s leaderships elections which Vizier uses manage pod leadership.
@@ -225,6 +352,85 @@ type LeadershipElectionParams struct {
 	ElectionPeriodMs int64 `json:"electionPeriodMs,omitempty"`
 }
 
+// WorkloadIdentity specifies the cloud provider identity that Vizier's service accounts act as. Only the settings
+// for the cloud provider that the cluster runs on need to be specified.
+type WorkloadIdentity struct {
+	// AWSRoleARN is the ARN of the IAM role to assume through EKS IAM roles for service accounts (IRSA).
+	AWSRoleARN string `json:"awsRoleARN,omitempty"`
+	// GCPServiceAccount is the email of the Google service account to act as through GKE Workload Identity.
+	GCPServiceAccount string `json:"gcpServiceAccount,omitempty"`
+	// AzureClientID is the client ID of the Azure AD application or managed identity to use through
+	// Azure Workload Identity.
+	AzureClientID string `json:"azureClientID,omitempty"`
+	// AzureTenantID is the Azure AD tenant of the application. If not specified, the cluster's tenant is used.
+	AzureTenantID string `json:"azureTenantID,omitempty"`
+}
+
+// NetworkPolicyProvider is the kind of policy resources used to restrict Vizier's traffic.
+// +kubebuilder:validation:Enum=kubernetes;cilium
+type NetworkPolicyProvider string
+
+const (
+	// NetworkPolicyProviderKubernetes uses standard K8s NetworkPolicies. Since these can't select traffic by domain,
+	// the control plane is allowed to reach any address on the API server and HTTPS ports.
+	NetworkPolicyProviderKubernetes NetworkPolicyProvider = "kubernetes"
+	// NetworkPolicyProviderCilium additionally uses a CiliumNetworkPolicy to limit the control plane's external
+	// traffic to the K8s API server and the Pixie cloud's domain.
+	NetworkPolicyProviderCilium NetworkPolicyProvider = "cilium"
+)
+
+// NetworkPolicy specifies the network policies which are deployed with the Vizier.
+type NetworkPolicy struct {
+	// Enabled specifies whether network policies should be deployed.
+	Enabled bool `json:"enabled,omitempty"`
+	// Provider is the kind of policy resources to deploy. Defaults to "kubernetes". With "cilium", any external
+	// endpoint other than the Pixie cloud, such as an object store for exports, must be allowed by another policy.
+	Provider NetworkPolicyProvider `json:"provider,omitempty"`
+}
+
+// NATSParams specifies how the Vizier services authenticate to NATS, and what they are permitted to do on it.
+// Regardless of these settings, NATS only accepts clients with a certificate signed by the Vizier's CA.
+type NATSParams struct {
+	// Authorization gives each Vizier service its own NATS user with a generated password, stored in the
+	// pl-nats-auth secret. Each user is only permitted to publish and subscribe to the subjects in its permissions.
+	// By default, the agents (PEMs and Kelvin) can't access the subjects bridged to Pixie Cloud, and the other
+	// services are unrestricted.
+	Authorization bool `json:"authorization,omitempty"`
+	// Permissions overrides the default permissions of the NATS users, keyed by user: agent, metadata, query-broker,
+	// cloud-connector or control-plane, which is the user of the single process control plane.
+	Permissions map[string]NATSPermissions `json:"permissions,omitempty"`
+}
+
+// NATSPermissions are the subjects that a NATS user may publish and subscribe to.
+type NATSPermissions struct {
+	// Publish restricts the subjects that the user may publish to.
+	Publish *NATSSubjectPermissions `json:"publish,omitempty"`
+	// Subscribe restricts the subjects that the user may subscribe to.
+	Subscribe *NATSSubjectPermissions `json:"subscribe,omitempty"`
+}
+
+// NATSSubjectPermissions lists the allowed and denied subjects, which may include NATS wildcards. If allow is
+// empty, all subjects that aren't denied are allowed.
+type NATSSubjectPermissions struct {
+	// Allow is the subjects that may be used.
+	Allow []string `json:"allow,omitempty"`
+	// Deny is the subjects that may not be used, even if they match an allowed subject.
+	Deny []string `json:"deny,omitempty"`
+}
+
+// AvailabilityParams specifies how Kelvin, the query broker, the metadata service and the cloud connector are
+// protected against voluntary disruptions.
+type AvailabilityParams struct {
+	// PodDisruptionBudget deploys a PodDisruptionBudget for each of the components, so that at most one of its pods
+	// is evicted at a time.
+	PodDisruptionBudget bool `json:"podDisruptionBudget,omitempty"`
+	// TopologyKey is the node label which the pods of each component are spread across, for example
+	// "kubernetes.io/hostname" or "topology.kubernetes.io/zone". The pods are not spread if this is unset.
+	TopologyKey string `json:"topologyKey,omitempty"`
+	// WhenUnsatisfiable specifies how pods are scheduled if they can't be spread evenly. Defaults to ScheduleAnyway.
+	WhenUnsatisfiable v1.UnsatisfiableConstraintAction `json:"whenUnsatisfiable,omitempty"`
+}
+
 // Vizier is the Schema for the viziers API
 // +genclient
 // +genclient:noStatus
@@ -259,7 +465,8 @@ func ReasonToPhase(reason status.VizierReason) VizierPhase {
 		return VizierPhaseHealthy
 	case status.CloudConnectorMissing:
 		return VizierPhaseDisconnected
-	case status.PEMsSomeInsufficientMemory, status.KernelVersionsIncompatible, status.PEMsHighFailureRate:
+	case status.PEMsSomeInsufficientMemory, status.KernelVersionsIncompatible, status.PEMsHighFailureRate, status.NodesPartiallyMonitored,
+		status.ExportsFailing, status.MetadataDatastoreSizeAlarm:
 		return VizierPhaseDegraded
 	default:
 		return VizierPhaseUnhealthy
@@ -9,6 +9,36 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AvailabilityParams) DeepCopyInto(out *AvailabilityParams) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AvailabilityParams.
+func (in *AvailabilityParams) DeepCopy() *AvailabilityParams {
+	if in == nil {
+		return nil
+	}
+	out := new(AvailabilityParams)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CloudTLS) DeepCopyInto(out *CloudTLS) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloudTLS.
+func (in *CloudTLS) DeepCopy() *CloudTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(CloudTLS)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DataCollectorParams) DeepCopyInto(out *DataCollectorParams) {
 	*out = *in
@@ -19,6 +49,11 @@ func (in *DataCollectorParams) DeepCopyInto(out *DataCollectorParams) {
 			(*out)[key] = val
 		}
 	}
+	if in.PayloadCapture != nil {
+		in, out := &in.PayloadCapture, &out.PayloadCapture
+		*out = new(PayloadCapturePolicy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataCollectorParams.
@@ -31,6 +66,53 @@ func (in *DataCollectorParams) DeepCopy() *DataCollectorParams {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatastoreHealthStatus) DeepCopyInto(out *DatastoreHealthStatus) {
+	*out = *in
+	if in.Alarms != nil {
+		in, out := &in.Alarms, &out.Alarms
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastCompactionTime != nil {
+		in, out := &in.LastCompactionTime, &out.LastCompactionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastDefragTime != nil {
+		in, out := &in.LastDefragTime, &out.LastDefragTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatastoreHealthStatus.
+func (in *DatastoreHealthStatus) DeepCopy() *DatastoreHealthStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatastoreHealthStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExportHealthStatus) DeepCopyInto(out *ExportHealthStatus) {
+	*out = *in
+	if in.LastFailureTime != nil {
+		in, out := &in.LastFailureTime, &out.LastFailureTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExportHealthStatus.
+func (in *ExportHealthStatus) DeepCopy() *ExportHealthStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ExportHealthStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LeadershipElectionParams) DeepCopyInto(out *LeadershipElectionParams) {
 	*out = *in
@@ -46,6 +128,128 @@ func (in *LeadershipElectionParams) DeepCopy() *LeadershipElectionParams {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NATSParams) DeepCopyInto(out *NATSParams) {
+	*out = *in
+	if in.Permissions != nil {
+		in, out := &in.Permissions, &out.Permissions
+		*out = make(map[string]NATSPermissions, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NATSParams.
+func (in *NATSParams) DeepCopy() *NATSParams {
+	if in == nil {
+		return nil
+	}
+	out := new(NATSParams)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NATSPermissions) DeepCopyInto(out *NATSPermissions) {
+	*out = *in
+	if in.Publish != nil {
+		in, out := &in.Publish, &out.Publish
+		*out = new(NATSSubjectPermissions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Subscribe != nil {
+		in, out := &in.Subscribe, &out.Subscribe
+		*out = new(NATSSubjectPermissions)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NATSPermissions.
+func (in *NATSPermissions) DeepCopy() *NATSPermissions {
+	if in == nil {
+		return nil
+	}
+	out := new(NATSPermissions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NATSSubjectPermissions) DeepCopyInto(out *NATSSubjectPermissions) {
+	*out = *in
+	if in.Allow != nil {
+		in, out := &in.Allow, &out.Allow
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Deny != nil {
+		in, out := &in.Deny, &out.Deny
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NATSSubjectPermissions.
+func (in *NATSSubjectPermissions) DeepCopy() *NATSSubjectPermissions {
+	if in == nil {
+		return nil
+	}
+	out := new(NATSSubjectPermissions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkPolicy) DeepCopyInto(out *NetworkPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkPolicy.
+func (in *NetworkPolicy) DeepCopy() *NetworkPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PayloadCapturePolicy) DeepCopyInto(out *PayloadCapturePolicy) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]PayloadCaptureRule, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PayloadCapturePolicy.
+func (in *PayloadCapturePolicy) DeepCopy() *PayloadCapturePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(PayloadCapturePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PayloadCaptureRule) DeepCopyInto(out *PayloadCaptureRule) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PayloadCaptureRule.
+func (in *PayloadCaptureRule) DeepCopy() *PayloadCaptureRule {
+	if in == nil {
+		return nil
+	}
+	out := new(PayloadCaptureRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PodPolicy) DeepCopyInto(out *PodPolicy) {
 	*out = *in
@@ -165,6 +369,11 @@ func (in *VizierList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VizierSpec) DeepCopyInto(out *VizierSpec) {
 	*out = *in
+	if in.CloudTLS != nil {
+		in, out := &in.CloudTLS, &out.CloudTLS
+		*out = new(CloudTLS)
+		**out = **in
+	}
 	if in.Pod != nil {
 		in, out := &in.Pod, &out.Pod
 		*out = new(PodPolicy)
@@ -187,6 +396,26 @@ func (in *VizierSpec) DeepCopyInto(out *VizierSpec) {
 		*out = new(LeadershipElectionParams)
 		**out = **in
 	}
+	if in.WorkloadIdentity != nil {
+		in, out := &in.WorkloadIdentity, &out.WorkloadIdentity
+		*out = new(WorkloadIdentity)
+		**out = **in
+	}
+	if in.NetworkPolicy != nil {
+		in, out := &in.NetworkPolicy, &out.NetworkPolicy
+		*out = new(NetworkPolicy)
+		**out = **in
+	}
+	if in.NATS != nil {
+		in, out := &in.NATS, &out.NATS
+		*out = new(NATSParams)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Availability != nil {
+		in, out := &in.Availability, &out.Availability
+		*out = new(AvailabilityParams)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VizierSpec.
@@ -211,6 +440,16 @@ func (in *VizierStatus) DeepCopyInto(out *VizierStatus) {
 		*out = make([]byte, len(*in))
 		copy(*out, *in)
 	}
+	if in.ExportHealth != nil {
+		in, out := &in.ExportHealth, &out.ExportHealth
+		*out = new(ExportHealthStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DatastoreHealth != nil {
+		in, out := &in.DatastoreHealth, &out.DatastoreHealth
+		*out = new(DatastoreHealthStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VizierStatus.
@@ -222,3 +461,18 @@ func (in *VizierStatus) DeepCopy() *VizierStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkloadIdentity) DeepCopyInto(out *WorkloadIdentity) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadIdentity.
+func (in *WorkloadIdentity) DeepCopy() *WorkloadIdentity {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkloadIdentity)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -8,12 +8,14 @@ import (
 	discovery "k8s.io/client-go/discovery"
 	rest "k8s.io/client-go/rest"
 	flowcontrol "k8s.io/client-go/util/flowcontrol"
+	pxv1 "px.dev/pixie/src/operator/client/versioned/typed/px.dev/v1"
 	pxv1alpha1 "px.dev/pixie/src/operator/client/versioned/typed/px.dev/v1alpha1"
 )
 
 type Interface interface {
 	Discovery() discovery.DiscoveryInterface
 	PxV1alpha1() pxv1alpha1.PxV1alpha1Interface
+	PxV1() pxv1.PxV1Interface
 }
 
 // Clientset contains the clients for groups. Each group has exactly one
@@ -21,6 +23,7 @@ type Interface interface {
 type Clientset struct {
 	*discovery.DiscoveryClient
 	pxV1alpha1 *pxv1alpha1.PxV1alpha1Client
+	pxV1       *pxv1.PxV1Client
 }
 
 // PxV1alpha1 retrieves the PxV1alpha1Client
@@ -28,6 +31,11 @@ func (c *Clientset) PxV1alpha1() pxv1alpha1.PxV1alpha1Interface {
 	return c.pxV1alpha1
 }
 
+// PxV1 retrieves the PxV1Client
+func (c *Clientset) PxV1() pxv1.PxV1Interface {
+	return c.pxV1
+}
+
 // Discovery retrieves the DiscoveryClient
 func (c *Clientset) Discovery() discovery.DiscoveryInterface {
 	if c == nil {
@@ -53,6 +61,10 @@ func NewForConfig(c *rest.Config) (*Clientset, error) {
 	if err != nil {
 		return nil, err
 	}
+	cs.pxV1, err = pxv1.NewForConfig(&configShallowCopy)
+	if err != nil {
+		return nil, err
+	}
 
 	cs.DiscoveryClient, err = discovery.NewDiscoveryClientForConfig(&configShallowCopy)
 	if err != nil {
@@ -66,6 +78,7 @@ func NewForConfig(c *rest.Config) (*Clientset, error) {
 func NewForConfigOrDie(c *rest.Config) *Clientset {
 	var cs Clientset
 	cs.pxV1alpha1 = pxv1alpha1.NewForConfigOrDie(c)
+	cs.pxV1 = pxv1.NewForConfigOrDie(c)
 
 	cs.DiscoveryClient = discovery.NewDiscoveryClientForConfigOrDie(c)
 	return &cs
@@ -75,6 +88,7 @@ func NewForConfigOrDie(c *rest.Config) *Clientset {
 func New(c rest.Interface) *Clientset {
 	var cs Clientset
 	cs.pxV1alpha1 = pxv1alpha1.New(c)
+	cs.pxV1 = pxv1.New(c)
 
 	cs.DiscoveryClient = discovery.NewDiscoveryClient(c)
 	return &cs
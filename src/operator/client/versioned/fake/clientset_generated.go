@@ -9,6 +9,8 @@ import (
 	fakediscovery "k8s.io/client-go/discovery/fake"
 	"k8s.io/client-go/testing"
 	clientset "px.dev/pixie/src/operator/client/versioned"
+	pxv1 "px.dev/pixie/src/operator/client/versioned/typed/px.dev/v1"
+	fakepxv1 "px.dev/pixie/src/operator/client/versioned/typed/px.dev/v1/fake"
 	pxv1alpha1 "px.dev/pixie/src/operator/client/versioned/typed/px.dev/v1alpha1"
 	fakepxv1alpha1 "px.dev/pixie/src/operator/client/versioned/typed/px.dev/v1alpha1/fake"
 )
@@ -64,3 +66,8 @@ var _ clientset.Interface = &Clientset{}
 func (c *Clientset) PxV1alpha1() pxv1alpha1.PxV1alpha1Interface {
 	return &fakepxv1alpha1.FakePxV1alpha1{Fake: &c.Fake}
 }
+
+// PxV1 retrieves the PxV1Client
+func (c *Clientset) PxV1() pxv1.PxV1Interface {
+	return &fakepxv1.FakePxV1{Fake: &c.Fake}
+}
@@ -8,6 +8,7 @@ import (
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
 	serializer "k8s.io/apimachinery/pkg/runtime/serializer"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	pxv1 "px.dev/pixie/src/operator/apis/px.dev/v1"
 	pxv1alpha1 "px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
 )
 
@@ -16,6 +17,7 @@ var Codecs = serializer.NewCodecFactory(Scheme)
 var ParameterCodec = runtime.NewParameterCodec(Scheme)
 var localSchemeBuilder = runtime.SchemeBuilder{
 	pxv1alpha1.AddToScheme,
+	pxv1.AddToScheme,
 }
 
 // AddToScheme adds all types of this clientset into the given scheme. This allows composition
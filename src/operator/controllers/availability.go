@@ -0,0 +1,175 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/blang/semver"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+// podDisruptionBudgetLabel is set on the PodDisruptionBudgets deployed by the operator, so that they can be
+// cleaned up when they are disabled.
+const podDisruptionBudgetLabel = "vizier-pdb"
+
+// availabilityComponents are the components on the query path, which are protected by the availability settings.
+// Each component's pods are selected by their "name" label, which matches the name of the component.
+var availabilityComponents = []string{"kelvin", queryBrokerDeployment, "vizier-metadata", "vizier-cloud-connector"}
+
+// topologySpreadPatches returns the patches which spread the pods of each component across the topology in the spec.
+func topologySpreadPatches(spec *v1alpha1.VizierSpec) map[string]string {
+	if spec.Availability == nil || spec.Availability.TopologyKey == "" {
+		return nil
+	}
+
+	whenUnsatisfiable := spec.Availability.WhenUnsatisfiable
+	if whenUnsatisfiable == "" {
+		whenUnsatisfiable = v1.ScheduleAnyway
+	}
+
+	patches := make(map[string]interface{})
+	for _, name := range availabilityComponents {
+		patches[name] = map[string]interface{}{
+			"spec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"topologySpreadConstraints": []interface{}{
+							map[string]interface{}{
+								"maxSkew":           1,
+								"topologyKey":       spec.Availability.TopologyKey,
+								"whenUnsatisfiable": whenUnsatisfiable,
+								"labelSelector": map[string]interface{}{
+									"matchLabels": map[string]string{"name": name},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	return marshalPatches(patches)
+}
+
+// useBetaPDBVersion returns whether the cluster only supports the beta PodDisruptionBudget API.
+func useBetaPDBVersion(k8sVersion string) bool {
+	if k8sVersion == "" {
+		return false
+	}
+	// podDisruptionBudget graduated from beta to stable as of v1.21.
+	currentK8sVers, err := semver.ParseTolerant(k8sVersion)
+	if err != nil {
+		return false
+	}
+	return currentK8sVers.LT(semver.Version{Major: 1, Minor: 21})
+}
+
+// generatePodDisruptionBudgetYAMLs generates a PodDisruptionBudget for each of the components on the query path.
+func generatePodDisruptionBudgetYAMLs(k8sVersion string) string {
+	apiVersion := "policy/v1"
+	if useBetaPDBVersion(k8sVersion) {
+		apiVersion = "policy/v1beta1"
+	}
+
+	var pdbs []string
+	for _, name := range availabilityComponents {
+		pdbs = append(pdbs, fmt.Sprintf(`apiVersion: %s
+kind: PodDisruptionBudget
+metadata:
+  name: %s-pdb
+  labels:
+    %s: "true"
+spec:
+  maxUnavailable: 1
+  selector:
+    matchLabels:
+      name: %s
+`, apiVersion, name, podDisruptionBudgetLabel, name))
+	}
+	return "---\n" + strings.Join(pdbs, "---\n")
+}
+
+// deployPodDisruptionBudgets deploys the Vizier's PodDisruptionBudgets, or removes them if they are disabled.
+func (r *VizierReconciler) deployPodDisruptionBudgets(ctx context.Context, namespace string, vz *v1alpha1.Vizier) error {
+	if vz.Spec.Availability == nil || !vz.Spec.Availability.PodDisruptionBudget {
+		od := k8s.ObjectDeleter{
+			Namespace:  namespace,
+			Clientset:  r.Clientset,
+			RestConfig: r.RestConfig,
+			Timeout:    2 * time.Minute,
+		}
+		_, _ = od.DeleteByLabel(ctx, podDisruptionBudgetLabel+"=true", "poddisruptionbudgets")
+		return nil
+	}
+
+	log.Info("Deploying pod disruption budgets")
+	resources, err := k8s.GetResourcesFromYAML(strings.NewReader(generatePodDisruptionBudgetYAMLs(r.K8sVersion)))
+	if err != nil {
+		return err
+	}
+	for _, r := range resources {
+		err = updateResourceConfiguration(r, vz)
+		if err != nil {
+			return err
+		}
+	}
+	return k8s.ApplyResources(r.Clientset, r.RestConfig, resources, namespace, nil, true)
+}
+
+// mergePatches merges two JSON patches for the same resource. Fields which are set in both are taken from the
+// second patch, unless both are objects, in which case they are merged recursively.
+func mergePatches(a, b string) (string, error) {
+	var aObj, bObj map[string]interface{}
+	if err := json.Unmarshal([]byte(a), &aObj); err != nil {
+		return "", err
+	}
+	if err := json.Unmarshal([]byte(b), &bObj); err != nil {
+		return "", err
+	}
+	merged, err := json.Marshal(mergeObjects(aObj, bObj))
+	if err != nil {
+		return "", err
+	}
+	return string(merged), nil
+}
+
+func mergeObjects(a, b map[string]interface{}) map[string]interface{} {
+	if a == nil {
+		return b
+	}
+	for k, bVal := range b {
+		aMap, aIsMap := a[k].(map[string]interface{})
+		bMap, bIsMap := bVal.(map[string]interface{})
+		if aIsMap && bIsMap {
+			a[k] = mergeObjects(aMap, bMap)
+			continue
+		}
+		a[k] = bVal
+	}
+	return a
+}
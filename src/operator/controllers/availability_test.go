@@ -0,0 +1,102 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func TestTopologySpreadPatches(t *testing.T) {
+	assert.Nil(t, topologySpreadPatches(&v1alpha1.VizierSpec{}))
+	assert.Nil(t, topologySpreadPatches(&v1alpha1.VizierSpec{
+		Availability: &v1alpha1.AvailabilityParams{PodDisruptionBudget: true},
+	}))
+
+	patches := topologySpreadPatches(&v1alpha1.VizierSpec{
+		Availability: &v1alpha1.AvailabilityParams{TopologyKey: "topology.kubernetes.io/zone"},
+	})
+	assert.Len(t, patches, 4)
+	assert.Equal(t,
+		`{"spec":{"template":{"spec":{"topologySpreadConstraints":[{"labelSelector":{"matchLabels":{"name":"kelvin"}},"maxSkew":1,"topologyKey":"topology.kubernetes.io/zone","whenUnsatisfiable":"ScheduleAnyway"}]}}}}`,
+		patches["kelvin"])
+}
+
+func TestGeneratePodDisruptionBudgetYAMLs(t *testing.T) {
+	tests := []struct {
+		name        string
+		k8sVersion  string
+		expectedAPI string
+	}{
+		{
+			name:        "unknown version",
+			k8sVersion:  "",
+			expectedAPI: "policy/v1",
+		},
+		{
+			name:        "stable",
+			k8sVersion:  "v1.24.3-gke.900",
+			expectedAPI: "policy/v1",
+		},
+		{
+			name:        "beta",
+			k8sVersion:  "v1.20.15",
+			expectedAPI: "policy/v1beta1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resources, err := k8s.GetResourcesFromYAML(strings.NewReader(generatePodDisruptionBudgetYAMLs(test.k8sVersion)))
+			require.NoError(t, err)
+			require.Len(t, resources, len(availabilityComponents))
+			for i, r := range resources {
+				assert.Equal(t, test.expectedAPI, r.Object.GetAPIVersion())
+				assert.Equal(t, availabilityComponents[i]+"-pdb", r.Object.GetName())
+				assert.Equal(t, "true", r.Object.GetLabels()[podDisruptionBudgetLabel])
+			}
+		})
+	}
+}
+
+func TestMergePatches(t *testing.T) {
+	merged, err := mergePatches(
+		`{"spec":{"template":{"metadata":{"labels":{"a":"b"}}}}}`,
+		`{"spec":{"replicas":2,"template":{"spec":{"hostNetwork":true}}}}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"spec":{"replicas":2,"template":{"metadata":{"labels":{"a":"b"}},"spec":{"hostNetwork":true}}}}`, merged)
+
+	_, err = mergePatches(`{`, `{}`)
+	assert.Error(t, err)
+}
+
+func TestVizierPatchesMergesGeneratedPatches(t *testing.T) {
+	patches := vizierPatches(&v1alpha1.VizierSpec{
+		WorkloadIdentity: &v1alpha1.WorkloadIdentity{AzureClientID: "client"},
+		Availability:     &v1alpha1.AvailabilityParams{TopologyKey: "kubernetes.io/hostname"},
+	})
+	assert.Contains(t, patches[queryBrokerDeployment], azureUseLabel)
+	assert.Contains(t, patches[queryBrokerDeployment], "topologySpreadConstraints")
+}
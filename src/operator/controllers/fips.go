@@ -0,0 +1,122 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+// fipsImages maps the images of the Go Vizier services to their variants built with BoringCrypto. The services
+// refuse to run in FIPS mode without BoringCrypto, so FIPS mode must always run them from these images.
+var fipsImages = map[string]string{
+	"cloud_connector_server_image": "cloud_connector_server_fips_image",
+	"control_plane_server_image":   "control_plane_server_fips_image",
+	"metadata_server_image":        "metadata_server_fips_image",
+	"query_broker_server_image":    "query_broker_server_fips_image",
+}
+
+// fipsTLSConfigMaps are the config maps that the Go Vizier services load their TLS settings from.
+var fipsTLSConfigMaps = []string{"pl-tls-config", "pl-cloud-connector-tls-config"}
+
+// fipsNATSConfig is the NATS server config for FIPS mode. It matches the default config, except that the TLS
+// listener is restricted to FIPS approved cipher suites and curves.
+const fipsNATSConfig = `pid_file: "/var/run/nats/nats.pid"
+http: 8222
+
+tls {
+  ca_file: "/etc/nats-server-tls-certs/ca.crt",
+  cert_file: "/etc/nats-server-tls-certs/server.crt",
+  key_file: "/etc/nats-server-tls-certs/server.key",
+  timeout: 3
+  verify: true
+  cipher_suites: [
+    "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+    "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+    "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+    "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"
+  ]
+  curve_preferences: [
+    "CurveP256",
+    "CurveP384"
+  ]
+}
+`
+
+// fipsModePatches returns the patches which turn on FIPS mode in the Vizier services and restrict the NATS listener
+// to FIPS approved ciphers.
+func fipsModePatches(spec *v1alpha1.VizierSpec) map[string]string {
+	if !spec.FIPSMode {
+		return nil
+	}
+
+	patches := map[string]interface{}{
+		"nats-config": map[string]interface{}{
+			"data": map[string]string{"nats.conf": fipsNATSConfig},
+		},
+	}
+	for _, cm := range fipsTLSConfigMaps {
+		patches[cm] = map[string]interface{}{
+			"data": map[string]string{"PL_FIPS_MODE": "true"},
+		}
+	}
+	return marshalPatches(patches)
+}
+
+// fipsImage returns the FIPS variant of the image, or the image itself if it has none. Images from a custom registry
+// have their path flattened into their name, so the variant is found by the suffix of the name.
+func fipsImage(image string) string {
+	name, ref := image, ""
+	base := strings.LastIndex(image, "/") + 1
+	if idx := strings.IndexAny(image[base:], ":@"); idx >= 0 {
+		name, ref = image[:base+idx], image[base+idx:]
+	}
+	for stock, fips := range fipsImages {
+		if strings.HasSuffix(name, stock) {
+			return strings.TrimSuffix(name, stock) + fips + ref
+		}
+	}
+	return image
+}
+
+// useFIPSImages switches the containers of the resource's pod template to the FIPS variants of their images.
+func useFIPSImages(res map[string]interface{}) {
+	for _, field := range []string{"containers", "initContainers"} {
+		containers, ok, err := unstructured.NestedFieldNoCopy(res, "spec", "template", "spec", field)
+		if !ok || err != nil {
+			continue
+		}
+		cList, ok := containers.([]interface{})
+		if !ok {
+			continue
+		}
+		for _, c := range cList {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if image, ok := container["image"].(string); ok {
+				container["image"] = fipsImage(image)
+			}
+		}
+	}
+}
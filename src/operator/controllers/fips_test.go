@@ -0,0 +1,115 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+func TestFIPSModePatches(t *testing.T) {
+	assert.Nil(t, fipsModePatches(&v1alpha1.VizierSpec{}))
+
+	patches := fipsModePatches(&v1alpha1.VizierSpec{FIPSMode: true})
+	assert.Equal(t, `{"data":{"PL_FIPS_MODE":"true"}}`, patches["pl-tls-config"])
+	assert.Equal(t, `{"data":{"PL_FIPS_MODE":"true"}}`, patches["pl-cloud-connector-tls-config"])
+
+	var natsPatch struct {
+		Data map[string]string `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(patches["nats-config"]), &natsPatch))
+	assert.Contains(t, natsPatch.Data["nats.conf"], "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256")
+}
+
+func TestFIPSImage(t *testing.T) {
+	tests := []struct {
+		image    string
+		expected string
+	}{
+		{
+			image:    "gcr.io/pixie-oss/pixie-prod/vizier/metadata_server_image:0.14.1",
+			expected: "gcr.io/pixie-oss/pixie-prod/vizier/metadata_server_fips_image:0.14.1",
+		},
+		{
+			image:    "localhost:5000/gcr.io-pixie-oss-pixie-prod-vizier-query_broker_server_image:0.14.1",
+			expected: "localhost:5000/gcr.io-pixie-oss-pixie-prod-vizier-query_broker_server_fips_image:0.14.1",
+		},
+		{
+			image:    "gcr.io/pixie-oss/pixie-prod/vizier/cloud_connector_server_image@sha256:abcd",
+			expected: "gcr.io/pixie-oss/pixie-prod/vizier/cloud_connector_server_fips_image@sha256:abcd",
+		},
+		{
+			// The C++ agents don't have FIPS variants.
+			image:    "gcr.io/pixie-oss/pixie-prod/vizier/kelvin_image:0.14.1",
+			expected: "gcr.io/pixie-oss/pixie-prod/vizier/kelvin_image:0.14.1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.image, func(t *testing.T) {
+			assert.Equal(t, test.expected, fipsImage(test.image))
+		})
+	}
+}
+
+func TestUseFIPSImages(t *testing.T) {
+	res := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"initContainers": []interface{}{
+						map[string]interface{}{"image": "gcr.io/pixie-oss/pixie-prod/vizier/cert_provisioner_image:0.14.1"},
+					},
+					"containers": []interface{}{
+						map[string]interface{}{"image": "gcr.io/pixie-oss/pixie-prod/vizier/metadata_server_image:0.14.1"},
+					},
+				},
+			},
+		},
+	}
+	useFIPSImages(res)
+
+	podSpec := res["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})
+	assert.Equal(t, "gcr.io/pixie-oss/pixie-prod/vizier/metadata_server_fips_image:0.14.1",
+		podSpec["containers"].([]interface{})[0].(map[string]interface{})["image"])
+	assert.Equal(t, "gcr.io/pixie-oss/pixie-prod/vizier/cert_provisioner_image:0.14.1",
+		podSpec["initContainers"].([]interface{})[0].(map[string]interface{})["image"])
+}
+
+func TestVizierPatches(t *testing.T) {
+	spec := &v1alpha1.VizierSpec{Patches: map[string]string{"vizier-pem": "{}"}}
+	assert.Equal(t, map[string]string{"vizier-pem": "{}"}, vizierPatches(spec))
+
+	spec = &v1alpha1.VizierSpec{
+		FIPSMode:         true,
+		WorkloadIdentity: &v1alpha1.WorkloadIdentity{AWSRoleARN: "arn:aws:iam::123:role/pixie"},
+		Patches:          map[string]string{"pl-tls-config": "{}"},
+	}
+	patches := vizierPatches(spec)
+	// Custom patches take precedence over the generated ones.
+	assert.Equal(t, "{}", patches["pl-tls-config"])
+	assert.Contains(t, patches, "pl-cloud-connector-tls-config")
+	assert.Contains(t, patches, "nats-config")
+	assert.Contains(t, patches, "query-broker-service-account")
+}
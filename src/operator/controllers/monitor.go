@@ -22,6 +22,7 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"encoding/pem"
 	"fmt"
 	"io"
@@ -57,8 +58,12 @@ const (
 	cloudConnName = "vizier-cloud-connector"
 	// The name label for PEMs.
 	vizierPemLabel = "vizier-pem"
+	// The name label for query broker pods.
+	vizierQueryBrokerLabel = "vizier-query-broker"
 	// The name label for metadata pods.
 	vizierMetadataLabel = "vizier-metadata"
+	// The name label for the pods of the single process control plane.
+	vizierControlPlaneLabel = "vizier-control-plane"
 	// The timeout for pending metadata pods.
 	vizierMetadataTimeout = 5 * time.Minute
 	// The name label for nats pods.
@@ -371,12 +376,21 @@ func getEtcdState(pods *concurrentPodMap) *vizierState {
 	return okState()
 }
 
+// serviceLabel returns the name label of the pods which run the given control plane service. The single process
+// control plane runs all of them in its own pods.
+func serviceLabel(vz *pixiev1alpha1.Vizier, nameLabel string) string {
+	if vz.Spec.SingleProcessControlPlane {
+		return vizierControlPlaneLabel
+	}
+	return nameLabel
+}
+
 // getCloudConnState determines the state of the cloud connector then translates
 // that to a corresponding VizierState.
-func getCloudConnState(client HTTPClient, pods *concurrentPodMap) *vizierState {
+func getCloudConnState(client HTTPClient, pods *concurrentPodMap, nameLabel string) *vizierState {
 	pods.mapMu.Lock()
 	defer pods.mapMu.Unlock()
-	labelMap, ok := pods.unsafeMap[cloudConnName]
+	labelMap, ok := pods.unsafeMap[nameLabel]
 	if !ok || len(labelMap) == 0 {
 		return &vizierState{Reason: status.CloudConnectorMissing}
 	}
@@ -400,6 +414,88 @@ func getCloudConnState(client HTTPClient, pods *concurrentPodMap) *vizierState {
 	return okState()
 }
 
+// getExportHealth fetches the health of the cron script exports from a running query broker. It returns nil if
+// no query broker reported its export health, for example because it is an older version which does not serve it.
+func getExportHealth(client HTTPClient, pods *concurrentPodMap, nameLabel string) *pixiev1alpha1.ExportHealthStatus {
+	pods.mapMu.Lock()
+	defer pods.mapMu.Unlock()
+	for _, qbPod := range pods.unsafeMap[nameLabel] {
+		if qbPod.pod.Status.Phase != v1.PodRunning {
+			continue
+		}
+		health, err := queryPodExportHealth(client, qbPod.pod)
+		if err != nil {
+			log.WithError(err).Debug("Failed to get the export health of the query broker")
+			continue
+		}
+		exportHealth := &pixiev1alpha1.ExportHealthStatus{
+			FailingExports:     health.FailingExports,
+			PendingRetries:     health.PendingRetries,
+			DeadLetters:        health.DeadLetters,
+			DroppedDeadLetters: health.DroppedDeadLetters,
+			LastError:          health.LastError,
+		}
+		if health.LastFailureTime != nil {
+			t := metav1.NewTime(*health.LastFailureTime)
+			exportHealth.LastFailureTime = &t
+		}
+		return exportHealth
+	}
+	return nil
+}
+
+// getDatastoreHealth fetches the health of the metadata datastore from a running metadata pod. It returns nil if no
+// metadata pod reported it, for example because it is an older version which does not serve it.
+func getDatastoreHealth(client HTTPClient, pods *concurrentPodMap, nameLabel string) *pixiev1alpha1.DatastoreHealthStatus {
+	pods.mapMu.Lock()
+	defer pods.mapMu.Unlock()
+	for _, mdPod := range pods.unsafeMap[nameLabel] {
+		if mdPod.pod.Status.Phase != v1.PodRunning {
+			continue
+		}
+		health := &status.DatastoreHealth{}
+		if err := queryPodHealth(client, mdPod.pod, status.DatastoreHealthPath, health); err != nil {
+			log.WithError(err).Debug("Failed to get the datastore health of the metadata service")
+			continue
+		}
+		datastoreHealth := &pixiev1alpha1.DatastoreHealthStatus{
+			Backend:    health.Backend,
+			SizeBytes:  health.SizeBytes,
+			InUseBytes: health.InUseBytes,
+			QuotaBytes: health.QuotaBytes,
+			SizeAlarm:  health.SizeAlarm,
+			Alarms:     health.Alarms,
+			LastError:  health.LastError,
+		}
+		if health.LastCompactionTime != nil {
+			t := metav1.NewTime(*health.LastCompactionTime)
+			datastoreHealth.LastCompactionTime = &t
+		}
+		if health.LastDefragTime != nil {
+			t := metav1.NewTime(*health.LastDefragTime)
+			datastoreHealth.LastDefragTime = &t
+		}
+		return datastoreHealth
+	}
+	return nil
+}
+
+// getDatastoreState determines whether the metadata datastore is close to filling up.
+func getDatastoreState(health *pixiev1alpha1.DatastoreHealthStatus) *vizierState {
+	if health != nil && health.SizeAlarm {
+		return &vizierState{Reason: status.MetadataDatastoreSizeAlarm}
+	}
+	return okState()
+}
+
+// getExportsState determines whether the exports of the cron scripts are failing.
+func getExportsState(health *pixiev1alpha1.ExportHealthStatus) *vizierState {
+	if health != nil && health.FailingExports > 0 {
+		return &vizierState{Reason: status.ExportsFailing}
+	}
+	return okState()
+}
+
 // getStatefulMetadataPendingState returns whether the stateful metadata pod is pending.
 func getStatefulMetadataPendingState(pods *concurrentPodMap, vz *v1alpha1.Vizier) *vizierState {
 	// We wait for a timeout because pvc provisioning can take some time.
@@ -595,13 +691,15 @@ func (m *VizierMonitor) getVizierState(vz *pixiev1alpha1.Vizier) *vizierState {
 		return m.certState
 	}
 
-	if !vz.Spec.UseEtcdOperator && !isOk(m.pvcState) {
+	// The single process control plane keeps its metadata in an emptyDir, and embeds NATS.
+	stateful := !vz.Spec.UseEtcdOperator && !vz.Spec.SingleProcessControlPlane
+	if stateful && !isOk(m.pvcState) {
 		return m.pvcState
 	}
 
 	// Only show the metadata state if etcd is not being used.
 	ssMetadataState := getStatefulMetadataPendingState(m.podStates, vz)
-	if !vz.Spec.UseEtcdOperator && !isOk(ssMetadataState) {
+	if stateful && !isOk(ssMetadataState) {
 		return ssMetadataState
 	}
 
@@ -614,9 +712,11 @@ func (m *VizierMonitor) getVizierState(vz *pixiev1alpha1.Vizier) *vizierState {
 		return podState
 	}
 
-	natsState := getNATSState(m.httpClient, m.podStates)
-	if !isOk(natsState) {
-		return natsState
+	if !vz.Spec.SingleProcessControlPlane {
+		natsState := getNATSState(m.httpClient, m.podStates)
+		if !isOk(natsState) {
+			return natsState
+		}
 	}
 
 	if vz.Spec.UseEtcdOperator {
@@ -636,11 +736,21 @@ func (m *VizierMonitor) getVizierState(vz *pixiev1alpha1.Vizier) *vizierState {
 		return pemCrashingState
 	}
 
-	ccState := getCloudConnState(m.httpClient, m.podStates)
+	ccState := getCloudConnState(m.httpClient, m.podStates, serviceLabel(vz, cloudConnName))
 	if !isOk(ccState) {
 		return ccState
 	}
 
+	exportsState := getExportsState(vz.Status.ExportHealth)
+	if !isOk(exportsState) {
+		return exportsState
+	}
+
+	datastoreState := getDatastoreState(vz.Status.DatastoreHealth)
+	if !isOk(datastoreState) {
+		return datastoreState
+	}
+
 	return okState()
 }
 
@@ -736,7 +846,7 @@ func (m *VizierMonitor) repairVizier(state *vizierState) error {
 		m.certState = okState()
 
 		log.Info("Bouncing Vizier pods to get certs update")
-		err = k8s.DeletePods(m.clientset, m.namespace, "")
+		err = k8s.DeletePods(context.Background(), m.clientset, m.namespace, "", 0, nil)
 		if err != nil {
 			return err
 		}
@@ -760,6 +870,8 @@ func (m *VizierMonitor) runReconciler() {
 				continue
 			}
 
+			vz.Status.ExportHealth = getExportHealth(m.httpClient, m.podStates, serviceLabel(vz, vizierQueryBrokerLabel))
+			vz.Status.DatastoreHealth = getDatastoreHealth(m.httpClient, m.podStates, serviceLabel(vz, vizierMetadataLabel))
 			vizierState := m.getVizierState(vz)
 			vz.SetStatus(vizierState.Reason)
 
@@ -817,6 +929,40 @@ func queryPodStatusz(client HTTPClient, pod *v1.Pod) (bool, string) {
 	return false, strings.TrimSpace(string(body))
 }
 
+// queryPodExportHealth returns the export health served by a query broker pod.
+func queryPodExportHealth(client HTTPClient, pod *v1.Pod) (*status.ExportHealth, error) {
+	health := &status.ExportHealth{}
+	if err := queryPodHealth(client, pod, status.ExportHealthPath, health); err != nil {
+		return nil, err
+	}
+	return health, nil
+}
+
+// queryPodHealth decodes the JSON served by a pod on the given path into health.
+func queryPodHealth(client HTTPClient, pod *v1.Pod, path string, health interface{}) error {
+	// Assume that the endpoint is on the first port in the first container, like statusz.
+	var port int32
+	if len(pod.Spec.Containers) > 0 && len(pod.Spec.Containers[0].Ports) > 0 {
+		port = pod.Spec.Containers[0].Ports[0].ContainerPort
+	}
+
+	u := url.URL{
+		Scheme: "https",
+		Host:   net.JoinHostPort(k8s.GetPodAddr(*pod), fmt.Sprintf("%d", port)),
+		Path:   path,
+	}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(health)
+}
+
 // Quit stops the VizierMonitor from monitoring the vizier in the given namespace.
 func (m *VizierMonitor) Quit() {
 	if m.ctx != nil {
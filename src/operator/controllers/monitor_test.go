@@ -206,13 +206,247 @@ func TestMonitor_getCloudConnState(t *testing.T) {
 					},
 				})
 
-			state := getCloudConnState(httpClient, pods)
+			state := getCloudConnState(httpClient, pods, cloudConnName)
 			assert.Equal(t, test.expectedReason, state.Reason)
 			assert.Equal(t, test.expectedVizierPhase, v1alpha1.ReasonToPhase(state.Reason))
 		})
 	}
 }
 
+type fakeExportHealthClient struct {
+	bodies map[string]string
+}
+
+func (f *fakeExportHealthClient) Get(url string) (*http.Response, error) {
+	if body, ok := f.bodies[url]; ok {
+		return &http.Response{
+			Status:     "200",
+			StatusCode: 200,
+			Body:       io.NopCloser(bytes.NewBufferString(body)),
+		}, nil
+	}
+	return &http.Response{
+		Status:     "404",
+		StatusCode: 404,
+		Body:       io.NopCloser(bytes.NewBufferString("")),
+	}, nil
+}
+
+func TestMonitor_getExportHealth(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		phase          v1.PodPhase
+		expectedHealth *v1alpha1.ExportHealthStatus
+		expectedReason status.VizierReason
+	}{
+		{
+			name:           "healthy",
+			body:           `{"failingExports":0,"pendingRetries":1,"deadLetters":0,"droppedDeadLetters":0}`,
+			phase:          v1.PodRunning,
+			expectedHealth: &v1alpha1.ExportHealthStatus{PendingRetries: 1},
+			expectedReason: "",
+		},
+		{
+			name:  "failing",
+			body:  `{"failingExports":2,"pendingRetries":0,"deadLetters":3,"droppedDeadLetters":1,"lastError":"OTel export failed","lastFailureTime":"2023-01-02T03:04:05Z"}`,
+			phase: v1.PodRunning,
+			expectedHealth: &v1alpha1.ExportHealthStatus{
+				FailingExports:     2,
+				DeadLetters:        3,
+				DroppedDeadLetters: 1,
+				LastError:          "OTel export failed",
+				LastFailureTime:    &metav1.Time{Time: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)},
+			},
+			expectedReason: status.ExportsFailing,
+		},
+		{
+			name:           "not served",
+			phase:          v1.PodRunning,
+			expectedHealth: nil,
+			expectedReason: "",
+		},
+		{
+			name:           "pending",
+			body:           `{"failingExports":2}`,
+			phase:          v1.PodPending,
+			expectedHealth: nil,
+			expectedReason: "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			httpClient := &fakeExportHealthClient{bodies: map[string]string{}}
+			if test.body != "" {
+				httpClient.bodies["https://127-0-0-1.pl.pod.cluster.local:50300/statusz/exports"] = test.body
+			}
+
+			pods := &concurrentPodMap{unsafeMap: make(map[string]map[string]*podWrapper)}
+			pods.write(
+				"vizier-query-broker",
+				"vizier-query-broker-abcdefg",
+				&podWrapper{
+					pod: &v1.Pod{
+						Status: v1.PodStatus{
+							PodIP: "127.0.0.1",
+							Phase: test.phase,
+						},
+						ObjectMeta: metav1.ObjectMeta{
+							Namespace: "pl",
+						},
+						Spec: v1.PodSpec{
+							Containers: []v1.Container{
+								{
+									Ports: []v1.ContainerPort{
+										{
+											ContainerPort: 50300,
+										},
+									},
+								},
+							},
+						},
+					},
+				})
+
+			health := getExportHealth(httpClient, pods, vizierQueryBrokerLabel)
+			if test.expectedHealth == nil {
+				assert.Nil(t, health)
+			} else {
+				assert.Equal(t, test.expectedHealth.FailingExports, health.FailingExports)
+				assert.Equal(t, test.expectedHealth.PendingRetries, health.PendingRetries)
+				assert.Equal(t, test.expectedHealth.DeadLetters, health.DeadLetters)
+				assert.Equal(t, test.expectedHealth.DroppedDeadLetters, health.DroppedDeadLetters)
+				assert.Equal(t, test.expectedHealth.LastError, health.LastError)
+				if test.expectedHealth.LastFailureTime == nil {
+					assert.Nil(t, health.LastFailureTime)
+				} else {
+					assert.True(t, test.expectedHealth.LastFailureTime.Equal(health.LastFailureTime))
+				}
+			}
+
+			state := getExportsState(health)
+			assert.Equal(t, test.expectedReason, state.Reason)
+			if test.expectedReason != "" {
+				assert.Equal(t, v1alpha1.VizierPhaseDegraded, v1alpha1.ReasonToPhase(state.Reason))
+			}
+		})
+	}
+}
+
+func TestMonitor_getDatastoreHealth(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		phase          v1.PodPhase
+		expectedHealth *v1alpha1.DatastoreHealthStatus
+		expectedReason status.VizierReason
+	}{
+		{
+			name:  "healthy",
+			body:  `{"backend":"pebble","sizeBytes":1024,"inUseBytes":512,"quotaBytes":4096,"sizeAlarm":false,"lastCompactionTime":"2023-01-02T03:04:05Z"}`,
+			phase: v1.PodRunning,
+			expectedHealth: &v1alpha1.DatastoreHealthStatus{
+				Backend:            "pebble",
+				SizeBytes:          1024,
+				InUseBytes:         512,
+				QuotaBytes:         4096,
+				LastCompactionTime: &metav1.Time{Time: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)},
+			},
+			expectedReason: "",
+		},
+		{
+			name:  "alarm",
+			body:  `{"backend":"etcd","sizeBytes":2048,"inUseBytes":2048,"quotaBytes":2048,"sizeAlarm":true,"alarms":["NOSPACE"],"lastError":"etcdserver: mvcc: database space exceeded"}`,
+			phase: v1.PodRunning,
+			expectedHealth: &v1alpha1.DatastoreHealthStatus{
+				Backend:    "etcd",
+				SizeBytes:  2048,
+				InUseBytes: 2048,
+				QuotaBytes: 2048,
+				SizeAlarm:  true,
+				Alarms:     []string{"NOSPACE"},
+				LastError:  "etcdserver: mvcc: database space exceeded",
+			},
+			expectedReason: status.MetadataDatastoreSizeAlarm,
+		},
+		{
+			name:           "not served",
+			phase:          v1.PodRunning,
+			expectedHealth: nil,
+			expectedReason: "",
+		},
+		{
+			name:           "pending",
+			body:           `{"sizeAlarm":true}`,
+			phase:          v1.PodPending,
+			expectedHealth: nil,
+			expectedReason: "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			httpClient := &fakeExportHealthClient{bodies: map[string]string{}}
+			if test.body != "" {
+				httpClient.bodies["https://127-0-0-1.pl.pod.cluster.local:50400/statusz/datastore"] = test.body
+			}
+
+			pods := &concurrentPodMap{unsafeMap: make(map[string]map[string]*podWrapper)}
+			pods.write(
+				"vizier-metadata",
+				"vizier-metadata-0",
+				&podWrapper{
+					pod: &v1.Pod{
+						Status: v1.PodStatus{
+							PodIP: "127.0.0.1",
+							Phase: test.phase,
+						},
+						ObjectMeta: metav1.ObjectMeta{
+							Namespace: "pl",
+						},
+						Spec: v1.PodSpec{
+							Containers: []v1.Container{
+								{
+									Ports: []v1.ContainerPort{
+										{
+											ContainerPort: 50400,
+										},
+									},
+								},
+							},
+						},
+					},
+				})
+
+			health := getDatastoreHealth(httpClient, pods, vizierMetadataLabel)
+			if test.expectedHealth == nil {
+				assert.Nil(t, health)
+			} else {
+				assert.Equal(t, test.expectedHealth.Backend, health.Backend)
+				assert.Equal(t, test.expectedHealth.SizeBytes, health.SizeBytes)
+				assert.Equal(t, test.expectedHealth.InUseBytes, health.InUseBytes)
+				assert.Equal(t, test.expectedHealth.QuotaBytes, health.QuotaBytes)
+				assert.Equal(t, test.expectedHealth.SizeAlarm, health.SizeAlarm)
+				assert.Equal(t, test.expectedHealth.Alarms, health.Alarms)
+				assert.Equal(t, test.expectedHealth.LastError, health.LastError)
+				assert.Nil(t, health.LastDefragTime)
+				if test.expectedHealth.LastCompactionTime == nil {
+					assert.Nil(t, health.LastCompactionTime)
+				} else {
+					assert.True(t, test.expectedHealth.LastCompactionTime.Equal(health.LastCompactionTime))
+				}
+			}
+
+			state := getDatastoreState(health)
+			assert.Equal(t, test.expectedReason, state.Reason)
+			if test.expectedReason != "" {
+				assert.Equal(t, v1alpha1.VizierPhaseDegraded, v1alpha1.ReasonToPhase(state.Reason))
+			}
+		})
+	}
+}
+
 // The following is a test for getStatefulMetadataPendingState.
 // It should test the following cases:
 // 1. Vizier metadata pod with statefulset is pending and initContainers are complete: MetadataStatefulSetPodPending
@@ -544,11 +778,52 @@ func TestMonitor_getCloudConnState_SeveralCloudConns(t *testing.T) {
 		},
 	})
 
-	state := getCloudConnState(httpClient, pods)
+	state := getCloudConnState(httpClient, pods, cloudConnName)
 	assert.Equal(t, status.CloudConnectorPodPending, state.Reason)
 	assert.Equal(t, v1alpha1.VizierPhaseUnhealthy, v1alpha1.ReasonToPhase(state.Reason))
 }
 
+func TestMonitor_getCloudConnState_SingleProcessControlPlane(t *testing.T) {
+	httpClient := &FakeHTTPClient{
+		responses: map[string]string{
+			"https://127-0-0-1.pl.pod.cluster.local:50300/statusz": "CloudConnectFailed",
+		},
+	}
+
+	pods := &concurrentPodMap{unsafeMap: make(map[string]map[string]*podWrapper)}
+	pods.write("vizier-control-plane", "vizier-control-plane-abcdefg", &podWrapper{
+		pod: &v1.Pod{
+			Status: v1.PodStatus{
+				PodIP: "127.0.0.1",
+				Phase: v1.PodRunning,
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "pl",
+			},
+			Spec: v1.PodSpec{
+				Containers: []v1.Container{
+					{
+						Ports: []v1.ContainerPort{
+							{
+								ContainerPort: 50300,
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+
+	vz := &v1alpha1.Vizier{}
+	state := getCloudConnState(httpClient, pods, serviceLabel(vz, cloudConnName))
+	assert.Equal(t, status.CloudConnectorMissing, state.Reason)
+
+	// The cloud connector runs in the control plane pods, which report its status.
+	vz.Spec.SingleProcessControlPlane = true
+	state = getCloudConnState(httpClient, pods, serviceLabel(vz, cloudConnName))
+	assert.Equal(t, status.CloudConnectorFailedToConnect, state.Reason)
+}
+
 func TestMonitor_NATSPods(t *testing.T) {
 	httpClient := &FakeHTTPClient{
 		responses: map[string]string{
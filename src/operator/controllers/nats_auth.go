@@ -0,0 +1,296 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+const (
+	// natsAuthSecret holds the generated passwords of the Vizier's NATS users, keyed by user.
+	natsAuthSecret = "pl-nats-auth"
+	// natsConfigChecksumAnnotation is set on the NATS pods to the checksum of the generated NATS config, so that NATS
+	// is redeployed when its authorization changes.
+	natsConfigChecksumAnnotation = "px.dev/nats-config-checksum"
+	natsStatefulSet              = "pl-nats"
+	// The single process control plane embeds the NATS server, and reads its authorization from this config map.
+	controlPlaneDeployment    = "vizier-control-plane"
+	controlPlaneNATSConfigMap = "pl-control-plane-nats-config"
+)
+
+// defaultNATSConfig is the NATS server config that Vizier is deployed with.
+const defaultNATSConfig = `pid_file: "/var/run/nats/nats.pid"
+http: 8222
+
+tls {
+  ca_file: "/etc/nats-server-tls-certs/ca.crt",
+  cert_file: "/etc/nats-server-tls-certs/server.crt",
+  key_file: "/etc/nats-server-tls-certs/server.key",
+  timeout: 3
+  verify: true
+}
+`
+
+type natsUser struct {
+	// workloads maps the workloads which connect to NATS as the user to the name of their container.
+	workloads   map[string]string
+	permissions v1alpha1.NATSPermissions
+}
+
+// natsUsers are the NATS users of the Vizier services when NATS authorization is enabled. The agents may only
+// publish their updates and metrics, and can't use the subjects that the cloud connector bridges to Pixie Cloud.
+var natsUsers = map[string]natsUser{
+	"agent": {
+		workloads: map[string]string{"vizier-pem": "pem", "kelvin": "app"},
+		permissions: v1alpha1.NATSPermissions{
+			Publish: &v1alpha1.NATSSubjectPermissions{
+				Allow: []string{"UpdateAgent", "MissingMetadataRequests", "Metrics", "_INBOX.>"},
+			},
+			Subscribe: &v1alpha1.NATSSubjectPermissions{
+				Deny: []string{"c2v.>", "v2c.>"},
+			},
+		},
+	},
+	"metadata":        {workloads: map[string]string{"vizier-metadata": "app"}},
+	"query-broker":    {workloads: map[string]string{queryBrokerDeployment: "app"}},
+	"cloud-connector": {workloads: map[string]string{"vizier-cloud-connector": "app"}},
+}
+
+// singleProcessNATSUsers are the NATS users when the control plane runs in a single process. The control plane
+// services share one connection to the embedded NATS server, so they connect as a single user.
+var singleProcessNATSUsers = map[string]natsUser{
+	"agent":         natsUsers["agent"],
+	"control-plane": {workloads: map[string]string{controlPlaneDeployment: "app"}},
+}
+
+// vizierNATSUsers returns the NATS users of the Vizier services for the given spec.
+func vizierNATSUsers(spec *v1alpha1.VizierSpec) map[string]natsUser {
+	if spec.SingleProcessControlPlane {
+		return singleProcessNATSUsers
+	}
+	return natsUsers
+}
+
+func natsAuthorizationEnabled(spec *v1alpha1.VizierSpec) bool {
+	return spec.NATS != nil && spec.NATS.Authorization
+}
+
+// natsPasswordEnv is the environment variable of the NATS server that holds the password of the user.
+func natsPasswordEnv(user string) string {
+	return "PL_NATS_" + strings.ToUpper(strings.ReplaceAll(user, "-", "_")) + "_PASSWORD"
+}
+
+func sortedNATSUsers(users map[string]natsUser) []string {
+	names := make([]string, 0, len(users))
+	for name := range users {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// natsConfig returns the NATS server config with a user for each of the Vizier services. The passwords are read
+// from the environment of the NATS server, so that they don't appear in the config map.
+func natsConfig(spec *v1alpha1.VizierSpec) string {
+	var b strings.Builder
+	switch {
+	case spec.SingleProcessControlPlane:
+		// The embedded NATS server only reads the authorization from its config, the rest of its settings come
+		// from the flags of the control plane.
+	case spec.FIPSMode:
+		b.WriteString(fipsNATSConfig)
+	default:
+		b.WriteString(defaultNATSConfig)
+	}
+
+	users := vizierNATSUsers(spec)
+	b.WriteString("\nauthorization {\n  users = [\n")
+	for _, name := range sortedNATSUsers(users) {
+		perms := users[name].permissions
+		if override, ok := spec.NATS.Permissions[name]; ok {
+			perms = override
+		}
+		fmt.Fprintf(&b, "    {\n      user: %q\n      password: $%s\n", name, natsPasswordEnv(name))
+		if perms.Publish != nil || perms.Subscribe != nil {
+			b.WriteString("      permissions: {\n")
+			writeNATSSubjectPermissions(&b, "publish", perms.Publish)
+			writeNATSSubjectPermissions(&b, "subscribe", perms.Subscribe)
+			b.WriteString("      }\n")
+		}
+		b.WriteString("    }\n")
+	}
+	b.WriteString("  ]\n}\n")
+	return b.String()
+}
+
+// natsStringList formats subjects as a NATS config array. Subjects are quoted individually
+// since JSON encoding would escape wildcards such as ">".
+func natsStringList(subjects []string) string {
+	quoted := make([]string, len(subjects))
+	for i, s := range subjects {
+		quoted[i] = strconv.Quote(s)
+	}
+	return "[" + strings.Join(quoted, ",") + "]"
+}
+
+func writeNATSSubjectPermissions(b *strings.Builder, kind string, perms *v1alpha1.NATSSubjectPermissions) {
+	if perms == nil || (len(perms.Allow) == 0 && len(perms.Deny) == 0) {
+		return
+	}
+	fmt.Fprintf(b, "        %s: {\n", kind)
+	if len(perms.Allow) > 0 {
+		fmt.Fprintf(b, "          allow: %s\n", natsStringList(perms.Allow))
+	}
+	if len(perms.Deny) > 0 {
+		fmt.Fprintf(b, "          deny: %s\n", natsStringList(perms.Deny))
+	}
+	b.WriteString("        }\n")
+}
+
+func containerPatch(template map[string]interface{}, container string, env []interface{}) map[string]interface{} {
+	spec := map[string]interface{}{
+		"containers": []interface{}{
+			map[string]interface{}{"name": container, "env": env},
+		},
+	}
+	if template == nil {
+		template = make(map[string]interface{})
+	}
+	template["spec"] = spec
+	return map[string]interface{}{"spec": map[string]interface{}{"template": template}}
+}
+
+func secretEnv(name, key string) map[string]interface{} {
+	return map[string]interface{}{
+		"name": name,
+		"valueFrom": map[string]interface{}{
+			"secretKeyRef": map[string]string{"name": natsAuthSecret, "key": key},
+		},
+	}
+}
+
+// natsAuthPatches returns the patches which turn on authorization in NATS, and have each of the Vizier services
+// connect to it as their own user.
+func natsAuthPatches(spec *v1alpha1.VizierSpec) map[string]string {
+	if !natsAuthorizationEnabled(spec) {
+		return nil
+	}
+
+	conf := natsConfig(spec)
+	checksum := sha256.Sum256([]byte(conf))
+
+	configMap, server, serverContainer := "nats-config", natsStatefulSet, natsStatefulSet
+	if spec.SingleProcessControlPlane {
+		configMap, server, serverContainer = controlPlaneNATSConfigMap, controlPlaneDeployment, "app"
+	}
+
+	var serverEnv []interface{}
+	patches := map[string]interface{}{
+		configMap: map[string]interface{}{
+			"data": map[string]string{"nats.conf": conf},
+		},
+	}
+	users := vizierNATSUsers(spec)
+	for _, name := range sortedNATSUsers(users) {
+		serverEnv = append(serverEnv, secretEnv(natsPasswordEnv(name), name))
+		for workload, container := range users[name].workloads {
+			clientEnv := []interface{}{
+				map[string]string{"name": "PL_NATS_USER", "value": name},
+				secretEnv("PL_NATS_PASSWORD", name),
+			}
+			// The NATS server's own process connects to it as well when it is embedded.
+			if workload == server {
+				serverEnv = append(serverEnv, clientEnv...)
+				continue
+			}
+			patches[workload] = containerPatch(nil, container, clientEnv)
+		}
+	}
+	patches[server] = containerPatch(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{natsConfigChecksumAnnotation: hex.EncodeToString(checksum[:])},
+		},
+	}, serverContainer, serverEnv)
+	return marshalPatches(patches)
+}
+
+// ensureNATSAuthSecret creates the secret with the passwords of the NATS users, if authorization is enabled.
+// Passwords are only generated for users that don't have one yet, so that NATS and its clients keep agreeing on
+// them across deploys.
+func ensureNATSAuthSecret(ctx context.Context, clientset kubernetes.Interface, namespace string, vz *v1alpha1.Vizier) error {
+	if !natsAuthorizationEnabled(&vz.Spec) {
+		return nil
+	}
+
+	secrets := clientset.CoreV1().Secrets(namespace)
+	s, err := secrets.Get(ctx, natsAuthSecret, metav1.GetOptions{})
+	exists := err == nil
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	if !exists {
+		s = &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: natsAuthSecret, Namespace: namespace}}
+		if vz.Spec.Pod != nil {
+			s.Labels = vz.Spec.Pod.Labels
+		}
+	}
+	if s.Data == nil {
+		s.Data = make(map[string][]byte)
+	}
+
+	changed := false
+	for _, name := range sortedNATSUsers(vizierNATSUsers(&vz.Spec)) {
+		if len(s.Data[name]) > 0 {
+			continue
+		}
+		password := make([]byte, 32)
+		if _, err := rand.Read(password); err != nil {
+			return err
+		}
+		s.Data[name] = []byte(hex.EncodeToString(password))
+		changed = true
+	}
+
+	if !exists {
+		log.Info("Creating NATS credentials")
+		_, err = secrets.Create(ctx, s, metav1.CreateOptions{})
+		return err
+	}
+	if changed {
+		log.Info("Adding missing NATS credentials")
+		_, err = secrets.Update(ctx, s, metav1.UpdateOptions{})
+		return err
+	}
+	return nil
+}
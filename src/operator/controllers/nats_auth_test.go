@@ -0,0 +1,209 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+type podTemplatePatch struct {
+	Spec struct {
+		Template struct {
+			Metadata struct {
+				Annotations map[string]string `json:"annotations"`
+			} `json:"metadata"`
+			Spec struct {
+				Containers []v1.Container `json:"containers"`
+			} `json:"spec"`
+		} `json:"template"`
+	} `json:"spec"`
+}
+
+func TestNATSAuthPatches_Disabled(t *testing.T) {
+	assert.Nil(t, natsAuthPatches(&v1alpha1.VizierSpec{}))
+	assert.Nil(t, natsAuthPatches(&v1alpha1.VizierSpec{NATS: &v1alpha1.NATSParams{}}))
+}
+
+func TestNATSAuthPatches(t *testing.T) {
+	spec := &v1alpha1.VizierSpec{NATS: &v1alpha1.NATSParams{Authorization: true}}
+	patches := natsAuthPatches(spec)
+
+	var configPatch struct {
+		Data map[string]string `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(patches["nats-config"]), &configPatch))
+	conf := configPatch.Data["nats.conf"]
+	assert.Contains(t, conf, "verify: true")
+	assert.NotContains(t, conf, "cipher_suites")
+	assert.Contains(t, conf, `user: "agent"`)
+	assert.Contains(t, conf, "password: $PL_NATS_AGENT_PASSWORD")
+	assert.Contains(t, conf, `allow: ["UpdateAgent","MissingMetadataRequests","Metrics","_INBOX.>"]`)
+	assert.Contains(t, conf, `deny: ["c2v.>","v2c.>"]`)
+	assert.Contains(t, conf, `user: "cloud-connector"`)
+	assert.Contains(t, conf, "password: $PL_NATS_CLOUD_CONNECTOR_PASSWORD")
+
+	var pem podTemplatePatch
+	require.NoError(t, json.Unmarshal([]byte(patches["vizier-pem"]), &pem))
+	require.Len(t, pem.Spec.Template.Spec.Containers, 1)
+	container := pem.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, "pem", container.Name)
+	require.Len(t, container.Env, 2)
+	assert.Equal(t, v1.EnvVar{Name: "PL_NATS_USER", Value: "agent"}, container.Env[0])
+	assert.Equal(t, "PL_NATS_PASSWORD", container.Env[1].Name)
+	assert.Equal(t, &v1.SecretKeySelector{
+		LocalObjectReference: v1.LocalObjectReference{Name: natsAuthSecret},
+		Key:                  "agent",
+	}, container.Env[1].ValueFrom.SecretKeyRef)
+
+	for _, workload := range []string{"kelvin", "vizier-metadata", "vizier-query-broker", "vizier-cloud-connector"} {
+		assert.Contains(t, patches, workload)
+	}
+
+	var nats podTemplatePatch
+	require.NoError(t, json.Unmarshal([]byte(patches[natsStatefulSet]), &nats))
+	assert.NotEmpty(t, nats.Spec.Template.Metadata.Annotations[natsConfigChecksumAnnotation])
+	require.Len(t, nats.Spec.Template.Spec.Containers, 1)
+	var envs []string
+	for _, env := range nats.Spec.Template.Spec.Containers[0].Env {
+		envs = append(envs, env.Name)
+	}
+	assert.ElementsMatch(t, []string{
+		"PL_NATS_AGENT_PASSWORD", "PL_NATS_CLOUD_CONNECTOR_PASSWORD", "PL_NATS_METADATA_PASSWORD",
+		"PL_NATS_QUERY_BROKER_PASSWORD",
+	}, envs)
+}
+
+func TestNATSAuthPatches_SingleProcessControlPlane(t *testing.T) {
+	spec := &v1alpha1.VizierSpec{SingleProcessControlPlane: true, NATS: &v1alpha1.NATSParams{Authorization: true}}
+	patches := natsAuthPatches(spec)
+	assert.NotContains(t, patches, "nats-config")
+	assert.NotContains(t, patches, natsStatefulSet)
+
+	var configPatch struct {
+		Data map[string]string `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(patches[controlPlaneNATSConfigMap]), &configPatch))
+	conf := configPatch.Data["nats.conf"]
+	// The listener and TLS settings of the embedded server come from the control plane's flags.
+	assert.NotContains(t, conf, "tls")
+	assert.Contains(t, conf, `user: "agent"`)
+	assert.Contains(t, conf, `user: "control-plane"`)
+	assert.Contains(t, conf, "password: $PL_NATS_CONTROL_PLANE_PASSWORD")
+	assert.NotContains(t, conf, `user: "metadata"`)
+
+	// The control plane runs the NATS server, and connects to it as the control-plane user.
+	var controlPlane podTemplatePatch
+	require.NoError(t, json.Unmarshal([]byte(patches[controlPlaneDeployment]), &controlPlane))
+	assert.NotEmpty(t, controlPlane.Spec.Template.Metadata.Annotations[natsConfigChecksumAnnotation])
+	require.Len(t, controlPlane.Spec.Template.Spec.Containers, 1)
+	container := controlPlane.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, "app", container.Name)
+	envs := make(map[string]v1.EnvVar)
+	for _, env := range container.Env {
+		envs[env.Name] = env
+	}
+	assert.Len(t, envs, 4)
+	assert.Equal(t, "control-plane", envs["PL_NATS_USER"].Value)
+	assert.Equal(t, "control-plane", envs["PL_NATS_PASSWORD"].ValueFrom.SecretKeyRef.Key)
+	assert.Equal(t, "agent", envs["PL_NATS_AGENT_PASSWORD"].ValueFrom.SecretKeyRef.Key)
+	assert.Equal(t, "control-plane", envs["PL_NATS_CONTROL_PLANE_PASSWORD"].ValueFrom.SecretKeyRef.Key)
+
+	for _, workload := range []string{"vizier-pem", "kelvin"} {
+		assert.Contains(t, patches, workload)
+	}
+	for _, workload := range []string{"vizier-metadata", "vizier-query-broker", "vizier-cloud-connector"} {
+		assert.NotContains(t, patches, workload)
+	}
+}
+
+func TestNATSAuthPatches_PermissionOverrides(t *testing.T) {
+	spec := &v1alpha1.VizierSpec{NATS: &v1alpha1.NATSParams{
+		Authorization: true,
+		Permissions: map[string]v1alpha1.NATSPermissions{
+			"agent":        {Publish: &v1alpha1.NATSSubjectPermissions{Allow: []string{"UpdateAgent"}}},
+			"query-broker": {Subscribe: &v1alpha1.NATSSubjectPermissions{Deny: []string{"c2v.>"}}},
+		},
+	}}
+	conf := natsConfig(spec)
+	assert.Contains(t, conf, `allow: ["UpdateAgent"]`)
+	assert.NotContains(t, conf, `deny: ["c2v.>","v2c.>"]`)
+	assert.Contains(t, conf, `deny: ["c2v.>"]`)
+
+	// The checksum follows the config, so that NATS is redeployed with the new permissions.
+	var before, after podTemplatePatch
+	require.NoError(t, json.Unmarshal([]byte(natsAuthPatches(spec)[natsStatefulSet]), &after))
+	spec.NATS.Permissions = nil
+	require.NoError(t, json.Unmarshal([]byte(natsAuthPatches(spec)[natsStatefulSet]), &before))
+	assert.NotEqual(t, before.Spec.Template.Metadata.Annotations, after.Spec.Template.Metadata.Annotations)
+}
+
+func TestVizierPatches_NATSAuthWithFIPS(t *testing.T) {
+	spec := &v1alpha1.VizierSpec{FIPSMode: true, NATS: &v1alpha1.NATSParams{Authorization: true}}
+	patches := vizierPatches(spec)
+
+	var configPatch struct {
+		Data map[string]string `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(patches["nats-config"]), &configPatch))
+	assert.Contains(t, configPatch.Data["nats.conf"], "cipher_suites")
+	assert.Contains(t, configPatch.Data["nats.conf"], "authorization")
+}
+
+func TestEnsureNATSAuthSecret(t *testing.T) {
+	ctx := context.Background()
+	clientset := fake.NewSimpleClientset()
+	vz := &v1alpha1.Vizier{Spec: v1alpha1.VizierSpec{NATS: &v1alpha1.NATSParams{Authorization: true}}}
+
+	require.NoError(t, ensureNATSAuthSecret(ctx, clientset, "pl", vz))
+	s, err := clientset.CoreV1().Secrets("pl").Get(ctx, natsAuthSecret, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Len(t, s.Data, 4)
+	for user, password := range s.Data {
+		assert.Len(t, password, 64, user)
+	}
+	agentPassword := string(s.Data["agent"])
+
+	// Existing passwords are kept, and missing ones are generated.
+	delete(s.Data, "metadata")
+	_, err = clientset.CoreV1().Secrets("pl").Update(ctx, s, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	require.NoError(t, ensureNATSAuthSecret(ctx, clientset, "pl", vz))
+	s, err = clientset.CoreV1().Secrets("pl").Get(ctx, natsAuthSecret, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, agentPassword, string(s.Data["agent"]))
+	assert.NotEmpty(t, s.Data["metadata"])
+}
+
+func TestEnsureNATSAuthSecret_Disabled(t *testing.T) {
+	ctx := context.Background()
+	clientset := fake.NewSimpleClientset()
+	require.NoError(t, ensureNATSAuthSecret(ctx, clientset, "pl", &v1alpha1.Vizier{}))
+	_, err := clientset.CoreV1().Secrets("pl").Get(ctx, natsAuthSecret, metav1.GetOptions{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,416 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"text/template"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+// networkPolicyLabel is set on all network policies deployed by the operator, so that they can be cleaned up
+// when network policies are disabled.
+const networkPolicyLabel = "vizier-network-policy"
+
+// networkPolicyTemplate contains the policies for the Vizier. All traffic in the namespace is denied by default,
+// then each component only accepts traffic on the ports that it serves.
+var networkPolicyTemplate = template.Must(template.New("networkPolicies").Parse(`---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: pl-default-deny
+  labels:
+    {{ .Label }}: "true"
+spec:
+  podSelector: {}
+  policyTypes:
+  - Ingress
+  - Egress
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: pl-allow-dns
+  labels:
+    {{ .Label }}: "true"
+spec:
+  podSelector: {}
+  policyTypes:
+  - Egress
+  egress:
+  - to:
+    - namespaceSelector: {}
+    ports:
+    - protocol: UDP
+      port: 53
+    - protocol: TCP
+      port: 53
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: pl-allow-vizier-egress
+  labels:
+    {{ .Label }}: "true"
+spec:
+  podSelector: {}
+  policyTypes:
+  - Egress
+  egress:
+  - to:
+    - podSelector: {}
+{{- if .DevCloudNamespace }}
+  - to:
+    - namespaceSelector:
+        matchLabels:
+          kubernetes.io/metadata.name: {{ .DevCloudNamespace }}
+{{- end }}
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: pl-allow-operator-ingress
+  labels:
+    {{ .Label }}: "true"
+spec:
+  podSelector: {}
+  policyTypes:
+  - Ingress
+  ingress:
+  # The operator checks the health of the Vizier pods.
+  - from:
+    - namespaceSelector: {}
+      podSelector:
+        matchLabels:
+          name: vizier-operator
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: pl-nats
+  labels:
+    {{ .Label }}: "true"
+spec:
+  podSelector:
+    matchLabels:
+      name: pl-nats
+  policyTypes:
+  - Ingress
+  ingress:
+  - from:
+    - podSelector: {}
+    ports:
+    - protocol: TCP
+      port: 4222
+  - from:
+    - podSelector:
+        matchLabels:
+          name: pl-nats
+    ports:
+    - protocol: TCP
+      port: 6222
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: pl-etcd
+  labels:
+    {{ .Label }}: "true"
+spec:
+  podSelector:
+    matchLabels:
+      etcd_cluster: pl-etcd
+  policyTypes:
+  - Ingress
+  ingress:
+  - from:
+    - podSelector:
+        matchLabels:
+          name: vizier-metadata
+    ports:
+    - protocol: TCP
+      port: 2379
+  - from:
+    - podSelector:
+        matchLabels:
+          etcd_cluster: pl-etcd
+    ports:
+    - protocol: TCP
+      port: 2379
+    - protocol: TCP
+      port: 2380
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: pl-metadata
+  labels:
+    {{ .Label }}: "true"
+spec:
+  podSelector:
+    matchLabels:
+      name: vizier-metadata
+  policyTypes:
+  - Ingress
+  ingress:
+  - from:
+    - podSelector: {}
+    ports:
+    - protocol: TCP
+      port: 50400
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: pl-query-broker
+  labels:
+    {{ .Label }}: "true"
+spec:
+  podSelector:
+    matchLabels:
+      name: vizier-query-broker
+  policyTypes:
+  - Ingress
+  ingress:
+  - from:
+    - podSelector: {}
+    ports:
+    - protocol: TCP
+      port: 50300
+  # The proxy serves direct connections to the Vizier from outside the cluster.
+  - ports:
+    - protocol: TCP
+      port: 50305
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: pl-kelvin
+  labels:
+    {{ .Label }}: "true"
+spec:
+  podSelector:
+    matchLabels:
+      name: kelvin
+  policyTypes:
+  - Ingress
+  ingress:
+  - from:
+    - podSelector: {}
+    ports:
+    - protocol: TCP
+      port: 59300
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: pl-cloud-connector
+  labels:
+    {{ .Label }}: "true"
+spec:
+  podSelector:
+    matchLabels:
+      name: vizier-cloud-connector
+  policyTypes:
+  - Ingress
+  ingress:
+  - from:
+    - podSelector: {}
+    ports:
+    - protocol: TCP
+      port: 50800
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: pl-control-plane
+  labels:
+    {{ .Label }}: "true"
+spec:
+  podSelector:
+    matchLabels:
+      name: vizier-control-plane
+  policyTypes:
+  - Ingress
+  ingress:
+  # The single process control plane serves the metadata service, the query broker and the cloud connector, and
+  # embeds the NATS server that the agents connect to.
+  - from:
+    - podSelector: {}
+    ports:
+    - protocol: TCP
+      port: 50300
+    - protocol: TCP
+      port: 4222
+  - ports:
+    - protocol: TCP
+      port: 50305
+{{- if .Cilium }}
+---
+apiVersion: cilium.io/v2
+kind: CiliumNetworkPolicy
+metadata:
+  name: pl-allow-external-egress
+  labels:
+    {{ .Label }}: "true"
+spec:
+  endpointSelector:
+    matchLabels:
+      plane: control
+  egress:
+  - toEntities:
+    - kube-apiserver
+  # DNS must be proxied by Cilium for the FQDN rule to apply.
+  - toEndpoints:
+    - matchLabels:
+        k8s:io.kubernetes.pod.namespace: kube-system
+        k8s-app: kube-dns
+    toPorts:
+    - ports:
+      - port: "53"
+        protocol: ANY
+      rules:
+        dns:
+        - matchPattern: "*"
+{{- if .CloudHost }}
+  - {{ if .CloudIsIP }}toCIDR:
+    - {{ .CloudHost }}/32{{ else }}toFQDNs:
+    - matchName: {{ .CloudHost }}{{ end }}
+    toPorts:
+    - ports:
+      - port: "{{ .CloudPort }}"
+        protocol: TCP
+{{- end }}
+{{- else }}
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: pl-allow-external-egress
+  labels:
+    {{ .Label }}: "true"
+spec:
+  podSelector:
+    matchLabels:
+      plane: control
+  policyTypes:
+  - Egress
+  egress:
+  # The control plane talks to the K8s API server and the Pixie cloud.
+  - ports:
+    - protocol: TCP
+      port: 443
+    - protocol: TCP
+      port: 6443
+{{- if and .CloudPort (ne .CloudPort "443") }}
+    - protocol: TCP
+      port: {{ .CloudPort }}
+{{- end }}
+{{- end }}
+`))
+
+type networkPolicyValues struct {
+	Label             string
+	Cilium            bool
+	CloudHost         string
+	CloudIsIP         bool
+	CloudPort         string
+	DevCloudNamespace string
+}
+
+// generateNetworkPolicyYAMLs generates the network policies for the given Vizier spec.
+func generateNetworkPolicyYAMLs(spec *v1alpha1.VizierSpec) (string, error) {
+	values := &networkPolicyValues{
+		Label:             networkPolicyLabel,
+		Cilium:            spec.NetworkPolicy != nil && spec.NetworkPolicy.Provider == v1alpha1.NetworkPolicyProviderCilium,
+		DevCloudNamespace: spec.DevCloudNamespace,
+	}
+
+	if spec.CloudAddr != "" {
+		host, port, err := net.SplitHostPort(spec.CloudAddr)
+		if err != nil {
+			host, port = spec.CloudAddr, "443"
+		}
+		values.CloudHost = host
+		values.CloudIsIP = net.ParseIP(host) != nil
+		values.CloudPort = port
+	}
+
+	var buf bytes.Buffer
+	if err := networkPolicyTemplate.Execute(&buf, values); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func networkPoliciesEnabled(spec *v1alpha1.VizierSpec) bool {
+	return spec.NetworkPolicy != nil && spec.NetworkPolicy.Enabled
+}
+
+// deployNetworkPolicies deploys the Vizier's network policies, or removes them if network policies are disabled.
+func (r *VizierReconciler) deployNetworkPolicies(ctx context.Context, namespace string, vz *v1alpha1.Vizier) error {
+	od := k8s.ObjectDeleter{
+		Namespace:  namespace,
+		Clientset:  r.Clientset,
+		RestConfig: r.RestConfig,
+		Timeout:    2 * time.Minute,
+	}
+	selector := networkPolicyLabel + "=true"
+
+	if !networkPoliciesEnabled(&vz.Spec) {
+		// The Cilium CRD may not exist on the cluster, in which case there is nothing to delete.
+		_, _ = od.DeleteByLabel(ctx, selector, "networkpolicies")
+		_, _ = od.DeleteByLabel(ctx, selector, "ciliumnetworkpolicies")
+		return nil
+	}
+
+	log.Info("Deploying network policies")
+	policyYAMLs, err := generateNetworkPolicyYAMLs(&vz.Spec)
+	if err != nil {
+		return err
+	}
+	resources, err := k8s.GetResourcesFromYAML(strings.NewReader(policyYAMLs))
+	if err != nil {
+		return err
+	}
+	for _, r := range resources {
+		err = updateResourceConfiguration(r, vz)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Remove the external egress policy of the other provider, if the provider was changed.
+	if vz.Spec.NetworkPolicy.Provider == v1alpha1.NetworkPolicyProviderCilium {
+		_ = r.Clientset.NetworkingV1().NetworkPolicies(namespace).Delete(ctx, "pl-allow-external-egress", metav1.DeleteOptions{})
+	} else {
+		_, _ = od.DeleteByLabel(ctx, selector, "ciliumnetworkpolicies")
+	}
+
+	return k8s.ApplyResources(r.Clientset, r.RestConfig, resources, namespace, nil, true)
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func TestGenerateNetworkPolicyYAMLs(t *testing.T) {
+	tests := []struct {
+		name          string
+		spec          *v1alpha1.VizierSpec
+		expectedKinds map[string]string
+		contains      []string
+		notContains   []string
+	}{
+		{
+			name: "kubernetes",
+			spec: &v1alpha1.VizierSpec{
+				CloudAddr:     "withpixie.ai:443",
+				NetworkPolicy: &v1alpha1.NetworkPolicy{Enabled: true},
+			},
+			expectedKinds: map[string]string{
+				"pl-default-deny":          "NetworkPolicy",
+				"pl-control-plane":         "NetworkPolicy",
+				"pl-allow-external-egress": "NetworkPolicy",
+			},
+			notContains: []string{"CiliumNetworkPolicy", "kubernetes.io/metadata.name"},
+		},
+		{
+			name: "cilium",
+			spec: &v1alpha1.VizierSpec{
+				CloudAddr: "withpixie.ai:443",
+				NetworkPolicy: &v1alpha1.NetworkPolicy{
+					Enabled:  true,
+					Provider: v1alpha1.NetworkPolicyProviderCilium,
+				},
+			},
+			expectedKinds: map[string]string{
+				"pl-default-deny":          "NetworkPolicy",
+				"pl-allow-external-egress": "CiliumNetworkPolicy",
+			},
+			contains: []string{"matchName: withpixie.ai"},
+		},
+		{
+			name: "dev cloud",
+			spec: &v1alpha1.VizierSpec{
+				CloudAddr:         "vzconn-service.plc-dev.svc:51600",
+				DevCloudNamespace: "plc-dev",
+				NetworkPolicy:     &v1alpha1.NetworkPolicy{Enabled: true},
+			},
+			expectedKinds: map[string]string{
+				"pl-allow-vizier-egress": "NetworkPolicy",
+			},
+			contains: []string{"kubernetes.io/metadata.name: plc-dev", "port: 51600"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policyYAMLs, err := generateNetworkPolicyYAMLs(test.spec)
+			require.NoError(t, err)
+
+			resources, err := k8s.GetResourcesFromYAML(strings.NewReader(policyYAMLs))
+			require.NoError(t, err)
+
+			kinds := make(map[string]string)
+			for _, r := range resources {
+				kinds[r.Object.GetName()] = r.GVK.Kind
+				assert.Equal(t, "true", r.Object.GetLabels()[networkPolicyLabel])
+			}
+			for name, kind := range test.expectedKinds {
+				assert.Equal(t, kind, kinds[name], name)
+			}
+			for _, s := range test.contains {
+				assert.Contains(t, policyYAMLs, s)
+			}
+			for _, s := range test.notContains {
+				assert.NotContains(t, policyYAMLs, s)
+			}
+		})
+	}
+}
@@ -41,6 +41,12 @@ var (
 	kernelMinVersion = semver.Version{Major: 4, Minor: 14, Patch: 0}
 )
 
+// nodeRunsLinux returns whether the PEM can run on the node. Nodes that don't report an OS are assumed to be Linux.
+func nodeRunsLinux(node *v1.Node) bool {
+	nodeOS := node.Status.NodeInfo.OperatingSystem
+	return nodeOS == "" || nodeOS == "linux"
+}
+
 func getNodeKernelVersion(node *v1.Node) string {
 	version := node.Status.NodeInfo.KernelVersion
 	// We don't actually care about pre-release tags, so drop them since they sometimes cause parse error.
@@ -70,9 +76,16 @@ type nodeCompatTracker struct {
 	numIncompatible   float64
 	numNodes          float64
 	kernelVersionDist map[string]int
+	// numNonLinux is the number of nodes, such as Windows nodes, that the PEM is never scheduled on.
+	// These are not counted in numNodes.
+	numNonLinux float64
 }
 
 func (n *nodeCompatTracker) addNode(node *v1.Node) {
+	if !nodeRunsLinux(node) {
+		n.numNonLinux++
+		return
+	}
 	n.numNodes++
 	kernelVersion := getNodeKernelVersion(node)
 	n.kernelVersionDist[kernelVersion]++
@@ -82,6 +95,10 @@ func (n *nodeCompatTracker) addNode(node *v1.Node) {
 }
 
 func (n *nodeCompatTracker) removeNode(node *v1.Node) {
+	if !nodeRunsLinux(node) {
+		n.numNonLinux--
+		return
+	}
 	n.numNodes--
 	kernelVersion := getNodeKernelVersion(node)
 	n.kernelVersionDist[kernelVersion]--
@@ -94,6 +111,9 @@ func (n *nodeCompatTracker) state() *vizierState {
 	if n.numIncompatible > degradedThreshold*n.numNodes {
 		return &vizierState{Reason: status.KernelVersionsIncompatible}
 	}
+	if n.numNonLinux > 0 {
+		return &vizierState{Reason: status.NodesPartiallyMonitored}
+	}
 	return okState()
 }
 
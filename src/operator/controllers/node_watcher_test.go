@@ -130,3 +130,24 @@ func TestMonitor_nodeWatcherHandleNode(t *testing.T) {
 		})
 	}
 }
+
+func TestMonitor_nodeWatcherNonLinuxNodes(t *testing.T) {
+	n := nodeCompatTracker{
+		kernelVersionDist: make(map[string]int),
+	}
+	linux := &v1.Node{Status: v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{OperatingSystem: "linux", KernelVersion: "5.4.0"}}}
+	windows := &v1.Node{Status: v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{OperatingSystem: "windows", KernelVersion: "10.0.17763.2114"}}}
+
+	n.addNode(linux)
+	assert.Equal(t, status.VizierReason(""), n.state().Reason)
+
+	n.addNode(windows)
+	state := n.state()
+	assert.Equal(t, status.NodesPartiallyMonitored, state.Reason)
+	assert.Equal(t, v1alpha1.VizierPhaseDegraded, v1alpha1.ReasonToPhase(state.Reason))
+	assert.Equal(t, 1.0, n.numNodes)
+	assert.Equal(t, 0, n.kernelVersionDist["10.0.17763.2114"])
+
+	n.removeNode(windows)
+	assert.Equal(t, status.VizierReason(""), n.state().Reason)
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"encoding/json"
+
+	log "github.com/sirupsen/logrus"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+// payloadCapturePolicyEnv is the PEM environment variable that Stirling reads its payload capture policy from.
+const payloadCapturePolicyEnv = "PL_STIRLING_PAYLOAD_CAPTURE_POLICY"
+
+// stirlingPayloadCaptureModes maps the CRD's payload capture modes to the ones understood by Stirling.
+var stirlingPayloadCaptureModes = map[v1alpha1.PayloadCaptureMode]string{
+	v1alpha1.PayloadCaptureFull:        "full",
+	v1alpha1.PayloadCaptureTruncate:    "truncate",
+	v1alpha1.PayloadCaptureHeadersOnly: "headers_only",
+}
+
+type stirlingPayloadCaptureRule struct {
+	Namespace    string `json:"namespace,omitempty"`
+	Service      string `json:"service,omitempty"`
+	Protocol     string `json:"protocol,omitempty"`
+	Mode         string `json:"mode"`
+	MaxBodyBytes uint32 `json:"maxBodyBytes,omitempty"`
+}
+
+type stirlingPayloadCapturePolicy struct {
+	DefaultMode string                       `json:"defaultMode,omitempty"`
+	Rules       []stirlingPayloadCaptureRule `json:"rules,omitempty"`
+}
+
+// customPEMFlags returns the custom flags of the PEMs, including the flag that passes them the payload capture
+// policy. A policy that is set explicitly through the custom flags takes precedence.
+func customPEMFlags(params *v1alpha1.DataCollectorParams) map[string]string {
+	if params.PayloadCapture == nil {
+		return params.CustomPEMFlags
+	}
+	if _, ok := params.CustomPEMFlags[payloadCapturePolicyEnv]; ok {
+		return params.CustomPEMFlags
+	}
+
+	policy := stirlingPayloadCapturePolicy{
+		DefaultMode: stirlingPayloadCaptureModes[params.PayloadCapture.DefaultMode],
+	}
+	for _, r := range params.PayloadCapture.Rules {
+		mode, ok := stirlingPayloadCaptureModes[r.Mode]
+		if !ok {
+			// Unknown modes are rejected by the CRD validation, but fail closed in case they slip through.
+			mode = stirlingPayloadCaptureModes[v1alpha1.PayloadCaptureHeadersOnly]
+		}
+		policy.Rules = append(policy.Rules, stirlingPayloadCaptureRule{
+			Namespace:    r.Namespace,
+			Service:      r.Service,
+			Protocol:     r.Protocol,
+			Mode:         mode,
+			MaxBodyBytes: r.MaxBodyBytes,
+		})
+	}
+	b, err := json.Marshal(policy)
+	if err != nil {
+		log.WithError(err).Error("Failed to marshal payload capture policy")
+		return params.CustomPEMFlags
+	}
+
+	flags := make(map[string]string, len(params.CustomPEMFlags)+1)
+	for k, v := range params.CustomPEMFlags {
+		flags[k] = v
+	}
+	flags[payloadCapturePolicyEnv] = string(b)
+	return flags
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+func TestCustomPEMFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   *v1alpha1.DataCollectorParams
+		expected map[string]string
+	}{
+		{
+			name: "no policy",
+			params: &v1alpha1.DataCollectorParams{
+				CustomPEMFlags: map[string]string{"PL_STIRLING_MAX_BODY_BYTES": "1024"},
+			},
+			expected: map[string]string{"PL_STIRLING_MAX_BODY_BYTES": "1024"},
+		},
+		{
+			name: "policy",
+			params: &v1alpha1.DataCollectorParams{
+				CustomPEMFlags: map[string]string{"PL_STIRLING_MAX_BODY_BYTES": "1024"},
+				PayloadCapture: &v1alpha1.PayloadCapturePolicy{
+					DefaultMode: v1alpha1.PayloadCaptureHeadersOnly,
+					Rules: []v1alpha1.PayloadCaptureRule{
+						{Namespace: "dev", Service: "api", Protocol: "http", Mode: v1alpha1.PayloadCaptureFull, MaxBodyBytes: 16384},
+						{Namespace: "dev", Mode: v1alpha1.PayloadCaptureTruncate},
+					},
+				},
+			},
+			expected: map[string]string{
+				"PL_STIRLING_MAX_BODY_BYTES": "1024",
+				"PL_STIRLING_PAYLOAD_CAPTURE_POLICY": `{"defaultMode":"headers_only","rules":[` +
+					`{"namespace":"dev","service":"api","protocol":"http","mode":"full","maxBodyBytes":16384},` +
+					`{"namespace":"dev","mode":"truncate"}]}`,
+			},
+		},
+		{
+			name: "unknown mode fails closed",
+			params: &v1alpha1.DataCollectorParams{
+				PayloadCapture: &v1alpha1.PayloadCapturePolicy{
+					Rules: []v1alpha1.PayloadCaptureRule{{Namespace: "dev", Mode: "Everything"}},
+				},
+			},
+			expected: map[string]string{
+				"PL_STIRLING_PAYLOAD_CAPTURE_POLICY": `{"rules":[{"namespace":"dev","mode":"headers_only"}]}`,
+			},
+		},
+		{
+			name: "explicit flag takes precedence",
+			params: &v1alpha1.DataCollectorParams{
+				CustomPEMFlags: map[string]string{"PL_STIRLING_PAYLOAD_CAPTURE_POLICY": `{"defaultMode":"full"}`},
+				PayloadCapture: &v1alpha1.PayloadCapturePolicy{DefaultMode: v1alpha1.PayloadCaptureHeadersOnly},
+			},
+			expected: map[string]string{"PL_STIRLING_PAYLOAD_CAPTURE_POLICY": `{"defaultMode":"full"}`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, customPEMFlags(test.params))
+		})
+	}
+}
+
+func TestCustomPEMFlagsDoesNotModifySpec(t *testing.T) {
+	params := &v1alpha1.DataCollectorParams{
+		CustomPEMFlags: map[string]string{"PL_STIRLING_MAX_BODY_BYTES": "1024"},
+		PayloadCapture: &v1alpha1.PayloadCapturePolicy{DefaultMode: v1alpha1.PayloadCaptureFull},
+	}
+	flags := customPEMFlags(params)
+	assert.Contains(t, flags, payloadCapturePolicyEnv)
+	assert.Equal(t, map[string]string{"PL_STIRLING_MAX_BODY_BYTES": "1024"}, params.CustomPEMFlags)
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+const (
+	smallProfilePEMMemoryLimit   = "1Gi"
+	smallProfilePEMMemoryRequest = "512Mi"
+	smallProfileBufferSize       = 512 * 1024
+	smallProfileBufferSpikeSize  = 10 * 1024 * 1024
+)
+
+// smallProfilePEMFlags are the PEM flags set by the small deployment profile.
+var smallProfilePEMFlags = map[string]string{
+	// Keep the table store well within the PEM memory limit.
+	"PL_TABLE_STORE_DATA_LIMIT_MB": "384",
+	// The default sources without the continuous profiler, which is the most expensive of them.
+	"PL_STIRLING_SOURCES": "process_stats,network_stats,jvm_stats,socket_tracer,proc_exit_tracer,stirling_error",
+}
+
+// applyDeploymentProfile fills in the settings of the Vizier's deployment profile that
+// have not been specified explicitly.
+func applyDeploymentProfile(vz *v1alpha1.Vizier) {
+	if vz.Spec.Profile != v1alpha1.DeploymentProfileSmall {
+		return
+	}
+
+	if vz.Spec.PemMemoryLimit == "" {
+		vz.Spec.PemMemoryLimit = smallProfilePEMMemoryLimit
+	}
+	if vz.Spec.PemMemoryRequest == "" {
+		vz.Spec.PemMemoryRequest = smallProfilePEMMemoryRequest
+	}
+
+	if vz.Spec.DataCollectorParams == nil {
+		vz.Spec.DataCollectorParams = &v1alpha1.DataCollectorParams{}
+	}
+	params := vz.Spec.DataCollectorParams
+	if params.DatastreamBufferSize == 0 {
+		params.DatastreamBufferSize = smallProfileBufferSize
+	}
+	if params.DatastreamBufferSpikeSize == 0 {
+		params.DatastreamBufferSpikeSize = smallProfileBufferSpikeSize
+	}
+	if params.CustomPEMFlags == nil {
+		params.CustomPEMFlags = make(map[string]string)
+	}
+	for k, v := range smallProfilePEMFlags {
+		if _, ok := params.CustomPEMFlags[k]; !ok {
+			params.CustomPEMFlags[k] = v
+		}
+	}
+}
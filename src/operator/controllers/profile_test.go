@@ -0,0 +1,92 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+func TestApplyDeploymentProfile(t *testing.T) {
+	tests := []struct {
+		name         string
+		spec         v1alpha1.VizierSpec
+		expectedSpec v1alpha1.VizierSpec
+	}{
+		{
+			name:         "default profile",
+			spec:         v1alpha1.VizierSpec{PemMemoryLimit: "2Gi"},
+			expectedSpec: v1alpha1.VizierSpec{PemMemoryLimit: "2Gi"},
+		},
+		{
+			name: "small profile",
+			spec: v1alpha1.VizierSpec{Profile: v1alpha1.DeploymentProfileSmall},
+			expectedSpec: v1alpha1.VizierSpec{
+				Profile:          v1alpha1.DeploymentProfileSmall,
+				PemMemoryLimit:   "1Gi",
+				PemMemoryRequest: "512Mi",
+				DataCollectorParams: &v1alpha1.DataCollectorParams{
+					DatastreamBufferSize:      512 * 1024,
+					DatastreamBufferSpikeSize: 10 * 1024 * 1024,
+					CustomPEMFlags: map[string]string{
+						"PL_TABLE_STORE_DATA_LIMIT_MB": "384",
+						"PL_STIRLING_SOURCES":          "process_stats,network_stats,jvm_stats,socket_tracer,proc_exit_tracer,stirling_error",
+					},
+				},
+			},
+		},
+		{
+			name: "small profile with overrides",
+			spec: v1alpha1.VizierSpec{
+				Profile:        v1alpha1.DeploymentProfileSmall,
+				PemMemoryLimit: "1500Mi",
+				DataCollectorParams: &v1alpha1.DataCollectorParams{
+					DatastreamBufferSize: 1024,
+					CustomPEMFlags: map[string]string{
+						"PL_STIRLING_SOURCES": "kProd",
+					},
+				},
+			},
+			expectedSpec: v1alpha1.VizierSpec{
+				Profile:          v1alpha1.DeploymentProfileSmall,
+				PemMemoryLimit:   "1500Mi",
+				PemMemoryRequest: "512Mi",
+				DataCollectorParams: &v1alpha1.DataCollectorParams{
+					DatastreamBufferSize:      1024,
+					DatastreamBufferSpikeSize: 10 * 1024 * 1024,
+					CustomPEMFlags: map[string]string{
+						"PL_TABLE_STORE_DATA_LIMIT_MB": "384",
+						"PL_STIRLING_SOURCES":          "kProd",
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vz := &v1alpha1.Vizier{Spec: test.spec}
+			applyDeploymentProfile(vz)
+			assert.Equal(t, test.expectedSpec, vz.Spec)
+		})
+	}
+}
@@ -59,6 +59,8 @@ const (
 	// This is the key for the annotation that the operator applies on all of its deployed resources for a CRD.
 	operatorAnnotation  = "vizier-name"
 	clusterSecretJWTKey = "jwt-signing-key"
+	// nodeOSLabel is the well-known node label that holds the node's operating system.
+	nodeOSLabel = "kubernetes.io/os"
 	// updatingFailedTimeout is the amount of time we wait since an Updated started
 	// before we consider the Update Failed.
 	updatingFailedTimeout = 10 * time.Minute
@@ -88,7 +90,7 @@ type VizierReconciler struct {
 // +kubebuilder:rbac:groups=pixie.px.dev,resources=viziers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=pixie.px.dev,resources=viziers/status,verbs=get;update;patch
 
-func getCloudClientConnection(cloudAddr string, devCloudNS string, extraDialOpts ...grpc.DialOption) (*grpc.ClientConn, error) {
+func getCloudClientConnection(cloudAddr string, devCloudNS string, cloudTLS *v1alpha1.CloudTLS, extraDialOpts ...grpc.DialOption) (*grpc.ClientConn, error) {
 	isInternal := false
 
 	if devCloudNS != "" {
@@ -96,7 +98,11 @@ func getCloudClientConnection(cloudAddr string, devCloudNS string, extraDialOpts
 		isInternal = true
 	}
 
-	dialOpts, err := services.GetGRPCClientDialOptsServerSideTLS(isInternal)
+	var tlsOverrides *services.ServerTLSOverrides
+	if cloudTLS != nil {
+		tlsOverrides = &services.ServerTLSOverrides{ServerName: cloudTLS.ServerName, CABundle: []byte(cloudTLS.CABundle)}
+	}
+	dialOpts, err := services.GetGRPCClientDialOptsServerSideTLSWithOverrides(isInternal, tlsOverrides)
 	dialOpts = append(dialOpts, extraDialOpts...)
 	if err != nil {
 		return nil, err
@@ -296,7 +302,7 @@ func (r *VizierReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 			restConfig:        r.RestConfig,
 		}
 
-		cloudClient, err := getCloudClientConnection(vizier.Spec.CloudAddr, vizier.Spec.DevCloudNamespace, grpc.FailOnNonTempDialError(true), grpc.WithBlock())
+		cloudClient, err := getCloudClientConnection(vizier.Spec.CloudAddr, vizier.Spec.DevCloudNamespace, vizier.Spec.CloudTLS, grpc.FailOnNonTempDialError(true), grpc.WithBlock())
 		if err != nil {
 			vizier.SetStatus(status.UnableToConnectToCloud)
 			err := r.Status().Update(ctx, &vizier)
@@ -373,14 +379,14 @@ func (r *VizierReconciler) deleteVizier(ctx context.Context, req ctrl.Request) e
 	}
 
 	keyValueLabel := operatorAnnotation + "=" + req.Name
-	_, _ = od.DeleteByLabel(keyValueLabel)
+	_, _ = od.DeleteByLabel(ctx, keyValueLabel)
 	return nil
 }
 
 // createVizier deploys a new vizier instance in the given namespace.
 func (r *VizierReconciler) createVizier(ctx context.Context, req ctrl.Request, vz *v1alpha1.Vizier) error {
 	log.Info("Creating a new vizier instance")
-	cloudClient, err := getCloudClientConnection(vz.Spec.CloudAddr, vz.Spec.DevCloudNamespace)
+	cloudClient, err := getCloudClientConnection(vz.Spec.CloudAddr, vz.Spec.DevCloudNamespace, vz.Spec.CloudTLS)
 	if err != nil {
 		vz.SetStatus(status.UnableToConnectToCloud)
 		err := r.Status().Update(ctx, vz)
@@ -418,7 +424,7 @@ func (r *VizierReconciler) createVizier(ctx context.Context, req ctrl.Request, v
 
 func (r *VizierReconciler) deployVizier(ctx context.Context, req ctrl.Request, vz *v1alpha1.Vizier, update bool) error {
 	log.Info("Starting a vizier deploy")
-	cloudClient, err := getCloudClientConnection(vz.Spec.CloudAddr, vz.Spec.DevCloudNamespace)
+	cloudClient, err := getCloudClientConnection(vz.Spec.CloudAddr, vz.Spec.DevCloudNamespace, vz.Spec.CloudTLS)
 	if err != nil {
 		vz.SetStatus(status.UnableToConnectToCloud)
 		err := r.Status().Update(ctx, vz)
@@ -458,7 +464,7 @@ func (r *VizierReconciler) deployVizier(ctx context.Context, req ctrl.Request, v
 		vz.Spec.Pod.NodeSelector = make(map[string]string)
 	}
 
-	if !vz.Spec.UseEtcdOperator && !update {
+	if !vz.Spec.UseEtcdOperator && !vz.Spec.SingleProcessControlPlane && !update {
 		// Check if the cluster offers PVC support.
 		// If it does not, we should default to using the etcd operator, which does not
 		// require PVC support.
@@ -477,6 +483,8 @@ func (r *VizierReconciler) deployVizier(ctx context.Context, req ctrl.Request, v
 	vz.Spec.Pod.Annotations[operatorAnnotation] = req.Name
 	vz.Spec.Pod.Labels[operatorAnnotation] = req.Name
 
+	applyDeploymentProfile(vz)
+
 	// Update the spec in the k8s api as other parts of the code expect this to be true.
 	err = r.Update(ctx, vz)
 	if err != nil {
@@ -501,6 +509,24 @@ func (r *VizierReconciler) deployVizier(ctx context.Context, req ctrl.Request, v
 	// vizier pods.
 	vz.Status.SentryDSN = configForVizierResp.SentryDSN
 
+	err = r.deployNetworkPolicies(ctx, req.Namespace, vz)
+	if err != nil {
+		log.WithError(err).Error("Failed to deploy network policies")
+		return err
+	}
+
+	err = r.deployPodDisruptionBudgets(ctx, req.Namespace, vz)
+	if err != nil {
+		log.WithError(err).Error("Failed to deploy pod disruption budgets")
+		return err
+	}
+
+	err = ensureNATSAuthSecret(ctx, r.Clientset, req.Namespace, vz)
+	if err != nil {
+		log.WithError(err).Error("Failed to deploy NATS credentials")
+		return err
+	}
+
 	if !update {
 		err = r.deployVizierConfigs(ctx, req.Namespace, vz, yamlMap)
 		if err != nil {
@@ -514,12 +540,15 @@ func (r *VizierReconciler) deployVizier(ctx context.Context, req ctrl.Request, v
 			return err
 		}
 
-		err = r.deployNATSStatefulset(ctx, req.Namespace, vz, yamlMap)
-		if err != nil {
-			log.WithError(err).Error("Failed to deploy NATS")
-			return err
+		// The single process control plane embeds the NATS server that the agents connect to.
+		if !vz.Spec.SingleProcessControlPlane {
+			err = r.deployNATSStatefulset(ctx, req.Namespace, vz, yamlMap)
+			if err != nil {
+				log.WithError(err).Error("Failed to deploy NATS")
+				return err
+			}
 		}
-	} else {
+	} else if !vz.Spec.SingleProcessControlPlane {
 		err = r.upgradeNats(ctx, req.Namespace, vz, yamlMap)
 		if err != nil {
 			log.WithError(err).Warning("Failed to upgrade nats")
@@ -644,7 +673,8 @@ func (r *VizierReconciler) upgradeNats(ctx context.Context, namespace string, vz
 		return r.deployNATSStatefulset(ctx, namespace, vz, yamlMap)
 	}
 
-	if natsImage == newSS.Spec.Template.Spec.Containers[0].Image {
+	if natsImage == newSS.Spec.Template.Spec.Containers[0].Image &&
+		ss.Spec.Template.Annotations[natsConfigChecksumAnnotation] == newSS.Spec.Template.Annotations[natsConfigChecksumAnnotation] {
 		log.Info("NATS up to date. Nothing to do.")
 		return nil
 	}
@@ -757,6 +787,10 @@ func (r *VizierReconciler) deployVizierCore(ctx context.Context, namespace strin
 		vzYaml = fmt.Sprintf("%s_ap", vzYaml)
 	}
 
+	if vz.Spec.SingleProcessControlPlane {
+		vzYaml = "vizier_control_plane"
+	}
+
 	resources, err := k8s.GetResourcesFromYAML(strings.NewReader(yamlMap[vzYaml]))
 	if err != nil {
 		log.WithError(err).Error("Error getting resources from Vizier YAML")
@@ -785,6 +819,9 @@ func updateResourceConfiguration(resource *k8s.Resource, vz *v1alpha1.Vizier) er
 	addKeyValueMapToResource("annotations", vz.Spec.Pod.Annotations, resource.Object.Object)
 	updateResourceRequirements(vz.Spec.Pod.Resources, resource.Object.Object)
 	updatePodSpec(vz.Spec.Pod.NodeSelector, vz.Spec.Pod.SecurityContext, resource.Object.Object)
+	if vz.Spec.FIPSMode {
+		useFIPSImages(resource.Object.Object)
+	}
 	return nil
 }
 
@@ -800,6 +837,42 @@ func convertResourceType(originalLst v1.ResourceList) *vizierconfigpb.ResourceLi
 	}
 }
 
+// vizierPatches returns the patches to apply to the Vizier's resources: the ones generated from the workload
+// identity, FIPS, NATS and availability settings, together with the ones in the spec. Patches that have been specified
+// explicitly for the same resources take precedence.
+func vizierPatches(spec *v1alpha1.VizierSpec) map[string]string {
+	patches := make(map[string]string)
+	generatedPatches := []map[string]string{
+		workloadIdentityPatches(spec),
+		fipsModePatches(spec),
+		// The NATS config with authorization includes the FIPS settings, so it must override the FIPS patch.
+		natsAuthPatches(spec),
+		topologySpreadPatches(spec),
+	}
+	for _, generated := range generatedPatches {
+		for name, patch := range generated {
+			existing, ok := patches[name]
+			if !ok {
+				patches[name] = patch
+				continue
+			}
+			merged, err := mergePatches(existing, patch)
+			if err != nil {
+				log.WithError(err).WithField("resource", name).Error("Failed to merge patches")
+				continue
+			}
+			patches[name] = merged
+		}
+	}
+	if len(patches) == 0 {
+		return spec.Patches
+	}
+	for name, patch := range spec.Patches {
+		patches[name] = patch
+	}
+	return patches
+}
+
 // generateVizierYAMLsConfig is responsible retrieving a yaml map of configurations from
 // Pixie Cloud.
 func generateVizierYAMLsConfig(ctx context.Context, ns string, k8sVersion string, vz *v1alpha1.Vizier, conn *grpc.ClientConn) (*cloudpb.ConfigForVizierResponse,
@@ -831,8 +904,9 @@ func generateVizierYAMLsConfig(ctx context.Context, ns string, k8sVersion string
 				},
 				NodeSelector: vz.Spec.Pod.NodeSelector,
 			},
-			Patches:  vz.Spec.Patches,
-			Registry: vz.Spec.Registry,
+			Patches:                   vizierPatches(&vz.Spec),
+			Registry:                  vz.Spec.Registry,
+			SingleProcessControlPlane: vz.Spec.SingleProcessControlPlane,
 		},
 	}
 
@@ -840,7 +914,7 @@ func generateVizierYAMLsConfig(ctx context.Context, ns string, k8sVersion string
 		req.VzSpec.DataCollectorParams = &vizierconfigpb.DataCollectorParams{
 			DatastreamBufferSize:      vz.Spec.DataCollectorParams.DatastreamBufferSize,
 			DatastreamBufferSpikeSize: vz.Spec.DataCollectorParams.DatastreamBufferSpikeSize,
-			CustomPEMFlags:            vz.Spec.DataCollectorParams.CustomPEMFlags,
+			CustomPEMFlags:            customPEMFlags(vz.Spec.DataCollectorParams),
 		}
 	}
 
@@ -976,6 +1050,11 @@ func updatePodSpec(nodeSelector map[string]string, securityCtx *v1alpha1.PodSecu
 		}
 		castedNodeSelector[k] = v
 	}
+	// Vizier only runs on Linux, so keep pods off of Windows nodes in mixed clusters even if the
+	// default node affinity was patched away.
+	if _, ok := castedNodeSelector[nodeOSLabel]; !ok {
+		castedNodeSelector[nodeOSLabel] = "linux"
+	}
 	podSpec["nodeSelector"] = castedNodeSelector
 
 	// Add securityContext only if enabled.
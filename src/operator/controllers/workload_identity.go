@@ -0,0 +1,98 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"encoding/json"
+
+	log "github.com/sirupsen/logrus"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+const (
+	// queryBrokerServiceAccount is the service account of the query broker, which accesses object storage for exports.
+	queryBrokerServiceAccount = "query-broker-service-account"
+	queryBrokerDeployment     = "vizier-query-broker"
+
+	awsRoleARNAnnotation        = "eks.amazonaws.com/role-arn"
+	gcpServiceAccountAnnotation = "iam.gke.io/gcp-service-account"
+	azureClientIDAnnotation     = "azure.workload.identity/client-id"
+	azureTenantIDAnnotation     = "azure.workload.identity/tenant-id"
+	azureUseLabel               = "azure.workload.identity/use"
+)
+
+// workloadIdentityPatches returns the patches which bind the Vizier's cloud facing service accounts to the workload
+// identity in the spec.
+func workloadIdentityPatches(spec *v1alpha1.VizierSpec) map[string]string {
+	wi := spec.WorkloadIdentity
+	if wi == nil {
+		return nil
+	}
+
+	annotations := make(map[string]string)
+	if wi.AWSRoleARN != "" {
+		annotations[awsRoleARNAnnotation] = wi.AWSRoleARN
+	}
+	if wi.GCPServiceAccount != "" {
+		annotations[gcpServiceAccountAnnotation] = wi.GCPServiceAccount
+	}
+	if wi.AzureClientID != "" {
+		annotations[azureClientIDAnnotation] = wi.AzureClientID
+		if wi.AzureTenantID != "" {
+			annotations[azureTenantIDAnnotation] = wi.AzureTenantID
+		}
+	}
+	if len(annotations) == 0 {
+		return nil
+	}
+
+	wiPatches := map[string]interface{}{
+		queryBrokerServiceAccount: map[string]interface{}{
+			"metadata": map[string]interface{}{"annotations": annotations},
+		},
+	}
+	// The Azure webhook only injects the federated token into pods which opt in.
+	if wi.AzureClientID != "" {
+		wiPatches[queryBrokerDeployment] = map[string]interface{}{
+			"spec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"labels": map[string]string{azureUseLabel: "true"},
+					},
+				},
+			},
+		}
+	}
+	return marshalPatches(wiPatches)
+}
+
+// marshalPatches encodes each of the patches as JSON.
+func marshalPatches(patches map[string]interface{}) map[string]string {
+	marshaled := make(map[string]string)
+	for name, patch := range patches {
+		b, err := json.Marshal(patch)
+		if err != nil {
+			log.WithError(err).WithField("resource", name).Error("Failed to marshal patch")
+			continue
+		}
+		marshaled[name] = string(b)
+	}
+	return marshaled
+}
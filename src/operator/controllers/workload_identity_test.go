@@ -0,0 +1,75 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+func TestWorkloadIdentityPatches(t *testing.T) {
+	tests := []struct {
+		name            string
+		spec            v1alpha1.VizierSpec
+		expectedPatches map[string]string
+	}{
+		{
+			name:            "no workload identity",
+			spec:            v1alpha1.VizierSpec{},
+			expectedPatches: nil,
+		},
+		{
+			name: "aws",
+			spec: v1alpha1.VizierSpec{
+				WorkloadIdentity: &v1alpha1.WorkloadIdentity{AWSRoleARN: "arn:aws:iam::123:role/pixie"},
+			},
+			expectedPatches: map[string]string{
+				"query-broker-service-account": `{"metadata":{"annotations":{"eks.amazonaws.com/role-arn":"arn:aws:iam::123:role/pixie"}}}`,
+			},
+		},
+		{
+			name: "gcp",
+			spec: v1alpha1.VizierSpec{
+				WorkloadIdentity: &v1alpha1.WorkloadIdentity{GCPServiceAccount: "pixie@project.iam.gserviceaccount.com"},
+			},
+			expectedPatches: map[string]string{
+				"query-broker-service-account": `{"metadata":{"annotations":{"iam.gke.io/gcp-service-account":"pixie@project.iam.gserviceaccount.com"}}}`,
+			},
+		},
+		{
+			name: "azure",
+			spec: v1alpha1.VizierSpec{
+				WorkloadIdentity: &v1alpha1.WorkloadIdentity{AzureClientID: "client", AzureTenantID: "tenant"},
+			},
+			expectedPatches: map[string]string{
+				"query-broker-service-account": `{"metadata":{"annotations":{"azure.workload.identity/client-id":"client","azure.workload.identity/tenant-id":"tenant"}}}`,
+				"vizier-query-broker":          `{"spec":{"template":{"metadata":{"labels":{"azure.workload.identity/use":"true"}}}}}`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expectedPatches, workloadIdentityPatches(&test.spec))
+		})
+	}
+}
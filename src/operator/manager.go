@@ -21,6 +21,7 @@ package main
 import (
 	"flag"
 	"os"
+	"path/filepath"
 
 	log "github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -29,6 +30,7 @@ import (
 	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
 
+	v1 "px.dev/pixie/src/operator/apis/px.dev/v1"
 	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
 	"px.dev/pixie/src/operator/controllers"
 	"px.dev/pixie/src/utils/shared/k8s"
@@ -47,16 +49,21 @@ func init() {
 	_ = clientgoscheme.AddToScheme(scheme)
 
 	_ = v1alpha1.AddToScheme(scheme)
+	_ = v1.AddToScheme(scheme)
 	// +kubebuilder:scaffold:scheme
 }
 
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
+	var webhookCertDir string
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", "/tmp/k8s-webhook-server/serving-certs",
+		"The directory containing the tls.crt and tls.key for the Vizier conversion, defaulting and validation webhooks. "+
+			"The webhooks are only served if the certificate exists, which OLM provisions.")
 	flag.Parse()
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
@@ -65,6 +72,7 @@ func main() {
 		Port:               9443,
 		LeaderElection:     enableLeaderElection,
 		LeaderElectionID:   leaderElectionID,
+		CertDir:            webhookCertDir,
 	})
 	if err != nil {
 		log.WithError(err).Error("Unable to start manager")
@@ -105,6 +113,17 @@ func main() {
 		os.Exit(1)
 	}
 	defer vr.Stop()
+
+	if _, err := os.Stat(filepath.Join(webhookCertDir, "tls.crt")); err == nil {
+		err = ctrl.NewWebhookManagedBy(mgr).For(&v1.Vizier{}).Complete()
+		if err != nil {
+			log.WithError(err).Error("Unable to create Vizier webhooks")
+			os.Exit(1)
+		}
+		log.Info("Serving Vizier webhooks")
+	} else {
+		log.WithError(err).Info("No webhook certificate found, not serving Vizier webhooks")
+	}
 	// +kubebuilder:scaffold:builder
 
 	log.Info("Starting manager")
@@ -0,0 +1,104 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cloudinstall
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	// Namespace is the namespace that the self-hosted cloud is deployed to. The released manifests
+	// reference it in service addresses, so it can't be changed at install time.
+	Namespace = "plc"
+	// DefaultDomain is the domain that the released manifests are configured with.
+	DefaultDomain = "dev.withpixie.dev"
+	// InstallConfigMapName is the name of the configmap that records the current installation.
+	InstallConfigMapName = "pl-cloud-install-config"
+)
+
+// The identity providers that the cloud can be configured with.
+const (
+	// IdPHydra uses the bundled Ory Kratos and Hydra deployment.
+	IdPHydra = "hydra"
+	// IdPOIDC uses an external OpenID Connect provider.
+	IdPOIDC = "oidc"
+)
+
+// IdPConfig holds the identity provider settings of the cloud.
+type IdPConfig struct {
+	Provider string
+	// Host is the OIDC issuer URL. It is unused for hydra.
+	Host         string
+	ClientID     string
+	ClientSecret string
+	// MetadataURL optionally overrides the OIDC discovery endpoint.
+	MetadataURL string
+}
+
+// Config holds the settings that are prompted for when installing the cloud.
+type Config struct {
+	// Version is the cloud release to install, or "latest".
+	Version string
+	// Domain is the domain that the cloud will be served at.
+	Domain string
+	// TLSCertFile and TLSKeyFile are the certificate served for the domain. If they are
+	// empty a self-signed certificate is generated.
+	TLSCertFile string
+	TLSKeyFile  string
+	IdP         IdPConfig
+}
+
+// Validate checks that the config is complete. The identity provider is validated separately, since
+// upgrades keep the existing one.
+func (c *Config) Validate() error {
+	if c.Version == "" {
+		return errors.New("a cloud version is required")
+	}
+	if c.Domain == "" {
+		return errors.New("a domain is required")
+	}
+	if strings.Contains(c.Domain, "/") || strings.Contains(c.Domain, ":") {
+		return fmt.Errorf("domain %q must be a bare hostname", c.Domain)
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return errors.New("the TLS cert and key must be specified together")
+	}
+	return nil
+}
+
+// Validate checks that the identity provider is supported and fully configured.
+func (c *IdPConfig) Validate() error {
+	switch c.Provider {
+	case IdPHydra:
+		return nil
+	case IdPOIDC:
+	default:
+		return fmt.Errorf("unsupported identity provider %q, must be %s or %s", c.Provider, IdPHydra, IdPOIDC)
+	}
+	if u, err := url.Parse(c.Host); err != nil || u.Scheme != "https" || u.Host == "" {
+		return errors.New("the OIDC issuer must be an https URL")
+	}
+	if c.ClientID == "" || c.ClientSecret == "" {
+		return errors.New("the OIDC client ID and secret are required")
+	}
+	return nil
+}
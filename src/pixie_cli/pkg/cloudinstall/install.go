@@ -0,0 +1,161 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cloudinstall
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+// Installation describes the cloud that is currently installed in the cluster.
+type Installation struct {
+	Version     string
+	Domain      string
+	OAuthConfig map[string]string
+}
+
+// Installer deploys the cloud to a cluster and checks on its health.
+type Installer struct {
+	clientset kubernetes.Interface
+	config    *rest.Config
+}
+
+// NewInstaller creates an installer for the cluster.
+func NewInstaller(clientset kubernetes.Interface, config *rest.Config) *Installer {
+	return &Installer{clientset: clientset, config: config}
+}
+
+// CurrentInstallation returns the installation in the cluster, or nil if the cloud has not been installed
+// by px.
+func (i *Installer) CurrentInstallation(ctx context.Context) (*Installation, error) {
+	cm, err := i.clientset.CoreV1().ConfigMaps(Namespace).Get(ctx, InstallConfigMapName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	inst := &Installation{
+		Version: cm.Data["PL_CLOUD_VERSION"],
+		Domain:  cm.Data["PL_DOMAIN_NAME"],
+	}
+
+	oauth, err := i.clientset.CoreV1().ConfigMaps(Namespace).Get(ctx, "pl-oauth-config", metav1.GetOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return nil, err
+	}
+	if err == nil {
+		inst.OAuthConfig = oauth.Data
+	}
+	return inst, nil
+}
+
+// Apply applies the stage's resources to the cluster.
+func (i *Installer) Apply(stage *Stage) error {
+	if stage.YAML == "" {
+		return nil
+	}
+	return k8s.ApplyYAML(i.clientset, i.config, "", strings.NewReader(stage.YAML), stage.AllowUpdate)
+}
+
+// UnreadyWorkloads returns the deployments and statefulsets in the cloud namespace that have not finished
+// rolling out.
+func (i *Installer) UnreadyWorkloads(ctx context.Context) ([]string, error) {
+	var unready []string
+
+	deployments, err := i.clientset.AppsV1().Deployments(Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range deployments.Items {
+		replicas := int32(1)
+		if d.Spec.Replicas != nil {
+			replicas = *d.Spec.Replicas
+		}
+		if d.Status.ObservedGeneration < d.Generation || d.Status.UpdatedReplicas < replicas || d.Status.ReadyReplicas < replicas {
+			unready = append(unready, fmt.Sprintf("deployment/%s (%d/%d ready)", d.Name, d.Status.ReadyReplicas, replicas))
+		}
+	}
+
+	statefulSets, err := i.clientset.AppsV1().StatefulSets(Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range statefulSets.Items {
+		replicas := int32(1)
+		if s.Spec.Replicas != nil {
+			replicas = *s.Spec.Replicas
+		}
+		if s.Status.ObservedGeneration < s.Generation || s.Status.ReadyReplicas < replicas {
+			unready = append(unready, fmt.Sprintf("statefulset/%s (%d/%d ready)", s.Name, s.Status.ReadyReplicas, replicas))
+		}
+	}
+
+	sort.Strings(unready)
+	return unready, nil
+}
+
+// WaitForReady polls the workloads in the cloud namespace until they are all ready. If the context
+// expires first, the error lists the workloads that are still unready.
+func (i *Installer) WaitForReady(ctx context.Context, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		unready, err := i.UnreadyWorkloads(ctx)
+		if err == nil && len(unready) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("timed out waiting for %s", strings.Join(unready, ", "))
+		case <-t.C:
+		}
+	}
+}
+
+// ProxyAddress returns the external address of the cloud proxy, which the domain's DNS records should
+// point to. It is empty until the load balancer has been provisioned.
+func (i *Installer) ProxyAddress(ctx context.Context) (string, error) {
+	svc, err := i.clientset.CoreV1().Services(Namespace).Get(ctx, "cloud-proxy-service", metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			return ingress.IP, nil
+		}
+		if ingress.Hostname != "" {
+			return ingress.Hostname, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,127 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cloudinstall_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"px.dev/pixie/src/pixie_cli/pkg/cloudinstall"
+)
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func TestInstaller_CurrentInstallation(t *testing.T) {
+	ctx := context.Background()
+
+	inst, err := cloudinstall.NewInstaller(fake.NewSimpleClientset(), nil).CurrentInstallation(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, inst)
+
+	clientset := fake.NewSimpleClientset(
+		&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: cloudinstall.InstallConfigMapName, Namespace: cloudinstall.Namespace},
+			Data:       map[string]string{"PL_CLOUD_VERSION": "0.1.2", "PL_DOMAIN_NAME": "pixie.example.com"},
+		},
+		&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "pl-oauth-config", Namespace: cloudinstall.Namespace},
+			Data:       map[string]string{"PL_OAUTH_PROVIDER": "oidc"},
+		},
+	)
+	inst, err = cloudinstall.NewInstaller(clientset, nil).CurrentInstallation(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &cloudinstall.Installation{
+		Version:     "0.1.2",
+		Domain:      "pixie.example.com",
+		OAuthConfig: map[string]string{"PL_OAUTH_PROVIDER": "oidc"},
+	}, inst)
+}
+
+func TestInstaller_UnreadyWorkloads(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "api-server", Namespace: cloudinstall.Namespace},
+			Spec:       appsv1.DeploymentSpec{Replicas: int32Ptr(2)},
+			Status:     appsv1.DeploymentStatus{UpdatedReplicas: 2, ReadyReplicas: 2},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "auth-server", Namespace: cloudinstall.Namespace},
+			Spec:       appsv1.DeploymentSpec{Replicas: int32Ptr(1)},
+			Status:     appsv1.DeploymentStatus{UpdatedReplicas: 1, ReadyReplicas: 0},
+		},
+		&appsv1.Deployment{
+			// Still rolling out an upgrade: the ready pod is from the previous version.
+			ObjectMeta: metav1.ObjectMeta{Name: "vzmgr-server", Namespace: cloudinstall.Namespace},
+			Spec:       appsv1.DeploymentSpec{Replicas: int32Ptr(1)},
+			Status:     appsv1.DeploymentStatus{UpdatedReplicas: 0, ReadyReplicas: 1},
+		},
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "pl-nats", Namespace: cloudinstall.Namespace},
+			Spec:       appsv1.StatefulSetSpec{Replicas: int32Ptr(3)},
+			Status:     appsv1.StatefulSetStatus{ReadyReplicas: 1},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"},
+			Spec:       appsv1.DeploymentSpec{Replicas: int32Ptr(1)},
+		},
+	)
+	installer := cloudinstall.NewInstaller(clientset, nil)
+
+	unready, err := installer.UnreadyWorkloads(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"deployment/auth-server (0/1 ready)",
+		"deployment/vzmgr-server (1/1 ready)",
+		"statefulset/pl-nats (1/3 ready)",
+	}, unready)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = installer.WaitForReady(ctx, 10*time.Millisecond)
+	assert.ErrorContains(t, err, "timed out waiting for deployment/auth-server")
+}
+
+func TestInstaller_ProxyAddress(t *testing.T) {
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "cloud-proxy-service", Namespace: cloudinstall.Namespace},
+	}
+	clientset := fake.NewSimpleClientset(svc)
+	installer := cloudinstall.NewInstaller(clientset, nil)
+
+	addr, err := installer.ProxyAddress(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "", addr)
+
+	svc.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{IP: "10.0.0.7"}}
+	_, err = clientset.CoreV1().Services(cloudinstall.Namespace).UpdateStatus(context.Background(), svc, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	addr, err = installer.ProxyAddress(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.7", addr)
+}
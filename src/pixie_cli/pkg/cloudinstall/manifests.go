@@ -0,0 +1,138 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cloudinstall
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"px.dev/pixie/src/utils/shared/artifacts"
+	"px.dev/pixie/src/utils/shared/tar"
+)
+
+const (
+	ghReleaseURLFormat = "https://github.com/pixie-io/pixie/releases/download/release/cloud/v%s/pixie_cloud.tar.gz"
+	gcsURLFormat       = "https://storage.googleapis.com/pixie-dev-public/cloud/%s/pixie_cloud.tar.gz"
+
+	depsPath            = "pixie_cloud/yamls/cloud_deps.yaml"
+	elasticOperatorPath = "pixie_cloud/yamls/cloud_deps_elastic_operator.yaml"
+	cloudPath           = "pixie_cloud/yamls/cloud.yaml"
+)
+
+// Manifests are the YAMLs of a cloud release, as published in pixie_cloud.tar.gz.
+type Manifests struct {
+	ElasticOperator string
+	Deps            string
+	Cloud           string
+}
+
+// ManifestURLs returns the mirrors that the manifests for the version can be downloaded from, in
+// the order they should be tried. Only the GCS mirror hosts "latest".
+func ManifestURLs(version string) []string {
+	if version == "latest" {
+		return []string{fmt.Sprintf(gcsURLFormat, version)}
+	}
+	version = strings.TrimPrefix(version, "v")
+	return []string{
+		fmt.Sprintf(ghReleaseURLFormat, version),
+		fmt.Sprintf(gcsURLFormat, version),
+	}
+}
+
+// FetchManifests downloads and verifies the manifests for the version from the first mirror
+// that has them.
+func FetchManifests(version string, opts *artifacts.VerificationOptions) (*Manifests, error) {
+	var errs []string
+	for _, u := range ManifestURLs(version) {
+		m, err := fetchManifestsFromURL(u, opts)
+		if err == nil {
+			return m, nil
+		}
+		if artifacts.IsVerificationError(err) {
+			return nil, err
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", u, err))
+	}
+	return nil, fmt.Errorf("failed to download cloud manifests: %s", strings.Join(errs, "; "))
+}
+
+func fetchManifestsFromURL(u string, opts *artifacts.VerificationOptions) (*Manifests, error) {
+	tarball, err := download(u)
+	if err != nil {
+		return nil, err
+	}
+	var sha, signature []byte
+	if opts == nil || !opts.SkipVerification {
+		if sha, err = download(u + ".sha256"); err != nil {
+			return nil, err
+		}
+		if opts != nil && len(opts.Keyring) > 0 {
+			if signature, err = download(u + ".asc"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := opts.Verify(tarball, string(sha), string(signature)); err != nil {
+		return nil, err
+	}
+	return ReadManifests(bytes.NewReader(tarball))
+}
+
+func download(u string) ([]byte, error) {
+	resp, err := http.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// ReadManifests reads the manifests from a gzipped pixie_cloud.tar.gz.
+func ReadManifests(r io.Reader) (*Manifests, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	files, err := tar.ReadTarFileFromReader(gz)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifests{}
+	for path, dest := range map[string]*string{
+		elasticOperatorPath: &m.ElasticOperator,
+		depsPath:            &m.Deps,
+		cloudPath:           &m.Cloud,
+	} {
+		contents, ok := files[path]
+		if !ok {
+			return nil, fmt.Errorf("cloud manifests are missing %s", path)
+		}
+		*dest = contents
+	}
+	return m, nil
+}
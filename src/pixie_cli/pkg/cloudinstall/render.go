@@ -0,0 +1,138 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cloudinstall
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+	"px.dev/pixie/src/utils/shared/yamls"
+)
+
+// Stage is a set of resources that is applied to the cluster in one step.
+type Stage struct {
+	Name string
+	YAML string
+	// AllowUpdate is whether resources that already exist in the cluster are updated.
+	AllowUpdate bool
+}
+
+// OAuthConfigData returns the contents of the pl-oauth-config configmap for the identity provider.
+func OAuthConfigData(idp IdPConfig) map[string]string {
+	if idp.Provider != IdPOIDC {
+		return map[string]string{
+			"PL_OAUTH_PROVIDER":    IdPHydra,
+			"PL_AUTH_URI":          "/oauth/hydra",
+			"PL_AUTH_CLIENT_ID":    "auth-code-client",
+			"PL_OIDC_HOST":         "",
+			"PL_OIDC_CLIENT_ID":    "",
+			"PL_OIDC_METADATA_URL": "",
+		}
+	}
+	authURI := idp.Host
+	if u, err := url.Parse(idp.Host); err == nil {
+		authURI = u.Host
+	}
+	return map[string]string{
+		"PL_OAUTH_PROVIDER":    IdPOIDC,
+		"PL_AUTH_URI":          authURI,
+		"PL_AUTH_CLIENT_ID":    idp.ClientID,
+		"PL_OIDC_HOST":         idp.Host,
+		"PL_OIDC_CLIENT_ID":    idp.ClientID,
+		"PL_OIDC_METADATA_URL": idp.MetadataURL,
+	}
+}
+
+// Render configures the release manifests for the installation, and returns the stages that deploy it
+// in the order they must be applied. The oauth config is the data of the pl-oauth-config configmap,
+// which upgrades carry over from the existing installation.
+func Render(m *Manifests, cfg *Config, oauthConfig map[string]string) ([]*Stage, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	generated, err := generatedSecretsYAML(cfg)
+	if err != nil {
+		return nil, err
+	}
+	configured, err := configuredSecretsYAML(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	cloudYAML := strings.ReplaceAll(m.Cloud, DefaultDomain, cfg.Domain)
+	domainPatch, err := configMapPatch(map[string]string{"PL_DOMAIN_NAME": cfg.Domain})
+	if err != nil {
+		return nil, err
+	}
+	oauthPatch, err := configMapPatch(oauthConfig)
+	if err != nil {
+		return nil, err
+	}
+	cloudYAML, err = yamls.AddPatchesToYAML(cloudYAML, map[string]string{
+		"pl-domain-config": domainPatch,
+		"pl-oauth-config":  oauthPatch,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	installYAML, err := installConfigMapYAML(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	stages := []*Stage{
+		{Name: "Generate secrets", YAML: generated},
+		{Name: "Deploy Elastic operator", YAML: m.ElasticOperator, AllowUpdate: true},
+		{Name: "Deploy cloud dependencies", YAML: m.Deps, AllowUpdate: true},
+	}
+	if configured != "" {
+		stages = append(stages, &Stage{Name: "Configure secrets", YAML: configured, AllowUpdate: true})
+	}
+	return append(stages,
+		&Stage{Name: "Deploy Pixie Cloud", YAML: cloudYAML, AllowUpdate: true},
+		&Stage{Name: "Record installation", YAML: installYAML, AllowUpdate: true},
+	), nil
+}
+
+func configMapPatch(data map[string]string) (string, error) {
+	b, err := json.Marshal(map[string]interface{}{"data": data})
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func installConfigMapYAML(cfg *Config) (string, error) {
+	cm, err := k8s.CreateConfigMapFromLiterals(Namespace, InstallConfigMapName, map[string]string{
+		"PL_CLOUD_VERSION": cfg.Version,
+		"PL_DOMAIN_NAME":   cfg.Domain,
+	})
+	if err != nil {
+		return "", err
+	}
+	yaml, err := k8s.ConvertResourceToYAML(cm)
+	if err != nil {
+		return "", err
+	}
+	return joinYAMLs([]string{yaml}), nil
+}
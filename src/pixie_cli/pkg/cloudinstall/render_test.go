@@ -0,0 +1,288 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cloudinstall_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/pixie_cli/pkg/cloudinstall"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+const testCloudYAML = `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: pl-domain-config
+  namespace: plc
+data:
+  PL_DOMAIN_NAME: dev.withpixie.dev
+  PASSTHROUGH_PROXY_PORT: "4444"
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: pl-oauth-config
+  namespace: plc
+data:
+  PL_OAUTH_PROVIDER: hydra
+  PL_AUTH_URI: /oauth/hydra
+  PL_AUTH_CLIENT_ID: auth-code-client
+  PL_AUTH_EMAIL_PASSWORD_CONN: ""
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: proxy-envoy-config
+  namespace: plc
+data:
+  envoy.yaml: |
+    domains:
+    - suffix: "dev.withpixie.dev"
+`
+
+func makeManifestsTarball(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, contents := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0o644,
+			Size:     int64(len(contents)),
+			Typeflag: tar.TypeReg,
+		}))
+		_, err := tw.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestReadManifests(t *testing.T) {
+	tarball := makeManifestsTarball(t, map[string]string{
+		"pixie_cloud/yamls/cloud_deps.yaml":                  "deps",
+		"pixie_cloud/yamls/cloud_deps_elastic_operator.yaml": "operator",
+		"pixie_cloud/yamls/cloud.yaml":                       "cloud",
+		"pixie_cloud/cloud_image_list.txt":                   "images",
+	})
+	m, err := cloudinstall.ReadManifests(bytes.NewReader(tarball))
+	require.NoError(t, err)
+	assert.Equal(t, "deps", m.Deps)
+	assert.Equal(t, "operator", m.ElasticOperator)
+	assert.Equal(t, "cloud", m.Cloud)
+
+	tarball = makeManifestsTarball(t, map[string]string{
+		"pixie_cloud/yamls/cloud.yaml": "cloud",
+	})
+	_, err = cloudinstall.ReadManifests(bytes.NewReader(tarball))
+	assert.ErrorContains(t, err, "missing")
+}
+
+func TestManifestURLs(t *testing.T) {
+	assert.Equal(t, []string{
+		"https://github.com/pixie-io/pixie/releases/download/release/cloud/v0.1.2/pixie_cloud.tar.gz",
+		"https://storage.googleapis.com/pixie-dev-public/cloud/0.1.2/pixie_cloud.tar.gz",
+	}, cloudinstall.ManifestURLs("v0.1.2"))
+	assert.Equal(t, []string{
+		"https://storage.googleapis.com/pixie-dev-public/cloud/latest/pixie_cloud.tar.gz",
+	}, cloudinstall.ManifestURLs("latest"))
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     cloudinstall.Config
+		wantErr string
+	}{
+		{
+			name: "valid",
+			cfg:  cloudinstall.Config{Version: "latest", Domain: "pixie.example.com"},
+		},
+		{
+			name:    "missing domain",
+			cfg:     cloudinstall.Config{Version: "latest"},
+			wantErr: "domain is required",
+		},
+		{
+			name:    "domain with scheme",
+			cfg:     cloudinstall.Config{Version: "latest", Domain: "https://pixie.example.com"},
+			wantErr: "bare hostname",
+		},
+		{
+			name:    "cert without key",
+			cfg:     cloudinstall.Config{Version: "latest", Domain: "pixie.example.com", TLSCertFile: "tls.crt"},
+			wantErr: "specified together",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.cfg.Validate()
+			if test.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, test.wantErr)
+		})
+	}
+}
+
+func TestIdPConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		idp     cloudinstall.IdPConfig
+		wantErr string
+	}{
+		{
+			name: "hydra",
+			idp:  cloudinstall.IdPConfig{Provider: cloudinstall.IdPHydra},
+		},
+		{
+			name: "oidc",
+			idp:  cloudinstall.IdPConfig{Provider: cloudinstall.IdPOIDC, Host: "https://idp.example.com", ClientID: "id", ClientSecret: "secret"},
+		},
+		{
+			name:    "oidc over http",
+			idp:     cloudinstall.IdPConfig{Provider: cloudinstall.IdPOIDC, Host: "http://idp.example.com", ClientID: "id", ClientSecret: "secret"},
+			wantErr: "https URL",
+		},
+		{
+			name:    "oidc without secret",
+			idp:     cloudinstall.IdPConfig{Provider: cloudinstall.IdPOIDC, Host: "https://idp.example.com", ClientID: "id"},
+			wantErr: "client ID and secret",
+		},
+		{
+			name:    "unknown",
+			idp:     cloudinstall.IdPConfig{Provider: "auth0"},
+			wantErr: "unsupported identity provider",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.idp.Validate()
+			if test.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, test.wantErr)
+		})
+	}
+}
+
+func resourcesByName(t *testing.T, yaml string) map[string]*k8s.Resource {
+	resources, err := k8s.GetResourcesFromYAML(strings.NewReader(yaml))
+	require.NoError(t, err)
+	byName := make(map[string]*k8s.Resource)
+	for _, r := range resources {
+		byName[r.Object.GetKind()+"/"+r.Object.GetName()] = r
+	}
+	return byName
+}
+
+func stageNames(stages []*cloudinstall.Stage) []string {
+	var names []string
+	for _, s := range stages {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func TestRender(t *testing.T) {
+	m := &cloudinstall.Manifests{
+		ElasticOperator: "operator",
+		Deps:            "deps",
+		Cloud:           testCloudYAML,
+	}
+	cfg := &cloudinstall.Config{
+		Version: "0.1.2",
+		Domain:  "pixie.example.com",
+		IdP: cloudinstall.IdPConfig{
+			Provider:     cloudinstall.IdPOIDC,
+			Host:         "https://idp.example.com/realms/pixie",
+			ClientID:     "pixie",
+			ClientSecret: "secret",
+		},
+	}
+	stages, err := cloudinstall.Render(m, cfg, cloudinstall.OAuthConfigData(cfg.IdP))
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"Generate secrets",
+		"Deploy Elastic operator",
+		"Deploy cloud dependencies",
+		"Configure secrets",
+		"Deploy Pixie Cloud",
+		"Record installation",
+	}, stageNames(stages))
+
+	// Generated secrets must never overwrite the ones of an existing installation.
+	assert.False(t, stages[0].AllowUpdate)
+	generated := resourcesByName(t, stages[0].YAML)
+	for _, name := range []string{
+		"Namespace/plc",
+		"Secret/cloud-auth-secrets",
+		"Secret/cloud-session-secrets",
+		"Secret/pl-hydra-secrets",
+		"Secret/pl-db-secrets",
+		"Secret/service-tls-certs",
+		"Secret/cloud-proxy-tls-certs",
+	} {
+		assert.Contains(t, generated, name)
+	}
+
+	configured := resourcesByName(t, stages[3].YAML)
+	assert.Contains(t, configured, "Secret/cloud-oidc-secrets")
+
+	assert.NotContains(t, stages[4].YAML, cloudinstall.DefaultDomain)
+	cloud := resourcesByName(t, stages[4].YAML)
+	domain := cloud["ConfigMap/pl-domain-config"].Object.Object["data"].(map[string]interface{})
+	assert.Equal(t, "pixie.example.com", domain["PL_DOMAIN_NAME"])
+	assert.Equal(t, "4444", domain["PASSTHROUGH_PROXY_PORT"])
+	oauth := cloud["ConfigMap/pl-oauth-config"].Object.Object["data"].(map[string]interface{})
+	assert.Equal(t, "oidc", oauth["PL_OAUTH_PROVIDER"])
+	assert.Equal(t, "idp.example.com", oauth["PL_AUTH_URI"])
+	assert.Equal(t, "https://idp.example.com/realms/pixie", oauth["PL_OIDC_HOST"])
+	assert.Equal(t, "pixie", oauth["PL_AUTH_CLIENT_ID"])
+	envoy := cloud["ConfigMap/proxy-envoy-config"].Object.Object["data"].(map[string]interface{})
+	assert.Contains(t, envoy["envoy.yaml"], `suffix: "pixie.example.com"`)
+
+	install := resourcesByName(t, stages[5].YAML)["ConfigMap/pl-cloud-install-config"]
+	require.NotNil(t, install)
+	assert.Equal(t, "0.1.2", install.Object.Object["data"].(map[string]interface{})["PL_CLOUD_VERSION"])
+}
+
+func TestRender_Hydra(t *testing.T) {
+	m := &cloudinstall.Manifests{Cloud: testCloudYAML}
+	cfg := &cloudinstall.Config{Version: "latest", Domain: "pixie.example.com"}
+	stages, err := cloudinstall.Render(m, cfg, cloudinstall.OAuthConfigData(cloudinstall.IdPConfig{Provider: cloudinstall.IdPHydra}))
+	require.NoError(t, err)
+	assert.NotContains(t, stageNames(stages), "Configure secrets")
+
+	cloud := resourcesByName(t, stages[3].YAML)
+	oauth := cloud["ConfigMap/pl-oauth-config"].Object.Object["data"].(map[string]interface{})
+	assert.Equal(t, "hydra", oauth["PL_OAUTH_PROVIDER"])
+	assert.Equal(t, "/oauth/hydra", oauth["PL_AUTH_URI"])
+}
@@ -0,0 +1,158 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cloudinstall
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strings"
+
+	"px.dev/pixie/src/utils/shared/certs"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+const (
+	alphanumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	// The database key allows a few symbols, matching scripts/create_cloud_secrets.sh.
+	dbKeyChars = alphanumeric + "#$%&()."
+)
+
+func randomString(chars string, n int) (string, error) {
+	b := make([]byte, n)
+	max := big.NewInt(int64(len(chars)))
+	for i := range b {
+		idx, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = chars[idx.Int64()]
+	}
+	return string(b), nil
+}
+
+// generatedSecretsYAML generates the secrets that the cloud needs. They are only ever created, so that
+// keys and passwords are kept across upgrades.
+func generatedSecretsYAML(cfg *Config) (string, error) {
+	literals := make(map[string]map[string]string)
+	for name, keys := range map[string]map[string]int{
+		"cloud-auth-secrets":    {"jwt-signing-key": 64},
+		"cloud-session-secrets": {"session-key": 24},
+		"pl-hydra-secrets": {
+			"SECRETS_SYSTEM":                         64,
+			"OIDC_SUBJECT_IDENTIFIERS_PAIRWISE_SALT": 64,
+			"CLIENT_SECRET":                          64,
+		},
+		"pl-db-secrets": {"PL_POSTGRES_PASSWORD": 24},
+	} {
+		literals[name] = make(map[string]string)
+		for key, n := range keys {
+			v, err := randomString(alphanumeric, n)
+			if err != nil {
+				return "", err
+			}
+			literals[name][key] = v
+		}
+	}
+	dbKey, err := randomString(dbKeyChars, 24)
+	if err != nil {
+		return "", err
+	}
+	literals["pl-db-secrets"]["database-key"] = dbKey
+	literals["pl-db-secrets"]["PL_POSTGRES_USERNAME"] = "pl"
+
+	yamls := []string{namespaceYAML}
+	for _, name := range []string{"cloud-auth-secrets", "cloud-session-secrets", "pl-hydra-secrets", "pl-db-secrets"} {
+		secret, err := k8s.CreateGenericSecretFromLiterals(Namespace, name, literals[name])
+		if err != nil {
+			return "", err
+		}
+		yaml, err := k8s.ConvertResourceToYAML(secret)
+		if err != nil {
+			return "", err
+		}
+		yamls = append(yamls, yaml)
+	}
+
+	serviceCerts, err := certs.GenerateCloudCertYAMLs(Namespace)
+	if err != nil {
+		return "", err
+	}
+	yamls = append(yamls, serviceCerts)
+
+	if cfg.TLSCertFile == "" {
+		proxyCerts, err := certs.GenerateCloudProxyCertYAML(Namespace, cfg.Domain)
+		if err != nil {
+			return "", err
+		}
+		yamls = append(yamls, proxyCerts)
+	}
+	return joinYAMLs(yamls), nil
+}
+
+// configuredSecretsYAML creates the secrets that come from the user's config. Unlike the generated
+// secrets, these are updated whenever the cloud is installed or upgraded with new values.
+func configuredSecretsYAML(cfg *Config) (string, error) {
+	var yamls []string
+	if cfg.TLSCertFile != "" {
+		secret, err := k8s.CreateTLSSecret(Namespace, "cloud-proxy-tls-certs", cfg.TLSKeyFile, cfg.TLSCertFile)
+		if err != nil {
+			return "", err
+		}
+		yaml, err := k8s.ConvertResourceToYAML(secret)
+		if err != nil {
+			return "", err
+		}
+		yamls = append(yamls, yaml)
+	}
+	if cfg.IdP.Provider == IdPOIDC && cfg.IdP.ClientSecret != "" {
+		secret, err := k8s.CreateGenericSecretFromLiterals(Namespace, "cloud-oidc-secrets", map[string]string{
+			"oidc-client-id":     cfg.IdP.ClientID,
+			"oidc-client-secret": cfg.IdP.ClientSecret,
+		})
+		if err != nil {
+			return "", err
+		}
+		yaml, err := k8s.ConvertResourceToYAML(secret)
+		if err != nil {
+			return "", err
+		}
+		yamls = append(yamls, yaml)
+	}
+	return joinYAMLs(yamls), nil
+}
+
+const namespaceYAML = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: ` + Namespace + `
+`
+
+func joinYAMLs(yamls []string) string {
+	var parts []string
+	for _, y := range yamls {
+		y = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(y), "---"))
+		if y != "" {
+			parts = append(parts, y)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "---\n" + strings.Join(parts, "\n---\n") + "\n"
+}
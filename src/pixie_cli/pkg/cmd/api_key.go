@@ -24,8 +24,10 @@ import (
 	"os"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -35,6 +37,7 @@ import (
 	"px.dev/pixie/src/pixie_cli/pkg/auth"
 	"px.dev/pixie/src/pixie_cli/pkg/components"
 	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/shared/services/rbac"
 	utils2 "px.dev/pixie/src/utils"
 )
 
@@ -47,6 +50,9 @@ func init() {
 
 	CreateAPIKeyCmd.Flags().StringP("desc", "d", "", "A description for the API key")
 	CreateAPIKeyCmd.Flags().BoolP("short", "s", false, "Return only the created API key, for use to pipe to other tools")
+	CreateAPIKeyCmd.Flags().String("role", "", "Limit the API key to a role: one of: viewer|editor|admin. Defaults to your own access")
+	CreateAPIKeyCmd.Flags().StringSlice("cluster", []string{}, "Limit the role to the given cluster IDs. Requires --role")
+	CreateAPIKeyCmd.Flags().Duration("expires", 0, "Expire the API key after the given duration, for example 720h. Defaults to never")
 
 	DeleteAPIKeyCmd.Flags().StringP("id", "i", "", "The API key to delete")
 
@@ -77,8 +83,16 @@ var CreateAPIKeyCmd = &cobra.Command{
 		cloudAddr := viper.GetString("cloud_addr")
 		desc, _ := cmd.Flags().GetString("desc")
 		short, _ := cmd.Flags().GetBool("short")
+		role, _ := cmd.Flags().GetString("role")
+		clusters, _ := cmd.Flags().GetStringSlice("cluster")
+		expires, _ := cmd.Flags().GetDuration("expires")
 
-		keyID, key, err := generateAPIKey(cloudAddr, desc)
+		scopes, err := apiKeyScopes(role, clusters)
+		if err != nil {
+			utils.WithError(err).Fatal("Invalid API key scope")
+		}
+
+		keyID, key, err := generateAPIKey(cloudAddr, desc, scopes, expires)
 		if err != nil {
 			// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
 			log.WithError(err).Fatal("Failed to generate API key")
@@ -139,10 +153,10 @@ var ListAPIKeyCmd = &cobra.Command{
 		// Throw keys into table.
 		w := components.CreateStreamWriter(format, os.Stdout)
 		defer w.Finish()
-		w.SetHeader("api-keys", []string{"ID", "Key", "CreatedAt", "Description"})
+		w.SetHeader("api-keys", []string{"ID", "Key", "CreatedAt", "Description", "Scopes", "ExpiresAt", "LastUsedAt"})
 		for _, k := range keys {
 			_ = w.Write([]interface{}{utils2.UUIDFromProtoOrNil(k.ID), "<hidden>", k.CreatedAt,
-				k.Desc})
+				k.Desc, formatKeyScopes(k.Scopes), formatKeyTime(k.ExpiresAt, "never"), formatKeyTime(k.LastUsedAt, "never")})
 		}
 	},
 }
@@ -173,9 +187,9 @@ var LookupAPIKeyCmd = &cobra.Command{
 		// Throw keys into table.
 		w := components.CreateStreamWriter(format, os.Stdout)
 		defer w.Finish()
-		w.SetHeader("api-keys", []string{"ID", "Key", "CreatedAt", "Description"})
+		w.SetHeader("api-keys", []string{"ID", "Key", "CreatedAt", "Description", "Scopes", "ExpiresAt", "LastUsedAt"})
 		_ = w.Write([]interface{}{utils2.UUIDFromProtoOrNil(k.ID), "<hidden>", k.CreatedAt,
-			k.Desc})
+			k.Desc, formatKeyScopes(k.Scopes), formatKeyTime(k.ExpiresAt, "never"), formatKeyTime(k.LastUsedAt, "never")})
 	},
 }
 
@@ -204,9 +218,9 @@ var GetAPIKeyCmd = &cobra.Command{
 		// Throw keys into table.
 		w := components.CreateStreamWriter(format, os.Stdout)
 		defer w.Finish()
-		w.SetHeader("api-keys", []string{"ID", "Key", "CreatedAt", "Description"})
+		w.SetHeader("api-keys", []string{"ID", "Key", "CreatedAt", "Description", "Scopes", "ExpiresAt", "LastUsedAt"})
 		_ = w.Write([]interface{}{utils2.UUIDFromProtoOrNil(k.ID), k.Key, k.CreatedAt,
-			k.Desc})
+			k.Desc, formatKeyScopes(k.Scopes), formatKeyTime(k.ExpiresAt, "never"), formatKeyTime(k.LastUsedAt, "never")})
 	},
 }
 
@@ -225,13 +239,78 @@ func getAPIKeyClientAndContext(cloudAddr string) (cloudpb.APIKeyManagerClient, c
 	return apiKeyMgr, ctxWithCreds, nil
 }
 
-func generateAPIKey(cloudAddr string, desc string) (string, string, error) {
+// apiKeyScopes builds the role scopes for an API key. An empty role leaves the key with the
+// access of the user that created it.
+func apiKeyScopes(role string, clusters []string) ([]string, error) {
+	if role == "" {
+		if len(clusters) > 0 {
+			return nil, fmt.Errorf("--cluster requires --role")
+		}
+		return nil, nil
+	}
+	r, err := rbac.ParseRole(role)
+	if err != nil {
+		return nil, err
+	}
+	if len(clusters) == 0 {
+		return []string{r.Scope()}, nil
+	}
+	scopes := make([]string, len(clusters))
+	for i, c := range clusters {
+		clusterID, err := uuid.FromString(c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cluster ID '%s'", c)
+		}
+		scopes[i] = r.ClusterScope(clusterID)
+	}
+	return scopes, nil
+}
+
+// expiryTimestamp returns the expiry time for a key that expires after d, or nil if d is zero.
+func expiryTimestamp(d time.Duration) (*types.Timestamp, error) {
+	if d == 0 {
+		return nil, nil
+	}
+	if d < 0 {
+		return nil, fmt.Errorf("expiry must be a positive duration")
+	}
+	return types.TimestampProto(time.Now().Add(d))
+}
+
+func formatKeyTime(ts *types.Timestamp, unset string) string {
+	if ts == nil {
+		return unset
+	}
+	t, err := types.TimestampFromProto(ts)
+	if err != nil {
+		return unset
+	}
+	return t.Format(time.RFC3339)
+}
+
+func formatKeyScopes(scopes []string) string {
+	if len(scopes) == 0 {
+		return "<owner>"
+	}
+	return strings.Join(scopes, ",")
+}
+
+func generateAPIKey(cloudAddr string, desc string, scopes []string, expires time.Duration) (string, string, error) {
+	expiresAt, err := expiryTimestamp(expires)
+	if err != nil {
+		return "", "", err
+	}
+
 	apiKeyMgr, ctxWithCreds, err := getAPIKeyClientAndContext(cloudAddr)
 	if err != nil {
 		return "", "", err
 	}
 
-	resp, err := apiKeyMgr.Create(ctxWithCreds, &cloudpb.CreateAPIKeyRequest{Desc: desc})
+	resp, err := apiKeyMgr.Create(ctxWithCreds, &cloudpb.CreateAPIKeyRequest{
+		Desc:      desc,
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	})
 	if err != nil {
 		return "", "", err
 	}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"px.dev/pixie/src/pixie_cli/pkg/auth"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/utils/shared/artifacts"
+)
+
+func init() {
+	ArtifactsCmd.AddCommand(DownloadArtifactsCmd)
+
+	DownloadArtifactsCmd.Flags().String("bundle", "", "The tar file to write the bundle to")
+	DownloadArtifactsCmd.MarkFlagRequired("bundle")
+	DownloadArtifactsCmd.Flags().StringP("vizier_version", "v", "", "The Vizier version to bundle. Defaults to the latest version")
+	DownloadArtifactsCmd.Flags().String("operator_version", "", "The operator version to bundle. Defaults to the latest version")
+}
+
+// ArtifactsCmd is the artifacts sub-command of the CLI.
+var ArtifactsCmd = &cobra.Command{
+	Use:   "artifacts",
+	Short: "Manage Pixie release artifacts",
+	Run: func(cmd *cobra.Command, args []string) {
+		utils.Info("Nothing here... Please execute one of the subcommands")
+		cmd.Help()
+	},
+}
+
+// DownloadArtifactsCmd downloads the artifacts needed to deploy Pixie into a bundle, which
+// `px deploy --bundle` can deploy from without contacting the artifact tracker.
+var DownloadArtifactsCmd = &cobra.Command{
+	Use:   "download",
+	Short: "Download an offline bundle of the artifacts needed to deploy Pixie",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlag("vizier_version", cmd.Flags().Lookup("vizier_version"))
+		viper.BindPFlag("operator_version", cmd.Flags().Lookup("operator_version"))
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		bundlePath, _ := cmd.Flags().GetString("bundle")
+		cloudAddr := viper.GetString("cloud_addr")
+
+		cloudConn, err := utils.GetCloudClientConnection(cloudAddr)
+		if err != nil {
+			// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
+			log.WithError(err).Fatalln("Failed to get grpc connection to cloud")
+		}
+
+		vizierVersion := viper.GetString("vizier_version")
+		if vizierVersion == "" {
+			vizierVersion, err = getLatestVizierVersion(cloudConn)
+			if err != nil {
+				log.WithError(err).Fatal("Failed to fetch Vizier versions")
+			}
+		}
+		operatorVersion := viper.GetString("operator_version")
+		if operatorVersion == "" {
+			operatorVersion, err = getLatestOperatorVersion(cloudConn)
+			if err != nil {
+				log.WithError(err).Fatal("Failed to fetch Operator versions")
+			}
+		}
+
+		utils.Infof("Downloading Vizier %s and operator %s", vizierVersion, operatorVersion)
+		creds := auth.MustLoadDefaultCredentials()
+		bundle, err := artifacts.DownloadBundle(cloudConn, creds.Token, vizierVersion, operatorVersion, mustGetArtifactVerificationOptions())
+		if artifacts.IsVerificationError(err) {
+			utils.WithError(err).Fatal("Downloaded artifacts failed verification. To bundle them anyway, rerun with --skip_artifact_verification")
+		}
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to download artifacts")
+		}
+
+		f, err := os.Create(bundlePath)
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to create bundle file")
+		}
+		defer f.Close()
+		if err := bundle.Write(f); err != nil {
+			utils.WithError(err).Fatal("Failed to write bundle")
+		}
+
+		utils.Infof("Wrote bundle to %s", bundlePath)
+		utils.Info("Mirror the following images into a registry that your cluster can reach, then deploy with --bundle and --registry:")
+		for _, image := range bundle.Manifest.Images {
+			utils.Infof("  %s", image)
+		}
+	},
+}
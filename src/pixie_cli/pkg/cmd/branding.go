@@ -0,0 +1,100 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/viper"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/pixie_cli/pkg/auth"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+)
+
+const orgBrandingTimeout = 2 * time.Second
+
+var (
+	orgBrandingOnce sync.Once
+	orgBranding     *cloudpb.OrgBranding
+)
+
+// getOrgBranding fetches the branding configured for the user's org. It returns nil if the user
+// isn't logged in or the branding couldn't be fetched, since branding should never block a command.
+func getOrgBranding() *cloudpb.OrgBranding {
+	orgBrandingOnce.Do(func() {
+		if viper.GetString("direct_vizier_addr") != "" {
+			return
+		}
+		creds, err := auth.LoadDefaultCredentials()
+		if err != nil || creds.Token == "" {
+			return
+		}
+		conn, err := utils.GetCloudClientConnection(viper.GetString("cloud_addr"))
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), orgBrandingTimeout)
+		defer cancel()
+		ctx = auth.CtxWithCreds(ctx)
+		resp, err := cloudpb.NewArtifactTrackerClient(conn).GetOrgBranding(ctx, &cloudpb.GetOrgBrandingRequest{})
+		if err != nil {
+			return
+		}
+		orgBranding = resp
+	})
+	return orgBranding
+}
+
+// printOrgAnnouncement prints the org's announcement banner, if there is one.
+func printOrgAnnouncement() {
+	if viper.GetBool("quiet") {
+		return
+	}
+	b := getOrgBranding()
+	if b == nil || b.Announcement == "" {
+		return
+	}
+	c := color.New(color.Bold, color.FgYellow)
+	_, _ = c.Fprintf(os.Stderr, "%s\n", b.Announcement)
+	if b.AnnouncementURL != "" {
+		fmt.Fprintf(os.Stderr, "More info: %s\n", b.AnnouncementURL)
+	}
+}
+
+// printOrgSupportInfo prints where to get help for the org, if it has been configured.
+func printOrgSupportInfo() {
+	b := getOrgBranding()
+	if b == nil {
+		return
+	}
+	if b.DocsURL != "" {
+		fmt.Fprintf(os.Stderr, "Docs: %s\n", b.DocsURL)
+	}
+	if b.SupportContact != "" {
+		fmt.Fprintf(os.Stderr, "For help, contact: %s\n", b.SupportContact)
+	}
+}
@@ -0,0 +1,302 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"px.dev/pixie/src/pixie_cli/pkg/cloudinstall"
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func init() {
+	CloudCmd.AddCommand(CloudInstallCmd)
+	CloudCmd.AddCommand(CloudUpgradeCmd)
+	CloudCmd.AddCommand(CloudStatusCmd)
+
+	for _, c := range []*cobra.Command{CloudInstallCmd, CloudUpgradeCmd} {
+		c.Flags().String("version", "latest", "The Pixie Cloud release to deploy")
+		c.Flags().String("manifests", "", "Deploy from a local pixie_cloud.tar.gz, rather than downloading the release")
+		c.Flags().String("tls_cert", "", "The TLS certificate to serve for the domain. If unset, a self-signed certificate is generated")
+		c.Flags().String("tls_key", "", "The key of the TLS certificate")
+		c.Flags().String("extract_yaml", "", "If set, write the rendered YAMLs to this directory instead of deploying them")
+		c.Flags().Duration("wait_timeout", 10*time.Minute, "How long to wait for the cloud to become ready")
+	}
+
+	CloudInstallCmd.Flags().String("domain", "", "The domain that Pixie Cloud will be served at")
+	CloudInstallCmd.Flags().String("idp", cloudinstall.IdPHydra, "The identity provider: 'hydra' for the bundled Ory deployment, or 'oidc'")
+	CloudInstallCmd.Flags().String("oidc_issuer", "", "The issuer URL of the OIDC provider")
+	CloudInstallCmd.Flags().String("oidc_client_id", "", "The client ID registered with the OIDC provider")
+	CloudInstallCmd.Flags().String("oidc_client_secret", "", "The client secret registered with the OIDC provider")
+	CloudInstallCmd.Flags().String("oidc_metadata_url", "", "The OIDC discovery URL, if it isn't under the issuer")
+}
+
+// CloudCmd is the "cloud" command, which manages self-hosted Pixie Cloud installations.
+var CloudCmd = &cobra.Command{
+	Use:   "cloud",
+	Short: "Install and upgrade a self-hosted Pixie Cloud on the current K8s cluster",
+	Run: func(cmd *cobra.Command, args []string) {
+		utils.Info("Nothing here... Please execute one of the subcommands")
+		cmd.Help()
+	},
+}
+
+// CloudInstallCmd is the "cloud install" command.
+var CloudInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install Pixie Cloud on the current K8s cluster",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := cloudConfigFromFlags(cmd)
+		cfg.Domain, _ = cmd.Flags().GetString("domain")
+		if cfg.Domain == "" {
+			cfg.Domain = components.TextPrompt("Domain that Pixie Cloud will be served at", "")
+		}
+		cfg.IdP = idpConfigFromFlags(cmd)
+		if err := cfg.Validate(); err != nil {
+			utils.WithError(err).Fatal("Invalid cloud config")
+		}
+		if err := cfg.IdP.Validate(); err != nil {
+			utils.WithError(err).Fatal("Invalid identity provider config")
+		}
+
+		// Extracting the YAMLs doesn't need access to a cluster.
+		var installer *cloudinstall.Installer
+		if extractPath, _ := cmd.Flags().GetString("extract_yaml"); extractPath == "" {
+			installer = cloudinstall.NewInstaller(k8s.GetClientset(k8s.GetConfig()), k8s.GetConfig())
+			inst, err := installer.CurrentInstallation(context.Background())
+			if err != nil {
+				utils.WithError(err).Fatal("Failed to check for an existing installation")
+			}
+			if inst != nil {
+				utils.Fatalf("Pixie Cloud %s is already installed at %s. Use `px cloud upgrade` to change its version.", inst.Version, inst.Domain)
+			}
+		}
+
+		stages := renderCloudStages(cmd, cfg, cloudinstall.OAuthConfigData(cfg.IdP))
+		runCloudStages(cmd, installer, stages, "Installing")
+		printCloudNextSteps(installer, cfg)
+	},
+}
+
+// CloudUpgradeCmd is the "cloud upgrade" command.
+var CloudUpgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Upgrade the Pixie Cloud on the current K8s cluster, keeping its domain and identity provider",
+	Run: func(cmd *cobra.Command, args []string) {
+		installer := cloudinstall.NewInstaller(k8s.GetClientset(k8s.GetConfig()), k8s.GetConfig())
+		inst, err := installer.CurrentInstallation(context.Background())
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to read the existing installation")
+		}
+		if inst == nil {
+			utils.Fatal("Pixie Cloud was not installed by px on this cluster. Use `px cloud install` first.")
+		}
+
+		cfg := cloudConfigFromFlags(cmd)
+		cfg.Domain = inst.Domain
+		if cfg.Version == inst.Version && cfg.Version != "latest" {
+			utils.Infof("Pixie Cloud is already at version %s", inst.Version)
+			return
+		}
+		if err := cfg.Validate(); err != nil {
+			utils.WithError(err).Fatal("Invalid cloud config")
+		}
+		oauthConfig := inst.OAuthConfig
+		if oauthConfig == nil {
+			oauthConfig = cloudinstall.OAuthConfigData(cloudinstall.IdPConfig{Provider: cloudinstall.IdPHydra})
+		}
+
+		utils.Infof("Upgrading Pixie Cloud at %s from %s to %s", inst.Domain, inst.Version, cfg.Version)
+		stages := renderCloudStages(cmd, cfg, oauthConfig)
+		runCloudStages(cmd, installer, stages, "Upgrading")
+		utils.Infof("Pixie Cloud at %s is running version %s", cfg.Domain, cfg.Version)
+	},
+}
+
+// CloudStatusCmd is the "cloud status" command.
+var CloudStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the version and health of the Pixie Cloud on the current K8s cluster",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		installer := cloudinstall.NewInstaller(k8s.GetClientset(k8s.GetConfig()), k8s.GetConfig())
+		inst, err := installer.CurrentInstallation(ctx)
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to read the existing installation")
+		}
+		if inst == nil {
+			utils.Info("Pixie Cloud was not installed by px on this cluster")
+			return
+		}
+		utils.Infof("Pixie Cloud %s at %s (identity provider: %s)", inst.Version, inst.Domain, inst.OAuthConfig["PL_OAUTH_PROVIDER"])
+
+		unready, err := installer.UnreadyWorkloads(ctx)
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to check the cloud workloads")
+		}
+		if len(unready) == 0 {
+			utils.WithColor(color.New(color.FgGreen)).Info("All workloads are ready")
+			return
+		}
+		utils.WithColor(color.New(color.FgYellow)).Infof("Workloads that aren't ready:\n  %s", strings.Join(unready, "\n  "))
+	},
+}
+
+func cloudConfigFromFlags(cmd *cobra.Command) *cloudinstall.Config {
+	cfg := &cloudinstall.Config{}
+	cfg.Version, _ = cmd.Flags().GetString("version")
+	cfg.TLSCertFile, _ = cmd.Flags().GetString("tls_cert")
+	cfg.TLSKeyFile, _ = cmd.Flags().GetString("tls_key")
+	return cfg
+}
+
+func idpConfigFromFlags(cmd *cobra.Command) cloudinstall.IdPConfig {
+	idp := cloudinstall.IdPConfig{}
+	idp.Provider, _ = cmd.Flags().GetString("idp")
+	if !cmd.Flags().Changed("idp") {
+		idp.Provider = strings.ToLower(components.NewPrompter("Identity provider", []string{cloudinstall.IdPHydra, cloudinstall.IdPOIDC}, idp.Provider).Prompt())
+	}
+	if idp.Provider != cloudinstall.IdPOIDC {
+		return idp
+	}
+
+	idp.Host, _ = cmd.Flags().GetString("oidc_issuer")
+	idp.ClientID, _ = cmd.Flags().GetString("oidc_client_id")
+	idp.ClientSecret, _ = cmd.Flags().GetString("oidc_client_secret")
+	idp.MetadataURL, _ = cmd.Flags().GetString("oidc_metadata_url")
+	if idp.Host == "" {
+		idp.Host = components.TextPrompt("OIDC issuer URL", "")
+	}
+	if idp.ClientID == "" {
+		idp.ClientID = components.TextPrompt("OIDC client ID", "")
+	}
+	if idp.ClientSecret == "" && !viper.GetBool("y") {
+		secret, err := components.SecretPrompt("OIDC client secret")
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to read the OIDC client secret")
+		}
+		idp.ClientSecret = secret
+	}
+	return idp
+}
+
+func renderCloudStages(cmd *cobra.Command, cfg *cloudinstall.Config, oauthConfig map[string]string) []*cloudinstall.Stage {
+	var manifests *cloudinstall.Manifests
+	var err error
+	if path, _ := cmd.Flags().GetString("manifests"); path != "" {
+		f, openErr := os.Open(path)
+		if openErr != nil {
+			utils.WithError(openErr).Fatal("Failed to open cloud manifests")
+		}
+		defer f.Close()
+		manifests, err = cloudinstall.ReadManifests(f)
+	} else {
+		if pxconfig.LocalOnly() {
+			utils.WithError(pxconfig.ErrLocalOnly).Fatal("Cannot download the cloud manifests. Use --manifests to install from a local copy")
+		}
+		utils.Infof("Downloading Pixie Cloud %s", cfg.Version)
+		manifests, err = cloudinstall.FetchManifests(cfg.Version, mustGetArtifactVerificationOptions())
+	}
+	if err != nil {
+		utils.WithError(err).Fatal("Failed to load cloud manifests")
+	}
+
+	if cfg.TLSCertFile == "" {
+		utils.WithColor(color.New(color.FgYellow)).Info("No TLS certificate was given, so a self-signed certificate will be used. Browsers and the px CLI won't trust it until its CA is trusted.")
+	}
+	stages, err := cloudinstall.Render(manifests, cfg, oauthConfig)
+	if err != nil {
+		utils.WithError(err).Fatal("Failed to render cloud manifests")
+	}
+	return stages
+}
+
+func runCloudStages(cmd *cobra.Command, installer *cloudinstall.Installer, stages []*cloudinstall.Stage, verb string) {
+	if extractPath, _ := cmd.Flags().GetString("extract_yaml"); extractPath != "" {
+		if err := os.MkdirAll(extractPath, 0o755); err != nil {
+			utils.WithError(err).Fatal("Failed to create the YAML directory")
+		}
+		for i, s := range stages {
+			name := fmt.Sprintf("%02d_%s.yaml", i, strings.ReplaceAll(strings.ToLower(s.Name), " ", "_"))
+			if err := os.WriteFile(filepath.Join(extractPath, name), []byte(s.YAML), 0o600); err != nil {
+				utils.WithError(err).Fatal("Failed to write YAMLs")
+			}
+		}
+		utils.Infof("Wrote the Pixie Cloud YAMLs to %s. Apply them in order.", extractPath)
+		os.Exit(0)
+	}
+
+	utils.Infof("%s Pixie Cloud on the following cluster: %s", verb, k8s.GetClientAPIConfig().CurrentContext)
+	if !components.YNPrompt("Is the cluster correct?", true) {
+		utils.Fatal("Cluster is not correct. Aborting.")
+	}
+
+	var tasks []utils.Task
+	for _, s := range stages {
+		stage := s
+		tasks = append(tasks, newTaskWrapper(stage.Name, func() error {
+			return installer.Apply(stage)
+		}))
+	}
+	timeout, _ := cmd.Flags().GetDuration("wait_timeout")
+	tasks = append(tasks, newTaskWrapper("Wait for Pixie Cloud to be ready", func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		return installer.WaitForReady(ctx, 5*time.Second)
+	}))
+
+	if err := utils.NewSerialTaskRunner(tasks).RunAndMonitor(); err != nil {
+		utils.WithError(err).Fatal("Failed to deploy Pixie Cloud. Check `px cloud status` and rerun the command once the issue is fixed.")
+	}
+}
+
+func printCloudNextSteps(installer *cloudinstall.Installer, cfg *cloudinstall.Config) {
+	p := func(s string, a ...interface{}) {
+		fmt.Fprintf(os.Stderr, s, a...)
+	}
+	b := color.New(color.Bold).Sprintf
+	g := color.GreenString
+
+	addr, err := installer.ProxyAddress(context.Background())
+	if err != nil || addr == "" {
+		addr = "<the external address of the cloud-proxy-service>"
+	}
+
+	fmt.Fprint(os.Stderr, "\n")
+	p(color.CyanString("==> ") + b("Next Steps:\n"))
+	p("\nPoint the DNS records for %s and %s to %s.\n", g(cfg.Domain), g("*."+cfg.Domain), g(addr))
+	if cfg.IdP.Provider == cloudinstall.IdPOIDC {
+		p("Register %s as a redirect URI with your OIDC provider.\n", g("https://work.%s/auth/callback", cfg.Domain))
+	}
+	p("\nThen log in and deploy Pixie with:\n")
+	p("- %s\n", g("px auth login --cloud_addr %s:443", cfg.Domain))
+	p("- %s\n", g("px deploy --cloud_addr %s:443", cfg.Domain))
+}
@@ -0,0 +1,216 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/gofrs/uuid"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	apiutils "px.dev/pixie/src/api/go/pxapi/utils"
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/cloud/api/ptproxy"
+	"px.dev/pixie/src/pixie_cli/pkg/compare"
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/pixie_cli/pkg/vizier"
+	"px.dev/pixie/src/utils/script"
+)
+
+func init() {
+	CompareCmd.Flags().String("baseline-start", "-1h", "The start of the time window of the baseline")
+	CompareCmd.Flags().String("baseline-end", "-30m", "The end of the time window of the baseline")
+	CompareCmd.Flags().String("candidate-start", "-30m", "The start of the time window of the candidate")
+	CompareCmd.Flags().String("candidate-end", "", "The end of the time window of the candidate. Defaults to now")
+	CompareCmd.Flags().StringP("namespace", "n", "", "Only compare the pods of namespaces whose name contains this value")
+	CompareCmd.Flags().String("service", "", "Only compare the pods of services whose name contains this value")
+	CompareCmd.Flags().String("pod", "", "Only compare the pods whose name contains this value")
+	CompareCmd.Flags().String("baseline-pod", "", "Only use the pods whose name contains this value as the baseline")
+	CompareCmd.Flags().String("candidate-pod", "", "Only use the pods whose name contains this value as the candidate")
+	CompareCmd.Flags().String("baseline-service", "", "Only use the pods of services whose name contains this value as the baseline")
+	CompareCmd.Flags().String("candidate-service", "", "Only use the pods of services whose name contains this value as the candidate")
+	CompareCmd.Flags().Float64("max-p99-increase", 0, "Exit with an error if the p99 latency of an endpoint increases by more than this percentage")
+	CompareCmd.Flags().Float64("max-error-rate-increase", 0, "Exit with an error if the error rate of an endpoint increases by more than this many percentage points")
+	CompareCmd.Flags().StringP("output", "o", "table", "Output format: one of: json|table|csv")
+	CompareCmd.Flags().BoolP("e2e_encryption", "e", true, "Enable E2E encryption")
+	CompareCmd.Flags().StringP("cluster", "c", "", "ID of the cluster to run on. "+
+		"Use 'px get viziers', or visit Admin console: work.withpixie.ai/admin, to find the ID")
+	CompareCmd.Flags().String("candidate-cluster", "", "ID of the cluster of the candidate. Defaults to the cluster of the baseline")
+}
+
+// CompareCmd is the "compare" command.
+var CompareCmd = &cobra.Command{
+	Use:   "compare",
+	Short: "Compare the HTTP latency and error rate of endpoints between two time windows or deployments",
+	Long: `Compare the HTTP latency and error rate of endpoints between two time windows or deployments.
+
+The same statistics are computed for the baseline and the candidate, and the change of each endpoint
+is listed, including the endpoints that only one of them served. By default the last 30 minutes
+are compared against the 30 minutes before them.`,
+	Example: `  px compare --service sock-shop/carts
+  px compare --baseline-pod carts-stable --candidate-pod carts-canary --baseline-start -15m --baseline-end ""
+  px compare --service carts --max-p99-increase 20 --max-error-rate-increase 1`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cloudAddr := viper.GetString("cloud_addr")
+		directVzAddr := viper.GetString("direct_vizier_addr")
+		directVzKey := viper.GetString("direct_vizier_key")
+
+		baseline := &compare.Target{}
+		candidate := &compare.Target{}
+		baseline.Start, _ = cmd.Flags().GetString("baseline-start")
+		baseline.End, _ = cmd.Flags().GetString("baseline-end")
+		candidate.Start, _ = cmd.Flags().GetString("candidate-start")
+		candidate.End, _ = cmd.Flags().GetString("candidate-end")
+		namespace, _ := cmd.Flags().GetString("namespace")
+		service, _ := cmd.Flags().GetString("service")
+		pod, _ := cmd.Flags().GetString("pod")
+		for _, t := range []*compare.Target{baseline, candidate} {
+			t.Namespace, t.Service, t.Pod = namespace, service, pod
+		}
+		if v, _ := cmd.Flags().GetString("baseline-pod"); v != "" {
+			baseline.Pod = v
+		}
+		if v, _ := cmd.Flags().GetString("candidate-pod"); v != "" {
+			candidate.Pod = v
+		}
+		if v, _ := cmd.Flags().GetString("baseline-service"); v != "" {
+			baseline.Service = v
+		}
+		if v, _ := cmd.Flags().GetString("candidate-service"); v != "" {
+			candidate.Service = v
+		}
+		thresholds := compare.Thresholds{}
+		thresholds.MaxLatencyP99Increase, _ = cmd.Flags().GetFloat64("max-p99-increase")
+		thresholds.MaxErrorRateIncrease, _ = cmd.Flags().GetFloat64("max-error-rate-increase")
+		format, _ := cmd.Flags().GetString("output")
+
+		byService := compare.SameWorkload(baseline, candidate)
+		baselineScript, err := compare.Script(baseline, byService)
+		if err != nil {
+			utils.WithError(err).Fatal("Invalid baseline")
+		}
+		candidateScript, err := compare.Script(candidate, byService)
+		if err != nil {
+			utils.WithError(err).Fatal("Invalid candidate")
+		}
+
+		selectedCluster, _ := cmd.Flags().GetString("cluster")
+		clusterID := uuid.FromStringOrNil(selectedCluster)
+		if clusterID == uuid.Nil && directVzAddr == "" {
+			clusterID, err = vizier.GetCurrentVizier(cloudAddr)
+			if err != nil {
+				utils.WithError(err).Fatal("Could not fetch healthy vizier")
+			}
+		}
+		baselineConns := vizier.MustConnectVizier(cloudAddr, false, clusterID, directVzAddr, directVzKey)
+		candidateConns := baselineConns
+		if candidateCluster, _ := cmd.Flags().GetString("candidate-cluster"); candidateCluster != "" {
+			candidateID, err := uuid.FromString(candidateCluster)
+			if err != nil {
+				utils.WithError(err).Fatal("Invalid candidate cluster ID")
+			}
+			candidateConns = vizier.MustConnectVizier(cloudAddr, false, candidateID, directVzAddr, directVzKey)
+		}
+
+		useEncryption, _ := cmd.Flags().GetBool("e2e_encryption")
+		// There is no e2e encryption for direct mode.
+		useEncryption = useEncryption && directVzAddr == ""
+
+		ctx, cleanup := utils.WithSignalCancellable(context.Background())
+		defer cleanup()
+
+		baselineStats, err := runEndpointStats(ctx, baselineConns, baselineScript, useEncryption)
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to compute the statistics of the baseline")
+		}
+		candidateStats, err := runEndpointStats(ctx, candidateConns, candidateScript, useEncryption)
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to compute the statistics of the candidate")
+		}
+		if len(baselineStats) == 0 && len(candidateStats) == 0 {
+			utils.Error("Neither the baseline nor the candidate served any HTTP requests.")
+			os.Exit(1)
+		}
+
+		diffs := compare.Diffs(baselineStats, candidateStats)
+		w := components.CreateStreamWriter(format, os.Stdout)
+		w.SetHeader("comparison", compare.Header(byService))
+		for _, d := range diffs {
+			row := d.Row(byService)
+			if format == "table" {
+				for i, v := range row {
+					if v == nil {
+						row[i] = "-"
+					}
+				}
+			}
+			if err := w.Write(row); err != nil {
+				utils.WithError(err).Fatal("Failed to write the comparison")
+			}
+		}
+		w.Finish()
+
+		if regressions := compare.Regressions(diffs, thresholds); len(regressions) > 0 {
+			for _, r := range regressions {
+				endpoint := fmt.Sprintf("%s %s", r.Method, r.Endpoint)
+				if r.Service != "" {
+					endpoint = fmt.Sprintf("%s: %s", r.Service, endpoint)
+				}
+				utils.Errorf("Regression of %s", endpoint)
+			}
+			utils.Fatalf("%d endpoints regressed", len(regressions))
+		}
+	},
+}
+
+// runEndpointStats runs the statistics script of compare, and returns the statistics of every
+// endpoint.
+func runEndpointStats(ctx context.Context, conns []*vizier.Connector, execScript *script.ExecutableScript, useEncryption bool) (map[compare.Key]*compare.Stats, error) {
+	var encOpts, decOpts *vizierpb.ExecuteScriptRequest_EncryptionOptions
+	var err error
+	if useEncryption {
+		encOpts, decOpts, err = apiutils.CreateEncryptionOptions()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := vizier.RunScript(ctx, conns, execScript, encOpts)
+	if err != nil {
+		return nil, err
+	}
+	collector := compare.NewCollector()
+	// The in memory format turns off the formatting of the adapter, so that the collector gets
+	// the values as they are.
+	tw := vizier.NewStreamOutputAdapterWithFactory(ctx, resp, vizier.FormatInMemory, decOpts,
+		func(md *vizierpb.ExecuteScriptResponse_MetaData) components.OutputStreamWriter {
+			return collector
+		})
+	if err := tw.Finish(); err != nil {
+		if err == ptproxy.ErrNotAvailable {
+			return nil, fmt.Errorf("the cluster is not available: %w", err)
+		}
+		return nil, err
+	}
+	return collector.Stats(), nil
+}
@@ -40,6 +40,7 @@ func init() {
 	DebugCmd.AddCommand(DebugLogCmd)
 	DebugCmd.AddCommand(DebugPodsCmd)
 	DebugCmd.AddCommand(DebugContainersCmd)
+	DebugCmd.AddCommand(DebugSelfTestCmd)
 	DebugCmd.PersistentFlags().StringP("cluster", "c", "", "Run only on selected cluster")
 
 	DebugLogCmd.Flags().BoolP("previous", "p", false, "Show log from previous pod instead.")
@@ -47,6 +48,9 @@ func init() {
 
 	DebugPodsCmd.Flags().StringP("plane", "p", "all", "Optional filter for the plane (data, control, all)")
 	DebugContainersCmd.Flags().StringP("plane", "p", "all", "Optional filter for the plane (data, control, all)")
+
+	DebugSelfTestCmd.Flags().StringSliceP("target", "t", nil, "Extra host:port to check from every component, or tls://host:port to also check the TLS handshake")
+	DebugSelfTestCmd.Flags().Duration("timeout", 5*time.Second, "The timeout of the check of a single dependency")
 }
 
 // DebugCmd has internal debug functionality.
@@ -213,3 +217,86 @@ var DebugContainersCmd = &cobra.Command{
 		}
 	},
 }
+
+func formatLatency(ns int64) string {
+	if ns == 0 {
+		return "-"
+	}
+	return time.Duration(ns).Round(time.Microsecond).String()
+}
+
+// DebugSelfTestCmd is the self-test debug command.
+var DebugSelfTestCmd = &cobra.Command{
+	Use:   "selftest",
+	Short: "Check the DNS resolution and connectivity of the vizier components to their dependencies",
+	Run: func(cmd *cobra.Command, args []string) {
+		cloudAddr := viper.GetString("cloud_addr")
+		selectedCluster, _ := cmd.Flags().GetString("cluster")
+		targets, _ := cmd.Flags().GetStringSlice("target")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		clusterID := uuid.FromStringOrNil(selectedCluster)
+		if clusterID == uuid.Nil {
+			var err error
+			clusterID, err = getVizier(cloudAddr)
+			if err != nil {
+				utils.WithError(err).Fatal("Could not fetch healthy vizier")
+			}
+		}
+		fmt.Printf("Cluster ID : %s\n", clusterID.String())
+
+		conn, err := vizier.ConnectionToVizierByID(cloudAddr, clusterID)
+		if err != nil {
+			utils.WithError(err).Fatal("Could not connect to vizier")
+		}
+
+		ctx, cleanup := utils.WithSignalCancellable(context.Background())
+		defer cleanup()
+		resp, err := conn.SelfTestRequest(ctx, targets, timeout)
+		if err != nil {
+			utils.WithError(err).Fatal("Self-test failed")
+		}
+
+		var reports []*vizierpb.SelfTestResponse
+		for v := range resp {
+			if v == nil {
+				continue
+			}
+			if v.Err != nil {
+				utils.WithError(v.Err).Fatal("Failed to get self-test")
+			}
+			reports = append(reports, v.Response)
+		}
+
+		failed := 0
+		w := components.CreateStreamWriter("table", os.Stdout)
+		w.SetHeader("selftest", []string{"Component", "Pod", "Dependency", "Address", "OK", "Resolved", "DNS", "Connect", "TLS", "Cert Expiry", "Error"})
+		for _, r := range reports {
+			if r.Error != "" {
+				failed++
+				_ = w.Write([]interface{}{r.Component, r.PodName, "", "", false, "", "-", "-", "-", "", r.Error})
+				continue
+			}
+			for _, c := range r.Checks {
+				if !c.OK {
+					failed++
+				}
+				expiry := ""
+				if c.TLS != nil && c.TLS.PeerNotAfterNs != 0 {
+					expiry = time.Unix(0, c.TLS.PeerNotAfterNs).UTC().Format(time.RFC3339)
+				}
+				_ = w.Write([]interface{}{
+					r.Component, r.PodName, c.Name, c.Address, c.OK, strings.Join(c.ResolvedAddrs, ","),
+					formatLatency(c.DnsLatencyNs), formatLatency(c.ConnectLatencyNs), formatLatency(c.TlsLatencyNs), expiry, c.Error,
+				})
+			}
+		}
+		w.Finish()
+
+		if failed > 0 {
+			fmt.Println(color.RedString("%d check(s) failed", failed))
+			return
+		}
+		fmt.Println(color.GreenString("All checks passed"))
+	},
+}
@@ -19,7 +19,10 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/fatih/color"
@@ -35,6 +38,8 @@ import (
 func init() {
 	DeleteCmd.Flags().BoolP("clobber", "d", true, "Whether to delete all dependencies in the cluster")
 	DeleteCmd.Flags().StringP("namespace", "n", "", "The namespace where Pixie is located")
+	DeleteCmd.Flags().Bool("dry-run", false, "List the resources that would be deleted, without deleting them")
+	DeleteCmd.Flags().Bool("strip-finalizers", false, "Remove the finalizers of objects that are stuck in deletion, so that the namespaces can be deleted")
 }
 
 // DeleteCmd is the "delete" command.
@@ -44,38 +49,89 @@ var DeleteCmd = &cobra.Command{
 	PreRun: func(cmd *cobra.Command, args []string) {
 		viper.BindPFlag("clobber", cmd.Flags().Lookup("clobber"))
 		viper.BindPFlag("namespace", cmd.Flags().Lookup("namespace"))
+		viper.BindPFlag("dry-run", cmd.Flags().Lookup("dry-run"))
+		viper.BindPFlag("strip-finalizers", cmd.Flags().Lookup("strip-finalizers"))
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		clobberAll, _ := cmd.Flags().GetBool("clobber")
 		ns, _ := cmd.Flags().GetString("namespace")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		stripFinalizers, _ := cmd.Flags().GetBool("strip-finalizers")
 		if ns == "" {
 			ns = vizier.MustFindVizierNamespace()
 		}
-		deletePixie(ns, clobberAll)
+		deletePixie(ns, clobberAll, dryRun, stripFinalizers)
 	},
 }
 
-func deletePixie(ns string, clobberAll bool) {
+func deletePixie(ns string, clobberAll bool, dryRun bool, stripFinalizers bool) {
 	kubeConfig := k8s.GetConfig()
 	kubeAPIConfig := k8s.GetClientAPIConfig()
 	clientset := k8s.GetClientset(kubeConfig)
+	ctx := context.Background()
 
 	opNs, _ := vizier.FindOperatorNamespace(clientset)
 
 	od := k8s.ObjectDeleter{
-		Namespace:  ns,
-		Clientset:  clientset,
-		RestConfig: kubeConfig,
-		Timeout:    2 * time.Minute,
+		Namespace:       ns,
+		Clientset:       clientset,
+		RestConfig:      kubeConfig,
+		Timeout:         2 * time.Minute,
+		DryRun:          dryRun,
+		StripFinalizers: stripFinalizers,
 	}
 	opOd := k8s.ObjectDeleter{
-		Namespace:  opNs,
-		Clientset:  clientset,
-		RestConfig: kubeConfig,
-		Timeout:    2 * time.Minute,
+		Namespace:       opNs,
+		Clientset:       clientset,
+		RestConfig:      kubeConfig,
+		Timeout:         2 * time.Minute,
+		DryRun:          dryRun,
+		StripFinalizers: stripFinalizers,
 	}
 
 	tasks := make([]utils.Task, 0)
+	if clobberAll {
+		// The custom resources are deleted while the operator is still running, so that it can handle their
+		// finalizers.
+		tasks = append(tasks, newDeleteTask("Deleting custom resources", &od, func() error {
+			_, err := od.DeleteCustomResources(ctx, "app=pl-monitoring", "px.dev")
+			return err
+		}))
+		tasks = append(tasks, newDeleteTask("Deleting namespace", &od, func() error {
+			return od.DeleteNamespace(ctx)
+		}))
+		if opNs != "" {
+			tasks = append(tasks, newDeleteTask("Deleting operator namespace", &opOd, func() error {
+				return opOd.DeleteNamespace(ctx)
+			}))
+		}
+		tasks = append(tasks, newDeleteTask("Deleting cluster-scoped resources", &od, func() error {
+			_, err := od.DeleteByLabel(ctx, "app=pl-monitoring")
+			return err
+		}))
+	} else {
+		tasks = append(tasks, newDeleteTask("Deleting Vizier pods/services", &od, func() error {
+			_, err := od.DeleteByLabel(ctx, "component=vizier")
+			return err
+		}))
+	}
+
+	if dryRun {
+		// The deleters only discover the objects in dry-run mode, so the tasks are run without the task runner's
+		// progress output, and the discovered objects are listed instead.
+		for _, t := range tasks {
+			if err := t.Run(); err != nil {
+				utils.WithError(err).Fatal("Error listing the Pixie resources" + deleteErrorHint(err))
+			}
+		}
+		w := components.CreateStreamWriter("table", os.Stdout)
+		w.SetHeader("resources", []string{"Kind", "Namespace", "Name"})
+		for _, obj := range append(od.DryRunObjects(), opOd.DryRunObjects()...) {
+			_ = w.Write([]interface{}{obj.Kind, obj.Namespace, obj.Name})
+		}
+		w.Finish()
+		return
+	}
 
 	currentCluster := kubeAPIConfig.CurrentContext
 	var noClobberInfo string
@@ -90,25 +146,60 @@ func deletePixie(ns string, clobberAll bool) {
 		return
 	}
 
-	if clobberAll {
-		tasks = append(tasks, newTaskWrapper("Deleting namespace", od.DeleteNamespace))
-		if opNs != "" {
-			tasks = append(tasks, newTaskWrapper("Deleting operator namespace", opOd.DeleteNamespace))
-		}
-		tasks = append(tasks, newTaskWrapper("Deleting cluster-scoped resources", func() error {
-			_, err := od.DeleteByLabel("app=pl-monitoring")
-			return err
-		}))
-	} else {
-		tasks = append(tasks, newTaskWrapper("Deleting Vizier pods/services", func() error {
-			_, err := od.DeleteByLabel("component=vizier")
-			return err
-		}))
-	}
-
 	delJr := utils.NewSerialTaskRunner(tasks)
 	err := delJr.RunAndMonitor()
 	if err != nil {
-		utils.WithError(err).Fatal("Error deleting Pixie")
+		utils.WithError(err).Fatal("Error deleting Pixie" + deleteErrorHint(err))
+	}
+}
+
+// deleteErrorHint returns a suggestion for how to resolve the given deletion error, if there is one.
+func deleteErrorHint(err error) string {
+	switch {
+	case errors.Is(err, k8s.ErrForbidden):
+		return ". The current user is not allowed to delete the Pixie resources, check its RBAC permissions"
+	case errors.Is(err, k8s.ErrWaitTimeout):
+		return ". Some of the resources are stuck in deletion, try again with --strip-finalizers"
+	case errors.Is(err, k8s.ErrConflict):
+		return ". The resources were modified during the deletion, try again"
+	default:
+		return ""
+	}
+}
+
+// deleteTask is a task that deletes objects with an ObjectDeleter, and reports the progress of the wait for them to
+// be removed.
+type deleteTask struct {
+	*taskWrapper
+	od *k8s.ObjectDeleter
+}
+
+func newDeleteTask(name string, od *k8s.ObjectDeleter, run func() error) *deleteTask {
+	return &deleteTask{newTaskWrapper(name, run), od}
+}
+
+func (t *deleteTask) RunWithProgress(setProgress func(msg string)) error {
+	t.od.Progress = &deleteProgress{setProgress: setProgress, start: time.Now()}
+	defer func() { t.od.Progress = nil }()
+	return t.Run()
+}
+
+// deleteProgress describes how many of the deleted objects have been removed, so that long deletions don't appear
+// to hang.
+type deleteProgress struct {
+	setProgress func(msg string)
+	start       time.Time
+	removed     int
+}
+
+func (p *deleteProgress) OnDeleted(obj k8s.ObjectReference) {
+	p.removed++
+}
+
+func (p *deleteProgress) OnWaiting(remaining int) {
+	if remaining == 0 {
+		return
 	}
+	p.setProgress(fmt.Sprintf(" %d removed, waiting for %d (%s)", p.removed, remaining,
+		time.Since(p.start).Round(time.Second)))
 }
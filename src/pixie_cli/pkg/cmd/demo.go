@@ -70,11 +70,15 @@ var DemoCmd = &cobra.Command{
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		// This pre run might be run from a subcommand. To bind the correct flag, we should check
 		// the persistent flags on both the current command and the parent.
-		if cmd.PersistentFlags().Lookup("artifacts") != nil {
-			viper.BindPFlag("artifacts", cmd.PersistentFlags().Lookup("artifacts"))
-			return
+		flag := cmd.PersistentFlags().Lookup("artifacts")
+		if flag == nil {
+			flag = cmd.Parent().PersistentFlags().Lookup("artifacts")
+		}
+		viper.BindPFlag("artifacts", flag)
+		// The default artifacts are hosted by Pixie, but a mirror given with --artifacts can still be used.
+		if pxconfig.LocalOnly() && !flag.Changed {
+			utils.WithError(pxconfig.ErrLocalOnly).Fatal("Cannot download the demo apps. Use --artifacts to download them from a mirror")
 		}
-		viper.BindPFlag("artifacts", cmd.Parent().PersistentFlags().Lookup("artifacts"))
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		utils.Info("Nothing here... Please execute one of the subcommands")
@@ -400,7 +404,7 @@ func deleteDemoApp(appName string) error {
 				Timeout:    2 * time.Minute,
 			}
 
-			_, err := od.DeleteByLabel(fmt.Sprintf("pixie-demo-initial-cleanup=true,pixie-demo=%s", appName))
+			_, err := od.DeleteByLabel(context.Background(), fmt.Sprintf("pixie-demo-initial-cleanup=true,pixie-demo=%s", appName))
 			if err != nil {
 				return err
 			}
@@ -412,7 +416,7 @@ func deleteDemoApp(appName string) error {
 				Timeout:    2 * time.Minute,
 			}
 
-			_, err = od.DeleteByLabel(fmt.Sprintf("pixie-demo=%s", appName))
+			_, err = od.DeleteByLabel(context.Background(), fmt.Sprintf("pixie-demo=%s", appName))
 			if err != nil {
 				return err
 			}
@@ -37,6 +37,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
@@ -61,6 +62,9 @@ const (
 	DefaultCloudAddr = "withpixie.ai:443"
 	// DeploySuccess is the successful deploy const.
 	DeploySuccess = "successfulDeploy"
+	// encryptedDeployKeySecretName is the name of the secret that a SealedSecret or ExternalSecret for the
+	// deploy key is decrypted into. It must differ from pl-deploy-secrets, which the operator manages.
+	encryptedDeployKeySecretName = "pl-custom-deploy-key"
 )
 
 // BlockListedLabels are labels that we won't allow users to specify, since these are labels that we
@@ -80,6 +84,7 @@ func init() {
 	DeployCmd.Flags().StringP("namespace", "n", "pl", "The namespace to deploy Vizier to")
 	DeployCmd.Flags().StringP("deploy_key", "k", "", "The deploy key to use to deploy Pixie")
 	DeployCmd.Flags().BoolP("use_etcd_operator", "o", false, "Whether to use the operator for etcd instead of the statefulset")
+	DeployCmd.Flags().Bool("single_process_control_plane", false, "Run the control plane services and NATS in a single process, for small and edge clusters.")
 	DeployCmd.Flags().StringP("labels", "l", "", "Custom labels to apply to Pixie resources")
 	DeployCmd.Flags().StringP("annotations", "t", "", "Custom annotations to apply to Pixie resources")
 	DeployCmd.Flags().StringP("cluster_name", "u", "", "The name for your cluster. Otherwise, the name will be taken from the current kubeconfig.")
@@ -89,17 +94,30 @@ func init() {
 	DeployCmd.Flags().String("pem_flags", "", "Flags to be set on the PEM.")
 	DeployCmd.Flags().String("registry", "", "The custom image registry to use rather than Pixie's default (gcr.io).")
 	DeployCmd.Flags().BoolP("disable_auto_update", "d", false, "Disable the auto-update feature for the vizier client.")
+	DeployCmd.Flags().String("profile", "", "The preset of resource settings to deploy with. Use 'small' for edge and single-node clusters, such as k3s and microk8s.")
+	DeployCmd.Flags().String("network_policy", "", "Deploy network policies which only allow the traffic Vizier needs. Must be one of: 'kubernetes', 'cilium'.")
+	DeployCmd.Flags().String("cloud_tls_server_name", "", "The server name to verify the cloud's TLS certificate against, for when the cloud address is an IP or an in-cluster name.")
+	DeployCmd.Flags().String("cloud_tls_ca_bundle", "", "Path to a PEM-encoded CA bundle which Vizier uses to verify the cloud's TLS certificate.")
+	DeployCmd.Flags().Bool("fips", false, "Restrict Vizier to FIPS approved cryptography, and run its services from their BoringCrypto images.")
 
 	// Flags for deploying OLM.
 	DeployCmd.Flags().String("operator_version", "", "Operator version to deploy")
+	DeployCmd.Flags().String("bundle", "", "Deploy from an offline bundle created by `px artifacts download`, rather than downloading the artifacts")
 	DeployCmd.Flags().Bool("deploy_olm", true, "Whether to deploy Operator Lifecycle Manager. OLM is required. This should only be false if OLM is already deployed on the cluster (either manually or through another application). Note: OLM is deployed by default on Openshift clusters.")
 	DeployCmd.Flags().String("olm_namespace", "olm", "The namespace to use for the Operator Lifecycle Manager")
 	DeployCmd.Flags().String("olm_operator_namespace", "px-operator", "The namespace to use for the Pixie operator")
 	DeployCmd.Flags().String("data_access", "Full", "Data access level defines the level of data that may be accessed when executing a script on the cluster. Options: 'Full' and 'Restricted'")
 	DeployCmd.Flags().Uint32("datastream_buffer_size", 0, "Internal data collector parameters: the maximum size of a data stream buffer retained between cycles.")
 	DeployCmd.Flags().Uint32("datastream_buffer_spike_size", 0, "Internal data collector parameters: the maximum temporary size of a data stream buffer before processing.")
+	DeployCmd.Flags().String("secret_format", k8s.SecretFormatSecret, "The format of the deploy key secret written with --extract_yaml. Options: 'secret', 'sealed-secret' and 'external-secret'")
+	DeployCmd.Flags().String("sealed_secrets_cert", "", "Path to the sealed-secrets controller certificate (from `kubeseal --fetch-cert`), used with --secret_format=sealed-secret")
+	DeployCmd.Flags().String("external_secret_store", "", "The name of the SecretStore holding the deploy key, used with --secret_format=external-secret")
+	DeployCmd.Flags().String("external_secret_store_kind", "SecretStore", "The kind of the secret store: 'SecretStore' or 'ClusterSecretStore'")
+	DeployCmd.Flags().String("external_secret_deploy_key", "", "The key of the deploy key in the external secret store, used with --secret_format=external-secret")
 	// Super secret flags for Pixies.
 	DeployCmd.Flags().MarkHidden("namespace")
+	// Hidden until the Vizier releases include the FIPS images.
+	DeployCmd.Flags().MarkHidden("fips")
 }
 
 // DeployCmd is the "deploy" command.
@@ -114,6 +132,7 @@ var DeployCmd = &cobra.Command{
 		viper.BindPFlag("namespace", cmd.Flags().Lookup("namespace"))
 		viper.BindPFlag("deploy_key", cmd.Flags().Lookup("deploy_key"))
 		viper.BindPFlag("use_etcd_operator", cmd.Flags().Lookup("use_etcd_operator"))
+		viper.BindPFlag("single_process_control_plane", cmd.Flags().Lookup("single_process_control_plane"))
 		viper.BindPFlag("labels", cmd.Flags().Lookup("labels"))
 		viper.BindPFlag("annotations", cmd.Flags().Lookup("annotations"))
 		viper.BindPFlag("cluster_name", cmd.Flags().Lookup("cluster_name"))
@@ -129,6 +148,16 @@ var DeployCmd = &cobra.Command{
 		viper.BindPFlag("datastream_buffer_size", cmd.Flags().Lookup("datastream_buffer_size"))
 		viper.BindPFlag("datastream_buffer_spike_size", cmd.Flags().Lookup("datastream_buffer_spike_size"))
 		viper.BindPFlag("disable_auto_update", cmd.Flags().Lookup("disable_auto_update"))
+		viper.BindPFlag("profile", cmd.Flags().Lookup("profile"))
+		viper.BindPFlag("fips", cmd.Flags().Lookup("fips"))
+		viper.BindPFlag("network_policy", cmd.Flags().Lookup("network_policy"))
+		viper.BindPFlag("cloud_tls_server_name", cmd.Flags().Lookup("cloud_tls_server_name"))
+		viper.BindPFlag("cloud_tls_ca_bundle", cmd.Flags().Lookup("cloud_tls_ca_bundle"))
+		viper.BindPFlag("secret_format", cmd.Flags().Lookup("secret_format"))
+		viper.BindPFlag("sealed_secrets_cert", cmd.Flags().Lookup("sealed_secrets_cert"))
+		viper.BindPFlag("external_secret_store", cmd.Flags().Lookup("external_secret_store"))
+		viper.BindPFlag("external_secret_store_kind", cmd.Flags().Lookup("external_secret_store_kind"))
+		viper.BindPFlag("external_secret_deploy_key", cmd.Flags().Lookup("external_secret_deploy_key"))
 	},
 	PostRun: func(cmd *cobra.Command, args []string) {
 		if cmd.Annotations["status"] != DeploySuccess {
@@ -184,6 +213,33 @@ func newArtifactTrackerClient(conn *grpc.ClientConn) cloudpb.ArtifactTrackerClie
 	return cloudpb.NewArtifactTrackerClient(conn)
 }
 
+// mustGetArtifactVerificationOptions loads the options used to check downloaded artifacts from the global flags.
+func mustGetArtifactVerificationOptions() *artifacts.VerificationOptions {
+	opts, err := artifacts.LoadVerificationOptions(viper.GetString("artifact_keyring"), viper.GetBool("skip_artifact_verification"))
+	if err != nil {
+		log.WithError(err).Fatal("Could not load artifact signing keyring")
+	}
+	return opts
+}
+
+// mustReadBundle reads and verifies the offline artifact bundle at the given path.
+func mustReadBundle(path string) *artifacts.Bundle {
+	f, err := os.Open(path)
+	if err != nil {
+		utils.WithError(err).Fatal("Failed to open artifact bundle")
+	}
+	defer f.Close()
+
+	bundle, err := artifacts.ReadBundle(f, mustGetArtifactVerificationOptions())
+	if artifacts.IsVerificationError(err) {
+		utils.WithError(err).Fatal("Artifact bundle failed verification. To use it anyway, rerun with --skip_artifact_verification")
+	}
+	if err != nil {
+		utils.WithError(err).Fatal("Failed to read artifact bundle")
+	}
+	return bundle
+}
+
 func getLatestVizierVersion(conn *grpc.ClientConn) (string, error) {
 	client := newArtifactTrackerClient(conn)
 
@@ -238,6 +294,7 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 
 	deployKey, _ := cmd.Flags().GetString("deploy_key")
 	useEtcdOperator, _ := cmd.Flags().GetBool("use_etcd_operator")
+	singleProcessControlPlane, _ := cmd.Flags().GetBool("single_process_control_plane")
 	disableAutoUpdate, _ := cmd.Flags().GetBool("disable_auto_update")
 	customLabels, _ := cmd.Flags().GetString("labels")
 	customAnnotations, _ := cmd.Flags().GetString("annotations")
@@ -249,6 +306,12 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 	datastreamBufferSize, _ := cmd.Flags().GetUint32("datastream_buffer_size")
 	datastreamBufferSpikeSize, _ := cmd.Flags().GetUint32("datastream_buffer_spike_size")
 	registry, _ := cmd.Flags().GetString("registry")
+	profile, _ := cmd.Flags().GetString("profile")
+	fipsMode, _ := cmd.Flags().GetBool("fips")
+	networkPolicyProvider, _ := cmd.Flags().GetString("network_policy")
+	secretFormat, _ := cmd.Flags().GetString("secret_format")
+	cloudTLSServerName, _ := cmd.Flags().GetString("cloud_tls_server_name")
+	cloudTLSCABundle, _ := cmd.Flags().GetString("cloud_tls_ca_bundle")
 
 	labelMap := make(map[string]string)
 	if customLabels != "" {
@@ -305,17 +368,57 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 		utils.Fatal("--data_access must be a valid data access level")
 	}
 
-	if deployKey == "" && extractPath != "" {
+	if singleProcessControlPlane && useEtcdOperator {
+		utils.Fatal("--single_process_control_plane can't be used with --use_etcd_operator")
+	}
+
+	castedProfile := vztypes.DeploymentProfile(profile)
+	if castedProfile != "" && castedProfile != vztypes.DeploymentProfileDefault && castedProfile != vztypes.DeploymentProfileSmall {
+		utils.Fatal("--profile must be one of: 'default', 'small'")
+	}
+
+	networkPolicy := make(map[string]interface{})
+	if networkPolicyProvider != "" {
+		castedProvider := vztypes.NetworkPolicyProvider(networkPolicyProvider)
+		if castedProvider != vztypes.NetworkPolicyProviderKubernetes && castedProvider != vztypes.NetworkPolicyProviderCilium {
+			utils.Fatal("--network_policy must be one of: 'kubernetes', 'cilium'")
+		}
+		networkPolicy["enabled"] = true
+		networkPolicy["provider"] = castedProvider
+	}
+
+	cloudTLS := make(map[string]interface{})
+	if cloudTLSServerName != "" {
+		cloudTLS["serverName"] = cloudTLSServerName
+	}
+	if cloudTLSCABundle != "" {
+		caBundle, err := os.ReadFile(cloudTLSCABundle)
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to read --cloud_tls_ca_bundle")
+		}
+		cloudTLS["caBundle"] = string(caBundle)
+	}
+
+	if !k8s.IsValidSecretFormat(secretFormat) {
+		utils.Fatal("--secret_format must be one of: 'secret', 'sealed-secret', 'external-secret'")
+	}
+	if secretFormat != k8s.SecretFormatSecret && extractPath == "" {
+		utils.Fatal("--secret_format can only be used with --extract_yaml")
+	}
+
+	// With an ExternalSecret the deploy key lives in the external store, so it doesn't need to be passed in.
+	if deployKey == "" && extractPath != "" && secretFormat != k8s.SecretFormatExternalSecret {
 		utils.Fatal("--deploy_key must be specified when running with --extract_yaml. Please run px deploy-key create.")
 	}
 
+	namespace, _ := cmd.Flags().GetString("namespace")
 	if (check || checkOnly) && extractPath == "" {
 		_ = pxanalytics.Client().Enqueue(&analytics.Track{
 			UserId: pxconfig.Cfg().UniqueClientID,
 			Event:  "Cluster Check Run",
 		})
 
-		err := utils.RunDefaultClusterChecks()
+		err := utils.RunDefaultClusterChecks(namespace)
 		if err != nil {
 			_ = pxanalytics.Client().Enqueue(&analytics.Track{
 				UserId: pxconfig.Cfg().UniqueClientID,
@@ -341,7 +444,6 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	namespace, _ := cmd.Flags().GetString("namespace")
 	devCloudNS := viper.GetString("dev_cloud_namespace")
 	cloudAddr := viper.GetString("cloud_addr")
 
@@ -352,7 +454,18 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 		log.WithError(err).Fatalln("Failed to get grpc connection to cloud")
 	}
 
+	var bundle *artifacts.Bundle
+	if bundlePath, _ := cmd.Flags().GetString("bundle"); bundlePath != "" {
+		bundle = mustReadBundle(bundlePath)
+	}
+
 	versionString := viper.GetString("vizier_version")
+	if bundle != nil {
+		if versionString != "" && versionString != bundle.Manifest.VizierVersion {
+			utils.Fatalf("--vizier_version %s does not match the bundle's Vizier version %s", versionString, bundle.Manifest.VizierVersion)
+		}
+		versionString = bundle.Manifest.VizierVersion
+	}
 	if len(versionString) == 0 {
 		// Fetch latest version.
 		versionString, err = getLatestVizierVersion(cloudConn)
@@ -364,6 +477,12 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 	utils.Infof("Installing Vizier version: %s", versionString)
 
 	operatorVersion := viper.GetString("operator_version")
+	if bundle != nil {
+		if operatorVersion != "" && operatorVersion != bundle.Manifest.OperatorVersion {
+			utils.Fatalf("--operator_version %s does not match the bundle's operator version %s", operatorVersion, bundle.Manifest.OperatorVersion)
+		}
+		operatorVersion = bundle.Manifest.OperatorVersion
+	}
 	if len(operatorVersion) == 0 {
 		operatorVersion, err = getLatestOperatorVersion(cloudConn)
 		if err != nil {
@@ -378,8 +497,8 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 
 	// Get deploy key, if not already specified.
 	var deployKeyID string
-	if deployKey == "" {
-		deployKeyID, deployKey, err = generateDeployKey(cloudAddr, "Auto-generated by the Pixie CLI")
+	if deployKey == "" && secretFormat != k8s.SecretFormatExternalSecret {
+		deployKeyID, deployKey, err = generateDeployKey(cloudAddr, "Auto-generated by the Pixie CLI", 0, false)
 		if err != nil {
 			// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
 			log.WithError(err).Fatal("Failed to generate deployment key")
@@ -402,7 +521,15 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 
 	utils.Infof("Generating YAMLs for Pixie")
 
-	templatedYAMLs, err := artifacts.FetchOperatorTemplates(cloudConn, operatorVersion)
+	var templatedYAMLs []*yamlsutils.YAMLFile
+	if bundle != nil {
+		templatedYAMLs, err = bundle.OperatorTemplates()
+	} else {
+		templatedYAMLs, err = artifacts.FetchOperatorTemplates(cloudConn, operatorVersion, mustGetArtifactVerificationOptions())
+	}
+	if artifacts.IsVerificationError(err) {
+		log.WithError(err).Fatal("Downloaded YAMLs failed verification. To use them anyway, rerun with --skip_artifact_verification")
+	}
 	if err != nil {
 		log.WithError(err).Fatal("Could not fetch Vizier YAMLs")
 	}
@@ -417,23 +544,38 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 		tmplCloudAddr = fmt.Sprintf("api-service.%s.svc.cluster.local:51200", devCloudNS)
 	}
 
+	// When the deploy key is written as an encrypted secret, the Vizier reads it from that secret rather
+	// than from the plaintext key in its spec.
+	customDeployKeySecret := ""
+	var deployKeySecretYAML string
+	if secretFormat != k8s.SecretFormatSecret {
+		deployKeySecretYAML, err = encryptedDeployKeySecretYAML(cmd, secretFormat, namespace, deployKey)
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to generate the deploy key secret")
+		}
+		customDeployKeySecret = encryptedDeployKeySecretName
+		deployKey = ""
+	}
+
 	// Fill in template values.
 	tmplArgs := &yamlsutils.YAMLTmplArguments{
 		Values: &map[string]interface{}{
-			"deployOLM":            deployOLM,
-			"olmNamespace":         olmNamespace,
-			"olmBundleChannel":     olmBundleChannel,
-			"olmOperatorNamespace": olmOperatorNamespace,
-			"name":                 "pixie",
-			"version":              versionString,
-			"deployKey":            deployKey,
-			"cloudAddr":            tmplCloudAddr,
-			"clusterName":          clusterName,
-			"disableAutoUpdate":    disableAutoUpdate,
-			"useEtcdOperator":      useEtcdOperator,
-			"devCloudNamespace":    devCloudNS,
-			"pemMemoryLimit":       pemMemoryLimit,
-			"pemMemoryRequest":     pemMemoryRequest,
+			"deployOLM":                 deployOLM,
+			"olmNamespace":              olmNamespace,
+			"olmBundleChannel":          olmBundleChannel,
+			"olmOperatorNamespace":      olmOperatorNamespace,
+			"name":                      "pixie",
+			"version":                   versionString,
+			"deployKey":                 deployKey,
+			"customDeployKeySecret":     customDeployKeySecret,
+			"cloudAddr":                 tmplCloudAddr,
+			"clusterName":               clusterName,
+			"disableAutoUpdate":         disableAutoUpdate,
+			"useEtcdOperator":           useEtcdOperator,
+			"singleProcessControlPlane": singleProcessControlPlane,
+			"devCloudNamespace":         devCloudNS,
+			"pemMemoryLimit":            pemMemoryLimit,
+			"pemMemoryRequest":          pemMemoryRequest,
 			"pod": &map[string]interface{}{
 				"annotations": annotationMap,
 				"labels":      labelMap,
@@ -442,6 +584,10 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 			"dataAccess":          castedDataAccess,
 			"dataCollectorParams": dataCollectorParams,
 			"registry":            registry,
+			"profile":             castedProfile,
+			"fipsMode":            fipsMode,
+			"networkPolicy":       networkPolicy,
+			"cloudTLS":            cloudTLS,
 		},
 		Release: &map[string]interface{}{
 			"Namespace": namespace,
@@ -453,6 +599,10 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 		log.WithError(err).Fatal("Failed to fill in templated deployment YAMLs")
 	}
 
+	if deployKeySecretYAML != "" {
+		yamls = append(yamls, &yamlsutils.YAMLFile{Name: "deploy_key_secret", YAML: deployKeySecretYAML})
+	}
+
 	// If extract_path is specified, write out yamls to file.
 	if extractPath != "" {
 		if err := yamlsutils.ExtractYAMLs(yamls, extractPath, "pixie_yamls", yamlsutils.MultiFileExtractYAMLFormat); err != nil {
@@ -509,6 +659,49 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 	cmd.Annotations["status"] = DeploySuccess
 }
 
+// encryptedDeployKeySecretYAML generates the SealedSecret or ExternalSecret YAML that provides the deploy key.
+func encryptedDeployKeySecretYAML(cmd *cobra.Command, secretFormat, namespace, deployKey string) (string, error) {
+	var secret *unstructured.Unstructured
+	switch secretFormat {
+	case k8s.SecretFormatSealedSecret:
+		certPath, _ := cmd.Flags().GetString("sealed_secrets_cert")
+		if certPath == "" {
+			return "", errors.New("--sealed_secrets_cert must be specified with --secret_format=sealed-secret")
+		}
+		certPEM, err := os.ReadFile(certPath)
+		if err != nil {
+			return "", err
+		}
+		pubKey, err := k8s.ParseSealedSecretsCert(certPEM)
+		if err != nil {
+			return "", err
+		}
+		secret, err = k8s.CreateSealedSecretFromLiterals(namespace, encryptedDeployKeySecretName, map[string]string{
+			"deploy-key": deployKey,
+		}, pubKey)
+		if err != nil {
+			return "", err
+		}
+	case k8s.SecretFormatExternalSecret:
+		storeName, _ := cmd.Flags().GetString("external_secret_store")
+		storeKind, _ := cmd.Flags().GetString("external_secret_store_kind")
+		remoteKey, _ := cmd.Flags().GetString("external_secret_deploy_key")
+		if remoteKey == "" {
+			return "", errors.New("--external_secret_deploy_key must be specified with --secret_format=external-secret")
+		}
+		var err error
+		secret, err = k8s.CreateExternalSecret(namespace, encryptedDeployKeySecretName, storeName, storeKind, map[string]string{
+			"deploy-key": remoteKey,
+		})
+		if err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("unsupported secret format %q", secretFormat)
+	}
+	return k8s.ConvertResourceToYAML(secret)
+}
+
 func deploy(cloudConn *grpc.ClientConn, clientset *kubernetes.Clientset, vzClient *versioned.Clientset, kubeConfig *rest.Config, yamlMap map[string]string, deployOLM bool, olmNs, olmOpNs, namespace string) uuid.UUID {
 	olmCRDJob := newTaskWrapper("Installing OLM CRDs", func() error {
 		return retryDeploy(clientset, kubeConfig, yamlMap["olm_crd"])
@@ -773,24 +966,6 @@ func retryDeploy(clientset *kubernetes.Clientset, config *rest.Config, yamlConte
 	return nil
 }
 
-func isPodUnschedulable(podStatus *v1.PodStatus) bool {
-	for _, cond := range podStatus.Conditions {
-		if cond.Reason == "Unschedulable" {
-			return true
-		}
-	}
-	return false
-}
-
-func podUnschedulableMessage(podStatus *v1.PodStatus) string {
-	for _, cond := range podStatus.Conditions {
-		if cond.Reason == "Unschedulable" {
-			return cond.Message
-		}
-	}
-	return ""
-}
-
 func getNumNodes(clientset *kubernetes.Clientset) (int, error) {
 	nodes, err := clientset.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
 	if err != nil {
@@ -799,53 +974,41 @@ func getNumNodes(clientset *kubernetes.Clientset) (int, error) {
 	return len(nodes.Items), nil
 }
 
-var empty struct{}
+// waitForPems waits for the Vizier's PEMs to be running on all of the nodes, or for every PEM that isn't running to be
+// unschedulable. A PEM that is crash looping isn't counted as running, since it may still recover. It fails as soon as a
+// PEM can't pull its image, rather than waiting for it. Like the healthcheck that follows, there is no timeout.
+func waitForPems(clientset kubernetes.Interface, namespace string, expectedPods int) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-// waitForPems waits for the Vizier's Proxy service to be ready with an external IP.
-func waitForPems(clientset *kubernetes.Clientset, namespace string, expectedPods int) error {
-	// Watch for pod updates.
-	watcher, err := clientset.CoreV1().Pods(namespace).Watch(context.Background(), metav1.ListOptions{})
-	if err != nil {
+	w := k8s.NewWatcher(clientset, namespace, "name=vizier-pem")
+	if err := w.Start(ctx); err != nil {
 		return err
 	}
 
-	failedSchedulingPods := make(map[string]string)
-	successfulPods := make(map[string]struct{})
-	for c := range watcher.ResultChan() {
-		pod := c.Object.(*v1.Pod)
-		name, ok := pod.Labels["name"]
-		if !ok {
-			continue
-		}
-
-		// Skip any pods that are not vizier-pems.
-		if name != "vizier-pem" {
-			continue
-		}
-
-		switch pod.Status.Phase {
-		case "Pending":
-			if isPodUnschedulable(&pod.Status) {
-				failedSchedulingPods[pod.Name] = podUnschedulableMessage(&pod.Status)
+	return w.WaitFor(ctx, func(statuses []k8s.ResourceStatus) (bool, error) {
+		started := 0
+		failedSchedulingPems := make([]string, 0)
+		for _, s := range statuses {
+			switch {
+			case s.Kind != "Pod":
+			case s.Failed():
+				return false, fmt.Errorf("PEM '%s' failed to start: %s: %s", s.Name, s.Phase, s.Message)
+			case s.Phase == k8s.PhaseCrashLoopBackOff:
+			case s.Phase == k8s.PhaseUnschedulable:
+				failedSchedulingPems = append(failedSchedulingPems, fmt.Sprintf("'%s': '%s'", s.Name, s.Message))
+			case s.PodPhase == v1.PodRunning:
+				started++
+			case s.PodPhase != v1.PodPending:
+				return false, fmt.Errorf("unexpected status for PEM '%s': '%v'", s.Name, s.PodPhase)
 			}
-
-		case "Running":
-			successfulPods[pod.Name] = empty
-		default:
-			return fmt.Errorf("unexpected status for PEM '%s': '%v'", pod.Name, pod.Status.Phase)
 		}
-
-		if len(successfulPods) == expectedPods {
-			return nil
+		if started == expectedPods {
+			return true, nil
 		}
-		if len(successfulPods)+len(failedSchedulingPods) == expectedPods {
-			failedPems := make([]string, 0)
-			for k, v := range failedSchedulingPods {
-				failedPems = append(failedPems, fmt.Sprintf("'%s': '%s'", k, v))
-			}
-
-			return fmt.Errorf("Failed to schedule pems:\n%s", strings.Join(failedPems, "\n"))
+		if started+len(failedSchedulingPems) == expectedPods {
+			return false, fmt.Errorf("Failed to schedule pems:\n%s", strings.Join(failedSchedulingPems, "\n"))
 		}
-	}
-	return nil
+		return false, nil
+	})
 }
@@ -0,0 +1,146 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testPem(i int, phase v1.PodPhase, conditions ...v1.PodCondition) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("vizier-pem-%d", i),
+			Namespace: "pl",
+			Labels:    map[string]string{"name": "vizier-pem"},
+		},
+		Spec:   v1.PodSpec{Containers: []v1.Container{{Name: "pem"}}},
+		Status: v1.PodStatus{Phase: phase, Conditions: conditions},
+	}
+}
+
+func withWaitingContainer(pod *v1.Pod, reason string) *v1.Pod {
+	pod.Status.ContainerStatuses = []v1.ContainerStatus{{
+		Name:  "pem",
+		State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: reason, Message: reason}},
+	}}
+	return pod
+}
+
+var unschedulable = v1.PodCondition{
+	Type:    v1.PodScheduled,
+	Status:  v1.ConditionFalse,
+	Reason:  v1.PodReasonUnschedulable,
+	Message: "0/2 nodes are available",
+}
+
+// waitForPemsAsync runs waitForPems, which has no timeout, in the background.
+func waitForPemsAsync(clientset *fake.Clientset, expectedPods int) <-chan error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- waitForPems(clientset, "pl", expectedPods)
+	}()
+	return errCh
+}
+
+func requireResult(t *testing.T, errCh <-chan error) error {
+	select {
+	case err := <-errCh:
+		return err
+	case <-time.After(10 * time.Second):
+		require.FailNow(t, "timed out waiting for the PEMs")
+		return nil
+	}
+}
+
+func requirePending(t *testing.T, errCh <-chan error) {
+	select {
+	case err := <-errCh:
+		require.FailNow(t, "waitForPems returned early", "err: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestWaitForPems_RunningPemsNeedNotBeReady(t *testing.T) {
+	clientset := fake.NewSimpleClientset(testPem(0, v1.PodRunning), testPem(1, v1.PodPending))
+	errCh := waitForPemsAsync(clientset, 2)
+	requirePending(t, errCh)
+
+	_, err := clientset.CoreV1().Pods("pl").UpdateStatus(context.Background(), testPem(1, v1.PodRunning), metav1.UpdateOptions{})
+	require.NoError(t, err)
+	assert.NoError(t, requireResult(t, errCh))
+}
+
+func TestWaitForPems_CrashLoopingPemsAreNotRunning(t *testing.T) {
+	clientset := fake.NewSimpleClientset(withWaitingContainer(testPem(0, v1.PodRunning), "CrashLoopBackOff"))
+	errCh := waitForPemsAsync(clientset, 1)
+	requirePending(t, errCh)
+
+	_, err := clientset.CoreV1().Pods("pl").UpdateStatus(context.Background(), testPem(0, v1.PodRunning), metav1.UpdateOptions{})
+	require.NoError(t, err)
+	assert.NoError(t, requireResult(t, errCh))
+}
+
+func TestWaitForPems_Unschedulable(t *testing.T) {
+	clientset := fake.NewSimpleClientset(testPem(0, v1.PodRunning), testPem(1, v1.PodPending, unschedulable))
+	err := requireResult(t, waitForPemsAsync(clientset, 2))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Failed to schedule pems")
+	assert.Contains(t, err.Error(), "'vizier-pem-1': '0/2 nodes are available'")
+}
+
+func TestWaitForPems_Failures(t *testing.T) {
+	tests := []struct {
+		name   string
+		pod    *v1.Pod
+		errMsg string
+	}{
+		{
+			name:   "image pull error",
+			pod:    withWaitingContainer(testPem(1, v1.PodPending), "ImagePullBackOff"),
+			errMsg: "PEM 'vizier-pem-1' failed to start: ImagePullError",
+		},
+		{
+			name:   "failed",
+			pod:    testPem(1, v1.PodFailed),
+			errMsg: "PEM 'vizier-pem-1' failed to start: Failed",
+		},
+		{
+			name:   "succeeded",
+			pod:    testPem(1, v1.PodSucceeded),
+			errMsg: "unexpected status for PEM 'vizier-pem-1': 'Succeeded'",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(testPem(0, v1.PodPending), test.pod)
+			err := requireResult(t, waitForPemsAsync(clientset, 2))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errMsg)
+		})
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"os"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/gofrs/uuid"
 	log "github.com/sirupsen/logrus"
@@ -47,6 +48,8 @@ func init() {
 
 	CreateDeployKeyCmd.Flags().StringP("desc", "d", "", "A description for the deploy key")
 	CreateDeployKeyCmd.Flags().BoolP("short", "s", false, "Return only the created deploy key, for use to pipe to other tools")
+	CreateDeployKeyCmd.Flags().Duration("expires", 0, "Expire the deploy key after the given duration, for example 720h. Defaults to never")
+	CreateDeployKeyCmd.Flags().Bool("viewer", false, "Create a viewer key. Clusters deployed with it stream data, but reject mutations such as tracepoints, script deployments and config changes")
 
 	DeleteDeployKeyCmd.Flags().StringP("id", "i", "", "The deploy key to delete")
 
@@ -77,8 +80,10 @@ var CreateDeployKeyCmd = &cobra.Command{
 		cloudAddr := viper.GetString("cloud_addr")
 		desc := viper.GetString("desc")
 		short, _ := cmd.Flags().GetBool("short")
+		expires, _ := cmd.Flags().GetDuration("expires")
+		viewer, _ := cmd.Flags().GetBool("viewer")
 
-		keyID, key, err := generateDeployKey(cloudAddr, desc)
+		keyID, key, err := generateDeployKey(cloudAddr, desc, expires, viewer)
 		if err != nil {
 			// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
 			log.WithError(err).Fatal("Failed to generate deployment key")
@@ -140,10 +145,10 @@ var ListDeployKeyCmd = &cobra.Command{
 		// Throw keys into table.
 		w := components.CreateStreamWriter(format, os.Stdout)
 		defer w.Finish()
-		w.SetHeader("deployment-keys", []string{"ID", "Key", "CreatedAt", "Description"})
+		w.SetHeader("deployment-keys", []string{"ID", "Key", "CreatedAt", "Description", "ExpiresAt", "LastUsedAt", "Viewer"})
 		for _, k := range keys {
 			_ = w.Write([]interface{}{utils2.UUIDFromProtoOrNil(k.ID), "<hidden>", k.CreatedAt,
-				k.Desc})
+				k.Desc, formatKeyTime(k.ExpiresAt, "never"), formatKeyTime(k.LastUsedAt, "never"), k.Viewer})
 		}
 	},
 }
@@ -175,9 +180,9 @@ var LookupDeployKeyCmd = &cobra.Command{
 		// Throw keys into table.
 		w := components.CreateStreamWriter(format, os.Stdout)
 		defer w.Finish()
-		w.SetHeader("api-keys", []string{"ID", "Key", "CreatedAt", "Description"})
+		w.SetHeader("api-keys", []string{"ID", "Key", "CreatedAt", "Description", "ExpiresAt", "LastUsedAt", "Viewer"})
 		_ = w.Write([]interface{}{utils2.UUIDFromProtoOrNil(k.ID), "<hidden>", k.CreatedAt,
-			k.Desc})
+			k.Desc, formatKeyTime(k.ExpiresAt, "never"), formatKeyTime(k.LastUsedAt, "never"), k.Viewer})
 	},
 }
 
@@ -206,9 +211,9 @@ var GetDeployKeyCmd = &cobra.Command{
 		// Throw keys into table.
 		w := components.CreateStreamWriter(format, os.Stdout)
 		defer w.Finish()
-		w.SetHeader("deployment-keys", []string{"ID", "Key", "CreatedAt", "Description"})
+		w.SetHeader("deployment-keys", []string{"ID", "Key", "CreatedAt", "Description", "ExpiresAt", "LastUsedAt", "Viewer"})
 		_ = w.Write([]interface{}{utils2.UUIDFromProtoOrNil(k.ID), k.Key, k.CreatedAt,
-			k.Desc})
+			k.Desc, formatKeyTime(k.ExpiresAt, "never"), formatKeyTime(k.LastUsedAt, "never"), k.Viewer})
 	},
 }
 
@@ -227,13 +232,18 @@ func getClientAndContext(cloudAddr string) (cloudpb.VizierDeploymentKeyManagerCl
 	return deployMgrClient, ctxWithCreds, nil
 }
 
-func generateDeployKey(cloudAddr string, desc string) (string, string, error) {
+func generateDeployKey(cloudAddr string, desc string, expires time.Duration, viewer bool) (string, string, error) {
+	expiresAt, err := expiryTimestamp(expires)
+	if err != nil {
+		return "", "", err
+	}
+
 	deployMgrClient, ctxWithCreds, err := getClientAndContext(cloudAddr)
 	if err != nil {
 		return "", "", err
 	}
 
-	resp, err := deployMgrClient.Create(ctxWithCreds, &cloudpb.CreateDeploymentKeyRequest{Desc: desc})
+	resp, err := deployMgrClient.Create(ctxWithCreds, &cloudpb.CreateDeploymentKeyRequest{Desc: desc, ExpiresAt: expiresAt, Viewer: viewer})
 	if err != nil {
 		return "", "", err
 	}
@@ -0,0 +1,194 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fatih/color"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/pixie_cli/pkg/vizier"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func init() {
+	DoctorCmd.AddCommand(DoctorDuplicatesCmd)
+
+	DoctorCmd.Flags().StringP("namespace", "n", "", "The namespace where Pixie is located")
+	DoctorCmd.Flags().Bool("fix", false, "Offer to apply the suggested fixes that are safe to automate")
+
+	DoctorDuplicatesCmd.Flags().Bool("cleanup", false, "Offer to delete the leftover objects that cause conflicts")
+}
+
+// DoctorCmd is the "doctor" command, which diagnoses problems with the Pixie install on the current cluster.
+var DoctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose problems with the Pixie install on the current K8s cluster",
+	Run: func(cmd *cobra.Command, args []string) {
+		fix, _ := cmd.Flags().GetBool("fix")
+		ns, _ := cmd.Flags().GetString("namespace")
+
+		kubeConfig := k8s.GetConfig()
+		clientset := k8s.GetClientset(kubeConfig)
+		if ns == "" {
+			var err error
+			ns, err = vizier.FindVizierNamespace(clientset)
+			if err != nil {
+				log.WithError(err).Fatal("Failed to get Vizier namespace")
+			}
+		}
+
+		findings := vizier.PreflightFindings(utils.DefaultClusterChecks, vizier.SeverityCritical)
+		findings = append(findings, vizier.PreflightFindings(utils.ExtraClusterChecks, vizier.SeverityWarning)...)
+		if ns == "" {
+			utils.Info("Cannot find running Vizier instance, only checking the cluster.")
+		} else {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			live, err := vizier.Diagnose(ctx, clientset, ns)
+			if err != nil {
+				log.WithError(err).Fatal("Failed to inspect cluster")
+			}
+			findings = append(findings, live...)
+		}
+		vizier.RankFindings(findings)
+
+		if len(findings) == 0 {
+			utils.WithColor(color.New(color.FgGreen)).Info("No problems found.")
+			return
+		}
+		fixable := 0
+		for i, f := range findings {
+			c := color.New(color.FgYellow)
+			if f.Severity == vizier.SeverityCritical {
+				c = color.New(color.FgRed)
+			}
+			utils.WithColor(c).Infof("%d. [%s] %s: %s", i+1, f.Severity, f.Check, f.Description)
+			utils.Infof("   Suggested fix: %s", f.Remediation)
+			if f.Fix != nil {
+				fixable++
+			}
+		}
+		if fixable == 0 {
+			return
+		}
+		if !fix {
+			utils.Infof("%d of these can be fixed automatically. Rerun with --fix to apply them.", fixable)
+			return
+		}
+
+		for i, f := range findings {
+			if f.Fix == nil {
+				continue
+			}
+			if !components.YNPrompt(fmt.Sprintf("Apply the fix for finding %d (%s)?", i+1, f.Check), false) {
+				continue
+			}
+			if err := f.Fix(context.Background()); err != nil {
+				utils.WithError(err).Errorf("Failed to apply the fix for finding %d", i+1)
+			}
+		}
+	},
+}
+
+// DoctorDuplicatesCmd finds multiple or partial Pixie installs on the current cluster.
+var DoctorDuplicatesCmd = &cobra.Command{
+	Use:   "duplicates",
+	Short: "Find multiple or partial Pixie installs and leftover cluster-scoped objects",
+	Run: func(cmd *cobra.Command, args []string) {
+		cleanup, _ := cmd.Flags().GetBool("cleanup")
+
+		kubeConfig := k8s.GetConfig()
+		clientset := k8s.GetClientset(kubeConfig)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		report, err := vizier.FindVizierInstalls(ctx, clientset)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to inspect cluster")
+		}
+
+		if len(report.Installs) == 0 {
+			utils.Info("No Vizier objects found on this cluster.")
+		}
+		for _, i := range report.Installs {
+			state := "running"
+			if i.Partial() {
+				state = "partial"
+			}
+			utils.Infof("Namespace %s: %s, cluster ID %q, version %q, %d/%d pods running",
+				i.Namespace, state, i.ClusterID, i.Version, i.RunningPods, i.Pods)
+		}
+		for a := 0; a < len(report.Installs); a++ {
+			for b := a + 1; b < len(report.Installs); b++ {
+				diffs := vizier.DiffInstalls(report.Installs[a], report.Installs[b])
+				if len(diffs) == 0 {
+					continue
+				}
+				utils.Infof("Differences between %s and %s:", report.Installs[a].Namespace, report.Installs[b].Namespace)
+				for _, d := range diffs {
+					utils.Infof("  %s", d)
+				}
+			}
+		}
+
+		if len(report.Conflicts) == 0 {
+			utils.WithColor(color.New(color.FgGreen)).Info("No conflicting Pixie installs found.")
+			return
+		}
+		for _, c := range report.Conflicts {
+			utils.WithColor(color.New(color.FgRed)).Infof("Conflict: %s", c.Description)
+		}
+		if !cleanup {
+			utils.Info("Rerun with --cleanup to delete the leftover objects.")
+			return
+		}
+
+		for _, c := range report.Conflicts {
+			od := k8s.ObjectDeleter{
+				Clientset:  clientset,
+				RestConfig: kubeConfig,
+				Timeout:    2 * time.Minute,
+			}
+			switch c.Cleanup {
+			case vizier.CleanupObject:
+				if !components.YNPrompt(fmt.Sprintf("Delete %s %s?", c.Resource, c.Name), false) {
+					continue
+				}
+				if err := od.DeleteCustomObject(context.Background(), c.Resource, c.Name); err != nil {
+					utils.WithError(err).Errorf("Failed to delete %s %s", c.Resource, c.Name)
+				}
+			case vizier.CleanupNamespaceObjects:
+				if !components.YNPrompt(fmt.Sprintf("Delete the Vizier objects in namespace %s?", c.Namespace), false) {
+					continue
+				}
+				od.Namespace = c.Namespace
+				if _, err := od.DeleteByLabel(context.Background(), "component=vizier"); err != nil {
+					utils.WithError(err).Errorf("Failed to delete Vizier objects in namespace %s", c.Namespace)
+				}
+			}
+		}
+	},
+}
@@ -0,0 +1,338 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/pixie_cli/pkg/auth"
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/pixie_cli/pkg/vizier"
+	utils2 "px.dev/pixie/src/utils"
+)
+
+func init() {
+	FleetCmd.AddCommand(CreateFleetCmd)
+	FleetCmd.AddCommand(ListFleetCmd)
+	FleetCmd.AddCommand(GetFleetCmd)
+	FleetCmd.AddCommand(DeleteFleetCmd)
+	FleetCmd.AddCommand(FleetHealthCmd)
+	FleetCmd.AddCommand(LabelClusterCmd)
+	FleetCmd.AddCommand(RolloutFleetScriptCmd)
+	FleetCmd.AddCommand(UpdateFleetCmd)
+
+	CreateFleetCmd.Flags().StringP("desc", "d", "", "A description for the fleet")
+	CreateFleetCmd.Flags().StringToString("selector", map[string]string{},
+		"The cluster labels that select the fleet, for example env=prod,region=us. Defaults to every cluster")
+
+	ListFleetCmd.Flags().StringP("output", "o", "", "Output format: one of: json|proto")
+	GetFleetCmd.Flags().StringP("output", "o", "", "Output format: one of: json|proto")
+	FleetHealthCmd.Flags().StringP("output", "o", "", "Output format: one of: json|proto")
+
+	LabelClusterCmd.Flags().Bool("clear", false, "Remove all labels from the cluster")
+
+	RolloutFleetScriptCmd.Flags().String("script-id", "", "ID of the retention script to run on the fleet")
+
+	UpdateFleetCmd.Flags().StringP("version", "v", "", "Vizier version to update the fleet to. Defaults to the latest release")
+	UpdateFleetCmd.Flags().Bool("redeploy_etcd", false, "Whether or not to redeploy etcd during the update")
+}
+
+// FleetCmd is the fleet sub-command of the CLI.
+var FleetCmd = &cobra.Command{
+	Use:   "fleet",
+	Short: "Manage groups of clusters selected by label",
+	Run: func(cmd *cobra.Command, args []string) {
+		utils.Info("Nothing here... Please execute one of the subcommands")
+		cmd.Help()
+	},
+}
+
+// CreateFleetCmd is the Create sub-command of Fleet.
+var CreateFleetCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a fleet of clusters",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cloudAddr := viper.GetString("cloud_addr")
+		desc, _ := cmd.Flags().GetString("desc")
+		selector, _ := cmd.Flags().GetStringToString("selector")
+
+		client, ctx := getFleetClientAndContext(cloudAddr)
+		f, err := client.CreateFleet(ctx, &cloudpb.CreateFleetRequest{
+			Name:     args[0],
+			Desc:     desc,
+			Selector: selector,
+		})
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to create fleet")
+		}
+		utils.Infof("Created fleet '%s' with %d clusters\nID: %s", f.Name, len(f.ClusterIDs), utils2.UUIDFromProtoOrNil(f.ID))
+	},
+}
+
+// ListFleetCmd is the List sub-command of Fleet.
+var ListFleetCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all fleets",
+	Run: func(cmd *cobra.Command, args []string) {
+		cloudAddr := viper.GetString("cloud_addr")
+		format, _ := cmd.Flags().GetString("output")
+		format = strings.ToLower(format)
+
+		client, ctx := getFleetClientAndContext(cloudAddr)
+		resp, err := client.ListFleets(ctx, &cloudpb.ListFleetsRequest{})
+		if err != nil {
+			// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
+			log.WithError(err).Fatal("Failed to list fleets")
+		}
+
+		w := components.CreateStreamWriter(format, os.Stdout)
+		defer w.Finish()
+		w.SetHeader("fleets", []string{"ID", "Name", "Selector", "NumClusters", "CreatedAt", "Description"})
+		for _, f := range resp.Fleets {
+			_ = w.Write([]interface{}{utils2.UUIDFromProtoOrNil(f.ID), f.Name, formatLabels(f.Selector),
+				len(f.ClusterIDs), formatKeyTime(f.CreatedAt, ""), f.Desc})
+		}
+	},
+}
+
+// GetFleetCmd is the Get sub-command of Fleet.
+var GetFleetCmd = &cobra.Command{
+	Use:   "get <name>",
+	Short: "Get the clusters in a fleet",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cloudAddr := viper.GetString("cloud_addr")
+		format, _ := cmd.Flags().GetString("output")
+		format = strings.ToLower(format)
+
+		f := mustGetFleet(cloudAddr, args[0])
+
+		w := components.CreateStreamWriter(format, os.Stdout)
+		defer w.Finish()
+		w.SetHeader("fleet-clusters", []string{"Fleet", "Selector", "ClusterID"})
+		for _, id := range f.ClusterIDs {
+			_ = w.Write([]interface{}{f.Name, formatLabels(f.Selector), utils2.UUIDFromProtoOrNil(id)})
+		}
+	},
+}
+
+// DeleteFleetCmd is the Delete sub-command of Fleet.
+var DeleteFleetCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a fleet. The clusters in the fleet are not affected",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cloudAddr := viper.GetString("cloud_addr")
+		f := mustGetFleet(cloudAddr, args[0])
+
+		client, ctx := getFleetClientAndContext(cloudAddr)
+		if _, err := client.DeleteFleet(ctx, f.ID); err != nil {
+			utils.WithError(err).Fatal("Failed to delete fleet")
+		}
+		utils.Infof("Successfully deleted fleet '%s'", f.Name)
+	},
+}
+
+// FleetHealthCmd is the Health sub-command of Fleet.
+var FleetHealthCmd = &cobra.Command{
+	Use:   "health <name>",
+	Short: "Get the status of every cluster in a fleet",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cloudAddr := viper.GetString("cloud_addr")
+		format, _ := cmd.Flags().GetString("output")
+		format = strings.ToLower(format)
+
+		f := mustGetFleet(cloudAddr, args[0])
+		client, ctx := getFleetClientAndContext(cloudAddr)
+		resp, err := client.GetFleetHealth(ctx, &cloudpb.GetFleetHealthRequest{FleetID: f.ID})
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to get fleet health")
+		}
+
+		w := components.CreateStreamWriter(format, os.Stdout)
+		defer w.Finish()
+		w.SetHeader("fleet-health", []string{"ClusterName", "ID", "K8s Version", "Vizier Version", "Status", "LastHeartbeat"})
+		for _, c := range resp.Clusters {
+			_ = w.Write([]interface{}{c.ClusterName, utils2.UUIDFromProtoOrNil(c.ID), c.ClusterVersion,
+				c.VizierVersion, strings.TrimPrefix(c.Status.String(), "CS_"), c.LastHeartbeatNs})
+		}
+		if format == "" || format == "table" {
+			utils.Infof("%d of %d clusters in fleet '%s' are healthy", resp.NumHealthyClusters, resp.NumClusters, f.Name)
+		}
+	},
+}
+
+// LabelClusterCmd is the Label sub-command of Fleet.
+var LabelClusterCmd = &cobra.Command{
+	Use:   "label <cluster-id> [key=value...]",
+	Short: "Set the labels on a cluster, or print them if none are given",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cloudAddr := viper.GetString("cloud_addr")
+		clearLabels, _ := cmd.Flags().GetBool("clear")
+
+		clusterID, err := uuid.FromString(args[0])
+		if err != nil {
+			utils.WithError(err).Fatal("Invalid cluster ID")
+		}
+
+		client, ctx := getFleetClientAndContext(cloudAddr)
+		if len(args) == 1 && !clearLabels {
+			resp, err := client.GetClusterLabels(ctx, &cloudpb.GetClusterLabelsRequest{ClusterID: utils2.ProtoFromUUID(clusterID)})
+			if err != nil {
+				utils.WithError(err).Fatal("Failed to get cluster labels")
+			}
+			fmt.Fprintf(os.Stdout, "%s\n", formatLabels(resp.Labels))
+			return
+		}
+		if len(args) > 1 && clearLabels {
+			utils.Fatal("--clear cannot be combined with labels")
+		}
+
+		labels := make(map[string]string)
+		for _, l := range args[1:] {
+			kv := strings.SplitN(l, "=", 2)
+			if len(kv) != 2 {
+				utils.Fatalf("Invalid label '%s', expected key=value", l)
+			}
+			labels[kv[0]] = kv[1]
+		}
+		_, err = client.UpdateClusterLabels(ctx, &cloudpb.UpdateClusterLabelsRequest{
+			ClusterID: utils2.ProtoFromUUID(clusterID),
+			Labels:    labels,
+		})
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to update cluster labels")
+		}
+		utils.Info("Successfully updated cluster labels")
+	},
+}
+
+// RolloutFleetScriptCmd is the RolloutScript sub-command of Fleet.
+var RolloutFleetScriptCmd = &cobra.Command{
+	Use:   "rollout-script <name>",
+	Short: "Run a retention script on exactly the clusters in a fleet",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cloudAddr := viper.GetString("cloud_addr")
+		scriptStr, _ := cmd.Flags().GetString("script-id")
+		scriptID, err := uuid.FromString(scriptStr)
+		if err != nil {
+			utils.WithError(err).Fatal("Retention script ID must be specified using --script-id flag")
+		}
+
+		f := mustGetFleet(cloudAddr, args[0])
+		client, ctx := getFleetClientAndContext(cloudAddr)
+		resp, err := client.RolloutFleetRetentionScript(ctx, &cloudpb.RolloutFleetRetentionScriptRequest{
+			FleetID:  f.ID,
+			ScriptID: utils2.ProtoFromUUID(scriptID),
+		})
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to roll out retention script")
+		}
+		utils.Infof("Retention script now runs on %d clusters in fleet '%s'", len(resp.ClusterIDs), f.Name)
+	},
+}
+
+// UpdateFleetCmd is the Update sub-command of Fleet.
+var UpdateFleetCmd = &cobra.Command{
+	Use:   "update <name>",
+	Short: "Update Vizier on every cluster in a fleet",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cloudAddr := viper.GetString("cloud_addr")
+		version, _ := cmd.Flags().GetString("version")
+		redeployEtcd, _ := cmd.Flags().GetBool("redeploy_etcd")
+
+		f := mustGetFleet(cloudAddr, args[0])
+		client, ctx := getFleetClientAndContext(cloudAddr)
+		resp, err := client.UpdateOrInstallFleet(ctx, &cloudpb.UpdateOrInstallFleetRequest{
+			FleetID:      f.ID,
+			Version:      version,
+			RedeployEtcd: redeployEtcd,
+		})
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to update fleet")
+		}
+
+		w := components.CreateStreamWriter("", os.Stdout)
+		defer w.Finish()
+		w.SetHeader("fleet-update", []string{"ClusterID", "UpdateStarted", "Error"})
+		for _, r := range resp.Results {
+			_ = w.Write([]interface{}{utils2.UUIDFromProtoOrNil(r.ClusterID), r.UpdateStarted, r.Error})
+		}
+	},
+}
+
+func getFleetClientAndContext(cloudAddr string) (cloudpb.FleetManagerClient, context.Context) {
+	// Get grpc connection to cloud.
+	cloudConn, err := utils.GetCloudClientConnection(cloudAddr)
+	if err != nil {
+		// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
+		log.Fatalln(err)
+	}
+
+	ctxWithCreds := auth.CtxWithCreds(context.Background())
+	return cloudpb.NewFleetManagerClient(cloudConn), ctxWithCreds
+}
+
+func mustGetFleet(cloudAddr string, name string) *cloudpb.Fleet {
+	client, ctx := getFleetClientAndContext(cloudAddr)
+	f, err := client.GetFleet(ctx, &cloudpb.GetFleetRequest{Name: name})
+	if err != nil {
+		utils.WithError(err).Fatalf("Failed to get fleet '%s'", name)
+	}
+	return f
+}
+
+// mustConnectFleet connects to every healthy cluster in the named fleet.
+func mustConnectFleet(cloudAddr string, name string) []*vizier.Connector {
+	f := mustGetFleet(cloudAddr, name)
+	clusterIDs := make([]uuid.UUID, len(f.ClusterIDs))
+	for i, id := range f.ClusterIDs {
+		clusterIDs[i] = utils2.UUIDFromProtoOrNil(id)
+	}
+	conns, err := vizier.ConnectToViziers(cloudAddr, clusterIDs)
+	if err != nil {
+		utils.WithError(err).Fatalf("Failed to connect to fleet '%s'", name)
+	}
+	return conns
+}
+
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
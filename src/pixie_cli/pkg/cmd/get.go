@@ -47,11 +47,15 @@ func init() {
 	GetPEMsCmd.Flags().BoolP("all-clusters", "d", false, "Run script across all clusters")
 	GetPEMsCmd.Flags().StringP("cluster", "c", "", "Run only on selected cluster")
 	GetPEMsCmd.Flags().MarkHidden("all-clusters")
+	GetPEMsCmd.Flags().String("fleet", "", "Get pems across every cluster in the named fleet")
+
+	GetCapabilitiesCmd.Flags().StringP("cluster", "c", "", "Run only on selected cluster")
 
 	GetClusterCmd.Flags().Bool("id", false, "Whether to only fetch the cluster ID from the cluster running in the current kubeconfig")
 	GetClusterCmd.Flags().Bool("cloud-addr", false, "Whether to only fetch the cloud address from the cluster running in the current kubeconfig")
 
 	GetCmd.AddCommand(GetPEMsCmd)
+	GetCmd.AddCommand(GetCapabilitiesCmd)
 	GetCmd.AddCommand(GetViziersCmd)
 	GetCmd.AddCommand(GetClusterCmd)
 }
@@ -70,20 +74,58 @@ var GetPEMsCmd = &cobra.Command{
 
 		allClusters, _ := cmd.Flags().GetBool("all-clusters")
 		selectedCluster, _ := cmd.Flags().GetString("cluster")
+		fleetName, _ := cmd.Flags().GetString("fleet")
 		clusterID := uuid.FromStringOrNil(selectedCluster)
 		var err error
-		if !allClusters && clusterID == uuid.Nil {
+		if !allClusters && fleetName == "" && clusterID == uuid.Nil {
+			clusterID, err = vizier.GetCurrentVizier(cloudAddr)
+			if err != nil {
+				cliUtils.WithError(err).Fatal("Could not fetch healthy vizier")
+			}
+		}
+
+		var conns []*vizier.Connector
+		if fleetName != "" {
+			conns = mustConnectFleet(cloudAddr, fleetName)
+		} else {
+			conns = vizier.MustConnectHealthyDefaultVizier(cloudAddr, allClusters, clusterID)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := vizier.RunScriptAndOutputResults(ctx, conns, execScript, format, false, 0); err != nil {
+			cliUtils.Fatalf("Script failed: %s", vizier.FormatErrorMessage(err))
+		}
+	},
+}
+
+// GetCapabilitiesCmd is the "get capabilities" command.
+var GetCapabilitiesCmd = &cobra.Command{
+	Use:     "capabilities",
+	Aliases: []string{"caps"},
+	Short:   "Get the kernel capabilities of each node running a pem",
+	Run: func(cmd *cobra.Command, args []string) {
+		cloudAddr := viper.GetString("cloud_addr")
+		format, _ := cmd.Flags().GetString("output")
+		format = strings.ToLower(format)
+		br := mustCreateBundleReader()
+		execScript := br.MustGetScript(script.NodeCapabilitiesScript)
+
+		selectedCluster, _ := cmd.Flags().GetString("cluster")
+		clusterID := uuid.FromStringOrNil(selectedCluster)
+		var err error
+		if clusterID == uuid.Nil {
 			clusterID, err = vizier.GetCurrentVizier(cloudAddr)
 			if err != nil {
 				cliUtils.WithError(err).Fatal("Could not fetch healthy vizier")
 			}
 		}
 
-		conns := vizier.MustConnectHealthyDefaultVizier(cloudAddr, allClusters, clusterID)
+		conns := vizier.MustConnectHealthyDefaultVizier(cloudAddr, false, clusterID)
 
 		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 		defer cancel()
-		if err := vizier.RunScriptAndOutputResults(ctx, conns, execScript, format, false); err != nil {
+		if err := vizier.RunScriptAndOutputResults(ctx, conns, execScript, format, false, 0); err != nil {
 			cliUtils.Fatalf("Script failed: %s", vizier.FormatErrorMessage(err))
 		}
 	},
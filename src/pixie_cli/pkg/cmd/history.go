@@ -0,0 +1,376 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	apiutils "px.dev/pixie/src/api/go/pxapi/utils"
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/pixie_cli/pkg/auth"
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/pixie_cli/pkg/vizier"
+	utils2 "px.dev/pixie/src/utils"
+	"px.dev/pixie/src/utils/script"
+)
+
+func init() {
+	HistoryCmd.AddCommand(SaveHistoryCmd)
+	HistoryCmd.AddCommand(ListHistoryCmd)
+	HistoryCmd.AddCommand(GetHistoryCmd)
+	HistoryCmd.AddCommand(ShareHistoryCmd)
+	HistoryCmd.AddCommand(DeleteHistoryCmd)
+
+	SaveHistoryCmd.Flags().StringP("name", "n", "", "A name for the saved query. Defaults to the script name")
+	SaveHistoryCmd.Flags().StringP("file", "f", "", "Script file, specify - for STDIN")
+	SaveHistoryCmd.Flags().StringP("cluster", "c", "", "ID of the cluster to run on. Defaults to the current cluster")
+	SaveHistoryCmd.Flags().StringP("bundle", "b", "", "Path/URL to bundle file")
+	SaveHistoryCmd.Flags().BoolP("e2e_encryption", "e", true, "Enable E2E encryption")
+
+	ListHistoryCmd.Flags().StringP("output", "o", "", "Output format: one of: json|proto")
+	ListHistoryCmd.Flags().Bool("mine", false, "Only list the queries that you saved")
+
+	GetHistoryCmd.Flags().StringP("output", "o", "", "Output format: one of: json|table|csv")
+
+	ShareHistoryCmd.Flags().Bool("revoke", false, "Revoke the sharing link of the query")
+}
+
+// HistoryCmd is the history sub-command of the CLI.
+var HistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Save script executions and their results, and share them with links",
+	Run: func(cmd *cobra.Command, args []string) {
+		utils.Info("Nothing here... Please execute one of the subcommands")
+		cmd.Help()
+	},
+}
+
+// SaveHistoryCmd is the Save sub-command of History.
+var SaveHistoryCmd = &cobra.Command{
+	Use:   "save [script_name] [-- script args]",
+	Short: "Execute a script on a cluster and save the execution with its results",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlag("bundle", cmd.Flags().Lookup("bundle"))
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		cloudAddr := viper.GetString("cloud_addr")
+		name, _ := cmd.Flags().GetString("name")
+		scriptFile, _ := cmd.Flags().GetString("file")
+		useEncryption, _ := cmd.Flags().GetBool("e2e_encryption")
+
+		var execScript *script.ExecutableScript
+		var scriptArgs []string
+		var err error
+		if scriptFile == "" {
+			if len(args) == 0 {
+				utils.Fatal("Expected script_name with script args.")
+			}
+			br, bErr := createBundleReader()
+			if bErr != nil {
+				log.WithError(bErr).Fatal("Failed to read script bundle")
+			}
+			execScript = br.MustGetScript(args[0])
+			scriptArgs = args[1:]
+		} else {
+			execScript, err = loadScriptFromFile(scriptFile)
+			if err != nil {
+				utils.WithError(err).Fatal("Failed to get query string")
+			}
+			scriptArgs = args
+		}
+		if fs := execScript.GetFlagSet(); fs != nil {
+			if err := fs.Parse(scriptArgs); err != nil {
+				if err == flag.ErrHelp {
+					os.Exit(0)
+				}
+				utils.WithError(err).Fatal("Failed to parse script flags")
+			}
+			if err := execScript.UpdateFlags(fs); err != nil {
+				if errors.Is(err, script.ErrMissingRequiredArgument) {
+					utils.Fatal("Missing required argument, use '-- --help' to see the arguments of the script")
+				}
+				utils.WithError(err).Fatal("Error parsing script flags")
+			}
+		}
+		computedArgs, err := execScript.ComputedArgs()
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to get script args")
+		}
+		if name == "" {
+			name = execScript.ScriptName
+		}
+		if name == "" {
+			utils.Fatal("A name for the saved query must be specified using --name flag")
+		}
+
+		selectedCluster, _ := cmd.Flags().GetString("cluster")
+		clusterID := uuid.FromStringOrNil(selectedCluster)
+		if clusterID == uuid.Nil {
+			clusterID, err = vizier.GetCurrentVizier(cloudAddr)
+			if err != nil {
+				utils.WithError(err).Fatal("Could not fetch healthy vizier")
+			}
+		}
+		conns := vizier.MustConnectVizier(cloudAddr, false, clusterID, "", "")
+
+		ctx, cleanup := utils.WithSignalCancellable(context.Background())
+		defer cleanup()
+		executedAt := types.TimestampNow()
+		result, err := runScriptForHistory(ctx, conns, execScript, useEncryption)
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to execute script")
+		}
+
+		req := &cloudpb.SaveQueryRequest{
+			Name:       name,
+			ClusterID:  utils2.ProtoFromUUID(clusterID),
+			ScriptName: execScript.ScriptName,
+			Pxl:        execScript.ScriptString,
+			ExecutedAt: executedAt,
+			Result:     string(result),
+		}
+		for _, arg := range computedArgs {
+			req.Args = append(req.Args, &cloudpb.SavedQueryArg{Name: arg.Name, Value: arg.Value})
+		}
+
+		client, cloudCtx := getHistoryClientAndContext(cloudAddr)
+		resp, err := client.SaveQuery(cloudCtx, req)
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to save query")
+		}
+		utils.Infof("Saved query '%s' with ID %s", name, utils2.UUIDFromProtoOrNil(resp.ID))
+	},
+}
+
+// ListHistoryCmd is the List sub-command of History.
+var ListHistoryCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the saved queries of your org",
+	Run: func(cmd *cobra.Command, args []string) {
+		cloudAddr := viper.GetString("cloud_addr")
+		format, _ := cmd.Flags().GetString("output")
+		format = strings.ToLower(format)
+		mine, _ := cmd.Flags().GetBool("mine")
+
+		client, ctx := getHistoryClientAndContext(cloudAddr)
+		resp, err := client.ListSavedQueries(ctx, &cloudpb.ListSavedQueriesRequest{Mine: mine})
+		if err != nil {
+			// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
+			log.WithError(err).Fatal("Failed to list saved queries")
+		}
+
+		w := components.CreateStreamWriter(format, os.Stdout)
+		defer w.Finish()
+		w.SetHeader("saved-queries", []string{"ID", "Name", "Script", "ClusterID", "ExecutedAt", "ResultSizeBytes", "Shared"})
+		for _, q := range resp.Queries {
+			clusterID := ""
+			if q.ClusterID != nil {
+				clusterID = utils2.UUIDFromProtoOrNil(q.ClusterID).String()
+			}
+			_ = w.Write([]interface{}{utils2.UUIDFromProtoOrNil(q.ID), q.Name, q.ScriptName, clusterID,
+				formatKeyTime(q.ExecutedAt, ""), q.ResultSizeBytes, q.Shared})
+		}
+	},
+}
+
+// GetHistoryCmd is the Get sub-command of History.
+var GetHistoryCmd = &cobra.Command{
+	Use:   "get <id>",
+	Short: "Print the saved results of a query",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cloudAddr := viper.GetString("cloud_addr")
+		format, _ := cmd.Flags().GetString("output")
+		format = strings.ToLower(format)
+		id := mustParseSavedQueryID(args[0])
+
+		client, ctx := getHistoryClientAndContext(cloudAddr)
+		resp, err := client.GetSavedQuery(ctx, id)
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to get saved query")
+		}
+		if err := writeSavedQueryResult(format, resp.Result); err != nil {
+			utils.WithError(err).Fatal("Failed to read the saved results")
+		}
+	},
+}
+
+// ShareHistoryCmd is the Share sub-command of History.
+var ShareHistoryCmd = &cobra.Command{
+	Use:   "share <id>",
+	Short: "Create a link that shares a query that you saved, or revoke it",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cloudAddr := viper.GetString("cloud_addr")
+		revoke, _ := cmd.Flags().GetBool("revoke")
+		id := mustParseSavedQueryID(args[0])
+
+		client, ctx := getHistoryClientAndContext(cloudAddr)
+		resp, err := client.ShareSavedQuery(ctx, &cloudpb.ShareSavedQueryRequest{ID: id, Revoke: revoke})
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to share saved query")
+		}
+		if revoke {
+			utils.Infof("Revoked the sharing link of %s", args[0])
+			return
+		}
+		utils.Infof("Shared %s: %s", args[0], resp.ShareURL)
+	},
+}
+
+// DeleteHistoryCmd is the Delete sub-command of History.
+var DeleteHistoryCmd = &cobra.Command{
+	Use:   "delete <id>",
+	Short: "Delete a query that you saved",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cloudAddr := viper.GetString("cloud_addr")
+		id := mustParseSavedQueryID(args[0])
+
+		client, ctx := getHistoryClientAndContext(cloudAddr)
+		if _, err := client.DeleteSavedQuery(ctx, id); err != nil {
+			utils.WithError(err).Fatal("Failed to delete saved query")
+		}
+		utils.Infof("Successfully deleted %s", args[0])
+	},
+}
+
+func getHistoryClientAndContext(cloudAddr string) (cloudpb.QueryHistoryClient, context.Context) {
+	// Get grpc connection to cloud.
+	cloudConn, err := utils.GetCloudClientConnection(cloudAddr)
+	if err != nil {
+		// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
+		log.Fatalln(err)
+	}
+
+	ctxWithCreds := auth.CtxWithCreds(context.Background())
+	return cloudpb.NewQueryHistoryClient(cloudConn), ctxWithCreds
+}
+
+func mustParseSavedQueryID(s string) *uuidpb.UUID {
+	id, err := uuid.FromString(s)
+	if err != nil {
+		utils.Fatalf("Invalid saved query ID '%s'", s)
+	}
+	return utils2.ProtoFromUUID(id)
+}
+
+// savedQueryColumn, savedQueryTable and savedQueryResult are the format of saved results. They match
+// the response of the script execution HTTP API, so that saved results can be read the same way.
+type savedQueryColumn struct {
+	Name string `json:"name"`
+}
+
+type savedQueryTable struct {
+	Name      string                   `json:"name"`
+	Columns   []*savedQueryColumn      `json:"columns"`
+	Rows      []map[string]interface{} `json:"rows"`
+	TotalRows int64                    `json:"totalRows"`
+}
+
+type savedQueryResult struct {
+	Tables []*savedQueryTable `json:"tables"`
+}
+
+// runScriptForHistory runs a script and returns its results in the format that they are saved in.
+func runScriptForHistory(ctx context.Context, conns []*vizier.Connector, execScript *script.ExecutableScript, useEncryption bool) ([]byte, error) {
+	var encOpts, decOpts *vizierpb.ExecuteScriptRequest_EncryptionOptions
+	var err error
+	if useEncryption {
+		encOpts, decOpts, err = apiutils.CreateEncryptionOptions()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := vizier.RunScript(ctx, conns, execScript, encOpts)
+	if err != nil {
+		return nil, err
+	}
+	tw := vizier.NewStreamOutputAdapter(ctx, resp, vizier.FormatInMemory, decOpts)
+	if err := tw.Finish(); err != nil {
+		return nil, err
+	}
+	views, err := tw.Views()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &savedQueryResult{Tables: make([]*savedQueryTable, 0, len(views))}
+	for _, view := range views {
+		table := &savedQueryTable{Name: view.Name(), Rows: make([]map[string]interface{}, 0)}
+		header := view.Header()
+		for _, col := range header {
+			table.Columns = append(table.Columns, &savedQueryColumn{Name: col})
+		}
+		for _, data := range view.Data() {
+			row := make(map[string]interface{}, len(header))
+			for i, col := range header {
+				if i < len(data) {
+					row[col] = data[i]
+				}
+			}
+			table.Rows = append(table.Rows, row)
+		}
+		table.TotalRows = int64(len(table.Rows))
+		result.Tables = append(result.Tables, table)
+	}
+	sort.Slice(result.Tables, func(i, j int) bool { return result.Tables[i].Name < result.Tables[j].Name })
+	return json.Marshal(result)
+}
+
+// writeSavedQueryResult prints the tables of saved results in the given format.
+func writeSavedQueryResult(format string, result string) error {
+	res := &savedQueryResult{}
+	if err := json.Unmarshal([]byte(result), res); err != nil {
+		return err
+	}
+	for _, table := range res.Tables {
+		header := make([]string, len(table.Columns))
+		for i, col := range table.Columns {
+			header[i] = col.Name
+		}
+		w := components.CreateStreamWriter(format, os.Stdout)
+		w.SetHeader(table.Name, header)
+		for _, row := range table.Rows {
+			data := make([]interface{}, len(header))
+			for i, col := range header {
+				data[i] = row[col]
+			}
+			_ = w.Write(data)
+		}
+		w.Finish()
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	apiutils "px.dev/pixie/src/api/go/pxapi/utils"
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/cloud/api/ptproxy"
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/profile"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/pixie_cli/pkg/vizier"
+)
+
+func init() {
+	formats := make([]string, len(profile.Formats))
+	for i, f := range profile.Formats {
+		formats[i] = string(f)
+	}
+	ProfileCmd.Flags().String("format", string(profile.FormatCollapsed), fmt.Sprintf("The format to export the profile in. One of: %s",
+		strings.Join(formats, "|")))
+	ProfileCmd.Flags().String("out", "", "The file to write the profile to. Defaults to stdout")
+	ProfileCmd.Flags().String("node", "", "Only profile the pods of nodes whose name contains this value")
+	ProfileCmd.Flags().StringP("namespace", "n", "", "Only profile the pods of namespaces whose name contains this value")
+	ProfileCmd.Flags().String("pod", "", "Only profile the pods whose name contains this value")
+	ProfileCmd.Flags().String("since", "-5m", "How far back the profile starts, for example -30m")
+	ProfileCmd.Flags().BoolP("e2e_encryption", "e", true, "Enable E2E encryption")
+	ProfileCmd.Flags().StringP("cluster", "c", "", "ID of the cluster to run on. "+
+		"Use 'px get viziers', or visit Admin console: work.withpixie.ai/admin, to find the ID")
+}
+
+// ProfileCmd is the "profile" command.
+var ProfileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Export the CPU profiles of pods from the continuous profiler",
+	Example: `  px profile --pod frontend > frontend.folded
+  px profile --format pprof --out profile.pb.gz -n sock-shop
+  px profile --format speedscope --since -30m --out profile.speedscope.json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cloudAddr := viper.GetString("cloud_addr")
+		directVzAddr := viper.GetString("direct_vizier_addr")
+		directVzKey := viper.GetString("direct_vizier_key")
+
+		formatStr, _ := cmd.Flags().GetString("format")
+		format := profile.Format(strings.ToLower(formatStr))
+		validFormat := false
+		for _, f := range profile.Formats {
+			validFormat = validFormat || f == format
+		}
+		if !validFormat {
+			utils.Fatalf("Unsupported profile format %q", formatStr)
+		}
+		outFile, _ := cmd.Flags().GetString("out")
+
+		opts := &profile.Options{}
+		opts.Node, _ = cmd.Flags().GetString("node")
+		opts.Namespace, _ = cmd.Flags().GetString("namespace")
+		opts.Pod, _ = cmd.Flags().GetString("pod")
+		opts.Since, _ = cmd.Flags().GetString("since")
+		execScript, err := profile.Script(opts)
+		if err != nil {
+			utils.WithError(err).Fatal("Invalid profile options")
+		}
+		duration, _ := opts.Duration()
+
+		selectedCluster, _ := cmd.Flags().GetString("cluster")
+		clusterID := uuid.FromStringOrNil(selectedCluster)
+		if clusterID == uuid.Nil && directVzAddr == "" {
+			clusterID, err = vizier.GetCurrentVizier(cloudAddr)
+			if err != nil {
+				utils.WithError(err).Fatal("Could not fetch healthy vizier")
+			}
+		}
+		conns := vizier.MustConnectVizier(cloudAddr, false, clusterID, directVzAddr, directVzKey)
+
+		useEncryption, _ := cmd.Flags().GetBool("e2e_encryption")
+		// There is no e2e encryption for direct mode.
+		useEncryption = useEncryption && directVzAddr == ""
+		var encOpts, decOpts *vizierpb.ExecuteScriptRequest_EncryptionOptions
+		if useEncryption {
+			encOpts, decOpts, err = apiutils.CreateEncryptionOptions()
+			if err != nil {
+				utils.WithError(err).Fatal("Failed to create encryption options")
+			}
+		}
+
+		ctx, cleanup := utils.WithSignalCancellable(context.Background())
+		defer cleanup()
+
+		end := time.Now()
+		builder := profile.NewBuilder(end.Add(-duration), duration)
+		resp, err := vizier.RunScript(ctx, conns, execScript, encOpts)
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to fetch the profile")
+		}
+		// The in memory format turns off the formatting of the adapter, so that the builder gets
+		// the values as they are.
+		tw := vizier.NewStreamOutputAdapterWithFactory(ctx, resp, vizier.FormatInMemory, decOpts,
+			func(md *vizierpb.ExecuteScriptResponse_MetaData) components.OutputStreamWriter {
+				return builder
+			})
+		if err := tw.Finish(); err != nil {
+			if err == ptproxy.ErrNotAvailable {
+				utils.WithError(err).Fatal("Cannot fetch the profile")
+			}
+			utils.WithError(err).Fatal("Failed to fetch the profile")
+		}
+
+		p := builder.Profile()
+		if len(p.Samples) == 0 {
+			utils.Error("No stack traces were sampled for the selected pods. Is the profiler enabled?")
+			os.Exit(1)
+		}
+
+		var w io.Writer = os.Stdout
+		if outFile != "" {
+			f, err := os.Create(outFile)
+			if err != nil {
+				utils.WithError(err).Fatal("Failed to create the output file")
+			}
+			defer f.Close()
+			w = f
+		}
+		if err := profile.Write(w, format, p); err != nil {
+			utils.WithError(err).Fatal("Failed to write the profile")
+		}
+		if outFile != "" {
+			utils.Infof("Wrote a profile of %d stack traces to %s", len(p.Samples), outFile)
+		}
+	},
+}
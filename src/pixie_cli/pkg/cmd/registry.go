@@ -0,0 +1,276 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/pixie_cli/pkg/auth"
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	utils2 "px.dev/pixie/src/utils"
+)
+
+func init() {
+	RegistryCmd.AddCommand(ListRegistryCmd)
+	RegistryCmd.AddCommand(HistoryRegistryCmd)
+	RegistryCmd.AddCommand(PushRegistryCmd)
+	RegistryCmd.AddCommand(SubmitRegistryCmd)
+	RegistryCmd.AddCommand(ReviewRegistryCmd)
+	RegistryCmd.AddCommand(DeleteRegistryCmd)
+
+	ListRegistryCmd.Flags().StringP("output", "o", "", "Output format: one of: json|proto")
+	HistoryRegistryCmd.Flags().StringP("output", "o", "", "Output format: one of: json|proto")
+
+	PushRegistryCmd.Flags().String("pxl", "", "Path to the PxL script")
+	PushRegistryCmd.Flags().String("vis", "", "Path to the vis spec (vis.json) for the script")
+	PushRegistryCmd.Flags().String("short", "", "A short description of the script")
+	PushRegistryCmd.Flags().String("long", "", "A long description of the script")
+	PushRegistryCmd.Flags().Bool("submit", false, "Submit the new version for review")
+
+	ReviewRegistryCmd.Flags().Bool("approve", false, "Approve and publish the version")
+	ReviewRegistryCmd.Flags().Bool("reject", false, "Reject the version")
+	ReviewRegistryCmd.Flags().StringP("comment", "m", "", "A comment for the author of the version")
+}
+
+// RegistryCmd is the registry sub-command of the CLI.
+var RegistryCmd = &cobra.Command{
+	Use:   "registry",
+	Short: "Manage the scripts in your org's script registry",
+	Run: func(cmd *cobra.Command, args []string) {
+		utils.Info("Nothing here... Please execute one of the subcommands")
+		cmd.Help()
+	},
+}
+
+// ListRegistryCmd is the List sub-command of Registry.
+var ListRegistryCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the scripts in the registry",
+	Run: func(cmd *cobra.Command, args []string) {
+		cloudAddr := viper.GetString("cloud_addr")
+		format, _ := cmd.Flags().GetString("output")
+		format = strings.ToLower(format)
+
+		client, ctx := getRegistryClientAndContext(cloudAddr)
+		resp, err := client.ListRegistryScripts(ctx, &cloudpb.ListRegistryScriptsRequest{})
+		if err != nil {
+			// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
+			log.WithError(err).Fatal("Failed to list registry scripts")
+		}
+
+		w := components.CreateStreamWriter(format, os.Stdout)
+		defer w.Finish()
+		w.SetHeader("registry-scripts", []string{"Name", "LatestVersion", "PublishedVersion", "CreatedAt"})
+		for _, s := range resp.Scripts {
+			_ = w.Write([]interface{}{s.Name, s.LatestVersion, s.PublishedVersion, formatKeyTime(s.CreatedAt, "")})
+		}
+	},
+}
+
+// HistoryRegistryCmd is the History sub-command of Registry.
+var HistoryRegistryCmd = &cobra.Command{
+	Use:   "history <name>",
+	Short: "List the versions of a script and their review state",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cloudAddr := viper.GetString("cloud_addr")
+		format, _ := cmd.Flags().GetString("output")
+		format = strings.ToLower(format)
+
+		resp := mustGetRegistryScript(cloudAddr, args[0])
+
+		w := components.CreateStreamWriter(format, os.Stdout)
+		defer w.Finish()
+		w.SetHeader("registry-script-versions", []string{"Version", "Status", "AuthorID", "ReviewerID", "CreatedAt", "PublishedAt", "Comment"})
+		for _, v := range resp.Versions {
+			reviewerID := ""
+			if v.ReviewerID != nil {
+				reviewerID = utils2.UUIDFromProtoOrNil(v.ReviewerID).String()
+			}
+			_ = w.Write([]interface{}{v.Version, strings.TrimPrefix(v.Status.String(), "RSV_"), utils2.UUIDFromProtoOrNil(v.AuthorID),
+				reviewerID, formatKeyTime(v.CreatedAt, ""), formatKeyTime(v.PublishedAt, ""), v.ReviewComment})
+		}
+	},
+}
+
+// PushRegistryCmd is the Push sub-command of Registry.
+var PushRegistryCmd = &cobra.Command{
+	Use:   "push <name>",
+	Short: "Push a new draft version of a script to the registry",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cloudAddr := viper.GetString("cloud_addr")
+		pxlFile, _ := cmd.Flags().GetString("pxl")
+		visFile, _ := cmd.Flags().GetString("vis")
+		shortDoc, _ := cmd.Flags().GetString("short")
+		longDoc, _ := cmd.Flags().GetString("long")
+		submit, _ := cmd.Flags().GetBool("submit")
+
+		if pxlFile == "" {
+			utils.Fatal("The PxL script must be specified using --pxl flag")
+		}
+		pxl, err := os.ReadFile(pxlFile)
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to read PxL script")
+		}
+		var vis []byte
+		if visFile != "" {
+			vis, err = os.ReadFile(visFile)
+			if err != nil {
+				utils.WithError(err).Fatal("Failed to read vis spec")
+			}
+		}
+
+		client, ctx := getRegistryClientAndContext(cloudAddr)
+		resp, err := client.CreateRegistryScriptVersion(ctx, &cloudpb.CreateRegistryScriptVersionRequest{
+			Name:     args[0],
+			ShortDoc: shortDoc,
+			LongDoc:  longDoc,
+			Pxl:      string(pxl),
+			Vis:      string(vis),
+		})
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to push script")
+		}
+		utils.Infof("Pushed version %d of '%s'", resp.Version, args[0])
+
+		if !submit {
+			return
+		}
+		_, err = client.SubmitRegistryScriptVersion(ctx, &cloudpb.SubmitRegistryScriptVersionRequest{
+			ScriptID: resp.ScriptID,
+			Version:  resp.Version,
+		})
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to submit version for review")
+		}
+		utils.Infof("Submitted version %d of '%s' for review", resp.Version, args[0])
+	},
+}
+
+// SubmitRegistryCmd is the Submit sub-command of Registry.
+var SubmitRegistryCmd = &cobra.Command{
+	Use:   "submit <name> <version>",
+	Short: "Submit a version of a script for review",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		cloudAddr := viper.GetString("cloud_addr")
+		version := mustParseRegistryVersion(args[1])
+
+		s := mustGetRegistryScript(cloudAddr, args[0])
+		client, ctx := getRegistryClientAndContext(cloudAddr)
+		_, err := client.SubmitRegistryScriptVersion(ctx, &cloudpb.SubmitRegistryScriptVersionRequest{
+			ScriptID: s.Script.ID,
+			Version:  version,
+		})
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to submit version for review")
+		}
+		utils.Infof("Submitted version %d of '%s' for review", version, args[0])
+	},
+}
+
+// ReviewRegistryCmd is the Review sub-command of Registry.
+var ReviewRegistryCmd = &cobra.Command{
+	Use:   "review <name> <version>",
+	Short: "Approve or reject a version of a script that is in review",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		cloudAddr := viper.GetString("cloud_addr")
+		approve, _ := cmd.Flags().GetBool("approve")
+		reject, _ := cmd.Flags().GetBool("reject")
+		comment, _ := cmd.Flags().GetString("comment")
+		if approve == reject {
+			utils.Fatal("Exactly one of --approve or --reject must be specified")
+		}
+		version := mustParseRegistryVersion(args[1])
+
+		s := mustGetRegistryScript(cloudAddr, args[0])
+		client, ctx := getRegistryClientAndContext(cloudAddr)
+		_, err := client.ReviewRegistryScriptVersion(ctx, &cloudpb.ReviewRegistryScriptVersionRequest{
+			ScriptID: s.Script.ID,
+			Version:  version,
+			Approve:  approve,
+			Comment:  comment,
+		})
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to review version")
+		}
+		if approve {
+			utils.Infof("Published version %d of '%s'", version, args[0])
+		} else {
+			utils.Infof("Rejected version %d of '%s'", version, args[0])
+		}
+	},
+}
+
+// DeleteRegistryCmd is the Delete sub-command of Registry.
+var DeleteRegistryCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a script and all of its versions from the registry",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cloudAddr := viper.GetString("cloud_addr")
+		s := mustGetRegistryScript(cloudAddr, args[0])
+
+		client, ctx := getRegistryClientAndContext(cloudAddr)
+		if _, err := client.DeleteRegistryScript(ctx, s.Script.ID); err != nil {
+			utils.WithError(err).Fatal("Failed to delete script")
+		}
+		utils.Infof("Successfully deleted '%s'", args[0])
+	},
+}
+
+func getRegistryClientAndContext(cloudAddr string) (cloudpb.ScriptRegistryClient, context.Context) {
+	// Get grpc connection to cloud.
+	cloudConn, err := utils.GetCloudClientConnection(cloudAddr)
+	if err != nil {
+		// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
+		log.Fatalln(err)
+	}
+
+	ctxWithCreds := auth.CtxWithCreds(context.Background())
+	return cloudpb.NewScriptRegistryClient(cloudConn), ctxWithCreds
+}
+
+func mustGetRegistryScript(cloudAddr string, name string) *cloudpb.GetRegistryScriptResponse {
+	client, ctx := getRegistryClientAndContext(cloudAddr)
+	resp, err := client.GetRegistryScript(ctx, &cloudpb.GetRegistryScriptRequest{Name: name})
+	if err != nil {
+		utils.WithError(err).Fatalf("Failed to get script '%s'", name)
+	}
+	return resp
+}
+
+func mustParseRegistryVersion(s string) int64 {
+	version, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || version <= 0 {
+		utils.Fatalf("Invalid version '%s'", s)
+	}
+	return version
+}
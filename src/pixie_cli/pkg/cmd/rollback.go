@@ -0,0 +1,161 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/operator/client/versioned"
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/pixie_cli/pkg/vizier"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func init() {
+	RollbackCmd.Flags().StringP("namespace", "n", "", "The namespace where Pixie is located")
+	RollbackCmd.Flags().String("snapshot", "", "Path to the snapshot to restore. Defaults to the latest snapshot of the current cluster")
+}
+
+// RollbackCmd is the "rollback" command, which restores a snapshot taken by `px update vizier --snapshot`.
+var RollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Roll Pixie back to the snapshot taken before the last update",
+	Run: func(cmd *cobra.Command, args []string) {
+		ns, _ := cmd.Flags().GetString("namespace")
+		snapshotPath, _ := cmd.Flags().GetString("snapshot")
+
+		kubeConfig := k8s.GetConfig()
+		kubeAPIConfig := k8s.GetClientAPIConfig()
+		clientset := k8s.GetClientset(kubeConfig)
+		vzClient, err := versioned.NewForConfig(kubeConfig)
+		if err != nil {
+			log.WithError(err).Fatal("Could not start vizier client")
+		}
+
+		if snapshotPath == "" {
+			if ns == "" {
+				ns = vizier.MustFindVizierNamespace()
+			}
+			secret := k8s.GetSecret(clientset, ns, "pl-cluster-secrets")
+			if secret == nil {
+				utils.Fatalf("Could not find pl-cluster-secrets in namespace %s", ns)
+			}
+			dir, err := utils.EnsureSnapshotDirPath()
+			if err != nil {
+				log.WithError(err).Fatal("Failed to get snapshot directory")
+			}
+			snapshotPath, err = vizier.LatestSnapshotPath(dir, string(secret.Data["cluster-id"]))
+			if err == vizier.ErrNoSnapshot {
+				utils.Fatal("No snapshot found for this cluster. Snapshots are taken by `px update vizier --snapshot`.")
+			}
+			if err != nil {
+				log.WithError(err).Fatal("Failed to find snapshot")
+			}
+		}
+
+		s, err := vizier.LoadSnapshot(snapshotPath)
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to load snapshot")
+		}
+
+		utils.Infof("Rolling back cluster %s (%s) to version %s, from the snapshot taken at %s.",
+			s.ClusterName, s.ClusterID, s.VizierVersion, s.CreatedAt.Format(time.RFC3339))
+		prompt := fmt.Sprintf("Confirm to proceed on cluster %s.", kubeAPIConfig.CurrentContext)
+		if !components.YNPrompt(prompt, true) {
+			utils.Error("User exited.")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+		tasks := []utils.Task{
+			newTaskWrapper("Restoring Vizier spec and secrets", func() error {
+				return s.Restore(ctx, clientset, vzClient)
+			}),
+			newTaskWrapper("Wait for rollback to complete (this may take a few minutes)", func() error {
+				return waitForVizierVersion(ctx, vzClient, s)
+			}),
+		}
+		err = utils.NewSerialTaskRunner(tasks).RunAndMonitor()
+		if err != nil {
+			log.WithError(err).Fatal("Rollback failed")
+		}
+	},
+}
+
+func waitForVizierVersion(ctx context.Context, vzClient versioned.Interface, s *vizier.Snapshot) error {
+	t := time.NewTicker(5 * time.Second)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timeout waiting for Vizier to return to version %s", s.VizierVersion)
+		case <-t.C:
+			vz, err := vzClient.PxV1alpha1().Viziers(s.Namespace).Get(ctx, s.Vizier.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			if vz.Status.Version == s.VizierVersion && vz.Status.VizierPhase == v1alpha1.VizierPhaseHealthy {
+				return nil
+			}
+		}
+	}
+}
+
+// saveUpgradeSnapshot snapshots the Vizier in the current kube context, which must be the cluster being updated.
+func saveUpgradeSnapshot(clusterID uuid.UUID) (string, error) {
+	kubeConfig := k8s.GetConfig()
+	clientset := k8s.GetClientset(kubeConfig)
+	vzClient, err := versioned.NewForConfig(kubeConfig)
+	if err != nil {
+		return "", err
+	}
+	ns, err := vizier.FindVizierNamespace(clientset)
+	if err != nil {
+		return "", err
+	}
+	if ns == "" {
+		return "", fmt.Errorf("could not find Vizier in the current kube context")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	s, err := vizier.TakeSnapshot(ctx, clientset, vzClient, ns)
+	if err != nil {
+		return "", err
+	}
+	if s.ClusterID != clusterID.String() {
+		return "", fmt.Errorf("the current kube context is cluster %s, not the cluster being updated (%s)", s.ClusterID, clusterID)
+	}
+
+	dir, err := utils.EnsureSnapshotDirPath()
+	if err != nil {
+		return "", err
+	}
+	return s.Save(dir)
+}
@@ -50,35 +50,66 @@ func init() {
 	RootCmd.PersistentFlags().BoolP("quiet", "q", false, "quiet mode")
 	viper.BindPFlag("quiet", RootCmd.PersistentFlags().Lookup("quiet"))
 
-	RootCmd.PersistentFlags().Bool("do_not_track", false, "do_not_track")
+	RootCmd.PersistentFlags().Bool("do_not_track", false, "Don't send analytics or error reports, or check Pixie Cloud for CLI updates. Also set with PX_DO_NOT_TRACK or DO_NOT_TRACK.")
 	viper.BindPFlag("do_not_track", RootCmd.PersistentFlags().Lookup("do_not_track"))
 
+	RootCmd.PersistentFlags().Bool("local_only", false, "Don't contact any Pixie hosted endpoint other than the configured cloud, such as the public script bundles and demo artifacts. Implies do_not_track. Also set with PX_LOCAL_ONLY.")
+	viper.BindPFlag("local_only", RootCmd.PersistentFlags().Lookup("local_only"))
+
+	RootCmd.PersistentFlags().String("artifact_keyring", "", "Path to an armored GPG public keyring. If set, downloaded Pixie artifacts must be signed by one of its keys.")
+	viper.BindPFlag("artifact_keyring", RootCmd.PersistentFlags().Lookup("artifact_keyring"))
+
+	RootCmd.PersistentFlags().Bool("skip_artifact_verification", false, "Use downloaded Pixie artifacts without verifying their checksum and signature. Not recommended.")
+	viper.BindPFlag("skip_artifact_verification", RootCmd.PersistentFlags().Lookup("skip_artifact_verification"))
+
 	RootCmd.PersistentFlags().String("direct_vizier_addr", "", "If set, connect directly to the Vizier service at the given address.")
 	viper.BindPFlag("direct_vizier_addr", RootCmd.PersistentFlags().Lookup("direct_vizier_addr"))
 
 	RootCmd.PersistentFlags().String("direct_vizier_key", "", "Should be set if direct_vizier_addr is set, the key to authenticate whether the user has permissions to connect to the Vizier service.")
 	viper.BindPFlag("direct_vizier_key", RootCmd.PersistentFlags().Lookup("direct_vizier_key"))
 
+	RootCmd.PersistentFlags().String("direct_vizier_client_cert", "", "Path to a client certificate to present to the Vizier service, when connecting with direct_vizier_addr.")
+	viper.BindPFlag("direct_vizier_client_cert", RootCmd.PersistentFlags().Lookup("direct_vizier_client_cert"))
+
+	RootCmd.PersistentFlags().String("direct_vizier_client_key", "", "Path to the key for direct_vizier_client_cert.")
+	viper.BindPFlag("direct_vizier_client_key", RootCmd.PersistentFlags().Lookup("direct_vizier_client_key"))
+
+	RootCmd.PersistentFlags().String("direct_vizier_ca_cert", "", "Path to a CA certificate to verify the Vizier service with, when connecting with direct_vizier_addr.")
+	viper.BindPFlag("direct_vizier_ca_cert", RootCmd.PersistentFlags().Lookup("direct_vizier_ca_cert"))
+
+	RootCmd.PersistentFlags().StringSlice("direct_vizier_pinned_keys", []string{}, "Base64 encoded SHA-256 hashes of the Vizier public keys to trust, when connecting with direct_vizier_addr.")
+	viper.BindPFlag("direct_vizier_pinned_keys", RootCmd.PersistentFlags().Lookup("direct_vizier_pinned_keys"))
+
 	RootCmd.AddCommand(VersionCmd)
 	RootCmd.AddCommand(AuthCmd)
 	RootCmd.AddCommand(CollectLogsCmd)
 	RootCmd.AddCommand(CreateCloudCertsCmd)
+	RootCmd.AddCommand(CloudCmd)
 	RootCmd.AddCommand(DemoCmd)
 	RootCmd.AddCommand(DeployCmd)
+	RootCmd.AddCommand(ArtifactsCmd)
 	RootCmd.AddCommand(DeleteCmd)
 	RootCmd.AddCommand(UpdateCmd)
+	RootCmd.AddCommand(RollbackCmd)
+	RootCmd.AddCommand(StatusCmd)
 	RootCmd.AddCommand(RunCmd)
 	RootCmd.AddCommand(LiveCmd)
+	RootCmd.AddCommand(TapCmd)
+	RootCmd.AddCommand(ProfileCmd)
+	RootCmd.AddCommand(CompareCmd)
 	RootCmd.AddCommand(GetCmd)
 	RootCmd.AddCommand(ScriptCmd)
 	RootCmd.AddCommand(CreateBundle)
 	RootCmd.AddCommand(DeployKeyCmd)
 	RootCmd.AddCommand(APIKeyCmd)
+	RootCmd.AddCommand(FleetCmd)
+	RootCmd.AddCommand(RegistryCmd)
+	RootCmd.AddCommand(HistoryCmd)
 	RootCmd.AddCommand(DebugCmd)
+	RootCmd.AddCommand(DoctorCmd)
 
 	RootCmd.PersistentFlags().MarkHidden("cloud_addr")
 	RootCmd.PersistentFlags().MarkHidden("dev_cloud_namespace")
-	RootCmd.PersistentFlags().MarkHidden("do_not_track")
 
 	viper.AutomaticEnv()
 	viper.SetEnvPrefix("PX")
@@ -91,6 +122,13 @@ func init() {
 	viper.BindEnv("vizier_version", "PX_VIZIER_VERSION", "PL_VIZIER_VERSION")
 	viper.BindEnv("direct_vizier_key", "PX_DIRECT_VIZIER_KEY")
 	viper.BindEnv("direct_vizier_addr", "PX_DIRECT_VIZIER_ADDR")
+	viper.BindEnv("direct_vizier_client_cert", "PX_DIRECT_VIZIER_CLIENT_CERT")
+	viper.BindEnv("direct_vizier_client_key", "PX_DIRECT_VIZIER_CLIENT_KEY")
+	viper.BindEnv("direct_vizier_ca_cert", "PX_DIRECT_VIZIER_CA_CERT")
+	viper.BindEnv("direct_vizier_pinned_keys", "PX_DIRECT_VIZIER_PINNED_KEYS")
+	viper.BindEnv("artifact_keyring", "PX_ARTIFACT_KEYRING")
+	viper.BindEnv("do_not_track", "PX_DO_NOT_TRACK", "DO_NOT_TRACK")
+	viper.BindEnv("local_only", "PX_LOCAL_ONLY")
 
 	viper.BindPFlags(pflag.CommandLine)
 
@@ -157,7 +195,11 @@ var RootCmd = &cobra.Command{
 		if p == UpdateCmd {
 			return
 		}
-		versionStr := update.UpdatesAvailable(viper.GetString("cloud_addr"))
+		// Checking for updates probes Pixie Cloud, which users who opted out of telemetry don't want.
+		versionStr := ""
+		if !pxconfig.DoNotTrack() {
+			versionStr = update.UpdatesAvailable(viper.GetString("cloud_addr"))
+		}
 		if versionStr != "" {
 			cmdName := "<NONE>"
 			if p != nil {
@@ -207,6 +249,7 @@ func checkAuthForCmd(c *cobra.Command) {
 			utils.Errorf("Failed to authenticate. Please retry `px auth login`.")
 			os.Exit(1)
 		}
+		printOrgAnnouncement()
 	default:
 	}
 }
@@ -218,6 +261,7 @@ func Execute() {
 			UserId: pxconfig.Cfg().UniqueClientID,
 			Event:  "Exec Error",
 		})
+		printOrgSupportInfo()
 		utils.WithError(err).Fatal("Error executing command")
 	}
 }
@@ -32,6 +32,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"px.dev/pixie/src/api/go/pxapi"
 	"px.dev/pixie/src/cloud/api/ptproxy"
 	"px.dev/pixie/src/pixie_cli/pkg/utils"
 	"px.dev/pixie/src/pixie_cli/pkg/vizier"
@@ -47,8 +48,12 @@ func init() {
 	RunCmd.Flags().StringP("cluster", "c", "", "ID of the cluster to run on. "+
 		"Use 'px get viziers', or visit Admin console: work.withpixie.ai/admin, to find the ID")
 	RunCmd.Flags().MarkHidden("all-clusters")
+	RunCmd.Flags().String("fleet", "", "Name of a fleet to run the script across. See 'px fleet list'")
 
 	RunCmd.Flags().StringP("bundle", "b", "", "Path/URL to bundle file")
+	RunCmd.Flags().Bool("explain", false, "Also return the distributed query plan for the script")
+	RunCmd.Flags().Bool("analyze", false, "Also return the query plan annotated with per-operator execution stats")
+	RunCmd.Flags().Int64("max_rows", 10000, "The maximum number of rows to output for each table. Tables with more rows are truncated with a warning. If 0, the limit of the script or Vizier applies")
 
 	RunCmd.SetHelpFunc(func(command *cobra.Command, args []string) {
 		viper.BindPFlag("bundle", command.Flags().Lookup("bundle"))
@@ -176,18 +181,33 @@ func createNewCobraCommand() *cobra.Command {
 				}
 			}
 
+			explain, _ := cmd.Flags().GetBool("explain")
+			analyze, _ := cmd.Flags().GetBool("analyze")
+			switch {
+			case analyze:
+				execScript.ScriptString = pxapi.ApplyScriptOptions(execScript.ScriptString, pxapi.WithAnalyze())
+			case explain:
+				execScript.ScriptString = pxapi.ApplyScriptOptions(execScript.ScriptString, pxapi.WithExplain())
+			}
+
 			allClusters, _ := cmd.Flags().GetBool("all-clusters")
 			selectedCluster, _ := cmd.Flags().GetString("cluster")
+			fleetName, _ := cmd.Flags().GetString("fleet")
 			clusterID := uuid.FromStringOrNil(selectedCluster)
 
-			if !allClusters && clusterID == uuid.Nil && directVzAddr == "" {
+			if !allClusters && fleetName == "" && clusterID == uuid.Nil && directVzAddr == "" {
 				clusterID, err = vizier.GetCurrentVizier(cloudAddr)
 				if err != nil {
 					utils.WithError(err).Fatal("Could not fetch healthy vizier")
 				}
 			}
 
-			conns := vizier.MustConnectVizier(cloudAddr, allClusters, clusterID, directVzAddr, directVzKey)
+			var conns []*vizier.Connector
+			if fleetName != "" {
+				conns = mustConnectFleet(cloudAddr, fleetName)
+			} else {
+				conns = vizier.MustConnectVizier(cloudAddr, allClusters, clusterID, directVzAddr, directVzKey)
+			}
 			useEncryption, _ := cmd.Flags().GetBool("e2e_encryption")
 			if directVzAddr != "" {
 				// There is no e2e encryption for direct mode.
@@ -197,7 +217,8 @@ func createNewCobraCommand() *cobra.Command {
 			// Support Ctrl+C to cancel a query.
 			ctx, cleanup := utils.WithSignalCancellable(context.Background())
 			defer cleanup()
-			err = vizier.RunScriptAndOutputResults(ctx, conns, execScript, format, useEncryption)
+			maxRows, _ := cmd.Flags().GetInt64("max_rows")
+			err = vizier.RunScriptAndOutputResults(ctx, conns, execScript, format, useEncryption, maxRows)
 
 			if err != nil {
 				vzErr, ok := err.(*vizier.ScriptExecutionError)
@@ -211,8 +232,8 @@ func createNewCobraCommand() *cobra.Command {
 				}
 			}
 
-			// Don't print cloudAddr live view link for direct mode.
-			if directVzAddr != "" {
+			// Don't print cloudAddr live view link for direct mode or when running across a fleet.
+			if directVzAddr != "" || fleetName != "" {
 				return
 			}
 
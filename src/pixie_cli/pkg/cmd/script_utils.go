@@ -29,8 +29,10 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 
+	"px.dev/pixie/src/api/proto/cloudpb"
 	"px.dev/pixie/src/pixie_cli/pkg/auth"
 	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
 	"px.dev/pixie/src/utils/script"
 )
 
@@ -48,9 +50,16 @@ func mustCreateBundleReader() *script.BundleManager {
 }
 
 func createBundleReader() (*script.BundleManager, error) {
-	bundleFile := viper.GetString("bundle")
-	if bundleFile == "" {
-		bundleFile = defaultBundleFile
+	bundleFiles := []string{defaultBundleFile, ossBundleFile}
+	if bundleFile := viper.GetString("bundle"); bundleFile != "" {
+		bundleFiles = []string{bundleFile, ossBundleFile}
+		if pxconfig.LocalOnly() {
+			bundleFiles = []string{bundleFile}
+		}
+	} else if pxconfig.LocalOnly() {
+		// The public bundles are hosted by Pixie, so only the org's registry scripts are available.
+		log.Debug("Skipping the public script bundles in local only mode")
+		bundleFiles = nil
 	}
 	direct := viper.GetString("direct_vizier_addr")
 
@@ -62,13 +71,45 @@ func createBundleReader() (*script.BundleManager, error) {
 		orgName = authInfo.OrgName
 	}
 
-	br, err := script.NewBundleManagerWithOrg([]string{bundleFile, ossBundleFile}, orgID, orgName)
+	br, err := script.NewBundleManagerWithOrg(bundleFiles, orgID, orgName)
 	if err != nil {
 		return nil, err
 	}
+	if direct == "" && orgName != "" {
+		addRegistryScripts(br, orgName)
+	}
 	return br, nil
 }
 
+// addRegistryScripts adds the published scripts in the org's script registry to the bundle, named
+// "<org name>/<script name>". Failing to reach the registry isn't fatal, since the public scripts
+// can still be used.
+func addRegistryScripts(br *script.BundleManager, orgName string) {
+	client, ctx := getRegistryClientAndContext(viper.GetString("cloud_addr"))
+	resp, err := client.GetRegistryBundle(ctx, &cloudpb.GetRegistryBundleRequest{})
+	if err != nil {
+		log.WithError(err).Debug("Failed to load scripts from the script registry")
+		return
+	}
+	for _, s := range resp.Scripts {
+		vis, err := script.ParseVisSpec(s.Vis)
+		if err != nil {
+			log.WithError(err).Debugf("Skipping registry script '%s' with invalid vis spec", s.Name)
+			continue
+		}
+		err = br.AddScript(&script.ExecutableScript{
+			ScriptName:   fmt.Sprintf("%s/%s", orgName, s.Name),
+			ShortDoc:     s.ShortDoc,
+			LongDoc:      s.LongDoc,
+			Vis:          vis,
+			ScriptString: s.Pxl,
+		})
+		if err != nil {
+			log.WithError(err).Debugf("Skipping registry script '%s'", s.Name)
+		}
+	}
+}
+
 func listBundleScripts(br *script.BundleManager, format string) {
 	w := components.CreateStreamWriter(format, os.Stdout)
 	defer w.Finish()
@@ -0,0 +1,127 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"px.dev/pixie/src/operator/client/versioned"
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/pixie_cli/pkg/vizier"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func init() {
+	StatusCmd.Flags().StringP("namespace", "n", "", "The namespace where Pixie is located")
+	StatusCmd.Flags().StringP("output", "o", "", "Output format: one of: json|table")
+}
+
+// StatusCmd is the "status" command, which reports the status of the Vizier in the current kube context, as
+// recorded in its Vizier resource by the operator.
+var StatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Get the status of the Vizier in the current kube context",
+	Run: func(cmd *cobra.Command, args []string) {
+		ns, _ := cmd.Flags().GetString("namespace")
+		format, _ := cmd.Flags().GetString("output")
+		format = strings.ToLower(format)
+
+		kubeConfig := k8s.GetConfig()
+		vzClient, err := versioned.NewForConfig(kubeConfig)
+		if err != nil {
+			log.WithError(err).Fatal("Could not start vizier client")
+		}
+		if ns == "" {
+			ns = vizier.MustFindVizierNamespace()
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		vzs, err := vzClient.PxV1alpha1().Viziers(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to get the Vizier")
+		}
+		if len(vzs.Items) == 0 {
+			utils.Fatalf("Could not find a Vizier in namespace %s", ns)
+		}
+
+		w := components.CreateStreamWriter(format, os.Stdout)
+		w.SetHeader("viziers", []string{"Name", "Version", "Status", "Reason", "Status Message"})
+		for _, vz := range vzs.Items {
+			_ = w.Write([]interface{}{vz.Name, prettyVersion(vz.Status.Version), vz.Status.VizierPhase,
+				vz.Status.VizierReason, vz.Status.Message})
+		}
+		w.Finish()
+
+		w = components.CreateStreamWriter(format, os.Stdout)
+		w.SetHeader("exports", []string{"Name", "Failing Exports", "Pending Retries", "Dead Letters",
+			"Dropped Dead Letters", "Last Failure", "Last Error"})
+		for _, vz := range vzs.Items {
+			h := vz.Status.ExportHealth
+			if h == nil {
+				continue
+			}
+			var lastFailure interface{}
+			if h.LastFailureTime != nil {
+				lastFailure = h.LastFailureTime.Time
+				if format == "" || format == "table" {
+					lastFailure = humanize.Time(h.LastFailureTime.Time)
+				}
+			}
+			_ = w.Write([]interface{}{vz.Name, h.FailingExports, h.PendingRetries, h.DeadLetters,
+				h.DroppedDeadLetters, lastFailure, h.LastError})
+		}
+		w.Finish()
+
+		w = components.CreateStreamWriter(format, os.Stdout)
+		w.SetHeader("datastore", []string{"Name", "Backend", "Size", "In Use", "Quota", "Size Alarm", "Alarms",
+			"Last Compaction", "Last Error"})
+		for _, vz := range vzs.Items {
+			h := vz.Status.DatastoreHealth
+			if h == nil {
+				continue
+			}
+			var size, inUse, quota, lastCompaction interface{}
+			size, inUse, quota = h.SizeBytes, h.InUseBytes, h.QuotaBytes
+			if h.LastCompactionTime != nil {
+				lastCompaction = h.LastCompactionTime.Time
+			}
+			if format == "" || format == "table" {
+				size = humanize.IBytes(uint64(h.SizeBytes))
+				inUse = humanize.IBytes(uint64(h.InUseBytes))
+				quota = humanize.IBytes(uint64(h.QuotaBytes))
+				if h.LastCompactionTime != nil {
+					lastCompaction = humanize.Time(h.LastCompactionTime.Time)
+				}
+			}
+			_ = w.Write([]interface{}{vz.Name, h.Backend, size, inUse, quota, h.SizeAlarm,
+				strings.Join(h.Alarms, ","), lastCompaction, h.LastError})
+		}
+		w.Finish()
+	},
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	apiutils "px.dev/pixie/src/api/go/pxapi/utils"
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/cloud/api/ptproxy"
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/pixie_cli/pkg/vizier"
+)
+
+func init() {
+	TapCmd.Flags().StringP("protocol", "p", "http", fmt.Sprintf("The protocol to stream the events of. One of: %s",
+		strings.Join(vizier.TapProtocolNames(), "|")))
+	TapCmd.Flags().String("pod", "", "Only stream the events of pods whose name contains this value")
+	TapCmd.Flags().StringP("namespace", "n", "", "Only stream the events of pods in this namespace")
+	TapCmd.Flags().String("service", "", "Only stream the events of pods whose service contains this value")
+	TapCmd.Flags().String("since", "-10s", "How far back to start the stream, for example -1m")
+	TapCmd.Flags().StringSliceP("fields", "f", nil, "The fields to show, in addition to the time and pod. Defaults to the main fields of the protocol")
+	TapCmd.Flags().BoolP("e2e_encryption", "e", true, "Enable E2E encryption")
+	TapCmd.Flags().StringP("cluster", "c", "", "ID of the cluster to run on. "+
+		"Use 'px get viziers', or visit Admin console: work.withpixie.ai/admin, to find the ID")
+}
+
+// TapCmd is the "tap" command.
+var TapCmd = &cobra.Command{
+	Use:   "tap",
+	Short: "Stream the raw protocol events of pods in real time",
+	Example: `  px tap --protocol http --pod frontend
+  px tap -p mysql -n sock-shop -f req_cmd,req_body,latency`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cloudAddr := viper.GetString("cloud_addr")
+		directVzAddr := viper.GetString("direct_vizier_addr")
+		directVzKey := viper.GetString("direct_vizier_key")
+
+		opts := &vizier.TapOptions{}
+		opts.Protocol, _ = cmd.Flags().GetString("protocol")
+		opts.Pod, _ = cmd.Flags().GetString("pod")
+		opts.Namespace, _ = cmd.Flags().GetString("namespace")
+		opts.Service, _ = cmd.Flags().GetString("service")
+		opts.Since, _ = cmd.Flags().GetString("since")
+		opts.Fields, _ = cmd.Flags().GetStringSlice("fields")
+		execScript, err := vizier.TapScript(opts)
+		if err != nil {
+			utils.WithError(err).Fatal("Invalid tap options")
+		}
+
+		selectedCluster, _ := cmd.Flags().GetString("cluster")
+		clusterID := uuid.FromStringOrNil(selectedCluster)
+		if clusterID == uuid.Nil && directVzAddr == "" {
+			clusterID, err = vizier.GetCurrentVizier(cloudAddr)
+			if err != nil {
+				utils.WithError(err).Fatal("Could not fetch healthy vizier")
+			}
+		}
+		conns := vizier.MustConnectVizier(cloudAddr, false, clusterID, directVzAddr, directVzKey)
+
+		useEncryption, _ := cmd.Flags().GetBool("e2e_encryption")
+		// There is no e2e encryption for direct mode.
+		useEncryption = useEncryption && directVzAddr == ""
+		var encOpts, decOpts *vizierpb.ExecuteScriptRequest_EncryptionOptions
+		if useEncryption {
+			encOpts, decOpts, err = apiutils.CreateEncryptionOptions()
+			if err != nil {
+				utils.WithError(err).Fatal("Failed to create encryption options")
+			}
+		}
+
+		// Support Ctrl+C to stop the stream.
+		ctx, cleanup := utils.WithSignalCancellable(context.Background())
+		defer cleanup()
+
+		resp, err := vizier.RunScript(ctx, conns, execScript, encOpts)
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to start the stream")
+		}
+		// The in memory format turns off the formatting of the adapter, so that the tap writer
+		// gets the values as they are.
+		tw := vizier.NewStreamOutputAdapterWithFactory(ctx, resp, vizier.FormatInMemory, decOpts,
+			func(md *vizierpb.ExecuteScriptResponse_MetaData) components.OutputStreamWriter {
+				return vizier.NewTapWriter(os.Stdout, md.MetaData.Relation)
+			})
+		err = tw.Finish()
+		if err != nil {
+			vzErr, ok := err.(*vizier.ScriptExecutionError)
+			switch {
+			case ok && vzErr.Code() == vizier.CodeCanceled:
+				utils.Info("Stream was stopped. Exiting.")
+			case err == ptproxy.ErrNotAvailable:
+				utils.WithError(err).Fatal("Cannot stream events")
+			default:
+				utils.WithError(err).Fatal("Failed to stream events")
+			}
+		}
+	},
+}
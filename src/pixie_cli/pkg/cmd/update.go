@@ -40,6 +40,7 @@ import (
 	"px.dev/pixie/src/pixie_cli/pkg/vizier"
 	version "px.dev/pixie/src/shared/goversion"
 	utils2 "px.dev/pixie/src/utils"
+	"px.dev/pixie/src/utils/shared/artifacts"
 )
 
 func init() {
@@ -53,12 +54,14 @@ func init() {
 	VizierUpdateCmd.Flags().MarkHidden("vizier_version")
 	VizierUpdateCmd.Flags().BoolP("redeploy_etcd", "e", false, "Whether or not to redeploy etcd during the update")
 	VizierUpdateCmd.Flags().StringP("cluster", "c", "", "Run only on selected cluster")
+	VizierUpdateCmd.Flags().Bool("snapshot", false, "Record the current Vizier version, spec and secrets before updating, so the update can be undone with px rollback")
 }
 
 // UpdateCmd is the "update" sub-command of the CLI.
 var UpdateCmd = &cobra.Command{
-	Use:   "update",
-	Short: "Update Pixie/CLI",
+	Use:     "update",
+	Aliases: []string{"upgrade"},
+	Short:   "Update Pixie/CLI",
 	Run: func(cmd *cobra.Command, args []string) {
 		utils.Info("Nothing here... Please execute one of the subcommands")
 		cmd.Help()
@@ -78,6 +81,7 @@ var VizierUpdateCmd = &cobra.Command{
 		versionString := viper.GetString("vizier_version")
 		cloudAddr := viper.GetString("cloud_addr")
 		redeployEtcd := viper.GetBool("redeploy_etcd")
+		snapshot, _ := cmd.Flags().GetBool("snapshot")
 
 		clusterID := uuid.Nil
 		clusterStr, _ := cmd.Flags().GetString("cluster")
@@ -142,6 +146,14 @@ var VizierUpdateCmd = &cobra.Command{
 				Set("cluster_status", clusterInfo.Status.String()),
 		})
 
+		if snapshot {
+			path, err := saveUpgradeSnapshot(clusterID)
+			if err != nil {
+				utils.WithError(err).Fatal("Failed to snapshot Vizier before updating")
+			}
+			utils.Infof("Saved snapshot of version %s to %s. Run `px rollback` to restore it.", clusterInfo.VizierVersion, path)
+		}
+
 		utils.Infof("Updating to version: %s", versionString)
 
 		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
@@ -220,7 +232,7 @@ var CLIUpdateCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		selectedVersion := viper.GetString("cli_version")
 
-		updater := update.NewCLIUpdater(viper.GetString("cloud_addr"))
+		updater := update.NewCLIUpdater(viper.GetString("cloud_addr"), mustGetArtifactVerificationOptions())
 		currVersion := version.GetVersion()
 		if len(selectedVersion) == 0 {
 			// Not specified try to get available.
@@ -270,6 +282,9 @@ Update anyway?`, false)
 
 func mustInstallVersion(u *update.CLIUpdater, v string) {
 	err := u.UpdateSelf(v)
+	if artifacts.IsVerificationError(err) {
+		utils.WithError(err).Fatal("Downloaded CLI failed verification. To install it anyway, rerun with --skip_artifact_verification")
+	}
 	if err != nil {
 		utils.WithError(err).Fatal("Failed to apply update.")
 	}
@@ -0,0 +1,218 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package compare runs the same HTTP statistics over two targets, such as two time windows or two
+// deployments of a service, and diffs the statistics of every endpoint.
+package compare
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"px.dev/pixie/src/utils/script"
+)
+
+// Target is one side of a comparison: the requests served by a set of pods during a time window.
+type Target struct {
+	// Start and End are the time window, as relative or absolute times that PxL accepts. An empty
+	// End means now.
+	Start string
+	End   string
+	// Namespace, Service and Pod filter the requests to the matching pods. They match if they
+	// contain the value.
+	Namespace string
+	Service   string
+	Pod       string
+}
+
+func (t *Target) workload() string {
+	return strings.Join([]string{t.Namespace, t.Service, t.Pod}, "/")
+}
+
+// SameWorkload returns whether the two targets select the same pods, in which case the endpoints
+// are also qualified by service. Otherwise the targets are different deployments, and their
+// endpoints are matched by path alone.
+func SameWorkload(a, b *Target) bool {
+	return a.workload() == b.workload()
+}
+
+// Stats are the statistics of the requests served by an endpoint.
+type Stats struct {
+	// LatencyP50 and LatencyP99 are in nanoseconds.
+	LatencyP50 float64
+	LatencyP99 float64
+	// ErrorRate is the fraction of requests with a response status of 400 or above.
+	ErrorRate float64
+	Requests  int64
+}
+
+// Key identifies an endpoint.
+type Key struct {
+	// Service is empty when the targets are different deployments.
+	Service  string
+	Method   string
+	Endpoint string
+}
+
+func (k Key) less(o Key) bool {
+	if k.Service != o.Service {
+		return k.Service < o.Service
+	}
+	if k.Endpoint != o.Endpoint {
+		return k.Endpoint < o.Endpoint
+	}
+	return k.Method < o.Method
+}
+
+// StatsTable is the name of the table displayed by the statistics script.
+const StatsTable = "endpoint_stats"
+
+// Script returns the script that computes the statistics of every endpoint served by the target.
+func Script(t *Target, byService bool) (*script.ExecutableScript, error) {
+	if t.Start == "" {
+		return nil, errors.New("the start of the time window is required")
+	}
+
+	var b strings.Builder
+	b.WriteString("import px\n")
+	if t.End != "" {
+		fmt.Fprintf(&b, "df = px.DataFrame(table='http_events', start_time=%s, end_time=%s)\n",
+			strconv.Quote(t.Start), strconv.Quote(t.End))
+	} else {
+		fmt.Fprintf(&b, "df = px.DataFrame(table='http_events', start_time=%s)\n", strconv.Quote(t.Start))
+	}
+	// Only the requests served by the pods, not the ones that they sent.
+	b.WriteString("df = df[df.trace_role == 2]\n")
+	b.WriteString("df.namespace = df.ctx['namespace']\n")
+	b.WriteString("df.service = df.ctx['service']\n")
+	b.WriteString("df.pod = df.ctx['pod']\n")
+	for _, f := range []struct{ col, val string }{
+		{"namespace", t.Namespace},
+		{"service", t.Service},
+		{"pod", t.Pod},
+	} {
+		if f.val != "" {
+			fmt.Fprintf(&b, "df = df[px.contains(df.%s, %s)]\n", f.col, strconv.Quote(f.val))
+		}
+	}
+	// Drops the query string, so that the requests to an endpoint are grouped together.
+	b.WriteString("df.endpoint = px.pluck(px.uri_parse(df.req_path), 'path')\n")
+	b.WriteString("df.failure = df.resp_status >= 400\n")
+
+	groupBy := "['req_method', 'endpoint']"
+	cols := "['req_method', 'endpoint', 'latency_p50', 'latency_p99', 'error_rate', 'requests']"
+	if byService {
+		b.WriteString("df = df[df.service != '']\n")
+		groupBy = "['service', 'req_method', 'endpoint']"
+		cols = "['service', " + cols[1:]
+	}
+	fmt.Fprintf(&b, "df = df.groupby(%s).agg(\n", groupBy)
+	b.WriteString("    latency_quantiles=('latency', px.quantiles),\n")
+	b.WriteString("    error_rate=('failure', px.mean),\n")
+	b.WriteString("    requests=('latency', px.count),\n")
+	b.WriteString(")\n")
+	b.WriteString("df.latency_p50 = px.pluck_float64(df.latency_quantiles, 'p50')\n")
+	b.WriteString("df.latency_p99 = px.pluck_float64(df.latency_quantiles, 'p99')\n")
+	fmt.Fprintf(&b, "px.display(df[%s], '%s')\n", cols, StatsTable)
+
+	return &script.ExecutableScript{
+		ScriptName:   "px/compare",
+		ScriptString: b.String(),
+		IsLocal:      true,
+	}, nil
+}
+
+// Collector collects the rows of the statistics table. It implements
+// components.OutputStreamWriter, and expects unformatted values.
+type Collector struct {
+	stats  map[Key]*Stats
+	colIdx map[string]int
+	err    error
+}
+
+// NewCollector creates a collector.
+func NewCollector() *Collector {
+	return &Collector{stats: make(map[Key]*Stats)}
+}
+
+// SetHeader sets the names of the columns.
+func (c *Collector) SetHeader(id string, headerValues []string) {
+	c.colIdx = make(map[string]int)
+	for i, h := range headerValues {
+		c.colIdx[h] = i
+	}
+	for _, col := range []string{"req_method", "endpoint", "latency_p50", "latency_p99", "error_rate", "requests"} {
+		if _, ok := c.colIdx[col]; !ok {
+			c.err = fmt.Errorf("endpoint stats table is missing column %q", col)
+		}
+	}
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch u := v.(type) {
+	case float64:
+		return u, nil
+	case int64:
+		return float64(u), nil
+	default:
+		return 0, fmt.Errorf("unexpected value %v", v)
+	}
+}
+
+// Write adds the statistics of a single endpoint.
+func (c *Collector) Write(data []interface{}) error {
+	if c.err != nil {
+		return c.err
+	}
+	k := Key{
+		Method:   fmt.Sprintf("%v", data[c.colIdx["req_method"]]),
+		Endpoint: fmt.Sprintf("%v", data[c.colIdx["endpoint"]]),
+	}
+	if i, ok := c.colIdx["service"]; ok {
+		k.Service = fmt.Sprintf("%v", data[i])
+	}
+
+	s := &Stats{}
+	var err error
+	if s.LatencyP50, err = toFloat(data[c.colIdx["latency_p50"]]); err != nil {
+		return err
+	}
+	if s.LatencyP99, err = toFloat(data[c.colIdx["latency_p99"]]); err != nil {
+		return err
+	}
+	if s.ErrorRate, err = toFloat(data[c.colIdx["error_rate"]]); err != nil {
+		return err
+	}
+	requests, err := toFloat(data[c.colIdx["requests"]])
+	if err != nil {
+		return err
+	}
+	s.Requests = int64(requests)
+	c.stats[k] = s
+	return nil
+}
+
+// Finish is a no-op, the statistics are returned by Stats.
+func (c *Collector) Finish() {}
+
+// Stats returns the collected statistics of every endpoint.
+func (c *Collector) Stats() map[Key]*Stats {
+	return c.stats
+}
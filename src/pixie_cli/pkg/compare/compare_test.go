@@ -0,0 +1,90 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package compare_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/pixie_cli/pkg/compare"
+)
+
+func TestScript(t *testing.T) {
+	s, err := compare.Script(&compare.Target{Start: "-1h", End: "-30m", Namespace: "sock-shop"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, `import px
+df = px.DataFrame(table='http_events', start_time="-1h", end_time="-30m")
+df = df[df.trace_role == 2]
+df.namespace = df.ctx['namespace']
+df.service = df.ctx['service']
+df.pod = df.ctx['pod']
+df = df[px.contains(df.namespace, "sock-shop")]
+df.endpoint = px.pluck(px.uri_parse(df.req_path), 'path')
+df.failure = df.resp_status >= 400
+df = df[df.service != '']
+df = df.groupby(['service', 'req_method', 'endpoint']).agg(
+    latency_quantiles=('latency', px.quantiles),
+    error_rate=('failure', px.mean),
+    requests=('latency', px.count),
+)
+df.latency_p50 = px.pluck_float64(df.latency_quantiles, 'p50')
+df.latency_p99 = px.pluck_float64(df.latency_quantiles, 'p99')
+px.display(df[['service', 'req_method', 'endpoint', 'latency_p50', 'latency_p99', 'error_rate', 'requests']], 'endpoint_stats')
+`, s.ScriptString)
+
+	s, err = compare.Script(&compare.Target{Start: "-30m", Pod: "carts-canary"}, false)
+	require.NoError(t, err)
+	assert.Contains(t, s.ScriptString, `df = px.DataFrame(table='http_events', start_time="-30m")`)
+	assert.Contains(t, s.ScriptString, `df = df[px.contains(df.pod, "carts-canary")]`)
+	assert.Contains(t, s.ScriptString, "df.groupby(['req_method', 'endpoint'])")
+	assert.NotContains(t, s.ScriptString, "'service', 'req_method'")
+
+	_, err = compare.Script(&compare.Target{}, true)
+	assert.Error(t, err)
+}
+
+func TestSameWorkload(t *testing.T) {
+	assert.True(t, compare.SameWorkload(
+		&compare.Target{Start: "-1h", End: "-30m", Service: "carts"},
+		&compare.Target{Start: "-30m", Service: "carts"}))
+	assert.False(t, compare.SameWorkload(
+		&compare.Target{Start: "-30m", Pod: "carts-stable"},
+		&compare.Target{Start: "-30m", Pod: "carts-canary"}))
+}
+
+func TestCollector(t *testing.T) {
+	c := compare.NewCollector()
+	c.SetHeader("endpoint_stats", []string{"service", "req_method", "endpoint", "latency_p50", "latency_p99", "error_rate", "requests"})
+	require.NoError(t, c.Write([]interface{}{"sock-shop/carts", "GET", "/carts", 1e6, int64(5000000), 0.25, int64(40)}))
+	c.Finish()
+
+	assert.Equal(t, map[compare.Key]*compare.Stats{
+		{Service: "sock-shop/carts", Method: "GET", Endpoint: "/carts"}: {
+			LatencyP50: 1e6, LatencyP99: 5e6, ErrorRate: 0.25, Requests: 40,
+		},
+	}, c.Stats())
+}
+
+func TestCollector_MissingColumn(t *testing.T) {
+	c := compare.NewCollector()
+	c.SetHeader("endpoint_stats", []string{"req_method", "endpoint"})
+	assert.Error(t, c.Write([]interface{}{"GET", "/carts"}))
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package compare
+
+import (
+	"sort"
+	"time"
+)
+
+// Status is how an endpoint changed between the baseline and the candidate.
+type Status string
+
+const (
+	// StatusChanged means the endpoint served requests in both targets.
+	StatusChanged Status = "changed"
+	// StatusNew means the endpoint only served requests in the candidate.
+	StatusNew Status = "new"
+	// StatusRemoved means the endpoint only served requests in the baseline.
+	StatusRemoved Status = "removed"
+)
+
+// Diff is the change of the statistics of an endpoint. Baseline or Candidate is nil if the
+// endpoint didn't serve any requests in that target.
+type Diff struct {
+	Key
+	Status    Status
+	Baseline  *Stats
+	Candidate *Stats
+}
+
+// Diffs returns the change of every endpoint served by either target, ordered by endpoint.
+func Diffs(baseline, candidate map[Key]*Stats) []*Diff {
+	diffs := make([]*Diff, 0, len(candidate))
+	for k, c := range candidate {
+		d := &Diff{Key: k, Status: StatusNew, Candidate: c}
+		if b, ok := baseline[k]; ok {
+			d.Status = StatusChanged
+			d.Baseline = b
+		}
+		diffs = append(diffs, d)
+	}
+	for k, b := range baseline {
+		if _, ok := candidate[k]; !ok {
+			diffs = append(diffs, &Diff{Key: k, Status: StatusRemoved, Baseline: b})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Key.less(diffs[j].Key)
+	})
+	return diffs
+}
+
+// LatencyP99Increase returns the relative increase of the p99 latency, in percent. It is
+// false if the endpoint didn't serve requests in both targets.
+func (d *Diff) LatencyP99Increase() (float64, bool) {
+	if d.Baseline == nil || d.Candidate == nil || d.Baseline.LatencyP99 == 0 {
+		return 0, false
+	}
+	return 100 * (d.Candidate.LatencyP99 - d.Baseline.LatencyP99) / d.Baseline.LatencyP99, true
+}
+
+// ErrorRateIncrease returns the increase of the error rate, in percentage points. A new endpoint
+// is compared against an error rate of 0.
+func (d *Diff) ErrorRateIncrease() (float64, bool) {
+	if d.Candidate == nil {
+		return 0, false
+	}
+	base := 0.0
+	if d.Baseline != nil {
+		base = d.Baseline.ErrorRate
+	}
+	return 100 * (d.Candidate.ErrorRate - base), true
+}
+
+// Thresholds are the largest changes of an endpoint that are not a regression. A threshold of
+// zero disables the check.
+type Thresholds struct {
+	// MaxLatencyP99Increase is in percent of the baseline p99 latency.
+	MaxLatencyP99Increase float64
+	// MaxErrorRateIncrease is in percentage points.
+	MaxErrorRateIncrease float64
+}
+
+// Regressions returns the diffs that exceed the thresholds.
+func Regressions(diffs []*Diff, t Thresholds) []*Diff {
+	var regressions []*Diff
+	for _, d := range diffs {
+		if inc, ok := d.LatencyP99Increase(); ok && t.MaxLatencyP99Increase > 0 && inc > t.MaxLatencyP99Increase {
+			regressions = append(regressions, d)
+			continue
+		}
+		if inc, ok := d.ErrorRateIncrease(); ok && t.MaxErrorRateIncrease > 0 && inc > t.MaxErrorRateIncrease {
+			regressions = append(regressions, d)
+		}
+	}
+	return regressions
+}
+
+// Header returns the columns of the rows returned by Row.
+func Header(byService bool) []string {
+	h := []string{
+		"method", "endpoint", "status",
+		"baseline_p50", "candidate_p50", "baseline_p99", "candidate_p99", "p99_change_%",
+		"baseline_errors_%", "candidate_errors_%", "errors_change_pp",
+		"baseline_requests", "candidate_requests",
+	}
+	if byService {
+		h = append([]string{"service"}, h...)
+	}
+	return h
+}
+
+// Row returns the values of the diff, in the order of Header. The values that are missing
+// because the endpoint didn't serve requests in one of the targets are nil.
+func (d *Diff) Row(byService bool) []interface{} {
+	row := []interface{}{d.Method, d.Endpoint, string(d.Status)}
+	if byService {
+		row = append([]interface{}{d.Service}, row...)
+	}
+
+	var bp50, cp50, bp99, cp99, p99Inc, bErr, cErr, errInc, bReq, cReq interface{}
+	if b := d.Baseline; b != nil {
+		bp50, bp99 = time.Duration(b.LatencyP50), time.Duration(b.LatencyP99)
+		bErr, bReq = 100*b.ErrorRate, b.Requests
+	}
+	if c := d.Candidate; c != nil {
+		cp50, cp99 = time.Duration(c.LatencyP50), time.Duration(c.LatencyP99)
+		cErr, cReq = 100*c.ErrorRate, c.Requests
+	}
+	if inc, ok := d.LatencyP99Increase(); ok {
+		p99Inc = inc
+	}
+	if inc, ok := d.ErrorRateIncrease(); ok {
+		errInc = inc
+	}
+	return append(row, bp50, cp50, bp99, cp99, p99Inc, bErr, cErr, errInc, bReq, cReq)
+}
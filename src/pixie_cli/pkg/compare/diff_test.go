@@ -0,0 +1,114 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package compare_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/pixie_cli/pkg/compare"
+)
+
+var (
+	cartsKey  = compare.Key{Service: "carts", Method: "GET", Endpoint: "/carts"}
+	ordersKey = compare.Key{Service: "orders", Method: "POST", Endpoint: "/orders"}
+	healthKey = compare.Key{Service: "carts", Method: "GET", Endpoint: "/health"}
+)
+
+func testDiffs() []*compare.Diff {
+	baseline := map[compare.Key]*compare.Stats{
+		cartsKey:  {LatencyP50: 1e6, LatencyP99: 10e6, ErrorRate: 0.01, Requests: 100},
+		healthKey: {LatencyP50: 1e5, LatencyP99: 2e5, Requests: 10},
+	}
+	candidate := map[compare.Key]*compare.Stats{
+		cartsKey:  {LatencyP50: 2e6, LatencyP99: 15e6, ErrorRate: 0.02, Requests: 120},
+		ordersKey: {LatencyP50: 3e6, LatencyP99: 4e6, ErrorRate: 0.1, Requests: 5},
+	}
+	return compare.Diffs(baseline, candidate)
+}
+
+func TestDiffs(t *testing.T) {
+	diffs := testDiffs()
+	require.Len(t, diffs, 3)
+
+	assert.Equal(t, cartsKey, diffs[0].Key)
+	assert.Equal(t, compare.StatusChanged, diffs[0].Status)
+	assert.Equal(t, healthKey, diffs[1].Key)
+	assert.Equal(t, compare.StatusRemoved, diffs[1].Status)
+	assert.Nil(t, diffs[1].Candidate)
+	assert.Equal(t, ordersKey, diffs[2].Key)
+	assert.Equal(t, compare.StatusNew, diffs[2].Status)
+	assert.Nil(t, diffs[2].Baseline)
+
+	inc, ok := diffs[0].LatencyP99Increase()
+	require.True(t, ok)
+	assert.InDelta(t, 50, inc, 1e-9)
+	inc, ok = diffs[0].ErrorRateIncrease()
+	require.True(t, ok)
+	assert.InDelta(t, 1, inc, 1e-9)
+
+	_, ok = diffs[1].ErrorRateIncrease()
+	assert.False(t, ok)
+	_, ok = diffs[2].LatencyP99Increase()
+	assert.False(t, ok)
+	inc, ok = diffs[2].ErrorRateIncrease()
+	require.True(t, ok)
+	assert.InDelta(t, 10, inc, 1e-9)
+}
+
+func TestRegressions(t *testing.T) {
+	diffs := testDiffs()
+
+	assert.Empty(t, compare.Regressions(diffs, compare.Thresholds{}))
+
+	r := compare.Regressions(diffs, compare.Thresholds{MaxLatencyP99Increase: 20})
+	require.Len(t, r, 1)
+	assert.Equal(t, cartsKey, r[0].Key)
+
+	r = compare.Regressions(diffs, compare.Thresholds{MaxErrorRateIncrease: 5})
+	require.Len(t, r, 1)
+	assert.Equal(t, ordersKey, r[0].Key)
+
+	r = compare.Regressions(diffs, compare.Thresholds{MaxLatencyP99Increase: 20, MaxErrorRateIncrease: 0.5})
+	assert.Len(t, r, 2)
+}
+
+func TestDiff_Row(t *testing.T) {
+	diffs := testDiffs()
+	header := compare.Header(true)
+
+	row := diffs[0].Row(true)
+	require.Len(t, row, len(header))
+	assert.Equal(t, "carts", row[0])
+	assert.Equal(t, "changed", row[3])
+	assert.Equal(t, 2*time.Millisecond, row[5])
+	assert.Equal(t, 15*time.Millisecond, row[7])
+	assert.InDelta(t, 50, row[8], 1e-9)
+	assert.Equal(t, int64(120), row[13])
+
+	row = diffs[2].Row(false)
+	require.Len(t, row, len(compare.Header(false)))
+	assert.Equal(t, []interface{}{"POST", "/orders", "new"}, row[:3])
+	assert.Nil(t, row[3])
+	assert.Nil(t, row[7])
+	assert.InDelta(t, 10, row[10], 1e-9)
+}
@@ -25,6 +25,7 @@ import (
 	"strings"
 
 	"github.com/spf13/viper"
+	"golang.org/x/term"
 )
 
 // This file has components that interact with the user via prompts.
@@ -102,3 +103,36 @@ func YNPrompt(message string, defaultValue bool) bool {
 	}
 	return strings.ToLower(NewPrompter(message, []string{"y", "n"}, defaultChoice).Prompt()) == "y"
 }
+
+// TextPrompt prompts the user for free-form text, returning the default if the input is empty
+// or the config parameter "y" is set.
+func TextPrompt(message string, defaultValue string) string {
+	if viper.GetBool("y") {
+		return defaultValue
+	}
+	if defaultValue != "" {
+		fmt.Printf("%s [%s]: ", message, defaultValue)
+	} else {
+		fmt.Printf("%s: ", message)
+	}
+	s := bufio.NewScanner(os.Stdin)
+	if !s.Scan() {
+		return defaultValue
+	}
+	input := strings.TrimSpace(s.Text())
+	if input == "" {
+		return defaultValue
+	}
+	return input
+}
+
+// SecretPrompt prompts the user for a value without echoing it to the terminal.
+func SecretPrompt(message string) (string, error) {
+	fmt.Printf("%s: ", message)
+	b, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
@@ -20,6 +20,7 @@ package components
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/fatih/color"
 	"github.com/spf13/viper"
@@ -34,6 +35,12 @@ type TaskInfo struct {
 	bar *mpb.Bar
 	sd  *statusDecorator
 	evd *errorViewDecorator
+	pd  *progressDecorator
+}
+
+// SetProgress shows a message describing the progress of the task, until it completes.
+func (t *TaskInfo) SetProgress(msg string) {
+	t.pd.setMessage(msg)
 }
 
 // Complete finishes the task.
@@ -67,6 +74,7 @@ func (s *SpinnerTable) AddTask(name string) *TaskInfo {
 	ti := &TaskInfo{}
 	sd := newStatusDecorator(barWidth)
 	evd := newErrorViewDecorator()
+	pd := newProgressDecorator()
 	// We treat the spinner is either done/not-done, so we only need progress of 1 and 0, respectively.
 	maxProgress := int64(1)
 	bar := s.m.AddSpinner(maxProgress, mpb.SpinnerOnLeft,
@@ -74,11 +82,13 @@ func (s *SpinnerTable) AddTask(name string) *TaskInfo {
 		mpb.BarWidth(barWidth),
 		mpb.AppendDecorators(
 			decor.Name(name, decor.WC{W: len(name) + 1, C: decor.DidentRight}),
+			pd,
 			evd),
 		mpb.BarClearOnComplete())
 
 	ti.sd = sd
 	ti.evd = evd
+	ti.pd = pd
 	ti.bar = bar
 	s.tasks = append(s.tasks, ti)
 
@@ -142,3 +152,33 @@ func (d *errorViewDecorator) Decor(stat *decor.Statistics) string {
 func (d *errorViewDecorator) setError(err error) {
 	d.err = err
 }
+
+// progressDecorator shows the latest progress message of a task that is running. It is updated by the task's
+// goroutine while the display renders it, so the message is guarded by a lock.
+type progressDecorator struct {
+	decor.WC
+	mu  sync.Mutex
+	msg string
+}
+
+func newProgressDecorator() *progressDecorator {
+	wc := decor.WC{}
+	wc.Init()
+	return &progressDecorator{WC: wc}
+}
+
+// Decor is the output function for this decorator.
+func (d *progressDecorator) Decor(stat *decor.Statistics) string {
+	if stat.Completed {
+		return ""
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.msg
+}
+
+func (d *progressDecorator) setMessage(msg string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.msg = msg
+}
@@ -0,0 +1,266 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package profile
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Format is the format that a profile is exported in.
+type Format string
+
+const (
+	// FormatCollapsed is the folded stack format of FlameGraph and other tools, a line per stack
+	// trace with the frames separated by semicolons and followed by the count.
+	FormatCollapsed Format = "collapsed"
+	// FormatPprof is the gzipped protobuf format of pprof.
+	FormatPprof Format = "pprof"
+	// FormatSpeedscope is the JSON format of speedscope.
+	FormatSpeedscope Format = "speedscope"
+)
+
+// Formats are the supported formats.
+var Formats = []Format{FormatCollapsed, FormatPprof, FormatSpeedscope}
+
+// Write writes the profile in the given format.
+func Write(w io.Writer, format Format, p *Profile) error {
+	switch format {
+	case FormatCollapsed:
+		return WriteCollapsed(w, p)
+	case FormatPprof:
+		return WritePprof(w, p)
+	case FormatSpeedscope:
+		return WriteSpeedscope(w, p)
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// rootFrames are the frames that the stack traces of a sample are nested under, so that the
+// profiles of different containers stay apart.
+func rootFrames(s *Sample) []string {
+	return []string{s.Pod, s.Container}
+}
+
+// WriteCollapsed writes the profile in the folded stack format, with the pod and container as the
+// root frames of every stack trace.
+func WriteCollapsed(w io.Writer, p *Profile) error {
+	bw := bufio.NewWriter(w)
+	for _, s := range p.Samples {
+		frames := append(rootFrames(s), s.Stack...)
+		if _, err := fmt.Fprintf(bw, "%s %d\n", strings.Join(frames, ";"), s.Count); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+type speedscopeFile struct {
+	Schema   string               `json:"$schema"`
+	Shared   speedscopeShared     `json:"shared"`
+	Profiles []*speedscopeProfile `json:"profiles"`
+	Name     string               `json:"name"`
+	Exporter string               `json:"exporter"`
+}
+
+type speedscopeShared struct {
+	Frames []speedscopeFrame `json:"frames"`
+}
+
+type speedscopeFrame struct {
+	Name string `json:"name"`
+}
+
+type speedscopeProfile struct {
+	Type       string  `json:"type"`
+	Name       string  `json:"name"`
+	Unit       string  `json:"unit"`
+	StartValue int64   `json:"startValue"`
+	EndValue   int64   `json:"endValue"`
+	Samples    [][]int `json:"samples"`
+	Weights    []int64 `json:"weights"`
+}
+
+// WriteSpeedscope writes the profile in the speedscope format, with a profile per container.
+func WriteSpeedscope(w io.Writer, p *Profile) error {
+	f := &speedscopeFile{
+		Schema:   "https://www.speedscope.app/file-format-schema.json",
+		Shared:   speedscopeShared{Frames: []speedscopeFrame{}},
+		Profiles: []*speedscopeProfile{},
+		Name:     fmt.Sprintf("Pixie profile %s", p.Start.UTC().Format("2006-01-02T15:04:05Z")),
+		Exporter: "px",
+	}
+	frameIdx := make(map[string]int)
+	profiles := make(map[string]*speedscopeProfile)
+	for _, s := range p.Samples {
+		name := strings.Join(rootFrames(s), "/")
+		sp, ok := profiles[name]
+		if !ok {
+			sp = &speedscopeProfile{Type: "sampled", Name: name, Unit: "none"}
+			profiles[name] = sp
+			f.Profiles = append(f.Profiles, sp)
+		}
+		stack := make([]int, len(s.Stack))
+		for i, frame := range s.Stack {
+			idx, ok := frameIdx[frame]
+			if !ok {
+				idx = len(f.Shared.Frames)
+				frameIdx[frame] = idx
+				f.Shared.Frames = append(f.Shared.Frames, speedscopeFrame{Name: frame})
+			}
+			stack[i] = idx
+		}
+		sp.Samples = append(sp.Samples, stack)
+		sp.Weights = append(sp.Weights, s.Count)
+		sp.EndValue += s.Count
+	}
+	return json.NewEncoder(w).Encode(f)
+}
+
+// Field numbers of profile.proto, see https://github.com/google/pprof/blob/main/proto/profile.proto.
+const (
+	pprofProfileSampleType    = 1
+	pprofProfileSample        = 2
+	pprofProfileLocation      = 4
+	pprofProfileFunction      = 5
+	pprofProfileStringTable   = 6
+	pprofProfileTimeNanos     = 9
+	pprofProfileDurationNanos = 10
+
+	pprofValueTypeType = 1
+	pprofValueTypeUnit = 2
+
+	pprofSampleLocationID = 1
+	pprofSampleValue      = 2
+	pprofSampleLabel      = 3
+
+	pprofLabelKey = 1
+	pprofLabelStr = 2
+
+	pprofLocationID   = 1
+	pprofLocationLine = 4
+
+	pprofLineFunctionID = 1
+
+	pprofFunctionID   = 1
+	pprofFunctionName = 2
+)
+
+// pprofStrings is the string table of a pprof profile. The first string must be empty.
+type pprofStrings struct {
+	idx     map[string]int64
+	strings []string
+}
+
+func (t *pprofStrings) get(s string) int64 {
+	if idx, ok := t.idx[s]; ok {
+		return idx
+	}
+	idx := int64(len(t.strings))
+	t.idx[s] = idx
+	t.strings = append(t.strings, s)
+	return idx
+}
+
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+// WritePprof writes the profile in the pprof format. Every frame becomes a function, with a
+// location of its own, and the pod and container are labels of the samples.
+func WritePprof(w io.Writer, p *Profile) error {
+	strs := &pprofStrings{idx: map[string]int64{"": 0}, strings: []string{""}}
+	var b []byte
+
+	var sampleType []byte
+	sampleType = appendVarint(sampleType, pprofValueTypeType, uint64(strs.get("samples")))
+	sampleType = appendVarint(sampleType, pprofValueTypeUnit, uint64(strs.get("count")))
+	b = appendMessage(b, pprofProfileSampleType, sampleType)
+
+	// The location and function of a frame share the same ID.
+	frameIDs := make(map[string]uint64)
+	var frames []string
+	for _, s := range p.Samples {
+		var sample []byte
+		// pprof expects the leaf frame first.
+		var locs []byte
+		for i := len(s.Stack) - 1; i >= 0; i-- {
+			id, ok := frameIDs[s.Stack[i]]
+			if !ok {
+				id = uint64(len(frames) + 1)
+				frameIDs[s.Stack[i]] = id
+				frames = append(frames, s.Stack[i])
+			}
+			locs = protowire.AppendVarint(locs, id)
+		}
+		sample = appendMessage(sample, pprofSampleLocationID, locs)
+		sample = appendMessage(sample, pprofSampleValue, protowire.AppendVarint(nil, uint64(s.Count)))
+		for _, l := range []struct{ key, val string }{{"pod", s.Pod}, {"container", s.Container}} {
+			var label []byte
+			label = appendVarint(label, pprofLabelKey, uint64(strs.get(l.key)))
+			label = appendVarint(label, pprofLabelStr, uint64(strs.get(l.val)))
+			sample = appendMessage(sample, pprofSampleLabel, label)
+		}
+		b = appendMessage(b, pprofProfileSample, sample)
+	}
+
+	for i, frame := range frames {
+		id := uint64(i + 1)
+		var line []byte
+		line = appendVarint(line, pprofLineFunctionID, id)
+		var loc []byte
+		loc = appendVarint(loc, pprofLocationID, id)
+		loc = appendMessage(loc, pprofLocationLine, line)
+		b = appendMessage(b, pprofProfileLocation, loc)
+
+		var fn []byte
+		fn = appendVarint(fn, pprofFunctionID, id)
+		fn = appendVarint(fn, pprofFunctionName, uint64(strs.get(frame)))
+		b = appendMessage(b, pprofProfileFunction, fn)
+	}
+
+	if !p.Start.IsZero() {
+		b = appendVarint(b, pprofProfileTimeNanos, uint64(p.Start.UnixNano()))
+	}
+	b = appendVarint(b, pprofProfileDurationNanos, uint64(p.Duration.Nanoseconds()))
+	// The string table is written last, after all of the strings have been added.
+	for _, s := range strs.strings {
+		b = protowire.AppendTag(b, pprofProfileStringTable, protowire.BytesType)
+		b = protowire.AppendString(b, s)
+	}
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(b); err != nil {
+		return err
+	}
+	return zw.Close()
+}
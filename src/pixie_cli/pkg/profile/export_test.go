@@ -0,0 +1,127 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package profile_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"px.dev/pixie/src/pixie_cli/pkg/profile"
+)
+
+func testProfile() *profile.Profile {
+	return &profile.Profile{
+		Start:    time.Unix(1700000000, 0),
+		Duration: time.Minute,
+		Samples: []*profile.Sample{
+			{Pod: "ns/a", Container: "app", Stack: []string{"main", "run", "work"}, Count: 5},
+			{Pod: "ns/a", Container: "app", Stack: []string{"main", "run"}, Count: 2},
+			{Pod: "ns/b", Container: "db", Stack: []string{"main", "query"}, Count: 1},
+		},
+	}
+}
+
+func TestWriteCollapsed(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, profile.Write(&buf, profile.FormatCollapsed, testProfile()))
+	assert.Equal(t, `ns/a;app;main;run;work 5
+ns/a;app;main;run 2
+ns/b;db;main;query 1
+`, buf.String())
+}
+
+func TestWriteSpeedscope(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, profile.Write(&buf, profile.FormatSpeedscope, testProfile()))
+
+	var f struct {
+		Shared struct {
+			Frames []struct {
+				Name string `json:"name"`
+			} `json:"frames"`
+		} `json:"shared"`
+		Profiles []struct {
+			Name     string  `json:"name"`
+			Type     string  `json:"type"`
+			EndValue int64   `json:"endValue"`
+			Samples  [][]int `json:"samples"`
+			Weights  []int64 `json:"weights"`
+		} `json:"profiles"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &f))
+
+	require.Len(t, f.Shared.Frames, 4)
+	assert.Equal(t, "work", f.Shared.Frames[2].Name)
+	require.Len(t, f.Profiles, 2)
+	assert.Equal(t, "ns/a/app", f.Profiles[0].Name)
+	assert.Equal(t, "sampled", f.Profiles[0].Type)
+	assert.Equal(t, int64(7), f.Profiles[0].EndValue)
+	assert.Equal(t, [][]int{{0, 1, 2}, {0, 1}}, f.Profiles[0].Samples)
+	assert.Equal(t, []int64{5, 2}, f.Profiles[0].Weights)
+	assert.Equal(t, [][]int{{0, 3}}, f.Profiles[1].Samples)
+}
+
+func TestWritePprof(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, profile.Write(&buf, profile.FormatPprof, testProfile()))
+
+	zr, err := gzip.NewReader(&buf)
+	require.NoError(t, err)
+	b, err := io.ReadAll(zr)
+	require.NoError(t, err)
+
+	// Count the top-level fields of the profile.
+	fields := make(map[protowire.Number]int)
+	var strs []string
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.GreaterOrEqual(t, n, 0)
+		b = b[n:]
+		if num == 6 {
+			s, n := protowire.ConsumeString(b)
+			require.GreaterOrEqual(t, n, 0)
+			strs = append(strs, s)
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		require.GreaterOrEqual(t, n, 0)
+		b = b[n:]
+		fields[num]++
+	}
+
+	assert.Equal(t, 1, fields[1], "sample types")
+	assert.Equal(t, 3, fields[2], "samples")
+	assert.Equal(t, 4, fields[4], "locations")
+	assert.Equal(t, 4, fields[5], "functions")
+	require.NotEmpty(t, strs)
+	assert.Equal(t, "", strs[0])
+	assert.Contains(t, strs, "work")
+	assert.Contains(t, strs, "ns/b")
+}
+
+func TestWrite_UnknownFormat(t *testing.T) {
+	assert.Error(t, profile.Write(io.Discard, profile.Format("svg"), testProfile()))
+}
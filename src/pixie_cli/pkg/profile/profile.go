@@ -0,0 +1,175 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package profile collects the stack traces sampled by the continuous profiler, and exports them in
+// the formats of standard profiling tools.
+package profile
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"px.dev/pixie/src/utils/script"
+)
+
+// Options selects the processes that the profile covers.
+type Options struct {
+	// Node, Namespace and Pod filter the profile to the matching pods. They match if they contain
+	// the value.
+	Node      string
+	Namespace string
+	Pod       string
+	// Since is how far back the profile starts, for example -5m.
+	Since string
+}
+
+var sinceRegex = regexp.MustCompile(`^-([0-9]+)(s|m|h)$`)
+
+// Duration returns the time range covered by the profile.
+func (o *Options) Duration() (time.Duration, error) {
+	m := sinceRegex.FindStringSubmatch(o.Since)
+	if m == nil {
+		return 0, fmt.Errorf("invalid start time %q, expected a relative time such as -5m", o.Since)
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, err
+	}
+	unit := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour}[m[2]]
+	return time.Duration(n) * unit, nil
+}
+
+// Script returns the script that fetches the stack traces selected by the options.
+func Script(opts *Options) (*script.ExecutableScript, error) {
+	if _, err := opts.Duration(); err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	b.WriteString("import px\n")
+	fmt.Fprintf(&b, "df = px.DataFrame(table='stack_traces.beta', start_time='%s')\n", opts.Since)
+	b.WriteString("df.node = px.Node(px._exec_hostname())\n")
+	b.WriteString("df.namespace = df.ctx['namespace']\n")
+	b.WriteString("df.pod = df.ctx['pod']\n")
+	b.WriteString("df.container = df.ctx['container']\n")
+	b.WriteString("df = df[df.pod != '']\n")
+	for _, f := range []struct{ col, val string }{
+		{"node", opts.Node},
+		{"namespace", opts.Namespace},
+		{"pod", opts.Pod},
+	} {
+		if f.val != "" {
+			fmt.Fprintf(&b, "df = df[px.contains(df.%s, %s)]\n", f.col, strconv.Quote(f.val))
+		}
+	}
+	// Merges the profiles that were taken during the time range into one.
+	b.WriteString("df = df.groupby(['pod', 'container', 'stack_trace']).agg(count=('count', px.sum))\n")
+	b.WriteString("px.display(df, 'stack_traces')\n")
+
+	return &script.ExecutableScript{
+		ScriptName:   "px/profile",
+		ScriptString: b.String(),
+		IsLocal:      true,
+	}, nil
+}
+
+// Sample is the number of times that a stack trace was sampled in a container.
+type Sample struct {
+	Pod       string
+	Container string
+	// Stack is the stack trace, from the root frame to the leaf frame.
+	Stack []string
+	Count int64
+}
+
+// Profile is a set of sampled stack traces.
+type Profile struct {
+	Samples []*Sample
+	// Start and Duration are the time range covered by the profile.
+	Start    time.Time
+	Duration time.Duration
+}
+
+// Builder collects the rows of the stack traces table into a profile. It implements
+// components.OutputStreamWriter, and expects unformatted values.
+type Builder struct {
+	profile *Profile
+	colIdx  map[string]int
+	err     error
+}
+
+// NewBuilder creates a builder for a profile of the given time range.
+func NewBuilder(start time.Time, duration time.Duration) *Builder {
+	return &Builder{
+		profile: &Profile{Start: start, Duration: duration},
+	}
+}
+
+// SetHeader sets the names of the columns.
+func (b *Builder) SetHeader(id string, headerValues []string) {
+	b.colIdx = make(map[string]int)
+	for i, h := range headerValues {
+		b.colIdx[h] = i
+	}
+	for _, col := range []string{"pod", "container", "stack_trace", "count"} {
+		if _, ok := b.colIdx[col]; !ok {
+			b.err = fmt.Errorf("stack traces table is missing column %q", col)
+		}
+	}
+}
+
+// Write adds a single row to the profile.
+func (b *Builder) Write(data []interface{}) error {
+	if b.err != nil {
+		return b.err
+	}
+	stack := fmt.Sprintf("%v", data[b.colIdx["stack_trace"]])
+	count, ok := data[b.colIdx["count"]].(int64)
+	if !ok {
+		return fmt.Errorf("unexpected count %v", data[b.colIdx["count"]])
+	}
+	b.profile.Samples = append(b.profile.Samples, &Sample{
+		Pod:       fmt.Sprintf("%v", data[b.colIdx["pod"]]),
+		Container: fmt.Sprintf("%v", data[b.colIdx["container"]]),
+		Stack:     strings.Split(stack, ";"),
+		Count:     count,
+	})
+	return nil
+}
+
+// Finish is a no-op, the profile is returned by Profile.
+func (b *Builder) Finish() {}
+
+// Profile returns the collected profile, with its samples in a stable order.
+func (b *Builder) Profile() *Profile {
+	samples := b.profile.Samples
+	sort.SliceStable(samples, func(i, j int) bool {
+		if samples[i].Pod != samples[j].Pod {
+			return samples[i].Pod < samples[j].Pod
+		}
+		if samples[i].Container != samples[j].Container {
+			return samples[i].Container < samples[j].Container
+		}
+		return strings.Join(samples[i].Stack, ";") < strings.Join(samples[j].Stack, ";")
+	})
+	return b.profile
+}
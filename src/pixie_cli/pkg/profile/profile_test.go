@@ -0,0 +1,79 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package profile_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/pixie_cli/pkg/profile"
+)
+
+func TestScript(t *testing.T) {
+	s, err := profile.Script(&profile.Options{Namespace: "sock-shop", Pod: "front", Since: "-5m"})
+	require.NoError(t, err)
+	assert.Equal(t, `import px
+df = px.DataFrame(table='stack_traces.beta', start_time='-5m')
+df.node = px.Node(px._exec_hostname())
+df.namespace = df.ctx['namespace']
+df.pod = df.ctx['pod']
+df.container = df.ctx['container']
+df = df[df.pod != '']
+df = df[px.contains(df.namespace, "sock-shop")]
+df = df[px.contains(df.pod, "front")]
+df = df.groupby(['pod', 'container', 'stack_trace']).agg(count=('count', px.sum))
+px.display(df, 'stack_traces')
+`, s.ScriptString)
+
+	_, err = profile.Script(&profile.Options{Since: "5m"})
+	assert.Error(t, err)
+}
+
+func TestOptions_Duration(t *testing.T) {
+	d, err := (&profile.Options{Since: "-90s"}).Duration()
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Second, d)
+
+	d, err = (&profile.Options{Since: "-2h"}).Duration()
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Hour, d)
+}
+
+func TestBuilder(t *testing.T) {
+	b := profile.NewBuilder(time.Unix(0, 0), time.Minute)
+	b.SetHeader("stack_traces", []string{"pod", "container", "stack_trace", "count"})
+	require.NoError(t, b.Write([]interface{}{"ns/b", "app", "main;run", int64(2)}))
+	require.NoError(t, b.Write([]interface{}{"ns/a", "app", "main;run;work", int64(5)}))
+	b.Finish()
+
+	p := b.Profile()
+	assert.Equal(t, time.Minute, p.Duration)
+	require.Len(t, p.Samples, 2)
+	assert.Equal(t, &profile.Sample{Pod: "ns/a", Container: "app", Stack: []string{"main", "run", "work"}, Count: 5}, p.Samples[0])
+	assert.Equal(t, "ns/b", p.Samples[1].Pod)
+}
+
+func TestBuilder_MissingColumn(t *testing.T) {
+	b := profile.NewBuilder(time.Unix(0, 0), time.Minute)
+	b.SetHeader("stack_traces", []string{"pod", "stack_trace", "count"})
+	assert.Error(t, b.Write([]interface{}{"ns/a", "main", int64(1)}))
+}
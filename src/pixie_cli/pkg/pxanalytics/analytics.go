@@ -29,6 +29,7 @@ import (
 	"github.com/segmentio/analytics-go/v3"
 	"github.com/spf13/viper"
 
+	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
 	version "px.dev/pixie/src/shared/goversion"
 )
 
@@ -58,7 +59,7 @@ func Client() analytics.Client {
 	once.Do(func() {
 		client = disabledAnalyticsClient{}
 
-		if viper.GetBool("do_not_track") {
+		if pxconfig.DoNotTrack() {
 			return
 		}
 
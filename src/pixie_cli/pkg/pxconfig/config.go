@@ -32,6 +32,24 @@ import (
 type ConfigInfo struct {
 	// UniqueClientID is the ID assigned to this user on first startup when auth information is not know. This can be later associated with the UserID.
 	UniqueClientID string `json:"uniqueClientID"`
+	// DirectVizier is used to secure connections to a Vizier which are made directly, rather than through the cloud.
+	DirectVizier *DirectVizierConfig `json:"directVizier,omitempty"`
+	// DoNotTrack opts the CLI out of telemetry. See DoNotTrack.
+	DoNotTrack bool `json:"doNotTrack,omitempty"`
+	// LocalOnly stops the CLI from contacting Pixie hosted endpoints other than the configured cloud. See LocalOnly.
+	LocalOnly bool `json:"localOnly,omitempty"`
+}
+
+// DirectVizierConfig stores the TLS settings for direct connections to a Vizier.
+type DirectVizierConfig struct {
+	// ClientCert and ClientKey are the paths to a PEM encoded certificate and key, which are presented to the Vizier.
+	ClientCert string `json:"clientCert,omitempty"`
+	ClientKey  string `json:"clientKey,omitempty"`
+	// CACert is the path to a PEM encoded CA certificate to verify the Vizier with, instead of the system roots.
+	CACert string `json:"caCert,omitempty"`
+	// PinnedKeys are the base64 encoded SHA-256 hashes of the Vizier public keys which are trusted. If set, the
+	// Vizier must present a certificate for one of these keys.
+	PinnedKeys []string `json:"pinnedKeys,omitempty"`
 }
 
 var (
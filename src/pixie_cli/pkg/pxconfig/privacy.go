@@ -0,0 +1,44 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxconfig
+
+import (
+	"errors"
+
+	"github.com/spf13/viper"
+)
+
+// ErrLocalOnly is returned for operations that would contact a Pixie hosted endpoint other than the configured
+// cloud, when the CLI is in local only mode.
+var ErrLocalOnly = errors.New("disabled in local only mode, since it contacts an endpoint other than the configured Pixie Cloud")
+
+// DoNotTrack returns whether the CLI is opted out of telemetry. When set, no analytics events or error reports are
+// sent, and Pixie Cloud isn't probed for features the user didn't ask for, such as CLI updates. It is set with
+// --do_not_track, PX_DO_NOT_TRACK or DO_NOT_TRACK, or "doNotTrack" in the config file, and is implied by LocalOnly.
+func DoNotTrack() bool {
+	return LocalOnly() || viper.GetBool("do_not_track") || Cfg().DoNotTrack
+}
+
+// LocalOnly returns whether the CLI is in local only mode. In this mode, the CLI only talks to the configured
+// cloud_addr (or the Vizier given by direct_vizier_addr), and to any URLs it is explicitly given, but never to other
+// Pixie hosted endpoints such as the public script bundles, demo app artifacts and cloud release mirrors. It is set
+// with --local_only or PX_LOCAL_ONLY, or "localOnly" in the config file.
+func LocalOnly() bool {
+	return viper.GetBool("local_only") || Cfg().LocalOnly
+}
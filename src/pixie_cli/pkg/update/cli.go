@@ -40,6 +40,7 @@ import (
 	"px.dev/pixie/src/pixie_cli/pkg/utils"
 	version "px.dev/pixie/src/shared/goversion"
 	"px.dev/pixie/src/shared/services"
+	"px.dev/pixie/src/utils/shared/artifacts"
 )
 
 func newATClient(cloudAddr string) (cloudpb.ArtifactTrackerClient, error) {
@@ -59,11 +60,15 @@ func newATClient(cloudAddr string) (cloudpb.ArtifactTrackerClient, error) {
 }
 
 func getArtifactTypes() cloudpb.ArtifactType {
-	switch runtime.GOOS {
-	case "darwin":
+	switch runtime.GOOS + "/" + runtime.GOARCH {
+	case "darwin/amd64":
 		return cloudpb.AT_DARWIN_AMD64
-	case "linux":
+	case "darwin/arm64":
+		return cloudpb.AT_DARWIN_ARM64
+	case "linux/amd64":
 		return cloudpb.AT_LINUX_AMD64
+	case "linux/arm64":
+		return cloudpb.AT_LINUX_ARM64
 	}
 	return cloudpb.AT_UNKNOWN
 }
@@ -71,7 +76,7 @@ func getArtifactTypes() cloudpb.ArtifactType {
 // UpdatesAvailable returns the version if updates are available, otherwise empty string.
 // Errors also return empty strings.
 func UpdatesAvailable(cloudAddr string) string {
-	u := NewCLIUpdater(cloudAddr)
+	u := NewCLIUpdater(cloudAddr, nil)
 	versions, err := u.GetAvailableVersions(version.GetVersion().Semver())
 	if err != nil {
 		return ""
@@ -88,12 +93,14 @@ func UpdatesAvailable(cloudAddr string) string {
 // CLIUpdater manages updates to the CLI.
 type CLIUpdater struct {
 	cloudAddr string
+	verify    *artifacts.VerificationOptions
 }
 
-// NewCLIUpdater creates a new CLIUpdater.
-func NewCLIUpdater(cloudAddr string) *CLIUpdater {
+// NewCLIUpdater creates a new CLIUpdater. Downloaded binaries are checked with the given verification options.
+func NewCLIUpdater(cloudAddr string, verify *artifacts.VerificationOptions) *CLIUpdater {
 	return &CLIUpdater{
 		cloudAddr: cloudAddr,
+		verify:    verify,
 	}
 }
 
@@ -184,6 +191,14 @@ func (c *CLIUpdater) UpdateSelf(version string) error {
 		return err
 	}
 
+	binary, err := os.ReadFile(tempFile.Name())
+	if err != nil {
+		return err
+	}
+	if err := c.verify.Verify(binary, resp.SHA256, resp.Signature); err != nil {
+		return err
+	}
+
 	utils.Info("Download complete, applying update ...")
 	checksum, err := hex.DecodeString(resp.SHA256)
 	if err != nil {
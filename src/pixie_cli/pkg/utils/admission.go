@@ -0,0 +1,190 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+const (
+	admissionCheckPodName = "px-admission-check"
+	// fallbackAdmissionCheckNamespace is where the probe pod is dry-run when the Vizier namespace
+	// doesn't exist yet. A new namespace gets the cluster's default policies, as does an
+	// unlabeled default namespace.
+	fallbackAdmissionCheckNamespace = "default"
+)
+
+var (
+	// Pod Security Admission rejects pods with: violates PodSecurity "baseline:latest": <details>.
+	podSecurityRe = regexp.MustCompile(`violates PodSecurity "([^":]+)(?::[^"]*)?": (.*)`)
+	// Validating webhooks reject pods with: admission webhook "<name>" denied the request: <details>.
+	webhookRe = regexp.MustCompile(`admission webhook "([^"]+)" denied the request: (?s)(.*)`)
+	// Gatekeeper prefixes each violation with the name of the constraint: [<name>] <message>.
+	gatekeeperConstraintRe = regexp.MustCompile(`\[([^\]]+)\]`)
+)
+
+// admissionRejection describes why the API server's admission chain rejected a pod.
+type admissionRejection struct {
+	// PodSecurityLevel is the Pod Security Admission level that rejected the pod, if any.
+	PodSecurityLevel string
+	// Webhook is the validating webhook that rejected the pod, if any.
+	Webhook string
+	// Constraints are the Gatekeeper constraints that the pod violates.
+	Constraints []string
+	Details     string
+}
+
+func (r *admissionRejection) Error() string {
+	switch {
+	case r.PodSecurityLevel != "":
+		return fmt.Sprintf("the PEM would be rejected by Pod Security Admission, which enforces the %q level: %s. The Vizier namespace must allow the \"privileged\" level", r.PodSecurityLevel, r.Details)
+	case len(r.Constraints) > 0:
+		return fmt.Sprintf("the PEM would be rejected by the Gatekeeper constraints (%s): %s. Exempt the Vizier namespace from these constraints", strings.Join(r.Constraints, ", "), r.Details)
+	case r.Webhook != "":
+		return fmt.Sprintf("the PEM would be rejected by the admission webhook %q: %s", r.Webhook, r.Details)
+	default:
+		return fmt.Sprintf("the PEM would be rejected: %s", r.Details)
+	}
+}
+
+// parseAdmissionError reads the policy that rejected a pod out of the API server's error. It
+// returns nil if the error is not an admission rejection.
+func parseAdmissionError(err error) *admissionRejection {
+	if err == nil || !(k8serrors.IsForbidden(err) || k8serrors.IsInvalid(err) || k8serrors.IsBadRequest(err)) {
+		return nil
+	}
+	msg := err.Error()
+	if m := podSecurityRe.FindStringSubmatch(msg); m != nil {
+		return &admissionRejection{PodSecurityLevel: m[1], Details: m[2]}
+	}
+	if m := webhookRe.FindStringSubmatch(msg); m != nil {
+		r := &admissionRejection{Webhook: m[1], Details: strings.TrimSpace(m[2])}
+		if strings.Contains(r.Webhook, "gatekeeper") {
+			for _, c := range gatekeeperConstraintRe.FindAllStringSubmatch(m[2], -1) {
+				r.Constraints = append(r.Constraints, c[1])
+			}
+		}
+		return r
+	}
+	// RBAC denials are also Forbidden, but say nothing about whether the PEM is allowed.
+	if k8serrors.IsForbidden(err) && strings.Contains(msg, "cannot create resource") {
+		return nil
+	}
+	return &admissionRejection{Details: msg}
+}
+
+// pemProbePod returns a pod with the same security-relevant settings as the PEM daemonset, so
+// that it is admitted or rejected by the same policies.
+func pemProbePod(namespace string) *v1.Pod {
+	hostPathDir := v1.HostPathDirectory
+	privileged := true
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      admissionCheckPodName,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app":       "pl-monitoring",
+				"component": "vizier",
+				"name":      "vizier-pem",
+				"plane":     "data",
+			},
+		},
+		Spec: v1.PodSpec{
+			HostPID:     true,
+			HostNetwork: true,
+			DNSPolicy:   v1.DNSClusterFirstWithHostNet,
+			SecurityContext: &v1.PodSecurityContext{
+				SeccompProfile: &v1.SeccompProfile{Type: v1.SeccompProfileTypeRuntimeDefault},
+			},
+			Containers: []v1.Container{
+				{
+					Name:  "pem",
+					Image: "gcr.io/pixie-oss/pixie-prod/vizier-pem_image:latest",
+					SecurityContext: &v1.SecurityContext{
+						Privileged: &privileged,
+						Capabilities: &v1.Capabilities{
+							Add: []v1.Capability{"SYS_PTRACE", "SYS_ADMIN"},
+						},
+						SeccompProfile: &v1.SeccompProfile{Type: v1.SeccompProfileTypeRuntimeDefault},
+					},
+					VolumeMounts: []v1.VolumeMount{
+						{Name: "host-root", MountPath: "/host", ReadOnly: true},
+						{Name: "sys", MountPath: "/sys", ReadOnly: true},
+					},
+				},
+			},
+			Volumes: []v1.Volume{
+				{
+					Name: "host-root",
+					VolumeSource: v1.VolumeSource{
+						HostPath: &v1.HostPathVolumeSource{Path: "/", Type: &hostPathDir},
+					},
+				},
+				{
+					Name: "sys",
+					VolumeSource: v1.VolumeSource{
+						HostPath: &v1.HostPathVolumeSource{Path: "/sys", Type: &hostPathDir},
+					},
+				},
+			},
+		},
+	}
+}
+
+// dryRunPEM creates the PEM probe pod with a server-side dry-run, which runs it through all of
+// the admission plugins and webhooks without persisting it.
+func dryRunPEM(ctx context.Context, clientset kubernetes.Interface, namespace string) error {
+	_, err := clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		namespace = fallbackAdmissionCheckNamespace
+	} else if err != nil {
+		return err
+	}
+
+	_, err = clientset.CoreV1().Pods(namespace).Create(ctx, pemProbePod(namespace), metav1.CreateOptions{
+		DryRun: []string{metav1.DryRunAll},
+	})
+	if r := parseAdmissionError(err); r != nil {
+		return r
+	}
+	if k8serrors.IsForbidden(err) {
+		// The user can't create pods, so the policies can't be checked.
+		return nil
+	}
+	return err
+}
+
+// PEMAdmissionCheck checks that the admission policies of the cluster, such as Pod Security
+// Admission or Gatekeeper constraints, allow the privileged PEM pods in the given namespace.
+func PEMAdmissionCheck(namespace string) Checker {
+	return NamedCheck("Admission policies allow the privileged PEM", func() error {
+		clientset := k8s.GetClientset(k8s.GetConfig())
+		return dryRunPEM(context.Background(), clientset, namespace)
+	})
+}
@@ -0,0 +1,156 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var podsResource = schema.GroupResource{Resource: "pods"}
+
+func TestParseAdmissionError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected *admissionRejection
+	}{
+		{
+			name: "pod security admission",
+			err: k8serrors.NewForbidden(podsResource, "px-admission-check",
+				errors.New(`violates PodSecurity "baseline:latest": host namespaces (hostNetwork=true, hostPID=true), privileged (container "pem" must not set securityContext.privileged=true)`)),
+			expected: &admissionRejection{
+				PodSecurityLevel: "baseline",
+				Details:          `host namespaces (hostNetwork=true, hostPID=true), privileged (container "pem" must not set securityContext.privileged=true)`,
+			},
+		},
+		{
+			name: "gatekeeper",
+			err: k8serrors.NewForbidden(podsResource, "px-admission-check",
+				errors.New("admission webhook \"validation.gatekeeper.sh\" denied the request: [psp-privileged-container] Privileged container is not allowed: pem\n[psp-host-namespace] Sharing the host namespace is not allowed: px-admission-check")),
+			expected: &admissionRejection{
+				Webhook:     "validation.gatekeeper.sh",
+				Constraints: []string{"psp-privileged-container", "psp-host-namespace"},
+				Details:     "[psp-privileged-container] Privileged container is not allowed: pem\n[psp-host-namespace] Sharing the host namespace is not allowed: px-admission-check",
+			},
+		},
+		{
+			name: "other webhook",
+			err: k8serrors.NewForbidden(podsResource, "px-admission-check",
+				errors.New(`admission webhook "policy.kyverno.svc" denied the request: privileged containers are not allowed`)),
+			expected: &admissionRejection{
+				Webhook: "policy.kyverno.svc",
+				Details: "privileged containers are not allowed",
+			},
+		},
+		{
+			name: "rbac",
+			err: k8serrors.NewForbidden(podsResource, "px-admission-check",
+				errors.New(`User "jane" cannot create resource "pods" in API group "" in the namespace "pl"`)),
+		},
+		{
+			name: "not an admission error",
+			err:  errors.New("connection refused"),
+		},
+		{
+			name: "no error",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, parseAdmissionError(test.err))
+		})
+	}
+}
+
+func TestDryRunPEM(t *testing.T) {
+	tests := []struct {
+		name              string
+		existingNamespace bool
+		createErr         error
+		expectedNamespace string
+		expectedErr       bool
+	}{
+		{
+			name:              "admitted",
+			existingNamespace: true,
+			expectedNamespace: "pl",
+		},
+		{
+			name:              "missing namespace",
+			expectedNamespace: "default",
+		},
+		{
+			name:              "rejected",
+			existingNamespace: true,
+			createErr: k8serrors.NewForbidden(podsResource, "px-admission-check",
+				errors.New(`violates PodSecurity "restricted:latest": privileged`)),
+			expectedNamespace: "pl",
+			expectedErr:       true,
+		},
+		{
+			name:              "not allowed to create pods",
+			existingNamespace: true,
+			createErr: k8serrors.NewForbidden(podsResource, "px-admission-check",
+				errors.New(`User "jane" cannot create resource "pods" in API group "" in the namespace "pl"`)),
+			expectedNamespace: "pl",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var objs []runtime.Object
+			if test.existingNamespace {
+				objs = append(objs, &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "pl"}})
+			}
+			cs := fake.NewSimpleClientset(objs...)
+			var created bool
+			cs.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				created = true
+				create := action.(k8stesting.CreateActionImpl)
+				assert.Equal(t, test.expectedNamespace, create.GetNamespace())
+				pod := create.GetObject().(*v1.Pod)
+				assert.True(t, *pod.Spec.Containers[0].SecurityContext.Privileged)
+				return true, pod, test.createErr
+			})
+
+			err := dryRunPEM(context.Background(), cs, "pl")
+			require.True(t, created)
+			if test.expectedErr {
+				var r *admissionRejection
+				require.ErrorAs(t, err, &r)
+				assert.Equal(t, "restricted", r.PodSecurityLevel)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
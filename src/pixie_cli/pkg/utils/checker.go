@@ -101,10 +101,12 @@ func RunClusterChecks(checks []Checker) error {
 	return jr.RunAndMonitor()
 }
 
-// RunDefaultClusterChecks runs the default configured checks.
-func RunDefaultClusterChecks() error {
+// RunDefaultClusterChecks runs the default configured checks, and checks that the PEM can be
+// admitted to the given namespace.
+func RunDefaultClusterChecks(namespace string) error {
 	fmt.Printf("\nRunning Cluster Checks:\n")
-	return RunClusterChecks(DefaultClusterChecks)
+	checks := append([]Checker{}, DefaultClusterChecks...)
+	return RunClusterChecks(append(checks, PEMAdmissionCheck(namespace)))
 }
 
 // RunExtraClusterChecks runs the extra configured checks.
@@ -24,6 +24,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"px.dev/pixie/src/pixie_cli/pkg/utils"
 )
@@ -70,3 +72,20 @@ func TestVersionCompatible(t *testing.T) {
 		})
 	}
 }
+
+func TestUnsupportedArchNodes(t *testing.T) {
+	node := func(name, arch string) v1.Node {
+		return v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{Architecture: arch}},
+		}
+	}
+	nodes := []v1.Node{
+		node("amd", "amd64"),
+		node("graviton", "arm64"),
+		node("power", "ppc64le"),
+		node("z", "s390x"),
+	}
+	assert.Equal(t, []string{"power", "z"}, utils.UnsupportedArchNodes(nodes))
+	assert.Empty(t, utils.UnsupportedArchNodes(nodes[:2]))
+}
@@ -27,6 +27,7 @@ import (
 	"strings"
 
 	"gopkg.in/yaml.v2"
+	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"px.dev/pixie/src/utils/shared/k8s"
@@ -38,6 +39,38 @@ const (
 	kernelMinVersion  = "4.14.0"
 )
 
+// supportedNodeArchs are the node architectures that Pixie images are built for.
+var supportedNodeArchs = []string{"amd64", "arm64"}
+
+// UnsupportedArchNodes returns the names of the nodes whose architecture Pixie does not support.
+func UnsupportedArchNodes(nodes []v1.Node) []string {
+	var unsupported []string
+	for _, node := range nodes {
+		supported := false
+		for _, arch := range supportedNodeArchs {
+			if node.Status.NodeInfo.Architecture == arch {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			unsupported = append(unsupported, node.Name)
+		}
+	}
+	return unsupported
+}
+
+func listNodes() ([]v1.Node, error) {
+	kubeConfig := k8s.GetConfig()
+	clientset := k8s.GetClientset(kubeConfig)
+
+	nodes, err := clientset.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return nodes.Items, nil
+}
+
 // ClusterType represents all possible types of a K8s cluster.
 type ClusterType int
 
@@ -65,6 +98,8 @@ const (
 	ClusterTypeK0s
 	// ClusterTypeK3s is a k3s cluster.
 	ClusterTypeK3s
+	// ClusterTypeMicroK8s is a microk8s cluster.
+	ClusterTypeMicroK8s
 )
 
 var allowedClusterTypes = []ClusterType{
@@ -75,6 +110,7 @@ var allowedClusterTypes = []ClusterType{
 	ClusterTypeMinikubeHyperkit,
 	ClusterTypeK0s,
 	ClusterTypeK3s,
+	ClusterTypeMicroK8s,
 }
 
 // detectClusterType gets the cluster type of the cluster for the current kube config context.
@@ -100,6 +136,11 @@ func detectClusterType() ClusterType {
 		return ClusterTypeDockerDesktop
 	}
 
+	// Check if it is a microk8s cluster, which names its cluster in the generated kube config.
+	if s == "microk8s-cluster" {
+		return ClusterTypeMicroK8s
+	}
+
 	// Check if it a minikube cluster.
 	result, err := exec.Command("/bin/sh", "-c", fmt.Sprintf(`minikube profile list | grep " %s "| cut -f3 -d'|'`, currentContext)).Output()
 	if err == nil {
@@ -158,15 +199,12 @@ func detectClusterType() ClusterType {
 
 var (
 	kernelVersionCheck = NamedCheck(fmt.Sprintf("Kernel version > %s", kernelMinVersion), func() error {
-		kubeConfig := k8s.GetConfig()
-		clientset := k8s.GetClientset(kubeConfig)
-
-		nodes, err := clientset.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+		nodes, err := listNodes()
 		if err != nil {
 			return err
 		}
 
-		for _, node := range nodes.Items {
+		for _, node := range nodes {
 			compatible, err := VersionCompatible(node.Status.NodeInfo.KernelVersion, kernelMinVersion)
 			if err != nil {
 				return err
@@ -177,6 +215,16 @@ var (
 		}
 		return nil
 	})
+	nodeArchCheck = NamedCheck(fmt.Sprintf("Node architecture is one of (%s)", strings.Join(supportedNodeArchs, ", ")), func() error {
+		nodes, err := listNodes()
+		if err != nil {
+			return err
+		}
+		if len(nodes) > 0 && len(UnsupportedArchNodes(nodes)) == len(nodes) {
+			return fmt.Errorf("no nodes with a supported architecture. Must have at least one node with architecture in (%s)", strings.Join(supportedNodeArchs, ", "))
+		}
+		return nil
+	})
 	clusterTypeIsSupported = NamedCheck("Cluster type is supported", func() error {
 		clusterType := detectClusterType()
 
@@ -260,11 +308,40 @@ var (
 
 		return errors.New("Cluster type is not in list of known supported cluster types. Please see: https://docs.px.dev/installing-pixie/requirements/")
 	})
+	// allNodesLinuxCheck flags nodes, such as Windows nodes, that PEMs are never scheduled on.
+	allNodesLinuxCheck = NamedCheck("All nodes run Linux", func() error {
+		nodes, err := listNodes()
+		if err != nil {
+			return err
+		}
+		var nonLinux []string
+		for _, node := range nodes {
+			if nodeOS := node.Status.NodeInfo.OperatingSystem; nodeOS != "" && nodeOS != "linux" {
+				nonLinux = append(nonLinux, node.Name)
+			}
+		}
+		if len(nonLinux) > 0 {
+			return fmt.Errorf("nodes (%s) do not run Linux and will not be monitored by Pixie", strings.Join(nonLinux, ", "))
+		}
+		return nil
+	})
+	// allNodesArchCheck flags nodes in a mixed-architecture cluster that Pixie will not be scheduled on.
+	allNodesArchCheck = NamedCheck("All nodes have a supported architecture", func() error {
+		nodes, err := listNodes()
+		if err != nil {
+			return err
+		}
+		if unsupported := UnsupportedArchNodes(nodes); len(unsupported) > 0 {
+			return fmt.Errorf("nodes (%s) have an unsupported architecture and will not be monitored by Pixie", strings.Join(unsupported, ", "))
+		}
+		return nil
+	})
 )
 
 // DefaultClusterChecks is a list of cluster that are performed by default.
 var DefaultClusterChecks = []Checker{
 	kernelVersionCheck,
+	nodeArchCheck,
 	clusterTypeIsSupported,
 	k8sVersionCheck,
 	hasKubectlCheck,
@@ -274,4 +351,6 @@ var DefaultClusterChecks = []Checker{
 // ExtraClusterChecks is a list of checks for the cluster that are not required for deployment, but are highly recommended.
 var ExtraClusterChecks = []Checker{
 	allowListClusterCheck,
+	allNodesLinuxCheck,
+	allNodesArchCheck,
 }
@@ -27,6 +27,7 @@ const (
 	pixieDotPath    = ".pixie"
 	pixieConfigFile = "config.json"
 	pixieAuthFile   = "auth.json"
+	pixieSnapshots  = "snapshots"
 )
 
 // ensureDotFolderPath returns and creates the dot folder for cli config/auth.
@@ -68,3 +69,17 @@ func EnsureDefaultAuthFilePath() (string, error) {
 	pixieAuthFilePath := filepath.Join(pixieDirPath, pixieAuthFile)
 	return pixieAuthFilePath, nil
 }
+
+// EnsureSnapshotDirPath returns and creates the directory that Vizier upgrade snapshots are saved in.
+func EnsureSnapshotDirPath() (string, error) {
+	pixieDirPath, err := ensureDotFolderPath()
+	if err != nil {
+		return "", err
+	}
+
+	snapshotDirPath := filepath.Join(pixieDirPath, pixieSnapshots)
+	if err := os.MkdirAll(snapshotDirPath, 0700); err != nil {
+		return "", err
+	}
+	return snapshotDirPath, nil
+}
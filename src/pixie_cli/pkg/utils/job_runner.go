@@ -30,6 +30,20 @@ type Task interface {
 	Run() error
 }
 
+// ProgressTask is a Task that can report its progress while it runs.
+type ProgressTask interface {
+	Task
+	// RunWithProgress runs the task, and calls setProgress with a message whenever its progress changes.
+	RunWithProgress(setProgress func(msg string)) error
+}
+
+func runTask(t Task, ti *components.TaskInfo) error {
+	if pt, ok := t.(ProgressTask); ok {
+		return pt.RunWithProgress(ti.SetProgress)
+	}
+	return t.Run()
+}
+
 // SerialTaskRunner runs tasks in serial and displays them in a table.
 type SerialTaskRunner struct {
 	tasks []Task
@@ -48,7 +62,7 @@ func (s *SerialTaskRunner) RunAndMonitor() error {
 	defer st.Wait()
 	for _, t := range s.tasks {
 		ti := st.AddTask(t.Name())
-		err := t.Run()
+		err := runTask(t, ti)
 		ti.Complete(err)
 		if err != nil {
 			return err
@@ -77,7 +91,7 @@ func (s *ParallelTaskRunner) RunAndMonitor() error {
 		boundTask := t
 		g.Go(func() error {
 			ti := st.AddTask(boundTask.Name())
-			err := boundTask.Run()
+			err := runTask(boundTask, ti)
 			ti.Complete(err)
 			return err
 		})
@@ -104,7 +104,13 @@ func (c *Connector) connect(addr string) error {
 	}()
 	isInternal := strings.Contains(addr, "cluster.local")
 
-	dialOpts, err := services.GetGRPCClientDialOptsServerSideTLS(isInternal)
+	var dialOpts []grpc.DialOption
+	var err error
+	if c.directVzAddr != "" {
+		dialOpts, err = directVizierDialOpts(isInternal)
+	} else {
+		dialOpts, err = services.GetGRPCClientDialOptsServerSideTLS(isInternal)
+	}
 	if err != nil {
 		return err
 	}
@@ -464,3 +470,57 @@ func (c *Connector) DebugPodsRequest(ctx context.Context) (chan *DebugPodsRespon
 	}()
 	return results, nil
 }
+
+// SelfTestResponse contains the self-test of one of the Vizier components.
+type SelfTestResponse struct {
+	Response *vizierpb.SelfTestResponse
+	Err      error
+}
+
+// SelfTestRequest sends a self-test request, which also checks the extra targets from every component, and
+// returns the reports of the components in a chan.
+func (c *Connector) SelfTestRequest(ctx context.Context, extraTargets []string, timeout time.Duration) (chan *SelfTestResponse, error) {
+	reqPB := &vizierpb.SelfTestRequest{
+		ClusterID:    c.id.String(),
+		ExtraTargets: extraTargets,
+		TimeoutNs:    timeout.Nanoseconds(),
+	}
+	ctx = auth.CtxWithCreds(ctx)
+	resp, err := c.vzDebug.SelfTest(ctx, reqPB)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(chan *SelfTestResponse)
+	go func() {
+		defer close(results)
+		for {
+			select {
+			case <-resp.Context().Done():
+				if resp.Context().Err() != nil {
+					results <- &SelfTestResponse{
+						Err: resp.Context().Err(),
+					}
+				}
+				return
+			case <-ctx.Done():
+				return
+			default:
+				msg, err := resp.Recv()
+				if msg == nil || err == io.EOF {
+					return
+				}
+				if err != nil {
+					results <- &SelfTestResponse{
+						Err: err,
+					}
+					return
+				}
+				results <- &SelfTestResponse{
+					Response: msg,
+				}
+			}
+		}
+	}()
+	return results, nil
+}
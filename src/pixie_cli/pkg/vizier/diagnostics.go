@@ -0,0 +1,264 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package vizier
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+)
+
+// certExpiryWarning is how long before a Vizier cert expires that it is reported.
+const certExpiryWarning = 30 * 24 * time.Hour
+
+const requirementsDocs = "https://docs.px.dev/installing-pixie/requirements/"
+
+// Severity ranks how urgently a Finding needs to be addressed.
+type Severity int
+
+const (
+	// SeverityInfo is for a limitation that doesn't need to be fixed.
+	SeverityInfo Severity = iota
+	// SeverityWarning is for a problem that degrades Pixie, or will break it in the future.
+	SeverityWarning
+	// SeverityCritical is for a problem that currently breaks Pixie.
+	SeverityCritical
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityCritical:
+		return "critical"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// Finding is a problem found with the cluster or the Vizier on it, and how to fix it.
+type Finding struct {
+	Severity Severity
+	// Check is the name of the check that produced the finding.
+	Check       string
+	Description string
+	Remediation string
+	// Fix automatically remediates the finding. It is only set when the fix is safe to apply without review.
+	Fix func(ctx context.Context) error
+}
+
+// kernelFeature is a Pixie feature that needs a newer kernel than Pixie itself does.
+type kernelFeature struct {
+	Name       string
+	MinVersion string
+}
+
+// kernelFeatures are the features whose availability is reported for each node.
+var kernelFeatures = []kernelFeature{
+	{Name: "gRPC-C tracing", MinVersion: "5.3.0"},
+}
+
+// PreflightFindings runs the given checks, and returns a Finding with the given severity for each one that fails.
+func PreflightFindings(checks []utils.Checker, severity Severity) []*Finding {
+	var findings []*Finding
+	for _, c := range checks {
+		if err := c.Check(); err != nil {
+			findings = append(findings, &Finding{
+				Severity:    severity,
+				Check:       c.Name(),
+				Description: err.Error(),
+				Remediation: fmt.Sprintf("See %s for the cluster requirements.", requirementsDocs),
+			})
+		}
+	}
+	return findings
+}
+
+// Diagnose inspects the running cluster, and the Vizier in the given namespace, for known problems.
+func Diagnose(ctx context.Context, clientset kubernetes.Interface, namespace string) ([]*Finding, error) {
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	findings := crashLoopFindings(clientset, pods.Items)
+	findings = append(findings, versionSkewFindings(pods.Items)...)
+
+	certs, err := certExpiryFindings(ctx, clientset, namespace, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	findings = append(findings, certs...)
+
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	findings = append(findings, kernelFindings(nodes.Items)...)
+	return findings, nil
+}
+
+// RankFindings sorts the findings so that the most severe ones come first.
+func RankFindings(findings []*Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Severity > findings[j].Severity
+	})
+}
+
+func crashLoopFindings(clientset kubernetes.Interface, pods []v1.Pod) []*Finding {
+	var findings []*Finding
+	for _, p := range pods {
+		for _, c := range p.Status.ContainerStatuses {
+			if c.State.Waiting == nil || c.State.Waiting.Reason != "CrashLoopBackOff" {
+				continue
+			}
+			f := &Finding{
+				Severity: SeverityCritical,
+				Check:    "Crash-looping pods",
+				Description: fmt.Sprintf("Container %s of pod %s is crash looping (%d restarts).",
+					c.Name, p.Name, c.RestartCount),
+				Remediation: fmt.Sprintf("Check the logs of the last crash with `kubectl logs -n %s %s -c %s --previous`.",
+					p.Namespace, p.Name, c.Name),
+			}
+			// Pods that belong to a controller are recreated once deleted, which also resets their backoff.
+			if len(p.OwnerReferences) > 0 {
+				ns, name := p.Namespace, p.Name
+				f.Remediation += " Restarting the pod clears the backoff once the cause is fixed."
+				f.Fix = func(ctx context.Context) error {
+					return clientset.CoreV1().Pods(ns).Delete(ctx, name, metav1.DeleteOptions{})
+				}
+			}
+			findings = append(findings, f)
+			break
+		}
+	}
+	return findings
+}
+
+func versionSkewFindings(pods []v1.Pod) []*Finding {
+	versions := make(map[string][]string)
+	for _, p := range pods {
+		if p.Labels["component"] != "vizier" || len(p.Spec.Containers) == 0 {
+			continue
+		}
+		if tag := imageTag(p.Spec.Containers[0].Image); tag != "" {
+			versions[tag] = append(versions[tag], p.Name)
+		}
+	}
+	if len(versions) < 2 {
+		return nil
+	}
+
+	tags := make([]string, 0, len(versions))
+	for tag := range versions {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	var desc []string
+	for _, tag := range tags {
+		desc = append(desc, fmt.Sprintf("%s (%s)", tag, strings.Join(versions[tag], ", ")))
+	}
+	return []*Finding{{
+		Severity:    SeverityWarning,
+		Check:       "Vizier version skew",
+		Description: fmt.Sprintf("Vizier pods run different versions: %s.", strings.Join(desc, "; ")),
+		Remediation: "An update may not have finished. Check `px status`, and rerun `px update vizier` if it failed.",
+	}}
+}
+
+func certExpiryFindings(ctx context.Context, clientset kubernetes.Interface, namespace string, now time.Time) ([]*Finding, error) {
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, "service-tls-certs", metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []*Finding
+	for _, key := range []string{"ca.crt", "server.crt", "client.crt"} {
+		block, _ := pem.Decode(secret.Data[key])
+		if block == nil {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			findings = append(findings, &Finding{
+				Severity:    SeverityCritical,
+				Check:       "TLS certificate expiry",
+				Description: fmt.Sprintf("Certificate %s in secret service-tls-certs can't be parsed: %v.", key, err),
+				Remediation: "Redeploy Vizier with `px deploy` to generate new certificates.",
+			})
+			continue
+		}
+		remediation := "The operator regenerates expiring certificates. If Vizier was deployed without the operator, " +
+			"redeploy it with `px deploy`."
+		switch {
+		case now.After(cert.NotAfter):
+			findings = append(findings, &Finding{
+				Severity:    SeverityCritical,
+				Check:       "TLS certificate expiry",
+				Description: fmt.Sprintf("Certificate %s in secret service-tls-certs expired on %s.", key, cert.NotAfter.Format(time.RFC3339)),
+				Remediation: remediation,
+			})
+		case now.Add(certExpiryWarning).After(cert.NotAfter):
+			findings = append(findings, &Finding{
+				Severity:    SeverityWarning,
+				Check:       "TLS certificate expiry",
+				Description: fmt.Sprintf("Certificate %s in secret service-tls-certs expires on %s.", key, cert.NotAfter.Format(time.RFC3339)),
+				Remediation: remediation,
+			})
+		}
+	}
+	return findings, nil
+}
+
+func kernelFindings(nodes []v1.Node) []*Finding {
+	var findings []*Finding
+	for _, feature := range kernelFeatures {
+		var unsupported []string
+		for _, n := range nodes {
+			ok, err := utils.VersionCompatible(n.Status.NodeInfo.KernelVersion, feature.MinVersion)
+			if err != nil || !ok {
+				unsupported = append(unsupported, fmt.Sprintf("%s (%s)", n.Name, n.Status.NodeInfo.KernelVersion))
+			}
+		}
+		if len(unsupported) == 0 {
+			continue
+		}
+		findings = append(findings, &Finding{
+			Severity: SeverityInfo,
+			Check:    "Kernel capabilities",
+			Description: fmt.Sprintf("%s needs kernel %s or newer, and is unavailable on nodes %s.",
+				feature.Name, feature.MinVersion, strings.Join(unsupported, ", ")),
+			Remediation: "Upgrade the kernel on these nodes to use this feature.",
+		})
+	}
+	return findings
+}
@@ -0,0 +1,214 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package vizier_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/pixie_cli/pkg/vizier"
+)
+
+func selfSignedCert(t *testing.T, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "pixie"},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func vizierPod(name, image string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "pl", Labels: vizierLabels},
+		Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "app", Image: image}}},
+		Status:     v1.PodStatus{Phase: v1.PodRunning},
+	}
+}
+
+func node(name, kernel string) *v1.Node {
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{KernelVersion: kernel}},
+	}
+}
+
+func TestDiagnose_Healthy(t *testing.T) {
+	objs := []runtime.Object{
+		vizierPod("kelvin-1", "gcr.io/pixie-oss/pixie-prod/vizier-kelvin_image:0.10.0"),
+		vizierPod("vizier-pem-1", "gcr.io/pixie-oss/pixie-prod/vizier-pem_image:0.10.0"),
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "service-tls-certs", Namespace: "pl"},
+			Data:       map[string][]byte{"server.crt": selfSignedCert(t, time.Now().Add(365*24*time.Hour))},
+		},
+		node("node-1", "5.15.0-1019-gcp"),
+	}
+	findings, err := vizier.Diagnose(context.Background(), fake.NewSimpleClientset(objs...), "pl")
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+}
+
+func TestDiagnose_CrashLoop(t *testing.T) {
+	owned := vizierPod("vizier-pem-1", "gcr.io/pixie-oss/pixie-prod/vizier-pem_image:0.10.0")
+	owned.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "vizier-pem"}}
+	owned.Status.ContainerStatuses = []v1.ContainerStatus{{
+		Name:         "pem",
+		RestartCount: 7,
+		State:        v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+	}}
+	bare := vizierPod("kelvin-1", "gcr.io/pixie-oss/pixie-prod/vizier-kelvin_image:0.10.0")
+	bare.Status.ContainerStatuses = owned.Status.ContainerStatuses
+
+	clientset := fake.NewSimpleClientset(owned, bare)
+	findings, err := vizier.Diagnose(context.Background(), clientset, "pl")
+	require.NoError(t, err)
+	require.Len(t, findings, 2)
+
+	byPod := map[string]*vizier.Finding{}
+	for _, f := range findings {
+		assert.Equal(t, vizier.SeverityCritical, f.Severity)
+		assert.Equal(t, "Crash-looping pods", f.Check)
+		if f.Fix != nil {
+			byPod["vizier-pem-1"] = f
+		} else {
+			byPod["kelvin-1"] = f
+		}
+	}
+	require.Contains(t, byPod, "vizier-pem-1")
+	require.Contains(t, byPod, "kelvin-1")
+	assert.Contains(t, byPod["vizier-pem-1"].Description, "(7 restarts)")
+	assert.Contains(t, byPod["kelvin-1"].Remediation, "kubectl logs -n pl kelvin-1 -c pem --previous")
+
+	require.NoError(t, byPod["vizier-pem-1"].Fix(context.Background()))
+	pods, err := clientset.CoreV1().Pods("pl").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, pods.Items, 1)
+	assert.Equal(t, "kelvin-1", pods.Items[0].Name)
+}
+
+func TestDiagnose_VersionSkew(t *testing.T) {
+	objs := []runtime.Object{
+		vizierPod("kelvin-1", "gcr.io/pixie-oss/pixie-prod/vizier-kelvin_image:0.10.0"),
+		vizierPod("vizier-pem-1", "gcr.io/pixie-oss/pixie-prod/vizier-pem_image:0.11.0"),
+		vizierPod("vizier-pem-2", "gcr.io/pixie-oss/pixie-prod/vizier-pem_image:0.11.0"),
+	}
+	findings, err := vizier.Diagnose(context.Background(), fake.NewSimpleClientset(objs...), "pl")
+	require.NoError(t, err)
+	require.Len(t, findings, 1)
+	assert.Equal(t, vizier.SeverityWarning, findings[0].Severity)
+	assert.Equal(t, "Vizier pods run different versions: 0.10.0 (kelvin-1); 0.11.0 (vizier-pem-1, vizier-pem-2).",
+		findings[0].Description)
+	assert.Nil(t, findings[0].Fix)
+}
+
+func TestDiagnose_CertExpiry(t *testing.T) {
+	tests := []struct {
+		name     string
+		notAfter time.Time
+		severity vizier.Severity
+	}{
+		{
+			name:     "expired",
+			notAfter: time.Now().Add(-time.Hour),
+			severity: vizier.SeverityCritical,
+		},
+		{
+			name:     "expiring",
+			notAfter: time.Now().Add(7 * 24 * time.Hour),
+			severity: vizier.SeverityWarning,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			secret := &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "service-tls-certs", Namespace: "pl"},
+				Data:       map[string][]byte{"ca.crt": selfSignedCert(t, test.notAfter)},
+			}
+			findings, err := vizier.Diagnose(context.Background(), fake.NewSimpleClientset(secret), "pl")
+			require.NoError(t, err)
+			require.Len(t, findings, 1)
+			assert.Equal(t, test.severity, findings[0].Severity)
+			assert.Equal(t, "TLS certificate expiry", findings[0].Check)
+			assert.Contains(t, findings[0].Description, "ca.crt")
+		})
+	}
+}
+
+func TestDiagnose_KernelCapabilities(t *testing.T) {
+	objs := []runtime.Object{
+		node("node-1", "5.15.0-1019-gcp"),
+		node("node-2", "4.19.0-21-amd64"),
+	}
+	findings, err := vizier.Diagnose(context.Background(), fake.NewSimpleClientset(objs...), "pl")
+	require.NoError(t, err)
+	require.Len(t, findings, 1)
+	assert.Equal(t, vizier.SeverityInfo, findings[0].Severity)
+	assert.Equal(t, "gRPC-C tracing needs kernel 5.3.0 or newer, and is unavailable on nodes node-2 (4.19.0-21-amd64).",
+		findings[0].Description)
+}
+
+func TestPreflightFindings(t *testing.T) {
+	checks := []utils.Checker{
+		utils.NamedCheck("passes", func() error { return nil }),
+		utils.NamedCheck("fails", func() error { return errors.New("not supported") }),
+	}
+	findings := vizier.PreflightFindings(checks, vizier.SeverityWarning)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "fails", findings[0].Check)
+	assert.Equal(t, "not supported", findings[0].Description)
+	assert.Equal(t, vizier.SeverityWarning, findings[0].Severity)
+}
+
+func TestRankFindings(t *testing.T) {
+	findings := []*vizier.Finding{
+		{Check: "a", Severity: vizier.SeverityInfo},
+		{Check: "b", Severity: vizier.SeverityCritical},
+		{Check: "c", Severity: vizier.SeverityWarning},
+		{Check: "d", Severity: vizier.SeverityCritical},
+	}
+	vizier.RankFindings(findings)
+	var order []string
+	for _, f := range findings {
+		order = append(order, f.Check)
+	}
+	assert.Equal(t, []string{"b", "d", "c", "a"}, order)
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package vizier
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding/gzip"
+
+	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
+	"px.dev/pixie/src/shared/services"
+)
+
+// PublicKeyPin returns the pin for the certificate's public key, which is the base64 encoded SHA-256 hash of its
+// DER encoded SubjectPublicKeyInfo.
+func PublicKeyPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// directVizierConfig returns the TLS settings for direct Vizier connections. Settings from flags take precedence over
+// the ones in the CLI config.
+func directVizierConfig() *pxconfig.DirectVizierConfig {
+	cfg := &pxconfig.DirectVizierConfig{}
+	if stored := pxconfig.Cfg().DirectVizier; stored != nil {
+		*cfg = *stored
+	}
+	if v := viper.GetString("direct_vizier_client_cert"); v != "" {
+		cfg.ClientCert = v
+	}
+	if v := viper.GetString("direct_vizier_client_key"); v != "" {
+		cfg.ClientKey = v
+	}
+	if v := viper.GetString("direct_vizier_ca_cert"); v != "" {
+		cfg.CACert = v
+	}
+	if v := viper.GetStringSlice("direct_vizier_pinned_keys"); len(v) > 0 {
+		cfg.PinnedKeys = v
+	}
+	return cfg
+}
+
+// DirectTLSConfig creates the TLS config for a direct connection to a Vizier. Internal Vizier addresses are not
+// verified unless a CA cert or pinned keys are configured, matching connections made without these settings.
+func DirectTLSConfig(cfg *pxconfig.DirectVizierConfig, isInternal bool) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if (cfg.ClientCert == "") != (cfg.ClientKey == "") {
+		return nil, errors.New("both a client cert and key must be specified for mutual TLS")
+	}
+	if cfg.ClientCert != "" {
+		pair, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client cert: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{pair}
+	}
+
+	if cfg.CACert != "" {
+		caCert, err := os.ReadFile(cfg.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA cert: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, errors.New("failed to parse CA cert")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if len(cfg.PinnedKeys) == 0 {
+		tlsConfig.InsecureSkipVerify = isInternal && cfg.CACert == ""
+		return tlsConfig, nil
+	}
+
+	pins := make(map[string]bool)
+	for _, p := range cfg.PinnedKeys {
+		pins[p] = true
+	}
+	// On-prem Viziers commonly serve self-signed certs. If there is no CA to verify the chain with, the pinned key is
+	// the only thing that is trusted.
+	tlsConfig.InsecureSkipVerify = cfg.CACert == ""
+	tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("vizier did not present a certificate")
+		}
+		leaf, err := x509.ParseCertificate(rawCerts[0])
+		if err != nil {
+			return err
+		}
+		pin := PublicKeyPin(leaf)
+		if !pins[pin] {
+			return fmt.Errorf("vizier public key %s does not match any of the pinned keys", pin)
+		}
+		return nil
+	}
+	return tlsConfig, nil
+}
+
+func directVizierDialOpts(isInternal bool) ([]grpc.DialOption, error) {
+	if viper.GetBool("disable_ssl") {
+		return services.GetGRPCClientDialOptsServerSideTLS(isInternal)
+	}
+
+	tlsConfig, err := DirectTLSConfig(directVizierConfig(), isInternal)
+	if err != nil {
+		return nil, err
+	}
+	return []grpc.DialOption{
+		grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)),
+		grpc.WithTransportCredentials(credentials.NewTLS(services.ApplyTLSPolicy(tlsConfig))),
+	}, nil
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package vizier_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
+	"px.dev/pixie/src/pixie_cli/pkg/vizier"
+)
+
+func newTLSServer(t *testing.T, clientAuth tls.ClientAuthType) *httptest.Server {
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	s.TLS = &tls.Config{ClientAuth: clientAuth}
+	s.StartTLS()
+	t.Cleanup(s.Close)
+	return s
+}
+
+func get(t *testing.T, s *httptest.Server, cfg *pxconfig.DirectVizierConfig) error {
+	return getWithInternal(t, s, cfg, false)
+}
+
+func getWithInternal(t *testing.T, s *httptest.Server, cfg *pxconfig.DirectVizierConfig, isInternal bool) error {
+	tlsConfig, err := vizier.DirectTLSConfig(cfg, isInternal)
+	require.NoError(t, err)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	resp, err := client.Get(s.URL)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func TestDirectTLSConfig_PinnedKeys(t *testing.T) {
+	s := newTLSServer(t, tls.NoClientCert)
+	pin := vizier.PublicKeyPin(s.Certificate())
+
+	// The test server's cert is self-signed, so it is only trusted through the pin.
+	assert.NoError(t, get(t, s, &pxconfig.DirectVizierConfig{PinnedKeys: []string{pin}}))
+	assert.Error(t, get(t, s, &pxconfig.DirectVizierConfig{PinnedKeys: []string{"bm90IHRoZSBrZXk="}}))
+	assert.Error(t, get(t, s, &pxconfig.DirectVizierConfig{}))
+}
+
+func TestDirectTLSConfig_Internal(t *testing.T) {
+	s := newTLSServer(t, tls.NoClientCert)
+
+	// Internal addresses fall back to skipping verification when nothing to verify against is configured.
+	assert.NoError(t, getWithInternal(t, s, &pxconfig.DirectVizierConfig{}, true))
+	// Configured pinned keys are still enforced for internal addresses.
+	assert.Error(t, getWithInternal(t, s, &pxconfig.DirectVizierConfig{PinnedKeys: []string{"bm90IHRoZSBrZXk="}}, true))
+}
+
+func TestDirectTLSConfig_CACert(t *testing.T) {
+	s := newTLSServer(t, tls.NoClientCert)
+	dir := t.TempDir()
+	caPath := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(caPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw}), 0600))
+
+	assert.NoError(t, get(t, s, &pxconfig.DirectVizierConfig{CACert: caPath}))
+	assert.NoError(t, get(t, s, &pxconfig.DirectVizierConfig{
+		CACert:     caPath,
+		PinnedKeys: []string{vizier.PublicKeyPin(s.Certificate())},
+	}))
+}
+
+func TestDirectTLSConfig_ClientCert(t *testing.T) {
+	s := newTLSServer(t, tls.RequireAnyClientCert)
+	pin := vizier.PublicKeyPin(s.Certificate())
+	assert.Error(t, get(t, s, &pxconfig.DirectVizierConfig{PinnedKeys: []string{pin}}))
+
+	// Reuse the test server's cert and key as the client cert.
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "client.crt")
+	keyPath := filepath.Join(dir, "client.key")
+	keyDER, err := x509.MarshalPKCS8PrivateKey(s.TLS.Certificates[0].PrivateKey)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw}), 0600))
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600))
+
+	assert.NoError(t, get(t, s, &pxconfig.DirectVizierConfig{
+		ClientCert: certPath,
+		ClientKey:  keyPath,
+		PinnedKeys: []string{pin},
+	}))
+
+	_, err = vizier.DirectTLSConfig(&pxconfig.DirectVizierConfig{ClientCert: certPath}, false)
+	assert.Error(t, err)
+}
@@ -0,0 +1,357 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package vizier
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	vizierSelector     = "component=vizier"
+	monitoringSelector = "app=pl-monitoring"
+)
+
+// VizierInstall is the Vizier state found in a single namespace.
+type VizierInstall struct {
+	Namespace string
+	// ClusterID is read from pl-cluster-secrets, and is empty if that secret is missing.
+	ClusterID string
+	// Version is the image tag that the Vizier pods run.
+	Version string
+	// Components are the names of the Vizier deployments, daemonsets and statefulsets, sorted.
+	Components  []string
+	Pods        int
+	RunningPods int
+}
+
+// Partial returns whether the namespace has Vizier objects, but not a running Vizier.
+func (i *VizierInstall) Partial() bool {
+	return i.ClusterID == "" || i.RunningPods == 0
+}
+
+// CleanupKind is how a conflict between installs can be cleaned up.
+type CleanupKind int
+
+const (
+	// CleanupNone means the conflict needs to be resolved by hand.
+	CleanupNone CleanupKind = iota
+	// CleanupObject means the conflict is resolved by deleting a single cluster-scoped object.
+	CleanupObject
+	// CleanupNamespaceObjects means the conflict is resolved by deleting the Vizier objects in a namespace.
+	CleanupNamespaceObjects
+)
+
+// InstallConflict is a problem caused by more than one, or a partial, Pixie install.
+type InstallConflict struct {
+	Description string
+	Cleanup     CleanupKind
+	// Resource and Name identify the object that CleanupObject deletes, e.g. "clusterrolebinding".
+	Resource string
+	Name     string
+	// Namespace is the namespace that CleanupNamespaceObjects deletes from.
+	Namespace string
+}
+
+// InstallReport is the result of looking for Pixie installs in a cluster.
+type InstallReport struct {
+	Installs  []*VizierInstall
+	Conflicts []*InstallConflict
+}
+
+// FindVizierInstalls looks for every namespace with Vizier objects in it, and for cluster-scoped Pixie objects
+// that don't belong to a running install.
+func FindVizierInstalls(ctx context.Context, clientset kubernetes.Interface) (*InstallReport, error) {
+	installs, err := findInstalls(ctx, clientset)
+	if err != nil {
+		return nil, err
+	}
+	report := &InstallReport{}
+	for _, ns := range sortedKeys(installs) {
+		report.Installs = append(report.Installs, installs[ns])
+	}
+
+	var running []string
+	for _, i := range report.Installs {
+		if !i.Partial() {
+			running = append(running, i.Namespace)
+		}
+	}
+	if len(running) > 1 {
+		report.Conflicts = append(report.Conflicts, &InstallConflict{
+			Description: fmt.Sprintf("Vizier is running in more than one namespace (%s). Only one Vizier can run per cluster, "+
+				"since they share cluster-scoped RBAC. Delete all but one with `px delete -n <namespace>`.",
+				strings.Join(running, ", ")),
+		})
+	}
+	if len(report.Installs) > 1 {
+		for _, i := range report.Installs {
+			if !i.Partial() {
+				continue
+			}
+			report.Conflicts = append(report.Conflicts, &InstallConflict{
+				Description: fmt.Sprintf("Namespace %s has %d leftover Vizier objects but no running Vizier.",
+					i.Namespace, len(i.Components)+i.Pods),
+				Cleanup:   CleanupNamespaceObjects,
+				Namespace: i.Namespace,
+			})
+		}
+	}
+
+	rbac, err := findRBACConflicts(ctx, clientset, installs, running)
+	if err != nil {
+		return nil, err
+	}
+	report.Conflicts = append(report.Conflicts, rbac...)
+
+	webhooks, err := findWebhookConflicts(ctx, clientset, running)
+	if err != nil {
+		return nil, err
+	}
+	report.Conflicts = append(report.Conflicts, webhooks...)
+	return report, nil
+}
+
+func findInstalls(ctx context.Context, clientset kubernetes.Interface) (map[string]*VizierInstall, error) {
+	installs := make(map[string]*VizierInstall)
+	get := func(ns string) *VizierInstall {
+		if _, ok := installs[ns]; !ok {
+			installs[ns] = &VizierInstall{Namespace: ns}
+		}
+		return installs[ns]
+	}
+	opts := metav1.ListOptions{LabelSelector: vizierSelector}
+
+	deployments, err := clientset.AppsV1().Deployments("").List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range deployments.Items {
+		i := get(d.Namespace)
+		i.Components = append(i.Components, d.Name)
+	}
+	daemonSets, err := clientset.AppsV1().DaemonSets("").List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range daemonSets.Items {
+		i := get(d.Namespace)
+		i.Components = append(i.Components, d.Name)
+	}
+	statefulSets, err := clientset.AppsV1().StatefulSets("").List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range statefulSets.Items {
+		i := get(s.Namespace)
+		i.Components = append(i.Components, s.Name)
+	}
+
+	pods, err := clientset.CoreV1().Pods("").List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range pods.Items {
+		i := get(p.Namespace)
+		i.Pods++
+		if p.Status.Phase == v1.PodRunning {
+			i.RunningPods++
+		}
+		if i.Version == "" && len(p.Spec.Containers) > 0 {
+			i.Version = imageTag(p.Spec.Containers[0].Image)
+		}
+	}
+
+	for ns, i := range installs {
+		sort.Strings(i.Components)
+		secret, err := clientset.CoreV1().Secrets(ns).Get(ctx, "pl-cluster-secrets", metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		i.ClusterID = string(secret.Data["cluster-id"])
+	}
+	return installs, nil
+}
+
+func findRBACConflicts(ctx context.Context, clientset kubernetes.Interface, installs map[string]*VizierInstall, running []string) ([]*InstallConflict, error) {
+	var conflicts []*InstallConflict
+	opts := metav1.ListOptions{LabelSelector: monitoringSelector}
+
+	bindings, err := clientset.RbacV1().ClusterRoleBindings().List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	boundRoles := make(map[string]bool)
+	for _, b := range bindings.Items {
+		boundRoles[b.RoleRef.Name] = true
+		for _, s := range b.Subjects {
+			if s.Kind != "ServiceAccount" {
+				continue
+			}
+			if _, ok := installs[s.Namespace]; !ok {
+				conflicts = append(conflicts, &InstallConflict{
+					Description: fmt.Sprintf("ClusterRoleBinding %s grants access to namespace %s, which has no Vizier.",
+						b.Name, s.Namespace),
+					Cleanup:  CleanupObject,
+					Resource: "clusterrolebinding",
+					Name:     b.Name,
+				})
+				break
+			}
+			if len(running) == 1 && s.Namespace != running[0] {
+				conflicts = append(conflicts, &InstallConflict{
+					Description: fmt.Sprintf("ClusterRoleBinding %s grants access to namespace %s instead of the running Vizier in %s. "+
+						"Clean up %s, then redeploy Pixie in %s.", b.Name, s.Namespace, running[0], s.Namespace, running[0]),
+				})
+				break
+			}
+		}
+	}
+
+	roles, err := clientset.RbacV1().ClusterRoles().List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range roles.Items {
+		if boundRoles[r.Name] {
+			continue
+		}
+		conflicts = append(conflicts, &InstallConflict{
+			Description: fmt.Sprintf("ClusterRole %s is not bound by any Pixie ClusterRoleBinding.", r.Name),
+			Cleanup:     CleanupObject,
+			Resource:    "clusterrole",
+			Name:        r.Name,
+		})
+	}
+	return conflicts, nil
+}
+
+func findWebhookConflicts(ctx context.Context, clientset kubernetes.Interface, running []string) ([]*InstallConflict, error) {
+	var conflicts []*InstallConflict
+	isRunning := func(ns string) bool {
+		for _, r := range running {
+			if r == ns {
+				return true
+			}
+		}
+		return false
+	}
+	opts := metav1.ListOptions{LabelSelector: monitoringSelector}
+
+	mutating, err := clientset.AdmissionregistrationV1().MutatingWebhookConfigurations().List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range mutating.Items {
+		for _, h := range w.Webhooks {
+			if h.ClientConfig.Service != nil && !isRunning(h.ClientConfig.Service.Namespace) {
+				conflicts = append(conflicts, webhookConflict("mutatingwebhookconfiguration", w.Name, h.ClientConfig.Service.Namespace))
+				break
+			}
+		}
+	}
+
+	validating, err := clientset.AdmissionregistrationV1().ValidatingWebhookConfigurations().List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range validating.Items {
+		for _, h := range w.Webhooks {
+			if h.ClientConfig.Service != nil && !isRunning(h.ClientConfig.Service.Namespace) {
+				conflicts = append(conflicts, webhookConflict("validatingwebhookconfiguration", w.Name, h.ClientConfig.Service.Namespace))
+				break
+			}
+		}
+	}
+	return conflicts, nil
+}
+
+func webhookConflict(resource, name, ns string) *InstallConflict {
+	return &InstallConflict{
+		Description: fmt.Sprintf("Webhook %s calls a service in namespace %s, which has no running Vizier. "+
+			"Requests it intercepts may fail.", name, ns),
+		Cleanup:  CleanupObject,
+		Resource: resource,
+		Name:     name,
+	}
+}
+
+// DiffInstalls describes how two installs differ.
+func DiffInstalls(a, b *VizierInstall) []string {
+	var diffs []string
+	if a.ClusterID != b.ClusterID {
+		diffs = append(diffs, fmt.Sprintf("cluster ID: %q in %s, %q in %s", a.ClusterID, a.Namespace, b.ClusterID, b.Namespace))
+	}
+	if a.Version != b.Version {
+		diffs = append(diffs, fmt.Sprintf("version: %q in %s, %q in %s", a.Version, a.Namespace, b.Version, b.Namespace))
+	}
+	if a.RunningPods != b.RunningPods || a.Pods != b.Pods {
+		diffs = append(diffs, fmt.Sprintf("running pods: %d/%d in %s, %d/%d in %s",
+			a.RunningPods, a.Pods, a.Namespace, b.RunningPods, b.Pods, b.Namespace))
+	}
+	if only := difference(a.Components, b.Components); len(only) > 0 {
+		diffs = append(diffs, fmt.Sprintf("only in %s: %s", a.Namespace, strings.Join(only, ", ")))
+	}
+	if only := difference(b.Components, a.Components); len(only) > 0 {
+		diffs = append(diffs, fmt.Sprintf("only in %s: %s", b.Namespace, strings.Join(only, ", ")))
+	}
+	return diffs
+}
+
+func difference(a, b []string) []string {
+	inB := make(map[string]bool, len(b))
+	for _, s := range b {
+		inB[s] = true
+	}
+	var out []string
+	for _, s := range a {
+		if !inB[s] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func imageTag(image string) string {
+	// Strip any digest, then take the tag after the last path component.
+	image = strings.SplitN(image, "@", 2)[0]
+	if idx := strings.LastIndex(image, ":"); idx > strings.LastIndex(image, "/") {
+		return image[idx+1:]
+	}
+	return ""
+}
+
+func sortedKeys(m map[string]*VizierInstall) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,150 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package vizier_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"px.dev/pixie/src/pixie_cli/pkg/vizier"
+)
+
+var vizierLabels = map[string]string{"app": "pl-monitoring", "component": "vizier"}
+
+func vizierObjects(ns, clusterID string, running bool) []runtime.Object {
+	phase := v1.PodPending
+	if running {
+		phase = v1.PodRunning
+	}
+	objs := []runtime.Object{
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "kelvin", Namespace: ns, Labels: vizierLabels}},
+		&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "vizier-pem", Namespace: ns, Labels: vizierLabels}},
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "kelvin-1", Namespace: ns, Labels: vizierLabels},
+			Spec:       v1.PodSpec{Containers: []v1.Container{{Image: "gcr.io/pixie-oss/pixie-prod/vizier-kelvin_image:0.10.0"}}},
+			Status:     v1.PodStatus{Phase: phase},
+		},
+	}
+	if clusterID != "" {
+		objs = append(objs, &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "pl-cluster-secrets", Namespace: ns},
+			Data:       map[string][]byte{"cluster-id": []byte(clusterID)},
+		})
+	}
+	return objs
+}
+
+func clusterBinding(name, ns string) *rbacv1.ClusterRoleBinding {
+	return &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: vizierLabels},
+		RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: "pl-node-view"},
+		Subjects:   []rbacv1.Subject{{Kind: "ServiceAccount", Name: "default", Namespace: ns}},
+	}
+}
+
+func TestFindVizierInstalls_SingleInstall(t *testing.T) {
+	objs := vizierObjects("pl", "cluster-1", true)
+	objs = append(objs,
+		clusterBinding("pl-node-view-cluster-binding", "pl"),
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "pl-node-view", Labels: vizierLabels}},
+	)
+	report, err := vizier.FindVizierInstalls(context.Background(), fake.NewSimpleClientset(objs...))
+	require.NoError(t, err)
+
+	require.Len(t, report.Installs, 1)
+	i := report.Installs[0]
+	assert.Equal(t, "pl", i.Namespace)
+	assert.Equal(t, "cluster-1", i.ClusterID)
+	assert.Equal(t, "0.10.0", i.Version)
+	assert.Equal(t, []string{"kelvin", "vizier-pem"}, i.Components)
+	assert.False(t, i.Partial())
+	assert.Empty(t, report.Conflicts)
+}
+
+func TestFindVizierInstalls_Duplicates(t *testing.T) {
+	objs := append(vizierObjects("pl", "cluster-1", true), vizierObjects("pixie", "cluster-2", true)...)
+	report, err := vizier.FindVizierInstalls(context.Background(), fake.NewSimpleClientset(objs...))
+	require.NoError(t, err)
+
+	require.Len(t, report.Installs, 2)
+	require.Len(t, report.Conflicts, 1)
+	assert.Equal(t, vizier.CleanupNone, report.Conflicts[0].Cleanup)
+	assert.Contains(t, report.Conflicts[0].Description, "pixie, pl")
+}
+
+func TestFindVizierInstalls_PartialAndStale(t *testing.T) {
+	objs := append(vizierObjects("pl", "cluster-1", true), vizierObjects("old-pl", "", false)...)
+	objs = append(objs,
+		clusterBinding("pl-node-view-cluster-binding", "old-pl"),
+		clusterBinding("pl-updater-cluster-binding", "deleted"),
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "pl-unused", Labels: vizierLabels}},
+		&admissionv1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "pl-webhook", Labels: vizierLabels},
+			Webhooks: []admissionv1.MutatingWebhook{{
+				Name:         "pl.px.dev",
+				ClientConfig: admissionv1.WebhookClientConfig{Service: &admissionv1.ServiceReference{Namespace: "old-pl", Name: "webhook"}},
+			}},
+		},
+	)
+	report, err := vizier.FindVizierInstalls(context.Background(), fake.NewSimpleClientset(objs...))
+	require.NoError(t, err)
+	require.Len(t, report.Installs, 2)
+
+	byCleanup := make(map[vizier.CleanupKind][]*vizier.InstallConflict)
+	for _, c := range report.Conflicts {
+		byCleanup[c.Cleanup] = append(byCleanup[c.Cleanup], c)
+	}
+	require.Len(t, byCleanup[vizier.CleanupNamespaceObjects], 1)
+	assert.Equal(t, "old-pl", byCleanup[vizier.CleanupNamespaceObjects][0].Namespace)
+
+	var deletable []string
+	for _, c := range byCleanup[vizier.CleanupObject] {
+		deletable = append(deletable, c.Resource+"/"+c.Name)
+	}
+	assert.ElementsMatch(t, []string{
+		"clusterrolebinding/pl-updater-cluster-binding",
+		"clusterrole/pl-unused",
+		"mutatingwebhookconfiguration/pl-webhook",
+	}, deletable)
+
+	// The binding to the partial install can't be fixed by deleting it alone.
+	require.Len(t, byCleanup[vizier.CleanupNone], 1)
+	assert.Contains(t, byCleanup[vizier.CleanupNone][0].Description, "pl-node-view-cluster-binding")
+}
+
+func TestDiffInstalls(t *testing.T) {
+	a := &vizier.VizierInstall{Namespace: "pl", ClusterID: "c1", Version: "0.10.0", Components: []string{"kelvin", "vizier-pem"}, Pods: 2, RunningPods: 2}
+	b := &vizier.VizierInstall{Namespace: "pixie", ClusterID: "c1", Version: "0.9.0", Components: []string{"vizier-pem"}, Pods: 1}
+	assert.Equal(t, []string{
+		`version: "0.10.0" in pl, "0.9.0" in pixie`,
+		"running pods: 2/2 in pl, 0/1 in pixie",
+		"only in pl: kelvin",
+	}, vizier.DiffInstalls(a, b))
+	assert.Empty(t, vizier.DiffInstalls(a, a))
+}
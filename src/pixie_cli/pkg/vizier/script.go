@@ -30,6 +30,7 @@ import (
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc/codes"
 
+	"px.dev/pixie/src/api/go/pxapi"
 	apiutils "px.dev/pixie/src/api/go/pxapi/utils"
 	"px.dev/pixie/src/api/proto/vizierpb"
 	"px.dev/pixie/src/pixie_cli/pkg/pxanalytics"
@@ -58,15 +59,24 @@ func (t *taskWrapper) Run() error {
 	return t.run()
 }
 
-// RunScriptAndOutputResults runs the specified script on vizier and outputs based on format string.
-func RunScriptAndOutputResults(ctx context.Context, conns []*Connector, execScript *script.ExecutableScript, format string, useEncryption bool) error {
+// RunScriptAndOutputResults runs the specified script on vizier and outputs based on format string. If maxRows is
+// positive, only the first maxRows rows of each table are output, and Vizier is asked to stop after one more, so that
+// the truncated tables can be warned about. Streaming scripts aren't limited.
+func RunScriptAndOutputResults(ctx context.Context, conns []*Connector, execScript *script.ExecutableScript, format string, useEncryption bool, maxRows int64) error {
 	// Check for the presence of df.stream() in the query.
-	if strings.Contains(execScript.ScriptString, "stream()") && format != "json" {
+	streaming := strings.Contains(execScript.ScriptString, "stream()")
+	if streaming && format != "json" {
 		return fmt.Errorf("Cannot execute a query containing df.stream() using px run with table output. " +
 			"Please try using `px live` instead or setting output format to json (`-o json`).")
 	}
+	if streaming {
+		maxRows = 0
+	}
+	if maxRows > 0 {
+		execScript.ScriptString = pxapi.ApplyScriptOptions(execScript.ScriptString, pxapi.WithMaxRows(maxRows))
+	}
 
-	tw, err := runScript(ctx, conns, execScript, format, useEncryption)
+	tw, err := runScript(ctx, conns, execScript, format, useEncryption, maxRows)
 	if err == nil { // Script ran successfully.
 		err = tw.Finish()
 		if err != nil {
@@ -134,7 +144,7 @@ func RunScriptAndOutputResults(ctx context.Context, conns []*Connector, execScri
 
 		tries := 5
 		for tries > 0 {
-			tw, err = runScript(ctx, conns, execScript, format, useEncryption)
+			tw, err = runScript(ctx, conns, execScript, format, useEncryption, maxRows)
 			if err == nil {
 				schemaCh <- true
 				break
@@ -175,7 +185,7 @@ func RunScriptAndOutputResults(ctx context.Context, conns []*Connector, execScri
 	return err
 }
 
-func runScript(ctx context.Context, conns []*Connector, execScript *script.ExecutableScript, format string, useEncryption bool) (*StreamOutputAdapter, error) {
+func runScript(ctx context.Context, conns []*Connector, execScript *script.ExecutableScript, format string, useEncryption bool, maxRows int64) (*StreamOutputAdapter, error) {
 	var encOpts, decOpts *vizierpb.ExecuteScriptRequest_EncryptionOptions
 	var err error
 	if useEncryption {
@@ -190,7 +200,7 @@ func runScript(ctx context.Context, conns []*Connector, execScript *script.Execu
 		return nil, err
 	}
 
-	tw := NewStreamOutputAdapter(ctx, resp, format, decOpts)
+	tw := NewStreamOutputAdapterWithMaxRows(ctx, resp, format, decOpts, maxRows)
 	err = tw.WaitForCompletion()
 	return tw, err
 }
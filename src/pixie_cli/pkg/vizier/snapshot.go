@@ -0,0 +1,223 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package vizier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/operator/client/versioned"
+)
+
+const snapshotTimeFormat = "20060102T150405Z"
+
+// snapshotSecrets are the secrets that an upgrade may rewrite and a rollback needs to put back.
+var snapshotSecrets = []string{"pl-cluster-secrets", "pl-deploy-secrets"}
+
+// ErrNoSnapshot is returned when there is no saved snapshot for a cluster.
+var ErrNoSnapshot = errors.New("no snapshot found")
+
+// Snapshot is the state of a Vizier that is recorded before an upgrade, so that the upgrade can be rolled back.
+type Snapshot struct {
+	CreatedAt     time.Time        `json:"createdAt"`
+	ClusterID     string           `json:"clusterID"`
+	ClusterName   string           `json:"clusterName"`
+	Namespace     string           `json:"namespace"`
+	VizierVersion string           `json:"vizierVersion"`
+	Vizier        *v1alpha1.Vizier `json:"vizier"`
+	Secrets       []*v1.Secret     `json:"secrets"`
+}
+
+// TakeSnapshot records the Vizier CR and its secrets in the given namespace.
+func TakeSnapshot(ctx context.Context, clientset kubernetes.Interface, vzClient versioned.Interface, namespace string) (*Snapshot, error) {
+	vzs, err := vzClient.PxV1alpha1().Viziers(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if len(vzs.Items) != 1 {
+		return nil, fmt.Errorf("expected one Vizier in namespace %s, found %d", namespace, len(vzs.Items))
+	}
+	vz := vzs.Items[0].DeepCopy()
+
+	s := &Snapshot{
+		CreatedAt:     time.Now().UTC(),
+		Namespace:     namespace,
+		ClusterName:   vz.Spec.ClusterName,
+		VizierVersion: vz.Status.Version,
+		Vizier:        stripVizier(vz),
+	}
+	if s.VizierVersion == "" {
+		s.VizierVersion = vz.Spec.Version
+	}
+
+	secretNames := append([]string{}, snapshotSecrets...)
+	if vz.Spec.CustomDeployKeySecret != "" {
+		secretNames = append(secretNames, vz.Spec.CustomDeployKeySecret)
+	}
+	for _, name := range secretNames {
+		secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.Secrets = append(s.Secrets, stripSecret(secret))
+		if name == "pl-cluster-secrets" {
+			s.ClusterID = string(secret.Data["cluster-id"])
+			if s.ClusterName == "" {
+				s.ClusterName = string(secret.Data["cluster-name"])
+			}
+		}
+	}
+	if s.ClusterID == "" {
+		return nil, errors.New("could not determine the cluster ID from pl-cluster-secrets")
+	}
+	return s, nil
+}
+
+// stripVizier drops the fields of the CR that are owned by the API server or the operator.
+func stripVizier(vz *v1alpha1.Vizier) *v1alpha1.Vizier {
+	vz.ObjectMeta = metav1.ObjectMeta{
+		Name:        vz.Name,
+		Namespace:   vz.Namespace,
+		Labels:      vz.Labels,
+		Annotations: vz.Annotations,
+	}
+	vz.Status = v1alpha1.VizierStatus{}
+	return vz
+}
+
+func stripSecret(secret *v1.Secret) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        secret.Name,
+			Namespace:   secret.Namespace,
+			Labels:      secret.Labels,
+			Annotations: secret.Annotations,
+		},
+		Type: secret.Type,
+		Data: secret.Data,
+	}
+}
+
+// Save writes the snapshot to <dir>/<cluster ID>/<timestamp>.json and returns the path to it.
+// The snapshot contains secrets, so it is only readable by the current user.
+func (s *Snapshot) Save(dir string) (string, error) {
+	clusterDir := filepath.Join(dir, s.ClusterID)
+	if err := os.MkdirAll(clusterDir, 0700); err != nil {
+		return "", err
+	}
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(clusterDir, s.CreatedAt.Format(snapshotTimeFormat)+".json")
+	if err := os.WriteFile(path, b, 0600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// LoadSnapshot reads a snapshot written by Save.
+func LoadSnapshot(path string) (*Snapshot, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Snapshot{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("invalid snapshot %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// LatestSnapshotPath returns the path of the most recent snapshot saved for the cluster.
+func LatestSnapshotPath(dir, clusterID string) (string, error) {
+	entries, err := os.ReadDir(filepath.Join(dir, clusterID))
+	if os.IsNotExist(err) {
+		return "", ErrNoSnapshot
+	}
+	if err != nil {
+		return "", err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			names = append(names, e.Name())
+		}
+	}
+	if len(names) == 0 {
+		return "", ErrNoSnapshot
+	}
+	// The timestamp format sorts lexically.
+	sort.Strings(names)
+	return filepath.Join(dir, clusterID, names[len(names)-1]), nil
+}
+
+// Restore puts the secrets and the Vizier CR spec back to their state in the snapshot. The operator then
+// reconciles the Vizier to the snapshot's version.
+func (s *Snapshot) Restore(ctx context.Context, clientset kubernetes.Interface, vzClient versioned.Interface) error {
+	for _, secret := range s.Secrets {
+		if err := restoreSecret(ctx, clientset, s.Namespace, secret); err != nil {
+			return fmt.Errorf("failed to restore secret %s: %w", secret.Name, err)
+		}
+	}
+
+	vzs := vzClient.PxV1alpha1().Viziers(s.Namespace)
+	current, err := vzs.Get(ctx, s.Vizier.Name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		_, err = vzs.Create(ctx, s.Vizier.DeepCopy(), metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	current.Spec = s.Vizier.Spec
+	_, err = vzs.Update(ctx, current, metav1.UpdateOptions{})
+	return err
+}
+
+func restoreSecret(ctx context.Context, clientset kubernetes.Interface, namespace string, secret *v1.Secret) error {
+	secrets := clientset.CoreV1().Secrets(namespace)
+	current, err := secrets.Get(ctx, secret.Name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		_, err = secrets.Create(ctx, secret.DeepCopy(), metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	current.Data = secret.Data
+	_, err = secrets.Update(ctx, current, metav1.UpdateOptions{})
+	return err
+}
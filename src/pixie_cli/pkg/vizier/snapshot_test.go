@@ -0,0 +1,153 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package vizier_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	vzfake "px.dev/pixie/src/operator/client/versioned/fake"
+	"px.dev/pixie/src/pixie_cli/pkg/vizier"
+)
+
+const testNamespace = "pl"
+
+func newSnapshotClients() (*fake.Clientset, *vzfake.Clientset) {
+	clientset := fake.NewSimpleClientset(
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "pl-cluster-secrets", Namespace: testNamespace},
+			Data: map[string][]byte{
+				"cluster-id":   []byte("7ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+				"cluster-name": []byte("test-cluster"),
+			},
+		},
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "pl-deploy-secrets", Namespace: testNamespace},
+			Data:       map[string][]byte{"deploy-key": []byte("old-key")},
+		},
+	)
+	vzClient := vzfake.NewSimpleClientset(&v1alpha1.Vizier{
+		ObjectMeta: metav1.ObjectMeta{Name: "pixie", Namespace: testNamespace, ResourceVersion: "10"},
+		Spec:       v1alpha1.VizierSpec{Version: "0.10.0", CloudAddr: "withpixie.ai:443"},
+		Status:     v1alpha1.VizierStatus{Version: "0.10.0"},
+	})
+	return clientset, vzClient
+}
+
+func TestSnapshot_TakeAndRestore(t *testing.T) {
+	ctx := context.Background()
+	clientset, vzClient := newSnapshotClients()
+
+	s, err := vizier.TakeSnapshot(ctx, clientset, vzClient, testNamespace)
+	require.NoError(t, err)
+	assert.Equal(t, "7ba7b810-9dad-11d1-80b4-00c04fd430c8", s.ClusterID)
+	assert.Equal(t, "test-cluster", s.ClusterName)
+	assert.Equal(t, "0.10.0", s.VizierVersion)
+	assert.Len(t, s.Secrets, 2)
+	assert.Empty(t, s.Vizier.ResourceVersion)
+	assert.Empty(t, s.Vizier.Status.Version)
+
+	// Simulate an upgrade that bumps the version and rewrites a secret.
+	vzs := vzClient.PxV1alpha1().Viziers(testNamespace)
+	vz, err := vzs.Get(ctx, "pixie", metav1.GetOptions{})
+	require.NoError(t, err)
+	vz.Spec.Version = "0.11.0"
+	_, err = vzs.Update(ctx, vz, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	secrets := clientset.CoreV1().Secrets(testNamespace)
+	_, err = secrets.Update(ctx, &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "pl-deploy-secrets", Namespace: testNamespace},
+		Data:       map[string][]byte{"deploy-key": []byte("new-key")},
+	}, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	require.NoError(t, s.Restore(ctx, clientset, vzClient))
+
+	vz, err = vzs.Get(ctx, "pixie", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "0.10.0", vz.Spec.Version)
+	secret, err := secrets.Get(ctx, "pl-deploy-secrets", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "old-key", string(secret.Data["deploy-key"]))
+}
+
+func TestSnapshot_RestoreRecreatesDeleted(t *testing.T) {
+	ctx := context.Background()
+	clientset, vzClient := newSnapshotClients()
+
+	s, err := vizier.TakeSnapshot(ctx, clientset, vzClient, testNamespace)
+	require.NoError(t, err)
+
+	require.NoError(t, vzClient.PxV1alpha1().Viziers(testNamespace).Delete(ctx, "pixie", metav1.DeleteOptions{}))
+	require.NoError(t, clientset.CoreV1().Secrets(testNamespace).Delete(ctx, "pl-deploy-secrets", metav1.DeleteOptions{}))
+
+	require.NoError(t, s.Restore(ctx, clientset, vzClient))
+
+	vz, err := vzClient.PxV1alpha1().Viziers(testNamespace).Get(ctx, "pixie", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "0.10.0", vz.Spec.Version)
+	_, err = clientset.CoreV1().Secrets(testNamespace).Get(ctx, "pl-deploy-secrets", metav1.GetOptions{})
+	require.NoError(t, err)
+}
+
+func TestSnapshot_NoVizier(t *testing.T) {
+	clientset, _ := newSnapshotClients()
+	_, err := vizier.TakeSnapshot(context.Background(), clientset, vzfake.NewSimpleClientset(), testNamespace)
+	assert.Error(t, err)
+}
+
+func TestSnapshot_SaveAndLatest(t *testing.T) {
+	dir := t.TempDir()
+	clusterID := "7ba7b810-9dad-11d1-80b4-00c04fd430c8"
+
+	_, err := vizier.LatestSnapshotPath(dir, clusterID)
+	assert.ErrorIs(t, err, vizier.ErrNoSnapshot)
+
+	older := &vizier.Snapshot{
+		CreatedAt:     time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		ClusterID:     clusterID,
+		VizierVersion: "0.9.0",
+		Vizier:        &v1alpha1.Vizier{ObjectMeta: metav1.ObjectMeta{Name: "pixie"}},
+	}
+	newer := &vizier.Snapshot{
+		CreatedAt:     time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC),
+		ClusterID:     clusterID,
+		VizierVersion: "0.10.0",
+		Vizier:        &v1alpha1.Vizier{ObjectMeta: metav1.ObjectMeta{Name: "pixie"}},
+	}
+	_, err = newer.Save(dir)
+	require.NoError(t, err)
+	_, err = older.Save(dir)
+	require.NoError(t, err)
+
+	path, err := vizier.LatestSnapshotPath(dir, clusterID)
+	require.NoError(t, err)
+	s, err := vizier.LoadSnapshot(path)
+	require.NoError(t, err)
+	assert.Equal(t, "0.10.0", s.VizierVersion)
+	assert.Equal(t, "pixie", s.Vizier.Name)
+}
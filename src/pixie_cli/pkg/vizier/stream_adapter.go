@@ -31,6 +31,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/fatih/color"
 	"github.com/gofrs/uuid"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc/status"
@@ -50,6 +51,8 @@ type TableInfo struct {
 	ID         string
 	relation   *vizierpb.Relation
 	timeColIdx int
+	// rows is the number of rows that were written to w.
+	rows int64
 }
 
 // ExecData contains information from script executions.
@@ -78,6 +81,18 @@ type StreamOutputAdapter struct {
 	err error
 
 	totalBytes int
+
+	// Data loss reported across all of the clusters that ran the script.
+	perfBufferLostEvents  int64
+	tablesWithEvictedData []string
+	// Agents that were left out of the script because they were unresponsive.
+	missingAgentIDs []string
+	// The largest skew between the clocks of the agents that ran the script, if it exceeded the threshold.
+	maxClockSkew time.Duration
+
+	// The limit on the rows written for each table, if positive, and the tables that had more rows.
+	maxRows         int64
+	truncatedTables []string
 }
 
 var (
@@ -94,6 +109,12 @@ const FormatInMemory string = "inmemory"
 func NewStreamOutputAdapterWithFactory(ctx context.Context, stream chan *ExecData, format string,
 	decOpts *vizierpb.ExecuteScriptRequest_EncryptionOptions,
 	factoryFunc func(*vizierpb.ExecuteScriptResponse_MetaData) components.OutputStreamWriter) *StreamOutputAdapter {
+	return newStreamOutputAdapter(ctx, stream, format, decOpts, factoryFunc, 0)
+}
+
+func newStreamOutputAdapter(ctx context.Context, stream chan *ExecData, format string,
+	decOpts *vizierpb.ExecuteScriptRequest_EncryptionOptions,
+	factoryFunc func(*vizierpb.ExecuteScriptResponse_MetaData) components.OutputStreamWriter, maxRows int64) *StreamOutputAdapter {
 	enableFormat := format != "json" && format != FormatInMemory
 
 	adapter := &StreamOutputAdapter{
@@ -104,6 +125,7 @@ func NewStreamOutputAdapterWithFactory(ctx context.Context, stream chan *ExecDat
 		formatters:          make(map[string]DataFormatter),
 		tabledIDToName:      make(map[string]string),
 		decOpts:             decOpts,
+		maxRows:             maxRows,
 	}
 
 	adapter.wg.Add(1)
@@ -114,10 +136,17 @@ func NewStreamOutputAdapterWithFactory(ctx context.Context, stream chan *ExecDat
 
 // NewStreamOutputAdapter creates a new vizier output adapter.
 func NewStreamOutputAdapter(ctx context.Context, stream chan *ExecData, format string, decOpts *vizierpb.ExecuteScriptRequest_EncryptionOptions) *StreamOutputAdapter {
+	return NewStreamOutputAdapterWithMaxRows(ctx, stream, format, decOpts, 0)
+}
+
+// NewStreamOutputAdapterWithMaxRows creates a new vizier output adapter, which only writes the first maxRows rows of
+// each table, if maxRows is positive. The tables that had more rows are reported by TruncatedTables, and are warned
+// about on Finish.
+func NewStreamOutputAdapterWithMaxRows(ctx context.Context, stream chan *ExecData, format string, decOpts *vizierpb.ExecuteScriptRequest_EncryptionOptions, maxRows int64) *StreamOutputAdapter {
 	factoryFunc := func(md *vizierpb.ExecuteScriptResponse_MetaData) components.OutputStreamWriter {
 		return components.CreateStreamWriter(format, os.Stdout)
 	}
-	return NewStreamOutputAdapterWithFactory(ctx, stream, format, decOpts, factoryFunc)
+	return newStreamOutputAdapter(ctx, stream, format, decOpts, factoryFunc, maxRows)
 }
 
 // Finish must be called to wait for the output and flush all the data.
@@ -131,9 +160,45 @@ func (v *StreamOutputAdapter) Finish() error {
 	for _, ti := range v.tableNameToInfo {
 		ti.w.Finish()
 	}
+	v.warnOnDataLoss()
 	return nil
 }
 
+// warnOnDataLoss lets the user know when the results are undercounted. The warning goes to stderr,
+// so that it does not break the formatted output.
+func (v *StreamOutputAdapter) warnOnDataLoss() {
+	if v.format == FormatInMemory {
+		return
+	}
+	warn := utils.WithColor(color.New(color.FgYellow))
+	if v.perfBufferLostEvents > 0 {
+		warn.Errorf("Warning: %d events were dropped during the time range of this script, so the results may be undercounted.", v.perfBufferLostEvents)
+	}
+	if len(v.tablesWithEvictedData) > 0 {
+		warn.Errorf("Warning: data in the time range of this script was already evicted from tables [%s], so the results may be undercounted.", strings.Join(v.tablesWithEvictedData, ", "))
+	}
+	if len(v.missingAgentIDs) > 0 {
+		warn.Errorf("Warning: %d unresponsive agents were left out of this script, so the results are partial. Missing agents: [%s]", len(v.missingAgentIDs), strings.Join(v.missingAgentIDs, ", "))
+	}
+	if len(v.truncatedTables) > 0 {
+		warn.Errorf("Warning: only the first %d rows of tables [%s] are shown. Use --max_rows to change the limit, or narrow the time range or filters of the script.", v.maxRows, strings.Join(v.truncatedTables, ", "))
+	}
+	if v.maxClockSkew > 0 {
+		warn.Errorf("Warning: the clocks of the nodes that ran this script are up to %s apart, so durations across nodes, such as the latency of cross-node requests, may be off by as much. Check the time synchronization (NTP) of the nodes.", v.maxClockSkew)
+	}
+}
+
+// DataLoss returns the data loss reported for the time range of the script. This function is only valid after Finish.
+func (v *StreamOutputAdapter) DataLoss() (int64, []string) {
+	return v.perfBufferLostEvents, v.tablesWithEvictedData
+}
+
+// TruncatedTables returns the tables that had more rows than the limit, so that only their first rows were written.
+// This function is only valid after Finish.
+func (v *StreamOutputAdapter) TruncatedTables() []string {
+	return v.truncatedTables
+}
+
 // WaitForCompletion waits for the stream to complete, but does not flush the data.
 func (v *StreamOutputAdapter) WaitForCompletion() error {
 	v.wg.Wait()
@@ -351,9 +416,34 @@ func (v *StreamOutputAdapter) parseError(ctx context.Context, s *vizierpb.Status
 
 func (v *StreamOutputAdapter) handleExecutionStats(ctx context.Context, es *vizierpb.QueryExecutionStats) error {
 	v.execStats = es
+	if dl := es.DataLoss; dl != nil {
+		v.perfBufferLostEvents += dl.PerfBufferLostEvents
+		for _, table := range dl.TablesWithEvictedData {
+			if !containsString(v.tablesWithEvictedData, table) {
+				v.tablesWithEvictedData = append(v.tablesWithEvictedData, table)
+			}
+		}
+	}
+	for _, agentID := range es.MissingAgentIDs {
+		if !containsString(v.missingAgentIDs, agentID) {
+			v.missingAgentIDs = append(v.missingAgentIDs, agentID)
+		}
+	}
+	if cs := es.ClockSkew; cs != nil && time.Duration(cs.MaxSkewNs) > v.maxClockSkew {
+		v.maxClockSkew = time.Duration(cs.MaxSkewNs)
+	}
 	return nil
 }
 
+func containsString(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
+
 func (v *StreamOutputAdapter) handleMutationInfo(ctx context.Context, mi *vizierpb.MutationInfo) {
 	v.mutationInfo = mi
 }
@@ -402,6 +492,13 @@ func (v *StreamOutputAdapter) handleData(ctx context.Context, d *vizierpb.Execut
 
 	cols := d.Data.Batch.Cols
 	for rowIdx := 0; rowIdx < numRows; rowIdx++ {
+		if v.maxRows > 0 && tableInfo.rows >= v.maxRows {
+			if !containsString(v.truncatedTables, tableName) {
+				v.truncatedTables = append(v.truncatedTables, tableName)
+			}
+			break
+		}
+		tableInfo.rows++
 		// Add the cluster ID to the output colums.
 		rec := make([]interface{}, len(cols))
 		for colIdx, col := range cols {
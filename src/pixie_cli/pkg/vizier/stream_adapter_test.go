@@ -0,0 +1,78 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package vizier_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/pixie_cli/pkg/vizier"
+)
+
+func metadataResp(name string) *vizier.ExecData {
+	return &vizier.ExecData{Resp: &vizierpb.ExecuteScriptResponse{
+		Result: &vizierpb.ExecuteScriptResponse_MetaData{MetaData: &vizierpb.QueryMetadata{
+			Name: name,
+			ID:   name,
+			Relation: &vizierpb.Relation{Columns: []*vizierpb.Relation_ColumnInfo{
+				{ColumnName: "count", ColumnType: vizierpb.INT64},
+			}},
+		}},
+	}}
+}
+
+func int64DataResp(name string, data ...int64) *vizier.ExecData {
+	return &vizier.ExecData{Resp: &vizierpb.ExecuteScriptResponse{
+		Result: &vizierpb.ExecuteScriptResponse_Data{Data: &vizierpb.QueryData{
+			Batch: &vizierpb.RowBatchData{
+				TableID: name,
+				Cols: []*vizierpb.Column{
+					{ColData: &vizierpb.Column_Int64Data{Int64Data: &vizierpb.Int64Column{Data: data}}},
+				},
+				NumRows: int64(len(data)),
+			},
+		}},
+	}}
+}
+
+func TestStreamOutputAdapter_MaxRows(t *testing.T) {
+	stream := make(chan *vizier.ExecData, 10)
+	stream <- metadataResp("big")
+	stream <- metadataResp("small")
+	stream <- int64DataResp("big", 1, 2)
+	stream <- int64DataResp("small", 1, 2)
+	stream <- int64DataResp("big", 3, 4)
+	close(stream)
+
+	tw := vizier.NewStreamOutputAdapterWithMaxRows(context.Background(), stream, vizier.FormatInMemory, nil, 3)
+	require.NoError(t, tw.Finish())
+
+	views, err := tw.Views()
+	require.NoError(t, err)
+	rows := make(map[string]int)
+	for _, v := range views {
+		rows[v.Name()] = len(v.Data())
+	}
+	assert.Equal(t, map[string]int{"big": 3, "small": 2}, rows)
+	assert.Equal(t, []string{"big"}, tw.TruncatedTables())
+}
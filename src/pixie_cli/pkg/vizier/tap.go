@@ -0,0 +1,201 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package vizier
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/utils/script"
+)
+
+// TapProtocol is a traced protocol that px tap can stream the events of.
+type TapProtocol struct {
+	// Table is the table that holds the protocol's events.
+	Table string
+	// Fields are the columns that are shown by default.
+	Fields []string
+}
+
+// TapProtocols are the protocols supported by px tap, by name.
+var TapProtocols = map[string]TapProtocol{
+	"http":  {Table: "http_events", Fields: []string{"req_method", "req_path", "resp_status", "latency"}},
+	"dns":   {Table: "dns_events", Fields: []string{"req_body", "resp_body", "latency"}},
+	"mysql": {Table: "mysql_events", Fields: []string{"req_cmd", "req_body", "resp_status", "latency"}},
+	"pgsql": {Table: "pgsql_events", Fields: []string{"req_cmd", "req", "resp", "latency"}},
+	"redis": {Table: "redis_events", Fields: []string{"req_cmd", "req_args", "resp", "latency"}},
+	"kafka": {Table: "kafka_events.beta", Fields: []string{"req_cmd", "client_id", "req_body", "latency"}},
+	"cql":   {Table: "cql_events", Fields: []string{"req_op", "req_body", "resp_op", "latency"}},
+	"nats":  {Table: "nats_events.beta", Fields: []string{"cmd", "body", "resp"}},
+}
+
+// TapProtocolNames returns the names of the supported protocols, in order.
+func TapProtocolNames() []string {
+	names := make([]string, 0, len(TapProtocols))
+	for name := range TapProtocols {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TapOptions selects the events that px tap streams.
+type TapOptions struct {
+	Protocol string
+	// Pod, Namespace and Service filter the events to the matching pods. Pod and Service match
+	// if they contain the value, while Namespace has to match exactly.
+	Pod       string
+	Namespace string
+	Service   string
+	// Since is how far back the stream starts, for example -30s.
+	Since string
+	// Fields are the columns to show. Defaults to the fields of the protocol.
+	Fields []string
+}
+
+var (
+	// Keeps every event on a single line, even if its bodies span several.
+	tapNewlineReplacer = strings.NewReplacer("\n", `\n`, "\r", `\r`)
+	tapFieldRegex      = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	tapSinceRegex      = regexp.MustCompile(`^-[0-9]+(s|m|h)$`)
+)
+
+// TapScript returns the script that streams the events selected by the options.
+func TapScript(opts *TapOptions) (*script.ExecutableScript, error) {
+	protocol, ok := TapProtocols[opts.Protocol]
+	if !ok {
+		return nil, fmt.Errorf("unsupported protocol %q, expected one of: %s", opts.Protocol, strings.Join(TapProtocolNames(), ", "))
+	}
+	if !tapSinceRegex.MatchString(opts.Since) {
+		return nil, fmt.Errorf("invalid start time %q, expected a relative time such as -30s", opts.Since)
+	}
+	fields := opts.Fields
+	if len(fields) == 0 {
+		fields = protocol.Fields
+	}
+	for _, f := range fields {
+		if !tapFieldRegex.MatchString(f) {
+			return nil, fmt.Errorf("invalid field %q", f)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("import px\n")
+	fmt.Fprintf(&b, "df = px.DataFrame(table='%s', start_time='%s')\n", protocol.Table, opts.Since)
+	b.WriteString("df.pod = df.ctx['pod']\n")
+	if opts.Pod != "" {
+		fmt.Fprintf(&b, "df = df[px.contains(df.pod, %s)]\n", strconv.Quote(opts.Pod))
+	}
+	if opts.Namespace != "" {
+		b.WriteString("df.namespace = df.ctx['namespace']\n")
+		fmt.Fprintf(&b, "df = df[df.namespace == %s]\n", strconv.Quote(opts.Namespace))
+	}
+	if opts.Service != "" {
+		b.WriteString("df.service = df.ctx['service']\n")
+		fmt.Fprintf(&b, "df = df[px.contains(df.service, %s)]\n", strconv.Quote(opts.Service))
+	}
+
+	cols := []string{"'time_'", "'pod'"}
+	for _, f := range fields {
+		if f != "time_" && f != "pod" {
+			cols = append(cols, "'"+f+"'")
+		}
+	}
+	fmt.Fprintf(&b, "df = df[[%s]]\n", strings.Join(cols, ", "))
+	fmt.Fprintf(&b, "px.display(df.stream(), '%s')\n", opts.Protocol)
+
+	return &script.ExecutableScript{
+		ScriptName:   "px/tap",
+		ScriptString: b.String(),
+		IsLocal:      true,
+	}, nil
+}
+
+// TapWriter writes every event as a single line, in the order that the events arrive. It expects
+// unformatted values, and formats them itself with the formatter of the table.
+type TapWriter struct {
+	w         io.Writer
+	formatter DataFormatter
+	header    []string
+
+	timeSprint  func(a ...interface{}) string
+	podSprint   func(a ...interface{}) string
+	fieldSprint func(a ...interface{}) string
+}
+
+// NewTapWriter creates a writer for a table with the given relation.
+func NewTapWriter(w io.Writer, relation *vizierpb.Relation) *TapWriter {
+	return &TapWriter{
+		w:           w,
+		formatter:   NewDataFormatterForTable(relation),
+		timeSprint:  color.New(color.Faint).SprintFunc(),
+		podSprint:   color.New(color.FgCyan).SprintFunc(),
+		fieldSprint: color.New(color.FgBlue).SprintFunc(),
+	}
+}
+
+// SetHeader sets the names of the columns.
+func (t *TapWriter) SetHeader(id string, headerValues []string) {
+	t.header = headerValues
+}
+
+// Write writes a single event.
+func (t *TapWriter) Write(data []interface{}) error {
+	var line strings.Builder
+	for i, val := range data {
+		if i > 0 {
+			line.WriteByte(' ')
+		}
+		name := ""
+		if i < len(t.header) {
+			name = t.header[i]
+		}
+		switch name {
+		case "time_":
+			if ts, ok := val.(time.Time); ok {
+				line.WriteString(t.timeSprint(ts.Format("15:04:05.000")))
+				continue
+			}
+		case "pod":
+			line.WriteString(t.podSprint(val))
+			continue
+		}
+		formatted := tapNewlineReplacer.Replace(fmt.Sprintf("%v", t.formatter.FormatValue(i, val)))
+		if name == "" {
+			line.WriteString(formatted)
+			continue
+		}
+		line.WriteString(t.fieldSprint(name + "="))
+		line.WriteString(formatted)
+	}
+	line.WriteByte('\n')
+	_, err := io.WriteString(t.w, line.String())
+	return err
+}
+
+// Finish is a no-op, because every event is written as it arrives.
+func (t *TapWriter) Finish() {}
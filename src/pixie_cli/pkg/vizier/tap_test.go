@@ -0,0 +1,114 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package vizier_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/pixie_cli/pkg/vizier"
+)
+
+func TestTapScript(t *testing.T) {
+	s, err := vizier.TapScript(&vizier.TapOptions{
+		Protocol:  "http",
+		Pod:       "frontend",
+		Namespace: "sock-shop",
+		Since:     "-30s",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `import px
+df = px.DataFrame(table='http_events', start_time='-30s')
+df.pod = df.ctx['pod']
+df = df[px.contains(df.pod, "frontend")]
+df.namespace = df.ctx['namespace']
+df = df[df.namespace == "sock-shop"]
+df = df[['time_', 'pod', 'req_method', 'req_path', 'resp_status', 'latency']]
+px.display(df.stream(), 'http')
+`, s.ScriptString)
+}
+
+func TestTapScript_Fields(t *testing.T) {
+	s, err := vizier.TapScript(&vizier.TapOptions{
+		Protocol: "mysql",
+		Service:  `"quoted"`,
+		Since:    "-1m",
+		Fields:   []string{"time_", "req_body"},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, s.ScriptString, `df = df[px.contains(df.service, "\"quoted\"")]`)
+	assert.Contains(t, s.ScriptString, `df = df[['time_', 'pod', 'req_body']]`)
+}
+
+func TestTapScript_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		opts vizier.TapOptions
+	}{
+		{
+			name: "unknown protocol",
+			opts: vizier.TapOptions{Protocol: "smtp", Since: "-10s"},
+		},
+		{
+			name: "invalid since",
+			opts: vizier.TapOptions{Protocol: "http", Since: "10s"},
+		},
+		{
+			name: "invalid field",
+			opts: vizier.TapOptions{Protocol: "http", Since: "-10s", Fields: []string{"req_body']]"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := vizier.TapScript(&test.opts)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestTapWriter(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	relation := &vizierpb.Relation{
+		Columns: []*vizierpb.Relation_ColumnInfo{
+			{ColumnName: "time_", ColumnType: vizierpb.TIME64NS},
+			{ColumnName: "pod", ColumnType: vizierpb.STRING},
+			{ColumnName: "req_body", ColumnType: vizierpb.STRING},
+			{ColumnName: "resp_status", ColumnType: vizierpb.INT64, ColumnSemanticType: vizierpb.ST_HTTP_RESP_STATUS},
+			{ColumnName: "latency", ColumnType: vizierpb.INT64, ColumnSemanticType: vizierpb.ST_DURATION_NS},
+		},
+	}
+	var buf bytes.Buffer
+	w := vizier.NewTapWriter(&buf, relation)
+	w.SetHeader("http", []string{"time_", "pod", "req_body", "resp_status", "latency"})
+
+	ts := time.Date(2023, 1, 2, 3, 4, 5, 678000000, time.Local)
+	require.NoError(t, w.Write([]interface{}{ts, "ns/frontend", "a\nb", int64(200), int64(1500000)}))
+	w.Finish()
+
+	assert.Equal(t, "03:04:05.678 ns/frontend req_body=a\\nb resp_status=200 latency=1.5 ms\n", buf.String())
+}
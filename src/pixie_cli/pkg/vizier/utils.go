@@ -325,6 +325,41 @@ func ConnectToAllViziers(cloudAddr string) ([]*Connector, error) {
 	return conns, nil
 }
 
+// ConnectToViziers connects to the healthy viziers among the given cluster IDs, e.g. the clusters in a fleet.
+// Clusters that aren't healthy are skipped with a warning.
+func ConnectToViziers(cloudAddr string, clusterIDs []uuid.UUID) ([]*Connector, error) {
+	vzInfos, err := GetVizierList(cloudAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[uuid.UUID]bool, len(clusterIDs))
+	for _, id := range clusterIDs {
+		wanted[id] = true
+	}
+
+	var conns []*Connector
+	for _, vzInfo := range vzInfos {
+		if !wanted[utils.UUIDFromProtoOrNil(vzInfo.ID)] {
+			continue
+		}
+		if vzInfo.Status != cloudpb.CS_HEALTHY && vzInfo.Status != cloudpb.CS_DEGRADED {
+			cliUtils.Infof("Skipping cluster '%s', status is %s", vzInfo.PrettyClusterName, vzInfo.Status.String())
+			continue
+		}
+		c, err := createVizierConnection(cloudAddr, vzInfo)
+		if err != nil {
+			return nil, err
+		}
+		conns = append(conns, c)
+	}
+
+	if len(conns) == 0 {
+		return nil, errors.New("no healthy Viziers available")
+	}
+	return conns, nil
+}
+
 // GetClusterIDFromKubeConfig returns the clusterID given the kubeconfig. If anything fails, then will return a nil UUID.
 func GetClusterIDFromKubeConfig(config *rest.Config) uuid.UUID {
 	if config == nil {
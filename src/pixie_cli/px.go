@@ -42,9 +42,9 @@ import (
 const sentryDSN = "https://ef3a781b5e7b42e282706fc541077f3a@sentry.io/4090453"
 
 func main() {
-	// Disable Sentry in dev mode.
+	// Disable Sentry in dev mode, or if the user opted out of telemetry.
 	selectedDSN := sentryDSN
-	if version.GetVersion().IsDev() {
+	if version.GetVersion().IsDev() || pxconfig.DoNotTrack() {
 		selectedDSN = ""
 	}
 
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package alerting sends alert events to the external systems that both the Vizier alert notifiers and the cloud alert
+// plugins support.
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// DefaultPagerDutyURL is the endpoint of the PagerDuty Events API v2.
+const DefaultPagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDutyPayload describes a triggered PagerDuty event.
+type PagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Timestamp     string            `json:"timestamp"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+// PagerDutyEvent is a request to the PagerDuty Events API v2.
+type PagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *PagerDutyPayload `json:"payload,omitempty"`
+}
+
+// NewPagerDutyEvent creates an event that triggers an incident with the payload or, if the payload is nil, resolves
+// the incident that was triggered with the same dedup key.
+func NewPagerDutyEvent(routingKey, dedupKey string, payload *PagerDutyPayload) *PagerDutyEvent {
+	ev := &PagerDutyEvent{
+		RoutingKey:  routingKey,
+		EventAction: "trigger",
+		DedupKey:    dedupKey,
+		Payload:     payload,
+	}
+	if payload == nil {
+		ev.EventAction = "resolve"
+	}
+	return ev
+}
+
+// PagerDutySeverity returns the severity as one that PagerDuty accepts, defaulting to "error".
+func PagerDutySeverity(s string) string {
+	switch s {
+	case "critical", "error", "warning", "info":
+		return s
+	default:
+		return "error"
+	}
+}
+
+// PostJSON posts the JSON encoded body to the URL, and returns an error if the response isn't successful.
+func PostJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("alert endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
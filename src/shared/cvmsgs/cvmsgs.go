@@ -34,4 +34,8 @@ var (
 
 	// VizierMetricsChannel is the NATS channel on the cloud side that Vizier metrics are published to.
 	VizierMetricsChannel = "VZMetrics"
+	// AlertEventChannel is the NATS channel that alert events from cron script alert rules are published to.
+	AlertEventChannel = "AlertEvent"
+	// UsageReportChannel is the NATS channel that cron script usage reports are published to.
+	UsageReportChannel = "UsageReport"
 )
@@ -165,6 +165,8 @@ start_timestamp_ns: 6
 container_state: CONTAINER_STATE_RUNNING
 message: "Running message"
 reason: "Running reason"
+image: "gcr.io/pixie-oss/demo/app:1.4.2"
+image_id: "docker-pullable://gcr.io/pixie-oss/demo/app@sha256:abcdef"
 )";
 
 const char* kTerminatingContainerUpdatePbTxt = R"(
@@ -362,6 +364,15 @@ conditions: {
 }
 )";
 
+const char* kSelectorlessServiceUpdatePbTxt = R"(
+uid: "selectorless_service_uid"
+name: "external_db"
+namespace: "pl"
+start_timestamp_ns: 7
+endpoint_ips: "10.0.0.1"
+endpoint_ips: "10.0.0.2"
+)";
+
 std::unique_ptr<px::shared::k8s::metadatapb::ResourceUpdate> CreateRunningPodUpdatePB() {
   auto update = std::make_unique<px::shared::k8s::metadatapb::ResourceUpdate>();
   auto update_proto = absl::Substitute(kResourceUpdateTmpl, "pod_update", kRunningPodUpdatePbTxt);
@@ -370,6 +381,15 @@ std::unique_ptr<px::shared::k8s::metadatapb::ResourceUpdate> CreateRunningPodUpd
   return update;
 }
 
+std::unique_ptr<px::shared::k8s::metadatapb::ResourceUpdate> CreateSelectorlessServiceUpdatePB() {
+  auto update = std::make_unique<px::shared::k8s::metadatapb::ResourceUpdate>();
+  auto update_proto =
+      absl::Substitute(kResourceUpdateTmpl, "service_update", kSelectorlessServiceUpdatePbTxt);
+  CHECK(google::protobuf::TextFormat::MergeFromString(update_proto, update.get()))
+      << "Failed to parse proto";
+  return update;
+}
+
 std::unique_ptr<px::shared::k8s::metadatapb::ResourceUpdate> CreateReusedIPUpdatePB() {
   auto update = std::make_unique<px::shared::k8s::metadatapb::ResourceUpdate>();
   auto update_proto = absl::Substitute(kResourceUpdateTmpl, "pod_update", kReusedIPPodUpdatePbTxt);
@@ -22,7 +22,9 @@ import (
 	"fmt"
 
 	apps "k8s.io/api/apps/v1"
+	batch "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -492,6 +494,53 @@ func EndpointsFromProto(pb *metadatapb.Endpoints) *v1.Endpoints {
 	}
 }
 
+// EndpointSliceToProto converts an EndpointSlice into an Endpoints proto with a single subset.
+// The slice keeps its own metadata, the service it belongs to is given by its
+// "kubernetes.io/service-name" label.
+func EndpointSliceToProto(e *discovery.EndpointSlice) *metadatapb.Endpoints {
+	subset := &metadatapb.EndpointSubset{}
+	for _, ep := range e.Endpoints {
+		for _, ip := range ep.Addresses {
+			addr := &metadatapb.EndpointAddress{
+				IP: ip,
+			}
+			if ep.Hostname != nil {
+				addr.Hostname = *ep.Hostname
+			}
+			if ep.NodeName != nil {
+				addr.NodeName = *ep.NodeName
+			}
+			if ep.TargetRef != nil {
+				addr.TargetRef = ObjectReferenceToProto(ep.TargetRef)
+			}
+			// An endpoint with unknown readiness should be treated as ready.
+			if ep.Conditions.Ready == nil || *ep.Conditions.Ready {
+				subset.Addresses = append(subset.Addresses, addr)
+			} else {
+				subset.NotReadyAddresses = append(subset.NotReadyAddresses, addr)
+			}
+		}
+	}
+	for _, p := range e.Ports {
+		port := &metadatapb.EndpointPort{}
+		if p.Name != nil {
+			port.Name = *p.Name
+		}
+		if p.Port != nil {
+			port.Port = *p.Port
+		}
+		if p.Protocol != nil {
+			port.Protocol = ipProtocolObjToPbMap[*p.Protocol]
+		}
+		subset.Ports = append(subset.Ports, port)
+	}
+
+	return &metadatapb.Endpoints{
+		Metadata: ObjectMetadataToProto(&e.ObjectMeta),
+		Subsets:  []*metadatapb.EndpointSubset{subset},
+	}
+}
+
 // ServicePortToProto converts a ServicePort into a proto.
 func ServicePortToProto(e *v1.ServicePort) *metadatapb.ServicePort {
 	return &metadatapb.ServicePort{
@@ -570,6 +619,8 @@ func ContainerStatusToProto(c *v1.ContainerStatus) *metadatapb.ContainerStatus {
 		Name:         c.Name,
 		ContainerID:  c.ContainerID,
 		RestartCount: int64(c.RestartCount),
+		Image:        c.Image,
+		ImageID:      c.ImageID,
 	}
 	switch {
 	case c.State.Waiting != nil:
@@ -815,3 +866,51 @@ func DeploymentToProto(d *apps.Deployment) *metadatapb.Deployment {
 		Status:   DeploymentStatusToProto(&d.Status),
 	}
 }
+
+// JobSpecToProto converts a batch JobSpec to proto.
+func JobSpecToProto(j *batch.JobSpec) *metadatapb.JobSpec {
+	var parallelism, completions, backoffLimit int32
+	if j.Parallelism != nil {
+		parallelism = *j.Parallelism
+	}
+	if j.Completions != nil {
+		completions = *j.Completions
+	}
+	if j.BackoffLimit != nil {
+		backoffLimit = *j.BackoffLimit
+	}
+
+	return &metadatapb.JobSpec{
+		Parallelism:  parallelism,
+		Completions:  completions,
+		BackoffLimit: backoffLimit,
+	}
+}
+
+// JobStatusToProto converts a batch JobStatus to proto.
+func JobStatusToProto(j *batch.JobStatus) *metadatapb.JobStatus {
+	var startTimeNS, completionTimeNS int64
+	if j.StartTime != nil {
+		startTimeNS = j.StartTime.UnixNano()
+	}
+	if j.CompletionTime != nil {
+		completionTimeNS = j.CompletionTime.UnixNano()
+	}
+
+	return &metadatapb.JobStatus{
+		StartTimeNS:      startTimeNS,
+		CompletionTimeNS: completionTimeNS,
+		Active:           j.Active,
+		Succeeded:        j.Succeeded,
+		Failed:           j.Failed,
+	}
+}
+
+// JobToProto converts a batch Job to proto.
+func JobToProto(j *batch.Job) *metadatapb.Job {
+	return &metadatapb.Job{
+		Metadata: ObjectMetadataToProto(&j.ObjectMeta),
+		Spec:     JobSpecToProto(&j.Spec),
+		Status:   JobStatusToProto(&j.Status),
+	}
+}
@@ -26,7 +26,9 @@ import (
 	"github.com/gogo/protobuf/proto"
 	"github.com/stretchr/testify/assert"
 	apps "k8s.io/api/apps/v1"
+	batch "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 
@@ -403,6 +405,8 @@ name: "test_container"
 container_id: "test_id"
 container_state: 1
 start_timestamp_ns: 4
+image: "gcr.io/pixie/vizier:1.2.3"
+image_id: "gcr.io/pixie/vizier@sha256:abcd"
 `
 
 const terminatedContainerStatusPb = `
@@ -1308,6 +1312,94 @@ func TestEndpointsToProto(t *testing.T) {
 	assert.Equal(t, expectedPb, oPb)
 }
 
+func TestEndpointSliceToProto(t *testing.T) {
+	ready := true
+	notReady := false
+	hostname := "host"
+	nodeName := "this-is-a-node"
+	portName := "endpt"
+	port := int32(10)
+	protocol := v1.ProtocolTCP
+
+	o := discovery.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "object_md-abcde",
+			Namespace: "a_namespace",
+			UID:       "mnop",
+			Labels: map[string]string{
+				discovery.LabelServiceName: "object_md",
+			},
+		},
+		AddressType: discovery.AddressTypeIPv4,
+		Endpoints: []discovery.Endpoint{
+			{
+				Addresses:  []string{"127.0.0.1"},
+				Conditions: discovery.EndpointConditions{Ready: &ready},
+				Hostname:   &hostname,
+				NodeName:   &nodeName,
+				TargetRef: &v1.ObjectReference{
+					Kind:      "Pod",
+					Namespace: "a_namespace",
+					Name:      "pod-name",
+					UID:       "abcd",
+				},
+			},
+			{
+				Addresses:  []string{"127.0.0.2"},
+				Conditions: discovery.EndpointConditions{Ready: &notReady},
+			},
+			{
+				Addresses: []string{"127.0.0.3"},
+			},
+		},
+		Ports: []discovery.EndpointPort{
+			{
+				Name:     &portName,
+				Port:     &port,
+				Protocol: &protocol,
+			},
+		},
+	}
+
+	oPb := k8s.EndpointSliceToProto(&o)
+
+	assert.Equal(t, "object_md-abcde", oPb.Metadata.Name)
+	assert.Equal(t, "mnop", oPb.Metadata.UID)
+	assert.Equal(t, "object_md", oPb.Metadata.Labels[discovery.LabelServiceName])
+	assert.Equal(t, []*metadatapb.EndpointSubset{
+		{
+			Addresses: []*metadatapb.EndpointAddress{
+				{
+					IP:       "127.0.0.1",
+					Hostname: "host",
+					NodeName: "this-is-a-node",
+					TargetRef: &metadatapb.ObjectReference{
+						Kind:      "Pod",
+						Namespace: "a_namespace",
+						Name:      "pod-name",
+						UID:       "abcd",
+					},
+				},
+				{
+					IP: "127.0.0.3",
+				},
+			},
+			NotReadyAddresses: []*metadatapb.EndpointAddress{
+				{
+					IP: "127.0.0.2",
+				},
+			},
+			Ports: []*metadatapb.EndpointPort{
+				{
+					Name:     "endpt",
+					Port:     10,
+					Protocol: metadatapb.TCP,
+				},
+			},
+		},
+	}, oPb.Subsets)
+}
+
 func TestEndpointsFromProto(t *testing.T) {
 	oPb := &metadatapb.Endpoints{}
 	if err := proto.UnmarshalText(endpointsPb, oPb); err != nil {
@@ -1517,6 +1609,8 @@ func TestContainerStatusToProtoRunning(t *testing.T) {
 		Name:        "test_container",
 		ContainerID: "test_id",
 		State:       state,
+		Image:       "gcr.io/pixie/vizier:1.2.3",
+		ImageID:     "gcr.io/pixie/vizier@sha256:abcd",
 	}
 
 	oPb := k8s.ContainerStatusToProto(&o)
@@ -1855,3 +1949,63 @@ func TestDeploymentToProto(t *testing.T) {
 	t.Logf("%v\n", expectedPb)
 	assert.Equal(t, expectedPb, oPb)
 }
+
+func TestJobToProto(t *testing.T) {
+	startTime := metav1.Unix(0, 5)
+	var parallelism int32 = 2
+	var backoffLimit int32 = 6
+
+	job := &batch.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "job_1",
+			Namespace:         "a_namespace",
+			UID:               "ijkl",
+			ResourceVersion:   "1",
+			CreationTimestamp: metav1.Unix(0, 4),
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					Kind: "CronJob",
+					Name: "cronjob_1",
+					UID:  "abcd",
+				},
+			},
+		},
+		Spec: batch.JobSpec{
+			Parallelism:  &parallelism,
+			BackoffLimit: &backoffLimit,
+		},
+		Status: batch.JobStatus{
+			StartTime: &startTime,
+			Active:    2,
+			Failed:    1,
+		},
+	}
+
+	expected := &metadatapb.Job{
+		Metadata: &metadatapb.ObjectMetadata{
+			Name:                "job_1",
+			Namespace:           "a_namespace",
+			UID:                 "ijkl",
+			ResourceVersion:     "1",
+			CreationTimestampNS: 4,
+			OwnerReferences: []*metadatapb.OwnerReference{
+				{
+					Kind: "CronJob",
+					Name: "cronjob_1",
+					UID:  "abcd",
+				},
+			},
+		},
+		Spec: &metadatapb.JobSpec{
+			Parallelism:  2,
+			BackoffLimit: 6,
+		},
+		Status: &metadatapb.JobStatus{
+			StartTimeNS: 5,
+			Active:      2,
+			Failed:      1,
+		},
+	}
+
+	assert.Equal(t, expected, k8s.JobToProto(job))
+}
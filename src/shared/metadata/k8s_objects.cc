@@ -65,5 +65,12 @@ std::string DeploymentInfo::DebugString(int indent) const {
                           stop_time_ns());
 }
 
+std::string JobInfo::DebugString(int indent) const {
+  std::string state = stop_time_ns() != 0 ? "S" : "R";
+  return absl::Substitute("$0<Job:ns=$1:name=$2:uid=$3:state=$4:start=$5:stop=$6>",
+                          Indent(indent), ns(), name(), uid(), state, start_time_ns(),
+                          stop_time_ns());
+}
+
 }  // namespace md
 }  // namespace px
@@ -51,7 +51,15 @@ struct OwnerReference {
 /**
  * Enum with all the different metadata types.
  */
-enum class K8sObjectType { kUnknown, kPod, kService, kNamespace, kReplicaSet, kDeployment };
+enum class K8sObjectType {
+  kUnknown,
+  kPod,
+  kService,
+  kNamespace,
+  kReplicaSet,
+  kDeployment,
+  kJob
+};
 
 /**
  * Base class for all K8s metadata objects.
@@ -411,7 +419,10 @@ class ContainerInfo {
                       ConvertToContainerType(container_update_info.container_type()),
                       container_update_info.message(), container_update_info.reason(),
                       container_update_info.start_timestamp_ns(),
-                      container_update_info.stop_timestamp_ns()) {}
+                      container_update_info.stop_timestamp_ns()) {
+    set_image(container_update_info.image());
+    set_image_id(container_update_info.image_id());
+  }
 
   const CID& cid() const { return cid_; }
   const std::string& name() const { return name_; }
@@ -437,6 +448,12 @@ class ContainerInfo {
   const std::string& state_reason() const { return state_reason_; }
   void set_state_reason(std::string_view state_reason) { state_reason_ = state_reason; }
 
+  const std::string& image() const { return image_; }
+  void set_image(std::string_view image) { image_ = image; }
+
+  const std::string& image_id() const { return image_id_; }
+  void set_image_id(std::string_view image_id) { image_id_ = image_id; }
+
   std::unique_ptr<ContainerInfo> Clone() const {
     return std::unique_ptr<ContainerInfo>(new ContainerInfo(*this));
   }
@@ -469,6 +486,10 @@ class ContainerInfo {
   std::string state_message_;
   // A more detailed message for why the container is in its current state.
   std::string state_reason_;
+  // The image that the container is running, such as gcr.io/pixie/vizier:1.2.3.
+  std::string image_;
+  // The ID of the image that the container is running, which includes its digest.
+  std::string image_id_;
 
   /**
    * Start time of this K8s object.
@@ -747,5 +768,32 @@ class DeploymentInfo : public K8sMetadataObject {
   int32_t requested_replicas_;
   DeploymentConditions conditions_;
 };
+
+/**
+ * JobInfo contains information about K8s jobs.
+ */
+class JobInfo : public K8sMetadataObject {
+ public:
+  JobInfo(UID uid, std::string_view ns, std::string_view name, int64_t start_timestamp_ns = 0,
+          int64_t stop_timestamp_ns = 0)
+      : K8sMetadataObject(K8sObjectType::kJob, uid, ns, name, start_timestamp_ns,
+                          stop_timestamp_ns) {}
+
+  explicit JobInfo(const px::shared::k8s::metadatapb::JobUpdate& job_update_info)
+      : JobInfo(job_update_info.uid(), job_update_info.namespace_(), job_update_info.name(),
+                job_update_info.start_timestamp_ns(), job_update_info.stop_timestamp_ns()) {}
+
+  virtual ~JobInfo() = default;
+
+  std::unique_ptr<K8sMetadataObject> Clone() const override {
+    return std::unique_ptr<JobInfo>(new JobInfo(*this));
+  }
+
+  std::string DebugString(int indent = 0) const override;
+
+ protected:
+  JobInfo(const JobInfo& other) = default;
+  JobInfo& operator=(const JobInfo& other) = delete;
+};
 }  // namespace md
 }  // namespace px
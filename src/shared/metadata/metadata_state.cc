@@ -16,6 +16,8 @@
  * SPDX-License-Identifier: Apache-2.0
  */
 
+#include <algorithm>
+#include <array>
 #include <memory>
 #include <string>
 #include <utility>
@@ -68,6 +70,11 @@ const DeploymentInfo* K8sMetadataState::DeploymentInfoByID(UIDView deployment_id
   return static_cast<const DeploymentInfo*>(K8sMetadataObjectByID(deployment_id, type));
 }
 
+const JobInfo* K8sMetadataState::JobInfoByID(UIDView job_id) const {
+  auto type = K8sObjectType::kJob;
+  return static_cast<const JobInfo*>(K8sMetadataObjectByID(job_id, type));
+}
+
 const ContainerInfo* K8sMetadataState::ContainerInfoByID(CIDView id) const {
   auto it = containers_by_id_.find(id);
 
@@ -106,6 +113,11 @@ UID K8sMetadataState::ServiceIDByClusterIP(std::string_view cluster_ip) const {
   return (it == services_by_cluster_ip_.end()) ? "" : it->second;
 }
 
+UID K8sMetadataState::ServiceIDByEndpointIP(std::string_view endpoint_ip) const {
+  auto it = services_by_endpoint_ip_.find(endpoint_ip);
+  return (it == services_by_endpoint_ip_.end()) ? "" : it->second;
+}
+
 CID K8sMetadataState::ContainerIDByName(std::string_view container_name) const {
   auto it = containers_by_name_.find(container_name);
   return (it == containers_by_name_.end()) ? "" : it->second;
@@ -159,6 +171,56 @@ const DeploymentInfo* K8sMetadataState::OwnerDeploymentInfo(
   return nullptr;
 }
 
+namespace {
+
+// Owner chains are short (eg. Pod -> ReplicaSet -> Deployment), the limit guards against cycles.
+constexpr int kMaxOwnerChainDepth = 8;
+
+constexpr std::array<std::string_view, 7> kWorkloadKinds = {
+    "ReplicaSet", "Deployment", "StatefulSet", "DaemonSet", "Job", "CronJob", "Rollout"};
+
+// K8s allows several owners, but only one of them can be the controller. Owner references don't
+// say which one it is, so prefer the owners that are known workload kinds.
+const OwnerReference* ControllerOwnerReference(const K8sMetadataObject* obj_info) {
+  const OwnerReference* owner = nullptr;
+  for (const auto& owner_reference : obj_info->owner_references()) {
+    if (std::find(kWorkloadKinds.begin(), kWorkloadKinds.end(), owner_reference.kind) !=
+        kWorkloadKinds.end()) {
+      return &owner_reference;
+    }
+    if (owner == nullptr) {
+      owner = &owner_reference;
+    }
+  }
+  return owner;
+}
+
+}  // namespace
+
+OwnerReference K8sMetadataState::PodWorkload(const PodInfo* pod_info) const {
+  OwnerReference workload{pod_info->uid(), pod_info->name(), "Pod"};
+
+  const K8sMetadataObject* obj_info = pod_info;
+  for (int depth = 0; depth < kMaxOwnerChainDepth && obj_info != nullptr; ++depth) {
+    const OwnerReference* owner = ControllerOwnerReference(obj_info);
+    if (owner == nullptr) {
+      break;
+    }
+    workload = *owner;
+
+    // ReplicaSets and Jobs are usually managed by another workload (Deployments, Argo Rollouts
+    // and CronJobs), so keep following the chain. Other workloads own the pods directly.
+    if (owner->kind == "ReplicaSet") {
+      obj_info = ReplicaSetInfoByID(owner->uid);
+    } else if (owner->kind == "Job") {
+      obj_info = JobInfoByID(owner->uid);
+    } else {
+      obj_info = nullptr;
+    }
+  }
+  return workload;
+}
+
 std::unique_ptr<K8sMetadataState> K8sMetadataState::Clone() const {
   auto other = std::make_unique<K8sMetadataState>();
 
@@ -184,6 +246,7 @@ std::unique_ptr<K8sMetadataState> K8sMetadataState::Clone() const {
   other->pods_by_ip_ = pods_by_ip_;
   other->pods_by_ip_and_start_time_ = pods_by_ip_and_start_time_;
   other->services_by_cluster_ip_ = services_by_cluster_ip_;
+  other->services_by_endpoint_ip_ = services_by_endpoint_ip_;
 
   return other;
 }
@@ -237,6 +300,9 @@ std::string K8sMetadataState::DebugString(int indent_level) const {
   for (const auto& [k, v] : services_by_cluster_ip_) {
     str += absl::Substitute("service_id: $0, cluster_ip: $1\n", v, k);
   }
+  for (const auto& [k, v] : services_by_endpoint_ip_) {
+    str += absl::Substitute("service_id: $0, endpoint_ip: $1\n", v, k);
+  }
   str += prefix + absl::Substitute("PodCIDRs($0): ", pod_cidrs_.size());
   for (const auto& cidr : pod_cidrs_) {
     str += absl::Substitute("$0,", ToString(cidr));
@@ -321,6 +387,13 @@ Status K8sMetadataState::HandleContainerUpdate(const ContainerUpdate& update) {
   container_info->set_state(ConvertToContainerState(update.container_state()));
   container_info->set_state_message(update.message());
   container_info->set_state_reason(update.reason());
+  // The image is only known once the container has been pulled, so earlier updates may lack it.
+  if (!update.image().empty()) {
+    container_info->set_image(update.image());
+  }
+  if (!update.image_id().empty()) {
+    container_info->set_image_id(update.image_id());
+  }
 
   containers_by_name_[update.name()] = cid;
 
@@ -368,6 +441,9 @@ Status K8sMetadataState::HandleServiceUpdate(const ServiceUpdate& update) {
                                           update.external_ips().end());
     service_info->set_external_ips(external_ips);
   }
+  for (const auto& endpoint_ip : update.endpoint_ips()) {
+    services_by_endpoint_ip_[endpoint_ip] = service_uid;
+  }
 
   VLOG(1) << "service update: " << update.name();
   services_by_name_[{ns, name}] = service_uid;
@@ -466,6 +542,28 @@ Status K8sMetadataState::HandleDeploymentUpdate(const DeploymentUpdate& update)
   return Status::OK();
 }
 
+Status K8sMetadataState::HandleJobUpdate(const JobUpdate& update) {
+  const UID& job_uid = update.uid();
+
+  auto it = k8s_objects_by_id_.find(job_uid);
+  if (it == k8s_objects_by_id_.end()) {
+    auto job = std::make_unique<JobInfo>(update);
+    VLOG(1) << "Adding Job: " << job->DebugString();
+    it = k8s_objects_by_id_.try_emplace(job_uid, std::move(job)).first;
+  }
+  auto job_info = static_cast<JobInfo*>(it->second.get());
+
+  for (const auto& owner_ref : update.owner_references()) {
+    job_info->AddOwnerReference(owner_ref.uid(), owner_ref.name(), owner_ref.kind());
+  }
+
+  job_info->set_start_time_ns(update.start_timestamp_ns());
+  job_info->set_stop_time_ns(update.stop_timestamp_ns());
+
+  VLOG(1) << "job update: " << update.name();
+  return Status::OK();
+}
+
 template <typename T>
 bool IsExpired(const T& obj, int64_t retention_time, int64_t now) {
   if (obj.stop_time_ns() == 0) {
@@ -531,6 +629,17 @@ Status K8sMetadataState::CleanupExpiredMetadata(int64_t now, int64_t retention_t
             k8s_object->uid()) {
           services_by_name_.erase({k8s_object->ns(), k8s_object->name()});
         }
+        for (auto ip_iter = services_by_endpoint_ip_.begin();
+             ip_iter != services_by_endpoint_ip_.end();) {
+          if (ip_iter->second == k8s_object->uid()) {
+            services_by_endpoint_ip_.erase(ip_iter++);
+          } else {
+            ++ip_iter;
+          }
+        }
+        break;
+      case K8sObjectType::kJob:
+        // Jobs are only looked up by ID.
         break;
       default:
         LOG(DFATAL) << absl::Substitute("Unexpected object type: $0",
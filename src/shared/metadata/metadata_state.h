@@ -64,6 +64,7 @@ class K8sMetadataState : NotCopyable {
   using NodeUpdate = px::shared::k8s::metadatapb::NodeUpdate;
   using ReplicaSetUpdate = px::shared::k8s::metadatapb::ReplicaSetUpdate;
   using DeploymentUpdate = px::shared::k8s::metadatapb::DeploymentUpdate;
+  using JobUpdate = px::shared::k8s::metadatapb::JobUpdate;
 
   // K8s names consist of both a namespace and name : <ns, name>.
   using K8sNameIdent = std::pair<std::string, std::string>;
@@ -169,6 +170,14 @@ class K8sMetadataState : NotCopyable {
    */
   UID ServiceIDByClusterIP(std::string_view cluster_ip) const;
 
+  /**
+   * ServiceIDByEndpointIP returns the ServiceID for the service which is served by the given IP,
+   * for IPs that don't belong to a pod. For example, the addresses of a service without a selector.
+   * @param endpoint_ip string of the endpoint IP.
+   * @return the service_id or empty string if the service does not exist.
+   */
+  UID ServiceIDByEndpointIP(std::string_view endpoint_ip) const;
+
   /**
    * ContainerInfoByID returns the container info by ID.
    * @param id The ID of the container.
@@ -261,6 +270,23 @@ class K8sMetadataState : NotCopyable {
    */
   const DeploymentInfo* OwnerDeploymentInfo(const K8sMetadataObject* obj_info) const;
 
+  /**
+   * JobInfoByID gets an unowned pointer to the job. This pointer will remain active
+   * for the lifetime of this metadata state instance.
+   * @param job_id the id of the Job.
+   * @return Pointer to the JobInfo.
+   */
+  const JobInfo* JobInfoByID(UIDView job_id) const;
+
+  /**
+   * PodWorkload follows the owner chain of the given pod up to the top-level workload that
+   * controls it, for example the Deployment that owns the pod's ReplicaSet, or the CronJob that
+   * owns the pod's Job. Pods that aren't owned by anything are their own workload.
+   * @param pod_info pointer to the given pod.
+   * @return The reference to the workload.
+   */
+  OwnerReference PodWorkload(const PodInfo* pod_info) const;
+
   std::unique_ptr<K8sMetadataState> Clone() const;
 
   Status HandlePodUpdate(const PodUpdate& update);
@@ -270,6 +296,7 @@ class K8sMetadataState : NotCopyable {
   Status HandleNodeUpdate(const NodeUpdate& update);
   Status HandleReplicaSetUpdate(const ReplicaSetUpdate& update);
   Status HandleDeploymentUpdate(const DeploymentUpdate& update);
+  Status HandleJobUpdate(const JobUpdate& update);
 
   Status CleanupExpiredMetadata(int64_t now, int64_t retention_time_ns);
 
@@ -336,6 +363,11 @@ class K8sMetadataState : NotCopyable {
    * Mapping of Services by Cluster IP.
    */
   ServicesByServiceIpMap services_by_cluster_ip_;
+
+  /**
+   * Mapping of Services by the IPs of their endpoints that don't belong to pods.
+   */
+  ServicesByServiceIpMap services_by_endpoint_ip_;
 };
 
 class AgentMetadataState : NotCopyable {
@@ -120,6 +120,8 @@ constexpr char kContainer0UpdatePbTxt[] = R"(
   container_state: CONTAINER_STATE_RUNNING
   message: "a container message"
   reason: "a container reason"
+  image: "gcr.io/pl/container0:v1"
+  image_id: "gcr.io/pl/container0@sha256:1234"
 )";
 
 constexpr char kRunningServiceUpdatePbTxt[] = R"(
@@ -172,6 +174,19 @@ constexpr char kReplicaSetUpdatePbTxt[] = R"(
   }
 )";
 
+constexpr char kJobUpdatePbTxt[] = R"(
+  uid: "job0_uid"
+  name: "job0"
+  start_timestamp_ns: 101
+  stop_timestamp_ns: 0
+  namespace: "ns0"
+  owner_references: {
+    kind: "CronJob"
+    name: "cronjob0"
+    uid: "cronjob0_uid"
+  }
+)";
+
 constexpr char kDeploymentUpdatePbTxt00[] = R"(
   uid: "deployment_uid"
   name: "deployment1"
@@ -297,6 +312,8 @@ TEST(K8sMetadataStateTest, HandleContainerUpdate) {
   EXPECT_EQ(ContainerState::kRunning, info->state());
   EXPECT_EQ("a container message", info->state_message());
   EXPECT_EQ("a container reason", info->state_reason());
+  EXPECT_EQ("gcr.io/pl/container0:v1", info->image());
+  EXPECT_EQ("gcr.io/pl/container0@sha256:1234", info->image_id());
 }
 
 TEST(K8sMetadataStateTest, HandlePodUpdate) {
@@ -401,6 +418,24 @@ TEST(K8sMetadataStateTest, HandleServiceUpdate) {
   EXPECT_EQ("127.0.0.2", service_info->cluster_ip());
 }
 
+TEST(K8sMetadataStateTest, HandleServiceUpdateEndpointIPs) {
+  K8sMetadataState state;
+
+  K8sMetadataState::ServiceUpdate service_update;
+  service_update.set_uid("service0_uid");
+  service_update.set_name("external_db");
+  service_update.set_namespace_("ns0");
+  service_update.add_endpoint_ips("10.0.0.1");
+  service_update.add_endpoint_ips("10.0.0.2");
+
+  EXPECT_OK(state.HandleServiceUpdate(service_update));
+
+  EXPECT_EQ("service0_uid", state.ServiceIDByEndpointIP("10.0.0.1"));
+  EXPECT_EQ("service0_uid", state.ServiceIDByEndpointIP("10.0.0.2"));
+  EXPECT_EQ("", state.ServiceIDByEndpointIP("10.0.0.3"));
+  EXPECT_EQ("", state.ServiceIDByClusterIP("10.0.0.1"));
+}
+
 TEST(K8sMetadataStateTest, HandleNamespaceUpdate) {
   K8sMetadataState state;
 
@@ -519,6 +554,96 @@ TEST(K8sMetadataStateTest, HandleDeploymentUpdate) {
   EXPECT_EQ(ConditionStatus::kTrue, info->conditions()[DeploymentConditionType::kReplicaFailure]);
 }
 
+TEST(K8sMetadataStateTest, HandleJobUpdate) {
+  K8sMetadataState state;
+
+  K8sMetadataState::JobUpdate update;
+  ASSERT_TRUE(TextFormat::MergeFromString(kJobUpdatePbTxt, &update)) << "Failed to parse proto";
+
+  EXPECT_OK(state.HandleJobUpdate(update));
+  auto info = state.JobInfoByID("job0_uid");
+  ASSERT_NE(nullptr, info);
+  EXPECT_EQ("job0_uid", info->uid());
+  EXPECT_EQ("job0", info->name());
+  EXPECT_EQ("ns0", info->ns());
+  EXPECT_EQ(101, info->start_time_ns());
+  EXPECT_EQ(0, info->stop_time_ns());
+  EXPECT_THAT(info->owner_references(),
+              UnorderedElementsAre(OwnerReference{"cronjob0_uid", "cronjob0", "CronJob"}));
+}
+
+TEST(K8sMetadataStateTest, PodWorkload) {
+  K8sMetadataState state;
+
+  K8sMetadataState::ReplicaSetUpdate rs_update;
+  ASSERT_TRUE(TextFormat::MergeFromString(kReplicaSetUpdatePbTxt, &rs_update))
+      << "Failed to parse proto";
+  EXPECT_OK(state.HandleReplicaSetUpdate(rs_update));
+
+  K8sMetadataState::JobUpdate job_update;
+  ASSERT_TRUE(TextFormat::MergeFromString(kJobUpdatePbTxt, &job_update))
+      << "Failed to parse proto";
+  EXPECT_OK(state.HandleJobUpdate(job_update));
+
+  auto add_pod = [&](std::string_view uid, std::string_view owner_kind,
+                     std::string_view owner_name, std::string_view owner_uid) {
+    K8sMetadataState::PodUpdate pod_update;
+    pod_update.set_uid(std::string(uid));
+    pod_update.set_name(std::string(uid));
+    pod_update.set_namespace_("ns0");
+    if (!owner_kind.empty()) {
+      auto owner = pod_update.add_owner_references();
+      owner->set_kind(std::string(owner_kind));
+      owner->set_name(std::string(owner_name));
+      owner->set_uid(std::string(owner_uid));
+    }
+    EXPECT_OK(state.HandlePodUpdate(pod_update));
+    return state.PodInfoByID(uid);
+  };
+
+  // ReplicaSet -> Deployment.
+  auto pod_info = add_pod("pod_rs", "ReplicaSet", "rs0", "rs0_uid");
+  ASSERT_NE(nullptr, pod_info);
+  auto workload = state.PodWorkload(pod_info);
+  EXPECT_EQ("deployment1", workload.name);
+  EXPECT_EQ("Deployment", workload.kind);
+  EXPECT_EQ("deployment_uid", workload.uid);
+
+  // Job -> CronJob.
+  pod_info = add_pod("pod_job", "Job", "job0", "job0_uid");
+  ASSERT_NE(nullptr, pod_info);
+  workload = state.PodWorkload(pod_info);
+  EXPECT_EQ("cronjob0", workload.name);
+  EXPECT_EQ("CronJob", workload.kind);
+
+  // Workloads that own their pods directly.
+  pod_info = add_pod("pod_sts", "StatefulSet", "sts0", "sts0_uid");
+  ASSERT_NE(nullptr, pod_info);
+  workload = state.PodWorkload(pod_info);
+  EXPECT_EQ("sts0", workload.name);
+  EXPECT_EQ("StatefulSet", workload.kind);
+
+  pod_info = add_pod("pod_ds", "DaemonSet", "ds0", "ds0_uid");
+  ASSERT_NE(nullptr, pod_info);
+  workload = state.PodWorkload(pod_info);
+  EXPECT_EQ("ds0", workload.name);
+  EXPECT_EQ("DaemonSet", workload.kind);
+
+  // A ReplicaSet we haven't seen an update for is the top-most workload we know about.
+  pod_info = add_pod("pod_unknown_rs", "ReplicaSet", "rs1", "rs1_uid");
+  ASSERT_NE(nullptr, pod_info);
+  workload = state.PodWorkload(pod_info);
+  EXPECT_EQ("rs1", workload.name);
+  EXPECT_EQ("ReplicaSet", workload.kind);
+
+  // Pods without owners are their own workload.
+  pod_info = add_pod("pod_bare", "", "", "");
+  ASSERT_NE(nullptr, pod_info);
+  workload = state.PodWorkload(pod_info);
+  EXPECT_EQ("pod_bare", workload.name);
+  EXPECT_EQ("Pod", workload.kind);
+}
+
 TEST(K8sMetadataStateTest, ReusedIPs) {
   K8sMetadataState state;
 
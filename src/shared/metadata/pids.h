@@ -22,6 +22,8 @@
 #include <string>
 #include <utility>
 
+#include <absl/container/flat_hash_map.h>
+
 #include "src/common/base/base.h"
 #include "src/shared/upid/upid.h"
 
@@ -55,6 +57,11 @@ class PIDInfo {
 
   const CID& cid() const { return cid_; }
 
+  const absl::flat_hash_map<std::string, std::string>& env_labels() const { return env_labels_; }
+  void set_env_labels(absl::flat_hash_map<std::string, std::string> env_labels) {
+    env_labels_ = std::move(env_labels);
+  }
+
   std::unique_ptr<PIDInfo> Clone() {
     auto pid_info = std::make_unique<PIDInfo>(*this);
     return pid_info;
@@ -63,7 +70,7 @@ class PIDInfo {
   bool operator==(const PIDInfo& other) const {
     return (other.upid_ == upid_) && (other.exe_path_ == exe_path_) &&
            (other.cmdline_ == cmdline_) && (other.cid_ == cid_) &&
-           (other.stop_time_ns_ == stop_time_ns_);
+           (other.env_labels_ == env_labels_) && (other.stop_time_ns_ == stop_time_ns_);
   }
 
   bool operator!=(const PIDInfo& other) const { return !(other == *this); }
@@ -88,6 +95,11 @@ class PIDInfo {
    */
   CID cid_;
 
+  /**
+   * The environment variables of this PID that were selected as labels, by name.
+   */
+  absl::flat_hash_map<std::string, std::string> env_labels_;
+
   /**
    * The time that this PID stopped running. If 0 we can assume it's still running.
    */
@@ -19,8 +19,11 @@
 #include <memory>
 #include <string>
 #include <utility>
+#include <vector>
 
 #include <absl/base/internal/spinlock.h>
+#include <absl/strings/str_split.h>
+#include "src/common/base/file.h"
 #include "src/common/system/proc_pid_path.h"
 #include "src/shared/metadata/standalone_state_manager.h"
 
@@ -46,6 +49,42 @@ absl::flat_hash_set<md::UPID> ListUPIDs(const std::filesystem::path& proc_path,
   return pids;
 }
 
+std::string CgroupPathFromProcCgroup(std::string_view proc_pid_cgroup) {
+  std::string_view fallback;
+  for (std::string_view line : absl::StrSplit(proc_pid_cgroup, '\n', absl::SkipEmpty())) {
+    // Each line is of the form hierarchy-ID:controller-list:cgroup-path.
+    std::vector<std::string_view> fields = absl::StrSplit(line, absl::MaxSplits(':', 2));
+    if (fields.size() != 3) {
+      continue;
+    }
+    if (fields[0] == "0" && fields[1].empty()) {
+      // The unified hierarchy.
+      return std::string(fields[2]);
+    }
+    if (fields[1] == "name=systemd" || fallback.empty()) {
+      fallback = fields[2];
+    }
+  }
+  return std::string(fallback);
+}
+
+namespace {
+
+// Returns the CID to use for a process, which is its cgroup path.
+CID GetCID(uint32_t pid) {
+  StatusOr<std::string> contents = ReadFileToString(system::ProcPidPath(pid, "cgroup").string());
+  if (!contents.ok()) {
+    return CID("unknown");
+  }
+  std::string cgroup_path = CgroupPathFromProcCgroup(contents.ValueOrDie());
+  if (cgroup_path.empty()) {
+    return CID("unknown");
+  }
+  return CID(std::move(cgroup_path));
+}
+
+}  // namespace
+
 std::shared_ptr<const AgentMetadataState>
 StandaloneAgentMetadataStateManager::CurrentAgentMetadataState() {
   absl::base_internal::SpinLockHolder lock(&agent_metadata_state_lock_);
@@ -90,7 +129,8 @@ Status StandaloneAgentMetadataStateManager::PerformMetadataStateUpdate() {
       std::string exe_path = proc_parser.GetExePath(up.pid()).ValueOr("");
       std::string cmdline = proc_parser.GetPIDCmdline(up.pid());
       auto pid_info =
-          std::make_unique<PIDInfo>(up, std::move(exe_path), std::move(cmdline), CID("unknown"));
+          std::make_unique<PIDInfo>(up, std::move(exe_path), std::move(cmdline), GetCID(up.pid()));
+      pid_info->set_env_labels(proc_parser.GetPIDEnvVars(up.pid(), EnvLabelNames()));
       shadow_state->AddUPID(up, std::move(pid_info));
     }
   }
@@ -19,6 +19,8 @@
 #pragma once
 
 #include <memory>
+#include <string>
+#include <string_view>
 #include <vector>
 
 #include <absl/base/internal/spinlock.h>
@@ -30,6 +32,16 @@
 namespace px {
 namespace md {
 
+/**
+ * Returns the cgroup path of a process, given the contents of its /proc/<pid>/cgroup file.
+ * The path in the unified (cgroup v2) hierarchy is preferred, followed by the systemd hierarchy,
+ * followed by the first hierarchy listed. Returns an empty string if no path is found.
+ *
+ * Outside of K8s, the cgroup path (e.g. /system.slice/nginx.service) is what identifies the
+ * service that a process belongs to, so the standalone state manager uses it as the CID.
+ */
+std::string CgroupPathFromProcCgroup(std::string_view proc_pid_cgroup);
+
 /**
  * Implements AgentMetadataStateManger.
  */
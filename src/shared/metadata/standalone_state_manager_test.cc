@@ -0,0 +1,60 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#include <gtest/gtest.h>
+
+#include "src/shared/metadata/standalone_state_manager.h"
+
+namespace px {
+namespace md {
+
+TEST(CgroupPathFromProcCgroupTest, UnifiedHierarchy) {
+  EXPECT_EQ("/system.slice/nginx.service",
+            CgroupPathFromProcCgroup("0::/system.slice/nginx.service\n"));
+}
+
+TEST(CgroupPathFromProcCgroupTest, HybridHierarchyPrefersUnified) {
+  constexpr char kContents[] =
+      "12:pids:/system.slice/nginx.service\n"
+      "1:name=systemd:/system.slice/nginx.service\n"
+      "0::/system.slice/nginx.service\n";
+  EXPECT_EQ("/system.slice/nginx.service", CgroupPathFromProcCgroup(kContents));
+}
+
+TEST(CgroupPathFromProcCgroupTest, LegacyHierarchyPrefersSystemd) {
+  constexpr char kContents[] =
+      "12:pids:/user.slice\n"
+      "4:cpu,cpuacct:/user.slice\n"
+      "1:name=systemd:/user.slice/user-1000.slice/session-2.scope\n";
+  EXPECT_EQ("/user.slice/user-1000.slice/session-2.scope", CgroupPathFromProcCgroup(kContents));
+}
+
+TEST(CgroupPathFromProcCgroupTest, LegacyHierarchyFallsBackToFirst) {
+  constexpr char kContents[] =
+      "12:pids:/docker/abcd\n"
+      "4:cpu,cpuacct:/docker/efgh\n";
+  EXPECT_EQ("/docker/abcd", CgroupPathFromProcCgroup(kContents));
+}
+
+TEST(CgroupPathFromProcCgroupTest, Malformed) {
+  EXPECT_EQ("", CgroupPathFromProcCgroup(""));
+  EXPECT_EQ("", CgroupPathFromProcCgroup("garbage\n"));
+}
+
+}  // namespace md
+}  // namespace px
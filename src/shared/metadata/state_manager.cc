@@ -17,12 +17,18 @@
  */
 
 #include <memory>
+#include <string>
 #include <utility>
 #include <vector>
 
 #include <absl/base/internal/spinlock.h>
+#include <absl/strings/str_split.h>
 #include "src/shared/metadata/state_manager.h"
 
+DEFINE_string(metadata_env_labels, gflags::StringFromEnv("PL_METADATA_ENV_LABELS", ""),
+              "Comma-separated names of process environment variables to attach to the process "
+              "metadata as labels, e.g. APP_VERSION,GIT_SHA.");
+
 namespace px {
 namespace md {
 
@@ -38,6 +44,12 @@ constexpr uint64_t kEpochsBetweenObjectDeletion = 100;
  */
 constexpr uint64_t kMinObjectRetentionAfterDeathNS = 24ULL * 3600ULL * 1'000'000'000ULL;
 
+const absl::flat_hash_set<std::string>& EnvLabelNames() {
+  static const absl::flat_hash_set<std::string> names =
+      absl::StrSplit(FLAGS_metadata_env_labels, ',', absl::SkipWhitespace());
+  return names;
+}
+
 std::shared_ptr<const AgentMetadataState>
 AgentMetadataStateManagerImpl::CurrentAgentMetadataState() {
   absl::base_internal::SpinLockHolder lock(&agent_metadata_state_lock_);
@@ -161,6 +173,9 @@ Status ApplyK8sUpdates(
         PX_RETURN_IF_ERROR(
             HandleDeploymentUpdate(update->deployment_update(), state, metadata_filter));
         break;
+      case ResourceUpdate::kJobUpdate:
+        PX_RETURN_IF_ERROR(HandleJobUpdate(update->job_update(), state, metadata_filter));
+        break;
       default:
         LOG(ERROR) << "Unhandled Update Type: " << update->update_case() << " (ignoring)";
     }
@@ -223,6 +238,7 @@ void ProcessContainerPIDUpdates(
     std::string cmdline = proc_parser.GetPIDCmdline(upid.pid());
     auto pid_info =
         std::make_unique<PIDInfo>(upid, std::move(exe_path), std::move(cmdline), CID(cid));
+    pid_info->set_env_labels(proc_parser.GetPIDEnvVars(upid.pid(), EnvLabelNames()));
 
     // Push creation events to the queue.
     pid_updates->enqueue(std::make_unique<PIDStartedEvent>(*pid_info));
@@ -360,5 +376,10 @@ Status HandleDeploymentUpdate(const DeploymentUpdate& update, AgentMetadataState
   return state->k8s_metadata_state()->HandleDeploymentUpdate(update);
 }
 
+Status HandleJobUpdate(const JobUpdate& update, AgentMetadataState* state, AgentMetadataFilter*) {
+  VLOG(2) << "Job Update: " << update.DebugString();
+  return state->k8s_metadata_state()->HandleJobUpdate(update);
+}
+
 }  // namespace md
 }  // namespace px
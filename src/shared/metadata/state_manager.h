@@ -56,6 +56,7 @@ using NamespaceUpdate = px::shared::k8s::metadatapb::NamespaceUpdate;
 using NodeUpdate = px::shared::k8s::metadatapb::NodeUpdate;
 using ReplicaSetUpdate = px::shared::k8s::metadatapb::ReplicaSetUpdate;
 using DeploymentUpdate = px::shared::k8s::metadatapb::DeploymentUpdate;
+using JobUpdate = px::shared::k8s::metadatapb::JobUpdate;
 
 /**
  * AgentMetadataStateManager has all the metadata that is tracked on a per agent basis.
@@ -195,6 +196,12 @@ Status ApplyK8sUpdates(
  */
 void RemoveDeadPods(int64_t ts, AgentMetadataState* md, CGroupMetadataReader* md_reader);
 
+/**
+ * Returns the names of the environment variables that are attached to processes as labels,
+ * as specified by --metadata_env_labels.
+ */
+const absl::flat_hash_set<std::string>& EnvLabelNames();
+
 /**
  * Processes PID updates.
  */
@@ -224,5 +231,7 @@ Status HandleReplicaSetUpdate(const ReplicaSetUpdate& update, AgentMetadataState
                               AgentMetadataFilter* metadata_filter);
 Status HandleDeploymentUpdate(const DeploymentUpdate& update, AgentMetadataState* state,
                               AgentMetadataFilter* metadata_filter);
+Status HandleJobUpdate(const JobUpdate& update, AgentMetadataState* state,
+                       AgentMetadataFilter* metadata_filter);
 }  // namespace md
 }  // namespace px
@@ -21,6 +21,8 @@ package scripts
 // Config represents the configuration for a script. For example: which variables should be pulled in and how.
 type Config struct {
 	OtelEndpointConfig *OtelEndpointConfig `yaml:"otelEndpointConfig"`
+	AlertConfig        *AlertConfig        `yaml:"alertConfig"`
+	ObjectStoreConfig  *ObjectStoreConfig  `yaml:"objectStoreConfig"`
 }
 
 // OtelEndpointConfig specifies values that should be filled in for all OTel endpoints in the script.
@@ -29,3 +31,64 @@ type OtelEndpointConfig struct {
 	Headers  map[string]string `yaml:"headers"`
 	Insecure bool              `yaml:"insecure"`
 }
+
+// ObjectStoreConfig specifies an S3, GCS or Azure Blob Storage location that the script's output tables are exported
+// to as Parquet. Credentials that are left empty are picked up from the environment, eg. through IAM roles or
+// workload identity.
+type ObjectStoreConfig struct {
+	// URL is the bucket and prefix to write to, eg. "s3://my-bucket/pixie", "gs://my-bucket/pixie" or
+	// "az://my-container/pixie".
+	URL string `yaml:"url"`
+	// Region is the AWS region of an S3 bucket.
+	Region string `yaml:"region"`
+	// Endpoint overrides the S3 endpoint, for S3 compatible stores such as MinIO, or the Azure Blob Storage endpoint.
+	Endpoint string `yaml:"endpoint"`
+	// StorageAccount is the Azure storage account that holds an Azure Blob Storage container.
+	StorageAccount string `yaml:"storageAccount"`
+	// AccessKeyID and SecretAccessKey are static AWS credentials for writing to an S3 bucket.
+	AccessKeyID     string `yaml:"accessKeyID"`
+	SecretAccessKey string `yaml:"secretAccessKey"`
+	// ServiceAccountKey is the JSON key of a GCP service account that can write to a GCS bucket.
+	ServiceAccountKey string `yaml:"serviceAccountKey"`
+}
+
+// AlertConfig specifies the alerting rules that should be evaluated against the output of the script, and where
+// notifications for those rules should be sent.
+type AlertConfig struct {
+	Rules     []*AlertRule      `yaml:"rules"`
+	Notifiers []*NotifierConfig `yaml:"notifiers"`
+}
+
+// AlertRule is a threshold rule evaluated against a single column of one of the script's output tables.
+type AlertRule struct {
+	// Name is a unique, human readable name for the rule.
+	Name string `yaml:"name"`
+	// Table is the name of the output table (from px.display) that the rule applies to.
+	Table string `yaml:"table"`
+	// Column is the numeric column that is compared against the threshold.
+	Column string `yaml:"column"`
+	// GroupBy lists the columns used to split the rule into independent alert instances, eg. per service.
+	GroupBy []string `yaml:"groupBy"`
+	// Operator is one of ">", ">=", "<", "<=", "==", "!=".
+	Operator string `yaml:"operator"`
+	// Threshold is the value the column is compared against.
+	Threshold float64 `yaml:"threshold"`
+	// For is how long the condition must continuously hold before the alert fires, eg. "5m".
+	For string `yaml:"for"`
+	// Severity is passed through to notifiers, eg. "critical" or "warning".
+	Severity string `yaml:"severity"`
+	// Labels are static labels attached to every notification for this rule.
+	Labels map[string]string `yaml:"labels"`
+}
+
+// NotifierConfig specifies a destination for alert notifications.
+type NotifierConfig struct {
+	// Type is one of "webhook", "slack" or "pagerduty".
+	Type string `yaml:"type"`
+	// URL is the endpoint notifications are sent to. For PagerDuty, this defaults to the Events API v2 endpoint.
+	URL string `yaml:"url"`
+	// Headers are additional HTTP headers sent with webhook notifications.
+	Headers map[string]string `yaml:"headers"`
+	// RoutingKey is the PagerDuty integration key.
+	RoutingKey string `yaml:"routingKey"`
+}
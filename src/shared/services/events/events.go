@@ -46,4 +46,8 @@ const (
 	PluginRetentionScriptUpdated = "Plugin Retention Script Updated"
 	// PluginRetentionScriptDeleted is an event for when a retention script is deleted.
 	PluginRetentionScriptDeleted = "Plugin Retention Script Deleted"
+	// PluginAlertRouteCreated is an event for when an alert route is created.
+	PluginAlertRouteCreated = "Plugin Alert Route Created"
+	// PluginAlertRouteDeleted is an event for when an alert route is deleted.
+	PluginAlertRouteDeleted = "Plugin Alert Route Deleted"
 )
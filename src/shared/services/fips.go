@@ -0,0 +1,72 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package services
+
+import (
+	"crypto/tls"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// FIPSCipherSuites are the TLS cipher suites which are approved for use in FIPS mode.
+var FIPSCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+}
+
+// FIPSCurves are the elliptic curves which are approved for use in FIPS mode.
+var FIPSCurves = []tls.CurveID{tls.CurveP256, tls.CurveP384}
+
+// FIPSModeEnabled returns whether the service should only use FIPS approved cryptography.
+func FIPSModeEnabled() bool {
+	return viper.GetBool("fips_mode")
+}
+
+// BoringCryptoEnabled returns whether the binary was built with the BoringCrypto Go toolchain, so that its
+// cryptography comes from the FIPS validated BoringCrypto module.
+func BoringCryptoEnabled() bool {
+	return boringCryptoEnabled()
+}
+
+// ApplyTLSPolicy restricts the TLS config to the FIPS approved versions, cipher suites and curves when FIPS mode is
+// enabled. TLS 1.3 isn't allowed by Go's FIPS-only TLS mode, so the version is capped at TLS 1.2.
+func ApplyTLSPolicy(cfg *tls.Config) *tls.Config {
+	if !FIPSModeEnabled() {
+		return cfg
+	}
+	cfg.MinVersion = tls.VersionTLS12
+	cfg.MaxVersion = tls.VersionTLS12
+	cfg.CipherSuites = FIPSCipherSuites
+	cfg.CurvePreferences = FIPSCurves
+	return cfg
+}
+
+// CheckFIPSMode exits if FIPS mode is enabled in a binary that wasn't built with BoringCrypto.
+func CheckFIPSMode() {
+	if !FIPSModeEnabled() {
+		return
+	}
+	if !BoringCryptoEnabled() {
+		log.Fatal("FIPS mode requires a binary built with BoringCrypto. Use the FIPS images of Pixie, or disable --fips_mode.")
+	}
+	log.Info("Running in FIPS mode")
+}
@@ -0,0 +1,150 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package msgbus
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"px.dev/pixie/src/shared/services"
+)
+
+// certReloader serves the client certificate and the CA of TLS connections from files, and reloads them whenever
+// the files change. This lets long-lived NATS connections pick up rotated certificates when they reconnect, without
+// restarting the service.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	certMod  time.Time
+	roots    *x509.CertPool
+	rootsMod time.Time
+}
+
+func newCertReloader(certFile, keyFile, caFile string) *certReloader {
+	return &certReloader{certFile: certFile, keyFile: keyFile, caFile: caFile}
+}
+
+// tlsConfig returns a TLS config which presents the current client certificate, and verifies the server against the
+// current CA.
+func (r *certReloader) tlsConfig() *tls.Config {
+	return services.ApplyTLSPolicy(&tls.Config{
+		MinVersion:           tls.VersionTLS12,
+		GetClientCertificate: r.clientCertificate,
+		// The static RootCAs can't follow a rotated CA, so the server is verified in VerifyConnection instead.
+		InsecureSkipVerify: true, //nolint:gosec
+		VerifyConnection:   r.verifyConnection,
+	})
+}
+
+func (r *certReloader) clientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	mod, err := latestModTime(r.certFile, r.keyFile)
+	if err == nil && r.cert != nil && !mod.After(r.certMod) {
+		return r.cert, nil
+	}
+	var cert tls.Certificate
+	if err == nil {
+		cert, err = tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	}
+	if err != nil {
+		if r.cert != nil {
+			log.WithError(err).Warn("Failed to reload the client certificate, using the previous one")
+			return r.cert, nil
+		}
+		return nil, err
+	}
+	r.cert = &cert
+	r.certMod = mod
+	return r.cert, nil
+}
+
+func (r *certReloader) rootCAs() (*x509.CertPool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	mod, err := latestModTime(r.caFile)
+	if err == nil && r.roots != nil && !mod.After(r.rootsMod) {
+		return r.roots, nil
+	}
+	roots := x509.NewCertPool()
+	if err == nil {
+		var pem []byte
+		pem, err = os.ReadFile(r.caFile)
+		if err == nil && !roots.AppendCertsFromPEM(pem) {
+			err = errors.New("the CA file contains no certificates")
+		}
+	}
+	if err != nil {
+		if r.roots != nil {
+			log.WithError(err).Warn("Failed to reload the CA, using the previous one")
+			return r.roots, nil
+		}
+		return nil, err
+	}
+	r.roots = roots
+	r.rootsMod = mod
+	return r.roots, nil
+}
+
+func (r *certReloader) verifyConnection(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("the server presented no certificate")
+	}
+	roots, err := r.rootCAs()
+	if err != nil {
+		return err
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err = cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       cs.ServerName,
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	return err
+}
+
+// latestModTime returns the time that the most recently modified of the files was changed at.
+func latestModTime(files ...string) (time.Time, error) {
+	var latest time.Time
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,156 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package msgbus
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a leaf certificate for the DNS name, and its PEM encoded cert and key.
+func (ca *testCA) issue(t *testing.T, dnsName string) (*x509.Certificate, []byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: dnsName},
+		DNSNames:     []string{dnsName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeFile writes the file and moves its modification time forward, so that each write is seen as a change.
+func writeFile(t *testing.T, path string, data []byte, mod time.Time) {
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	require.NoError(t, os.Chtimes(path, mod, mod))
+}
+
+func TestCertReloader_ClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	ca := newTestCA(t)
+
+	first, certPEM, keyPEM := ca.issue(t, "client")
+	mod := time.Now().Add(-time.Minute)
+	writeFile(t, certFile, certPEM, mod)
+	writeFile(t, keyFile, keyPEM, mod)
+
+	r := newCertReloader(certFile, keyFile, filepath.Join(dir, "ca.crt"))
+	cert, err := r.clientCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, first.Raw, cert.Certificate[0])
+
+	// The rotated certificate is served once the files change.
+	second, certPEM, keyPEM := ca.issue(t, "client")
+	mod = mod.Add(time.Second)
+	writeFile(t, certFile, certPEM, mod)
+	writeFile(t, keyFile, keyPEM, mod)
+	cert, err = r.clientCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, second.Raw, cert.Certificate[0])
+
+	// A broken rotation keeps the previous certificate.
+	writeFile(t, keyFile, []byte("not a key"), mod.Add(time.Second))
+	cert, err = r.clientCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, second.Raw, cert.Certificate[0])
+}
+
+func TestCertReloader_ClientCertificateMissing(t *testing.T) {
+	dir := t.TempDir()
+	r := newCertReloader(filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key"), filepath.Join(dir, "ca.crt"))
+	_, err := r.clientCertificate(nil)
+	assert.Error(t, err)
+}
+
+func TestCertReloader_VerifyConnection(t *testing.T) {
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.crt")
+	oldCA := newTestCA(t)
+	newCA := newTestCA(t)
+
+	mod := time.Now().Add(-time.Minute)
+	writeFile(t, caFile, oldCA.pem, mod)
+	r := newCertReloader(filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key"), caFile)
+
+	oldServer, _, _ := oldCA.issue(t, "pl-nats")
+	newServer, _, _ := newCA.issue(t, "pl-nats")
+	state := func(cert *x509.Certificate, serverName string) tls.ConnectionState {
+		return tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}, ServerName: serverName}
+	}
+
+	assert.NoError(t, r.verifyConnection(state(oldServer, "pl-nats")))
+	assert.Error(t, r.verifyConnection(state(oldServer, "other")))
+	assert.Error(t, r.verifyConnection(state(newServer, "pl-nats")))
+	assert.Error(t, r.verifyConnection(tls.ConnectionState{ServerName: "pl-nats"}))
+
+	// Once the CA is rotated, only servers with certificates from the new CA are trusted.
+	writeFile(t, caFile, newCA.pem, mod.Add(time.Second))
+	assert.NoError(t, r.verifyConnection(state(newServer, "pl-nats")))
+	assert.Error(t, r.verifyConnection(state(oldServer, "pl-nats")))
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package msgbus
+
+import (
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"px.dev/pixie/src/shared/services"
+)
+
+// MustStartEmbeddedNATS starts a NATS server inside of this process. The server listens on the
+// given port so that agents running in other pods can still reach it, and uses the server TLS
+// certs unless SSL is disabled. If a NATS server config file is given, the server enforces the
+// users and permissions in its authorization block, the same way that pl-nats does.
+func MustStartEmbeddedNATS(port int, configFile string) *server.Server {
+	opts := &server.Options{
+		ServerName: "pl-nats-embedded",
+		Port:       port,
+		NoSigs:     true,
+	}
+	if configFile != "" {
+		users, err := authorizedUsers(configFile)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to load the authorization of the embedded NATS server")
+		}
+		opts.Users = users
+	}
+	if !viper.GetBool("disable_ssl") {
+		tlsConfig, err := server.GenTLSConfig(&server.TLSConfigOpts{
+			CertFile: viper.GetString("server_tls_cert"),
+			KeyFile:  viper.GetString("server_tls_key"),
+			CaFile:   viper.GetString("tls_ca_cert"),
+			Verify:   true,
+		})
+		if err != nil {
+			log.WithError(err).Fatal("Failed to load TLS config for embedded NATS")
+		}
+		opts.TLS = true
+		opts.TLSVerify = true
+		opts.TLSConfig = services.ApplyTLSPolicy(tlsConfig)
+	}
+
+	ns, err := server.NewServer(opts)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to create embedded NATS server")
+	}
+	go ns.Start()
+	if !ns.ReadyForConnections(10 * time.Second) {
+		log.Fatal("Embedded NATS server failed to start")
+	}
+	log.WithField("port", port).Info("Started embedded NATS server")
+	return ns
+}
+
+// authorizedUsers reads the users of a NATS server config file. Only the authorization block of
+// the config is used, since the listener and TLS settings of the embedded server come from the
+// flags of this process.
+func authorizedUsers(configFile string) ([]*server.User, error) {
+	fileOpts, err := server.ProcessConfigFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+	return fileOpts.Users, nil
+}
+
+// MustConnectInProcessNATS connects to a NATS server embedded in this process without going
+// through the network, as the configured NATS user, if any.
+func MustConnectInProcessNATS(ns *server.Server) *nats.Conn {
+	opts := append([]nats.Option{nats.InProcessServer(ns)}, ClientOptions()...)
+	nc, err := nats.Connect(ns.ClientURL(), opts...)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to connect to embedded NATS")
+	}
+	nc.SetErrorHandler(func(conn *nats.Conn, subscription *nats.Subscription, err error) {
+		log.WithField("Sub", subscription.Subject).
+			WithError(err).
+			Error("Error with NATS handler")
+	})
+	return nc
+}
@@ -27,6 +27,8 @@ import (
 
 func init() {
 	pflag.String("nats_url", "pl-nats", "The url of the nats message bus")
+	pflag.String("nats_user", "", "The user to authenticate to the nats message bus as, if it requires authorization")
+	pflag.String("nats_password", "", "The password of the nats user")
 }
 
 // MustConnectNATS attempts to connect to the NATS message bus.
@@ -34,13 +36,7 @@ func MustConnectNATS() *nats.Conn {
 	var nc *nats.Conn
 	var err error
 	natsURL := viper.GetString("nats_url")
-	if viper.GetBool("disable_ssl") {
-		nc, err = nats.Connect(natsURL)
-	} else {
-		nc, err = nats.Connect(natsURL,
-			nats.ClientCert(viper.GetString("client_tls_cert"), viper.GetString("client_tls_key")),
-			nats.RootCAs(viper.GetString("tls_ca_cert")))
-	}
+	nc, err = nats.Connect(natsURL, ClientOptions()...)
 
 	if err != nil && !viper.GetBool("disable_ssl") {
 		log.WithError(err).
@@ -60,3 +56,24 @@ func MustConnectNATS() *nats.Conn {
 	log.WithField("URL", natsURL).Info("Connected to NATS")
 	return nc
 }
+
+// ClientOptions are the options for connecting to NATS: with the client TLS certs unless SSL is disabled, and as the
+// configured NATS user, if any.
+func ClientOptions() []nats.Option {
+	var opts []nats.Option
+	if !viper.GetBool("disable_ssl") {
+		opts = append(opts, clientTLSOptions()...)
+	}
+	if user := viper.GetString("nats_user"); user != "" {
+		opts = append(opts, nats.UserInfo(user, viper.GetString("nats_password")))
+	}
+	return opts
+}
+
+// clientTLSOptions are the options for connecting to NATS with the client TLS certs. The certs are reloaded when they
+// are rotated, so that reconnects use the current ones.
+func clientTLSOptions() []nats.Option {
+	certs := newCertReloader(viper.GetString("client_tls_cert"), viper.GetString("client_tls_key"),
+		viper.GetString("tls_ca_cert"))
+	return []nats.Option{nats.Secure(certs.tlsConfig())}
+}
@@ -19,13 +19,19 @@
 package msgbus_test
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/nats-io/nats.go"
+	"github.com/phayes/freeport"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"px.dev/pixie/src/shared/services/msgbus"
 	"px.dev/pixie/src/utils/testingutils"
 )
 
@@ -46,3 +52,104 @@ func TestMustConnectNATS(t *testing.T) {
 	natsMsg := <-ch
 	assert.Equal(t, natsMsg.Data, msg)
 }
+
+func TestMustConnectInProcessNATS(t *testing.T) {
+	viper.Set("disable_ssl", true)
+	port, err := freeport.GetFreePort()
+	require.NoError(t, err)
+
+	ns := msgbus.MustStartEmbeddedNATS(port, "")
+	defer ns.Shutdown()
+	nc := msgbus.MustConnectInProcessNATS(ns)
+	defer nc.Close()
+
+	sub := "sub"
+	msg := []byte("test")
+	ch := make(chan *nats.Msg)
+	_, err = nc.ChanSubscribe(sub, ch)
+	require.NoError(t, err)
+
+	// Clients connecting over the network share the bus with in-process clients.
+	remote, err := nats.Connect(fmt.Sprintf("nats://127.0.0.1:%d", port))
+	require.NoError(t, err)
+	defer remote.Close()
+	err = remote.Publish(sub, msg)
+	require.NoError(t, err)
+	natsMsg := <-ch
+	assert.Equal(t, natsMsg.Data, msg)
+}
+
+// embeddedNATSConfig has the same form as the NATS config that the operator generates when NATS
+// authorization is enabled.
+const embeddedNATSConfig = `pid_file: "/var/run/nats/nats.pid"
+
+authorization {
+  users = [
+    {
+      user: "agent"
+      password: $PL_NATS_AGENT_PASSWORD
+      permissions: {
+        publish: {
+          allow: ["UpdateAgent","_INBOX.>"]
+        }
+        subscribe: {
+          deny: ["c2v.>","v2c.>"]
+        }
+      }
+    }
+    {
+      user: "control-plane"
+      password: $PL_NATS_CONTROL_PLANE_PASSWORD
+    }
+  ]
+}
+`
+
+func TestMustStartEmbeddedNATS_Authorization(t *testing.T) {
+	viper.Set("disable_ssl", true)
+	viper.Set("nats_user", "control-plane")
+	viper.Set("nats_password", "cp-password")
+	defer viper.Set("nats_user", "")
+	defer viper.Set("nats_password", "")
+	t.Setenv("PL_NATS_AGENT_PASSWORD", "agent-password")
+	t.Setenv("PL_NATS_CONTROL_PLANE_PASSWORD", "cp-password")
+
+	configFile := filepath.Join(t.TempDir(), "nats.conf")
+	require.NoError(t, os.WriteFile(configFile, []byte(embeddedNATSConfig), 0o600))
+	port, err := freeport.GetFreePort()
+	require.NoError(t, err)
+
+	ns := msgbus.MustStartEmbeddedNATS(port, configFile)
+	defer ns.Shutdown()
+	// The in-process connection authenticates as the configured user.
+	nc := msgbus.MustConnectInProcessNATS(ns)
+	defer nc.Close()
+
+	url := fmt.Sprintf("nats://127.0.0.1:%d", port)
+	_, err = nats.Connect(url)
+	assert.Error(t, err)
+	_, err = nats.Connect(url, nats.UserInfo("agent", "wrong"))
+	assert.Error(t, err)
+
+	agent, err := nats.Connect(url, nats.UserInfo("agent", "agent-password"))
+	require.NoError(t, err)
+	defer agent.Close()
+
+	updates, err := nc.SubscribeSync("UpdateAgent")
+	require.NoError(t, err)
+	bridged, err := nc.SubscribeSync("v2c.cluster")
+	require.NoError(t, err)
+	require.NoError(t, nc.Flush())
+
+	require.NoError(t, agent.Publish("UpdateAgent", []byte("update")))
+	// The agent isn't permitted to publish to the subjects bridged to Pixie Cloud, so the server
+	// drops the message.
+	require.NoError(t, agent.Publish("v2c.cluster", []byte("bridged")))
+	require.NoError(t, agent.Flush())
+
+	msg, err := updates.NextMsg(5 * time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("update"), msg.Data)
+	_, err = bridged.NextMsg(100 * time.Millisecond)
+	assert.ErrorIs(t, err, nats.ErrTimeout)
+}
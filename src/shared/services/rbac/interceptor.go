@@ -0,0 +1,128 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package rbac
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/shared/services/authcontext"
+	"px.dev/pixie/src/utils"
+)
+
+// Policy maps full gRPC method names to the role required to call them. Methods missing from the
+// policy require DefaultRole.
+type Policy struct {
+	Methods     map[string]Role
+	DefaultRole Role
+}
+
+// RequiredRole returns the role required to call the method.
+func (p *Policy) RequiredRole(method string) Role {
+	if r, ok := p.Methods[method]; ok {
+		return r
+	}
+	return p.DefaultRole
+}
+
+// Requests that target a single cluster implement one of these interfaces. The role is then
+// checked against that cluster, so that cluster-scoped roles apply.
+type clusterScopedRequest interface {
+	GetClusterID() *uuidpb.UUID
+}
+
+type clusterScopedStringRequest interface {
+	GetClusterID() string
+}
+
+func clusterIDFromRequest(req interface{}) uuid.UUID {
+	switch r := req.(type) {
+	case clusterScopedRequest:
+		return utils.UUIDFromProtoOrNil(r.GetClusterID())
+	case clusterScopedStringRequest:
+		return uuid.FromStringOrNil(r.GetClusterID())
+	}
+	return uuid.Nil
+}
+
+// Authorize checks that the caller in the context has the required role. If the request targets a
+// cluster, cluster-scoped roles for that cluster count as well.
+func Authorize(ctx context.Context, required Role, req interface{}) error {
+	if required == RoleNone {
+		return nil
+	}
+	sCtx, err := authcontext.FromContext(ctx)
+	if err != nil || sCtx.Claims == nil {
+		return status.Error(codes.Unauthenticated, "authentication is required for this operation")
+	}
+
+	bindings := BindingsForClaims(sCtx.Claims)
+	role := bindings.OrgRole
+	if clusterID := clusterIDFromRequest(req); clusterID != uuid.Nil {
+		role = bindings.RoleForCluster(clusterID)
+	}
+	if !role.Includes(required) {
+		return status.Errorf(codes.PermissionDenied, "the %s role is required for this operation", required)
+	}
+	return nil
+}
+
+// UnaryServerInterceptor enforces the policy on unary calls. It must run after authentication.
+func UnaryServerInterceptor(p *Policy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := Authorize(ctx, p.RequiredRole(info.FullMethod), req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor enforces the policy on streaming calls. The role is checked against the
+// first message of the stream, since that is the one that carries the target cluster.
+func StreamServerInterceptor(p *Policy) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		required := p.RequiredRole(info.FullMethod)
+		if required == RoleNone {
+			return handler(srv, stream)
+		}
+		return handler(srv, &authorizedStream{ServerStream: stream, required: required})
+	}
+}
+
+type authorizedStream struct {
+	grpc.ServerStream
+	required Role
+	checked  bool
+}
+
+func (s *authorizedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if s.checked {
+		return nil
+	}
+	s.checked = true
+	return Authorize(s.Context(), s.required, m)
+}
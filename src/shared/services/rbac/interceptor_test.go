@@ -0,0 +1,157 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package rbac_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/shared/services/authcontext"
+	"px.dev/pixie/src/shared/services/rbac"
+	"px.dev/pixie/src/utils/testingutils"
+)
+
+func contextWithScopes(t *testing.T, scopes ...string) context.Context {
+	sCtx := authcontext.New()
+	sCtx.Claims = testingutils.GenerateTestClaims(t)
+	sCtx.Claims.Scopes = append(sCtx.Claims.Scopes, scopes...)
+	return authcontext.NewContext(context.Background(), sCtx)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	policy := &rbac.Policy{
+		Methods: map[string]rbac.Role{
+			"/test/Admin":  rbac.RoleAdmin,
+			"/test/Editor": rbac.RoleEditor,
+		},
+		DefaultRole: rbac.RoleViewer,
+	}
+	interceptor := rbac.UnaryServerInterceptor(policy)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		scopes  []string
+		req     interface{}
+		allowed bool
+	}{
+		{
+			name:    "admin",
+			method:  "/test/Admin",
+			scopes:  []string{"role:admin"},
+			allowed: true,
+		},
+		{
+			name:    "user without roles",
+			method:  "/test/Other",
+			allowed: false,
+		},
+		{
+			name:    "viewer calling default method",
+			method:  "/test/Other",
+			scopes:  []string{"role:viewer"},
+			allowed: true,
+		},
+		{
+			name:    "viewer calling editor method",
+			method:  "/test/Editor",
+			scopes:  []string{"role:viewer"},
+			allowed: false,
+		},
+		{
+			name:    "cluster editor on their cluster",
+			method:  "/test/Editor",
+			scopes:  []string{"role:viewer", rbac.RoleEditor.ClusterScope(testClusterID)},
+			req:     &vizierpb.ExecuteScriptRequest{ClusterID: testClusterID.String()},
+			allowed: true,
+		},
+		{
+			name:    "cluster editor on another cluster",
+			method:  "/test/Editor",
+			scopes:  []string{"role:viewer", rbac.RoleEditor.ClusterScope(testClusterID)},
+			req:     &vizierpb.ExecuteScriptRequest{ClusterID: "8ba7b810-9dad-11d1-80b4-00c04fd430c8"},
+			allowed: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := contextWithScopes(t, test.scopes...)
+			resp, err := interceptor(ctx, test.req, &grpc.UnaryServerInfo{FullMethod: test.method}, handler)
+			if test.allowed {
+				require.NoError(t, err)
+				assert.Equal(t, "ok", resp)
+				return
+			}
+			assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		})
+	}
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test/Other"}, handler)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+	req *vizierpb.ExecuteScriptRequest
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeServerStream) RecvMsg(m interface{}) error {
+	*(m.(*vizierpb.ExecuteScriptRequest)) = *s.req
+	return nil
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	policy := &rbac.Policy{
+		Methods: map[string]rbac.Role{"/test/Stream": rbac.RoleEditor},
+	}
+	interceptor := rbac.StreamServerInterceptor(policy)
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		req := &vizierpb.ExecuteScriptRequest{}
+		return stream.RecvMsg(req)
+	}
+	info := &grpc.StreamServerInfo{FullMethod: "/test/Stream"}
+
+	stream := &fakeServerStream{
+		ctx: contextWithScopes(t, rbac.RoleEditor.ClusterScope(testClusterID)),
+		req: &vizierpb.ExecuteScriptRequest{ClusterID: testClusterID.String()},
+	}
+	require.NoError(t, interceptor(nil, stream, info, handler))
+
+	stream.ctx = contextWithScopes(t, rbac.RoleViewer.Scope())
+	err := interceptor(nil, stream, info, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -0,0 +1,234 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package rbac implements role based access control for Pixie Cloud. Roles are carried in the
+// scopes of a user's JWT claims, either org-wide ("role:editor") or for a single cluster
+// ("role:editor:cluster:<cluster ID>").
+package rbac
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gofrs/uuid"
+
+	"px.dev/pixie/src/shared/services/jwtpb"
+	"px.dev/pixie/src/shared/services/utils"
+)
+
+// Role is a set of permissions that a user has in their org or in a cluster.
+type Role string
+
+const (
+	// RoleNone grants no permissions.
+	RoleNone Role = ""
+	// RoleViewer can view the org and its clusters, and run read-only scripts.
+	RoleViewer Role = "viewer"
+	// RoleEditor can additionally change clusters, scripts and plugins.
+	RoleEditor Role = "editor"
+	// RoleAdmin can additionally manage the org, its users and their keys.
+	RoleAdmin Role = "admin"
+)
+
+const (
+	rolePrefix    = "role:"
+	clusterMarker = ":cluster:"
+)
+
+var roleRanks = map[Role]int{
+	RoleNone:   0,
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+// ParseRole parses the name of a role.
+func ParseRole(s string) (Role, error) {
+	r := Role(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := roleRanks[r]; !ok || r == RoleNone {
+		return RoleNone, fmt.Errorf("unknown role '%s'", s)
+	}
+	return r, nil
+}
+
+// Includes returns whether the role grants all the permissions of the other role.
+func (r Role) Includes(other Role) bool {
+	return roleRanks[r] >= roleRanks[other]
+}
+
+func maxRole(a, b Role) Role {
+	if a.Includes(b) {
+		return a
+	}
+	return b
+}
+
+func minRole(a, b Role) Role {
+	if a.Includes(b) {
+		return b
+	}
+	return a
+}
+
+// Scope returns the org-wide scope for the role.
+func (r Role) Scope() string {
+	return rolePrefix + string(r)
+}
+
+// ClusterScope returns the scope for the role in the given cluster.
+func (r Role) ClusterScope(clusterID uuid.UUID) string {
+	return rolePrefix + string(r) + clusterMarker + clusterID.String()
+}
+
+// Bindings are the roles that a user has.
+type Bindings struct {
+	// OrgRole applies to the whole org, including all of its clusters.
+	OrgRole Role
+	// ClusterRoles apply to a single cluster, in addition to the OrgRole.
+	ClusterRoles map[uuid.UUID]Role
+}
+
+// ParseScopes returns the role bindings encoded in the scopes. Malformed role scopes are ignored.
+// The second return value is false if the scopes contain no roles at all.
+func ParseScopes(scopes []string) (*Bindings, bool) {
+	b := &Bindings{ClusterRoles: make(map[uuid.UUID]Role)}
+	found := false
+	for _, s := range scopes {
+		if !strings.HasPrefix(s, rolePrefix) {
+			continue
+		}
+		roleStr := strings.TrimPrefix(s, rolePrefix)
+		clusterStr := ""
+		if idx := strings.Index(roleStr, clusterMarker); idx >= 0 {
+			clusterStr = roleStr[idx+len(clusterMarker):]
+			roleStr = roleStr[:idx]
+		}
+		role, err := ParseRole(roleStr)
+		if err != nil {
+			continue
+		}
+		found = true
+		if clusterStr == "" {
+			b.OrgRole = maxRole(b.OrgRole, role)
+			continue
+		}
+		clusterID, err := uuid.FromString(clusterStr)
+		if err != nil {
+			continue
+		}
+		b.ClusterRoles[clusterID] = maxRole(b.ClusterRoles[clusterID], role)
+	}
+	return b, found
+}
+
+// RoleForCluster returns the effective role of the user in the given cluster.
+func (b *Bindings) RoleForCluster(clusterID uuid.UUID) Role {
+	return maxRole(b.OrgRole, b.ClusterRoles[clusterID])
+}
+
+// Includes returns whether the bindings grant at least the roles in the other bindings, in the org
+// and in every cluster.
+func (b *Bindings) Includes(other *Bindings) bool {
+	if !b.OrgRole.Includes(other.OrgRole) {
+		return false
+	}
+	for clusterID, role := range other.ClusterRoles {
+		if !b.RoleForCluster(clusterID).Includes(role) {
+			return false
+		}
+	}
+	return true
+}
+
+// Intersect returns the roles that are granted by both bindings, in the org and in every cluster.
+func (b *Bindings) Intersect(other *Bindings) *Bindings {
+	res := &Bindings{
+		OrgRole:      minRole(b.OrgRole, other.OrgRole),
+		ClusterRoles: make(map[uuid.UUID]Role),
+	}
+	for _, clusterRoles := range []map[uuid.UUID]Role{b.ClusterRoles, other.ClusterRoles} {
+		for clusterID := range clusterRoles {
+			role := minRole(b.RoleForCluster(clusterID), other.RoleForCluster(clusterID))
+			if !res.OrgRole.Includes(role) {
+				res.ClusterRoles[clusterID] = role
+			}
+		}
+	}
+	return res
+}
+
+// ValidateScopes returns an error if any of the scopes isn't a well formed role scope.
+func ValidateScopes(scopes []string) error {
+	for _, s := range scopes {
+		if b, found := ParseScopes([]string{s}); !found || len(b.Scopes()) != 1 {
+			return fmt.Errorf("invalid role scope '%s'", s)
+		}
+	}
+	return nil
+}
+
+// Scopes encodes the bindings as claim scopes.
+func (b *Bindings) Scopes() []string {
+	var scopes []string
+	if b.OrgRole != RoleNone {
+		scopes = append(scopes, b.OrgRole.Scope())
+	}
+	for clusterID, role := range b.ClusterRoles {
+		if role != RoleNone {
+			scopes = append(scopes, role.ClusterScope(clusterID))
+		}
+	}
+	return scopes
+}
+
+// RoleScopes returns the role scopes in the given scopes, so that they can be carried over to a new token.
+func RoleScopes(scopes []string) []string {
+	var roleScopes []string
+	for _, s := range scopes {
+		if strings.HasPrefix(s, rolePrefix) {
+			roleScopes = append(roleScopes, s)
+		}
+	}
+	return roleScopes
+}
+
+// ScopesForRoles returns the org-wide scopes for the named roles, skipping unknown roles.
+func ScopesForRoles(roles []string) []string {
+	var scopes []string
+	for _, r := range roles {
+		role, err := ParseRole(r)
+		if err != nil {
+			continue
+		}
+		scopes = append(scopes, role.Scope())
+	}
+	return scopes
+}
+
+// BindingsForClaims returns the roles granted by the claims. Users without any role scopes have no
+// role. Service and cluster claims aren't subject to roles, and get full access.
+func BindingsForClaims(claims *jwtpb.JWTClaims) *Bindings {
+	if claims == nil {
+		return &Bindings{OrgRole: RoleNone}
+	}
+	if utils.GetClaimsType(claims) != utils.UserClaimType {
+		return &Bindings{OrgRole: RoleAdmin}
+	}
+	b, _ := ParseScopes(claims.Scopes)
+	return b
+}
@@ -0,0 +1,180 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package rbac_test
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/shared/services/rbac"
+	"px.dev/pixie/src/utils/testingutils"
+)
+
+var testClusterID = uuid.FromStringOrNil("7ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+func TestParseRole(t *testing.T) {
+	r, err := rbac.ParseRole("Editor")
+	require.NoError(t, err)
+	assert.Equal(t, rbac.RoleEditor, r)
+
+	_, err = rbac.ParseRole("owner")
+	assert.Error(t, err)
+	_, err = rbac.ParseRole("")
+	assert.Error(t, err)
+}
+
+func TestRole_Includes(t *testing.T) {
+	assert.True(t, rbac.RoleAdmin.Includes(rbac.RoleEditor))
+	assert.True(t, rbac.RoleEditor.Includes(rbac.RoleEditor))
+	assert.False(t, rbac.RoleViewer.Includes(rbac.RoleEditor))
+	assert.True(t, rbac.RoleNone.Includes(rbac.RoleNone))
+	assert.False(t, rbac.RoleNone.Includes(rbac.RoleViewer))
+}
+
+func TestParseScopes(t *testing.T) {
+	tests := []struct {
+		name            string
+		scopes          []string
+		expectedFound   bool
+		expectedOrgRole rbac.Role
+		expectedCluster rbac.Role
+	}{
+		{
+			name:          "no roles",
+			scopes:        []string{"user"},
+			expectedFound: false,
+		},
+		{
+			name:            "org role",
+			scopes:          []string{"user", "role:viewer"},
+			expectedFound:   true,
+			expectedOrgRole: rbac.RoleViewer,
+			expectedCluster: rbac.RoleViewer,
+		},
+		{
+			name:            "highest org role wins",
+			scopes:          []string{"user", "role:viewer", "role:admin"},
+			expectedFound:   true,
+			expectedOrgRole: rbac.RoleAdmin,
+			expectedCluster: rbac.RoleAdmin,
+		},
+		{
+			name:            "cluster role",
+			scopes:          []string{"user", "role:viewer", rbac.RoleEditor.ClusterScope(testClusterID)},
+			expectedFound:   true,
+			expectedOrgRole: rbac.RoleViewer,
+			expectedCluster: rbac.RoleEditor,
+		},
+		{
+			name:            "malformed scopes are ignored",
+			scopes:          []string{"user", "role:owner", "role:admin:cluster:abcd"},
+			expectedFound:   true,
+			expectedOrgRole: rbac.RoleNone,
+			expectedCluster: rbac.RoleNone,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, found := rbac.ParseScopes(test.scopes)
+			assert.Equal(t, test.expectedFound, found)
+			assert.Equal(t, test.expectedOrgRole, b.OrgRole)
+			assert.Equal(t, test.expectedCluster, b.RoleForCluster(testClusterID))
+		})
+	}
+}
+
+func TestBindings_Scopes(t *testing.T) {
+	b := &rbac.Bindings{
+		OrgRole:      rbac.RoleViewer,
+		ClusterRoles: map[uuid.UUID]rbac.Role{testClusterID: rbac.RoleAdmin},
+	}
+	parsed, found := rbac.ParseScopes(b.Scopes())
+	require.True(t, found)
+	assert.Equal(t, b, parsed)
+}
+
+func TestBindings_Includes(t *testing.T) {
+	otherClusterID := uuid.FromStringOrNil("8ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	b := &rbac.Bindings{
+		OrgRole:      rbac.RoleViewer,
+		ClusterRoles: map[uuid.UUID]rbac.Role{testClusterID: rbac.RoleEditor},
+	}
+
+	assert.True(t, b.Includes(&rbac.Bindings{OrgRole: rbac.RoleViewer}))
+	assert.True(t, b.Includes(&rbac.Bindings{ClusterRoles: map[uuid.UUID]rbac.Role{testClusterID: rbac.RoleEditor}}))
+	assert.True(t, b.Includes(&rbac.Bindings{ClusterRoles: map[uuid.UUID]rbac.Role{otherClusterID: rbac.RoleViewer}}))
+	assert.False(t, b.Includes(&rbac.Bindings{OrgRole: rbac.RoleEditor}))
+	assert.False(t, b.Includes(&rbac.Bindings{ClusterRoles: map[uuid.UUID]rbac.Role{otherClusterID: rbac.RoleEditor}}))
+	assert.False(t, b.Includes(&rbac.Bindings{ClusterRoles: map[uuid.UUID]rbac.Role{testClusterID: rbac.RoleAdmin}}))
+}
+
+func TestBindings_Intersect(t *testing.T) {
+	otherClusterID := uuid.FromStringOrNil("8ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	b := &rbac.Bindings{
+		OrgRole:      rbac.RoleViewer,
+		ClusterRoles: map[uuid.UUID]rbac.Role{testClusterID: rbac.RoleAdmin},
+	}
+
+	res := b.Intersect(&rbac.Bindings{OrgRole: rbac.RoleEditor})
+	assert.Equal(t, rbac.RoleViewer, res.OrgRole)
+	assert.Equal(t, rbac.RoleEditor, res.RoleForCluster(testClusterID))
+	assert.Equal(t, rbac.RoleViewer, res.RoleForCluster(otherClusterID))
+
+	res = b.Intersect(&rbac.Bindings{ClusterRoles: map[uuid.UUID]rbac.Role{otherClusterID: rbac.RoleAdmin}})
+	assert.Equal(t, rbac.RoleNone, res.OrgRole)
+	assert.Equal(t, rbac.RoleNone, res.RoleForCluster(testClusterID))
+	assert.Equal(t, rbac.RoleViewer, res.RoleForCluster(otherClusterID))
+
+	assert.Empty(t, b.Intersect(&rbac.Bindings{}).Scopes())
+}
+
+func TestValidateScopes(t *testing.T) {
+	assert.NoError(t, rbac.ValidateScopes(nil))
+	assert.NoError(t, rbac.ValidateScopes([]string{"role:viewer", "role:editor:cluster:" + testClusterID.String()}))
+	assert.Error(t, rbac.ValidateScopes([]string{"user"}))
+	assert.Error(t, rbac.ValidateScopes([]string{"role:owner"}))
+	assert.Error(t, rbac.ValidateScopes([]string{"role:editor:cluster:not-a-uuid"}))
+}
+
+func TestScopesForRoles(t *testing.T) {
+	assert.Equal(t, []string{"role:admin", "role:viewer"}, rbac.ScopesForRoles([]string{"admin", "unknown", "viewer"}))
+}
+
+func TestRoleScopes(t *testing.T) {
+	assert.Equal(t, []string{"role:editor"}, rbac.RoleScopes([]string{"user", "role:editor"}))
+}
+
+func TestBindingsForClaims(t *testing.T) {
+	// Users without any role scopes have no access.
+	claims := testingutils.GenerateTestClaims(t)
+	assert.Equal(t, rbac.RoleNone, rbac.BindingsForClaims(claims).OrgRole)
+
+	claims.Scopes = append(claims.Scopes, rbac.RoleViewer.Scope())
+	assert.Equal(t, rbac.RoleViewer, rbac.BindingsForClaims(claims).OrgRole)
+
+	svcClaims := testingutils.GenerateTestServiceClaims(t, "vzmgr")
+	svcClaims.Scopes = append(svcClaims.Scopes, rbac.RoleViewer.Scope())
+	assert.Equal(t, rbac.RoleAdmin, rbac.BindingsForClaims(svcClaims).OrgRole)
+
+	assert.Equal(t, rbac.RoleNone, rbac.BindingsForClaims(nil).OrgRole)
+}
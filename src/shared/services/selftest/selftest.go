@@ -0,0 +1,386 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package selftest checks the connectivity of a Vizier component to its dependencies, from the DNS resolution of
+// their addresses to the TLS handshake with them.
+package selftest
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/jsonpb"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/shared/services"
+)
+
+// Path is the path of the endpoint that serves the self-test of a component as JSON. Unlike the statusz endpoints
+// it requires bearer auth, since it dials the targets in the request.
+const Path = "/selftest"
+
+// DefaultTimeout is the timeout of the check of a single dependency when none is specified.
+const DefaultTimeout = 5 * time.Second
+
+const (
+	tlsTargetPrefix    = "tls://"
+	serviceAccountCA   = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+	apiServerName      = "kubernetes.default.svc"
+	targetQueryParam   = "target"
+	timeoutQueryParam  = "timeout"
+	apiServerCheckName = "api-server"
+)
+
+// DefaultChecker returns a Checker that presents the client certificates of the default service flags in the TLS
+// handshakes, unless SSL is disabled.
+func DefaultChecker() (*Checker, error) {
+	if viper.GetBool("disable_ssl") {
+		return &Checker{}, nil
+	}
+	tlsConfig, err := services.DefaultClientTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &Checker{TLSConfig: tlsConfig}, nil
+}
+
+// Target is a dependency of a component whose connectivity is checked.
+type Target struct {
+	// Name identifies the dependency in the report, eg. nats or cloud.
+	Name string
+	// Address is the host:port of the dependency.
+	Address string
+	// TLS is whether a TLS handshake is performed with the dependency once connected.
+	TLS bool
+	// TLSConfig overrides the TLS config of the Checker for this dependency.
+	TLSConfig *tls.Config
+}
+
+// ParseTarget parses a target given as host:port, or as tls://host:port to also check the TLS handshake.
+func ParseTarget(s string) Target {
+	t := Target{Name: s, Address: s}
+	if strings.HasPrefix(s, tlsTargetPrefix) {
+		t.Address = strings.TrimPrefix(s, tlsTargetPrefix)
+		t.TLS = true
+	}
+	return t
+}
+
+// URLTarget returns the target of a dependency addressed by a URL such as https://pl-etcd-client:2379, or by a bare
+// host with an optional port. The TLS handshake is only checked for https URLs, since protocols such as NATS
+// upgrade to TLS after a plaintext handshake of their own.
+func URLTarget(name, rawURL, defaultPort string) Target {
+	host := rawURL
+	scheme := ""
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		host = u.Host
+		scheme = u.Scheme
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, defaultPort)
+	}
+	return Target{
+		Name:    name,
+		Address: host,
+		TLS:     scheme == "https",
+	}
+}
+
+// APIServerTarget returns the Kubernetes API server of the cluster the component runs in, if it runs in one.
+func APIServerTarget() (Target, bool) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return Target{}, false
+	}
+	cfg := &tls.Config{ServerName: apiServerName}
+	if ca, err := os.ReadFile(serviceAccountCA); err == nil {
+		cfg.RootCAs = x509.NewCertPool()
+		cfg.RootCAs.AppendCertsFromPEM(ca)
+	}
+	return Target{
+		Name:      apiServerCheckName,
+		Address:   net.JoinHostPort(host, port),
+		TLS:       true,
+		TLSConfig: cfg,
+	}, true
+}
+
+// Resolver looks up the addresses of a host.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// Checker checks the DNS resolution, TCP connectivity and TLS handshake of dependencies.
+type Checker struct {
+	// TLSConfig has the client certificates and root CAs used in the TLS handshakes. The system roots are used
+	// when it is nil.
+	TLSConfig *tls.Config
+	// Resolver defaults to net.DefaultResolver.
+	Resolver Resolver
+	// Timeout bounds the check of a single dependency, and defaults to DefaultTimeout.
+	Timeout time.Duration
+}
+
+// Run checks the targets concurrently, and returns their checks in the same order.
+func (c *Checker) Run(ctx context.Context, targets []Target) []*vizierpb.DependencyCheck {
+	checks := make([]*vizierpb.DependencyCheck, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t Target) {
+			defer wg.Done()
+			checks[i] = c.Check(ctx, t)
+		}(i, t)
+	}
+	wg.Wait()
+	return checks
+}
+
+// Check checks the connectivity to a single target. It stops at the first step that fails, and records its error.
+func (c *Checker) Check(ctx context.Context, t Target) *vizierpb.DependencyCheck {
+	check := &vizierpb.DependencyCheck{
+		Name:    t.Name,
+		Address: t.Address,
+	}
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	host, port, err := net.SplitHostPort(t.Address)
+	if err != nil {
+		check.Error = fmt.Sprintf("invalid address: %v", err)
+		return check
+	}
+
+	if net.ParseIP(host) != nil {
+		check.ResolvedAddrs = []string{host}
+	} else {
+		resolver := c.Resolver
+		if resolver == nil {
+			resolver = net.DefaultResolver
+		}
+		start := time.Now()
+		addrs, err := resolver.LookupHost(ctx, host)
+		check.DnsLatencyNs = time.Since(start).Nanoseconds()
+		if err == nil && len(addrs) == 0 {
+			err = errors.New("no addresses found")
+		}
+		if err != nil {
+			check.Error = fmt.Sprintf("dns: %v", err)
+			return check
+		}
+		check.ResolvedAddrs = addrs
+	}
+
+	// Dial the resolved address so that the connect latency does not include a second lookup.
+	var dialer net.Dialer
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(check.ResolvedAddrs[0], port))
+	check.ConnectLatencyNs = time.Since(start).Nanoseconds()
+	if err != nil {
+		check.Error = fmt.Sprintf("connect: %v", err)
+		return check
+	}
+	defer conn.Close()
+
+	if !t.TLS {
+		check.OK = true
+		return check
+	}
+
+	cfg := t.TLSConfig
+	if cfg == nil {
+		cfg = c.TLSConfig
+	}
+	start = time.Now()
+	diag, err := handshake(ctx, conn, host, cfg)
+	check.TlsLatencyNs = time.Since(start).Nanoseconds()
+	check.TLS = diag
+	if err != nil {
+		check.Error = fmt.Sprintf("tls: %v", err)
+		return check
+	}
+	if diag.VerifyError != "" {
+		check.Error = fmt.Sprintf("tls: certificate verification failed: %s", diag.VerifyError)
+		return check
+	}
+	check.OK = true
+	return check
+}
+
+// handshake performs a TLS handshake without verifying the certificate of the peer, so that the certificate can
+// be reported even when it is not trusted, and then verifies it separately. The verification is skipped entirely
+// if the config skips it, as it does for dev clouds.
+func handshake(ctx context.Context, conn net.Conn, host string, cfg *tls.Config) (*vizierpb.TLSDiagnostics, error) {
+	if cfg == nil {
+		cfg = &tls.Config{}
+	}
+	cfg = cfg.Clone()
+	skipVerify := cfg.InsecureSkipVerify
+	if cfg.ServerName == "" {
+		cfg.ServerName = host
+	}
+	cfg.InsecureSkipVerify = true
+
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil, err
+	}
+	state := tlsConn.ConnectionState()
+	diag := &vizierpb.TLSDiagnostics{
+		Version:     versionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+	}
+	if len(state.PeerCertificates) == 0 {
+		diag.VerifyError = "no peer certificates"
+		return diag, nil
+	}
+
+	leaf := state.PeerCertificates[0]
+	diag.PeerSubject = leaf.Subject.String()
+	diag.PeerDNSNames = leaf.DNSNames
+	diag.PeerNotAfterNs = leaf.NotAfter.UnixNano()
+	if skipVerify {
+		return diag, nil
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{
+		DNSName:       cfg.ServerName,
+		Roots:         cfg.RootCAs,
+		Intermediates: intermediates,
+	})
+	if err != nil {
+		diag.VerifyError = err.Error()
+	}
+	return diag, nil
+}
+
+func versionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	default:
+		return fmt.Sprintf("0x%04X", version)
+	}
+}
+
+// NewHandler returns the handler of the self-test endpoint of a component, which checks the targets along with
+// the targets and timeout in the query parameters of the request.
+func NewHandler(component string, checker *Checker, targets func() []Target) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := *checker
+		if s := r.URL.Query().Get(timeoutQueryParam); s != "" {
+			timeout, err := time.ParseDuration(s)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid timeout: %v", err), http.StatusBadRequest)
+				return
+			}
+			c.Timeout = timeout
+		}
+
+		ts := targets()
+		for _, s := range r.URL.Query()[targetQueryParam] {
+			ts = append(ts, ParseTarget(s))
+		}
+
+		resp := &vizierpb.SelfTestResponse{
+			Component: component,
+			PodName:   PodName(),
+			Checks:    c.Run(r.Context(), ts),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := (&jsonpb.Marshaler{}).Marshal(w, resp); err != nil {
+			log.WithError(err).Error("Failed to write self-test response")
+		}
+	})
+}
+
+// PodName returns the name of the pod of the component, which is also its hostname when the flag is not set.
+func PodName() string {
+	if name := viper.GetString("pod_name"); name != "" && name != "<unknown>" {
+		return name
+	}
+	name, _ := os.Hostname()
+	return name
+}
+
+// Query runs the self-test of the component served at baseURL, eg. https://vizier-metadata-svc.pl.svc:50400, which
+// also checks the extra targets. The token is used for the bearer auth of the request.
+func Query(ctx context.Context, client *http.Client, baseURL, token string, extraTargets []string, timeout time.Duration) (*vizierpb.SelfTestResponse, error) {
+	q := url.Values{}
+	for _, t := range extraTargets {
+		q.Add(targetQueryParam, t)
+	}
+	if timeout > 0 {
+		q.Set(timeoutQueryParam, timeout.String())
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = Path
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	result := &vizierpb.SelfTestResponse{}
+	if err := jsonpb.Unmarshal(resp.Body, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,226 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package selftest_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/shared/services/selftest"
+)
+
+type fakeResolver struct {
+	addrs map[string][]string
+}
+
+func (r *fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	addrs, ok := r.addrs[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return addrs, nil
+}
+
+func serverAddr(t *testing.T, srv *httptest.Server) (string, string) {
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	_, port, err := net.SplitHostPort(u.Host)
+	require.NoError(t, err)
+	return u.Host, port
+}
+
+func TestChecker_Check(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+	addr, port := serverAddr(t, srv)
+
+	// The test certificate is valid for example.com.
+	trusted := x509.NewCertPool()
+	trusted.AddCert(srv.Certificate())
+	checker := &selftest.Checker{
+		TLSConfig: &tls.Config{RootCAs: trusted},
+		Resolver: &fakeResolver{addrs: map[string][]string{
+			"example.com": {"127.0.0.1"},
+		}},
+		Timeout: time.Second,
+	}
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedAddr := closed.Addr().String()
+	closed.Close()
+
+	tests := []struct {
+		name            string
+		target          selftest.Target
+		expectedOK      bool
+		expectedError   string
+		expectedTLS     bool
+		expectedResolve []string
+	}{
+		{
+			name:            "tls",
+			target:          selftest.Target{Name: "tls", Address: net.JoinHostPort("example.com", port), TLS: true},
+			expectedOK:      true,
+			expectedTLS:     true,
+			expectedResolve: []string{"127.0.0.1"},
+		},
+		{
+			name:            "untrusted tls",
+			target:          selftest.Target{Name: "untrusted", Address: addr, TLS: true, TLSConfig: &tls.Config{}},
+			expectedError:   "tls: certificate verification failed",
+			expectedTLS:     true,
+			expectedResolve: []string{"127.0.0.1"},
+		},
+		{
+			name:            "tcp",
+			target:          selftest.Target{Name: "tcp", Address: addr},
+			expectedOK:      true,
+			expectedResolve: []string{"127.0.0.1"},
+		},
+		{
+			name:            "skipped verification",
+			target:          selftest.Target{Name: "dev", Address: addr, TLS: true, TLSConfig: &tls.Config{InsecureSkipVerify: true}},
+			expectedOK:      true,
+			expectedTLS:     true,
+			expectedResolve: []string{"127.0.0.1"},
+		},
+		{
+			name:          "dns failure",
+			target:        selftest.Target{Name: "dns", Address: net.JoinHostPort("unknown.local", port)},
+			expectedError: "dns: no such host",
+		},
+		{
+			name:            "connect failure",
+			target:          selftest.Target{Name: "closed", Address: closedAddr},
+			expectedError:   "connect:",
+			expectedResolve: []string{"127.0.0.1"},
+		},
+		{
+			name:          "invalid address",
+			target:        selftest.Target{Name: "invalid", Address: "example.com"},
+			expectedError: "invalid address",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			check := checker.Check(context.Background(), test.target)
+			assert.Equal(t, test.target.Name, check.Name)
+			assert.Equal(t, test.expectedOK, check.OK)
+			if test.expectedError == "" {
+				assert.Empty(t, check.Error)
+			} else {
+				assert.Contains(t, check.Error, test.expectedError)
+			}
+			assert.Equal(t, test.expectedResolve, check.ResolvedAddrs)
+			if !test.expectedTLS {
+				assert.Nil(t, check.TLS)
+				return
+			}
+			require.NotNil(t, check.TLS)
+			assert.NotEmpty(t, check.TLS.Version)
+			assert.NotEmpty(t, check.TLS.CipherSuite)
+			assert.Contains(t, check.TLS.PeerDNSNames, "example.com")
+			assert.Equal(t, srv.Certificate().NotAfter.UnixNano(), check.TLS.PeerNotAfterNs)
+		})
+	}
+}
+
+func TestChecker_RunKeepsOrder(t *testing.T) {
+	checker := &selftest.Checker{Resolver: &fakeResolver{}}
+	checks := checker.Run(context.Background(), []selftest.Target{
+		{Name: "a", Address: "a.local:1"},
+		{Name: "b", Address: "b.local:2"},
+		{Name: "c", Address: "c.local:3"},
+	})
+	require.Len(t, checks, 3)
+	for i, name := range []string{"a", "b", "c"} {
+		assert.Equal(t, name, checks[i].Name)
+		assert.False(t, checks[i].OK)
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	assert.Equal(t, selftest.Target{Name: "nats:4222", Address: "nats:4222"}, selftest.ParseTarget("nats:4222"))
+	assert.Equal(t,
+		selftest.Target{Name: "tls://nats:4222", Address: "nats:4222", TLS: true},
+		selftest.ParseTarget("tls://nats:4222"))
+}
+
+func TestURLTarget(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected selftest.Target
+	}{
+		{"pl-nats", selftest.Target{Name: "nats", Address: "pl-nats:4222"}},
+		{"nats://pl-nats:4223", selftest.Target{Name: "nats", Address: "pl-nats:4223"}},
+		{"https://pl-etcd-client.pl.svc:2379", selftest.Target{Name: "nats", Address: "pl-etcd-client.pl.svc:2379", TLS: true}},
+		{"https://pl-etcd-client.pl.svc", selftest.Target{Name: "nats", Address: "pl-etcd-client.pl.svc:4222", TLS: true}},
+	}
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			assert.Equal(t, test.expected, selftest.URLTarget("nats", test.url, "4222"))
+		})
+	}
+}
+
+func TestQuery(t *testing.T) {
+	backend := httptest.NewServer(http.NotFoundHandler())
+	defer backend.Close()
+	backendAddr, _ := serverAddr(t, backend)
+
+	mux := http.NewServeMux()
+	mux.Handle(selftest.Path, selftest.NewHandler("query-broker", &selftest.Checker{}, func() []selftest.Target {
+		return []selftest.Target{{Name: "backend", Address: backendAddr}}
+	}))
+	srv := httptest.NewTLSServer(mux)
+	defer srv.Close()
+
+	resp, err := selftest.Query(context.Background(), srv.Client(), srv.URL, "", []string{"invalid"}, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "query-broker", resp.Component)
+	assert.NotEmpty(t, resp.PodName)
+	require.Len(t, resp.Checks, 2)
+	assert.Equal(t, "backend", resp.Checks[0].Name)
+	assert.True(t, resp.Checks[0].OK)
+	assert.Equal(t, "invalid", resp.Checks[1].Name)
+	assert.False(t, resp.Checks[1].OK)
+	assert.Contains(t, resp.Checks[1].Error, "invalid address")
+
+	_, err = selftest.Query(context.Background(), srv.Client(), srv.URL, "", nil, -time.Second)
+	require.NoError(t, err)
+
+	u := srv.URL + selftest.Path + "?timeout=soon"
+	r, err := srv.Client().Get(u)
+	require.NoError(t, err)
+	defer r.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, r.StatusCode)
+}
@@ -21,6 +21,7 @@ package services
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"os"
 	"sync"
@@ -53,6 +54,7 @@ func setupCommonFlags() {
 	pflag.String("jwt_signing_key", "", "The signing key used for JWTs")
 	pflag.String("pod_name", "<unknown>", "The pod name")
 	pflag.Bool("version", false, "Print the version and quit.")
+	pflag.Bool("fips_mode", false, "Only use FIPS approved cryptography. Requires a binary built with BoringCrypto.")
 }
 
 // SetupCommonFlags sets flags that are used by every service, even non GRPC servers.
@@ -95,6 +97,8 @@ func CheckServiceFlags() {
 		log.Panic("Flag --jwt_signing_key or ENV PL_JWT_SIGNING_KEY is required")
 	}
 
+	CheckFIPSMode()
+
 	if !viper.GetBool("disable_ssl") {
 		if len(viper.GetString("server_tls_key")) == 0 {
 			log.Panic("Flag --server_tls_key or ENV PL_SERVER_TLS_KEY is required when ssl is enabled")
@@ -148,38 +152,11 @@ func GetGRPCClientDialOpts() ([]grpc.DialOption, error) {
 		return dialOpts, nil
 	}
 
-	tlsCert := viper.GetString("client_tls_cert")
-	tlsKey := viper.GetString("client_tls_key")
-	tlsCACert := viper.GetString("tls_ca_cert")
-
-	log.WithFields(log.Fields{
-		"tlsCertFile": tlsCert,
-		"tlsKeyFile":  tlsKey,
-		"tlsCA":       tlsCACert,
-	}).Info("Loading HTTP TLS certs")
-
-	pair, err := tls.LoadX509KeyPair(tlsCert, tlsKey)
-	if err != nil {
-		return nil, err
-	}
-
-	certPool := x509.NewCertPool()
-	ca, err := os.ReadFile(tlsCACert)
+	tlsConfig, err := DefaultClientTLSConfig()
 	if err != nil {
 		return nil, err
 	}
 
-	// Append the client certificates from the CA
-	if ok := certPool.AppendCertsFromPEM(ca); !ok {
-		return nil, fmt.Errorf("failed to append CA cert: %s", tlsCACert)
-	}
-
-	tlsConfig := &tls.Config{
-		Certificates: []tls.Certificate{pair},
-		NextProtos:   []string{"h2"},
-		RootCAs:      certPool,
-	}
-
 	creds := credentials.NewTLS(tlsConfig)
 	dialOpts = append(dialOpts, grpc.WithTransportCredentials(creds))
 	dialOpts = append(dialOpts, grpc.WithDefaultServiceConfig(`{"loadBalancingPolicy":"round_robin"}`))
@@ -187,8 +164,42 @@ func GetGRPCClientDialOpts() ([]grpc.DialOption, error) {
 	return dialOpts, nil
 }
 
+// ServerTLSOverrides change how a client verifies a server with server-side TLS. They allow dialing the server by IP,
+// when its name can't be resolved.
+type ServerTLSOverrides struct {
+	// ServerName is sent as the SNI, and the server's certificate is verified against it.
+	ServerName string
+	// CABundle is a PEM encoded bundle of the CAs that the server's certificate is verified against, instead of the
+	// system's roots.
+	CABundle []byte
+}
+
+// Apply sets the overrides on the TLS config. A nil ServerTLSOverrides leaves the config unchanged.
+func (o *ServerTLSOverrides) Apply(tlsConfig *tls.Config) error {
+	if o == nil {
+		return nil
+	}
+	if o.ServerName != "" {
+		tlsConfig.ServerName = o.ServerName
+	}
+	if len(o.CABundle) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(o.CABundle) {
+			return errors.New("the CA bundle has no valid PEM certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return nil
+}
+
 // GetGRPCClientDialOptsServerSideTLS gets default dial options for GRPC clients accessing a server with server-side TLS.
 func GetGRPCClientDialOptsServerSideTLS(isInternal bool) ([]grpc.DialOption, error) {
+	return GetGRPCClientDialOptsServerSideTLSWithOverrides(isInternal, nil)
+}
+
+// GetGRPCClientDialOptsServerSideTLSWithOverrides is like GetGRPCClientDialOptsServerSideTLS, but verifies the server
+// with the given overrides.
+func GetGRPCClientDialOptsServerSideTLSWithOverrides(isInternal bool, overrides *ServerTLSOverrides) ([]grpc.DialOption, error) {
 	dialOpts := make([]grpc.DialOption, 0)
 	dialOpts = append(dialOpts, grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)))
 
@@ -198,7 +209,10 @@ func GetGRPCClientDialOptsServerSideTLS(isInternal bool) ([]grpc.DialOption, err
 	}
 
 	tlsConfig := &tls.Config{InsecureSkipVerify: isInternal}
-	creds := credentials.NewTLS(tlsConfig)
+	if err := overrides.Apply(tlsConfig); err != nil {
+		return nil, err
+	}
+	creds := credentials.NewTLS(ApplyTLSPolicy(tlsConfig))
 
 	dialOpts = append(dialOpts, grpc.WithTransportCredentials(creds))
 	return dialOpts, nil
@@ -56,9 +56,44 @@ func DefaultServerTLSConfig() (*tls.Config, error) {
 		return nil, fmt.Errorf("failed to append CA cert")
 	}
 
-	return &tls.Config{
+	return ApplyTLSPolicy(&tls.Config{
 		Certificates: []tls.Certificate{pair},
 		NextProtos:   []string{"h2"},
 		ClientCAs:    certPool,
-	}, nil
+	}), nil
+}
+
+// DefaultClientTLSConfig has the client TLS config setup by the default service flags.
+func DefaultClientTLSConfig() (*tls.Config, error) {
+	tlsCert := viper.GetString("client_tls_cert")
+	tlsKey := viper.GetString("client_tls_key")
+	tlsCACert := viper.GetString("tls_ca_cert")
+
+	log.WithFields(log.Fields{
+		"tlsCertFile": tlsCert,
+		"tlsKeyFile":  tlsKey,
+		"tlsCA":       tlsCACert,
+	}).Info("Loading HTTP TLS certs")
+
+	pair, err := tls.LoadX509KeyPair(tlsCert, tlsKey)
+	if err != nil {
+		return nil, err
+	}
+
+	certPool := x509.NewCertPool()
+	ca, err := os.ReadFile(tlsCACert)
+	if err != nil {
+		return nil, err
+	}
+
+	// Append the client certificates from the CA
+	if ok := certPool.AppendCertsFromPEM(ca); !ok {
+		return nil, fmt.Errorf("failed to append CA cert: %s", tlsCACert)
+	}
+
+	return ApplyTLSPolicy(&tls.Config{
+		Certificates: []tls.Certificate{pair},
+		NextProtos:   []string{"h2"},
+		RootCAs:      certPool,
+	}), nil
 }
@@ -131,3 +131,22 @@ func GenerateJWTForCluster(clusterID string, audience string) *jwtpb.JWTClaims {
 	}
 	return &pbClaims
 }
+
+// GenerateJWTForClusterUser creates a protobuf claims for a user's requests to a cluster that are proxied by the
+// cloud. Like the claims for the cluster, they are short lived and allow for clock skew, but they carry the
+// identity and scopes of the user, so that the cluster can apply the user's roles.
+func GenerateJWTForClusterUser(user *jwtpb.JWTClaims, audience string) *jwtpb.JWTClaims {
+	pbClaims := GenerateJWTForCluster("", audience)
+	pbClaims.Subject = user.Subject
+	pbClaims.Scopes = append([]string{}, user.Scopes...)
+	userClaims := user.GetUserClaims()
+	pbClaims.CustomClaims = &jwtpb.JWTClaims_UserClaims{
+		UserClaims: &jwtpb.UserJWTClaims{
+			UserID:    userClaims.GetUserID(),
+			OrgID:     userClaims.GetOrgID(),
+			Email:     userClaims.GetEmail(),
+			IsAPIUser: userClaims.GetIsAPIUser(),
+		},
+	}
+	return pbClaims
+}
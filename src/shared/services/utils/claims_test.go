@@ -20,6 +20,7 @@ package utils_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -42,3 +43,17 @@ func TestGetClaimsType(t *testing.T) {
 
 	assert.Equal(t, utils.UserClaimType, utils.GetClaimsType(p))
 }
+
+func TestGenerateJWTForClusterUser(t *testing.T) {
+	user := utils.GenerateJWTForUser("user_id", "org_id", "user@email.com", time.Now().Add(24*time.Hour), "withpixie.ai")
+	user.Scopes = append(user.Scopes, "role:viewer")
+
+	claims := utils.GenerateJWTForClusterUser(user, "vizier")
+	assert.Equal(t, utils.UserClaimType, utils.GetClaimsType(claims))
+	assert.Equal(t, "vizier", claims.Audience)
+	assert.Equal(t, "user_id", claims.Subject)
+	assert.Equal(t, []string{"user", "role:viewer"}, claims.Scopes)
+	assert.Equal(t, &jwtpb.UserJWTClaims{UserID: "user_id", OrgID: "org_id", Email: "user@email.com"}, claims.GetUserClaims())
+	// The claims expire as soon as the claims for the cluster would.
+	assert.LessOrEqual(t, claims.ExpiresAt, time.Now().Add(time.Hour).Unix())
+}
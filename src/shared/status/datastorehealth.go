@@ -0,0 +1,48 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package status
+
+import "time"
+
+// DatastoreHealthPath is the path of the metadata service endpoint that serves the DatastoreHealth of the metadata
+// datastore as JSON.
+const DatastoreHealthPath = "/statusz/datastore"
+
+// DatastoreHealth summarizes the size of the metadata datastore, and the maintenance which keeps it from filling up.
+type DatastoreHealth struct {
+	// Backend is the kind of datastore, eg. etcd or pebble.
+	Backend string `json:"backend"`
+	// SizeBytes is the space that the datastore occupies on disk.
+	SizeBytes int64 `json:"sizeBytes"`
+	// InUseBytes is the part of SizeBytes that is used by live data. The rest is reclaimed by defragmenting.
+	InUseBytes int64 `json:"inUseBytes"`
+	// QuotaBytes is the size past which the datastore rejects writes, or 0 if it is unknown.
+	QuotaBytes int64 `json:"quotaBytes,omitempty"`
+	// SizeAlarm is set when the datastore is close enough to its quota that metadata writes may soon fail, or when
+	// the datastore itself raised an alarm.
+	SizeAlarm bool `json:"sizeAlarm"`
+	// Alarms are the alarms raised by the datastore itself, such as etcd's NOSPACE.
+	Alarms []string `json:"alarms,omitempty"`
+	// LastCompactionTime is the time of the most recent successful compaction.
+	LastCompactionTime *time.Time `json:"lastCompactionTime,omitempty"`
+	// LastDefragTime is the time of the most recent successful defragmentation.
+	LastDefragTime *time.Time `json:"lastDefragTime,omitempty"`
+	// LastError is the error of the most recent round of maintenance, if it failed.
+	LastError string `json:"lastError,omitempty"`
+}
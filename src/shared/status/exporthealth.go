@@ -0,0 +1,58 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package status
+
+import "time"
+
+// ExportHealthPath is the path of the query broker endpoint that serves the ExportHealth of the cron scripts
+// as JSON.
+const ExportHealthPath = "/statusz/exports"
+
+// ExportHealth summarizes the delivery of the results that the cron scripts of a Vizier export to OTel collectors
+// and object stores.
+type ExportHealth struct {
+	// FailingExports is the number of exports that failed every retry of a delivery since they last succeeded.
+	FailingExports int `json:"failingExports"`
+	// PendingRetries is the number of failed deliveries that are waiting to be retried.
+	PendingRetries int `json:"pendingRetries"`
+	// DeadLetters is the number of deliveries in the dead-letter buffer, which failed every retry.
+	DeadLetters int `json:"deadLetters"`
+	// DroppedDeadLetters is the number of deliveries that were evicted from the full dead-letter buffer.
+	DroppedDeadLetters int64 `json:"droppedDeadLetters"`
+	// LastError is the error of the most recent failed delivery.
+	LastError string `json:"lastError,omitempty"`
+	// LastFailureTime is the time of the most recent failed delivery.
+	LastFailureTime *time.Time `json:"lastFailureTime,omitempty"`
+	// Exports are the health of the export of each script to each of its sinks.
+	Exports []*ExportStatus `json:"exports,omitempty"`
+}
+
+// ExportStatus is the health of the export of a cron script to one sink.
+type ExportStatus struct {
+	ScriptID string `json:"scriptID"`
+	// Sink is the kind of destination, eg. otel or objectstore.
+	Sink                string     `json:"sink"`
+	Failing             bool       `json:"failing"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	PendingRetries      int        `json:"pendingRetries"`
+	DeadLetters         int        `json:"deadLetters"`
+	LastSuccessTime     *time.Time `json:"lastSuccessTime,omitempty"`
+	LastFailureTime     *time.Time `json:"lastFailureTime,omitempty"`
+	LastError           string     `json:"lastError,omitempty"`
+}
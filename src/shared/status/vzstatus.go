@@ -25,6 +25,7 @@ var reasonToMessageMap = map[VizierReason]string{
 	"":                             "",
 	VizierVersionTooOld:            "Vizier version is older by more than one major version and may no longer be supported. Please update to the latest version by redeploying or running `px update vizier`.",
 	KernelVersionsIncompatible:     "Majority of nodes on the cluster have an incompatible Kernel version. Instrumentation may be incomplete. See https://docs.px.dev/installing-pixie/requirements/ for list of supported Kernel versions.",
+	NodesPartiallyMonitored:        "Some nodes on the cluster run an operating system that Pixie does not support, such as Windows. No data will be collected from those nodes.",
 	CloudConnectorFailedToConnect:  "Cloud connector failed to connect to Pixie Cloud. Please check the cloud address in the Vizier object to ensure it is correct and accessible within your firewall and network configurations.",
 	CloudConnectorRegistering:      "Cloud connector is registering with Pixie Cloud. This may take a few minutes.",
 	CloudConnectorInvalidDeployKey: "Invalid deploy key specified. If deploying via Helm or Manifest, please check your deployment key. Otherwise, ensure you have deployed with an authorized account and retry.",
@@ -59,6 +60,10 @@ var reasonToMessageMap = map[VizierReason]string{
 	PEMsHighFailureRate: "PEMs are experiencing a high crash rate. Your Pixie experience will be degraded while this occurs. If PEMs are getting OOMKilled, increase your PEM memory limits using the `pemMemoryLimit` flag.",
 	PEMsAllFailing:      "PEMs are all crashing. If PEMs are getting OOMKilled, increase your PEM memory limits using the `pemMemoryLimit` flag. Otherwise, consider filing a bug so someone can address your problem: https://github.com/pixie-io/pixie",
 	TLSCertsExpired:     "Service TLS certs are expired. If using the operator, the certs will be auto-regenerated. Otherwise, please redeploy Vizier.",
+	ExportsFailing: "Some cron scripts are failing to export their results to OTel collectors or object stores, and deliveries are being dead-lettered. " +
+		"Run `px status` for the failing exports, and check the export endpoints and credentials in the script configs.",
+	MetadataDatastoreSizeAlarm: "The metadata datastore is close to its size limit, and metadata writes will fail once it is full. " +
+		"Check the datastore health on the Vizier CR. If compaction isn't freeing enough space, increase the storage size of the `metadata-pv-claim` PersistentVolumeClaim, or the etcd quota.",
 }
 
 // VizierReason is the reason that Vizier is in its current state.
@@ -79,6 +84,8 @@ const (
 	VizierVersionTooOld VizierReason = "VizierVersionOld"
 	// KernelVersionsIncompatible occurs when the majority of nodes have an incompatible kernel version.
 	KernelVersionsIncompatible VizierReason = "KernelVersionsIncompatible"
+	// NodesPartiallyMonitored occurs when some nodes run an operating system that the PEM cannot run on, such as Windows.
+	NodesPartiallyMonitored VizierReason = "NodesPartiallyMonitored"
 
 	// CloudConnectorFailedToConnect occurs when the cloud connector is unable to connect to the specified cloud addr.
 	CloudConnectorFailedToConnect VizierReason = "CloudConnectFailed"
@@ -145,4 +152,10 @@ const (
 
 	// TLSCertsExpired occurs when the service TLS certs are expired or almost expired.
 	TLSCertsExpired VizierReason = "TLSCertsExpired"
+
+	// ExportsFailing occurs when the deliveries of some cron script exports failed every retry.
+	ExportsFailing VizierReason = "ExportsFailing"
+
+	// MetadataDatastoreSizeAlarm occurs when the metadata datastore is close to its quota, or raised an alarm itself.
+	MetadataDatastoreSizeAlarm VizierReason = "MetadataDatastoreSizeAlarm"
 )
@@ -26,6 +26,7 @@
 #include "src/stirling/core/data_table.h"
 #include "src/stirling/core/types.h"
 #include "src/stirling/utils/index_sorted_vector.h"
+#include "src/stirling/utils/monitor.h"
 
 namespace px {
 namespace stirling {
@@ -85,7 +86,10 @@ std::vector<TaggedRecordBatch> DataTable::ConsumeRecords() {
     int num_pushable = positions[1] - positions[0];
     int num_carryover = tablet.times.size() - positions[1];
 
-    // Case 1: Expired records. Just print a message.
+    // Case 1: Expired records. Count them and print a message.
+    if (num_expired > 0) {
+      StirlingMonitor::GetInstance()->NotifyRecordsDropped(num_expired);
+    }
     VLOG_IF(1, num_expired > 0) << absl::Substitute(
         "$0 records for table $1 dropped due to late arrival [cutoff time=$2, oldest event "
         "time=$3].",
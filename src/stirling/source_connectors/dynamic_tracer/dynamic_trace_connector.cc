@@ -28,6 +28,7 @@
 #include "src/common/base/base.h"
 #include "src/shared/types/typespb/wrapper/types_pb_wrapper.h"
 #include "src/stirling/source_connectors/dynamic_tracer/dynamic_tracing/dynamic_tracer.h"
+#include "src/stirling/utils/monitor.h"
 
 namespace px {
 namespace stirling {
@@ -61,6 +62,7 @@ void GenericHandleEvent(void* cb_cookie, void* data, int data_size) {
 void GenericHandleEventLoss(void* cb_cookie, uint64_t lost) {
   DCHECK_NE(cb_cookie, nullptr);
   VLOG(1) << absl::Substitute("Lost $0 events", lost);
+  StirlingMonitor::GetInstance()->NotifyPerfBufferLoss(lost);
 }
 
 }  // namespace
@@ -27,6 +27,7 @@
 #include <vector>
 
 #include "src/stirling/bpf_tools/macros.h"
+#include "src/stirling/utils/monitor.h"
 
 OBJ_STRVIEW(profiler_bcc_script, profiler);
 
@@ -206,6 +207,7 @@ void PerfProfileConnector::HandleHistoEvent(void* cb_cookie, void* data, int /*d
 
 void PerfProfileConnector::HandleHistoLoss(void* cb_cookie, uint64_t lost) {
   DCHECK(cb_cookie != nullptr) << "Perf buffer callback not set-up properly. Missing cb_cookie.";
+  StirlingMonitor::GetInstance()->NotifyPerfBufferLoss(lost);
   auto* connector = static_cast<PerfProfileConnector*>(cb_cookie);
   connector->stats_.Increment(StatKey::kLossHistoEvent, lost);
 }
@@ -29,6 +29,7 @@
 #include "src/stirling/bpf_tools/utils.h"
 #include "src/stirling/source_connectors/proc_exit/bcc_bpf_intf/proc_exit.h"
 #include "src/stirling/utils/detect_application.h"
+#include "src/stirling/utils/monitor.h"
 #include "src/stirling/utils/proc_tracker.h"
 
 OBJ_STRVIEW(proc_exit_trace_bcc_script, proc_exit_trace);
@@ -49,8 +50,8 @@ void HandleProcExitEvent(void* cb_cookie, void* data, int /*data_size*/) {
   connector->AcceptProcExitEvent(*event);
 }
 
-void HandleProcExitEventLoss(void* /*cb_cookie*/, uint64_t /*lost*/) {
-  // TODO(yzhao): Add stats counter.
+void HandleProcExitEventLoss(void* /*cb_cookie*/, uint64_t lost) {
+  StirlingMonitor::GetInstance()->NotifyPerfBufferLoss(lost);
 }
 
 const auto kPerfBufferSpecs = MakeArray<bpf_tools::PerfBufferSpec>({
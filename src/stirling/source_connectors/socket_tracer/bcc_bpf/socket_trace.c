@@ -44,6 +44,14 @@ const int32_t kInvalidFD = -1;
 // is reported to user-space. It applies to read and write traffic combined.
 const int kConnStatsDataThreshold = 65536;
 
+// Data events are rate limited per connection over fixed windows of this duration.
+// The limit itself is set by user-space through control_values[kConnDataEventRateLimitIndex].
+const uint64_t kRateLimitWindowNS = 1000000000ULL;
+
+// Once a connection is over its rate limit, messages that differ from the previous one are still
+// let through, up to this multiple of the limit. Repeated identical messages are always collapsed.
+const int kRateLimitBurstFactor = 2;
+
 // This is the perf buffer for BPF program to export data from kernel to user space.
 BPF_PERF_OUTPUT(socket_data_events);
 BPF_PERF_OUTPUT(socket_control_events);
@@ -215,6 +223,7 @@ static __inline struct socket_data_event_t* fill_socket_data_event(
   event->attr.pos = (direction == kEgress) ? conn_info->wr_bytes : conn_info->rd_bytes;
   event->attr.prepend_length_header = conn_info->prepend_length_header;
   BPF_PROBE_READ_VAR(event->attr.length_header, conn_info->prev_buf);
+  event->attr.num_suppressed_events = 0;
   return event;
 }
 
@@ -699,6 +708,98 @@ static __inline bool should_send_data(uint32_t tgid, uint64_t conn_disabled_tsid
   return (force_trace_tgid || should_trace_protocol_data(conn_info));
 }
 
+// The number of leading 8-byte words of a message that are hashed into its signature.
+#define MSG_SIGNATURE_WORDS 8
+
+// Returns a signature of a message, used to detect repeats of the same message (e.g. health checks)
+// on a connection. It is an FNV-1a style hash over the message size, its leading
+// MSG_SIGNATURE_WORDS words and its trailing 8 bytes.
+// buf_size is the number of bytes readable from buf, which can be less than the message size
+// for vectorized buffers, where buf is the first non-empty iovec.
+static __inline uint64_t msg_signature(const char* buf, size_t buf_size, size_t count) {
+  const uint64_t kFNVPrime = 0x100000001b3ULL;
+  uint64_t hash = 0xcbf29ce484222325ULL;
+
+  hash = (hash ^ count) * kFNVPrime;
+  if (buf == NULL) {
+    return hash;
+  }
+
+#pragma unroll
+  for (int i = 0; i < MSG_SIGNATURE_WORDS; ++i) {
+    if ((i + 1) * sizeof(uint64_t) > buf_size) {
+      break;
+    }
+    uint64_t word = 0;
+    BPF_PROBE_READ_VAR(word, buf + i * sizeof(uint64_t));
+    hash = (hash ^ word) * kFNVPrime;
+  }
+
+  if (buf_size > MSG_SIGNATURE_WORDS * sizeof(uint64_t)) {
+    uint64_t tail = 0;
+    BPF_PROBE_READ_VAR(tail, buf + buf_size - sizeof(uint64_t));
+    hash = (hash ^ tail) * kFNVPrime;
+  }
+  return hash;
+}
+
+// Applies the per-connection data event rate limit. Returns true if the data event should be
+// suppressed.
+//
+// Requests and their responses are suppressed together, so that user-space never sees one
+// without the other. The decision is made on the first event of each request, and the
+// remaining events of the request and all events of its response follow it. Within the limit,
+// all requests are let through. Over the limit, requests repeating the previous request are
+// collapsed, and distinct requests are let through until the burst limit is hit.
+//
+// Connections whose role is not known yet cannot be split into requests and responses,
+// so they are not rate limited.
+static __inline bool should_rate_limit_data(struct conn_info_t* conn_info,
+                                            enum traffic_direction_t direction, const char* buf,
+                                            size_t buf_size, size_t count) {
+  int idx = kConnDataEventRateLimitIndex;
+  int64_t* limit_ptr = control_values.lookup(&idx);
+  if (limit_ptr == NULL || *limit_ptr <= 0) {
+    // Non-positive values mean no limit.
+    return false;
+  }
+  int64_t limit = *limit_ptr;
+
+  if (conn_info->role != kRoleClient && conn_info->role != kRoleServer) {
+    return false;
+  }
+
+  uint64_t now = bpf_ktime_get_ns();
+  if (now - conn_info->rate_window_start_ns >= kRateLimitWindowNS) {
+    conn_info->rate_window_start_ns = now;
+    conn_info->rate_window_event_count = 0;
+  }
+  ++conn_info->rate_window_event_count;
+
+  // Clients send requests, while servers receive them.
+  enum traffic_direction_t request_direction =
+      (conn_info->role == kRoleClient) ? kEgress : kIngress;
+  bool is_request = (direction == request_direction);
+  bool starts_request = is_request && !conn_info->rate_limit_in_request;
+  conn_info->rate_limit_in_request = is_request;
+
+  if (starts_request) {
+    uint64_t signature = msg_signature(buf, buf_size, count);
+    bool repeated = (conn_info->last_request_signature == signature);
+    conn_info->last_request_signature = signature;
+
+    conn_info->rate_limit_suppressing =
+        conn_info->rate_window_event_count > limit &&
+        (repeated || conn_info->rate_window_event_count > kRateLimitBurstFactor * limit);
+  }
+
+  if (conn_info->rate_limit_suppressing) {
+    ++conn_info->suppressed_event_count;
+    return true;
+  }
+  return false;
+}
+
 static __inline void update_conn_stats(struct pt_regs* ctx, struct conn_info_t* conn_info,
                                        enum traffic_direction_t direction, ssize_t bytes_count) {
   // Update state of the connection.
@@ -769,14 +870,19 @@ static __inline void process_data(const bool vecs, struct pt_regs* ctx, uint64_t
 
   // Only process plaintext data.
   if (conn_info->ssl == ssl) {
+    // The buffer that the message signature is computed over, for rate limiting.
+    const char* sig_buf = NULL;
+    size_t buf_size = 0;
+
     // TODO(yzhao): Split the interface such that the singular buf case and multiple bufs in msghdr
     // are handled separately without mixed interface. The plan is to factor out helper functions
     // for lower-level functionalities, and call them separately for each case.
     if (!vecs) {
       update_traffic_class(conn_info, direction, args->buf, bytes_count);
+      sig_buf = args->buf;
+      buf_size = bytes_count;
     } else {
       struct iovec iov_cpy;
-      size_t buf_size = 0;
       // With vectorized buffers, there can be empty elements sent.
       // For protocol inference, it requires a non empty buffer to get the real data
 
@@ -786,18 +892,22 @@ static __inline void process_data(const bool vecs, struct pt_regs* ctx, uint64_t
         buf_size = min_size_t(iov_cpy.iov_len, bytes_count);
         if (buf_size != 0) {
           update_traffic_class(conn_info, direction, iov_cpy.iov_base, buf_size);
+          sig_buf = iov_cpy.iov_base;
           break;
         }
       }
     }
 
-    if (should_send_data(tgid, conn_disabled_tsid, force_trace_tgid, conn_info)) {
+    if (should_send_data(tgid, conn_disabled_tsid, force_trace_tgid, conn_info) &&
+        !should_rate_limit_data(conn_info, direction, sig_buf, buf_size, bytes_count)) {
       struct socket_data_event_t* event =
           fill_socket_data_event(args->source_fn, direction, conn_info);
       if (event == NULL) {
         // event == NULL not expected to ever happen.
         return;
       }
+      event->attr.num_suppressed_events = conn_info->suppressed_event_count;
+      conn_info->suppressed_event_count = 0;
 
       // TODO(yzhao): Same TODO for split the interface.
       if (!vecs) {
@@ -89,6 +89,9 @@ enum control_value_index_t {
   // * Support efficient lookup inside bpf to minimize overhead.
   kTargetTGIDIndex = 0,
   kStirlingTGIDIndex,
+  // The maximum number of data events per second that a single connection may submit before
+  // the BPF rate limiter kicks in. Non-positive values disable rate limiting.
+  kConnDataEventRateLimitIndex,
   kNumControlValues,
 };
 
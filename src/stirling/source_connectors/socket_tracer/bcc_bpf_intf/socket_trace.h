@@ -87,6 +87,20 @@ struct conn_info_t {
   size_t prev_count;
   char prev_buf[4];
   bool prepend_length_header;
+
+  // State of the per-connection data event rate limiter.
+  //
+  // Start time of the current rate limiting window, and the number of data events seen within it.
+  uint64_t rate_window_start_ns;
+  uint32_t rate_window_event_count;
+  // The number of data events suppressed since the last data event was submitted.
+  uint32_t suppressed_event_count;
+  // Signature of the last request seen, used to collapse repeated requests.
+  uint64_t last_request_signature;
+  // Whether the last data event was part of a request, used to detect the start of a new request.
+  bool rate_limit_in_request;
+  // Whether the current request and its response are being suppressed.
+  bool rate_limit_suppressing;
 };
 
 // This struct is a subset of conn_info_t. It is used to communicate connect/accept events.
@@ -209,6 +223,10 @@ struct socket_data_event_t {
     // See infer_kafka_message in protocol_inference.h for details.
     bool prepend_length_header;
     uint32_t length_header;
+
+    // The number of data events on this connection that were suppressed by the BPF rate limiter
+    // since the previous data event was submitted.
+    uint32_t num_suppressed_events;
   } attr;
   char msg[MAX_MSG_SIZE];
 };
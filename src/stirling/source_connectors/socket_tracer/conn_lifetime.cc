@@ -0,0 +1,125 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#include "src/stirling/source_connectors/socket_tracer/conn_lifetime.h"
+
+#include <algorithm>
+
+#include <absl/strings/substitute.h>
+
+#include "src/common/base/base.h"
+
+namespace px {
+namespace stirling {
+
+namespace {
+
+double Rate(uint64_t bytes, uint64_t window_ns) {
+  if (window_ns == 0) {
+    return 0;
+  }
+  return static_cast<double>(bytes) * 1e9 / window_ns;
+}
+
+}  // namespace
+
+std::vector<ConnLifetimes::Stats> ConnLifetimes::UpdateStats(uint64_t now_ns) {
+  ++update_counter_;
+
+  std::vector<Stats> updates;
+  for (const auto& tracker : conn_trackers_mgr_->active_trackers()) {
+    if (tracker->IsZombie()) {
+      // The final stats of the connection are reported below, so the tracker can be destroyed.
+      tracker->MarkFinalConnStatsReported();
+    }
+
+    if (!(tracker->remote_endpoint().family == SockAddrFamily::kIPv4 ||
+          tracker->remote_endpoint().family == SockAddrFamily::kIPv6) ||
+        tracker->role() == kRoleUnknown) {
+      continue;
+    }
+
+    const conn_id_t& conn_id = tracker->conn_id();
+    Key key = {.upid = conn_id.upid, .fd = conn_id.fd, .tsid = conn_id.tsid};
+
+    bool first_report = !reported_.contains(key);
+    ReportedState& state = reported_[key];
+    state.last_update = update_counter_;
+    if (state.closed) {
+      continue;
+    }
+
+    auto& conn_stats = tracker->conn_stats();
+    uint64_t bytes_sent = conn_stats.bytes_sent();
+    uint64_t bytes_recv = conn_stats.bytes_recv();
+    bool closed = conn_stats.closed();
+    if (!first_report && !closed && bytes_sent == state.bytes_sent &&
+        bytes_recv == state.bytes_recv) {
+      continue;
+    }
+
+    // The open timestamp is missing if the connection was opened before tracing started. Its tsid
+    // is then the time it was first seen, which gives a lower bound on its duration.
+    uint64_t open_ns = tracker->conn().timestamp_ns;
+    if (open_ns == 0) {
+      open_ns = conn_id.tsid;
+    }
+    uint64_t close_ns = now_ns;
+    if (closed && tracker->conn_close().timestamp_ns != 0) {
+      close_ns = tracker->conn_close().timestamp_ns;
+    }
+    uint64_t window_start_ns = std::max(last_update_ns_, open_ns);
+    uint64_t window_ns = close_ns > window_start_ns ? close_ns - window_start_ns : 0;
+
+    Stats stats;
+    stats.key = key;
+    stats.remote_endpoint = tracker->remote_endpoint();
+    stats.protocol = tracker->protocol();
+    stats.role = tracker->role();
+    stats.ssl = tracker->ssl();
+    stats.tracked_upid = tracker->is_tracked_upid();
+    stats.conn_open = first_report;
+    stats.conn_close = closed;
+    stats.duration_ns = close_ns > open_ns ? close_ns - open_ns : 0;
+    stats.bytes_sent = bytes_sent;
+    stats.bytes_recv = bytes_recv;
+    stats.bytes_sent_per_sec = Rate(bytes_sent - state.bytes_sent, window_ns);
+    stats.bytes_recv_per_sec = Rate(bytes_recv - state.bytes_recv, window_ns);
+    updates.push_back(std::move(stats));
+
+    state.bytes_sent = bytes_sent;
+    state.bytes_recv = bytes_recv;
+    state.closed = closed;
+  }
+
+  // Forget the connections whose trackers have been destroyed.
+  auto iter = reported_.begin();
+  while (iter != reported_.end()) {
+    if (iter->second.last_update != update_counter_) {
+      reported_.erase(iter++);
+      continue;
+    }
+    ++iter;
+  }
+
+  last_update_ns_ = now_ns;
+  return updates;
+}
+
+}  // namespace stirling
+}  // namespace px
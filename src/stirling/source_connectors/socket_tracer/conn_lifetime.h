@@ -0,0 +1,119 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#pragma once
+
+#include <string>
+#include <utility>
+#include <vector>
+
+#include <absl/container/flat_hash_map.h>
+#include <absl/hash/hash.h>
+
+#include "src/stirling/source_connectors/socket_tracer/conn_trackers_manager.h"
+
+namespace px {
+namespace stirling {
+
+/**
+ * Records the stats of each individual connection, unlike ConnStats which aggregates them per
+ * client-server pair. This makes the traffic on every connection visible, including the
+ * connections whose protocol is not recognized.
+ */
+class ConnLifetimes {
+ public:
+  explicit ConnLifetimes(ConnTrackersManager* conn_trackers_mgr = nullptr)
+      : conn_trackers_mgr_(conn_trackers_mgr) {}
+
+  // Key identifies a single connection.
+  struct Key {
+    struct upid_t upid;
+    int32_t fd;
+    uint64_t tsid;
+
+    bool operator==(const Key& rhs) const {
+      return upid.tgid == rhs.upid.tgid && upid.start_time_ticks == rhs.upid.start_time_ticks &&
+             fd == rhs.fd && tsid == rhs.tsid;
+    }
+
+    template <typename H>
+    friend H AbslHashValue(H h, const Key& key) {
+      return H::combine(std::move(h), key.upid.tgid, key.upid.start_time_ticks, key.fd, key.tsid);
+    }
+
+    std::string ToString() const {
+      return absl::Substitute("[tgid=$0 fd=$1 tsid=$2]", upid.tgid, fd, tsid);
+    }
+  };
+
+  struct Stats {
+    Key key = {};
+    SockAddr remote_endpoint;
+    traffic_protocol_t protocol = kProtocolUnknown;
+    endpoint_role_t role = kRoleUnknown;
+    bool ssl = false;
+    // Whether the process that owns the connection has been seen in the metadata context.
+    bool tracked_upid = false;
+
+    // Whether the connection was opened or closed since it was last reported.
+    bool conn_open = false;
+    bool conn_close = false;
+
+    uint64_t duration_ns = 0;
+    uint64_t bytes_sent = 0;
+    uint64_t bytes_recv = 0;
+    double bytes_sent_per_sec = 0;
+    double bytes_recv_per_sec = 0;
+
+    std::string ToString() const {
+      return absl::Substitute(
+          "[key=$0 open=$1 close=$2 duration_ns=$3 bytes_sent=$4 bytes_recv=$5 protocol=$6 "
+          "role=$7]",
+          key.ToString(), conn_open, conn_close, duration_ns, bytes_sent, bytes_recv,
+          magic_enum::enum_name(protocol), magic_enum::enum_name(role));
+    }
+  };
+
+  /**
+   * Iterates through all the trackers, and returns the stats of the connections that were opened,
+   * closed, or had traffic since the previous call. A connection is not reported again after the
+   * record of its close is returned.
+   *
+   * @param now_ns The current time, using the same monotonic clock as the BPF timestamps.
+   */
+  std::vector<Stats> UpdateStats(uint64_t now_ns);
+
+ private:
+  // The state of a connection when it was last reported.
+  struct ReportedState {
+    uint64_t bytes_sent = 0;
+    uint64_t bytes_recv = 0;
+    bool closed = false;
+    int last_update = 0;
+  };
+
+  absl::flat_hash_map<Key, ReportedState> reported_;
+
+  ConnTrackersManager* conn_trackers_mgr_ = nullptr;
+
+  int update_counter_ = 0;
+  uint64_t last_update_ns_ = 0;
+};
+
+}  // namespace stirling
+}  // namespace px
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#pragma once
+
+#include "src/stirling/core/output.h"
+#include "src/stirling/core/types.h"
+#include "src/stirling/source_connectors/socket_tracer/canonical_types.h"
+
+namespace px {
+namespace stirling {
+
+// clang-format off
+constexpr DataElement kConnLifetimeElements[] = {
+        canonical_data_elements::kTime,
+        canonical_data_elements::kUPID,
+        canonical_data_elements::kRemoteAddr,
+        canonical_data_elements::kRemotePort,
+        canonical_data_elements::kTraceRole,
+        {"fd", "The file descriptor of the connection in the local process.",
+         types::DataType::INT64, types::SemanticType::ST_NONE, types::PatternType::GENERAL},
+        {"addr_family", "The socket address family of the connection.",
+         types::DataType::INT64, types::SemanticType::ST_NONE, types::PatternType::GENERAL_ENUM,
+         &kSockAddrFamilyDecoder},
+        {"protocol", "The protocol of the traffic on the connection, if it was recognized.",
+         types::DataType::INT64, types::SemanticType::ST_NONE, types::PatternType::GENERAL_ENUM,
+         &kTrafficProtocolDecoder},
+        {"ssl", "Was SSL traffic detected on this connection.",
+         types::DataType::BOOLEAN, types::SemanticType::ST_NONE, types::PatternType::GENERAL_ENUM},
+        {"conn_open", "Whether the connection was opened since its previous record.",
+         types::DataType::BOOLEAN, types::SemanticType::ST_NONE, types::PatternType::GENERAL_ENUM},
+        {"conn_close", "Whether the connection was closed since its previous record.",
+         types::DataType::BOOLEAN, types::SemanticType::ST_NONE, types::PatternType::GENERAL_ENUM},
+        {"duration", "How long the connection has been open, or was open for if it is closed.",
+         types::DataType::INT64, types::SemanticType::ST_DURATION_NS,
+         types::PatternType::METRIC_GAUGE},
+        {"bytes_sent", "The number of bytes sent on the connection since it was opened.",
+         types::DataType::INT64, types::SemanticType::ST_BYTES, types::PatternType::METRIC_COUNTER},
+        {"bytes_recv", "The number of bytes received on the connection since it was opened.",
+         types::DataType::INT64, types::SemanticType::ST_BYTES, types::PatternType::METRIC_COUNTER},
+        {"bytes_sent_per_sec", "The rate that bytes were sent since the previous record.",
+         types::DataType::FLOAT64, types::SemanticType::ST_NONE, types::PatternType::METRIC_GAUGE},
+        {"bytes_recv_per_sec", "The rate that bytes were received since the previous record.",
+         types::DataType::FLOAT64, types::SemanticType::ST_NONE, types::PatternType::METRIC_GAUGE},
+#ifndef NDEBUG
+        canonical_data_elements::kPXInfo,
+#endif
+};
+// clang-format on
+
+constexpr DataTableSchema kConnLifetimeTable(
+    "conn_lifetime",
+    "Per-connection stats. Unlike the Connection-level stats (conn_stats) table, which aggregates "
+    "the connections between client-server pairs, this table has a record for each connection "
+    "with activity, whether or not its protocol is recognized.",
+    kConnLifetimeElements);
+DEFINE_PRINT_TABLE(ConnLifetime)
+
+namespace conn_lifetime_idx {
+
+constexpr int kTime = kConnLifetimeTable.ColIndex("time_");
+constexpr int kUPID = kConnLifetimeTable.ColIndex("upid");
+constexpr int kRemoteAddr = kConnLifetimeTable.ColIndex("remote_addr");
+constexpr int kRemotePort = kConnLifetimeTable.ColIndex("remote_port");
+constexpr int kRole = kConnLifetimeTable.ColIndex("trace_role");
+constexpr int kFD = kConnLifetimeTable.ColIndex("fd");
+constexpr int kAddrFamily = kConnLifetimeTable.ColIndex("addr_family");
+constexpr int kProtocol = kConnLifetimeTable.ColIndex("protocol");
+constexpr int kSSL = kConnLifetimeTable.ColIndex("ssl");
+constexpr int kConnOpen = kConnLifetimeTable.ColIndex("conn_open");
+constexpr int kConnClose = kConnLifetimeTable.ColIndex("conn_close");
+constexpr int kDuration = kConnLifetimeTable.ColIndex("duration");
+constexpr int kBytesSent = kConnLifetimeTable.ColIndex("bytes_sent");
+constexpr int kBytesRecv = kConnLifetimeTable.ColIndex("bytes_recv");
+constexpr int kBytesSentPerSec = kConnLifetimeTable.ColIndex("bytes_sent_per_sec");
+constexpr int kBytesRecvPerSec = kConnLifetimeTable.ColIndex("bytes_recv_per_sec");
+#ifndef NDEBUG
+constexpr int kPxInfo = kConnLifetimeTable.ColIndex("px_info_");
+#endif
+
+}  // namespace conn_lifetime_idx
+
+}  // namespace stirling
+}  // namespace px
@@ -0,0 +1,159 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#include "src/stirling/source_connectors/socket_tracer/conn_lifetime.h"
+
+#include <algorithm>
+
+#include "src/common/testing/testing.h"
+#include "src/stirling/source_connectors/socket_tracer/testing/event_generator.h"
+
+namespace px {
+namespace stirling {
+
+// Automatically converts ToString() to stream operator for gtest.
+using ::px::operator<<;
+
+using ::testing::AllOf;
+using ::testing::DoubleEq;
+using ::testing::ElementsAre;
+using ::testing::Field;
+using ::testing::IsEmpty;
+
+class ConnLifetimesTest : public ::testing::Test {
+ protected:
+  ConnLifetimesTest() : conn_lifetimes_(&conn_trackers_mgr_) {}
+
+  ConnTrackersManager conn_trackers_mgr_;
+  ConnLifetimes conn_lifetimes_;
+};
+
+auto ConnIs(int fd, bool open, bool close, uint64_t duration_ns) {
+  return AllOf(Field(&ConnLifetimes::Stats::key, Field(&ConnLifetimes::Key::fd, fd)),
+               Field(&ConnLifetimes::Stats::conn_open, open),
+               Field(&ConnLifetimes::Stats::conn_close, close),
+               Field(&ConnLifetimes::Stats::duration_ns, duration_ns));
+}
+
+auto BytesIs(uint64_t sent, uint64_t recv, double sent_per_sec, double recv_per_sec) {
+  return AllOf(Field(&ConnLifetimes::Stats::bytes_sent, sent),
+               Field(&ConnLifetimes::Stats::bytes_recv, recv),
+               Field(&ConnLifetimes::Stats::bytes_sent_per_sec, DoubleEq(sent_per_sec)),
+               Field(&ConnLifetimes::Stats::bytes_recv_per_sec, DoubleEq(recv_per_sec)));
+}
+
+struct conn_stats_event_t InitConnStatsEvent(const struct conn_id_t& conn_id, endpoint_role_t role,
+                                             int port) {
+  struct conn_stats_event_t event;
+  event.timestamp_ns = conn_id.tsid;
+  event.conn_id = conn_id;
+  event.role = role;
+  event.addr.in4.sin_family = AF_INET;
+  event.addr.in4.sin_port = htons(port);
+  event.addr.in4.sin_addr.s_addr = 0x01010101;  // 1.1.1.1
+  event.conn_events = 0;
+  event.rd_bytes = 0;
+  event.wr_bytes = 0;
+  return event;
+}
+
+// Model the lifetime of a single connection, and check that a record is only produced when there
+// is activity, and that the rates are computed over the time since the previous update.
+TEST_F(ConnLifetimesTest, Basic) {
+  constexpr struct conn_id_t kConnID0 = {
+      .upid = {.pid = 12345, .start_time_ticks = 1000},
+      .fd = 3,
+      .tsid = 1000,
+  };
+
+  struct conn_stats_event_t event = InitConnStatsEvent(kConnID0, kRoleClient, 80);
+  ConnTracker& tracker = conn_trackers_mgr_.GetOrCreateConnTracker(event.conn_id);
+
+  // Event: just a conn_open with no bytes transferred.
+  event.timestamp_ns += 1;
+  event.conn_events |= CONN_OPEN;
+  tracker.AddConnStats(event);
+
+  auto stats = conn_lifetimes_.UpdateStats(2000);
+  ASSERT_THAT(stats, ElementsAre(AllOf(ConnIs(3, true, false, 1000), BytesIs(0, 0, 0, 0))));
+  EXPECT_EQ(stats[0].remote_endpoint.AddrStr(), "1.1.1.1");
+  EXPECT_EQ(stats[0].remote_endpoint.port(), 80);
+  EXPECT_EQ(stats[0].role, kRoleClient);
+
+  // No new traffic, so there is no record.
+  EXPECT_THAT(conn_lifetimes_.UpdateStats(3000), IsEmpty());
+
+  // Event: 300 bytes written in the 1us since the previous update.
+  event.timestamp_ns += 1;
+  event.wr_bytes += 300;
+  tracker.AddConnStats(event);
+
+  EXPECT_THAT(conn_lifetimes_.UpdateStats(4000),
+              ElementsAre(AllOf(ConnIs(3, false, false, 3000), BytesIs(300, 0, 3e8, 0))));
+
+  // Event: connection close, after 5 bytes were read in the 2us since the previous update.
+  event.timestamp_ns += 1;
+  event.rd_bytes += 5;
+  event.conn_events |= CONN_CLOSE;
+  tracker.AddConnStats(event);
+
+  EXPECT_THAT(conn_lifetimes_.UpdateStats(6000),
+              ElementsAre(AllOf(ConnIs(3, false, true, 5000), BytesIs(300, 5, 0, 2.5e6))));
+
+  // The closed connection is not reported again.
+  EXPECT_THAT(conn_lifetimes_.UpdateStats(7000), IsEmpty());
+}
+
+// Connections from one client to a server are aggregated by ConnStats, but get records of their own
+// here, with the port of the client.
+TEST_F(ConnLifetimesTest, ServerSide) {
+  constexpr struct conn_id_t kConnID0 = {
+      .upid = {.pid = 12345, .start_time_ticks = 1000},
+      .fd = 3,
+      .tsid = 1000,
+  };
+  constexpr struct conn_id_t kConnID1 = {
+      .upid = {.pid = 12345, .start_time_ticks = 1000},
+      .fd = 4,
+      .tsid = 1500,
+  };
+
+  struct conn_stats_event_t event0 = InitConnStatsEvent(kConnID0, kRoleServer, 54321);
+  event0.timestamp_ns += 1;
+  event0.conn_events |= CONN_OPEN;
+  event0.rd_bytes = 100;
+  conn_trackers_mgr_.GetOrCreateConnTracker(event0.conn_id).AddConnStats(event0);
+
+  struct conn_stats_event_t event1 = InitConnStatsEvent(kConnID1, kRoleServer, 54322);
+  event1.timestamp_ns += 1;
+  event1.conn_events |= CONN_OPEN | CONN_CLOSE;
+  event1.rd_bytes = 50;
+  conn_trackers_mgr_.GetOrCreateConnTracker(event1.conn_id).AddConnStats(event1);
+
+  auto stats = conn_lifetimes_.UpdateStats(2000);
+  ASSERT_EQ(stats.size(), 2);
+  std::sort(stats.begin(), stats.end(),
+            [](const auto& a, const auto& b) { return a.key.fd < b.key.fd; });
+  EXPECT_THAT(stats, ElementsAre(AllOf(ConnIs(3, true, false, 1000), BytesIs(0, 100, 0, 1e8)),
+                                 AllOf(ConnIs(4, true, true, 500), BytesIs(0, 50, 0, 1e8))));
+  EXPECT_EQ(stats[0].remote_endpoint.port(), 54321);
+  EXPECT_EQ(stats[1].remote_endpoint.port(), 54322);
+}
+
+}  // namespace stirling
+}  // namespace px
@@ -753,6 +753,7 @@ void ConnTracker::UpdateState(const std::vector<CIDRBlock>& cluster_cidrs) {
 }
 
 void ConnTracker::UpdateDataStats(const SocketDataEvent& event) {
+  stats_.Increment(StatKey::kDataEventSuppressed, event.attr.num_suppressed_events);
   switch (event.attr.direction) {
     case traffic_direction_t::kEgress: {
       stats_.Increment(StatKey::kDataEventSent, 1);
@@ -100,6 +100,9 @@ class ConnTracker : NotCopyMoveable {
     kBytesSentTransferred,
     kBytesRecvTransferred,
 
+    // The number of data events suppressed by the BPF rate limiter.
+    kDataEventSuppressed,
+
     // The number of valid/invalid records.
     kValidRecords,
     kInvalidRecords,
@@ -329,6 +332,11 @@ class ConnTracker : NotCopyMoveable {
    */
   const SocketOpen& conn() const { return open_info_; }
 
+  /**
+   * Get the close information for this connection. It is only set once the close event is received.
+   */
+  const SocketClose& conn_close() const { return close_info_; }
+
   /**
    * Get the DataStream of sent frames for this connection.
    */
@@ -367,6 +367,20 @@ TEST_F(ConnTrackerTest, DataEventsChangesCounter) {
   EXPECT_EQ(kHTTPResp0.size(), tracker.GetStat(ConnTracker::StatKey::kBytesSent));
 }
 
+TEST_F(ConnTrackerTest, SuppressedDataEventsCounter) {
+  auto frame0 = event_gen_.InitRecvEvent<kProtocolHTTP>(kHTTPReq0);
+  auto frame1 = event_gen_.InitSendEvent<kProtocolHTTP>(kHTTPResp0);
+  frame1->attr.num_suppressed_events = 7;
+
+  ConnTracker tracker;
+
+  tracker.AddDataEvent(std::move(frame0));
+  EXPECT_EQ(0, tracker.GetStat(ConnTracker::StatKey::kDataEventSuppressed));
+
+  tracker.AddDataEvent(std::move(frame1));
+  EXPECT_EQ(7, tracker.GetStat(ConnTracker::StatKey::kDataEventSuppressed));
+}
+
 TEST_F(ConnTrackerTest, MemUsage) {
   testing::MockClock mock_clock;
   testing::EventGenerator event_gen_(&mock_clock);
@@ -0,0 +1,147 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#include "src/stirling/source_connectors/socket_tracer/payload_capture_policy.h"
+
+#include <algorithm>
+#include <utility>
+
+#include <rapidjson/document.h>
+
+namespace px {
+namespace stirling {
+
+namespace {
+
+StatusOr<PayloadCaptureMode> ParseMode(std::string_view mode) {
+  if (mode == "full") {
+    return PayloadCaptureMode::kFull;
+  }
+  if (mode == "truncate") {
+    return PayloadCaptureMode::kTruncate;
+  }
+  if (mode == "headers_only") {
+    return PayloadCaptureMode::kHeadersOnly;
+  }
+  return error::InvalidArgument("Unknown payload capture mode '$0'", mode);
+}
+
+StatusOr<std::string> GetOptionalString(const rapidjson::Value& obj, const char* name) {
+  auto it = obj.FindMember(name);
+  if (it == obj.MemberEnd()) {
+    return std::string();
+  }
+  if (!it->value.IsString()) {
+    return error::InvalidArgument("Payload capture field '$0' must be a string", name);
+  }
+  return std::string(it->value.GetString(), it->value.GetStringLength());
+}
+
+bool Matches(std::string_view pattern, std::string_view value) {
+  return pattern.empty() || pattern == value;
+}
+
+size_t ModeLimit(PayloadCaptureMode mode, size_t rule_limit, size_t default_limit) {
+  switch (mode) {
+    case PayloadCaptureMode::kFull:
+      return rule_limit != 0 ? rule_limit : PayloadCapturePolicy::kMaxFullBodyBytes;
+    case PayloadCaptureMode::kTruncate:
+      return rule_limit != 0 ? rule_limit : default_limit;
+    case PayloadCaptureMode::kHeadersOnly:
+      return 0;
+  }
+  // Needed for GCC build.
+  return 0;
+}
+
+}  // namespace
+
+StatusOr<PayloadCapturePolicy> PayloadCapturePolicy::Parse(std::string_view json) {
+  PayloadCapturePolicy policy;
+  if (json.empty()) {
+    return policy;
+  }
+
+  rapidjson::Document d;
+  d.Parse(json.data(), json.size());
+  if (d.HasParseError() || !d.IsObject()) {
+    return error::InvalidArgument("Payload capture policy must be a JSON object");
+  }
+
+  PX_ASSIGN_OR_RETURN(std::string default_mode, GetOptionalString(d, "defaultMode"));
+  if (!default_mode.empty()) {
+    PX_ASSIGN_OR_RETURN(policy.default_mode_, ParseMode(default_mode));
+  }
+
+  auto rules = d.FindMember("rules");
+  if (rules == d.MemberEnd()) {
+    return policy;
+  }
+  if (!rules->value.IsArray()) {
+    return error::InvalidArgument("Payload capture policy 'rules' must be an array");
+  }
+  for (const auto& r : rules->value.GetArray()) {
+    if (!r.IsObject()) {
+      return error::InvalidArgument("Payload capture rules must be JSON objects");
+    }
+    PayloadCaptureRule rule;
+    PX_ASSIGN_OR_RETURN(rule.ns, GetOptionalString(r, "namespace"));
+    PX_ASSIGN_OR_RETURN(rule.service, GetOptionalString(r, "service"));
+    PX_ASSIGN_OR_RETURN(rule.protocol, GetOptionalString(r, "protocol"));
+    PX_ASSIGN_OR_RETURN(std::string mode, GetOptionalString(r, "mode"));
+    if (mode.empty()) {
+      return error::InvalidArgument("Payload capture rules must specify a mode");
+    }
+    PX_ASSIGN_OR_RETURN(rule.mode, ParseMode(mode));
+
+    auto max_body_bytes = r.FindMember("maxBodyBytes");
+    if (max_body_bytes != r.MemberEnd()) {
+      if (!max_body_bytes->value.IsUint()) {
+        return error::InvalidArgument("Payload capture 'maxBodyBytes' must be a positive integer");
+      }
+      rule.max_body_bytes = max_body_bytes->value.GetUint();
+    }
+    policy.rules_.push_back(std::move(rule));
+  }
+  return policy;
+}
+
+PayloadCapturePolicy PayloadCapturePolicy::HeadersOnly() {
+  PayloadCapturePolicy policy;
+  policy.default_mode_ = PayloadCaptureMode::kHeadersOnly;
+  return policy;
+}
+
+size_t PayloadCapturePolicy::BodyLimit(std::string_view protocol, std::string_view ns,
+                                       const std::vector<std::string_view>& services,
+                                       size_t default_limit) const {
+  for (const auto& rule : rules_) {
+    if (!Matches(rule.protocol, protocol) || !Matches(rule.ns, ns)) {
+      continue;
+    }
+    if (!rule.service.empty() &&
+        std::find(services.begin(), services.end(), rule.service) == services.end()) {
+      continue;
+    }
+    return ModeLimit(rule.mode, rule.max_body_bytes, default_limit);
+  }
+  return ModeLimit(default_mode_, 0, default_limit);
+}
+
+}  // namespace stirling
+}  // namespace px
@@ -0,0 +1,98 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#pragma once
+
+#include <string>
+#include <string_view>
+#include <vector>
+
+#include "src/common/base/base.h"
+
+namespace px {
+namespace stirling {
+
+/**
+ * How much of the request and response payloads of matching traffic is captured.
+ */
+enum class PayloadCaptureMode {
+  // Bodies are captured up to the rule's limit, or kMaxFullBodyBytes if the rule has none.
+  kFull,
+  // Bodies are truncated to the rule's limit, or --max_body_bytes if the rule has none.
+  kTruncate,
+  // Only headers and metadata are captured, bodies are dropped.
+  kHeadersOnly,
+};
+
+struct PayloadCaptureRule {
+  // Empty fields match everything.
+  std::string ns;
+  std::string service;
+  std::string protocol;
+  PayloadCaptureMode mode = PayloadCaptureMode::kTruncate;
+  // 0 means the default limit of the mode.
+  size_t max_body_bytes = 0;
+};
+
+/**
+ * PayloadCapturePolicy decides how many bytes of a body may be recorded for traffic of a protocol
+ * in a pod, based on the pod's namespace and services. Rules are evaluated in order and the first
+ * matching rule wins. Traffic that matches no rule falls back to the default mode.
+ *
+ * Policies are specified as JSON, for example:
+ *   {"defaultMode": "truncate",
+ *    "rules": [{"namespace": "payments", "mode": "headers_only"},
+ *              {"namespace": "dev", "service": "api", "protocol": "http", "mode": "full",
+ *               "maxBodyBytes": 16384}]}
+ */
+class PayloadCapturePolicy {
+ public:
+  static constexpr size_t kMaxFullBodyBytes = 64 * 1024;
+
+  /**
+   * Parses a JSON policy. An empty string is an empty policy, which leaves every body truncated
+   * to the default limit.
+   */
+  static StatusOr<PayloadCapturePolicy> Parse(std::string_view json);
+
+  /**
+   * A policy that drops all bodies. Used when the configured policy can't be parsed, so that a
+   * mistake in the policy never captures more than was intended.
+   */
+  static PayloadCapturePolicy HeadersOnly();
+
+  bool empty() const { return rules_.empty() && default_mode_ == PayloadCaptureMode::kTruncate; }
+
+  /**
+   * Returns the maximum number of body bytes to record for traffic of the given protocol in a
+   * pod of the namespace and services. 0 means that the body must not be recorded.
+   * default_limit is the limit used by the kTruncate mode when a rule doesn't specify one.
+   */
+  size_t BodyLimit(std::string_view protocol, std::string_view ns,
+                   const std::vector<std::string_view>& services, size_t default_limit) const;
+
+  const std::vector<PayloadCaptureRule>& rules() const { return rules_; }
+  PayloadCaptureMode default_mode() const { return default_mode_; }
+
+ private:
+  PayloadCaptureMode default_mode_ = PayloadCaptureMode::kTruncate;
+  std::vector<PayloadCaptureRule> rules_;
+};
+
+}  // namespace stirling
+}  // namespace px
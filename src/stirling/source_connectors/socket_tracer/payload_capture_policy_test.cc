@@ -0,0 +1,80 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#include "src/stirling/source_connectors/socket_tracer/payload_capture_policy.h"
+
+#include "src/common/testing/testing.h"
+
+namespace px {
+namespace stirling {
+
+constexpr size_t kDefaultLimit = 512;
+
+TEST(PayloadCapturePolicyTest, EmptyPolicyTruncatesToDefault) {
+  ASSERT_OK_AND_ASSIGN(PayloadCapturePolicy policy, PayloadCapturePolicy::Parse(""));
+  EXPECT_TRUE(policy.empty());
+  EXPECT_EQ(policy.BodyLimit("http", "default", {"svc"}, kDefaultLimit), kDefaultLimit);
+}
+
+TEST(PayloadCapturePolicyTest, FirstMatchingRuleWins) {
+  constexpr char kPolicy[] = R"json(
+    {"defaultMode": "headers_only",
+     "rules": [
+       {"namespace": "payments", "mode": "headers_only"},
+       {"namespace": "dev", "service": "api", "protocol": "http", "mode": "full",
+        "maxBodyBytes": 16384},
+       {"namespace": "dev", "mode": "full"},
+       {"protocol": "mysql", "mode": "truncate", "maxBodyBytes": 128}
+     ]})json";
+  ASSERT_OK_AND_ASSIGN(PayloadCapturePolicy policy, PayloadCapturePolicy::Parse(kPolicy));
+  EXPECT_FALSE(policy.empty());
+  EXPECT_EQ(policy.rules().size(), 4);
+
+  EXPECT_EQ(policy.BodyLimit("http", "payments", {"api"}, kDefaultLimit), 0);
+  EXPECT_EQ(policy.BodyLimit("http", "dev", {"web", "api"}, kDefaultLimit), 16384);
+  EXPECT_EQ(policy.BodyLimit("http2", "dev", {"api"}, kDefaultLimit),
+            PayloadCapturePolicy::kMaxFullBodyBytes);
+  EXPECT_EQ(policy.BodyLimit("mysql", "prod", {}, kDefaultLimit), 128);
+  EXPECT_EQ(policy.BodyLimit("http", "prod", {"api"}, kDefaultLimit), 0);
+}
+
+TEST(PayloadCapturePolicyTest, TruncateUsesDefaultLimit) {
+  ASSERT_OK_AND_ASSIGN(PayloadCapturePolicy policy,
+                       PayloadCapturePolicy::Parse(R"({"rules": [{"mode": "truncate"}]})"));
+  EXPECT_EQ(policy.BodyLimit("pgsql", "default", {}, kDefaultLimit), kDefaultLimit);
+}
+
+TEST(PayloadCapturePolicyTest, HeadersOnlyDropsEverything) {
+  PayloadCapturePolicy policy = PayloadCapturePolicy::HeadersOnly();
+  EXPECT_FALSE(policy.empty());
+  EXPECT_EQ(policy.BodyLimit("http", "default", {"svc"}, kDefaultLimit), 0);
+}
+
+TEST(PayloadCapturePolicyTest, InvalidPolicies) {
+  EXPECT_NOT_OK(PayloadCapturePolicy::Parse("not json"));
+  EXPECT_NOT_OK(PayloadCapturePolicy::Parse("[]"));
+  EXPECT_NOT_OK(PayloadCapturePolicy::Parse(R"({"defaultMode": "everything"})"));
+  EXPECT_NOT_OK(PayloadCapturePolicy::Parse(R"({"rules": {"mode": "full"}})"));
+  EXPECT_NOT_OK(PayloadCapturePolicy::Parse(R"({"rules": [{"namespace": "dev"}]})"));
+  EXPECT_NOT_OK(PayloadCapturePolicy::Parse(R"({"rules": [{"mode": "full", "namespace": 1}]})"));
+  EXPECT_NOT_OK(
+      PayloadCapturePolicy::Parse(R"({"rules": [{"mode": "full", "maxBodyBytes": -1}]})"));
+}
+
+}  // namespace stirling
+}  // namespace px
@@ -18,25 +18,44 @@
 
 #include "src/stirling/source_connectors/socket_tracer/protocols/http2/grpc.h"
 
+#include <algorithm>
+#include <memory>
 #include <utility>
+#include <vector>
 
+#include <absl/container/flat_hash_set.h>
+#include <absl/strings/match.h>
+#include <absl/strings/str_replace.h>
+#include <absl/strings/strip.h>
 #include <google/protobuf/empty.pb.h>
 #include <google/protobuf/text_format.h>
+#include <google/protobuf/util/json_util.h>
 
 #include "src/common/base/base.h"
+#include "src/common/base/file.h"
 #include "src/common/zlib/zlib_wrapper.h"
 #include "src/stirling/utils/binary_decoder.h"
 
 DEFINE_bool(socket_tracer_enable_http2_gzip, false,
             "If true, decompress gzipped request and response bodies of HTTP2 messages.");
+DEFINE_string(stirling_grpc_descriptor_set_dir,
+              gflags::StringFromEnv("PL_STIRLING_GRPC_DESCRIPTOR_SET_DIR", ""),
+              "Directory of serialized FileDescriptorSet files, e.g. a mounted ConfigMap, used to "
+              "render the request and response bodies of the traced gRPC methods that they "
+              "describe as JSON. Other bodies are rendered as schemaless text format protobuf.");
 
 namespace px {
 namespace stirling {
 namespace grpc {
 
 using ::google::protobuf::Empty;
+using ::google::protobuf::FieldDescriptor;
+using ::google::protobuf::FileDescriptorSet;
 using ::google::protobuf::Message;
+using ::google::protobuf::Reflection;
 using ::google::protobuf::TextFormat;
+using ::px::grpc::MethodInputOutput;
+using ::px::grpc::ServiceDescriptorDatabase;
 using ::px::stirling::protocols::http2::HalfStream;
 using ::px::stirling::protocols::http2::Stream;
 
@@ -59,11 +78,77 @@ Status PBWireToText(std::string_view message, TextFormat::Printer* pb_printer, s
   return Status::OK();
 }
 
+// The suffix that TextFormat::Printer appends to truncated string fields.
+constexpr std::string_view kTruncatedFieldSuffix = "...<truncated>...";
+
+// Truncates the string and bytes fields of a message, and of its nested messages, the same way
+// that TextFormat::Printer::SetTruncateStringFieldLongerThan() does.
+void TruncateStringFields(size_t len, Message* message) {
+  const Reflection* reflection = message->GetReflection();
+  std::vector<const FieldDescriptor*> fields;
+  reflection->ListFields(*message, &fields);
+
+  auto truncate = [len](std::string* str) {
+    if (str->size() <= len) {
+      return false;
+    }
+    str->resize(len);
+    str->append(kTruncatedFieldSuffix);
+    return true;
+  };
+
+  for (const FieldDescriptor* field : fields) {
+    if (field->cpp_type() == FieldDescriptor::CPPTYPE_MESSAGE) {
+      if (field->is_repeated()) {
+        for (int i = 0; i < reflection->FieldSize(*message, field); ++i) {
+          TruncateStringFields(len, reflection->MutableRepeatedMessage(message, field, i));
+        }
+      } else {
+        TruncateStringFields(len, reflection->MutableMessage(message, field));
+      }
+    } else if (field->cpp_type() == FieldDescriptor::CPPTYPE_STRING) {
+      if (field->is_repeated()) {
+        for (int i = 0; i < reflection->FieldSize(*message, field); ++i) {
+          std::string str = reflection->GetRepeatedString(*message, field, i);
+          if (truncate(&str)) {
+            reflection->SetRepeatedString(message, field, i, std::move(str));
+          }
+        }
+      } else {
+        std::string str = reflection->GetString(*message, field);
+        if (truncate(&str)) {
+          reflection->SetString(message, field, std::move(str));
+        }
+      }
+    }
+  }
+}
+
+// Unlike PBWireToText(), fails if the message is incomplete, so that the caller could fall back to
+// the partial text format message.
+Status PBWireToJSON(std::string_view message, const Message& prototype,
+                    std::optional<int> str_field_truncation_len, std::string* json) {
+  std::unique_ptr<Message> pb(prototype.New());
+  if (!pb->ParseFromArray(message.data(), message.size())) {
+    return error::InvalidArgument("Failed to parse the serialized $0 message.",
+                                  prototype.GetTypeName());
+  }
+  if (str_field_truncation_len.value_or(0) > 0) {
+    TruncateStringFields(str_field_truncation_len.value(), pb.get());
+  }
+  auto status = google::protobuf::util::MessageToJsonString(*pb, json);
+  if (!status.ok()) {
+    return error::InvalidArgument("Failed to convert $0 message to JSON: $1",
+                                  prototype.GetTypeName(), status.ToString());
+  }
+  return Status::OK();
+}
+
 // Parses the gRPC payload into text format protobuf. In addition to parsing protobuf messages, this
 // function extracts compression and length field, and also handles multiple concatenated payloads
-// as well.
+// as well. Messages are rendered as JSON instead, if they parse as the prototype.
 Status GRPCPBWireToText(std::string_view message, bool is_gzipped, std::string* text,
-                        std::optional<int> str_field_truncation_len) {
+                        std::optional<int> str_field_truncation_len, const Message* prototype) {
   // 1 byte compression flag, and 4 bytes length field.
   constexpr size_t kGRPCMessageHeaderSizeBytes = 1 + sizeof(int32_t);
   if (message.size() < kGRPCMessageHeaderSizeBytes) {
@@ -111,9 +196,18 @@ Status GRPCPBWireToText(std::string_view message, bool is_gzipped, std::string*
         continue;
       }
     }
+    std::string_view pb_data = is_compressed ? gunzipped_data : data;
     std::string pb_str;
+    if (prototype != nullptr &&
+        PBWireToJSON(pb_data, *prototype, str_field_truncation_len, &pb_str).ok()) {
+      status = Status::OK();
+      text->append(pb_str);
+      text->append("\n");
+      continue;
+    }
+    pb_str.clear();
     // Include the most recent status.
-    status = PBWireToText(is_compressed ? gunzipped_data : data, &pb_printer, &pb_str);
+    status = PBWireToText(pb_data, &pb_printer, &pb_str);
 
     text->append(pb_str);
   }
@@ -122,12 +216,11 @@ Status GRPCPBWireToText(std::string_view message, bool is_gzipped, std::string*
 
 }  // namespace
 
-// TODO(yzhao): Support reflection to get message types instead of empty message.
 // TODO(yzhao): This wrapper is too thin, remove.
 std::string ParsePB(std::string_view str, bool is_gzipped,
-                    std::optional<int> str_field_truncation_len) {
+                    std::optional<int> str_field_truncation_len, const Message* prototype) {
   std::string text;
-  Status s = GRPCPBWireToText(str, is_gzipped, &text, str_field_truncation_len);
+  Status s = GRPCPBWireToText(str, is_gzipped, &text, str_field_truncation_len, prototype);
   absl::StripTrailingAsciiWhitespace(&text);
   if (!s.ok() && text.empty()) {
     return "<Failed to parse protobuf>";
@@ -137,7 +230,8 @@ std::string ParsePB(std::string_view str, bool is_gzipped,
 
 void ParseReqRespBody(px::stirling::protocols::http2::Stream* http2_stream,
                       std::string_view truncation_suffix,
-                      std::optional<int> str_field_truncation_len) {
+                      std::optional<int> str_field_truncation_len,
+                      ServiceDescriptorDatabase* desc_db) {
   bool has_grpc_encoding = http2_stream->HasGRPCEncodingHeader();
   bool is_gzipped = http2_stream->HasGZipGRPCEncoding();
   if (has_grpc_encoding && !is_gzipped) {
@@ -145,10 +239,21 @@ void ParseReqRespBody(px::stirling::protocols::http2::Stream* http2_stream,
     return;
   }
   if (http2_stream->HasGRPCContentType()) {
-    *http2_stream->send.mutable_data() =
-        ParsePB(http2_stream->send.data(), is_gzipped, str_field_truncation_len);
-    *http2_stream->recv.mutable_data() =
-        ParsePB(http2_stream->recv.data(), is_gzipped, str_field_truncation_len);
+    // Only the request half-stream has the :path header, which names the method.
+    HalfStream* req = &http2_stream->send;
+    HalfStream* resp = &http2_stream->recv;
+    if (!req->headers().HasKey(protocols::http2::headers::kPath)) {
+      std::swap(req, resp);
+    }
+    MethodInputOutput method;
+    if (desc_db != nullptr && req->headers().HasKey(protocols::http2::headers::kPath)) {
+      method = desc_db->GetMethodInputOutput(
+          GRPCMethodName(req->headers().ValueByKey(protocols::http2::headers::kPath)));
+    }
+    *req->mutable_data() =
+        ParsePB(req->data(), is_gzipped, str_field_truncation_len, method.input.get());
+    *resp->mutable_data() =
+        ParsePB(resp->data(), is_gzipped, str_field_truncation_len, method.output.get());
   }
   if (http2_stream->send.data_truncated()) {
     http2_stream->send.mutable_data()->append(truncation_suffix);
@@ -158,6 +263,61 @@ void ParseReqRespBody(px::stirling::protocols::http2::Stream* http2_stream,
   }
 }
 
+std::string GRPCMethodName(std::string_view path) {
+  absl::ConsumePrefix(&path, "/");
+  return absl::StrReplaceAll(path, {{"/", "."}});
+}
+
+StatusOr<FileDescriptorSet> ReadFileDescriptorSets(const std::filesystem::path& dir) {
+  std::error_code ec;
+  std::filesystem::directory_iterator iter(dir, ec);
+  if (ec) {
+    return error::InvalidArgument("Failed to list $0: $1", dir.string(), ec.message());
+  }
+
+  std::vector<std::filesystem::path> paths;
+  for (const auto& entry : iter) {
+    if (absl::StartsWith(entry.path().filename().string(), ".") || !entry.is_regular_file(ec)) {
+      continue;
+    }
+    paths.push_back(entry.path());
+  }
+  // Sort so that the first of several different definitions of a file is always the same one.
+  std::sort(paths.begin(), paths.end());
+
+  FileDescriptorSet res;
+  absl::flat_hash_set<std::string> file_names;
+  for (const auto& path : paths) {
+    PX_ASSIGN_OR_RETURN(std::string contents, ReadFileToString(path.string()));
+    FileDescriptorSet fdset;
+    if (!fdset.ParseFromString(contents)) {
+      return error::InvalidArgument("$0 is not a serialized FileDescriptorSet.", path.string());
+    }
+    for (auto& file : *fdset.mutable_file()) {
+      if (file_names.insert(file.name()).second) {
+        *res.add_file() = std::move(file);
+      }
+    }
+  }
+  return res;
+}
+
+ServiceDescriptorDatabase* DefaultServiceDescriptorDatabase() {
+  static ServiceDescriptorDatabase* desc_db = []() -> ServiceDescriptorDatabase* {
+    if (FLAGS_stirling_grpc_descriptor_set_dir.empty()) {
+      return nullptr;
+    }
+    auto fdset_or = ReadFileDescriptorSets(FLAGS_stirling_grpc_descriptor_set_dir);
+    if (!fdset_or.ok()) {
+      LOG(ERROR) << absl::Substitute("Failed to read gRPC descriptors from $0: $1",
+                                     FLAGS_stirling_grpc_descriptor_set_dir, fdset_or.msg());
+      return nullptr;
+    }
+    return new ServiceDescriptorDatabase(fdset_or.ConsumeValueOrDie());
+  }();
+  return desc_db;
+}
+
 }  // namespace grpc
 }  // namespace stirling
 }  // namespace px
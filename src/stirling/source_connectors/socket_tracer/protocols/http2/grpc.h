@@ -18,15 +18,19 @@
 
 #pragma once
 
+#include <google/protobuf/descriptor.pb.h>
 #include <google/protobuf/message.h>
 
+#include <filesystem>
 #include <optional>
 #include <string>
 #include <string_view>
 
+#include "src/common/grpcutils/service_descriptor_database.h"
 #include "src/stirling/source_connectors/socket_tracer/protocols/http2/types.h"
 
 DECLARE_bool(socket_tracer_enable_http2_gzip);
+DECLARE_string(stirling_grpc_descriptor_set_dir);
 
 namespace px {
 namespace stirling {
@@ -34,10 +38,13 @@ namespace grpc {
 
 /**
  * Parses protobuf body of a HTTP2 message.
+ * If a prototype is given, its messages are rendered as JSON; otherwise, or if a message does not
+ * parse as the prototype, as schemaless text format protobuf.
  * Exported for testing.
  */
 std::string ParsePB(std::string_view str, bool is_gzipped = false,
-                    std::optional<int> str_field_truncation_len = std::nullopt);
+                    std::optional<int> str_field_truncation_len = std::nullopt,
+                    const google::protobuf::Message* prototype = nullptr);
 
 /**
  * Parses the request & response body of the input HTTP2 Stream object.
@@ -48,10 +55,32 @@ std::string ParsePB(std::string_view str, bool is_gzipped = false,
  * @param truncation_suffix The string suffix appended to any truncated string/bytes fields.
  * @param str_field_truncation_len The string length of any string/bytes fields beyond which
  *        truncation applies, if specified.
+ * @param desc_db If given, the bodies of the methods that it describes are rendered as JSON.
  */
 void ParseReqRespBody(px::stirling::protocols::http2::Stream* http2_stream,
                       std::string_view truncation_suffix = {},
-                      std::optional<int> str_truncation_len = std::nullopt);
+                      std::optional<int> str_truncation_len = std::nullopt,
+                      ::px::grpc::ServiceDescriptorDatabase* desc_db = nullptr);
+
+/**
+ * Returns the fully-qualified method name of a gRPC :path header, e.g. "/pkg.Service/Method" is
+ * "pkg.Service.Method".
+ */
+std::string GRPCMethodName(std::string_view path);
+
+/**
+ * Reads and merges all of the serialized FileDescriptorSet files in a directory.
+ * Hidden files, like the ones that a mounted ConfigMap keeps its data in, are skipped.
+ * Files that appear in more than one set are kept once.
+ */
+StatusOr<google::protobuf::FileDescriptorSet> ReadFileDescriptorSets(
+    const std::filesystem::path& dir);
+
+/**
+ * Returns the descriptors read from --stirling_grpc_descriptor_set_dir, or nullptr if it is not
+ * set or cannot be read.
+ */
+::px::grpc::ServiceDescriptorDatabase* DefaultServiceDescriptorDatabase();
 
 }  // namespace grpc
 }  // namespace stirling
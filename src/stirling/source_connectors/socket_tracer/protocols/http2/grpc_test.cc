@@ -21,6 +21,7 @@
 #include <utility>
 
 #include "src/common/base/base.h"
+#include "src/common/base/file.h"
 #include "src/common/testing/testing.h"
 #include "src/stirling/source_connectors/socket_tracer/protocols/http2/testing/proto/greet.pb.h"
 #include "src/stirling/source_connectors/socket_tracer/protocols/http2/testing/proto/multi_fields.pb.h"
@@ -29,11 +30,16 @@ namespace px {
 namespace stirling {
 namespace grpc {
 
+using ::google::protobuf::FileDescriptorProto;
+using ::google::protobuf::FileDescriptorSet;
 using ::google::protobuf::TextFormat;
+using ::px::grpc::MethodInputOutput;
+using ::px::grpc::ServiceDescriptorDatabase;
 using ::px::stirling::protocols::http2::testing::HelloReply;
 using ::px::stirling::protocols::http2::testing::HelloRequest;
 using ::px::stirling::protocols::http2::testing::MultiFieldsMessage;
 using ::testing::HasSubstr;
+using ::testing::ElementsAre;
 using ::testing::StrEq;
 
 std::string PackGRPCMsg(std::string_view serialized_pb) {
@@ -149,6 +155,117 @@ TEST(ParseReqRespBodyTest, BodyUnchangedForUnsupportedCompressionAlgo) {
   EXPECT_THAT(http2_stream.recv.data(), StrEq("recv message"));
 }
 
+FileDescriptorSet GreetFileDescriptorSet() {
+  FileDescriptorSet fdset;
+  HelloRequest::descriptor()->file()->CopyTo(fdset.add_file());
+  return fdset;
+}
+
+TEST(ParsePB, JSONWithPrototype) {
+  HelloRequest req;
+  req.set_name("pixielabs");
+  req.set_count(2);
+  std::string s = absl::StrCat(PackGRPCMsg(req.SerializeAsString()),
+                               PackGRPCMsg(req.SerializeAsString()));
+  EXPECT_THAT(ParsePB(s, /*is_gzipped*/ false, /*str_field_truncation_len*/ std::nullopt, &req),
+              StrEq("{\"name\":\"pixielabs\",\"count\":2}\n{\"name\":\"pixielabs\",\"count\":2}"));
+}
+
+TEST(ParsePB, JSONLongStringTruncation) {
+  HelloReply reply;
+  reply.set_message("This is a long string. It is so long that is expected to get truncated.");
+  std::string s = PackGRPCMsg(reply.SerializeAsString());
+  EXPECT_THAT(ParsePB(s, /*is_gzipped*/ false, /*str_field_truncation_len*/ 32, &reply),
+              StrEq(R"({"message":"This is a long string. It is so ...<truncated>..."})"));
+}
+
+// Tests that a message that does not parse as the prototype falls back to text format.
+TEST(ParsePB, TextWhenNotParsedWithPrototype) {
+  HelloRequest req;
+  std::string_view data = CreateStringView<char>("\x00\x00\x00\x00\x02\x0A\x0B");
+  EXPECT_THAT(ParsePB(data, /*is_gzipped*/ false, /*str_field_truncation_len*/ std::nullopt, &req),
+              StrEq("<Failed to parse protobuf>"));
+}
+
+TEST(GRPCMethodNameTest, Basic) {
+  EXPECT_EQ(GRPCMethodName("/px.stirling.protocols.http2.testing.Greeter/SayHello"),
+            "px.stirling.protocols.http2.testing.Greeter.SayHello");
+  EXPECT_EQ(GRPCMethodName(""), "");
+}
+
+// Tests that the bodies of a method in the descriptor database are rendered as JSON, whichever
+// half-stream has the request.
+TEST(ParseReqRespBodyTest, JSONForDescribedMethod) {
+  ServiceDescriptorDatabase desc_db(GreetFileDescriptorSet());
+
+  HelloRequest req;
+  req.set_name("pixielabs");
+  HelloReply reply;
+  reply.set_message("Hello pixielabs");
+
+  protocols::http2::Stream http2_stream;
+  http2_stream.recv.mutable_headers()->insert(
+      std::make_pair(":path", "/px.stirling.protocols.http2.testing.Greeter/SayHello"));
+  http2_stream.recv.mutable_headers()->insert(std::make_pair("content-type", "application/grpc"));
+  http2_stream.recv.mutable_data()->assign(PackGRPCMsg(req.SerializeAsString()));
+  http2_stream.send.mutable_headers()->insert(std::make_pair("content-type", "application/grpc"));
+  http2_stream.send.mutable_data()->assign(PackGRPCMsg(reply.SerializeAsString()));
+
+  ParseReqRespBody(&http2_stream, /*truncation_suffix*/ {},
+                   /*str_field_truncation_len*/ std::nullopt, &desc_db);
+  EXPECT_THAT(http2_stream.recv.data(), StrEq(R"({"name":"pixielabs"})"));
+  EXPECT_THAT(http2_stream.send.data(), StrEq(R"({"message":"Hello pixielabs"})"));
+}
+
+// Tests that the bodies of a method that is not in the descriptor database are text format.
+TEST(ParseReqRespBodyTest, TextForUnknownMethod) {
+  ServiceDescriptorDatabase desc_db(GreetFileDescriptorSet());
+
+  HelloRequest req;
+  req.set_name("pixielabs");
+
+  protocols::http2::Stream http2_stream;
+  http2_stream.send.mutable_headers()->insert(std::make_pair(":path", "/foo.Bar/Baz"));
+  http2_stream.send.mutable_headers()->insert(std::make_pair("content-type", "application/grpc"));
+  http2_stream.send.mutable_data()->assign(PackGRPCMsg(req.SerializeAsString()));
+
+  ParseReqRespBody(&http2_stream, /*truncation_suffix*/ {},
+                   /*str_field_truncation_len*/ std::nullopt, &desc_db);
+  EXPECT_THAT(http2_stream.send.data(), StrEq("1 {\n  14: 105\n  15: 105\n  12: 0x7362616c\n}"));
+}
+
+TEST(ReadFileDescriptorSetsTest, MergesSetsAndSkipsHiddenFiles) {
+  px::testing::TempDir dir;
+
+  FileDescriptorSet fdset = GreetFileDescriptorSet();
+  FileDescriptorProto* other = fdset.add_file();
+  other->set_name("other.proto");
+  other->set_package("other");
+  ASSERT_OK(WriteFileFromString((dir.path() / "a.pb").string(), fdset.SerializeAsString()));
+  ASSERT_OK(WriteFileFromString((dir.path() / "b.pb").string(),
+                                GreetFileDescriptorSet().SerializeAsString()));
+  ASSERT_OK(WriteFileFromString((dir.path() / ".hidden").string(), "not a descriptor set"));
+
+  ASSERT_OK_AND_ASSIGN(FileDescriptorSet merged, ReadFileDescriptorSets(dir.path()));
+  std::vector<std::string> names;
+  for (const auto& file : merged.file()) {
+    names.push_back(file.name());
+  }
+  EXPECT_THAT(names, ElementsAre(HelloRequest::descriptor()->file()->name(), "other.proto"));
+
+  ServiceDescriptorDatabase desc_db(merged);
+  MethodInputOutput method =
+      desc_db.GetMethodInputOutput("px.stirling.protocols.http2.testing.Greeter.SayHello");
+  ASSERT_NE(method.input, nullptr);
+  EXPECT_EQ(method.input->GetTypeName(), "px.stirling.protocols.http2.testing.HelloRequest");
+}
+
+TEST(ReadFileDescriptorSetsTest, InvalidFile) {
+  px::testing::TempDir dir;
+  ASSERT_OK(WriteFileFromString((dir.path() / "a.pb").string(), "not a descriptor set"));
+  EXPECT_NOT_OK(ReadFileDescriptorSets(dir.path()));
+}
+
 }  // namespace grpc
 }  // namespace stirling
 }  // namespace px
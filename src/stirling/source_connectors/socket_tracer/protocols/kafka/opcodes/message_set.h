@@ -34,10 +34,21 @@ namespace kafka {
 struct RecordMessage {
   std::string key;
   std::string value;
+  // Whether the key and value were decoded into JSON with their schema.
+  bool key_is_json = false;
+  bool value_is_json = false;
 
   void ToJSON(utils::JSONObjectBuilder* builder) const {
-    builder->WriteKV("key", key);
-    builder->WriteKV("value", value);
+    if (key_is_json) {
+      builder->WriteKVRawJSON("key", key);
+    } else {
+      builder->WriteKV("key", key);
+    }
+    if (value_is_json) {
+      builder->WriteKVRawJSON("value", value);
+    } else {
+      builder->WriteKV("value", value);
+    }
   }
 };
 
@@ -52,10 +63,13 @@ struct RecordBatch {
 struct MessageSet {
   int64_t size = 0;
   std::vector<RecordBatch> record_batches;
+  // Whether any of the records were decoded with their schema. The record batches are only
+  // included in the JSON when they are, as the raw records are usually not human readable.
+  bool records_decoded = false;
 
   void ToJSON(utils::JSONObjectBuilder* builder, bool omit_record_batches = true) const {
     builder->WriteKV("size", size);
-    if (!omit_record_batches) {
+    if (!omit_record_batches || records_decoded) {
       builder->WriteKVArrayRecursive<RecordBatch>("record_batchs", record_batches);
     }
   }
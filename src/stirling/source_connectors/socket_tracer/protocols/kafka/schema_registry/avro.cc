@@ -0,0 +1,278 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+
+#include "src/stirling/source_connectors/socket_tracer/protocols/kafka/schema_registry/avro.h"
+
+#include <absl/strings/escaping.h>
+
+#include <utility>
+
+namespace px {
+namespace stirling {
+namespace protocols {
+namespace kafka {
+
+namespace {
+
+// Avro ints and longs are zig-zag encoded variable-length integers.
+StatusOr<int64_t> ExtractLong(BinaryDecoder* decoder) {
+  PX_ASSIGN_OR_RETURN(uint64_t n, decoder->ExtractUVarInt());
+  return static_cast<int64_t>(n >> 1) ^ -static_cast<int64_t>(n & 1);
+}
+
+StatusOr<std::string_view> ExtractBytes(BinaryDecoder* decoder) {
+  PX_ASSIGN_OR_RETURN(int64_t len, ExtractLong(decoder));
+  if (len < 0) {
+    return error::InvalidArgument("Negative Avro bytes length $0.", len);
+  }
+  return decoder->ExtractString(len);
+}
+
+// Returns the full name of a named type, which is qualified by its namespace, or by the enclosing
+// namespace if it has none.
+std::string FullName(const rapidjson::Value& schema, std::string_view ns) {
+  std::string_view name = schema["name"].GetString();
+  if (name.find('.') != std::string_view::npos) {
+    return std::string(name);
+  }
+  if (schema.HasMember("namespace") && schema["namespace"].IsString()) {
+    ns = schema["namespace"].GetString();
+  }
+  if (ns.empty()) {
+    return std::string(name);
+  }
+  return absl::StrCat(ns, ".", name);
+}
+
+// Returns the namespace of a full name.
+std::string_view Namespace(std::string_view full_name) {
+  size_t pos = full_name.rfind('.');
+  if (pos == std::string_view::npos) {
+    return {};
+  }
+  return full_name.substr(0, pos);
+}
+
+}  // namespace
+
+StatusOr<std::unique_ptr<AvroSchema>> AvroSchema::Parse(std::string_view schema) {
+  std::unique_ptr<AvroSchema> s(new AvroSchema());
+  s->doc_.Parse(schema.data(), schema.size());
+  if (s->doc_.HasParseError()) {
+    return error::InvalidArgument("Failed to parse Avro schema, error code $0.",
+                                  s->doc_.GetParseError());
+  }
+  PX_RETURN_IF_ERROR(s->RegisterNamedTypes(s->doc_, ""));
+  return s;
+}
+
+Status AvroSchema::RegisterNamedTypes(const rapidjson::Value& schema, std::string_view ns) {
+  if (schema.IsArray()) {
+    for (const auto& branch : schema.GetArray()) {
+      PX_RETURN_IF_ERROR(RegisterNamedTypes(branch, ns));
+    }
+    return Status::OK();
+  }
+  if (!schema.IsObject()) {
+    return Status::OK();
+  }
+  if (!schema.HasMember("type")) {
+    return error::InvalidArgument("Avro schema is missing a type.");
+  }
+
+  const rapidjson::Value& type = schema["type"];
+  if (!type.IsString()) {
+    return RegisterNamedTypes(type, ns);
+  }
+
+  std::string_view type_name = type.GetString();
+  if (type_name == "record" || type_name == "error" || type_name == "enum" ||
+      type_name == "fixed") {
+    if (!schema.HasMember("name") || !schema["name"].IsString()) {
+      return error::InvalidArgument("Avro $0 is missing a name.", type_name);
+    }
+    std::string full_name = FullName(schema, ns);
+    named_types_[full_name] = &schema;
+    if (type_name == "record" || type_name == "error") {
+      if (!schema.HasMember("fields") || !schema["fields"].IsArray()) {
+        return error::InvalidArgument("Avro record $0 is missing its fields.", full_name);
+      }
+      for (const auto& field : schema["fields"].GetArray()) {
+        if (!field.IsObject() || !field.HasMember("name") || !field["name"].IsString() ||
+            !field.HasMember("type")) {
+          return error::InvalidArgument("Avro record $0 has an invalid field.", full_name);
+        }
+        PX_RETURN_IF_ERROR(RegisterNamedTypes(field["type"], Namespace(full_name)));
+      }
+    }
+  } else if (type_name == "array" && schema.HasMember("items")) {
+    PX_RETURN_IF_ERROR(RegisterNamedTypes(schema["items"], ns));
+  } else if (type_name == "map" && schema.HasMember("values")) {
+    PX_RETURN_IF_ERROR(RegisterNamedTypes(schema["values"], ns));
+  }
+  return Status::OK();
+}
+
+StatusOr<std::pair<std::string_view, const rapidjson::Value*>> AvroSchema::Resolve(
+    std::string_view name, std::string_view ns) const {
+  auto it = named_types_.end();
+  if (!ns.empty() && name.find('.') == std::string_view::npos) {
+    it = named_types_.find(absl::StrCat(ns, ".", name));
+  }
+  if (it == named_types_.end()) {
+    it = named_types_.find(name);
+  }
+  if (it == named_types_.end()) {
+    return error::InvalidArgument("Unknown Avro type $0.", name);
+  }
+  return std::make_pair(std::string_view(it->first), it->second);
+}
+
+StatusOr<std::string> AvroSchema::ToJSON(std::string_view data) const {
+  BinaryDecoder decoder(data);
+  rapidjson::StringBuffer buffer;
+  JSONWriter writer(buffer);
+  PX_RETURN_IF_ERROR(Decode(doc_, "", &decoder, &writer));
+  if (!decoder.eof()) {
+    return error::InvalidArgument("$0 bytes left over after decoding the Avro datum.",
+                                  decoder.BufSize());
+  }
+  return std::string(buffer.GetString());
+}
+
+Status AvroSchema::Decode(const rapidjson::Value& schema, std::string_view ns,
+                          BinaryDecoder* decoder, JSONWriter* writer) const {
+  // A union.
+  if (schema.IsArray()) {
+    PX_ASSIGN_OR_RETURN(int64_t index, ExtractLong(decoder));
+    if (index < 0 || index >= static_cast<int64_t>(schema.Size())) {
+      return error::InvalidArgument("Avro union index $0 is out of range.", index);
+    }
+    return Decode(schema[static_cast<rapidjson::SizeType>(index)], ns, decoder, writer);
+  }
+  if (schema.IsString()) {
+    return DecodeType(schema.GetString(), schema, ns, decoder, writer);
+  }
+  if (!schema.IsObject() || !schema.HasMember("type")) {
+    return error::InvalidArgument("Invalid Avro schema.");
+  }
+  const rapidjson::Value& type = schema["type"];
+  if (!type.IsString()) {
+    return Decode(type, ns, decoder, writer);
+  }
+  return DecodeType(type.GetString(), schema, ns, decoder, writer);
+}
+
+Status AvroSchema::DecodeType(std::string_view type, const rapidjson::Value& schema,
+                               std::string_view ns, BinaryDecoder* decoder,
+                               JSONWriter* writer) const {
+  if (type == "null") {
+    writer->Null();
+  } else if (type == "boolean") {
+    PX_ASSIGN_OR_RETURN(uint8_t b, decoder->ExtractInt<uint8_t>());
+    writer->Bool(b != 0);
+  } else if (type == "int" || type == "long") {
+    PX_ASSIGN_OR_RETURN(int64_t n, ExtractLong(decoder));
+    writer->Int64(n);
+  } else if (type == "float") {
+    PX_ASSIGN_OR_RETURN(std::string_view buf, decoder->ExtractString(sizeof(float)));
+    writer->Double(::px::utils::LEndianBytesToFloat<float>(buf));
+  } else if (type == "double") {
+    PX_ASSIGN_OR_RETURN(std::string_view buf, decoder->ExtractString(sizeof(double)));
+    writer->Double(::px::utils::LEndianBytesToFloat<double>(buf));
+  } else if (type == "string") {
+    PX_ASSIGN_OR_RETURN(std::string_view s, ExtractBytes(decoder));
+    writer->String(s.data(), s.size());
+  } else if (type == "bytes") {
+    PX_ASSIGN_OR_RETURN(std::string_view s, ExtractBytes(decoder));
+    std::string encoded = absl::Base64Escape(s);
+    writer->String(encoded.data(), encoded.size());
+  } else if (!schema.IsObject() && (type == "array" || type == "map" || type == "record" ||
+                                     type == "error" || type == "enum" || type == "fixed")) {
+    return error::InvalidArgument("Avro $0 must be defined by an object.", type);
+  } else if (type == "array" || type == "map") {
+    const bool is_map = type == "map";
+    const char* items_key = is_map ? "values" : "items";
+    if (!schema.HasMember(items_key)) {
+      return error::InvalidArgument("Avro $0 is missing its $1.", type, items_key);
+    }
+    is_map ? writer->StartObject() : writer->StartArray();
+    // Arrays and maps are encoded as blocks, each with a count of items, and end with an empty
+    // block. A negative count is followed by the size of the block in bytes.
+    while (true) {
+      PX_ASSIGN_OR_RETURN(int64_t count, ExtractLong(decoder));
+      if (count == 0) {
+        break;
+      }
+      if (count < 0) {
+        count = -count;
+        PX_ASSIGN_OR_RETURN(int64_t block_size, ExtractLong(decoder));
+        PX_UNUSED(block_size);
+      }
+      for (int64_t i = 0; i < count; ++i) {
+        if (is_map) {
+          PX_ASSIGN_OR_RETURN(std::string_view key, ExtractBytes(decoder));
+          writer->Key(key.data(), key.size());
+        }
+        PX_RETURN_IF_ERROR(Decode(schema[items_key], ns, decoder, writer));
+      }
+    }
+    is_map ? writer->EndObject() : writer->EndArray();
+  } else if (type == "record" || type == "error") {
+    std::string full_name = FullName(schema, ns);
+    writer->StartObject();
+    for (const auto& field : schema["fields"].GetArray()) {
+      const rapidjson::Value& name = field["name"];
+      writer->Key(name.GetString(), name.GetStringLength());
+      PX_RETURN_IF_ERROR(Decode(field["type"], Namespace(full_name), decoder, writer));
+    }
+    writer->EndObject();
+  } else if (type == "enum") {
+    PX_ASSIGN_OR_RETURN(int64_t index, ExtractLong(decoder));
+    if (!schema.HasMember("symbols") || !schema["symbols"].IsArray()) {
+      return error::InvalidArgument("Avro enum is missing its symbols.");
+    }
+    const rapidjson::Value& symbols = schema["symbols"];
+    if (index < 0 || index >= static_cast<int64_t>(symbols.Size())) {
+      return error::InvalidArgument("Avro enum index $0 is out of range.", index);
+    }
+    const rapidjson::Value& symbol = symbols[static_cast<rapidjson::SizeType>(index)];
+    if (!symbol.IsString()) {
+      return error::InvalidArgument("Avro enum symbol $0 is not a string.", index);
+    }
+    writer->String(symbol.GetString(), symbol.GetStringLength());
+  } else if (type == "fixed") {
+    if (!schema.HasMember("size") || !schema["size"].IsInt()) {
+      return error::InvalidArgument("Avro fixed is missing its size.");
+    }
+    PX_ASSIGN_OR_RETURN(std::string_view s, decoder->ExtractString(schema["size"].GetInt()));
+    std::string encoded = absl::Base64Escape(s);
+    writer->String(encoded.data(), encoded.size());
+  } else {
+    // A reference to a named type defined earlier in the schema.
+    PX_ASSIGN_OR_RETURN(auto named, Resolve(type, ns));
+    return Decode(*named.second, Namespace(named.first), decoder, writer);
+  }
+  return Status::OK();
+}
+
+}  // namespace kafka
+}  // namespace protocols
+}  // namespace stirling
+}  // namespace px
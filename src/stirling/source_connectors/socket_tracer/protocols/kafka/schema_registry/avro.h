@@ -0,0 +1,84 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+
+#pragma once
+
+#include <rapidjson/document.h>
+#include <rapidjson/stringbuffer.h>
+#include <rapidjson/writer.h>
+
+#include <memory>
+#include <string>
+#include <string_view>
+#include <utility>
+
+#include <absl/container/flat_hash_map.h>
+
+#include "src/common/base/base.h"
+#include "src/stirling/source_connectors/socket_tracer/protocols/kafka/schema_registry/schema.h"
+#include "src/stirling/utils/binary_decoder.h"
+
+namespace px {
+namespace stirling {
+namespace protocols {
+namespace kafka {
+
+/**
+ * An Avro schema, which decodes data in the Avro binary encoding into JSON.
+ *
+ * Unions are written as their selected branch, without the type wrapper of the Avro JSON encoding,
+ * and bytes and fixed values are base64 encoded. Logical types are written as their underlying
+ * type.
+ */
+class AvroSchema : public Schema {
+ public:
+  /**
+   * Parses the JSON representation of an Avro schema.
+   */
+  static StatusOr<std::unique_ptr<AvroSchema>> Parse(std::string_view schema);
+
+  /**
+   * Decodes a single datum of this schema into JSON.
+   */
+  StatusOr<std::string> ToJSON(std::string_view data) const override;
+
+ private:
+  using JSONWriter = rapidjson::Writer<rapidjson::StringBuffer>;
+
+  AvroSchema() = default;
+
+  Status RegisterNamedTypes(const rapidjson::Value& schema, std::string_view ns);
+  // Looks up a named type from within the given namespace, and returns its full name and schema.
+  StatusOr<std::pair<std::string_view, const rapidjson::Value*>> Resolve(
+      std::string_view name, std::string_view ns) const;
+  Status Decode(const rapidjson::Value& schema, std::string_view ns, BinaryDecoder* decoder,
+                JSONWriter* writer) const;
+  Status DecodeType(std::string_view type, const rapidjson::Value& schema, std::string_view ns,
+                     BinaryDecoder* decoder, JSONWriter* writer) const;
+
+  rapidjson::Document doc_;
+  // Records, enums and fixed types by their full name, which later parts of the schema can use as
+  // a type.
+  absl::flat_hash_map<std::string, const rapidjson::Value*> named_types_;
+};
+
+}  // namespace kafka
+}  // namespace protocols
+}  // namespace stirling
+}  // namespace px
@@ -0,0 +1,98 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+
+#include "src/stirling/source_connectors/socket_tracer/protocols/kafka/schema_registry/avro.h"
+
+#include "src/common/base/types.h"
+#include "src/common/testing/testing.h"
+
+namespace px {
+namespace stirling {
+namespace protocols {
+namespace kafka {
+
+constexpr char kOrderSchema[] = R"({
+  "type": "record",
+  "name": "Order",
+  "namespace": "shop",
+  "fields": [
+    {"name": "id", "type": "long"},
+    {"name": "item", "type": "string"},
+    {"name": "note", "type": ["null", "string"]},
+    {"name": "tags", "type": {"type": "array", "items": "string"}},
+    {"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["NEW", "PAID"]}},
+    {"name": "next", "type": ["null", "Order"]}
+  ]
+})";
+
+TEST(AvroSchemaTest, DecodeRecord) {
+  ASSERT_OK_AND_ASSIGN(auto schema, AvroSchema::Parse(kOrderSchema));
+
+  // id=42, item="pen", note="hi", tags=["a", "b"], status=PAID, next=null.
+  std::string_view data =
+      ConstStringView("\x54\x06pen\x02\x04hi\x04\x02"
+                             "a\x02"
+                             "b\x00\x02\x00");
+  EXPECT_OK_AND_EQ(schema->ToJSON(data),
+                   R"({"id":42,"item":"pen","note":"hi","tags":["a","b"],"status":"PAID",)"
+                   R"("next":null})");
+}
+
+TEST(AvroSchemaTest, DecodeRecursiveRecord) {
+  ASSERT_OK_AND_ASSIGN(auto schema, AvroSchema::Parse(kOrderSchema));
+
+  // The next order refers to the Order type by its name.
+  std::string_view data = ConstStringView(
+      "\x54\x06pen\x00\x00\x00\x02"
+      "\x02\x00\x00\x00\x00\x00");
+  EXPECT_OK_AND_EQ(schema->ToJSON(data),
+                   R"({"id":42,"item":"pen","note":null,"tags":[],"status":"NEW","next":)"
+                   R"({"id":1,"item":"","note":null,"tags":[],"status":"NEW","next":null}})");
+}
+
+TEST(AvroSchemaTest, DecodePrimitives) {
+  ASSERT_OK_AND_ASSIGN(auto schema, AvroSchema::Parse(R"({"type": "map", "values": "double"})"));
+  // {"x": 1.5}, with 1.5 as a little-endian double.
+  std::string_view data =
+      ConstStringView("\x02\x02x\x00\x00\x00\x00\x00\x00\xf8\x3f\x00");
+  EXPECT_OK_AND_EQ(schema->ToJSON(data), R"({"x":1.5})");
+
+  ASSERT_OK_AND_ASSIGN(schema, AvroSchema::Parse(R"("bytes")"));
+  EXPECT_OK_AND_EQ(schema->ToJSON(ConstStringView("\x06\x01\x02\x03")), R"("AQID")");
+}
+
+TEST(AvroSchemaTest, Errors) {
+  EXPECT_NOT_OK(AvroSchema::Parse("{"));
+  EXPECT_NOT_OK(AvroSchema::Parse(R"({"type": "record", "fields": []})"));
+
+  ASSERT_OK_AND_ASSIGN(auto schema, AvroSchema::Parse(kOrderSchema));
+  // Truncated data.
+  EXPECT_NOT_OK(schema->ToJSON(ConstStringView("\x54\x06pe")));
+  // Left over data.
+  ASSERT_OK_AND_ASSIGN(schema, AvroSchema::Parse(R"("long")"));
+  EXPECT_NOT_OK(schema->ToJSON(ConstStringView("\x54\x00")));
+  // Unknown named type.
+  ASSERT_OK_AND_ASSIGN(schema, AvroSchema::Parse(R"("shop.Missing")"));
+  EXPECT_NOT_OK(schema->ToJSON(ConstStringView("\x00")));
+}
+
+}  // namespace kafka
+}  // namespace protocols
+}  // namespace stirling
+}  // namespace px
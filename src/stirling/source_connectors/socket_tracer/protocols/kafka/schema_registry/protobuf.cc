@@ -0,0 +1,141 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+
+#include "src/stirling/source_connectors/socket_tracer/protocols/kafka/schema_registry/protobuf.h"
+
+#include <google/protobuf/compiler/parser.h>
+#include <google/protobuf/descriptor.pb.h>
+#include <google/protobuf/io/tokenizer.h>
+#include <google/protobuf/io/zero_copy_stream_impl_lite.h>
+#include <google/protobuf/util/json_util.h>
+
+#include <vector>
+
+#include "src/stirling/utils/binary_decoder.h"
+
+namespace px {
+namespace stirling {
+namespace protocols {
+namespace kafka {
+
+using ::google::protobuf::Descriptor;
+using ::google::protobuf::DescriptorPool;
+using ::google::protobuf::FileDescriptorProto;
+using ::google::protobuf::Message;
+
+namespace {
+
+// Collects the first error from parsing a .proto file.
+class ParseErrorCollector : public google::protobuf::io::ErrorCollector {
+ public:
+  void AddError(int line, int column, const std::string& message) override {
+    if (error_.empty()) {
+      error_ = absl::Substitute("$0:$1: $2", line + 1, column + 1, message);
+    }
+  }
+
+  const std::string& error() const { return error_; }
+
+ private:
+  std::string error_;
+};
+
+// Confluent encodes the message indexes as zig-zag encoded variable-length integers.
+StatusOr<int64_t> ExtractZigZagVarint(BinaryDecoder* decoder) {
+  PX_ASSIGN_OR_RETURN(uint64_t n, decoder->ExtractUVarInt());
+  return static_cast<int64_t>(n >> 1) ^ -static_cast<int64_t>(n & 1);
+}
+
+}  // namespace
+
+// The generated pool is the underlay, so that imports of the well-known types resolve.
+ProtobufSchema::ProtobufSchema()
+    : pool_(DescriptorPool::generated_pool()), message_factory_(&pool_) {}
+
+StatusOr<std::unique_ptr<ProtobufSchema>> ProtobufSchema::Parse(std::string_view schema) {
+  google::protobuf::io::ArrayInputStream input(schema.data(), schema.size());
+  ParseErrorCollector error_collector;
+  google::protobuf::io::Tokenizer tokenizer(&input, &error_collector);
+  google::protobuf::compiler::Parser parser;
+  parser.RecordErrorsTo(&error_collector);
+
+  FileDescriptorProto file_proto;
+  if (!parser.Parse(&tokenizer, &file_proto)) {
+    return error::InvalidArgument("Failed to parse Protobuf schema: $0", error_collector.error());
+  }
+  // Schemas from the registry have no file name, but the pool requires one.
+  file_proto.set_name("schema.proto");
+
+  std::unique_ptr<ProtobufSchema> s(new ProtobufSchema());
+  s->file_ = s->pool_.BuildFile(file_proto);
+  if (s->file_ == nullptr) {
+    return error::InvalidArgument("Failed to build Protobuf schema.");
+  }
+  return s;
+}
+
+StatusOr<std::string> ProtobufSchema::ToJSON(std::string_view payload) const {
+  BinaryDecoder decoder(payload);
+
+  // The message indexes are a count followed by that many indexes. A count of 0 is shorthand for
+  // the first message type in the file.
+  PX_ASSIGN_OR_RETURN(int64_t count, ExtractZigZagVarint(&decoder));
+  if (count < 0) {
+    return error::InvalidArgument("Negative Protobuf message index count $0.", count);
+  }
+  std::vector<int64_t> indexes;
+  for (int64_t i = 0; i < count; ++i) {
+    PX_ASSIGN_OR_RETURN(int64_t index, ExtractZigZagVarint(&decoder));
+    indexes.push_back(index);
+  }
+  if (indexes.empty()) {
+    indexes.push_back(0);
+  }
+
+  if (indexes[0] < 0 || indexes[0] >= file_->message_type_count()) {
+    return error::InvalidArgument("Protobuf message index $0 is out of range.", indexes[0]);
+  }
+  const Descriptor* descriptor = file_->message_type(indexes[0]);
+  for (size_t i = 1; i < indexes.size(); ++i) {
+    if (indexes[i] < 0 || indexes[i] >= descriptor->nested_type_count()) {
+      return error::InvalidArgument("Protobuf message index $0 is out of range.", indexes[i]);
+    }
+    descriptor = descriptor->nested_type(indexes[i]);
+  }
+
+  std::unique_ptr<Message> message(message_factory_.GetPrototype(descriptor)->New());
+  std::string_view data = decoder.Buf();
+  if (!message->ParseFromArray(data.data(), data.size())) {
+    return error::InvalidArgument("Failed to parse the serialized $0 message.",
+                                  descriptor->full_name());
+  }
+
+  std::string json;
+  auto status = google::protobuf::util::MessageToJsonString(*message, &json);
+  if (!status.ok()) {
+    return error::InvalidArgument("Failed to convert $0 message to JSON: $1",
+                                  descriptor->full_name(), status.ToString());
+  }
+  return json;
+}
+
+}  // namespace kafka
+}  // namespace protocols
+}  // namespace stirling
+}  // namespace px
@@ -0,0 +1,65 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+
+#pragma once
+
+#include <google/protobuf/descriptor.h>
+#include <google/protobuf/dynamic_message.h>
+
+#include <memory>
+#include <string>
+#include <string_view>
+
+#include "src/common/base/base.h"
+#include "src/stirling/source_connectors/socket_tracer/protocols/kafka/schema_registry/schema.h"
+
+namespace px {
+namespace stirling {
+namespace protocols {
+namespace kafka {
+
+/**
+ * A Protobuf schema, defined by the text of a .proto file, which decodes payloads in the
+ * Confluent Protobuf format into JSON. The payload starts with the indexes of the message type
+ * within the file, followed by the serialized message.
+ *
+ * Imports are only resolved against the protobuf files that are linked into the binary, such as
+ * the well-known types.
+ */
+class ProtobufSchema : public Schema {
+ public:
+  /**
+   * Parses the text of a .proto file.
+   */
+  static StatusOr<std::unique_ptr<ProtobufSchema>> Parse(std::string_view schema);
+
+  StatusOr<std::string> ToJSON(std::string_view payload) const override;
+
+ private:
+  ProtobufSchema();
+
+  google::protobuf::DescriptorPool pool_;
+  mutable google::protobuf::DynamicMessageFactory message_factory_;
+  const google::protobuf::FileDescriptor* file_ = nullptr;
+};
+
+}  // namespace kafka
+}  // namespace protocols
+}  // namespace stirling
+}  // namespace px
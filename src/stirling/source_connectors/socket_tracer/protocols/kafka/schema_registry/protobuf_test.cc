@@ -0,0 +1,73 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+
+#include "src/stirling/source_connectors/socket_tracer/protocols/kafka/schema_registry/protobuf.h"
+
+#include "src/common/base/types.h"
+#include "src/common/testing/testing.h"
+
+namespace px {
+namespace stirling {
+namespace protocols {
+namespace kafka {
+
+constexpr char kOrderSchema[] = R"(
+syntax = "proto3";
+package shop;
+
+message Order {
+  int64 id = 1;
+  string item = 2;
+
+  message Line {
+    string sku = 1;
+  }
+}
+)";
+
+TEST(ProtobufSchemaTest, DecodeFirstMessage) {
+  ASSERT_OK_AND_ASSIGN(auto schema, ProtobufSchema::Parse(kOrderSchema));
+
+  // The message indexes [0] are written as a single 0, followed by id=42, item="pen".
+  std::string_view payload = ConstStringView("\x00\x08\x2a\x12\x03pen");
+  EXPECT_OK_AND_EQ(schema->ToJSON(payload), R"({"id":"42","item":"pen"})");
+}
+
+TEST(ProtobufSchemaTest, DecodeNestedMessage) {
+  ASSERT_OK_AND_ASSIGN(auto schema, ProtobufSchema::Parse(kOrderSchema));
+
+  // The message indexes [0, 0] select Order.Line, followed by sku="xy".
+  std::string_view payload = ConstStringView("\x04\x00\x00\x0a\x02xy");
+  EXPECT_OK_AND_EQ(schema->ToJSON(payload), R"({"sku":"xy"})");
+}
+
+TEST(ProtobufSchemaTest, Errors) {
+  EXPECT_NOT_OK(ProtobufSchema::Parse("message {"));
+
+  ASSERT_OK_AND_ASSIGN(auto schema, ProtobufSchema::Parse(kOrderSchema));
+  // The message index [1] is out of range.
+  EXPECT_NOT_OK(schema->ToJSON(ConstStringView("\x02\x02\x08\x2a")));
+  // The message is malformed.
+  EXPECT_NOT_OK(schema->ToJSON(ConstStringView("\x00\x12\x09pen")));
+}
+
+}  // namespace kafka
+}  // namespace protocols
+}  // namespace stirling
+}  // namespace px
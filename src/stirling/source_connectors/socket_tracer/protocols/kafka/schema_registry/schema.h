@@ -0,0 +1,49 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+
+#pragma once
+
+#include <string>
+#include <string_view>
+
+#include "src/common/base/base.h"
+
+namespace px {
+namespace stirling {
+namespace protocols {
+namespace kafka {
+
+/**
+ * A schema from a schema registry, which decodes the Kafka record payloads written with it into
+ * JSON.
+ */
+class Schema {
+ public:
+  virtual ~Schema() = default;
+
+  /**
+   * Decodes a payload into JSON. The payload follows the schema ID in the record.
+   */
+  virtual StatusOr<std::string> ToJSON(std::string_view payload) const = 0;
+};
+
+}  // namespace kafka
+}  // namespace protocols
+}  // namespace stirling
+}  // namespace px
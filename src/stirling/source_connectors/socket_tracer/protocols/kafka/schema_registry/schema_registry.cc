@@ -0,0 +1,131 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+
+#include "src/stirling/source_connectors/socket_tracer/protocols/kafka/schema_registry/schema_registry.h"
+
+#include <rapidjson/document.h>
+
+#include <utility>
+
+#include "src/common/base/file.h"
+#include "src/stirling/source_connectors/socket_tracer/protocols/kafka/schema_registry/avro.h"
+#include "src/stirling/source_connectors/socket_tracer/protocols/kafka/schema_registry/protobuf.h"
+#include "src/stirling/utils/binary_decoder.h"
+
+DEFINE_string(stirling_kafka_schema_registry_dir,
+              gflags::StringFromEnv("PL_STIRLING_KAFKA_SCHEMA_REGISTRY_DIR", ""),
+              "Directory of schemas from a Confluent Schema Registry, used to decode the Avro and "
+              "Protobuf records of traced Kafka messages. Holds one <id>.json file per schema, "
+              "with the response of the registry's GET /schemas/ids/<id> endpoint. If empty, "
+              "records are not decoded.");
+
+namespace px {
+namespace stirling {
+namespace protocols {
+namespace kafka {
+
+// The magic byte that starts data in the Confluent wire format.
+constexpr uint8_t kWireFormatMagic = 0;
+
+StatusOr<std::string> SchemaRegistry::Decode(std::string_view data) {
+  BinaryDecoder decoder(data);
+  PX_ASSIGN_OR_RETURN(uint8_t magic, decoder.ExtractInt<uint8_t>());
+  if (magic != kWireFormatMagic) {
+    return error::InvalidArgument("Data is not in the schema registry wire format.");
+  }
+  PX_ASSIGN_OR_RETURN(int32_t id, decoder.ExtractInt<int32_t>());
+
+  absl::MutexLock lock(&mu_);
+  PX_ASSIGN_OR_RETURN(const Schema* schema, GetSchema(id));
+  return schema->ToJSON(decoder.Buf());
+}
+
+Status SchemaRegistry::AddSchema(int32_t id, std::string_view registry_response) {
+  absl::MutexLock lock(&mu_);
+  return AddSchemaLocked(id, registry_response);
+}
+
+Status SchemaRegistry::AddSchemaLocked(int32_t id, std::string_view registry_response) {
+  rapidjson::Document doc;
+  doc.Parse(registry_response.data(), registry_response.size());
+  if (doc.HasParseError() || !doc.IsObject()) {
+    return error::InvalidArgument("Failed to parse the registry response for schema $0.", id);
+  }
+  if (!doc.HasMember("schema") || !doc["schema"].IsString()) {
+    return error::InvalidArgument("The registry response for schema $0 has no schema.", id);
+  }
+  std::string_view text(doc["schema"].GetString(), doc["schema"].GetStringLength());
+
+  // The registry leaves out the schema type for Avro, which is its default.
+  std::string_view type = "AVRO";
+  if (doc.HasMember("schemaType") && doc["schemaType"].IsString()) {
+    type = doc["schemaType"].GetString();
+  }
+
+  std::unique_ptr<Schema> schema;
+  if (type == "AVRO") {
+    PX_ASSIGN_OR_RETURN(schema, AvroSchema::Parse(text));
+  } else if (type == "PROTOBUF") {
+    PX_ASSIGN_OR_RETURN(schema, ProtobufSchema::Parse(text));
+  } else {
+    return error::Unimplemented("Schema $0 has unsupported type $1.", id, type);
+  }
+  schemas_[id] = std::move(schema);
+  missing_schemas_.erase(id);
+  return Status::OK();
+}
+
+StatusOr<const Schema*> SchemaRegistry::GetSchema(int32_t id) {
+  auto it = schemas_.find(id);
+  if (it != schemas_.end()) {
+    return it->second.get();
+  }
+
+  auto now = std::chrono::steady_clock::now();
+  auto missing_it = missing_schemas_.find(id);
+  if (missing_it != missing_schemas_.end() &&
+      now - missing_it->second < kMissingSchemaRetryInterval) {
+    return error::NotFound("Schema $0 is not known.", id);
+  }
+
+  auto path = dir_ / absl::StrCat(id, ".json");
+  auto contents_or = ReadFileToString(path.string());
+  Status s =
+      contents_or.ok() ? AddSchemaLocked(id, contents_or.ValueOrDie()) : contents_or.status();
+  if (!s.ok()) {
+    VLOG(1) << absl::Substitute("Failed to read schema $0 from $1: $2", id, path.string(),
+                                s.msg());
+    missing_schemas_[id] = now;
+    return error::NotFound("Schema $0 is not known.", id);
+  }
+  return schemas_[id].get();
+}
+
+SchemaRegistry* DefaultSchemaRegistry() {
+  static SchemaRegistry* registry =
+      FLAGS_stirling_kafka_schema_registry_dir.empty()
+          ? nullptr
+          : new SchemaRegistry(FLAGS_stirling_kafka_schema_registry_dir);
+  return registry;
+}
+
+}  // namespace kafka
+}  // namespace protocols
+}  // namespace stirling
+}  // namespace px
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+
+#pragma once
+
+#include <absl/base/thread_annotations.h>
+#include <absl/container/flat_hash_map.h>
+#include <absl/synchronization/mutex.h>
+
+#include <chrono>
+#include <filesystem>
+#include <memory>
+#include <string>
+#include <string_view>
+
+#include "src/common/base/base.h"
+#include "src/stirling/source_connectors/socket_tracer/protocols/kafka/schema_registry/schema.h"
+
+DECLARE_string(stirling_kafka_schema_registry_dir);
+
+namespace px {
+namespace stirling {
+namespace protocols {
+namespace kafka {
+
+/**
+ * Decodes Kafka record keys and values that are written in the Confluent Schema Registry wire
+ * format: a zero magic byte, a 4-byte big-endian schema ID, and the payload. Avro and Protobuf
+ * schemas are supported.
+ *
+ * The schemas are read from a directory that holds one <id>.json file per schema, with the
+ * response of the registry's GET /schemas/ids/<id> endpoint. Schema IDs are immutable, so each
+ * schema is read once, when a record first refers to it.
+ */
+class SchemaRegistry {
+ public:
+  explicit SchemaRegistry(std::filesystem::path dir) : dir_(std::move(dir)) {}
+
+  /**
+   * Decodes a record key or value into JSON. Returns an error if the data is not in the wire
+   * format, or its schema is not known.
+   */
+  StatusOr<std::string> Decode(std::string_view data);
+
+  /**
+   * Adds a schema from the response of the registry's GET /schemas/ids/<id> endpoint.
+   */
+  Status AddSchema(int32_t id, std::string_view registry_response);
+
+ private:
+  // How long to wait before looking for a schema file again, after it was not found.
+  static constexpr std::chrono::seconds kMissingSchemaRetryInterval{60};
+
+  StatusOr<const Schema*> GetSchema(int32_t id) ABSL_EXCLUSIVE_LOCKS_REQUIRED(mu_);
+  Status AddSchemaLocked(int32_t id, std::string_view registry_response)
+      ABSL_EXCLUSIVE_LOCKS_REQUIRED(mu_);
+
+  const std::filesystem::path dir_;
+
+  absl::Mutex mu_;
+  absl::flat_hash_map<int32_t, std::unique_ptr<Schema>> schemas_ ABSL_GUARDED_BY(mu_);
+  // The last time that a schema could not be read, by schema ID.
+  absl::flat_hash_map<int32_t, std::chrono::steady_clock::time_point> missing_schemas_
+      ABSL_GUARDED_BY(mu_);
+};
+
+/**
+ * Returns the schema registry for --stirling_kafka_schema_registry_dir, or nullptr if it is not
+ * set.
+ */
+SchemaRegistry* DefaultSchemaRegistry();
+
+}  // namespace kafka
+}  // namespace protocols
+}  // namespace stirling
+}  // namespace px
@@ -0,0 +1,64 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+
+#include "src/stirling/source_connectors/socket_tracer/protocols/kafka/schema_registry/schema_registry.h"
+
+#include "src/common/base/types.h"
+#include "src/common/testing/temp_dir.h"
+#include "src/common/testing/testing.h"
+
+namespace px {
+namespace stirling {
+namespace protocols {
+namespace kafka {
+
+TEST(SchemaRegistryTest, DecodeWithSchemaFromDir) {
+  px::testing::TempDir dir;
+  ASSERT_OK(WriteFileFromString((dir.path() / "7.json").string(), R"({"schema":"\"string\""})"));
+  SchemaRegistry registry(dir.path());
+
+  // Schema ID 7, followed by the Avro string "hi".
+  EXPECT_OK_AND_EQ(registry.Decode(ConstStringView("\x00\x00\x00\x00\x07\x04hi")),
+                   R"("hi")");
+  // Schema ID 8 is not in the directory.
+  EXPECT_NOT_OK(registry.Decode(ConstStringView("\x00\x00\x00\x00\x08\x04hi")));
+}
+
+TEST(SchemaRegistryTest, AddSchema) {
+  SchemaRegistry registry("/nonexistent");
+  constexpr char kProtobufResponse[] =
+      R"({"schemaType":"PROTOBUF","schema":"syntax = \"proto3\"; message M { string s = 1; }"})";
+  ASSERT_OK(registry.AddSchema(1, kProtobufResponse));
+  EXPECT_OK_AND_EQ(registry.Decode(ConstStringView("\x00\x00\x00\x00\x01\x00\x0a\x01x")),
+                   R"({"s":"x"})");
+
+  EXPECT_NOT_OK(registry.AddSchema(2, R"({"schemaType":"JSON","schema":"{}"})"));
+  EXPECT_NOT_OK(registry.AddSchema(3, "not json"));
+}
+
+TEST(SchemaRegistryTest, NotWireFormat) {
+  SchemaRegistry registry("/nonexistent");
+  EXPECT_NOT_OK(registry.Decode("plain text"));
+  EXPECT_NOT_OK(registry.Decode(""));
+}
+
+}  // namespace kafka
+}  // namespace protocols
+}  // namespace stirling
+}  // namespace px
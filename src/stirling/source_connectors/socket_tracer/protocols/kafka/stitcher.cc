@@ -19,6 +19,8 @@
 #include "src/stirling/source_connectors/socket_tracer/protocols/kafka/stitcher.h"
 
 #include <absl/container/flat_hash_map.h>
+#include <absl/strings/ascii.h>
+#include <algorithm>
 #include <deque>
 #include <string>
 #include <utility>
@@ -26,14 +28,50 @@
 #include "src/common/base/base.h"
 #include "src/stirling/source_connectors/socket_tracer/protocols/kafka/common/types.h"
 #include "src/stirling/source_connectors/socket_tracer/protocols/kafka/decoder/packet_decoder.h"
+#include "src/stirling/source_connectors/socket_tracer/protocols/kafka/schema_registry/schema_registry.h"
 
 namespace px {
 namespace stirling {
 namespace protocols {
 namespace kafka {
 
+// Decodes a record key or value with the schema registry. Data that cannot be decoded is kept if
+// it is human readable, so that string keys are still shown.
+void DecodeRecordData(SchemaRegistry* registry, std::string* data, bool* is_json) {
+  auto json_or = registry->Decode(*data);
+  if (json_or.ok()) {
+    *data = json_or.ConsumeValueOrDie();
+    *is_json = true;
+    return;
+  }
+  if (!std::all_of(data->begin(), data->end(),
+                   [](char c) { return absl::ascii_isprint(static_cast<unsigned char>(c)); })) {
+    *data = absl::Substitute("<$0 bytes>", data->size());
+  }
+}
+
+// Decodes the records of the message set with the schema registry, if there is one.
+void DecodeRecords(MessageSet* message_set) {
+  SchemaRegistry* registry = DefaultSchemaRegistry();
+  if (registry == nullptr) {
+    return;
+  }
+  for (auto& record_batch : message_set->record_batches) {
+    for (auto& record : record_batch.records) {
+      DecodeRecordData(registry, &record.key, &record.key_is_json);
+      DecodeRecordData(registry, &record.value, &record.value_is_json);
+      message_set->records_decoded |= record.key_is_json || record.value_is_json;
+    }
+  }
+}
+
 Status ProcessProduceReq(PacketDecoder* decoder, Request* req) {
   PX_ASSIGN_OR_RETURN(ProduceReq r, decoder->ExtractProduceReq());
+  for (auto& topic : r.topics) {
+    for (auto& partition : topic.partitions) {
+      DecodeRecords(&partition.message_set);
+    }
+  }
 
   req->msg = ToString(r);
   return Status::OK();
@@ -55,6 +93,11 @@ Status ProcessFetchReq(PacketDecoder* decoder, Request* req) {
 
 Status ProcessFetchResp(PacketDecoder* decoder, Response* resp) {
   PX_ASSIGN_OR_RETURN(FetchResp r, decoder->ExtractFetchResp());
+  for (auto& topic : r.topics) {
+    for (auto& partition : topic.partitions) {
+      DecodeRecords(&partition.message_set);
+    }
+  }
 
   resp->msg = ToString(r);
   return Status::OK();
@@ -434,6 +434,76 @@ TEST_F(SocketTraceBPFTest, MultipleConnections) {
   }
 }
 
+// Tests that the BPF rate limiter collapses repeated requests on a connection,
+// and that requests are always suppressed together with their responses.
+TEST_F(SocketTraceBPFTest, RateLimitRepeatedRequests) {
+  constexpr size_t kNumReqResps = 100;
+  constexpr size_t kMaxEventsPerSec = 10;
+
+  FLAGS_stirling_socket_tracer_max_conn_data_events_per_sec = kMaxEventsPerSec;
+  ASSERT_OK(source_->SetConnDataEventRateLimit());
+  // Flags don't reset between tests, so restore the default for the tests that follow.
+  FLAGS_stirling_socket_tracer_max_conn_data_events_per_sec = 0;
+
+  ConfigureBPFCapture(traffic_protocol_t::kProtocolHTTP, kRoleClient);
+
+  StartTransferDataThread();
+
+  testing::SendRecvScript script;
+  for (size_t i = 0; i < kNumReqResps; ++i) {
+    script.push_back({{kHTTPReqMsg1}, {kHTTPRespMsg1}});
+  }
+  testing::ClientServerSystem system;
+  system.RunClientServer<&TCPSocket::Read, &TCPSocket::Write>(script);
+
+  StopTransferDataThread();
+
+  std::vector<TaggedRecordBatch> tablets = ConsumeRecords(kHTTPTableNum);
+  ASSERT_NOT_EMPTY_AND_GET_RECORDS(const types::ColumnWrapperRecordBatch& record_batch, tablets);
+
+  ColumnWrapperRecordBatch records =
+      FindRecordsMatchingPID(record_batch, kHTTPUPIDIdx, system.ClientPID());
+
+  // Each request and response is a single data event, so the limit lets through
+  // kMaxEventsPerSec / 2 request-response pairs per second.
+  const size_t num_records = records[kHTTPReqPathIdx]->Size();
+  EXPECT_GE(num_records, kMaxEventsPerSec / 2);
+  EXPECT_LT(num_records, kNumReqResps);
+
+  // Every captured request must still be matched with its own response.
+  for (size_t i = 0; i < num_records; ++i) {
+    EXPECT_EQ(records[kHTTPReqPathIdx]->Get<types::StringValue>(i), "/endpoint1");
+    EXPECT_THAT(records[kHTTPRespHeadersIdx]->Get<types::StringValue>(i), HasSubstr("msg1"));
+  }
+}
+
+// Tests that the BPF rate limiter is disabled by default, so no requests are suppressed.
+TEST_F(SocketTraceBPFTest, RateLimitDisabledByDefault) {
+  constexpr size_t kNumReqResps = 100;
+
+  ASSERT_EQ(FLAGS_stirling_socket_tracer_max_conn_data_events_per_sec, 0);
+
+  ConfigureBPFCapture(traffic_protocol_t::kProtocolHTTP, kRoleClient);
+
+  StartTransferDataThread();
+
+  testing::SendRecvScript script;
+  for (size_t i = 0; i < kNumReqResps; ++i) {
+    script.push_back({{kHTTPReqMsg1}, {kHTTPRespMsg1}});
+  }
+  testing::ClientServerSystem system;
+  system.RunClientServer<&TCPSocket::Read, &TCPSocket::Write>(script);
+
+  StopTransferDataThread();
+
+  std::vector<TaggedRecordBatch> tablets = ConsumeRecords(kHTTPTableNum);
+  ASSERT_NOT_EMPTY_AND_GET_RECORDS(const types::ColumnWrapperRecordBatch& record_batch, tablets);
+
+  ColumnWrapperRecordBatch records =
+      FindRecordsMatchingPID(record_batch, kHTTPUPIDIdx, system.ClientPID());
+  EXPECT_THAT(records, RecordBatchSizeIs(kNumReqResps));
+}
+
 // Tests that the start time of UPIDs reported in data table are within a specified time window.
 TEST_F(SocketTraceBPFTest, StartTime) {
   ConfigureBPFCapture(traffic_protocol_t::kProtocolHTTP, kRoleClient);
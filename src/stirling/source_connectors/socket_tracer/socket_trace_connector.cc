@@ -47,6 +47,7 @@
 #include "src/stirling/source_connectors/socket_tracer/protocols/http/utils.h"
 #include "src/stirling/source_connectors/socket_tracer/protocols/http2/grpc.h"
 #include "src/stirling/utils/linux_headers.h"
+#include "src/stirling/utils/monitor.h"
 #include "src/stirling/utils/proc_path_tools.h"
 
 // 50 X less often than the normal sampling frequency. Based on the conn_stats_table.h's
@@ -109,6 +110,13 @@ DEFINE_bool(stirling_disable_golang_tls_tracing,
 DEFINE_bool(stirling_disable_self_tracing, true,
             "If true, stirling will not trace and process syscalls made by itself.");
 
+DEFINE_int32(stirling_socket_tracer_max_conn_data_events_per_sec,
+             gflags::Int32FromEnv("PL_STIRLING_MAX_CONN_DATA_EVENTS_PER_SEC", 0),
+             "Maximum number of data events per second a single connection may submit from BPF. "
+             "Over this limit, repeated identical requests are collapsed together with their "
+             "responses, and distinct requests are dropped beyond twice the limit. "
+             "Disabled by default; a value of 0 or less disables the limit.");
+
 // Assume a moderate default network bandwidth peak of 100MiB/s across socket connections for data.
 DEFINE_uint32(stirling_socket_tracer_target_data_bw_percpu, 100 * 1024 * 1024,
               "Target bytes/sec of data per CPU");
@@ -152,6 +160,12 @@ DEFINE_uint32(datastream_buffer_retention_size,
 DEFINE_uint64(max_body_bytes, gflags::Uint64FromEnv("PL_STIRLING_MAX_BODY_BYTES", 512),
               "The maximum number of bytes in the body of protocols like HTTP");
 
+DEFINE_string(stirling_payload_capture_policy,
+              gflags::StringFromEnv("PL_STIRLING_PAYLOAD_CAPTURE_POLICY", ""),
+              "A JSON policy that controls, per namespace, service and protocol, whether bodies "
+              "are captured in full, truncated, or dropped. See payload_capture_policy.h for the "
+              "format.");
+
 DEFINE_bool(
     stirling_trace_static_tls_binaries, gflags::BoolFromEnv("PX_TRACE_STATIC_TLS_BINARIES", false),
     "If true, stirling will tls trace binaries statically linked with OpenSSL or BoringSSL");
@@ -180,6 +194,7 @@ constexpr char openssl_mismatched_fds_help[] =
 SocketTraceConnector::SocketTraceConnector(std::string_view source_name)
     : SourceConnector(source_name, kTables),
       conn_stats_(&conn_trackers_mgr_),
+      conn_lifetimes_(&conn_trackers_mgr_),
       openssl_trace_mismatched_fds_counter_family_(
           BuildCounterFamily(openssl_mismatched_fds_metric, openssl_mismatched_fds_help)),
       uprobe_mgr_(this) {
@@ -446,6 +461,7 @@ Status SocketTraceConnector::InitBPF() {
   if (FLAGS_stirling_disable_self_tracing) {
     PX_RETURN_IF_ERROR(DisableSelfTracing());
   }
+  PX_RETURN_IF_ERROR(SetConnDataEventRateLimit());
   if (!FLAGS_socket_trace_data_events_output_path.empty()) {
     SetupOutput(FLAGS_socket_trace_data_events_output_path);
   }
@@ -480,6 +496,16 @@ Status SocketTraceConnector::InitImpl() {
         "timestamps in a way that matches how /proc/stat does it");
   }
 
+  auto policy_or = PayloadCapturePolicy::Parse(FLAGS_stirling_payload_capture_policy);
+  if (!policy_or.ok()) {
+    LOG(ERROR) << absl::Substitute(
+        "Invalid payload capture policy, no bodies will be captured. Message: $0",
+        policy_or.msg());
+    payload_capture_policy_ = PayloadCapturePolicy::HeadersOnly();
+  } else {
+    payload_capture_policy_ = policy_or.ConsumeValueOrDie();
+  }
+
   PX_RETURN_IF_ERROR(InitBPF());
 
   auto s = system::SocketInfoManager::Create(
@@ -691,6 +717,14 @@ void SocketTraceConnector::TransferDataImpl(ConnectorContext* ctx) {
     TransferConnStats(ctx, conn_stats_table);
   }
 
+  // The conn_lifetime table is populated after conn_stats, and at the same rate, so that both see
+  // the final stats of connections before their trackers are destroyed.
+  DataTable* conn_lifetime_table = data_tables_[kConnLifetimeTableNum];
+  if (conn_lifetime_table != nullptr &&
+      sampling_freq_mgr_.count() % FLAGS_stirling_conn_stats_sampling_ratio == 0) {
+    TransferConnLifetimes(ctx, conn_lifetime_table);
+  }
+
   if ((sampling_freq_mgr_.count() + 1) % FLAGS_stirling_socket_tracer_stats_logging_ratio == 0) {
     conn_trackers_mgr_.ComputeProtocolStats();
     LOG(INFO) << "ConnTracker statistics: " << conn_trackers_mgr_.StatsString();
@@ -711,9 +745,9 @@ void SocketTraceConnector::TransferDataImpl(ConnectorContext* ctx) {
     DataTable* data_table = data_tables_[i];
 
     // Ensure records are within the time window, in order to ensure the order between record
-    // batches. Exception: conn_stats table does not need cutoff time, because its timestamps
-    // are assigned artificially.
-    if (i != kConnStatsTableNum && data_table != nullptr) {
+    // batches. Exception: conn_stats and conn_lifetime tables do not need cutoff time, because
+    // their timestamps are assigned artificially.
+    if (i != kConnStatsTableNum && i != kConnLifetimeTableNum && data_table != nullptr) {
       data_table->SetConsumeRecordsCutoffTime(perf_buffer_drain_time_);
     }
   }
@@ -787,6 +821,13 @@ Status SocketTraceConnector::DisableSelfTracing() {
   return bpf_tools::UpdatePerCPUArrayValue(kStirlingTGIDIndex, self_pid, &control_map_handle);
 }
 
+Status SocketTraceConnector::SetConnDataEventRateLimit() {
+  auto control_map_handle = GetPerCPUArrayTable<int64_t>(kControlValuesArrayName);
+  int64_t limit = FLAGS_stirling_socket_tracer_max_conn_data_events_per_sec;
+  return bpf_tools::UpdatePerCPUArrayValue(kConnDataEventRateLimitIndex, limit,
+                                           &control_map_handle);
+}
+
 //-----------------------------------------------------------------------------
 // Perf Buffer Polling and Callback functions.
 //-----------------------------------------------------------------------------
@@ -823,6 +864,7 @@ void SocketTraceConnector::HandleDataEvent(void* cb_cookie, void* data, int data
 
 void SocketTraceConnector::HandleDataEventLoss(void* cb_cookie, uint64_t lost) {
   DCHECK(cb_cookie != nullptr) << "Perf buffer callback not set-up properly. Missing cb_cookie.";
+  StirlingMonitor::GetInstance()->NotifyPerfBufferLoss(lost);
   static_cast<SocketTraceConnector*>(cb_cookie)->stats_.Increment(StatKey::kLossSocketDataEvent,
                                                                   lost);
 }
@@ -835,6 +877,7 @@ void SocketTraceConnector::HandleControlEvent(void* cb_cookie, void* data, int /
 
 void SocketTraceConnector::HandleControlEventLoss(void* cb_cookie, uint64_t lost) {
   DCHECK(cb_cookie != nullptr) << "Perf buffer callback not set-up properly. Missing cb_cookie.";
+  StirlingMonitor::GetInstance()->NotifyPerfBufferLoss(lost);
   static_cast<SocketTraceConnector*>(cb_cookie)->stats_.Increment(StatKey::kLossSocketControlEvent,
                                                                   lost);
 }
@@ -847,6 +890,7 @@ void SocketTraceConnector::HandleConnStatsEvent(void* cb_cookie, void* data, int
 
 void SocketTraceConnector::HandleConnStatsEventLoss(void* cb_cookie, uint64_t lost) {
   DCHECK(cb_cookie != nullptr) << "Perf buffer callback not set-up properly. Missing cb_cookie.";
+  StirlingMonitor::GetInstance()->NotifyPerfBufferLoss(lost);
   static_cast<SocketTraceConnector*>(cb_cookie)->stats_.Increment(StatKey::kLossConnStatsEvent,
                                                                   lost);
 }
@@ -859,6 +903,7 @@ void SocketTraceConnector::HandleMMapEvent(void* cb_cookie, void* data, int /*da
 
 void SocketTraceConnector::HandleMMapEventLoss(void* cb_cookie, uint64_t lost) {
   DCHECK(cb_cookie != nullptr) << "Perf buffer callback not set-up properly. Missing cb_cookie.";
+  StirlingMonitor::GetInstance()->NotifyPerfBufferLoss(lost);
   static_cast<SocketTraceConnector*>(cb_cookie)->stats_.Increment(StatKey::kLossMMapEvent, lost);
 }
 
@@ -904,6 +949,7 @@ void SocketTraceConnector::HandleHTTP2Event(void* cb_cookie, void* data, int /*d
 
 void SocketTraceConnector::HandleHTTP2EventLoss(void* cb_cookie, uint64_t lost) {
   DCHECK(cb_cookie != nullptr) << "Perf buffer callback not set-up properly. Missing cb_cookie.";
+  StirlingMonitor::GetInstance()->NotifyPerfBufferLoss(lost);
   static_cast<SocketTraceConnector*>(cb_cookie)->stats_.Increment(StatKey::kLossHTTP2Event, lost);
 }
 
@@ -946,17 +992,20 @@ void SocketTraceConnector::HandleGrpcCCloseEvent(void* cb_cookie, void* data, in
 
 void SocketTraceConnector::HandleGrpcCDataLoss(void* cb_cookie, uint64_t lost) {
   DCHECK(cb_cookie != nullptr) << "Perf buffer callback not set-up properly. Missing cb_cookie.";
+  StirlingMonitor::GetInstance()->NotifyPerfBufferLoss(lost);
   static_cast<SocketTraceConnector*>(cb_cookie)->stats_.Increment(StatKey::kLossGrpcCEvent, lost);
 }
 
 void SocketTraceConnector::HandleGrpcCHeaderDataLoss(void* cb_cookie, uint64_t lost) {
   DCHECK(cb_cookie != nullptr) << "Perf buffer callback not set-up properly. Missing cb_cookie.";
+  StirlingMonitor::GetInstance()->NotifyPerfBufferLoss(lost);
   static_cast<SocketTraceConnector*>(cb_cookie)->stats_.Increment(StatKey::kLossGrpcCHeaderEvent,
                                                                   lost);
 }
 
 void SocketTraceConnector::HandleGrpcCCloseDataLoss(void* cb_cookie, uint64_t lost) {
   DCHECK(cb_cookie != nullptr) << "Perf buffer callback not set-up properly. Missing cb_cookie.";
+  StirlingMonitor::GetInstance()->NotifyPerfBufferLoss(lost);
   static_cast<SocketTraceConnector*>(cb_cookie)->stats_.Increment(StatKey::kLossGrpcCCloseEvent,
                                                                   lost);
 }
@@ -980,6 +1029,7 @@ void SocketTraceConnector::AcceptDataEvent(std::unique_ptr<SocketDataEvent> even
   stats_.Increment(StatKey::kPollSocketDataEventCount);
   stats_.Increment(StatKey::kPollSocketDataEventAttrSize, sizeof(event->attr));
   stats_.Increment(StatKey::kPollSocketDataEventDataSize, event->msg.size());
+  stats_.Increment(StatKey::kRateLimitedSocketDataEvent, event->attr.num_suppressed_events);
 
   ConnTracker& tracker = GetOrCreateConnTracker(event->attr.conn_id);
   tracker.AddDataEvent(std::move(event));
@@ -1173,8 +1223,38 @@ std::string PXInfoString(const ConnTracker& conn_tracker, const TRecordType& rec
   return absl::Substitute("conn_tracker=$0 record=$1", conn_tracker.ToString(), record.ToString());
 }
 
+// The limit that RecordBuilder::Append() applies to strings, if none is specified.
+constexpr size_t kDefaultMaxStringBytes = 1024;
+
 }  // namespace
 
+size_t SocketTraceConnector::BodyLimit(ConnectorContext* ctx, const md::UPID& upid,
+                                       std::string_view protocol, size_t default_limit) {
+  if (payload_capture_policy_.empty()) {
+    return default_limit;
+  }
+
+  std::string_view ns;
+  std::vector<std::string_view> services;
+  const md::K8sMetadataState& k8s_md = ctx->GetK8SMetadata();
+  auto it = ctx->GetPIDInfoMap().find(upid);
+  if (it != ctx->GetPIDInfoMap().end() && it->second != nullptr) {
+    const md::ContainerInfo* container_info = k8s_md.ContainerInfoByID(it->second->cid());
+    const md::PodInfo* pod_info =
+        container_info != nullptr ? k8s_md.PodInfoByID(container_info->pod_id()) : nullptr;
+    if (pod_info != nullptr) {
+      ns = pod_info->ns();
+      for (const auto& service_id : pod_info->services()) {
+        const md::ServiceInfo* service_info = k8s_md.ServiceInfoByID(service_id);
+        if (service_info != nullptr) {
+          services.push_back(service_info->name());
+        }
+      }
+    }
+  }
+  return payload_capture_policy_.BodyLimit(protocol, ns, services, default_limit);
+}
+
 template <>
 void SocketTraceConnector::AppendMessage(ConnectorContext* ctx, const ConnTracker& conn_tracker,
                                          protocols::http::Record record, DataTable* data_table) {
@@ -1193,6 +1273,8 @@ void SocketTraceConnector::AppendMessage(ConnectorContext* ctx, const ConnTracke
     content_type = HTTPContentType::kJSON;
   }
 
+  const size_t body_limit = BodyLimit(ctx, upid, "http", FLAGS_max_body_bytes);
+
   DataTable::RecordBuilder<&kHTTPTable> r(data_table, resp_message.timestamp_ns);
   r.Append<r.ColIndex("time_")>(resp_message.timestamp_ns);
   r.Append<r.ColIndex("upid")>(upid.value());
@@ -1208,12 +1290,12 @@ void SocketTraceConnector::AppendMessage(ConnectorContext* ctx, const ConnTracke
   r.Append<r.ColIndex("req_method")>(std::move(req_message.req_method));
   r.Append<r.ColIndex("req_path")>(std::move(req_message.req_path));
   r.Append<r.ColIndex("req_body_size")>(req_message.body_size);
-  r.Append<r.ColIndex("req_body")>(std::move(req_message.body), FLAGS_max_body_bytes);
+  r.Append<r.ColIndex("req_body")>(std::move(req_message.body), body_limit);
   r.Append<r.ColIndex("resp_headers")>(ToJSONString(resp_message.headers), kMaxHTTPHeadersBytes);
   r.Append<r.ColIndex("resp_status")>(resp_message.resp_status);
   r.Append<r.ColIndex("resp_message")>(std::move(resp_message.resp_message));
   r.Append<r.ColIndex("resp_body_size")>(resp_message.body_size);
-  r.Append<r.ColIndex("resp_body")>(std::move(resp_message.body), FLAGS_max_body_bytes);
+  r.Append<r.ColIndex("resp_body")>(std::move(resp_message.body), body_limit);
   r.Append<r.ColIndex("latency")>(
       CalculateLatency(req_message.timestamp_ns, resp_message.timestamp_ns));
 #ifndef NDEBUG
@@ -1255,7 +1337,9 @@ void SocketTraceConnector::AppendMessage(ConnectorContext* ctx, const ConnTracke
     content_type = HTTPContentType::kGRPC;
   }
 
-  ParseReqRespBody(&record, DataTable::kTruncatedMsg, kMaxPBStringLen);
+  ParseReqRespBody(&record, DataTable::kTruncatedMsg, kMaxPBStringLen,
+                   grpc::DefaultServiceDescriptorDatabase());
+  const size_t body_limit = BodyLimit(ctx, upid, "http2", kDefaultMaxStringBytes);
 
   DataTable::RecordBuilder<&kHTTPTable> r(data_table, resp_stream->timestamp_ns);
   r.Append<r.ColIndex("time_")>(resp_stream->timestamp_ns);
@@ -1279,9 +1363,9 @@ void SocketTraceConnector::AppendMessage(ConnectorContext* ctx, const ConnTracke
   // Do not apply truncation at this point, as the truncation was already done on serialized
   // protobuf message. This might result into longer text format data here, but the increase is
   // minimal.
-  r.Append<r.ColIndex("req_body")>(req_stream->ConsumeData());
+  r.Append<r.ColIndex("req_body")>(req_stream->ConsumeData(), body_limit);
   r.Append<r.ColIndex("resp_body_size")>(resp_stream->original_data_size());
-  r.Append<r.ColIndex("resp_body")>(resp_stream->ConsumeData());
+  r.Append<r.ColIndex("resp_body")>(resp_stream->ConsumeData(), body_limit);
   int64_t latency_ns = CalculateLatency(req_stream->timestamp_ns, resp_stream->timestamp_ns);
   r.Append<r.ColIndex("latency")>(latency_ns);
   // TODO(yzhao): Remove once http2::Record::bpf_timestamp_ns is removed.
@@ -1298,6 +1382,8 @@ void SocketTraceConnector::AppendMessage(ConnectorContext* ctx, const ConnTracke
   md::UPID upid(ctx->GetASID(), conn_tracker.conn_id().upid.pid,
                 conn_tracker.conn_id().upid.start_time_ticks);
 
+  const size_t body_limit = BodyLimit(ctx, upid, "mysql", FLAGS_max_body_bytes);
+
   DataTable::RecordBuilder<&kMySQLTable> r(data_table, entry.resp.timestamp_ns);
   r.Append<r.ColIndex("time_")>(entry.resp.timestamp_ns);
   r.Append<r.ColIndex("upid")>(upid.value());
@@ -1305,9 +1391,9 @@ void SocketTraceConnector::AppendMessage(ConnectorContext* ctx, const ConnTracke
   r.Append<r.ColIndex("remote_port")>(conn_tracker.remote_endpoint().port());
   r.Append<r.ColIndex("trace_role")>(conn_tracker.role());
   r.Append<r.ColIndex("req_cmd")>(static_cast<uint64_t>(entry.req.cmd));
-  r.Append<r.ColIndex("req_body")>(std::move(entry.req.msg), FLAGS_max_body_bytes);
+  r.Append<r.ColIndex("req_body")>(std::move(entry.req.msg), body_limit);
   r.Append<r.ColIndex("resp_status")>(static_cast<uint64_t>(entry.resp.status));
-  r.Append<r.ColIndex("resp_body")>(std::move(entry.resp.msg), FLAGS_max_body_bytes);
+  r.Append<r.ColIndex("resp_body")>(std::move(entry.resp.msg), body_limit);
   r.Append<r.ColIndex("latency")>(
       CalculateLatency(entry.req.timestamp_ns, entry.resp.timestamp_ns));
 #ifndef NDEBUG
@@ -1321,6 +1407,8 @@ void SocketTraceConnector::AppendMessage(ConnectorContext* ctx, const ConnTracke
   md::UPID upid(ctx->GetASID(), conn_tracker.conn_id().upid.pid,
                 conn_tracker.conn_id().upid.start_time_ticks);
 
+  const size_t body_limit = BodyLimit(ctx, upid, "cql", FLAGS_max_body_bytes);
+
   DataTable::RecordBuilder<&kCQLTable> r(data_table, entry.resp.timestamp_ns);
   r.Append<r.ColIndex("time_")>(entry.resp.timestamp_ns);
   r.Append<r.ColIndex("upid")>(upid.value());
@@ -1328,9 +1416,9 @@ void SocketTraceConnector::AppendMessage(ConnectorContext* ctx, const ConnTracke
   r.Append<r.ColIndex("remote_port")>(conn_tracker.remote_endpoint().port());
   r.Append<r.ColIndex("trace_role")>(conn_tracker.role());
   r.Append<r.ColIndex("req_op")>(static_cast<uint64_t>(entry.req.op));
-  r.Append<r.ColIndex("req_body")>(std::move(entry.req.msg), FLAGS_max_body_bytes);
+  r.Append<r.ColIndex("req_body")>(std::move(entry.req.msg), body_limit);
   r.Append<r.ColIndex("resp_op")>(static_cast<uint64_t>(entry.resp.op));
-  r.Append<r.ColIndex("resp_body")>(std::move(entry.resp.msg), FLAGS_max_body_bytes);
+  r.Append<r.ColIndex("resp_body")>(std::move(entry.resp.msg), body_limit);
   r.Append<r.ColIndex("latency")>(
       CalculateLatency(entry.req.timestamp_ns, entry.resp.timestamp_ns));
 #ifndef NDEBUG
@@ -1367,14 +1455,16 @@ void SocketTraceConnector::AppendMessage(ConnectorContext* ctx, const ConnTracke
   md::UPID upid(ctx->GetASID(), conn_tracker.conn_id().upid.pid,
                 conn_tracker.conn_id().upid.start_time_ticks);
 
+  const size_t body_limit = BodyLimit(ctx, upid, "pgsql", kDefaultMaxStringBytes);
+
   DataTable::RecordBuilder<&kPGSQLTable> r(data_table, entry.resp.timestamp_ns);
   r.Append<r.ColIndex("time_")>(entry.resp.timestamp_ns);
   r.Append<r.ColIndex("upid")>(upid.value());
   r.Append<r.ColIndex("remote_addr")>(conn_tracker.remote_endpoint().AddrStr());
   r.Append<r.ColIndex("remote_port")>(conn_tracker.remote_endpoint().port());
   r.Append<r.ColIndex("trace_role")>(conn_tracker.role());
-  r.Append<r.ColIndex("req")>(std::move(entry.req.payload));
-  r.Append<r.ColIndex("resp")>(std::move(entry.resp.payload));
+  r.Append<r.ColIndex("req")>(std::move(entry.req.payload), body_limit);
+  r.Append<r.ColIndex("resp")>(std::move(entry.resp.payload), body_limit);
   r.Append<r.ColIndex("latency")>(
       CalculateLatency(entry.req.timestamp_ns, entry.resp.timestamp_ns));
   r.Append<r.ColIndex("req_cmd")>(ToString(entry.req.tag, /* is_req */ true));
@@ -1409,6 +1499,7 @@ void SocketTraceConnector::AppendMessage(ConnectorContext* ctx, const ConnTracke
   md::UPID upid(ctx->GetASID(), conn_tracker.conn_id().upid.pid,
                 conn_tracker.conn_id().upid.start_time_ticks);
   int64_t timestamp_ns = std::max(entry.req.timestamp_ns, entry.resp.timestamp_ns);
+  const size_t body_limit = BodyLimit(ctx, upid, "amqp", kDefaultMaxStringBytes);
   DataTable::RecordBuilder<&kAMQPTable> r(data_table, timestamp_ns);
 
   r.Append<r.ColIndex("time_")>(timestamp_ns);
@@ -1426,8 +1517,8 @@ void SocketTraceConnector::AppendMessage(ConnectorContext* ctx, const ConnTracke
   r.Append<r.ColIndex("resp_class_id")>(entry.resp.class_id);
   r.Append<r.ColIndex("resp_method_id")>(entry.resp.method_id);
 
-  r.Append<r.ColIndex("req_msg")>(entry.req.msg);
-  r.Append<r.ColIndex("resp_msg")>(entry.resp.msg);
+  r.Append<r.ColIndex("req_msg")>(entry.req.msg, body_limit);
+  r.Append<r.ColIndex("resp_msg")>(entry.resp.msg, body_limit);
   r.Append<r.ColIndex("latency")>(
       AMQPCalculateLatency(entry.req.timestamp_ns, entry.resp.timestamp_ns, entry.req.synchronous,
                            entry.resp.synchronous));
@@ -1461,6 +1552,8 @@ void SocketTraceConnector::AppendMessage(ConnectorContext* ctx, const ConnTracke
     role = Swapendpoint_role_t(role);
   }
 
+  const size_t body_limit = BodyLimit(ctx, upid, "redis", kDefaultMaxStringBytes);
+
   DataTable::RecordBuilder<&kRedisTable> r(data_table, entry.resp.timestamp_ns);
   r.Append<r.ColIndex("time_")>(entry.resp.timestamp_ns);
   r.Append<r.ColIndex("upid")>(upid.value());
@@ -1468,8 +1561,8 @@ void SocketTraceConnector::AppendMessage(ConnectorContext* ctx, const ConnTracke
   r.Append<r.ColIndex("remote_port")>(conn_tracker.remote_endpoint().port());
   r.Append<r.ColIndex("trace_role")>(role);
   r.Append<r.ColIndex("req_cmd")>(std::string(entry.req.command));
-  r.Append<r.ColIndex("req_args")>(std::string(entry.req.payload));
-  r.Append<r.ColIndex("resp")>(std::string(entry.resp.payload));
+  r.Append<r.ColIndex("req_args")>(std::string(entry.req.payload), body_limit);
+  r.Append<r.ColIndex("resp")>(std::string(entry.resp.payload), body_limit);
   r.Append<r.ColIndex("latency")>(
       CalculateLatency(entry.req.timestamp_ns, entry.resp.timestamp_ns));
 #ifndef NDEBUG
@@ -1484,6 +1577,7 @@ void SocketTraceConnector::AppendMessage(ConnectorContext* ctx, const ConnTracke
                 conn_tracker.conn_id().upid.start_time_ticks);
 
   endpoint_role_t role = conn_tracker.role();
+  const size_t body_limit = BodyLimit(ctx, upid, "nats", kDefaultMaxStringBytes);
   DataTable::RecordBuilder<&kNATSTable> r(data_table, record.resp.timestamp_ns);
   r.Append<r.ColIndex("time_")>(record.req.timestamp_ns);
   r.Append<r.ColIndex("upid")>(upid.value());
@@ -1491,7 +1585,7 @@ void SocketTraceConnector::AppendMessage(ConnectorContext* ctx, const ConnTracke
   r.Append<r.ColIndex("remote_port")>(conn_tracker.remote_endpoint().port());
   r.Append<r.ColIndex("trace_role")>(role);
   r.Append<r.ColIndex("cmd")>(record.req.command);
-  r.Append<r.ColIndex("body")>(record.req.options);
+  r.Append<r.ColIndex("body")>(record.req.options, body_limit);
   r.Append<r.ColIndex("resp")>(record.resp.command);
 #ifndef NDEBUG
   r.Append<r.ColIndex("px_info_")>(PXInfoString(conn_tracker, record));
@@ -1507,6 +1601,7 @@ void SocketTraceConnector::AppendMessage(ConnectorContext* ctx, const ConnTracke
                 conn_tracker.conn_id().upid.start_time_ticks);
 
   endpoint_role_t role = conn_tracker.role();
+  const size_t body_limit = BodyLimit(ctx, upid, "kafka", kMaxKafkaBodyBytes);
   DataTable::RecordBuilder<&kKafkaTable> r(data_table, record.resp.timestamp_ns);
   r.Append<r.ColIndex("time_")>(record.req.timestamp_ns);
   r.Append<r.ColIndex("upid")>(upid.value());
@@ -1515,8 +1610,8 @@ void SocketTraceConnector::AppendMessage(ConnectorContext* ctx, const ConnTracke
   r.Append<r.ColIndex("trace_role")>(role);
   r.Append<r.ColIndex("req_cmd")>(static_cast<int64_t>(record.req.api_key));
   r.Append<r.ColIndex("client_id")>(std::move(record.req.client_id), FLAGS_max_body_bytes);
-  r.Append<r.ColIndex("req_body")>(std::move(record.req.msg), kMaxKafkaBodyBytes);
-  r.Append<r.ColIndex("resp")>(std::move(record.resp.msg), kMaxKafkaBodyBytes);
+  r.Append<r.ColIndex("req_body")>(std::move(record.req.msg), body_limit);
+  r.Append<r.ColIndex("resp")>(std::move(record.resp.msg), body_limit);
   r.Append<r.ColIndex("latency")>(
       CalculateLatency(record.req.timestamp_ns, record.resp.timestamp_ns));
 #ifndef NDEBUG
@@ -1676,5 +1771,45 @@ void SocketTraceConnector::TransferConnStats(ConnectorContext* ctx, DataTable* d
   }
 }
 
+void SocketTraceConnector::TransferConnLifetimes(ConnectorContext* ctx, DataTable* data_table) {
+  namespace idx = ::px::stirling::conn_lifetime_idx;
+
+  absl::flat_hash_set<md::UPID> upids = ctx->GetUPIDs();
+  uint64_t time = AdjustedSteadyClockNowNS();
+  uint64_t now_ns = std::chrono::duration_cast<std::chrono::nanoseconds>(
+                        iteration_time().time_since_epoch())
+                        .count();
+
+  for (const auto& stats : conn_lifetimes_.UpdateStats(now_ns)) {
+    md::UPID upid(ctx->GetASID(), stats.key.upid.pid, stats.key.upid.start_time_ticks);
+    // Processes that exited are no longer in the context, but their final records are kept.
+    if (!stats.tracked_upid && !upids.contains(upid)) {
+      continue;
+    }
+
+    DataTable::RecordBuilder<&kConnLifetimeTable> r(data_table, time);
+
+    r.Append<idx::kTime>(time);
+    r.Append<idx::kUPID>(upid.value());
+    r.Append<idx::kRemoteAddr>(stats.remote_endpoint.AddrStr());
+    r.Append<idx::kRemotePort>(stats.remote_endpoint.port());
+    r.Append<idx::kRole>(stats.role);
+    r.Append<idx::kFD>(stats.key.fd);
+    r.Append<idx::kAddrFamily>(static_cast<int>(stats.remote_endpoint.family));
+    r.Append<idx::kProtocol>(stats.protocol);
+    r.Append<idx::kSSL>(stats.ssl);
+    r.Append<idx::kConnOpen>(stats.conn_open);
+    r.Append<idx::kConnClose>(stats.conn_close);
+    r.Append<idx::kDuration>(stats.duration_ns);
+    r.Append<idx::kBytesSent>(stats.bytes_sent);
+    r.Append<idx::kBytesRecv>(stats.bytes_recv);
+    r.Append<idx::kBytesSentPerSec>(stats.bytes_sent_per_sec);
+    r.Append<idx::kBytesRecvPerSec>(stats.bytes_recv_per_sec);
+#ifndef NDEBUG
+    r.Append<idx::kPxInfo>("");
+#endif
+  }
+}
+
 }  // namespace stirling
 }  // namespace px
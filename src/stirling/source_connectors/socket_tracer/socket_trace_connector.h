@@ -24,6 +24,7 @@
 #include <memory>
 #include <set>
 #include <string>
+#include <string_view>
 #include <utility>
 #include <vector>
 
@@ -40,9 +41,11 @@
 #include "src/stirling/core/source_connector.h"
 #include "src/stirling/source_connectors/socket_tracer/bcc_bpf_intf/grpc_c.h"
 #include "src/stirling/source_connectors/socket_tracer/bcc_bpf_intf/socket_trace.hpp"
+#include "src/stirling/source_connectors/socket_tracer/conn_lifetime.h"
 #include "src/stirling/source_connectors/socket_tracer/conn_stats.h"
 #include "src/stirling/source_connectors/socket_tracer/conn_tracker.h"
 #include "src/stirling/source_connectors/socket_tracer/conn_trackers_manager.h"
+#include "src/stirling/source_connectors/socket_tracer/payload_capture_policy.h"
 #include "src/stirling/source_connectors/socket_tracer/socket_trace_bpf_tables.h"
 #include "src/stirling/source_connectors/socket_tracer/socket_trace_tables.h"
 #include "src/stirling/source_connectors/socket_tracer/uprobe_manager.h"
@@ -65,6 +68,7 @@ DECLARE_int32(stirling_enable_kafka_tracing);
 DECLARE_int32(stirling_enable_mux_tracing);
 DECLARE_int32(stirling_enable_amqp_tracing);
 DECLARE_bool(stirling_disable_self_tracing);
+DECLARE_int32(stirling_socket_tracer_max_conn_data_events_per_sec);
 DECLARE_string(stirling_role_to_trace);
 
 DECLARE_uint32(stirling_socket_tracer_target_data_bw_percpu);
@@ -76,6 +80,7 @@ DECLARE_uint32(datastream_buffer_expiry_duration_secs);
 DECLARE_uint32(datastream_buffer_retention_size);
 
 DECLARE_uint64(max_body_bytes);
+DECLARE_string(stirling_payload_capture_policy);
 
 namespace px {
 namespace stirling {
@@ -91,10 +96,11 @@ class SocketTraceConnector : public SourceConnector, public bpf_tools::BCCWrappe
  public:
   static constexpr std::string_view kName = "socket_tracer";
   static constexpr auto kTables =
-      MakeArray(kConnStatsTable, kHTTPTable, kMySQLTable, kCQLTable, kPGSQLTable, kDNSTable,
-                kRedisTable, kNATSTable, kKafkaTable, kMuxTable, kAMQPTable);
+      MakeArray(kConnStatsTable, kConnLifetimeTable, kHTTPTable, kMySQLTable, kCQLTable,
+                kPGSQLTable, kDNSTable, kRedisTable, kNATSTable, kKafkaTable, kMuxTable, kAMQPTable);
 
   static constexpr uint32_t kConnStatsTableNum = TableNum(kTables, kConnStatsTable);
+  static constexpr uint32_t kConnLifetimeTableNum = TableNum(kTables, kConnLifetimeTable);
   static constexpr uint32_t kHTTPTableNum = TableNum(kTables, kHTTPTable);
   static constexpr uint32_t kMySQLTableNum = TableNum(kTables, kMySQLTable);
   static constexpr uint32_t kCQLTableNum = TableNum(kTables, kCQLTable);
@@ -140,6 +146,10 @@ class SocketTraceConnector : public SourceConnector, public bpf_tools::BCCWrappe
   Status TestOnlySetTargetPID();
   Status DisableSelfTracing();
 
+  // Sets the per-connection data event rate limit enforced inside BPF, as specified by
+  // --stirling_socket_tracer_max_conn_data_events_per_sec.
+  Status SetConnDataEventRateLimit();
+
   void DisablePIDTrace(int pid) override {
     SourceConnector::DisablePIDTrace(pid);
     pids_to_trace_disable_.insert(pid);
@@ -206,6 +216,7 @@ class SocketTraceConnector : public SourceConnector, public bpf_tools::BCCWrappe
   template <typename TProtocolTraits>
   void TransferStream(ConnectorContext* ctx, ConnTracker* tracker, DataTable* data_table);
   void TransferConnStats(ConnectorContext* ctx, DataTable* data_table);
+  void TransferConnLifetimes(ConnectorContext* ctx, DataTable* data_table);
 
   void set_iteration_time(std::chrono::time_point<std::chrono::steady_clock> time) {
     DCHECK(time >= iteration_time_);
@@ -216,8 +227,13 @@ class SocketTraceConnector : public SourceConnector, public bpf_tools::BCCWrappe
   void UpdateTrackerTraceLevel(ConnTracker* tracker);
 
   template <typename TRecordType>
-  static void AppendMessage(ConnectorContext* ctx, const ConnTracker& conn_tracker,
-                            TRecordType record, DataTable* data_table);
+  void AppendMessage(ConnectorContext* ctx, const ConnTracker& conn_tracker, TRecordType record,
+                     DataTable* data_table);
+
+  // Returns the maximum number of body bytes that the payload capture policy allows to be recorded
+  // for the protocol's traffic of the process. default_limit is returned if there is no policy.
+  size_t BodyLimit(ConnectorContext* ctx, const md::UPID& upid, std::string_view protocol,
+                   size_t default_limit);
 
   std::thread RunDeployUProbesThread(const absl::flat_hash_set<md::UPID>& pids);
 
@@ -230,6 +246,7 @@ class SocketTraceConnector : public SourceConnector, public bpf_tools::BCCWrappe
   ConnTrackersManager conn_trackers_mgr_;
 
   ConnStats conn_stats_;
+  ConnLifetimes conn_lifetimes_;
 
   std::unique_ptr<ebpf::BPFArrayTable<int>> openssl_trace_state_;
   std::unique_ptr<ebpf::BPFHashTable<uint32_t, struct openssl_trace_state_debug_t>>
@@ -290,6 +307,10 @@ class SocketTraceConnector : public SourceConnector, public bpf_tools::BCCWrappe
 
   UProbeManager uprobe_mgr_;
 
+  // Decides how much of the request and response bodies is recorded, per namespace, service and
+  // protocol. Set from --stirling_payload_capture_policy.
+  PayloadCapturePolicy payload_capture_policy_;
+
   enum class StatKey {
     kLossSocketDataEvent,
     kLossSocketControlEvent,
@@ -304,6 +325,9 @@ class SocketTraceConnector : public SourceConnector, public bpf_tools::BCCWrappe
     kPollSocketDataEventAttrSize,
     kPollSocketDataEventDataSize,
     kPollSocketDataEventSize,
+
+    // Data events suppressed by the per-connection rate limiter in BPF.
+    kRateLimitedSocketDataEvent,
   };
 
   utils::StatCounter<StatKey> stats_;
@@ -18,6 +18,7 @@
 
 #pragma once
 
+#include "src/stirling/source_connectors/socket_tracer/conn_lifetime_table.h"
 #include "src/stirling/source_connectors/socket_tracer/conn_stats_table.h"
 
 // PROTOCOL_LIST: Requires update on new protocols.
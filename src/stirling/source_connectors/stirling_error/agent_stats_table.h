@@ -0,0 +1,63 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#pragma once
+
+#include "src/common/base/base.h"
+#include "src/stirling/core/canonical_types.h"
+#include "src/stirling/core/output.h"
+#include "src/stirling/core/source_connector.h"
+
+namespace px {
+namespace stirling {
+
+// clang-format off
+constexpr DataElement kAgentStatsElements[] = {
+  canonical_data_elements::kTime,
+  canonical_data_elements::kUPID,
+  {"cpu_utime_ns", "Time spent on user space by the agent",
+   types::DataType::INT64, types::SemanticType::ST_DURATION_NS, types::PatternType::METRIC_COUNTER},
+  {"cpu_ktime_ns", "Time spent on kernel by the agent",
+   types::DataType::INT64, types::SemanticType::ST_DURATION_NS, types::PatternType::METRIC_COUNTER},
+  {"num_threads", "Number of threads of the agent",
+   types::DataType::INT64, types::SemanticType::ST_NONE, types::PatternType::METRIC_GAUGE},
+  {"vsize_bytes", "Virtual memory size in bytes of the agent",
+   types::DataType::INT64, types::SemanticType::ST_BYTES, types::PatternType::METRIC_GAUGE},
+  {"rss_bytes", "Resident memory size in bytes of the agent",
+   types::DataType::INT64, types::SemanticType::ST_BYTES, types::PatternType::METRIC_GAUGE},
+  {"perf_buffer_lost_events", "Number of events lost because the eBPF perf buffers were full",
+   types::DataType::INT64, types::SemanticType::ST_NONE, types::PatternType::METRIC_COUNTER},
+  {"records_dropped", "Number of records dropped by Stirling before they were pushed to tables",
+   types::DataType::INT64, types::SemanticType::ST_NONE, types::PatternType::METRIC_COUNTER},
+  {"table_batches_expired", "Number of row batches evicted from the agent's tables to stay within the table size limits",
+   types::DataType::INT64, types::SemanticType::ST_NONE, types::PatternType::METRIC_COUNTER},
+  {"tracer_errors", "Number of errors reported by the source connectors and their probes",
+   types::DataType::INT64, types::SemanticType::ST_NONE, types::PatternType::METRIC_COUNTER},
+};
+
+constexpr DataTableSchema kAgentStatsTable {
+  "px_agent_stats",
+  "This table contains the resource usage of the Pixie agent and counts of the data it lost, so that its overhead and data loss can be measured",
+  kAgentStatsElements
+};
+
+// clang-format on
+DEFINE_PRINT_TABLE(AgentStats);
+
+}  // namespace stirling
+}  // namespace px
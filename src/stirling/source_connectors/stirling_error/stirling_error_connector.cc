@@ -19,12 +19,35 @@
 #include <magic_enum.hpp>
 
 #include "src/common/base/base.h"
+#include "src/common/metrics/metrics.h"
+#include "src/common/system/config.h"
 #include "src/common/system/proc_parser.h"
 #include "src/stirling/source_connectors/stirling_error/stirling_error_connector.h"
 
 namespace px {
 namespace stirling {
 
+namespace {
+
+// The table store registers its eviction counters in the global metrics registry,
+// which is shared with Stirling inside the PEM.
+constexpr char kTableBatchesExpiredMetric[] = "table_batches_expired";
+
+int64_t TotalTableBatchesExpired() {
+  double total = 0;
+  for (const auto& family : GetMetricsRegistry().Collect()) {
+    if (family.name != kTableBatchesExpiredMetric) {
+      continue;
+    }
+    for (const auto& metric : family.metric) {
+      total += metric.counter.value;
+    }
+  }
+  return static_cast<int64_t>(total);
+}
+
+}  // namespace
+
 Status StirlingErrorConnector::InitImpl() {
   // Set Stirling start_time.
   pid_ = getpid();
@@ -39,7 +62,7 @@ Status StirlingErrorConnector::InitImpl() {
 Status StirlingErrorConnector::StopImpl() { return Status::OK(); }
 
 void StirlingErrorConnector::TransferDataImpl(ConnectorContext* ctx) {
-  DCHECK_EQ(data_tables_.size(), 2U) << "StirlingErrorConnector has two data tables.";
+  DCHECK_EQ(data_tables_.size(), 3U) << "StirlingErrorConnector has three data tables.";
 
   if (data_tables_[kStirlingErrorTableNum] != nullptr) {
     TransferStirlingErrorTable(ctx, data_tables_[kStirlingErrorTableNum]);
@@ -48,6 +71,10 @@ void StirlingErrorConnector::TransferDataImpl(ConnectorContext* ctx) {
   if (data_tables_[kProbeStatusTableNum] != nullptr) {
     TransferProbeStatusTable(ctx, data_tables_[kProbeStatusTableNum]);
   }
+
+  if (data_tables_[kAgentStatsTableNum] != nullptr) {
+    TransferAgentStatsTable(ctx, data_tables_[kAgentStatsTableNum]);
+  }
 }
 
 void StirlingErrorConnector::TransferStirlingErrorTable(ConnectorContext* ctx,
@@ -79,5 +106,33 @@ void StirlingErrorConnector::TransferProbeStatusTable(ConnectorContext* ctx,
   }
 }
 
+void StirlingErrorConnector::TransferAgentStatsTable(ConnectorContext* ctx,
+                                                     DataTable* data_table) {
+  system::ProcParser::ProcessStats stats;
+  const auto& config = system::Config::GetInstance();
+  Status s = proc_parser_.ParseProcPIDStat(pid_, config.PageSizeBytes(),
+                                           config.KernelTickTimeNS(), &stats);
+  if (!s.ok()) {
+    VLOG(1) << absl::Substitute("Failed to fetch the agent's own stats. Error=\"$0\"", s.msg());
+    return;
+  }
+  const AgentStatsRecord agent_stats = monitor_.GetAgentStats();
+
+  md::UPID upid = md::UPID(ctx->GetASID(), pid_, start_time_);
+  uint64_t timestamp_ns = AdjustedSteadyClockNowNS();
+  DataTable::RecordBuilder<&kAgentStatsTable> r(data_table, timestamp_ns);
+  r.Append<r.ColIndex("time_")>(timestamp_ns);
+  r.Append<r.ColIndex("upid")>(upid.value());
+  r.Append<r.ColIndex("cpu_utime_ns")>(stats.utime_ns);
+  r.Append<r.ColIndex("cpu_ktime_ns")>(stats.ktime_ns);
+  r.Append<r.ColIndex("num_threads")>(stats.num_threads);
+  r.Append<r.ColIndex("vsize_bytes")>(stats.vsize_bytes);
+  r.Append<r.ColIndex("rss_bytes")>(stats.rss_bytes);
+  r.Append<r.ColIndex("perf_buffer_lost_events")>(agent_stats.perf_buffer_lost_events);
+  r.Append<r.ColIndex("records_dropped")>(agent_stats.records_dropped);
+  r.Append<r.ColIndex("table_batches_expired")>(TotalTableBatchesExpired());
+  r.Append<r.ColIndex("tracer_errors")>(agent_stats.tracer_errors);
+}
+
 }  // namespace stirling
 }  // namespace px
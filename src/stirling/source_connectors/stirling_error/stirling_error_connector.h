@@ -24,7 +24,9 @@
 #include <vector>
 
 #include "src/common/base/base.h"
+#include "src/common/system/proc_parser.h"
 #include "src/stirling/core/source_connector.h"
+#include "src/stirling/source_connectors/stirling_error/agent_stats_table.h"
 #include "src/stirling/source_connectors/stirling_error/probe_status_table.h"
 #include "src/stirling/source_connectors/stirling_error/stirling_error_table.h"
 #include "src/stirling/utils/monitor.h"
@@ -37,9 +39,11 @@ class StirlingErrorConnector : public SourceConnector {
   static constexpr std::string_view kName = "stirling_error";
   static constexpr auto kSamplingPeriod = std::chrono::milliseconds{1000};
   static constexpr auto kPushPeriod = std::chrono::milliseconds{1000};
-  static constexpr auto kTables = MakeArray(kStirlingErrorTable, kProbeStatusTable);
+  static constexpr auto kTables =
+      MakeArray(kStirlingErrorTable, kProbeStatusTable, kAgentStatsTable);
   static constexpr uint32_t kStirlingErrorTableNum = TableNum(kTables, kStirlingErrorTable);
   static constexpr uint32_t kProbeStatusTableNum = TableNum(kTables, kProbeStatusTable);
+  static constexpr uint32_t kAgentStatsTableNum = TableNum(kTables, kAgentStatsTable);
 
   StirlingErrorConnector() = delete;
   ~StirlingErrorConnector() override = default;
@@ -59,8 +63,10 @@ class StirlingErrorConnector : public SourceConnector {
 
   void TransferStirlingErrorTable(ConnectorContext* ctx, DataTable* data_table);
   void TransferProbeStatusTable(ConnectorContext* ctx, DataTable* data_table);
+  void TransferAgentStatsTable(ConnectorContext* ctx, DataTable* data_table);
 
   StirlingMonitor& monitor_ = *StirlingMonitor::GetInstance();
+  system::ProcParser proc_parser_;
   int32_t pid_ = -1;
   uint64_t start_time_ = -1;
 };
@@ -26,6 +26,7 @@
 #include "src/common/base/inet_utils.h"
 #include "src/stirling/bpf_tools/macros.h"
 #include "src/stirling/source_connectors/tcp_stats/tcp_stats.h"
+#include "src/stirling/utils/monitor.h"
 
 OBJ_STRVIEW(tcpstats_bcc_script, tcpstats);
 
@@ -56,8 +57,8 @@ void TCPStatsConnector::AcceptTcpEvent(const struct tcp_event_t& event) {
   events_.push_back(event);
 }
 
-void HandleTcpEventLoss(void* /*cb_cookie*/, uint64_t /*lost*/) {
-  // TODO(RagalahariP): Add stats counter.
+void HandleTcpEventLoss(void* /*cb_cookie*/, uint64_t lost) {
+  StirlingMonitor::GetInstance()->NotifyPerfBufferLoss(lost);
 }
 
 const auto kPerfBufferSpecs = MakeArray<bpf_tools::PerfBufferSpec>({
@@ -33,6 +33,7 @@
 #include "src/common/base/base.h"
 #include "src/common/json/json.h"
 #include "src/common/perf/elapsed_timer.h"
+#include "src/stirling/utils/kernel_artifacts.h"
 #include "src/stirling/utils/run_core_stats.h"
 #include "src/stirling/utils/system_info.h"
 
@@ -203,6 +204,7 @@ class StirlingImpl final : public Stirling {
   StatusOr<stirlingpb::Publish> GetTracepointInfo(sole::uuid trace_id) override;
   Status RemoveTracepoint(sole::uuid trace_id) override;
   void GetPublishProto(stirlingpb::Publish* publish_pb) override;
+  std::map<std::string, Status> SourceInitStatus() const override { return source_init_status_; }
   void RegisterDataPushCallback(DataPushCallback f) override { data_push_callback_ = f; }
   void RegisterAgentMetadataCallback(AgentMetadataCallback f) override {
     DCHECK(f != nullptr);
@@ -253,6 +255,9 @@ class StirlingImpl final : public Stirling {
 
   std::atomic<bool> run_enable_ = false;
   std::atomic<bool> running_ = false;
+
+  // The result of initializing each source from the registry. Only written during Init().
+  std::map<std::string, Status> source_init_status_;
   std::vector<std::unique_ptr<SourceConnector>> sources_ ABSL_GUARDED_BY(info_class_mgrs_lock_);
 
   InfoClassManagerVec info_class_mgrs_ ABSL_GUARDED_BY(info_class_mgrs_lock_);
@@ -393,6 +398,14 @@ void StirlingImpl::RegisterUserDebugSignalHandlers(int signum) {
 Status StirlingImpl::Init() {
   system::LogSystemInfo();
 
+  // Locate the kernel headers and BTF up front, so that a missing artifact is reported once, with
+  // every location that was searched, instead of surfacing as a compile error in each tracer.
+  const utils::KernelArtifactsStatus kernel_artifacts = utils::InitKernelArtifacts();
+  monitor_.AppendSourceStatusRecord("kernel_headers", kernel_artifacts.headers.status,
+                                    kernel_artifacts.headers.ToString());
+  monitor_.AppendSourceStatusRecord("kernel_btf", kernel_artifacts.btf.status,
+                                    kernel_artifacts.btf.ToString());
+
   // Clean up any probes from a previous instance.
   Status s = utils::CleanProbes();
 
@@ -412,6 +425,7 @@ Status StirlingImpl::Init() {
 
     Status s = AddSource(std::move(source_ptr));
     monitor_.AppendSourceStatusRecord(name, s, "Init");
+    source_init_status_[name] = s;
 
     LOG_IF(WARNING, !s.ok()) << absl::Substitute(
         "Source Connector (registry name=$0) not instantiated, error: $1", name, s.ToString());
@@ -20,6 +20,7 @@
 
 #include <signal.h>
 
+#include <map>
 #include <memory>
 #include <string>
 #include <vector>
@@ -138,6 +139,12 @@ class Stirling : public NotCopyable {
    */
   virtual void GetPublishProto(stirlingpb::Publish* publish_pb) = 0;
 
+  /**
+   * Returns the result of initializing each of the registered source connectors, keyed by the
+   * source name. Sources that failed to initialize are not run.
+   */
+  virtual std::map<std::string, Status> SourceInitStatus() const = 0;
+
   /**
    * Register call-back from Agent. Used to periodically send data.
    *
@@ -19,6 +19,7 @@
 #pragma once
 
 #include <gmock/gmock.h>
+#include <map>
 #include <memory>
 #include <sole.hpp>
 
@@ -41,6 +42,7 @@ class MockStirling : public Stirling {
   MOCK_METHOD(StatusOr<stirlingpb::Publish>, GetTracepointInfo, (sole::uuid trace_id), (override));
   MOCK_METHOD(Status, RemoveTracepoint, (sole::uuid trace_id), (override));
   MOCK_METHOD(void, GetPublishProto, (stirlingpb::Publish * publish_pb), (override));
+  MOCK_METHOD((std::map<std::string, Status>), SourceInitStatus, (), (const, override));
   MOCK_METHOD(void, RegisterDataPushCallback, (DataPushCallback f), (override));
   MOCK_METHOD(void, RegisterAgentMetadataCallback, (AgentMetadataCallback f), (override));
   MOCK_METHOD(void, Run, (), (override));
@@ -0,0 +1,204 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#include "src/stirling/utils/kernel_artifacts.h"
+
+#include <sys/utsname.h>
+
+#include <cstdlib>
+
+#include <absl/strings/str_join.h>
+#include <absl/strings/str_split.h>
+#include <absl/strings/strip.h>
+
+#include "src/common/base/file.h"
+#include "src/common/fs/fs_wrapper.h"
+#include "src/common/system/config.h"
+#include "src/stirling/utils/linux_headers.h"
+
+DEFINE_string(stirling_kernel_btf_dirs, "/px/btf-hub:downloaded,/px/btf:packaged",
+              "Comma-separated directories with <kernel release>.btf files, used when the kernel "
+              "does not expose its own BTF. Each directory is tagged with where its files come "
+              "from, as <dir>:downloaded or <dir>:packaged.");
+
+namespace px {
+namespace stirling {
+namespace utils {
+
+namespace {
+
+std::string_view SourceName(KernelArtifactSource source) {
+  switch (source) {
+    case KernelArtifactSource::kHost:
+      return "host";
+    case KernelArtifactSource::kPackaged:
+      return "packaged";
+    case KernelArtifactSource::kDownloaded:
+      return "downloaded";
+    case KernelArtifactSource::kNotFound:
+      return "not found";
+  }
+  return "unknown";
+}
+
+}  // namespace
+
+std::string KernelArtifactStatus::ToString() const {
+  if (!found()) {
+    return absl::Substitute("not found ($0)", status.msg());
+  }
+  return absl::Substitute("$0 (source=$1)", path.string(), SourceName(source));
+}
+
+StatusOr<OSRelease> ParseOSRelease(std::string_view contents) {
+  OSRelease os_release;
+  for (std::string_view line : absl::StrSplit(contents, '\n', absl::SkipWhitespace())) {
+    std::vector<std::string_view> kv = absl::StrSplit(line, absl::MaxSplits('=', 1));
+    if (kv.size() != 2) {
+      continue;
+    }
+    std::string_view value = kv[1];
+    // Values may be quoted, e.g. VERSION_ID="20.04".
+    if (value.size() >= 2 && (value.front() == '"' || value.front() == '\'') &&
+        value.back() == value.front()) {
+      value = value.substr(1, value.size() - 2);
+    }
+    if (kv[0] == "ID") {
+      os_release.id = value;
+    } else if (kv[0] == "VERSION_ID") {
+      os_release.version_id = value;
+    }
+  }
+  if (os_release.id.empty() || os_release.version_id.empty()) {
+    return error::NotFound("os-release is missing ID or VERSION_ID");
+  }
+  return os_release;
+}
+
+std::string BTFHubArchivePath(const OSRelease& os_release, std::string_view arch,
+                              std::string_view kernel_release) {
+  // BTF hubs use the Debian name of the architecture for ARM.
+  if (arch == "aarch64") {
+    arch = "arm64";
+  }
+  return absl::Substitute("$0/$1/$2/$3.btf.tar.xz", os_release.id, os_release.version_id, arch,
+                          kernel_release);
+}
+
+BTFSearchPaths DefaultBTFSearchPaths(std::string_view kernel_release) {
+  const system::Config& sysconfig = system::Config::GetInstance();
+
+  BTFSearchPaths search_paths;
+  search_paths.sysfs_vmlinux = sysconfig.sysfs_path() / "kernel/btf/vmlinux";
+  // The locations where distros install vmlinux with debug info, as searched by libbpf.
+  for (const auto& p : {
+           absl::Substitute("/boot/vmlinux-$0", kernel_release),
+           absl::Substitute("/lib/modules/$0/vmlinux-$0", kernel_release),
+           absl::Substitute("/lib/modules/$0/build/vmlinux", kernel_release),
+           absl::Substitute("/usr/lib/modules/$0/kernel/vmlinux", kernel_release),
+           absl::Substitute("/usr/lib/debug/boot/vmlinux-$0", kernel_release),
+           absl::Substitute("/usr/lib/debug/boot/vmlinux-$0.debug", kernel_release),
+           absl::Substitute("/usr/lib/debug/lib/modules/$0/vmlinux", kernel_release),
+       }) {
+    search_paths.host_vmlinux.push_back(sysconfig.ToHostPath(p));
+  }
+  for (std::string_view entry :
+       absl::StrSplit(FLAGS_stirling_kernel_btf_dirs, ',', absl::SkipWhitespace())) {
+    std::string_view dir = entry;
+    KernelArtifactSource source = KernelArtifactSource::kPackaged;
+    if (absl::ConsumeSuffix(&dir, ":downloaded")) {
+      source = KernelArtifactSource::kDownloaded;
+    } else {
+      absl::ConsumeSuffix(&dir, ":packaged");
+    }
+    search_paths.btf_dirs.emplace_back(std::filesystem::path(dir), source);
+  }
+  return search_paths;
+}
+
+KernelArtifactStatus FindKernelBTF(const BTFSearchPaths& search_paths,
+                                   std::string_view kernel_release) {
+  std::vector<std::string> searched;
+
+  if (fs::Exists(search_paths.sysfs_vmlinux)) {
+    return {KernelArtifactSource::kHost, search_paths.sysfs_vmlinux, Status::OK()};
+  }
+  searched.push_back(search_paths.sysfs_vmlinux.string());
+
+  for (const auto& p : search_paths.host_vmlinux) {
+    if (fs::Exists(p)) {
+      return {KernelArtifactSource::kHost, p, Status::OK()};
+    }
+    searched.push_back(p.string());
+  }
+
+  for (const auto& [dir, source] : search_paths.btf_dirs) {
+    std::filesystem::path p = dir / absl::StrCat(kernel_release, ".btf");
+    if (fs::Exists(p)) {
+      return {source, p, Status::OK()};
+    }
+    searched.push_back(p.string());
+  }
+
+  return {KernelArtifactSource::kNotFound, {},
+          error::NotFound("No BTF for kernel $0, searched: $1", kernel_release,
+                          absl::StrJoin(searched, ", "))};
+}
+
+namespace {
+
+KernelArtifactStatus FindKernelHeaders() {
+  auto headers_or = FindOrInstallLinuxHeaders();
+  if (!headers_or.ok()) {
+    return {KernelArtifactSource::kNotFound, {}, headers_or.status()};
+  }
+  KernelArtifactSource source =
+      g_packaged_headers_installed ? KernelArtifactSource::kPackaged : KernelArtifactSource::kHost;
+  return {source, headers_or.ConsumeValueOrDie(), Status::OK()};
+}
+
+}  // namespace
+
+KernelArtifactsStatus InitKernelArtifacts() {
+  KernelArtifactsStatus status;
+  status.headers = FindKernelHeaders();
+
+  struct utsname buffer;
+  if (uname(&buffer) != 0) {
+    status.btf.status = error::Internal("Could not determine kernel release (uname -r)");
+  } else {
+    std::string kernel_release(buffer.release);
+    status.btf = FindKernelBTF(DefaultBTFSearchPaths(kernel_release), kernel_release);
+  }
+
+  // bpftrace only looks for BTF in the locations that the kernel and distros use.
+  if (status.btf.found() && status.btf.source != KernelArtifactSource::kHost) {
+    setenv("BPFTRACE_BTF", status.btf.path.c_str(), /*overwrite*/ 0);
+  }
+
+  LOG(INFO) << absl::Substitute("Kernel headers: $0", status.headers.ToString());
+  LOG(INFO) << absl::Substitute("Kernel BTF: $0", status.btf.ToString());
+  LOG_IF(WARNING, !status.headers.found() && !status.btf.found())
+      << "Neither kernel headers nor BTF are available, BPF tracers that need them will not "
+         "be deployed. Install the kernel headers on the host, or set PL_BTF_HUB_URL.";
+  return status;
+}
+
+}  // namespace utils
+}  // namespace stirling
+}  // namespace px
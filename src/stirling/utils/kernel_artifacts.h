@@ -0,0 +1,114 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#pragma once
+
+#include <filesystem>
+#include <string>
+#include <utility>
+#include <vector>
+
+#include "src/common/base/base.h"
+
+namespace px {
+namespace stirling {
+namespace utils {
+
+// Where the kernel headers or BTF that Stirling uses came from.
+enum class KernelArtifactSource {
+  kNotFound,
+  // Found on the host, e.g. /sys/kernel/btf/vmlinux or /lib/modules/<release>/build.
+  kHost,
+  // Packaged in the PEM image and installed for the running kernel.
+  kPackaged,
+  // Downloaded from a BTF hub by the PEM's init container.
+  kDownloaded,
+};
+
+/**
+ * The outcome of the search for one kernel artifact. When nothing was found, status describes
+ * every location that was tried, so that the reason tracers can't be deployed is visible.
+ */
+struct KernelArtifactStatus {
+  KernelArtifactSource source = KernelArtifactSource::kNotFound;
+  std::filesystem::path path;
+  Status status;
+
+  bool found() const { return source != KernelArtifactSource::kNotFound; }
+  std::string ToString() const;
+};
+
+/**
+ * The fields of /etc/os-release that identify the distro, in the form that BTF hubs use to lay out
+ * their archives (e.g. ubuntu/20.04/x86_64/5.4.0-1036-gke.btf.tar.xz).
+ */
+struct OSRelease {
+  std::string id;
+  std::string version_id;
+};
+
+StatusOr<OSRelease> ParseOSRelease(std::string_view contents);
+
+/**
+ * Returns the path of the BTF archive of the given kernel within a BTF hub.
+ */
+std::string BTFHubArchivePath(const OSRelease& os_release, std::string_view arch,
+                              std::string_view kernel_release);
+
+/**
+ * The locations that are searched for the BTF of the running kernel, in order.
+ */
+struct BTFSearchPaths {
+  // The BTF exposed by the kernel itself, when it is built with CONFIG_DEBUG_INFO_BTF.
+  std::filesystem::path sysfs_vmlinux;
+  // vmlinux images with BTF that distros install alongside the kernel.
+  std::vector<std::filesystem::path> host_vmlinux;
+  // Directories containing a <release>.btf file for the kernel, and where each came from.
+  std::vector<std::pair<std::filesystem::path, KernelArtifactSource>> btf_dirs;
+};
+
+/**
+ * Returns the default search paths for the given kernel release, using the host's filesystem and
+ * the --stirling_kernel_btf_dirs flag.
+ */
+BTFSearchPaths DefaultBTFSearchPaths(std::string_view kernel_release);
+
+/**
+ * Finds the BTF for the given kernel release.
+ */
+KernelArtifactStatus FindKernelBTF(const BTFSearchPaths& search_paths,
+                                   std::string_view kernel_release);
+
+struct KernelArtifactsStatus {
+  KernelArtifactStatus headers;
+  KernelArtifactStatus btf;
+};
+
+/**
+ * Locates the kernel headers and BTF that the BPF tracers need, installing the packaged headers if
+ * the host has none, and reports the outcome of each. If the BTF is not exposed by the kernel,
+ * the BTF that was found is exported through BPFTRACE_BTF so that dynamic tracepoints can use it.
+ *
+ * Neither artifact is strictly required, since some tracers need neither, so this never fails:
+ * the caller records the returned status to surface why tracers that do need them did not deploy.
+ */
+KernelArtifactsStatus InitKernelArtifacts();
+
+}  // namespace utils
+}  // namespace stirling
+}  // namespace px
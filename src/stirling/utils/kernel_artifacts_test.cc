@@ -0,0 +1,116 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#include "src/stirling/utils/kernel_artifacts.h"
+
+#include "src/common/base/file.h"
+#include "src/common/fs/fs_wrapper.h"
+#include "src/common/testing/testing.h"
+
+namespace px {
+namespace stirling {
+namespace utils {
+
+using ::px::testing::TempDir;
+using ::testing::HasSubstr;
+
+TEST(KernelArtifacts, ParseOSRelease) {
+  constexpr char kUbuntu[] = R"(NAME="Ubuntu"
+VERSION="20.04.6 LTS (Focal Fossa)"
+ID=ubuntu
+ID_LIKE=debian
+VERSION_ID="20.04"
+)";
+  ASSERT_OK_AND_ASSIGN(OSRelease os_release, ParseOSRelease(kUbuntu));
+  EXPECT_EQ(os_release.id, "ubuntu");
+  EXPECT_EQ(os_release.version_id, "20.04");
+
+  EXPECT_NOT_OK(ParseOSRelease("NAME=\"Unknown\"\n"));
+}
+
+TEST(KernelArtifacts, BTFHubArchivePath) {
+  OSRelease os_release{"centos", "7"};
+  EXPECT_EQ(BTFHubArchivePath(os_release, "x86_64", "3.10.0-1160.el7.x86_64"),
+            "centos/7/x86_64/3.10.0-1160.el7.x86_64.btf.tar.xz");
+  EXPECT_EQ(BTFHubArchivePath(os_release, "aarch64", "4.18.0-305.el8.aarch64"),
+            "centos/7/arm64/4.18.0-305.el8.aarch64.btf.tar.xz");
+}
+
+class FindKernelBTFTest : public ::testing::Test {
+ protected:
+  void SetUp() override {
+    search_paths_.sysfs_vmlinux = tmp_dir_.path() / "sys/kernel/btf/vmlinux";
+    search_paths_.host_vmlinux = {tmp_dir_.path() / "boot/vmlinux-5.4.0"};
+    search_paths_.btf_dirs = {
+        {tmp_dir_.path() / "btf-hub", KernelArtifactSource::kDownloaded},
+        {tmp_dir_.path() / "btf", KernelArtifactSource::kPackaged},
+    };
+  }
+
+  void Touch(const std::filesystem::path& p) {
+    ASSERT_OK(fs::CreateDirectories(p.parent_path()));
+    ASSERT_OK(WriteFileFromString(p, "btf"));
+  }
+
+  TempDir tmp_dir_;
+  BTFSearchPaths search_paths_;
+};
+
+TEST_F(FindKernelBTFTest, NotFound) {
+  KernelArtifactStatus status = FindKernelBTF(search_paths_, "5.4.0");
+  EXPECT_FALSE(status.found());
+  EXPECT_THAT(status.status.msg(), HasSubstr("sys/kernel/btf/vmlinux"));
+  EXPECT_THAT(status.status.msg(), HasSubstr("btf-hub/5.4.0.btf"));
+  EXPECT_THAT(status.ToString(), HasSubstr("not found"));
+}
+
+TEST_F(FindKernelBTFTest, PrefersSysfs) {
+  Touch(search_paths_.sysfs_vmlinux);
+  Touch(tmp_dir_.path() / "btf/5.4.0.btf");
+  KernelArtifactStatus status = FindKernelBTF(search_paths_, "5.4.0");
+  EXPECT_EQ(status.source, KernelArtifactSource::kHost);
+  EXPECT_EQ(status.path, search_paths_.sysfs_vmlinux);
+}
+
+TEST_F(FindKernelBTFTest, HostVmlinux) {
+  Touch(search_paths_.host_vmlinux[0]);
+  KernelArtifactStatus status = FindKernelBTF(search_paths_, "5.4.0");
+  EXPECT_EQ(status.source, KernelArtifactSource::kHost);
+  EXPECT_EQ(status.path, search_paths_.host_vmlinux[0]);
+}
+
+TEST_F(FindKernelBTFTest, DownloadedBeforePackaged) {
+  Touch(tmp_dir_.path() / "btf-hub/5.4.0.btf");
+  Touch(tmp_dir_.path() / "btf/5.4.0.btf");
+  KernelArtifactStatus status = FindKernelBTF(search_paths_, "5.4.0");
+  EXPECT_EQ(status.source, KernelArtifactSource::kDownloaded);
+  EXPECT_EQ(status.path, tmp_dir_.path() / "btf-hub/5.4.0.btf");
+  EXPECT_THAT(status.ToString(), HasSubstr("source=downloaded"));
+}
+
+TEST_F(FindKernelBTFTest, Packaged) {
+  Touch(tmp_dir_.path() / "btf/5.4.0.btf");
+  Touch(tmp_dir_.path() / "btf/5.10.0.btf");
+  KernelArtifactStatus status = FindKernelBTF(search_paths_, "5.4.0");
+  EXPECT_EQ(status.source, KernelArtifactSource::kPackaged);
+  EXPECT_EQ(status.path, tmp_dir_.path() / "btf/5.4.0.btf");
+}
+
+}  // namespace utils
+}  // namespace stirling
+}  // namespace px
@@ -61,6 +61,9 @@ void StirlingMonitor::NotifyJavaProcessCrashed(const struct upid_t& upid) {
 
 void StirlingMonitor::AppendSourceStatusRecord(const std::string& source_connector,
                                                const Status& status, const std::string& context) {
+  if (!status.ok()) {
+    ++tracer_errors_;
+  }
   absl::base_internal::SpinLockHolder lock(&source_status_lock_);
   source_status_records_.push_back(
       {CurrentTimeNS(), source_connector, status.code(), status.msg(), context});
@@ -69,6 +72,9 @@ void StirlingMonitor::AppendSourceStatusRecord(const std::string& source_connect
 void StirlingMonitor::AppendProbeStatusRecord(const std::string& source_connector,
                                               const std::string& tracepoint, const Status& status,
                                               const std::string& info) {
+  if (!status.ok()) {
+    ++tracer_errors_;
+  }
   absl::base_internal::SpinLockHolder lock(&probe_status_lock_);
   probe_status_records_.push_back(
       {CurrentTimeNS(), source_connector, tracepoint, status.code(), status.msg(), info});
@@ -84,5 +90,13 @@ std::vector<ProbeStatusRecord> StirlingMonitor::ConsumeProbeStatusRecords() {
   return std::move(probe_status_records_);
 }
 
+void StirlingMonitor::NotifyPerfBufferLoss(uint64_t lost) { perf_buffer_lost_events_ += lost; }
+
+void StirlingMonitor::NotifyRecordsDropped(uint64_t num_records) { records_dropped_ += num_records; }
+
+AgentStatsRecord StirlingMonitor::GetAgentStats() const {
+  return {perf_buffer_lost_events_.load(), records_dropped_.load(), tracer_errors_.load()};
+}
+
 }  // namespace stirling
 }  // namespace px
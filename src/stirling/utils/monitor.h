@@ -21,6 +21,7 @@
 #include <prometheus/counter.h>
 
 #include <absl/container/flat_hash_map.h>
+#include <atomic>
 #include <string>
 #include <utility>
 #include <vector>
@@ -50,6 +51,13 @@ struct ProbeStatusRecord {
   std::string info = "";
 };
 
+// Counters of data loss and errors in Stirling itself, accumulated over the agent's lifetime.
+struct AgentStatsRecord {
+  uint64_t perf_buffer_lost_events = 0;
+  uint64_t records_dropped = 0;
+  uint64_t tracer_errors = 0;
+};
+
 class StirlingMonitor : NotCopyMoveable {
  public:
   static StirlingMonitor* GetInstance() {
@@ -70,6 +78,11 @@ class StirlingMonitor : NotCopyMoveable {
   std::vector<ProbeStatusRecord> ConsumeProbeStatusRecords();
   std::vector<SourceStatusRecord> ConsumeSourceStatusRecords();
 
+  // Agent self-monitoring.
+  void NotifyPerfBufferLoss(uint64_t lost);
+  void NotifyRecordsDropped(uint64_t num_records);
+  AgentStatsRecord GetAgentStats() const;
+
   static constexpr auto kCrashWindow = std::chrono::seconds{5};
 
  private:
@@ -86,6 +99,12 @@ class StirlingMonitor : NotCopyMoveable {
   absl::base_internal::SpinLock probe_status_lock_;
   absl::base_internal::SpinLock source_status_lock_;
 
+  // Agent self-monitoring counters. These are updated from perf buffer callbacks and the
+  // Stirling core, so they are kept lock-free.
+  std::atomic<uint64_t> perf_buffer_lost_events_{0};
+  std::atomic<uint64_t> records_dropped_{0};
+  std::atomic<uint64_t> tracer_errors_{0};
+
   prometheus::Counter& java_proc_crashed_during_attach_;
 };
 
@@ -66,5 +66,25 @@ TEST(MonitorTest, DoesNotDisableJavaIfNotInCrashInWindow) {
   EXPECT_TRUE(FLAGS_stirling_profiler_java_symbols);
 }
 
+TEST(MonitorTest, AccumulatesAgentStats) {
+  StirlingMonitor& monitor = *StirlingMonitor::GetInstance();
+  const AgentStatsRecord before = monitor.GetAgentStats();
+
+  monitor.NotifyPerfBufferLoss(3);
+  monitor.NotifyPerfBufferLoss(4);
+  monitor.NotifyRecordsDropped(5);
+  monitor.AppendSourceStatusRecord("source", Status::OK(), "context");
+  monitor.AppendSourceStatusRecord("source", error::Internal("failed"), "context");
+  monitor.AppendProbeStatusRecord("source", "tracepoint", error::Internal("failed"), "info");
+
+  const AgentStatsRecord after = monitor.GetAgentStats();
+  EXPECT_EQ(after.perf_buffer_lost_events - before.perf_buffer_lost_events, 7U);
+  EXPECT_EQ(after.records_dropped - before.records_dropped, 5U);
+  EXPECT_EQ(after.tracer_errors - before.tracer_errors, 2U);
+
+  monitor.ConsumeSourceStatusRecords();
+  monitor.ConsumeProbeStatusRecords();
+}
+
 }  // namespace stirling
 }  // namespace px
@@ -40,6 +40,7 @@ import {
   GQLClusterInfo,
   GQLUserInfo,
   GQLOrgInfo,
+  GQLOrgBranding,
 } from 'app/types/schema';
 
 import { selectClusterName } from './cluster-info';
@@ -57,6 +58,17 @@ const useStyles = makeStyles((theme: Theme) => createStyles({
     color: 'white',
     background: 'rgba(220,0,0,0.5)',
   },
+  announcement: {
+    width: '100%',
+    textAlign: 'center',
+    padding: theme.spacing(0.5),
+    color: theme.palette.primary.contrastText,
+    background: theme.palette.primary.dark,
+    '& a': {
+      color: 'inherit',
+      marginLeft: theme.spacing(1),
+    },
+  },
 }), { name: 'App' });
 
 // eslint-disable-next-line react-memo/require-memo
@@ -77,6 +89,35 @@ const ClusterWarningBanner: React.FC<{ user: Pick<GQLUserInfo, 'email' | 'orgNam
 };
 ClusterWarningBanner.displayName = 'ClusterWarningBanner';
 
+// eslint-disable-next-line react-memo/require-memo
+const OrgAnnouncementBanner: React.FC = () => {
+  const classes = useStyles();
+  const { data } = useQuery<{
+    orgBranding: Pick<GQLOrgBranding, 'announcement' | 'announcementURL'>,
+  }>(gql`
+    query getOrgAnnouncement {
+      orgBranding {
+        announcement
+        announcementURL
+      }
+    }
+  `);
+
+  const announcement = data?.orgBranding?.announcement;
+  if (!announcement) {
+    return null;
+  }
+
+  const url = data.orgBranding.announcementURL;
+  return (
+    <div className={classes.announcement}>
+      {announcement}
+      {url && <a href={url} target='_blank' rel='noopener noreferrer'>Learn more</a>}
+    </div>
+  );
+};
+OrgAnnouncementBanner.displayName = 'OrgAnnouncementBanner';
+
 // Convenience routes: sends `/clusterID/:clusterID`,  to the appropriate Live url.
 // eslint-disable-next-line react-memo/require-memo
 const ClusterIDShortcut = ({ match, location }) => {
@@ -315,6 +356,7 @@ export default function PixieWithContext(): React.ReactElement {
     <UserContext.Provider value={userContext}>
       <OrgContext.Provider value={orgContext}>
         <ClusterWarningBanner user={user} />
+        {!isPixieEmbedded() && <OrgAnnouncementBanner />}
         {
           setupComplete ? (
             <Switch>
@@ -32,6 +32,7 @@ export enum AgentState {
   AGENT_STATE_HEALTHY = 'AGENT_STATE_HEALTHY',
   AGENT_STATE_UNRESPONSIVE = 'AGENT_STATE_UNRESPONSIVE',
   AGENT_STATE_DISCONNECTED = 'AGENT_STATE_DISCONNECTED',
+  AGENT_STATE_LAMEDUCK = 'AGENT_STATE_LAMEDUCK',
 }
 
 export interface AgentInfo {
@@ -65,6 +65,8 @@ export function agentStatusGroup(status: string): StatusGroup {
     return 'healthy';
   } if (['AGENT_STATE_UNRESPONSIVE'].indexOf(status) !== -1) {
     return 'unhealthy';
+  } if (['AGENT_STATE_LAMEDUCK'].indexOf(status) !== -1) {
+    return 'pending';
   }
   return 'unknown';
 }
@@ -17,6 +17,7 @@ export interface GQLQuery {
   verifyInviteToken: boolean;
   user: GQLUserInfo;
   org: GQLOrgInfo;
+  orgBranding: GQLOrgBranding;
   userSettings: GQLUserSettings;
   userAttributes: GQLUserAttributes;
   orgUsers: Array<GQLUserInfo>;
@@ -60,6 +61,7 @@ export interface GQLMutation {
   UpdateUserPermissions: GQLUserInfo;
   CreateOrg: string;
   UpdateOrgSettings: GQLOrgInfo;
+  UpdateOrgBranding: GQLOrgBranding;
   CreateInviteToken: string;
   RevokeAllInviteTokens: boolean;
   RemoveUserFromOrg: boolean;
@@ -92,6 +94,20 @@ export interface GQLOrgInfo {
   idePaths: Array<GQLIDEPath>;
 }
 
+export interface GQLOrgBranding {
+  announcement: string;
+  announcementURL: string;
+  docsURL: string;
+  supportContact: string;
+}
+
+export interface GQLEditableOrgBranding {
+  announcement?: string;
+  announcementURL?: string;
+  docsURL?: string;
+  supportContact?: string;
+}
+
 export interface GQLUserSettings {
   analyticsOptout: boolean;
   id: string;
@@ -114,6 +130,9 @@ export interface GQLAPIKeyMetadata {
   id: string;
   createdAtMs: number;
   desc: string;
+  scopes: Array<string>;
+  expiresAtMs?: number;
+  lastUsedAtMs?: number;
 }
 
 export interface GQLAPIKey {
@@ -121,12 +140,18 @@ export interface GQLAPIKey {
   key: string;
   createdAtMs: number;
   desc: string;
+  scopes: Array<string>;
+  expiresAtMs?: number;
+  lastUsedAtMs?: number;
 }
 
 export interface GQLDeploymentKeyMetadata {
   id: string;
   createdAtMs: number;
   desc: string;
+  expiresAtMs?: number;
+  lastUsedAtMs?: number;
+  viewer: boolean;
 }
 
 export interface GQLDeploymentKey {
@@ -134,6 +159,9 @@ export interface GQLDeploymentKey {
   key: string;
   createdAtMs: number;
   desc: string;
+  expiresAtMs?: number;
+  lastUsedAtMs?: number;
+  viewer: boolean;
 }
 
 export enum GQLAutocompleteEntityState {
@@ -378,6 +406,7 @@ export interface GQLResolver {
   UserInfo?: GQLUserInfoTypeResolver;
   IDEPath?: GQLIDEPathTypeResolver;
   OrgInfo?: GQLOrgInfoTypeResolver;
+  OrgBranding?: GQLOrgBrandingTypeResolver;
   UserSettings?: GQLUserSettingsTypeResolver;
   UserAttributes?: GQLUserAttributesTypeResolver;
   APIKeyMetadata?: GQLAPIKeyMetadataTypeResolver;
@@ -410,6 +439,7 @@ export interface GQLQueryTypeResolver<TParent = any> {
   verifyInviteToken?: QueryToVerifyInviteTokenResolver<TParent>;
   user?: QueryToUserResolver<TParent>;
   org?: QueryToOrgResolver<TParent>;
+  orgBranding?: QueryToOrgBrandingResolver<TParent>;
   userSettings?: QueryToUserSettingsResolver<TParent>;
   userAttributes?: QueryToUserAttributesResolver<TParent>;
   orgUsers?: QueryToOrgUsersResolver<TParent>;
@@ -453,6 +483,10 @@ export interface QueryToOrgResolver<TParent = any, TResult = any> {
   (parent: TParent, args: {}, context: any, info: GraphQLResolveInfo): TResult;
 }
 
+export interface QueryToOrgBrandingResolver<TParent = any, TResult = any> {
+  (parent: TParent, args: {}, context: any, info: GraphQLResolveInfo): TResult;
+}
+
 export interface QueryToUserSettingsResolver<TParent = any, TResult = any> {
   (parent: TParent, args: {}, context: any, info: GraphQLResolveInfo): TResult;
 }
@@ -601,6 +635,7 @@ export interface GQLMutationTypeResolver<TParent = any> {
   UpdateUserPermissions?: MutationToUpdateUserPermissionsResolver<TParent>;
   CreateOrg?: MutationToCreateOrgResolver<TParent>;
   UpdateOrgSettings?: MutationToUpdateOrgSettingsResolver<TParent>;
+  UpdateOrgBranding?: MutationToUpdateOrgBrandingResolver<TParent>;
   CreateInviteToken?: MutationToCreateInviteTokenResolver<TParent>;
   RevokeAllInviteTokens?: MutationToRevokeAllInviteTokensResolver<TParent>;
   RemoveUserFromOrg?: MutationToRemoveUserFromOrgResolver<TParent>;
@@ -618,8 +653,12 @@ export interface MutationToCreateClusterResolver<TParent = any, TResult = any> {
   (parent: TParent, args: {}, context: any, info: GraphQLResolveInfo): TResult;
 }
 
+export interface MutationToCreateDeploymentKeyArgs {
+  expiresAtMs?: number;
+  viewer?: boolean;
+}
 export interface MutationToCreateDeploymentKeyResolver<TParent = any, TResult = any> {
-  (parent: TParent, args: {}, context: any, info: GraphQLResolveInfo): TResult;
+  (parent: TParent, args: MutationToCreateDeploymentKeyArgs, context: any, info: GraphQLResolveInfo): TResult;
 }
 
 export interface MutationToDeleteDeploymentKeyArgs {
@@ -629,8 +668,12 @@ export interface MutationToDeleteDeploymentKeyResolver<TParent = any, TResult =
   (parent: TParent, args: MutationToDeleteDeploymentKeyArgs, context: any, info: GraphQLResolveInfo): TResult;
 }
 
+export interface MutationToCreateAPIKeyArgs {
+  scopes?: Array<string>;
+  expiresAtMs?: number;
+}
 export interface MutationToCreateAPIKeyResolver<TParent = any, TResult = any> {
-  (parent: TParent, args: {}, context: any, info: GraphQLResolveInfo): TResult;
+  (parent: TParent, args: MutationToCreateAPIKeyArgs, context: any, info: GraphQLResolveInfo): TResult;
 }
 
 export interface MutationToDeleteAPIKeyArgs {
@@ -690,6 +733,13 @@ export interface MutationToUpdateOrgSettingsResolver<TParent = any, TResult = an
   (parent: TParent, args: MutationToUpdateOrgSettingsArgs, context: any, info: GraphQLResolveInfo): TResult;
 }
 
+export interface MutationToUpdateOrgBrandingArgs {
+  branding: GQLEditableOrgBranding;
+}
+export interface MutationToUpdateOrgBrandingResolver<TParent = any, TResult = any> {
+  (parent: TParent, args: MutationToUpdateOrgBrandingArgs, context: any, info: GraphQLResolveInfo): TResult;
+}
+
 export interface MutationToCreateInviteTokenArgs {
   orgID: string;
 }
@@ -822,6 +872,29 @@ export interface OrgInfoToIdePathsResolver<TParent = any, TResult = any> {
   (parent: TParent, args: {}, context: any, info: GraphQLResolveInfo): TResult;
 }
 
+export interface GQLOrgBrandingTypeResolver<TParent = any> {
+  announcement?: OrgBrandingToAnnouncementResolver<TParent>;
+  announcementURL?: OrgBrandingToAnnouncementURLResolver<TParent>;
+  docsURL?: OrgBrandingToDocsURLResolver<TParent>;
+  supportContact?: OrgBrandingToSupportContactResolver<TParent>;
+}
+
+export interface OrgBrandingToAnnouncementResolver<TParent = any, TResult = any> {
+  (parent: TParent, args: {}, context: any, info: GraphQLResolveInfo): TResult;
+}
+
+export interface OrgBrandingToAnnouncementURLResolver<TParent = any, TResult = any> {
+  (parent: TParent, args: {}, context: any, info: GraphQLResolveInfo): TResult;
+}
+
+export interface OrgBrandingToDocsURLResolver<TParent = any, TResult = any> {
+  (parent: TParent, args: {}, context: any, info: GraphQLResolveInfo): TResult;
+}
+
+export interface OrgBrandingToSupportContactResolver<TParent = any, TResult = any> {
+  (parent: TParent, args: {}, context: any, info: GraphQLResolveInfo): TResult;
+}
+
 export interface GQLUserSettingsTypeResolver<TParent = any> {
   analyticsOptout?: UserSettingsToAnalyticsOptoutResolver<TParent>;
   id?: UserSettingsToIdResolver<TParent>;
@@ -852,6 +925,9 @@ export interface GQLAPIKeyMetadataTypeResolver<TParent = any> {
   id?: APIKeyMetadataToIdResolver<TParent>;
   createdAtMs?: APIKeyMetadataToCreatedAtMsResolver<TParent>;
   desc?: APIKeyMetadataToDescResolver<TParent>;
+  scopes?: APIKeyMetadataToScopesResolver<TParent>;
+  expiresAtMs?: APIKeyMetadataToExpiresAtMsResolver<TParent>;
+  lastUsedAtMs?: APIKeyMetadataToLastUsedAtMsResolver<TParent>;
 }
 
 export interface APIKeyMetadataToIdResolver<TParent = any, TResult = any> {
@@ -866,11 +942,26 @@ export interface APIKeyMetadataToDescResolver<TParent = any, TResult = any> {
   (parent: TParent, args: {}, context: any, info: GraphQLResolveInfo): TResult;
 }
 
+export interface APIKeyMetadataToScopesResolver<TParent = any, TResult = any> {
+  (parent: TParent, args: {}, context: any, info: GraphQLResolveInfo): TResult;
+}
+
+export interface APIKeyMetadataToExpiresAtMsResolver<TParent = any, TResult = any> {
+  (parent: TParent, args: {}, context: any, info: GraphQLResolveInfo): TResult;
+}
+
+export interface APIKeyMetadataToLastUsedAtMsResolver<TParent = any, TResult = any> {
+  (parent: TParent, args: {}, context: any, info: GraphQLResolveInfo): TResult;
+}
+
 export interface GQLAPIKeyTypeResolver<TParent = any> {
   id?: APIKeyToIdResolver<TParent>;
   key?: APIKeyToKeyResolver<TParent>;
   createdAtMs?: APIKeyToCreatedAtMsResolver<TParent>;
   desc?: APIKeyToDescResolver<TParent>;
+  scopes?: APIKeyToScopesResolver<TParent>;
+  expiresAtMs?: APIKeyToExpiresAtMsResolver<TParent>;
+  lastUsedAtMs?: APIKeyToLastUsedAtMsResolver<TParent>;
 }
 
 export interface APIKeyToIdResolver<TParent = any, TResult = any> {
@@ -889,10 +980,25 @@ export interface APIKeyToDescResolver<TParent = any, TResult = any> {
   (parent: TParent, args: {}, context: any, info: GraphQLResolveInfo): TResult;
 }
 
+export interface APIKeyToScopesResolver<TParent = any, TResult = any> {
+  (parent: TParent, args: {}, context: any, info: GraphQLResolveInfo): TResult;
+}
+
+export interface APIKeyToExpiresAtMsResolver<TParent = any, TResult = any> {
+  (parent: TParent, args: {}, context: any, info: GraphQLResolveInfo): TResult;
+}
+
+export interface APIKeyToLastUsedAtMsResolver<TParent = any, TResult = any> {
+  (parent: TParent, args: {}, context: any, info: GraphQLResolveInfo): TResult;
+}
+
 export interface GQLDeploymentKeyMetadataTypeResolver<TParent = any> {
   id?: DeploymentKeyMetadataToIdResolver<TParent>;
   createdAtMs?: DeploymentKeyMetadataToCreatedAtMsResolver<TParent>;
   desc?: DeploymentKeyMetadataToDescResolver<TParent>;
+  expiresAtMs?: DeploymentKeyMetadataToExpiresAtMsResolver<TParent>;
+  lastUsedAtMs?: DeploymentKeyMetadataToLastUsedAtMsResolver<TParent>;
+  viewer?: DeploymentKeyMetadataToViewerResolver<TParent>;
 }
 
 export interface DeploymentKeyMetadataToIdResolver<TParent = any, TResult = any> {
@@ -907,11 +1013,26 @@ export interface DeploymentKeyMetadataToDescResolver<TParent = any, TResult = an
   (parent: TParent, args: {}, context: any, info: GraphQLResolveInfo): TResult;
 }
 
+export interface DeploymentKeyMetadataToExpiresAtMsResolver<TParent = any, TResult = any> {
+  (parent: TParent, args: {}, context: any, info: GraphQLResolveInfo): TResult;
+}
+
+export interface DeploymentKeyMetadataToLastUsedAtMsResolver<TParent = any, TResult = any> {
+  (parent: TParent, args: {}, context: any, info: GraphQLResolveInfo): TResult;
+}
+
+export interface DeploymentKeyMetadataToViewerResolver<TParent = any, TResult = any> {
+  (parent: TParent, args: {}, context: any, info: GraphQLResolveInfo): TResult;
+}
+
 export interface GQLDeploymentKeyTypeResolver<TParent = any> {
   id?: DeploymentKeyToIdResolver<TParent>;
   key?: DeploymentKeyToKeyResolver<TParent>;
   createdAtMs?: DeploymentKeyToCreatedAtMsResolver<TParent>;
   desc?: DeploymentKeyToDescResolver<TParent>;
+  expiresAtMs?: DeploymentKeyToExpiresAtMsResolver<TParent>;
+  lastUsedAtMs?: DeploymentKeyToLastUsedAtMsResolver<TParent>;
+  viewer?: DeploymentKeyToViewerResolver<TParent>;
 }
 
 export interface DeploymentKeyToIdResolver<TParent = any, TResult = any> {
@@ -930,6 +1051,18 @@ export interface DeploymentKeyToDescResolver<TParent = any, TResult = any> {
   (parent: TParent, args: {}, context: any, info: GraphQLResolveInfo): TResult;
 }
 
+export interface DeploymentKeyToExpiresAtMsResolver<TParent = any, TResult = any> {
+  (parent: TParent, args: {}, context: any, info: GraphQLResolveInfo): TResult;
+}
+
+export interface DeploymentKeyToLastUsedAtMsResolver<TParent = any, TResult = any> {
+  (parent: TParent, args: {}, context: any, info: GraphQLResolveInfo): TResult;
+}
+
+export interface DeploymentKeyToViewerResolver<TParent = any, TResult = any> {
+  (parent: TParent, args: {}, context: any, info: GraphQLResolveInfo): TResult;
+}
+
 export interface GQLAutocompleteSuggestionTypeResolver<TParent = any> {
   kind?: AutocompleteSuggestionToKindResolver<TParent>;
   name?: AutocompleteSuggestionToNameResolver<TParent>;
@@ -16,7 +16,7 @@
  * SPDX-License-Identifier: Apache-2.0
  */
 
-import Axios from 'axios';
+import Axios, { AxiosResponse } from 'axios';
 import * as QueryString from 'query-string';
 
 import { SCRIPT_BUNDLE_DEV, SCRIPT_BUNDLE_URLS } from 'app/containers/constants';
@@ -27,6 +27,8 @@ import {
 const OVERRIDE_URLS_KEY = 'px-custom-script-bundle-paths';
 const OLD_OVERRIDE_CORE_KEY = 'px-custom-core-bundle-path';
 const OLD_OVERRIDE_OSS_KEY = 'px-custom-oss-bundle-path';
+// The published scripts in the org's script registry, served in the same format as the other bundles.
+const REGISTRY_BUNDLE_URL = '/api/script-registry/bundle.json';
 
 export interface Script {
   id: string;
@@ -87,6 +89,11 @@ function getBundleUrls(): { urls: string[], isDev: boolean } {
 export function GetPxScripts(orgID: string, orgName: string): Promise<Script[]> {
   const { urls, isDev } = getBundleUrls();
   const fetchPromises = urls.map(url => Axios({ method: 'get', url }));
+  if (orgID) {
+    // The registry is optional: if it can't be reached, the public scripts are still available.
+    fetchPromises.push(Axios({ method: 'get', url: REGISTRY_BUNDLE_URL })
+      .catch(() => ({ data: { scripts: {} } }) as AxiosResponse));
+  }
   return Promise.all(fetchPromises)
     .then((response) => {
       const scripts: Script[] = [];
@@ -49,11 +49,11 @@ func init() {
 func availableArtifacts(artifactName string) []vpb.ArtifactType {
 	switch {
 	case artifactName == "cli":
-		return []vpb.ArtifactType{vpb.AT_LINUX_AMD64, vpb.AT_DARWIN_AMD64}
+		return []vpb.ArtifactType{vpb.AT_LINUX_AMD64, vpb.AT_DARWIN_AMD64, vpb.AT_DARWIN_ARM64}
 	case artifactName == "vizier":
-		return []vpb.ArtifactType{vpb.AT_CONTAINER_SET_LINUX_AMD64, vpb.AT_CONTAINER_SET_YAMLS, vpb.AT_CONTAINER_SET_TEMPLATE_YAMLS}
+		return []vpb.ArtifactType{vpb.AT_CONTAINER_SET_LINUX_AMD64, vpb.AT_CONTAINER_SET_LINUX_ARM64, vpb.AT_CONTAINER_SET_YAMLS, vpb.AT_CONTAINER_SET_TEMPLATE_YAMLS}
 	case artifactName == "operator":
-		return []vpb.ArtifactType{vpb.AT_CONTAINER_SET_LINUX_AMD64, vpb.AT_CONTAINER_SET_TEMPLATE_YAMLS}
+		return []vpb.ArtifactType{vpb.AT_CONTAINER_SET_LINUX_AMD64, vpb.AT_CONTAINER_SET_LINUX_ARM64, vpb.AT_CONTAINER_SET_TEMPLATE_YAMLS}
 	default:
 		panic(fmt.Sprintf("Unknown artifact type: %s", artifactName))
 	}
@@ -80,6 +80,7 @@ func availableArtifactMirrors(ghRepo string, component string, version string, m
 	switch component {
 	case "cli":
 		artifacts["cli_darwin_amd64"] = vpb.AT_DARWIN_AMD64
+		artifacts["cli_darwin_arm64"] = vpb.AT_DARWIN_ARM64
 		artifacts["cli_linux_amd64"] = vpb.AT_LINUX_AMD64
 	case "vizier":
 		artifacts["vizier_yamls.tar"] = vpb.AT_CONTAINER_SET_YAMLS
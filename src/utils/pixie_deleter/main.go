@@ -19,6 +19,7 @@
 package main
 
 import (
+	"context"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -52,6 +53,7 @@ func main() {
 	}
 	clientset := k8s.GetClientset(kubeConfig)
 
+	ctx := context.Background()
 	od := k8s.ObjectDeleter{
 		Namespace:  ns,
 		Clientset:  clientset,
@@ -75,7 +77,7 @@ func main() {
 		},
 	})
 
-	_, _ = od.DeleteByLabel(vzNameLabelSelector)
+	_, _ = od.DeleteByLabel(ctx, vzNameLabelSelector)
 
 	// Delete clusterrole objects.
 	labelSelector := metav1.FormatLabelSelector(&metav1.LabelSelector{
@@ -88,5 +90,5 @@ func main() {
 		},
 	})
 
-	_, _ = od.DeleteByLabel(labelSelector)
+	_, _ = od.DeleteByLabel(ctx, labelSelector)
 }
@@ -19,6 +19,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -54,6 +55,8 @@ func init() {
 	pflag.String("custom_labels", "", "Custom labels that should be attached to the vizier resources")
 	pflag.String("custom_annotations", "", "Custom annotations that should be attached to the vizier resources")
 	pflag.String("pem_memory_limit", "", "The memory limit to apply to the PEMS")
+	pflag.String("artifact_keyring", "", "Path to an armored GPG public keyring that the downloaded YAMLs must be signed by")
+	pflag.Bool("skip_artifact_verification", false, "Use the downloaded YAMLs without verifying their checksum and signature")
 }
 
 func getCloudClientConnection(cloudAddr string) (*grpc.ClientConn, error) {
@@ -114,7 +117,11 @@ func main() {
 	if err != nil {
 		log.WithError(err).Fatal("Failed to get cloud connection")
 	}
-	templatedYAMLs, err := artifacts.FetchVizierTemplates(conn, token, version)
+	verifyOpts, err := artifacts.LoadVerificationOptions(viper.GetString("artifact_keyring"), viper.GetBool("skip_artifact_verification"))
+	if err != nil {
+		log.WithError(err).Fatal("Failed to load artifact signing keyring")
+	}
+	templatedYAMLs, err := artifacts.FetchVizierTemplates(conn, token, version, verifyOpts)
 	if err != nil {
 		log.WithError(err).Fatal("Failed to download YAMLs")
 	}
@@ -148,6 +155,7 @@ func main() {
 	}
 
 	// Delete everything but updater dependencies + bootstrap dependencies.
+	ctx := context.Background()
 	od := k8s.ObjectDeleter{
 		Namespace:  ns,
 		Clientset:  clientset,
@@ -155,7 +163,7 @@ func main() {
 		Timeout:    deleteTimeout,
 	}
 
-	_, err = od.DeleteByLabel("component=vizier,vizier-updater-dep!=true,vizier-bootstrap!=true")
+	_, err = od.DeleteByLabel(ctx, "component=vizier,vizier-updater-dep!=true,vizier-bootstrap!=true")
 	if err != nil {
 		if isTimeoutError(err) {
 			log.WithError(err).Error("Old components taking longer to terminate than timeout")
@@ -167,7 +175,7 @@ func main() {
 	}
 
 	// Delete cronjob.
-	_, err = od.DeleteByLabel("app=pl-monitoring", "CronJob")
+	_, err = od.DeleteByLabel(ctx, "app=pl-monitoring", "CronJob")
 	if err != nil {
 		if isTimeoutError(err) {
 			log.WithError(err).Error("Old components taking longer to terminate than timeout")
@@ -175,7 +183,7 @@ func main() {
 	}
 
 	// Delete StatefulSet, if present.
-	_, err = od.DeleteByLabel("app=pl-monitoring,name!=pl-nats,name!=pl-etcd", "StatefulSet")
+	_, err = od.DeleteByLabel(ctx, "app=pl-monitoring,name!=pl-nats,name!=pl-etcd", "StatefulSet")
 	if err != nil {
 		if isTimeoutError(err) {
 			log.WithError(err).Error("Existing etcd taking longer to terminate than timeout")
@@ -183,7 +191,7 @@ func main() {
 			log.WithError(err).Error("Could not delete existing etc")
 		}
 	}
-	_, err = od.DeleteByLabel("app=pl-monitoring", "PersistentVolumeClaim")
+	_, err = od.DeleteByLabel(ctx, "app=pl-monitoring", "PersistentVolumeClaim")
 	if err != nil {
 		if isTimeoutError(err) {
 			log.WithError(err).Error("Existing etcd pvc taking longer to terminate than timeout")
@@ -198,18 +206,18 @@ func main() {
 		// This deletes the pl-etcd instance and clears out any existing etcd data.
 		// Deleting the etcd instance does not delete the etcd-operator, but the operator is
 		// robust to a new etcd instance starting up.
-		_, err = od.DeleteByLabel("app=pl-monitoring", "etcdclusters.etcd.database.coreos.com")
+		_, err = od.DeleteByLabel(ctx, "app=pl-monitoring", "etcdclusters.etcd.database.coreos.com")
 		if err != nil {
 			log.WithError(err).Error("Failed to delete old etcd")
 		}
 
 		// Delete etcd operator and pl-etcd pods in case there is some issue with
 		// the underlying pods. This is usually unncessary if we just want to clear out etcd data.
-		_, err = od.DeleteByLabel("name=etcd-operator", "Pod")
+		_, err = od.DeleteByLabel(ctx, "name=etcd-operator", "Pod")
 		if err != nil {
 			log.WithError(err).Error("Failed to delete old etcd operator")
 		}
-		_, err = od.DeleteByLabel("app=etcd", "Pod")
+		_, err = od.DeleteByLabel(ctx, "app=etcd", "Pod")
 		if err != nil {
 			log.WithError(err).Error("Failed to delete old pl-etcd pods")
 		}
@@ -229,7 +237,7 @@ func main() {
 	// Bounce the cloud-connector pod so that it deletes the updater job. This is only necessary
 	// when upgrading to the same Vizier version, because the cloud-connector pod should normally restart
 	// when applying YAMLs above.
-	_, err = od.DeleteByLabel("name=vizier-cloud-connector", "Pod")
+	_, err = od.DeleteByLabel(ctx, "name=vizier-cloud-connector", "Pod")
 	if err != nil {
 		log.WithError(err).Error("Failed to bounce cloud-connector")
 	}
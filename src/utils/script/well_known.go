@@ -21,6 +21,7 @@ package script
 // This file has a list of well known scripts, that can be referenced
 // from various part of the CLI.
 const (
-	AgentStatusScript  = "px/agent_status"
-	ServiceStatsScript = "px/service_stats"
+	AgentStatusScript      = "px/agent_status"
+	NodeCapabilitiesScript = "px/node_capabilities"
+	ServiceStatsScript     = "px/service_stats"
 )
@@ -0,0 +1,283 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package artifacts
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	tarutils "px.dev/pixie/src/utils/shared/tar"
+	"px.dev/pixie/src/utils/shared/yamls"
+)
+
+const (
+	bundleManifestFile   = "manifest.json"
+	bundleImagesFile     = "images.txt"
+	vizierArtifactName   = "vizier"
+	operatorArtifactName = "operator"
+)
+
+// ErrBundleInvalid is returned when a bundle is missing files or its manifest can't be parsed.
+var ErrBundleInvalid = errors.New("invalid artifact bundle")
+
+// BundleArtifact is a single artifact stored in an offline bundle.
+type BundleArtifact struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// File is the path of the artifact in the bundle. Its detached signature, if any, is stored at File + ".asc".
+	File   string `json:"file"`
+	SHA256 string `json:"sha256"`
+}
+
+// BundleManifest describes the contents of an offline bundle.
+type BundleManifest struct {
+	VizierVersion   string            `json:"vizierVersion"`
+	OperatorVersion string            `json:"operatorVersion"`
+	Artifacts       []*BundleArtifact `json:"artifacts"`
+	// Images are the default container images deployed by the bundle. They must be mirrored into a registry
+	// that the cluster can pull from, which is then passed to deploy with --registry.
+	Images []string `json:"images"`
+}
+
+// Bundle is a set of Pixie artifacts that can be deployed without contacting the artifact tracker.
+type Bundle struct {
+	Manifest *BundleManifest
+	// files maps the path in the bundle to the file contents.
+	files map[string][]byte
+}
+
+// DownloadBundle downloads and verifies the Vizier and operator templates for the given versions, and
+// packages them into a bundle.
+func DownloadBundle(conn *grpc.ClientConn, authToken, vizierVersion, operatorVersion string, opts *VerificationOptions) (*Bundle, error) {
+	b := &Bundle{
+		Manifest: &BundleManifest{
+			VizierVersion:   vizierVersion,
+			OperatorVersion: operatorVersion,
+		},
+		files: make(map[string][]byte),
+	}
+
+	for _, a := range []struct{ name, version string }{
+		{vizierArtifactName, vizierVersion},
+		{operatorArtifactName, operatorVersion},
+	} {
+		resp, contents, err := downloadTemplates(conn, authToken, a.name, a.version, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download %s templates: %w", a.name, err)
+		}
+		b.addArtifact(a.name, a.version, contents, resp.SHA256, resp.Signature)
+	}
+
+	images, err := b.listImages()
+	if err != nil {
+		return nil, err
+	}
+	b.Manifest.Images = images
+	return b, nil
+}
+
+func downloadTemplates(conn *grpc.ClientConn, authToken, name, versionStr string, opts *VerificationOptions) (*cloudpb.GetDownloadLinkResponse, []byte, error) {
+	client := cloudpb.NewArtifactTrackerClient(conn)
+	req := &cloudpb.GetDownloadLinkRequest{
+		ArtifactName: name,
+		VersionStr:   versionStr,
+		ArtifactType: cloudpb.AT_CONTAINER_SET_TEMPLATE_YAMLS,
+	}
+	ctx := context.Background()
+	if authToken != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", fmt.Sprintf("bearer %s", authToken))
+	}
+
+	resp, err := client.GetDownloadLink(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	contents, err := downloadVerifiedArtifact(resp, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, contents, nil
+}
+
+func (b *Bundle) addArtifact(name, version string, contents []byte, sha256, signature string) {
+	file := fmt.Sprintf("%s_template_yamls.tar", name)
+	b.files[file] = contents
+	if signature != "" {
+		b.files[file+".asc"] = []byte(signature)
+	}
+	b.Manifest.Artifacts = append(b.Manifest.Artifacts, &BundleArtifact{
+		Name:    name,
+		Version: version,
+		File:    file,
+		SHA256:  strings.TrimSpace(sha256),
+	})
+}
+
+func (b *Bundle) artifact(name string) ([]byte, error) {
+	for _, a := range b.Manifest.Artifacts {
+		if a.Name == name {
+			return b.files[a.File], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: bundle has no %s artifact", ErrBundleInvalid, name)
+}
+
+// VizierTemplates returns the Vizier templates in the bundle.
+func (b *Bundle) VizierTemplates() ([]*yamls.YAMLFile, error) {
+	contents, err := b.artifact(vizierArtifactName)
+	if err != nil {
+		return nil, err
+	}
+	return vizierTemplatesFromTar(bytes.NewReader(contents))
+}
+
+// OperatorTemplates returns the operator templates in the bundle.
+func (b *Bundle) OperatorTemplates() ([]*yamls.YAMLFile, error) {
+	contents, err := b.artifact(operatorArtifactName)
+	if err != nil {
+		return nil, err
+	}
+	return operatorTemplatesFromTar(bytes.NewReader(contents))
+}
+
+var imageRe = regexp.MustCompile(`(?m)^\s*(?:-\s*)?image:\s*(.+)$`)
+
+// listImages returns the default images referenced by the templates in the bundle. Image
+// templates select a custom registry only when one is set, so rendering them without values
+// gives the default image.
+func (b *Bundle) listImages() ([]string, error) {
+	vzYAMLs, err := b.VizierTemplates()
+	if err != nil {
+		return nil, err
+	}
+	opYAMLs, err := b.OperatorTemplates()
+	if err != nil {
+		return nil, err
+	}
+
+	images := make(map[string]bool)
+	for _, y := range append(vzYAMLs, opYAMLs...) {
+		for _, m := range imageRe.FindAllStringSubmatch(y.YAML, -1) {
+			tmpl, err := template.New("image").Parse(strings.Trim(strings.TrimSpace(m[1]), `"'`))
+			if err != nil {
+				continue
+			}
+			var image bytes.Buffer
+			if err := tmpl.Execute(&image, map[string]interface{}{"Values": map[string]interface{}{}}); err != nil {
+				continue
+			}
+			if image.Len() > 0 {
+				images[image.String()] = true
+			}
+		}
+	}
+
+	var imageList []string
+	for image := range images {
+		imageList = append(imageList, image)
+	}
+	sort.Strings(imageList)
+	return imageList, nil
+}
+
+// Write writes the bundle as a tar file.
+func (b *Bundle) Write(w io.Writer) error {
+	manifest, err := json.MarshalIndent(b.Manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	files := map[string][]byte{
+		bundleManifestFile: manifest,
+		bundleImagesFile:   []byte(strings.Join(b.Manifest.Images, "\n") + "\n"),
+	}
+	for name, contents := range b.files {
+		files[name] = contents
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tar.NewWriter(w)
+	for _, name := range names {
+		hdr := &tar.Header{
+			Name: name,
+			Mode: 0644,
+			Size: int64(len(files[name])),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(files[name]); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// ReadBundle reads a bundle written by Write, and verifies each artifact in it with the given options.
+func ReadBundle(r io.Reader, opts *VerificationOptions) (*Bundle, error) {
+	fileMap, err := tarutils.ReadTarFileFromReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrBundleInvalid, err)
+	}
+
+	manifest, ok := fileMap[bundleManifestFile]
+	if !ok {
+		return nil, fmt.Errorf("%w: missing %s", ErrBundleInvalid, bundleManifestFile)
+	}
+	b := &Bundle{
+		Manifest: &BundleManifest{},
+		files:    make(map[string][]byte),
+	}
+	if err := json.Unmarshal([]byte(manifest), b.Manifest); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrBundleInvalid, err)
+	}
+
+	for _, a := range b.Manifest.Artifacts {
+		contents, ok := fileMap[a.File]
+		if !ok {
+			return nil, fmt.Errorf("%w: missing %s", ErrBundleInvalid, a.File)
+		}
+		signature := fileMap[a.File+".asc"]
+		if err := opts.Verify([]byte(contents), a.SHA256, signature); err != nil {
+			return nil, fmt.Errorf("%s artifact: %w", a.Name, err)
+		}
+		b.files[a.File] = []byte(contents)
+		if signature != "" {
+			b.files[a.File+".asc"] = []byte(signature)
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,176 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package artifacts_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/utils/shared/artifacts"
+)
+
+const vizierPEMTemplate = `apiVersion: apps/v1
+kind: DaemonSet
+spec:
+  template:
+    spec:
+      containers:
+      - name: pem
+        image: {{ if .Values.registry }}{{ .Values.registry }}/gcr.io-pixie-oss-pixie-prod-vizier-pem_image:0.14.2{{else}}gcr.io/pixie-oss/pixie-prod/vizier-pem_image:0.14.2{{end}}
+`
+
+const operatorCatalogTemplate = `apiVersion: operators.coreos.com/v1alpha1
+kind: CatalogSource
+spec:
+  image: {{ if .Values.registry }}{{ .Values.registry }}/gcr.io-pixie-oss-pixie-prod-operator-bundle_index:0.0.1{{ else }}gcr.io/pixie-oss/pixie-prod/operator/bundle_index:0.0.1{{ end }}
+`
+
+func makeTar(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, contents := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(contents))}))
+		_, err := tw.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+type fakeArtifactTracker struct {
+	cloudpb.UnimplementedArtifactTrackerServer
+	url      string
+	checksum map[string]string
+}
+
+func (f *fakeArtifactTracker) GetDownloadLink(ctx context.Context, req *cloudpb.GetDownloadLinkRequest) (*cloudpb.GetDownloadLinkResponse, error) {
+	return &cloudpb.GetDownloadLinkResponse{
+		Url:    f.url + "/" + req.ArtifactName + "/" + req.VersionStr,
+		SHA256: f.checksum[req.ArtifactName],
+	}, nil
+}
+
+func TestDownloadBundle(t *testing.T) {
+	files := map[string][]byte{
+		"/vizier/0.14.2": makeTar(t, map[string]string{
+			"pixie_yamls/00_namespace.yaml": "apiVersion: v1\nkind: Namespace\n",
+			"pixie_yamls/01_pem.yaml":       vizierPEMTemplate,
+		}),
+		"/operator/0.0.30": makeTar(t, map[string]string{
+			"pixie_yamls/02_catalog.yaml":      operatorCatalogTemplate,
+			"pixie_yamls/crds/vizier_crd.yaml": "kind: CustomResourceDefinition\n",
+		}),
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write(files[r.URL.Path])
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	checksum := func(b []byte) string {
+		s := sha256.Sum256(b)
+		return hex.EncodeToString(s[:])
+	}
+	at := &fakeArtifactTracker{
+		url: ts.URL,
+		checksum: map[string]string{
+			"vizier":   checksum(files["/vizier/0.14.2"]),
+			"operator": checksum(files["/operator/0.0.30"]),
+		},
+	}
+
+	lis := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer()
+	cloudpb.RegisterArtifactTrackerServer(s, at)
+	go func() { _ = s.Serve(lis) }()
+	defer s.Stop()
+	conn, err := grpc.Dial("bufnet", grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	}), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	b, err := artifacts.DownloadBundle(conn, "token", "0.14.2", "0.0.30", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"gcr.io/pixie-oss/pixie-prod/operator/bundle_index:0.0.1",
+		"gcr.io/pixie-oss/pixie-prod/vizier-pem_image:0.14.2",
+	}, b.Manifest.Images)
+
+	var out bytes.Buffer
+	require.NoError(t, b.Write(&out))
+
+	read, err := artifacts.ReadBundle(bytes.NewReader(out.Bytes()), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "0.14.2", read.Manifest.VizierVersion)
+	assert.Equal(t, "0.0.30", read.Manifest.OperatorVersion)
+
+	vzYAMLs, err := read.VizierTemplates()
+	require.NoError(t, err)
+	require.Len(t, vzYAMLs, 2)
+	assert.Equal(t, "namespace", vzYAMLs[0].Name)
+	assert.Equal(t, "pem", vzYAMLs[1].Name)
+
+	opYAMLs, err := read.OperatorTemplates()
+	require.NoError(t, err)
+	require.Len(t, opYAMLs, 2)
+	assert.Equal(t, "catalog", opYAMLs[0].Name)
+	assert.Equal(t, "vizier_crd", opYAMLs[1].Name)
+
+	// A bundle whose artifacts were modified after download should be rejected.
+	tampered := makeTar(t, map[string]string{
+		"manifest.json":               string(mustReadFile(t, out.Bytes(), "manifest.json")),
+		"vizier_template_yamls.tar":   "tampered",
+		"operator_template_yamls.tar": string(files["/operator/0.0.30"]),
+	})
+	_, err = artifacts.ReadBundle(bytes.NewReader(tampered), nil)
+	assert.ErrorIs(t, err, artifacts.ErrChecksumMismatch)
+
+	_, err = artifacts.ReadBundle(strings.NewReader(""), nil)
+	assert.ErrorIs(t, err, artifacts.ErrBundleInvalid)
+}
+
+func mustReadFile(t *testing.T, tarball []byte, name string) []byte {
+	tr := tar.NewReader(bytes.NewReader(tarball))
+	for {
+		hdr, err := tr.Next()
+		require.NoError(t, err)
+		if hdr.Name == name {
+			var buf bytes.Buffer
+			_, err := buf.ReadFrom(tr)
+			require.NoError(t, err)
+			return buf.Bytes()
+		}
+	}
+}
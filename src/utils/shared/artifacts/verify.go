@@ -0,0 +1,114 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package artifacts
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+)
+
+var (
+	// ErrChecksumMissing is returned when the artifact tracker did not return a checksum for the artifact.
+	ErrChecksumMissing = errors.New("artifact has no sha256 checksum")
+	// ErrChecksumMismatch is returned when the downloaded artifact does not match its checksum.
+	ErrChecksumMismatch = errors.New("artifact does not match its sha256 checksum")
+	// ErrSignatureMissing is returned when a keyring is configured, but the artifact is unsigned.
+	ErrSignatureMissing = errors.New("artifact is not signed")
+	// ErrSignatureInvalid is returned when the artifact signature was not made by a key in the keyring.
+	ErrSignatureInvalid = errors.New("artifact signature is invalid")
+)
+
+// IsVerificationError returns whether the error is due to the downloaded artifact failing verification.
+func IsVerificationError(err error) bool {
+	return errors.Is(err, ErrChecksumMissing) || errors.Is(err, ErrChecksumMismatch) ||
+		errors.Is(err, ErrSignatureMissing) || errors.Is(err, ErrSignatureInvalid)
+}
+
+// VerificationOptions controls how downloaded artifacts are checked before they are used.
+// The zero value requires a valid sha256 checksum, and does not check signatures.
+type VerificationOptions struct {
+	// SkipVerification disables all checks on the downloaded artifact.
+	SkipVerification bool
+	// Keyring is an armored GPG public keyring. If set, artifacts must carry a detached signature
+	// made by one of its keys.
+	Keyring []byte
+}
+
+// LoadVerificationOptions creates the options from the given keyring file, which may be empty.
+func LoadVerificationOptions(keyringPath string, skip bool) (*VerificationOptions, error) {
+	opts := &VerificationOptions{SkipVerification: skip}
+	if keyringPath == "" {
+		return opts, nil
+	}
+	keyring, err := os.ReadFile(keyringPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact signing keyring: %w", err)
+	}
+	if _, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(keyring)); err != nil {
+		return nil, fmt.Errorf("failed to parse artifact signing keyring: %w", err)
+	}
+	opts.Keyring = keyring
+	return opts, nil
+}
+
+// Verify checks the artifact against the sha256 checksum and signature returned by the artifact tracker.
+// A nil VerificationOptions behaves like the zero value.
+func (o *VerificationOptions) Verify(artifact []byte, sha256Hex, signature string) error {
+	if o == nil {
+		o = &VerificationOptions{}
+	}
+	if o.SkipVerification {
+		return nil
+	}
+
+	sha256Hex = strings.TrimSpace(sha256Hex)
+	if sha256Hex == "" {
+		return ErrChecksumMissing
+	}
+	expected, err := hex.DecodeString(sha256Hex)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrChecksumMismatch, err)
+	}
+	actual := sha256.Sum256(artifact)
+	if !bytes.Equal(expected, actual[:]) {
+		return ErrChecksumMismatch
+	}
+
+	if len(o.Keyring) == 0 {
+		return nil
+	}
+	if signature == "" {
+		return ErrSignatureMissing
+	}
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(o.Keyring))
+	if err != nil {
+		return err
+	}
+	if _, err := openpgp.CheckArmoredDetachedSignature(keyring, bytes.NewReader(artifact), strings.NewReader(signature)); err != nil {
+		return fmt.Errorf("%w: %s", ErrSignatureInvalid, err)
+	}
+	return nil
+}
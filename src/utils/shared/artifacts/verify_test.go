@@ -0,0 +1,130 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package artifacts_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+
+	"px.dev/pixie/src/utils/shared/artifacts"
+)
+
+func newSigner(t *testing.T) (*openpgp.Entity, []byte) {
+	entity, err := openpgp.NewEntity("buildbot", "", "buildbot@px.dev", nil)
+	require.NoError(t, err)
+
+	var keyring bytes.Buffer
+	w, err := armor.Encode(&keyring, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.Serialize(w))
+	require.NoError(t, w.Close())
+	return entity, keyring.Bytes()
+}
+
+func sign(t *testing.T, entity *openpgp.Entity, artifact []byte) string {
+	var sig bytes.Buffer
+	require.NoError(t, openpgp.ArmoredDetachSign(&sig, entity, bytes.NewReader(artifact), nil))
+	return sig.String()
+}
+
+func TestVerificationOptions_Verify(t *testing.T) {
+	artifact := []byte("vizier yamls")
+	digest := sha256.Sum256(artifact)
+	checksum := hex.EncodeToString(digest[:])
+
+	signer, keyring := newSigner(t)
+	other, _ := newSigner(t)
+	signature := sign(t, signer, artifact)
+
+	tests := []struct {
+		name        string
+		opts        *artifacts.VerificationOptions
+		artifact    []byte
+		sha256      string
+		signature   string
+		expectedErr error
+	}{
+		{
+			name:     "nil options checks the checksum",
+			opts:     nil,
+			artifact: artifact,
+			sha256:   checksum,
+		},
+		{
+			name:        "missing checksum",
+			opts:        &artifacts.VerificationOptions{},
+			artifact:    artifact,
+			expectedErr: artifacts.ErrChecksumMissing,
+		},
+		{
+			name:        "checksum mismatch",
+			opts:        &artifacts.VerificationOptions{},
+			artifact:    []byte("tampered yamls"),
+			sha256:      checksum,
+			expectedErr: artifacts.ErrChecksumMismatch,
+		},
+		{
+			name:     "skip verification",
+			opts:     &artifacts.VerificationOptions{SkipVerification: true},
+			artifact: []byte("tampered yamls"),
+			sha256:   checksum,
+		},
+		{
+			name:      "valid signature",
+			opts:      &artifacts.VerificationOptions{Keyring: keyring},
+			artifact:  artifact,
+			sha256:    checksum,
+			signature: signature,
+		},
+		{
+			name:        "missing signature",
+			opts:        &artifacts.VerificationOptions{Keyring: keyring},
+			artifact:    artifact,
+			sha256:      checksum,
+			expectedErr: artifacts.ErrSignatureMissing,
+		},
+		{
+			name:        "signed by unknown key",
+			opts:        &artifacts.VerificationOptions{Keyring: keyring},
+			artifact:    artifact,
+			sha256:      checksum,
+			signature:   sign(t, other, artifact),
+			expectedErr: artifacts.ErrSignatureInvalid,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.opts.Verify(test.artifact, test.sha256, test.signature)
+			if test.expectedErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, test.expectedErr)
+			assert.True(t, artifacts.IsVerificationError(err))
+		})
+	}
+}
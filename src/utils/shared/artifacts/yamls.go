@@ -19,6 +19,7 @@
 package artifacts
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -43,7 +44,25 @@ func downloadFile(url string) (io.ReadCloser, error) {
 	return resp.Body, nil
 }
 
-func downloadVizierYAMLs(conn *grpc.ClientConn, authToken, versionStr string, templated bool) (io.ReadCloser, error) {
+// downloadVerifiedArtifact downloads the artifact from the link and verifies it before returning its contents.
+func downloadVerifiedArtifact(resp *cloudpb.GetDownloadLinkResponse, opts *VerificationOptions) ([]byte, error) {
+	reader, err := downloadFile(resp.Url)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	artifact, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.Verify(artifact, resp.SHA256, resp.Signature); err != nil {
+		return nil, err
+	}
+	return artifact, nil
+}
+
+func downloadVizierYAMLs(conn *grpc.ClientConn, authToken, versionStr string, templated bool, opts *VerificationOptions) (io.Reader, error) {
 	client := cloudpb.NewArtifactTrackerClient(conn)
 	at := cloudpb.AT_CONTAINER_SET_YAMLS
 	if templated {
@@ -63,17 +82,20 @@ func downloadVizierYAMLs(conn *grpc.ClientConn, authToken, versionStr string, te
 		return nil, err
 	}
 
-	return downloadFile(resp.Url)
+	artifact, err := downloadVerifiedArtifact(resp, opts)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(artifact), nil
 }
 
 // FetchVizierYAMLMap fetches Vizier YAML files and write to a map <fname>:<yaml string>.
-func FetchVizierYAMLMap(conn *grpc.ClientConn, authToken, versionStr string) (map[string]string, error) {
-	reader, err := downloadVizierYAMLs(conn, authToken, versionStr, false)
-
+// The download is verified with the given options before it is used.
+func FetchVizierYAMLMap(conn *grpc.ClientConn, authToken, versionStr string, opts *VerificationOptions) (map[string]string, error) {
+	reader, err := downloadVizierYAMLs(conn, authToken, versionStr, false, opts)
 	if err != nil {
 		return nil, err
 	}
-	defer reader.Close()
 
 	yamlMap, err := tar.ReadTarFileFromReader(reader)
 	if err != nil {
@@ -82,16 +104,40 @@ func FetchVizierYAMLMap(conn *grpc.ClientConn, authToken, versionStr string) (ma
 	return yamlMap, nil
 }
 
-// FetchVizierTemplates fetches the Vizier templates for the given version.
-func FetchVizierTemplates(conn *grpc.ClientConn, authToken, versionStr string) ([]*yamls.YAMLFile, error) {
-	reader, err := downloadVizierYAMLs(conn, authToken, versionStr, true)
+// FetchVizierTemplates fetches and verifies the Vizier templates for the given version.
+func FetchVizierTemplates(conn *grpc.ClientConn, authToken, versionStr string, opts *VerificationOptions) ([]*yamls.YAMLFile, error) {
+	reader, err := downloadVizierYAMLs(conn, authToken, versionStr, true, opts)
+	if err != nil {
+		return nil, err
+	}
+	return vizierTemplatesFromTar(reader)
+}
+
+// FetchOperatorTemplates fetches and verifies the operator templates for the given version.
+func FetchOperatorTemplates(conn *grpc.ClientConn, versionStr string, opts *VerificationOptions) ([]*yamls.YAMLFile, error) {
+	client := cloudpb.NewArtifactTrackerClient(conn)
+
+	req := &cloudpb.GetDownloadLinkRequest{
+		ArtifactName: "operator",
+		VersionStr:   versionStr,
+		ArtifactType: cloudpb.AT_CONTAINER_SET_TEMPLATE_YAMLS,
+	}
+
+	resp, err := client.GetDownloadLink(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
 
+	artifact, err := downloadVerifiedArtifact(resp, opts)
 	if err != nil {
 		return nil, err
 	}
-	defer reader.Close()
+	return operatorTemplatesFromTar(bytes.NewReader(artifact))
+}
 
-	yamlMap, err := tar.ReadTarFileFromReader(reader)
+// vizierTemplatesFromTar reads the Vizier templates out of the template YAMLs tar.
+func vizierTemplatesFromTar(r io.Reader) ([]*yamls.YAMLFile, error) {
+	yamlMap, err := tar.ReadTarFileFromReader(r)
 	if err != nil {
 		return nil, err
 	}
@@ -124,28 +170,9 @@ func FetchVizierTemplates(conn *grpc.ClientConn, authToken, versionStr string) (
 	return yamlFiles, nil
 }
 
-// FetchOperatorTemplates fetches the operator templates for the given version.
-func FetchOperatorTemplates(conn *grpc.ClientConn, versionStr string) ([]*yamls.YAMLFile, error) {
-	client := cloudpb.NewArtifactTrackerClient(conn)
-
-	req := &cloudpb.GetDownloadLinkRequest{
-		ArtifactName: "operator",
-		VersionStr:   versionStr,
-		ArtifactType: cloudpb.AT_CONTAINER_SET_TEMPLATE_YAMLS,
-	}
-
-	resp, err := client.GetDownloadLink(context.Background(), req)
-	if err != nil {
-		return nil, err
-	}
-
-	reader, err := downloadFile(resp.Url)
-	if err != nil {
-		return nil, err
-	}
-	defer reader.Close()
-
-	yamlMap, err := tar.ReadTarFileFromReader(reader)
+// operatorTemplatesFromTar reads the operator templates out of the template YAMLs tar.
+func operatorTemplatesFromTar(r io.Reader) ([]*yamls.YAMLFile, error) {
+	yamlMap, err := tar.ReadTarFileFromReader(r)
 	if err != nil {
 		return nil, err
 	}
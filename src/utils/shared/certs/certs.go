@@ -29,6 +29,8 @@ import (
 	"strings"
 	"time"
 
+	v1 "k8s.io/api/core/v1"
+
 	"px.dev/pixie/src/utils/shared/k8s"
 )
 
@@ -275,3 +277,32 @@ func GenerateVizierCertYAMLs(namespace string) (string, error) {
 
 	return "---\n" + strings.Join(yamls, "\n---\n"), nil
 }
+
+// GenerateCloudProxyCertYAML generates the yaml for a self-signed cert for the cloud proxy, which
+// serves the given domain.
+func GenerateCloudProxyCertYAML(namespace, domain string) (string, error) {
+	cg, err := newCertGenerator()
+	if err != nil {
+		return "", err
+	}
+
+	cert, key, err := cg.generateSignedCertAndKey([]string{domain, "*." + domain})
+	if err != nil {
+		return "", err
+	}
+
+	proxyCert, err := k8s.CreateGenericSecretFromLiterals(namespace, "cloud-proxy-tls-certs", map[string]string{
+		"tls.key": string(key),
+		"tls.crt": string(cert),
+	})
+	if err != nil {
+		return "", err
+	}
+	proxyCert.Type = v1.SecretTypeTLS
+	yaml, err := k8s.ConvertResourceToYAML(proxyCert)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("---\n%s\n", yaml), nil
+}
@@ -0,0 +1,281 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// applyPollInterval is how often the ObjectApplier checks whether applied objects are ready.
+const applyPollInterval = time.Second
+
+// defaultFieldManager is the field manager used for server-side apply when none is specified.
+const defaultFieldManager = "px"
+
+// ObjectApplier has methods to apply K8s objects using server-side apply, and wait for them to become ready. It is
+// the counterpart of the ObjectDeleter.
+type ObjectApplier struct {
+	// Namespace is the namespace that namespaced objects are applied to. If empty, the namespace from each object is
+	// used.
+	Namespace  string
+	Clientset  *kubernetes.Clientset
+	RestConfig *rest.Config
+	// FieldManager is the name of the manager that owns the applied fields. Defaults to "px".
+	FieldManager string
+	// Force makes the apply take ownership of fields that are owned by other field managers, instead of failing
+	// with a conflict.
+	Force bool
+	// Wait makes the ObjectApplier block until the applied Deployments, DaemonSets and Jobs are ready.
+	Wait bool
+	// Timeout bounds how long to wait for the applied objects to be ready. If zero, the wait is only bounded by the
+	// context.
+	Timeout time.Duration
+	// Progress, if set, is notified as the applied objects become ready.
+	Progress ApplyProgressHandler
+
+	rcg           *restClientGetter
+	restMapper    meta.RESTMapper
+	dynamicClient dynamic.Interface
+}
+
+// ApplyProgressHandler is notified of the progress of the ObjectApplier while it waits for applied objects to be
+// ready. Its methods are called from the goroutine that is applying.
+type ApplyProgressHandler interface {
+	// OnReady is called once an applied object is ready.
+	OnReady(obj ObjectReference)
+	// OnWaiting is called each time the applied objects are checked, with the number that are not ready yet.
+	OnWaiting(remaining int)
+}
+
+// appliedObject is an object that was applied, along with the client to fetch it again.
+type appliedObject struct {
+	ref    ObjectReference
+	client dynamic.ResourceInterface
+}
+
+// ApplyYAML applies all of the objects in the given YAML. Returns the applied objects, in order.
+func (o *ObjectApplier) ApplyYAML(ctx context.Context, yamlFile io.Reader) ([]ObjectReference, error) {
+	resources, err := GetResourcesFromYAML(yamlFile)
+	if err != nil {
+		return nil, err
+	}
+	return o.ApplyResources(ctx, resources)
+}
+
+// ApplyObjects applies the given objects. Returns the applied objects, in order.
+func (o *ObjectApplier) ApplyObjects(ctx context.Context, objs ...*unstructured.Unstructured) ([]ObjectReference, error) {
+	resources := make([]*Resource, len(objs))
+	for i, obj := range objs {
+		gvk := obj.GroupVersionKind()
+		resources[i] = &Resource{
+			Object: obj,
+			GVK:    &gvk,
+		}
+	}
+	return o.ApplyResources(ctx, resources)
+}
+
+// ApplyResources applies the given resources in order. If Wait is set, blocks until the applied workloads are ready.
+func (o *ObjectApplier) ApplyResources(ctx context.Context, resources []*Resource) ([]ObjectReference, error) {
+	if err := o.init(); err != nil {
+		return nil, wrapError(err)
+	}
+
+	fieldManager := o.FieldManager
+	if fieldManager == "" {
+		fieldManager = defaultFieldManager
+	}
+
+	var applied []ObjectReference
+	var waitFor []appliedObject
+	for _, resource := range resources {
+		mapping, err := o.restMapper.RESTMapping(resource.GVK.GroupKind(), resource.GVK.Version)
+		if err != nil {
+			return applied, wrapError(err)
+		}
+
+		ref := ObjectReference{
+			Kind: mapping.GroupVersionKind.Kind,
+			Name: resource.Object.GetName(),
+		}
+		var client dynamic.ResourceInterface = o.dynamicClient.Resource(mapping.Resource)
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			ref.Namespace = o.Namespace
+			if ref.Namespace == "" {
+				ref.Namespace = resource.Object.GetNamespace()
+			}
+			resource.Object.SetNamespace(ref.Namespace)
+			client = o.dynamicClient.Resource(mapping.Resource).Namespace(ref.Namespace)
+		}
+
+		_, err = client.Apply(ctx, ref.Name, resource.Object, metav1.ApplyOptions{
+			FieldManager: fieldManager,
+			Force:        o.Force,
+		})
+		if err != nil {
+			return applied, wrapError(fmt.Errorf("applying %s %s: %w", ref.Kind, ref.Name, err))
+		}
+		applied = append(applied, ref)
+
+		if hasReadiness(ref.Kind) {
+			waitFor = append(waitFor, appliedObject{ref: ref, client: client})
+		}
+	}
+
+	if !o.Wait || len(waitFor) == 0 {
+		return applied, nil
+	}
+	return applied, wrapError(o.waitForReady(ctx, waitFor))
+}
+
+// waitForReady blocks until all of the given objects are ready. It returns the context's error if the context is done,
+// or the timeout expires, before then.
+func (o *ObjectApplier) waitForReady(ctx context.Context, objs []appliedObject) error {
+	if o.Timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.Timeout)
+		defer cancel()
+	}
+
+	remaining := objs
+	err := wait.PollImmediateUntilWithContext(ctx, applyPollInterval, func(ctx context.Context) (bool, error) {
+		var pending []appliedObject
+		for _, obj := range remaining {
+			u, err := obj.client.Get(ctx, obj.ref.Name, metav1.GetOptions{})
+			if err != nil {
+				return false, err
+			}
+			ready, err := IsObjectReady(u)
+			if err != nil {
+				return false, err
+			}
+			if ready {
+				if o.Progress != nil {
+					o.Progress.OnReady(obj.ref)
+				}
+				continue
+			}
+			pending = append(pending, obj)
+		}
+		remaining = pending
+		if o.Progress != nil {
+			o.Progress.OnWaiting(len(remaining))
+		}
+		return len(remaining) == 0, nil
+	})
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+func hasReadiness(kind string) bool {
+	switch kind {
+	case "Deployment", "DaemonSet", "Job":
+		return true
+	}
+	return false
+}
+
+// IsObjectReady returns whether the given Deployment, DaemonSet or Job is ready. A Deployment or DaemonSet is ready
+// once all of its pods are updated and available, and a Job is ready once it has completed. Returns an error if the
+// Job has failed. Objects of any other kind are always ready.
+func IsObjectReady(obj *unstructured.Unstructured) (bool, error) {
+	switch obj.GetKind() {
+	case "Deployment":
+		if !observedLatestGeneration(obj) {
+			return false, nil
+		}
+		replicas, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+		if !found {
+			replicas = 1
+		}
+		updated, _, _ := unstructured.NestedInt64(obj.Object, "status", "updatedReplicas")
+		available, _, _ := unstructured.NestedInt64(obj.Object, "status", "availableReplicas")
+		return updated >= replicas && available >= replicas, nil
+	case "DaemonSet":
+		if !observedLatestGeneration(obj) {
+			return false, nil
+		}
+		desired, _, _ := unstructured.NestedInt64(obj.Object, "status", "desiredNumberScheduled")
+		updated, _, _ := unstructured.NestedInt64(obj.Object, "status", "updatedNumberScheduled")
+		available, _, _ := unstructured.NestedInt64(obj.Object, "status", "numberAvailable")
+		return updated >= desired && available >= desired, nil
+	case "Job":
+		conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+		for _, c := range conditions {
+			condition, ok := c.(map[string]interface{})
+			if !ok || condition["status"] != "True" {
+				continue
+			}
+			switch condition["type"] {
+			case "Complete":
+				return true, nil
+			case "Failed":
+				return false, fmt.Errorf("job %s failed: %v", obj.GetName(), condition["message"])
+			}
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+func observedLatestGeneration(obj *unstructured.Unstructured) bool {
+	observed, _, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	return observed >= obj.GetGeneration()
+}
+
+func (o *ObjectApplier) init() error {
+	if o.rcg == nil {
+		o.rcg = &restClientGetter{
+			clientset:  o.Clientset,
+			restConfig: o.RestConfig,
+		}
+	}
+	if o.restMapper == nil {
+		rm, err := o.rcg.ToRESTMapper()
+		if err != nil {
+			return err
+		}
+		o.restMapper = rm
+	}
+	if o.dynamicClient == nil {
+		config, err := o.rcg.ToRESTConfig()
+		if err != nil {
+			return err
+		}
+		c, err := dynamic.NewForConfig(config)
+		if err != nil {
+			return err
+		}
+		o.dynamicClient = c
+	}
+	return nil
+}
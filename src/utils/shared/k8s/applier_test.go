@@ -0,0 +1,163 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func TestIsObjectReady(t *testing.T) {
+	tests := []struct {
+		name        string
+		obj         map[string]interface{}
+		expected    bool
+		expectError bool
+	}{
+		{
+			name: "deployment available",
+			obj: map[string]interface{}{
+				"kind":     "Deployment",
+				"metadata": map[string]interface{}{"name": "a", "generation": int64(2)},
+				"spec":     map[string]interface{}{"replicas": int64(3)},
+				"status": map[string]interface{}{
+					"observedGeneration": int64(2),
+					"updatedReplicas":    int64(3),
+					"availableReplicas":  int64(3),
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "deployment rolling out",
+			obj: map[string]interface{}{
+				"kind":     "Deployment",
+				"metadata": map[string]interface{}{"name": "a", "generation": int64(2)},
+				"spec":     map[string]interface{}{"replicas": int64(3)},
+				"status": map[string]interface{}{
+					"observedGeneration": int64(2),
+					"updatedReplicas":    int64(1),
+					"availableReplicas":  int64(3),
+				},
+			},
+			expected: false,
+		},
+		{
+			name: "deployment with stale status",
+			obj: map[string]interface{}{
+				"kind":     "Deployment",
+				"metadata": map[string]interface{}{"name": "a", "generation": int64(3)},
+				"status": map[string]interface{}{
+					"observedGeneration": int64(2),
+					"updatedReplicas":    int64(1),
+					"availableReplicas":  int64(1),
+				},
+			},
+			expected: false,
+		},
+		{
+			name: "daemonset available",
+			obj: map[string]interface{}{
+				"kind":     "DaemonSet",
+				"metadata": map[string]interface{}{"name": "a", "generation": int64(1)},
+				"status": map[string]interface{}{
+					"observedGeneration":     int64(1),
+					"desiredNumberScheduled": int64(4),
+					"updatedNumberScheduled": int64(4),
+					"numberAvailable":        int64(4),
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "daemonset unavailable",
+			obj: map[string]interface{}{
+				"kind":     "DaemonSet",
+				"metadata": map[string]interface{}{"name": "a", "generation": int64(1)},
+				"status": map[string]interface{}{
+					"observedGeneration":     int64(1),
+					"desiredNumberScheduled": int64(4),
+					"updatedNumberScheduled": int64(4),
+					"numberAvailable":        int64(2),
+				},
+			},
+			expected: false,
+		},
+		{
+			name: "job complete",
+			obj: map[string]interface{}{
+				"kind":     "Job",
+				"metadata": map[string]interface{}{"name": "a"},
+				"status": map[string]interface{}{
+					"conditions": []interface{}{
+						map[string]interface{}{"type": "Complete", "status": "True"},
+					},
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "job running",
+			obj: map[string]interface{}{
+				"kind":     "Job",
+				"metadata": map[string]interface{}{"name": "a"},
+				"status":   map[string]interface{}{"active": int64(1)},
+			},
+			expected: false,
+		},
+		{
+			name: "job failed",
+			obj: map[string]interface{}{
+				"kind":     "Job",
+				"metadata": map[string]interface{}{"name": "a"},
+				"status": map[string]interface{}{
+					"conditions": []interface{}{
+						map[string]interface{}{"type": "Failed", "status": "True", "message": "BackoffLimitExceeded"},
+					},
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "other kind",
+			obj: map[string]interface{}{
+				"kind":     "ConfigMap",
+				"metadata": map[string]interface{}{"name": "a"},
+			},
+			expected: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ready, err := k8s.IsObjectReady(&unstructured.Unstructured{Object: tc.obj})
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, ready)
+		})
+	}
+}
@@ -193,14 +193,14 @@ func ApplyResources(clientset kubernetes.Interface, config *rest.Config, resourc
 
 	apiGroupResources, err := restmapper.GetAPIGroupResources(discoveryClient)
 	if err != nil {
-		return err
+		return wrapError(err)
 	}
 	rm := restmapper.NewDiscoveryRESTMapper(apiGroupResources)
 
 	for _, resource := range resources {
 		mapping, err := rm.RESTMapping(resource.GVK.GroupKind(), resource.GVK.Version)
 		if err != nil {
-			return err
+			return wrapError(err)
 		}
 
 		k8sRes := mapping.Resource.Resource
@@ -223,7 +223,7 @@ func ApplyResources(clientset kubernetes.Interface, config *rest.Config, resourc
 		}
 		dynamicClient, err := dynamic.NewForConfig(restconfig)
 		if err != nil {
-			return err
+			return wrapError(err)
 		}
 
 		res := dynamicClient.Resource(mapping.Resource)
@@ -243,7 +243,7 @@ func ApplyResources(clientset kubernetes.Interface, config *rest.Config, resourc
 		_, err = createRes.Create(context.Background(), resource.Object, metav1.CreateOptions{})
 		if err != nil {
 			if !k8serrors.IsAlreadyExists(err) {
-				return err
+				return wrapError(err)
 			} else if (k8sRes == "clusterroles" || k8sRes == "cronjobs") || allowUpdate {
 				// TODO(michelle,vihang,philkuz) Update() fails on services and PVCs that are already running on the
 				// cluster. We will need to fix this before we can successfully update those resources. K8s is unhappy
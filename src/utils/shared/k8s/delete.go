@@ -20,19 +20,23 @@ package k8s
 
 import (
 	"context"
-	"io"
 	"strings"
 	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
-	"k8s.io/cli-runtime/pkg/genericclioptions"
-	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/cli-runtime/pkg/resource"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/discovery/cached/memory"
@@ -41,24 +45,151 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/restmapper"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
-	cmdwait "k8s.io/kubectl/pkg/cmd/wait"
 )
 
+// deletePollInterval is how often the ObjectDeleter checks whether deleted objects are gone.
+const deletePollInterval = time.Second
+
+// defaultFinalizerGracePeriod is how long the ObjectDeleter waits for deleted objects to be removed before it strips
+// their finalizers, when StripFinalizers is set.
+const defaultFinalizerGracePeriod = 30 * time.Second
+
+// DefaultStrippableFinalizers are the finalizers that the ObjectDeleter strips when StripFinalizers is set and no
+// AllowedFinalizers are given. "kubernetes" is the finalizer that the namespace controller uses to remove the contents
+// of a namespace, which gets stuck when an API in the cluster is unavailable. The others are used by the garbage
+// collector for foreground and orphan deletion.
+var DefaultStrippableFinalizers = []string{"kubernetes", metav1.FinalizerDeleteDependents, metav1.FinalizerOrphanDependents}
+
+// DefaultDeleteConcurrency is the maximum number of objects that are deleted at once, when no concurrency is given.
+const DefaultDeleteConcurrency = 10
+
 // ObjectDeleter has methods to delete K8s objects and wait for them. This code is adopted from `kubectl delete`.
 type ObjectDeleter struct {
 	Namespace  string
 	Clientset  *kubernetes.Clientset
 	RestConfig *rest.Config
-	Timeout    time.Duration
+	// Namespaces, if set, are the namespaces that DeleteByLabel deletes objects from, instead of Namespace. The objects
+	// in all of the namespaces are deleted first, and then waited for together.
+	Namespaces []string
+	// AllNamespaces makes DeleteByLabel delete objects from every namespace, instead of Namespace or Namespaces.
+	AllNamespaces bool
+	// Timeout bounds how long to wait for the deleted objects to be removed. If zero, the wait is only bounded by
+	// the context.
+	Timeout time.Duration
+	// DryRun makes the ObjectDeleter only discover the objects that would be deleted, without deleting them. The
+	// discovered objects are returned by DryRunObjects.
+	DryRun bool
+	// Progress, if set, is notified as the deleted objects are removed.
+	Progress DeleteProgressHandler
+	// StripFinalizers makes the ObjectDeleter remove the finalizers of objects that are still not removed after
+	// FinalizerGracePeriod, so that the deletion can complete when the controllers handling those finalizers are
+	// gone. For namespaces, this applies to the objects within the namespace as well. Only finalizers in
+	// AllowedFinalizers are removed.
+	StripFinalizers bool
+	// FinalizerGracePeriod is how long to wait before stripping finalizers, and then between attempts. Defaults to
+	// 30 seconds.
+	FinalizerGracePeriod time.Duration
+	// AllowedFinalizers are the finalizers that may be stripped. If nil, DefaultStrippableFinalizers is used.
+	AllowedFinalizers []string
+	// Concurrency is the maximum number of objects to delete at once. Defaults to DefaultDeleteConcurrency.
+	Concurrency int
+	// RetryPolicy is used to retry the API calls that fail with transient errors. If nil, the policy set on the
+	// context with WithRetryPolicy is used, or DefaultRetryPolicy.
+	RetryPolicy *RetryPolicy
+	// PropagationPolicy is how the dependents of the deleted objects are garbage collected. Defaults to
+	// metav1.DeletePropagationBackground. With metav1.DeletePropagationForeground, the deleted objects are only
+	// removed, and so only waited for, once their dependents are.
+	PropagationPolicy *metav1.DeletionPropagation
+	// GracePeriodSeconds is how long the deleted objects are given to terminate. Defaults to 0, which deletes them
+	// immediately. If negative, the default grace period of each object is used.
+	GracePeriodSeconds *int64
 
 	rcg           *restClientGetter
 	dynamicClient dynamic.Interface
+
+	dryRunObjects []ObjectReference
+	dryRunSeen    map[ObjectReference]bool
+}
+
+// DeleteOptions control how the typed delete helpers, such as DeletePods, delete objects. A nil DeleteOptions, or an
+// unset field, uses the API server's default.
+type DeleteOptions struct {
+	// PropagationPolicy is how the dependents of the deleted objects are garbage collected.
+	PropagationPolicy *metav1.DeletionPropagation
+	// GracePeriodSeconds is how long the deleted objects are given to terminate. Zero deletes them immediately.
+	GracePeriodSeconds *int64
+}
+
+func (d *DeleteOptions) toDeleteOptions() metav1.DeleteOptions {
+	if d == nil {
+		return metav1.DeleteOptions{}
+	}
+	return metav1.DeleteOptions{
+		PropagationPolicy:  d.PropagationPolicy,
+		GracePeriodSeconds: d.GracePeriodSeconds,
+	}
+}
+
+// ObjectReference identifies a K8s object.
+type ObjectReference struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// DeleteProgressHandler is notified of the progress of the ObjectDeleter while it waits for deleted objects to be
+// removed. Its methods are called from the goroutine that is deleting.
+type DeleteProgressHandler interface {
+	// OnDeleted is called once a deleted object has been removed.
+	OnDeleted(obj ObjectReference)
+	// OnWaiting is called each time the deleted objects are checked, with the number that have not been removed yet.
+	OnWaiting(remaining int)
+}
+
+func objectReference(info *resource.Info) ObjectReference {
+	return ObjectReference{
+		Kind:      info.Mapping.GroupVersionKind.Kind,
+		Namespace: info.Namespace,
+		Name:      info.Name,
+	}
+}
+
+// retryPolicy returns the RetryPolicy for the calls made with the given context.
+func (o *ObjectDeleter) retryPolicy(ctx context.Context) RetryPolicy {
+	if o.RetryPolicy != nil {
+		return *o.RetryPolicy
+	}
+	return retryPolicyFromContext(ctx)
+}
+
+// deleteOptions returns the options that the ObjectDeleter deletes objects with.
+func (o *ObjectDeleter) deleteOptions() *metav1.DeleteOptions {
+	options := &metav1.DeleteOptions{}
+	gracePeriod := int64(0)
+	if o.GracePeriodSeconds != nil {
+		gracePeriod = *o.GracePeriodSeconds
+	}
+	if gracePeriod >= 0 {
+		options.GracePeriodSeconds = &gracePeriod
+	}
+	policy := metav1.DeletePropagationBackground
+	if o.PropagationPolicy != nil {
+		policy = *o.PropagationPolicy
+	}
+	options.PropagationPolicy = &policy
+	return options
+}
+
+// DryRunObjects returns the objects that the ObjectDeleter would have deleted, in the order they were discovered.
+// It only returns objects when DryRun is set.
+func (o *ObjectDeleter) DryRunObjects() []ObjectReference {
+	return o.dryRunObjects
 }
 
 // DeleteCustomObject is used to delete a custom object (instantiation of CRD).
-func (o *ObjectDeleter) DeleteCustomObject(resourceName, resourceValue string) error {
+func (o *ObjectDeleter) DeleteCustomObject(ctx context.Context, resourceName, resourceValue string) error {
 	if err := o.initRestClientGetter(); err != nil {
-		return err
+		return wrapError(err)
 	}
 	b := resource.NewBuilder(o.rcg)
 	r := b.
@@ -72,20 +203,26 @@ func (o *ObjectDeleter) DeleteCustomObject(resourceName, resourceValue string) e
 
 	err := r.Err()
 	if err != nil {
-		return err
+		return wrapError(err)
 	}
 	if err := o.initDynamicClient(); err != nil {
-		return err
+		return wrapError(err)
 	}
 
-	_, err = o.runDelete(r)
-	return err
+	_, err = o.runDelete(ctx, r)
+	return wrapError(err)
 }
 
 // DeleteNamespace removes the namespace and all objects within it. Waits for deletion to complete.
-func (o *ObjectDeleter) DeleteNamespace() error {
+func (o *ObjectDeleter) DeleteNamespace(ctx context.Context) error {
 	if err := o.initRestClientGetter(); err != nil {
-		return err
+		return wrapError(err)
+	}
+	if o.DryRun {
+		// Deleting the namespace deletes all of the objects within it, so those are listed as well.
+		if err := o.dryRunNamespaceContents(ctx); err != nil {
+			return wrapError(err)
+		}
 	}
 	b := resource.NewBuilder(o.rcg)
 
@@ -100,23 +237,82 @@ func (o *ObjectDeleter) DeleteNamespace() error {
 
 	err := r.Err()
 	if err != nil {
-		return err
+		return wrapError(err)
 	}
 	if err := o.initDynamicClient(); err != nil {
+		return wrapError(err)
+	}
+
+	_, err = o.runDelete(ctx, r)
+	return wrapError(err)
+}
+
+// dryRunNamespaceContents records all of the objects in the namespace that can be deleted.
+func (o *ObjectDeleter) dryRunNamespaceContents(ctx context.Context) error {
+	return o.visitNamespaceContents(ctx, o.Namespace, func(info *resource.Info) error {
+		o.recordDryRun(info)
+		return nil
+	})
+}
+
+// visitNamespaceContents calls fn for each of the objects in the given namespace that can be deleted.
+func (o *ObjectDeleter) visitNamespaceContents(ctx context.Context, ns string, fn func(info *resource.Info) error) error {
+	kinds, err := o.getDeletableResourceTypes(ctx, true)
+	if err != nil {
 		return err
 	}
+	if len(kinds) == 0 {
+		return nil
+	}
 
-	_, err = o.runDelete(r)
-	return err
+	r := resource.NewBuilder(o.rcg).
+		Unstructured().
+		ContinueOnError().
+		NamespaceParam(ns).
+		SelectAllParam(true).
+		ResourceTypeOrNameArgs(false, strings.Join(kinds, ",")).
+		RequireObject(false).
+		Flatten().
+		Do()
+	if err := r.Err(); err != nil {
+		return err
+	}
+
+	return r.IgnoreErrors(errors.IsNotFound).Visit(func(info *resource.Info, err error) error {
+		if err != nil {
+			return err
+		}
+		return fn(info)
+	})
+}
+
+func (o *ObjectDeleter) recordDryRun(info *resource.Info) {
+	ref := objectReference(info)
+	if o.dryRunSeen == nil {
+		o.dryRunSeen = make(map[ObjectReference]bool)
+	}
+	// The same object can be matched by several calls, or by several names for its resource type.
+	if o.dryRunSeen[ref] {
+		return
+	}
+	o.dryRunSeen[ref] = true
+	o.dryRunObjects = append(o.dryRunObjects, ref)
 }
 
-func (o *ObjectDeleter) getDeletableResourceTypes() ([]string, error) {
+// getDeletableResourceTypes returns the resource types that support deletion. If namespacedOnly is set, cluster-scoped
+// resource types are left out.
+func (o *ObjectDeleter) getDeletableResourceTypes(ctx context.Context, namespacedOnly bool) ([]string, error) {
 	discoveryClient, err := o.rcg.ToDiscoveryClient()
 	if err != nil {
 		return nil, err
 	}
 
-	lists, err := discoveryClient.ServerPreferredResources()
+	var lists []*metav1.APIResourceList
+	err = o.retryPolicy(ctx).Do(ctx, func(ctx context.Context) error {
+		var err error
+		lists, err = discoveryClient.ServerPreferredResources()
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -131,6 +327,9 @@ func (o *ObjectDeleter) getDeletableResourceTypes() ([]string, error) {
 			if len(resource.Verbs) == 0 {
 				continue
 			}
+			if namespacedOnly && !resource.Namespaced {
+				continue
+			}
 			if !sets.NewString(resource.Verbs...).HasAll("delete") {
 				continue
 			}
@@ -140,68 +339,217 @@ func (o *ObjectDeleter) getDeletableResourceTypes() ([]string, error) {
 	return resources, nil
 }
 
-// DeleteByLabel delete objects that match the labels and specified by resourceKinds. Waits for deletion.
-func (o *ObjectDeleter) DeleteByLabel(selector string, resourceKinds ...string) (int, error) {
+// crdResource is the resource for CustomResourceDefinitions.
+var crdResource = schema.GroupVersionResource{
+	Group:    "apiextensions.k8s.io",
+	Version:  "v1",
+	Resource: "customresourcedefinitions",
+}
+
+// DeleteCustomResources deletes the CustomResourceDefinitions that match the selector, or that belong to one of the
+// given API groups, along with all of their custom resources. All of the custom resources are deleted first, and
+// waited on until their finalizers have run, so that the controllers handling them can clean up before the CRDs are
+// removed. Returns the number of objects deleted.
+func (o *ObjectDeleter) DeleteCustomResources(ctx context.Context, selector string, groups ...string) (int, error) {
+	if selector == "" && len(groups) == 0 {
+		// An empty selector matches everything, so it is only used alongside groups.
+		return 0, nil
+	}
 	if err := o.initRestClientGetter(); err != nil {
-		return 0, err
+		return 0, wrapError(err)
+	}
+	if err := o.initDynamicClient(); err != nil {
+		return 0, wrapError(err)
 	}
-	b := resource.NewBuilder(o.rcg)
 
-	if len(resourceKinds) == 0 {
-		allKinds, err := o.getDeletableResourceTypes()
-		if err != nil {
-			return 0, err
-		}
-		resourceKinds = allKinds
+	crds, err := o.findCustomResourceDefinitions(ctx, selector, groups)
+	if err != nil {
+		return 0, wrapError(err)
+	}
+	if len(crds) == 0 {
+		return 0, nil
 	}
 
-	r := b.
+	crdNames := make([]string, len(crds))
+	crTypes := make([]string, len(crds))
+	for i, crd := range crds {
+		crdNames[i] = crd.GetName()
+		plural, _, _ := unstructured.NestedString(crd.Object, "spec", "names", "plural")
+		group, _, _ := unstructured.NestedString(crd.Object, "spec", "group")
+		crTypes[i] = plural + "." + group
+	}
+
+	r := resource.NewBuilder(o.rcg).
 		Unstructured().
 		ContinueOnError().
-		NamespaceParam(o.Namespace).
-		LabelSelector(selector).
-		ResourceTypeOrNameArgs(false, strings.Join(resourceKinds, ",")).
+		AllNamespaces(true).
+		SelectAllParam(true).
+		ResourceTypeOrNameArgs(false, strings.Join(crTypes, ",")).
 		RequireObject(false).
 		Flatten().
 		Do()
+	if err := r.Err(); err != nil {
+		return 0, wrapError(err)
+	}
+	deletedCRs, err := o.runDelete(ctx, r)
+	if err != nil {
+		return deletedCRs, wrapError(err)
+	}
 
-	err := r.Err()
+	r = resource.NewBuilder(o.rcg).
+		Unstructured().
+		ContinueOnError().
+		ResourceNames(crdResource.Resource, crdNames...).
+		RequireObject(false).
+		Flatten().
+		Do()
+	if err := r.Err(); err != nil {
+		return deletedCRs, wrapError(err)
+	}
+	deletedCRDs, err := o.runDelete(ctx, r)
+	return deletedCRs + deletedCRDs, wrapError(err)
+}
+
+// findCustomResourceDefinitions returns the CRDs that match the selector, or that belong to one of the groups.
+func (o *ObjectDeleter) findCustomResourceDefinitions(ctx context.Context, selector string, groups []string) ([]unstructured.Unstructured, error) {
+	var labelSelector labels.Selector
+	if selector != "" {
+		s, err := labels.Parse(selector)
+		if err != nil {
+			return nil, err
+		}
+		labelSelector = s
+	}
+	groupSet := sets.NewString(groups...)
+
+	var list *unstructured.UnstructuredList
+	err := o.retryPolicy(ctx).Do(ctx, func(ctx context.Context) error {
+		var err error
+		list, err = o.dynamicClient.Resource(crdResource).List(ctx, metav1.ListOptions{})
+		return err
+	})
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+
+	var crds []unstructured.Unstructured
+	for _, crd := range list.Items {
+		group, _, _ := unstructured.NestedString(crd.Object, "spec", "group")
+		if groupSet.Has(group) || (labelSelector != nil && labelSelector.Matches(labels.Set(crd.GetLabels()))) {
+			crds = append(crds, crd)
+		}
+	}
+	return crds, nil
+}
+
+// DeleteByLabel delete objects that match the labels and specified by resourceKinds. Waits for deletion.
+func (o *ObjectDeleter) DeleteByLabel(ctx context.Context, selector string, resourceKinds ...string) (int, error) {
+	if err := o.initRestClientGetter(); err != nil {
+		return 0, wrapError(err)
+	}
+
+	if len(resourceKinds) == 0 {
+		allKinds, err := o.getDeletableResourceTypes(ctx, false)
+		if err != nil {
+			return 0, wrapError(err)
+		}
+		resourceKinds = allKinds
+	}
+
+	var results []*resource.Result
+	for _, ns := range o.labelNamespaces() {
+		r := resource.NewBuilder(o.rcg).
+			Unstructured().
+			ContinueOnError().
+			NamespaceParam(ns).
+			AllNamespaces(o.AllNamespaces).
+			LabelSelector(selector).
+			ResourceTypeOrNameArgs(false, strings.Join(resourceKinds, ",")).
+			RequireObject(false).
+			Flatten().
+			Do()
+		if err := r.Err(); err != nil {
+			return 0, wrapError(err)
+		}
+		results = append(results, r)
 	}
 	if err := o.initDynamicClient(); err != nil {
-		return 0, err
+		return 0, wrapError(err)
 	}
 
-	return o.runDelete(r)
+	found, err := o.runDelete(ctx, results...)
+	return found, wrapError(err)
 }
 
-func (o *ObjectDeleter) runDelete(r *resource.Result) (int, error) {
-	r = r.IgnoreErrors(errors.IsNotFound)
-	deletedInfos := []*resource.Info{}
-	uidMap := cmdwait.UIDMap{}
-	found := 0
-	err := r.Visit(func(info *resource.Info, err error) error {
-		if err != nil {
-			return err
+// labelNamespaces returns the namespaces that DeleteByLabel deletes objects from. With AllNamespaces, the namespace
+// is ignored by the builder, so a single, arbitrary one is returned.
+func (o *ObjectDeleter) labelNamespaces() []string {
+	if o.AllNamespaces || len(o.Namespaces) == 0 {
+		return []string{o.Namespace}
+	}
+	return sets.NewString(o.Namespaces...).List()
+}
+
+// runDelete deletes the objects in the results, and waits for them to be removed. An object that is in several of the
+// results, such as a cluster-scoped object matched from several namespaces, is only deleted once.
+func (o *ObjectDeleter) runDelete(ctx context.Context, results ...*resource.Result) (int, error) {
+	for _, r := range results {
+		r.IgnoreErrors(errors.IsNotFound)
+	}
+	retry := o.retryPolicy(ctx)
+	var deletedInfos []*resource.Info
+	// Visiting fetches the objects from the API server, so it is retried as a whole.
+	err := retry.Do(ctx, func(ctx context.Context) error {
+		deletedInfos = []*resource.Info{}
+		seen := make(map[string]bool)
+		for _, r := range results {
+			err := r.Visit(func(info *resource.Info, err error) error {
+				if err != nil {
+					return err
+				}
+				key := info.Mapping.Resource.String() + "/" + info.Namespace + "/" + info.Name
+				if seen[key] {
+					return nil
+				}
+				seen[key] = true
+				if o.DryRun {
+					o.recordDryRun(info)
+				}
+				deletedInfos = append(deletedInfos, info)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
 		}
-		deletedInfos = append(deletedInfos, info)
-		found++
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	found := len(deletedInfos)
+	if found == 0 || o.DryRun {
+		return found, nil
+	}
 
-		options := metav1.NewDeleteOptions(0)
-		policy := metav1.DeletePropagationBackground
-		options.PropagationPolicy = &policy
-		response, err := o.deleteResource(info, options)
+	options := o.deleteOptions()
+	var uidMapMu sync.Mutex
+	uidMap := map[*resource.Info]types.UID{}
+	err = runConcurrently(ctx, len(deletedInfos), o.Concurrency, func(ctx context.Context, i int) error {
+		info := deletedInfos[i]
+		var response runtime.Object
+		err := retry.doDelete(ctx, func(ctx context.Context) error {
+			var err error
+			response, err = o.deleteResource(ctx, info, options)
+			return err
+		})
 		if err != nil {
 			return err
 		}
-		resourceLocation := cmdwait.ResourceLocation{
-			GroupResource: info.Mapping.Resource.GroupResource(),
-			Namespace:     info.Namespace,
-			Name:          info.Name,
-		}
+		uidMapMu.Lock()
+		defer uidMapMu.Unlock()
 		if status, ok := response.(*metav1.Status); ok && status.Details != nil {
-			uidMap[resourceLocation] = status.Details.UID
+			uidMap[info] = status.Details.UID
 			return nil
 		}
 		responseMetadata, err := meta.Accessor(response)
@@ -210,42 +558,198 @@ func (o *ObjectDeleter) runDelete(r *resource.Result) (int, error) {
 			log.WithError(err).Trace("missing UID")
 			return nil
 		}
-		uidMap[resourceLocation] = responseMetadata.GetUID()
-
+		uidMap[info] = responseMetadata.GetUID()
 		return nil
 	})
 	if err != nil {
-		return 0, err
+		return found, err
 	}
-	if found == 0 {
-		return 0, nil
+
+	return found, o.waitForDeletion(ctx, deletedInfos, uidMap)
+}
+
+// waitForDeletion blocks until all of the given objects are gone, or have been replaced by an object with a different
+// UID. It returns the context's error if the context is done, or the timeout expires, before then.
+func (o *ObjectDeleter) waitForDeletion(ctx context.Context, infos []*resource.Info, uidMap map[*resource.Info]types.UID) error {
+	if o.Timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.Timeout)
+		defer cancel()
+	}
+
+	gracePeriod := o.FinalizerGracePeriod
+	if gracePeriod == 0 {
+		gracePeriod = defaultFinalizerGracePeriod
+	}
+	nextStrip := time.Now().Add(gracePeriod)
+
+	retry := o.retryPolicy(ctx)
+	remaining := infos
+	err := wait.PollImmediateUntilWithContext(ctx, deletePollInterval, func(ctx context.Context) (bool, error) {
+		if o.StripFinalizers && !time.Now().Before(nextStrip) {
+			err := retry.Do(ctx, func(ctx context.Context) error {
+				return o.stripFinalizers(ctx, remaining)
+			})
+			if err != nil {
+				return false, err
+			}
+			nextStrip = time.Now().Add(gracePeriod)
+		}
+
+		var pending []*resource.Info
+		for _, info := range remaining {
+			var obj *unstructured.Unstructured
+			err := retry.Do(ctx, func(ctx context.Context) error {
+				var err error
+				obj, err = o.dynamicClient.
+					Resource(info.Mapping.Resource).
+					Namespace(info.Namespace).
+					Get(ctx, info.Name, metav1.GetOptions{})
+				return err
+			})
+			gone := errors.IsNotFound(err)
+			if err != nil && !gone {
+				return false, err
+			}
+			if uid, ok := uidMap[info]; ok && !gone && obj.GetUID() != uid {
+				gone = true
+			}
+			if gone {
+				if o.Progress != nil {
+					o.Progress.OnDeleted(objectReference(info))
+				}
+				continue
+			}
+			pending = append(pending, info)
+		}
+		remaining = pending
+		if o.Progress != nil {
+			o.Progress.OnWaiting(len(remaining))
+		}
+		return len(remaining) == 0, nil
+	})
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// stripFinalizers removes the allowed finalizers from the given objects, which are still not removed. For namespaces,
+// the finalizers of the objects that are being deleted within them are removed first.
+func (o *ObjectDeleter) stripFinalizers(ctx context.Context, infos []*resource.Info) error {
+	allowed := o.AllowedFinalizers
+	if allowed == nil {
+		allowed = DefaultStrippableFinalizers
+	}
+	allowedSet := sets.NewString(allowed...)
+
+	for _, info := range infos {
+		client := o.dynamicClient.Resource(info.Mapping.Resource).Namespace(info.Namespace)
+		isNamespace := info.Mapping.GroupVersionKind.Kind == "Namespace"
+		if isNamespace {
+			err := o.visitNamespaceContents(ctx, info.Name, func(contentInfo *resource.Info) error {
+				obj, ok := contentInfo.Object.(*unstructured.Unstructured)
+				if !ok || obj.GetDeletionTimestamp() == nil {
+					return nil
+				}
+				contentClient := o.dynamicClient.Resource(contentInfo.Mapping.Resource).Namespace(contentInfo.Namespace)
+				return stripObjectFinalizers(ctx, contentClient, obj, allowedSet)
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		obj, err := client.Get(ctx, info.Name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := stripObjectFinalizers(ctx, client, obj, allowedSet); err != nil {
+			return err
+		}
+		if isNamespace {
+			if err := stripNamespaceSpecFinalizers(ctx, client, info.Name, allowedSet); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// stripObjectFinalizers removes the allowed finalizers from the metadata of the object.
+func stripObjectFinalizers(ctx context.Context, client dynamic.ResourceInterface, obj *unstructured.Unstructured, allowed sets.String) error {
+	finalizers := obj.GetFinalizers()
+	kept := []string{}
+	for _, f := range finalizers {
+		if !allowed.Has(f) {
+			kept = append(kept, f)
+		}
+	}
+	if len(kept) == len(finalizers) {
+		return nil
 	}
 
-	effectiveTimeout := o.Timeout
-	if effectiveTimeout == 0 {
-		// if we requested to wait forever, set it to a week.
-		effectiveTimeout = 168 * time.Hour
+	log.WithField("kind", obj.GetKind()).WithField("name", obj.GetName()).
+		WithField("finalizers", finalizers).Info("Stripping finalizers from object stuck in deletion")
+	obj.SetFinalizers(kept)
+	_, err := client.Update(ctx, obj, metav1.UpdateOptions{})
+	// A conflict or a missing object means the object changed since it was read. It is retried, if still needed,
+	// after the next grace period.
+	if errors.IsConflict(err) || errors.IsNotFound(err) {
+		return nil
 	}
-	waitOptions := cmdwait.WaitOptions{
-		ResourceFinder: genericclioptions.ResourceFinderForResult(resource.InfoListVisitor(deletedInfos)),
-		UIDMap:         uidMap,
-		DynamicClient:  o.dynamicClient,
-		Timeout:        effectiveTimeout,
+	return err
+}
 
-		Printer:     printers.NewDiscardingPrinter(),
-		ConditionFn: cmdwait.IsDeleted,
-		IOStreams: genericclioptions.IOStreams{
-			Out:    io.Discard,
-			ErrOut: io.Discard,
-		},
+// stripNamespaceSpecFinalizers removes the allowed finalizers from the spec of the namespace. These are separate from
+// the metadata finalizers, and can only be updated through the namespace's finalize subresource.
+func stripNamespaceSpecFinalizers(ctx context.Context, client dynamic.ResourceInterface, name string, allowed sets.String) error {
+	obj, err := client.Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
 	}
-	return found, waitOptions.RunWait()
+	if err != nil {
+		return err
+	}
+
+	finalizers, _, err := unstructured.NestedStringSlice(obj.Object, "spec", "finalizers")
+	if err != nil {
+		return err
+	}
+	kept := []interface{}{}
+	for _, f := range finalizers {
+		if !allowed.Has(f) {
+			kept = append(kept, f)
+		}
+	}
+	if len(kept) == len(finalizers) {
+		return nil
+	}
+
+	log.WithField("namespace", name).WithField("finalizers", finalizers).
+		Info("Stripping finalizers from namespace stuck in deletion")
+	if err := unstructured.SetNestedSlice(obj.Object, kept, "spec", "finalizers"); err != nil {
+		return err
+	}
+	_, err = client.Update(ctx, obj, metav1.UpdateOptions{}, "finalize")
+	if errors.IsConflict(err) || errors.IsNotFound(err) {
+		return nil
+	}
+	return err
 }
 
-func (o *ObjectDeleter) deleteResource(info *resource.Info, deleteOptions *metav1.DeleteOptions) (runtime.Object, error) {
-	deleteResponse, err := resource.
-		NewHelper(info.Client, info.Mapping).
-		DeleteWithOptions(info.Namespace, info.Name, deleteOptions)
+func (o *ObjectDeleter) deleteResource(ctx context.Context, info *resource.Info, deleteOptions *metav1.DeleteOptions) (runtime.Object, error) {
+	deleteResponse, err := info.Client.
+		Delete().
+		NamespaceIfScoped(info.Namespace, info.Mapping.Scope.Name() == meta.RESTScopeNameNamespace).
+		Resource(info.Mapping.Resource.Resource).
+		Name(info.Name).
+		Body(deleteOptions).
+		Do(ctx).
+		Get()
 
 	if err != nil {
 		return nil, cmdutil.AddSourceToErr("deleting", info.Source, err)
@@ -281,59 +785,69 @@ func (o *ObjectDeleter) initDynamicClient() error {
 	return nil
 }
 
-// DeleteClusterRole deletes the clusterrole with the given name.
-func DeleteClusterRole(clientset kubernetes.Interface, name string) error {
+// DeleteClusterRole deletes the clusterrole with the given name, using the given options, which may be nil.
+func DeleteClusterRole(ctx context.Context, clientset kubernetes.Interface, name string, opts *DeleteOptions) error {
 	crs := clientset.RbacV1().ClusterRoles()
-	err := crs.Delete(context.Background(), name, metav1.DeleteOptions{})
+	err := retryPolicyFromContext(ctx).doDelete(ctx, func(ctx context.Context) error {
+		return crs.Delete(ctx, name, opts.toDeleteOptions())
+	})
 	if err != nil {
-		return err
+		return wrapError(err)
 	}
 
 	return nil
 }
 
-// DeleteClusterRoleBinding deletes the clusterrolebinding with the given name.
-func DeleteClusterRoleBinding(clientset kubernetes.Interface, name string) error {
+// DeleteClusterRoleBinding deletes the clusterrolebinding with the given name, using the given options, which may be
+// nil.
+func DeleteClusterRoleBinding(ctx context.Context, clientset kubernetes.Interface, name string, opts *DeleteOptions) error {
 	crbs := clientset.RbacV1().ClusterRoleBindings()
 
-	err := crbs.Delete(context.Background(), name, metav1.DeleteOptions{})
+	err := retryPolicyFromContext(ctx).doDelete(ctx, func(ctx context.Context) error {
+		return crbs.Delete(ctx, name, opts.toDeleteOptions())
+	})
 	if err != nil {
-		return err
+		return wrapError(err)
 	}
 
 	return nil
 }
 
-// DeleteConfigMap deletes the config map in the namespace with the given name.
-func DeleteConfigMap(clientset kubernetes.Interface, name string, namespace string) error {
+// DeleteConfigMap deletes the config map in the namespace with the given name, using the given options, which may be
+// nil.
+func DeleteConfigMap(ctx context.Context, clientset kubernetes.Interface, name string, namespace string, opts *DeleteOptions) error {
 	cm := clientset.CoreV1().ConfigMaps(namespace)
 
-	err := cm.Delete(context.Background(), name, metav1.DeleteOptions{})
+	err := retryPolicyFromContext(ctx).doDelete(ctx, func(ctx context.Context) error {
+		return cm.Delete(ctx, name, opts.toDeleteOptions())
+	})
 	if err != nil {
-		return err
+		return wrapError(err)
 	}
 
 	return nil
 }
 
-// DeleteAllResources deletes all resources in the given namespace with the given selector.
-func DeleteAllResources(clientset kubernetes.Interface, ns string, selectors string) error {
-	err := DeleteDeployments(clientset, ns, selectors)
+// DeleteAllResources deletes all resources in the given namespace with the given selector, using the given options,
+// which may be nil. At most concurrency services or pods are deleted at once, or DefaultDeleteConcurrency if it is
+// zero.
+func DeleteAllResources(ctx context.Context, clientset kubernetes.Interface, ns string, selectors string, concurrency int, opts *DeleteOptions) error {
+	err := DeleteDeployments(ctx, clientset, ns, selectors, opts)
 	if err != nil {
 		return err
 	}
 
-	err = DeleteDaemonSets(clientset, ns, selectors)
+	err = DeleteDaemonSets(ctx, clientset, ns, selectors, opts)
 	if err != nil {
 		return err
 	}
 
-	err = DeleteServices(clientset, ns, selectors)
+	err = DeleteServices(ctx, clientset, ns, selectors, concurrency, opts)
 	if err != nil {
 		return err
 	}
 
-	err = DeletePods(clientset, ns, selectors)
+	err = DeletePods(ctx, clientset, ns, selectors, concurrency, opts)
 	if err != nil {
 		return err
 	}
@@ -341,60 +855,112 @@ func DeleteAllResources(clientset kubernetes.Interface, ns string, selectors str
 	return nil
 }
 
-// DeleteDeployments deletes all deployments in the namespace with the given selector.
-func DeleteDeployments(clientset kubernetes.Interface, namespace string, selectors string) error {
+// DeleteDeployments deletes all deployments in the namespace with the given selector, using the given options, which
+// may be nil.
+func DeleteDeployments(ctx context.Context, clientset kubernetes.Interface, namespace string, selectors string, opts *DeleteOptions) error {
 	deployments := clientset.AppsV1().Deployments(namespace)
 
-	if err := deployments.DeleteCollection(context.Background(), metav1.DeleteOptions{}, metav1.ListOptions{LabelSelector: selectors}); err != nil {
-		return err
+	err := retryPolicyFromContext(ctx).Do(ctx, func(ctx context.Context) error {
+		return deployments.DeleteCollection(ctx, opts.toDeleteOptions(), metav1.ListOptions{LabelSelector: selectors})
+	})
+	if err != nil {
+		return wrapError(err)
 	}
 	return nil
 }
 
-// DeleteDaemonSets deletes all daemonsets in the namespace with the given selector.
-func DeleteDaemonSets(clientset kubernetes.Interface, namespace string, selectors string) error {
+// DeleteDaemonSets deletes all daemonsets in the namespace with the given selector, using the given options, which
+// may be nil.
+func DeleteDaemonSets(ctx context.Context, clientset kubernetes.Interface, namespace string, selectors string, opts *DeleteOptions) error {
 	daemonsets := clientset.AppsV1().DaemonSets(namespace)
 
-	if err := daemonsets.DeleteCollection(context.Background(), metav1.DeleteOptions{}, metav1.ListOptions{LabelSelector: selectors}); err != nil {
-		return err
+	err := retryPolicyFromContext(ctx).Do(ctx, func(ctx context.Context) error {
+		return daemonsets.DeleteCollection(ctx, opts.toDeleteOptions(), metav1.ListOptions{LabelSelector: selectors})
+	})
+	if err != nil {
+		return wrapError(err)
 	}
 	return nil
 }
 
-// DeleteServices deletes all services in the namespace with the given selector.
-func DeleteServices(clientset kubernetes.Interface, namespace string, selectors string) error {
+// DeleteServices deletes all services in the namespace with the given selector, using the given options, which may be
+// nil. At most concurrency services are deleted at once, or DefaultDeleteConcurrency if it is zero. All of the
+// services are attempted, and the errors for those that could not be deleted are aggregated.
+func DeleteServices(ctx context.Context, clientset kubernetes.Interface, namespace string, selectors string, concurrency int, opts *DeleteOptions) error {
 	svcs := clientset.CoreV1().Services(namespace)
 
-	l, err := svcs.List(context.Background(), metav1.ListOptions{LabelSelector: selectors})
-	if err != nil {
+	retry := retryPolicyFromContext(ctx)
+	var l *v1.ServiceList
+	err := retry.Do(ctx, func(ctx context.Context) error {
+		var err error
+		l, err = svcs.List(ctx, metav1.ListOptions{LabelSelector: selectors})
 		return err
+	})
+	if err != nil {
+		return wrapError(err)
 	}
-	for _, s := range l.Items {
-		err = svcs.Delete(context.Background(), s.ObjectMeta.Name, metav1.DeleteOptions{})
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return wrapError(runConcurrently(ctx, len(l.Items), concurrency, func(ctx context.Context, i int) error {
+		return retry.doDelete(ctx, func(ctx context.Context) error {
+			return svcs.Delete(ctx, l.Items[i].ObjectMeta.Name, opts.toDeleteOptions())
+		})
+	}))
 }
 
-// DeletePods deletes all pods in the namespace with the given selector.
-func DeletePods(clientset kubernetes.Interface, namespace string, selectors string) error {
+// DeletePods deletes all pods in the namespace with the given selector, using the given options, which may be nil. At
+// most concurrency pods are deleted at once, or DefaultDeleteConcurrency if it is zero. All of the pods are attempted,
+// and the errors for those that could not be deleted are aggregated.
+func DeletePods(ctx context.Context, clientset kubernetes.Interface, namespace string, selectors string, concurrency int, opts *DeleteOptions) error {
 	pods := clientset.CoreV1().Pods(namespace)
 
-	l, err := pods.List(context.Background(), metav1.ListOptions{LabelSelector: selectors})
-	if err != nil {
+	retry := retryPolicyFromContext(ctx)
+	var l *v1.PodList
+	err := retry.Do(ctx, func(ctx context.Context) error {
+		var err error
+		l, err = pods.List(ctx, metav1.ListOptions{LabelSelector: selectors})
 		return err
+	})
+	if err != nil {
+		return wrapError(err)
 	}
-	for _, s := range l.Items {
-		err = pods.Delete(context.Background(), s.ObjectMeta.Name, metav1.DeleteOptions{})
-		if err != nil {
-			return err
+	return wrapError(runConcurrently(ctx, len(l.Items), concurrency, func(ctx context.Context, i int) error {
+		return retry.doDelete(ctx, func(ctx context.Context) error {
+			return pods.Delete(ctx, l.Items[i].ObjectMeta.Name, opts.toDeleteOptions())
+		})
+	}))
+}
+
+// runConcurrently calls fn for each index in [0, n), with at most concurrency calls running at once, or
+// DefaultDeleteConcurrency if it is zero. Every index is attempted, even if some of the calls fail, unless the context
+// is done first. Returns the aggregate of the errors, or nil if all of the calls succeed.
+func runConcurrently(ctx context.Context, n int, concurrency int, fn func(ctx context.Context, i int) error) error {
+	if concurrency <= 0 {
+		concurrency = DefaultDeleteConcurrency
+	}
+
+	var wg sync.WaitGroup
+	var errsMu sync.Mutex
+	var errs []error
+	sem := make(chan struct{}, concurrency)
+	for i := 0; i < n; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return utilerrors.NewAggregate(append(errs, ctx.Err()))
 		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := fn(ctx, i); err != nil {
+				errsMu.Lock()
+				errs = append(errs, err)
+				errsMu.Unlock()
+			}
+		}(i)
 	}
-
-	return nil
+	wg.Wait()
+	return utilerrors.NewAggregate(errs)
 }
 
 type restClientGetter struct {
@@ -0,0 +1,443 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func testPods(n int) []runtime.Object {
+	objs := make([]runtime.Object, n)
+	for i := range objs {
+		objs[i] = &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("pod-%d", i),
+				Namespace: "pl",
+				Labels:    map[string]string{"app": "pl-monitoring"},
+			},
+		}
+	}
+	return objs
+}
+
+func TestDeletePods(t *testing.T) {
+	clientset := fake.NewSimpleClientset(testPods(25)...)
+
+	err := k8s.DeletePods(context.Background(), clientset, "pl", "app=pl-monitoring", 4, nil)
+	require.NoError(t, err)
+
+	l, err := clientset.CoreV1().Pods("pl").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, l.Items)
+}
+
+func TestDeletePods_AggregatesErrors(t *testing.T) {
+	clientset := fake.NewSimpleClientset(testPods(5)...)
+	clientset.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		name := action.(k8stesting.DeleteAction).GetName()
+		if name == "pod-1" || name == "pod-3" {
+			return true, nil, errors.New("delete failed")
+		}
+		return false, nil, nil
+	})
+
+	err := k8s.DeletePods(context.Background(), clientset, "pl", "", 0, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "delete failed")
+
+	// The pods that could be deleted are, despite the failures.
+	l, err := clientset.CoreV1().Pods("pl").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	names := []string{}
+	for _, p := range l.Items {
+		names = append(names, p.Name)
+	}
+	assert.ElementsMatch(t, []string{"pod-1", "pod-3"}, names)
+}
+
+func TestDeleteServices(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "pl", Labels: map[string]string{"app": "pl"}}},
+		&v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "pl", Labels: map[string]string{"app": "pl"}}},
+		&v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "c", Namespace: "pl", Labels: map[string]string{"app": "other"}}},
+	)
+
+	err := k8s.DeleteServices(context.Background(), clientset, "pl", "app=pl", 2, nil)
+	require.NoError(t, err)
+
+	l, err := clientset.CoreV1().Services("pl").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, l.Items, 1)
+	assert.Equal(t, "c", l.Items[0].Name)
+}
+
+func TestDeleteErrorKinds(t *testing.T) {
+	clientset := fake.NewSimpleClientset(testPods(2)...)
+	clientset.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		name := action.(k8stesting.DeleteAction).GetName()
+		if name == "pod-0" {
+			return true, nil, k8serrors.NewForbidden(schema.GroupResource{Resource: "pods"}, name, errors.New("no access"))
+		}
+		return true, nil, k8serrors.NewConflict(schema.GroupResource{Resource: "pods"}, name, errors.New("modified"))
+	})
+
+	err := k8s.DeletePods(context.Background(), clientset, "pl", "", 0, nil)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, k8s.ErrForbidden)
+	assert.ErrorIs(t, err, k8s.ErrConflict)
+	assert.NotErrorIs(t, err, k8s.ErrNotFound)
+
+	err = k8s.DeleteConfigMap(context.Background(), clientset, "missing", "pl", nil)
+	assert.ErrorIs(t, err, k8s.ErrNotFound)
+	// The client error is still available.
+	assert.True(t, k8serrors.IsNotFound(err))
+	assert.Contains(t, err.Error(), "missing")
+
+	assert.NoError(t, k8s.DeleteDeployments(context.Background(), clientset, "pl", "", nil))
+}
+
+func TestDeleteOptions(t *testing.T) {
+	clientset := fake.NewSimpleClientset(testPods(3)...)
+	var pods []metav1.DeleteOptions
+	clientset.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		pods = append(pods, action.(k8stesting.DeleteActionImpl).DeleteOptions)
+		return false, nil, nil
+	})
+
+	policy := metav1.DeletePropagationForeground
+	gracePeriod := int64(0)
+	opts := &k8s.DeleteOptions{PropagationPolicy: &policy, GracePeriodSeconds: &gracePeriod}
+	require.NoError(t, k8s.DeletePods(context.Background(), clientset, "pl", "", 1, opts))
+
+	require.Len(t, pods, 3)
+	for _, o := range pods {
+		assert.Equal(t, &policy, o.PropagationPolicy)
+		assert.Equal(t, &gracePeriod, o.GracePeriodSeconds)
+	}
+
+	// Without options, the API server's defaults are used.
+	clientset = fake.NewSimpleClientset(testPods(1)...)
+	pods = nil
+	clientset.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		pods = append(pods, action.(k8stesting.DeleteActionImpl).DeleteOptions)
+		return false, nil, nil
+	})
+	require.NoError(t, k8s.DeletePods(context.Background(), clientset, "pl", "", 0, nil))
+	require.Len(t, pods, 1)
+	assert.Nil(t, pods[0].PropagationPolicy)
+	assert.Nil(t, pods[0].GracePeriodSeconds)
+}
+
+// markDeleted sets the deletion timestamp of the object, as if it was deleted while it had finalizers.
+func markDeleted(obj *unstructured.Unstructured) {
+	now := metav1.Now()
+	obj.SetDeletionTimestamp(&now)
+}
+
+func TestObjectDeleter_StripFinalizers_AllowedFinalizers(t *testing.T) {
+	f := newFakeAPIServer(t, fakePods)
+	f.add(fakePods, "pl", "pod-a", map[string]string{"app": "pl"}, "example.com/allowed")
+	f.add(fakePods, "pl", "pod-b", map[string]string{"app": "pl"}, "example.com/allowed", "example.com/kept")
+	f.add(fakePods, "pl", "pod-c", map[string]string{"app": "pl"}, metav1.FinalizerOrphanDependents)
+
+	od := f.deleter()
+	od.Namespace = "pl"
+	od.StripFinalizers = true
+	od.FinalizerGracePeriod = time.Millisecond
+	od.AllowedFinalizers = []string{"example.com/allowed"}
+	od.Timeout = 2500 * time.Millisecond
+	_, err := od.DeleteByLabel(context.Background(), "app=pl", "pods")
+	assert.ErrorIs(t, err, k8s.ErrWaitTimeout)
+
+	assert.Nil(t, f.get(fakePods, "pl", "pod-a"))
+	// Finalizers that are not allowed are never stripped, including the default ones.
+	podB := f.get(fakePods, "pl", "pod-b")
+	require.NotNil(t, podB)
+	assert.Equal(t, []string{"example.com/kept"}, podB.GetFinalizers())
+	podC := f.get(fakePods, "pl", "pod-c")
+	require.NotNil(t, podC)
+	assert.Equal(t, []string{metav1.FinalizerOrphanDependents}, podC.GetFinalizers())
+}
+
+func TestObjectDeleter_StripFinalizers_Namespace(t *testing.T) {
+	f := newFakeAPIServer(t, fakeNamespaces, fakePods)
+	ns := f.add(fakeNamespaces, "", "pl", nil)
+	require.NoError(t, unstructured.SetNestedStringSlice(ns.Object, []string{"kubernetes"}, "spec", "finalizers"))
+	// The contents of the namespace are being deleted by the namespace controller, which is stuck.
+	markDeleted(f.add(fakePods, "pl", "pod-default", nil, metav1.FinalizerDeleteDependents))
+	markDeleted(f.add(fakePods, "pl", "pod-custom", nil, "example.com/custom"))
+	// Objects that aren't being deleted keep their finalizers.
+	f.add(fakePods, "pl", "pod-running", nil, metav1.FinalizerDeleteDependents)
+
+	od := f.deleter()
+	od.Namespace = "pl"
+	od.StripFinalizers = true
+	od.FinalizerGracePeriod = time.Millisecond
+	od.Timeout = 5 * time.Second
+	require.NoError(t, od.DeleteNamespace(context.Background()))
+
+	assert.Nil(t, f.get(fakeNamespaces, "", "pl"))
+	assert.Contains(t, f.requestsWithMethod(http.MethodPut), "PUT /api/v1/namespaces/pl/finalize")
+	assert.Nil(t, f.get(fakePods, "pl", "pod-default"))
+	podCustom := f.get(fakePods, "pl", "pod-custom")
+	require.NotNil(t, podCustom)
+	assert.Equal(t, []string{"example.com/custom"}, podCustom.GetFinalizers())
+	podRunning := f.get(fakePods, "pl", "pod-running")
+	require.NotNil(t, podRunning)
+	assert.Equal(t, []string{metav1.FinalizerDeleteDependents}, podRunning.GetFinalizers())
+}
+
+func TestObjectDeleter_FinalizerGracePeriod(t *testing.T) {
+	f := newFakeAPIServer(t, fakePods)
+	f.add(fakePods, "pl", "pod-a", map[string]string{"app": "pl"}, "example.com/allowed")
+
+	od := f.deleter()
+	od.Namespace = "pl"
+	od.StripFinalizers = true
+	od.FinalizerGracePeriod = time.Hour
+	od.AllowedFinalizers = []string{"example.com/allowed"}
+	od.Timeout = 1500 * time.Millisecond
+	_, err := od.DeleteByLabel(context.Background(), "app=pl", "pods")
+	assert.ErrorIs(t, err, k8s.ErrWaitTimeout)
+
+	// The finalizers aren't stripped before the grace period.
+	assert.Empty(t, f.requestsWithMethod(http.MethodPut))
+	podA := f.get(fakePods, "pl", "pod-a")
+	require.NotNil(t, podA)
+	assert.Equal(t, []string{"example.com/allowed"}, podA.GetFinalizers())
+
+	// Once the grace period passes, they are.
+	od = f.deleter()
+	od.Namespace = "pl"
+	od.StripFinalizers = true
+	od.FinalizerGracePeriod = 1500 * time.Millisecond
+	od.AllowedFinalizers = []string{"example.com/allowed"}
+	od.Timeout = 5 * time.Second
+	start := time.Now()
+	_, err = od.DeleteByLabel(context.Background(), "app=pl", "pods")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 1500*time.Millisecond)
+	assert.Len(t, f.requestsWithMethod(http.MethodPut), 1)
+	assert.Nil(t, f.get(fakePods, "pl", "pod-a"))
+}
+
+func TestObjectDeleter_DryRun(t *testing.T) {
+	f := newFakeAPIServer(t, fakeNamespaces, fakePods)
+	f.add(fakeNamespaces, "", "pl", nil)
+	f.add(fakePods, "pl", "pod-a", map[string]string{"app": "pl"})
+	f.add(fakePods, "pl", "pod-b", map[string]string{"app": "pl"})
+	f.add(fakePods, "pl", "pod-c", map[string]string{"app": "other"})
+
+	od := f.deleter()
+	od.Namespace = "pl"
+	od.DryRun = true
+	found, err := od.DeleteByLabel(context.Background(), "app=pl", "pods")
+	require.NoError(t, err)
+	assert.Equal(t, 2, found)
+	assert.ElementsMatch(t, []k8s.ObjectReference{
+		{Kind: "Pod", Namespace: "pl", Name: "pod-a"},
+		{Kind: "Pod", Namespace: "pl", Name: "pod-b"},
+	}, od.DryRunObjects())
+
+	// Deleting the namespace lists its contents too, and objects that were already listed are only listed once.
+	require.NoError(t, od.DeleteNamespace(context.Background()))
+	assert.ElementsMatch(t, []k8s.ObjectReference{
+		{Kind: "Pod", Namespace: "pl", Name: "pod-a"},
+		{Kind: "Pod", Namespace: "pl", Name: "pod-b"},
+		{Kind: "Pod", Namespace: "pl", Name: "pod-c"},
+		{Kind: "Namespace", Name: "pl"},
+	}, od.DryRunObjects())
+
+	assert.Empty(t, f.requestsWithMethod(http.MethodDelete))
+	assert.NotNil(t, f.get(fakeNamespaces, "", "pl"))
+	assert.NotNil(t, f.get(fakePods, "pl", "pod-a"))
+}
+
+func TestObjectDeleter_DeleteCustomResources(t *testing.T) {
+	f := newFakeAPIServer(t, fakeCRDs, fakeViziers, fakeWidgets)
+	f.addCRD(fakeViziers, map[string]string{"app": "pl-monitoring"})
+	f.addCRD(fakeWidgets, nil)
+	f.add(fakeViziers, "pl", "pixie", nil)
+	f.add(fakeViziers, "other", "pixie", nil)
+	f.add(fakeWidgets, "pl", "widget", nil)
+
+	od := f.deleter()
+	deleted, err := od.DeleteCustomResources(context.Background(), "app=pl-monitoring")
+	require.NoError(t, err)
+	assert.Equal(t, 3, deleted)
+
+	// The custom resources in every namespace are deleted before their CRD.
+	assert.Equal(t, []string{
+		"DELETE /apis/px.dev/v1alpha1/namespaces/other/viziers/pixie",
+		"DELETE /apis/px.dev/v1alpha1/namespaces/pl/viziers/pixie",
+		"DELETE /apis/apiextensions.k8s.io/v1/customresourcedefinitions/viziers.px.dev",
+	}, sortedCRDeletes(f.requestsWithMethod(http.MethodDelete)))
+	assert.Nil(t, f.get(fakeCRDs, "", "viziers.px.dev"))
+	// CRDs that don't match the selector, and their custom resources, are left alone.
+	assert.NotNil(t, f.get(fakeCRDs, "", "widgets.other.dev"))
+	assert.NotNil(t, f.get(fakeWidgets, "pl", "widget"))
+
+	// CRDs can also be selected by their API group.
+	deleted, err = od.DeleteCustomResources(context.Background(), "", "other.dev")
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+	assert.Nil(t, f.get(fakeCRDs, "", "widgets.other.dev"))
+	assert.Nil(t, f.get(fakeWidgets, "pl", "widget"))
+}
+
+// sortedCRDeletes sorts the deletes of custom resources, which are made concurrently, while keeping them before the
+// deletes of the CRDs.
+func sortedCRDeletes(reqs []string) []string {
+	isCRD := func(r string) bool { return strings.Contains(r, "/customresourcedefinitions/") }
+	sorted := append([]string{}, reqs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if isCRD(sorted[i]) != isCRD(sorted[j]) {
+			return false
+		}
+		return sorted[i] < sorted[j]
+	})
+	return sorted
+}
+
+func TestObjectDeleter_DeleteByLabel_Namespaces(t *testing.T) {
+	f := newFakeAPIServer(t, fakeNamespaces, fakePods)
+	f.add(fakeNamespaces, "", "pl-extra", map[string]string{"app": "pl"})
+	for _, ns := range []string{"pl", "plc", "other"} {
+		f.add(fakePods, ns, "pod-a", map[string]string{"app": "pl"})
+	}
+
+	od := f.deleter()
+	od.Namespace = "pl"
+	od.Namespaces = []string{"plc", "pl", "plc"}
+	found, err := od.DeleteByLabel(context.Background(), "app=pl", "pods", "namespaces")
+	require.NoError(t, err)
+	assert.Equal(t, 3, found)
+
+	// The cluster-scoped namespace is matched from both namespaces, but only deleted once.
+	assert.ElementsMatch(t, []string{
+		"DELETE /api/v1/namespaces/pl/pods/pod-a",
+		"DELETE /api/v1/namespaces/plc/pods/pod-a",
+		"DELETE /api/v1/namespaces/pl-extra",
+	}, f.requestsWithMethod(http.MethodDelete))
+	assert.NotNil(t, f.get(fakePods, "other", "pod-a"))
+}
+
+func TestObjectDeleter_DeleteByLabel_AllNamespaces(t *testing.T) {
+	f := newFakeAPIServer(t, fakeNamespaces, fakePods)
+	for _, ns := range []string{"pl", "plc", "other"} {
+		f.add(fakePods, ns, "pod-a", map[string]string{"app": "pl"})
+		f.add(fakePods, ns, "pod-b", map[string]string{"app": "other"})
+	}
+
+	od := f.deleter()
+	od.Namespace = "pl"
+	// Namespaces is ignored when deleting from all namespaces.
+	od.Namespaces = []string{"pl", "plc"}
+	od.AllNamespaces = true
+	found, err := od.DeleteByLabel(context.Background(), "app=pl", "pods")
+	require.NoError(t, err)
+	assert.Equal(t, 3, found)
+
+	assert.ElementsMatch(t, []string{
+		"DELETE /api/v1/namespaces/pl/pods/pod-a",
+		"DELETE /api/v1/namespaces/plc/pods/pod-a",
+		"DELETE /api/v1/namespaces/other/pods/pod-a",
+	}, f.requestsWithMethod(http.MethodDelete))
+	for _, ns := range []string{"pl", "plc", "other"} {
+		assert.NotNil(t, f.get(fakePods, ns, "pod-b"))
+	}
+}
+
+type recordingDeleteProgress struct {
+	mu      sync.Mutex
+	deleted []k8s.ObjectReference
+	waiting []int
+}
+
+func (p *recordingDeleteProgress) OnDeleted(obj k8s.ObjectReference) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.deleted = append(p.deleted, obj)
+}
+
+func (p *recordingDeleteProgress) OnWaiting(remaining int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.waiting = append(p.waiting, remaining)
+}
+
+func TestObjectDeleter_Progress(t *testing.T) {
+	f := newFakeAPIServer(t, fakeNamespaces, fakePods)
+	f.add(fakePods, "pl", "pod-a", map[string]string{"app": "pl"})
+	f.add(fakePods, "pl", "pod-b", map[string]string{"app": "pl"})
+	f.add(fakePods, "pl", "pod-c", map[string]string{"app": "pl"}, "example.com/hold")
+
+	// pod-c is held by its finalizer until it has been checked once after being deleted.
+	checks := 0
+	f.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/pods/pod-c") {
+			checks++
+			if checks == 2 {
+				f.removeLocked(fakePods, "pl", "pod-c")
+			}
+		}
+		return false
+	}
+
+	progress := &recordingDeleteProgress{}
+	od := f.deleter()
+	od.Namespace = "pl"
+	od.Progress = progress
+	found, err := od.DeleteByLabel(context.Background(), "app=pl", "pods")
+	require.NoError(t, err)
+	assert.Equal(t, 3, found)
+
+	progress.mu.Lock()
+	defer progress.mu.Unlock()
+	require.Len(t, progress.deleted, 3)
+	assert.ElementsMatch(t, []k8s.ObjectReference{
+		{Kind: "Pod", Namespace: "pl", Name: "pod-a"},
+		{Kind: "Pod", Namespace: "pl", Name: "pod-b"},
+	}, progress.deleted[:2])
+	assert.Equal(t, []k8s.ObjectReference{
+		{Kind: "Pod", Namespace: "pl", Name: "pod-c"},
+	}, progress.deleted[2:])
+	assert.Equal(t, []int{1, 0}, progress.waiting)
+}
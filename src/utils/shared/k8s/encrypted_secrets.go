@@ -0,0 +1,189 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// SecretFormatSecret emits plain Kubernetes Secrets.
+	SecretFormatSecret = "secret"
+	// SecretFormatSealedSecret emits Bitnami SealedSecrets, encrypted with the sealed-secrets controller's certificate.
+	SecretFormatSealedSecret = "sealed-secret"
+	// SecretFormatExternalSecret emits ExternalSecrets which reference values held in an external secret store.
+	SecretFormatExternalSecret = "external-secret"
+)
+
+// IsValidSecretFormat returns whether the given string is one of the supported secret formats.
+func IsValidSecretFormat(format string) bool {
+	switch format {
+	case SecretFormatSecret, SecretFormatSealedSecret, SecretFormatExternalSecret:
+		return true
+	}
+	return false
+}
+
+// ParseSealedSecretsCert parses the PEM encoded certificate of a sealed-secrets controller, as output by
+// `kubeseal --fetch-cert`, and returns its public key.
+func ParseSealedSecretsCert(certPEM []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, errors.New("no PEM data found in sealed-secrets certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	pubKey, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("sealed-secrets certificate does not contain an RSA public key")
+	}
+	return pubKey, nil
+}
+
+// sealValue encrypts the value in the same hybrid scheme as kubeseal: a random AES-GCM session key encrypts the
+// value, and the session key is itself encrypted with RSA-OAEP using the label.
+func sealValue(pubKey *rsa.PublicKey, value, label []byte) ([]byte, error) {
+	sessionKey := make([]byte, 32)
+	if _, err := rand.Read(sessionKey); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+	aed, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaCiphertext, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pubKey, sessionKey, label)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext := make([]byte, 2, 2+len(rsaCiphertext)+len(value)+aed.Overhead())
+	binary.BigEndian.PutUint16(ciphertext, uint16(len(rsaCiphertext)))
+	ciphertext = append(ciphertext, rsaCiphertext...)
+
+	// The session key is only ever used once, so a zero nonce is safe.
+	zeroNonce := make([]byte, aed.NonceSize())
+	return aed.Seal(ciphertext, zeroNonce, value, nil), nil
+}
+
+// CreateSealedSecretFromLiterals creates a strict-scoped SealedSecret, which the sealed-secrets controller in the
+// cluster decrypts into a generic secret with the given name and literals. Only that controller can read the values.
+func CreateSealedSecretFromLiterals(namespace, name string, fromLiterals map[string]string, pubKey *rsa.PublicKey) (*unstructured.Unstructured, error) {
+	label := []byte(fmt.Sprintf("%s/%s", namespace, name))
+	encryptedData := make(map[string]interface{})
+	for k, v := range fromLiterals {
+		sealed, err := sealValue(pubKey, []byte(v), label)
+		if err != nil {
+			return nil, err
+		}
+		encryptedData[k] = base64.StdEncoding.EncodeToString(sealed)
+	}
+
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "bitnami.com/v1alpha1",
+			"kind":       "SealedSecret",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": namespace,
+			},
+			"spec": map[string]interface{}{
+				"encryptedData": encryptedData,
+				"template": map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      name,
+						"namespace": namespace,
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+// CreateExternalSecret creates an ExternalSecret, which the external-secrets operator in the cluster syncs into a
+// generic secret with the given name. remoteKeys maps each key in the secret to its key in the secret store.
+func CreateExternalSecret(namespace, name, storeName, storeKind string, remoteKeys map[string]string) (*unstructured.Unstructured, error) {
+	if storeName == "" {
+		return nil, errors.New("a secret store must be specified for an ExternalSecret")
+	}
+	if storeKind == "" {
+		storeKind = "SecretStore"
+	}
+	if storeKind != "SecretStore" && storeKind != "ClusterSecretStore" {
+		return nil, fmt.Errorf("invalid secret store kind %q, must be SecretStore or ClusterSecretStore", storeKind)
+	}
+
+	keys := make([]string, 0, len(remoteKeys))
+	for k := range remoteKeys {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	data := make([]interface{}, 0, len(keys))
+	for _, k := range keys {
+		data = append(data, map[string]interface{}{
+			"secretKey": k,
+			"remoteRef": map[string]interface{}{
+				"key": remoteKeys[k],
+			},
+		})
+	}
+
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "external-secrets.io/v1beta1",
+			"kind":       "ExternalSecret",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": namespace,
+			},
+			"spec": map[string]interface{}{
+				"refreshInterval": "1h",
+				"secretStoreRef": map[string]interface{}{
+					"name": storeName,
+					"kind": storeKind,
+				},
+				"target": map[string]interface{}{
+					"name":           name,
+					"creationPolicy": "Owner",
+				},
+				"data": data,
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s_test
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+// unseal decrypts a value the way the sealed-secrets controller does.
+func unseal(t *testing.T, key *rsa.PrivateKey, value string, label []byte) string {
+	ciphertext, err := base64.StdEncoding.DecodeString(value)
+	require.NoError(t, err)
+
+	rsaLen := int(binary.BigEndian.Uint16(ciphertext))
+	sessionKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, key, ciphertext[2:2+rsaLen], label)
+	require.NoError(t, err)
+
+	block, err := aes.NewCipher(sessionKey)
+	require.NoError(t, err)
+	aed, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	plaintext, err := aed.Open(nil, make([]byte, aed.NonceSize()), ciphertext[2+rsaLen:], nil)
+	require.NoError(t, err)
+	return string(plaintext)
+}
+
+func TestCreateSealedSecretFromLiterals(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	s, err := k8s.CreateSealedSecretFromLiterals("pl", "pl-deploy-secrets", map[string]string{
+		"deploy-key": "abcd",
+	}, &key.PublicKey)
+	require.NoError(t, err)
+
+	assert.Equal(t, "SealedSecret", s.GetKind())
+	assert.Equal(t, "pl-deploy-secrets", s.GetName())
+	assert.Equal(t, "pl", s.GetNamespace())
+
+	sealed, found, err := unstructured.NestedString(s.Object, "spec", "encryptedData", "deploy-key")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.NotContains(t, sealed, "abcd")
+	assert.Equal(t, "abcd", unseal(t, key, sealed, []byte("pl/pl-deploy-secrets")))
+
+	_, err = k8s.ConvertResourceToYAML(s)
+	require.NoError(t, err)
+}
+
+func TestCreateExternalSecret(t *testing.T) {
+	s, err := k8s.CreateExternalSecret("pl", "pl-deploy-secrets", "vault", "ClusterSecretStore", map[string]string{
+		"deploy-key": "pixie/deploy-key",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "ExternalSecret", s.GetKind())
+	target, _, _ := unstructured.NestedString(s.Object, "spec", "target", "name")
+	assert.Equal(t, "pl-deploy-secrets", target)
+	store, _, _ := unstructured.NestedStringMap(s.Object, "spec", "secretStoreRef")
+	assert.Equal(t, map[string]string{"name": "vault", "kind": "ClusterSecretStore"}, store)
+	data, _, _ := unstructured.NestedSlice(s.Object, "spec", "data")
+	require.Len(t, data, 1)
+	assert.Equal(t, map[string]interface{}{
+		"secretKey": "deploy-key",
+		"remoteRef": map[string]interface{}{"key": "pixie/deploy-key"},
+	}, data[0])
+
+	_, err = k8s.CreateExternalSecret("pl", "pl-deploy-secrets", "", "", nil)
+	assert.Error(t, err)
+	_, err = k8s.CreateExternalSecret("pl", "pl-deploy-secrets", "vault", "Vault", nil)
+	assert.Error(t, err)
+}
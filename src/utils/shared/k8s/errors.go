@@ -0,0 +1,102 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s
+
+import (
+	"context"
+	"errors"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// The kinds of errors returned by the K8s helpers in this package. The returned errors wrap the underlying client
+// errors, and can be checked against these with errors.Is.
+var (
+	// ErrNotFound means that an object does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrForbidden means that the user is not authorized to perform the operation.
+	ErrForbidden = errors.New("forbidden")
+	// ErrWaitTimeout means that a timeout expired while waiting on the cluster.
+	ErrWaitTimeout = errors.New("timed out waiting")
+	// ErrConflict means that an object already exists, or was modified concurrently.
+	ErrConflict = errors.New("conflict")
+	// ErrRolloutFailed means that a watched object failed to roll out, for example because its image can't be pulled.
+	ErrRolloutFailed = errors.New("rollout failed")
+)
+
+// kindError is an error that has been classified as one of the error kinds.
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() error {
+	return e.err
+}
+
+func (e *kindError) Is(target error) bool {
+	return target == e.kind
+}
+
+// errorKind returns the kind of the given error, or nil if it is not one of the error kinds.
+func errorKind(err error) error {
+	switch {
+	case k8serrors.IsNotFound(err):
+		return ErrNotFound
+	case k8serrors.IsForbidden(err), k8serrors.IsUnauthorized(err):
+		return ErrForbidden
+	case k8serrors.IsConflict(err), k8serrors.IsAlreadyExists(err):
+		return ErrConflict
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, wait.ErrWaitTimeout),
+		k8serrors.IsTimeout(err), k8serrors.IsServerTimeout(err):
+		return ErrWaitTimeout
+	default:
+		return nil
+	}
+}
+
+// wrapError classifies the given error as one of the error kinds. Each of the errors in an aggregate is classified
+// separately. Errors that are not of any kind are returned as is.
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var ke *kindError
+	if errors.As(err, &ke) {
+		return err
+	}
+	if agg, ok := err.(utilerrors.Aggregate); ok {
+		errs := agg.Errors()
+		wrapped := make([]error, len(errs))
+		for i, e := range errs {
+			wrapped[i] = wrapError(e)
+		}
+		return utilerrors.NewAggregate(wrapped)
+	}
+	if kind := errorKind(err); kind != nil {
+		return &kindError{kind: kind, err: err}
+	}
+	return err
+}
@@ -0,0 +1,343 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+// fakeResource is a resource type served by the fakeAPIServer.
+type fakeResource struct {
+	gv         schema.GroupVersion
+	kind       string
+	resource   string
+	namespaced bool
+}
+
+var (
+	fakeNamespaces = fakeResource{schema.GroupVersion{Version: "v1"}, "Namespace", "namespaces", false}
+	fakePods       = fakeResource{schema.GroupVersion{Version: "v1"}, "Pod", "pods", true}
+	fakeCRDs       = fakeResource{schema.GroupVersion{Group: "apiextensions.k8s.io", Version: "v1"}, "CustomResourceDefinition", "customresourcedefinitions", false}
+	fakeViziers    = fakeResource{schema.GroupVersion{Group: "px.dev", Version: "v1alpha1"}, "Vizier", "viziers", true}
+	fakeWidgets    = fakeResource{schema.GroupVersion{Group: "other.dev", Version: "v1"}, "Widget", "widgets", true}
+)
+
+// fakeAPIServer is a minimal K8s API server, with just enough of discovery, and of get, list, update and delete, for
+// the ObjectDeleter. Objects with finalizers are only marked as deleted, and are removed once their finalizers are.
+type fakeAPIServer struct {
+	resources []fakeResource
+	srv       *httptest.Server
+
+	mu      sync.Mutex
+	objects map[string]*unstructured.Unstructured
+	// requests are the mutating requests that were made, as "<method> <path>".
+	requests []string
+	// intercept, if set, is called for each resource request before it is served. It returns true if it handled
+	// the request itself. It is called with mu held, so it may modify the objects.
+	intercept func(w http.ResponseWriter, r *http.Request) bool
+}
+
+func newFakeAPIServer(t *testing.T, resources ...fakeResource) *fakeAPIServer {
+	f := &fakeAPIServer{
+		resources: resources,
+		objects:   make(map[string]*unstructured.Unstructured),
+	}
+	f.srv = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+// deleter returns an ObjectDeleter that talks to the server.
+func (f *fakeAPIServer) deleter() *k8s.ObjectDeleter {
+	return &k8s.ObjectDeleter{RestConfig: &rest.Config{Host: f.srv.URL}}
+}
+
+// addCRD stores a CustomResourceDefinition for the resource type.
+func (f *fakeAPIServer) addCRD(res fakeResource, objLabels map[string]string) {
+	crd := f.add(fakeCRDs, "", res.resource+"."+res.gv.Group, objLabels)
+	_ = unstructured.SetNestedField(crd.Object, res.gv.Group, "spec", "group")
+	_ = unstructured.SetNestedField(crd.Object, res.resource, "spec", "names", "plural")
+	_ = unstructured.SetNestedField(crd.Object, res.kind, "spec", "names", "kind")
+}
+
+func objectKey(res fakeResource, ns, name string) string {
+	return path.Join(res.gv.String(), res.resource, ns, name)
+}
+
+// add stores a new object of the resource type.
+func (f *fakeAPIServer) add(res fakeResource, ns, name string, objLabels map[string]string, finalizers ...string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(res.gv.String())
+	obj.SetKind(res.kind)
+	obj.SetNamespace(ns)
+	obj.SetName(name)
+	obj.SetUID(types.UID("uid-" + name))
+	obj.SetLabels(objLabels)
+	obj.SetFinalizers(finalizers)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.objects[objectKey(res, ns, name)] = obj
+	return obj
+}
+
+// get returns the stored object, or nil if there is none.
+func (f *fakeAPIServer) get(res fakeResource, ns, name string) *unstructured.Unstructured {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.objects[objectKey(res, ns, name)]
+}
+
+// removeLocked drops the object from the store, as a controller completing its finalizer would.
+func (f *fakeAPIServer) removeLocked(res fakeResource, ns, name string) {
+	delete(f.objects, objectKey(res, ns, name))
+}
+
+// requestsWithMethod returns the requests that were made with the method, as "<method> <path>".
+func (f *fakeAPIServer) requestsWithMethod(method string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var reqs []string
+	for _, r := range f.requests {
+		if strings.HasPrefix(r, method+" ") {
+			reqs = append(reqs, r)
+		}
+	}
+	return reqs
+}
+
+func writeJSON(w http.ResponseWriter, code int, obj interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(obj)
+}
+
+func writeStatusError(w http.ResponseWriter, err *k8serrors.StatusError) {
+	status := err.Status()
+	status.Kind = "Status"
+	status.APIVersion = "v1"
+	writeJSON(w, int(status.Code), status)
+}
+
+func (f *fakeAPIServer) serve(w http.ResponseWriter, r *http.Request) {
+	p := strings.Trim(r.URL.Path, "/")
+	switch {
+	case p == "api":
+		writeJSON(w, http.StatusOK, &metav1.APIVersions{
+			TypeMeta: metav1.TypeMeta{Kind: "APIVersions"},
+			Versions: []string{"v1"},
+		})
+		return
+	case p == "apis":
+		f.serveGroups(w)
+		return
+	}
+
+	var gv schema.GroupVersion
+	var segs []string
+	parts := strings.Split(p, "/")
+	switch {
+	case parts[0] == "api" && len(parts) >= 2:
+		gv = schema.GroupVersion{Version: parts[1]}
+		segs = parts[2:]
+	case parts[0] == "apis" && len(parts) >= 3:
+		gv = schema.GroupVersion{Group: parts[1], Version: parts[2]}
+		segs = parts[3:]
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if len(segs) == 0 {
+		f.serveResourceList(w, gv)
+		return
+	}
+
+	ns := ""
+	if segs[0] == "namespaces" && len(segs) >= 3 {
+		if _, ok := f.lookup(gv, segs[2]); ok {
+			ns = segs[1]
+			segs = segs[2:]
+		}
+	}
+	res, ok := f.lookup(gv, segs[0])
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	name := ""
+	if len(segs) > 1 {
+		name = segs[1]
+	}
+	subresource := ""
+	if len(segs) > 2 {
+		subresource = segs[2]
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Method != http.MethodGet {
+		f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	}
+	if f.intercept != nil && f.intercept(w, r) {
+		return
+	}
+
+	if name == "" {
+		f.serveListLocked(w, r, res, ns)
+		return
+	}
+	key := objectKey(res, ns, name)
+	obj, ok := f.objects[key]
+	if !ok {
+		writeStatusError(w, k8serrors.NewNotFound(schema.GroupResource{Group: res.gv.Group, Resource: res.resource}, name))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, obj.Object)
+	case http.MethodDelete:
+		if hasFinalizers(obj) {
+			if obj.GetDeletionTimestamp() == nil {
+				now := metav1.Now()
+				obj.SetDeletionTimestamp(&now)
+			}
+		} else {
+			delete(f.objects, key)
+		}
+		writeJSON(w, http.StatusOK, obj.Object)
+	case http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		updated := &unstructured.Unstructured{}
+		if err := updated.UnmarshalJSON(body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if subresource == "finalize" {
+			// The finalize subresource only updates the finalizers in the spec of a namespace.
+			finalizers, _, _ := unstructured.NestedStringSlice(updated.Object, "spec", "finalizers")
+			_ = unstructured.SetNestedStringSlice(obj.Object, finalizers, "spec", "finalizers")
+		} else {
+			updated.SetDeletionTimestamp(obj.GetDeletionTimestamp())
+			obj = updated
+			f.objects[key] = obj
+		}
+		if obj.GetDeletionTimestamp() != nil && !hasFinalizers(obj) {
+			delete(f.objects, key)
+		}
+		writeJSON(w, http.StatusOK, obj.Object)
+	default:
+		http.Error(w, "unsupported method", http.StatusMethodNotAllowed)
+	}
+}
+
+func hasFinalizers(obj *unstructured.Unstructured) bool {
+	specFinalizers, _, _ := unstructured.NestedStringSlice(obj.Object, "spec", "finalizers")
+	return len(obj.GetFinalizers()) > 0 || len(specFinalizers) > 0
+}
+
+func (f *fakeAPIServer) lookup(gv schema.GroupVersion, resource string) (fakeResource, bool) {
+	for _, res := range f.resources {
+		if res.gv == gv && res.resource == resource {
+			return res, true
+		}
+	}
+	return fakeResource{}, false
+}
+
+func (f *fakeAPIServer) serveGroups(w http.ResponseWriter) {
+	list := &metav1.APIGroupList{TypeMeta: metav1.TypeMeta{Kind: "APIGroupList", APIVersion: "v1"}}
+	seen := map[string]bool{}
+	for _, res := range f.resources {
+		if res.gv.Group == "" || seen[res.gv.Group] {
+			continue
+		}
+		seen[res.gv.Group] = true
+		version := metav1.GroupVersionForDiscovery{GroupVersion: res.gv.String(), Version: res.gv.Version}
+		list.Groups = append(list.Groups, metav1.APIGroup{
+			Name:             res.gv.Group,
+			Versions:         []metav1.GroupVersionForDiscovery{version},
+			PreferredVersion: version,
+		})
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (f *fakeAPIServer) serveResourceList(w http.ResponseWriter, gv schema.GroupVersion) {
+	list := &metav1.APIResourceList{
+		TypeMeta:     metav1.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+		GroupVersion: gv.String(),
+	}
+	for _, res := range f.resources {
+		if res.gv != gv {
+			continue
+		}
+		list.APIResources = append(list.APIResources, metav1.APIResource{
+			Name:       res.resource,
+			Namespaced: res.namespaced,
+			Kind:       res.kind,
+			Verbs:      []string{"get", "list", "update", "delete"},
+		})
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (f *fakeAPIServer) serveListLocked(w http.ResponseWriter, r *http.Request, res fakeResource, ns string) {
+	selector, err := labels.Parse(r.URL.Query().Get("labelSelector"))
+	if err != nil {
+		writeStatusError(w, k8serrors.NewBadRequest(err.Error()))
+		return
+	}
+	prefix := path.Join(res.gv.String(), res.resource) + "/"
+	if ns != "" {
+		prefix += ns + "/"
+	}
+	items := []interface{}{}
+	for key, obj := range f.objects {
+		if strings.HasPrefix(key, prefix) && selector.Matches(labels.Set(obj.GetLabels())) {
+			items = append(items, obj.Object)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"apiVersion": res.gv.String(),
+		"kind":       res.kind + "List",
+		"metadata":   map[string]interface{}{"resourceVersion": "1"},
+		"items":      items,
+	})
+}
@@ -0,0 +1,382 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// DefaultReconnectPolicy is how a PortForwarder retries connecting to a pod when the tunnel to the previous one is
+// lost. It retries until the PortForwarder is stopped.
+var DefaultReconnectPolicy = RetryPolicy{
+	MaxAttempts:    math.MaxInt32,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+// PortForwarder forwards a local port to a pod over a SPDY tunnel through the API server, like
+// `kubectl port-forward`. The pod is picked from a service or a pod label selector, and a new pod is picked whenever
+// the tunnel is lost, for example because the pod was deleted or restarted, so that the local port remains usable
+// across rollouts.
+type PortForwarder struct {
+	clientset kubernetes.Interface
+	config    *rest.Config
+	namespace string
+
+	// Service is the name of the service to forward to. RemotePort is then a port of the service, which is mapped
+	// to the target port of the picked pod.
+	Service string
+	// Selector is the label selector of the pods to forward to, used when Service is empty. RemotePort is then a
+	// port of the pod.
+	Selector string
+	// RemotePort is the service or pod port to forward to.
+	RemotePort int32
+	// LocalPort is the local port to listen on. If zero, a free port is picked on the first connection and kept
+	// when reconnecting.
+	LocalPort int32
+	// Address is the local address to listen on. Defaults to localhost.
+	Address string
+	// ReconnectPolicy configures how to retry connecting to a pod after the tunnel is lost. If its MaxAttempts is
+	// zero, DefaultReconnectPolicy is used.
+	ReconnectPolicy RetryPolicy
+
+	mu        sync.Mutex
+	localPort int32
+	pod       string
+	done      chan struct{}
+}
+
+// NewPortForwarder creates a PortForwarder for the pods in the given namespace. Either Service or Selector, and
+// RemotePort must be set before it is started.
+func NewPortForwarder(clientset kubernetes.Interface, config *rest.Config, namespace string) *PortForwarder {
+	return &PortForwarder{
+		clientset: clientset,
+		config:    config,
+		namespace: namespace,
+		done:      make(chan struct{}),
+	}
+}
+
+// tunnel is a single port-forward connection to a pod.
+type tunnel struct {
+	pod    string
+	stopCh chan struct{}
+	errCh  chan error
+}
+
+// stop closes the tunnel and waits for it to stop listening.
+func (t *tunnel) stop() {
+	close(t.stopCh)
+	<-t.errCh
+}
+
+// Start connects to a pod and starts listening on the local port. After the first connection, the PortForwarder
+// keeps reconnecting in the background until the context is done. Returns an error if no pod can be connected to.
+func (p *PortForwarder) Start(ctx context.Context) error {
+	t, err := p.connect(ctx)
+	if err != nil {
+		return err
+	}
+	go p.run(ctx, t)
+	return nil
+}
+
+// LocalAddr returns the local address that is forwarded to the pod, once started.
+func (p *PortForwarder) LocalAddr() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return net.JoinHostPort(p.address(), strconv.Itoa(int(p.localPort)))
+}
+
+// Pod returns the name of the pod that the local port is currently forwarded to.
+func (p *PortForwarder) Pod() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pod
+}
+
+// Done returns a channel that is closed once the PortForwarder has stopped listening, after the context given to
+// Start is done.
+func (p *PortForwarder) Done() <-chan struct{} {
+	return p.done
+}
+
+func (p *PortForwarder) address() string {
+	if p.Address == "" {
+		return "localhost"
+	}
+	return p.Address
+}
+
+func (p *PortForwarder) reconnectPolicy() RetryPolicy {
+	policy := p.ReconnectPolicy
+	if policy.MaxAttempts == 0 {
+		policy = DefaultReconnectPolicy
+	}
+	if policy.Retryable == nil {
+		// Unlike API calls, connecting fails for reasons such as no pod being ready yet, which often resolve
+		// themselves while a rollout progresses.
+		policy.Retryable = func(err error) bool { return true }
+	}
+	return policy
+}
+
+// run waits for the tunnel to be lost and reconnects, until the context is done.
+func (p *PortForwarder) run(ctx context.Context, t *tunnel) {
+	defer close(p.done)
+	for {
+		err := p.awaitDisconnect(ctx, t)
+		if ctx.Err() != nil {
+			return
+		}
+		log.WithError(err).WithField("pod", t.pod).Info("Lost port-forward connection to pod, reconnecting")
+
+		err = p.reconnectPolicy().Do(ctx, func(ctx context.Context) error {
+			var err error
+			t, err = p.connect(ctx)
+			if err != nil {
+				log.WithError(err).Debug("Failed to reconnect port-forward")
+			}
+			return err
+		})
+		if err != nil {
+			if ctx.Err() == nil {
+				log.WithError(err).Error("Giving up reconnecting port-forward")
+			}
+			return
+		}
+	}
+}
+
+// awaitDisconnect blocks until the tunnel is lost, the pod it is connected to is deleted or starts terminating, or
+// the context is done. The tunnel is stopped before returning.
+func (p *PortForwarder) awaitDisconnect(ctx context.Context, t *tunnel) error {
+	// The tunnel isn't always closed promptly when the pod goes away, so watch the pod as well, in order to move
+	// on to a new pod as soon as possible.
+	var events <-chan watch.Event
+	w, err := p.clientset.CoreV1().Pods(p.namespace).Watch(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("metadata.name", t.pod).String(),
+	})
+	if err != nil {
+		log.WithError(err).Debug("Failed to watch port-forwarded pod")
+	} else {
+		defer w.Stop()
+		events = w.ResultChan()
+	}
+
+	for {
+		select {
+		case err := <-t.errCh:
+			if err == nil {
+				err = fmt.Errorf("connection to pod %s closed", t.pod)
+			}
+			return err
+		case <-ctx.Done():
+			t.stop()
+			return ctx.Err()
+		case ev, ok := <-events:
+			if !ok {
+				// The watch expired, which doesn't mean that the tunnel is lost.
+				events = nil
+				continue
+			}
+			pod, isPod := ev.Object.(*v1.Pod)
+			if ev.Type == watch.Deleted || (isPod && pod.DeletionTimestamp != nil) {
+				t.stop()
+				return fmt.Errorf("pod %s is terminating", t.pod)
+			}
+		}
+	}
+}
+
+// connect picks a pod and starts forwarding the local port to it.
+func (p *PortForwarder) connect(ctx context.Context) (*tunnel, error) {
+	pod, remotePort, err := p.ResolvePod(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	transport, upgrader, err := spdy.RoundTripperFor(p.config)
+	if err != nil {
+		return nil, err
+	}
+	req := p.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(pod.Namespace).
+		Name(pod.Name).
+		SubResource("portforward")
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, req.URL())
+
+	p.mu.Lock()
+	localPort := p.localPort
+	if localPort == 0 {
+		localPort = p.LocalPort
+	}
+	p.mu.Unlock()
+
+	t := &tunnel{
+		pod:    pod.Name,
+		stopCh: make(chan struct{}),
+		errCh:  make(chan error, 1),
+	}
+	readyCh := make(chan struct{})
+	fw, err := portforward.NewOnAddresses(dialer, []string{p.address()},
+		[]string{fmt.Sprintf("%d:%d", localPort, remotePort)}, t.stopCh, readyCh, io.Discard, io.Discard)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		t.errCh <- fw.ForwardPorts()
+	}()
+
+	select {
+	case <-readyCh:
+	case err := <-t.errCh:
+		if err == nil {
+			err = fmt.Errorf("connection to pod %s closed", pod.Name)
+		}
+		return nil, err
+	case <-ctx.Done():
+		t.stop()
+		return nil, wrapError(ctx.Err())
+	}
+
+	ports, err := fw.GetPorts()
+	if err != nil || len(ports) == 0 {
+		t.stop()
+		return nil, fmt.Errorf("failed to get forwarded ports: %v", err)
+	}
+
+	p.mu.Lock()
+	p.localPort = int32(ports[0].Local)
+	p.pod = pod.Name
+	p.mu.Unlock()
+
+	log.WithField("pod", pod.Name).WithField("addr", p.LocalAddr()).Debug("Port-forward connected")
+	return t, nil
+}
+
+// ResolvePod picks the pod to forward to, and returns it with the container port that RemotePort maps to. Running
+// pods that are ready and not terminating are preferred, newest first. Returns ErrNotFound if there is no such pod.
+func (p *PortForwarder) ResolvePod(ctx context.Context) (*v1.Pod, int32, error) {
+	selector := p.Selector
+	var svcPort *v1.ServicePort
+	if p.Service != "" {
+		svc, err := p.clientset.CoreV1().Services(p.namespace).Get(ctx, p.Service, metav1.GetOptions{})
+		if err != nil {
+			return nil, 0, wrapError(err)
+		}
+		if len(svc.Spec.Selector) == 0 {
+			return nil, 0, fmt.Errorf("service %s has no pod selector", p.Service)
+		}
+		for i := range svc.Spec.Ports {
+			if svc.Spec.Ports[i].Port == p.RemotePort {
+				svcPort = &svc.Spec.Ports[i]
+				break
+			}
+		}
+		if svcPort == nil {
+			return nil, 0, fmt.Errorf("service %s has no port %d", p.Service, p.RemotePort)
+		}
+		selector = labels.SelectorFromSet(svc.Spec.Selector).String()
+	}
+
+	pods, err := p.clientset.CoreV1().Pods(p.namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, 0, wrapError(err)
+	}
+	var candidates []*v1.Pod
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase == v1.PodRunning && pod.DeletionTimestamp == nil {
+			candidates = append(candidates, pod)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, 0, &kindError{kind: ErrNotFound, err: fmt.Errorf("no running pods match %q", selector)}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		ri, rj := isPodReady(candidates[i]), isPodReady(candidates[j])
+		if ri != rj {
+			return ri
+		}
+		return candidates[j].CreationTimestamp.Before(&candidates[i].CreationTimestamp)
+	})
+	pod := candidates[0]
+
+	if svcPort == nil {
+		return pod, p.RemotePort, nil
+	}
+	port, err := containerPort(pod, svcPort)
+	if err != nil {
+		return nil, 0, err
+	}
+	return pod, port, nil
+}
+
+// containerPort returns the port of the pod that the service port targets.
+func containerPort(pod *v1.Pod, svcPort *v1.ServicePort) (int32, error) {
+	target := svcPort.TargetPort
+	switch {
+	case target.Type == intstr.Int && target.IntVal == 0:
+		return svcPort.Port, nil
+	case target.Type == intstr.Int:
+		return target.IntVal, nil
+	}
+	for _, c := range pod.Spec.Containers {
+		for _, port := range c.Ports {
+			if port.Name == target.StrVal {
+				return port.ContainerPort, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("pod %s has no port named %s", pod.Name, target.StrVal)
+}
+
+func isPodReady(pod *v1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == v1.PodReady {
+			return cond.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func testConnectorPod(name string, created time.Time, phase v1.PodPhase, ready bool) *v1.Pod {
+	readyStatus := v1.ConditionFalse
+	if ready {
+		readyStatus = v1.ConditionTrue
+	}
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "pl",
+			Labels:            map[string]string{"name": "vizier-cloud-connector"},
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{{
+				Name:  "app",
+				Ports: []v1.ContainerPort{{Name: "tcp-http2", ContainerPort: 50800}},
+			}},
+		},
+		Status: v1.PodStatus{
+			Phase:      phase,
+			Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: readyStatus}},
+		},
+	}
+}
+
+func testConnectorService(targetPort intstr.IntOrString) *v1.Service {
+	return &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "vizier-cloud-connector-svc", Namespace: "pl"},
+		Spec: v1.ServiceSpec{
+			Selector: map[string]string{"name": "vizier-cloud-connector"},
+			Ports:    []v1.ServicePort{{Port: 80, TargetPort: targetPort}},
+		},
+	}
+}
+
+func TestPortForwarder_ResolvePod(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name       string
+		targetPort intstr.IntOrString
+		pods       []*v1.Pod
+		expPod     string
+		expPort    int32
+	}{
+		{
+			name:       "named target port",
+			targetPort: intstr.FromString("tcp-http2"),
+			pods:       []*v1.Pod{testConnectorPod("a", now, v1.PodRunning, true)},
+			expPod:     "a",
+			expPort:    50800,
+		},
+		{
+			name:       "numeric target port",
+			targetPort: intstr.FromInt(50801),
+			pods:       []*v1.Pod{testConnectorPod("a", now, v1.PodRunning, true)},
+			expPod:     "a",
+			expPort:    50801,
+		},
+		{
+			name:       "prefers ready pods",
+			targetPort: intstr.FromString("tcp-http2"),
+			pods: []*v1.Pod{
+				testConnectorPod("unready", now, v1.PodRunning, false),
+				testConnectorPod("ready", now.Add(-time.Hour), v1.PodRunning, true),
+			},
+			expPod:  "ready",
+			expPort: 50800,
+		},
+		{
+			name:       "prefers newest pods",
+			targetPort: intstr.FromString("tcp-http2"),
+			pods: []*v1.Pod{
+				testConnectorPod("old", now.Add(-time.Hour), v1.PodRunning, true),
+				testConnectorPod("new", now, v1.PodRunning, true),
+				testConnectorPod("pending", now.Add(time.Hour), v1.PodPending, false),
+			},
+			expPod:  "new",
+			expPort: 50800,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(testConnectorService(test.targetPort))
+			for _, pod := range test.pods {
+				_, err := clientset.CoreV1().Pods("pl").Create(context.Background(), pod, metav1.CreateOptions{})
+				require.NoError(t, err)
+			}
+
+			pf := k8s.NewPortForwarder(clientset, nil, "pl")
+			pf.Service = "vizier-cloud-connector-svc"
+			pf.RemotePort = 80
+			pod, port, err := pf.ResolvePod(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, test.expPod, pod.Name)
+			assert.Equal(t, test.expPort, port)
+		})
+	}
+}
+
+func TestPortForwarder_ResolvePodSelector(t *testing.T) {
+	clientset := fake.NewSimpleClientset(testConnectorPod("a", time.Now(), v1.PodRunning, true))
+
+	pf := k8s.NewPortForwarder(clientset, nil, "pl")
+	pf.Selector = "name=vizier-cloud-connector"
+	pf.RemotePort = 50800
+	pod, port, err := pf.ResolvePod(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "a", pod.Name)
+	assert.Equal(t, int32(50800), port)
+}
+
+func TestPortForwarder_ResolvePodErrors(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		testConnectorService(intstr.FromString("tcp-http2")),
+		testConnectorPod("pending", time.Now(), v1.PodPending, false),
+	)
+
+	pf := k8s.NewPortForwarder(clientset, nil, "pl")
+	pf.Service = "vizier-cloud-connector-svc"
+	pf.RemotePort = 80
+	_, _, err := pf.ResolvePod(context.Background())
+	assert.True(t, errors.Is(err, k8s.ErrNotFound))
+
+	pf.RemotePort = 443
+	_, _, err = pf.ResolvePod(context.Background())
+	assert.Error(t, err)
+
+	pf.Service = "missing"
+	_, _, err = pf.ResolvePod(context.Background())
+	assert.True(t, errors.Is(err, k8s.ErrNotFound))
+}
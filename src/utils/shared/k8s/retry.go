@@ -0,0 +1,180 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// RetryPolicy configures how the K8s helpers in this package retry API calls that fail with transient errors, such
+// as the API server throttling requests on a large cluster.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a call is made, including the first. Values below one are treated
+	// as one, which disables retries.
+	MaxAttempts int
+	// InitialBackoff is how long to wait before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between retries. If zero, the wait is not capped.
+	MaxBackoff time.Duration
+	// Multiplier is the factor the wait grows by after each retry. Values below one are treated as one.
+	Multiplier float64
+	// Jitter randomly lengthens each wait by up to this fraction of it, so that concurrent calls don't retry in
+	// lockstep.
+	Jitter float64
+	// Retryable decides whether a failed call should be retried. If nil, IsRetryable is used.
+	Retryable func(err error) bool
+	// HonorRetryAfter makes the wait before a retry match the delay the API server suggests in its response, such
+	// as the Retry-After header of a 429 Too Many Requests, when it gives one.
+	HonorRetryAfter bool
+}
+
+// DefaultRetryPolicy is the RetryPolicy used when none is given.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:     5,
+	InitialBackoff:  500 * time.Millisecond,
+	MaxBackoff:      30 * time.Second,
+	Multiplier:      2,
+	Jitter:          0.2,
+	HonorRetryAfter: true,
+}
+
+// NoRetryPolicy is a RetryPolicy that makes each call only once.
+var NoRetryPolicy = RetryPolicy{MaxAttempts: 1}
+
+// IsRetryable returns whether the error is likely to be transient: the API server throttled the request, was briefly
+// unavailable or timed out, or the connection to it failed. Errors from the caller's context are never retryable. An
+// aggregate is retryable if all of its errors are.
+func IsRetryable(err error) bool {
+	if agg, ok := err.(utilerrors.Aggregate); ok {
+		for _, e := range agg.Errors() {
+			if !IsRetryable(e) {
+				return false
+			}
+		}
+		return len(agg.Errors()) > 0
+	}
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	case k8serrors.IsTooManyRequests(err), k8serrors.IsServiceUnavailable(err),
+		k8serrors.IsServerTimeout(err), k8serrors.IsTimeout(err):
+		return true
+	case utilnet.IsConnectionReset(err), utilnet.IsConnectionRefused(err), utilnet.IsProbableEOF(err),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+type retryPolicyKey struct{}
+
+// WithRetryPolicy returns a context that makes the K8s helpers in this package, and any ObjectDeleter without a
+// RetryPolicy of its own, use the given policy for the calls made with it.
+func WithRetryPolicy(ctx context.Context, policy RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyKey{}, policy)
+}
+
+// retryPolicyFromContext returns the policy set on the context with WithRetryPolicy, or DefaultRetryPolicy.
+func retryPolicyFromContext(ctx context.Context) RetryPolicy {
+	if p, ok := ctx.Value(retryPolicyKey{}).(RetryPolicy); ok {
+		return p
+	}
+	return DefaultRetryPolicy
+}
+
+// Do calls fn until it succeeds, it fails with an error that isn't retryable, MaxAttempts is reached, or the context
+// is done. Returns the error from the last call.
+func (p RetryPolicy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+
+	backoff := p.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= p.MaxAttempts || !retryable(err) {
+			return err
+		}
+
+		delay := p.delay(backoff, err)
+		log.WithError(err).WithField("attempt", attempt).WithField("delay", delay).
+			Debug("Retrying K8s API call after transient error")
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+		backoff = p.nextBackoff(backoff)
+	}
+}
+
+// doDelete is Do for a call that deletes an object. A delete that fails with a transient error, such as a timeout,
+// may still have succeeded on the API server, so a retry that finds the object gone counts as success.
+func (p RetryPolicy) doDelete(ctx context.Context, fn func(ctx context.Context) error) error {
+	attempt := 0
+	return p.Do(ctx, func(ctx context.Context) error {
+		attempt++
+		err := fn(ctx)
+		if attempt > 1 && k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	})
+}
+
+// delay returns how long to wait before retrying the call that failed with err.
+func (p RetryPolicy) delay(backoff time.Duration, err error) time.Duration {
+	if p.HonorRetryAfter {
+		if seconds, ok := k8serrors.SuggestsClientDelay(err); ok && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	if p.Jitter > 0 {
+		backoff = wait.Jitter(backoff, p.Jitter)
+	}
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	return backoff
+}
+
+func (p RetryPolicy) nextBackoff(backoff time.Duration) time.Duration {
+	next := time.Duration(float64(backoff) * math.Max(p.Multiplier, 1))
+	if p.MaxBackoff > 0 && next > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return next
+}
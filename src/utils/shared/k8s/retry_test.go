@@ -0,0 +1,280 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+var fastRetryPolicy = k8s.RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     5 * time.Millisecond,
+	Multiplier:     2,
+}
+
+func TestIsRetryable(t *testing.T) {
+	gr := schema.GroupResource{Resource: "pods"}
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{"nil", nil, false},
+		{"too many requests", k8serrors.NewTooManyRequests("slow down", 0), true},
+		{"service unavailable", k8serrors.NewServiceUnavailable("unavailable"), true},
+		{"server timeout", k8serrors.NewServerTimeout(gr, "delete", 0), true},
+		{"timeout", k8serrors.NewTimeoutError("timed out", 0), true},
+		{"unexpected EOF", fmt.Errorf("reading body: %w", io.ErrUnexpectedEOF), true},
+		{"connection refused", &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, true},
+		{"not found", k8serrors.NewNotFound(gr, "pod"), false},
+		{"forbidden", k8serrors.NewForbidden(gr, "pod", errors.New("no access")), false},
+		{"canceled", context.Canceled, false},
+		{"aggregate", utilerrors.NewAggregate([]error{
+			k8serrors.NewTooManyRequests("slow down", 0),
+			k8serrors.NewServiceUnavailable("unavailable"),
+		}), true},
+		{"mixed aggregate", utilerrors.NewAggregate([]error{
+			k8serrors.NewTooManyRequests("slow down", 0),
+			k8serrors.NewNotFound(gr, "pod"),
+		}), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.retryable, k8s.IsRetryable(test.err))
+		})
+	}
+}
+
+func TestRetryPolicy_Do(t *testing.T) {
+	calls := 0
+	err := fastRetryPolicy.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return k8serrors.NewTooManyRequests("slow down", 0)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestRetryPolicy_DoGivesUp(t *testing.T) {
+	calls := 0
+	err := fastRetryPolicy.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return k8serrors.NewServiceUnavailable("unavailable")
+	})
+	require.Error(t, err)
+	assert.True(t, k8serrors.IsServiceUnavailable(err))
+	assert.Equal(t, fastRetryPolicy.MaxAttempts, calls)
+
+	// Errors that aren't retryable are returned immediately.
+	calls = 0
+	err = fastRetryPolicy.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return errors.New("bad request")
+	})
+	require.Error(t, err)
+	assert.Equal(t, 1, calls)
+
+	calls = 0
+	err = k8s.NoRetryPolicy.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return k8serrors.NewServiceUnavailable("unavailable")
+	})
+	require.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetryPolicy_CustomClassifier(t *testing.T) {
+	errFlaky := errors.New("flaky")
+	policy := fastRetryPolicy
+	policy.Retryable = func(err error) bool {
+		return errors.Is(err, errFlaky)
+	}
+
+	calls := 0
+	err := policy.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return errFlaky
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestRetryPolicy_HonorRetryAfter(t *testing.T) {
+	policy := fastRetryPolicy
+	policy.HonorRetryAfter = true
+
+	calls := 0
+	start := time.Now()
+	err := policy.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return k8serrors.NewTooManyRequests("slow down", 1)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+}
+
+func TestRetryPolicy_ContextDone(t *testing.T) {
+	policy := fastRetryPolicy
+	policy.InitialBackoff = time.Hour
+	policy.MaxBackoff = 0
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	calls := 0
+	err := policy.Do(ctx, func(ctx context.Context) error {
+		calls++
+		return k8serrors.NewTooManyRequests("slow down", 0)
+	})
+	require.Error(t, err)
+	assert.True(t, k8serrors.IsTooManyRequests(err))
+	assert.Equal(t, 1, calls)
+}
+
+func TestDeletePods_RetriesThrottledRequests(t *testing.T) {
+	clientset := fake.NewSimpleClientset(testPods(5)...)
+	var throttled int32
+	clientset.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		// Throttle the first couple of requests.
+		if atomic.AddInt32(&throttled, 1) <= 2 {
+			return true, nil, k8serrors.NewTooManyRequests("slow down", 0)
+		}
+		return false, nil, nil
+	})
+
+	ctx := k8s.WithRetryPolicy(context.Background(), fastRetryPolicy)
+	err := k8s.DeletePods(ctx, clientset, "pl", "", 2, nil)
+	require.NoError(t, err)
+
+	l, err := clientset.CoreV1().Pods("pl").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, l.Items)
+}
+
+func TestDeleteConfigMap_NoRetry(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	calls := 0
+	clientset.PrependReactor("delete", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		calls++
+		return true, nil, k8serrors.NewTooManyRequests("slow down", 0)
+	})
+
+	ctx := k8s.WithRetryPolicy(context.Background(), k8s.NoRetryPolicy)
+	err := k8s.DeleteConfigMap(ctx, clientset, "pl-config", "pl", nil)
+	require.Error(t, err)
+	assert.True(t, k8serrors.IsTooManyRequests(err))
+	assert.Equal(t, 1, calls)
+}
+
+// deletedWithTimeout returns a reactor that deletes the first object, but fails as if the request timed out, so that
+// the retry finds the object gone.
+func deletedWithTimeout(clientset *fake.Clientset, resource string) k8stesting.ReactionFunc {
+	var calls int32
+	return func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if atomic.AddInt32(&calls, 1) > 1 {
+			return false, nil, nil
+		}
+		del := action.(k8stesting.DeleteAction)
+		gvr := schema.GroupVersionResource{Version: "v1", Resource: resource}
+		if err := clientset.Tracker().Delete(gvr, del.GetNamespace(), del.GetName()); err != nil {
+			return true, nil, err
+		}
+		return true, nil, k8serrors.NewTimeoutError("request timed out", 0)
+	}
+}
+
+func TestDeletePods_DeletedDuringTimedOutAttempt(t *testing.T) {
+	clientset := fake.NewSimpleClientset(testPods(1)...)
+	clientset.PrependReactor("delete", "pods", deletedWithTimeout(clientset, "pods"))
+
+	ctx := k8s.WithRetryPolicy(context.Background(), fastRetryPolicy)
+	require.NoError(t, k8s.DeletePods(ctx, clientset, "pl", "", 1, nil))
+
+	// Without a retry, the not found error is returned.
+	err := k8s.DeleteConfigMap(ctx, clientset, "missing", "pl", nil)
+	assert.ErrorIs(t, err, k8s.ErrNotFound)
+}
+
+func TestDeleteServices_DeletedDuringTimedOutAttempt(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "pl"}})
+	clientset.PrependReactor("delete", "services", deletedWithTimeout(clientset, "services"))
+
+	ctx := k8s.WithRetryPolicy(context.Background(), fastRetryPolicy)
+	require.NoError(t, k8s.DeleteServices(ctx, clientset, "pl", "", 1, nil))
+}
+
+func TestObjectDeleter_DeletedDuringTimedOutAttempt(t *testing.T) {
+	f := newFakeAPIServer(t, fakePods)
+	f.add(fakePods, "pl", "pod-0", map[string]string{"app": "pl"})
+	deletes := 0
+	f.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method != http.MethodDelete {
+			return false
+		}
+		deletes++
+		if deletes > 1 {
+			return false
+		}
+		f.removeLocked(fakePods, "pl", "pod-0")
+		writeStatusError(w, k8serrors.NewTimeoutError("request timed out", 0))
+		return true
+	}
+
+	od := f.deleter()
+	od.Namespace = "pl"
+	od.RetryPolicy = &fastRetryPolicy
+	deleted, err := od.DeleteByLabel(context.Background(), "app=pl", "pods")
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	assert.Equal(t, 2, deletes)
+	assert.Nil(t, f.get(fakePods, "pl", "pod-0"))
+}
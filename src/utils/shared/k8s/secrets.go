@@ -28,20 +28,24 @@ import (
 	"os"
 	"strings"
 
-	log "github.com/sirupsen/logrus"
-
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes"
 )
 
-// DeleteSecret deletes the given secret in kubernetes.
-func DeleteSecret(clientset kubernetes.Interface, namespace, name string) {
-	err := clientset.CoreV1().Secrets(namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+// DeleteSecret deletes the secret in the namespace with the given name, using the given options, which may be nil.
+func DeleteSecret(ctx context.Context, clientset kubernetes.Interface, namespace, name string, opts *DeleteOptions) error {
+	secrets := clientset.CoreV1().Secrets(namespace)
+
+	err := retryPolicyFromContext(ctx).doDelete(ctx, func(ctx context.Context) error {
+		return secrets.Delete(ctx, name, opts.toDeleteOptions())
+	})
 	if err != nil {
-		log.WithError(err).Error("Failed to delete secret")
+		return wrapError(err)
 	}
+
+	return nil
 }
 
 // GetSecret gets the secret in kubernetes.
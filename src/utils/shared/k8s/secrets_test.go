@@ -0,0 +1,110 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func TestDeleteSecret(t *testing.T) {
+	ctx := context.Background()
+	clientset := fake.NewSimpleClientset(&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "pl-deploy-secrets", Namespace: "pl"}})
+
+	require.NoError(t, k8s.DeleteSecret(ctx, clientset, "pl", "pl-deploy-secrets", nil))
+	_, err := clientset.CoreV1().Secrets("pl").Get(ctx, "pl-deploy-secrets", metav1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err))
+
+	err = k8s.DeleteSecret(ctx, clientset, "pl", "pl-deploy-secrets", nil)
+	assert.ErrorIs(t, err, k8s.ErrNotFound)
+	assert.True(t, k8serrors.IsNotFound(err))
+}
+
+func TestDeleteSecret_Retries(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "pl-deploy-secrets", Namespace: "pl"}})
+	calls := 0
+	clientset.PrependReactor("delete", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		calls++
+		if calls == 1 {
+			return true, nil, k8serrors.NewTooManyRequests("slow down", 0)
+		}
+		return false, nil, nil
+	})
+
+	ctx := k8s.WithRetryPolicy(context.Background(), fastRetryPolicy)
+	require.NoError(t, k8s.DeleteSecret(ctx, clientset, "pl", "pl-deploy-secrets", nil))
+	assert.Equal(t, 2, calls)
+
+	// A secret that was deleted by a timed out attempt is found gone by the retry.
+	clientset = fake.NewSimpleClientset(&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "pl-deploy-secrets", Namespace: "pl"}})
+	clientset.PrependReactor("delete", "secrets", deletedWithTimeout(clientset, "secrets"))
+	require.NoError(t, k8s.DeleteSecret(ctx, clientset, "pl", "pl-deploy-secrets", nil))
+}
+
+func TestDeleteSecret_Cancelled(t *testing.T) {
+	deleting := make(chan struct{})
+	done := make(chan struct{})
+	// The API server doesn't respond to the delete until the test is done, so that it is still in progress when
+	// the context is cancelled.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			close(deleting)
+		}
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	clientset, err := kubernetes.NewForConfig(&rest.Config{Host: srv.URL})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- k8s.DeleteSecret(ctx, clientset, "pl", "pl-deploy-secrets", nil)
+	}()
+
+	<-deleting
+	cancel()
+	select {
+	case err := <-errCh:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("DeleteSecret did not return after its context was cancelled")
+	}
+}
@@ -0,0 +1,428 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Phase is the rollout phase of an object tracked by a Watcher.
+type Phase string
+
+const (
+	// PhasePending means that the object isn't ready yet. For workloads, not all of the replicas are available.
+	PhasePending Phase = "Pending"
+	// PhaseUnschedulable means that a pod can't be scheduled onto a node, for example because of insufficient
+	// resources. This may resolve itself, for example if the cluster is autoscaled.
+	PhaseUnschedulable Phase = "Unschedulable"
+	// PhaseRunning means that a pod is running and ready, or that all of the replicas of a workload are available.
+	PhaseRunning Phase = "Running"
+	// PhaseSucceeded means that all of the containers of a pod terminated successfully.
+	PhaseSucceeded Phase = "Succeeded"
+	// PhaseCrashLoopBackOff means that a container of a pod keeps crashing.
+	PhaseCrashLoopBackOff Phase = "CrashLoopBackOff"
+	// PhaseImagePullError means that the image of a container of a pod can't be pulled.
+	PhaseImagePullError Phase = "ImagePullError"
+	// PhaseFailed means that a pod failed, or that a deployment exceeded its progress deadline.
+	PhaseFailed Phase = "Failed"
+	// PhaseDeleted means that the object was deleted.
+	PhaseDeleted Phase = "Deleted"
+)
+
+// imagePullErrorReasons are the reasons of waiting containers whose image can't be pulled.
+var imagePullErrorReasons = map[string]bool{
+	"ErrImagePull":      true,
+	"ImagePullBackOff":  true,
+	"InvalidImageName":  true,
+	"ErrImageNeverPull": true,
+}
+
+// ResourceStatus is the rollout status of an object tracked by a Watcher.
+type ResourceStatus struct {
+	ObjectReference
+	Phase Phase
+	// Ready and Desired are the number of available and desired replicas of a workload. For pods, they are the
+	// number of ready and total containers.
+	Ready   int32
+	Desired int32
+	// Message explains the phase, for example with the reason that a container is waiting.
+	Message string
+	// PodPhase is the phase that Kubernetes reports for a pod, which is Running as soon as its containers have
+	// started, even if they aren't ready. It is empty for workloads.
+	PodPhase v1.PodPhase
+}
+
+// Failed returns whether the object failed in a way that won't resolve itself without an intervention, such as an
+// image that can't be pulled. Crash looping pods aren't considered failed, since they often recover once the
+// services they depend on are up.
+func (s ResourceStatus) Failed() bool {
+	return s.Phase == PhaseImagePullError || s.Phase == PhaseFailed
+}
+
+func (s ResourceStatus) String() string {
+	str := fmt.Sprintf("%s %s/%s: %s", s.Kind, s.Namespace, s.Name, s.Phase)
+	if s.Desired > 0 {
+		str += fmt.Sprintf(" (%d/%d)", s.Ready, s.Desired)
+	}
+	if s.Message != "" {
+		str += ": " + s.Message
+	}
+	return str
+}
+
+// PhaseTransition is a change in the phase of an object tracked by a Watcher.
+type PhaseTransition struct {
+	// From is the previous phase of the object, which is empty when the object is first seen.
+	From   Phase
+	Status ResourceStatus
+}
+
+// Watcher tracks the rollout of the deployments, daemonsets and pods in a namespace that match a label selector,
+// using shared informers, and reports the transitions between their phases.
+type Watcher struct {
+	clientset kubernetes.Interface
+	namespace string
+	selector  string
+
+	mu       sync.Mutex
+	statuses map[ObjectReference]ResourceStatus
+	// changed is closed and replaced whenever a status changes, to wake up WaitFor.
+	changed chan struct{}
+
+	// notifyMu serializes the transitions that are reported to the handlers and the channel, and guards closing the
+	// channel.
+	notifyMu    sync.Mutex
+	handlers    []func(PhaseTransition)
+	transitions chan PhaseTransition
+	done        <-chan struct{}
+}
+
+// NewWatcher creates a Watcher of the objects in the namespace that match the label selector. If namespace is empty,
+// all namespaces are watched.
+func NewWatcher(clientset kubernetes.Interface, namespace, selector string) *Watcher {
+	return &Watcher{
+		clientset: clientset,
+		namespace: namespace,
+		selector:  selector,
+		statuses:  make(map[ObjectReference]ResourceStatus),
+		changed:   make(chan struct{}),
+	}
+}
+
+// OnTransition registers a callback for every phase transition. It must be called before Start. Callbacks are called
+// one at a time, from the informers' goroutines, so they must not block.
+func (w *Watcher) OnTransition(fn func(PhaseTransition)) {
+	w.handlers = append(w.handlers, fn)
+}
+
+// Transitions returns a channel that receives every phase transition, and is closed once the Watcher stops. It must
+// be called before Start. The channel must be drained, since the informers block until each transition is received.
+func (w *Watcher) Transitions() <-chan PhaseTransition {
+	if w.transitions == nil {
+		w.transitions = make(chan PhaseTransition, 64)
+	}
+	return w.transitions
+}
+
+// Start starts watching the objects, and returns once the initial state of the objects has been synced. The Watcher
+// stops when the context is done.
+func (w *Watcher) Start(ctx context.Context) error {
+	factory := informers.NewSharedInformerFactoryWithOptions(w.clientset, 0,
+		informers.WithNamespace(w.namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = w.selector
+		}))
+
+	for _, informer := range []cache.SharedIndexInformer{
+		factory.Apps().V1().Deployments().Informer(),
+		factory.Apps().V1().DaemonSets().Informer(),
+		factory.Core().V1().Pods().Informer(),
+	} {
+		_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    w.onUpdate,
+			UpdateFunc: func(oldObj, newObj interface{}) { w.onUpdate(newObj) },
+			DeleteFunc: w.onDelete,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	w.done = ctx.Done()
+	factory.Start(ctx.Done())
+	for informerType, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			return wrapError(fmt.Errorf("failed to sync the informer for %v: %w", informerType, ctx.Err()))
+		}
+	}
+	go func() {
+		<-ctx.Done()
+		factory.Shutdown()
+		w.notifyMu.Lock()
+		defer w.notifyMu.Unlock()
+		if w.transitions != nil {
+			close(w.transitions)
+			w.transitions = nil
+		}
+	}()
+	return nil
+}
+
+// Statuses returns the current status of every tracked object, ordered by kind, namespace and name.
+func (w *Watcher) Statuses() []ResourceStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	statuses := make([]ResourceStatus, 0, len(w.statuses))
+	for _, s := range w.statuses {
+		statuses = append(statuses, s)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		a, b := statuses[i].ObjectReference, statuses[j].ObjectReference
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return statuses
+}
+
+// WaitForReady blocks until the tracked objects are ready: all of the deployments and daemonsets are running or, if
+// there are none, all of the pods are running or succeeded. It returns an error that is ErrRolloutFailed as soon as
+// any object fails, so that callers can fail fast on errors such as images that can't be pulled. If the context is
+// done first, an error that is ErrWaitTimeout is returned.
+func (w *Watcher) WaitForReady(ctx context.Context) error {
+	return w.WaitFor(ctx, ready)
+}
+
+// WaitFor blocks until cond returns true or an error. cond is called with the current statuses, as returned by
+// Statuses, and again each time any of them changes, including changes that don't transition the phase. If the
+// context is done first, an error that is ErrWaitTimeout is returned.
+func (w *Watcher) WaitFor(ctx context.Context, cond func(statuses []ResourceStatus) (bool, error)) error {
+	for {
+		w.mu.Lock()
+		changed := w.changed
+		w.mu.Unlock()
+		// The statuses are read after the channel, so that no change is missed in between.
+		done, err := cond(w.Statuses())
+		if err != nil || done {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return wrapError(ctx.Err())
+		case <-changed:
+		}
+	}
+}
+
+func ready(statuses []ResourceStatus) (bool, error) {
+	if len(statuses) == 0 {
+		return false, nil
+	}
+	hasWorkloads := false
+	workloadsReady := true
+	podsReady := true
+	for _, s := range statuses {
+		if s.Failed() {
+			return false, &kindError{kind: ErrRolloutFailed, err: fmt.Errorf("rollout failed: %s", s)}
+		}
+		if s.Kind == "Pod" {
+			podsReady = podsReady && (s.Phase == PhaseRunning || s.Phase == PhaseSucceeded)
+			continue
+		}
+		hasWorkloads = true
+		workloadsReady = workloadsReady && s.Phase == PhaseRunning
+	}
+	if hasWorkloads {
+		return workloadsReady, nil
+	}
+	return podsReady, nil
+}
+
+func (w *Watcher) onUpdate(obj interface{}) {
+	var s ResourceStatus
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		s = deploymentStatus(o)
+	case *appsv1.DaemonSet:
+		s = daemonSetStatus(o)
+	case *v1.Pod:
+		s = podStatus(o)
+	default:
+		return
+	}
+	w.update(s)
+}
+
+func (w *Watcher) onDelete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	var ref ObjectReference
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		ref = ObjectReference{Kind: "Deployment", Namespace: o.Namespace, Name: o.Name}
+	case *appsv1.DaemonSet:
+		ref = ObjectReference{Kind: "DaemonSet", Namespace: o.Namespace, Name: o.Name}
+	case *v1.Pod:
+		ref = ObjectReference{Kind: "Pod", Namespace: o.Namespace, Name: o.Name}
+	default:
+		return
+	}
+	w.update(ResourceStatus{ObjectReference: ref, Phase: PhaseDeleted})
+}
+
+// update records the status of an object, and reports the transition if its phase changed.
+func (w *Watcher) update(s ResourceStatus) {
+	w.mu.Lock()
+	prev, seen := w.statuses[s.ObjectReference]
+	if s.Phase == PhaseDeleted {
+		delete(w.statuses, s.ObjectReference)
+	} else {
+		w.statuses[s.ObjectReference] = s
+	}
+	if seen && prev == s {
+		w.mu.Unlock()
+		return
+	}
+	close(w.changed)
+	w.changed = make(chan struct{})
+	w.mu.Unlock()
+
+	if (seen && prev.Phase == s.Phase) || (!seen && s.Phase == PhaseDeleted) {
+		return
+	}
+	t := PhaseTransition{From: prev.Phase, Status: s}
+	w.notifyMu.Lock()
+	defer w.notifyMu.Unlock()
+	for _, fn := range w.handlers {
+		fn(t)
+	}
+	if w.transitions != nil {
+		select {
+		case w.transitions <- t:
+		case <-w.done:
+		}
+	}
+}
+
+func deploymentStatus(d *appsv1.Deployment) ResourceStatus {
+	s := ResourceStatus{
+		ObjectReference: ObjectReference{Kind: "Deployment", Namespace: d.Namespace, Name: d.Name},
+		Phase:           PhasePending,
+		Ready:           d.Status.AvailableReplicas,
+		Desired:         1,
+	}
+	if d.Spec.Replicas != nil {
+		s.Desired = *d.Spec.Replicas
+	}
+	for _, c := range d.Status.Conditions {
+		if c.Type == appsv1.DeploymentProgressing && c.Reason == "ProgressDeadlineExceeded" {
+			s.Phase = PhaseFailed
+			s.Message = c.Message
+			return s
+		}
+	}
+	if d.Status.ObservedGeneration >= d.Generation && d.Status.UpdatedReplicas >= s.Desired &&
+		d.Status.AvailableReplicas >= s.Desired {
+		s.Phase = PhaseRunning
+	}
+	return s
+}
+
+func daemonSetStatus(ds *appsv1.DaemonSet) ResourceStatus {
+	s := ResourceStatus{
+		ObjectReference: ObjectReference{Kind: "DaemonSet", Namespace: ds.Namespace, Name: ds.Name},
+		Phase:           PhasePending,
+		Ready:           ds.Status.NumberAvailable,
+		Desired:         ds.Status.DesiredNumberScheduled,
+	}
+	if ds.Status.ObservedGeneration >= ds.Generation && ds.Status.UpdatedNumberScheduled >= s.Desired &&
+		ds.Status.NumberAvailable >= s.Desired {
+		s.Phase = PhaseRunning
+	}
+	return s
+}
+
+func podStatus(pod *v1.Pod) ResourceStatus {
+	s := ResourceStatus{
+		ObjectReference: ObjectReference{Kind: "Pod", Namespace: pod.Namespace, Name: pod.Name},
+		Phase:           PhasePending,
+		Desired:         int32(len(pod.Spec.Containers)),
+		PodPhase:        pod.Status.Phase,
+	}
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.Ready {
+			s.Ready++
+		}
+	}
+
+	switch pod.Status.Phase {
+	case v1.PodSucceeded:
+		s.Phase = PhaseSucceeded
+		return s
+	case v1.PodFailed:
+		s.Phase = PhaseFailed
+		s.Message = pod.Status.Message
+		return s
+	}
+
+	statuses := append(append([]v1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, cs := range statuses {
+		if cs.State.Waiting == nil {
+			continue
+		}
+		reason := cs.State.Waiting.Reason
+		switch {
+		case imagePullErrorReasons[reason]:
+			s.Phase = PhaseImagePullError
+		case reason == "CrashLoopBackOff":
+			s.Phase = PhaseCrashLoopBackOff
+		default:
+			continue
+		}
+		s.Message = fmt.Sprintf("container %s: %s", cs.Name, cs.State.Waiting.Message)
+		return s
+	}
+
+	for _, c := range pod.Status.Conditions {
+		if c.Type == v1.PodScheduled && c.Status == v1.ConditionFalse && c.Reason == v1.PodReasonUnschedulable {
+			s.Phase = PhaseUnschedulable
+			s.Message = c.Message
+			return s
+		}
+		if c.Type == v1.PodReady && c.Status == v1.ConditionTrue && pod.Status.Phase == v1.PodRunning {
+			s.Phase = PhaseRunning
+		}
+	}
+	return s
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func testDeployment(replicas, available int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "vizier-query-broker", Namespace: "pl", Labels: map[string]string{"component": "vizier"}},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status: appsv1.DeploymentStatus{
+			UpdatedReplicas:   available,
+			AvailableReplicas: available,
+		},
+	}
+}
+
+func TestWatcher_WaitForReady(t *testing.T) {
+	clientset := fake.NewSimpleClientset(testDeployment(2, 0))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	w := k8s.NewWatcher(clientset, "pl", "component=vizier")
+	var mu sync.Mutex
+	var transitions []k8s.PhaseTransition
+	w.OnTransition(func(t k8s.PhaseTransition) {
+		mu.Lock()
+		defer mu.Unlock()
+		transitions = append(transitions, t)
+	})
+	require.NoError(t, w.Start(ctx))
+
+	statuses := w.Statuses()
+	require.Len(t, statuses, 1)
+	assert.Equal(t, k8s.PhasePending, statuses[0].Phase)
+	assert.Equal(t, int32(2), statuses[0].Desired)
+
+	waitCtx, waitCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer waitCancel()
+	assert.ErrorIs(t, w.WaitForReady(waitCtx), k8s.ErrWaitTimeout)
+
+	_, err := clientset.AppsV1().Deployments("pl").UpdateStatus(ctx, testDeployment(2, 2), metav1.UpdateOptions{})
+	require.NoError(t, err)
+	require.NoError(t, w.WaitForReady(ctx))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, transitions, 2)
+	assert.Equal(t, k8s.Phase(""), transitions[0].From)
+	assert.Equal(t, k8s.PhasePending, transitions[0].Status.Phase)
+	assert.Equal(t, k8s.PhasePending, transitions[1].From)
+	assert.Equal(t, k8s.PhaseRunning, transitions[1].Status.Phase)
+}
+
+func TestWatcher_FailsOnImagePullError(t *testing.T) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "vizier-pem-abcde", Namespace: "pl", Labels: map[string]string{"component": "vizier"}},
+		Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "pem"}}},
+		Status:     v1.PodStatus{Phase: v1.PodPending},
+	}
+	clientset := fake.NewSimpleClientset(pod)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	w := k8s.NewWatcher(clientset, "pl", "component=vizier")
+	transitions := w.Transitions()
+	require.NoError(t, w.Start(ctx))
+
+	tr := <-transitions
+	assert.Equal(t, k8s.PhasePending, tr.Status.Phase)
+
+	pod = pod.DeepCopy()
+	pod.Status.ContainerStatuses = []v1.ContainerStatus{{
+		Name: "pem",
+		State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{
+			Reason:  "ImagePullBackOff",
+			Message: "Back-off pulling image",
+		}},
+	}}
+	_, err := clientset.CoreV1().Pods("pl").UpdateStatus(ctx, pod, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	tr = <-transitions
+	assert.Equal(t, k8s.PhasePending, tr.From)
+	assert.Equal(t, k8s.PhaseImagePullError, tr.Status.Phase)
+	assert.Contains(t, tr.Status.Message, "Back-off pulling image")
+
+	err = w.WaitForReady(ctx)
+	assert.ErrorIs(t, err, k8s.ErrRolloutFailed)
+	assert.Contains(t, err.Error(), "vizier-pem-abcde")
+
+	// The channel is closed once the watcher stops.
+	cancel()
+	_, ok := <-transitions
+	assert.False(t, ok)
+}
+
+func TestWatcher_WaitForWakesOnStatusChanges(t *testing.T) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "vizier-pem-abcde", Namespace: "pl", Labels: map[string]string{"component": "vizier"}},
+		Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "pem"}}},
+		Status:     v1.PodStatus{Phase: v1.PodPending},
+	}
+	clientset := fake.NewSimpleClientset(pod)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	w := k8s.NewWatcher(clientset, "pl", "component=vizier")
+	require.NoError(t, w.Start(ctx))
+
+	// The pod starts running without becoming ready, which changes its status but not its phase.
+	pod = pod.DeepCopy()
+	pod.Status.Phase = v1.PodRunning
+	_, err := clientset.CoreV1().Pods("pl").UpdateStatus(ctx, pod, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	err = w.WaitFor(ctx, func(statuses []k8s.ResourceStatus) (bool, error) {
+		require.Len(t, statuses, 1)
+		assert.Equal(t, k8s.PhasePending, statuses[0].Phase)
+		return statuses[0].PodPhase == v1.PodRunning, nil
+	})
+	assert.NoError(t, err)
+}
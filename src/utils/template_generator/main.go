@@ -19,6 +19,9 @@
 package main
 
 import (
+	"os"
+	"path/filepath"
+
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
@@ -31,6 +34,20 @@ func init() {
 	pflag.String("base", "", "Path to the tar containing the base Vizier YAMLs")
 	pflag.String("out", "", "The output path")
 	pflag.String("version", "", "The version string for the YAMLs")
+	pflag.String("chart_out", "", "If set, the directory to write the Vizier Helm chart to")
+}
+
+func writeChart(files map[string]string, dir string) error {
+	for name, contents := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(p, []byte(contents), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func main() {
@@ -43,6 +60,7 @@ func main() {
 	base := viper.GetString("base")
 	out := viper.GetString("out")
 	version := viper.GetString("version")
+	chartOut := viper.GetString("chart_out")
 
 	if len(base) == 0 {
 		log.Fatalln("Base YAML path (--base) is required")
@@ -66,4 +84,16 @@ func main() {
 	if err := yamls.ExtractYAMLs(templatedYAMLs, out, "pixie_yamls", yamls.MultiFileExtractYAMLFormat); err != nil {
 		log.WithError(err).Fatal("failed to extract deployment YAMLs")
 	}
+
+	if len(chartOut) == 0 {
+		return
+	}
+	log.WithField("chart_out", chartOut).Info("Helm chart path")
+	chart, err := vizieryamls.GenerateHelmChart(templatedYAMLs, version)
+	if err != nil {
+		log.WithError(err).Fatal("failed to generate Helm chart")
+	}
+	if err := writeChart(chart, chartOut); err != nil {
+		log.WithError(err).Fatal("failed to write Helm chart")
+	}
 }
@@ -0,0 +1,323 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package vizieryamls
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	goyaml "gopkg.in/yaml.v2"
+
+	"px.dev/pixie/src/utils/shared/yamls"
+)
+
+// ChartName is the name of the Vizier Helm chart.
+const ChartName = "vizier-chart"
+
+// ChartValue is a value that can be set when installing the Vizier Helm chart.
+type ChartValue struct {
+	// Name is the key of the value in values.yaml.
+	Name string
+	// Default is the value in values.yaml. Its type is the type of the value.
+	Default     interface{}
+	Description string
+	// CRDField is the field of the Vizier CRD that sets the same option, if any.
+	CRDField string
+	// DeployFlag is the `px deploy` flag that sets the same option, if any.
+	DeployFlag string
+}
+
+// ChartValues are the values accepted by the Vizier Helm chart, in the order they appear in values.yaml.
+// Every value used by the templated YAMLs must be listed, or GenerateHelmChart fails.
+var ChartValues = []*ChartValue{
+	{
+		Name:        "deployKey",
+		Default:     "",
+		Description: "The deploy key used to register the cluster with Pixie Cloud.",
+		CRDField:    "spec.deployKey",
+		DeployFlag:  "deploy_key",
+	},
+	{
+		Name:        "customDeployKeySecret",
+		Default:     "",
+		Description: "The name of an existing secret containing the deploy key, under the key \"deploy-key\". Defaults to pl-deploy-secrets.",
+		CRDField:    "spec.customDeployKeySecret",
+	},
+	{
+		Name:        "clusterName",
+		Default:     "",
+		Description: "The name of the cluster. If empty, a name is generated.",
+		CRDField:    "spec.clusterName",
+		DeployFlag:  "cluster_name",
+	},
+	{
+		Name:        "cloudAddr",
+		Default:     "",
+		Description: "The address of Pixie Cloud. Defaults to withpixie.ai:443.",
+		CRDField:    "spec.cloudAddr",
+	},
+	{
+		Name:        "cloudUpdateAddr",
+		Default:     "",
+		Description: "The address of Pixie Cloud used by the updater. Defaults to withpixie.ai:443.",
+	},
+	{
+		Name:        "disableAutoUpdate",
+		Default:     false,
+		Description: "Disables automatic updates of Vizier.",
+		CRDField:    "spec.disableAutoUpdate",
+		DeployFlag:  "disable_auto_update",
+	},
+	{
+		Name:        "useEtcdOperator",
+		Default:     false,
+		Description: "Stores metadata in etcd instead of a persistent volume.",
+		CRDField:    "spec.useEtcdOperator",
+		DeployFlag:  "use_etcd_operator",
+	},
+	{
+		Name:        "autopilot",
+		Default:     false,
+		Description: "Should be set when running on GKE Autopilot.",
+		CRDField:    "spec.autopilot",
+	},
+	{
+		Name:        "singleProcessControlPlane",
+		Default:     false,
+		Description: "Runs the metadata service, the query broker, the cloud connector and NATS in a single pod, for small and edge clusters.",
+		CRDField:    "spec.singleProcessControlPlane",
+		DeployFlag:  "single_process_control_plane",
+	},
+	{
+		Name:        "useBetaPdbVersion",
+		Default:     false,
+		Description: "Uses the policy/v1beta1 PodDisruptionBudget API, which is required on Kubernetes versions before 1.21.",
+	},
+	{
+		Name:        "customLabels",
+		Default:     "",
+		Description: "Comma-separated key=value labels to add to all Pixie resources.",
+		CRDField:    "spec.pod.labels",
+		DeployFlag:  "labels",
+	},
+	{
+		Name:        "customAnnotations",
+		Default:     "",
+		Description: "Comma-separated key=value annotations to add to all Pixie resources.",
+		CRDField:    "spec.pod.annotations",
+		DeployFlag:  "annotations",
+	},
+	{
+		Name:        "registry",
+		Default:     "",
+		Description: "A custom image registry to use instead of the default registries.",
+		CRDField:    "spec.registry",
+		DeployFlag:  "registry",
+	},
+	{
+		Name:        "pemMemoryLimit",
+		Default:     "",
+		Description: fmt.Sprintf("The memory limit of the PEMs. Defaults to %s.", defaultMemoryLimit),
+		CRDField:    "spec.pemMemoryLimit",
+		DeployFlag:  "pem_memory_limit",
+	},
+	{
+		Name:        "pemMemoryRequest",
+		Default:     "",
+		Description: fmt.Sprintf("The memory request of the PEMs. Defaults to %s.", defaultMemoryLimit),
+		CRDField:    "spec.pemMemoryRequest",
+		DeployFlag:  "pem_memory_request",
+	},
+	{
+		Name:        "customPEMFlags",
+		Default:     map[string]string{},
+		Description: "Environment variables to set on the PEMs, keyed by name.",
+		CRDField:    "spec.dataCollectorParams.customPEMFlags",
+		DeployFlag:  "pem_flags",
+	},
+	{
+		Name:        "datastreamBufferSize",
+		Default:     0,
+		Description: "The maximum size of a data stream buffer retained between cycles. 0 uses the PEM's default.",
+		CRDField:    "spec.dataCollectorParams.datastreamBufferSize",
+		DeployFlag:  "datastream_buffer_size",
+	},
+	{
+		Name:        "datastreamBufferSpikeSize",
+		Default:     0,
+		Description: "The maximum temporary size of a data stream buffer before processing. 0 uses the PEM's default.",
+		CRDField:    "spec.dataCollectorParams.datastreamBufferSpikeSize",
+		DeployFlag:  "datastream_buffer_spike_size",
+	},
+	{
+		Name:        "clockConverter",
+		Default:     "",
+		Description: "The clock converter used by the PEMs, either \"default\" or \"grpc\".",
+		CRDField:    "spec.clockConverter",
+	},
+	{
+		Name:        "dataAccess",
+		Default:     "",
+		Description: fmt.Sprintf("The level of data that scripts may access, either \"Full\" or \"Restricted\". Defaults to %s.", defaultDataAccess),
+		CRDField:    "spec.dataAccess",
+		DeployFlag:  "data_access",
+	},
+	{
+		Name:        "electionPeriodMs",
+		Default:     0,
+		Description: fmt.Sprintf("The leader election period of the Vizier services, in milliseconds. Defaults to %d.", defaultElectionPeriodMs),
+		CRDField:    "spec.leadershipElectionParams.electionPeriodMs",
+	},
+	{
+		Name:        "sentryDSN",
+		Default:     "",
+		Description: "The Sentry DSN that Vizier reports errors to.",
+	},
+}
+
+var valuesRefRegex = regexp.MustCompile(`\.Values\.(\w+)`)
+
+// checkChartValues returns an error if the YAMLs use a value that is not in ChartValues.
+func checkChartValues(templatedYAMLs []*yamls.YAMLFile) error {
+	known := make(map[string]bool, len(ChartValues))
+	for _, v := range ChartValues {
+		known[v.Name] = true
+	}
+
+	missing := make(map[string]bool)
+	for _, y := range templatedYAMLs {
+		for _, m := range valuesRefRegex.FindAllStringSubmatch(y.YAML, -1) {
+			if !known[m[1]] {
+				missing[m[1]] = true
+			}
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(missing))
+	for name := range missing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("templated YAMLs use values that are missing from ChartValues: %s", strings.Join(names, ", "))
+}
+
+func generateValuesYAML() (string, error) {
+	var b strings.Builder
+	b.WriteString("# Default values for the Vizier chart. The same options can be set on the Vizier CRD, or with `px deploy`.\n")
+	for _, v := range ChartValues {
+		b.WriteString("\n# " + v.Description + "\n")
+		var sources []string
+		if v.CRDField != "" {
+			sources = append(sources, "Vizier CRD: "+v.CRDField)
+		}
+		if v.DeployFlag != "" {
+			sources = append(sources, "px deploy: --"+v.DeployFlag)
+		}
+		if len(sources) > 0 {
+			b.WriteString("# " + strings.Join(sources, ", ") + "\n")
+		}
+
+		value, err := goyaml.Marshal(map[string]interface{}{v.Name: v.Default})
+		if err != nil {
+			return "", err
+		}
+		b.Write(value)
+	}
+	return b.String(), nil
+}
+
+func jsonSchemaType(v interface{}) (map[string]interface{}, error) {
+	switch v.(type) {
+	case string:
+		return map[string]interface{}{"type": "string"}, nil
+	case bool:
+		return map[string]interface{}{"type": "boolean"}, nil
+	case int, int64, uint32:
+		return map[string]interface{}{"type": "integer", "minimum": 0}, nil
+	case map[string]string:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": map[string]interface{}{"type": "string"},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported chart value type %T", v)
+	}
+}
+
+func generateValuesSchema() (string, error) {
+	properties := make(map[string]interface{}, len(ChartValues))
+	for _, v := range ChartValues {
+		s, err := jsonSchemaType(v.Default)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", v.Name, err)
+		}
+		s["description"] = v.Description
+		properties[v.Name] = s
+	}
+
+	schema, err := json.MarshalIndent(map[string]interface{}{
+		"$schema":    "http://json-schema.org/draft-07/schema#",
+		"type":       "object",
+		"properties": properties,
+	}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(schema) + "\n", nil
+}
+
+// GenerateHelmChart generates the files in the Vizier Helm chart from the templated Vizier YAMLs, keyed by
+// their path in the chart.
+func GenerateHelmChart(templatedYAMLs []*yamls.YAMLFile, versionStr string) (map[string]string, error) {
+	if err := checkChartValues(templatedYAMLs); err != nil {
+		return nil, err
+	}
+
+	values, err := generateValuesYAML()
+	if err != nil {
+		return nil, err
+	}
+	schema, err := generateValuesSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string]string{
+		"Chart.yaml": fmt.Sprintf(`apiVersion: v2
+name: %s
+description: Pixie's in-cluster data plane, Vizier.
+type: application
+version: %s
+appVersion: %s
+`, ChartName, versionStr, versionStr),
+		"values.yaml":        values,
+		"values.schema.json": schema,
+	}
+	// Keep the same file names as the extracted template YAMLs, so that Helm applies them in the same order.
+	for i, y := range templatedYAMLs {
+		files[fmt.Sprintf("templates/%02d_%s.yaml", i, y.Name)] = y.YAML
+	}
+	return files, nil
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package vizieryamls_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	goyaml "gopkg.in/yaml.v2"
+
+	"px.dev/pixie/src/utils/shared/yamls"
+	vizieryamls "px.dev/pixie/src/utils/template_generator/vizier_yamls"
+)
+
+const testPEMYAML = `
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: vizier-pem
+  namespace: pl
+spec:
+  template:
+    spec:
+      containers:
+      - name: pem
+        image: gcr.io/pixie-oss/pixie-prod/vizier-pem_image:latest
+`
+
+func testYAMLMap() map[string]string {
+	return map[string]string{
+		"yamls/vizier/vizier_etcd_metadata_prod.yaml":              testPEMYAML,
+		"yamls/vizier/vizier_metadata_persist_prod.yaml":           testPEMYAML,
+		"yamls/vizier/vizier_etcd_metadata_autopilot_prod.yaml":    testPEMYAML,
+		"yamls/vizier/vizier_metadata_persist_autopilot_prod.yaml": testPEMYAML,
+		"yamls/vizier_deps/etcd_prod.yaml":                         "",
+		"yamls/vizier_deps/nats_prod.yaml":                         "",
+	}
+}
+
+func TestChartValues_MatchTemplateValues(t *testing.T) {
+	args := vizieryamls.VizierTmplValuesToArgs(&vizieryamls.VizierTmplValues{})
+
+	chartValues := make(map[string]bool)
+	for _, v := range vizieryamls.ChartValues {
+		assert.False(t, chartValues[v.Name], "duplicate chart value %s", v.Name)
+		chartValues[v.Name] = true
+		assert.NotEmpty(t, v.Description, v.Name)
+	}
+	for name := range *args.Values {
+		assert.True(t, chartValues[name], "template value %s is missing from ChartValues", name)
+	}
+	assert.Equal(t, len(*args.Values), len(chartValues))
+}
+
+func TestGenerateHelmChart(t *testing.T) {
+	tmpls, err := vizieryamls.GenerateTemplatedDeployYAMLs(testYAMLMap(), "0.12.0")
+	require.NoError(t, err)
+
+	chart, err := vizieryamls.GenerateHelmChart(tmpls, "0.12.0")
+	require.NoError(t, err)
+
+	meta := map[string]interface{}{}
+	require.NoError(t, goyaml.Unmarshal([]byte(chart["Chart.yaml"]), &meta))
+	assert.Equal(t, "vizier-chart", meta["name"])
+	assert.Equal(t, "0.12.0", meta["version"])
+
+	values := map[string]interface{}{}
+	require.NoError(t, goyaml.Unmarshal([]byte(chart["values.yaml"]), &values))
+	assert.Equal(t, len(vizieryamls.ChartValues), len(values))
+	assert.Equal(t, false, values["useEtcdOperator"])
+	assert.Contains(t, chart["values.yaml"], "# Vizier CRD: spec.deployKey, px deploy: --deploy_key\ndeployKey: \"\"\n")
+
+	schema := struct {
+		Properties map[string]map[string]interface{} `json:"properties"`
+	}{}
+	require.NoError(t, json.Unmarshal([]byte(chart["values.schema.json"]), &schema))
+	assert.Equal(t, "boolean", schema.Properties["autopilot"]["type"])
+	assert.Equal(t, "object", schema.Properties["customPEMFlags"]["type"])
+	assert.Equal(t, "integer", schema.Properties["electionPeriodMs"]["type"])
+
+	for i, y := range tmpls {
+		assert.Equal(t, y.YAML, chart[fmt.Sprintf("templates/%02d_%s.yaml", i, y.Name)])
+	}
+
+	// The templates should render with the chart's default values.
+	_, err = yamls.ExecuteTemplatedYAMLs(tmpls, &yamls.YAMLTmplArguments{
+		Values:  &values,
+		Release: &map[string]interface{}{"Namespace": "pl"},
+	})
+	require.NoError(t, err)
+}
+
+func TestGenerateHelmChart_UnknownValue(t *testing.T) {
+	_, err := vizieryamls.GenerateHelmChart([]*yamls.YAMLFile{
+		{Name: "test", YAML: `replicas: {{ .Values.replicas }}{{ .Values.deployKey }}`},
+	}, "0.12.0")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "replicas")
+	assert.NotContains(t, err.Error(), "deployKey")
+}
+
+const testControlPlaneYAML = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: vizier-control-plane
+  namespace: pl
+`
+
+func TestGenerateTemplatedDeployYAMLs_SingleProcessControlPlane(t *testing.T) {
+	yamlMap := testYAMLMap()
+	yamlMap["yamls/vizier/vizier_control_plane_prod.yaml"] = testControlPlaneYAML
+	tmpls, err := vizieryamls.GenerateTemplatedDeployYAMLs(yamlMap, "0.12.0")
+	require.NoError(t, err)
+
+	render := func(singleProcess bool) map[string]string {
+		args := vizieryamls.VizierTmplValuesToArgs(&vizieryamls.VizierTmplValues{SingleProcessControlPlane: singleProcess})
+		rendered, err := yamls.ExecuteTemplatedYAMLs(tmpls, args)
+		require.NoError(t, err)
+		vizierYAMLs := make(map[string]string)
+		for _, y := range rendered {
+			if strings.HasPrefix(y.Name, "vizier_") && strings.TrimSpace(y.YAML) != "" {
+				vizierYAMLs[y.Name] = y.YAML
+			}
+		}
+		return vizierYAMLs
+	}
+
+	distributed := render(false)
+	assert.Len(t, distributed, 1)
+	assert.Contains(t, distributed["vizier_persistent"], "vizier-pem")
+
+	singleProcess := render(true)
+	assert.Len(t, singleProcess, 1)
+	assert.Contains(t, singleProcess["vizier_control_plane"], "vizier-control-plane")
+}
+
+func TestGenerateTemplatedDeployYAMLs_SingleProcessControlPlaneUnsupported(t *testing.T) {
+	// The YAML map of a Vizier version from before the single process control plane.
+	tmpls, err := vizieryamls.GenerateTemplatedDeployYAMLs(testYAMLMap(), "0.12.0")
+	require.NoError(t, err)
+
+	_, err = yamls.ExecuteTemplatedYAMLs(tmpls, vizieryamls.VizierTmplValuesToArgs(&vizieryamls.VizierTmplValues{}))
+	require.NoError(t, err)
+	_, err = yamls.ExecuteTemplatedYAMLs(tmpls, vizieryamls.VizierTmplValuesToArgs(&vizieryamls.VizierTmplValues{SingleProcessControlPlane: true}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "single process control plane")
+}
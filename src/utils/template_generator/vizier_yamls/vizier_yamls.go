@@ -32,6 +32,7 @@ const (
 	vizierMetadataPersistYAMLPath          = "yamls/vizier/vizier_metadata_persist_prod.yaml"
 	vizierEtcdAutopilotYAMLPath            = "yamls/vizier/vizier_etcd_metadata_autopilot_prod.yaml"
 	vizierMetadataPersistAutopilotYAMLPath = "yamls/vizier/vizier_metadata_persist_autopilot_prod.yaml"
+	vizierControlPlaneYAMLPath             = "yamls/vizier/vizier_control_plane_prod.yaml"
 	etcdYAMLPath                           = "yamls/vizier_deps/etcd_prod.yaml"
 	natsYAMLPath                           = "yamls/vizier_deps/nats_prod.yaml"
 	// Note: if you update this value, make sure you also update defaultUncappedTableStoreSizeMB in
@@ -65,6 +66,10 @@ type VizierTmplValues struct {
 	CustomPEMFlags            map[string]string
 	Registry                  string
 	UseBetaPdbVersion         bool
+	Autopilot                 bool
+	// SingleProcessControlPlane deploys the single process control plane instead of the separate
+	// metadata, query broker and cloud connector services.
+	SingleProcessControlPlane bool
 }
 
 // VizierTmplValuesToArgs converts the vizier template values to args which can be used to fill out a template.
@@ -91,6 +96,8 @@ func VizierTmplValuesToArgs(tmplValues *VizierTmplValues) *yamls.YAMLTmplArgumen
 			"customPEMFlags":            tmplValues.CustomPEMFlags,
 			"registry":                  tmplValues.Registry,
 			"useBetaPdbVersion":         tmplValues.UseBetaPdbVersion,
+			"autopilot":                 tmplValues.Autopilot,
+			"singleProcessControlPlane": tmplValues.SingleProcessControlPlane,
 		},
 		Release: &map[string]interface{}{
 			"Namespace": tmplValues.Namespace,
@@ -478,6 +485,66 @@ func generateVzYAMLs(yamlMap map[string]string) ([]*yamls.YAMLFile, error) {
 			Placeholder:     "__PX_DEPLOY_KEY_SECRET_NAME__",
 			TemplateValue:   `{{ if .Values.customDeployKeySecret }}"{{ .Values.customDeployKeySecret }}"{{else}}"pl-deploy-secrets"{{end}}`,
 		},
+		{
+			TemplateMatcher: yamls.GenerateResourceNameMatcherFn("pl-control-plane-metadata-cluster-binding"),
+			Patch:           `{ "subjects": [{ "name": "control-plane-service-account", "namespace": "__PX_SUBJECT_NAMESPACE__", "kind": "ServiceAccount" }] }`,
+			Placeholder:     "__PX_SUBJECT_NAMESPACE__",
+			TemplateValue:   nsTmpl,
+		},
+		{
+			TemplateMatcher: yamls.GenerateResourceNameMatcherFn("pl-control-plane-node-view-cluster-binding"),
+			Patch:           `{ "subjects": [{ "name": "control-plane-service-account", "namespace": "__PX_SUBJECT_NAMESPACE__", "kind": "ServiceAccount" }] }`,
+			Placeholder:     "__PX_SUBJECT_NAMESPACE__",
+			TemplateValue:   nsTmpl,
+		},
+		{
+			TemplateMatcher: yamls.GenerateResourceNameMatcherFn("pl-control-plane-cloud-connector-cluster-binding"),
+			Patch:           `{ "subjects": [{ "name": "control-plane-service-account", "namespace": "__PX_SUBJECT_NAMESPACE__", "kind": "ServiceAccount" }] }`,
+			Placeholder:     "__PX_SUBJECT_NAMESPACE__",
+			TemplateValue:   nsTmpl,
+		},
+		{
+			TemplateMatcher: yamls.GenerateResourceNameMatcherFn("pl-control-plane-crd-binding"),
+			Patch:           `{ "subjects": [{ "name": "control-plane-service-account", "namespace": "__PX_SUBJECT_NAMESPACE__", "kind": "ServiceAccount" }] }`,
+			Placeholder:     "__PX_SUBJECT_NAMESPACE__",
+			TemplateValue:   nsTmpl,
+		},
+		{
+			TemplateMatcher: yamls.GenerateResourceNameMatcherFn("pl-control-plane-metadata-binding"),
+			Patch:           `{ "subjects": [{ "name": "control-plane-service-account", "namespace": "__PX_SUBJECT_NAMESPACE__", "kind": "ServiceAccount" }] }`,
+			Placeholder:     "__PX_SUBJECT_NAMESPACE__",
+			TemplateValue:   nsTmpl,
+		},
+		{
+			TemplateMatcher: yamls.GenerateResourceNameMatcherFn("pl-control-plane-query-broker-binding"),
+			Patch:           `{ "subjects": [{ "name": "control-plane-service-account", "namespace": "__PX_SUBJECT_NAMESPACE__", "kind": "ServiceAccount" }] }`,
+			Placeholder:     "__PX_SUBJECT_NAMESPACE__",
+			TemplateValue:   nsTmpl,
+		},
+		{
+			TemplateMatcher: yamls.GenerateResourceNameMatcherFn("pl-control-plane-cloud-connector-binding"),
+			Patch:           `{ "subjects": [{ "name": "control-plane-service-account", "namespace": "__PX_SUBJECT_NAMESPACE__", "kind": "ServiceAccount" }] }`,
+			Placeholder:     "__PX_SUBJECT_NAMESPACE__",
+			TemplateValue:   nsTmpl,
+		},
+		{
+			TemplateMatcher: yamls.GenerateResourceNameMatcherFn("vizier-control-plane"),
+			Patch:           `{"spec": {"template": {"spec": {"containers": [{"name": "app", "env": [{"name": "PL_DATA_ACCESS","value": "__PX_DATA_ACCESS__"}]}] } } } }`,
+			Placeholder:     "__PX_DATA_ACCESS__",
+			TemplateValue:   fmt.Sprintf(`{{ if .Values.dataAccess }}"{{ .Values.dataAccess }}"{{else}}"%s"{{end}}`, defaultDataAccess),
+		},
+		{
+			TemplateMatcher: yamls.GenerateResourceNameMatcherFn("vizier-control-plane"),
+			Patch:           `{"spec": {"template": {"spec": {"containers": [{"name": "app", "env": [{"name": "PL_RENEW_PERIOD","value": "__PX_RENEW_PERIOD__"}]}] } } } }`,
+			Placeholder:     "__PX_RENEW_PERIOD__",
+			TemplateValue:   fmt.Sprintf(`{{ if .Values.electionPeriodMs }}"{{ .Values.electionPeriodMs }}"{{else}}"%d"{{end}}`, defaultElectionPeriodMs),
+		},
+		{
+			TemplateMatcher: yamls.GenerateResourceNameMatcherFn("vizier-control-plane"),
+			Patch:           `{"spec": {"template": {"spec": {"containers": [{"name": "app", "env": [{"name": "PL_DEPLOY_KEY", "valueFrom": { "secretKeyRef": { "key": "deploy-key", "name": "__PX_DEPLOY_KEY_SECRET_NAME__", "optional": true} } }]}] }  } } }`,
+			Placeholder:     "__PX_DEPLOY_KEY_SECRET_NAME__",
+			TemplateValue:   `{{ if .Values.customDeployKeySecret }}"{{ .Values.customDeployKeySecret }}"{{else}}"pl-deploy-secrets"{{end}}`,
+		},
 	}...)
 
 	persistentYAML, err := yamls.TemplatizeK8sYAML(yamlMap[vizierMetadataPersistYAMLPath], tmplOptions)
@@ -487,7 +554,7 @@ func generateVzYAMLs(yamlMap map[string]string) ([]*yamls.YAMLFile, error) {
 	}
 	// The persistent YAML should only be applied if --use_etcd_operator is false. The entire YAML should be wrapped in a template.
 	wrappedPersistent := fmt.Sprintf(
-		`{{if and (not .Values.autopilot) (not .Values.useEtcdOperator)}}
+		`{{if and (not .Values.singleProcessControlPlane) (not .Values.autopilot) (not .Values.useEtcdOperator)}}
 %s
 {{- end}}`,
 		persistentYAML)
@@ -498,7 +565,7 @@ func generateVzYAMLs(yamlMap map[string]string) ([]*yamls.YAMLFile, error) {
 	}
 	// The etcd version of Vizier should only be applied if --use_etcd_operator is true. The entire YAML should be wrapped in a template.
 	wrappedEtcd := fmt.Sprintf(
-		`{{if and (not .Values.autopilot) .Values.useEtcdOperator}}
+		`{{if and (not .Values.singleProcessControlPlane) (not .Values.autopilot) .Values.useEtcdOperator}}
 %s
 {{- end}}`,
 		etcdYAML)
@@ -508,7 +575,7 @@ func generateVzYAMLs(yamlMap map[string]string) ([]*yamls.YAMLFile, error) {
 		return nil, err
 	}
 	wrappedPersistentAP := fmt.Sprintf(
-		`{{if and (.Values.autopilot) (not .Values.useEtcdOperator)}}
+		`{{if and (not .Values.singleProcessControlPlane) (.Values.autopilot) (not .Values.useEtcdOperator)}}
 %s
 {{- end}}`,
 		persistentAutopilotYAML)
@@ -518,11 +585,28 @@ func generateVzYAMLs(yamlMap map[string]string) ([]*yamls.YAMLFile, error) {
 		return nil, err
 	}
 	wrappedEtcdAP := fmt.Sprintf(
-		`{{if and (.Values.autopilot) (.Values.useEtcdOperator)}}
+		`{{if and (not .Values.singleProcessControlPlane) (.Values.autopilot) (.Values.useEtcdOperator)}}
 %s
 {{- end}}`,
 		etcdAutopilotYAML)
 
+	// Vizier versions from before the single process control plane don't include its YAML, so deploying them
+	// with it enabled fails instead of deploying no control plane at all.
+	wrappedControlPlane := `{{if .Values.singleProcessControlPlane}}
+{{ fail "This Vizier version does not support the single process control plane. Please update to the latest Vizier version." }}
+{{- end}}`
+	if controlPlaneYAML, ok := yamlMap[vizierControlPlaneYAMLPath]; ok {
+		controlPlaneYAML, err = yamls.TemplatizeK8sYAML(controlPlaneYAML, tmplOptions)
+		if err != nil {
+			return nil, err
+		}
+		wrappedControlPlane = fmt.Sprintf(
+			`{{if .Values.singleProcessControlPlane}}
+%s
+{{- end}}`,
+			controlPlaneYAML)
+	}
+
 	return []*yamls.YAMLFile{
 		{
 			Name: "vizier_etcd",
@@ -540,6 +624,10 @@ func generateVzYAMLs(yamlMap map[string]string) ([]*yamls.YAMLFile, error) {
 			Name: "vizier_persistent_ap",
 			YAML: wrappedPersistentAP,
 		},
+		{
+			Name: "vizier_control_plane",
+			YAML: wrappedControlPlane,
+		},
 	}, nil
 }
 
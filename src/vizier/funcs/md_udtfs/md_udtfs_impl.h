@@ -28,6 +28,7 @@
 #include <vector>
 
 #include <absl/numeric/int128.h>
+#include <absl/strings/str_join.h>
 #include <grpcpp/grpcpp.h>
 #include <magic_enum.hpp>
 
@@ -396,6 +397,79 @@ class GetProfilerSamplingPeriodMS final : public carnot::udf::UDTF<GetProfilerSa
   std::function<void(grpc::ClientContext*)> add_context_authentication_func_;
 };
 
+/**
+ * This UDTF shows the kernel capabilities reported by each data collecting agent.
+ */
+class GetNodeCapabilities final : public carnot::udf::UDTF<GetNodeCapabilities> {
+ public:
+  using MDSStub = vizier::services::metadata::MetadataService::Stub;
+  GetNodeCapabilities() = delete;
+  GetNodeCapabilities(std::shared_ptr<MDSStub> stub,
+                      std::function<void(grpc::ClientContext*)> add_context_authentication)
+      : idx_(0), stub_(stub), add_context_authentication_func_(add_context_authentication) {}
+
+  static constexpr auto Executor() { return carnot::udfspb::UDTFSourceExecutor::UDTF_ONE_KELVIN; }
+
+  static constexpr auto OutputRelation() {
+    return MakeArray(
+        ColInfo("asid", types::DataType::INT64, types::PatternType::GENERAL, "The Agent Short ID"),
+        ColInfo("hostname", types::DataType::STRING, types::PatternType::GENERAL,
+                "The hostname of the agent"),
+        ColInfo("kernel_version", types::DataType::STRING, types::PatternType::GENERAL,
+                "The version of the kernel running on the node"),
+        ColInfo("btf_available", types::DataType::BOOLEAN, types::PatternType::GENERAL,
+                "Whether the kernel exposes BTF type information"),
+        ColInfo("attached_sources", types::DataType::STRING, types::PatternType::GENERAL,
+                "Comma separated list of the sources that initialized successfully"),
+        ColInfo("failed_sources", types::DataType::STRING, types::PatternType::GENERAL,
+                "Comma separated list of the sources that failed to initialize"));
+  }
+
+  Status Init(FunctionContext*) {
+    px::vizier::services::metadata::AgentInfoRequest req;
+    resp_ = std::make_unique<px::vizier::services::metadata::AgentInfoResponse>();
+
+    grpc::ClientContext ctx;
+    add_context_authentication_func_(&ctx);
+    auto s = stub_->GetAgentInfo(&ctx, req, resp_.get());
+    if (!s.ok()) {
+      return error::Internal("Failed to make RPC call to GetAgentInfo");
+    }
+    // Only the agents that collect data run Stirling, so only they report kernel capabilities.
+    for (const auto& agent_metadata : resp_->info()) {
+      if (agent_metadata.agent().info().capabilities().collects_data()) {
+        agents_.push_back(&agent_metadata.agent());
+      }
+    }
+    return Status::OK();
+  }
+
+  bool NextRecord(FunctionContext*, RecordWriter* rw) {
+    if (agents_.empty()) {
+      return false;
+    }
+    const auto& agent_info = *agents_[idx_];
+    const auto& kernel = agent_info.info().capabilities().kernel();
+
+    rw->Append<IndexOf("asid")>(agent_info.asid());
+    rw->Append<IndexOf("hostname")>(agent_info.info().host_info().hostname());
+    rw->Append<IndexOf("kernel_version")>(kernel.kernel_version());
+    rw->Append<IndexOf("btf_available")>(kernel.btf_available());
+    rw->Append<IndexOf("attached_sources")>(absl::StrJoin(kernel.attached_sources(), ","));
+    rw->Append<IndexOf("failed_sources")>(absl::StrJoin(kernel.failed_sources(), ","));
+
+    ++idx_;
+    return idx_ < static_cast<int>(agents_.size());
+  }
+
+ private:
+  int idx_ = 0;
+  std::unique_ptr<px::vizier::services::metadata::AgentInfoResponse> resp_;
+  std::vector<const px::vizier::services::shared::agent::Agent*> agents_;
+  std::shared_ptr<MDSStub> stub_;
+  std::function<void(grpc::ClientContext*)> add_context_authentication_func_;
+};
+
 namespace internal {
 inline rapidjson::GenericStringRef<char> StringRef(std::string_view s) {
   return rapidjson::GenericStringRef<char>(s.data(), s.size());
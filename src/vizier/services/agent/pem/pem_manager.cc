@@ -18,7 +18,11 @@
 
 #include "src/vizier/services/agent/pem/pem_manager.h"
 
+#include <filesystem>
+#include <map>
+
 #include "src/common/system/config.h"
+#include "src/stirling/utils/linux_headers.h"
 #include "src/vizier/services/agent/shared/manager/exec.h"
 #include "src/vizier/services/agent/shared/manager/manager.h"
 
@@ -43,6 +47,19 @@ DEFINE_int32(table_store_proc_exit_events_limit_bytes,
              gflags::Int32FromEnv("PL_TABLE_STORE_PROC_EXIT_EVENTS_LIMIT_BYTES", 10 * 1024 * 1024),
              "The maximum amount of data to store in the proc_exit_events table.");
 
+DEFINE_int32(user_events_port, gflags::Int32FromEnv("PL_USER_EVENTS_PORT", 0),
+             "The port that applications push their own events to. The events are stored in "
+             "user_ tables, which can be queried alongside the traced data. The service is "
+             "unauthenticated, so it is disabled unless a port is set.");
+
+DEFINE_int32(user_events_max_tables, gflags::Int32FromEnv("PL_USER_EVENTS_MAX_TABLES", 16),
+             "The maximum number of tables that applications can push events to.");
+
+DEFINE_int32(user_events_table_size_limit_bytes,
+             gflags::Int32FromEnv("PL_USER_EVENTS_TABLE_SIZE_LIMIT_BYTES", 16 * 1024 * 1024),
+             "The maximum amount of data to store in each table of pushed events. This is in "
+             "addition to the table store data limit.");
+
 namespace px {
 namespace vizier {
 namespace agent {
@@ -50,9 +67,34 @@ namespace agent {
 Status PEMManager::InitImpl() {
   PX_RETURN_IF_ERROR(InitClockConverters());
   StartNodeMemoryCollector();
+  InitKernelCapabilities();
   return Status::OK();
 }
 
+void PEMManager::InitKernelCapabilities() {
+  auto* kernel = info()->capabilities.mutable_kernel();
+
+  auto kernel_version_or = px::stirling::utils::GetKernelVersion();
+  if (kernel_version_or.ok()) {
+    kernel->set_kernel_version(kernel_version_or.ValueOrDie().ToString());
+  } else {
+    VLOG(1) << absl::Substitute("Unable to determine kernel version: $0",
+                                kernel_version_or.msg());
+  }
+
+  std::error_code ec;
+  const auto btf_path = px::system::Config::GetInstance().ToHostPath("/sys/kernel/btf/vmlinux");
+  kernel->set_btf_available(std::filesystem::exists(btf_path, ec));
+
+  for (const auto& [source, status] : stirling_->SourceInitStatus()) {
+    if (status.ok()) {
+      kernel->add_attached_sources(source);
+    } else {
+      kernel->add_failed_sources(source);
+    }
+  }
+}
+
 Status PEMManager::PostRegisterHookImpl() {
   stirling_->RegisterDataPushCallback(std::bind(&table_store::TableStore::AppendData, table_store(),
                                                 std::placeholders::_1, std::placeholders::_2,
@@ -78,10 +120,23 @@ Status PEMManager::PostRegisterHookImpl() {
                                           stirling_.get(), table_store(), relation_info_manager());
   PX_RETURN_IF_ERROR(RegisterMessageHandler(messages::VizierMessage::MsgCase::kTracepointMessage,
                                             tracepoint_manager_));
-  return Status::OK();
+  return StartUserEventsServer();
+}
+
+Status PEMManager::StartUserEventsServer() {
+  if (FLAGS_user_events_port <= 0) {
+    return Status::OK();
+  }
+  user_events_server_ = std::make_unique<UserEventsServer>(
+      table_store(), relation_info_manager(), FLAGS_user_events_max_tables,
+      FLAGS_user_events_table_size_limit_bytes);
+  return user_events_server_->Start(FLAGS_user_events_port);
 }
 
 Status PEMManager::StopImpl(std::chrono::milliseconds) {
+  if (user_events_server_ != nullptr) {
+    user_events_server_->Stop();
+  }
   stirling_->Stop();
   stirling_.reset();
   return Status::OK();
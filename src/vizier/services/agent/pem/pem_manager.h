@@ -28,6 +28,7 @@
 
 #include "src/stirling/stirling.h"
 #include "src/vizier/services/agent/pem/tracepoint_manager.h"
+#include "src/vizier/services/agent/pem/user_events_server.h"
 #include "src/vizier/services/agent/shared/manager/manager.h"
 
 DECLARE_uint32(stirling_profiler_stack_trace_sample_period_ms);
@@ -84,7 +85,11 @@ class PEMManager : public Manager {
  private:
   Status InitSchemas();
   Status InitClockConverters();
+  Status StartUserEventsServer();
   void StartNodeMemoryCollector();
+  // Records the kernel version, BTF availability and source init results in the agent
+  // capabilities, so they are reported to the metadata service on registration.
+  void InitKernelCapabilities();
   static services::shared::agent::AgentCapabilities Capabilities() {
     services::shared::agent::AgentCapabilities capabilities;
     capabilities.set_collects_data(true);
@@ -100,6 +105,8 @@ class PEMManager : public Manager {
 
   std::unique_ptr<stirling::Stirling> stirling_;
   std::shared_ptr<TracepointManager> tracepoint_manager_;
+  // Only set if the user events server is enabled.
+  std::unique_ptr<UserEventsServer> user_events_server_;
 
   // Timer for triggering ClockConverter polls.
   px::event::TimerUPtr clock_converter_timer_;
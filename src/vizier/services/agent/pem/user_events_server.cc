@@ -0,0 +1,257 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#include "src/vizier/services/agent/pem/user_events_server.h"
+
+#include <utility>
+#include <vector>
+
+#include <absl/strings/ascii.h>
+#include <absl/strings/match.h>
+
+namespace px {
+namespace vizier {
+namespace agent {
+
+namespace {
+
+constexpr char kTimeColumn[] = "time_";
+
+::grpc::Status ToGRPCStatus(const Status& s) {
+  if (s.ok()) {
+    return ::grpc::Status::OK;
+  }
+  switch (s.code()) {
+    case statuspb::INVALID_ARGUMENT:
+      return ::grpc::Status(::grpc::StatusCode::INVALID_ARGUMENT, s.msg());
+    case statuspb::RESOURCE_UNAVAILABLE:
+      return ::grpc::Status(::grpc::StatusCode::RESOURCE_EXHAUSTED, s.msg());
+    default:
+      return ::grpc::Status(::grpc::StatusCode::INTERNAL, s.msg());
+  }
+}
+
+Status ValidateTableName(std::string_view name) {
+  if (!absl::StartsWith(name, kUserTablePrefix) || name.size() == kUserTablePrefix.size()) {
+    return error::InvalidArgument("Table name '$0' must start with '$1'.", name, kUserTablePrefix);
+  }
+  for (char c : name) {
+    if (!absl::ascii_isalnum(c) && c != '_') {
+      return error::InvalidArgument(
+          "Table name '$0' may only contain letters, digits and underscores.", name);
+    }
+  }
+  return Status::OK();
+}
+
+bool IsSupportedType(types::DataType type) {
+  switch (type) {
+    case types::BOOLEAN:
+    case types::INT64:
+    case types::FLOAT64:
+    case types::STRING:
+    case types::TIME64NS:
+      return true;
+    default:
+      return false;
+  }
+}
+
+// Appends the value to the column, if it has the type of the column.
+Status AppendValue(const userevents::Value& value, types::ColumnWrapper* col) {
+  switch (col->data_type()) {
+    case types::BOOLEAN:
+      if (value.value_case() == userevents::Value::kBoolValue) {
+        col->Append<types::BoolValue>(value.bool_value());
+        return Status::OK();
+      }
+      break;
+    case types::INT64:
+      if (value.value_case() == userevents::Value::kInt64Value) {
+        col->Append<types::Int64Value>(value.int64_value());
+        return Status::OK();
+      }
+      break;
+    case types::FLOAT64:
+      if (value.value_case() == userevents::Value::kFloat64Value) {
+        col->Append<types::Float64Value>(value.float64_value());
+        return Status::OK();
+      }
+      break;
+    case types::STRING:
+      if (value.value_case() == userevents::Value::kStringValue) {
+        col->Append<types::StringValue>(value.string_value());
+        return Status::OK();
+      }
+      break;
+    case types::TIME64NS:
+      if (value.value_case() == userevents::Value::kTime64NsValue) {
+        col->Append<types::Time64NSValue>(value.time64ns_value());
+        return Status::OK();
+      }
+      break;
+    default:
+      break;
+  }
+  return error::InvalidArgument("Expected a value of type $0.", types::ToString(col->data_type()));
+}
+
+}  // namespace
+
+Status UserEventsServer::Start(int port) {
+  std::string server_address(absl::Substitute("0.0.0.0:$0", port));
+  ::grpc::ServerBuilder builder;
+  builder.AddListeningPort(server_address, ::grpc::InsecureServerCredentials());
+  builder.RegisterService(this);
+  server_ = builder.BuildAndStart();
+  if (server_ == nullptr) {
+    return error::Internal("Failed to start the user events server on $0.", server_address);
+  }
+  LOG(INFO) << "User events server listening on " << server_address;
+  return Status::OK();
+}
+
+void UserEventsServer::Stop() {
+  if (server_ != nullptr) {
+    server_->Shutdown();
+    server_.reset();
+  }
+}
+
+::grpc::Status UserEventsServer::PushEvents(::grpc::ServerContext*,
+                                            const userevents::PushEventsRequest* req,
+                                            userevents::PushEventsResponse*) {
+  auto s = Push(*req, CurrentTimeNS());
+  LOG_IF(WARNING, !s.ok() && s.code() != statuspb::INVALID_ARGUMENT)
+      << absl::Substitute("Failed to push events to table $0: $1", req->table_name(), s.msg());
+  return ToGRPCStatus(s);
+}
+
+StatusOr<UserEventsServer::UserTable*> UserEventsServer::GetOrCreateTable(
+    const userevents::PushEventsRequest& req) {
+  if (req.columns_size() > static_cast<int>(kMaxUserTableColumns)) {
+    return error::InvalidArgument("Tables can have at most $0 columns, got $1.",
+                                  kMaxUserTableColumns, req.columns_size());
+  }
+
+  table_store::schema::Relation relation;
+  bool has_time_col = false;
+  for (const auto& col : req.columns()) {
+    if (col.name().empty()) {
+      return error::InvalidArgument("Column names can't be empty.");
+    }
+    if (relation.HasColumn(col.name())) {
+      return error::InvalidArgument("Duplicate column '$0'.", col.name());
+    }
+    if (!IsSupportedType(col.type())) {
+      return error::InvalidArgument("Column '$0' has unsupported type $1.", col.name(),
+                                    types::ToString(col.type()));
+    }
+    if (col.name() == kTimeColumn) {
+      if (col.type() != types::TIME64NS) {
+        return error::InvalidArgument("Column '$0' must have type TIME64NS.", kTimeColumn);
+      }
+      has_time_col = true;
+    }
+    relation.AddColumn(col.type(), col.name());
+  }
+  if (!has_time_col) {
+    table_store::schema::Relation with_time;
+    with_time.AddColumn(types::TIME64NS, kTimeColumn);
+    for (size_t i = 0; i < relation.NumColumns(); ++i) {
+      with_time.AddColumn(relation.GetColumnType(i), relation.GetColumnName(i));
+    }
+    relation = std::move(with_time);
+  }
+
+  auto it = tables_.find(req.table_name());
+  if (it != tables_.end()) {
+    if (it->second.relation != relation) {
+      return error::InvalidArgument("Table $0 already exists with the columns $1.",
+                                    req.table_name(), it->second.relation.DebugString());
+    }
+    return &it->second;
+  }
+
+  PX_RETURN_IF_ERROR(ValidateTableName(req.table_name()));
+  if (relation_info_manager_->HasRelation(req.table_name())) {
+    return error::InvalidArgument("Table $0 already exists, and isn't a user table.",
+                                  req.table_name());
+  }
+  if (tables_.size() >= max_tables_) {
+    return error::ResourceUnavailable("At most $0 user tables can be created.", max_tables_);
+  }
+
+  uint64_t id = kUserTableIDStart + tables_.size();
+  auto table = std::make_shared<table_store::Table>(req.table_name(), relation, table_size_limit_);
+  RelationInfo relation_info(req.table_name(), id, "Events pushed by applications.", relation);
+  PX_RETURN_IF_ERROR(relation_info_manager_->AddRelationInfo(relation_info));
+  table_store_->AddTable(table, req.table_name(), id);
+  LOG(INFO) << absl::Substitute("Created user table $0 with columns $1", req.table_name(),
+                                relation.DebugString());
+
+  auto [inserted, _] =
+      tables_.emplace(req.table_name(), UserTable{relation, std::move(table), !has_time_col});
+  return &inserted->second;
+}
+
+Status UserEventsServer::Push(const userevents::PushEventsRequest& req,
+                              types::Time64NSValue now) {
+  if (static_cast<size_t>(req.rows_size()) > kMaxUserEventsRowsPerPush) {
+    return error::InvalidArgument("At most $0 rows can be pushed at once, got $1.",
+                                  kMaxUserEventsRowsPerPush, req.rows_size());
+  }
+
+  std::lock_guard<std::mutex> lock(mu_);
+  PX_ASSIGN_OR_RETURN(auto user_table, GetOrCreateTable(req));
+  if (req.rows_size() == 0) {
+    return Status::OK();
+  }
+
+  auto record_batch = std::make_unique<types::ColumnWrapperRecordBatch>();
+  for (size_t i = 0; i < user_table->relation.NumColumns(); ++i) {
+    auto col = types::ColumnWrapper::Make(user_table->relation.GetColumnType(i), 0);
+    col->Reserve(req.rows_size());
+    record_batch->push_back(std::move(col));
+  }
+
+  // The pushed columns follow the time_ column, if the PEM adds it.
+  size_t offset = user_table->adds_time_col ? 1 : 0;
+  for (int r = 0; r < req.rows_size(); ++r) {
+    const auto& row = req.rows(r);
+    if (row.values_size() != req.columns_size()) {
+      return error::InvalidArgument("Row $0 has $1 values, expected $2.", r, row.values_size(),
+                                    req.columns_size());
+    }
+    if (user_table->adds_time_col) {
+      (*record_batch)[0]->Append<types::Time64NSValue>(now);
+    }
+    for (int c = 0; c < row.values_size(); ++c) {
+      auto s = AppendValue(row.values(c), (*record_batch)[c + offset].get());
+      if (!s.ok()) {
+        return error::InvalidArgument("Row $0, column '$1': $2", r, req.columns(c).name(),
+                                      s.msg());
+      }
+    }
+  }
+  return user_table->table->TransferRecordBatch(std::move(record_batch));
+}
+
+}  // namespace agent
+}  // namespace vizier
+}  // namespace px
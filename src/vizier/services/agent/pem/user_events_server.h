@@ -0,0 +1,106 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#pragma once
+
+#include <grpcpp/grpcpp.h>
+
+#include <memory>
+#include <mutex>
+#include <string>
+#include <string_view>
+
+#include <absl/container/flat_hash_map.h>
+
+#include "src/common/base/base.h"
+#include "src/table_store/table_store.h"
+#include "src/vizier/services/agent/pem/usereventspb/user_events.grpc.pb.h"
+#include "src/vizier/services/agent/shared/manager/relation_info_manager.h"
+
+namespace px {
+namespace vizier {
+namespace agent {
+
+// User tables get IDs above the range used by Stirling, so that they can't collide.
+constexpr uint64_t kUserTableIDStart = 1ULL << 32;
+constexpr std::string_view kUserTablePrefix = "user_";
+constexpr size_t kMaxUserTableColumns = 64;
+constexpr size_t kMaxUserEventsRowsPerPush = 10000;
+
+/**
+ * UserEventsServer receives the events that applications push to the PEM, and stores them in
+ * named tables of the table store.
+ *
+ * A table is created by the first push to it, and its relation is registered with the
+ * RelationInfoManager, so that the schema reaches the metadata service with the next heartbeat
+ * and the table can be queried like the tables of Stirling.
+ */
+class UserEventsServer final : public userevents::UserEventsService::Service {
+ public:
+  UserEventsServer() = delete;
+  UserEventsServer(table_store::TableStore* table_store, RelationInfoManager* relation_info_manager,
+                   size_t max_tables, int64_t table_size_limit)
+      : table_store_(table_store),
+        relation_info_manager_(relation_info_manager),
+        max_tables_(max_tables),
+        table_size_limit_(table_size_limit) {}
+
+  /**
+   * Starts serving on the given port. The service doesn't authenticate its callers, so it is only
+   * started when a port is configured.
+   */
+  Status Start(int port);
+  void Stop();
+
+  ::grpc::Status PushEvents(::grpc::ServerContext*, const userevents::PushEventsRequest* req,
+                            userevents::PushEventsResponse*) override;
+
+  /**
+   * Appends the rows of the request to its table, creating the table on the first push.
+   * Either all of the rows are appended, or none of them are.
+   *
+   * @param req: the pushed events.
+   * @param now: the time used for the time_ column, if the request doesn't have one.
+   */
+  Status Push(const userevents::PushEventsRequest& req, types::Time64NSValue now);
+
+ private:
+  struct UserTable {
+    table_store::schema::Relation relation;
+    std::shared_ptr<table_store::Table> table;
+    // Whether the time_ column was added by the PEM, rather than pushed by the application.
+    bool adds_time_col;
+  };
+
+  StatusOr<UserTable*> GetOrCreateTable(const userevents::PushEventsRequest& req);
+
+  table_store::TableStore* table_store_;
+  RelationInfoManager* relation_info_manager_;
+  const size_t max_tables_;
+  const int64_t table_size_limit_;
+
+  std::unique_ptr<::grpc::Server> server_;
+
+  std::mutex mu_;
+  // Mapping from table names to the tables created by pushes.
+  absl::flat_hash_map<std::string, UserTable> tables_;
+};
+
+}  // namespace agent
+}  // namespace vizier
+}  // namespace px
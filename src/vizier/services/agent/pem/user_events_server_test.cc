@@ -0,0 +1,159 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#include <google/protobuf/text_format.h>
+#include <gtest/gtest.h>
+#include <vector>
+
+#include "src/common/testing/testing.h"
+#include "src/vizier/services/agent/pem/user_events_server.h"
+
+namespace px {
+namespace vizier {
+namespace agent {
+
+using ::google::protobuf::TextFormat;
+using table_store::schema::Relation;
+
+constexpr char kCheckoutEvents[] = R"(
+  table_name: "user_checkout"
+  columns { name: "trace_id" type: STRING }
+  columns { name: "amount" type: FLOAT64 }
+  rows {
+    values { string_value: "abc" }
+    values { float64_value: 12.5 }
+  }
+  rows {
+    values { string_value: "def" }
+    values { float64_value: 3 }
+  }
+)";
+
+constexpr char kLoginEvents[] = R"(
+  table_name: "user_logins"
+  columns { name: "user" type: STRING }
+  columns { name: "time_" type: TIME64NS }
+  rows {
+    values { string_value: "alice" }
+    values { time64ns_value: 42 }
+  }
+)";
+
+class UserEventsServerTest : public ::testing::Test {
+ protected:
+  void SetUp() override {
+    table_store_ = std::make_shared<table_store::TableStore>();
+    relation_info_manager_ = std::make_unique<RelationInfoManager>();
+    server_ = std::make_unique<UserEventsServer>(table_store_.get(), relation_info_manager_.get(),
+                                                 /*max_tables*/ 2,
+                                                 /*table_size_limit*/ 1024 * 1024);
+  }
+
+  userevents::PushEventsRequest ParseRequest(const char* text) {
+    userevents::PushEventsRequest req;
+    CHECK(TextFormat::ParseFromString(text, &req));
+    return req;
+  }
+
+  std::shared_ptr<table_store::TableStore> table_store_;
+  std::unique_ptr<RelationInfoManager> relation_info_manager_;
+  std::unique_ptr<UserEventsServer> server_;
+};
+
+TEST_F(UserEventsServerTest, creates_table_and_appends_rows) {
+  ASSERT_OK(server_->Push(ParseRequest(kCheckoutEvents), 100));
+  ASSERT_OK(server_->Push(ParseRequest(kCheckoutEvents), 200));
+
+  EXPECT_TRUE(relation_info_manager_->HasRelation("user_checkout"));
+  auto table = table_store_->GetTable("user_checkout");
+  ASSERT_NE(table, nullptr);
+  EXPECT_EQ(table->GetRelation(), Relation({types::TIME64NS, types::STRING, types::FLOAT64},
+                                           {"time_", "trace_id", "amount"}));
+  EXPECT_EQ(table_store_->GetTable(kUserTableIDStart), table);
+
+  table_store::Table::Cursor cursor(table);
+  auto rb = cursor.GetNextRowBatch({0, 1, 2}).ConsumeValueOrDie();
+  ASSERT_EQ(rb->num_rows(), 2);
+  std::vector<types::Time64NSValue> times = {100, 100};
+  std::vector<types::StringValue> trace_ids = {"abc", "def"};
+  std::vector<types::Float64Value> amounts = {12.5, 3};
+  EXPECT_TRUE(rb->ColumnAt(0)->Equals(types::ToArrow(times, arrow::default_memory_pool())));
+  EXPECT_TRUE(rb->ColumnAt(1)->Equals(types::ToArrow(trace_ids, arrow::default_memory_pool())));
+  EXPECT_TRUE(rb->ColumnAt(2)->Equals(types::ToArrow(amounts, arrow::default_memory_pool())));
+}
+
+TEST_F(UserEventsServerTest, keeps_pushed_time_column) {
+  ASSERT_OK(server_->Push(ParseRequest(kLoginEvents), 100));
+
+  auto table = table_store_->GetTable("user_logins");
+  ASSERT_NE(table, nullptr);
+  EXPECT_EQ(table->GetRelation(), Relation({types::STRING, types::TIME64NS}, {"user", "time_"}));
+}
+
+TEST_F(UserEventsServerTest, rejects_changed_columns) {
+  ASSERT_OK(server_->Push(ParseRequest(kCheckoutEvents), 100));
+
+  auto req = ParseRequest(kCheckoutEvents);
+  req.mutable_columns(1)->set_type(types::INT64);
+  EXPECT_NOT_OK(server_->Push(req, 100));
+}
+
+TEST_F(UserEventsServerTest, rejects_invalid_requests) {
+  auto req = ParseRequest(kCheckoutEvents);
+  req.set_table_name("checkout");
+  EXPECT_NOT_OK(server_->Push(req, 100));
+
+  req = ParseRequest(kCheckoutEvents);
+  req.set_table_name("user_check-out");
+  EXPECT_NOT_OK(server_->Push(req, 100));
+
+  req = ParseRequest(kCheckoutEvents);
+  req.mutable_columns(1)->set_name("trace_id");
+  EXPECT_NOT_OK(server_->Push(req, 100));
+
+  req = ParseRequest(kCheckoutEvents);
+  req.mutable_columns(1)->set_type(types::UINT128);
+  EXPECT_NOT_OK(server_->Push(req, 100));
+
+  // None of the rows are appended if one of them is invalid.
+  req = ParseRequest(kCheckoutEvents);
+  req.mutable_rows(1)->mutable_values(1)->set_string_value("3");
+  EXPECT_NOT_OK(server_->Push(req, 100));
+  req.mutable_rows(1)->mutable_values()->RemoveLast();
+  EXPECT_NOT_OK(server_->Push(req, 100));
+  auto table = table_store_->GetTable("user_checkout");
+  ASSERT_NE(table, nullptr);
+  EXPECT_EQ(table->GetTableStats().batches_added, 0);
+}
+
+TEST_F(UserEventsServerTest, limits_number_of_tables) {
+  auto req = ParseRequest(kCheckoutEvents);
+  for (const auto& name : {"user_a", "user_b"}) {
+    req.set_table_name(name);
+    ASSERT_OK(server_->Push(req, 100));
+  }
+  req.set_table_name("user_c");
+  auto s = server_->Push(req, 100);
+  ASSERT_NOT_OK(s);
+  EXPECT_EQ(s.code(), statuspb::RESOURCE_UNAVAILABLE);
+  EXPECT_FALSE(relation_info_manager_->HasRelation("user_c"));
+}
+
+}  // namespace agent
+}  // namespace vizier
+}  // namespace px
@@ -97,6 +97,7 @@ Status ExecuteQueryMessageHandler::HandleMessage(std::unique_ptr<messages::Vizie
   LOG(INFO) << "Queries in flight: " << running_queries_.size();
   num_queries_in_flight_.Set(running_queries_.size());
   running_queries_[query_id] = std::move(runnable);
+  num_running_queries_ = running_queries_.size();
   runnable_ptr->Run();
 
   return Status::OK();
@@ -109,6 +110,7 @@ void ExecuteQueryMessageHandler::HandleQueryExecutionComplete(sole::uuid query_i
     LOG(ERROR) << "Attempting to delete non-existent query: " << query_id.str();
     return;
   }
+  num_running_queries_ = running_queries_.size();
   dispatcher()->DeferredDelete(std::move(node.mapped()));
 }
 
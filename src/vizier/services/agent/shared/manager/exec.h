@@ -18,6 +18,7 @@
 
 #pragma once
 
+#include <atomic>
 #include <memory>
 
 #include <absl/container/flat_hash_map.h>
@@ -45,6 +46,12 @@ class ExecuteQueryMessageHandler : public Manager::MessageHandler {
 
   Status HandleMessage(std::unique_ptr<messages::VizierMessage> msg) override;
 
+  /**
+   * NumQueriesInFlight returns the number of queries that haven't completed yet. It is safe to
+   * call from any thread.
+   */
+  size_t NumQueriesInFlight() const { return num_running_queries_; }
+
  protected:
   /**
    * HandleQueryExecutionComplete can be called by the async task to signal that work has been
@@ -59,6 +66,8 @@ class ExecuteQueryMessageHandler : public Manager::MessageHandler {
   carnot::Carnot* carnot_;
   // Map from query_id -> Running query task.
   absl::flat_hash_map<sole::uuid, px::event::RunnableAsyncTaskUPtr> running_queries_;
+  // The size of running_queries_, which can be read outside of the event loop thread.
+  std::atomic<size_t> num_running_queries_ = 0;
 
   prometheus::Gauge& num_queries_in_flight_;
 };
@@ -68,6 +68,10 @@ DEFINE_string(vizier_name, gflags::StringFromEnv("PL_VIZIER_NAME", ""),
 DEFINE_string(vizier_namespace, gflags::StringFromEnv("PL_POD_NAMESPACE", ""),
               "The namespace in which vizier is deployed.");
 
+DEFINE_int64(lameduck_period_ms, gflags::Int64FromEnv("PL_LAMEDUCK_PERIOD_MS", 1000),
+             "How long the agent keeps accepting queries after it announced that it is shutting "
+             "down, so that queries planned before the announcement still reach it.");
+
 namespace px {
 namespace vizier {
 namespace agent {
@@ -193,6 +197,12 @@ Status Manager::Stop(std::chrono::milliseconds timeout) {
   }
   stop_called_ = true;
 
+  // Only a registered agent can be part of a query, so there is nothing to drain otherwise.
+  if (info_.asid != 0) {
+    ECHECK_OK(AnnounceLameduck());
+    DrainQueries(timeout);
+  }
+
   dispatcher_->Stop();
   auto s = StopImpl(timeout);
 
@@ -205,6 +215,39 @@ Status Manager::Stop(std::chrono::milliseconds timeout) {
   return s;
 }
 
+Status Manager::AnnounceLameduck() {
+  LOG(INFO) << "Announcing lameduck to the metadata service";
+  messages::VizierMessage msg;
+  ToProto(info_.agent_id, msg.mutable_agent_lameduck()->mutable_agent_id());
+  return agent_nats_connector_->Publish(msg);
+}
+
+void Manager::DrainQueries(std::chrono::milliseconds timeout) {
+  auto it = message_handlers_.find(messages::VizierMessage::MsgCase::kExecuteQueryRequest);
+  if (it == message_handlers_.end()) {
+    return;
+  }
+  auto exec_handler = std::dynamic_pointer_cast<ExecuteQueryMessageHandler>(it->second);
+  if (exec_handler == nullptr) {
+    return;
+  }
+
+  // The event loop keeps running while draining, so that queries which were planned before the
+  // lameduck announcement reached the query broker can still start and stream their results.
+  auto now = time_system_->MonotonicTime();
+  auto lameduck_expiration = now + std::chrono::milliseconds{FLAGS_lameduck_period_ms};
+  auto expiration_time = now + timeout;
+  while (time_system_->MonotonicTime() < expiration_time &&
+         (time_system_->MonotonicTime() < lameduck_expiration ||
+          exec_handler->NumQueriesInFlight() > 0)) {
+    std::this_thread::sleep_for(std::chrono::milliseconds{100});
+  }
+
+  size_t remaining = exec_handler->NumQueriesInFlight();
+  LOG_IF(WARNING, remaining > 0) << absl::Substitute(
+      "Timed out draining queries, $0 queries are still in flight", remaining);
+}
+
 Status Manager::RegisterBackgroundHelpers() {
   metadata_update_timer_ = dispatcher_->CreateTimer([this]() {
     VLOG(1) << "State Update";
@@ -155,6 +155,11 @@ class Manager : public BaseManager {
   Status PostRegisterHook(uint32_t asid);
   Status ReregisterHook();
   Status PostReregisterHook(uint32_t asid);
+  // Tells the metadata service that this agent is shutting down, so that it is left out of new
+  // queries.
+  Status AnnounceLameduck();
+  // Waits for the running queries to complete, for at most the given timeout.
+  void DrainQueries(std::chrono::milliseconds timeout);
 
   static constexpr char kAgentSubTopicPattern[] = "Agent/$0";
   static constexpr char kAgentPubTopic[] = "UpdateAgent";
@@ -0,0 +1,193 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package bridge
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/shared/cvmsgspb"
+	"px.dev/pixie/src/shared/services/selftest"
+	"px.dev/pixie/src/shared/services/utils"
+)
+
+// selfTestQueryGracePeriod is how much longer than the timeout of its checks the self-test of a component may take.
+const selfTestQueryGracePeriod = 5 * time.Second
+
+func init() {
+	pflag.StringToString("selftest_endpoints", map[string]string{
+		"query-broker": "vizier-query-broker-svc:50300",
+		"metadata":     "vizier-metadata-svc:50400",
+	}, "The components that run a self-test of their dependencies, and the service:port they are served at")
+}
+
+// selfTestEndpoints returns the base URLs of the self-tests of the components, in the order of their names.
+func selfTestEndpoints() ([]string, []string) {
+	endpoints := viper.GetStringMapString("selftest_endpoints")
+	names := make([]string, 0, len(endpoints))
+	for name := range endpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	scheme := "https"
+	if viper.GetBool("disable_ssl") {
+		scheme = "http"
+	}
+	urls := make([]string, len(names))
+	for i, name := range names {
+		addr := endpoints[name]
+		// The service certs are only valid for the fully qualified names of the services.
+		if host, port, err := net.SplitHostPort(addr); err == nil && !strings.Contains(host, ".") {
+			addr = net.JoinHostPort(fmt.Sprintf("%s.%s.svc", host, viper.GetString("pod_namespace")), port)
+		}
+		urls[i] = fmt.Sprintf("%s://%s", scheme, addr)
+	}
+	return names, urls
+}
+
+func (s *Bridge) selfTestTargets() []selftest.Target {
+	cloudAddr, tlsOverrides, err := getCloudAddr(s.vzOperator)
+	if err != nil {
+		log.WithError(err).Error("Failed to get the cloud's TLS config, checking it with the defaults")
+		cloudAddr = viper.GetString("cloud_addr")
+	}
+	// Dev clouds do not have certs that can be verified, like in NewVZConnClient.
+	cloudTLSConfig := &tls.Config{InsecureSkipVerify: strings.Contains(cloudAddr, ".svc.cluster.local")}
+	if err := tlsOverrides.Apply(cloudTLSConfig); err != nil {
+		log.WithError(err).Error("Failed to apply the cloud's TLS config, checking it with the defaults")
+	}
+	cloud := selftest.Target{
+		Name:      "cloud",
+		Address:   cloudAddr,
+		TLS:       true,
+		TLSConfig: cloudTLSConfig,
+	}
+
+	targets := []selftest.Target{
+		cloud,
+		selftest.URLTarget("nats", viper.GetString("nats_url"), "4222"),
+	}
+	if t, ok := selftest.APIServerTarget(); ok {
+		targets = append(targets, t)
+	}
+	return targets
+}
+
+// runSelfTest checks the dependencies of the cloud connector, and runs the self-tests of the other components
+// concurrently.
+func (s *Bridge) runSelfTest(ctx context.Context, req *vizierpb.SelfTestRequest) []*vizierpb.SelfTestResponse {
+	timeout := time.Duration(req.TimeoutNs)
+	if timeout <= 0 {
+		timeout = selftest.DefaultTimeout
+	}
+
+	names, urls := selfTestEndpoints()
+	resps := make([]*vizierpb.SelfTestResponse, len(names)+1)
+
+	var token string
+	checker, err := selftest.DefaultChecker()
+	if err == nil {
+		token, err = utils.SignJWTClaims(utils.GenerateJWTForService("cloud_conn", "vizier"), s.jwtSigningKey)
+	}
+	if err != nil {
+		resps[0] = &vizierpb.SelfTestResponse{Component: "cloud-connector", Error: err.Error()}
+		for i, name := range names {
+			resps[i+1] = &vizierpb.SelfTestResponse{Component: name, Error: err.Error()}
+		}
+		return resps
+	}
+	checker.Timeout = timeout
+
+	client := &http.Client{
+		Timeout:   timeout + selfTestQueryGracePeriod,
+		Transport: &http.Transport{TLSClientConfig: checker.TLSConfig},
+	}
+
+	var wg sync.WaitGroup
+	for i := range names {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := selftest.Query(ctx, client, urls[i], token, req.ExtraTargets, timeout)
+			if err != nil {
+				resp = &vizierpb.SelfTestResponse{
+					Component: names[i],
+					Error:     err.Error(),
+				}
+			}
+			resps[i+1] = resp
+		}(i)
+	}
+
+	targets := s.selfTestTargets()
+	for _, t := range req.ExtraTargets {
+		targets = append(targets, selftest.ParseTarget(t))
+	}
+	resps[0] = &vizierpb.SelfTestResponse{
+		Component: "cloud-connector",
+		PodName:   selftest.PodName(),
+		Checks:    checker.Run(ctx, targets),
+	}
+	wg.Wait()
+	return resps
+}
+
+func (s *Bridge) handleSelfTestRequest(reqID string, req *vizierpb.SelfTestRequest) error {
+	if req == nil {
+		err := status.Errorf(codes.Internal, "SelfTestRequest is unexpectedly nil")
+		s.sendPTStatusMessage(reqID, codes.Internal, err.Error())
+		return err
+	}
+
+	var resps []*cvmsgspb.V2CAPIStreamResponse
+	for _, resp := range s.runSelfTest(context.Background(), req) {
+		resps = append(resps, &cvmsgspb.V2CAPIStreamResponse{
+			RequestID: reqID,
+			Msg: &cvmsgspb.V2CAPIStreamResponse_SelfTestResp{
+				SelfTestResp: resp,
+			},
+		})
+	}
+	return s.sendDebugStreamResponse(reqID, resps)
+}
+
+// SelfTest is the GRPC stream method to check the connectivity of the Vizier components to their dependencies.
+func (s *Bridge) SelfTest(req *vizierpb.SelfTestRequest, srv vizierpb.VizierDebugService_SelfTestServer) error {
+	for _, resp := range s.runSelfTest(srv.Context(), req) {
+		if err := srv.Send(resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
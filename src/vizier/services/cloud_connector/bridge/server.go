@@ -47,6 +47,7 @@ import (
 	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
 	"px.dev/pixie/src/shared/cvmsgs"
 	"px.dev/pixie/src/shared/cvmsgspb"
+	"px.dev/pixie/src/shared/services/msgbus"
 	vzstatus "px.dev/pixie/src/shared/status"
 	"px.dev/pixie/src/utils"
 	"px.dev/pixie/src/vizier/messages/messagespb"
@@ -341,9 +342,7 @@ func (s *Bridge) RunStream() {
 
 		connectNats := func() error {
 			log.Info("Connecting to NATS...")
-			nc, err = nats.Connect(viper.GetString("nats_url"),
-				nats.ClientCert(viper.GetString("client_tls_cert"), viper.GetString("client_tls_key")),
-				nats.RootCAs(viper.GetString("tls_ca_cert")))
+			nc, err = nats.Connect(viper.GetString("nats_url"), msgbus.ClientOptions()...)
 			return err
 		}
 
@@ -971,6 +970,15 @@ func (s *Bridge) HandleNATSBridging(stream vzconnpb.VZConnService_NATSBridgeClie
 						log.WithError(err).Error("Could not handle debug pods request")
 					}
 					continue
+				case *cvmsgspb.C2VAPIStreamRequest_SelfTestReq:
+					// The self-test waits on the checks of every component, so it must not block the bridge.
+					go func(reqID string, req *vizierpb.SelfTestRequest) {
+						err := s.handleSelfTestRequest(reqID, req)
+						if err != nil {
+							log.WithError(err).Error("Could not handle self-test request")
+						}
+					}(pb.RequestID, pb.GetSelfTestReq())
+					continue
 				default:
 				}
 			}
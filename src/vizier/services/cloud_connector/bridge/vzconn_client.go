@@ -21,6 +21,7 @@ package bridge
 import (
 	"context"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
@@ -34,12 +35,14 @@ import (
 
 func init() {
 	pflag.String("cloud_addr", "vzconn-service.plc.svc:51600", "The Pixie Cloud service url (load balancer/list is ok)")
+	pflag.String("cloud_tls_server_name", "", "If set, the name that the cloud's certificate is verified against. Allows cloud_addr to be an IP")
+	pflag.String("cloud_tls_ca_bundle", "", "If set, the path to a PEM bundle of the CAs that the cloud's certificate is verified against")
 }
 
-func getCloudAddrFromCRD(vzOperator VizierOperatorInfo) (string, error) {
+func getCloudAddrFromCRD(vzOperator VizierOperatorInfo) (string, *services.ServerTLSOverrides, error) {
 	vz, err := vzOperator.GetVizierCRD()
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
 	// When cloudConn connects to dev cloud, it should communicate directly with VZConn.
@@ -49,24 +52,43 @@ func getCloudAddrFromCRD(vzOperator VizierOperatorInfo) (string, error) {
 		cloudAddr = fmt.Sprintf("vzconn-service.%s.svc.cluster.local:51600", devCloudNamespace)
 	}
 
-	return cloudAddr, nil
+	var overrides *services.ServerTLSOverrides
+	if t := vz.Spec.CloudTLS; t != nil {
+		overrides = &services.ServerTLSOverrides{ServerName: t.ServerName, CABundle: []byte(t.CABundle)}
+	}
+	return cloudAddr, overrides, nil
+}
+
+// getCloudAddr gets the cloud address and how its certificate is verified - first from the CRD, if it exists.
+// If that fails, they are pulled from the environment for Viziers that are not running the operator yet.
+func getCloudAddr(vzOperator VizierOperatorInfo) (string, *services.ServerTLSOverrides, error) {
+	cloudAddr, overrides, err := getCloudAddrFromCRD(vzOperator)
+	if err == nil {
+		return cloudAddr, overrides, nil
+	}
+
+	overrides = &services.ServerTLSOverrides{ServerName: viper.GetString("cloud_tls_server_name")}
+	if path := viper.GetString("cloud_tls_ca_bundle"); path != "" {
+		overrides.CABundle, err = os.ReadFile(path)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read the cloud CA bundle: %w", err)
+		}
+	}
+	return viper.GetString("cloud_addr"), overrides, nil
 }
 
 // NewVZConnClient creates a new vzconn RPC client stub.
 func NewVZConnClient(vzOperator VizierOperatorInfo) (vzconnpb.VZConnServiceClient, error) {
 	ctxBg := context.Background()
 
-	// Get the cloud address - first try the CRD, if it exists.
-	// If that fails, pull it from the environment for Viziers that are not
-	// running the operator yet.
-	cloudAddr, err := getCloudAddrFromCRD(vzOperator)
+	cloudAddr, tlsOverrides, err := getCloudAddr(vzOperator)
 	if err != nil {
-		cloudAddr = viper.GetString("cloud_addr")
+		return nil, err
 	}
 
 	isInternal := strings.Contains(cloudAddr, ".svc.cluster.local")
 
-	dialOpts, err := services.GetGRPCClientDialOptsServerSideTLS(isInternal)
+	dialOpts, err := services.GetGRPCClientDialOptsServerSideTLSWithOverrides(isInternal, tlsOverrides)
 	if err != nil {
 		return nil, err
 	}
@@ -541,9 +541,9 @@ func (v *K8sVizierInfo) LaunchJob(j *batchv1.Job) (*batchv1.Job, error) {
 // CreateSecret creates the K8s secret.
 func (v *K8sVizierInfo) CreateSecret(name string, literals map[string]string) error {
 	// Attempt to delete the secret first, if it already exists.
-	err := v.clientset.CoreV1().Secrets(v.ns).Delete(context.Background(), name, metav1.DeleteOptions{})
-	if err != nil {
-		log.WithError(err).Info("Failed to delete secret, possibly because it may not exist")
+	err := k8s.DeleteSecret(context.Background(), v.clientset, v.ns, name, nil)
+	if err != nil && !errors.Is(err, k8s.ErrNotFound) {
+		log.WithError(err).Info("Failed to delete secret")
 	}
 
 	secret := &corev1.Secret{}
@@ -20,35 +20,25 @@ package main
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
-	"github.com/gofrs/uuid"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
-	"google.golang.org/grpc"
-	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 
-	"px.dev/pixie/src/api/proto/vizierpb"
 	"px.dev/pixie/src/shared/services"
 	"px.dev/pixie/src/shared/services/election"
 	"px.dev/pixie/src/shared/services/env"
 	"px.dev/pixie/src/shared/services/healthz"
 	"px.dev/pixie/src/shared/services/httpmiddleware"
 	"px.dev/pixie/src/shared/services/server"
-	"px.dev/pixie/src/shared/services/statusz"
-	"px.dev/pixie/src/shared/status"
-	controllers "px.dev/pixie/src/vizier/services/cloud_connector/bridge"
-	"px.dev/pixie/src/vizier/services/cloud_connector/vizhealth"
-	"px.dev/pixie/src/vizier/services/cloud_connector/vzmetrics"
+	"px.dev/pixie/src/vizier/services/cloud_connector/cloudconnectorserver"
 )
 
 func init() {
 	pflag.String("cluster_id", "", "The Cluster ID to use for Pixie Cloud")
-	pflag.String("nats_url", "pl-nats", "The URL of NATS")
 	pflag.Duration("max_expected_clock_skew", 2000, "Duration in ms of expected maximum clock skew in a cluster")
 	pflag.Duration("renew_period", 5000, "Duration in ms of the time to wait to renew lease")
 	pflag.String("pod_namespace", "pl", "The namespace this pod runs in.")
@@ -60,38 +50,6 @@ func init() {
 	pflag.Bool("disable_auto_update", false, "Whether auto-update should be disabled")
 	pflag.Duration("metrics_scrape_period", 15*time.Minute, "Period that the metrics scraper should run at.")
 }
-func newVzServiceClient() (vizierpb.VizierServiceClient, error) {
-	dialOpts, err := services.GetGRPCClientDialOpts()
-	if err != nil {
-		return nil, err
-	}
-
-	qbAddr := fmt.Sprintf("%s.%s.svc:%s", viper.GetString("qb_service"), viper.GetString("pod_namespace"), viper.GetString("qb_port"))
-
-	qbChannel, err := grpc.Dial(qbAddr, dialOpts...)
-	if err != nil {
-		return nil, err
-	}
-
-	return vizierpb.NewVizierServiceClient(qbChannel), nil
-}
-
-// Checks to see if the cloud connector has successfully assigned a cluster ID.
-type readinessCheck struct {
-	bridge *controllers.Bridge
-}
-
-func (r *readinessCheck) Name() string {
-	return "cluster-id"
-}
-
-func (r *readinessCheck) Check() error {
-	s := r.bridge.GetStatus()
-	if s == "" {
-		return nil
-	}
-	return errors.New(string(s))
-}
 
 func main() {
 	services.SetupService("cloud-connector", 50800)
@@ -105,27 +63,6 @@ func main() {
 		viper.GetString("pod_namespace"))
 	defer flush()
 
-	clusterID := viper.GetString("cluster_id")
-	vizierID := uuid.FromStringOrNil(clusterID)
-
-	assignedClusterName := viper.GetString("vizier_name")
-
-	deployKey := viper.GetString("deploy_key")
-
-	vzInfo, err := controllers.NewK8sVizierInfo(viper.GetString("cluster_name"), viper.GetString("pod_namespace"))
-	if err != nil {
-		log.WithError(err).Fatal("Could not get k8s info")
-	}
-
-	// Clean up cert-provisioner-job, if exists.
-	certJob, err := vzInfo.GetJob("cert-provisioner-job")
-	if err == nil && certJob != nil {
-		err = vzInfo.DeleteJob("cert-provisioner-job")
-		if err != nil && !k8sErrors.IsNotFound(err) {
-			log.WithError(err).Info("Error deleting cert-provisioner-job")
-		}
-	}
-
 	leaderMgr, err := election.NewK8sLeaderElectionMgr(
 		viper.GetString("pod_namespace"),
 		viper.GetDuration("max_expected_clock_skew"),
@@ -150,57 +87,28 @@ func main() {
 	// Resign leadership after the server stops.
 	defer resign()
 
-	qbVzClient, err := newVzServiceClient()
+	qbAddr := fmt.Sprintf("%s.%s.svc:%s", viper.GetString("qb_service"), viper.GetString("pod_namespace"), viper.GetString("qb_port"))
+	qbVzClient, err := cloudconnectorserver.NewQueryBrokerClient(qbAddr)
 	if err != nil {
 		log.WithError(err).Fatal("Failed to init qb stub")
 	}
-	checker := vizhealth.NewChecker(viper.GetString("jwt_signing_key"), qbVzClient)
-	defer checker.Stop()
-
-	// Periodically clean up any completed jobs.
-	quitCh := make(chan bool)
-	go vzInfo.CleanupCronJob("etcd-defrag-job", 2*time.Hour, quitCh)
-	defer close(quitCh)
 
-	scraper := vzmetrics.NewScraper(viper.GetString("pod_namespace"), viper.GetDuration("metrics_scrape_period"))
-	go scraper.Run()
-	defer scraper.Stop()
-
-	// We just use the current time in nanoseconds to mark the session ID. This will let the cloud side know that
-	// the cloud connector restarted. Clock skew might make this incorrect, but we mostly want this for debugging.
-	sessionID := time.Now().UnixNano()
-	svr := controllers.New(vizierID, assignedClusterName, viper.GetString("jwt_signing_key"), deployKey, sessionID, nil, vzInfo, vzInfo, nil, checker, scraper.MetricsChannel())
-	go svr.RunStream()
-	defer svr.Stop()
+	svr, err := cloudconnectorserver.New(qbVzClient, nil)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to start cloud connector")
+	}
+	defer svr.Close()
 
 	mux := http.NewServeMux()
 	// Set up healthz endpoint.
 	healthz.RegisterDefaultChecks(mux)
-	// Set up readyz endpoint.
-	healthz.InstallPathHandler(mux, "/readyz", &readinessCheck{svr})
-
-	statusz.InstallPathHandler(mux, "/statusz", func() string {
-		// Check state of the bridge.
-		bridgeStatus := svr.GetStatus()
-		if bridgeStatus != "" {
-			return string(bridgeStatus)
-		}
-
-		// If bridge is functioning, check whether queries can be executed.
-		_, err := checker.GetStatus()
-		if err != nil {
-			log.WithError(err).Info("health check returned unhealthy")
-			return string(status.CloudConnectorBasicQueryFailed)
-		}
-
-		return ""
-	})
+	svr.InstallHandlers(mux)
 
 	e := env.New("vizier")
 	s := server.NewPLServer(e,
 		httpmiddleware.WithBearerAuthMiddleware(e, mux))
 
-	vizierpb.RegisterVizierDebugServiceServer(s.GRPCServer(), svr)
+	svr.RegisterGRPC(s.GRPCServer())
 
 	s.Start()
 	s.StopOnInterrupt()
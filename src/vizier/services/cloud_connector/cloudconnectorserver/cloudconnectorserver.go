@@ -0,0 +1,167 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package cloudconnectorserver runs the components of the cloud connector, so that they can be
+// started either by the cloud connector service or by the consolidated control plane.
+package cloudconnectorserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/nats-io/nats.go"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/shared/services"
+	"px.dev/pixie/src/shared/services/healthz"
+	"px.dev/pixie/src/shared/services/statusz"
+	"px.dev/pixie/src/shared/status"
+	"px.dev/pixie/src/vizier/services/cloud_connector/bridge"
+	"px.dev/pixie/src/vizier/services/cloud_connector/vizhealth"
+	"px.dev/pixie/src/vizier/services/cloud_connector/vzmetrics"
+)
+
+// NewQueryBrokerClient creates a VizierService client to the given query broker address.
+func NewQueryBrokerClient(qbAddr string) (vizierpb.VizierServiceClient, error) {
+	dialOpts, err := services.GetGRPCClientDialOpts()
+	if err != nil {
+		return nil, err
+	}
+
+	qbChannel, err := grpc.Dial(qbAddr, dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return vizierpb.NewVizierServiceClient(qbChannel), nil
+}
+
+// Checks to see if the cloud connector has successfully assigned a cluster ID.
+type readinessCheck struct {
+	bridge *bridge.Bridge
+}
+
+func (r *readinessCheck) Name() string {
+	return "cluster-id"
+}
+
+func (r *readinessCheck) Check() error {
+	s := r.bridge.GetStatus()
+	if s == "" {
+		return nil
+	}
+	return errors.New(string(s))
+}
+
+// Server holds the running components of the cloud connector.
+type Server struct {
+	bridge  *bridge.Bridge
+	checker *vizhealth.Checker
+	scraper vzmetrics.Scraper
+	quitCh  chan bool
+}
+
+// New starts the bridge to Pixie Cloud. Queries are run against qbVzClient. If nc is nil, the
+// bridge connects to the NATS server in nats_url.
+func New(qbVzClient vizierpb.VizierServiceClient, nc *nats.Conn) (*Server, error) {
+	clusterID := viper.GetString("cluster_id")
+	vizierID := uuid.FromStringOrNil(clusterID)
+
+	assignedClusterName := viper.GetString("vizier_name")
+
+	deployKey := viper.GetString("deploy_key")
+
+	vzInfo, err := bridge.NewK8sVizierInfo(viper.GetString("cluster_name"), viper.GetString("pod_namespace"))
+	if err != nil {
+		return nil, fmt.Errorf("could not get k8s info: %w", err)
+	}
+
+	// Clean up cert-provisioner-job, if exists.
+	certJob, err := vzInfo.GetJob("cert-provisioner-job")
+	if err == nil && certJob != nil {
+		err = vzInfo.DeleteJob("cert-provisioner-job")
+		if err != nil && !k8sErrors.IsNotFound(err) {
+			log.WithError(err).Info("Error deleting cert-provisioner-job")
+		}
+	}
+
+	checker := vizhealth.NewChecker(viper.GetString("jwt_signing_key"), qbVzClient)
+
+	// Periodically clean up any completed jobs.
+	quitCh := make(chan bool)
+	go vzInfo.CleanupCronJob("etcd-defrag-job", 2*time.Hour, quitCh)
+
+	scraper := vzmetrics.NewScraper(viper.GetString("pod_namespace"), viper.GetDuration("metrics_scrape_period"))
+	go scraper.Run()
+
+	// We just use the current time in nanoseconds to mark the session ID. This will let the cloud side know that
+	// the cloud connector restarted. Clock skew might make this incorrect, but we mostly want this for debugging.
+	sessionID := time.Now().UnixNano()
+	svr := bridge.New(vizierID, assignedClusterName, viper.GetString("jwt_signing_key"), deployKey, sessionID, nil, vzInfo, vzInfo, nc, checker, scraper.MetricsChannel())
+	go svr.RunStream()
+
+	return &Server{
+		bridge:  svr,
+		checker: checker,
+		scraper: scraper,
+		quitCh:  quitCh,
+	}, nil
+}
+
+// InstallHandlers installs the readyz and statusz endpoints of the cloud connector on the mux.
+func (s *Server) InstallHandlers(mux *http.ServeMux) {
+	// Set up readyz endpoint.
+	healthz.InstallPathHandler(mux, "/readyz", &readinessCheck{s.bridge})
+
+	statusz.InstallPathHandler(mux, "/statusz", func() string {
+		// Check state of the bridge.
+		bridgeStatus := s.bridge.GetStatus()
+		if bridgeStatus != "" {
+			return string(bridgeStatus)
+		}
+
+		// If bridge is functioning, check whether queries can be executed.
+		_, err := s.checker.GetStatus()
+		if err != nil {
+			log.WithError(err).Info("health check returned unhealthy")
+			return string(status.CloudConnectorBasicQueryFailed)
+		}
+
+		return ""
+	})
+}
+
+// RegisterGRPC registers the cloud connector GRPC services on the server.
+func (s *Server) RegisterGRPC(g *grpc.Server) {
+	vizierpb.RegisterVizierDebugServiceServer(g, s.bridge)
+}
+
+// Close stops the cloud connector components.
+func (s *Server) Close() {
+	s.bridge.Stop()
+	s.scraper.Stop()
+	close(s.quitCh)
+	s.checker.Stop()
+}
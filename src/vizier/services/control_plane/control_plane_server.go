@@ -0,0 +1,207 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// The control plane runs the metadata service, the query broker and the cloud connector in a
+// single process for small and edge clusters. NATS is embedded in the process, so the control
+// plane components talk over in-process connections while agents keep connecting to pl-nats,
+// and metadata is kept in pebble instead of etcd.
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	natsserver "github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	v1 "k8s.io/api/core/v1"
+
+	version "px.dev/pixie/src/shared/goversion"
+	"px.dev/pixie/src/shared/services"
+	"px.dev/pixie/src/shared/services/election"
+	"px.dev/pixie/src/shared/services/healthz"
+	"px.dev/pixie/src/shared/services/httpmiddleware"
+	"px.dev/pixie/src/shared/services/metrics"
+	"px.dev/pixie/src/shared/services/msgbus"
+	"px.dev/pixie/src/shared/services/server"
+	"px.dev/pixie/src/shared/status"
+	"px.dev/pixie/src/vizier/services/cloud_connector/cloudconnectorserver"
+	"px.dev/pixie/src/vizier/services/metadata/metadataenv"
+	"px.dev/pixie/src/vizier/services/metadata/metadataserver"
+	"px.dev/pixie/src/vizier/services/query_broker/querybrokerenv"
+	"px.dev/pixie/src/vizier/services/query_broker/querybrokerserver"
+	scriptrunner "px.dev/pixie/src/vizier/services/query_broker/script_runner"
+)
+
+const (
+	querybrokerHostname = "vizier-query-broker-svc"
+)
+
+func init() {
+	pflag.String("cluster_id", "", "The Cluster ID to use for Pixie Cloud")
+	pflag.Duration("max_expected_clock_skew", 2000, "Duration in ms of expected maximum clock skew in a cluster")
+	pflag.Duration("renew_period", 5000, "Duration in ms of the time to wait to renew lease")
+	pflag.String("pod_namespace", "pl", "The namespace this pod runs in.")
+	pflag.String("pod_ip_address", "", "The IP address of this pod to allow the agent to connect to this"+
+		" particular query broker instance across multiple requests")
+	pflag.Int("nats_port", 4222, "The port that the embedded NATS server listens on for agents")
+	pflag.String("nats_config", "", "The NATS server config file whose authorization the embedded NATS server enforces, if any")
+	pflag.StringSlice("metadata_namespaces", []string{v1.NamespaceAll}, "The list of namespaces to watch for metadata.")
+	pflag.StringArray("cron_script_sources", scriptrunner.DefaultSources, "Where to find cron scripts (cloud, configmaps)")
+	pflag.String("cluster_name", "", "The name of the user's K8s cluster")
+	pflag.String("vizier_name", "", "The name of the user's K8s cluster, assigned by Pixie cloud")
+	pflag.String("deploy_key", "", "The deploy key for the cluster")
+	pflag.Bool("disable_auto_update", false, "Whether auto-update should be disabled")
+	pflag.Duration("metrics_scrape_period", 15*time.Minute, "Period that the metrics scraper should run at.")
+}
+
+// mustStartMessageBus starts the NATS server that the agents connect to, and connects the control plane
+// components to it in process.
+func mustStartMessageBus(port int, configFile string) (*natsserver.Server, *nats.Conn) {
+	ns := msgbus.MustStartEmbeddedNATS(port, configFile)
+	return ns, msgbus.MustConnectInProcessNATS(ns)
+}
+
+func main() {
+	servicePort := uint(50300)
+	services.SetupService("control-plane", servicePort)
+	services.SetupSSLClientFlags()
+	services.PostFlagSetupAndParse()
+	services.CheckServiceFlags()
+	services.CheckSSLClientFlags()
+	services.SetupServiceLogging()
+
+	flush := services.InitSentryFromCRD(viper.GetString("cluster_id"),
+		viper.GetString("pod_namespace"))
+	defer flush()
+
+	podAddr := viper.GetString("pod_ip_address")
+	if podAddr == "" {
+		log.Fatal("Expected to receive pod IP address.")
+	}
+
+	// Only a single control plane may run at a time, since it owns the message bus and the
+	// metadata store. Wait for the previous instance to step down during a rollout.
+	leaderMgr, err := election.NewK8sLeaderElectionMgr(
+		viper.GetString("pod_namespace"),
+		viper.GetDuration("max_expected_clock_skew"),
+		viper.GetDuration("renew_period"),
+		"control-plane-election",
+	)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to connect to leader election manager.")
+	}
+	// Cancel callback causes leader to resign.
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	err = leaderMgr.Campaign(leaderCtx)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to become leader")
+	}
+	// Resign leadership after the server stops.
+	defer func() {
+		log.Info("Resigning leadership")
+		cancel()
+	}()
+	isLeader := true
+
+	ns, nc := mustStartMessageBus(viper.GetInt("nats_port"), viper.GetString("nats_config"))
+	defer ns.Shutdown()
+	defer nc.Close()
+
+	dataStore := metadataserver.MustInitPebbleDatastore()
+	defer dataStore.Close()
+	dsMgr := metadataserver.NewDatastoreManager(dataStore, "pebble", metadataserver.PebbleQuotaBytes())
+	dsMgr.Run()
+	defer dsMgr.Stop()
+
+	mdEnv, err := metadataenv.New("vizier")
+	if err != nil {
+		log.WithError(err).Fatal("Failed to create api environment")
+	}
+	mdSvr, err := metadataserver.New(mdEnv, nc, dataStore, &isLeader)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to start metadata service")
+	}
+	defer mdSvr.Close()
+
+	// The query broker reaches the metadata service through the GRPC server of this process. The
+	// connection is established lazily, once the server has started.
+	dialOpts, err := services.GetGRPCClientDialOpts()
+	if err != nil {
+		log.WithError(err).Fatal("Could not get dial opts.")
+	}
+	dialOpts = append(dialOpts, grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(8*1024*1024)))
+	// Note: This has to be localhost to pass the SSL cert verification.
+	mdsConn, err := grpc.Dial(fmt.Sprintf("localhost:%d", servicePort), dialOpts...)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to connect to Metadata Service.")
+	}
+	defer mdsConn.Close()
+
+	qbIPAddr := net.JoinHostPort(podAddr, fmt.Sprintf("%d", servicePort))
+	qbHostname := fmt.Sprintf("%s.%s.svc", querybrokerHostname, viper.GetString("pod_namespace"))
+	qbEnv, err := querybrokerenv.New(qbIPAddr, qbHostname, "vizier")
+	if err != nil {
+		log.WithError(err).Fatal("Failed to create api environment.")
+	}
+	qbSvr, err := querybrokerserver.New(qbEnv, mdsConn, nc)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to start query broker.")
+	}
+	defer qbSvr.Close()
+
+	qbVzClient, err := querybrokerserver.NewVizierServiceClient(servicePort)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to init qb stub")
+	}
+	ccSvr, err := cloudconnectorserver.New(qbVzClient, nc)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to start cloud connector")
+	}
+	defer ccSvr.Close()
+
+	mux := http.NewServeMux()
+	healthz.RegisterDefaultChecks(mux)
+	ccSvr.InstallHandlers(mux)
+	mux.Handle(status.DatastoreHealthPath, dsMgr)
+	metrics.MustRegisterMetricsHandlerNoDefaultMetrics(mux)
+
+	log.Infof("Control Plane Server: %s", version.GetVersion().ToString())
+
+	// Agent metadata and query results may both be larger than the default 4MB.
+	s := server.NewPLServer(qbEnv,
+		httpmiddleware.WithBearerAuthMiddleware(qbEnv, mux),
+		grpc.MaxSendMsgSize(8*1024*1024), grpc.MaxRecvMsgSize(8*1024*1024))
+	mdSvr.RegisterGRPC(s.GRPCServer())
+	qbSvr.RegisterGRPC(s.GRPCServer())
+	ccSvr.RegisterGRPC(s.GRPCServer())
+
+	err = qbSvr.Start(servicePort)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to start query broker.")
+	}
+
+	s.Start()
+	s.StopOnInterrupt()
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/phayes/freeport"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testNATSConfig = `authorization {
+  users = [
+    {
+      user: "agent"
+      password: $PL_NATS_AGENT_PASSWORD
+      permissions: {
+        publish: {
+          allow: ["UpdateAgent","_INBOX.>"]
+        }
+      }
+    }
+    {
+      user: "control-plane"
+      password: $PL_NATS_CONTROL_PLANE_PASSWORD
+    }
+  ]
+}
+`
+
+func startTestMessageBus(t *testing.T, configFile string) (string, *nats.Conn) {
+	port, err := freeport.GetFreePort()
+	require.NoError(t, err)
+	ns, nc := mustStartMessageBus(port, configFile)
+	t.Cleanup(ns.Shutdown)
+	t.Cleanup(nc.Close)
+	return fmt.Sprintf("nats://127.0.0.1:%d", port), nc
+}
+
+// expectDelivered checks that a message that an agent publishes reaches the components in the
+// control plane process.
+func expectDelivered(t *testing.T, agent *nats.Conn, nc *nats.Conn) {
+	sub, err := nc.SubscribeSync("UpdateAgent")
+	require.NoError(t, err)
+	require.NoError(t, nc.Flush())
+
+	require.NoError(t, agent.Publish("UpdateAgent", []byte("update")))
+	require.NoError(t, agent.Flush())
+
+	msg, err := sub.NextMsg(5 * time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("update"), msg.Data)
+}
+
+func TestMustStartMessageBus(t *testing.T) {
+	viper.Set("disable_ssl", true)
+
+	url, nc := startTestMessageBus(t, "")
+
+	agent, err := nats.Connect(url)
+	require.NoError(t, err)
+	defer agent.Close()
+	expectDelivered(t, agent, nc)
+}
+
+func TestMustStartMessageBus_Authorization(t *testing.T) {
+	viper.Set("disable_ssl", true)
+	viper.Set("nats_user", "control-plane")
+	viper.Set("nats_password", "cp-password")
+	defer viper.Set("nats_user", "")
+	defer viper.Set("nats_password", "")
+	t.Setenv("PL_NATS_AGENT_PASSWORD", "agent-password")
+	t.Setenv("PL_NATS_CONTROL_PLANE_PASSWORD", "cp-password")
+
+	configFile := filepath.Join(t.TempDir(), "nats.conf")
+	require.NoError(t, os.WriteFile(configFile, []byte(testNATSConfig), 0o600))
+	url, nc := startTestMessageBus(t, configFile)
+
+	_, err := nats.Connect(url)
+	assert.Error(t, err)
+
+	agent, err := nats.Connect(url, nats.UserInfo("agent", "agent-password"))
+	require.NoError(t, err)
+	defer agent.Close()
+	expectDelivered(t, agent, nc)
+}
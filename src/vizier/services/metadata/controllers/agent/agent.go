@@ -41,6 +41,9 @@ import (
 	"px.dev/pixie/src/vizier/utils/messagebus"
 )
 
+// clockOffsetSmoothing is the weight of the previous estimate of an agent's clock offset against a new sample.
+const clockOffsetSmoothing = 4
+
 var agentRegCounter *prometheus.CounterVec
 
 func init() {
@@ -97,8 +100,12 @@ type Manager interface {
 	// RegisterAgent registers a new agent.
 	RegisterAgent(info *agentpb.Agent) (uint32, error)
 
-	// UpdateHeartbeat updates the agent heartbeat with the current time.
-	UpdateHeartbeat(agentID uuid.UUID) error
+	// UpdateHeartbeat updates the agent heartbeat with the current time. agentTimeNS is the time on the agent's
+	// clock when it sent the heartbeat, which is used to estimate the offset of its clock, or 0 if it is unknown.
+	UpdateHeartbeat(agentID uuid.UUID, agentTimeNS int64) error
+
+	// MarkAgentLameduck marks the agent as shutting down, so that it is left out of new queries.
+	MarkAgentLameduck(agentID uuid.UUID) error
 
 	// Delete agent deletes the agent.
 	DeleteAgent(uuid.UUID) error
@@ -497,7 +504,7 @@ func (m *ManagerImpl) DeleteAgent(agentID uuid.UUID) error {
 }
 
 // UpdateHeartbeat updates the agent heartbeat with the current time.
-func (m *ManagerImpl) UpdateHeartbeat(agentID uuid.UUID) error {
+func (m *ManagerImpl) UpdateHeartbeat(agentID uuid.UUID, agentTimeNS int64) error {
 	// Get current AgentData.
 	agent, err := m.agtStore.GetAgent(agentID)
 	if err != nil {
@@ -509,6 +516,9 @@ func (m *ManagerImpl) UpdateHeartbeat(agentID uuid.UUID) error {
 
 	// Update LastHeartbeatNS in AgentData.
 	agent.LastHeartbeatNS = time.Now().UnixNano()
+	if agentTimeNS != 0 {
+		agent.ClockOffsetNS = smoothClockOffset(agent.ClockOffsetNS, agentTimeNS-agent.LastHeartbeatNS)
+	}
 
 	err = m.updateAgentWrapper(agentID, agent)
 	if err != nil {
@@ -518,6 +528,34 @@ func (m *ManagerImpl) UpdateHeartbeat(agentID uuid.UUID) error {
 	return nil
 }
 
+// MarkAgentLameduck marks the agent as shutting down. The agent stays registered until it stops sending heartbeats,
+// but the update tells the query broker to stop planning queries on it.
+func (m *ManagerImpl) MarkAgentLameduck(agentID uuid.UUID) error {
+	agent, err := m.agtStore.GetAgent(agentID)
+	if err != nil {
+		return err
+	}
+	if agent == nil {
+		return errors.New("Agent does not exist")
+	}
+	if agent.Lameduck {
+		return nil
+	}
+
+	agent.Lameduck = true
+	return m.updateAgentWrapper(agentID, agent)
+}
+
+// smoothClockOffset folds a new sample of an agent's clock offset into its previous estimate, so that the
+// jitter in the delivery of single heartbeats doesn't make the estimate jump around. An agent without an
+// estimate yet takes the sample as is.
+func smoothClockOffset(prevNS, sampleNS int64) int64 {
+	if prevNS == 0 {
+		return sampleNS
+	}
+	return prevNS + (sampleNS-prevNS)/clockOffsetSmoothing
+}
+
 // GetActiveAgents gets all of the current active agents.
 func (m *ManagerImpl) GetActiveAgents() ([]*agentpb.Agent, error) {
 	var agents []*agentpb.Agent
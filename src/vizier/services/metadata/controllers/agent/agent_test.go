@@ -243,7 +243,7 @@ func TestUpdateHeartbeat(t *testing.T) {
 	}
 
 	now := time.Now().UnixNano()
-	err = agtMgr.UpdateHeartbeat(u)
+	err = agtMgr.UpdateHeartbeat(u, 0)
 	require.NoError(t, err)
 
 	// Check that correct agent info is in ads.
@@ -254,6 +254,36 @@ func TestUpdateHeartbeat(t *testing.T) {
 	assert.Greater(t, agt.LastHeartbeatNS, now)
 }
 
+func TestUpdateHeartbeat_ClockOffset(t *testing.T) {
+	ads, agtMgr, _, cleanup := setupManager(t)
+	defer cleanup()
+
+	u, err := uuid.FromString(testutils.ExistingAgentUUID)
+	if err != nil {
+		t.Fatal("Could not generate UUID.")
+	}
+
+	err = agtMgr.UpdateHeartbeat(u, time.Now().Add(2*time.Second).UnixNano())
+	require.NoError(t, err)
+	agt, err := ads.GetAgent(u)
+	require.NoError(t, err)
+	assert.InDelta(t, (2 * time.Second).Nanoseconds(), agt.ClockOffsetNS, float64(100*time.Millisecond))
+
+	// A single outlier only moves the estimate part of the way.
+	err = agtMgr.UpdateHeartbeat(u, time.Now().Add(6*time.Second).UnixNano())
+	require.NoError(t, err)
+	agt, err = ads.GetAgent(u)
+	require.NoError(t, err)
+	assert.InDelta(t, (3 * time.Second).Nanoseconds(), agt.ClockOffsetNS, float64(100*time.Millisecond))
+
+	// Heartbeats without a time don't change the estimate.
+	err = agtMgr.UpdateHeartbeat(u, 0)
+	require.NoError(t, err)
+	agt, err = ads.GetAgent(u)
+	require.NoError(t, err)
+	assert.InDelta(t, (3 * time.Second).Nanoseconds(), agt.ClockOffsetNS, float64(100*time.Millisecond))
+}
+
 func TestUpdateHeartbeatForNonExistingAgent(t *testing.T) {
 	_, agtMgr, _, cleanup := setupManager(t)
 	defer cleanup()
@@ -263,7 +293,31 @@ func TestUpdateHeartbeatForNonExistingAgent(t *testing.T) {
 		t.Fatal("Could not generate UUID.")
 	}
 
-	err = agtMgr.UpdateHeartbeat(u)
+	err = agtMgr.UpdateHeartbeat(u, 0)
+	assert.NotNil(t, err)
+}
+
+func TestMarkAgentLameduck(t *testing.T) {
+	ads, agtMgr, _, cleanup := setupManager(t)
+	defer cleanup()
+
+	u, err := uuid.FromString(testutils.ExistingAgentUUID)
+	if err != nil {
+		t.Fatal("Could not generate UUID.")
+	}
+
+	err = agtMgr.MarkAgentLameduck(u)
+	require.NoError(t, err)
+
+	agt, err := ads.GetAgent(u)
+	require.NoError(t, err)
+	assert.True(t, agt.Lameduck)
+
+	// Marking the agent again is a no-op.
+	err = agtMgr.MarkAgentLameduck(u)
+	require.NoError(t, err)
+
+	err = agtMgr.MarkAgentLameduck(uuid.FromStringOrNil(testutils.NewAgentUUID))
 	assert.NotNil(t, err)
 }
 
@@ -709,7 +763,7 @@ func TestAgent_GetAgentUpdate(t *testing.T) {
 	assert.Len(t, schema.Tables, 2)
 
 	// Update the heartbeat of an agt.
-	err = agtMgr.UpdateHeartbeat(agUUID2)
+	err = agtMgr.UpdateHeartbeat(agUUID2, 0)
 	require.NoError(t, err)
 
 	// Now expire it
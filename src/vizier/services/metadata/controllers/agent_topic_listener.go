@@ -94,6 +94,10 @@ type AgentHandler struct {
 	tpMgr  *tracepoint.Manager
 	atl    *AgentTopicListener
 
+	// The sequence number of the last heartbeat. Resent heartbeats have the same sequence number, and
+	// carry the time that they were first sent at.
+	lastHeartbeatSeq int64
+
 	MsgChannel chan *nats.Msg
 	quitCh     chan struct{}
 
@@ -159,6 +163,8 @@ func (a *AgentTopicListener) HandleMessage(msg *nats.Msg) error {
 		a.forwardAgentHeartBeat(m.Heartbeat, msg)
 	case *messagespb.VizierMessage_RegisterAgentRequest:
 		a.forwardAgentRegisterRequest(m.RegisterAgentRequest, msg)
+	case *messagespb.VizierMessage_AgentLameduck:
+		a.forwardAgentLameduck(m.AgentLameduck, msg)
 	case *messagespb.VizierMessage_TracepointMessage:
 		a.onAgentTracepointMessage(m.TracepointMessage)
 	default:
@@ -193,6 +199,8 @@ func (a *AgentTopicListener) createAgentHandler(agentID uuid.UUID) *AgentHandler
 		atl:        a,
 		MsgChannel: make(chan *nats.Msg, 10),
 		quitCh:     make(chan struct{}),
+
+		lastHeartbeatSeq: -1,
 	}
 	a.agentMap.write(agentID, newAgentHandler)
 	go newAgentHandler.processMessages()
@@ -245,6 +253,23 @@ func (a *AgentTopicListener) forwardAgentRegisterRequest(m *messagespb.RegisterA
 	agentHandler.MsgChannel <- msg
 }
 
+func (a *AgentTopicListener) forwardAgentLameduck(m *messagespb.AgentLameduck, msg *nats.Msg) {
+	agentID, err := utils.UUIDFromProto(m.AgentID)
+	if err != nil {
+		log.WithError(err).Error("Could not parse UUID from proto.")
+		return
+	}
+
+	agentHandler := a.agentMap.read(agentID)
+	if agentHandler == nil {
+		// The agent is already gone, so there is nothing left to drain.
+		log.WithField("agentID", agentID.String()).Info("Received lameduck for agent whose agenthandler doesn't exist.")
+		return
+	}
+	// Add to agent handler to process.
+	agentHandler.MsgChannel <- msg
+}
+
 // StopAgent should be called when an agent should be deleted and its message processing should be stopped.
 func (a *AgentTopicListener) StopAgent(agentID uuid.UUID) {
 	agentHandler := a.agentMap.read(agentID)
@@ -344,6 +369,8 @@ func (ah *AgentHandler) processMessages() {
 				ah.onAgentHeartbeat(m.Heartbeat)
 			case *messagespb.VizierMessage_RegisterAgentRequest:
 				ah.onAgentRegisterRequest(m.RegisterAgentRequest)
+			case *messagespb.VizierMessage_AgentLameduck:
+				ah.onAgentLameduck()
 			default:
 				log.WithField("message-type", reflect.TypeOf(pb.Msg).String()).
 					Error("Unhandled message.")
@@ -438,8 +465,16 @@ func (ah *AgentHandler) onAgentRegisterRequest(m *messagespb.RegisterAgentReques
 func (ah *AgentHandler) onAgentHeartbeat(m *messagespb.Heartbeat) {
 	agentID := ah.id
 
+	// Only new heartbeats are used to estimate the offset of the agent's clock, since a resent
+	// heartbeat has the time of the original one.
+	var agentTimeNS int64
+	if m.SequenceNumber != ah.lastHeartbeatSeq {
+		agentTimeNS = m.Time
+	}
+	ah.lastHeartbeatSeq = m.SequenceNumber
+
 	// Update agent's heartbeat in agent manager.
-	err := ah.agtMgr.UpdateHeartbeat(agentID)
+	err := ah.agtMgr.UpdateHeartbeat(agentID, agentTimeNS)
 	if err != nil {
 		log.WithError(err).Error("Could not update agent heartbeat.")
 		resp := messagespb.VizierMessage{
@@ -484,6 +519,14 @@ func (ah *AgentHandler) onAgentHeartbeat(m *messagespb.Heartbeat) {
 	}
 }
 
+func (ah *AgentHandler) onAgentLameduck() {
+	log.WithField("agentID", ah.id.String()).Info("Agent is shutting down, marking it as lameduck")
+	err := ah.agtMgr.MarkAgentLameduck(ah.id)
+	if err != nil {
+		log.WithError(err).Error("Could not mark agent as lameduck.")
+	}
+}
+
 // Stop immediately stops the agent handler from listening to any messages. It blocks until
 // the agent is cleaned up.
 func (ah *AgentHandler) Stop() {
@@ -425,8 +425,8 @@ func TestAgentHeartbeat(t *testing.T) {
 
 	mockAgtMgr.
 		EXPECT().
-		UpdateHeartbeat(uuid.FromStringOrNil(testutils.UnhealthyKelvinAgentUUID)).
-		DoAndReturn(func(agentID uuid.UUID) error {
+		UpdateHeartbeat(uuid.FromStringOrNil(testutils.UnhealthyKelvinAgentUUID), int64(1)).
+		DoAndReturn(func(agentID uuid.UUID, agentTimeNS int64) error {
 			return nil
 		})
 
@@ -482,8 +482,8 @@ func TestAgentHeartbeat_Failed(t *testing.T) {
 
 	mockAgtMgr.
 		EXPECT().
-		UpdateHeartbeat(uuid.FromStringOrNil(testutils.UnhealthyKelvinAgentUUID)).
-		DoAndReturn(func(agentID uuid.UUID) error {
+		UpdateHeartbeat(uuid.FromStringOrNil(testutils.UnhealthyKelvinAgentUUID), int64(1)).
+		DoAndReturn(func(agentID uuid.UUID, agentTimeNS int64) error {
 			wg.Done()
 			return errors.New("Could not update heartbeat")
 		})
@@ -496,6 +496,40 @@ func TestAgentHeartbeat_Failed(t *testing.T) {
 	wg.Wait()
 }
 
+func TestAgentLameduck(t *testing.T) {
+	req := messagespb.VizierMessage{
+		Msg: &messagespb.VizierMessage_AgentLameduck{
+			AgentLameduck: &messagespb.AgentLameduck{
+				AgentID: utils.ProtoFromUUIDStrOrNil(testutils.UnhealthyKelvinAgentUUID),
+			},
+		},
+	}
+	reqPb, err := req.Marshal()
+	require.NoError(t, err)
+
+	// Set up mock.
+	atl, mockAgtMgr, _, cleanup := setup(t, assertSendMessageUncalled(t))
+	defer cleanup()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	mockAgtMgr.
+		EXPECT().
+		MarkAgentLameduck(uuid.FromStringOrNil(testutils.UnhealthyKelvinAgentUUID)).
+		DoAndReturn(func(agentID uuid.UUID) error {
+			wg.Done()
+			return nil
+		})
+
+	msg := nats.Msg{}
+	msg.Data = reqPb
+	err = atl.HandleMessage(&msg)
+	require.NoError(t, err)
+
+	wg.Wait()
+}
+
 func TestEmptyMessage(t *testing.T) {
 	// Set up mock.
 	atl, _, _, cleanup := setup(t, assertSendMessageUncalled(t))
@@ -0,0 +1,199 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"px.dev/pixie/src/shared/status"
+	"px.dev/pixie/src/vizier/utils/datastore"
+)
+
+const (
+	datastoreCheckInterval = 5 * time.Minute
+	// defragMinReclaimableBytes is how much space a defrag must free for it to be worth blocking writes for.
+	defragMinReclaimableBytes = 50 * 1024 * 1024
+	defragFrequency           = 1 * time.Hour // The minimum amount of times we should have between defrags.
+)
+
+// DatastoreManagerOptions configures the maintenance of the metadata datastore.
+type DatastoreManagerOptions struct {
+	// Backend is the kind of datastore, as reported in its health.
+	Backend string
+	// QuotaBytes is the size past which the datastore rejects writes. If zero, only the datastore's own alarms
+	// raise the size alarm.
+	QuotaBytes int64
+	// SizeAlarmRatio is the fraction of the quota past which the size alarm is raised.
+	SizeAlarmRatio float64
+	// CompactionInterval is how often the datastore is compacted.
+	CompactionInterval time.Duration
+}
+
+// DatastoreManager keeps the metadata datastore from filling up. It periodically compacts the datastore, defrags it
+// once enough space can be reclaimed, and raises an alarm when it nears its quota. While the alarm is raised, the
+// datastore is compacted and defragged on every check.
+type DatastoreManager struct {
+	ds   datastore.Maintainer
+	opts DatastoreManagerOptions
+
+	mu             sync.Mutex
+	health         status.DatastoreHealth
+	lastCompaction time.Time
+	lastDefrag     time.Time
+
+	quitCh chan bool
+	timer  *time.Ticker
+}
+
+// NewDatastoreManager creates a new datastore manager.
+func NewDatastoreManager(ds datastore.Maintainer, opts DatastoreManagerOptions) *DatastoreManager {
+	return &DatastoreManager{
+		ds:     ds,
+		opts:   opts,
+		health: status.DatastoreHealth{Backend: opts.Backend},
+		quitCh: make(chan bool),
+	}
+}
+
+// Run periodically maintains the datastore.
+func (m *DatastoreManager) Run() {
+	m.timer = time.NewTicker(datastoreCheckInterval)
+
+	go func() {
+		for {
+			select {
+			case _, ok := <-m.quitCh:
+				if !ok {
+					return
+				}
+			case <-m.timer.C:
+				m.Maintain()
+			}
+		}
+	}()
+}
+
+// Stop stops the datastore manager from periodically maintaining the datastore.
+func (m *DatastoreManager) Stop() {
+	m.timer.Stop()
+	close(m.quitCh)
+}
+
+// Maintain checks the size of the datastore, and compacts and defrags it if necessary.
+func (m *DatastoreManager) Maintain() {
+	usage, err := m.ds.Usage()
+	if err != nil {
+		log.WithError(err).Error("Failed to get the metadata datastore usage")
+		m.mu.Lock()
+		m.health.LastError = err.Error()
+		m.mu.Unlock()
+		return
+	}
+	log.WithField("sizeBytes", usage.SizeBytes).WithField("inUseBytes", usage.InUseBytes).Info("Metadata datastore state")
+
+	var lastErr error
+	alarm := m.sizeAlarm(usage)
+	now := time.Now()
+
+	if alarm || now.Sub(m.lastCompaction) >= m.opts.CompactionInterval {
+		log.WithField("sizeAlarm", alarm).Info("Compacting the metadata datastore")
+		if err := m.ds.Compact(); err != nil {
+			log.WithError(err).Error("Failed to compact the metadata datastore")
+			lastErr = err
+		} else {
+			m.lastCompaction = now
+			if usage, err = m.ds.Usage(); err != nil {
+				log.WithError(err).Error("Failed to get the metadata datastore usage")
+				lastErr = err
+			}
+		}
+	}
+
+	if lastErr == nil && usage.SizeBytes-usage.InUseBytes >= defragMinReclaimableBytes &&
+		(alarm || now.Sub(m.lastDefrag) >= defragFrequency) {
+		log.Info("Starting defrag")
+		if err := m.ds.Defragment(); err != nil {
+			log.WithError(err).Error("Failed to defrag")
+			lastErr = err
+		} else {
+			log.Info("Finished defragging")
+			m.lastDefrag = now
+			if usage, err = m.ds.Usage(); err != nil {
+				log.WithError(err).Error("Failed to get the metadata datastore usage")
+				lastErr = err
+			}
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.health.SizeBytes = usage.SizeBytes
+	m.health.InUseBytes = usage.InUseBytes
+	m.health.QuotaBytes = m.opts.QuotaBytes
+	m.health.Alarms = usage.Alarms
+	m.health.SizeAlarm = m.sizeAlarm(usage)
+	if !m.lastCompaction.IsZero() {
+		t := m.lastCompaction
+		m.health.LastCompactionTime = &t
+	}
+	if !m.lastDefrag.IsZero() {
+		t := m.lastDefrag
+		m.health.LastDefragTime = &t
+	}
+	m.health.LastError = ""
+	if lastErr != nil {
+		m.health.LastError = lastErr.Error()
+	}
+
+	if m.health.SizeAlarm {
+		log.WithField("sizeBytes", usage.SizeBytes).
+			WithField("quotaBytes", m.opts.QuotaBytes).
+			WithField("alarms", usage.Alarms).
+			Warn("Metadata datastore is close to its quota")
+	}
+}
+
+func (m *DatastoreManager) sizeAlarm(usage *datastore.Usage) bool {
+	if len(usage.Alarms) > 0 {
+		return true
+	}
+	return m.opts.QuotaBytes > 0 && float64(usage.SizeBytes) >= m.opts.SizeAlarmRatio*float64(m.opts.QuotaBytes)
+}
+
+// Health returns the latest health of the datastore.
+func (m *DatastoreManager) Health() *status.DatastoreHealth {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	health := m.health
+	health.Alarms = append([]string(nil), m.health.Alarms...)
+	return &health
+}
+
+// ServeHTTP serves the health of the datastore as JSON.
+func (m *DatastoreManager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(m.Health()); err != nil {
+		log.WithError(err).Error("Failed to write the datastore health")
+	}
+}
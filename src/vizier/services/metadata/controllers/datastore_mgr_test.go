@@ -0,0 +1,208 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/shared/status"
+	"px.dev/pixie/src/vizier/services/metadata/controllers"
+	"px.dev/pixie/src/vizier/utils/datastore"
+)
+
+const mib = 1024 * 1024
+
+// fakeMaintainer is a datastore whose compactions and defrags shrink it to its live data.
+type fakeMaintainer struct {
+	usage      datastore.Usage
+	compacted  int
+	defragged  int
+	compactErr error
+	// liveBytes is the space in use after a compaction.
+	liveBytes int64
+}
+
+func (f *fakeMaintainer) Usage() (*datastore.Usage, error) {
+	u := f.usage
+	return &u, nil
+}
+
+func (f *fakeMaintainer) Compact() error {
+	f.compacted++
+	if f.compactErr != nil {
+		return f.compactErr
+	}
+	f.usage.InUseBytes = f.liveBytes
+	return nil
+}
+
+func (f *fakeMaintainer) Defragment() error {
+	f.defragged++
+	f.usage.SizeBytes = f.usage.InUseBytes
+	f.usage.Alarms = nil
+	return nil
+}
+
+func TestDatastoreManager_Maintain(t *testing.T) {
+	tests := []struct {
+		name           string
+		usage          datastore.Usage
+		liveBytes      int64
+		compactErr     error
+		expectedDefrag bool
+		expectedHealth status.DatastoreHealth
+	}{
+		{
+			name:      "small",
+			usage:     datastore.Usage{SizeBytes: 10 * mib, InUseBytes: 10 * mib},
+			liveBytes: 8 * mib,
+			expectedHealth: status.DatastoreHealth{
+				Backend:    "fake",
+				SizeBytes:  10 * mib,
+				InUseBytes: 8 * mib,
+				QuotaBytes: 1000 * mib,
+			},
+		},
+		{
+			name:           "fragmented",
+			usage:          datastore.Usage{SizeBytes: 600 * mib, InUseBytes: 500 * mib},
+			liveBytes:      100 * mib,
+			expectedDefrag: true,
+			expectedHealth: status.DatastoreHealth{
+				Backend:    "fake",
+				SizeBytes:  100 * mib,
+				InUseBytes: 100 * mib,
+				QuotaBytes: 1000 * mib,
+			},
+		},
+		{
+			name:      "nearly full",
+			usage:     datastore.Usage{SizeBytes: 900 * mib, InUseBytes: 900 * mib},
+			liveBytes: 890 * mib,
+			expectedHealth: status.DatastoreHealth{
+				Backend:    "fake",
+				SizeBytes:  900 * mib,
+				InUseBytes: 890 * mib,
+				QuotaBytes: 1000 * mib,
+				SizeAlarm:  true,
+			},
+		},
+		{
+			name:           "nospace alarm",
+			usage:          datastore.Usage{SizeBytes: 1000 * mib, InUseBytes: 1000 * mib, Alarms: []string{"NOSPACE"}},
+			liveBytes:      300 * mib,
+			expectedDefrag: true,
+			expectedHealth: status.DatastoreHealth{
+				Backend:    "fake",
+				SizeBytes:  300 * mib,
+				InUseBytes: 300 * mib,
+				QuotaBytes: 1000 * mib,
+			},
+		},
+		{
+			name:       "compaction fails",
+			usage:      datastore.Usage{SizeBytes: 600 * mib, InUseBytes: 100 * mib},
+			compactErr: errors.New("etcdserver: request timed out"),
+			expectedHealth: status.DatastoreHealth{
+				Backend:    "fake",
+				SizeBytes:  600 * mib,
+				InUseBytes: 100 * mib,
+				QuotaBytes: 1000 * mib,
+				LastError:  "etcdserver: request timed out",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ds := &fakeMaintainer{usage: test.usage, liveBytes: test.liveBytes, compactErr: test.compactErr}
+			mgr := controllers.NewDatastoreManager(ds, controllers.DatastoreManagerOptions{
+				Backend:            "fake",
+				QuotaBytes:         1000 * mib,
+				SizeAlarmRatio:     0.8,
+				CompactionInterval: time.Hour,
+			})
+			mgr.Maintain()
+
+			assert.Equal(t, 1, ds.compacted)
+			assert.Equal(t, test.expectedDefrag, ds.defragged == 1)
+
+			health := mgr.Health()
+			if test.compactErr == nil {
+				assert.NotNil(t, health.LastCompactionTime)
+			} else {
+				assert.Nil(t, health.LastCompactionTime)
+			}
+			assert.Equal(t, test.expectedDefrag, health.LastDefragTime != nil)
+			health.LastCompactionTime = nil
+			health.LastDefragTime = nil
+			assert.Equal(t, test.expectedHealth, *health)
+		})
+	}
+}
+
+func TestDatastoreManager_CompactsOnInterval(t *testing.T) {
+	ds := &fakeMaintainer{usage: datastore.Usage{SizeBytes: 10 * mib, InUseBytes: 10 * mib}, liveBytes: 10 * mib}
+	mgr := controllers.NewDatastoreManager(ds, controllers.DatastoreManagerOptions{
+		Backend:            "fake",
+		QuotaBytes:         1000 * mib,
+		SizeAlarmRatio:     0.8,
+		CompactionInterval: time.Hour,
+	})
+	mgr.Maintain()
+	mgr.Maintain()
+	assert.Equal(t, 1, ds.compacted)
+
+	// While the size alarm is raised, the datastore is compacted on every check.
+	ds.usage.SizeBytes = 900 * mib
+	mgr.Maintain()
+	assert.Equal(t, 2, ds.compacted)
+}
+
+func TestDatastoreManager_ServeHTTP(t *testing.T) {
+	ds := &fakeMaintainer{
+		usage:     datastore.Usage{SizeBytes: 1000 * mib, InUseBytes: 1000 * mib, Alarms: []string{"NOSPACE"}},
+		liveBytes: 1000 * mib,
+	}
+	mgr := controllers.NewDatastoreManager(ds, controllers.DatastoreManagerOptions{
+		Backend:            "etcd",
+		QuotaBytes:         1000 * mib,
+		SizeAlarmRatio:     0.8,
+		CompactionInterval: time.Hour,
+	})
+	mgr.Maintain()
+
+	rec := httptest.NewRecorder()
+	mgr.ServeHTTP(rec, httptest.NewRequest("GET", status.DatastoreHealthPath, nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	health := &status.DatastoreHealth{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(health))
+	assert.Equal(t, "etcd", health.Backend)
+	assert.True(t, health.SizeAlarm)
+	assert.Equal(t, int64(1000*mib), health.SizeBytes)
+	assert.NotNil(t, health.LastCompactionTime)
+}
@@ -30,6 +30,9 @@ import (
 const (
 	// resourceUpdateTTL is how long the k8s update live in the DataStore.
 	resourceUpdateTTL = 24 * time.Hour
+	// ipAssignmentTTL is how long the assignment of an IP to a pod or service is kept after it stops,
+	// so that queries over older data can still resolve the IP.
+	ipAssignmentTTL = 7 * 24 * time.Hour
 )
 
 // Controller listens to any metadata updates from the K8s API and forwards them
@@ -76,9 +79,11 @@ func NewControllerWithClientSet(namespaces []string, updateCh chan *K8sResourceM
 		namespaceWatcher("namespaces", namespaces, updateCh, clientset),
 		podWatcher("pods", namespaces, updateCh, clientset),
 		endpointsWatcher("endpoints", namespaces, updateCh, clientset),
+		endpointSliceWatcher("endpointslices", namespaces, updateCh, clientset),
 		serviceWatcher("services", namespaces, updateCh, clientset),
 		replicaSetWatcher("replicasets", namespaces, updateCh, clientset),
 		deploymentWatcher("deployments", namespaces, updateCh, clientset),
+		jobWatcher("jobs", namespaces, updateCh, clientset),
 	}
 
 	mc := &Controller{quitCh: quitCh, updateCh: updateCh, watchers: watchers}
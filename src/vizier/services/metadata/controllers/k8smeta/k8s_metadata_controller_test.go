@@ -26,6 +26,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -811,6 +812,57 @@ func TestController(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "simple job",
+			updates: []resourceUpdate{
+				&job{
+					j: &batchv1.Job{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "myjob",
+							OwnerReferences: []metav1.OwnerReference{
+								{
+									Kind: "CronJob",
+									Name: "mycronjob",
+									UID:  "abcd",
+								},
+							},
+						},
+						Spec: batchv1.JobSpec{
+							Parallelism: int32ptr(2),
+						},
+					},
+					ns: "test",
+					t:  create,
+				},
+			},
+			expectedUpdates: []*k8smeta.K8sResourceMessage{
+				{
+					ObjectType: "jobs",
+					Object: &storepb.K8SResource{
+						Resource: &storepb.K8SResource_Job{
+							Job: &metadatapb.Job{
+								Metadata: &metadatapb.ObjectMetadata{
+									Name:      "myjob",
+									Namespace: "test",
+									OwnerReferences: []*metadatapb.OwnerReference{
+										{
+											Kind: "CronJob",
+											Name: "mycronjob",
+											UID:  "abcd",
+										},
+									},
+								},
+								Spec: &metadatapb.JobSpec{
+									Parallelism: 2,
+								},
+								Status: &metadatapb.JobStatus{},
+							},
+						},
+					},
+					EventType: watch.Added,
+				},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -844,13 +896,15 @@ func TestController(t *testing.T) {
 
 			watchersStarted := map[string]bool{
 				// This list needs to be kept up-to-date with the list of watchers started in k8s_metadata_controller.go
-				"nodes":       false,
-				"namespaces":  false,
-				"pods":        false,
-				"endpoints":   false,
-				"services":    false,
-				"replicasets": false,
-				"deployments": false,
+				"nodes":          false,
+				"namespaces":     false,
+				"pods":           false,
+				"endpoints":      false,
+				"endpointslices": false,
+				"services":       false,
+				"replicasets":    false,
+				"deployments":    false,
+				"jobs":           false,
 			}
 			allStarted := func() bool {
 				for _, started := range watchersStarted {
@@ -1384,13 +1438,15 @@ func TestControllerWithNotWatchedNameSpaces(t *testing.T) {
 
 			watchersStarted := map[string]bool{
 				// This list needs to be kept up-to-date with the list of watchers started in k8s_metadata_controller.go
-				"nodes":       false,
-				"namespaces":  false,
-				"pods":        false,
-				"endpoints":   false,
-				"services":    false,
-				"replicasets": false,
-				"deployments": false,
+				"nodes":          false,
+				"namespaces":     false,
+				"pods":           false,
+				"endpoints":      false,
+				"endpointslices": false,
+				"services":       false,
+				"replicasets":    false,
+				"deployments":    false,
+				"jobs":           false,
 			}
 			allStarted := func() bool {
 				for _, started := range watchersStarted {
@@ -1441,12 +1497,14 @@ func getNameSpaceFromResourceMessage(rm *k8smeta.K8sResourceMessage, resourceTyp
 		return rm.Object.GetPod().GetMetadata().GetNamespace()
 	case "deployments":
 		return rm.Object.GetDeployment().GetMetadata().GetNamespace()
-	case "endpoints":
+	case "endpoints", "endpointslices":
 		return rm.Object.GetEndpoints().GetMetadata().GetNamespace()
 	case "services":
 		return rm.Object.GetService().GetMetadata().GetNamespace()
 	case "replicasets":
 		return rm.Object.GetReplicaSet().GetMetadata().GetNamespace()
+	case "jobs":
+		return rm.Object.GetJob().GetMetadata().GetNamespace()
 	default:
 		return ""
 	}
@@ -1498,6 +1556,10 @@ func zeroTimestamps(updates []*k8smeta.K8sResourceMessage) []*k8smeta.K8sResourc
 				cond.LastTransitionTimeNS = 0
 			}
 		}
+		if v.Object.GetJob() != nil {
+			v.Object.GetJob().GetMetadata().CreationTimestampNS = 0
+			v.Object.GetJob().GetMetadata().DeletionTimestampNS = 0
+		}
 		out[i] = &v
 	}
 	return out
@@ -1666,3 +1728,25 @@ func (d *deployment) Apply(ctx context.Context, c kubernetes.Interface) error {
 		return errors.New("invalid resourceUpdateType")
 	}
 }
+
+type job struct {
+	j  *batchv1.Job
+	ns string
+	t  resourceUpdateType
+}
+
+func (j *job) Apply(ctx context.Context, c kubernetes.Interface) error {
+	ji := c.BatchV1().Jobs(j.ns)
+	switch j.t {
+	case create:
+		_, err := ji.Create(ctx, j.j, metav1.CreateOptions{})
+		return err
+	case update:
+		_, err := ji.Update(ctx, j.j, metav1.UpdateOptions{})
+		return err
+	case del:
+		return ji.Delete(ctx, j.j.Name, metav1.DeleteOptions{})
+	default:
+		return errors.New("invalid resourceUpdateType")
+	}
+}
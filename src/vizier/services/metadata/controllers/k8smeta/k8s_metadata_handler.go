@@ -19,6 +19,7 @@
 package k8smeta
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net"
@@ -33,6 +34,8 @@ import (
 	"github.com/gogo/protobuf/types"
 	"github.com/nats-io/nats.go"
 	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1"
 	"k8s.io/apimachinery/pkg/watch"
 
 	"px.dev/pixie/src/shared/cvmsgspb"
@@ -48,6 +51,9 @@ const KelvinUpdateTopic = "all"
 // K8sMetadataUpdateChannel is the channel where metadata updates are sent.
 const K8sMetadataUpdateChannel = "K8sUpdates"
 
+// externalNameLookupTimeout is how long to wait for the hostname of an ExternalName service to resolve.
+const externalNameLookupTimeout = 2 * time.Second
+
 // getK8sUpdateChannel returns the channel for sending updates.
 func getK8sUpdateChannel(topic string) string {
 	if topic == "" {
@@ -102,6 +108,17 @@ type Store interface {
 	GetUpdateVersion(topic string) (int64, error)
 	// SetUpdateVersion sets the last update version sent on a topic.
 	SetUpdateVersion(topic string, uv int64) error
+
+	IPAssignmentStore
+}
+
+// IPAssignmentStore handles storing and fetching the history of which pods and services had which IPs.
+type IPAssignmentStore interface {
+	// AddIPAssignment stores the assignment of an IP to a pod or service.
+	AddIPAssignment(assignment *storepb.IPAssignment) error
+	// FetchIPAssignments gets the assignments of the given IP, ordered by start time. If no IP is
+	// specified, the assignments of all IPs are returned.
+	FetchIPAssignments(ip string) ([]*storepb.IPAssignment, error)
 }
 
 // PodLabelStore handles storing and fetching data of pods and their associated labels.
@@ -149,6 +166,9 @@ type ProcessorState struct {
 	NodeToIP map[string]string
 	// A map from pod name to its IP.
 	PodToIP map[string]string
+	// A map from the name of an Endpoints object to its UID, for the Endpoints which have been truncated
+	// by K8s because they have too many addresses. The rest of their addresses are read from EndpointSlices.
+	TruncatedEndpoints map[string]string
 }
 
 // Handler handles any incoming k8s updates. It saves the update to the store for persistence, and
@@ -177,17 +197,19 @@ func NewHandler(updateCh <-chan *K8sResourceMessage, mds Store, pls PodLabelStor
 	done := make(chan struct{})
 	leaderMsgs := make(map[string]*metadatapb.Endpoints)
 	handlerMap := make(map[string]UpdateProcessor)
-	state := ProcessorState{LeaderMsgs: leaderMsgs, PodCIDRs: make([]string, 0), NodeToIP: make(map[string]string), PodToIP: make(map[string]string)}
+	state := ProcessorState{LeaderMsgs: leaderMsgs, PodCIDRs: make([]string, 0), NodeToIP: make(map[string]string), PodToIP: make(map[string]string), TruncatedEndpoints: make(map[string]string)}
 	mh := &Handler{updateCh: updateCh, mds: mds, pls: pls, conn: conn, done: done, processHandlerMap: handlerMap, state: state}
 
 	// Register update processors.
 	mh.processHandlerMap["endpoints"] = &EndpointsUpdateProcessor{}
-	mh.processHandlerMap["services"] = &ServiceUpdateProcessor{}
+	mh.processHandlerMap["endpointslices"] = &EndpointSliceUpdateProcessor{}
+	mh.processHandlerMap["services"] = &ServiceUpdateProcessor{LookupHost: lookupHost}
 	mh.processHandlerMap["pods"] = &PodUpdateProcessor{}
 	mh.processHandlerMap["nodes"] = &NodeUpdateProcessor{}
 	mh.processHandlerMap["namespaces"] = &NamespaceUpdateProcessor{}
 	mh.processHandlerMap["replicasets"] = &ReplicaSetUpdateProcessor{}
 	mh.processHandlerMap["deployments"] = &DeploymentUpdateProcessor{}
+	mh.processHandlerMap["jobs"] = &JobUpdateProcessor{}
 
 	go mh.processUpdates()
 	return mh
@@ -247,6 +269,7 @@ func (m *Handler) processUpdates() {
 					log.WithError(err).Error("Failed to update pod labels state")
 				}
 			}
+			m.recordIPAssignments(update)
 
 			// Persist the update in the data store.
 			updates := processor.GetStoredProtos(update)
@@ -400,6 +423,107 @@ func (m *Handler) GetUpdatesForIP(ip string, from int64, to int64) ([]*metadatap
 	return updates, nil
 }
 
+// recordIPAssignments stores the IP assigned to the pod or service in the update, if any.
+func (m *Handler) recordIPAssignments(update *storepb.K8SResource) {
+	assignment := getIPAssignment(update)
+	if assignment == nil {
+		return
+	}
+
+	prev, err := m.mds.FetchIPAssignments(assignment.IP)
+	if err != nil {
+		log.WithError(err).Error("Failed to fetch IP assignments")
+		return
+	}
+	var existing *storepb.IPAssignment
+	for _, p := range prev {
+		if p.UID == assignment.UID {
+			existing = p
+			break
+		}
+	}
+	if existing != nil {
+		// Keep the original start time, so that the assignment is updated in place. Keep the stop
+		// time if the assignment was already stopped because the IP was reassigned.
+		assignment.StartTimestampNS = existing.StartTimestampNS
+		if existing.StopTimestampNS != 0 && (assignment.StopTimestampNS == 0 || existing.StopTimestampNS < assignment.StopTimestampNS) {
+			assignment.StopTimestampNS = existing.StopTimestampNS
+		}
+	} else {
+		// IPs are only reassigned once the previous owner is gone. If the previous owner was
+		// deleted after this one was created, the IP was assigned after the deletion.
+		for _, p := range prev {
+			if p.StopTimestampNS > assignment.StartTimestampNS {
+				assignment.StartTimestampNS = p.StopTimestampNS
+			}
+		}
+	}
+	for _, p := range prev {
+		// If we missed the deletion of the previous owner, stop it when the IP was reassigned.
+		if p.UID != assignment.UID && p.StopTimestampNS == 0 && p.StartTimestampNS <= assignment.StartTimestampNS {
+			p.StopTimestampNS = assignment.StartTimestampNS
+			if err := m.mds.AddIPAssignment(p); err != nil {
+				log.WithError(err).Error("Failed to store IP assignment")
+			}
+		}
+	}
+
+	if err := m.mds.AddIPAssignment(assignment); err != nil {
+		log.WithError(err).Error("Failed to store IP assignment")
+	}
+}
+
+// getIPAssignment gets the assignment of the pod IP or service cluster IP in the update.
+func getIPAssignment(update *storepb.K8SResource) *storepb.IPAssignment {
+	switch {
+	case update.GetPod() != nil:
+		pod := update.GetPod()
+		// Pods on the host network share the host's IP, so their IP doesn't identify them.
+		if pod.Status == nil || pod.Status.PodIP == "" || pod.Status.PodIP == pod.Status.HostIP {
+			return nil
+		}
+		return &storepb.IPAssignment{
+			IP:               pod.Status.PodIP,
+			UID:              pod.Metadata.UID,
+			Name:             pod.Metadata.Name,
+			Namespace:        pod.Metadata.Namespace,
+			StartTimestampNS: pod.Metadata.CreationTimestampNS,
+			StopTimestampNS:  pod.Metadata.DeletionTimestampNS,
+		}
+	case update.GetService() != nil:
+		svc := update.GetService()
+		if svc.Spec == nil || svc.Spec.ClusterIP == "" || svc.Spec.ClusterIP == "None" {
+			return nil
+		}
+		return &storepb.IPAssignment{
+			IP:               svc.Spec.ClusterIP,
+			UID:              svc.Metadata.UID,
+			Name:             svc.Metadata.Name,
+			Namespace:        svc.Metadata.Namespace,
+			Service:          true,
+			StartTimestampNS: svc.Metadata.CreationTimestampNS,
+			StopTimestampNS:  svc.Metadata.DeletionTimestampNS,
+		}
+	default:
+		return nil
+	}
+}
+
+// IPAssignmentAtTime returns the assignment that was active at the given time, or nil if the IP was
+// not assigned at that time. If several were active, the one that started last is returned.
+func IPAssignmentAtTime(assignments []*storepb.IPAssignment, timestampNS int64) *storepb.IPAssignment {
+	var active *storepb.IPAssignment
+	for _, a := range assignments {
+		if a.StartTimestampNS > timestampNS || (a.StopTimestampNS != 0 && a.StopTimestampNS <= timestampNS) {
+			continue
+		}
+		if active == nil || a.StartTimestampNS > active.StartTimestampNS {
+			active = a
+		}
+	}
+	return active
+}
+
 // GetServiceCIDR returns the service CIDR for the current cluster.
 func (m *Handler) GetServiceCIDR() string {
 	if m.state.ServiceCIDR != nil {
@@ -475,14 +599,33 @@ func (p *EndpointsUpdateProcessor) ValidateUpdate(obj *storepb.K8SResource, stat
 		}
 	}
 
-	// Don't record the endpoint if there is no nodename.
-	if len(e.Subsets) == 0 || len(e.Subsets[0].Addresses) == 0 || e.Subsets[0].Addresses[0].NodeName == "" {
+	// Don't record the endpoint if there are no addresses, or if its pods haven't been scheduled yet.
+	// Addresses that don't belong to a pod, such as the ones of a service without a selector, don't
+	// have a nodename.
+	if len(e.Subsets) == 0 || len(e.Subsets[0].Addresses) == 0 {
+		return false
+	}
+	if addr := e.Subsets[0].Addresses[0]; isPodAddress(addr) && addr.NodeName == "" {
 		return false
 	}
 
+	p.updateTruncatedEndpoints(e, state)
 	return true
 }
 
+func (p *EndpointsUpdateProcessor) updateTruncatedEndpoints(e *metadatapb.Endpoints, state *ProcessorState) {
+	if state.TruncatedEndpoints == nil {
+		state.TruncatedEndpoints = make(map[string]string)
+	}
+
+	name := fmt.Sprintf("%s/%s", e.Metadata.Namespace, e.Metadata.Name)
+	if e.Metadata.DeletionTimestampNS == 0 && e.Metadata.Annotations[v1.EndpointsOverCapacity] == "truncated" {
+		state.TruncatedEndpoints[name] = e.Metadata.UID
+	} else {
+		delete(state.TruncatedEndpoints, name)
+	}
+}
+
 // GetStoredProtos gets the update protos that should be persisted.
 func (p *EndpointsUpdateProcessor) GetStoredProtos(obj *storepb.K8SResource) []*storepb.K8SResource {
 	return []*storepb.K8SResource{obj}
@@ -498,49 +641,161 @@ func (p *EndpointsUpdateProcessor) GetUpdatesToSend(storedUpdates []*StoredUpdat
 	pb := storedUpdates[0].Update.GetEndpoints()
 	rv := storedUpdates[0].UpdateVersion
 
+	return getServiceUpdatesFromEndpoints(pb, state, func(podIDs []string, podNames []string, endpointIPs []string) *metadatapb.ResourceUpdate {
+		return getServiceResourceUpdateFromEndpoint(pb, rv, podIDs, podNames, endpointIPs)
+	})
+}
+
+// EndpointSliceUpdateProcessor is a processor for endpoint slices. K8s truncates Endpoints objects which
+// have too many addresses, so the addresses of those services are read from their EndpointSlices instead.
+// The slices of every other service are dropped, since the Endpoints object already covers them.
+type EndpointSliceUpdateProcessor struct{}
+
+// IsNodeScoped returns whether this update is scoped to specific nodes, or should be sent to all nodes.
+func (p *EndpointSliceUpdateProcessor) IsNodeScoped() bool {
+	return true
+}
+
+// SetDeleted sets the deletion timestamp for the object, if there is none already set.
+func (p *EndpointSliceUpdateProcessor) SetDeleted(obj *storepb.K8SResource) {
+	e := obj.GetEndpoints()
+	if e == nil {
+		return
+	}
+	setDeleted(e.Metadata)
+}
+
+// ValidateUpdate checks that the provided endpoint slice belongs to truncated endpoints.
+func (p *EndpointSliceUpdateProcessor) ValidateUpdate(obj *storepb.K8SResource, state *ProcessorState) bool {
+	e := obj.GetEndpoints()
+	if e == nil {
+		log.WithField("object", obj).Trace("Received non-endpoints object when handling endpoint slice metadata.")
+		return false
+	}
+
+	// The lifetime of the service is tracked by its Endpoints object, so deleted slices don't need
+	// to be sent out.
+	if e.Metadata.DeletionTimestampNS != 0 {
+		return false
+	}
+
+	_, ok := state.TruncatedEndpoints[endpointSliceServiceName(e)]
+	return ok
+}
+
+// GetStoredProtos gets the update protos that should be persisted.
+func (p *EndpointSliceUpdateProcessor) GetStoredProtos(obj *storepb.K8SResource) []*storepb.K8SResource {
+	return []*storepb.K8SResource{obj}
+}
+
+// GetUpdatesToSend gets the resource updates that should be sent out to the agents, along with the agent IPs that the update should be sent to.
+func (p *EndpointSliceUpdateProcessor) GetUpdatesToSend(storedUpdates []*StoredUpdate, state *ProcessorState) []*OutgoingUpdate {
+	if len(storedUpdates) == 0 {
+		return nil
+	}
+
+	// We always expect one element in this array.
+	pb := storedUpdates[0].Update.GetEndpoints()
+	rv := storedUpdates[0].UpdateVersion
+
+	uid, ok := state.TruncatedEndpoints[endpointSliceServiceName(pb)]
+	if !ok {
+		return nil
+	}
+
+	return getServiceUpdatesFromEndpoints(pb, state, func(podIDs []string, podNames []string, endpointIPs []string) *metadatapb.ResourceUpdate {
+		return &metadatapb.ResourceUpdate{
+			UpdateVersion: rv,
+			Update: &metadatapb.ResourceUpdate_ServiceUpdate{
+				ServiceUpdate: &metadatapb.ServiceUpdate{
+					// Use the UID of the Endpoints object, so that the agents treat the slice as a part of it.
+					UID:       uid,
+					Name:      pb.Metadata.Labels[discovery.LabelServiceName],
+					Namespace: pb.Metadata.Namespace,
+					// Omit Start/Stop timestamps -- These are sent by the EndpointsUpdateProcessor.
+					PodIDs:      podIDs,
+					PodNames:    podNames,
+					EndpointIPs: endpointIPs,
+				},
+			},
+		}
+	})
+}
+
+// endpointSliceServiceName returns the name of the service, in the form of <namespace>/<name>, that the
+// given endpoint slice belongs to.
+func endpointSliceServiceName(e *metadatapb.Endpoints) string {
+	return fmt.Sprintf("%s/%s", e.Metadata.Namespace, e.Metadata.Labels[discovery.LabelServiceName])
+}
+
+func isPodAddress(addr *metadatapb.EndpointAddress) bool {
+	return addr.TargetRef != nil && addr.TargetRef.Kind == "Pod"
+}
+
+// getServiceUpdatesFromEndpoints gets the service updates for the nodes running the pods in the endpoints,
+// and the update for Kelvin, which contains all of the addresses.
+func getServiceUpdatesFromEndpoints(pb *metadatapb.Endpoints, state *ProcessorState, getUpdate func(podIDs []string, podNames []string, endpointIPs []string) *metadatapb.ResourceUpdate) []*OutgoingUpdate {
 	var updates []*OutgoingUpdate
 
 	// Track all pod name and pod UIDs for the update to Kelvin.
 	var allPodNames []string
 	var allPodUIDs []string
+	// Track the IPs which don't belong to any pod, so Kelvin can still resolve them to the service.
+	var endpointIPs []string
 
 	// Construct a map for each IP in the endpoints, to the associated pods.
 	ipToPodNames := make(map[string][]string)
 	ipToPodUIDs := make(map[string][]string)
 	for _, subset := range pb.Subsets {
 		for _, addr := range subset.Addresses {
-			if addr.TargetRef != nil && addr.TargetRef.Kind == "Pod" {
-				allPodNames = append(allPodNames, addr.TargetRef.Name)
-				allPodUIDs = append(allPodUIDs, addr.TargetRef.UID)
-
-				ip, ok := state.PodToIP[fmt.Sprintf("%s/%s", addr.TargetRef.Namespace, addr.TargetRef.Name)]
-				if !ok {
-					continue
+			if !isPodAddress(addr) {
+				if addr.IP != "" {
+					endpointIPs = append(endpointIPs, addr.IP)
 				}
+				continue
+			}
+
+			allPodNames = append(allPodNames, addr.TargetRef.Name)
+			allPodUIDs = append(allPodUIDs, addr.TargetRef.UID)
 
-				ipToPodNames[ip] = append(ipToPodNames[ip], addr.TargetRef.Name)
-				ipToPodUIDs[ip] = append(ipToPodUIDs[ip], addr.TargetRef.UID)
+			ip, ok := state.PodToIP[fmt.Sprintf("%s/%s", addr.TargetRef.Namespace, addr.TargetRef.Name)]
+			if !ok {
+				continue
 			}
+
+			ipToPodNames[ip] = append(ipToPodNames[ip], addr.TargetRef.Name)
+			ipToPodUIDs[ip] = append(ipToPodUIDs[ip], addr.TargetRef.UID)
 		}
 	}
 
 	for ip := range ipToPodNames {
 		updates = append(updates, &OutgoingUpdate{
-			Update: getServiceResourceUpdateFromEndpoint(pb, rv, ipToPodUIDs[ip], ipToPodNames[ip]),
+			Update: getUpdate(ipToPodUIDs[ip], ipToPodNames[ip], nil),
 			Topics: []string{ip},
 		})
 	}
 	// Also send update to Kelvin.
 	updates = append(updates, &OutgoingUpdate{
-		Update: getServiceResourceUpdateFromEndpoint(pb, rv, allPodUIDs, allPodNames),
+		Update: getUpdate(allPodUIDs, allPodNames, endpointIPs),
 		Topics: []string{KelvinUpdateTopic},
 	})
 
 	return updates
 }
 
+// lookupHost resolves the hostnames of ExternalName services.
+func lookupHost(host string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), externalNameLookupTimeout)
+	defer cancel()
+	return net.DefaultResolver.LookupHost(ctx, host)
+}
+
 // ServiceUpdateProcessor is a processor for services.
-type ServiceUpdateProcessor struct{}
+type ServiceUpdateProcessor struct {
+	// LookupHost resolves the external name of ExternalName services to their IPs. External names are only
+	// resolved if they are IPs themselves when this is nil.
+	LookupHost func(host string) ([]string, error)
+}
 
 // IsNodeScoped returns whether this update is scoped to specific nodes, or should be sent to all nodes.
 func (p *ServiceUpdateProcessor) IsNodeScoped() bool {
@@ -601,6 +856,12 @@ func (p *ServiceUpdateProcessor) GetUpdatesToSend(storedUpdates []*StoredUpdate,
 	service := storedUpdates[0].Update.GetService()
 	uv := storedUpdates[0].UpdateVersion
 
+	// Headless services don't have a Cluster IP, their traffic goes directly to the pods.
+	clusterIP := service.Spec.ClusterIP
+	if clusterIP == v1.ClusterIPNone {
+		clusterIP = ""
+	}
+
 	// Send service-level updates (which are detached from a specific pod) to Kelvin only.
 	// Service updates will also be sent to PEMs by the EndpointsUpdateProcessor in order to provide
 	// a mapping from service->pod.
@@ -617,7 +878,8 @@ func (p *ServiceUpdateProcessor) GetUpdatesToSend(storedUpdates []*StoredUpdate,
 						// The EndpointUpdateProcessor associates services to pods, and the pods should have
 						// consistent information about start/stop timestamps for the service they are part of.
 						ExternalIPs: service.Spec.ExternalIPs,
-						ClusterIP:   service.Spec.ClusterIP,
+						ClusterIP:   clusterIP,
+						EndpointIPs: p.externalNameIPs(service),
 					},
 				},
 			},
@@ -626,6 +888,27 @@ func (p *ServiceUpdateProcessor) GetUpdatesToSend(storedUpdates []*StoredUpdate,
 	}
 }
 
+// externalNameIPs gets the IPs that the external name of an ExternalName service points to, so that
+// traffic to those IPs can be attributed to the service.
+func (p *ServiceUpdateProcessor) externalNameIPs(service *metadatapb.Service) []string {
+	if service.Spec.Type != metadatapb.EXTERNAL_NAME || service.Spec.ExternalName == "" {
+		return nil
+	}
+	if net.ParseIP(service.Spec.ExternalName) != nil {
+		return []string{service.Spec.ExternalName}
+	}
+	if p.LookupHost == nil || service.Metadata.DeletionTimestampNS != 0 {
+		return nil
+	}
+
+	ips, err := p.LookupHost(service.Spec.ExternalName)
+	if err != nil {
+		log.WithError(err).WithField("service", service.Metadata.Name).Info("Failed to resolve external name of service")
+		return nil
+	}
+	return ips
+}
+
 // PodUpdateProcessor is a processor for pods.
 type PodUpdateProcessor struct{}
 
@@ -1007,6 +1290,62 @@ func (p *DeploymentUpdateProcessor) GetUpdatesToSend(updates []*StoredUpdate, st
 	}
 }
 
+// JobUpdateProcessor is a processor for job updates.
+type JobUpdateProcessor struct{}
+
+// IsNodeScoped returns whether this update is scoped to specific nodes, or should be sent to all nodes.
+func (p *JobUpdateProcessor) IsNodeScoped() bool {
+	return false
+}
+
+// SetDeleted sets the deletion timestamp for the object, if there is none already set.
+func (p *JobUpdateProcessor) SetDeleted(obj *storepb.K8SResource) {
+	job := obj.GetJob()
+	if job == nil {
+		return
+	}
+	setDeleted(job.Metadata)
+}
+
+// ValidateUpdate checks that the provided job object is valid, and casts it to the correct type.
+func (p *JobUpdateProcessor) ValidateUpdate(obj *storepb.K8SResource, state *ProcessorState) bool {
+	job := obj.GetJob()
+	if job == nil {
+		log.WithField("object", obj).Trace("Received non-job object when handling job metadata.")
+		return false
+	}
+
+	return true
+}
+
+// GetStoredProtos gets the update protos that should be persisted.
+func (p *JobUpdateProcessor) GetStoredProtos(obj *storepb.K8SResource) []*storepb.K8SResource {
+	return []*storepb.K8SResource{obj}
+}
+
+// GetUpdatesToSend gets the resource updates that should be sent out to the agents, along with the agent IPs that the update should be sent to.
+func (p *JobUpdateProcessor) GetUpdatesToSend(updates []*StoredUpdate, state *ProcessorState) []*OutgoingUpdate {
+	if len(updates) == 0 {
+		return nil
+	}
+
+	rv := updates[0].UpdateVersion
+	job := updates[0].Update.GetJob()
+
+	// Send the update to all PEMs + Kelvin, so that they can resolve the owners of the job's pods.
+	agents := []string{KelvinUpdateTopic}
+	for _, ip := range state.NodeToIP {
+		agents = append(agents, ip)
+	}
+
+	return []*OutgoingUpdate{
+		{
+			Update: getResourceUpdateFromJob(job, rv),
+			Topics: agents,
+		},
+	}
+}
+
 func formatContainerID(cid string) (metadatapb.ContainerType, string) {
 	// Strip prefixes like docker:// or containerd://
 	tokens := strings.SplitN(cid, "://", 2)
@@ -1050,6 +1389,8 @@ func GetContainerUpdatesFromPod(pod *metadatapb.Pod) []*metadatapb.ContainerUpda
 			Message:          s.Message,
 			Reason:           s.Reason,
 			ContainerType:    cType,
+			Image:            s.Image,
+			ImageID:          s.ImageID,
 		}
 	}
 	return updates
@@ -1069,7 +1410,7 @@ func getResourceUpdateFromNamespace(ns *metadatapb.Namespace, uv int64) *metadat
 	}
 }
 
-func getServiceResourceUpdateFromEndpoint(ep *metadatapb.Endpoints, uv int64, podIDs []string, podNames []string) *metadatapb.ResourceUpdate {
+func getServiceResourceUpdateFromEndpoint(ep *metadatapb.Endpoints, uv int64, podIDs []string, podNames []string, endpointIPs []string) *metadatapb.ResourceUpdate {
 	update := &metadatapb.ResourceUpdate{
 		UpdateVersion: uv,
 		Update: &metadatapb.ResourceUpdate_ServiceUpdate{
@@ -1081,6 +1422,7 @@ func getServiceResourceUpdateFromEndpoint(ep *metadatapb.Endpoints, uv int64, po
 				StopTimestampNS:  ep.Metadata.DeletionTimestampNS,
 				PodIDs:           podIDs,
 				PodNames:         podNames,
+				EndpointIPs:      endpointIPs,
 			},
 		},
 	}
@@ -1202,6 +1544,22 @@ func getResourceUpdateFromDeployment(dep *metadatapb.Deployment, uv int64) *meta
 	}
 }
 
+func getResourceUpdateFromJob(job *metadatapb.Job, uv int64) *metadatapb.ResourceUpdate {
+	return &metadatapb.ResourceUpdate{
+		UpdateVersion: uv,
+		Update: &metadatapb.ResourceUpdate_JobUpdate{
+			JobUpdate: &metadatapb.JobUpdate{
+				UID:              job.Metadata.UID,
+				Name:             job.Metadata.Name,
+				StartTimestampNS: job.Metadata.CreationTimestampNS,
+				StopTimestampNS:  job.Metadata.DeletionTimestampNS,
+				Namespace:        job.Metadata.Namespace,
+				OwnerReferences:  job.Metadata.OwnerReferences,
+			},
+		},
+	}
+}
+
 // Stop stops processing incoming k8s metadata updates.
 func (m *Handler) Stop() {
 	m.once.Do(func() {
@@ -19,6 +19,7 @@
 package k8smeta_test
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"sync"
@@ -176,11 +177,26 @@ func createDeploymentObject() *storepb.K8SResource {
 	}
 }
 
+func createJobObject() *storepb.K8SResource {
+	pb := &metadatapb.Job{}
+	err := proto.UnmarshalText(testutils.JobPb, pb)
+	if err != nil {
+		return &storepb.K8SResource{}
+	}
+
+	return &storepb.K8SResource{
+		Resource: &storepb.K8SResource_Job{
+			Job: pb,
+		},
+	}
+}
+
 type ResourceStore map[int64]*storepb.K8SResourceUpdate
 type InMemoryStore struct {
 	ResourceStoreByTopic map[string]ResourceStore
 	RVStore              map[string]int64
 	FullResourceStore    map[int64]*storepb.K8SResource
+	testutils.InMemoryIPAssignmentStore
 }
 
 func (s *InMemoryStore) AddResourceUpdateForTopic(uv int64, topic string, r *storepb.K8SResourceUpdate) error {
@@ -620,6 +636,124 @@ func TestHandler_ProcessUpdates(t *testing.T) {
 	}, mds.ResourceStoreByTopic["unscoped"][5])
 }
 
+func TestHandler_ProcessUpdates_IPAssignments(t *testing.T) {
+	updateCh := make(chan *k8smeta.K8sResourceMessage)
+
+	mds := &InMemoryStore{
+		ResourceStoreByTopic: make(map[string]ResourceStore),
+		RVStore:              map[string]int64{},
+		FullResourceStore:    make(map[int64]*storepb.K8SResource),
+	}
+	lps := &testutils.InMemoryPodLabelStore{
+		Store: make(map[string]string),
+	}
+
+	nc, natsCleanup := testingutils.MustStartTestNATS(t)
+	defer natsCleanup()
+
+	mdh := k8smeta.NewHandler(updateCh, mds, lps, nc)
+	defer mdh.Stop()
+
+	// The first pod is deleted, and its IP is later reassigned to the second pod, whose deletion
+	// we never see.
+	pod1 := createPodObject(metadatapb.RUNNING)
+	pod1.GetPod().Status.PodIP = "10.0.0.1"
+	pod1.GetPod().Metadata.CreationTimestampNS = 4
+	pod1.GetPod().Metadata.DeletionTimestampNS = 6
+
+	pod2 := createPodObject(metadatapb.RUNNING)
+	pod2.GetPod().Status.PodIP = "10.0.0.1"
+	pod2.GetPod().Metadata.UID = "efgh"
+	pod2.GetPod().Metadata.Name = "other-pod"
+	pod2.GetPod().Metadata.CreationTimestampNS = 5
+	pod2.GetPod().Metadata.DeletionTimestampNS = 0
+
+	pod3 := createPodObject(metadatapb.RUNNING)
+	pod3.GetPod().Status.PodIP = "10.0.0.1"
+	pod3.GetPod().Metadata.UID = "mnop"
+	pod3.GetPod().Metadata.Name = "third-pod"
+	pod3.GetPod().Metadata.CreationTimestampNS = 10
+	pod3.GetPod().Metadata.DeletionTimestampNS = 0
+
+	// Pods on the host network shouldn't be recorded.
+	hostPod := createPodObject(metadatapb.RUNNING)
+	hostPod.GetPod().Status.PodIP = hostPod.GetPod().Status.HostIP
+	hostPod.GetPod().Metadata.UID = "qrst"
+
+	for _, o := range []*storepb.K8SResource{pod1, pod2, pod3, hostPod} {
+		updateCh <- &k8smeta.K8sResourceMessage{
+			Object:     o,
+			ObjectType: "pods",
+		}
+	}
+	// The update channel is unbuffered, so once this is received the pod updates have been handled.
+	updateCh <- &k8smeta.K8sResourceMessage{
+		Object:     createNamespaceObject(),
+		ObjectType: "namespaces",
+	}
+
+	assignments, err := mds.FetchIPAssignments("10.0.0.1")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []*storepb.IPAssignment{
+		{
+			IP:               "10.0.0.1",
+			UID:              "ijkl",
+			Name:             "object_md",
+			StartTimestampNS: 4,
+			StopTimestampNS:  6,
+		},
+		{
+			IP:               "10.0.0.1",
+			UID:              "efgh",
+			Name:             "other-pod",
+			StartTimestampNS: 6,
+			StopTimestampNS:  10,
+		},
+		{
+			IP:               "10.0.0.1",
+			UID:              "mnop",
+			Name:             "third-pod",
+			StartTimestampNS: 10,
+		},
+	}, assignments)
+
+	hostAssignments, err := mds.FetchIPAssignments(hostPod.GetPod().Status.HostIP)
+	require.NoError(t, err)
+	assert.Empty(t, hostAssignments)
+}
+
+func TestIPAssignmentAtTime(t *testing.T) {
+	assignments := []*storepb.IPAssignment{
+		{UID: "abcd", StartTimestampNS: 4, StopTimestampNS: 6},
+		{UID: "efgh", StartTimestampNS: 6, StopTimestampNS: 10},
+		{UID: "ijkl", StartTimestampNS: 8},
+	}
+
+	tests := []struct {
+		name        string
+		timestampNS int64
+		expectedUID string
+	}{
+		{"before first assignment", 2, ""},
+		{"first assignment", 4, "abcd"},
+		{"at stop of first assignment", 6, "efgh"},
+		{"overlapping assignments", 9, "ijkl"},
+		{"open assignment", 100, "ijkl"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := k8smeta.IPAssignmentAtTime(assignments, test.timestampNS)
+			if test.expectedUID == "" {
+				assert.Nil(t, a)
+				return
+			}
+			require.NotNil(t, a)
+			assert.Equal(t, test.expectedUID, a.UID)
+		})
+	}
+}
+
 func TestEndpointsUpdateProcessor_SetDeleted(t *testing.T) {
 	// Construct endpoints object.
 	o := createEndpointsObject()
@@ -765,6 +899,201 @@ func TestEndpointsUpdateProcessor_GetUpdatesToSend(t *testing.T) {
 	})
 }
 
+func TestEndpointsUpdateProcessor_ValidateUpdate_NonPodAddresses(t *testing.T) {
+	// Endpoints of a service without a selector, which don't belong to any pod.
+	o := createEndpointsObject()
+	o.GetEndpoints().Subsets[0].Addresses = []*metadatapb.EndpointAddress{
+		{
+			IP: "10.0.0.1",
+		},
+	}
+
+	state := &k8smeta.ProcessorState{
+		LeaderMsgs: make(map[string]*metadatapb.Endpoints),
+	}
+	p := k8smeta.EndpointsUpdateProcessor{}
+	assert.True(t, p.ValidateUpdate(o, state))
+
+	// Endpoints with no addresses should fail.
+	o.GetEndpoints().Subsets[0].Addresses = nil
+	assert.False(t, p.ValidateUpdate(o, state))
+}
+
+func TestEndpointsUpdateProcessor_ValidateUpdate_TruncatedEndpoints(t *testing.T) {
+	o := createEndpointsObject()
+	o.GetEndpoints().Metadata.DeletionTimestampNS = 0
+	o.GetEndpoints().Metadata.Annotations = map[string]string{
+		"endpoints.kubernetes.io/over-capacity": "truncated",
+	}
+
+	state := &k8smeta.ProcessorState{
+		LeaderMsgs: make(map[string]*metadatapb.Endpoints),
+	}
+	p := k8smeta.EndpointsUpdateProcessor{}
+	assert.True(t, p.ValidateUpdate(o, state))
+	assert.Equal(t, map[string]string{"a_namespace/object_md": "ijkl"}, state.TruncatedEndpoints)
+
+	// Once the endpoints are no longer truncated, the slices aren't needed anymore.
+	o.GetEndpoints().Metadata.Annotations = nil
+	assert.True(t, p.ValidateUpdate(o, state))
+	assert.Equal(t, 0, len(state.TruncatedEndpoints))
+}
+
+func TestEndpointsUpdateProcessor_GetUpdatesToSend_NonPodAddresses(t *testing.T) {
+	expectedPb := &metadatapb.Endpoints{}
+	if err := proto.UnmarshalText(testutils.EndpointsPb, expectedPb); err != nil {
+		t.Fatal("Cannot Unmarshal protobuf.")
+	}
+	expectedPb.Subsets[0].Addresses = append(expectedPb.Subsets[0].Addresses, &metadatapb.EndpointAddress{
+		IP: "10.0.0.1",
+	})
+
+	storedProtos := []*k8smeta.StoredUpdate{
+		{
+			Update: &storepb.K8SResource{
+				Resource: &storepb.K8SResource_Endpoints{
+					Endpoints: expectedPb,
+				},
+			},
+			UpdateVersion: 2,
+		},
+	}
+
+	state := &k8smeta.ProcessorState{
+		PodToIP: map[string]string{
+			"pl/another-pod": "127.0.0.2",
+			"pl/pod-name":    "127.0.0.1",
+		},
+	}
+	p := k8smeta.EndpointsUpdateProcessor{}
+	updates := p.GetUpdatesToSend(storedProtos, state)
+	assert.Equal(t, 3, len(updates))
+
+	// The IPs which don't belong to a pod should only be sent to Kelvin.
+	assert.Contains(t, updates, &k8smeta.OutgoingUpdate{
+		Update: &metadatapb.ResourceUpdate{
+			UpdateVersion: 2,
+			Update: &metadatapb.ResourceUpdate_ServiceUpdate{
+				ServiceUpdate: &metadatapb.ServiceUpdate{
+					UID:              "ijkl",
+					Name:             "object_md",
+					Namespace:        "a_namespace",
+					StartTimestampNS: 4,
+					StopTimestampNS:  6,
+					PodIDs:           []string{"abcd"},
+					PodNames:         []string{"pod-name"},
+				},
+			},
+		},
+		Topics: []string{"127.0.0.1"},
+	})
+
+	assert.Contains(t, updates, &k8smeta.OutgoingUpdate{
+		Update: &metadatapb.ResourceUpdate{
+			UpdateVersion: 2,
+			Update: &metadatapb.ResourceUpdate_ServiceUpdate{
+				ServiceUpdate: &metadatapb.ServiceUpdate{
+					UID:              "ijkl",
+					Name:             "object_md",
+					Namespace:        "a_namespace",
+					StartTimestampNS: 4,
+					StopTimestampNS:  6,
+					PodIDs:           []string{"abcd", "efgh"},
+					PodNames:         []string{"pod-name", "another-pod"},
+					EndpointIPs:      []string{"10.0.0.1"},
+				},
+			},
+		},
+		Topics: []string{k8smeta.KelvinUpdateTopic},
+	})
+}
+
+func createEndpointSliceObject() *storepb.K8SResource {
+	o := createEndpointsObject()
+	md := o.GetEndpoints().Metadata
+	md.Name = "object_md-abcde"
+	md.UID = "mnop"
+	md.DeletionTimestampNS = 0
+	md.Labels = map[string]string{
+		"kubernetes.io/service-name": "object_md",
+	}
+	return o
+}
+
+func TestEndpointSliceUpdateProcessor_ValidateUpdate(t *testing.T) {
+	o := createEndpointSliceObject()
+
+	state := &k8smeta.ProcessorState{
+		TruncatedEndpoints: make(map[string]string),
+	}
+	p := k8smeta.EndpointSliceUpdateProcessor{}
+
+	// Slices of endpoints which aren't truncated are already covered by the endpoints.
+	assert.False(t, p.ValidateUpdate(o, state))
+
+	state.TruncatedEndpoints["a_namespace/object_md"] = "ijkl"
+	assert.True(t, p.ValidateUpdate(o, state))
+
+	// Deleted slices shouldn't be sent.
+	o.GetEndpoints().Metadata.DeletionTimestampNS = 6
+	assert.False(t, p.ValidateUpdate(o, state))
+}
+
+func TestEndpointSliceUpdateProcessor_GetUpdatesToSend(t *testing.T) {
+	storedProtos := []*k8smeta.StoredUpdate{
+		{
+			Update:        createEndpointSliceObject(),
+			UpdateVersion: 2,
+		},
+	}
+
+	state := &k8smeta.ProcessorState{
+		PodToIP: map[string]string{
+			"pl/another-pod": "127.0.0.2",
+			"pl/pod-name":    "127.0.0.1",
+		},
+		TruncatedEndpoints: map[string]string{
+			"a_namespace/object_md": "ijkl",
+		},
+	}
+	p := k8smeta.EndpointSliceUpdateProcessor{}
+	updates := p.GetUpdatesToSend(storedProtos, state)
+	assert.Equal(t, 3, len(updates))
+
+	// The updates should have the UID of the truncated endpoints and the name of the service.
+	assert.Contains(t, updates, &k8smeta.OutgoingUpdate{
+		Update: &metadatapb.ResourceUpdate{
+			UpdateVersion: 2,
+			Update: &metadatapb.ResourceUpdate_ServiceUpdate{
+				ServiceUpdate: &metadatapb.ServiceUpdate{
+					UID:       "ijkl",
+					Name:      "object_md",
+					Namespace: "a_namespace",
+					PodIDs:    []string{"efgh"},
+					PodNames:  []string{"another-pod"},
+				},
+			},
+		},
+		Topics: []string{"127.0.0.2"},
+	})
+
+	assert.Contains(t, updates, &k8smeta.OutgoingUpdate{
+		Update: &metadatapb.ResourceUpdate{
+			UpdateVersion: 2,
+			Update: &metadatapb.ResourceUpdate_ServiceUpdate{
+				ServiceUpdate: &metadatapb.ServiceUpdate{
+					UID:       "ijkl",
+					Name:      "object_md",
+					Namespace: "a_namespace",
+					PodIDs:    []string{"abcd", "efgh"},
+					PodNames:  []string{"pod-name", "another-pod"},
+				},
+			},
+		},
+		Topics: []string{k8smeta.KelvinUpdateTopic},
+	})
+}
+
 func TestServiceUpdateProcessor(t *testing.T) {
 	// Construct service object.
 	o := createServiceObject()
@@ -890,6 +1219,78 @@ func TestServiceUpdateProcessor_GetUpdatesToSend(t *testing.T) {
 	assert.Equal(t, updates[0], su)
 }
 
+func TestServiceUpdateProcessor_GetUpdatesToSend_Headless(t *testing.T) {
+	o := createServiceObject()
+	o.GetService().Spec.Type = metadatapb.CLUSTER_IP
+	o.GetService().Spec.ClusterIP = "None"
+
+	storedProtos := []*k8smeta.StoredUpdate{
+		{
+			Update:        o,
+			UpdateVersion: 2,
+		},
+	}
+
+	p := k8smeta.ServiceUpdateProcessor{}
+	updates := p.GetUpdatesToSend(storedProtos, &k8smeta.ProcessorState{})
+	require.Equal(t, 1, len(updates))
+	assert.Equal(t, "", updates[0].Update.GetServiceUpdate().ClusterIP)
+}
+
+func TestServiceUpdateProcessor_GetUpdatesToSend_ExternalName(t *testing.T) {
+	tests := []struct {
+		name         string
+		externalName string
+		expectedIPs  []string
+	}{
+		{
+			name:         "hostname",
+			externalName: "db.example.com",
+			expectedIPs:  []string{"10.0.0.1", "10.0.0.2"},
+		},
+		{
+			name:         "ip",
+			externalName: "10.0.0.3",
+			expectedIPs:  []string{"10.0.0.3"},
+		},
+		{
+			name:         "unresolved",
+			externalName: "unknown.example.com",
+			expectedIPs:  nil,
+		},
+	}
+
+	p := k8smeta.ServiceUpdateProcessor{
+		LookupHost: func(host string) ([]string, error) {
+			if host == "db.example.com" {
+				return []string{"10.0.0.1", "10.0.0.2"}, nil
+			}
+			return nil, errors.New("no such host")
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			o := createServiceObject()
+			o.GetService().Metadata.DeletionTimestampNS = 0
+			o.GetService().Spec.ClusterIP = ""
+			o.GetService().Spec.ExternalName = test.externalName
+
+			storedProtos := []*k8smeta.StoredUpdate{
+				{
+					Update:        o,
+					UpdateVersion: 2,
+				},
+			}
+
+			updates := p.GetUpdatesToSend(storedProtos, &k8smeta.ProcessorState{})
+			require.Equal(t, 1, len(updates))
+			assert.Equal(t, test.expectedIPs, updates[0].Update.GetServiceUpdate().EndpointIPs)
+			assert.Equal(t, []string{k8smeta.KelvinUpdateTopic}, updates[0].Topics)
+		})
+	}
+}
+
 func TestPodUpdateProcessor_SetDeleted(t *testing.T) {
 	// Construct pod object.
 	o := createPodObject(metadatapb.RUNNING)
@@ -1594,3 +1995,79 @@ func TestDeploymentUpdateProcessor_GetUpdatesToSend(t *testing.T) {
 	assert.Contains(t, updates[0].Topics, "127.0.0.1")
 	assert.Contains(t, updates[0].Topics, "127.0.0.2")
 }
+
+func TestJobUpdateProcessor(t *testing.T) {
+	// Construct job object.
+	o := createJobObject()
+
+	p := k8smeta.JobUpdateProcessor{}
+	p.SetDeleted(o)
+	assert.Equal(t, int64(6), o.GetJob().Metadata.DeletionTimestampNS)
+
+	o.GetJob().Metadata.DeletionTimestampNS = 0
+	p.SetDeleted(o)
+	assert.NotEqual(t, 0, o.GetJob().Metadata.DeletionTimestampNS)
+}
+
+func TestJobUpdateProcessor_ValidateUpdate(t *testing.T) {
+	// Construct job object.
+	o := createJobObject()
+
+	state := &k8smeta.ProcessorState{}
+	p := k8smeta.JobUpdateProcessor{}
+	assert.True(t, p.ValidateUpdate(o, state))
+	assert.False(t, p.ValidateUpdate(createDeploymentObject(), state))
+}
+
+func TestJobUpdateProcessor_GetUpdatesToSend(t *testing.T) {
+	// Construct job object.
+	expectedPb := &metadatapb.Job{}
+	if err := proto.UnmarshalText(testutils.JobPb, expectedPb); err != nil {
+		t.Fatal("Cannot Unmarshal protobuf.")
+	}
+
+	storedProtos := []*k8smeta.StoredUpdate{
+		{
+			Update: &storepb.K8SResource{
+				Resource: &storepb.K8SResource_Job{
+					Job: expectedPb,
+				},
+			},
+			UpdateVersion: 2,
+		},
+	}
+
+	state := &k8smeta.ProcessorState{NodeToIP: map[string]string{
+		"node-1": "127.0.0.1",
+		"node-2": "127.0.0.2",
+	}}
+
+	p := k8smeta.JobUpdateProcessor{}
+	updates := p.GetUpdatesToSend(storedProtos, state)
+	assert.Equal(t, 1, len(updates))
+
+	expectedUpdate := &metadatapb.ResourceUpdate{
+		UpdateVersion: 2,
+		Update: &metadatapb.ResourceUpdate_JobUpdate{
+			JobUpdate: &metadatapb.JobUpdate{
+				UID:              "ijkl",
+				Name:             "job_1",
+				StartTimestampNS: 4,
+				StopTimestampNS:  6,
+				Namespace:        "a_namespace",
+				OwnerReferences: []*metadatapb.OwnerReference{
+					{
+						UID:  "1234",
+						Name: "cronjob_1",
+						Kind: "CronJob",
+					},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, expectedUpdate, updates[0].Update)
+	assert.Contains(t, updates[0].Topics, k8smeta.KelvinUpdateTopic)
+	assert.Contains(t, updates[0].Topics, "127.0.0.1")
+	assert.Contains(t, updates[0].Topics, "127.0.0.2")
+}
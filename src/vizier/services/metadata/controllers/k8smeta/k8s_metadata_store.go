@@ -38,6 +38,7 @@ const (
 	topicVersionPrefix        = "/topicVersion"
 	labelPodUpdatePrefix      = "/labelPodUpdate" // labelPodUpdatePrefix/<namespace>/<labelKey>/<podName> -> <labelValue>
 	podLabelUpdatePrefix      = "/podLabelUpdate" // podLabelUpdatePrefix/<namespace>/<podName> -> [<labelKeys>]
+	ipAssignmentPrefix        = "/ipAssignment"   // ipAssignmentPrefix/<ip>/<startTimestampNS>/<uid> -> IPAssignment
 	// The topic for partial resource updates, which are not specific to a particular node.
 	unscopedTopic = "unscoped"
 )
@@ -79,6 +80,11 @@ func getNSPodUpdateKey(namespace string, podName string) string {
 	return path.Join(podLabelUpdatePrefix, namespace, podName)
 }
 
+// prefix/<ip>/<startTimestampNS>/<uid>
+func getIPAssignmentKey(ip string, startTimestampNS int64, uid string) string {
+	return path.Join(ipAssignmentPrefix, ip, fmt.Sprintf("%020d", startTimestampNS), uid)
+}
+
 func labelPodUpdateKeyToPodName(updateKey string) string {
 	keys := strings.Split(updateKey, "/")
 	return keys[len(keys)-1]
@@ -117,6 +123,44 @@ func (m *Datastore) AddFullResourceUpdate(updateVersion int64, resource *storepb
 	return m.ds.SetWithTTL(getFullResourceUpdateKey(updateVersion), string(val), resourceUpdateTTL)
 }
 
+// AddIPAssignment stores the assignment of an IP to a pod or service. Assignments that have stopped
+// are kept for ipAssignmentTTL.
+func (m *Datastore) AddIPAssignment(assignment *storepb.IPAssignment) error {
+	val, err := assignment.Marshal()
+	if err != nil {
+		return err
+	}
+
+	key := getIPAssignmentKey(assignment.IP, assignment.StartTimestampNS, assignment.UID)
+	if assignment.StopTimestampNS == 0 {
+		return m.ds.Set(key, string(val))
+	}
+	return m.ds.SetWithTTL(key, string(val), ipAssignmentTTL)
+}
+
+// FetchIPAssignments gets the assignments of the given IP, ordered by start time. If no IP is
+// specified, the assignments of all IPs are returned.
+func (m *Datastore) FetchIPAssignments(ip string) ([]*storepb.IPAssignment, error) {
+	prefix := ipAssignmentPrefix + "/"
+	if ip != "" {
+		prefix = path.Join(ipAssignmentPrefix, ip) + "/"
+	}
+	_, vals, err := m.ds.GetWithPrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	assignments := make([]*storepb.IPAssignment, 0, len(vals))
+	for _, val := range vals {
+		assignment := &storepb.IPAssignment{}
+		if err := proto.Unmarshal(val, assignment); err != nil {
+			continue
+		}
+		assignments = append(assignments, assignment)
+	}
+	return assignments, nil
+}
+
 // FetchResourceUpdates gets the resource updates from the `from` update version, to the `to`
 // update version (exclusive).
 func (m *Datastore) FetchResourceUpdates(topic string, from int64, to int64) ([]*storepb.K8SResourceUpdate, error) {
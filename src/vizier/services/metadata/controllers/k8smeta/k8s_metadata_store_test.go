@@ -217,6 +217,40 @@ func TestDatastore_AddResourceUpdate(t *testing.T) {
 	assert.Equal(t, update, savedResourceUpdatePb)
 }
 
+func TestDatastore_FetchIPAssignments(t *testing.T) {
+	_, mds, cleanup := setupMDSTest(t)
+	defer cleanup()
+
+	assignments := []*storepb.IPAssignment{
+		{IP: "10.0.0.1", UID: "efgh", Name: "pod2", StartTimestampNS: 6},
+		{IP: "10.0.0.1", UID: "abcd", Name: "pod1", StartTimestampNS: 4, StopTimestampNS: 6},
+		{IP: "10.0.0.10", UID: "ijkl", Name: "svc", Service: true, StartTimestampNS: 2},
+	}
+	for _, a := range assignments {
+		require.NoError(t, mds.AddIPAssignment(a))
+	}
+
+	// The prefix of one IP shouldn't match another IP.
+	fetched, err := mds.FetchIPAssignments("10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, []*storepb.IPAssignment{assignments[1], assignments[0]}, fetched)
+
+	fetched, err = mds.FetchIPAssignments("10.0.0.10")
+	require.NoError(t, err)
+	assert.Equal(t, []*storepb.IPAssignment{assignments[2]}, fetched)
+
+	fetched, err = mds.FetchIPAssignments("")
+	require.NoError(t, err)
+	assert.Equal(t, 3, len(fetched))
+
+	// Updating an assignment should replace it.
+	updated := &storepb.IPAssignment{IP: "10.0.0.1", UID: "efgh", Name: "pod2", StartTimestampNS: 6, StopTimestampNS: 8}
+	require.NoError(t, mds.AddIPAssignment(updated))
+	fetched, err = mds.FetchIPAssignments("10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, []*storepb.IPAssignment{assignments[1], updated}, fetched)
+}
+
 func TestDatastore_FetchResourceUpdates(t *testing.T) {
 	tests := []struct {
 		name                  string
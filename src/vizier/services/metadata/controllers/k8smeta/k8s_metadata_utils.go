@@ -24,7 +24,9 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	apps "k8s.io/api/apps/v1"
+	batch "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/informers"
@@ -173,6 +175,22 @@ func endpointsWatcher(resource string, namespaces []string, ch chan *K8sResource
 	return iw
 }
 
+func endpointSliceWatcher(resource string, namespaces []string, ch chan *K8sResourceMessage, clientset kubernetes.Interface) *informerWatcher {
+	iw := &informerWatcher{
+		convert: endpointSliceConverter,
+		objType: resource,
+		ch:      ch,
+	}
+
+	for _, ns := range namespaces {
+		factory := informers.NewSharedInformerFactoryWithOptions(clientset, 12*time.Hour, informers.WithNamespace(ns))
+		inf := factory.Discovery().V1().EndpointSlices().Informer()
+		iw.informers = append(iw.informers, inf)
+	}
+
+	return iw
+}
+
 func nodeWatcher(resource string, namespaces []string, ch chan *K8sResourceMessage, clientset kubernetes.Interface) *informerWatcher {
 	iw := &informerWatcher{
 		convert: nodeConverter,
@@ -224,6 +242,22 @@ func replicaSetWatcher(resource string, namespaces []string, ch chan *K8sResourc
 	return iw
 }
 
+func jobWatcher(resource string, namespaces []string, ch chan *K8sResourceMessage, clientset kubernetes.Interface) *informerWatcher {
+	iw := &informerWatcher{
+		convert: jobConverter,
+		objType: resource,
+		ch:      ch,
+	}
+
+	for _, ns := range namespaces {
+		factory := informers.NewSharedInformerFactoryWithOptions(clientset, 12*time.Hour, informers.WithNamespace(ns))
+		inf := factory.Batch().V1().Jobs().Informer()
+		iw.informers = append(iw.informers, inf)
+	}
+
+	return iw
+}
+
 func deploymentWatcher(resource string, namespaces []string, ch chan *K8sResourceMessage, clientset kubernetes.Interface) *informerWatcher {
 	iw := &informerWatcher{
 		convert: deploymentConverter,
@@ -300,6 +334,21 @@ func endpointsConverter(obj interface{}) *K8sResourceMessage {
 	}
 }
 
+func endpointSliceConverter(obj interface{}) *K8sResourceMessage {
+	o, ok := obj.(*discovery.EndpointSlice)
+	if !ok {
+		return nil
+	}
+
+	return &K8sResourceMessage{
+		Object: &storepb.K8SResource{
+			Resource: &storepb.K8SResource_Endpoints{
+				Endpoints: k8s.EndpointSliceToProto(o),
+			},
+		},
+	}
+}
+
 func nodeConverter(obj interface{}) *K8sResourceMessage {
 	o, ok := obj.(*v1.Node)
 	if !ok {
@@ -344,3 +393,18 @@ func deploymentConverter(obj interface{}) *K8sResourceMessage {
 		},
 	}
 }
+
+func jobConverter(obj interface{}) *K8sResourceMessage {
+	o, ok := obj.(*batch.Job)
+	if !ok {
+		return nil
+	}
+
+	return &K8sResourceMessage{
+		Object: &storepb.K8SResource{
+			Resource: &storepb.K8SResource_Job{
+				Job: k8s.JobToProto(o),
+			},
+		},
+	}
+}
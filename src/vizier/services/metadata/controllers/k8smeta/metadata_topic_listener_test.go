@@ -79,6 +79,14 @@ func (s *FakeStore) FetchFullResourceUpdates(from int64, to int64) ([]*storepb.K
 	return nil, nil
 }
 
+func (s *FakeStore) AddIPAssignment(assignment *storepb.IPAssignment) error {
+	return nil
+}
+
+func (s *FakeStore) FetchIPAssignments(ip string) ([]*storepb.IPAssignment, error) {
+	return nil, nil
+}
+
 func (s *FakeStore) SetPodLabels(namespace string, podName string, labels map[string]string) error {
 	return nil
 }
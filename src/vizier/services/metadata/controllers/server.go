@@ -24,6 +24,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -59,6 +60,7 @@ type Server struct {
 	env    metadataenv.MetadataEnv
 	ds     datastore.MultiGetterSetterDeleterCloser
 	pls    k8smeta.PodLabelStore
+	ips    k8smeta.IPAssignmentStore
 	agtMgr agent.Manager
 	tpMgr  *tracepoint.Manager
 	// The current cursor that is actively running the GetAgentsUpdate stream. Only one GetAgentsUpdate
@@ -68,11 +70,12 @@ type Server struct {
 }
 
 // NewServer creates GRPC handlers.
-func NewServer(env metadataenv.MetadataEnv, ds datastore.MultiGetterSetterDeleterCloser, pls k8smeta.PodLabelStore, agtMgr agent.Manager, tpMgr *tracepoint.Manager) *Server {
+func NewServer(env metadataenv.MetadataEnv, ds datastore.MultiGetterSetterDeleterCloser, pls k8smeta.PodLabelStore, ips k8smeta.IPAssignmentStore, agtMgr agent.Manager, tpMgr *tracepoint.Manager) *Server {
 	return &Server{
 		env:    env,
 		ds:     ds,
 		pls:    pls,
+		ips:    ips,
 		agtMgr: agtMgr,
 		tpMgr:  tpMgr,
 	}
@@ -167,7 +170,9 @@ func (s *Server) GetAgentInfo(ctx context.Context, req *metadatapb.AgentInfoRequ
 	for _, agt := range agents {
 		state := agentpb.AGENT_STATE_HEALTHY
 		timeSinceLastHb := currentTime.Sub(time.Unix(0, agt.LastHeartbeatNS))
-		if timeSinceLastHb > UnhealthyAgentThreshold {
+		if agt.Lameduck {
+			state = agentpb.AGENT_STATE_LAMEDUCK
+		} else if timeSinceLastHb > UnhealthyAgentThreshold {
 			state = agentpb.AGENT_STATE_UNRESPONSIVE
 		}
 
@@ -308,6 +313,42 @@ func (s *Server) GetAgentUpdates(req *metadatapb.AgentUpdatesRequest, srv metada
 	}
 }
 
+// GetIPMappingsAtTime returns the pods and services that had the requested IPs at the requested time.
+func (s *Server) GetIPMappingsAtTime(ctx context.Context, req *metadatapb.IPMappingsAtTimeRequest) (*metadatapb.IPMappingsAtTimeResponse, error) {
+	assignmentsByIP := make(map[string][]*storepb.IPAssignment)
+	if len(req.IPs) == 0 {
+		assignments, err := s.ips.FetchIPAssignments("")
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range assignments {
+			assignmentsByIP[a.IP] = append(assignmentsByIP[a.IP], a)
+		}
+	} else {
+		for _, ip := range req.IPs {
+			assignments, err := s.ips.FetchIPAssignments(ip)
+			if err != nil {
+				return nil, err
+			}
+			assignmentsByIP[ip] = assignments
+		}
+	}
+
+	ips := make([]string, 0, len(assignmentsByIP))
+	for ip := range assignmentsByIP {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+
+	resp := &metadatapb.IPMappingsAtTimeResponse{}
+	for _, ip := range ips {
+		if a := k8smeta.IPAssignmentAtTime(assignmentsByIP[ip], req.TimeNS); a != nil {
+			resp.Mappings = append(resp.Mappings, a)
+		}
+	}
+	return resp, nil
+}
+
 // GetWithPrefixKey fetches all the metadata KVs with the given prefix. This is used for debug purposes.
 func (s *Server) GetWithPrefixKey(ctx context.Context, req *metadatapb.WithPrefixKeyRequest) (*metadatapb.WithPrefixKeyResponse, error) {
 	prefix := req.Prefix
@@ -166,7 +166,7 @@ func TestGetAgentInfo(t *testing.T) {
 		t.Fatal("Failed to create api environment.")
 	}
 
-	s := controllers.NewServer(env, nil, nil, mockAgtMgr, nil)
+	s := controllers.NewServer(env, nil, nil, nil, mockAgtMgr, nil)
 
 	req := metadatapb.AgentInfoRequest{}
 
@@ -212,7 +212,7 @@ func TestGetAgentInfoGetActiveAgentsFailed(t *testing.T) {
 		t.Fatal("Failed to create api environment.")
 	}
 
-	s := controllers.NewServer(env, nil, nil, mockAgtMgr, nil)
+	s := controllers.NewServer(env, nil, nil, nil, mockAgtMgr, nil)
 
 	req := metadatapb.AgentInfoRequest{}
 
@@ -241,7 +241,7 @@ func TestGetSchemas(t *testing.T) {
 		t.Fatal("Failed to create api environment.")
 	}
 
-	s := controllers.NewServer(env, nil, nil, mockAgtMgr, nil)
+	s := controllers.NewServer(env, nil, nil, nil, mockAgtMgr, nil)
 
 	req := metadatapb.SchemaRequest{}
 
@@ -349,7 +349,7 @@ func Test_Server_RegisterTracepoint(t *testing.T) {
 		t.Fatal("Failed to create api environment.")
 	}
 
-	s := controllers.NewServer(env, nil, nil, mockAgtMgr, tracepointMgr)
+	s := controllers.NewServer(env, nil, nil, nil, mockAgtMgr, tracepointMgr)
 
 	reqs := []*metadatapb.RegisterTracepointRequest_TracepointRequest{
 		{
@@ -474,7 +474,7 @@ func Test_Server_RegisterTracepoint_Exists(t *testing.T) {
 		t.Fatal("Failed to create api environment.")
 	}
 
-	s := controllers.NewServer(env, nil, nil, mockAgtMgr, tracepointMgr)
+	s := controllers.NewServer(env, nil, nil, nil, mockAgtMgr, tracepointMgr)
 
 	reqs := []*metadatapb.RegisterTracepointRequest_TracepointRequest{
 		{
@@ -659,7 +659,7 @@ func Test_Server_GetTracepointInfo(t *testing.T) {
 				t.Fatal("Failed to create api environment.")
 			}
 
-			s := controllers.NewServer(env, nil, nil, mockAgtMgr, tracepointMgr)
+			s := controllers.NewServer(env, nil, nil, nil, mockAgtMgr, tracepointMgr)
 			req := metadatapb.GetTracepointInfoRequest{
 				IDs: []*uuidpb.UUID{utils.ProtoFromUUID(tID)},
 			}
@@ -717,7 +717,7 @@ func Test_Server_RemoveTracepoint(t *testing.T) {
 		t.Fatal("Failed to create api environment.")
 	}
 
-	s := controllers.NewServer(env, nil, nil, mockAgtMgr, tracepointMgr)
+	s := controllers.NewServer(env, nil, nil, nil, mockAgtMgr, tracepointMgr)
 
 	req := metadatapb.RemoveTracepointRequest{
 		Names: []string{"test1", "test2"},
@@ -903,7 +903,7 @@ func TestGetAgentUpdates(t *testing.T) {
 		t.Fatal("Failed to create api environment.")
 	}
 
-	srv := controllers.NewServer(mdEnv, nil, nil, mockAgtMgr, nil)
+	srv := controllers.NewServer(mdEnv, nil, nil, nil, mockAgtMgr, nil)
 
 	env := env.New("withpixie.ai")
 	s := server.CreateGRPCServer(env, &server.GRPCServerOptions{})
@@ -1064,7 +1064,7 @@ func Test_Server_UpdateConfig(t *testing.T) {
 		t.Fatal("Failed to create api environment.")
 	}
 
-	s := controllers.NewServer(env, nil, nil, mockAgtMgr, tracepointMgr)
+	s := controllers.NewServer(env, nil, nil, nil, mockAgtMgr, tracepointMgr)
 
 	req := metadatapb.UpdateConfigRequest{
 		AgentPodName: "pl/pem-1234",
@@ -1105,7 +1105,7 @@ func Test_Server_ConvertLabelsToPods(t *testing.T) {
 		t.Fatal("Failed to create api environment.")
 	}
 
-	s := controllers.NewServer(env, nil, pls, nil, nil)
+	s := controllers.NewServer(env, nil, pls, nil, nil, nil)
 
 	program := &logicalpb.TracepointDeployment{}
 	err = proto.UnmarshalText(testutils.TDLabelSelectorPb, program)
@@ -1121,3 +1121,36 @@ func Test_Server_ConvertLabelsToPods(t *testing.T) {
 
 	assert.True(t, proto.Equal(program, expected), fmt.Sprintf("expect: %s\nactual: %s", expected, program))
 }
+
+func Test_Server_GetIPMappingsAtTime(t *testing.T) {
+	ips := &testutils.InMemoryIPAssignmentStore{}
+	for _, a := range []*storepb.IPAssignment{
+		{IP: "10.0.0.1", UID: "abcd", Name: "pod1", Namespace: "ns", StartTimestampNS: 4, StopTimestampNS: 6},
+		{IP: "10.0.0.1", UID: "efgh", Name: "pod2", Namespace: "ns", StartTimestampNS: 6},
+		{IP: "10.0.0.2", UID: "ijkl", Name: "svc", Namespace: "ns", Service: true, StartTimestampNS: 2},
+		{IP: "10.0.0.3", UID: "mnop", Name: "pod3", Namespace: "ns", StartTimestampNS: 8},
+	} {
+		err := ips.AddIPAssignment(a)
+		require.NoError(t, err)
+	}
+
+	env, err := metadataenv.New("vizier")
+	if err != nil {
+		t.Fatal("Failed to create api environment.")
+	}
+
+	s := controllers.NewServer(env, nil, nil, ips, nil, nil)
+
+	resp, err := s.GetIPMappingsAtTime(context.Background(), &metadatapb.IPMappingsAtTimeRequest{
+		IPs:    []string{"10.0.0.1", "10.0.0.3", "10.0.0.4"},
+		TimeNS: 5,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []*storepb.IPAssignment{ips.Assignments[0]}, resp.Mappings)
+
+	resp, err = s.GetIPMappingsAtTime(context.Background(), &metadatapb.IPMappingsAtTimeRequest{
+		TimeNS: 10,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []*storepb.IPAssignment{ips.Assignments[1], ips.Assignments[2], ips.Assignments[3]}, resp.Mappings)
+}
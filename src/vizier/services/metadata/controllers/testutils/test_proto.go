@@ -897,6 +897,32 @@ status {
 }
 `
 
+// JobPb is a protobuf for a Job object owned by a CronJob.
+const JobPb = `
+metadata {
+	name: "job_1"
+	namespace: "a_namespace"
+	uid: "ijkl"
+	resource_version: "1"
+	creation_timestamp_ns: 4
+	deletion_timestamp_ns: 6
+	owner_references {
+		kind: "CronJob"
+		name: "cronjob_1"
+		uid: "1234"
+	}
+}
+spec {
+	parallelism: 1
+	completions: 1
+	backoff_limit: 6
+}
+status {
+	start_time_ns: 4
+	active: 1
+}
+`
+
 // TDLabelSelectorPb is a protobuf for a TracepointDeployment object with a LabelSelector.
 const TDLabelSelectorPb = `
 name: "test_probe"
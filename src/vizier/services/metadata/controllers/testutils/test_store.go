@@ -21,6 +21,8 @@ package testutils
 import (
 	"path"
 	"strings"
+
+	"px.dev/pixie/src/vizier/services/metadata/storepb"
 )
 
 // InMemoryPodLabelStore implements the PodLabelStore interface for testing.
@@ -97,3 +99,31 @@ func (s *InMemoryPodLabelStore) FetchPodsWithLabels(namespace string, labels map
 func (s *InMemoryPodLabelStore) GetWithPrefix(prefix string) ([]string, [][]byte, error) {
 	return nil, nil, nil
 }
+
+// InMemoryIPAssignmentStore implements the IPAssignmentStore interface for testing.
+type InMemoryIPAssignmentStore struct {
+	Assignments []*storepb.IPAssignment
+}
+
+// AddIPAssignment stores the assignment of an IP, replacing any assignment with the same IP, UID and start time.
+func (s *InMemoryIPAssignmentStore) AddIPAssignment(assignment *storepb.IPAssignment) error {
+	for i, a := range s.Assignments {
+		if a.IP == assignment.IP && a.UID == assignment.UID && a.StartTimestampNS == assignment.StartTimestampNS {
+			s.Assignments[i] = assignment
+			return nil
+		}
+	}
+	s.Assignments = append(s.Assignments, assignment)
+	return nil
+}
+
+// FetchIPAssignments gets the assignments of the given IP, or of all IPs if no IP is specified.
+func (s *InMemoryIPAssignmentStore) FetchIPAssignments(ip string) ([]*storepb.IPAssignment, error) {
+	var result []*storepb.IPAssignment
+	for _, a := range s.Assignments {
+		if ip == "" || a.IP == ip {
+			result = append(result, a)
+		}
+	}
+	return result, nil
+}
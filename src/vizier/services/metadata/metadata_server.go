@@ -21,14 +21,9 @@ package main
 import (
 	"context"
 	"crypto/tls"
-	"fmt"
 	"net/http"
-	"os"
-	"path/filepath"
-	"strings"
 	"time"
 
-	"github.com/cockroachdb/pebble"
 	"github.com/nats-io/nats.go"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
@@ -44,35 +39,24 @@ import (
 	"px.dev/pixie/src/shared/services/healthz"
 	"px.dev/pixie/src/shared/services/httpmiddleware"
 	"px.dev/pixie/src/shared/services/metrics"
+	"px.dev/pixie/src/shared/services/msgbus"
+	"px.dev/pixie/src/shared/services/selftest"
 	"px.dev/pixie/src/shared/services/server"
+	"px.dev/pixie/src/shared/status"
 	"px.dev/pixie/src/vizier/services/metadata/controllers"
-	"px.dev/pixie/src/vizier/services/metadata/controllers/agent"
-	"px.dev/pixie/src/vizier/services/metadata/controllers/cronscript"
-	"px.dev/pixie/src/vizier/services/metadata/controllers/k8smeta"
-	"px.dev/pixie/src/vizier/services/metadata/controllers/tracepoint"
 	"px.dev/pixie/src/vizier/services/metadata/metadataenv"
-	"px.dev/pixie/src/vizier/services/metadata/metadatapb"
+	"px.dev/pixie/src/vizier/services/metadata/metadataserver"
 	"px.dev/pixie/src/vizier/utils/datastore"
 	"px.dev/pixie/src/vizier/utils/datastore/etcd"
-	"px.dev/pixie/src/vizier/utils/datastore/pebbledb"
-)
-
-const (
-	// pebbledbTTLDuration represents how often we evict from pebble.
-	pebbledbTTLDuration = 1 * time.Minute
-	// pebbleOpenDir is where the files live in the directory.
-	pebbleOpenDir = "/metadata/pebble_20220209"
-	// metadataBaseMount is the base volume mount if we are running a PVC backed metadata.
-	metadataBaseMount = "/metadata"
 )
 
 func init() {
 	pflag.String("md_etcd_server", "https://pl-etcd-client.pl.svc:2379", "The address to metadata etcd server.")
+	pflag.Int64("md_etcd_quota_bytes", 2*1024*1024*1024, "The backend quota of the metadata etcd server, past which it rejects writes.")
 	pflag.String("cluster_id", "", "The Cluster ID to use for Pixie Cloud")
 	pflag.Duration("max_expected_clock_skew", 2000, "Duration in ms of expected maximum clock skew in a cluster")
 	pflag.Duration("renew_period", 5000, "Duration in ms of the time to wait to renew lease")
 	pflag.String("pod_namespace", "pl", "The namespace this pod runs in. Used for leader elections")
-	pflag.String("nats_url", "pl-nats", "The URL of NATS")
 	pflag.Bool("use_etcd_operator", false, "Whether the etcd operator should be used instead of the persistent version.")
 	pflag.StringSlice("metadata_namespaces", []string{v1.NamespaceAll}, "The list of namespaces to watch for metadata.")
 
@@ -84,7 +68,7 @@ func init() {
 	viper.BindEnv("use_etcd_operator", "PL_ETCD_OPERATOR_ENABLED")
 }
 
-func mustInitEtcdDatastore() (*etcd.DataStore, func()) {
+func mustInitEtcdDatastore() (*etcd.DataStore, *controllers.DatastoreManager, func()) {
 	log.Infof("Using etcd: %s for metadata", viper.GetString("md_etcd_server"))
 	var tlsConfig *tls.Config
 	if !viper.GetBool("disable_ssl") {
@@ -105,44 +89,12 @@ func mustInitEtcdDatastore() (*etcd.DataStore, func()) {
 		log.WithError(err).Fatalf("Failed to connect to etcd at %s. Please check status and logs for `pl-etcd` pods in the cluster.", viper.GetString("md_etcd_server"))
 	}
 
-	etcdMgr := controllers.NewEtcdManager(etcdClient)
-	etcdMgr.Run()
+	dataStore := etcd.New(etcdClient)
+	dsMgr := metadataserver.NewDatastoreManager(dataStore, "etcd", viper.GetInt64("md_etcd_quota_bytes"))
 	cleanupFunc := func() {
-		etcdMgr.Stop()
 		etcdClient.Close()
 	}
-	return etcd.New(etcdClient), cleanupFunc
-}
-
-func cleanupOldPebbleData() {
-	files, err := os.ReadDir(metadataBaseMount)
-	if err != nil {
-		log.WithError(err).Fatal("Failed to read the metadata dir. Is the PVC correctly provisioned and running?")
-	}
-	pebblePath := strings.TrimPrefix(pebbleOpenDir, fmt.Sprintf("%s/", metadataBaseMount))
-
-	for _, file := range files {
-		if file.IsDir() && strings.HasPrefix(file.Name(), pebblePath) {
-			// This is the current pebble dir, skip.
-			continue
-		}
-		// Not the current pebble dir, likely an older dir, so just remove it.
-		fullPath := filepath.Join(metadataBaseMount, file.Name())
-		err = os.RemoveAll(fullPath)
-		if err != nil {
-			log.WithError(err).Infof("Failed to cleanup path %s", fullPath)
-		}
-	}
-}
-
-func mustInitPebbleDatastore() *pebbledb.DataStore {
-	cleanupOldPebbleData()
-	log.Infof("Using pebbledb: %s for metadata", pebbleOpenDir)
-	pebbleDb, err := pebble.Open(pebbleOpenDir, &pebble.Options{})
-	if err != nil {
-		log.WithError(err).Fatal("Failed to open pebble database. If out of space, increase the storage size of the `metadata-pv-claim` PersistentVolumeClaim and restart the vizier-metadata pod")
-	}
-	return pebbledb.New(pebbleDb, pebbledbTTLDuration)
+	return dataStore, dsMgr, cleanupFunc
 }
 
 func etcdTLSConfig() (*tls.Config, error) {
@@ -171,16 +123,7 @@ func main() {
 		viper.GetString("pod_namespace"))
 	defer flush()
 
-	var nc *nats.Conn
-	var err error
-	if viper.GetBool("disable_ssl") {
-		nc, err = nats.Connect(viper.GetString("nats_url"))
-	} else {
-		nc, err = nats.Connect(viper.GetString("nats_url"),
-			nats.ClientCert(viper.GetString("client_tls_cert"), viper.GetString("client_tls_key")),
-			nats.RootCAs(viper.GetString("tls_ca_cert")))
-	}
-
+	nc, err := nats.Connect(viper.GetString("nats_url"), msgbus.ClientOptions()...)
 	if err != nil {
 		log.WithError(err).Fatal("Could not connect to NATS. Please check for the `pl-nats` pods in the namespace to confirm they are healthy and running.")
 	}
@@ -223,57 +166,19 @@ func main() {
 	}()
 
 	var dataStore datastore.MultiGetterSetterDeleterCloser
+	var dsMgr *controllers.DatastoreManager
 	var cleanupFunc func()
 	if viper.GetBool("use_etcd_operator") {
-		dataStore, cleanupFunc = mustInitEtcdDatastore()
+		dataStore, dsMgr, cleanupFunc = mustInitEtcdDatastore()
 		defer cleanupFunc()
 	} else {
-		dataStore = mustInitPebbleDatastore()
+		pebbleDataStore := metadataserver.MustInitPebbleDatastore()
+		dataStore = pebbleDataStore
+		dsMgr = metadataserver.NewDatastoreManager(pebbleDataStore, "pebble", metadataserver.PebbleQuotaBytes())
 	}
 	defer dataStore.Close()
-
-	k8sMds := k8smeta.NewDatastore(dataStore)
-	// Listen for K8s metadata updates.
-	updateCh := make(chan *k8smeta.K8sResourceMessage)
-	mdh := k8smeta.NewHandler(updateCh, k8sMds, k8sMds, nc)
-
-	namespaces := viper.GetStringSlice("metadata_namespaces")
-	k8sMc, err := k8smeta.NewController(namespaces, updateCh)
-	defer k8sMc.Stop()
-
-	ads := agent.NewDatastore(dataStore, 24*time.Hour)
-	agtMgr := agent.NewManager(ads, mdh, nc)
-
-	schemaQuitCh := make(chan struct{})
-	defer close(schemaQuitCh)
-	go func() {
-		schemaTimer := time.NewTicker(1 * time.Minute)
-		defer schemaTimer.Stop()
-		for {
-			select {
-			case <-schemaQuitCh:
-				return
-			case <-schemaTimer.C:
-				schemaErr := ads.PruneComputedSchema()
-				if schemaErr != nil {
-					log.WithError(schemaErr).Info("Failed to prune computed schema")
-				}
-			}
-		}
-	}()
-
-	tds := tracepoint.NewDatastore(dataStore)
-	// Initialize tracepoint handler.
-	tracepointMgr := tracepoint.NewManager(tds, agtMgr, 30*time.Second)
-	defer tracepointMgr.Close()
-
-	mc, err := controllers.NewMessageBusController(nc, agtMgr, tracepointMgr,
-		mdh, &isLeader)
-
-	if err != nil {
-		log.WithError(err).Fatal("Failed to connect to message bus")
-	}
-	defer mc.Close()
+	dsMgr.Run()
+	defer dsMgr.Stop()
 
 	// Set up server.
 	env, err := metadataenv.New("vizier")
@@ -283,11 +188,28 @@ func main() {
 	mux := http.NewServeMux()
 	healthz.RegisterDefaultChecks(mux)
 	metrics.MustRegisterMetricsHandlerNoDefaultMetrics(mux)
+	mux.Handle(status.DatastoreHealthPath, dsMgr)
 
-	svr := controllers.NewServer(env, dataStore, k8sMds, agtMgr, tracepointMgr)
+	selfTestChecker, err := selftest.DefaultChecker()
+	if err != nil {
+		log.WithError(err).Fatal("Failed to create self-test checker")
+	}
+	mux.Handle(selftest.Path, selftest.NewHandler("metadata", selfTestChecker, func() []selftest.Target {
+		targets := []selftest.Target{selftest.URLTarget("nats", viper.GetString("nats_url"), "4222")}
+		if viper.GetBool("use_etcd_operator") {
+			targets = append(targets, selftest.URLTarget("etcd", viper.GetString("md_etcd_server"), "2379"))
+		}
+		if t, ok := selftest.APIServerTarget(); ok {
+			targets = append(targets, t)
+		}
+		return targets
+	}))
 
-	csDs := cronscript.NewDatastore(dataStore)
-	cronScriptSvr := cronscript.New(csDs)
+	mdSvr, err := metadataserver.New(env, nc, dataStore, &isLeader)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to start metadata service")
+	}
+	defer mdSvr.Close()
 
 	log.Infof("Metadata Server: %s", version.GetVersion().ToString())
 
@@ -297,10 +219,7 @@ func main() {
 
 	s := server.NewPLServer(env,
 		httpmiddleware.WithBearerAuthMiddleware(env, mux), maxMsgSize)
-	metadatapb.RegisterMetadataServiceServer(s.GRPCServer(), svr)
-	metadatapb.RegisterMetadataTracepointServiceServer(s.GRPCServer(), svr)
-	metadatapb.RegisterMetadataConfigServiceServer(s.GRPCServer(), svr)
-	metadatapb.RegisterCronScriptStoreServiceServer(s.GRPCServer(), cronScriptSvr)
+	mdSvr.RegisterGRPC(s.GRPCServer())
 
 	s.Start()
 	s.StopOnInterrupt()
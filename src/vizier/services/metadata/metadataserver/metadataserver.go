@@ -0,0 +1,201 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package metadataserver runs the components of the metadata service, so that they can be
+// started either by the metadata service or by the consolidated control plane.
+package metadataserver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/nats-io/nats.go"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+
+	"px.dev/pixie/src/vizier/services/metadata/controllers"
+	"px.dev/pixie/src/vizier/services/metadata/controllers/agent"
+	"px.dev/pixie/src/vizier/services/metadata/controllers/cronscript"
+	"px.dev/pixie/src/vizier/services/metadata/controllers/k8smeta"
+	"px.dev/pixie/src/vizier/services/metadata/controllers/tracepoint"
+	"px.dev/pixie/src/vizier/services/metadata/metadataenv"
+	"px.dev/pixie/src/vizier/services/metadata/metadatapb"
+	"px.dev/pixie/src/vizier/utils/datastore"
+	"px.dev/pixie/src/vizier/utils/datastore/pebbledb"
+)
+
+const (
+	// pebbledbTTLDuration represents how often we evict from pebble.
+	pebbledbTTLDuration = 1 * time.Minute
+	// pebbleOpenDir is where the files live in the directory.
+	pebbleOpenDir = "/metadata/pebble_20220209"
+	// metadataBaseMount is the base volume mount if we are running a PVC backed metadata.
+	metadataBaseMount = "/metadata"
+)
+
+func init() {
+	pflag.Duration("datastore_compaction_interval", 1*time.Hour, "How often to compact the metadata datastore")
+	pflag.Float64("datastore_size_alarm_ratio", 0.8, "The fraction of the metadata datastore's quota to raise the size alarm at")
+}
+
+func cleanupOldPebbleData() {
+	files, err := os.ReadDir(metadataBaseMount)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to read the metadata dir. Is the PVC correctly provisioned and running?")
+	}
+	pebblePath := strings.TrimPrefix(pebbleOpenDir, fmt.Sprintf("%s/", metadataBaseMount))
+
+	for _, file := range files {
+		if file.IsDir() && strings.HasPrefix(file.Name(), pebblePath) {
+			// This is the current pebble dir, skip.
+			continue
+		}
+		// Not the current pebble dir, likely an older dir, so just remove it.
+		fullPath := filepath.Join(metadataBaseMount, file.Name())
+		err = os.RemoveAll(fullPath)
+		if err != nil {
+			log.WithError(err).Infof("Failed to cleanup path %s", fullPath)
+		}
+	}
+}
+
+// MustInitPebbleDatastore opens the pebble datastore on the metadata volume.
+func MustInitPebbleDatastore() *pebbledb.DataStore {
+	cleanupOldPebbleData()
+	log.Infof("Using pebbledb: %s for metadata", pebbleOpenDir)
+	pebbleDb, err := pebble.Open(pebbleOpenDir, &pebble.Options{})
+	if err != nil {
+		log.WithError(err).Fatal("Failed to open pebble database. If out of space, increase the storage size of the `metadata-pv-claim` PersistentVolumeClaim and restart the vizier-metadata pod")
+	}
+	return pebbledb.New(pebbleDb, pebbledbTTLDuration)
+}
+
+// NewDatastoreManager creates the manager that compacts the metadata datastore, and raises the size alarm once it
+// nears the given quota.
+func NewDatastoreManager(ds datastore.Maintainer, backend string, quotaBytes int64) *controllers.DatastoreManager {
+	return controllers.NewDatastoreManager(ds, controllers.DatastoreManagerOptions{
+		Backend:            backend,
+		QuotaBytes:         quotaBytes,
+		SizeAlarmRatio:     viper.GetFloat64("datastore_size_alarm_ratio"),
+		CompactionInterval: viper.GetDuration("datastore_compaction_interval"),
+	})
+}
+
+// PebbleQuotaBytes returns the size of the metadata volume, since pebble fails writes once the volume is full.
+// It returns 0 if the size is unknown.
+func PebbleQuotaBytes() int64 {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(metadataBaseMount, &fs); err != nil {
+		log.WithError(err).Warn("Failed to get the size of the metadata volume")
+		return 0
+	}
+	return int64(fs.Blocks) * int64(fs.Bsize)
+}
+
+// Server holds the running components of the metadata service.
+type Server struct {
+	svr           *controllers.Server
+	cronScriptSvr *cronscript.Server
+
+	k8sMc         *k8smeta.Controller
+	tracepointMgr *tracepoint.Manager
+	mc            *controllers.MessageBusController
+	schemaQuitCh  chan struct{}
+}
+
+// New starts the metadata service components on top of the given message bus and datastore.
+// Only the leader writes to the metadata store, as tracked by isLeader.
+func New(env metadataenv.MetadataEnv, nc *nats.Conn, dataStore datastore.MultiGetterSetterDeleterCloser, isLeader *bool) (*Server, error) {
+	k8sMds := k8smeta.NewDatastore(dataStore)
+	// Listen for K8s metadata updates.
+	updateCh := make(chan *k8smeta.K8sResourceMessage)
+	mdh := k8smeta.NewHandler(updateCh, k8sMds, k8sMds, nc)
+
+	namespaces := viper.GetStringSlice("metadata_namespaces")
+	k8sMc, err := k8smeta.NewController(namespaces, updateCh)
+	if err != nil {
+		return nil, err
+	}
+
+	ads := agent.NewDatastore(dataStore, 24*time.Hour)
+	agtMgr := agent.NewManager(ads, mdh, nc)
+
+	schemaQuitCh := make(chan struct{})
+	go func() {
+		schemaTimer := time.NewTicker(1 * time.Minute)
+		defer schemaTimer.Stop()
+		for {
+			select {
+			case <-schemaQuitCh:
+				return
+			case <-schemaTimer.C:
+				schemaErr := ads.PruneComputedSchema()
+				if schemaErr != nil {
+					log.WithError(schemaErr).Info("Failed to prune computed schema")
+				}
+			}
+		}
+	}()
+
+	tds := tracepoint.NewDatastore(dataStore)
+	// Initialize tracepoint handler.
+	tracepointMgr := tracepoint.NewManager(tds, agtMgr, 30*time.Second)
+
+	mc, err := controllers.NewMessageBusController(nc, agtMgr, tracepointMgr,
+		mdh, isLeader)
+	if err != nil {
+		close(schemaQuitCh)
+		tracepointMgr.Close()
+		k8sMc.Stop()
+		return nil, err
+	}
+
+	csDs := cronscript.NewDatastore(dataStore)
+
+	return &Server{
+		svr:           controllers.NewServer(env, dataStore, k8sMds, k8sMds, agtMgr, tracepointMgr),
+		cronScriptSvr: cronscript.New(csDs),
+		k8sMc:         k8sMc,
+		tracepointMgr: tracepointMgr,
+		mc:            mc,
+		schemaQuitCh:  schemaQuitCh,
+	}, nil
+}
+
+// RegisterGRPC registers the metadata GRPC services on the server.
+func (s *Server) RegisterGRPC(g *grpc.Server) {
+	metadatapb.RegisterMetadataServiceServer(g, s.svr)
+	metadatapb.RegisterMetadataTracepointServiceServer(g, s.svr)
+	metadatapb.RegisterMetadataConfigServiceServer(g, s.svr)
+	metadatapb.RegisterCronScriptStoreServiceServer(g, s.cronScriptSvr)
+}
+
+// Close stops the metadata service components.
+func (s *Server) Close() {
+	s.mc.Close()
+	s.tracepointMgr.Close()
+	close(s.schemaQuitCh)
+	s.k8sMc.Stop()
+}
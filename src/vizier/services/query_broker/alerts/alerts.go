@@ -0,0 +1,381 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package alerts
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/shared/scripts"
+)
+
+// Status is the state of an alert carried by an Event.
+type Status string
+
+// Alert statuses.
+const (
+	StatusFiring   Status = "firing"
+	StatusResolved Status = "resolved"
+)
+
+// Event is a notification about an alert instance changing state.
+type Event struct {
+	// Fingerprint uniquely identifies the alert instance (rule + group). It is stable across evaluations and
+	// can be used by receivers to deduplicate notifications.
+	Fingerprint string            `json:"fingerprint"`
+	RuleName    string            `json:"ruleName"`
+	Status      Status            `json:"status"`
+	Severity    string            `json:"severity,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Value       float64           `json:"value"`
+	Threshold   float64           `json:"threshold"`
+	Operator    string            `json:"operator"`
+	StartsAt    time.Time         `json:"startsAt"`
+	Timestamp   time.Time         `json:"timestamp"`
+}
+
+// Summary returns a single line, human readable description of the event.
+func (e *Event) Summary() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[%s] %s: value %v %s %v", strings.ToUpper(string(e.Status)), e.RuleName, e.Value, e.Operator, e.Threshold)
+	if len(e.Labels) > 0 {
+		keys := make([]string, 0, len(e.Labels))
+		for k := range e.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		pairs := make([]string, len(keys))
+		for i, k := range keys {
+			pairs[i] = fmt.Sprintf("%s=%s", k, e.Labels[k])
+		}
+		fmt.Fprintf(&sb, " (%s)", strings.Join(pairs, ", "))
+	}
+	return sb.String()
+}
+
+type compareFn func(v, threshold float64) bool
+
+var operators = map[string]compareFn{
+	">":  func(v, t float64) bool { return v > t },
+	">=": func(v, t float64) bool { return v >= t },
+	"<":  func(v, t float64) bool { return v < t },
+	"<=": func(v, t float64) bool { return v <= t },
+	"==": func(v, t float64) bool { return v == t },
+	"!=": func(v, t float64) bool { return v != t },
+}
+
+type rule struct {
+	cfg     *scripts.AlertRule
+	compare compareFn
+	forDur  time.Duration
+}
+
+// instance is the evaluation state of a single rule for a single group.
+type instance struct {
+	rule        *rule
+	activeSince time.Time
+	firing      bool
+	value       float64
+	labels      map[string]string
+}
+
+// observation is a breaching value for an instance seen during the current evaluation cycle.
+type observation struct {
+	rule   *rule
+	value  float64
+	labels map[string]string
+}
+
+// Evaluator evaluates alert rules against streamed script results and sends notifications when alert instances start
+// firing or resolve. Only state transitions generate notifications, so a condition that holds across many
+// evaluations results in a single firing event followed by a single resolved event.
+type Evaluator struct {
+	rules     []*rule
+	notifiers []Notifier
+
+	mu           sync.Mutex
+	tables       map[string]*vizierpb.QueryMetadata
+	instances    map[string]*instance
+	observations map[string]*observation
+}
+
+// NewEvaluator creates an evaluator for the given alert config.
+func NewEvaluator(cfg *scripts.AlertConfig, notifiers ...Notifier) (*Evaluator, error) {
+	if cfg == nil {
+		return nil, errors.New("alert config must not be nil")
+	}
+	e := &Evaluator{
+		notifiers:    notifiers,
+		tables:       make(map[string]*vizierpb.QueryMetadata),
+		instances:    make(map[string]*instance),
+		observations: make(map[string]*observation),
+	}
+	names := make(map[string]bool)
+	for _, r := range cfg.Rules {
+		if r.Name == "" || r.Table == "" || r.Column == "" {
+			return nil, errors.New("alert rules must specify a name, table and column")
+		}
+		if names[r.Name] {
+			return nil, fmt.Errorf("duplicate alert rule name '%s'", r.Name)
+		}
+		names[r.Name] = true
+		op := r.Operator
+		if op == "" {
+			op = ">"
+		}
+		compare, ok := operators[op]
+		if !ok {
+			return nil, fmt.Errorf("alert rule '%s' has invalid operator '%s'", r.Name, r.Operator)
+		}
+		var forDur time.Duration
+		if r.For != "" {
+			d, err := time.ParseDuration(r.For)
+			if err != nil {
+				return nil, fmt.Errorf("alert rule '%s' has invalid duration: %w", r.Name, err)
+			}
+			forDur = d
+		}
+		rc := *r
+		rc.Operator = op
+		e.rules = append(e.rules, &rule{cfg: &rc, compare: compare, forDur: forDur})
+	}
+	return e, nil
+}
+
+// NewEvaluatorFromConfig creates an evaluator along with the notifiers specified in the config. Any extra notifiers
+// are notified in addition to the configured ones.
+func NewEvaluatorFromConfig(cfg *scripts.AlertConfig, extra ...Notifier) (*Evaluator, error) {
+	if cfg == nil {
+		return nil, errors.New("alert config must not be nil")
+	}
+	notifiers := make([]Notifier, 0, len(cfg.Notifiers)+len(extra))
+	for _, nc := range cfg.Notifiers {
+		n, err := NewNotifier(nc)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, n)
+	}
+	notifiers = append(notifiers, extra...)
+	return NewEvaluator(cfg, notifiers...)
+}
+
+// ObserveTable registers the metadata of an output table so that subsequent batches can be resolved by name.
+func (e *Evaluator) ObserveTable(md *vizierpb.QueryMetadata) {
+	if md == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.tables[md.ID] = md
+}
+
+// ObserveBatch records the values in the batch that breach any of the rules for the batch's table.
+func (e *Evaluator) ObserveBatch(b *vizierpb.RowBatchData) {
+	if b == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	md, ok := e.tables[b.TableID]
+	if !ok || md.Relation == nil {
+		return
+	}
+	colIdx := make(map[string]int)
+	for i, c := range md.Relation.Columns {
+		colIdx[c.ColumnName] = i
+	}
+
+	for _, r := range e.rules {
+		if r.cfg.Table != md.Name {
+			continue
+		}
+		valIdx, ok := colIdx[r.cfg.Column]
+		if !ok || valIdx >= len(b.Cols) {
+			log.Warnf("Alert rule '%s' references unknown column '%s'", r.cfg.Name, r.cfg.Column)
+			continue
+		}
+		for row := int64(0); row < b.NumRows; row++ {
+			v, ok := numericValue(b.Cols[valIdx], row)
+			if !ok || !r.compare(v, r.cfg.Threshold) {
+				continue
+			}
+			labels := make(map[string]string, len(r.cfg.Labels)+len(r.cfg.GroupBy))
+			for k, v := range r.cfg.Labels {
+				labels[k] = v
+			}
+			for _, g := range r.cfg.GroupBy {
+				idx, ok := colIdx[g]
+				if !ok || idx >= len(b.Cols) {
+					continue
+				}
+				labels[g] = stringValue(b.Cols[idx], row)
+			}
+			fp := fingerprint(r.cfg.Name, r.cfg.GroupBy, labels)
+			if _, seen := e.observations[fp]; !seen {
+				e.observations[fp] = &observation{rule: r, value: v, labels: labels}
+			}
+		}
+	}
+}
+
+// Evaluate closes the current evaluation cycle, updates the state of every alert instance and sends notifications
+// for any instances that started firing or resolved. The returned events are the ones that were sent.
+func (e *Evaluator) Evaluate(ctx context.Context, now time.Time) []*Event {
+	e.mu.Lock()
+	var events []*Event
+	for fp, obs := range e.observations {
+		inst, ok := e.instances[fp]
+		if !ok {
+			inst = &instance{rule: obs.rule, activeSince: now}
+			e.instances[fp] = inst
+		}
+		inst.value = obs.value
+		inst.labels = obs.labels
+	}
+
+	for fp, inst := range e.instances {
+		r := inst.rule
+		if _, breaching := e.observations[fp]; !breaching {
+			if inst.firing {
+				events = append(events, newEvent(fp, r, inst, StatusResolved, now))
+			}
+			delete(e.instances, fp)
+			continue
+		}
+		if !inst.firing && now.Sub(inst.activeSince) >= r.forDur {
+			inst.firing = true
+			events = append(events, newEvent(fp, r, inst, StatusFiring, now))
+		}
+	}
+	e.observations = make(map[string]*observation)
+	e.tables = make(map[string]*vizierpb.QueryMetadata)
+	e.mu.Unlock()
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Fingerprint < events[j].Fingerprint
+	})
+	for _, ev := range events {
+		for _, n := range e.notifiers {
+			if err := n.Notify(ctx, ev); err != nil {
+				log.WithError(err).WithField("rule", ev.RuleName).Error("Failed to send alert notification")
+			}
+		}
+	}
+	return events
+}
+
+// Reset drops any partially observed results without changing alert state. It should be called when a script
+// execution fails, so that a failed run neither fires nor resolves alerts.
+func (e *Evaluator) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.observations = make(map[string]*observation)
+	e.tables = make(map[string]*vizierpb.QueryMetadata)
+}
+
+func newEvent(fp string, r *rule, inst *instance, status Status, now time.Time) *Event {
+	return &Event{
+		Fingerprint: fp,
+		RuleName:    r.cfg.Name,
+		Status:      status,
+		Severity:    r.cfg.Severity,
+		Labels:      inst.labels,
+		Value:       inst.value,
+		Threshold:   r.cfg.Threshold,
+		Operator:    r.cfg.Operator,
+		StartsAt:    inst.activeSince,
+		Timestamp:   now,
+	}
+}
+
+func fingerprint(ruleName string, groupBy []string, labels map[string]string) string {
+	h := sha256.New()
+	h.Write([]byte(ruleName))
+	for _, g := range groupBy {
+		h.Write([]byte{0})
+		h.Write([]byte(g))
+		h.Write([]byte{0})
+		h.Write([]byte(labels[g]))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+func numericValue(col *vizierpb.Column, row int64) (float64, bool) {
+	switch c := col.ColData.(type) {
+	case *vizierpb.Column_Int64Data:
+		if row < int64(len(c.Int64Data.Data)) {
+			return float64(c.Int64Data.Data[row]), true
+		}
+	case *vizierpb.Column_Float64Data:
+		if row < int64(len(c.Float64Data.Data)) {
+			return c.Float64Data.Data[row], true
+		}
+	case *vizierpb.Column_Time64NsData:
+		if row < int64(len(c.Time64NsData.Data)) {
+			return float64(c.Time64NsData.Data[row]), true
+		}
+	case *vizierpb.Column_BooleanData:
+		if row < int64(len(c.BooleanData.Data)) {
+			if c.BooleanData.Data[row] {
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	return 0, false
+}
+
+func stringValue(col *vizierpb.Column, row int64) string {
+	switch c := col.ColData.(type) {
+	case *vizierpb.Column_StringData:
+		if row < int64(len(c.StringData.Data)) {
+			return string(c.StringData.Data[row])
+		}
+	case *vizierpb.Column_Int64Data:
+		if row < int64(len(c.Int64Data.Data)) {
+			return strconv.FormatInt(c.Int64Data.Data[row], 10)
+		}
+	case *vizierpb.Column_Float64Data:
+		if row < int64(len(c.Float64Data.Data)) {
+			return strconv.FormatFloat(c.Float64Data.Data[row], 'g', -1, 64)
+		}
+	case *vizierpb.Column_BooleanData:
+		if row < int64(len(c.BooleanData.Data)) {
+			return strconv.FormatBool(c.BooleanData.Data[row])
+		}
+	case *vizierpb.Column_Uint128Data:
+		if row < int64(len(c.Uint128Data.Data)) {
+			v := c.Uint128Data.Data[row]
+			return fmt.Sprintf("%016x%016x", v.High, v.Low)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,201 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package alerts_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/shared/scripts"
+	"px.dev/pixie/src/vizier/services/query_broker/alerts"
+)
+
+type fakeNotifier struct {
+	events []*alerts.Event
+}
+
+func (f *fakeNotifier) Notify(ctx context.Context, e *alerts.Event) error {
+	f.events = append(f.events, e)
+	return nil
+}
+
+var testMetadata = &vizierpb.QueryMetadata{
+	ID:   "table1",
+	Name: "errors",
+	Relation: &vizierpb.Relation{
+		Columns: []*vizierpb.Relation_ColumnInfo{
+			{ColumnName: "service", ColumnType: vizierpb.STRING},
+			{ColumnName: "error_rate", ColumnType: vizierpb.FLOAT64},
+		},
+	},
+}
+
+func makeBatch(services []string, rates []float64) *vizierpb.RowBatchData {
+	svcs := make([][]byte, len(services))
+	for i, s := range services {
+		svcs[i] = []byte(s)
+	}
+	return &vizierpb.RowBatchData{
+		TableID: "table1",
+		NumRows: int64(len(services)),
+		Cols: []*vizierpb.Column{
+			{ColData: &vizierpb.Column_StringData{StringData: &vizierpb.StringColumn{Data: svcs}}},
+			{ColData: &vizierpb.Column_Float64Data{Float64Data: &vizierpb.Float64Column{Data: rates}}},
+		},
+	}
+}
+
+func testConfig() *scripts.AlertConfig {
+	return &scripts.AlertConfig{
+		Rules: []*scripts.AlertRule{
+			{
+				Name:      "high_error_rate",
+				Table:     "errors",
+				Column:    "error_rate",
+				GroupBy:   []string{"service"},
+				Operator:  ">",
+				Threshold: 0.5,
+				For:       "1m",
+				Severity:  "critical",
+			},
+		},
+	}
+}
+
+func TestEvaluator_FiresAfterForDurationAndResolves(t *testing.T) {
+	n := &fakeNotifier{}
+	e, err := alerts.NewEvaluator(testConfig(), n)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	start := time.Unix(1000, 0)
+
+	// Breaching, but not for long enough.
+	e.ObserveTable(testMetadata)
+	e.ObserveBatch(makeBatch([]string{"a", "b"}, []float64{0.9, 0.1}))
+	assert.Empty(t, e.Evaluate(ctx, start))
+
+	// Still breaching after the for duration, should fire once.
+	e.ObserveTable(testMetadata)
+	e.ObserveBatch(makeBatch([]string{"a", "b"}, []float64{0.8, 0.1}))
+	events := e.Evaluate(ctx, start.Add(time.Minute))
+	require.Len(t, events, 1)
+	assert.Equal(t, alerts.StatusFiring, events[0].Status)
+	assert.Equal(t, "high_error_rate", events[0].RuleName)
+	assert.Equal(t, map[string]string{"service": "a"}, events[0].Labels)
+	assert.Equal(t, 0.8, events[0].Value)
+	assert.Equal(t, start, events[0].StartsAt)
+
+	// Continued breach is deduplicated.
+	e.ObserveTable(testMetadata)
+	e.ObserveBatch(makeBatch([]string{"a"}, []float64{0.7}))
+	assert.Empty(t, e.Evaluate(ctx, start.Add(2*time.Minute)))
+
+	// Recovery sends a resolve event with the same fingerprint.
+	e.ObserveTable(testMetadata)
+	e.ObserveBatch(makeBatch([]string{"a"}, []float64{0.2}))
+	resolved := e.Evaluate(ctx, start.Add(3*time.Minute))
+	require.Len(t, resolved, 1)
+	assert.Equal(t, alerts.StatusResolved, resolved[0].Status)
+	assert.Equal(t, events[0].Fingerprint, resolved[0].Fingerprint)
+
+	assert.Len(t, n.events, 2)
+}
+
+func TestEvaluator_ResetKeepsState(t *testing.T) {
+	cfg := testConfig()
+	cfg.Rules[0].For = ""
+	e, err := alerts.NewEvaluator(cfg)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	now := time.Unix(1000, 0)
+	e.ObserveTable(testMetadata)
+	e.ObserveBatch(makeBatch([]string{"a"}, []float64{0.9}))
+	require.Len(t, e.Evaluate(ctx, now), 1)
+
+	// A failed run should not resolve the alert.
+	e.ObserveTable(testMetadata)
+	e.Reset()
+	e.ObserveTable(testMetadata)
+	e.ObserveBatch(makeBatch([]string{"a"}, []float64{0.9}))
+	assert.Empty(t, e.Evaluate(ctx, now.Add(time.Minute)))
+}
+
+func TestNewEvaluator_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		rule *scripts.AlertRule
+	}{
+		{
+			name: "missing column",
+			rule: &scripts.AlertRule{Name: "a", Table: "t"},
+		},
+		{
+			name: "bad operator",
+			rule: &scripts.AlertRule{Name: "a", Table: "t", Column: "c", Operator: "=>"},
+		},
+		{
+			name: "bad duration",
+			rule: &scripts.AlertRule{Name: "a", Table: "t", Column: "c", For: "five minutes"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := alerts.NewEvaluator(&scripts.AlertConfig{Rules: []*scripts.AlertRule{tc.rule}})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestPagerDutyNotifier(t *testing.T) {
+	var mu sync.Mutex
+	var received []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		mu.Lock()
+		received = append(received, body)
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	n, err := alerts.NewNotifier(&scripts.NotifierConfig{Type: "pagerduty", URL: srv.URL, RoutingKey: "key"})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, n.Notify(ctx, &alerts.Event{Fingerprint: "abc", RuleName: "r", Status: alerts.StatusFiring}))
+	require.NoError(t, n.Notify(ctx, &alerts.Event{Fingerprint: "abc", RuleName: "r", Status: alerts.StatusResolved}))
+
+	require.Len(t, received, 2)
+	assert.Equal(t, "trigger", received[0]["event_action"])
+	assert.Equal(t, "resolve", received[1]["event_action"])
+	assert.Equal(t, "abc", received[1]["dedup_key"])
+	assert.Equal(t, "key", received[0]["routing_key"])
+}
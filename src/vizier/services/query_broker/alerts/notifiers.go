@@ -0,0 +1,116 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"px.dev/pixie/src/shared/alerting"
+	"px.dev/pixie/src/shared/scripts"
+)
+
+const notifyTimeout = 10 * time.Second
+
+// Notifier sends alert events to an external system.
+type Notifier interface {
+	Notify(ctx context.Context, e *Event) error
+}
+
+// NewNotifier creates the notifier described by the config.
+func NewNotifier(cfg *scripts.NotifierConfig) (Notifier, error) {
+	client := &http.Client{Timeout: notifyTimeout}
+	switch cfg.Type {
+	case "webhook":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("webhook notifier requires a url")
+		}
+		return &WebhookNotifier{URL: cfg.URL, Headers: cfg.Headers, Client: client}, nil
+	case "slack":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("slack notifier requires a url")
+		}
+		return &SlackNotifier{WebhookURL: cfg.URL, Client: client}, nil
+	case "pagerduty":
+		if cfg.RoutingKey == "" {
+			return nil, fmt.Errorf("pagerduty notifier requires a routingKey")
+		}
+		url := cfg.URL
+		if url == "" {
+			url = alerting.DefaultPagerDutyURL
+		}
+		return &PagerDutyNotifier{URL: url, RoutingKey: cfg.RoutingKey, Client: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown notifier type '%s'", cfg.Type)
+	}
+}
+
+// WebhookNotifier posts the JSON encoded event to an arbitrary URL.
+type WebhookNotifier struct {
+	URL     string
+	Headers map[string]string
+	Client  *http.Client
+}
+
+// Notify sends the event to the webhook.
+func (w *WebhookNotifier) Notify(ctx context.Context, e *Event) error {
+	return alerting.PostJSON(ctx, w.Client, w.URL, w.Headers, e)
+}
+
+// SlackNotifier posts events to a Slack incoming webhook.
+type SlackNotifier struct {
+	WebhookURL string
+	Client     *http.Client
+}
+
+// Notify sends the event to Slack.
+func (s *SlackNotifier) Notify(ctx context.Context, e *Event) error {
+	icon := ":rotating_light:"
+	if e.Status == StatusResolved {
+		icon = ":white_check_mark:"
+	}
+	return alerting.PostJSON(ctx, s.Client, s.WebhookURL, nil, map[string]string{
+		"text": fmt.Sprintf("%s %s", icon, e.Summary()),
+	})
+}
+
+// PagerDutyNotifier sends events to the PagerDuty Events API v2. The event fingerprint is used as the dedup key, so
+// that the resolve event closes the incident opened by the firing event.
+type PagerDutyNotifier struct {
+	URL        string
+	RoutingKey string
+	Client     *http.Client
+}
+
+// Notify sends the event to PagerDuty.
+func (p *PagerDutyNotifier) Notify(ctx context.Context, e *Event) error {
+	var payload *alerting.PagerDutyPayload
+	if e.Status != StatusResolved {
+		payload = &alerting.PagerDutyPayload{
+			Summary:       e.Summary(),
+			Source:        "pixie",
+			Severity:      alerting.PagerDutySeverity(e.Severity),
+			Timestamp:     e.Timestamp.Format(time.RFC3339),
+			CustomDetails: e.Labels,
+		}
+	}
+	return alerting.PostJSON(ctx, p.Client, p.URL, nil, alerting.NewPagerDutyEvent(p.RoutingKey, e.Fingerprint, payload))
+}
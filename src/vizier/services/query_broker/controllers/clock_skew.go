@@ -0,0 +1,65 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/spf13/pflag"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/carnot/planner/distributedpb"
+	"px.dev/pixie/src/utils"
+)
+
+func init() {
+	pflag.Duration("clock_skew_threshold", 100*time.Millisecond, "Queries report the clock offsets of their PEMs when the clocks of the PEMs are further apart than this. Disabled if this is 0.")
+}
+
+// clockSkewStats returns the skew between the clocks of the PEMs in the distributed state, so that clients can
+// tell when durations across nodes are unreliable and correct the timestamps. It returns nil if the skew doesn't
+// exceed the threshold.
+func clockSkewStats(ds *distributedpb.DistributedState, offsets map[uuid.UUID]time.Duration, threshold time.Duration) *vizierpb.ClockSkewStats {
+	var minOffset, maxOffset time.Duration
+	agentOffsets := make(map[string]int64)
+	for _, carnotInfo := range ds.CarnotInfo {
+		agentID := utils.UUIDFromProtoOrNil(carnotInfo.AgentID)
+		offset, ok := offsets[agentID]
+		if !ok {
+			continue
+		}
+		if len(agentOffsets) == 0 || offset < minOffset {
+			minOffset = offset
+		}
+		if len(agentOffsets) == 0 || offset > maxOffset {
+			maxOffset = offset
+		}
+		agentOffsets[agentID.String()] = offset.Nanoseconds()
+	}
+
+	skew := maxOffset - minOffset
+	if skew <= threshold {
+		return nil
+	}
+	return &vizierpb.ClockSkewStats{
+		MaxSkewNs:           skew.Nanoseconds(),
+		AgentClockOffsetsNs: agentOffsets,
+	}
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/mock/gomock"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/carnot/planner/distributedpb"
+	"px.dev/pixie/src/carnot/planner/plannerpb"
+	"px.dev/pixie/src/utils"
+	"px.dev/pixie/src/utils/testingutils"
+	"px.dev/pixie/src/vizier/services/query_broker/controllers"
+	mock_controllers "px.dev/pixie/src/vizier/services/query_broker/controllers/mock"
+	"px.dev/pixie/src/vizier/services/query_broker/tracker"
+)
+
+const skewedAgentID = "61285cdd-1de9-4ab1-ae6a-0ba08c8c676c"
+
+// clockOffsetsAgentsInfo reports fixed clock offsets for the agents.
+type clockOffsetsAgentsInfo struct {
+	tracker.AgentsInfo
+	offsets map[uuid.UUID]time.Duration
+}
+
+func (a *clockOffsetsAgentsInfo) ClockOffsets() map[uuid.UUID]time.Duration {
+	return a.offsets
+}
+
+func TestQueryExecutor_ClockSkew(t *testing.T) {
+	tests := []struct {
+		name              string
+		threshold         time.Duration
+		expectedClockSkew *vizierpb.ClockSkewStats
+	}{
+		{
+			name:      "skew above threshold",
+			threshold: 100 * time.Millisecond,
+			expectedClockSkew: &vizierpb.ClockSkewStats{
+				MaxSkewNs: (550 * time.Millisecond).Nanoseconds(),
+				AgentClockOffsetsNs: map[string]int64{
+					"21285cdd-1de9-4ab1-ae6a-0ba08c8c676c": (-50 * time.Millisecond).Nanoseconds(),
+					skewedAgentID:                          (500 * time.Millisecond).Nanoseconds(),
+				},
+			},
+		},
+		{
+			name:              "skew within threshold",
+			threshold:         time.Second,
+			expectedClockSkew: nil,
+		},
+		{
+			name:              "disabled",
+			threshold:         0,
+			expectedClockSkew: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			viper.Set("clock_skew_threshold", test.threshold)
+			defer viper.Set("clock_skew_threshold", 0)
+
+			nc, cleanup := testingutils.MustStartTestNATS(t)
+			defer cleanup()
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			plannerState := buildPlannerState(t, singleAgentDistributedState)
+			skewed := proto.Clone(plannerState.DistributedState.CarnotInfo[0]).(*distributedpb.CarnotInfo)
+			skewedID := uuid.FromStringOrNil(skewedAgentID)
+			skewed.AgentID = utils.ProtoFromUUID(skewedID)
+			skewed.QueryBrokerAddress = skewedAgentID
+			plannerState.DistributedState.CarnotInfo = append(plannerState.DistributedState.CarnotInfo, skewed)
+
+			at := &fakeAgentsTracker{
+				agentsInfo: &clockOffsetsAgentsInfo{
+					AgentsInfo: tracker.NewTestAgentsInfo(plannerState.DistributedState),
+					offsets: map[uuid.UUID]time.Duration{
+						uuid.FromStringOrNil("21285cdd-1de9-4ab1-ae6a-0ba08c8c676c"): -50 * time.Millisecond,
+						skewedID: 500 * time.Millisecond,
+					},
+				},
+			}
+
+			stats := &vizierpb.ExecuteScriptResponse{
+				Result: &vizierpb.ExecuteScriptResponse_Data{
+					Data: &vizierpb.QueryData{
+						ExecutionStats: &vizierpb.QueryExecutionStats{
+							Timing: &vizierpb.QueryTimingInfo{},
+						},
+					},
+				},
+			}
+			rf := &fakeResultForwarder{
+				ClientResultsToSend: []*vizierpb.ExecuteScriptResponse{stats},
+			}
+
+			planner := mock_controllers.NewMockPlanner(ctrl)
+			planner.EXPECT().
+				Plan(gomock.Any()).
+				DoAndReturn(func(req *plannerpb.QueryRequest) (*distributedpb.LogicalPlannerResult, error) {
+					return buildPlannerResult(t, expectedPlannerResult), nil
+				})
+
+			queryExec := controllers.NewQueryExecutor("qb_address", "qb_hostname", at, &fakeDataPrivacy{}, nc, nil, nil, rf, planner, nil, nil)
+			consumer := newTestConsumer(nil)
+			require.NoError(t, queryExec.Run(context.Background(), &vizierpb.ExecuteScriptRequest{QueryStr: testQuery}, consumer))
+			require.NoError(t, queryExec.Wait())
+
+			var clockSkew *vizierpb.ClockSkewStats
+			for _, result := range consumer.results {
+				if es := result.GetData().GetExecutionStats(); es != nil {
+					clockSkew = es.ClockSkew
+				}
+			}
+			assert.Equal(t, test.expectedClockSkew, clockSkew)
+		})
+	}
+}
@@ -0,0 +1,204 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/gofrs/uuid"
+	"gopkg.in/yaml.v2"
+
+	"px.dev/pixie/src/carnot/planner/distributedpb"
+	"px.dev/pixie/src/shared/services/jwtpb"
+	"px.dev/pixie/src/shared/services/rbac"
+)
+
+// ColumnRedactionAction is how a column covered by a column policy is redacted.
+type ColumnRedactionAction string
+
+const (
+	// ColumnRedactionHide replaces the column's values with "REDACTED".
+	ColumnRedactionHide ColumnRedactionAction = "hide"
+	// ColumnRedactionHash replaces the column's values with a salted hash, so that they can still
+	// be grouped and joined on. Columns that aren't strings are hidden instead.
+	ColumnRedactionHash ColumnRedactionAction = "hash"
+)
+
+// ColumnRule redacts a single column of a table.
+type ColumnRule struct {
+	Table  string                `yaml:"table"`
+	Column string                `yaml:"column"`
+	Action ColumnRedactionAction `yaml:"action"`
+}
+
+// ColumnPolicy redacts columns for the users that have one of the roles in the cluster, and for
+// the API keys and tokens that have one of the scopes. A policy without any roles or scopes
+// applies to every query.
+type ColumnPolicy struct {
+	Name    string       `yaml:"name"`
+	Roles   []string     `yaml:"roles"`
+	Scopes  []string     `yaml:"scopes"`
+	Columns []ColumnRule `yaml:"columns"`
+}
+
+// ColumnPolicies are the column-level access controls of a Vizier. They are enforced by the query
+// compiler, so the redacted data never leaves the agents.
+type ColumnPolicies struct {
+	Policies []ColumnPolicy `yaml:"policies"`
+
+	clusterID uuid.UUID
+	hashSalt  string
+}
+
+// ParseColumnPolicies parses and validates the YAML column policies of the given cluster.
+func ParseColumnPolicies(b []byte, clusterID uuid.UUID) (*ColumnPolicies, error) {
+	p := &ColumnPolicies{clusterID: clusterID}
+	if err := yaml.UnmarshalStrict(b, p); err != nil {
+		return nil, fmt.Errorf("invalid column policies: %w", err)
+	}
+	for i, policy := range p.Policies {
+		name := policy.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+		for _, r := range policy.Roles {
+			if _, err := rbac.ParseRole(r); err != nil {
+				return nil, fmt.Errorf("column policy %s: %w", name, err)
+			}
+		}
+		if len(policy.Columns) == 0 {
+			return nil, fmt.Errorf("column policy %s: no columns", name)
+		}
+		for j, col := range policy.Columns {
+			if col.Table == "" || col.Column == "" {
+				return nil, fmt.Errorf("column policy %s: table and column are required", name)
+			}
+			switch col.Action {
+			case "":
+				p.Policies[i].Columns[j].Action = ColumnRedactionHide
+			case ColumnRedactionHide, ColumnRedactionHash:
+			default:
+				return nil, fmt.Errorf("column policy %s: invalid action '%s' for %s.%s", name, col.Action, col.Table, col.Column)
+			}
+		}
+	}
+	return p, nil
+}
+
+// LoadColumnPolicies loads the column policies from the file at the path. It returns nil if the
+// path is empty or the file doesn't exist, since the policies are optional.
+func LoadColumnPolicies(path string, clusterID uuid.UUID, signingKey string) (*ColumnPolicies, error) {
+	if path == "" {
+		return nil, nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	p, err := ParseColumnPolicies(b, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	p.SetHashSalt(HashSaltFromSigningKey(signingKey))
+	return p, nil
+}
+
+// HashSaltFromSigningKey derives the salt for hashed columns from the Vizier's JWT signing key,
+// which is secret and stays the same across query broker restarts.
+func HashSaltFromSigningKey(signingKey string) string {
+	h := sha256.Sum256([]byte("column-policy-hash-salt:" + signingKey))
+	return hex.EncodeToString(h[:])
+}
+
+// SetHashSalt sets the salt used for hashed columns.
+func (p *ColumnPolicies) SetHashSalt(salt string) {
+	p.hashSalt = salt
+}
+
+func (policy *ColumnPolicy) appliesTo(claims *jwtpb.JWTClaims, role rbac.Role) bool {
+	if len(policy.Roles) == 0 && len(policy.Scopes) == 0 {
+		return true
+	}
+	for _, r := range policy.Roles {
+		if parsed, _ := rbac.ParseRole(r); parsed == role {
+			return true
+		}
+	}
+	if claims == nil {
+		return false
+	}
+	for _, want := range policy.Scopes {
+		for _, have := range claims.Scopes {
+			if want == have {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ColumnRedactions returns the columns that are redacted for queries run with the claims. If more
+// than one policy covers a column, the column is hidden unless all of them hash it.
+func (p *ColumnPolicies) ColumnRedactions(claims *jwtpb.JWTClaims) []*distributedpb.RedactionOptions_ColumnRedaction {
+	if p == nil {
+		return nil
+	}
+	role := rbac.BindingsForClaims(claims).RoleForCluster(p.clusterID)
+
+	type tableColumn struct{ table, column string }
+	actions := make(map[tableColumn]ColumnRedactionAction)
+	var order []tableColumn
+	for i := range p.Policies {
+		policy := &p.Policies[i]
+		if !policy.appliesTo(claims, role) {
+			continue
+		}
+		for _, col := range policy.Columns {
+			k := tableColumn{col.Table, col.Column}
+			prev, ok := actions[k]
+			if !ok {
+				order = append(order, k)
+			}
+			if !ok || prev == ColumnRedactionHash {
+				actions[k] = col.Action
+			}
+		}
+	}
+
+	var redactions []*distributedpb.RedactionOptions_ColumnRedaction
+	for _, k := range order {
+		action := distributedpb.REDACTION_HIDE
+		if actions[k] == ColumnRedactionHash {
+			action = distributedpb.REDACTION_HASH
+		}
+		redactions = append(redactions, &distributedpb.RedactionOptions_ColumnRedaction{
+			TableName:  k.table,
+			ColumnName: k.column,
+			Action:     action,
+		})
+	}
+	return redactions
+}
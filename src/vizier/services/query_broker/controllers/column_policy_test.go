@@ -0,0 +1,179 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/carnot/planner/distributedpb"
+	"px.dev/pixie/src/shared/services/authcontext"
+	"px.dev/pixie/src/shared/services/jwtpb"
+	"px.dev/pixie/src/shared/services/rbac"
+	"px.dev/pixie/src/shared/services/utils"
+	"px.dev/pixie/src/vizier/services/query_broker/controllers"
+)
+
+const testColumnPolicies = `
+policies:
+- name: everyone
+  columns:
+  - table: http_events
+    column: req_body
+    action: hash
+- name: viewers
+  roles: [viewer]
+  columns:
+  - table: http_events
+    column: req_body
+  - table: http_events
+    column: remote_addr
+    action: hash
+- name: audit-keys
+  scopes: ["audit"]
+  columns:
+  - table: mysql_events
+    column: req_body
+`
+
+func userClaims(scopes ...string) *jwtpb.JWTClaims {
+	claims := utils.GenerateJWTForUser("user", "org", "user@example.com", time.Now().Add(time.Hour), "withpixie.ai")
+	claims.Scopes = append(claims.Scopes, scopes...)
+	return claims
+}
+
+func redactionNames(redactions []*distributedpb.RedactionOptions_ColumnRedaction) map[string]distributedpb.RedactionOptions_ColumnRedaction_Action {
+	names := make(map[string]distributedpb.RedactionOptions_ColumnRedaction_Action)
+	for _, r := range redactions {
+		names[r.TableName+"."+r.ColumnName] = r.Action
+	}
+	return names
+}
+
+func TestColumnPolicies_ColumnRedactions(t *testing.T) {
+	clusterID := uuid.Must(uuid.NewV4())
+	p, err := controllers.ParseColumnPolicies([]byte(testColumnPolicies), clusterID)
+	require.NoError(t, err)
+
+	// Admins only get the policy that applies to everyone.
+	assert.Equal(t, map[string]distributedpb.RedactionOptions_ColumnRedaction_Action{
+		"http_events.req_body": distributedpb.REDACTION_HASH,
+	}, redactionNames(p.ColumnRedactions(userClaims(rbac.RoleAdmin.Scope()))))
+
+	// Viewers of the cluster get the stricter action for the req_body.
+	assert.Equal(t, map[string]distributedpb.RedactionOptions_ColumnRedaction_Action{
+		"http_events.req_body":    distributedpb.REDACTION_HIDE,
+		"http_events.remote_addr": distributedpb.REDACTION_HASH,
+	}, redactionNames(p.ColumnRedactions(userClaims(rbac.RoleViewer.ClusterScope(clusterID)))))
+
+	// A viewer of another cluster isn't a viewer of this one.
+	otherCluster := uuid.Must(uuid.NewV4())
+	assert.Equal(t, map[string]distributedpb.RedactionOptions_ColumnRedaction_Action{
+		"http_events.req_body": distributedpb.REDACTION_HASH,
+	}, redactionNames(p.ColumnRedactions(userClaims(rbac.RoleEditor.Scope(), rbac.RoleViewer.ClusterScope(otherCluster)))))
+
+	// Policies can also select on the scopes of the token.
+	assert.Equal(t, map[string]distributedpb.RedactionOptions_ColumnRedaction_Action{
+		"http_events.req_body":  distributedpb.REDACTION_HASH,
+		"mysql_events.req_body": distributedpb.REDACTION_HIDE,
+	}, redactionNames(p.ColumnRedactions(userClaims(rbac.RoleAdmin.Scope(), "audit"))))
+
+	// Queries without claims still get the policies that apply to everyone.
+	assert.Equal(t, map[string]distributedpb.RedactionOptions_ColumnRedaction_Action{
+		"http_events.req_body": distributedpb.REDACTION_HASH,
+	}, redactionNames(p.ColumnRedactions(nil)))
+
+	var nilPolicies *controllers.ColumnPolicies
+	assert.Nil(t, nilPolicies.ColumnRedactions(userClaims()))
+}
+
+func TestParseColumnPolicies_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		policies string
+	}{
+		{
+			name:     "unknown role",
+			policies: "policies: [{roles: [owner], columns: [{table: a, column: b}]}]",
+		},
+		{
+			name:     "unknown action",
+			policies: "policies: [{columns: [{table: a, column: b, action: encrypt}]}]",
+		},
+		{
+			name:     "missing column",
+			policies: "policies: [{columns: [{table: a}]}]",
+		},
+		{
+			name:     "no columns",
+			policies: "policies: [{roles: [viewer]}]",
+		},
+		{
+			name:     "unknown field",
+			policies: "policies: [{role: viewer, columns: [{table: a, column: b}]}]",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := controllers.ParseColumnPolicies([]byte(test.policies), uuid.Nil)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoadColumnPolicies_MissingFile(t *testing.T) {
+	p, err := controllers.LoadColumnPolicies(t.TempDir()+"/policies.yaml", uuid.Nil, "key")
+	require.NoError(t, err)
+	assert.Nil(t, p)
+}
+
+func TestColumnPolicies_ProxiedClusterToken(t *testing.T) {
+	clusterID := uuid.Must(uuid.NewV4())
+	p, err := controllers.ParseColumnPolicies([]byte(testColumnPolicies), clusterID)
+	require.NoError(t, err)
+
+	// authenticate parses the token that the cloud signs for requests that it proxies to the Vizier, the same way
+	// that the Vizier's services do.
+	authenticate := func(claims *jwtpb.JWTClaims) *jwtpb.JWTClaims {
+		token, err := utils.SignJWTClaims(claims, "signing-key")
+		require.NoError(t, err)
+		aCtx := authcontext.New()
+		require.NoError(t, aCtx.UseJWTAuth("signing-key", token, "vizier"))
+		require.True(t, aCtx.ValidClaims())
+		return aCtx.Claims
+	}
+
+	// The roles and scopes of the user that the cloud proxies the query for apply.
+	claims := authenticate(utils.GenerateJWTForClusterUser(userClaims(rbac.RoleViewer.ClusterScope(clusterID), "audit"), "vizier"))
+	assert.Equal(t, map[string]distributedpb.RedactionOptions_ColumnRedaction_Action{
+		"http_events.req_body":    distributedpb.REDACTION_HIDE,
+		"http_events.remote_addr": distributedpb.REDACTION_HASH,
+		"mysql_events.req_body":   distributedpb.REDACTION_HIDE,
+	}, redactionNames(p.ColumnRedactions(claims)))
+
+	// Requests made for the cluster itself, rather than a user, aren't subject to roles.
+	claims = authenticate(utils.GenerateJWTForCluster(clusterID.String(), "vizier"))
+	assert.Equal(t, map[string]distributedpb.RedactionOptions_ColumnRedaction_Action{
+		"http_events.req_body": distributedpb.REDACTION_HASH,
+	}, redactionNames(p.ColumnRedactions(claims)))
+}
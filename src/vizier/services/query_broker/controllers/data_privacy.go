@@ -22,31 +22,51 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/gofrs/uuid"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 
 	"px.dev/pixie/src/carnot/planner/distributedpb"
+	"px.dev/pixie/src/shared/services/authcontext"
+	"px.dev/pixie/src/shared/services/jwtpb"
 
 	pixie "px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
 )
 
 func init() {
 	pflag.String("data_access", "Full", "The data access level for queries. Options are 'Full' or 'Restricted' or 'PIIRestricted")
+	pflag.String("column_policy_path", "", "The path to the YAML column policies, which redact columns per role or scope. Ignored if the file doesn't exist.")
 }
 
 type vizierCachedDataPrivacy struct {
-	dataAccess pixie.DataAccessLevel
+	dataAccess     pixie.DataAccessLevel
+	columnPolicies *ColumnPolicies
 }
 
-// RedactionOptions returns the proto message containing options for redaction based on the cached data privacy level.
+// RedactionOptions returns the proto message containing options for redaction based on the cached data privacy level,
+// and the column policies that apply to the caller.
 func (dp *vizierCachedDataPrivacy) RedactionOptions(ctx context.Context) (*distributedpb.RedactionOptions, error) {
-	if dp.dataAccess == pixie.DataAccessFull {
+	var columnRedactions []*distributedpb.RedactionOptions_ColumnRedaction
+	if dp.columnPolicies != nil {
+		// Queries without auth still get the policies that apply to everyone.
+		var claims *jwtpb.JWTClaims
+		if aCtx, err := authcontext.FromContext(ctx); err == nil {
+			claims = aCtx.Claims
+		}
+		columnRedactions = dp.columnPolicies.ColumnRedactions(claims)
+	}
+	if dp.dataAccess == pixie.DataAccessFull && len(columnRedactions) == 0 {
 		return nil, nil
 	}
-	return &distributedpb.RedactionOptions{
+	opts := &distributedpb.RedactionOptions{
 		UseFullRedaction:         dp.dataAccess == pixie.DataAccessRestricted,
 		UsePxRedactPiiBestEffort: dp.dataAccess == pixie.DataAccessPIIRestricted,
-	}, nil
+		ColumnRedactions:         columnRedactions,
+	}
+	if len(columnRedactions) > 0 {
+		opts.HashSalt = dp.columnPolicies.hashSalt
+	}
+	return opts, nil
 }
 
 // CreateDataPrivacyManager creates a privacy manager for the namespace.
@@ -55,8 +75,14 @@ func CreateDataPrivacyManager(ns string) (DataPrivacy, error) {
 	dataAccess := pixie.DataAccessLevel(dataAccessStr)
 	switch dataAccess {
 	case pixie.DataAccessFull, pixie.DataAccessRestricted, pixie.DataAccessPIIRestricted:
-		return &vizierCachedDataPrivacy{dataAccess}, nil
 	default:
 		return nil, fmt.Errorf("Invalid DataAccess: '%s'", dataAccessStr)
 	}
+
+	clusterID := uuid.FromStringOrNil(viper.GetString("cluster_id"))
+	policies, err := LoadColumnPolicies(viper.GetString("column_policy_path"), clusterID, viper.GetString("jwt_signing_key"))
+	if err != nil {
+		return nil, err
+	}
+	return &vizierCachedDataPrivacy{dataAccess: dataAccess, columnPolicies: policies}, nil
 }
@@ -0,0 +1,102 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+)
+
+func init() {
+	pflag.Duration("e2e_encryption_key_max_lifetime", 0, "How long an E2E encryption key may be used to start new scripts. Keys never expire if this is 0.")
+}
+
+// expiredKeyRetention is how many lifetimes a key is remembered for, so that expired keys can't be reused right away.
+const expiredKeyRetention = 10
+
+// EncryptionKeyPolicy enforces a maximum lifetime on the keys that clients ask results to be encrypted with.
+// The lifetime of a key starts when it is first used. Once a key expires, new scripts that use it are rejected
+// with ErrEncryptionKeyExpired, which tells the client to rotate its key and retry. Resumed queries keep
+// the key they started with, so rotating never breaks a result stream that is already in flight.
+type EncryptionKeyPolicy struct {
+	maxLifetime time.Duration
+
+	mu        sync.Mutex
+	firstUsed map[string]time.Time
+}
+
+// NewEncryptionKeyPolicy creates a policy that expires keys after the given lifetime.
+func NewEncryptionKeyPolicy(maxLifetime time.Duration) *EncryptionKeyPolicy {
+	return &EncryptionKeyPolicy{
+		maxLifetime: maxLifetime,
+		firstUsed:   make(map[string]time.Time),
+	}
+}
+
+// newEncryptionKeyPolicyFromFlags creates the policy from the flags, or returns nil if keys never expire.
+func newEncryptionKeyPolicyFromFlags() *EncryptionKeyPolicy {
+	maxLifetime := viper.GetDuration("e2e_encryption_key_max_lifetime")
+	if maxLifetime <= 0 {
+		return nil
+	}
+	return NewEncryptionKeyPolicy(maxLifetime)
+}
+
+// Check returns an error if the request starts a new script with a key that has outlived its lifetime.
+func (p *EncryptionKeyPolicy) Check(req *vizierpb.ExecuteScriptRequest, now time.Time) error {
+	if p == nil || req.EncryptionOptions == nil || req.QueryID != "" {
+		return nil
+	}
+	sum := sha256.Sum256([]byte(req.EncryptionOptions.JwkKey))
+	id := hex.EncodeToString(sum[:])
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pruneLocked(now)
+
+	firstUsed, ok := p.firstUsed[id]
+	if !ok {
+		p.firstUsed[id] = now
+		return nil
+	}
+	if now.Sub(firstUsed) >= p.maxLifetime {
+		return status.Error(codes.FailedPrecondition,
+			fmt.Sprintf("%s: the key was first used %s ago and may be used for at most %s", ErrEncryptionKeyExpired,
+				now.Sub(firstUsed).Round(time.Second), p.maxLifetime))
+	}
+	return nil
+}
+
+func (p *EncryptionKeyPolicy) pruneLocked(now time.Time) {
+	for id, firstUsed := range p.firstUsed {
+		if now.Sub(firstUsed) >= expiredKeyRetention*p.maxLifetime {
+			delete(p.firstUsed, id)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/vizier/services/query_broker/controllers"
+)
+
+func encryptedRequest(key string) *vizierpb.ExecuteScriptRequest {
+	return &vizierpb.ExecuteScriptRequest{
+		QueryStr:          "import px",
+		EncryptionOptions: &vizierpb.ExecuteScriptRequest_EncryptionOptions{JwkKey: key},
+	}
+}
+
+func TestEncryptionKeyPolicy_ExpiresKeys(t *testing.T) {
+	p := controllers.NewEncryptionKeyPolicy(time.Minute)
+	now := time.Unix(1000, 0)
+
+	require.NoError(t, p.Check(encryptedRequest("a"), now))
+	require.NoError(t, p.Check(encryptedRequest("a"), now.Add(59*time.Second)))
+
+	err := p.Check(encryptedRequest("a"), now.Add(time.Minute))
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.True(t, strings.HasPrefix(status.Convert(err).Message(), controllers.ErrEncryptionKeyExpired.Error()))
+
+	// A rotated key starts its own lifetime.
+	require.NoError(t, p.Check(encryptedRequest("b"), now.Add(time.Minute)))
+}
+
+func TestEncryptionKeyPolicy_ResumedQueriesKeepTheirKey(t *testing.T) {
+	p := controllers.NewEncryptionKeyPolicy(time.Minute)
+	now := time.Unix(1000, 0)
+
+	require.NoError(t, p.Check(encryptedRequest("a"), now))
+
+	resume := encryptedRequest("a")
+	resume.QueryID = "00000000-0000-0000-0000-000000000001"
+	assert.NoError(t, p.Check(resume, now.Add(time.Hour)))
+}
+
+func TestEncryptionKeyPolicy_Disabled(t *testing.T) {
+	var p *controllers.EncryptionKeyPolicy
+	assert.NoError(t, p.Check(encryptedRequest("a"), time.Unix(1000, 0)))
+
+	p = controllers.NewEncryptionKeyPolicy(time.Minute)
+	assert.NoError(t, p.Check(&vizierpb.ExecuteScriptRequest{QueryStr: "import px"}, time.Unix(1000, 0)))
+}
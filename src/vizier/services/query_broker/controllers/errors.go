@@ -29,4 +29,10 @@ var (
 	ErrTracepointPending = errors.New("tracepoints are still pending")
 	// ErrConfigUpdateFailed failed to send the config update request to an agent.
 	ErrConfigUpdateFailed = errors.New("failed to update config")
+	// ErrQueryExecTimeExceeded query ran for longer than its maximum execution time.
+	ErrQueryExecTimeExceeded = errors.New("query exceeded its maximum execution time")
+	// ErrQueryPreempted background query was cancelled to make room for an interactive query.
+	ErrQueryPreempted = errors.New("query was preempted")
+	// ErrEncryptionKeyExpired the result encryption key has outlived its maximum lifetime and must be rotated.
+	ErrEncryptionKeyExpired = errors.New("encryption key expired")
 )
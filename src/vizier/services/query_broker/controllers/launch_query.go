@@ -19,12 +19,17 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"golang.org/x/sync/errgroup"
 
 	"github.com/gofrs/uuid"
 	"github.com/nats-io/nats.go"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
 
 	"px.dev/pixie/src/carnot/planpb"
 	"px.dev/pixie/src/utils"
@@ -32,6 +37,14 @@ import (
 	"px.dev/pixie/src/vizier/utils/messagebus"
 )
 
+// The time to wait before retrying to send a plan to an agent. This doubles after every attempt.
+const launchQueryRetryBackoff = 50 * time.Millisecond
+
+func init() {
+	pflag.Int("query_launch_max_concurrency", 0, "The maximum number of agents that query plans are sent to at once. Unlimited if this is 0.")
+	pflag.Int("query_launch_retries", 2, "The number of times to retry sending a query plan to an agent.")
+}
+
 // LaunchQuery launches a query by sending query fragments to relevant agents.
 func LaunchQuery(queryID uuid.UUID, natsConn *nats.Conn, planMap map[uuid.UUID]*planpb.Plan, analyze bool) error {
 	if len(planMap) == 0 {
@@ -59,17 +72,33 @@ func LaunchQuery(queryID uuid.UUID, natsConn *nats.Conn, planMap map[uuid.UUID]*
 			return err
 		}
 
-		err = natsConn.Publish(agentTopic, msgAsBytes)
-		if err != nil {
-			return err
+		retries := viper.GetInt("query_launch_retries")
+		backoff := launchQueryRetryBackoff
+		for attempt := 0; ; attempt++ {
+			err = natsConn.Publish(agentTopic, msgAsBytes)
+			// Retrying can't help if the connection is closed or the plan is too large to send.
+			if err == nil || attempt >= retries || errors.Is(err, nats.ErrConnectionClosed) || errors.Is(err, nats.ErrMaxPayload) {
+				return err
+			}
+			log.WithError(err).Infof("Failed to send query %s to agent %s, retrying", queryID.String(), agentID.String())
+			time.Sleep(backoff)
+			backoff *= 2
 		}
-		return nil
 	}
 
+	// Limit how many agents the plans are sent to at once, so that large clusters don't flood the message bus.
+	var sem chan struct{}
+	if limit := viper.GetInt("query_launch_max_concurrency"); limit > 0 {
+		sem = make(chan struct{}, limit)
+	}
 	for agentID, logicalPlan := range planMap {
 		agentID := agentID
 		logicalPlan := logicalPlan
 		eg.Go(func() error {
+			if sem != nil {
+				sem <- struct{}{}
+				defer func() { <-sem }()
+			}
 			return broadcastToAgent(agentID, logicalPlan)
 		})
 	}
@@ -21,6 +21,7 @@ package controllers
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/gofrs/uuid"
 	"github.com/gogo/protobuf/types"
@@ -28,6 +29,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"px.dev/pixie/src/api/go/pxapi"
 	"px.dev/pixie/src/api/proto/vizierpb"
 	"px.dev/pixie/src/carnot/carnotpb"
 	"px.dev/pixie/src/carnot/planner/compilerpb"
@@ -273,6 +275,19 @@ func QueryResultStatsToVizierStats(e *queryresultspb.QueryExecutionStats, compil
 		},
 		BytesProcessed:   e.BytesProcessed,
 		RecordsProcessed: e.RecordsProcessed,
+		DataLoss:         dataLossToVizierDataLoss(e.DataLoss),
+	}
+}
+
+// dataLossToVizierDataLoss returns nil if no data was lost, so that the data loss is only sent
+// when it affected the results.
+func dataLossToVizierDataLoss(d *queryresultspb.DataLossStats) *vizierpb.DataLossStats {
+	if d == nil || (d.PerfBufferLostEvents == 0 && len(d.TablesWithEvictedData) == 0) {
+		return nil
+	}
+	return &vizierpb.DataLossStats{
+		PerfBufferLostEvents:  d.PerfBufferLostEvents,
+		TablesWithEvictedData: d.TablesWithEvictedData,
 	}
 }
 
@@ -492,6 +507,91 @@ func QueryPlanRelationResponse(queryID uuid.UUID, planTableID string) *vizierpb.
 	}
 }
 
+var queryExecStatsColumns = []*vizierpb.Relation_ColumnInfo{
+	{ColumnName: "agent_id", ColumnType: vizierpb.STRING, ColumnDesc: "The agent that executed the operator"},
+	{ColumnName: "node_id", ColumnType: vizierpb.INT64, ColumnDesc: "The ID of the operator in the agent's plan"},
+	{ColumnName: "operator", ColumnType: vizierpb.STRING, ColumnDesc: "The type of the operator"},
+	{ColumnName: "self_time_ns", ColumnType: vizierpb.INT64, ColumnDesc: "Time spent in the operator itself", ColumnSemanticType: vizierpb.ST_DURATION_NS},
+	{ColumnName: "total_time_ns", ColumnType: vizierpb.INT64, ColumnDesc: "Time spent in the operator and its children", ColumnSemanticType: vizierpb.ST_DURATION_NS},
+	{ColumnName: "bytes_output", ColumnType: vizierpb.INT64, ColumnDesc: "Bytes output by the operator", ColumnSemanticType: vizierpb.ST_BYTES},
+	{ColumnName: "records_output", ColumnType: vizierpb.INT64, ColumnDesc: "Records output by the operator"},
+}
+
+// QueryExecStatsRelationResponse returns the relation response for the per-operator execution stats table.
+func QueryExecStatsRelationResponse(queryID uuid.UUID, statsTableID string) *vizierpb.ExecuteScriptResponse {
+	return &vizierpb.ExecuteScriptResponse{
+		QueryID: queryID.String(),
+		Result: &vizierpb.ExecuteScriptResponse_MetaData{
+			MetaData: &vizierpb.QueryMetadata{
+				Name: pxapi.QueryExecStatsTableName,
+				ID:   statsTableID,
+				Relation: &vizierpb.Relation{
+					Columns: queryExecStatsColumns,
+				},
+			},
+		},
+	}
+}
+
+// QueryExecStatsResponse returns a single batch containing one row per operator per agent, with the execution
+// stats reported by that agent.
+func QueryExecStatsResponse(queryID uuid.UUID, planMap map[uuid.UUID]*planpb.Plan,
+	agentStats *[]*queryresultspb.AgentExecutionStats, statsTableID string) *vizierpb.ExecuteScriptResponse {
+	agentIDs := &vizierpb.StringColumn{}
+	nodeIDs := &vizierpb.Int64Column{}
+	operators := &vizierpb.StringColumn{}
+	selfTimes := &vizierpb.Int64Column{}
+	totalTimes := &vizierpb.Int64Column{}
+	bytesOutput := &vizierpb.Int64Column{}
+	recordsOutput := &vizierpb.Int64Column{}
+
+	if agentStats != nil {
+		for _, as := range *agentStats {
+			agentID := utils.UUIDFromProtoOrNil(as.AgentID)
+			opTypes := make(map[int64]string)
+			if plan, ok := planMap[agentID]; ok && plan != nil {
+				for _, fragment := range plan.Nodes {
+					for _, node := range fragment.Nodes {
+						opTypes[int64(node.Id)] = strings.ToLower(node.Op.OpType.String())
+					}
+				}
+			}
+			for _, os := range as.OperatorExecutionStats {
+				agentIDs.Data = append(agentIDs.Data, []byte(agentID.String()))
+				nodeIDs.Data = append(nodeIDs.Data, os.NodeId)
+				operators.Data = append(operators.Data, []byte(opTypes[os.NodeId]))
+				selfTimes.Data = append(selfTimes.Data, os.SelfExecutionTimeNs)
+				totalTimes.Data = append(totalTimes.Data, os.TotalExecutionTimeNs)
+				bytesOutput.Data = append(bytesOutput.Data, os.BytesOutput)
+				recordsOutput.Data = append(recordsOutput.Data, os.RecordsOutput)
+			}
+		}
+	}
+
+	return &vizierpb.ExecuteScriptResponse{
+		QueryID: queryID.String(),
+		Result: &vizierpb.ExecuteScriptResponse_Data{
+			Data: &vizierpb.QueryData{
+				Batch: &vizierpb.RowBatchData{
+					TableID: statsTableID,
+					Cols: []*vizierpb.Column{
+						{ColData: &vizierpb.Column_StringData{StringData: agentIDs}},
+						{ColData: &vizierpb.Column_Int64Data{Int64Data: nodeIDs}},
+						{ColData: &vizierpb.Column_StringData{StringData: operators}},
+						{ColData: &vizierpb.Column_Int64Data{Int64Data: selfTimes}},
+						{ColData: &vizierpb.Column_Int64Data{Int64Data: totalTimes}},
+						{ColData: &vizierpb.Column_Int64Data{Int64Data: bytesOutput}},
+						{ColData: &vizierpb.Column_Int64Data{Int64Data: recordsOutput}},
+					},
+					NumRows: int64(len(nodeIDs.Data)),
+					Eos:     true,
+					Eow:     true,
+				},
+			},
+		},
+	}
+}
+
 // OutputSchemaFromPlan takes in a plan map and returns the relations for all of the final output
 // tables in the plan map.
 func OutputSchemaFromPlan(planMap map[uuid.UUID]*planpb.Plan) map[string]*schemapb.Relation {
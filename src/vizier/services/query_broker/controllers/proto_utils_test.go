@@ -27,6 +27,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"px.dev/pixie/src/api/go/pxapi"
 	"px.dev/pixie/src/api/proto/vizierpb"
 	"px.dev/pixie/src/carnot/carnotpb"
 	"px.dev/pixie/src/carnot/planner/compilerpb"
@@ -459,6 +460,27 @@ func TestBuildExecuteScriptResponse_ExecutionStats(t *testing.T) {
 	assert.Equal(t, expectedStats, resp.GetData().GetExecutionStats())
 }
 
+func TestQueryResultStatsToVizierStats_DataLoss(t *testing.T) {
+	stats := controllers.QueryResultStatsToVizierStats(&queryresultspb.QueryExecutionStats{
+		Timing: &queryresultspb.QueryTimingInfo{},
+		DataLoss: &queryresultspb.DataLossStats{
+			PerfBufferLostEvents:  12,
+			TablesWithEvictedData: []string{"http_events"},
+		},
+	}, 0)
+	assert.Equal(t, &vizierpb.DataLossStats{
+		PerfBufferLostEvents:  12,
+		TablesWithEvictedData: []string{"http_events"},
+	}, stats.DataLoss)
+
+	// No data loss is not reported.
+	stats = controllers.QueryResultStatsToVizierStats(&queryresultspb.QueryExecutionStats{
+		Timing:   &queryresultspb.QueryTimingInfo{},
+		DataLoss: &queryresultspb.DataLossStats{},
+	}, 0)
+	assert.Nil(t, stats.DataLoss)
+}
+
 func TestQueryPlanResponse(t *testing.T) {
 	queryIDStr := "6683eddd-0824-430c-ac0d-ce05cf9624a8"
 	agentIDStr := "3ca421d4-5f85-4c99-8248-02252204e281"
@@ -625,6 +647,71 @@ func TestQueryPlanResponse(t *testing.T) {
 	assert.Equal(t, expected2[1], resp2[1])
 }
 
+func TestQueryExecStatsResponse(t *testing.T) {
+	queryID := uuid.Must(uuid.NewV4())
+	agentID := uuid.Must(uuid.FromString("3ca421d4-5f85-4c99-8248-02252204e281"))
+
+	agentPlan := &planpb.Plan{}
+	if err := proto.UnmarshalText(agentPlanPb, agentPlan); err != nil {
+		t.Fatal("Cannot Unmarshal protobuf.")
+	}
+	planMap := map[uuid.UUID]*planpb.Plan{agentID: agentPlan}
+
+	agentStats := []*queryresultspb.AgentExecutionStats{
+		{
+			AgentID: utils.ProtoFromUUID(agentID),
+			OperatorExecutionStats: []*queryresultspb.OperatorExecutionStats{
+				{
+					NodeId:               2,
+					BytesOutput:          456,
+					RecordsOutput:        12,
+					TotalExecutionTimeNs: 73,
+					SelfExecutionTimeNs:  70,
+				},
+				{
+					NodeId:               3,
+					BytesOutput:          10,
+					RecordsOutput:        1,
+					TotalExecutionTimeNs: 80,
+					SelfExecutionTimeNs:  7,
+				},
+			},
+		},
+	}
+
+	relResp := controllers.QueryExecStatsRelationResponse(queryID, "stats_table_id")
+	md := relResp.GetMetaData()
+	require.NotNil(t, md)
+	assert.Equal(t, pxapi.QueryExecStatsTableName, md.Name)
+	assert.Equal(t, "stats_table_id", md.ID)
+	assert.Equal(t, 7, len(md.Relation.Columns))
+
+	resp := controllers.QueryExecStatsResponse(queryID, planMap, &agentStats, "stats_table_id")
+	assert.Equal(t, queryID.String(), resp.QueryID)
+	batch := resp.GetData().Batch
+	assert.Equal(t, "stats_table_id", batch.TableID)
+	assert.Equal(t, int64(2), batch.NumRows)
+	assert.True(t, batch.Eos)
+	assert.True(t, batch.Eow)
+	require.Equal(t, len(md.Relation.Columns), len(batch.Cols))
+	assert.Equal(t, [][]byte{[]byte(agentID.String()), []byte(agentID.String())}, batch.Cols[0].GetStringData().Data)
+	assert.Equal(t, []int64{2, 3}, batch.Cols[1].GetInt64Data().Data)
+	assert.Equal(t, [][]byte{[]byte("memory_source_operator"), []byte("grpc_sink_operator")}, batch.Cols[2].GetStringData().Data)
+	assert.Equal(t, []int64{70, 7}, batch.Cols[3].GetInt64Data().Data)
+	assert.Equal(t, []int64{73, 80}, batch.Cols[4].GetInt64Data().Data)
+	assert.Equal(t, []int64{456, 10}, batch.Cols[5].GetInt64Data().Data)
+	assert.Equal(t, []int64{12, 1}, batch.Cols[6].GetInt64Data().Data)
+}
+
+func TestQueryExecStatsResponse_NoStats(t *testing.T) {
+	queryID := uuid.Must(uuid.NewV4())
+	resp := controllers.QueryExecStatsResponse(queryID, nil, nil, "stats_table_id")
+	batch := resp.GetData().Batch
+	assert.Equal(t, int64(0), batch.NumRows)
+	assert.Equal(t, 7, len(batch.Cols))
+	assert.True(t, batch.Eos)
+}
+
 func TestTableRelationResponses(t *testing.T) {
 	queryID := uuid.Must(uuid.NewV4())
 
@@ -44,6 +44,9 @@ import (
 	"px.dev/pixie/src/vizier/services/metadata/metadatapb"
 )
 
+// The name used for metrics of queries that don't have a name.
+const unnamedQueryName = "unnamed"
+
 var queryExecTimeSummary *prometheus.SummaryVec
 var queryExecNumPEMSummary *prometheus.SummaryVec
 
@@ -102,6 +105,7 @@ type QueryExecutorImpl struct {
 	mdconf              metadatapb.MetadataConfigServiceClient
 	resultForwarder     QueryResultForwarder
 	planner             Planner
+	slowQueryWatchdog   *SlowQueryWatchdog
 
 	eg *errgroup.Group
 
@@ -115,6 +119,12 @@ type QueryExecutorImpl struct {
 	queryName string
 	// numPEMsQueried is stored so that the prometheus metric is only updated if the query succeeded.
 	numPEMsQueried int
+	// execTimeout is how long the query may run for once it is launched, or 0 if it is not limited.
+	execTimeout time.Duration
+	// missingAgentIDs are the unresponsive agents that were left out of the query.
+	missingAgentIDs []string
+	// clockSkew is the skew between the clocks of the PEMs in the query, if it exceeds the threshold.
+	clockSkew *vizierpb.ClockSkewStats
 }
 
 // NewQueryExecutorFromServer creates a new QueryExecutor using the properties of a query broker server.
@@ -129,6 +139,7 @@ func NewQueryExecutorFromServer(s *Server, mutExecFactory MutationExecFactory) Q
 		s.mdconf,
 		s.resultForwarder,
 		s.planner,
+		s.slowQueryWatchdog,
 		mutExecFactory,
 	)
 }
@@ -144,6 +155,7 @@ func NewQueryExecutor(
 	mdconf metadatapb.MetadataConfigServiceClient,
 	resultForwarder QueryResultForwarder,
 	planner Planner,
+	slowQueryWatchdog *SlowQueryWatchdog,
 	mutExecFactory MutationExecFactory,
 ) QueryExecutor {
 	return &QueryExecutorImpl{
@@ -156,6 +168,7 @@ func NewQueryExecutor(
 		mdconf:              mdconf,
 		resultForwarder:     resultForwarder,
 		planner:             planner,
+		slowQueryWatchdog:   slowQueryWatchdog,
 		mutationExecFactory: mutExecFactory,
 		queryName:           "",
 		numPEMsQueried:      0,
@@ -182,7 +195,7 @@ func (q *QueryExecutorImpl) Run(ctx context.Context, req *vizierpb.ExecuteScript
 
 	q.queryName = req.QueryName
 	if q.queryName == "" {
-		q.queryName = "unnamed"
+		q.queryName = unnamedQueryName
 	}
 
 	resultCh := make(chan *vizierpb.ExecuteScriptResponse)
@@ -202,6 +215,7 @@ func (q *QueryExecutorImpl) Wait() error {
 		d := time.Since(q.startTime)
 		queryExecTimeSummary.With(prometheus.Labels{"script_name": q.queryName}).Observe(float64(d.Milliseconds()))
 		queryExecNumPEMSummary.With(prometheus.Labels{"script_name": q.queryName}).Observe(float64(q.numPEMsQueried))
+		q.slowQueryWatchdog.Observe(q.queryName, d)
 		return nil
 	}
 	// There are a few common failure cases that may occur naturally during query execution. For example, ctxDeadlineExceeded,
@@ -221,6 +235,19 @@ func (q *QueryExecutorImpl) Wait() error {
 	if errors.Is(err, context.DeadlineExceeded) {
 		return err
 	}
+	if errors.Is(err, ErrQueryExecTimeExceeded) {
+		log.WithField("query_id", q.queryID).
+			WithField("query_name", q.queryName).
+			WithError(err).
+			Warn("Cancelled slow query")
+		return err
+	}
+	if errors.Is(err, ErrQueryPreempted) {
+		log.WithField("query_id", q.queryID).
+			WithField("query_name", q.queryName).
+			Info("Preempted background query")
+		return err
+	}
 	if errors.Is(err, context.Canceled) {
 		log.WithField("query_id", q.queryID).
 			Info("Query cancelled")
@@ -247,6 +274,14 @@ func (q *QueryExecutorImpl) runConsumer(ctx context.Context, resultCh <-chan *vi
 			if !ok {
 				return nil
 			}
+			if stats := result.GetData().GetExecutionStats(); stats != nil {
+				if len(q.missingAgentIDs) > 0 {
+					stats.MissingAgentIDs = q.missingAgentIDs
+				}
+				if q.clockSkew != nil {
+					stats.ClockSkew = q.clockSkew
+				}
+			}
 			if err := consumer.Consume(result); err != nil {
 				return err
 			}
@@ -263,16 +298,6 @@ func (q *QueryExecutorImpl) sendResponse(ctx context.Context, resultCh chan<- *v
 	}
 }
 
-func (q *QueryExecutorImpl) getPlanOpts(queryStr string) (*planpb.PlanOptions, error) {
-	flags, err := ParseQueryFlags(queryStr)
-	if err != nil {
-		return nil, err
-	}
-
-	planOpts := flags.GetPlanOptions()
-	return planOpts, nil
-}
-
 func (q *QueryExecutorImpl) runMutation(ctx context.Context, resultCh chan<- *vizierpb.ExecuteScriptResponse, req *vizierpb.ExecuteScriptRequest, planOpts *planpb.PlanOptions, distributedState *distributedpb.DistributedState) error {
 	mutationExec := q.mutationExecFactory(q.planner, q.mdtp, q.mdconf, distributedState)
 
@@ -445,16 +470,42 @@ func (q *QueryExecutorImpl) buildQueryPlanOpts(ctx context.Context, resultCh cha
 		Plan:    plan,
 		PlanMap: planMap,
 	}
+
+	if planOpts.Analyze {
+		statsTableID, err := uuid.NewV4()
+		if err != nil {
+			return nil, err
+		}
+		if err := q.sendResponse(ctx, resultCh, QueryExecStatsRelationResponse(q.queryID, statsTableID.String())); err != nil {
+			return nil, err
+		}
+		queryPlanOpts.ExecStatsTableID = statsTableID.String()
+	}
 	return queryPlanOpts, nil
 }
 
 func (q *QueryExecutorImpl) prepareScript(ctx context.Context, resultCh chan<- *vizierpb.ExecuteScriptResponse, req *vizierpb.ExecuteScriptRequest) error {
-	planOpts, err := q.getPlanOpts(req.QueryStr)
+	flags, err := ParseQueryFlags(req.QueryStr)
 	if err != nil {
 		return err
 	}
-
-	distributedState := q.agentsTracker.GetAgentInfo().DistributedState()
+	planOpts := flags.GetPlanOptions()
+	scriptMaxExecTime := time.Duration(flags.GetInt64("max_exec_time_s")) * time.Second
+	q.execTimeout = q.slowQueryWatchdog.Timeout(q.queryName, scriptMaxExecTime)
+
+	agentsInfo := q.agentsTracker.GetAgentInfo()
+	distributedState := agentsInfo.DistributedState()
+	if timeout := viper.GetDuration("straggler_heartbeat_timeout"); timeout > 0 {
+		q.missingAgentIDs = excludeUnresponsiveAgents(&distributedState, agentsInfo.UnresponsiveAgents(timeout))
+	}
+	if threshold := viper.GetDuration("clock_skew_threshold"); threshold > 0 {
+		q.clockSkew = clockSkewStats(&distributedState, agentsInfo.ClockOffsets(), threshold)
+		if q.clockSkew != nil {
+			log.WithField("query_id", q.queryID).
+				WithField("max_skew", time.Duration(q.clockSkew.MaxSkewNs)).
+				Debug("Clocks of the PEMs in the query are skewed")
+		}
+	}
 
 	if req.Mutation {
 		if err := q.runMutation(ctx, resultCh, req, planOpts, &distributedState); err != nil {
@@ -515,6 +566,16 @@ func (q *QueryExecutorImpl) runScript(ctx context.Context, resultCh chan<- *vizi
 			return err
 		}
 	}
+	if q.execTimeout <= 0 {
+		return q.resultForwarder.StreamResults(ctx, q.queryID, resultCh)
+	}
 
-	return q.resultForwarder.StreamResults(ctx, q.queryID, resultCh)
+	stopWatch := watchQueryExecTime(q.queryID, q.queryName, q.execTimeout, q.resultForwarder)
+	err := q.resultForwarder.StreamResults(ctx, q.queryID, resultCh)
+	// The watch is left running if the client disconnects, so that the limit still applies if the query is resumed.
+	// Cancelling a query that has already finished has no effect.
+	if err == nil {
+		stopWatch()
+	}
+	return err
 }
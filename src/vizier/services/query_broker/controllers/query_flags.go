@@ -38,6 +38,7 @@ var defaultQueryFlags = map[string]interface{}{
 	"explain":                   false,
 	"analyze":                   false,
 	"max_output_rows_per_table": 10000,
+	"max_exec_time_s":           0,
 }
 
 // QueryFlags represents a set of Pixie configuration flags.
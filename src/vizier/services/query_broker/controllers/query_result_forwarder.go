@@ -64,6 +64,9 @@ type QueryPlanOpts struct {
 	TableID string
 	Plan    *distributedpb.DistributedPlan
 	PlanMap map[uuid.UUID]*planpb.Plan
+	// ExecStatsTableID is the ID of the per-operator execution stats table. It is only set when the
+	// query has analyze=true, since otherwise the agents don't collect operator stats.
+	ExecStatsTableID string
 }
 
 // The deadline for all sinks in a given query to initialize.
@@ -334,6 +337,10 @@ func (a *activeQuery) handleRequest(ctx context.Context, queryID uuid.UUID, msg
 			if err != nil {
 				return err
 			}
+			if a.queryPlanOpts.ExecStatsTableID != "" {
+				qpResps = append(qpResps, QueryExecStatsResponse(queryID, a.queryPlanOpts.PlanMap,
+					a.agentExecStats, a.queryPlanOpts.ExecStatsTableID))
+			}
 			for _, qpRes := range qpResps {
 				select {
 				case <-ctx.Done():
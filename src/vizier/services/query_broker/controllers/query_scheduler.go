@@ -0,0 +1,329 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc/metadata"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+)
+
+// QueryPriorityMetadataKey is the gRPC metadata key that callers of ExecuteScript set to the QueryPriority of the
+// query, either "interactive" or "background".
+const QueryPriorityMetadataKey = "px-query-priority"
+
+// QueryPriority is the class of a query for admission into the query broker.
+type QueryPriority int
+
+const (
+	// QueryPriorityInteractive is for queries a person is waiting on, from the CLI, UI or API.
+	QueryPriorityInteractive QueryPriority = iota
+	// QueryPriorityBackground is for scheduled scripts, such as exports and alert evaluations. These queue behind
+	// interactive queries, and may be preempted by them.
+	QueryPriorityBackground
+)
+
+func (p QueryPriority) String() string {
+	if p == QueryPriorityBackground {
+		return "background"
+	}
+	return "interactive"
+}
+
+var (
+	querySchedulerRunning     *prometheus.GaugeVec
+	querySchedulerQueued      *prometheus.GaugeVec
+	querySchedulerPreemptions prometheus.Counter
+)
+
+func init() {
+	querySchedulerRunning = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "query_scheduler_running",
+			Help: "The number of queries that are running, by priority.",
+		},
+		[]string{"priority"},
+	)
+	querySchedulerQueued = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "query_scheduler_queued",
+			Help: "The number of queries that are waiting to run, by priority.",
+		},
+		[]string{"priority"},
+	)
+	querySchedulerPreemptions = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "query_scheduler_preemptions",
+			Help: "The number of background queries that were cancelled to make room for interactive queries.",
+		},
+	)
+	pflag.Int("max_concurrent_queries", 0, "The maximum number of queries that run at once. Further queries wait, interactive ones first. Unlimited if this is 0.")
+	pflag.Int("max_concurrent_background_queries", 0, "The maximum number of scheduled queries that run at once. Only limited by max_concurrent_queries if this is 0.")
+	pflag.Bool("preempt_background_queries", true, "Whether interactive queries cancel running scheduled queries when max_concurrent_queries is reached.")
+}
+
+// QueryPriorityOf returns the priority of a request to ExecuteScript. The priority is taken from the incoming
+// metadata if it is set, and otherwise cron scripts are background queries.
+func QueryPriorityOf(ctx context.Context, req *vizierpb.ExecuteScriptRequest) QueryPriority {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, v := range md.Get(QueryPriorityMetadataKey) {
+			switch v {
+			case QueryPriorityBackground.String():
+				return QueryPriorityBackground
+			case QueryPriorityInteractive.String():
+				return QueryPriorityInteractive
+			}
+		}
+	}
+	if strings.HasPrefix(req.QueryName, "cron_") {
+		return QueryPriorityBackground
+	}
+	return QueryPriorityInteractive
+}
+
+// QueryScheduler limits how many queries run at once. Interactive queries are admitted before background queries,
+// and may preempt running background queries when every slot is taken.
+type QueryScheduler struct {
+	maxConcurrent           int
+	maxConcurrentBackground int
+	preempt                 bool
+
+	mu      sync.Mutex
+	running map[*QueryTicket]struct{}
+	// runningBackground is the number of tickets in running that are background queries.
+	runningBackground int
+	// preempting is the number of running tickets that have been preempted, but not released yet.
+	preempting int
+	waiting    map[QueryPriority][]*queryWaiter
+	// seq orders the tickets by when they were admitted.
+	seq uint64
+}
+
+type queryWaiter struct {
+	ticket  *QueryTicket
+	granted chan struct{}
+}
+
+// QueryTicket is the admission of a query into the scheduler. It must be released once the query is done.
+type QueryTicket struct {
+	s        *QueryScheduler
+	priority QueryPriority
+
+	// The following are guarded by s.mu.
+	preemptFn   func()
+	preempted   bool
+	released    bool
+	admittedSeq uint64
+}
+
+// NewQueryScheduler creates a new QueryScheduler which runs at most maxConcurrent queries, of which at most
+// maxConcurrentBackground are background queries, unless it is 0.
+func NewQueryScheduler(maxConcurrent, maxConcurrentBackground int, preempt bool) *QueryScheduler {
+	return &QueryScheduler{
+		maxConcurrent:           maxConcurrent,
+		maxConcurrentBackground: maxConcurrentBackground,
+		preempt:                 preempt,
+		running:                 make(map[*QueryTicket]struct{}),
+		waiting:                 make(map[QueryPriority][]*queryWaiter),
+	}
+}
+
+// newQuerySchedulerFromFlags creates the query scheduler from the flags, or returns nil if queries are unlimited.
+func newQuerySchedulerFromFlags() *QueryScheduler {
+	maxConcurrent := viper.GetInt("max_concurrent_queries")
+	if maxConcurrent <= 0 {
+		return nil
+	}
+	return NewQueryScheduler(maxConcurrent, viper.GetInt("max_concurrent_background_queries"),
+		viper.GetBool("preempt_background_queries"))
+}
+
+// Admit waits until a query of the given priority may run, or the context is done. A nil scheduler admits every
+// query immediately.
+func (s *QueryScheduler) Admit(ctx context.Context, priority QueryPriority) (*QueryTicket, error) {
+	t := &QueryTicket{s: s, priority: priority}
+	if s == nil {
+		return t, nil
+	}
+
+	s.mu.Lock()
+	// Queries don't skip ahead of waiting queries of the same or a higher priority.
+	queuedAhead := len(s.waiting[QueryPriorityInteractive])
+	if priority == QueryPriorityBackground {
+		queuedAhead += len(s.waiting[QueryPriorityBackground])
+	}
+	if queuedAhead == 0 && s.canRunLocked(priority) {
+		s.startLocked(t)
+		s.mu.Unlock()
+		return t, nil
+	}
+	w := &queryWaiter{ticket: t, granted: make(chan struct{})}
+	s.waiting[priority] = append(s.waiting[priority], w)
+	querySchedulerQueued.WithLabelValues(priority.String()).Inc()
+	var preemptFn func()
+	if priority == QueryPriorityInteractive {
+		preemptFn = s.preemptLocked()
+	}
+	s.mu.Unlock()
+	if preemptFn != nil {
+		preemptFn()
+	}
+
+	select {
+	case <-w.granted:
+		return t, nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		select {
+		case <-w.granted:
+			// The ticket was granted while the context was being cancelled, so give the slot back.
+			s.releaseLocked(t)
+		default:
+			s.removeWaiterLocked(w)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// SetPreemptFunc sets the func which cancels the query if it is preempted. If the query was preempted before the
+// func was set, it is called right away.
+func (t *QueryTicket) SetPreemptFunc(f func()) {
+	if t.s == nil || t.priority != QueryPriorityBackground {
+		return
+	}
+	t.s.mu.Lock()
+	preempted := t.preempted && !t.released
+	t.preemptFn = f
+	t.s.mu.Unlock()
+	if preempted {
+		f()
+	}
+}
+
+// Release returns the query's slot to the scheduler. It is safe to call more than once.
+func (t *QueryTicket) Release() {
+	if t == nil || t.s == nil {
+		return
+	}
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+	t.s.releaseLocked(t)
+}
+
+func (s *QueryScheduler) canRunLocked(priority QueryPriority) bool {
+	if len(s.running) >= s.maxConcurrent {
+		return false
+	}
+	if priority == QueryPriorityBackground && s.maxConcurrentBackground > 0 {
+		return s.runningBackground < s.maxConcurrentBackground
+	}
+	return true
+}
+
+func (s *QueryScheduler) startLocked(t *QueryTicket) {
+	s.seq++
+	t.admittedSeq = s.seq
+	s.running[t] = struct{}{}
+	if t.priority == QueryPriorityBackground {
+		s.runningBackground++
+	}
+	querySchedulerRunning.WithLabelValues(t.priority.String()).Inc()
+}
+
+func (s *QueryScheduler) releaseLocked(t *QueryTicket) {
+	if t.released {
+		return
+	}
+	t.released = true
+	if _, ok := s.running[t]; !ok {
+		return
+	}
+	delete(s.running, t)
+	if t.priority == QueryPriorityBackground {
+		s.runningBackground--
+	}
+	if t.preempted {
+		s.preempting--
+	}
+	querySchedulerRunning.WithLabelValues(t.priority.String()).Dec()
+	s.dispatchLocked()
+}
+
+// dispatchLocked admits waiting queries into free slots, interactive queries first.
+func (s *QueryScheduler) dispatchLocked() {
+	for _, p := range []QueryPriority{QueryPriorityInteractive, QueryPriorityBackground} {
+		for len(s.waiting[p]) > 0 && s.canRunLocked(p) {
+			if p == QueryPriorityBackground && len(s.waiting[QueryPriorityInteractive]) > 0 {
+				return
+			}
+			w := s.waiting[p][0]
+			s.waiting[p] = s.waiting[p][1:]
+			querySchedulerQueued.WithLabelValues(p.String()).Dec()
+			s.startLocked(w.ticket)
+			close(w.granted)
+		}
+	}
+}
+
+func (s *QueryScheduler) removeWaiterLocked(w *queryWaiter) {
+	p := w.ticket.priority
+	for i, other := range s.waiting[p] {
+		if other == w {
+			s.waiting[p] = append(s.waiting[p][:i], s.waiting[p][i+1:]...)
+			querySchedulerQueued.WithLabelValues(p.String()).Dec()
+			break
+		}
+	}
+	// A background query may have been held back by this waiter.
+	s.dispatchLocked()
+}
+
+// preemptLocked picks the most recently admitted background query to cancel, if interactive queries are waiting
+// for slots which aren't already being freed. It returns the func which cancels the query, to be called without
+// holding the lock.
+func (s *QueryScheduler) preemptLocked() func() {
+	if !s.preempt || len(s.waiting[QueryPriorityInteractive]) <= s.preempting {
+		return nil
+	}
+	var victim *QueryTicket
+	for t := range s.running {
+		if t.priority != QueryPriorityBackground || t.preempted {
+			continue
+		}
+		if victim == nil || t.admittedSeq > victim.admittedSeq {
+			victim = t
+		}
+	}
+	if victim == nil {
+		return nil
+	}
+	victim.preempted = true
+	s.preempting++
+	querySchedulerPreemptions.Inc()
+	return victim.preemptFn
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/vizier/services/query_broker/controllers"
+)
+
+// admitAsync admits a query in the background, and returns a channel that receives its ticket once it is admitted.
+func admitAsync(ctx context.Context, s *controllers.QueryScheduler, p controllers.QueryPriority) <-chan *controllers.QueryTicket {
+	ch := make(chan *controllers.QueryTicket, 1)
+	go func() {
+		t, err := s.Admit(ctx, p)
+		if err == nil {
+			ch <- t
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+func requireNotAdmitted(t *testing.T, ch <-chan *controllers.QueryTicket) {
+	select {
+	case <-ch:
+		t.Fatal("query was admitted early")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func requireAdmitted(t *testing.T, ch <-chan *controllers.QueryTicket) *controllers.QueryTicket {
+	select {
+	case ticket, ok := <-ch:
+		require.True(t, ok, "query was not admitted")
+		return ticket
+	case <-time.After(time.Second):
+		t.Fatal("query was not admitted")
+	}
+	return nil
+}
+
+func TestQueryScheduler_Unlimited(t *testing.T) {
+	var s *controllers.QueryScheduler
+	ticket, err := s.Admit(context.Background(), controllers.QueryPriorityBackground)
+	require.NoError(t, err)
+	ticket.SetPreemptFunc(func() { t.Fatal("unexpected preemption") })
+	ticket.Release()
+}
+
+func TestQueryScheduler_InteractiveFirst(t *testing.T) {
+	ctx := context.Background()
+	s := controllers.NewQueryScheduler(1, 0, false)
+
+	running, err := s.Admit(ctx, controllers.QueryPriorityInteractive)
+	require.NoError(t, err)
+
+	background := admitAsync(ctx, s, controllers.QueryPriorityBackground)
+	requireNotAdmitted(t, background)
+	interactive := admitAsync(ctx, s, controllers.QueryPriorityInteractive)
+	requireNotAdmitted(t, interactive)
+
+	// The interactive query runs next, even though the background query was queued first.
+	running.Release()
+	next := requireAdmitted(t, interactive)
+	requireNotAdmitted(t, background)
+
+	next.Release()
+	requireAdmitted(t, background).Release()
+}
+
+func TestQueryScheduler_Preemption(t *testing.T) {
+	ctx := context.Background()
+	s := controllers.NewQueryScheduler(1, 0, true)
+
+	background, err := s.Admit(ctx, controllers.QueryPriorityBackground)
+	require.NoError(t, err)
+	preempted := make(chan struct{})
+	background.SetPreemptFunc(func() { close(preempted) })
+
+	interactive := admitAsync(ctx, s, controllers.QueryPriorityInteractive)
+	select {
+	case <-preempted:
+	case <-time.After(time.Second):
+		t.Fatal("background query was not preempted")
+	}
+	requireNotAdmitted(t, interactive)
+
+	// The preempted query frees its slot once it stops.
+	background.Release()
+	requireAdmitted(t, interactive).Release()
+}
+
+func TestQueryScheduler_PreemptedBeforePreemptFuncSet(t *testing.T) {
+	ctx := context.Background()
+	s := controllers.NewQueryScheduler(1, 0, true)
+
+	background, err := s.Admit(ctx, controllers.QueryPriorityBackground)
+	require.NoError(t, err)
+	interactive := admitAsync(ctx, s, controllers.QueryPriorityInteractive)
+	requireNotAdmitted(t, interactive)
+
+	called := false
+	background.SetPreemptFunc(func() { called = true })
+	assert.True(t, called)
+	background.Release()
+	requireAdmitted(t, interactive).Release()
+}
+
+func TestQueryScheduler_BackgroundLimit(t *testing.T) {
+	ctx := context.Background()
+	s := controllers.NewQueryScheduler(3, 1, false)
+
+	b1, err := s.Admit(ctx, controllers.QueryPriorityBackground)
+	require.NoError(t, err)
+	b2 := admitAsync(ctx, s, controllers.QueryPriorityBackground)
+	requireNotAdmitted(t, b2)
+
+	// Interactive queries can still use the remaining slots.
+	i1, err := s.Admit(ctx, controllers.QueryPriorityInteractive)
+	require.NoError(t, err)
+
+	b1.Release()
+	requireAdmitted(t, b2).Release()
+	i1.Release()
+}
+
+func TestQueryScheduler_ContextCancelled(t *testing.T) {
+	s := controllers.NewQueryScheduler(1, 0, false)
+	running, err := s.Admit(context.Background(), controllers.QueryPriorityInteractive)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = s.Admit(ctx, controllers.QueryPriorityInteractive)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The cancelled query doesn't hold up the next one.
+	running.Release()
+	next, err := s.Admit(context.Background(), controllers.QueryPriorityBackground)
+	require.NoError(t, err)
+	next.Release()
+}
+
+func TestQueryPriorityOf(t *testing.T) {
+	incoming := func(kv ...string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(kv...))
+	}
+	interactive := &vizierpb.ExecuteScriptRequest{QueryName: "px/http_data"}
+	cron := &vizierpb.ExecuteScriptRequest{QueryName: "cron_7ba7b810-9dad-11d1-80b4-00c04fd430c8"}
+
+	assert.Equal(t, controllers.QueryPriorityInteractive, controllers.QueryPriorityOf(context.Background(), interactive))
+	assert.Equal(t, controllers.QueryPriorityBackground, controllers.QueryPriorityOf(context.Background(), cron))
+	assert.Equal(t, controllers.QueryPriorityBackground,
+		controllers.QueryPriorityOf(incoming(controllers.QueryPriorityMetadataKey, "background"), interactive))
+	assert.Equal(t, controllers.QueryPriorityInteractive,
+		controllers.QueryPriorityOf(incoming(controllers.QueryPriorityMetadataKey, "interactive"), cron))
+}
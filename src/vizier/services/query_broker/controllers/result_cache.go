@@ -0,0 +1,191 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/proto"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/carnot/planner/distributedpb"
+)
+
+func init() {
+	pflag.Duration("result_cache_ttl", 0, "How long the results of identical scripts are reused for. Results are not cached if this is 0.")
+	pflag.Int("result_cache_max_entries", 100, "The maximum number of script results to cache.")
+	pflag.Int("result_cache_max_entry_bytes", 16*1024*1024, "The maximum size of a script's results for them to be cached.")
+}
+
+type resultCacheEntry struct {
+	expiry    time.Time
+	responses []*vizierpb.ExecuteScriptResponse
+}
+
+// ResultCache stores the results of recently run scripts, so that dashboards which re-run the same script every
+// few seconds don't execute it on every refresh. Results are keyed by the script, its arguments and the time window
+// that the script ran in, so cached results are never older than the TTL.
+type ResultCache struct {
+	ttl           time.Duration
+	maxEntries    int
+	maxEntryBytes int
+
+	mu      sync.Mutex
+	entries map[string]*resultCacheEntry
+	now     func() time.Time
+}
+
+// NewResultCache creates a result cache which keeps results for the given TTL.
+func NewResultCache(ttl time.Duration, maxEntries int, maxEntryBytes int) *ResultCache {
+	return &ResultCache{
+		ttl:           ttl,
+		maxEntries:    maxEntries,
+		maxEntryBytes: maxEntryBytes,
+		entries:       make(map[string]*resultCacheEntry),
+		now:           time.Now,
+	}
+}
+
+// newResultCacheFromFlags creates the result cache from the flags, or returns nil if caching is disabled.
+func newResultCacheFromFlags() *ResultCache {
+	ttl := viper.GetDuration("result_cache_ttl")
+	if ttl <= 0 {
+		return nil
+	}
+	return NewResultCache(ttl, viper.GetInt("result_cache_max_entries"), viper.GetInt("result_cache_max_entry_bytes"))
+}
+
+// Key returns the cache key for the request, or false if the results of the request shouldn't be cached.
+// Mutations, resumed queries and scripts with export configs have side effects, so they are always executed.
+// The redaction options of the caller are part of the key, so that redacted data is never served unredacted.
+func (c *ResultCache) Key(req *vizierpb.ExecuteScriptRequest, redactOpts *distributedpb.RedactionOptions) (string, bool) {
+	if req.Mutation || req.QueryID != "" || req.Configs != nil {
+		return "", false
+	}
+
+	// The encryption options differ between clients, but the results are encrypted after they are cached.
+	keyReq := *req
+	keyReq.EncryptionOptions = nil
+	b, err := keyReq.Marshal()
+	if err != nil {
+		return "", false
+	}
+
+	var rb []byte
+	if redactOpts != nil {
+		rb, err = redactOpts.Marshal()
+		if err != nil {
+			return "", false
+		}
+	}
+
+	window := c.now().Truncate(c.ttl).UnixNano()
+	h := sha256.New()
+	h.Write(b)
+	h.Write(rb)
+	h.Write([]byte(fmt.Sprintf("%d", window)))
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// Get returns the cached results for the key.
+func (c *ResultCache) Get(key string) ([]*vizierpb.ExecuteScriptResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !c.now().Before(entry.expiry) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.responses, true
+}
+
+// Put caches the results for the key.
+func (c *ResultCache) Put(key string, responses []*vizierpb.ExecuteScriptResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if len(c.entries) >= c.maxEntries {
+		for k, entry := range c.entries {
+			if !now.Before(entry.expiry) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	if len(c.entries) >= c.maxEntries {
+		return
+	}
+	// The key includes the time window, so the entry is not used past the end of the window.
+	c.entries[key] = &resultCacheEntry{
+		expiry:    now.Truncate(c.ttl).Add(c.ttl),
+		responses: responses,
+	}
+}
+
+// cachingConsumer records the results that are passed to the underlying consumer. It stops recording once the
+// results exceed the maximum size of a cache entry, or if the script returns an error.
+type cachingConsumer struct {
+	c             QueryResultConsumer
+	maxEntryBytes int
+
+	size      int
+	skip      bool
+	responses []*vizierpb.ExecuteScriptResponse
+}
+
+func (r *cachingConsumer) Consume(result *vizierpb.ExecuteScriptResponse) error {
+	if !r.skip {
+		r.size += result.Size()
+		if r.size > r.maxEntryBytes || result.GetStatus().GetCode() != 0 {
+			r.skip = true
+			r.responses = nil
+		} else {
+			// The underlying consumers may modify the result, such as to encrypt it.
+			r.responses = append(r.responses, proto.Clone(result).(*vizierpb.ExecuteScriptResponse))
+		}
+	}
+	return r.c.Consume(result)
+}
+
+// replayCachedResults sends the cached results to the consumer, under a new query ID.
+func replayCachedResults(responses []*vizierpb.ExecuteScriptResponse, consumer QueryResultConsumer) (uuid.UUID, error) {
+	queryID, err := uuid.NewV4()
+	if err != nil {
+		return uuid.Nil, err
+	}
+	for _, resp := range responses {
+		replayed := proto.Clone(resp).(*vizierpb.ExecuteScriptResponse)
+		replayed.QueryID = queryID.String()
+		if err := consumer.Consume(replayed); err != nil {
+			return queryID, err
+		}
+	}
+	return queryID, nil
+}
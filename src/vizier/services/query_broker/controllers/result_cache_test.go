@@ -0,0 +1,155 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/golang/mock/gomock"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+	mock_vizierpb "px.dev/pixie/src/api/proto/vizierpb/mock"
+	"px.dev/pixie/src/carnot/planner/distributedpb"
+	"px.dev/pixie/src/shared/services/authcontext"
+	"px.dev/pixie/src/vizier/services/query_broker/controllers"
+)
+
+func TestResultCache_Key(t *testing.T) {
+	c := controllers.NewResultCache(time.Hour, 10, 1024)
+
+	req := &vizierpb.ExecuteScriptRequest{
+		QueryStr: "import px",
+		ExecFuncs: []*vizierpb.ExecuteScriptRequest_FuncToExecute{
+			{
+				FuncName: "f",
+				ArgValues: []*vizierpb.ExecuteScriptRequest_FuncToExecute_ArgValue{
+					{Name: "start_time", Value: "-5m"},
+				},
+			},
+		},
+	}
+	key, ok := c.Key(req, nil)
+	require.True(t, ok)
+
+	encrypted := *req
+	encrypted.EncryptionOptions = &vizierpb.ExecuteScriptRequest_EncryptionOptions{JwkKey: "key"}
+	encryptedKey, ok := c.Key(&encrypted, nil)
+	require.True(t, ok)
+	assert.Equal(t, key, encryptedKey)
+
+	otherArgs := &vizierpb.ExecuteScriptRequest{
+		QueryStr: "import px",
+		ExecFuncs: []*vizierpb.ExecuteScriptRequest_FuncToExecute{
+			{
+				FuncName: "f",
+				ArgValues: []*vizierpb.ExecuteScriptRequest_FuncToExecute_ArgValue{
+					{Name: "start_time", Value: "-30m"},
+				},
+			},
+		},
+	}
+	otherKey, ok := c.Key(otherArgs, nil)
+	require.True(t, ok)
+	assert.NotEqual(t, key, otherKey)
+
+	redactedKey, ok := c.Key(req, &distributedpb.RedactionOptions{
+		ColumnRedactions: []*distributedpb.RedactionOptions_ColumnRedaction{
+			{TableName: "http_events", ColumnName: "req_body"},
+		},
+	})
+	require.True(t, ok)
+	assert.NotEqual(t, key, redactedKey)
+
+	_, ok = c.Key(&vizierpb.ExecuteScriptRequest{QueryStr: "import px", Mutation: true}, nil)
+	assert.False(t, ok)
+	_, ok = c.Key(&vizierpb.ExecuteScriptRequest{QueryStr: "import px", QueryID: "1234"}, nil)
+	assert.False(t, ok)
+	_, ok = c.Key(&vizierpb.ExecuteScriptRequest{QueryStr: "import px", Configs: &vizierpb.Configs{}}, nil)
+	assert.False(t, ok)
+}
+
+func TestResultCache_MaxEntries(t *testing.T) {
+	c := controllers.NewResultCache(time.Hour, 1, 1024)
+	resps := []*vizierpb.ExecuteScriptResponse{{QueryID: "a"}}
+
+	c.Put("a", resps)
+	c.Put("b", resps)
+
+	cached, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, resps, cached)
+	_, ok = c.Get("b")
+	assert.False(t, ok)
+}
+
+func TestExecuteScript_ResultCache(t *testing.T) {
+	viper.Set("result_cache_ttl", time.Hour)
+	viper.Set("result_cache_max_entries", 10)
+	viper.Set("result_cache_max_entry_bytes", 1024*1024)
+	defer viper.Reset()
+
+	queryID := uuid.Must(uuid.NewV4())
+	runs := 0
+	queryExecFactory := func(*controllers.Server, controllers.MutationExecFactory) controllers.QueryExecutor {
+		runs++
+		return &fakeQueryExecutor{
+			ResultsToSend: buildExecuteScriptSuccessResponses(queryID),
+			queryID:       queryID,
+		}
+	}
+
+	s, err := controllers.NewServerWithForwarderAndPlanner(nil, nil, &fakeDataPrivacy{}, nil, nil, nil, nil, nil, queryExecFactory)
+	require.NoError(t, err)
+	defer s.Close()
+
+	execute := func(req *vizierpb.ExecuteScriptRequest) []*vizierpb.ExecuteScriptResponse {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		srv := mock_vizierpb.NewMockVizierService_ExecuteScriptServer(ctrl)
+		ctx := authcontext.NewContext(context.Background(), authcontext.New())
+		srv.EXPECT().Context().Return(ctx).AnyTimes()
+
+		var resps []*vizierpb.ExecuteScriptResponse
+		srv.EXPECT().
+			Send(gomock.Any()).
+			DoAndReturn(func(arg *vizierpb.ExecuteScriptResponse) error {
+				resps = append(resps, arg)
+				return nil
+			}).
+			AnyTimes()
+		require.NoError(t, s.ExecuteScript(req, srv))
+		return resps
+	}
+
+	first := execute(&vizierpb.ExecuteScriptRequest{QueryStr: "cached"})
+	second := execute(&vizierpb.ExecuteScriptRequest{QueryStr: "cached"})
+	assert.Equal(t, 1, runs)
+	require.Len(t, second, len(first))
+	assert.NotEqual(t, first[0].QueryID, second[0].QueryID)
+	assert.Equal(t, first[0].GetData(), second[0].GetData())
+
+	execute(&vizierpb.ExecuteScriptRequest{QueryStr: "cached", Mutation: true})
+	assert.Equal(t, 2, runs)
+}
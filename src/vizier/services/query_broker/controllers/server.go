@@ -88,6 +88,15 @@ type Server struct {
 	planner Planner
 
 	queryExecFactory QueryExecutorFactory
+
+	// resultCache is nil if result caching is disabled.
+	resultCache *ResultCache
+	// slowQueryWatchdog limits how long queries may run for.
+	slowQueryWatchdog *SlowQueryWatchdog
+	// queryScheduler is nil if the number of concurrent queries is unlimited.
+	queryScheduler *QueryScheduler
+	// encKeyPolicy is nil if encryption keys never expire.
+	encKeyPolicy *EncryptionKeyPolicy
 }
 
 // QueryExecutorFactory creates a new QueryExecutor.
@@ -131,6 +140,10 @@ func NewServerWithForwarderAndPlanner(env querybrokerenv.QueryBrokerEnv,
 		planner:           planner,
 		queryExecFactory:  queryExecFactory,
 		healthcheckQuitCh: make(chan struct{}),
+		resultCache:       newResultCacheFromFlags(),
+		slowQueryWatchdog: newSlowQueryWatchdogFromFlags(),
+		queryScheduler:    newQuerySchedulerFromFlags(),
+		encKeyPolicy:      newEncryptionKeyPolicyFromFlags(),
 	}
 	s.hcStatus.Store(fmt.Errorf("no healthcheck has run yet"))
 	go s.runHealthcheck()
@@ -313,6 +326,9 @@ func (s *Server) ExecuteScript(req *vizierpb.ExecuteScriptRequest, srv vizierpb.
 	consumer = &executeServerConsumer{
 		srv: srv,
 	}
+	if err := s.encKeyPolicy.Check(req, time.Now()); err != nil {
+		return err
+	}
 	if req.EncryptionOptions != nil {
 		c, err := newEncryptConsumer(consumer, req.EncryptionOptions)
 		if err != nil {
@@ -320,13 +336,54 @@ func (s *Server) ExecuteScript(req *vizierpb.ExecuteScriptRequest, srv vizierpb.
 		}
 		consumer = c
 	}
+
+	var cacheKey string
+	var cacheable bool
+	if s.resultCache != nil {
+		redactOpts, err := s.dataPrivacy.RedactionOptions(ctx)
+		if err != nil {
+			return err
+		}
+		cacheKey, cacheable = s.resultCache.Key(req, redactOpts)
+	}
+	if cacheable {
+		if responses, ok := s.resultCache.Get(cacheKey); ok {
+			queryID, err := replayCachedResults(responses, consumer)
+			log.Infof("Served query from cache: %s", queryID)
+			return err
+		}
+	}
+
+	var cc *cachingConsumer
+	if cacheable {
+		cc = &cachingConsumer{c: consumer, maxEntryBytes: s.resultCache.maxEntryBytes}
+		consumer = cc
+	}
+
+	ticket, err := s.queryScheduler.Admit(ctx, QueryPriorityOf(ctx, req))
+	if err != nil {
+		return err
+	}
+	defer ticket.Release()
+
 	queryExec := s.queryExecFactory(s, NewMutationExecutor)
 	if err := queryExec.Run(ctx, req, consumer); err != nil {
 		return err
 	}
 	log.Infof("Launched query: %s", queryExec.QueryID())
+	ticket.SetPreemptFunc(func() {
+		queryID := queryExec.QueryID()
+		s.resultForwarder.ProducerCancelStream(queryID, fmt.Errorf("%w: query %s was cancelled to run an interactive query",
+			ErrQueryPreempted, queryID.String()))
+	})
 
-	return queryExec.Wait()
+	if err := queryExec.Wait(); err != nil {
+		return err
+	}
+	if cc != nil && !cc.skip {
+		s.resultCache.Put(cacheKey, cc.responses)
+	}
+	return nil
 }
 
 // GenerateOTelScript generates an OTel script for the given DataFrame script.
@@ -0,0 +1,145 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// The number of successful runs of a script before its latency baseline is used to bound its execution time.
+const slowQueryMinSamples = 5
+
+// The weight of the latest execution time in the latency baseline of a script.
+const slowQueryBaselineWeight = 0.2
+
+var queryExecTimeoutCounter *prometheus.CounterVec
+
+func init() {
+	queryExecTimeoutCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "query_exec_timeouts",
+			Help: "The number of queries that were cancelled for exceeding their maximum execution time.",
+		},
+		[]string{"script_name"},
+	)
+	pflag.Duration("max_query_exec_time", 0, "The maximum time a query may run for before it is cancelled. Queries are not limited if this is 0.")
+	pflag.Float64("slow_query_baseline_multiplier", 0, "Cancel named scripts that run for longer than this multiple of their usual execution time. Disabled if this is 0.")
+	pflag.Duration("slow_query_min_exec_time", 30*time.Second, "The minimum execution time allowed for a script when it is bounded by its usual execution time.")
+}
+
+// SlowQueryWatchdog tracks the usual execution time of scripts, and decides how long each query may run for.
+type SlowQueryWatchdog struct {
+	maxExecTime        time.Duration
+	baselineMultiplier float64
+	minExecTime        time.Duration
+
+	mu sync.Mutex
+	// baselines is the moving average of the execution time of each named script.
+	baselines map[string]*latencyBaseline
+}
+
+type latencyBaseline struct {
+	avg     time.Duration
+	samples int
+}
+
+// NewSlowQueryWatchdog creates a new SlowQueryWatchdog. Queries run for at most maxExecTime, unless it is 0.
+// If baselineMultiplier is positive, named scripts are also limited to that multiple of their usual execution time,
+// but never less than minExecTime.
+func NewSlowQueryWatchdog(maxExecTime time.Duration, baselineMultiplier float64, minExecTime time.Duration) *SlowQueryWatchdog {
+	return &SlowQueryWatchdog{
+		maxExecTime:        maxExecTime,
+		baselineMultiplier: baselineMultiplier,
+		minExecTime:        minExecTime,
+		baselines:          make(map[string]*latencyBaseline),
+	}
+}
+
+func newSlowQueryWatchdogFromFlags() *SlowQueryWatchdog {
+	return NewSlowQueryWatchdog(viper.GetDuration("max_query_exec_time"),
+		viper.GetFloat64("slow_query_baseline_multiplier"), viper.GetDuration("slow_query_min_exec_time"))
+}
+
+// Timeout returns how long the script may run for, or 0 if it is not limited. A script can lower its own limit
+// by setting scriptMaxExecTime, but can't raise it.
+func (w *SlowQueryWatchdog) Timeout(queryName string, scriptMaxExecTime time.Duration) time.Duration {
+	timeout := scriptMaxExecTime
+	if w == nil {
+		return timeout
+	}
+	lower := func(d time.Duration) {
+		if d > 0 && (timeout <= 0 || d < timeout) {
+			timeout = d
+		}
+	}
+	lower(w.maxExecTime)
+
+	if w.baselineMultiplier <= 0 || queryName == unnamedQueryName {
+		return timeout
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	b, ok := w.baselines[queryName]
+	if !ok || b.samples < slowQueryMinSamples {
+		return timeout
+	}
+	adaptive := time.Duration(float64(b.avg) * w.baselineMultiplier)
+	if adaptive < w.minExecTime {
+		adaptive = w.minExecTime
+	}
+	lower(adaptive)
+	return timeout
+}
+
+// Observe records the execution time of a successful run of the script.
+func (w *SlowQueryWatchdog) Observe(queryName string, d time.Duration) {
+	if w == nil || queryName == unnamedQueryName {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	b, ok := w.baselines[queryName]
+	if !ok {
+		w.baselines[queryName] = &latencyBaseline{avg: d, samples: 1}
+		return
+	}
+	b.avg = time.Duration(slowQueryBaselineWeight*float64(d) + (1-slowQueryBaselineWeight)*float64(b.avg))
+	b.samples++
+}
+
+// watchQueryExecTime cancels the query on the agents if it runs for longer than the timeout.
+// The returned func stops the watch.
+func watchQueryExecTime(queryID uuid.UUID, queryName string, timeout time.Duration, forwarder QueryResultForwarder) func() bool {
+	t := time.AfterFunc(timeout, func() {
+		queryExecTimeoutCounter.With(prometheus.Labels{"script_name": queryName}).Inc()
+		// Cancelling the query in the result forwarder closes the streams from the agents, which stops the
+		// query on the agents.
+		forwarder.ProducerCancelStream(queryID, fmt.Errorf("%w: query %s was cancelled after %s",
+			ErrQueryExecTimeExceeded, queryID.String(), timeout))
+	})
+	return t.Stop
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"px.dev/pixie/src/vizier/services/query_broker/controllers"
+)
+
+func TestSlowQueryWatchdog_MaxExecTime(t *testing.T) {
+	w := controllers.NewSlowQueryWatchdog(time.Minute, 0, 0)
+
+	assert.Equal(t, time.Minute, w.Timeout("px/http_data", 0))
+	assert.Equal(t, 10*time.Second, w.Timeout("px/http_data", 10*time.Second))
+	// Scripts can't raise the limit.
+	assert.Equal(t, time.Minute, w.Timeout("px/http_data", time.Hour))
+
+	unlimited := controllers.NewSlowQueryWatchdog(0, 0, 0)
+	assert.Equal(t, time.Duration(0), unlimited.Timeout("px/http_data", 0))
+	assert.Equal(t, time.Hour, unlimited.Timeout("px/http_data", time.Hour))
+
+	var disabled *controllers.SlowQueryWatchdog
+	assert.Equal(t, time.Duration(0), disabled.Timeout("px/http_data", 0))
+}
+
+func TestSlowQueryWatchdog_Baseline(t *testing.T) {
+	w := controllers.NewSlowQueryWatchdog(time.Hour, 10, 30*time.Second)
+
+	// The baseline isn't used until the script has run enough times.
+	for i := 0; i < 4; i++ {
+		w.Observe("px/http_data", 10*time.Second)
+	}
+	assert.Equal(t, time.Hour, w.Timeout("px/http_data", 0))
+
+	w.Observe("px/http_data", 10*time.Second)
+	assert.Equal(t, 100*time.Second, w.Timeout("px/http_data", 0))
+	assert.Equal(t, 20*time.Second, w.Timeout("px/http_data", 20*time.Second))
+	// Other scripts don't share the baseline.
+	assert.Equal(t, time.Hour, w.Timeout("px/cluster", 0))
+
+	for i := 0; i < 5; i++ {
+		w.Observe("px/fast", time.Millisecond)
+	}
+	assert.Equal(t, 30*time.Second, w.Timeout("px/fast", 0))
+
+	// Unnamed queries don't have a baseline.
+	for i := 0; i < 5; i++ {
+		w.Observe("unnamed", time.Millisecond)
+	}
+	assert.Equal(t, time.Hour, w.Timeout("unnamed", 0))
+}
@@ -0,0 +1,69 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gofrs/uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+
+	"px.dev/pixie/src/carnot/planner/distributedpb"
+	"px.dev/pixie/src/utils"
+)
+
+func init() {
+	pflag.Duration("straggler_heartbeat_timeout", 30*time.Second, "PEMs that haven't sent a heartbeat for this long are left out of queries. Disabled if this is 0.")
+}
+
+// excludeUnresponsiveAgents removes the unresponsive agents from the distributed state, so that a single
+// flaky node doesn't fail the whole query. It returns the IDs of the agents that were removed.
+// Agents are only removed if at least one agent with data remains.
+func excludeUnresponsiveAgents(ds *distributedpb.DistributedState, unresponsive []uuid.UUID) []string {
+	if len(unresponsive) == 0 {
+		return nil
+	}
+	unresponsiveSet := make(map[uuid.UUID]bool, len(unresponsive))
+	for _, agentID := range unresponsive {
+		unresponsiveSet[agentID] = true
+	}
+
+	var remaining []*distributedpb.CarnotInfo
+	var missing []string
+	hasDataStore := false
+	for _, carnotInfo := range ds.CarnotInfo {
+		agentID := utils.UUIDFromProtoOrNil(carnotInfo.AgentID)
+		if unresponsiveSet[agentID] {
+			missing = append(missing, agentID.String())
+			continue
+		}
+		remaining = append(remaining, carnotInfo)
+		hasDataStore = hasDataStore || carnotInfo.HasDataStore
+	}
+	if len(missing) == 0 || !hasDataStore {
+		return nil
+	}
+
+	log.Infof("Leaving %d unresponsive agents out of the query: %v", len(missing), missing)
+	sort.Strings(missing)
+	ds.CarnotInfo = remaining
+	return missing
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/mock/gomock"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/carnot/planner/distributedpb"
+	"px.dev/pixie/src/carnot/planner/plannerpb"
+	"px.dev/pixie/src/utils"
+	"px.dev/pixie/src/utils/testingutils"
+	"px.dev/pixie/src/vizier/services/query_broker/controllers"
+	mock_controllers "px.dev/pixie/src/vizier/services/query_broker/controllers/mock"
+	"px.dev/pixie/src/vizier/services/query_broker/tracker"
+)
+
+const unresponsiveAgentID = "51285cdd-1de9-4ab1-ae6a-0ba08c8c676c"
+
+// unresponsiveAgentsInfo reports a fixed set of agents as unresponsive.
+type unresponsiveAgentsInfo struct {
+	tracker.AgentsInfo
+	unresponsive []uuid.UUID
+}
+
+func (a *unresponsiveAgentsInfo) UnresponsiveAgents(time.Duration) []uuid.UUID {
+	return a.unresponsive
+}
+
+func TestQueryExecutor_UnresponsiveAgents(t *testing.T) {
+	viper.Set("straggler_heartbeat_timeout", 30*time.Second)
+	defer viper.Set("straggler_heartbeat_timeout", 0)
+
+	nc, cleanup := testingutils.MustStartTestNATS(t)
+	defer cleanup()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	plannerState := buildPlannerState(t, singleAgentDistributedState)
+	straggler := proto.Clone(plannerState.DistributedState.CarnotInfo[0]).(*distributedpb.CarnotInfo)
+	stragglerID := uuid.FromStringOrNil(unresponsiveAgentID)
+	straggler.AgentID = utils.ProtoFromUUID(stragglerID)
+	straggler.QueryBrokerAddress = unresponsiveAgentID
+	plannerState.DistributedState.CarnotInfo = append(plannerState.DistributedState.CarnotInfo, straggler)
+
+	at := &fakeAgentsTracker{
+		agentsInfo: &unresponsiveAgentsInfo{
+			AgentsInfo:   tracker.NewTestAgentsInfo(plannerState.DistributedState),
+			unresponsive: []uuid.UUID{stragglerID},
+		},
+	}
+
+	stats := &vizierpb.ExecuteScriptResponse{
+		Result: &vizierpb.ExecuteScriptResponse_Data{
+			Data: &vizierpb.QueryData{
+				ExecutionStats: &vizierpb.QueryExecutionStats{
+					Timing: &vizierpb.QueryTimingInfo{},
+				},
+			},
+		},
+	}
+	rf := &fakeResultForwarder{
+		ClientResultsToSend: []*vizierpb.ExecuteScriptResponse{stats},
+	}
+
+	var plannedAgents []string
+	planner := mock_controllers.NewMockPlanner(ctrl)
+	planner.EXPECT().
+		Plan(gomock.Any()).
+		DoAndReturn(func(req *plannerpb.QueryRequest) (*distributedpb.LogicalPlannerResult, error) {
+			for _, carnotInfo := range req.LogicalPlannerState.DistributedState.CarnotInfo {
+				plannedAgents = append(plannedAgents, carnotInfo.QueryBrokerAddress)
+			}
+			return buildPlannerResult(t, expectedPlannerResult), nil
+		})
+
+	queryExec := controllers.NewQueryExecutor("qb_address", "qb_hostname", at, &fakeDataPrivacy{}, nc, nil, nil, rf, planner, nil, nil)
+	consumer := newTestConsumer(nil)
+	require.NoError(t, queryExec.Run(context.Background(), &vizierpb.ExecuteScriptRequest{QueryStr: testQuery}, consumer))
+	require.NoError(t, queryExec.Wait())
+
+	assert.Equal(t, []string{"21285cdd-1de9-4ab1-ae6a-0ba08c8c676c"}, plannedAgents)
+	var missing []string
+	for _, result := range consumer.results {
+		if es := result.GetData().GetExecutionStats(); es != nil {
+			missing = es.MissingAgentIDs
+		}
+	}
+	assert.Equal(t, []string{unresponsiveAgentID}, missing)
+}
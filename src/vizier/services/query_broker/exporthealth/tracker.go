@@ -0,0 +1,345 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package exporthealth tracks the delivery of the results that cron scripts export to OTel collectors and object
+// stores, retries the failed deliveries, and keeps the deliveries that failed every retry in a bounded dead-letter
+// buffer, so that export failures are surfaced instead of only being logged.
+package exporthealth
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	log "github.com/sirupsen/logrus"
+
+	"px.dev/pixie/src/shared/status"
+)
+
+// Sink is a kind of destination that cron scripts export their results to.
+type Sink string
+
+const (
+	// SinkOTel is an OTel collector, which the query exports to from Carnot.
+	SinkOTel Sink = "otel"
+	// SinkObjectStore is an object store bucket, which the query broker writes Parquet files to.
+	SinkObjectStore Sink = "objectstore"
+)
+
+// otelExportErrorPrefix is the start of the error that Carnot fails a query with when it can't export to the OTel
+// collector, see carnot/exec/otel_export_sink_node.cc.
+const otelExportErrorPrefix = "OTel export"
+
+// IsOTelExportError returns whether the error message of a failed query was caused by the OTel export.
+func IsOTelExportError(msg string) bool {
+	return strings.Contains(msg, otelExportErrorPrefix)
+}
+
+var (
+	deliveriesCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cron_script_export_deliveries",
+			Help: "The number of attempts to deliver the results of a cron script run to a sink, by result.",
+		},
+		[]string{"sink", "result"},
+	)
+	deadLettersGauge = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cron_script_export_dead_letters",
+			Help: "The number of deliveries in the dead-letter buffer, which failed every retry.",
+		},
+	)
+	droppedDeadLettersCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "cron_script_export_dead_letters_dropped",
+			Help: "The number of deliveries that were evicted from the full dead-letter buffer.",
+		},
+	)
+)
+
+// Delivery is the export of the results of a single run of a cron script to a sink.
+type Delivery struct {
+	ScriptID uuid.UUID
+	Sink     Sink
+	// Start and End are the time window that the script ran over.
+	Start time.Time
+	End   time.Time
+
+	Attempts    int
+	LastAttempt time.Time
+	LastError   string
+}
+
+type exportKey struct {
+	scriptID uuid.UUID
+	sink     Sink
+}
+
+type exportState struct {
+	lastSuccess         time.Time
+	lastFailure         time.Time
+	lastError           string
+	consecutiveFailures int
+	// deadLettered is whether a delivery failed every retry since the last successful delivery.
+	deadLettered bool
+}
+
+// Tracker records the result of every delivery, and schedules the retries of the failed ones.
+type Tracker struct {
+	maxAttempts    int
+	retryBackoff   time.Duration
+	maxDeadLetters int
+
+	mu      sync.Mutex
+	exports map[exportKey]*exportState
+	retries []*Delivery
+	// deadLetters is ordered from the oldest to the newest delivery.
+	deadLetters        []*Delivery
+	droppedDeadLetters int64
+}
+
+// NewTracker creates a tracker that attempts each delivery up to maxAttempts times, waiting retryBackoff before the
+// first retry and doubling the wait before every further retry. At most maxDeadLetters deliveries are kept in the
+// dead-letter buffer, the oldest are evicted first.
+func NewTracker(maxAttempts int, retryBackoff time.Duration, maxDeadLetters int) *Tracker {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &Tracker{
+		maxAttempts:    maxAttempts,
+		retryBackoff:   retryBackoff,
+		maxDeadLetters: maxDeadLetters,
+		exports:        make(map[exportKey]*exportState),
+	}
+}
+
+func (t *Tracker) unsafeExport(d *Delivery) *exportState {
+	k := exportKey{scriptID: d.ScriptID, sink: d.Sink}
+	e, ok := t.exports[k]
+	if !ok {
+		e = &exportState{}
+		t.exports[k] = e
+	}
+	return e
+}
+
+// RecordSuccess records that the delivery succeeded.
+func (t *Tracker) RecordSuccess(d *Delivery, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	d.Attempts++
+	d.LastAttempt = now
+	e := t.unsafeExport(d)
+	e.lastSuccess = now
+	e.consecutiveFailures = 0
+	e.deadLettered = false
+	deliveriesCounter.With(prometheus.Labels{"sink": string(d.Sink), "result": "success"}).Inc()
+}
+
+// RecordFailure records that the delivery failed. The delivery is scheduled for a retry, unless it was attempted
+// the maximum number of times, in which case it is moved to the dead-letter buffer and true is returned.
+func (t *Tracker) RecordFailure(d *Delivery, err error, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	d.Attempts++
+	d.LastAttempt = now
+	d.LastError = err.Error()
+	e := t.unsafeExport(d)
+	e.lastFailure = now
+	e.lastError = d.LastError
+	e.consecutiveFailures++
+
+	if d.Attempts < t.maxAttempts {
+		deliveriesCounter.With(prometheus.Labels{"sink": string(d.Sink), "result": "retry"}).Inc()
+		t.retries = append(t.retries, d)
+		return false
+	}
+	deliveriesCounter.With(prometheus.Labels{"sink": string(d.Sink), "result": "dead_letter"}).Inc()
+	log.WithField("script_id", d.ScriptID).
+		WithField("sink", d.Sink).
+		WithField("window_start", d.Start).
+		WithField("attempts", d.Attempts).
+		WithError(err).
+		Error("Cron script export failed every retry, moving it to the dead-letter buffer")
+	e.deadLettered = true
+	t.unsafeDeadLetter(d)
+	return true
+}
+
+func (t *Tracker) unsafeDeadLetter(d *Delivery) {
+	if t.maxDeadLetters <= 0 {
+		t.droppedDeadLetters++
+		droppedDeadLettersCounter.Inc()
+		return
+	}
+	if len(t.deadLetters) >= t.maxDeadLetters {
+		n := len(t.deadLetters) - t.maxDeadLetters + 1
+		t.deadLetters = t.deadLetters[n:]
+		t.droppedDeadLetters += int64(n)
+		droppedDeadLettersCounter.Add(float64(n))
+	}
+	t.deadLetters = append(t.deadLetters, d)
+	deadLettersGauge.Set(float64(len(t.deadLetters)))
+}
+
+func (t *Tracker) nextAttempt(d *Delivery) time.Time {
+	backoff := t.retryBackoff
+	for i := 1; i < d.Attempts; i++ {
+		backoff *= 2
+	}
+	return d.LastAttempt.Add(backoff)
+}
+
+// DueRetries removes and returns the failed deliveries of the script whose backoff has elapsed, from the oldest to
+// the newest. The caller must record the result of the retry with RecordSuccess or RecordFailure.
+func (t *Tracker) DueRetries(scriptID uuid.UUID, now time.Time) []*Delivery {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var due []*Delivery
+	remaining := t.retries[:0]
+	for _, d := range t.retries {
+		if d.ScriptID == scriptID && !t.nextAttempt(d).After(now) {
+			due = append(due, d)
+			continue
+		}
+		remaining = append(remaining, d)
+	}
+	t.retries = remaining
+	return due
+}
+
+// Abandon moves the pending retries of the script to the sink to the dead-letter buffer, eg. because the data
+// that they would deliver is gone.
+func (t *Tracker) Abandon(scriptID uuid.UUID, sink Sink, reason string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	remaining := t.retries[:0]
+	for _, d := range t.retries {
+		if d.ScriptID != scriptID || d.Sink != sink {
+			remaining = append(remaining, d)
+			continue
+		}
+		d.LastAttempt = now
+		d.LastError = reason
+		e := t.unsafeExport(d)
+		e.deadLettered = true
+		e.lastError = reason
+		t.unsafeDeadLetter(d)
+	}
+	t.retries = remaining
+}
+
+// RemoveScript forgets the exports and the pending retries of a deleted script. Its dead letters are kept.
+func (t *Tracker) RemoveScript(scriptID uuid.UUID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for k := range t.exports {
+		if k.scriptID == scriptID {
+			delete(t.exports, k)
+		}
+	}
+	remaining := t.retries[:0]
+	for _, d := range t.retries {
+		if d.ScriptID != scriptID {
+			remaining = append(remaining, d)
+		}
+	}
+	t.retries = remaining
+}
+
+// DeadLetters returns a copy of the deliveries in the dead-letter buffer, from the oldest to the newest.
+func (t *Tracker) DeadLetters() []Delivery {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	letters := make([]Delivery, len(t.deadLetters))
+	for i, d := range t.deadLetters {
+		letters[i] = *d
+	}
+	return letters
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// Health returns the health of every export.
+func (t *Tracker) Health() *status.ExportHealth {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h := &status.ExportHealth{
+		PendingRetries:     len(t.retries),
+		DeadLetters:        len(t.deadLetters),
+		DroppedDeadLetters: t.droppedDeadLetters,
+	}
+	byKey := make(map[exportKey]*status.ExportStatus)
+	for k, e := range t.exports {
+		s := &status.ExportStatus{
+			ScriptID:            k.scriptID.String(),
+			Sink:                string(k.sink),
+			Failing:             e.deadLettered,
+			ConsecutiveFailures: e.consecutiveFailures,
+			LastSuccessTime:     timePtr(e.lastSuccess),
+			LastFailureTime:     timePtr(e.lastFailure),
+			LastError:           e.lastError,
+		}
+		byKey[k] = s
+		h.Exports = append(h.Exports, s)
+		if s.Failing {
+			h.FailingExports++
+		}
+		if e.lastFailure.After(e.lastSuccess) && (h.LastFailureTime == nil || e.lastFailure.After(*h.LastFailureTime)) {
+			h.LastFailureTime = timePtr(e.lastFailure)
+			h.LastError = e.lastError
+		}
+	}
+	for _, d := range t.retries {
+		if s, ok := byKey[exportKey{scriptID: d.ScriptID, sink: d.Sink}]; ok {
+			s.PendingRetries++
+		}
+	}
+	for _, d := range t.deadLetters {
+		if s, ok := byKey[exportKey{scriptID: d.ScriptID, sink: d.Sink}]; ok {
+			s.DeadLetters++
+		}
+	}
+	sort.Slice(h.Exports, func(i, j int) bool {
+		if h.Exports[i].ScriptID != h.Exports[j].ScriptID {
+			return h.Exports[i].ScriptID < h.Exports[j].ScriptID
+		}
+		return h.Exports[i].Sink < h.Exports[j].Sink
+	})
+	return h
+}
+
+// ServeHTTP serves the health of the exports as JSON.
+func (t *Tracker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(t.Health()); err != nil {
+		log.WithError(err).Error("Failed to write the export health")
+	}
+}
@@ -0,0 +1,180 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package exporthealth_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/shared/status"
+	"px.dev/pixie/src/vizier/services/query_broker/exporthealth"
+)
+
+var (
+	testScriptID  = uuid.Must(uuid.FromString("a4b7a2b8-1e80-4a17-9d8c-2e9f6f5d3c11"))
+	otherScriptID = uuid.Must(uuid.FromString("f1c3d9e2-6b0a-4e5f-8c7d-1a2b3c4d5e6f"))
+	testNow       = time.Unix(1700000000, 0)
+)
+
+func testDelivery(scriptID uuid.UUID, sink exporthealth.Sink, start time.Time) *exporthealth.Delivery {
+	return &exporthealth.Delivery{ScriptID: scriptID, Sink: sink, Start: start, End: start.Add(time.Minute)}
+}
+
+func TestIsOTelExportError(t *testing.T) {
+	assert.True(t, exporthealth.IsOTelExportError(
+		"OTel export (carnot node_id=3) failed with error 'UNAVAILABLE'. Details: connection refused"))
+	assert.False(t, exporthealth.IsOTelExportError("Table 'http_events' not found"))
+}
+
+func TestTracker_RetryWithBackoff(t *testing.T) {
+	tr := exporthealth.NewTracker(3, 10*time.Second, 10)
+	d := testDelivery(testScriptID, exporthealth.SinkOTel, testNow)
+
+	assert.False(t, tr.RecordFailure(d, errors.New("unavailable"), testNow))
+	assert.Equal(t, 1, d.Attempts)
+	assert.Empty(t, tr.DueRetries(testScriptID, testNow.Add(5*time.Second)))
+	assert.Empty(t, tr.DueRetries(otherScriptID, testNow.Add(time.Minute)))
+
+	due := tr.DueRetries(testScriptID, testNow.Add(10*time.Second))
+	require.Len(t, due, 1)
+	assert.Equal(t, d, due[0])
+	assert.Empty(t, tr.DueRetries(testScriptID, testNow.Add(time.Hour)))
+
+	// The backoff doubles after every failed retry.
+	now := testNow.Add(10 * time.Second)
+	assert.False(t, tr.RecordFailure(d, errors.New("unavailable"), now))
+	assert.Empty(t, tr.DueRetries(testScriptID, now.Add(19*time.Second)))
+	require.Len(t, tr.DueRetries(testScriptID, now.Add(20*time.Second)), 1)
+
+	tr.RecordSuccess(d, now.Add(20*time.Second))
+	h := tr.Health()
+	assert.Equal(t, 0, h.FailingExports)
+	assert.Equal(t, 0, h.PendingRetries)
+	require.Len(t, h.Exports, 1)
+	assert.Equal(t, 0, h.Exports[0].ConsecutiveFailures)
+	assert.False(t, h.Exports[0].Failing)
+	assert.Empty(t, h.LastError)
+}
+
+func TestTracker_DeadLetter(t *testing.T) {
+	tr := exporthealth.NewTracker(2, time.Second, 10)
+	d := testDelivery(testScriptID, exporthealth.SinkObjectStore, testNow)
+
+	assert.False(t, tr.RecordFailure(d, errors.New("access denied"), testNow))
+	require.Len(t, tr.DueRetries(testScriptID, testNow.Add(time.Second)), 1)
+	assert.True(t, tr.RecordFailure(d, errors.New("access denied"), testNow.Add(time.Second)))
+	assert.Empty(t, tr.DueRetries(testScriptID, testNow.Add(time.Hour)))
+
+	letters := tr.DeadLetters()
+	require.Len(t, letters, 1)
+	assert.Equal(t, 2, letters[0].Attempts)
+	assert.Equal(t, "access denied", letters[0].LastError)
+
+	h := tr.Health()
+	assert.Equal(t, 1, h.FailingExports)
+	assert.Equal(t, 1, h.DeadLetters)
+	assert.Equal(t, "access denied", h.LastError)
+	require.NotNil(t, h.LastFailureTime)
+	assert.Equal(t, testNow.Add(time.Second), *h.LastFailureTime)
+	require.Len(t, h.Exports, 1)
+	assert.Equal(t, &status.ExportStatus{
+		ScriptID:            testScriptID.String(),
+		Sink:                "objectstore",
+		Failing:             true,
+		ConsecutiveFailures: 2,
+		DeadLetters:         1,
+		LastFailureTime:     h.LastFailureTime,
+		LastError:           "access denied",
+	}, h.Exports[0])
+
+	// A later successful delivery makes the export healthy again, the dead letter is kept.
+	tr.RecordSuccess(testDelivery(testScriptID, exporthealth.SinkObjectStore, testNow.Add(time.Minute)), testNow.Add(time.Minute))
+	h = tr.Health()
+	assert.Equal(t, 0, h.FailingExports)
+	assert.Equal(t, 1, h.DeadLetters)
+}
+
+func TestTracker_DeadLetterBufferIsBounded(t *testing.T) {
+	tr := exporthealth.NewTracker(1, time.Second, 2)
+	for i := 0; i < 5; i++ {
+		start := testNow.Add(time.Duration(i) * time.Minute)
+		assert.True(t, tr.RecordFailure(testDelivery(testScriptID, exporthealth.SinkOTel, start), errors.New("timeout"), start))
+	}
+
+	letters := tr.DeadLetters()
+	require.Len(t, letters, 2)
+	assert.Equal(t, testNow.Add(3*time.Minute), letters[0].Start)
+	assert.Equal(t, testNow.Add(4*time.Minute), letters[1].Start)
+	assert.Equal(t, int64(3), tr.Health().DroppedDeadLetters)
+}
+
+func TestTracker_Abandon(t *testing.T) {
+	tr := exporthealth.NewTracker(3, time.Second, 10)
+	tr.RecordFailure(testDelivery(testScriptID, exporthealth.SinkObjectStore, testNow), errors.New("timeout"), testNow)
+	tr.RecordFailure(testDelivery(testScriptID, exporthealth.SinkOTel, testNow), errors.New("timeout"), testNow)
+
+	tr.Abandon(testScriptID, exporthealth.SinkObjectStore, "the script was updated", testNow)
+
+	letters := tr.DeadLetters()
+	require.Len(t, letters, 1)
+	assert.Equal(t, exporthealth.SinkObjectStore, letters[0].Sink)
+	assert.Equal(t, "the script was updated", letters[0].LastError)
+	due := tr.DueRetries(testScriptID, testNow.Add(time.Minute))
+	require.Len(t, due, 1)
+	assert.Equal(t, exporthealth.SinkOTel, due[0].Sink)
+}
+
+func TestTracker_RemoveScript(t *testing.T) {
+	tr := exporthealth.NewTracker(3, time.Second, 10)
+	tr.RecordFailure(testDelivery(testScriptID, exporthealth.SinkOTel, testNow), errors.New("timeout"), testNow)
+	tr.RecordSuccess(testDelivery(otherScriptID, exporthealth.SinkOTel, testNow), testNow)
+
+	tr.RemoveScript(testScriptID)
+
+	h := tr.Health()
+	assert.Equal(t, 0, h.PendingRetries)
+	require.Len(t, h.Exports, 1)
+	assert.Equal(t, otherScriptID.String(), h.Exports[0].ScriptID)
+	assert.Empty(t, tr.DueRetries(testScriptID, testNow.Add(time.Hour)))
+}
+
+func TestTracker_ServeHTTP(t *testing.T) {
+	tr := exporthealth.NewTracker(1, time.Second, 10)
+	tr.RecordFailure(testDelivery(testScriptID, exporthealth.SinkOTel, testNow), errors.New("timeout"), testNow)
+
+	rec := httptest.NewRecorder()
+	tr.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, status.ExportHealthPath, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	h := &status.ExportHealth{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), h))
+	assert.Equal(t, 1, h.FailingExports)
+	assert.Equal(t, 1, h.DeadLetters)
+	require.Len(t, h.Exports, 1)
+	assert.Equal(t, "otel", h.Exports[0].Sink)
+}
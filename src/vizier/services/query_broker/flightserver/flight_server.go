@@ -0,0 +1,191 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package flightserver serves query results over Arrow Flight, so that large results can be
+// retrieved in bulk as Arrow record batches rather than streamed row batch by row batch.
+package flightserver
+
+import (
+	"encoding/json"
+
+	"github.com/apache/arrow/go/v9/arrow"
+	"github.com/apache/arrow/go/v9/arrow/flight"
+	"github.com/apache/arrow/go/v9/arrow/ipc"
+	"github.com/apache/arrow/go/v9/arrow/memory"
+	"github.com/spf13/pflag"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+)
+
+func init() {
+	pflag.Bool("enable_arrow_flight", true, "Whether to serve query results over Arrow Flight, on the query broker's GRPC port")
+}
+
+// Ticket is the JSON encoded Arrow Flight ticket that describes the query to run.
+type Ticket struct {
+	// Query is the PxL script to run.
+	Query string `json:"query"`
+	// Table is the name of the output table to return. If empty, the first table output by the
+	// script is returned.
+	Table string `json:"table,omitempty"`
+	// QueryName is the optional name of the query, used by the query broker to track its execution time.
+	QueryName string `json:"query_name,omitempty"`
+}
+
+// Server is an Arrow Flight server that runs PxL scripts on the VizierService and returns their
+// results as Arrow record batches.
+type Server struct {
+	flight.BaseFlightServer
+
+	vz  vizierpb.VizierServiceServer
+	mem memory.Allocator
+}
+
+// NewServer creates a Flight server that runs queries on the given VizierService.
+func NewServer(vz vizierpb.VizierServiceServer) *Server {
+	return &Server{
+		vz:  vz,
+		mem: memory.DefaultAllocator,
+	}
+}
+
+// Register registers the Flight service on the GRPC server, so that Flight requests go through the
+// same authentication as the rest of the query broker's services.
+func (s *Server) Register(g *grpc.Server) {
+	g.RegisterService(&flightServiceDesc, s)
+}
+
+// flightServiceDesc describes the subset of the Arrow Flight service that is implemented. The Flight
+// package only registers its service on GRPC servers that it creates itself.
+var flightServiceDesc = grpc.ServiceDesc{
+	ServiceName: "arrow.flight.protocol.FlightService",
+	HandlerType: (*flight.FlightServer)(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "DoGet",
+			Handler:       doGetHandler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "Flight.proto",
+}
+
+func doGetHandler(srv interface{}, stream grpc.ServerStream) error {
+	tkt := new(flight.Ticket)
+	if err := stream.RecvMsg(tkt); err != nil {
+		return err
+	}
+	return srv.(flight.FlightServer).DoGet(tkt, &doGetServer{stream})
+}
+
+type doGetServer struct {
+	grpc.ServerStream
+}
+
+func (d *doGetServer) Send(data *flight.FlightData) error {
+	return d.ServerStream.SendMsg(data)
+}
+
+// DoGet runs the query in the ticket and streams one of its output tables back.
+func (s *Server) DoGet(tkt *flight.Ticket, stream flight.FlightService_DoGetServer) error {
+	var t Ticket
+	if err := json.Unmarshal(tkt.Ticket, &t); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid ticket: %v", err)
+	}
+	if t.Query == "" {
+		return status.Error(codes.InvalidArgument, "ticket is missing a query")
+	}
+
+	srv := &executeScriptServer{
+		ServerStream: stream,
+		stream:       stream,
+		table:        t.Table,
+		mem:          s.mem,
+	}
+	defer srv.close()
+
+	req := &vizierpb.ExecuteScriptRequest{
+		QueryStr:  t.Query,
+		QueryName: t.QueryName,
+	}
+	if err := s.vz.ExecuteScript(req, srv); err != nil {
+		return err
+	}
+	if srv.writer == nil {
+		if t.Table == "" {
+			return status.Error(codes.NotFound, "script did not output any tables")
+		}
+		return status.Errorf(codes.NotFound, "script did not output table %q", t.Table)
+	}
+	return nil
+}
+
+// executeScriptServer adapts a Flight DoGet stream into an ExecuteScript stream. It writes
+// the batches of the selected table to the Flight stream and drops the rest.
+type executeScriptServer struct {
+	grpc.ServerStream
+
+	stream flight.FlightService_DoGetServer
+	table  string
+	mem    memory.Allocator
+
+	tableID string
+	schema  *arrow.Schema
+	writer  *flight.Writer
+}
+
+func (e *executeScriptServer) Send(resp *vizierpb.ExecuteScriptResponse) error {
+	if resp.Status != nil && resp.Status.Code != int32(codes.OK) {
+		return status.Error(codes.Code(resp.Status.Code), resp.Status.Message)
+	}
+	if md := resp.GetMetaData(); md != nil {
+		return e.handleMetadata(md)
+	}
+	if batch := resp.GetData().GetBatch(); batch != nil && e.writer != nil && batch.TableID == e.tableID {
+		rec, err := recordFromBatch(e.mem, e.schema, batch)
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to convert row batch: %v", err)
+		}
+		defer rec.Release()
+		return e.writer.Write(rec)
+	}
+	return nil
+}
+
+func (e *executeScriptServer) handleMetadata(md *vizierpb.QueryMetadata) error {
+	if e.writer != nil || (e.table != "" && md.Name != e.table) {
+		return nil
+	}
+	schema, err := schemaFromRelation(md.Relation)
+	if err != nil {
+		return status.Errorf(codes.Unimplemented, "cannot return table %q: %v", md.Name, err)
+	}
+	e.tableID = md.ID
+	e.schema = schema
+	e.writer = flight.NewRecordWriter(e.stream, ipc.WithSchema(schema), ipc.WithAllocator(e.mem))
+	return nil
+}
+
+func (e *executeScriptServer) close() {
+	if e.writer != nil {
+		e.writer.Close()
+	}
+}
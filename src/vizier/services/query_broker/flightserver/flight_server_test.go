@@ -0,0 +1,206 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package flightserver_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/apache/arrow/go/v9/arrow"
+	"github.com/apache/arrow/go/v9/arrow/array"
+	"github.com/apache/arrow/go/v9/arrow/flight"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/vizier/services/query_broker/flightserver"
+)
+
+const bufSize = 1024 * 1024
+
+type fakeVzServer struct {
+	vizierpb.UnimplementedVizierServiceServer
+}
+
+func relation() *vizierpb.Relation {
+	return &vizierpb.Relation{
+		Columns: []*vizierpb.Relation_ColumnInfo{
+			{ColumnName: "time_", ColumnType: vizierpb.TIME64NS, ColumnSemanticType: vizierpb.ST_TIME_NS},
+			{ColumnName: "upid", ColumnType: vizierpb.UINT128, ColumnSemanticType: vizierpb.ST_UPID},
+			{ColumnName: "req_path", ColumnType: vizierpb.STRING},
+			{ColumnName: "latency", ColumnType: vizierpb.FLOAT64},
+			{ColumnName: "resp_status", ColumnType: vizierpb.INT64},
+			{ColumnName: "encrypted", ColumnType: vizierpb.BOOLEAN},
+		},
+	}
+}
+
+func batch(tableID string, eos bool) *vizierpb.ExecuteScriptResponse {
+	return &vizierpb.ExecuteScriptResponse{
+		Result: &vizierpb.ExecuteScriptResponse_Data{
+			Data: &vizierpb.QueryData{
+				Batch: &vizierpb.RowBatchData{
+					TableID: tableID,
+					NumRows: 2,
+					Eos:     eos,
+					Eow:     eos,
+					Cols: []*vizierpb.Column{
+						{ColData: &vizierpb.Column_Time64NsData{Time64NsData: &vizierpb.Time64NSColumn{Data: []int64{10, 20}}}},
+						{ColData: &vizierpb.Column_Uint128Data{Uint128Data: &vizierpb.UInt128Column{Data: []*vizierpb.UInt128{
+							{High: 1, Low: 2}, {High: 3, Low: 4},
+						}}}},
+						{ColData: &vizierpb.Column_StringData{StringData: &vizierpb.StringColumn{Data: [][]byte{[]byte("/a"), []byte("/b")}}}},
+						{ColData: &vizierpb.Column_Float64Data{Float64Data: &vizierpb.Float64Column{Data: []float64{1.5, 2.5}}}},
+						{ColData: &vizierpb.Column_Int64Data{Int64Data: &vizierpb.Int64Column{Data: []int64{200, 500}}}},
+						{ColData: &vizierpb.Column_BooleanData{BooleanData: &vizierpb.BooleanColumn{Data: []bool{true, false}}}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func metadata(name, id string) *vizierpb.ExecuteScriptResponse {
+	return &vizierpb.ExecuteScriptResponse{
+		Result: &vizierpb.ExecuteScriptResponse_MetaData{
+			MetaData: &vizierpb.QueryMetadata{Name: name, ID: id, Relation: relation()},
+		},
+	}
+}
+
+func (f *fakeVzServer) ExecuteScript(req *vizierpb.ExecuteScriptRequest, srv vizierpb.VizierService_ExecuteScriptServer) error {
+	if req.QueryStr == "compile error" {
+		return srv.Send(&vizierpb.ExecuteScriptResponse{
+			Status: &vizierpb.Status{Code: int32(codes.InvalidArgument), Message: "bad script"},
+		})
+	}
+	resps := []*vizierpb.ExecuteScriptResponse{
+		metadata("http", "1"),
+		metadata("dns", "2"),
+		batch("1", false),
+		batch("2", true),
+		batch("1", true),
+	}
+	for _, resp := range resps {
+		if err := srv.Send(resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newTestClient(t *testing.T) flight.FlightServiceClient {
+	lis := bufconn.Listen(bufSize)
+	s := grpc.NewServer()
+	flightserver.NewServer(&fakeVzServer{}).Register(s)
+	go func() { _ = s.Serve(lis) }()
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, url string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+		s.Stop()
+	})
+	return flight.NewFlightServiceClient(conn)
+}
+
+func doGet(t *testing.T, client flight.FlightServiceClient, tkt flightserver.Ticket) (*flight.Reader, error) {
+	b, err := json.Marshal(tkt)
+	require.NoError(t, err)
+	stream, err := client.DoGet(context.Background(), &flight.Ticket{Ticket: b})
+	require.NoError(t, err)
+	return flight.NewRecordReader(stream)
+}
+
+func TestServer_DoGet(t *testing.T) {
+	tests := []struct {
+		name  string
+		table string
+	}{
+		{name: "named table", table: "http"},
+		{name: "first table", table: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := newTestClient(t)
+			r, err := doGet(t, client, flightserver.Ticket{Query: "px.display(df)", Table: test.table})
+			require.NoError(t, err)
+			defer r.Release()
+
+			schema := r.Schema()
+			require.Equal(t, 6, len(schema.Fields()))
+			assert.Equal(t, "time_", schema.Field(0).Name)
+			assert.Equal(t, arrow.FixedWidthTypes.Timestamp_ns, schema.Field(0).Type)
+			assert.Equal(t, &arrow.FixedSizeBinaryType{ByteWidth: 16}, schema.Field(1).Type)
+			md := schema.Field(1).Metadata
+			idx := md.FindKey("pixie.semantic_type")
+			require.NotEqual(t, -1, idx)
+			assert.Equal(t, "ST_UPID", md.Values()[idx])
+
+			numRecords := 0
+			for r.Next() {
+				rec := r.Record()
+				assert.Equal(t, int64(2), rec.NumRows())
+				assert.Equal(t, arrow.Timestamp(20), rec.Column(0).(*array.Timestamp).Value(1))
+				assert.Equal(t, []byte{0, 0, 0, 0, 0, 0, 0, 3, 0, 0, 0, 0, 0, 0, 0, 4},
+					rec.Column(1).(*array.FixedSizeBinary).Value(1))
+				assert.Equal(t, "/b", rec.Column(2).(*array.String).Value(1))
+				assert.Equal(t, 2.5, rec.Column(3).(*array.Float64).Value(1))
+				assert.Equal(t, int64(500), rec.Column(4).(*array.Int64).Value(1))
+				assert.False(t, rec.Column(5).(*array.Boolean).Value(1))
+				numRecords++
+			}
+			require.NoError(t, r.Err())
+			// Only the batches of the selected table are returned.
+			assert.Equal(t, 2, numRecords)
+		})
+	}
+}
+
+func TestServer_DoGetErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		ticket []byte
+		code   codes.Code
+	}{
+		{name: "invalid ticket", ticket: []byte("not json"), code: codes.InvalidArgument},
+		{name: "missing query", ticket: []byte(`{"table": "http"}`), code: codes.InvalidArgument},
+		{name: "compile error", ticket: []byte(`{"query": "compile error"}`), code: codes.InvalidArgument},
+		{name: "missing table", ticket: []byte(`{"query": "px.display(df)", "table": "conn_stats"}`), code: codes.NotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := newTestClient(t)
+			stream, err := client.DoGet(context.Background(), &flight.Ticket{Ticket: test.ticket})
+			require.NoError(t, err)
+			_, err = stream.Recv()
+			require.Error(t, err)
+			assert.Equal(t, test.code, status.Code(err))
+		})
+	}
+}
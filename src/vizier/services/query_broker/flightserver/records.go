@@ -0,0 +1,145 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package flightserver
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/apache/arrow/go/v9/arrow"
+	"github.com/apache/arrow/go/v9/arrow/array"
+	"github.com/apache/arrow/go/v9/arrow/memory"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+)
+
+// semanticTypeMetadataKey is the Arrow field metadata key that holds the column's semantic type.
+const semanticTypeMetadataKey = "pixie.semantic_type"
+
+// uint128Type stores UINT128 values, such as UPIDs, as 16 big-endian bytes: the high bits
+// followed by the low bits.
+var uint128Type = &arrow.FixedSizeBinaryType{ByteWidth: 16}
+
+func arrowType(t vizierpb.DataType) (arrow.DataType, error) {
+	switch t {
+	case vizierpb.BOOLEAN:
+		return arrow.FixedWidthTypes.Boolean, nil
+	case vizierpb.INT64:
+		return arrow.PrimitiveTypes.Int64, nil
+	case vizierpb.UINT128:
+		return uint128Type, nil
+	case vizierpb.FLOAT64:
+		return arrow.PrimitiveTypes.Float64, nil
+	case vizierpb.STRING:
+		return arrow.BinaryTypes.String, nil
+	case vizierpb.TIME64NS:
+		return arrow.FixedWidthTypes.Timestamp_ns, nil
+	default:
+		return nil, fmt.Errorf("unsupported data type %s", t.String())
+	}
+}
+
+// schemaFromRelation converts the relation of a table into an Arrow schema.
+func schemaFromRelation(rel *vizierpb.Relation) (*arrow.Schema, error) {
+	fields := make([]arrow.Field, len(rel.GetColumns()))
+	for i, col := range rel.GetColumns() {
+		dt, err := arrowType(col.ColumnType)
+		if err != nil {
+			return nil, fmt.Errorf("column %q: %w", col.ColumnName, err)
+		}
+		fields[i] = arrow.Field{
+			Name: col.ColumnName,
+			Type: dt,
+			Metadata: arrow.NewMetadata(
+				[]string{semanticTypeMetadataKey},
+				[]string{col.ColumnSemanticType.String()},
+			),
+		}
+	}
+	return arrow.NewSchema(fields, nil), nil
+}
+
+// recordFromBatch converts a row batch into an Arrow record with the given schema.
+func recordFromBatch(mem memory.Allocator, schema *arrow.Schema, batch *vizierpb.RowBatchData) (arrow.Record, error) {
+	if len(batch.Cols) != len(schema.Fields()) {
+		return nil, fmt.Errorf("row batch has %d columns, expected %d", len(batch.Cols), len(schema.Fields()))
+	}
+	b := array.NewRecordBuilder(mem, schema)
+	defer b.Release()
+
+	for i, col := range batch.Cols {
+		if err := appendColumn(b.Field(i), col); err != nil {
+			return nil, fmt.Errorf("column %q: %w", schema.Field(i).Name, err)
+		}
+	}
+	rec := b.NewRecord()
+	if rec.NumRows() != batch.NumRows {
+		rec.Release()
+		return nil, fmt.Errorf("row batch has %d rows, expected %d", rec.NumRows(), batch.NumRows)
+	}
+	return rec, nil
+}
+
+func appendColumn(fb array.Builder, col *vizierpb.Column) error {
+	switch c := col.ColData.(type) {
+	case *vizierpb.Column_BooleanData:
+		if b, ok := fb.(*array.BooleanBuilder); ok {
+			b.AppendValues(c.BooleanData.Data, nil)
+			return nil
+		}
+	case *vizierpb.Column_Int64Data:
+		if b, ok := fb.(*array.Int64Builder); ok {
+			b.AppendValues(c.Int64Data.Data, nil)
+			return nil
+		}
+	case *vizierpb.Column_Uint128Data:
+		if b, ok := fb.(*array.FixedSizeBinaryBuilder); ok {
+			buf := make([]byte, 16)
+			for _, v := range c.Uint128Data.Data {
+				binary.BigEndian.PutUint64(buf[:8], v.High)
+				binary.BigEndian.PutUint64(buf[8:], v.Low)
+				b.Append(buf)
+			}
+			return nil
+		}
+	case *vizierpb.Column_Float64Data:
+		if b, ok := fb.(*array.Float64Builder); ok {
+			b.AppendValues(c.Float64Data.Data, nil)
+			return nil
+		}
+	case *vizierpb.Column_StringData:
+		if b, ok := fb.(*array.StringBuilder); ok {
+			for _, v := range c.StringData.Data {
+				b.Append(string(v))
+			}
+			return nil
+		}
+	case *vizierpb.Column_Time64NsData:
+		if b, ok := fb.(*array.TimestampBuilder); ok {
+			b.Reserve(len(c.Time64NsData.Data))
+			for _, v := range c.Time64NsData.Data {
+				b.UnsafeAppend(arrow.Timestamp(v))
+			}
+			return nil
+		}
+	default:
+		return fmt.Errorf("unsupported column data %T", col.ColData)
+	}
+	return fmt.Errorf("column data %T does not match builder %T", col.ColData, fb)
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	azureStorageScope      = "https://storage.azure.com/.default"
+	azureStorageAPIVersion = "2021-08-06"
+	azureDefaultAuthority  = "https://login.microsoftonline.com/"
+)
+
+// AzureBlobBucket stores objects in an Azure Blob Storage container.
+type AzureBlobBucket struct {
+	client    *http.Client
+	endpoint  string
+	container string
+	prefix    string
+}
+
+// NewAzureBlobBucket creates a bucket for the container in the storage account at the endpoint, eg.
+// "https://myaccount.blob.core.windows.net". The client must authenticate its requests to the storage account.
+func NewAzureBlobBucket(client *http.Client, endpoint string, container string, prefix string) *AzureBlobBucket {
+	return &AzureBlobBucket{
+		client:    client,
+		endpoint:  strings.TrimSuffix(endpoint, "/"),
+		container: container,
+		prefix:    prefix,
+	}
+}
+
+func (b *AzureBlobBucket) blobURL(key string) string {
+	return fmt.Sprintf("%s/%s/%s", b.endpoint, b.container, path.Join(b.prefix, key))
+}
+
+// Get reads an object from the bucket.
+func (b *AzureBlobBucket) Get(ctx context.Context, key string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.blobURL(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-ms-version", azureStorageAPIVersion)
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrObjectNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get blob '%s': %s", key, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Put writes an object to the bucket, replacing any existing object.
+func (b *AzureBlobBucket) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, b.blobURL(key), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-ms-version", azureStorageAPIVersion)
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("failed to put blob '%s': %s", key, resp.Status)
+	}
+	return nil
+}
+
+// workloadIdentityTokenSource exchanges the service account token projected into the pod by Azure Workload
+// Identity for an Azure AD access token.
+type workloadIdentityTokenSource struct {
+	authorityHost string
+	tenantID      string
+	clientID      string
+	tokenFile     string
+	scope         string
+}
+
+// NewWorkloadIdentityTokenSource creates a token source for the Azure AD application with the given client ID,
+// which trusts the federated token in tokenFile. The tokens are cached until shortly before they expire.
+func NewWorkloadIdentityTokenSource(authorityHost, tenantID, clientID, tokenFile, scope string) oauth2.TokenSource {
+	if authorityHost == "" {
+		authorityHost = azureDefaultAuthority
+	}
+	return oauth2.ReuseTokenSource(nil, &workloadIdentityTokenSource{
+		authorityHost: authorityHost,
+		tenantID:      tenantID,
+		clientID:      clientID,
+		tokenFile:     tokenFile,
+		scope:         scope,
+	})
+}
+
+// newWorkloadIdentityTokenSourceFromEnv creates a token source from the environment variables that the Azure
+// Workload Identity webhook injects into pods which use it.
+func newWorkloadIdentityTokenSourceFromEnv() (oauth2.TokenSource, error) {
+	tenantID := os.Getenv("AZURE_TENANT_ID")
+	clientID := os.Getenv("AZURE_CLIENT_ID")
+	tokenFile := os.Getenv("AZURE_FEDERATED_TOKEN_FILE")
+	if tenantID == "" || clientID == "" || tokenFile == "" {
+		return nil, errors.New("azure workload identity is not configured for this pod")
+	}
+	return NewWorkloadIdentityTokenSource(os.Getenv("AZURE_AUTHORITY_HOST"), tenantID, clientID, tokenFile, azureStorageScope), nil
+}
+
+// Token reads the federated token and exchanges it for an access token.
+func (ts *workloadIdentityTokenSource) Token() (*oauth2.Token, error) {
+	// The federated token is rotated by the kubelet, so it's read again for every exchange.
+	assertion, err := os.ReadFile(ts.tokenFile)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", ts.clientID)
+	form.Set("scope", ts.scope)
+	form.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+	form.Set("client_assertion", strings.TrimSpace(string(assertion)))
+
+	tokenURL := fmt.Sprintf("%s/%s/oauth2/v2.0/token", strings.TrimSuffix(ts.authorityHost, "/"), ts.tenantID)
+	resp, err := http.PostForm(tokenURL, form)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to exchange azure workload identity token: %s", resp.Status)
+	}
+
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return nil, err
+	}
+	return &oauth2.Token{
+		AccessToken: tokenResp.AccessToken,
+		TokenType:   tokenResp.TokenType,
+		Expiry:      time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second),
+	}, nil
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package objectstore_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/vizier/services/query_broker/objectstore"
+)
+
+// fakeBlobServer is an in-memory Azure Blob Storage service.
+type fakeBlobServer struct {
+	mu    sync.Mutex
+	blobs map[string][]byte
+}
+
+func (s *fakeBlobServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		if r.Header.Get("x-ms-blob-type") != "BlockBlob" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		s.blobs[r.URL.Path] = b
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodGet:
+		b, ok := s.blobs[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(b)
+	}
+}
+
+func TestAzureBlobBucket(t *testing.T) {
+	blobs := &fakeBlobServer{blobs: make(map[string][]byte)}
+	srv := httptest.NewServer(blobs)
+	defer srv.Close()
+
+	b := objectstore.NewAzureBlobBucket(srv.Client(), srv.URL, "container", "pixie")
+	ctx := context.Background()
+
+	_, err := b.Get(ctx, "manifest.json")
+	assert.Equal(t, objectstore.ErrObjectNotFound, err)
+
+	require.NoError(t, b.Put(ctx, "manifest.json", []byte("{}"), "application/json"))
+	assert.Contains(t, blobs.blobs, "/container/pixie/manifest.json")
+
+	data, err := b.Get(ctx, "manifest.json")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("{}"), data)
+}
+
+func TestWorkloadIdentityTokenSource(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("federated-token\n"), 0600))
+
+	numExchanges := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		numExchanges++
+		assert.Equal(t, "/tenant/oauth2/v2.0/token", r.URL.Path)
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "client", r.PostForm.Get("client_id"))
+		assert.Equal(t, "federated-token", r.PostForm.Get("client_assertion"))
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	}))
+	defer srv.Close()
+
+	ts := objectstore.NewWorkloadIdentityTokenSource(srv.URL, "tenant", "client", tokenFile, "https://storage.azure.com/.default")
+	tok, err := ts.Token()
+	require.NoError(t, err)
+	assert.Equal(t, "access-token", tok.AccessToken)
+
+	// The token is reused until it expires.
+	_, err = ts.Token()
+	require.NoError(t, err)
+	assert.Equal(t, 1, numExchanges)
+}
@@ -0,0 +1,182 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/option"
+
+	"px.dev/pixie/src/shared/scripts"
+)
+
+// ErrObjectNotFound is returned by Bucket.Get when the object doesn't exist.
+var ErrObjectNotFound = errors.New("object not found")
+
+// Bucket is a location in object storage. Keys are relative to the bucket's prefix.
+type Bucket interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+}
+
+// NewBucket creates a bucket for the s3://, gs:// or az:// URL in the config. When the config has no static
+// credentials, S3 and GCS pick them up from the environment, which includes EKS IRSA and GKE Workload Identity.
+// Azure Blob Storage is only accessed through Azure Workload Identity.
+func NewBucket(ctx context.Context, cfg *scripts.ObjectStoreConfig) (Bucket, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid object store URL: %w", err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("object store URL '%s' must include a bucket", cfg.URL)
+	}
+	prefix := strings.Trim(u.Path, "/")
+
+	switch u.Scheme {
+	case "s3":
+		awsCfg := &aws.Config{Region: aws.String(cfg.Region)}
+		if cfg.Endpoint != "" {
+			awsCfg.Endpoint = aws.String(cfg.Endpoint)
+			awsCfg.S3ForcePathStyle = aws.Bool(true)
+		}
+		if cfg.AccessKeyID != "" {
+			awsCfg.Credentials = credentials.NewStaticCredentials(cfg.AccessKeyID, cfg.SecretAccessKey, "")
+		}
+		sess, err := session.NewSession(awsCfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewS3Bucket(s3.New(sess), u.Host, prefix), nil
+	case "gs":
+		var opts []option.ClientOption
+		if cfg.ServiceAccountKey != "" {
+			opts = append(opts, option.WithCredentialsJSON([]byte(cfg.ServiceAccountKey)))
+		}
+		client, err := storage.NewClient(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return NewGCSBucket(client.Bucket(u.Host), prefix), nil
+	case "az":
+		endpoint := cfg.Endpoint
+		if endpoint == "" {
+			if cfg.StorageAccount == "" {
+				return nil, errors.New("a storage account or endpoint is required for Azure Blob Storage")
+			}
+			endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", cfg.StorageAccount)
+		}
+		ts, err := newWorkloadIdentityTokenSourceFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		return NewAzureBlobBucket(oauth2.NewClient(ctx, ts), endpoint, u.Host, prefix), nil
+	default:
+		return nil, fmt.Errorf("unsupported object store scheme '%s', expected s3, gs or az", u.Scheme)
+	}
+}
+
+// S3Bucket stores objects in AWS S3 or an S3 compatible store.
+type S3Bucket struct {
+	client s3iface.S3API
+	bucket string
+	prefix string
+}
+
+// NewS3Bucket creates a bucket that uses the given S3 client.
+func NewS3Bucket(client s3iface.S3API, bucket string, prefix string) *S3Bucket {
+	return &S3Bucket{client: client, bucket: bucket, prefix: prefix}
+}
+
+// Get reads an object from the bucket.
+func (b *S3Bucket) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := b.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(path.Join(b.prefix, key)),
+	})
+	if err != nil {
+		var aerr awserr.Error
+		if errors.As(err, &aerr) && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, ErrObjectNotFound
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// Put writes an object to the bucket, replacing any existing object.
+func (b *S3Bucket) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	_, err := b.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(b.bucket),
+		Key:         aws.String(path.Join(b.prefix, key)),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(contentType),
+	})
+	return err
+}
+
+// GCSBucket stores objects in Google Cloud Storage.
+type GCSBucket struct {
+	bucket *storage.BucketHandle
+	prefix string
+}
+
+// NewGCSBucket creates a bucket that uses the given GCS bucket handle.
+func NewGCSBucket(bucket *storage.BucketHandle, prefix string) *GCSBucket {
+	return &GCSBucket{bucket: bucket, prefix: prefix}
+}
+
+// Get reads an object from the bucket.
+func (b *GCSBucket) Get(ctx context.Context, key string) ([]byte, error) {
+	r, err := b.bucket.Object(path.Join(b.prefix, key)).NewReader(ctx)
+	if err == storage.ErrObjectNotExist {
+		return nil, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// Put writes an object to the bucket, replacing any existing object.
+func (b *GCSBucket) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	w := b.bucket.Object(path.Join(b.prefix, key)).NewWriter(ctx)
+	w.ContentType = contentType
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,418 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package objectstore
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/shared/scripts"
+)
+
+const (
+	schemaManifestName    = "_schema.json"
+	partitionManifestName = "_manifest.json"
+)
+
+// PartitionKeys are the Hive style partitions that data files are written under, in order. They can be declared
+// as partition columns (or used for partition projection) in Athena, and are detected automatically by BigQuery
+// external tables with hive partitioning enabled.
+var PartitionKeys = []string{"dt", "hour", "cluster_id"}
+
+// ErrNothingToRetry is returned by Retry when there are no tables of the window that failed to be written.
+var ErrNothingToRetry = errors.New("no failed tables to retry")
+
+// SchemaManifest describes the layout of an exported table. It is written to <table>/_schema.json.
+type SchemaManifest struct {
+	Table         string            `json:"table"`
+	Location      string            `json:"location"`
+	Format        string            `json:"format"`
+	PartitionKeys []string          `json:"partitionKeys"`
+	Columns       []*ColumnManifest `json:"columns"`
+	UpdatedAt     time.Time         `json:"updatedAt"`
+}
+
+// ColumnManifest describes a single column of an exported table.
+type ColumnManifest struct {
+	Name string `json:"name"`
+	// Type is the Hive/Athena type of the column, eg. "bigint" or "timestamp".
+	Type         string `json:"type"`
+	PixieType    string `json:"pixieType"`
+	SemanticType string `json:"semanticType,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// PartitionManifest lists the data files that have been written to a partition. It is written to
+// <table>/dt=<date>/hour=<hour>/cluster_id=<id>/_manifest.json.
+type PartitionManifest struct {
+	Partition map[string]string `json:"partition"`
+	Files     []*FileManifest   `json:"files"`
+}
+
+// FileManifest describes a single data file in a partition.
+type FileManifest struct {
+	Path          string `json:"path"`
+	ScriptID      string `json:"scriptID"`
+	NumRows       int64  `json:"numRows"`
+	SizeBytes     int64  `json:"sizeBytes"`
+	WindowStartNs int64  `json:"windowStartNs"`
+}
+
+// manifestMu serializes updates to partition manifests, which are read, modified and written back. Every runner in
+// the query broker shares it, since several scripts can export to the same table.
+var manifestMu sync.Mutex
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// tableDirName converts a table name into a name that is safe to use as a Hive table and path, eg. "HTTP Events"
+// becomes "http_events".
+func tableDirName(name string) string {
+	n := strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if n == "" {
+		return "output"
+	}
+	return n
+}
+
+type table struct {
+	md      *vizierpb.QueryMetadata
+	batches []*vizierpb.RowBatchData
+	numRows int64
+}
+
+// Exporter collects the tables output by a single run of a script and writes them to object storage as Parquet,
+// along with schema and partition manifests.
+type Exporter struct {
+	bucket    Bucket
+	location  string
+	clusterID string
+	scriptID  uuid.UUID
+
+	mu     sync.Mutex
+	tables map[string]*table
+	// failed are the tables that Flush failed to write, by the start of their window in nanoseconds. They are kept
+	// until they are retried or discarded.
+	failed map[int64][]*table
+}
+
+// NewExporter creates an exporter that writes to the bucket. The location is the URL of the bucket, which is
+// recorded in the schema manifests.
+func NewExporter(bucket Bucket, location string, clusterID string, scriptID uuid.UUID) *Exporter {
+	return &Exporter{
+		bucket:    bucket,
+		location:  strings.TrimSuffix(location, "/"),
+		clusterID: clusterID,
+		scriptID:  scriptID,
+		tables:    make(map[string]*table),
+		failed:    make(map[int64][]*table),
+	}
+}
+
+// NewExporterFromConfig creates an exporter for the bucket specified in the config.
+func NewExporterFromConfig(ctx context.Context, cfg *scripts.ObjectStoreConfig, clusterID string, scriptID uuid.UUID) (*Exporter, error) {
+	if cfg == nil {
+		return nil, errors.New("object store config must not be nil")
+	}
+	b, err := NewBucket(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewExporter(b, cfg.URL, clusterID, scriptID), nil
+}
+
+// ObserveTable registers the metadata of an output table.
+func (e *Exporter) ObserveTable(md *vizierpb.QueryMetadata) {
+	if md == nil || md.Relation == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.tables[md.ID] = &table{md: md}
+}
+
+// ObserveBatch buffers a batch of rows for its table until the next Flush.
+func (e *Exporter) ObserveBatch(b *vizierpb.RowBatchData) {
+	if b == nil || b.NumRows == 0 {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	t, ok := e.tables[b.TableID]
+	if !ok {
+		return
+	}
+	t.batches = append(t.batches, b)
+	t.numRows += b.NumRows
+}
+
+// Reset drops any buffered rows, eg. after a failed run.
+func (e *Exporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.tables = make(map[string]*table)
+}
+
+// Flush writes the rows buffered for each table to a new Parquet file, partitioned by the start of the window the
+// script ran over, and then resets the exporter. It returns the number of bytes of Parquet data written. The tables
+// that fail to be written are kept, so that they can be written by Retry.
+func (e *Exporter) Flush(ctx context.Context, windowStart time.Time) (int64, error) {
+	e.mu.Lock()
+	tables := make([]*table, 0, len(e.tables))
+	for _, t := range e.tables {
+		tables = append(tables, t)
+	}
+	e.tables = make(map[string]*table)
+	e.mu.Unlock()
+
+	return e.writeTables(ctx, tables, windowStart)
+}
+
+// Retry writes the tables of the window that a previous Flush or Retry failed to write. It returns
+// ErrNothingToRetry if there are no such tables, eg. because they were discarded.
+func (e *Exporter) Retry(ctx context.Context, windowStart time.Time) (int64, error) {
+	e.mu.Lock()
+	tables, ok := e.failed[windowStart.UnixNano()]
+	delete(e.failed, windowStart.UnixNano())
+	e.mu.Unlock()
+	if !ok {
+		return 0, ErrNothingToRetry
+	}
+	return e.writeTables(ctx, tables, windowStart)
+}
+
+// Discard drops the tables of the window that failed to be written.
+func (e *Exporter) Discard(windowStart time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.failed, windowStart.UnixNano())
+}
+
+func (e *Exporter) writeTables(ctx context.Context, tables []*table, windowStart time.Time) (int64, error) {
+	var errs []string
+	var failed []*table
+	var written int64
+	for _, t := range tables {
+		if t.numRows == 0 {
+			continue
+		}
+		n, err := e.writeTable(ctx, t, windowStart)
+		written += n
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", t.md.Name, err))
+			failed = append(failed, t)
+		}
+	}
+	if len(errs) > 0 {
+		e.mu.Lock()
+		e.failed[windowStart.UnixNano()] = failed
+		e.mu.Unlock()
+		return written, fmt.Errorf("failed to export tables: %s", strings.Join(errs, "; "))
+	}
+	return written, nil
+}
+
+// writeTable writes the table's Parquet file and manifests, and returns the size of the Parquet file if it was
+// written.
+func (e *Exporter) writeTable(ctx context.Context, t *table, windowStart time.Time) (int64, error) {
+	data, err := tableToParquet(t)
+	if err != nil {
+		return 0, err
+	}
+
+	dir := tableDirName(t.md.Name)
+	windowStart = windowStart.UTC()
+	partition := map[string]string{
+		"dt":         windowStart.Format("2006-01-02"),
+		"hour":       windowStart.Format("15"),
+		"cluster_id": e.clusterID,
+	}
+	partitionDir := dir
+	for _, k := range PartitionKeys {
+		partitionDir = path.Join(partitionDir, fmt.Sprintf("%s=%s", k, partition[k]))
+	}
+	fileName := fmt.Sprintf("%s-%d.parquet", e.scriptID, windowStart.UnixNano())
+
+	if err := e.bucket.Put(ctx, path.Join(partitionDir, fileName), data, "application/vnd.apache.parquet"); err != nil {
+		return 0, err
+	}
+	size := int64(len(data))
+	if err := e.writeSchemaManifest(ctx, t, dir); err != nil {
+		return size, err
+	}
+	return size, e.appendToPartitionManifest(ctx, partitionDir, partition, &FileManifest{
+		Path:          fileName,
+		ScriptID:      e.scriptID.String(),
+		NumRows:       t.numRows,
+		SizeBytes:     size,
+		WindowStartNs: windowStart.UnixNano(),
+	})
+}
+
+func (e *Exporter) writeSchemaManifest(ctx context.Context, t *table, dir string) error {
+	m := &SchemaManifest{
+		Table:         dir,
+		Location:      e.location + "/" + dir + "/",
+		Format:        "parquet",
+		PartitionKeys: PartitionKeys,
+		UpdatedAt:     time.Now().UTC(),
+	}
+	for _, c := range t.md.Relation.Columns {
+		m.Columns = append(m.Columns, &ColumnManifest{
+			Name:         c.ColumnName,
+			Type:         hiveTypes[c.ColumnType],
+			PixieType:    c.ColumnType.String(),
+			SemanticType: c.ColumnSemanticType.String(),
+			Description:  c.ColumnDesc,
+		})
+	}
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return e.bucket.Put(ctx, path.Join(dir, schemaManifestName), b, "application/json")
+}
+
+func (e *Exporter) appendToPartitionManifest(ctx context.Context, dir string, partition map[string]string, f *FileManifest) error {
+	manifestMu.Lock()
+	defer manifestMu.Unlock()
+
+	key := path.Join(dir, partitionManifestName)
+	m := &PartitionManifest{Partition: partition}
+	existing, err := e.bucket.Get(ctx, key)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(existing, m); err != nil {
+			return fmt.Errorf("invalid partition manifest: %w", err)
+		}
+	case !errors.Is(err, ErrObjectNotFound):
+		return err
+	}
+	m.Files = append(m.Files, f)
+
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return e.bucket.Put(ctx, key, b, "application/json")
+}
+
+var hiveTypes = map[vizierpb.DataType]string{
+	vizierpb.BOOLEAN:  "boolean",
+	vizierpb.INT64:    "bigint",
+	vizierpb.UINT128:  "string",
+	vizierpb.FLOAT64:  "double",
+	vizierpb.STRING:   "string",
+	vizierpb.TIME64NS: "timestamp",
+}
+
+// tableToParquet converts the buffered batches of a table into a Parquet file. UINT128 values, which are mostly
+// UPIDs, are written as UUID formatted strings, and times are truncated to microseconds since that is the finest
+// timestamp precision supported by Athena and BigQuery.
+func tableToParquet(t *table) ([]byte, error) {
+	relCols := t.md.Relation.Columns
+	cols := make([]*parquetColumn, len(relCols))
+	for i, c := range relCols {
+		pc := &parquetColumn{name: c.ColumnName, convertedType: convertedNone}
+		switch c.ColumnType {
+		case vizierpb.BOOLEAN:
+			pc.physicalType = parquetBoolean
+		case vizierpb.INT64:
+			pc.physicalType = parquetInt64
+		case vizierpb.TIME64NS:
+			pc.physicalType = parquetInt64
+			pc.convertedType = convertedTimestampMicros
+		case vizierpb.FLOAT64:
+			pc.physicalType = parquetDouble
+		case vizierpb.STRING, vizierpb.UINT128:
+			pc.physicalType = parquetByteArray
+			pc.convertedType = convertedUTF8
+		default:
+			return nil, fmt.Errorf("column '%s' has unsupported type %s", c.ColumnName, c.ColumnType.String())
+		}
+		cols[i] = pc
+	}
+
+	for _, b := range t.batches {
+		if len(b.Cols) != len(cols) {
+			return nil, fmt.Errorf("batch has %d columns, expected %d", len(b.Cols), len(cols))
+		}
+		for i, c := range b.Cols {
+			if err := appendColumn(cols[i], c, b.NumRows); err != nil {
+				return nil, fmt.Errorf("column '%s': %w", cols[i].name, err)
+			}
+		}
+	}
+	return writeParquet(cols, t.numRows)
+}
+
+func appendColumn(pc *parquetColumn, c *vizierpb.Column, numRows int64) error {
+	var n int
+	switch d := c.ColData.(type) {
+	case *vizierpb.Column_BooleanData:
+		n = len(d.BooleanData.Data)
+		for _, v := range d.BooleanData.Data {
+			pc.appendBool(v)
+		}
+	case *vizierpb.Column_Int64Data:
+		n = len(d.Int64Data.Data)
+		for _, v := range d.Int64Data.Data {
+			pc.appendInt64(v)
+		}
+	case *vizierpb.Column_Time64NsData:
+		n = len(d.Time64NsData.Data)
+		for _, v := range d.Time64NsData.Data {
+			pc.appendInt64(v / int64(time.Microsecond))
+		}
+	case *vizierpb.Column_Float64Data:
+		n = len(d.Float64Data.Data)
+		for _, v := range d.Float64Data.Data {
+			pc.appendDouble(v)
+		}
+	case *vizierpb.Column_StringData:
+		n = len(d.StringData.Data)
+		for _, v := range d.StringData.Data {
+			pc.appendByteArray(v)
+		}
+	case *vizierpb.Column_Uint128Data:
+		n = len(d.Uint128Data.Data)
+		var b [16]byte
+		for _, v := range d.Uint128Data.Data {
+			binary.BigEndian.PutUint64(b[:8], v.High)
+			binary.BigEndian.PutUint64(b[8:], v.Low)
+			pc.appendByteArray([]byte(uuid.FromBytesOrNil(b[:]).String()))
+		}
+	default:
+		return errors.New("unsupported column data")
+	}
+	if int64(n) != numRows {
+		return fmt.Errorf("has %d values, expected %d", n, numRows)
+	}
+	return nil
+}
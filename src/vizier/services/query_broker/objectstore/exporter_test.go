@@ -0,0 +1,381 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package objectstore_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/vizier/services/query_broker/objectstore"
+)
+
+type fakeBucket struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	// putErr is returned by Put when it is set.
+	putErr error
+}
+
+func newFakeBucket() *fakeBucket {
+	return &fakeBucket{objects: make(map[string][]byte)}
+}
+
+func (b *fakeBucket) Get(ctx context.Context, key string) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	o, ok := b.objects[key]
+	if !ok {
+		return nil, objectstore.ErrObjectNotFound
+	}
+	return o, nil
+}
+
+func (b *fakeBucket) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.putErr != nil {
+		return b.putErr
+	}
+	b.objects[key] = data
+	return nil
+}
+
+var (
+	testScriptID  = uuid.FromStringOrNil("223e4567-e89b-12d3-a456-426655440001")
+	testClusterID = "7ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	testWindow    = time.Date(2026, 10, 14, 7, 30, 0, 0, time.UTC)
+)
+
+func testMetadata() *vizierpb.QueryMetadata {
+	return &vizierpb.QueryMetadata{
+		ID:   "table-1",
+		Name: "HTTP Events",
+		Relation: &vizierpb.Relation{
+			Columns: []*vizierpb.Relation_ColumnInfo{
+				{ColumnName: "time_", ColumnType: vizierpb.TIME64NS},
+				{ColumnName: "upid", ColumnType: vizierpb.UINT128},
+				{ColumnName: "service", ColumnType: vizierpb.STRING, ColumnSemanticType: vizierpb.ST_SERVICE_NAME},
+				{ColumnName: "latency", ColumnType: vizierpb.FLOAT64, ColumnDesc: "Request latency"},
+				{ColumnName: "resp_status", ColumnType: vizierpb.INT64},
+				{ColumnName: "is_error", ColumnType: vizierpb.BOOLEAN},
+			},
+		},
+	}
+}
+
+func testBatch(services ...string) *vizierpb.RowBatchData {
+	n := len(services)
+	times := make([]int64, n)
+	upids := make([]*vizierpb.UInt128, n)
+	svcs := make([][]byte, n)
+	latencies := make([]float64, n)
+	statuses := make([]int64, n)
+	errs := make([]bool, n)
+	for i, s := range services {
+		times[i] = testWindow.UnixNano() + int64(i)*int64(time.Second)
+		upids[i] = &vizierpb.UInt128{High: 1, Low: uint64(i)}
+		svcs[i] = []byte(s)
+		latencies[i] = float64(i) + 0.5
+		statuses[i] = 200
+		errs[i] = i%2 == 1
+	}
+	return &vizierpb.RowBatchData{
+		TableID: "table-1",
+		NumRows: int64(n),
+		Cols: []*vizierpb.Column{
+			{ColData: &vizierpb.Column_Time64NsData{Time64NsData: &vizierpb.Time64NSColumn{Data: times}}},
+			{ColData: &vizierpb.Column_Uint128Data{Uint128Data: &vizierpb.UInt128Column{Data: upids}}},
+			{ColData: &vizierpb.Column_StringData{StringData: &vizierpb.StringColumn{Data: svcs}}},
+			{ColData: &vizierpb.Column_Float64Data{Float64Data: &vizierpb.Float64Column{Data: latencies}}},
+			{ColData: &vizierpb.Column_Int64Data{Int64Data: &vizierpb.Int64Column{Data: statuses}}},
+			{ColData: &vizierpb.Column_BooleanData{BooleanData: &vizierpb.BooleanColumn{Data: errs}}},
+		},
+	}
+}
+
+const (
+	testFileKey     = "http_events/dt=2026-10-14/hour=07/cluster_id=7ba7b810-9dad-11d1-80b4-00c04fd430c8/223e4567-e89b-12d3-a456-426655440001-1791963000000000000.parquet"
+	testManifestKey = "http_events/dt=2026-10-14/hour=07/cluster_id=7ba7b810-9dad-11d1-80b4-00c04fd430c8/_manifest.json"
+	testSchemaKey   = "http_events/_schema.json"
+	testBucketURL   = "s3://bucket/pixie"
+	testNumCols     = 6
+)
+
+func TestExporter_Flush(t *testing.T) {
+	b := newFakeBucket()
+	e := objectstore.NewExporter(b, testBucketURL, testClusterID, testScriptID)
+	e.ObserveTable(testMetadata())
+	e.ObserveBatch(testBatch("a", "b"))
+	e.ObserveBatch(testBatch("c"))
+	// Batches for unknown tables are ignored.
+	e.ObserveBatch(&vizierpb.RowBatchData{TableID: "other", NumRows: 1})
+
+	written, err := e.Flush(context.Background(), testWindow)
+	require.NoError(t, err)
+	assert.Len(t, b.objects, 3)
+
+	data, ok := b.objects[testFileKey]
+	require.True(t, ok)
+	assert.Equal(t, int64(len(data)), written)
+	footer := readParquetFooter(t, data)
+	assert.Equal(t, int64(3), footer[3])
+	schema := footer[2].([]interface{})
+	require.Len(t, schema, testNumCols+1)
+	names := make([]string, 0, testNumCols)
+	for _, s := range schema[1:] {
+		names = append(names, string(s.(map[int16]interface{})[4].([]byte)))
+	}
+	assert.Equal(t, []string{"time_", "upid", "service", "latency", "resp_status", "is_error"}, names)
+
+	// Check that the resp_status column holds the plain encoded values.
+	rowGroup := footer[4].([]interface{})[0].(map[int16]interface{})
+	chunk := rowGroup[1].([]interface{})[4].(map[int16]interface{})
+	offset := chunk[3].(map[int16]interface{})[9].(int64)
+	values := readDataPage(t, data[offset:])
+	require.Len(t, values, 3*8)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, uint64(200), binary.LittleEndian.Uint64(values[i*8:]))
+	}
+
+	var schemaManifest objectstore.SchemaManifest
+	require.NoError(t, json.Unmarshal(b.objects[testSchemaKey], &schemaManifest))
+	assert.Equal(t, "http_events", schemaManifest.Table)
+	assert.Equal(t, "s3://bucket/pixie/http_events/", schemaManifest.Location)
+	assert.Equal(t, []string{"dt", "hour", "cluster_id"}, schemaManifest.PartitionKeys)
+	require.Len(t, schemaManifest.Columns, testNumCols)
+	assert.Equal(t, "timestamp", schemaManifest.Columns[0].Type)
+	assert.Equal(t, "string", schemaManifest.Columns[1].Type)
+	assert.Equal(t, "ST_SERVICE_NAME", schemaManifest.Columns[2].SemanticType)
+	assert.Equal(t, "Request latency", schemaManifest.Columns[3].Description)
+
+	var partitionManifest objectstore.PartitionManifest
+	require.NoError(t, json.Unmarshal(b.objects[testManifestKey], &partitionManifest))
+	assert.Equal(t, map[string]string{"dt": "2026-10-14", "hour": "07", "cluster_id": testClusterID}, partitionManifest.Partition)
+	require.Len(t, partitionManifest.Files, 1)
+	assert.Equal(t, int64(3), partitionManifest.Files[0].NumRows)
+	assert.Equal(t, int64(len(data)), partitionManifest.Files[0].SizeBytes)
+}
+
+func TestExporter_FlushAppendsToPartitionManifest(t *testing.T) {
+	b := newFakeBucket()
+	e := objectstore.NewExporter(b, testBucketURL, testClusterID, testScriptID)
+
+	for i := 0; i < 2; i++ {
+		e.ObserveTable(testMetadata())
+		e.ObserveBatch(testBatch("a"))
+		_, err := e.Flush(context.Background(), testWindow.Add(time.Duration(i)*time.Minute))
+		require.NoError(t, err)
+	}
+
+	var partitionManifest objectstore.PartitionManifest
+	require.NoError(t, json.Unmarshal(b.objects[testManifestKey], &partitionManifest))
+	require.Len(t, partitionManifest.Files, 2)
+	assert.NotEqual(t, partitionManifest.Files[0].Path, partitionManifest.Files[1].Path)
+}
+
+func TestExporter_Reset(t *testing.T) {
+	b := newFakeBucket()
+	e := objectstore.NewExporter(b, testBucketURL, testClusterID, testScriptID)
+	e.ObserveTable(testMetadata())
+	e.ObserveBatch(testBatch("a"))
+	e.Reset()
+
+	written, err := e.Flush(context.Background(), testWindow)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), written)
+	assert.Empty(t, b.objects)
+}
+
+func TestExporter_FlushMismatchedBatch(t *testing.T) {
+	b := newFakeBucket()
+	e := objectstore.NewExporter(b, testBucketURL, testClusterID, testScriptID)
+	e.ObserveTable(testMetadata())
+	batch := testBatch("a", "b")
+	batch.Cols = batch.Cols[:2]
+	e.ObserveBatch(batch)
+
+	_, err := e.Flush(context.Background(), testWindow)
+	assert.Error(t, err)
+	assert.Empty(t, b.objects)
+}
+
+func TestExporter_RetryFailedFlush(t *testing.T) {
+	b := newFakeBucket()
+	b.putErr = errors.New("access denied")
+	e := objectstore.NewExporter(b, testBucketURL, testClusterID, testScriptID)
+	e.ObserveTable(testMetadata())
+	e.ObserveBatch(testBatch("a", "b"))
+
+	_, err := e.Flush(context.Background(), testWindow)
+	require.Error(t, err)
+	assert.Empty(t, b.objects)
+
+	// The failed tables are kept for a retry, and aren't written by the next flush.
+	_, err = e.Retry(context.Background(), testWindow)
+	require.Error(t, err)
+	b.mu.Lock()
+	b.putErr = nil
+	b.mu.Unlock()
+	written, err := e.Flush(context.Background(), testWindow.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), written)
+
+	written, err = e.Retry(context.Background(), testWindow)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(b.objects[testFileKey])), written)
+	assert.Contains(t, b.objects, testManifestKey)
+
+	_, err = e.Retry(context.Background(), testWindow)
+	assert.ErrorIs(t, err, objectstore.ErrNothingToRetry)
+}
+
+func TestExporter_Discard(t *testing.T) {
+	b := newFakeBucket()
+	b.putErr = errors.New("access denied")
+	e := objectstore.NewExporter(b, testBucketURL, testClusterID, testScriptID)
+	e.ObserveTable(testMetadata())
+	e.ObserveBatch(testBatch("a"))
+
+	_, err := e.Flush(context.Background(), testWindow)
+	require.Error(t, err)
+	e.Discard(testWindow)
+
+	_, err = e.Retry(context.Background(), testWindow)
+	assert.ErrorIs(t, err, objectstore.ErrNothingToRetry)
+}
+
+// readParquetFooter checks the framing of a Parquet file and decodes its metadata into a generic form, where
+// structs are maps from field ID to value and lists are slices.
+func readParquetFooter(t *testing.T, data []byte) map[int16]interface{} {
+	require.True(t, len(data) > 12)
+	require.Equal(t, "PAR1", string(data[:4]))
+	require.Equal(t, "PAR1", string(data[len(data)-4:]))
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	r := bytes.NewReader(data[len(data)-8-footerLen : len(data)-8])
+	md, err := readThriftStruct(r)
+	require.NoError(t, err)
+	assert.Zero(t, r.Len())
+	return md
+}
+
+// readDataPage decodes the page header at the start of data and returns the uncompressed page.
+func readDataPage(t *testing.T, data []byte) []byte {
+	r := bytes.NewReader(data)
+	header, err := readThriftStruct(r)
+	require.NoError(t, err)
+	headerLen := len(data) - r.Len()
+	compressedLen := int(header[3].(int32))
+	zr, err := gzip.NewReader(bytes.NewReader(data[headerLen : headerLen+compressedLen]))
+	require.NoError(t, err)
+	page, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, int(header[2].(int32)), len(page))
+	return page
+}
+
+func readThriftStruct(r *bytes.Reader) (map[int16]interface{}, error) {
+	fields := make(map[int16]interface{})
+	var last int16
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b == 0 {
+			return fields, nil
+		}
+		typ := b & 0x0f
+		id := last + int16(b>>4)
+		if b>>4 == 0 {
+			v, err := binary.ReadUvarint(r)
+			if err != nil {
+				return nil, err
+			}
+			id = int16(v>>1) ^ -int16(v&1)
+		}
+		last = id
+		v, err := readThriftValue(r, typ)
+		if err != nil {
+			return nil, err
+		}
+		fields[id] = v
+	}
+}
+
+func readThriftValue(r *bytes.Reader, typ byte) (interface{}, error) {
+	switch typ {
+	case 1, 2:
+		return typ == 1, nil
+	case 5, 6:
+		v, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		n := int64(v>>1) ^ -int64(v&1)
+		if typ == 5 {
+			return int32(n), nil
+		}
+		return n, nil
+	case 8:
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, n)
+		_, err = io.ReadFull(r, b)
+		return b, err
+	case 9:
+		h, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		size := int(h >> 4)
+		if size == 15 {
+			n, err := binary.ReadUvarint(r)
+			if err != nil {
+				return nil, err
+			}
+			size = int(n)
+		}
+		elems := make([]interface{}, size)
+		for i := range elems {
+			if elems[i], err = readThriftValue(r, h&0x0f); err != nil {
+				return nil, err
+			}
+		}
+		return elems, nil
+	case 12:
+		return readThriftStruct(r)
+	default:
+		return nil, errors.New("unsupported thrift type")
+	}
+}
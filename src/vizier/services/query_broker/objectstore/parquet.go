@@ -0,0 +1,290 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package objectstore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"math"
+)
+
+// This file implements the subset of the Parquet format needed to write flat tables: a single row group of
+// REQUIRED columns, each stored as one PLAIN encoded, GZIP compressed data page. The field IDs and enum values
+// used below come from parquet.thrift in https://github.com/apache/parquet-format.
+
+const (
+	parquetMagic = "PAR1"
+
+	parquetBoolean   = int32(0)
+	parquetInt64     = int32(2)
+	parquetDouble    = int32(5)
+	parquetByteArray = int32(6)
+
+	convertedNone            = int32(-1)
+	convertedUTF8            = int32(0)
+	convertedTimestampMicros = int32(10)
+
+	repetitionRequired = int32(0)
+	encodingPlain      = int32(0)
+	encodingRLE        = int32(3)
+	codecGzip          = int32(2)
+	pageTypeData       = int32(0)
+)
+
+// parquetColumn accumulates the PLAIN encoded values of a single column.
+type parquetColumn struct {
+	name          string
+	physicalType  int32
+	convertedType int32
+
+	values    bytes.Buffer
+	bools     []bool
+	numValues int64
+}
+
+func (c *parquetColumn) appendBool(v bool) {
+	c.bools = append(c.bools, v)
+	c.numValues++
+}
+
+func (c *parquetColumn) appendInt64(v int64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(v))
+	c.values.Write(b[:])
+	c.numValues++
+}
+
+func (c *parquetColumn) appendDouble(v float64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], math.Float64bits(v))
+	c.values.Write(b[:])
+	c.numValues++
+}
+
+func (c *parquetColumn) appendByteArray(v []byte) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], uint32(len(v)))
+	c.values.Write(b[:])
+	c.values.Write(v)
+	c.numValues++
+}
+
+// encoded returns the PLAIN encoding of the column's values. Booleans are bit packed, least significant bit first.
+func (c *parquetColumn) encoded() []byte {
+	if c.physicalType != parquetBoolean {
+		return c.values.Bytes()
+	}
+	packed := make([]byte, (len(c.bools)+7)/8)
+	for i, v := range c.bools {
+		if v {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	return packed
+}
+
+// writeParquet writes the columns to a Parquet file. All columns must have numRows values.
+func writeParquet(columns []*parquetColumn, numRows int64) ([]byte, error) {
+	var out bytes.Buffer
+	out.WriteString(parquetMagic)
+
+	type chunk struct {
+		offset           int64
+		uncompressedSize int64
+		compressedSize   int64
+	}
+	chunks := make([]chunk, len(columns))
+	var totalSize int64
+	for i, c := range columns {
+		values := c.encoded()
+		var compressed bytes.Buffer
+		zw := gzip.NewWriter(&compressed)
+		if _, err := zw.Write(values); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+
+		header := newThriftWriter()
+		header.i32(1, pageTypeData)
+		header.i32(2, int32(len(values)))
+		header.i32(3, int32(compressed.Len()))
+		header.structField(5)
+		header.i32(1, int32(c.numValues))
+		header.i32(2, encodingPlain)
+		// Required columns have no definition or repetition levels, but the encodings must still be set.
+		header.i32(3, encodingRLE)
+		header.i32(4, encodingRLE)
+		header.structEnd()
+		header.structEnd()
+
+		chunks[i] = chunk{
+			offset:           int64(out.Len()),
+			uncompressedSize: int64(header.buf.Len() + len(values)),
+			compressedSize:   int64(header.buf.Len() + compressed.Len()),
+		}
+		totalSize += chunks[i].uncompressedSize
+		out.Write(header.buf.Bytes())
+		out.Write(compressed.Bytes())
+	}
+
+	md := newThriftWriter()
+	md.i32(1, 1)
+	md.listBegin(2, thriftStruct, len(columns)+1)
+	// The root of the schema is a group containing every column.
+	md.structBegin()
+	md.binary(4, []byte("schema"))
+	md.i32(5, int32(len(columns)))
+	md.structEnd()
+	for _, c := range columns {
+		md.structBegin()
+		md.i32(1, c.physicalType)
+		md.i32(3, repetitionRequired)
+		md.binary(4, []byte(c.name))
+		if c.convertedType != convertedNone {
+			md.i32(6, c.convertedType)
+		}
+		md.structEnd()
+	}
+	md.i64(3, numRows)
+	md.listBegin(4, thriftStruct, 1)
+	md.structBegin()
+	md.listBegin(1, thriftStruct, len(columns))
+	for i, c := range columns {
+		md.structBegin()
+		md.i64(2, chunks[i].offset)
+		md.structField(3)
+		md.i32(1, c.physicalType)
+		md.listBegin(2, thriftI32, 1)
+		md.listI32(encodingPlain)
+		md.listBegin(3, thriftBinary, 1)
+		md.listBinary([]byte(c.name))
+		md.i32(4, codecGzip)
+		md.i64(5, c.numValues)
+		md.i64(6, chunks[i].uncompressedSize)
+		md.i64(7, chunks[i].compressedSize)
+		md.i64(9, chunks[i].offset)
+		md.structEnd()
+		md.structEnd()
+	}
+	md.i64(2, totalSize)
+	md.i64(3, numRows)
+	md.structEnd()
+	md.binary(6, []byte("pixie"))
+	md.structEnd()
+
+	out.Write(md.buf.Bytes())
+	var footerLen [4]byte
+	binary.LittleEndian.PutUint32(footerLen[:], uint32(md.buf.Len()))
+	out.Write(footerLen[:])
+	out.WriteString(parquetMagic)
+	return out.Bytes(), nil
+}
+
+// Thrift compact protocol type IDs.
+const (
+	thriftI32    = byte(5)
+	thriftI64    = byte(6)
+	thriftBinary = byte(8)
+	thriftList   = byte(9)
+	thriftStruct = byte(12)
+)
+
+// thriftWriter encodes structs using the Thrift compact protocol, which is how Parquet stores its metadata.
+// Callers are responsible for writing fields in increasing ID order and matching every struct begin with an end.
+type thriftWriter struct {
+	buf bytes.Buffer
+	// lastField holds the ID of the last field written in each open struct, since field IDs are delta encoded.
+	lastField []int16
+}
+
+// newThriftWriter returns a writer with the top level struct already open.
+func newThriftWriter() *thriftWriter {
+	return &thriftWriter{lastField: []int16{0}}
+}
+
+func (w *thriftWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	w.buf.Write(b[:n])
+}
+
+func (w *thriftWriter) fieldHeader(id int16, typ byte) {
+	last := &w.lastField[len(w.lastField)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.varint(uint64(uint16((id << 1) ^ (id >> 15))))
+	}
+	*last = id
+}
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.fieldHeader(id, thriftI32)
+	w.listI32(v)
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.fieldHeader(id, thriftI64)
+	w.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (w *thriftWriter) binary(id int16, v []byte) {
+	w.fieldHeader(id, thriftBinary)
+	w.listBinary(v)
+}
+
+// structField opens a struct valued field.
+func (w *thriftWriter) structField(id int16) {
+	w.fieldHeader(id, thriftStruct)
+	w.structBegin()
+}
+
+// structBegin opens a struct, either as a list element or after structField.
+func (w *thriftWriter) structBegin() {
+	w.lastField = append(w.lastField, 0)
+}
+
+func (w *thriftWriter) structEnd() {
+	w.buf.WriteByte(0)
+	w.lastField = w.lastField[:len(w.lastField)-1]
+}
+
+// listBegin writes the header of a list valued field. It must be followed by size elements.
+func (w *thriftWriter) listBegin(id int16, elemType byte, size int) {
+	w.fieldHeader(id, thriftList)
+	if size < 15 {
+		w.buf.WriteByte(byte(size)<<4 | elemType)
+		return
+	}
+	w.buf.WriteByte(0xf0 | elemType)
+	w.varint(uint64(size))
+}
+
+func (w *thriftWriter) listI32(v int32) {
+	w.varint(uint64(uint32((v << 1) ^ (v >> 31))))
+}
+
+func (w *thriftWriter) listBinary(v []byte) {
+	w.varint(uint64(len(v)))
+	w.buf.Write(v)
+}
@@ -19,33 +19,26 @@
 package main
 
 import (
-	"context"
-	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"time"
 
-	"github.com/cenkalti/backoff/v4"
 	"github.com/nats-io/nats.go"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"google.golang.org/grpc"
 
-	"px.dev/pixie/src/api/proto/vizierpb"
-	"px.dev/pixie/src/carnot/carnotpb"
 	"px.dev/pixie/src/shared/services"
 	"px.dev/pixie/src/shared/services/healthz"
 	"px.dev/pixie/src/shared/services/httpmiddleware"
 	"px.dev/pixie/src/shared/services/metrics"
+	"px.dev/pixie/src/shared/services/msgbus"
 	"px.dev/pixie/src/shared/services/server"
-	"px.dev/pixie/src/vizier/services/metadata/metadatapb"
-	"px.dev/pixie/src/vizier/services/query_broker/controllers"
-	"px.dev/pixie/src/vizier/services/query_broker/ptproxy"
 	"px.dev/pixie/src/vizier/services/query_broker/querybrokerenv"
+	"px.dev/pixie/src/vizier/services/query_broker/querybrokerserver"
 	scriptrunner "px.dev/pixie/src/vizier/services/query_broker/script_runner"
-	"px.dev/pixie/src/vizier/services/query_broker/tracker"
 )
 
 const (
@@ -60,23 +53,9 @@ func init() {
 	pflag.String("mds_port", "50400", "The querybroker service port")
 	pflag.String("pod_namespace", "pl", "The namespace this pod runs in.")
 	pflag.StringArray("cron_script_sources", scriptrunner.DefaultSources, "Where to find cron scripts (cloud, configmaps)")
-}
-
-// NewVizierServiceClient creates a new vz RPC client stub.
-func NewVizierServiceClient(port uint) (vizierpb.VizierServiceClient, error) {
-	dialOpts, err := services.GetGRPCClientDialOpts()
-	if err != nil {
-		return nil, err
-	}
-
-	// Note: This has to be localhost to pass the SSL cert verification.
-	addr := fmt.Sprintf("localhost:%d", port)
-	vzChannel, err := grpc.Dial(addr, dialOpts...)
-	if err != nil {
-		return nil, err
-	}
-
-	return vizierpb.NewVizierServiceClient(vzChannel), nil
+	pflag.Int("cron_script_export_max_attempts", 3, "The number of times the export of a cron script run is attempted before it is dead-lettered")
+	pflag.Duration("cron_script_export_retry_backoff", 30*time.Second, "How long to wait before the first retry of a failed cron script export, doubled for every further retry")
+	pflag.Int("cron_script_export_dead_letter_limit", 256, "The number of failed cron script exports kept in the dead-letter buffer")
 }
 
 func main() {
@@ -110,50 +89,16 @@ func main() {
 	metrics.MustRegisterMetricsHandlerNoDefaultMetrics(mux)
 
 	// Connect to metadata service.
-	dialOpts, err := services.GetGRPCClientDialOpts()
-	if err != nil {
-		log.WithError(err).Fatal("Could not get dial opts.")
-	}
-	dialOpts = append(dialOpts, grpc.WithBlock())
-	dialOpts = append(dialOpts, grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(8*1024*1024)))
-
-	bOpts := backoff.NewExponentialBackOff()
-	bOpts.InitialInterval = 15 * time.Second
-	bOpts.MaxElapsedTime = 5 * time.Minute
-
-	var mdsConn *grpc.ClientConn
 	mdsAddr := fmt.Sprintf("%s.%s.svc:%s", viper.GetString("mds_service"), viper.GetString("pod_namespace"), viper.GetString("mds_port"))
-	err = backoff.Retry(func() error {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-		mdsConn, err = grpc.DialContext(ctx, mdsAddr, dialOpts...)
-		if !errors.Is(err, context.DeadlineExceeded) {
-			// Any errors that aren't timeouts are treated as permanent errors.
-			return backoff.Permanent(err)
-		}
-		return err
-	}, bOpts)
-
+	mdsConn, err := querybrokerserver.DialMetadataService(mdsAddr)
 	if err != nil {
 		log.WithError(err).Fatal("Failed to connect to Metadata Service.")
 	}
 
 	defer mdsConn.Close()
 
-	mdsClient := metadatapb.NewMetadataServiceClient(mdsConn)
-	mdtpClient := metadatapb.NewMetadataTracepointServiceClient(mdsConn)
-	mdconfClient := metadatapb.NewMetadataConfigServiceClient(mdsConn)
-	csClient := metadatapb.NewCronScriptStoreServiceClient(mdsConn)
-
 	// Connect to NATS.
-	var natsConn *nats.Conn
-	if viper.GetBool("disable_ssl") {
-		natsConn, err = nats.Connect("pl-nats")
-	} else {
-		natsConn, err = nats.Connect("pl-nats",
-			nats.ClientCert(viper.GetString("client_tls_cert"), viper.GetString("client_tls_key")),
-			nats.RootCAs(viper.GetString("tls_ca_cert")))
-	}
+	natsConn, err := nats.Connect("pl-nats", msgbus.ClientOptions()...)
 	if err != nil {
 		log.WithError(err).Fatal("Failed to connect to NATS.")
 	}
@@ -163,19 +108,12 @@ func main() {
 			Error("Got nats error")
 	})
 
-	dataPrivacy, err := controllers.CreateDataPrivacyManager(viper.GetString("pod_namespace"))
+	qbSvr, err := querybrokerserver.New(env, mdsConn, natsConn)
 	if err != nil {
-		log.WithError(err).Fatal("Failed to create data privacy manager.")
+		log.WithError(err).Fatal("Failed to start query broker.")
 	}
-
-	agentTracker := tracker.NewAgents(mdsClient, viper.GetString("jwt_signing_key"))
-	agentTracker.Start()
-	defer agentTracker.Stop()
-	svr, err := controllers.NewServer(env, agentTracker, dataPrivacy, mdtpClient, mdconfClient, natsConn, controllers.NewQueryExecutorFromServer)
-	if err != nil {
-		log.WithError(err).Fatal("Failed to initialize GRPC server funcs.")
-	}
-	defer svr.Close()
+	defer qbSvr.Close()
+	qbSvr.InstallHandlers(mux)
 
 	// For query broker we bump up the max message size since resuls might be larger than 4mb.
 	maxMsgSize := grpc.MaxRecvMsgSize(8 * 1024 * 1024)
@@ -183,46 +121,11 @@ func main() {
 	s := server.NewPLServer(env,
 		httpmiddleware.WithBearerAuthMiddleware(env, mux), maxMsgSize)
 
-	carnotpb.RegisterResultSinkServiceServer(s.GRPCServer(), svr)
-	vizierpb.RegisterVizierServiceServer(s.GRPCServer(), svr)
-
-	// For the passthrough proxy we create a GRPC client to the current server. It appears really
-	// hard to emulate the streaming GRPC connection and this helps keep the API straightforward.
-	vzServiceClient, err := NewVizierServiceClient(servicePort)
-	if err != nil {
-		log.WithError(err).Fatal("Failed to init vzservice client.")
-	}
-
-	// Start passthrough proxy.
-	ptProxy, err := ptproxy.NewPassThroughProxy(natsConn, vzServiceClient)
+	qbSvr.RegisterGRPC(s.GRPCServer())
+	err = qbSvr.Start(servicePort)
 	if err != nil {
-		log.WithError(err).Fatal("Failed to start passthrough proxy.")
+		log.WithError(err).Fatal("Failed to start query broker.")
 	}
-	go func() {
-		err := ptProxy.Run()
-		if err != nil {
-			log.WithError(err).Error("Passthrough proxy failed to run")
-		}
-	}()
-	defer ptProxy.Close()
-
-	// Start cron script runner.
-	sources := scriptrunner.Sources(
-		natsConn,
-		csClient,
-		viper.GetString("jwt_signing_key"),
-		viper.GetString("pod_namespace"),
-		viper.GetStringSlice("cron_script_sources"),
-	)
-	sr := scriptrunner.New(csClient, vzServiceClient, viper.GetString("jwt_signing_key"), sources...)
-
-	// Load the scripts and start the background sync.
-	go func() {
-		err := sr.SyncScripts()
-		if err != nil {
-			log.WithError(err).Error("Failed to sync cron scripts")
-		}
-	}()
 
 	s.Start()
 	s.StopOnInterrupt()
@@ -0,0 +1,238 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package querybrokerserver runs the components of the query broker, so that they can be
+// started either by the query broker service or by the consolidated control plane.
+package querybrokerserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/nats-io/nats.go"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/carnot/carnotpb"
+	"px.dev/pixie/src/shared/services"
+	"px.dev/pixie/src/shared/services/selftest"
+	"px.dev/pixie/src/shared/status"
+	"px.dev/pixie/src/vizier/services/metadata/metadatapb"
+	"px.dev/pixie/src/vizier/services/query_broker/controllers"
+	"px.dev/pixie/src/vizier/services/query_broker/exporthealth"
+	"px.dev/pixie/src/vizier/services/query_broker/flightserver"
+	"px.dev/pixie/src/vizier/services/query_broker/ptproxy"
+	"px.dev/pixie/src/vizier/services/query_broker/querybrokerenv"
+	scriptrunner "px.dev/pixie/src/vizier/services/query_broker/script_runner"
+	"px.dev/pixie/src/vizier/services/query_broker/tracker"
+)
+
+// NewVizierServiceClient creates a new vz RPC client stub.
+func NewVizierServiceClient(port uint) (vizierpb.VizierServiceClient, error) {
+	dialOpts, err := services.GetGRPCClientDialOpts()
+	if err != nil {
+		return nil, err
+	}
+
+	// Note: This has to be localhost to pass the SSL cert verification.
+	addr := fmt.Sprintf("localhost:%d", port)
+	vzChannel, err := grpc.Dial(addr, dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return vizierpb.NewVizierServiceClient(vzChannel), nil
+}
+
+// DialMetadataService connects to the metadata service at the given address, retrying while
+// the metadata service comes up.
+func DialMetadataService(mdsAddr string) (*grpc.ClientConn, error) {
+	dialOpts, err := services.GetGRPCClientDialOpts()
+	if err != nil {
+		return nil, err
+	}
+	dialOpts = append(dialOpts, grpc.WithBlock())
+	dialOpts = append(dialOpts, grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(8*1024*1024)))
+
+	bOpts := backoff.NewExponentialBackOff()
+	bOpts.InitialInterval = 15 * time.Second
+	bOpts.MaxElapsedTime = 5 * time.Minute
+
+	var mdsConn *grpc.ClientConn
+	err = backoff.Retry(func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		mdsConn, err = grpc.DialContext(ctx, mdsAddr, dialOpts...)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			// Any errors that aren't timeouts are treated as permanent errors.
+			return backoff.Permanent(err)
+		}
+		return err
+	}, bOpts)
+	if err != nil {
+		return nil, err
+	}
+	return mdsConn, nil
+}
+
+// Server holds the running components of the query broker.
+type Server struct {
+	svr          *controllers.Server
+	agentTracker *tracker.Agents
+	natsConn     *nats.Conn
+	csClient     metadatapb.CronScriptStoreServiceClient
+	ptProxy      *ptproxy.PassThroughProxy
+	exports      *exporthealth.Tracker
+	selfTest     *selftest.Checker
+	mdsAddr      string
+}
+
+// New creates the query broker components on top of the given metadata service connection and message bus.
+func New(env querybrokerenv.QueryBrokerEnv, mdsConn *grpc.ClientConn, natsConn *nats.Conn) (*Server, error) {
+	mdsClient := metadatapb.NewMetadataServiceClient(mdsConn)
+	mdtpClient := metadatapb.NewMetadataTracepointServiceClient(mdsConn)
+	mdconfClient := metadatapb.NewMetadataConfigServiceClient(mdsConn)
+
+	dataPrivacy, err := controllers.CreateDataPrivacyManager(viper.GetString("pod_namespace"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create data privacy manager: %w", err)
+	}
+
+	agentTracker := tracker.NewAgents(mdsClient, viper.GetString("jwt_signing_key"))
+	agentTracker.Start()
+	svr, err := controllers.NewServer(env, agentTracker, dataPrivacy, mdtpClient, mdconfClient, natsConn, controllers.NewQueryExecutorFromServer)
+	if err != nil {
+		agentTracker.Stop()
+		return nil, fmt.Errorf("failed to initialize GRPC server funcs: %w", err)
+	}
+
+	checker, err := selftest.DefaultChecker()
+	if err != nil {
+		agentTracker.Stop()
+		return nil, fmt.Errorf("failed to create self-test checker: %w", err)
+	}
+
+	return &Server{
+		svr:          svr,
+		agentTracker: agentTracker,
+		natsConn:     natsConn,
+		csClient:     metadatapb.NewCronScriptStoreServiceClient(mdsConn),
+		exports: exporthealth.NewTracker(
+			viper.GetInt("cron_script_export_max_attempts"),
+			viper.GetDuration("cron_script_export_retry_backoff"),
+			viper.GetInt("cron_script_export_dead_letter_limit"),
+		),
+		selfTest: checker,
+		mdsAddr:  mdsConn.Target(),
+	}, nil
+}
+
+// InstallHandlers installs the endpoints that serve the health of the cron script exports and the self-test of
+// the query broker on the mux.
+func (s *Server) InstallHandlers(mux *http.ServeMux) {
+	mux.Handle(status.ExportHealthPath, s.exports)
+	mux.Handle(selftest.Path, selftest.NewHandler("query-broker", s.selfTest, s.selfTestTargets))
+}
+
+func (s *Server) selfTestTargets() []selftest.Target {
+	targets := []selftest.Target{
+		{Name: "metadata", Address: s.mdsAddr, TLS: !viper.GetBool("disable_ssl")},
+	}
+	// In-process connections to an embedded NATS server have no URL to check.
+	if url := s.natsConn.Opts.Url; url != "" {
+		targets = append(targets, selftest.URLTarget("nats", url, "4222"))
+	}
+	if t, ok := selftest.APIServerTarget(); ok {
+		targets = append(targets, t)
+	}
+	return targets
+}
+
+// RegisterGRPC registers the query broker GRPC services on the server.
+func (s *Server) RegisterGRPC(g *grpc.Server) {
+	carnotpb.RegisterResultSinkServiceServer(g, s.svr)
+	vizierpb.RegisterVizierServiceServer(g, s.svr)
+	if viper.GetBool("enable_arrow_flight") {
+		flightserver.NewServer(s.svr).Register(g)
+	}
+}
+
+// Start starts the passthrough proxy and the cron script runner. Both of them call back
+// into the VizierService served on the given local port.
+func (s *Server) Start(servicePort uint) error {
+	// For the passthrough proxy we create a GRPC client to the current server. It appears really
+	// hard to emulate the streaming GRPC connection and this helps keep the API straightforward.
+	vzServiceClient, err := NewVizierServiceClient(servicePort)
+	if err != nil {
+		return fmt.Errorf("failed to init vzservice client: %w", err)
+	}
+
+	// Start passthrough proxy.
+	ptProxy, err := ptproxy.NewPassThroughProxy(s.natsConn, vzServiceClient)
+	if err != nil {
+		return fmt.Errorf("failed to start passthrough proxy: %w", err)
+	}
+	s.ptProxy = ptProxy
+	go func() {
+		err := ptProxy.Run()
+		if err != nil {
+			log.WithError(err).Error("Passthrough proxy failed to run")
+		}
+	}()
+
+	// Start cron script runner.
+	sources := scriptrunner.Sources(
+		s.natsConn,
+		s.csClient,
+		viper.GetString("jwt_signing_key"),
+		viper.GetString("pod_namespace"),
+		viper.GetStringSlice("cron_script_sources"),
+	)
+	sr := scriptrunner.New(s.csClient, vzServiceClient, viper.GetString("jwt_signing_key"), sources...)
+	sr.EnableExportTracking(s.exports)
+	for _, name := range viper.GetStringSlice("cron_script_sources") {
+		if name == scriptrunner.CloudSourceName {
+			sr.EnableCloudAlerts(s.natsConn)
+			sr.EnableUsageReporting(s.natsConn)
+		}
+	}
+
+	// Load the scripts and start the background sync.
+	go func() {
+		err := sr.SyncScripts()
+		if err != nil {
+			log.WithError(err).Error("Failed to sync cron scripts")
+		}
+	}()
+	return nil
+}
+
+// Close stops the query broker components.
+func (s *Server) Close() {
+	if s.ptProxy != nil {
+		s.ptProxy.Close()
+	}
+	s.svr.Close()
+	s.agentTracker.Stop()
+}
@@ -0,0 +1,76 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package scriptrunner
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
+	"github.com/nats-io/nats.go"
+
+	"px.dev/pixie/src/shared/cvmsgs"
+	"px.dev/pixie/src/shared/cvmsgspb"
+	"px.dev/pixie/src/utils"
+	"px.dev/pixie/src/vizier/services/query_broker/alerts"
+	"px.dev/pixie/src/vizier/utils/messagebus"
+)
+
+// AlertEventChannel is the NATS channel that alert events are published to, so they can be routed by the cloud.
+var AlertEventChannel = messagebus.V2CTopic(cvmsgs.AlertEventChannel)
+
+// cloudAlertNotifier forwards alert events to the cloud, where they are routed to the alert plugins
+// (Slack, PagerDuty, ...) configured for the org.
+type cloudAlertNotifier struct {
+	nc       *nats.Conn
+	scriptID uuid.UUID
+}
+
+func newCloudAlertNotifier(nc *nats.Conn, scriptID uuid.UUID) *cloudAlertNotifier {
+	return &cloudAlertNotifier{nc: nc, scriptID: scriptID}
+}
+
+// Notify publishes the event to the cloud.
+func (n *cloudAlertNotifier) Notify(_ context.Context, e *alerts.Event) error {
+	startsAt, err := types.TimestampProto(e.StartsAt)
+	if err != nil {
+		return err
+	}
+	ts, err := types.TimestampProto(e.Timestamp)
+	if err != nil {
+		return err
+	}
+	data, err := marshalV2C(&cvmsgspb.AlertEvent{
+		ScriptID:    utils.ProtoFromUUID(n.scriptID),
+		Fingerprint: e.Fingerprint,
+		RuleName:    e.RuleName,
+		Status:      string(e.Status),
+		Severity:    e.Severity,
+		Labels:      e.Labels,
+		Value:       e.Value,
+		Threshold:   e.Threshold,
+		Operator:    e.Operator,
+		StartsAt:    startsAt,
+		Timestamp:   ts,
+	})
+	if err != nil {
+		return err
+	}
+	return n.nc.Publish(AlertEventChannel, data)
+}
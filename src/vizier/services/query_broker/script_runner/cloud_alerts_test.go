@@ -0,0 +1,92 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package scriptrunner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/proto"
+	"github.com/gogo/protobuf/types"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/shared/cvmsgspb"
+	"px.dev/pixie/src/utils"
+	"px.dev/pixie/src/utils/testingutils"
+	"px.dev/pixie/src/vizier/services/query_broker/alerts"
+)
+
+func TestCloudAlertNotifier_Notify(t *testing.T) {
+	nc, natsCleanup := testingutils.MustStartTestNATS(t)
+	defer natsCleanup()
+
+	msgCh := make(chan *nats.Msg, 1)
+	sub, err := nc.ChanSubscribe(AlertEventChannel, msgCh)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, sub.Unsubscribe())
+	}()
+
+	scriptID := uuid.Must(uuid.NewV4())
+	startsAt := time.Unix(1700000000, 0).UTC()
+	now := startsAt.Add(time.Minute)
+	n := newCloudAlertNotifier(nc, scriptID)
+	err = n.Notify(context.Background(), &alerts.Event{
+		Fingerprint: "abcd",
+		RuleName:    "high_latency",
+		Status:      alerts.StatusFiring,
+		Severity:    "critical",
+		Labels:      map[string]string{"service": "px-sock-shop/orders"},
+		Value:       350,
+		Threshold:   200,
+		Operator:    ">",
+		StartsAt:    startsAt,
+		Timestamp:   now,
+	})
+	require.NoError(t, err)
+
+	select {
+	case msg := <-msgCh:
+		v2cMsg := &cvmsgspb.V2CMessage{}
+		require.NoError(t, proto.Unmarshal(msg.Data, v2cMsg))
+		ev := &cvmsgspb.AlertEvent{}
+		require.NoError(t, types.UnmarshalAny(v2cMsg.Msg, ev))
+
+		require.Equal(t, scriptID, utils.UUIDFromProtoOrNil(ev.ScriptID))
+		require.Equal(t, "abcd", ev.Fingerprint)
+		require.Equal(t, "high_latency", ev.RuleName)
+		require.Equal(t, "firing", ev.Status)
+		require.Equal(t, "critical", ev.Severity)
+		require.Equal(t, map[string]string{"service": "px-sock-shop/orders"}, ev.Labels)
+		require.Equal(t, 350.0, ev.Value)
+		require.Equal(t, 200.0, ev.Threshold)
+		require.Equal(t, ">", ev.Operator)
+		s, err := types.TimestampFromProto(ev.StartsAt)
+		require.NoError(t, err)
+		require.Equal(t, startsAt, s)
+		ts, err := types.TimestampFromProto(ev.Timestamp)
+		require.NoError(t, err)
+		require.Equal(t, now, ts)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for alert event")
+	}
+}
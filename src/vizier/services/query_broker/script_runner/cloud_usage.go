@@ -0,0 +1,64 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package scriptrunner
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
+	"github.com/nats-io/nats.go"
+
+	"px.dev/pixie/src/shared/cvmsgs"
+	"px.dev/pixie/src/shared/cvmsgspb"
+	"px.dev/pixie/src/utils"
+	"px.dev/pixie/src/vizier/utils/messagebus"
+)
+
+// UsageReportChannel is the NATS channel that cron script usage is published to, so that it can be metered by the cloud.
+var UsageReportChannel = messagebus.V2CTopic(cvmsgs.UsageReportChannel)
+
+// cloudUsageReporter reports the data scanned and exported by each run of a cron script to the cloud.
+type cloudUsageReporter struct {
+	nc       *nats.Conn
+	scriptID uuid.UUID
+}
+
+func newCloudUsageReporter(nc *nats.Conn, scriptID uuid.UUID) *cloudUsageReporter {
+	return &cloudUsageReporter{nc: nc, scriptID: scriptID}
+}
+
+// Report publishes the usage of a single run of the script.
+func (r *cloudUsageReporter) Report(ts time.Time, bytesProcessed, recordsProcessed, bytesExported int64) error {
+	tsPb, err := types.TimestampProto(ts)
+	if err != nil {
+		return err
+	}
+	data, err := marshalV2C(&cvmsgspb.UsageReport{
+		ScriptID:         utils.ProtoFromUUID(r.scriptID),
+		BytesProcessed:   bytesProcessed,
+		RecordsProcessed: recordsProcessed,
+		BytesExported:    bytesExported,
+		Timestamp:        tsPb,
+	})
+	if err != nil {
+		return err
+	}
+	return r.nc.Publish(UsageReportChannel, data)
+}
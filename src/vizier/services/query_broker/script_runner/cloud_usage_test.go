@@ -0,0 +1,69 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package scriptrunner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/proto"
+	"github.com/gogo/protobuf/types"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/shared/cvmsgspb"
+	"px.dev/pixie/src/utils"
+	"px.dev/pixie/src/utils/testingutils"
+)
+
+func TestCloudUsageReporter_Report(t *testing.T) {
+	nc, natsCleanup := testingutils.MustStartTestNATS(t)
+	defer natsCleanup()
+
+	msgCh := make(chan *nats.Msg, 1)
+	sub, err := nc.ChanSubscribe(UsageReportChannel, msgCh)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, sub.Unsubscribe())
+	}()
+
+	scriptID := uuid.Must(uuid.NewV4())
+	now := time.Unix(1700000000, 0).UTC()
+	r := newCloudUsageReporter(nc, scriptID)
+	require.NoError(t, r.Report(now, 4096, 100, 1024))
+
+	select {
+	case msg := <-msgCh:
+		v2cMsg := &cvmsgspb.V2CMessage{}
+		require.NoError(t, proto.Unmarshal(msg.Data, v2cMsg))
+		report := &cvmsgspb.UsageReport{}
+		require.NoError(t, types.UnmarshalAny(v2cMsg.Msg, report))
+
+		require.Equal(t, scriptID, utils.UUIDFromProtoOrNil(report.ScriptID))
+		require.Equal(t, int64(4096), report.BytesProcessed)
+		require.Equal(t, int64(100), report.RecordsProcessed)
+		require.Equal(t, int64(1024), report.BytesExported)
+		ts, err := types.TimestampFromProto(report.Timestamp)
+		require.NoError(t, err)
+		require.Equal(t, now, ts)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for usage report")
+	}
+}
@@ -20,6 +20,7 @@ package scriptrunner
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"sync"
@@ -32,7 +33,9 @@ import (
 
 	"github.com/gofrs/uuid"
 	"github.com/gogo/protobuf/types"
+	"github.com/nats-io/nats.go"
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"gopkg.in/yaml.v2"
@@ -44,6 +47,9 @@ import (
 	svcutils "px.dev/pixie/src/shared/services/utils"
 	"px.dev/pixie/src/utils"
 	"px.dev/pixie/src/vizier/services/metadata/metadatapb"
+	"px.dev/pixie/src/vizier/services/query_broker/alerts"
+	"px.dev/pixie/src/vizier/services/query_broker/exporthealth"
+	"px.dev/pixie/src/vizier/services/query_broker/objectstore"
 	"px.dev/pixie/src/vizier/utils/messagebus"
 )
 
@@ -78,6 +84,13 @@ type ScriptRunner struct {
 	updatesCh  chan *cvmsgspb.CronScriptUpdate
 	baseCtx    context.Context
 	sources    []Source
+
+	// alertsNC is used to forward alert events to the cloud. Nil if cloud alert routing is disabled.
+	alertsNC *nats.Conn
+	// usageNC is used to report the usage of each script run to the cloud. Nil if usage reporting is disabled.
+	usageNC *nats.Conn
+	// exports tracks and retries the deliveries of the scripts' exports. Nil if export tracking is disabled.
+	exports *exporthealth.Tracker
 }
 
 // New creates a new script runner.
@@ -118,6 +131,24 @@ func (s *ScriptRunner) Stop() {
 	})
 }
 
+// EnableCloudAlerts forwards events from the scripts' alert rules to the cloud, in addition to the notifiers
+// configured on the scripts themselves. It must be called before SyncScripts.
+func (s *ScriptRunner) EnableCloudAlerts(nc *nats.Conn) {
+	s.alertsNC = nc
+}
+
+// EnableUsageReporting reports the data scanned and exported by each script run to the cloud, so that it can be
+// metered. It must be called before SyncScripts.
+func (s *ScriptRunner) EnableUsageReporting(nc *nats.Conn) {
+	s.usageNC = nc
+}
+
+// EnableExportTracking records the result of the scripts' exports to OTel collectors and object stores in the
+// tracker, and retries the failed ones. It must be called before SyncScripts.
+func (s *ScriptRunner) EnableExportTracking(t *exporthealth.Tracker) {
+	s.exports = t
+}
+
 // SyncScripts syncs the known set of scripts in Vizier with scripts in Cloud.
 func (s *ScriptRunner) SyncScripts() error {
 	for _, source := range s.sources {
@@ -159,8 +190,20 @@ func (s *ScriptRunner) upsertScript(id uuid.UUID, script *cvmsgspb.CronScript) {
 	if v, ok := s.runnerMap[id]; ok {
 		v.stop()
 		delete(s.runnerMap, id)
+		// The rows that the old runner failed to write to the object store are gone with its exporter.
+		if s.exports != nil {
+			s.exports.Abandon(id, exporthealth.SinkObjectStore, "the script was updated before the export was retried", time.Now())
+		}
+	}
+	var notifiers []alerts.Notifier
+	if s.alertsNC != nil {
+		notifiers = append(notifiers, newCloudAlertNotifier(s.alertsNC, id))
 	}
-	r := newRunner(script, s.vzClient, s.signingKey, id, s.csClient)
+	r := newRunner(script, s.vzClient, s.signingKey, id, s.csClient, notifiers...)
+	if s.usageNC != nil {
+		r.usage = newCloudUsageReporter(s.usageNC, id)
+	}
+	r.exports = s.exports
 	s.runnerMap[id] = r
 	go r.start()
 }
@@ -174,12 +217,19 @@ func (s *ScriptRunner) deleteScript(id uuid.UUID) {
 	}
 	v.stop()
 	delete(s.runnerMap, id)
+	if s.exports != nil {
+		s.exports.RemoveScript(id)
+	}
 }
 
 // Logic for "runners" which handle the script execution.
 type runner struct {
 	cronScript *cvmsgspb.CronScript
 	config     *scripts.Config
+	alerts     *alerts.Evaluator
+	exporter   *objectstore.Exporter
+	usage      *cloudUsageReporter
+	exports    *exporthealth.Tracker
 
 	lastRun time.Time
 
@@ -193,7 +243,7 @@ type runner struct {
 	scriptID uuid.UUID
 }
 
-func newRunner(script *cvmsgspb.CronScript, vzClient vizierpb.VizierServiceClient, signingKey string, id uuid.UUID, csClient metadatapb.CronScriptStoreServiceClient) *runner {
+func newRunner(script *cvmsgspb.CronScript, vzClient vizierpb.VizierServiceClient, signingKey string, id uuid.UUID, csClient metadatapb.CronScriptStoreServiceClient, notifiers ...alerts.Notifier) *runner {
 	// Parse config YAML into struct.
 	var config scripts.Config
 	err := yaml.Unmarshal([]byte(script.Configs), &config)
@@ -201,6 +251,22 @@ func newRunner(script *cvmsgspb.CronScript, vzClient vizierpb.VizierServiceClien
 		log.WithError(err).Error("Failed to parse config YAML")
 	}
 
+	var evaluator *alerts.Evaluator
+	if config.AlertConfig != nil {
+		evaluator, err = alerts.NewEvaluatorFromConfig(config.AlertConfig, notifiers...)
+		if err != nil {
+			log.WithError(err).Error("Failed to parse alert config")
+		}
+	}
+
+	var exporter *objectstore.Exporter
+	if config.ObjectStoreConfig != nil {
+		exporter, err = objectstore.NewExporterFromConfig(context.Background(), config.ObjectStoreConfig, viper.GetString("cluster_id"), id)
+		if err != nil {
+			log.WithError(err).Error("Failed to create object store exporter")
+		}
+	}
+
 	return &runner{
 		cronScript: script,
 		done:       make(chan struct{}),
@@ -209,6 +275,8 @@ func newRunner(script *cvmsgspb.CronScript, vzClient vizierpb.VizierServiceClien
 		vzClient:   vzClient,
 		signingKey: signingKey,
 		config:     &config,
+		alerts:     evaluator,
+		exporter:   exporter,
 		scriptID:   id,
 	}
 }
@@ -246,13 +314,48 @@ func VizierStatusToStatus(s *vizierpb.Status) (*statuspb.Status, error) {
 }
 
 func (r *runner) runScript(scriptPeriod time.Duration) {
+	// We set the time 1 second in the past to cover colletor latency and request latencies
+	// which can cause data overlaps or cause data to be missed.
+	startTime := r.lastRun.Add(-time.Second)
+	endTime := startTime.Add(scriptPeriod)
+	r.lastRun = time.Now()
+	r.execute(startTime, endTime, nil)
+	r.retryExports()
+}
+
+// retryExports retries the failed deliveries of the script whose backoff has elapsed. The OTel exports are retried
+// by running the script over the window again, while the data is still retained by the agents. The object store
+// exports are retried by writing the rows that the exporter kept.
+func (r *runner) retryExports() {
+	if r.exports == nil {
+		return
+	}
+	for _, d := range r.exports.DueRetries(r.scriptID, time.Now()) {
+		switch d.Sink {
+		case exporthealth.SinkOTel:
+			r.execute(d.Start, d.End, d)
+		case exporthealth.SinkObjectStore:
+			if r.exporter == nil {
+				r.exports.RecordFailure(d, errors.New("the script no longer exports to an object store"), time.Now())
+				continue
+			}
+			_, err := r.exporter.Retry(context.Background(), d.Start)
+			r.recordDelivery(d, err)
+		}
+	}
+}
+
+// execute runs the script over the window, and delivers its results to the configured sinks. If it is the retry of
+// a failed OTel delivery, the alerts and usage aren't evaluated again.
+func (r *runner) execute(startTime time.Time, endTime time.Time, retry *exporthealth.Delivery) {
 	claims := svcutils.GenerateJWTForService("query_broker", "vizier")
 	token, _ := svcutils.SignJWTClaims(claims, r.signingKey)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	// Scheduled scripts queue behind interactive queries in the query broker, see controllers.QueryPriorityMetadataKey.
 	ctx = metadata.AppendToOutgoingContext(ctx, "authorization",
-		fmt.Sprintf("bearer %s", token))
+		fmt.Sprintf("bearer %s", token), "px-query-priority", "background")
 
 	var otelEndpoint *vizierpb.Configs_OTelEndpointConfig
 	if r.config != nil && r.config.OtelEndpointConfig != nil {
@@ -264,11 +367,11 @@ func (r *runner) runScript(scriptPeriod time.Duration) {
 		}
 	}
 
-	// We set the time 1 second in the past to cover colletor latency and request latencies
-	// which can cause data overlaps or cause data to be missed.
-	startTime := r.lastRun.Add(-time.Second)
-	endTime := startTime.Add(scriptPeriod)
-	r.lastRun = time.Now()
+	evaluator := r.alerts
+	usage := r.usage
+	if retry != nil {
+		evaluator, usage = nil, nil
+	}
 	execScriptClient, err := r.vzClient.ExecuteScript(ctx, &vizierpb.ExecuteScriptRequest{
 		QueryStr: r.cronScript.Script,
 		Configs: &vizierpb.Configs{
@@ -283,13 +386,62 @@ func (r *runner) runScript(scriptPeriod time.Duration) {
 	if err != nil {
 		log.WithError(err).Error("Failed to execute cronscript")
 	}
+	succeeded := false
+	// failure is the error message of a failed run.
+	failure := ""
+	var bytesProcessed, recordsProcessed, bytesExported int64
+	defer func() {
+		if r.exporter != nil {
+			if !succeeded {
+				r.exporter.Reset()
+			} else {
+				n, err := r.exporter.Flush(ctx, startTime)
+				if err != nil {
+					log.WithError(err).Error("Failed to export cronscript results to object store")
+				}
+				bytesExported = n
+				r.recordDelivery(&exporthealth.Delivery{
+					ScriptID: r.scriptID,
+					Sink:     exporthealth.SinkObjectStore,
+					Start:    startTime,
+					End:      endTime,
+				}, err)
+			}
+		}
+		if otelEndpoint != nil && (succeeded || exporthealth.IsOTelExportError(failure)) {
+			d := retry
+			if d == nil {
+				d = &exporthealth.Delivery{ScriptID: r.scriptID, Sink: exporthealth.SinkOTel, Start: startTime, End: endTime}
+			}
+			var err error
+			if !succeeded {
+				err = errors.New(failure)
+			}
+			r.recordDelivery(d, err)
+		}
+		if usage != nil && succeeded {
+			if err := usage.Report(time.Now(), bytesProcessed, recordsProcessed, bytesExported); err != nil {
+				log.WithError(err).Error("Failed to report cronscript usage")
+			}
+		}
+		if evaluator == nil {
+			return
+		}
+		if !succeeded {
+			evaluator.Reset()
+			return
+		}
+		evaluator.Evaluate(ctx, time.Now())
+	}()
 	for {
 		resp, err := execScriptClient.Recv()
 		if err == io.EOF {
+			succeeded = true
 			break
 		}
 		if err != nil {
 			grpcStatus, _ := status.FromError(err)
+			failure = grpcStatus.Message()
 
 			tsPb, err := types.TimestampProto(startTime)
 			if err != nil {
@@ -316,6 +468,7 @@ func (r *runner) runScript(scriptPeriod time.Duration) {
 		}
 
 		if vzStatus := resp.GetStatus(); vzStatus != nil {
+			failure = vzStatus.Message
 			tsPb, err := types.TimestampProto(startTime)
 			if err != nil {
 				log.WithError(err).Error("Error while creating timestamp proto")
@@ -340,7 +493,21 @@ func (r *runner) runScript(scriptPeriod time.Duration) {
 			}
 			break
 		}
+		if md := resp.GetMetaData(); md != nil {
+			if evaluator != nil {
+				evaluator.ObserveTable(md)
+			}
+			if r.exporter != nil {
+				r.exporter.ObserveTable(md)
+			}
+		}
 		if data := resp.GetData(); data != nil {
+			if evaluator != nil {
+				evaluator.ObserveBatch(data.GetBatch())
+			}
+			if r.exporter != nil {
+				r.exporter.ObserveBatch(data.GetBatch())
+			}
 			tsPb, err := types.TimestampProto(startTime)
 			if err != nil {
 				log.WithError(err).Error("Error while creating timestamp proto")
@@ -349,6 +516,7 @@ func (r *runner) runScript(scriptPeriod time.Duration) {
 			if stats == nil {
 				continue
 			}
+			bytesProcessed, recordsProcessed = stats.BytesProcessed, stats.RecordsProcessed
 			_, err = r.csClient.RecordExecutionResult(ctx, &metadatapb.RecordExecutionResultRequest{
 				ScriptID:  utils.ProtoFromUUID(r.scriptID),
 				Timestamp: tsPb,
@@ -367,11 +535,30 @@ func (r *runner) runScript(scriptPeriod time.Duration) {
 					log.WithError(err).Error("Error recording execution stats")
 				}
 			}
+			succeeded = true
 			break
 		}
 	}
 }
 
+// recordDelivery records the result of a delivery. The rows of an object store export are discarded once it won't
+// be retried.
+func (r *runner) recordDelivery(d *exporthealth.Delivery, err error) {
+	if r.exports == nil {
+		if err != nil && d.Sink == exporthealth.SinkObjectStore {
+			r.exporter.Discard(d.Start)
+		}
+		return
+	}
+	if err == nil {
+		r.exports.RecordSuccess(d, time.Now())
+		return
+	}
+	if r.exports.RecordFailure(d, err, time.Now()) && d.Sink == exporthealth.SinkObjectStore && r.exporter != nil {
+		r.exporter.Discard(d.Start)
+	}
+}
+
 func (r *runner) start() {
 	if r.cronScript.FrequencyS <= 0 {
 		return
@@ -28,6 +28,7 @@ import (
 	"github.com/gofrs/uuid"
 	"github.com/gogo/protobuf/types"
 	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -39,6 +40,7 @@ import (
 	"px.dev/pixie/src/shared/cvmsgspb"
 	"px.dev/pixie/src/utils"
 	"px.dev/pixie/src/vizier/services/metadata/metadatapb"
+	"px.dev/pixie/src/vizier/services/query_broker/exporthealth"
 )
 
 func TestScriptRunner_SyncScripts(t *testing.T) {
@@ -507,3 +509,75 @@ func TestScriptRunner_StoreResults(t *testing.T) {
 		})
 	}
 }
+
+func TestRunner_RetriesFailedOTelExports(t *testing.T) {
+	fcs := &fakeCronStore{
+		scripts:                 make(map[uuid.UUID]*cvmsgspb.CronScript),
+		receivedResultRequestCh: make(chan *metadatapb.RecordExecutionResultRequest, 10),
+	}
+	script := &cvmsgspb.CronScript{
+		ID:         utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440000"),
+		Script:     "px.display()",
+		Configs:    "otelEndpointConfig: {url: example.com}",
+		FrequencyS: 1,
+	}
+	id := uuid.FromStringOrNil("223e4567-e89b-12d3-a456-426655440000")
+	fvs := &fakeVizierServiceClient{responses: []*vizierpb.ExecuteScriptResponse{
+		{
+			Status: &vizierpb.Status{
+				Code:    13, // INTERNAL
+				Message: "OTel export (carnot node_id=3) failed with error 'UNAVAILABLE'. Details: connection refused",
+			},
+		},
+	}}
+	tracker := exporthealth.NewTracker(3, 0, 10)
+	r := newRunner(script, fvs, "test", id, fcs)
+	r.exports = tracker
+	r.lastRun = time.Now()
+
+	// Without a backoff, the failed delivery is retried right after the run.
+	r.runScript(time.Second)
+	h := tracker.Health()
+	assert.Equal(t, 1, h.PendingRetries)
+	require.Len(t, h.Exports, 1)
+	assert.Equal(t, "otel", h.Exports[0].Sink)
+	assert.Equal(t, 2, h.Exports[0].ConsecutiveFailures)
+	assert.False(t, h.Exports[0].Failing)
+
+	// The retry runs the script over the same window again.
+	fvs.responses = []*vizierpb.ExecuteScriptResponse{
+		{Result: &vizierpb.ExecuteScriptResponse_Data{Data: &vizierpb.QueryData{}}},
+		{Result: &vizierpb.ExecuteScriptResponse_Data{Data: &vizierpb.QueryData{
+			ExecutionStats: &vizierpb.QueryExecutionStats{Timing: &vizierpb.QueryTimingInfo{}},
+		}}},
+	}
+	r.retryExports()
+	h = tracker.Health()
+	assert.Equal(t, 0, h.PendingRetries)
+	assert.Equal(t, 0, h.Exports[0].ConsecutiveFailures)
+	assert.NotNil(t, h.Exports[0].LastSuccessTime)
+}
+
+func TestRunner_IgnoresNonExportFailures(t *testing.T) {
+	fcs := &fakeCronStore{
+		scripts:                 make(map[uuid.UUID]*cvmsgspb.CronScript),
+		receivedResultRequestCh: make(chan *metadatapb.RecordExecutionResultRequest, 10),
+	}
+	script := &cvmsgspb.CronScript{
+		ID:         utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440000"),
+		Script:     "px.display()",
+		Configs:    "otelEndpointConfig: {url: example.com}",
+		FrequencyS: 1,
+	}
+	id := uuid.FromStringOrNil("223e4567-e89b-12d3-a456-426655440000")
+	fvs := &fakeVizierServiceClient{err: status.New(codes.InvalidArgument, "Invalid").Err()}
+	tracker := exporthealth.NewTracker(3, 0, 10)
+	r := newRunner(script, fvs, "test", id, fcs)
+	r.exports = tracker
+	r.lastRun = time.Now()
+
+	r.runScript(time.Second)
+	h := tracker.Health()
+	assert.Equal(t, 0, h.PendingRetries)
+	assert.Empty(t, h.Exports)
+}
@@ -21,6 +21,7 @@ package tracker
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/gofrs/uuid"
 	log "github.com/sirupsen/logrus"
@@ -41,15 +42,23 @@ type AgentsInfo interface {
 	ClearPendingState()
 	UpdateAgentsInfo(update *metadatapb.AgentUpdatesResponse) error
 	DistributedState() distributedpb.DistributedState
+	UnresponsiveAgents(heartbeatTimeout time.Duration) []uuid.UUID
+	ClockOffsets() map[uuid.UUID]time.Duration
 }
 
 // AgentsInfoImpl implements AgentsInfo to track information about the distributed state of the system.
 type AgentsInfoImpl struct {
 	ds distributedpb.DistributedState
-	// Controls access to ds.
+	// Controls access to ds, lastHeartbeats and clockOffsets.
 	dsMutex sync.Mutex
 
 	pendingDs *distributedpb.DistributedState
+
+	// The time of the last heartbeat of each PEM. These are tracked outside of the versioned state, since
+	// heartbeats don't change what the agents can be queried for.
+	lastHeartbeats map[uuid.UUID]time.Time
+	// The estimated offset of the clock of each PEM from the clock of the metadata service, as of its last heartbeat.
+	clockOffsets map[uuid.UUID]time.Duration
 }
 
 // NewAgentsInfo creates an empty agents info.
@@ -60,14 +69,18 @@ func NewAgentsInfo() AgentsInfo {
 			SchemaInfo: []*distributedpb.SchemaInfo{},
 			CarnotInfo: []*distributedpb.CarnotInfo{},
 		},
+		lastHeartbeats: make(map[uuid.UUID]time.Time),
+		clockOffsets:   make(map[uuid.UUID]time.Duration),
 	}
 }
 
 // NewTestAgentsInfo creates an agents info from a passed in distributed state.
 func NewTestAgentsInfo(ds *distributedpb.DistributedState) AgentsInfo {
 	return &AgentsInfoImpl{
-		ds:        *(ds),
-		pendingDs: nil,
+		ds:             *(ds),
+		pendingDs:      nil,
+		lastHeartbeats: make(map[uuid.UUID]time.Time),
+		clockOffsets:   make(map[uuid.UUID]time.Duration),
 	}
 }
 
@@ -111,6 +124,18 @@ func (a *AgentsInfoImpl) UpdateAgentsInfo(update *metadatapb.AgentUpdatesRespons
 		}
 		// case 1: agent info update
 		agent := agentUpdate.GetAgent()
+		if agent != nil && agent.Lameduck {
+			// The agent is shutting down, so leave it out of the state that new queries are planned on.
+			if _, present := carnotInfoMap[agentUUID]; present {
+				deletedAgents++
+			}
+			delete(carnotInfoMap, agentUUID)
+			a.dsMutex.Lock()
+			delete(a.lastHeartbeats, agentUUID)
+			delete(a.clockOffsets, agentUUID)
+			a.dsMutex.Unlock()
+			continue
+		}
 		if agent != nil {
 			if _, present := carnotInfoMap[agentUUID]; present {
 				updatedAgents++
@@ -125,6 +150,9 @@ func (a *AgentsInfoImpl) UpdateAgentsInfo(update *metadatapb.AgentUpdatesRespons
 				}
 				// this is a PEM
 				carnotInfoMap[agentUUID] = makeAgentCarnotInfo(agentUUID, agent.ASID, metadataInfo)
+				if agent.LastHeartbeatNS != 0 {
+					a.recordHeartbeat(agentUUID, time.Unix(0, agent.LastHeartbeatNS), time.Duration(agent.ClockOffsetNS))
+				}
 			} else {
 				// this is a Kelvin
 				kelvinGRPCAddress := agent.Info.IPAddress
@@ -151,6 +179,10 @@ func (a *AgentsInfoImpl) UpdateAgentsInfo(update *metadatapb.AgentUpdatesRespons
 		if agentUpdate.GetDeleted() {
 			deletedAgents++
 			delete(carnotInfoMap, agentUUID)
+			a.dsMutex.Lock()
+			delete(a.lastHeartbeats, agentUUID)
+			delete(a.clockOffsets, agentUUID)
+			a.dsMutex.Unlock()
 		}
 	}
 
@@ -184,6 +216,52 @@ func (a *AgentsInfoImpl) DistributedState() distributedpb.DistributedState {
 	return a.ds
 }
 
+func (a *AgentsInfoImpl) recordHeartbeat(agentID uuid.UUID, t time.Time, clockOffset time.Duration) {
+	a.dsMutex.Lock()
+	defer a.dsMutex.Unlock()
+	a.lastHeartbeats[agentID] = t
+	a.clockOffsets[agentID] = clockOffset
+}
+
+// UnresponsiveAgents returns the PEMs in the current distributed state that haven't sent a heartbeat within the timeout.
+// PEMs that haven't reported a heartbeat yet are assumed to be responsive.
+func (a *AgentsInfoImpl) UnresponsiveAgents(heartbeatTimeout time.Duration) []uuid.UUID {
+	a.dsMutex.Lock()
+	defer a.dsMutex.Unlock()
+
+	var unresponsive []uuid.UUID
+	for _, carnotInfo := range a.ds.CarnotInfo {
+		agentID, err := utils.UUIDFromProto(carnotInfo.AgentID)
+		if err != nil {
+			continue
+		}
+		hb, ok := a.lastHeartbeats[agentID]
+		if ok && time.Since(hb) > heartbeatTimeout {
+			unresponsive = append(unresponsive, agentID)
+		}
+	}
+	return unresponsive
+}
+
+// ClockOffsets returns the estimated clock offsets of the PEMs in the current distributed state. PEMs that haven't
+// reported a heartbeat yet are left out.
+func (a *AgentsInfoImpl) ClockOffsets() map[uuid.UUID]time.Duration {
+	a.dsMutex.Lock()
+	defer a.dsMutex.Unlock()
+
+	offsets := make(map[uuid.UUID]time.Duration)
+	for _, carnotInfo := range a.ds.CarnotInfo {
+		agentID, err := utils.UUIDFromProto(carnotInfo.AgentID)
+		if err != nil {
+			continue
+		}
+		if offset, ok := a.clockOffsets[agentID]; ok {
+			offsets[agentID] = offset
+		}
+	}
+	return offsets
+}
+
 func makeAgentCarnotInfo(agentID uuid.UUID, asid uint32, agentMetadata *distributedpb.MetadataInfo) *distributedpb.CarnotInfo {
 	return &distributedpb.CarnotInfo{
 		QueryBrokerAddress:   agentID.String(),
@@ -20,6 +20,7 @@ package tracker_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/spf13/viper"
@@ -350,3 +351,153 @@ func TestAgentsInfo_UpdateAgentsInfo(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, 0, len(agentsInfo.DistributedState().SchemaInfo))
 }
+
+func TestAgentsInfo_UnresponsiveAgents(t *testing.T) {
+	viper.Set("pod_namespace", "pl")
+	uuidpbs := makeTestAgentIDs(t)
+	agents := makeTestAgents(t)
+
+	now := time.Now()
+	// The first PEM is up to date, the second PEM hasn't sent a heartbeat for a minute.
+	agents[0].LastHeartbeatNS = now.UnixNano()
+	agents[1].LastHeartbeatNS = now.Add(-time.Minute).UnixNano()
+	agents[2].LastHeartbeatNS = now.Add(-time.Minute).UnixNano()
+
+	agentsInfo := tracker.NewAgentsInfo()
+	var updates []*metadatapb.AgentUpdate
+	for i, agent := range agents {
+		updates = append(updates, &metadatapb.AgentUpdate{
+			AgentID: uuidpbs[i],
+			Update: &metadatapb.AgentUpdate_Agent{
+				Agent: agent,
+			},
+		})
+	}
+	err := agentsInfo.UpdateAgentsInfo(&metadatapb.AgentUpdatesResponse{
+		AgentUpdates: updates,
+		EndOfVersion: true,
+	})
+	require.NoError(t, err)
+
+	// Only PEMs are reported, so the Kelvin is never unresponsive.
+	assert.Equal(t, []uuid.UUID{utils.UUIDFromProtoOrNil(uuidpbs[2])}, agentsInfo.UnresponsiveAgents(30*time.Second))
+	assert.Empty(t, agentsInfo.UnresponsiveAgents(2*time.Minute))
+
+	err = agentsInfo.UpdateAgentsInfo(&metadatapb.AgentUpdatesResponse{
+		AgentUpdates: []*metadatapb.AgentUpdate{
+			{
+				AgentID: uuidpbs[2],
+				Update: &metadatapb.AgentUpdate_Deleted{
+					Deleted: true,
+				},
+			},
+		},
+		EndOfVersion: true,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, agentsInfo.UnresponsiveAgents(30*time.Second))
+}
+
+func TestAgentsInfo_Lameduck(t *testing.T) {
+	viper.Set("pod_namespace", "pl")
+	uuidpbs := makeTestAgentIDs(t)
+	agents := makeTestAgents(t)
+
+	now := time.Now()
+	for _, agent := range agents {
+		agent.LastHeartbeatNS = now.Add(-time.Minute).UnixNano()
+	}
+
+	agentsInfo := tracker.NewAgentsInfo()
+	var updates []*metadatapb.AgentUpdate
+	for i, agent := range agents {
+		updates = append(updates, &metadatapb.AgentUpdate{
+			AgentID: uuidpbs[i],
+			Update: &metadatapb.AgentUpdate_Agent{
+				Agent: agent,
+			},
+		})
+	}
+	err := agentsInfo.UpdateAgentsInfo(&metadatapb.AgentUpdatesResponse{
+		AgentUpdates: updates,
+		EndOfVersion: true,
+	})
+	require.NoError(t, err)
+	ds := agentsInfo.DistributedState()
+	assert.Len(t, ds.CarnotInfo, 3)
+
+	lameduck := *agents[2]
+	lameduck.Lameduck = true
+	err = agentsInfo.UpdateAgentsInfo(&metadatapb.AgentUpdatesResponse{
+		AgentUpdates: []*metadatapb.AgentUpdate{
+			{
+				AgentID: uuidpbs[2],
+				Update: &metadatapb.AgentUpdate_Agent{
+					Agent: &lameduck,
+				},
+			},
+		},
+		EndOfVersion: true,
+	})
+	require.NoError(t, err)
+
+	// The lameduck PEM is left out of new queries, and isn't reported as unresponsive once it goes quiet.
+	ds = agentsInfo.DistributedState()
+	require.Len(t, ds.CarnotInfo, 2)
+	for _, carnotInfo := range ds.CarnotInfo {
+		assert.NotEqual(t, uuidpbs[2], carnotInfo.AgentID)
+	}
+	assert.Equal(t, []uuid.UUID{utils.UUIDFromProtoOrNil(uuidpbs[0])}, agentsInfo.UnresponsiveAgents(30*time.Second))
+}
+
+func TestAgentsInfo_ClockOffsets(t *testing.T) {
+	viper.Set("pod_namespace", "pl")
+	uuidpbs := makeTestAgentIDs(t)
+	agents := makeTestAgents(t)
+
+	now := time.Now()
+	agents[0].LastHeartbeatNS = now.UnixNano()
+	agents[0].ClockOffsetNS = (-20 * time.Millisecond).Nanoseconds()
+	agents[1].LastHeartbeatNS = now.UnixNano()
+	agents[1].ClockOffsetNS = (time.Second).Nanoseconds()
+	agents[2].LastHeartbeatNS = now.UnixNano()
+	agents[2].ClockOffsetNS = (300 * time.Millisecond).Nanoseconds()
+
+	agentsInfo := tracker.NewAgentsInfo()
+	var updates []*metadatapb.AgentUpdate
+	for i, agent := range agents {
+		updates = append(updates, &metadatapb.AgentUpdate{
+			AgentID: uuidpbs[i],
+			Update: &metadatapb.AgentUpdate_Agent{
+				Agent: agent,
+			},
+		})
+	}
+	err := agentsInfo.UpdateAgentsInfo(&metadatapb.AgentUpdatesResponse{
+		AgentUpdates: updates,
+		EndOfVersion: true,
+	})
+	require.NoError(t, err)
+
+	// Only PEMs are reported, so the offset of the Kelvin is left out.
+	assert.Equal(t, map[uuid.UUID]time.Duration{
+		utils.UUIDFromProtoOrNil(uuidpbs[0]): -20 * time.Millisecond,
+		utils.UUIDFromProtoOrNil(uuidpbs[2]): 300 * time.Millisecond,
+	}, agentsInfo.ClockOffsets())
+
+	err = agentsInfo.UpdateAgentsInfo(&metadatapb.AgentUpdatesResponse{
+		AgentUpdates: []*metadatapb.AgentUpdate{
+			{
+				AgentID: uuidpbs[2],
+				Update: &metadatapb.AgentUpdate_Deleted{
+					Deleted: true,
+				},
+			},
+		},
+		EndOfVersion: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID]time.Duration{
+		utils.UUIDFromProtoOrNil(uuidpbs[0]): -20 * time.Millisecond,
+	}, agentsInfo.ClockOffsets())
+}
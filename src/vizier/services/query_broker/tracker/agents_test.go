@@ -21,7 +21,9 @@ package tracker_test
 import (
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/gofrs/uuid"
 	"github.com/golang/mock/gomock"
 
 	"px.dev/pixie/src/carnot/planner/distributedpb"
@@ -45,6 +47,16 @@ func (a *fakeAgentsInfo) DistributedState() distributedpb.DistributedState {
 	return distributedpb.DistributedState{}
 }
 
+// UnresponsiveAgents implementation for fake agents info.
+func (a *fakeAgentsInfo) UnresponsiveAgents(time.Duration) []uuid.UUID {
+	return nil
+}
+
+// ClockOffsets implementation for fake agents info.
+func (a *fakeAgentsInfo) ClockOffsets() map[uuid.UUID]time.Duration {
+	return nil
+}
+
 func (a *fakeAgentsInfo) UpdateAgentsInfo(update *metadatapb.AgentUpdatesResponse) error {
 	if len(update.AgentUpdates) > 0 || len(update.AgentSchemas) > 0 {
 		a.wg.Done()
@@ -61,6 +61,26 @@ type Closer interface {
 	Close() error
 }
 
+// Usage is the space used by a datastore.
+type Usage struct {
+	// SizeBytes is the space that the datastore occupies on disk.
+	SizeBytes int64
+	// InUseBytes is the part of SizeBytes that is used by live data.
+	InUseBytes int64
+	// Alarms are the alarms that the datastore raised itself, such as etcd's NOSPACE.
+	Alarms []string
+}
+
+// Maintainer is a datastore that reports its usage, and can reclaim the space used by old and deleted data.
+type Maintainer interface {
+	Usage() (*Usage, error)
+	// Compact discards the history and the deleted keys that are no longer needed.
+	Compact() error
+	// Defragment returns the space freed by compaction to the filesystem, and clears the alarms that were raised
+	// because the datastore was full.
+	Defragment() error
+}
+
 // MultiGetterSetterDeleterCloser combines MultiGetter, TTLSetter, MultiDeleter, and Closer.
 type MultiGetterSetterDeleterCloser interface {
 	MultiGetter
@@ -16,7 +16,7 @@
  * SPDX-License-Identifier: Apache-2.0
  */
 
-package datastore
+package datastore_test
 
 import (
 	"fmt"
@@ -29,11 +29,12 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"px.dev/pixie/src/utils/testingutils"
+	"px.dev/pixie/src/vizier/utils/datastore"
 	"px.dev/pixie/src/vizier/utils/datastore/etcd"
 	"px.dev/pixie/src/vizier/utils/datastore/pebbledb"
 )
 
-func setupDatastore(t *testing.T, db Setter) {
+func setupDatastore(t *testing.T, db datastore.Setter) {
 	err := db.Set("jam1", "neg")
 	require.NoError(t, err)
 	err = db.Set("key1", "val1")
@@ -64,7 +65,7 @@ func TestDatastore(t *testing.T) {
 	defer cleanup()
 
 	tests := []struct {
-		db          MultiGetterSetterDeleterCloser
+		db          datastore.MultiGetterSetterDeleterCloser
 		name        string
 		runTTLTests bool
 	}{
@@ -23,8 +23,12 @@ import (
 	"sync"
 	"time"
 
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
 	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"px.dev/pixie/src/vizier/utils/datastore"
 )
 
 // DataStore wraps a clientv3 datastore.
@@ -129,6 +133,73 @@ func (w *DataStore) DeleteWithPrefix(prefix string) error {
 	return err
 }
 
+// Usage returns the size of the etcd database, and the alarms raised by its members.
+func (w *DataStore) Usage() (*datastore.Usage, error) {
+	usage := &datastore.Usage{}
+	for _, ep := range w.client.Endpoints() {
+		resp, err := w.client.Status(context.Background(), ep)
+		if err != nil {
+			return nil, err
+		}
+		// Each member has a copy of the database, so report the largest one.
+		if resp.DbSize > usage.SizeBytes {
+			usage.SizeBytes = resp.DbSize
+			usage.InUseBytes = resp.DbSizeInUse
+		}
+	}
+
+	alarms, err := w.client.AlarmList(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range alarms.Alarms {
+		usage.Alarms = append(usage.Alarms, a.Alarm.String())
+	}
+	return usage, nil
+}
+
+// Compact discards the revisions before the current one.
+func (w *DataStore) Compact() error {
+	endpoints := w.client.Endpoints()
+	if len(endpoints) == 0 {
+		return nil
+	}
+	resp, err := w.client.Status(context.Background(), endpoints[0])
+	if err != nil {
+		return err
+	}
+	_, err = w.client.Compact(context.Background(), resp.Header.Revision, clientv3.WithCompactPhysical())
+	// The revision may have been compacted already by etcd's own auto compaction.
+	if err == rpctypes.ErrCompacted {
+		return nil
+	}
+	return err
+}
+
+// Defragment defragments each of the endpoints, then disarms the NOSPACE alarms. etcd raises the alarms again if
+// the database is still over its quota.
+func (w *DataStore) Defragment() error {
+	for _, ep := range w.client.Endpoints() {
+		if _, err := w.client.Defragment(context.Background(), ep); err != nil {
+			return err
+		}
+	}
+
+	alarms, err := w.client.AlarmList(context.Background())
+	if err != nil {
+		return err
+	}
+	for _, a := range alarms.Alarms {
+		if a.Alarm != etcdserverpb.AlarmType_NOSPACE {
+			continue
+		}
+		if _, err := w.client.AlarmDisarm(context.Background(), (*clientv3.AlarmMember)(a)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Close closes the underlying datastore.
 // All other operations will fail after calling Close.
 func (w *DataStore) Close() error {
@@ -19,12 +19,15 @@
 package pebbledb
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/cockroachdb/pebble"
+
+	"px.dev/pixie/src/vizier/utils/datastore"
 )
 
 const (
@@ -250,6 +253,42 @@ func (w *DataStore) DeleteWithPrefix(prefix string) error {
 	return w.db.DeleteRange([]byte(prefix), ub, pebble.Sync)
 }
 
+// Usage returns the size of the tables and the WAL. Tables that were compacted away, but are still being read from,
+// aren't in use.
+func (w *DataStore) Usage() (*datastore.Usage, error) {
+	m := w.db.Metrics()
+	inUse := m.Total().Size + int64(m.WAL.Size)
+	return &datastore.Usage{
+		SizeBytes:  inUse + int64(m.Table.ObsoleteSize+m.Table.ZombieSize),
+		InUseBytes: inUse,
+	}, nil
+}
+
+// Compact compacts the entire keyspace, which drops the deleted keys and the expired TTLs.
+func (w *DataStore) Compact() error {
+	iter := w.db.NewIter(nil)
+	var last []byte
+	if iter.Last() {
+		last = append([]byte(nil), iter.Key()...)
+	}
+	if err := iter.Close(); err != nil {
+		return err
+	}
+
+	// Deleted keys may sort after the last live key, so compact at least up to 0xff, which is past any key that
+	// the metadata service writes.
+	end := []byte{0xff}
+	if bytes.Compare(last, end) >= 0 {
+		end = append(last, 0)
+	}
+	return w.db.Compact([]byte{}, end)
+}
+
+// Defragment is a no-op, since pebble deletes the tables that were compacted away once they are no longer read.
+func (w *DataStore) Defragment() error {
+	return nil
+}
+
 // Close stops the TTL watcher, and closes the underlying datastore.
 // All other operations will fail after calling Close.
 func (w *DataStore) Close() error {
@@ -0,0 +1,65 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pebbledb
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataStore_CompactReclaimsDeletedKeys(t *testing.T) {
+	db, err := pebble.Open("test", &pebble.Options{FS: vfs.NewMem()})
+	require.NoError(t, err)
+	ds := New(db, time.Hour)
+	defer ds.Close()
+
+	value := strings.Repeat("a", 1024)
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, ds.Set(fmt.Sprintf("/pod/%04d", i), value))
+	}
+	require.NoError(t, ds.Compact())
+	before, err := ds.Usage()
+	require.NoError(t, err)
+	assert.Greater(t, before.InUseBytes, int64(0))
+	assert.GreaterOrEqual(t, before.SizeBytes, before.InUseBytes)
+
+	require.NoError(t, ds.DeleteWithPrefix("/pod/"))
+	require.NoError(t, ds.Compact())
+	require.NoError(t, ds.Defragment())
+	after, err := ds.Usage()
+	require.NoError(t, err)
+	assert.Less(t, after.InUseBytes, before.InUseBytes/10)
+	assert.Empty(t, after.Alarms)
+}
+
+func TestDataStore_CompactEmpty(t *testing.T) {
+	db, err := pebble.Open("test", &pebble.Options{FS: vfs.NewMem()})
+	require.NoError(t, err)
+	ds := New(db, time.Hour)
+	defer ds.Close()
+
+	assert.NoError(t, ds.Compact())
+}